@@ -0,0 +1,107 @@
+package account
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/account"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/srp"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler handles account overview requests
+type Handler struct {
+	accountService *account.Service
+	logger         *logger.Logger
+}
+
+// NewHandler creates a new account handler
+func NewHandler(accountService *account.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		accountService: accountService,
+		logger:         log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// GetSummary handles fetching everything the dashboard shows about the current user's account in one call
+func (h *Handler) GetSummary(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	summary, err := h.accountService.GetSummary(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get account summary", "getAccountSummary")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	// Security events carry IP addresses, keep them out of shared caches
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewAccountSummaryResponse(summary, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RequestDeletion handles scheduling the deletion of the current user's account. The account is
+// signed out everywhere and purged after the grace period unless the emailed link cancels it.
+func (h *Handler) RequestDeletion(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	scheduledAt, err := h.accountService.RequestDeletion(c.Request.Context(), userID, srp.GetClientIPFromRequest(c.Request))
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrDeletionPending):
+			c.JSON(http.StatusConflict, NewErrorResponse(err.Error(), status.StatusConflict, middleware.RequestID(c)))
+		case errors.Is(err, user.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+		default:
+			h.secureLog(c, err, "Failed to schedule account deletion", "requestAccountDeletion")
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, NewAccountDeletionResponse(scheduledAt, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CancelDeletion handles cancelling a pending account deletion with the token from a deletion email
+func (h *Handler) CancelDeletion(c *gin.Context) {
+	var req CancelDeletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("Invalid request format", status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(c.Request.Context(), req.Token, srp.GetClientIPFromRequest(c.Request)); err != nil {
+		if errors.Is(err, account.ErrInvalidCancelToken) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusInvalidToken, middleware.RequestID(c)))
+			return
+		}
+		h.secureLog(c, err, "Failed to cancel account deletion", "cancelAccountDeletion")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,7 @@
+package account
+
+// CancelDeletionRequest represents a request to cancel a pending account deletion with the token
+// from a deletion email
+type CancelDeletionRequest struct {
+	Token string `json:"token" binding:"required,max=128"`
+}
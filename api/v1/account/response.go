@@ -0,0 +1,133 @@
+package account
+
+import (
+	"cirrussync-api/internal/account"
+	"encoding/json"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// StorageData represents the account's storage usage in bytes
+type StorageData struct {
+	UsedBytes      int64 `json:"usedBytes"`
+	LimitBytes     int64 `json:"limitBytes"`
+	RemainingBytes int64 `json:"remainingBytes"`
+}
+
+// SecurityEventData represents a recorded security event
+type SecurityEventData struct {
+	ID        string          `json:"id"`
+	EventType string          `json:"eventType"`
+	Success   bool            `json:"success"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt int64           `json:"createdAt"`
+}
+
+// PlanData represents the account's current plan
+type PlanData struct {
+	PlanID           string `json:"planId,omitempty"`
+	PlanType         string `json:"planType"`
+	Status           string `json:"status,omitempty"`
+	BillingCycle     string `json:"billingCycle,omitempty"`
+	AutoRenew        bool   `json:"autoRenew"`
+	CurrentPeriodEnd int64  `json:"currentPeriodEnd,omitempty"`
+	Subscribed       bool   `json:"subscribed"`
+	Delinquent       bool   `json:"delinquent"`
+}
+
+// AccountSummaryResponse represents the dashboard overview of an account
+type AccountSummaryResponse struct {
+	BaseResponse
+	Storage              StorageData         `json:"storage"`
+	RecentSecurityEvents []SecurityEventData `json:"recentSecurityEvents"`
+	ActiveSessions       int                 `json:"activeSessions"`
+	Plan                 PlanData            `json:"plan"`
+	UnreadNotifications  int                 `json:"unreadNotifications"`
+	GeneratedAt          int64               `json:"generatedAt"`
+}
+
+// NewAccountSummaryResponse creates a new account summary response
+func NewAccountSummaryResponse(summary *account.Summary, code int16, requestID string) AccountSummaryResponse {
+	events := make([]SecurityEventData, len(summary.RecentSecurityEvents))
+	for i, event := range summary.RecentSecurityEvents {
+		events[i] = SecurityEventData{
+			ID:        event.ID,
+			EventType: event.EventType,
+			Success:   event.Success,
+			Metadata:  event.AdditionalMetadata,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+
+	return AccountSummaryResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Storage: StorageData{
+			UsedBytes:      summary.Storage.UsedBytes,
+			LimitBytes:     summary.Storage.LimitBytes,
+			RemainingBytes: summary.Storage.RemainingBytes(),
+		},
+		RecentSecurityEvents: events,
+		ActiveSessions:       summary.ActiveSessions,
+		Plan: PlanData{
+			PlanID:           summary.Plan.PlanID,
+			PlanType:         summary.Plan.PlanType,
+			Status:           summary.Plan.Status,
+			BillingCycle:     summary.Plan.BillingCycle,
+			AutoRenew:        summary.Plan.AutoRenew,
+			CurrentPeriodEnd: summary.Plan.CurrentPeriodEnd,
+			Subscribed:       summary.Plan.Subscribed,
+			Delinquent:       summary.Plan.Delinquent,
+		},
+		UnreadNotifications: summary.PendingInvitations,
+		GeneratedAt:         summary.GeneratedAt,
+	}
+}
+
+// AccountDeletionResponse represents a scheduled account deletion
+type AccountDeletionResponse struct {
+	BaseResponse
+	ScheduledAt int64 `json:"scheduledAt"` // When the account is purged unless the deletion is cancelled
+}
+
+// NewAccountDeletionResponse creates a new account deletion response
+func NewAccountDeletionResponse(scheduledAt int64, code int16, requestID string) AccountDeletionResponse {
+	return AccountDeletionResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		ScheduledAt: scheduledAt,
+	}
+}
+
+// NewSuccessResponse creates a new response without data
+func NewSuccessResponse(code int16, requestID string) BaseResponse {
+	return BaseResponse{
+		Code:   code,
+		Detail: "Success with requestId " + requestID,
+	}
+}
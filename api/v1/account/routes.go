@@ -0,0 +1,26 @@
+package account
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPublicRoutes registers account routes that are reached from emailed links
+func RegisterPublicRoutes(r *gin.RouterGroup, h *Handler) {
+	// Cancel a pending account deletion; the account cannot sign in until it is cancelled
+	r.POST("/account/deletion/cancel", h.CancelDeletion)
+}
+
+// RegisterProtectedRoutes registers account overview routes
+func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
+	accountGroup := r.Group("")
+	{
+		// Dashboard overview of the current user's account
+		accountGroup.GET("/summary", h.GetSummary)
+	}
+}
+
+// RegisterUserRoutes registers the routes that manage the current user's account
+func RegisterUserRoutes(r *gin.RouterGroup, h *Handler) {
+	// Schedule the deletion of the current user's account
+	r.DELETE("/@me", h.RequestDeletion)
+}
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListEmailTemplates returns the email templates that can be previewed and test-sent
+func (h *Handler) ListEmailTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, NewEmailTemplatesResponse(mfa.EmailTemplates, status.StatusOK, middleware.RequestID(c)))
+}
+
+// PreviewEmailTemplate renders an email template with sample data, in the language of ?locale= when
+// given. With ?format=html the HTML body is returned as a page so it can be opened directly in a browser.
+func (h *Handler) PreviewEmailTemplate(c *gin.Context) {
+	rendered, err := h.mfaService.RenderEmailTemplate(c.Param("template"), c.Query("locale"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to render email template", "previewEmailTemplate")
+		h.handleEmailTemplateError(c, err)
+		return
+	}
+
+	if c.Query("format") == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTMLBody))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewRenderedEmailResponse(rendered, "", status.StatusOK, middleware.RequestID(c)))
+}
+
+// SendTestEmail sends an email template with sample data to an address through the configured provider
+func (h *Handler) SendTestEmail(c *gin.Context) {
+	var req SendTestEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "sendTestEmail")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	rendered, err := h.mfaService.SendTestEmail(c.Param("template"), req.Locale, req.Email)
+	if err != nil {
+		h.secureLog(c, err, "Failed to send test email", "sendTestEmail")
+		h.handleEmailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewRenderedEmailResponse(rendered, req.Email, status.StatusOK, middleware.RequestID(c)))
+}
+
+// ListEmailDeadLetters returns the newest emails that failed every attempt to send them, 50 by default
+func (h *Handler) ListEmailDeadLetters(c *gin.Context) {
+	var req EmailDeadLettersQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "listEmailDeadLetters")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 50
+	}
+
+	deadLetters, err := h.mfaService.EmailDeadLetters(c.Request.Context(), req.Limit)
+	if err != nil {
+		h.secureLog(c, err, "Failed to list email dead letters", "listEmailDeadLetters")
+		h.handleEmailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewEmailDeadLettersResponse(deadLetters, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RetryEmailDeadLetters resends the emails in the dead-letter queue. Emails that fail again stay queued.
+func (h *Handler) RetryEmailDeadLetters(c *gin.Context) {
+	sent, err := h.mfaService.RetryEmailDeadLetters(c.Request.Context())
+	if err != nil {
+		h.secureLog(c, err, "Failed to retry email dead letters", "retryEmailDeadLetters")
+		h.handleEmailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewEmailDeadLetterRetryResponse(sent, status.StatusOK, middleware.RequestID(c)))
+}
+
+// handleEmailTemplateError maps email template errors to responses. Provider errors are passed on
+// since finding them is what a test send is for.
+func (h *Handler) handleEmailTemplateError(c *gin.Context, err error) {
+	var sendErr *mfa.EmailSendError
+	switch {
+	case errors.Is(err, mfa.ErrUnknownEmailTemplate):
+		c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+	case errors.Is(err, mfa.ErrEmailNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, NewErrorResponse(err.Error(), status.StatusServiceUnavailable, middleware.RequestID(c)))
+	case errors.Is(err, mfa.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+	case errors.As(err, &sendErr):
+		c.JSON(http.StatusBadGateway, NewErrorResponse(sendErr.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+	}
+}
@@ -0,0 +1,321 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"cirrussync-api/internal/admin"
+	"cirrussync-api/internal/analytics"
+	"cirrussync-api/internal/billing"
+	"cirrussync-api/internal/cdn"
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/middleware"
+	"cirrussync-api/internal/oauth"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler handles admin API requests
+type Handler struct {
+	driveService     *drive.Service
+	cdnService       *cdn.Service
+	billingService   *billing.Service
+	analyticsService *analytics.Service
+	adminService     *admin.Service
+	mfaService       *mfa.Service
+	oauthService     *oauth.Service
+	sessionService   *session.Service
+	jobService       *jobs.Service
+	logger           *logger.Logger
+}
+
+// NewHandler creates a new admin handler
+func NewHandler(driveService *drive.Service, cdnService *cdn.Service, billingService *billing.Service, analyticsService *analytics.Service, adminService *admin.Service, mfaService *mfa.Service, oauthService *oauth.Service, sessionService *session.Service, jobService *jobs.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		driveService:     driveService,
+		cdnService:       cdnService,
+		billingService:   billingService,
+		analyticsService: analyticsService,
+		adminService:     adminService,
+		mfaService:       mfaService,
+		oauthService:     oauthService,
+		sessionService:   sessionService,
+		jobService:       jobService,
+		logger:           log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// GetRuntimeSettings returns the current runtime settings
+func (h *Handler) GetRuntimeSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, NewRuntimeSettingsResponse(h.driveService.GetRuntimeSettings(), status.StatusOK, middleware.RequestID(c)))
+}
+
+// UpdateDriveRuntimeSettings tunes the drive concurrency and time budget settings
+func (h *Handler) UpdateDriveRuntimeSettings(c *gin.Context) {
+	var req UpdateDriveRuntimeSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updateDriveRuntimeSettings")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Start from the current settings so omitted fields are preserved
+	settings := h.driveService.GetRuntimeSettings()
+	if req.DefaultTimeout != nil {
+		settings.DefaultTimeout = time.Duration(*req.DefaultTimeout) * time.Second
+	}
+	if req.ExtendedTimeout != nil {
+		settings.ExtendedTimeout = time.Duration(*req.ExtendedTimeout) * time.Second
+	}
+	if req.MaxConcurrency != nil {
+		settings.MaxConcurrency = *req.MaxConcurrency
+	}
+
+	updated, err := h.driveService.UpdateRuntimeSettings(settings)
+	if err != nil {
+		h.secureLog(c, err, "Failed to update drive runtime settings", "updateDriveRuntimeSettings")
+		if errors.Is(err, drive.ErrInvalidRuntimeSettings) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewRuntimeSettingsResponse(updated, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// PurgeCache purges cached responses from the CDN by surrogate key
+func (h *Handler) PurgeCache(c *gin.Context) {
+	var req PurgeCacheRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "purgeCache")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	if err := h.cdnService.PurgeKeys(c.Request.Context(), req.Keys); err != nil {
+		h.secureLog(c, err, "Failed to purge CDN cache", "purgeCache")
+		switch {
+		case errors.Is(err, cdn.ErrInvalidSurrogateKey):
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+		case errors.Is(err, cdn.ErrPurgeNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+		default:
+			c.JSON(http.StatusBadGateway, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPurgeCacheResponse(req.Keys, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetCompressionStats returns how many bytes response compression has saved since startup
+func (h *Handler) GetCompressionStats(c *gin.Context) {
+	c.JSON(http.StatusOK, NewCompressionStatsResponse(middleware.GetCompressionStats(), status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetIntegrityReport summarizes storage integrity issues found by the audit and lists the latest ones
+func (h *Handler) GetIntegrityReport(c *gin.Context) {
+	var req IntegrityReportQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "getIntegrityReport")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	report, err := h.driveService.GetIntegrityReport(c.Request.Context(), drive.IntegrityFilter{
+		State:  req.State,
+		UserID: req.UserID,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		h.secureLog(c, err, "Failed to get integrity report", "getIntegrityReport")
+		if errors.Is(err, drive.ErrInvalidIntegrityState) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewIntegrityReportResponse(report, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetGarbageCollectionReport returns what the last storage garbage collection removed and the totals
+// of every run
+func (h *Handler) GetGarbageCollectionReport(c *gin.Context) {
+	report, err := h.driveService.GetGarbageCollectionReport(c.Request.Context())
+	if err != nil {
+		h.secureLog(c, err, "Failed to get garbage collection report", "getGarbageCollectionReport")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewGarbageCollectionReportResponse(report, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GenerateGiftCards creates a batch of gift card codes
+func (h *Handler) GenerateGiftCards(c *gin.Context) {
+	var req GenerateGiftCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "generateGiftCards")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	batch := billing.GiftCardBatch{
+		Count:    req.Count,
+		Amount:   req.Amount,
+		Currency: req.Currency,
+	}
+	if req.ExpiresAt != nil {
+		batch.ExpiresAt = *req.ExpiresAt
+	}
+
+	cards, err := h.billingService.GenerateGiftCards(c.Request.Context(), c.GetString("userID"), batch)
+	if err != nil {
+		h.secureLog(c, err, "Failed to generate gift cards", "generateGiftCards")
+		if errors.Is(err, billing.ErrInvalidGiftCardBatch) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewGiftCardsResponse(cards, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// GetUsageMetrics returns flushed daily feature usage, by default for the last 30 days
+func (h *Handler) GetUsageMetrics(c *gin.Context) {
+	var req UsageMetricsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "getUsageMetrics")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	now := time.Now().UTC()
+	if req.To == "" {
+		req.To = now.Format(analytics.DAY_FORMAT)
+	}
+	if req.From == "" {
+		req.From = now.AddDate(0, 0, -30).Format(analytics.DAY_FORMAT)
+	}
+
+	metrics, err := h.analyticsService.GetDailyUsage(c.Request.Context(), req.From, req.To, req.RouteFamily)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get usage metrics", "getUsageMetrics")
+		if errors.Is(err, analytics.ErrInvalidDay) || errors.Is(err, analytics.ErrInvalidRange) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUsageMetricsResponse(req.From, req.To, metrics, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetConcurrentSessionStats returns how many sessions users keep in use at the same time, per plan
+func (h *Handler) GetConcurrentSessionStats(c *gin.Context) {
+	var req ConcurrentSessionsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "getConcurrentSessionStats")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	stats, err := h.sessionService.GetConcurrentSessionStats(c.Request.Context(), time.Duration(req.Window)*time.Second)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get concurrent session stats", "getConcurrentSessionStats")
+		if errors.Is(err, session.ErrInvalidWindow) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewConcurrentSessionsResponse(stats, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetErrorMetrics returns flushed hourly API error counts, by default for the last 24 hours
+func (h *Handler) GetErrorMetrics(c *gin.Context) {
+	var req ErrorMetricsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "getErrorMetrics")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	now := time.Now().UTC()
+	if req.To == "" {
+		req.To = now.Format(analytics.HOUR_FORMAT)
+	}
+	if req.From == "" {
+		req.From = now.Add(-24 * time.Hour).Format(analytics.HOUR_FORMAT)
+	}
+
+	metrics, err := h.analyticsService.GetHourlyErrors(c.Request.Context(), req.From, req.To, req.filter())
+	if err != nil {
+		h.secureLog(c, err, "Failed to get error metrics", "getErrorMetrics")
+		if errors.Is(err, analytics.ErrInvalidHour) || errors.Is(err, analytics.ErrInvalidRange) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewErrorMetricsResponse(req.From, req.To, metrics, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetLiveErrorMetrics returns the API error counters of the hours not flushed yet, including the current one
+func (h *Handler) GetLiveErrorMetrics(c *gin.Context) {
+	var req LiveErrorMetricsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "getLiveErrorMetrics")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	counts, err := h.analyticsService.GetLiveErrors(c.Request.Context(), req.filter())
+	if err != nil {
+		h.secureLog(c, err, "Failed to get live error metrics", "getLiveErrorMetrics")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewLiveErrorMetricsResponse(counts, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetTodayUsageMetrics returns the live feature usage counters of the current UTC day
+func (h *Handler) GetTodayUsageMetrics(c *gin.Context) {
+	counts, err := h.analyticsService.GetTodayUsage(c.Request.Context())
+	if err != nil {
+		h.secureLog(c, err, "Failed to get today's usage metrics", "getTodayUsageMetrics")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewTodayUsageMetricsResponse(counts, status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/middleware"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs returns the newest background jobs, optionally filtered by state, type and user
+func (h *Handler) ListJobs(c *gin.Context) {
+	var req JobsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "listJobs")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+
+	jobList, err := h.jobService.ListJobs(c.Request.Context(), jobs.Filter{
+		State:  req.State,
+		Type:   req.Type,
+		UserID: req.UserID,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		h.secureLog(c, err, "Failed to list jobs", "listJobs")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewJobsResponse(jobList, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetJobStats returns how many jobs there are of every type and state, to spot backlogs and failures
+func (h *Handler) GetJobStats(c *gin.Context) {
+	stats, err := h.jobService.GetStats(c.Request.Context())
+	if err != nil {
+		h.secureLog(c, err, "Failed to get job stats", "getJobStats")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewJobStatsResponse(stats, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetJob returns a background job of any user
+func (h *Handler) GetJob(c *gin.Context) {
+	job, err := h.jobService.InspectJob(c.Request.Context(), c.Param("jobID"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to get job", "getJob")
+		h.handleJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewJobResponse(job, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RetryJob queues a failed job again, with all its attempts
+func (h *Handler) RetryJob(c *gin.Context) {
+	job, err := h.jobService.RetryFailedJob(c.Request.Context(), c.Param("jobID"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to retry job", "retryJob")
+		h.handleJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewJobResponse(job, status.StatusOK, middleware.RequestID(c)))
+}
+
+// handleJobError maps background job errors to responses
+func (h *Handler) handleJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+	case errors.Is(err, jobs.ErrJobNotFailed):
+		c.JSON(http.StatusConflict, NewErrorResponse(err.Error(), status.StatusConflict, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+	}
+}
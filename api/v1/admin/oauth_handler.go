@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/oauth"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOAuthClients returns the registered third-party OAuth clients
+func (h *Handler) ListOAuthClients(c *gin.Context) {
+	clients, err := h.oauthService.ListClients(c.Request.Context())
+	if err != nil {
+		h.secureLog(c, err, "Failed to list OAuth clients", "listOAuthClients")
+		h.handleOAuthClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewOAuthClientsResponse(clients, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CreateOAuthClient registers a third-party OAuth client. The secret of a confidential client is
+// only returned in this response.
+func (h *Handler) CreateOAuthClient(c *gin.Context) {
+	var req CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createOAuthClient")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	client, secret, err := h.oauthService.CreateClient(c.Request.Context(), c.GetString("userID"), oauth.ClientInput{
+		Name:         req.Name,
+		Description:  req.Description,
+		HomepageURL:  req.HomepageURL,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		h.secureLog(c, err, "Failed to create OAuth client", "createOAuthClient")
+		h.handleOAuthClientError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, NewOAuthClientResponse(client, secret, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// RotateOAuthClientSecret issues a new secret for a confidential client, invalidating the old one
+func (h *Handler) RotateOAuthClientSecret(c *gin.Context) {
+	client, secret, err := h.oauthService.RotateClientSecret(c.Request.Context(), c.Param("clientID"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to rotate OAuth client secret", "rotateOAuthClientSecret")
+		h.handleOAuthClientError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewOAuthClientResponse(client, secret, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// DisableOAuthClient disables a client and revokes every token issued to it
+func (h *Handler) DisableOAuthClient(c *gin.Context) {
+	client, err := h.oauthService.DisableClient(c.Request.Context(), c.Param("clientID"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to disable OAuth client", "disableOAuthClient")
+		h.handleOAuthClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewOAuthClientResponse(client, "", status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// handleOAuthClientError maps OAuth client registration errors to responses
+func (h *Handler) handleOAuthClientError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, oauth.ErrClientNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+	case errors.Is(err, oauth.ErrClientDisabled):
+		c.JSON(http.StatusConflict, NewErrorResponse(err.Error(), status.StatusConflict, middleware.RequestID(c)))
+	case errors.Is(err, oauth.ErrUnauthorizedClient):
+		c.JSON(http.StatusConflict, NewErrorResponse("Public clients have no secret", status.StatusConflict, middleware.RequestID(c)))
+	case errors.Is(err, oauth.ErrInvalidClientInput),
+		errors.Is(err, oauth.ErrInvalidRedirectURI),
+		errors.Is(err, oauth.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+	}
+}
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/admin"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyPermissions returns the admin permissions of the signed in admin
+func (h *Handler) GetMyPermissions(c *gin.Context) {
+	userID := c.GetString("userID")
+	permissions, err := h.adminService.GetPermissions(c.Request.Context(), userID, c.GetStringSlice("roles"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to get admin permissions", "getMyPermissions")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPermissionsResponse(userID, permissions, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetUserPermissions returns the admin permissions of a user
+func (h *Handler) GetUserPermissions(c *gin.Context) {
+	userID := c.Param("userID")
+	permissions, err := h.adminService.GetUserPermissions(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get admin permissions", "getUserPermissions")
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPermissionsResponse(userID, permissions, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GrantPermission gives an admin user a permission
+func (h *Handler) GrantPermission(c *gin.Context) {
+	userID := c.Param("userID")
+	permissions, err := h.adminService.GrantPermission(c.Request.Context(), c.GetString("userID"), userID, c.Param("permission"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to grant admin permission", "grantPermission")
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPermissionsResponse(userID, permissions, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// RevokePermission takes a permission away from an admin user
+func (h *Handler) RevokePermission(c *gin.Context) {
+	userID := c.Param("userID")
+	permissions, err := h.adminService.RevokePermission(c.Request.Context(), c.GetString("userID"), userID, c.Param("permission"))
+	if err != nil {
+		h.secureLog(c, err, "Failed to revoke admin permission", "revokePermission")
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPermissionsResponse(userID, permissions, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// GetAuditLog returns recorded admin API requests, newest first
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	var req AuditLogQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "getAuditLog")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	entries, err := h.adminService.GetAuditLog(c.Request.Context(), admin.AuditFilter{
+		ActorID: req.ActorID,
+		Since:   req.Since,
+		Before:  req.Before,
+		Limit:   req.Limit,
+	})
+	if err != nil {
+		h.secureLog(c, err, "Failed to get admin audit log", "getAuditLog")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewAuditLogResponse(entries, status.StatusOK, middleware.RequestID(c)))
+}
+
+// handlePermissionError maps admin permission errors to responses
+func (h *Handler) handlePermissionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, admin.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+	case errors.Is(err, admin.ErrUnknownPermission), errors.Is(err, admin.ErrNotAdmin):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusValidationFailed, middleware.RequestID(c)))
+	case errors.Is(err, admin.ErrCannotChangeSelf):
+		c.JSON(http.StatusForbidden, NewErrorResponse(err.Error(), status.StatusForbidden, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+	}
+}
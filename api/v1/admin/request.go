@@ -0,0 +1,105 @@
+package admin
+
+import "cirrussync-api/internal/analytics"
+
+// UpdateDriveRuntimeSettingsRequest represents a request to tune drive runtime settings.
+// Fields left out keep their current value; timeouts are in seconds.
+type UpdateDriveRuntimeSettingsRequest struct {
+	DefaultTimeout  *int `json:"defaultTimeout" binding:"omitempty,min=1"`
+	ExtendedTimeout *int `json:"extendedTimeout" binding:"omitempty,min=1"`
+	MaxConcurrency  *int `json:"maxConcurrency" binding:"omitempty,min=1"`
+}
+
+// PurgeCacheRequest represents a request to purge CDN caches by surrogate key
+type PurgeCacheRequest struct {
+	Keys []string `json:"keys" binding:"required,min=1,max=100,dive,required"`
+}
+
+// GenerateGiftCardsRequest represents a request to generate a batch of gift cards.
+// Amount is in the currency's smallest unit; cards without expiresAt never expire.
+type GenerateGiftCardsRequest struct {
+	Count     int    `json:"count" binding:"required,min=1,max=500"`
+	Amount    int    `json:"amount" binding:"required,min=1,max=1000000"`
+	Currency  string `json:"currency" binding:"required,len=3,alpha"`
+	ExpiresAt *int64 `json:"expiresAt" binding:"omitempty,min=1"`
+}
+
+// UsageMetricsQuery represents the filters of a usage metrics query. Days are UTC and formatted as YYYY-MM-DD.
+type UsageMetricsQuery struct {
+	From        string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To          string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	RouteFamily string `form:"routeFamily" binding:"omitempty,max=100"`
+}
+
+// LiveErrorMetricsQuery represents the filters of a live error metrics query. Empty filters match everything.
+type LiveErrorMetricsQuery struct {
+	Route         string `form:"route" binding:"omitempty,max=200"`
+	ClientVersion string `form:"clientVersion" binding:"omitempty,max=32"`
+	Code          int    `form:"code" binding:"omitempty,min=1"`
+}
+
+// ErrorMetricsQuery represents the filters of an error metrics query. Hours are UTC and formatted as YYYY-MM-DDTHH.
+type ErrorMetricsQuery struct {
+	LiveErrorMetricsQuery
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02T15"`
+	To   string `form:"to" binding:"omitempty,datetime=2006-01-02T15"`
+}
+
+// filter converts the query into an analytics error filter
+func (q LiveErrorMetricsQuery) filter() analytics.ErrorFilter {
+	return analytics.ErrorFilter{
+		Route:         q.Route,
+		ClientVersion: q.ClientVersion,
+		ErrorCode:     q.Code,
+	}
+}
+
+// ConcurrentSessionsQuery represents the window of a concurrent sessions query, in seconds.
+// Omitting it uses the configured default.
+type ConcurrentSessionsQuery struct {
+	Window int64 `form:"window" binding:"omitempty,min=300,max=2592000"`
+}
+
+// AuditLogQuery represents the filters of an admin audit log query. Times are Unix seconds.
+type AuditLogQuery struct {
+	ActorID string `form:"actorId" binding:"omitempty,max=64"`
+	Since   int64  `form:"since" binding:"omitempty,min=0"`
+	Before  int64  `form:"before" binding:"omitempty,min=0"`
+	Limit   int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// IntegrityReportQuery represents the filters of the issues listed in a storage integrity report
+type IntegrityReportQuery struct {
+	State  string `form:"state" binding:"omitempty,oneof=open resolved"`
+	UserID string `form:"userId" binding:"omitempty,max=64"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// SendTestEmailRequest represents a request to send a template with sample data to an address
+type SendTestEmailRequest struct {
+	Email  string `json:"email" binding:"required,email,max=254"`
+	Locale string `json:"locale" binding:"omitempty,max=10"` // Language to render in, English when omitted
+}
+
+// EmailDeadLettersQuery represents how many failed emails to list
+type EmailDeadLettersQuery struct {
+	Limit int64 `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// JobsQuery represents the filters of a background job listing
+type JobsQuery struct {
+	State  string `form:"state" binding:"omitempty,oneof=queued running completed failed"`
+	Type   string `form:"type" binding:"omitempty,max=50"`
+	UserID string `form:"userId" binding:"omitempty,max=64"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// CreateOAuthClientRequest represents a request to register a third-party OAuth client
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	Description  string   `json:"description" binding:"omitempty,max=255"`
+	HomepageURL  string   `json:"homepageUrl" binding:"omitempty,url,max=2048"`
+	RedirectURIs []string `json:"redirectUris" binding:"required,min=1,max=10,dive,required,max=2048"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,required"`
+	Confidential bool     `json:"confidential"`
+}
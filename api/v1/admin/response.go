@@ -0,0 +1,805 @@
+package admin
+
+import (
+	"strings"
+
+	"cirrussync-api/internal/analytics"
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/mailer"
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/middleware"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/session"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// DriveRuntimeSettingsData represents drive runtime settings in the response
+type DriveRuntimeSettingsData struct {
+	DefaultTimeout  int `json:"defaultTimeout"`
+	ExtendedTimeout int `json:"extendedTimeout"`
+	MaxConcurrency  int `json:"maxConcurrency"`
+}
+
+// RuntimeSettingsResponse represents the runtime settings response
+type RuntimeSettingsResponse struct {
+	BaseResponse
+	Drive DriveRuntimeSettingsData `json:"drive"`
+}
+
+// PurgeCacheResponse represents the result of a CDN purge
+type PurgeCacheResponse struct {
+	BaseResponse
+	PurgedKeys []string `json:"purgedKeys"`
+}
+
+// CompressionStatsData represents the compression counters of one encoding
+type CompressionStatsData struct {
+	Encoding          string `json:"encoding"`
+	Responses         int64  `json:"responses"`
+	UncompressedBytes int64  `json:"uncompressedBytes"`
+	CompressedBytes   int64  `json:"compressedBytes"`
+	SavedBytes        int64  `json:"savedBytes"`
+	SkippedTooSmall   int64  `json:"skippedTooSmall"`
+}
+
+// CompressionStatsResponse represents response compression metrics since startup
+type CompressionStatsResponse struct {
+	BaseResponse
+	Encodings []CompressionStatsData `json:"encodings"`
+}
+
+// GiftCardData represents a generated gift card
+type GiftCardData struct {
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	Amount    int    `json:"amount"`
+	Currency  string `json:"currency"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// GiftCardsResponse represents a batch of generated gift cards
+type GiftCardsResponse struct {
+	BaseResponse
+	GiftCards []GiftCardData `json:"giftCards"`
+}
+
+// ErrorMetricData represents how often a route answered a client version with an error during an hour
+type ErrorMetricData struct {
+	Hour          string `json:"hour"`
+	Route         string `json:"route"`
+	ClientVersion string `json:"clientVersion"`
+	HTTPStatus    int    `json:"httpStatus"`
+	Code          int    `json:"code"`
+	Count         int64  `json:"count"`
+}
+
+// ErrorMetricsResponse represents flushed hourly error metrics
+type ErrorMetricsResponse struct {
+	BaseResponse
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Metrics []ErrorMetricData `json:"metrics"`
+}
+
+// LiveErrorMetricsResponse represents the error counters of the hours not flushed yet
+type LiveErrorMetricsResponse struct {
+	BaseResponse
+	Metrics []ErrorMetricData `json:"metrics"`
+}
+
+// UsageMetricData represents the usage of one route family from one client version during a day
+type UsageMetricData struct {
+	Day           string `json:"day"`
+	RouteFamily   string `json:"routeFamily"`
+	ClientVersion string `json:"clientVersion"`
+	UniqueUsers   int64  `json:"uniqueUsers"`
+	Requests      int64  `json:"requests"`
+}
+
+// UsageMetricsResponse represents flushed daily usage metrics
+type UsageMetricsResponse struct {
+	BaseResponse
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Metrics []UsageMetricData `json:"metrics"`
+}
+
+// TodayUsageMetricsResponse represents the live usage counters of the current day
+type TodayUsageMetricsResponse struct {
+	BaseResponse
+	Metrics []UsageMetricData `json:"metrics"`
+}
+
+// PlanSessionStatsData represents the sessions in use by the users of one plan
+type PlanSessionStatsData struct {
+	PlanID   string  `json:"planId"`
+	Users    int64   `json:"users"`
+	Sessions int64   `json:"sessions"`
+	Average  float64 `json:"average"`
+	P50      float64 `json:"p50"`
+	P90      float64 `json:"p90"`
+	P99      float64 `json:"p99"`
+	Max      int64   `json:"max"`
+}
+
+// SessionCountBucketData represents the number of users with a given number of sessions in use
+type SessionCountBucketData struct {
+	Sessions int64 `json:"sessions"`
+	Users    int64 `json:"users"`
+}
+
+// ConcurrentSessionsResponse represents how many sessions users keep in use at the same time
+type ConcurrentSessionsResponse struct {
+	BaseResponse
+	Window      int64                    `json:"window"`
+	GeneratedAt int64                    `json:"generatedAt"`
+	Plans       []PlanSessionStatsData   `json:"plans"`
+	Histogram   []SessionCountBucketData `json:"histogram"`
+}
+
+// PermissionsResponse represents the admin permissions of a user
+type PermissionsResponse struct {
+	BaseResponse
+	UserID      string   `json:"userId"`
+	Permissions []string `json:"permissions"`
+}
+
+// AuditEntryData represents a recorded admin API request
+type AuditEntryData struct {
+	ID         string `json:"id"`
+	ActorID    string `json:"actorId"`
+	Method     string `json:"method"`
+	Route      string `json:"route"`
+	Path       string `json:"path"`
+	Permission string `json:"permission,omitempty"`
+	StatusCode int    `json:"statusCode"`
+	IPAddress  string `json:"ipAddress"`
+	UserAgent  string `json:"userAgent"`
+	CreatedAt  int64  `json:"createdAt"`
+}
+
+// AuditLogResponse represents a page of the admin audit log, newest first
+type AuditLogResponse struct {
+	BaseResponse
+	Entries []AuditEntryData `json:"entries"`
+}
+
+// IntegrityIssueData represents a file block whose stored object does not match its record
+type IntegrityIssueData struct {
+	ID               string `json:"id"`
+	BlockID          string `json:"blockId"`
+	RevisionID       string `json:"revisionId"`
+	ItemID           string `json:"itemId"`
+	VolumeID         string `json:"volumeId"`
+	UserID           string `json:"userId"`
+	StoragePath      string `json:"storagePath"`
+	Kind             string `json:"kind"`
+	ExpectedSize     int64  `json:"expectedSize"`
+	ActualSize       int64  `json:"actualSize"`
+	ExpectedChecksum string `json:"expectedChecksum,omitempty"`
+	ActualChecksum   string `json:"actualChecksum,omitempty"`
+	ETag             string `json:"etag,omitempty"`
+	Recoverable      bool   `json:"recoverable"`
+	State            string `json:"state"`
+	NotifiedAt       *int64 `json:"notifiedAt,omitempty"`
+	DetectedAt       int64  `json:"detectedAt"`
+	LastCheckedAt    int64  `json:"lastCheckedAt"`
+	ResolvedAt       *int64 `json:"resolvedAt,omitempty"`
+}
+
+// IntegrityReportResponse represents a summary of storage integrity issues
+type IntegrityReportResponse struct {
+	BaseResponse
+	OpenByKind        map[string]int64     `json:"openByKind"`
+	OpenIrrecoverable int64                `json:"openIrrecoverable"`
+	AffectedUsers     int64                `json:"affectedUsers"`
+	ResolvedLast30d   int64                `json:"resolvedLast30d"`
+	Issues            []IntegrityIssueData `json:"issues"`
+}
+
+// GarbageCollectionStatsData represents what storage garbage collection removed
+type GarbageCollectionStatsData struct {
+	OrphanedObjects int64 `json:"orphanedObjects"`
+	DanglingBlocks  int64 `json:"danglingBlocks"`
+	DanglingItems   int64 `json:"danglingItems"`
+	ReclaimedBytes  int64 `json:"reclaimedBytes"`
+}
+
+// GarbageCollectionReportResponse represents the last storage garbage collection and the totals of every run
+type GarbageCollectionReportResponse struct {
+	BaseResponse
+	LastRunAt *int64                     `json:"lastRunAt"`
+	LastRun   GarbageCollectionStatsData `json:"lastRun"`
+	Total     GarbageCollectionStatsData `json:"total"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// NewValidationError creates a validation error response
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	if errs, ok := err.(validator.ValidationErrors); ok && len(errs) > 0 {
+		full := errs[0].Error()
+		parts := strings.SplitN(full, "Error:", 2)
+		message := full
+		if len(parts) == 2 {
+			message = strings.TrimSpace(parts[1])
+		}
+		return NewErrorResponse(message, code, requestID)
+	}
+	return NewErrorResponse("Invalid request format", code, requestID)
+}
+
+// NewRuntimeSettingsResponse creates a new runtime settings response
+func NewRuntimeSettingsResponse(settings drive.RuntimeSettings, code int16, requestID string) RuntimeSettingsResponse {
+	return RuntimeSettingsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Drive: DriveRuntimeSettingsData{
+			DefaultTimeout:  int(settings.DefaultTimeout.Seconds()),
+			ExtendedTimeout: int(settings.ExtendedTimeout.Seconds()),
+			MaxConcurrency:  settings.MaxConcurrency,
+		},
+	}
+}
+
+// NewPurgeCacheResponse creates a new CDN purge response
+func NewPurgeCacheResponse(keys []string, code int16, requestID string) PurgeCacheResponse {
+	return PurgeCacheResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		PurgedKeys: keys,
+	}
+}
+
+// NewCompressionStatsResponse creates a new response compression metrics response
+func NewCompressionStatsResponse(stats []middleware.CompressionStats, code int16, requestID string) CompressionStatsResponse {
+	data := make([]CompressionStatsData, len(stats))
+	for i, encoding := range stats {
+		data[i] = CompressionStatsData{
+			Encoding:          encoding.Encoding,
+			Responses:         encoding.Responses,
+			UncompressedBytes: encoding.UncompressedBytes,
+			CompressedBytes:   encoding.CompressedBytes,
+			SavedBytes:        encoding.SavedBytes,
+			SkippedTooSmall:   encoding.SkippedTooSmall,
+		}
+	}
+
+	return CompressionStatsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Encodings: data,
+	}
+}
+
+// NewGiftCardsResponse creates a new generated gift cards response
+func NewGiftCardsResponse(cards []*models.GiftCard, code int16, requestID string) GiftCardsResponse {
+	data := make([]GiftCardData, len(cards))
+	for i, card := range cards {
+		data[i] = GiftCardData{
+			ID:        card.ID,
+			Code:      card.Value,
+			Amount:    card.Amount,
+			Currency:  card.Currency,
+			ExpiresAt: card.ExpirationDate,
+		}
+	}
+
+	return GiftCardsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		GiftCards: data,
+	}
+}
+
+// NewConcurrentSessionsResponse creates a new concurrent sessions response
+func NewConcurrentSessionsResponse(stats *session.ConcurrentSessionStats, code int16, requestID string) ConcurrentSessionsResponse {
+	plans := make([]PlanSessionStatsData, len(stats.Plans))
+	for i, plan := range stats.Plans {
+		plans[i] = PlanSessionStatsData{
+			PlanID:   plan.PlanID,
+			Users:    plan.Users,
+			Sessions: plan.Sessions,
+			Average:  plan.Average,
+			P50:      plan.P50,
+			P90:      plan.P90,
+			P99:      plan.P99,
+			Max:      plan.Max,
+		}
+	}
+
+	histogram := make([]SessionCountBucketData, len(stats.Histogram))
+	for i, bucket := range stats.Histogram {
+		histogram[i] = SessionCountBucketData{
+			Sessions: bucket.Sessions,
+			Users:    bucket.Users,
+		}
+	}
+
+	return ConcurrentSessionsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Window:      stats.Window,
+		GeneratedAt: stats.GeneratedAt,
+		Plans:       plans,
+		Histogram:   histogram,
+	}
+}
+
+// NewUsageMetricsResponse creates a new daily usage metrics response
+func NewUsageMetricsResponse(from, to string, metrics []models.UsageMetric, code int16, requestID string) UsageMetricsResponse {
+	data := make([]UsageMetricData, len(metrics))
+	for i, metric := range metrics {
+		data[i] = UsageMetricData{
+			Day:           metric.Day,
+			RouteFamily:   metric.RouteFamily,
+			ClientVersion: metric.ClientVersion,
+			UniqueUsers:   metric.UniqueUsers,
+			Requests:      metric.Requests,
+		}
+	}
+
+	return UsageMetricsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		From:    from,
+		To:      to,
+		Metrics: data,
+	}
+}
+
+// NewTodayUsageMetricsResponse creates a new live usage metrics response
+func NewTodayUsageMetricsResponse(counts []analytics.UsageCount, code int16, requestID string) TodayUsageMetricsResponse {
+	data := make([]UsageMetricData, len(counts))
+	for i, count := range counts {
+		data[i] = UsageMetricData{
+			Day:           count.Day,
+			RouteFamily:   count.RouteFamily,
+			ClientVersion: count.ClientVersion,
+			UniqueUsers:   count.UniqueUsers,
+			Requests:      count.Requests,
+		}
+	}
+
+	return TodayUsageMetricsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Metrics: data,
+	}
+}
+
+// NewErrorMetricsResponse creates a new hourly error metrics response
+func NewErrorMetricsResponse(from, to string, metrics []models.ErrorMetric, code int16, requestID string) ErrorMetricsResponse {
+	data := make([]ErrorMetricData, len(metrics))
+	for i, metric := range metrics {
+		data[i] = ErrorMetricData{
+			Hour:          metric.Hour,
+			Route:         metric.Route,
+			ClientVersion: metric.ClientVersion,
+			HTTPStatus:    metric.HTTPStatus,
+			Code:          metric.ErrorCode,
+			Count:         metric.Count,
+		}
+	}
+
+	return ErrorMetricsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		From:    from,
+		To:      to,
+		Metrics: data,
+	}
+}
+
+// NewLiveErrorMetricsResponse creates a new live error metrics response
+func NewLiveErrorMetricsResponse(counts []analytics.ErrorCount, code int16, requestID string) LiveErrorMetricsResponse {
+	data := make([]ErrorMetricData, len(counts))
+	for i, count := range counts {
+		data[i] = ErrorMetricData{
+			Hour:          count.Hour,
+			Route:         count.Route,
+			ClientVersion: count.ClientVersion,
+			HTTPStatus:    count.HTTPStatus,
+			Code:          count.ErrorCode,
+			Count:         count.Count,
+		}
+	}
+
+	return LiveErrorMetricsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Metrics: data,
+	}
+}
+
+// NewPermissionsResponse creates a new admin permissions response
+func NewPermissionsResponse(userID string, permissions []string, code int16, requestID string) PermissionsResponse {
+	return PermissionsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		UserID:      userID,
+		Permissions: permissions,
+	}
+}
+
+// NewAuditLogResponse creates a new admin audit log response
+func NewAuditLogResponse(entries []models.AdminAuditLog, code int16, requestID string) AuditLogResponse {
+	data := make([]AuditEntryData, len(entries))
+	for i, entry := range entries {
+		data[i] = AuditEntryData{
+			ID:         entry.ID,
+			ActorID:    entry.ActorID,
+			Method:     entry.Method,
+			Route:      entry.Route,
+			Path:       entry.Path,
+			Permission: entry.Permission,
+			StatusCode: entry.StatusCode,
+			IPAddress:  entry.IPAddress,
+			UserAgent:  entry.UserAgent,
+			CreatedAt:  entry.CreatedAt,
+		}
+	}
+
+	return AuditLogResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Entries: data,
+	}
+}
+
+// NewIntegrityReportResponse creates a new storage integrity report response
+func NewIntegrityReportResponse(report *drive.IntegrityReport, code int16, requestID string) IntegrityReportResponse {
+	issues := make([]IntegrityIssueData, len(report.Issues))
+	for i, issue := range report.Issues {
+		issues[i] = IntegrityIssueData{
+			ID:               issue.ID,
+			BlockID:          issue.BlockID,
+			RevisionID:       issue.RevisionID,
+			ItemID:           issue.ItemID,
+			VolumeID:         issue.VolumeID,
+			UserID:           issue.UserID,
+			StoragePath:      issue.StoragePath,
+			Kind:             issue.Kind,
+			ExpectedSize:     issue.ExpectedSize,
+			ActualSize:       issue.ActualSize,
+			ExpectedChecksum: issue.ExpectedChecksum,
+			ActualChecksum:   issue.ActualChecksum,
+			ETag:             issue.ETag,
+			Recoverable:      issue.Recoverable,
+			State:            issue.State,
+			NotifiedAt:       issue.NotifiedAt,
+			DetectedAt:       issue.DetectedAt,
+			LastCheckedAt:    issue.LastCheckedAt,
+			ResolvedAt:       issue.ResolvedAt,
+		}
+	}
+
+	return IntegrityReportResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		OpenByKind:        report.OpenByKind,
+		OpenIrrecoverable: report.OpenIrrecoverable,
+		AffectedUsers:     report.AffectedUsers,
+		ResolvedLast30d:   report.ResolvedSince,
+		Issues:            issues,
+	}
+}
+
+// NewGarbageCollectionReportResponse creates a new storage garbage collection report response
+func NewGarbageCollectionReportResponse(report *drive.GarbageCollectionReport, code int16, requestID string) GarbageCollectionReportResponse {
+	var lastRunAt *int64
+	if report.LastRunAt > 0 {
+		lastRunAt = &report.LastRunAt
+	}
+
+	return GarbageCollectionReportResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		LastRunAt: lastRunAt,
+		LastRun:   GarbageCollectionStatsData(report.LastRun),
+		Total:     GarbageCollectionStatsData(report.Total),
+	}
+}
+
+// EmailTemplatesResponse represents the email templates that can be previewed and test-sent
+type EmailTemplatesResponse struct {
+	BaseResponse
+	Templates []string `json:"templates"`
+}
+
+// RenderedEmailData represents an email template rendered with sample data
+type RenderedEmailData struct {
+	Template string `json:"template"`
+	Subject  string `json:"subject"`
+	HTMLBody string `json:"htmlBody"`
+	TextBody string `json:"textBody"`
+}
+
+// RenderedEmailResponse represents a rendered or test-sent email template
+type RenderedEmailResponse struct {
+	BaseResponse
+	Email  RenderedEmailData `json:"email"`
+	SentTo string            `json:"sentTo,omitempty"`
+}
+
+// NewEmailTemplatesResponse creates a new email templates response
+func NewEmailTemplatesResponse(templates []string, code int16, requestID string) EmailTemplatesResponse {
+	return EmailTemplatesResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Templates: templates,
+	}
+}
+
+// NewRenderedEmailResponse creates a new rendered email response, with the address it went to for test sends
+func NewRenderedEmailResponse(rendered *mfa.RenderedEmail, sentTo string, code int16, requestID string) RenderedEmailResponse {
+	return RenderedEmailResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Email: RenderedEmailData{
+			Template: rendered.Template,
+			Subject:  rendered.Subject,
+			HTMLBody: rendered.HTMLBody,
+			TextBody: rendered.TextBody,
+		},
+		SentTo: sentTo,
+	}
+}
+
+// EmailDeadLettersResponse represents emails that failed every attempt to send them
+type EmailDeadLettersResponse struct {
+	BaseResponse
+	DeadLetters []mailer.DeadLetter `json:"deadLetters"`
+}
+
+// EmailDeadLetterRetryResponse represents how many failed emails a retry sent
+type EmailDeadLetterRetryResponse struct {
+	BaseResponse
+	Sent int `json:"sent"`
+}
+
+// NewEmailDeadLettersResponse creates a new email dead letters response
+func NewEmailDeadLettersResponse(deadLetters []mailer.DeadLetter, code int16, requestID string) EmailDeadLettersResponse {
+	return EmailDeadLettersResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		DeadLetters: deadLetters,
+	}
+}
+
+// NewEmailDeadLetterRetryResponse creates a new email dead letter retry response
+func NewEmailDeadLetterRetryResponse(sent int, code int16, requestID string) EmailDeadLetterRetryResponse {
+	return EmailDeadLetterRetryResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Sent: sent,
+	}
+}
+
+// JobData represents a background job. Payloads are left out since they can hold email links.
+type JobData struct {
+	ID          string           `json:"id"`
+	UserID      string           `json:"userId,omitempty"`
+	Type        string           `json:"type"`
+	State       string           `json:"state"`
+	Total       int64            `json:"total"`
+	Processed   int64            `json:"processed"`
+	Result      map[string]int64 `json:"result,omitempty"`
+	Attempts    int              `json:"attempts"`
+	RunAfter    int64            `json:"runAfter,omitempty"`
+	Error       *string          `json:"error,omitempty"`
+	StartedAt   *int64           `json:"startedAt,omitempty"`
+	CompletedAt *int64           `json:"completedAt,omitempty"`
+	CreatedAt   int64            `json:"createdAt"`
+	ModifiedAt  int64            `json:"modifiedAt"`
+}
+
+// JobResponse represents a single background job
+type JobResponse struct {
+	BaseResponse
+	Job JobData `json:"job"`
+}
+
+// JobsResponse represents a listing of background jobs
+type JobsResponse struct {
+	BaseResponse
+	Jobs []JobData `json:"jobs"`
+}
+
+// JobStatsResponse represents job counts by type and state
+type JobStatsResponse struct {
+	BaseResponse
+	Stats []jobs.Stats `json:"stats"`
+}
+
+// convertToJobData converts a job to its response data
+func convertToJobData(job *models.Job) JobData {
+	return JobData{
+		ID:          job.ID,
+		UserID:      job.UserID,
+		Type:        job.Type,
+		State:       job.State,
+		Total:       job.Total,
+		Processed:   job.Processed,
+		Result:      job.Result,
+		Attempts:    job.Attempts,
+		RunAfter:    job.RunAfter,
+		Error:       job.Error,
+		StartedAt:   job.StartedAt,
+		CompletedAt: job.CompletedAt,
+		CreatedAt:   job.CreatedAt,
+		ModifiedAt:  job.ModifiedAt,
+	}
+}
+
+// NewJobResponse creates a new job response
+func NewJobResponse(job *models.Job, code int16, requestID string) JobResponse {
+	return JobResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Job: convertToJobData(job),
+	}
+}
+
+// NewJobsResponse creates a new jobs response
+func NewJobsResponse(jobList []models.Job, code int16, requestID string) JobsResponse {
+	data := make([]JobData, len(jobList))
+	for i := range jobList {
+		data[i] = convertToJobData(&jobList[i])
+	}
+
+	return JobsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Jobs: data,
+	}
+}
+
+// NewJobStatsResponse creates a new job stats response
+func NewJobStatsResponse(stats []jobs.Stats, code int16, requestID string) JobStatsResponse {
+	if stats == nil {
+		stats = []jobs.Stats{}
+	}
+
+	return JobStatsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Stats: stats,
+	}
+}
+
+// OAuthClientData represents a registered OAuth client. The secret is only set right after it was created.
+type OAuthClientData struct {
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret,omitempty"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	HomepageURL  string   `json:"homepageUrl,omitempty"`
+	Confidential bool     `json:"confidential"`
+	RedirectURIs []string `json:"redirectUris"`
+	Scopes       []string `json:"scopes"`
+	State        int      `json:"state"`
+	CreatedBy    string   `json:"createdBy"`
+	CreatedAt    int64    `json:"createdAt"`
+	ModifiedAt   int64    `json:"modifiedAt"`
+}
+
+// OAuthClientResponse represents a single OAuth client
+type OAuthClientResponse struct {
+	BaseResponse
+	Client OAuthClientData `json:"client"`
+}
+
+// OAuthClientsResponse represents the registered OAuth clients
+type OAuthClientsResponse struct {
+	BaseResponse
+	Clients []OAuthClientData `json:"clients"`
+}
+
+// newOAuthClientData converts an OAuth client to its response form
+func newOAuthClientData(client *models.OAuthClient, secret string) OAuthClientData {
+	return OAuthClientData{
+		ClientID:     client.ClientID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		Description:  client.Description,
+		HomepageURL:  client.HomepageURL,
+		Confidential: client.Confidential,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		State:        client.State,
+		CreatedBy:    client.CreatedBy,
+		CreatedAt:    client.CreatedAt,
+		ModifiedAt:   client.ModifiedAt,
+	}
+}
+
+// NewOAuthClientResponse creates a new OAuth client response, with the plaintext secret when one was just issued
+func NewOAuthClientResponse(client *models.OAuthClient, secret string, code int16, requestID string) OAuthClientResponse {
+	return OAuthClientResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Client: newOAuthClientData(client, secret),
+	}
+}
+
+// NewOAuthClientsResponse creates a new OAuth clients response
+func NewOAuthClientsResponse(clients []*models.OAuthClient, code int16, requestID string) OAuthClientsResponse {
+	data := make([]OAuthClientData, len(clients))
+	for i, client := range clients {
+		data[i] = newOAuthClientData(client, "")
+	}
+
+	return OAuthClientsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Clients: data,
+	}
+}
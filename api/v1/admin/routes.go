@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"cirrussync-api/internal/admin"
+	"cirrussync-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterProtectedRoutes registers admin routes. Every route requires one admin permission;
+// managing permissions and reading the audit log is reserved to superadmins.
+func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
+	requires := func(permission string) gin.HandlerFunc {
+		return middleware.AdminPermissionMiddleware(h.adminService, permission)
+	}
+	superadminOnly := middleware.RoleRequiredMiddleware(admin.ROLE_SUPERADMIN)
+
+	adminGroup := r.Group("")
+	{
+		// Runtime settings
+		adminGroup.GET("/settings/runtime", requires(admin.PERMISSION_INFRA_OPERATE), h.GetRuntimeSettings)
+		adminGroup.PATCH("/settings/runtime/drive", requires(admin.PERMISSION_INFRA_OPERATE), h.UpdateDriveRuntimeSettings)
+
+		// CDN cache
+		adminGroup.POST("/cache/purge", requires(admin.PERMISSION_INFRA_OPERATE), h.PurgeCache)
+
+		// Gift cards
+		adminGroup.POST("/giftcards", requires(admin.PERMISSION_BILLING_MANAGE), h.GenerateGiftCards)
+
+		// Email templates
+		adminGroup.GET("/emails/templates", requires(admin.PERMISSION_INFRA_OPERATE), h.ListEmailTemplates)
+		adminGroup.GET("/emails/templates/:template/preview", requires(admin.PERMISSION_INFRA_OPERATE), h.PreviewEmailTemplate)
+		adminGroup.POST("/emails/templates/:template/test", requires(admin.PERMISSION_INFRA_OPERATE), h.SendTestEmail)
+		adminGroup.GET("/emails/dead-letters", requires(admin.PERMISSION_INFRA_OPERATE), h.ListEmailDeadLetters)
+		adminGroup.POST("/emails/dead-letters/retry", requires(admin.PERMISSION_INFRA_OPERATE), h.RetryEmailDeadLetters)
+
+		// Background jobs
+		adminGroup.GET("/jobs", requires(admin.PERMISSION_INFRA_OPERATE), h.ListJobs)
+		adminGroup.GET("/jobs/stats", requires(admin.PERMISSION_INFRA_OPERATE), h.GetJobStats)
+		adminGroup.GET("/jobs/:jobID", requires(admin.PERMISSION_INFRA_OPERATE), h.GetJob)
+		adminGroup.POST("/jobs/:jobID/retry", requires(admin.PERMISSION_INFRA_OPERATE), h.RetryJob)
+
+		// Third-party OAuth clients
+		adminGroup.GET("/oauth/clients", requires(admin.PERMISSION_INFRA_OPERATE), h.ListOAuthClients)
+		adminGroup.POST("/oauth/clients", requires(admin.PERMISSION_INFRA_OPERATE), h.CreateOAuthClient)
+		adminGroup.POST("/oauth/clients/:clientID/secret", requires(admin.PERMISSION_INFRA_OPERATE), h.RotateOAuthClientSecret)
+		adminGroup.DELETE("/oauth/clients/:clientID", requires(admin.PERMISSION_INFRA_OPERATE), h.DisableOAuthClient)
+
+		// Metrics
+		adminGroup.GET("/metrics/compression", requires(admin.PERMISSION_INFRA_OPERATE), h.GetCompressionStats)
+
+		// Storage integrity
+		adminGroup.GET("/integrity/report", requires(admin.PERMISSION_INFRA_OPERATE), h.GetIntegrityReport)
+
+		// Storage garbage collection
+		adminGroup.GET("/storage/gc", requires(admin.PERMISSION_INFRA_OPERATE), h.GetGarbageCollectionReport)
+
+		// Anonymized feature usage
+		adminGroup.GET("/analytics/usage", requires(admin.PERMISSION_SUPPORT_READ), h.GetUsageMetrics)
+		adminGroup.GET("/analytics/usage/today", requires(admin.PERMISSION_SUPPORT_READ), h.GetTodayUsageMetrics)
+
+		// API error codes per route and client version, to spot failure spikes after releases
+		adminGroup.GET("/analytics/errors", requires(admin.PERMISSION_SUPPORT_READ), h.GetErrorMetrics)
+		adminGroup.GET("/analytics/errors/live", requires(admin.PERMISSION_SUPPORT_READ), h.GetLiveErrorMetrics)
+
+		// Concurrent sessions, to inform plan limits
+		adminGroup.GET("/analytics/sessions", requires(admin.PERMISSION_SUPPORT_READ), h.GetConcurrentSessionStats)
+
+		// Admin permissions
+		adminGroup.GET("/permissions/@me", h.GetMyPermissions)
+		adminGroup.GET("/users/:userID/permissions", superadminOnly, h.GetUserPermissions)
+		adminGroup.PUT("/users/:userID/permissions/:permission", superadminOnly, h.GrantPermission)
+		adminGroup.DELETE("/users/:userID/permissions/:permission", superadminOnly, h.RevokePermission)
+
+		// Audit log
+		adminGroup.GET("/audit", superadminOnly, h.GetAuditLog)
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/auth"
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/srp"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleRequestEmailChange sends a link that confirms moving the signed in user's account to a new
+// address to that address
+func (h *Handler) HandleRequestEmailChange(c *gin.Context) {
+	var req RequestEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "requestEmailChange")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	if _, err := h.authService.RequestEmailChange(c.Request.Context(), c.GetString("userID"), req.Email); err != nil {
+		h.secureLog(c, err, err.Error(), "requestEmailChange")
+		h.respondEmailChangeError(c, err, "Failed to send verification email")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Verification email sent to the new address", status.StatusEmailVerificationSent))
+}
+
+// HandleConfirmEmailChange moves the signed in user's account to the address the token was sent to
+// and signs out its other sessions
+func (h *Handler) HandleConfirmEmailChange(c *gin.Context) {
+	var req ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "confirmEmailChange")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	err := h.authService.ConfirmEmailChange(c.Request.Context(), c.GetString("userID"), auth.EmailChange{
+		Token:       req.Token,
+		SRPSalt:     req.SRPSalt,
+		SRPVerifier: req.SRPVerifier,
+		SessionID:   c.GetString("sessionID"),
+		IPAddress:   srp.GetClientIPFromRequest(c.Request),
+	})
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "confirmEmailChange")
+		h.respondEmailChangeError(c, err, "Failed to change email address")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Email address changed successfully", status.StatusUpdated))
+}
+
+// respondEmailChangeError maps email change errors to responses
+func (h *Handler) respondEmailChangeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidEmailChangeToken):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusInvalidToken))
+	case errors.Is(err, user.ErrInvalidEmail), errors.Is(err, mfa.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusInvalidEmail))
+	case errors.Is(err, user.ErrEmailAlreadyExists):
+		c.JSON(http.StatusConflict, NewErrorResponse(err.Error(), status.StatusEmailAlreadyExists))
+	case errors.Is(err, mfa.ErrRateLimitExceeded), errors.Is(err, mfa.ErrEmailAlreadySent):
+		c.JSON(http.StatusTooManyRequests, NewErrorResponse(err.Error(), status.StatusTooManyRequests))
+	case errors.Is(err, auth.ErrInvalidInput), errors.Is(err, srp.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest))
+	case errors.Is(err, user.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(fallback, status.StatusInternalServerError))
+	}
+}
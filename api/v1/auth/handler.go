@@ -9,11 +9,12 @@ import (
 	"cirrussync-api/internal/auth"
 	"cirrussync-api/internal/jwt"
 	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/middleware"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/org"
 	"cirrussync-api/internal/session"
 	"cirrussync-api/internal/srp"
 	"cirrussync-api/internal/user"
-	"cirrussync-api/internal/utils"
 	"cirrussync-api/pkg/status"
 
 	"github.com/gin-gonic/gin"
@@ -21,23 +22,24 @@ import (
 )
 
 // NewHandler creates a new auth handler
-func NewHandler(authService *auth.Service, userService *user.Service, jwtService *jwt.JWTService, sessionService *session.Service, log *logger.Logger) *Handler {
+func NewHandler(authService *auth.Service, userService *user.Service, jwtService *jwt.JWTService, sessionService *session.Service, cookies *middleware.Cookies, log *logger.Logger) *Handler {
 	return &Handler{
 		authService:    authService,
 		userService:    userService,
 		jwtService:     jwtService,
 		sessionService: sessionService,
+		cookies:        cookies,
 		logger:         log,
 	}
 }
 
-// secureLog logs errors without sensitive data that might expose code or credentials
-func (h *Handler) secureLog(err error, message string, route string) {
-	requestID := utils.GenerateShortID()
-	h.logger.WithFields(logrus.Fields{
-		"requestID": requestID,
-		"route":     route,
-		"errorMsg":  err.Error(),
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
 	}).Error(message)
 }
 
@@ -45,7 +47,7 @@ func (h *Handler) secureLog(err error, message string, route string) {
 func (h *Handler) HandleLoginInit(c *gin.Context) {
 	var req LoginInitRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "loginInit")
+		h.secureLog(c, err, "Invalid request format", "loginInit")
 		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
 		return
 	}
@@ -64,6 +66,11 @@ func (h *Handler) HandleLoginInit(c *gin.Context) {
 	)
 
 	if err != nil {
+		if h.respondLockoutError(c, err) {
+			h.secureLog(c, err, err.Error(), "loginInit")
+			return
+		}
+
 		statusCode := http.StatusInternalServerError
 		apiStatusCode := status.StatusInternalServerError
 
@@ -79,7 +86,7 @@ func (h *Handler) HandleLoginInit(c *gin.Context) {
 			apiStatusCode = status.StatusBadRequest
 		}
 
-		h.secureLog(err, err.Error(), "loginInit")
+		h.secureLog(c, err, err.Error(), "loginInit")
 		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatusCode))
 		return
 	}
@@ -96,7 +103,7 @@ func (h *Handler) HandleLoginInit(c *gin.Context) {
 func (h *Handler) HandleLoginVerify(c *gin.Context) {
 	var req LoginVerifyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "loginVerify")
+		h.secureLog(c, err, "Invalid request format", "loginVerify")
 		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
 		return
 	}
@@ -112,6 +119,11 @@ func (h *Handler) HandleLoginVerify(c *gin.Context) {
 		ipAddress,
 	)
 	if err != nil {
+		if h.respondLockoutError(c, err) {
+			h.secureLog(c, err, err.Error(), "loginVerify")
+			return
+		}
+
 		statusCode := http.StatusInternalServerError
 		apiStatusCode := status.StatusInternalServerError
 
@@ -132,7 +144,7 @@ func (h *Handler) HandleLoginVerify(c *gin.Context) {
 			statusCode = http.StatusBadRequest
 			apiStatusCode = status.StatusNotFound
 		}
-		h.secureLog(err, err.Error(), "loginVerify")
+		h.secureLog(c, err, err.Error(), "loginVerify")
 		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatusCode))
 		return
 	}
@@ -140,12 +152,7 @@ func (h *Handler) HandleLoginVerify(c *gin.Context) {
 	// Create channels for parallel operations
 	userChan := make(chan *models.User)
 	userErrChan := make(chan error)
-	sessionChan := make(chan *models.UserSession)
-	sessionErrChan := make(chan error)
-	tokenChan := make(chan jwt.TokenPair)
-	tokenErrChan := make(chan error)
 
-	deviceInfo := GetDeviceDetails(c)
 	ctx := c.Request.Context()
 
 	// Get user in parallel
@@ -164,11 +171,67 @@ func (h *Handler) HandleLoginVerify(c *gin.Context) {
 	case user = <-userChan:
 		// User retrieved successfully
 	case err := <-userErrChan:
-		h.secureLog(err, "Failed to get user after successful SRP authentication", "loginVerify")
+		h.secureLog(c, err, "Failed to get user after successful SRP authentication", "loginVerify")
 		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to get user information", status.StatusInternalServerError))
 		return
 	}
 
+	// Service accounts authenticate with access tokens only, never interactively
+	for _, role := range user.Roles {
+		if role == org.ROLE_SERVICE_ACCOUNT {
+			h.secureLog(c, org.ErrServiceAccountDisabled, "Interactive login attempted for service account", "loginVerify")
+			c.JSON(http.StatusForbidden, NewErrorResponse("Interactive login is not allowed for this account", status.StatusForbidden))
+			return
+		}
+	}
+
+	if h.rejectPendingDeletion(c, user, "loginVerify") {
+		return
+	}
+
+	// Accounts with a second factor, or whose settings require one, finish login with it before any token is issued,
+	// unless the login comes from a device the user trusts
+	deviceTrust, _ := c.Cookie(middleware.DeviceTrustCookie.Name)
+	challenge, err := h.authService.StartLoginMFA(ctx, user.ID, response.ServerProof, GetDeviceDetails(c).ClientUID, deviceTrust)
+	if err != nil {
+		h.secureLog(c, err, "Failed to check second factor after successful SRP authentication", "loginVerify")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to start login verification", status.StatusInternalServerError))
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, NewLoginMFARequiredResponse(challenge, response.ServerProof, status.StatusMFARequired))
+		return
+	}
+
+	h.completeLogin(c, user, response.ServerProof, ipAddress, "loginVerify")
+}
+
+// rejectPendingDeletion refuses the login of an account scheduled for deletion. It reports whether the
+// login was refused; the deletion is cancelled with the link from the deletion email.
+func (h *Handler) rejectPendingDeletion(c *gin.Context, account *models.User, route string) bool {
+	if account.DeletionScheduledAt == 0 {
+		return false
+	}
+	h.secureLog(c, user.ErrDeletionPending, "Login attempted for account scheduled for deletion", route)
+	c.JSON(http.StatusForbidden, NewErrorResponse(user.ErrDeletionPending.Error(), status.StatusAccountLocked))
+	return true
+}
+
+// completeLogin creates the session, issues tokens and sets the auth cookies of an authenticated user
+func (h *Handler) completeLogin(c *gin.Context, user *models.User, serverProof, ipAddress, route string) {
+	// A deletion may have been requested while the login waited for its second factor
+	if h.rejectPendingDeletion(c, user, route) {
+		return
+	}
+
+	sessionChan := make(chan *models.UserSession)
+	sessionErrChan := make(chan error)
+	tokenChan := make(chan jwt.TokenPair)
+	tokenErrChan := make(chan error)
+
+	deviceInfo := GetDeviceDetails(c)
+	ctx := c.Request.Context()
+
 	// Create session and generate token in parallel
 	go func() {
 		// Create session directly using the session service
@@ -191,6 +254,13 @@ func (h *Handler) HandleLoginVerify(c *gin.Context) {
 		}
 		sessionChan <- session
 
+		// Keep track of the device so the user can manage and trust it
+		if deviceInfo.ClientUID != "" {
+			if err := h.userService.RegisterDevice(ctx, user.ID, deviceInfo.ClientUID, deviceInfo.ClientName); err != nil {
+				h.secureLog(c, err, "Failed to register login device", route)
+			}
+		}
+
 		// Generate token once we have the session
 		token, err := h.jwtService.GenerateAuthTokens(*user, session.ID)
 		if err != nil {
@@ -209,46 +279,73 @@ func (h *Handler) HandleLoginVerify(c *gin.Context) {
 	case userSession = <-sessionChan:
 		// Session created successfully
 	case err := <-sessionErrChan:
-		h.secureLog(err, err.Error(), "loginVerify")
+		h.secureLog(c, err, err.Error(), route)
 		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
 		return
 	}
+	h.authService.RecordLogin(ctx, user.ID, userSession.ID, ipAddress)
 
 	// Get token result
 	select {
 	case token = <-tokenChan:
 		// Token generated successfully
 	case err := <-tokenErrChan:
-		h.secureLog(err, err.Error(), "loginVerify")
+		h.secureLog(c, err, err.Error(), route)
 		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusJWTError))
 		return
 	}
 
-	maxAge := int(userSession.ExpiresAt - time.Now().Unix()) // Lifetime in seconds
+	// Desktop clients apply their device's sync schedule from the moment they sign in
+	syncSchedule, err := h.userService.GetSyncSchedule(ctx, user.ID, deviceInfo.ClientUID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to load device sync schedule", route)
+	}
 
-	// Set SameSite once before setting any cookies
-	c.SetSameSite(http.SameSiteStrictMode)
+	maxAge := int(userSession.ExpiresAt - time.Now().Unix()) // Lifetime in seconds
 
 	// Set cookies
-	c.SetCookie("sessionID", userSession.ID, maxAge, "/", "localhost", false, true)
-	c.SetCookie("accessToken", token.AccessToken, 60*60, "/api/v1", "localhost", false, true)
-	c.SetCookie("refreshToken", token.RefreshToken, 24*60*60, "/api/v1/auth/refresh", "localhost", false, true)
+	h.cookies.SetSession(c, userSession.ID, maxAge, token.AccessToken, token.RefreshToken)
 
 	// Return the response
 	c.JSON(http.StatusOK, NewLoginVerifyResponse(
 		token,
 		user,
 		userSession,
-		response.ServerProof,
+		serverProof,
+		syncSchedule,
 		status.StatusLoginSuccess,
 	))
 }
 
+// HandleAvailability checks whether a username and an email address can be signed up with, so signup
+// forms can point out conflicts before the keys are generated
+func (h *Handler) HandleAvailability(c *gin.Context) {
+	var req AvailabilityRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "availability")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	result, err := h.userService.CheckAvailability(c.Request.Context(), req.Username, req.Email)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "availability")
+		if err == user.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("A username or email is required", status.StatusBadRequest))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to check availability", status.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewAvailabilityResponse(result, status.StatusOK))
+}
+
 // HandleSignup handles user registration
 func (h *Handler) HandleSignup(c *gin.Context) {
 	var req SignupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "signup")
+		h.secureLog(c, err, "Invalid request format", "signup")
 		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
 		return
 	}
@@ -257,30 +354,31 @@ func (h *Handler) HandleSignup(c *gin.Context) {
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 
 	// Create user
-	user, err := h.authService.CreateUser(c.Request.Context(), email, req.Username, req.Keys)
+	account, err := h.authService.CreateUser(c.Request.Context(), email, req.Username, req.Keys)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		apiStatusCode := status.StatusInternalServerError
 
+		// The user service reports its own errors for the same problems
 		switch err {
-		case auth.ErrInvalidEmail:
+		case auth.ErrInvalidEmail, user.ErrInvalidEmail:
 			statusCode = http.StatusBadRequest
 			apiStatusCode = status.StatusBadRequest
-		case auth.ErrInvalidUsername:
+		case auth.ErrInvalidUsername, user.ErrInvalidUsername:
 			statusCode = http.StatusBadRequest
 			apiStatusCode = status.StatusBadRequest
-		case auth.ErrInvalidInput:
+		case auth.ErrInvalidInput, user.ErrInvalidInput, user.ErrInvalidKey:
 			statusCode = http.StatusBadRequest
 			apiStatusCode = status.StatusBadRequest
-		case auth.ErrUsernameAlreadyExists:
+		case auth.ErrUsernameAlreadyExists, user.ErrUsernameAlreadyExists, user.ErrUsernameReserved:
 			statusCode = http.StatusConflict
-			apiStatusCode = status.StatusEmailAlreadyExists
-		case auth.ErrEmailAlreadyExists:
+			apiStatusCode = status.StatusConflict
+		case auth.ErrEmailAlreadyExists, user.ErrEmailAlreadyExists:
 			statusCode = http.StatusConflict
 			apiStatusCode = status.StatusEmailAlreadyExists
 		}
 
-		h.secureLog(err, err.Error(), "signup")
+		h.secureLog(c, err, err.Error(), "signup")
 		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatusCode))
 		return
 	}
@@ -288,21 +386,21 @@ func (h *Handler) HandleSignup(c *gin.Context) {
 	// Store SRP credentials that were generated client-side
 	err = h.authService.RegisterSRP(
 		c.Request.Context(),
-		user.ID,
+		account.ID,
 		email,
 		req.SRPSalt,     // Client-generated salt
 		req.SRPVerifier, // Client-generated verifier
 	)
 	if err != nil {
 		// If SRP registration fails, delete the user
-		h.userService.DeleteUser(c, user.ID)
-		h.secureLog(err, err.Error(), "signup")
+		h.userService.DeleteUser(c, account.ID)
+		h.secureLog(c, err, err.Error(), "signup")
 		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusSRPError))
 		return
 	}
 
 	c.JSON(http.StatusCreated, NewSignupResponse(
-		user.ID,
+		account.ID,
 		status.StatusSignupSuccess,
 	))
 }
@@ -311,7 +409,7 @@ func (h *Handler) HandleSignup(c *gin.Context) {
 func (h *Handler) HandleChangePassword(c *gin.Context) {
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "changePassword")
+		h.secureLog(c, err, "Invalid request format", "changePassword")
 		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
 		return
 	}
@@ -340,7 +438,7 @@ func (h *Handler) HandleChangePassword(c *gin.Context) {
 			apiStatusCode = status.StatusInvalidCredentials
 		}
 
-		h.secureLog(err, err.Error(), "changePassword")
+		h.secureLog(c, err, err.Error(), "changePassword")
 		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatusCode))
 		return
 	}
@@ -348,6 +446,56 @@ func (h *Handler) HandleChangePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, NewSuccessResponse("Password changed successfully", status.StatusPasswordChanged))
 }
 
+// HandlePasswordResetConfirm handles resetting a forgotten password. The client sends new SRP
+// credentials and its key passphrases re-encrypted for them; every session of the account is revoked.
+func (h *Handler) HandlePasswordResetConfirm(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "passwordResetConfirm")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	keys := make([]srp.KeyPassphrase, len(req.Keys))
+	for i, key := range req.Keys {
+		keys[i] = srp.KeyPassphrase{
+			KeyID:               key.ID,
+			Passphrase:          key.Passphrase,
+			PassphraseSignature: key.PassphraseSignature,
+		}
+	}
+
+	err := h.authService.ResetPassword(c.Request.Context(), auth.PasswordReset{
+		Token:       req.Token,
+		SRPSalt:     req.SRPSalt,
+		SRPVerifier: req.SRPVerifier,
+		Keys:        keys,
+		IPAddress:   srp.GetClientIPFromRequest(c.Request),
+	})
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		apiStatusCode := status.StatusInternalServerError
+		message := "Failed to reset password"
+
+		switch err {
+		case auth.ErrInvalidInput, srp.ErrKeysIncomplete:
+			statusCode = http.StatusBadRequest
+			apiStatusCode = status.StatusBadRequest
+			message = err.Error()
+		case auth.ErrInvalidResetToken:
+			statusCode = http.StatusBadRequest
+			apiStatusCode = status.StatusInvalidToken
+			message = err.Error()
+		}
+
+		h.secureLog(c, err, err.Error(), "passwordResetConfirm")
+		c.JSON(statusCode, NewErrorResponse(message, apiStatusCode))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Password reset successfully", status.StatusPasswordChanged))
+}
+
 // HandleLogout handles user logout
 func (h *Handler) HandleLogout(c *gin.Context) {
 	logoutErrChan := make(chan error, 1)
@@ -359,13 +507,8 @@ func (h *Handler) HandleLogout(c *gin.Context) {
 		return
 	}
 
-	// Set SameSite once before setting any cookies
-	c.SetSameSite(http.SameSiteStrictMode)
-
 	// Set cookies to expire immediately - do this first for good UX
-	c.SetCookie("sessionID", "", -1, "/", "localhost", false, true)
-	c.SetCookie("accessToken", "", -1, "/api/v1", "localhost", false, true)
-	c.SetCookie("refreshToken", "", -1, "/api/v1/auth/refresh", "localhost", false, true)
+	h.cookies.ClearSession(c)
 
 	// Return success response immediately
 	c.JSON(http.StatusOK, NewSuccessResponse("Logged out successfully", status.StatusLogoutSuccess))
@@ -381,7 +524,7 @@ func (h *Handler) HandleLogout(c *gin.Context) {
 	go func() {
 		err := <-logoutErrChan
 		if err != nil {
-			h.secureLog(err, err.Error(), "logout")
+			h.secureLog(c, err, err.Error(), "logout")
 		}
 	}()
 }
@@ -419,7 +562,7 @@ func (h *Handler) HandleRefreshToken(c *gin.Context) {
 		// Validate the refresh token
 		claims, err := h.jwtService.ValidateToken(refreshToken)
 		if err != nil || !*claims.IsRefreshToken {
-			h.secureLog(err, "Invalid refresh token", "refreshToken")
+			h.secureLog(c, err, "Invalid refresh token", "refreshToken")
 			c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid refresh token", status.StatusInvalidToken))
 			return
 		}
@@ -498,7 +641,7 @@ func (h *Handler) HandleRefreshToken(c *gin.Context) {
 	// Generate new tokens
 	token, err := h.jwtService.GenerateAuthTokens(*user, sessionID)
 	if err != nil {
-		h.secureLog(err, err.Error(), "refreshToken")
+		h.secureLog(c, err, err.Error(), "refreshToken")
 		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusInvalidToken))
 		return
 	}
@@ -512,13 +655,7 @@ func (h *Handler) HandleRefreshToken(c *gin.Context) {
 		sessionTTL = 0 // Prevent negative TTL
 	}
 
-	// Set SameSite once before setting any cookies
-	c.SetSameSite(http.SameSiteStrictMode)
-
-	// Then set all cookies (they'll inherit the SameSite setting)
-	c.SetCookie("sessionID", userSession.ID, sessionTTL, "/", "localhost", false, true)
-	c.SetCookie("accessToken", token.AccessToken, 60*60, "/api/v1", "localhost", false, true)                   // 1 hour
-	c.SetCookie("refreshToken", token.RefreshToken, 24*60*60, "/api/v1/auth/refresh", "localhost", false, true) // 1 day
+	h.cookies.SetSession(c, userSession.ID, sessionTTL, token.AccessToken, token.RefreshToken)
 
 	// Return response
 	c.JSON(http.StatusOK, NewRefreshTokenResponse(
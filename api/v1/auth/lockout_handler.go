@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"cirrussync-api/internal/auth"
+	"cirrussync-api/internal/srp"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondLockoutError answers a login attempt refused by the lockout with Retry-After. It reports
+// whether err was a lockout.
+func (h *Handler) respondLockoutError(c *gin.Context, err error) bool {
+	var lockout *auth.LockoutError
+	if !errors.As(err, &lockout) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+	if errors.Is(err, auth.ErrAccountLocked) {
+		c.JSON(http.StatusLocked, NewErrorResponse(err.Error(), status.StatusAccountLocked))
+		return true
+	}
+	c.JSON(http.StatusTooManyRequests, NewErrorResponse(err.Error(), status.StatusTooManyRequests))
+	return true
+}
+
+// HandleUnlockAccount unlocks an account with the token from the lockout email
+func (h *Handler) HandleUnlockAccount(c *gin.Context) {
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "unlockAccount")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	if err := h.authService.UnlockAccount(c.Request.Context(), req.Token, srp.GetClientIPFromRequest(c.Request)); err != nil {
+		h.secureLog(c, err, err.Error(), "unlockAccount")
+		if errors.Is(err, auth.ErrInvalidUnlockToken) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusInvalidToken))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to unlock account", status.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Account unlocked successfully", status.StatusOK))
+}
+
+// HandleGetLockoutStatus returns the failed logins and lock of the signed in user's account
+func (h *Handler) HandleGetLockoutStatus(c *gin.Context) {
+	lockoutStatus, err := h.authService.GetLockoutStatus(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "getLockoutStatus")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to get lockout status", status.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewLockoutStatusResponse(lockoutStatus, status.StatusOK))
+}
+
+// HandleUnlockOwnAccount lifts the lock of the signed in user's account
+func (h *Handler) HandleUnlockOwnAccount(c *gin.Context) {
+	if err := h.authService.UnlockOwnAccount(c.Request.Context(), c.GetString("userID"), srp.GetClientIPFromRequest(c.Request)); err != nil {
+		h.secureLog(c, err, err.Error(), "unlockOwnAccount")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to unlock account", status.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Account unlocked successfully", status.StatusOK))
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/auth"
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/srp"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleLoginPasskeyBegin issues the passkey challenge for a login waiting for its second factor
+func (h *Handler) HandleLoginPasskeyBegin(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "loginPasskeyBegin")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	options, err := h.authService.BeginLoginPasskey(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "loginPasskeyBegin")
+		h.respondLoginMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewLoginPasskeyOptionsResponse(options, status.StatusChallengeIssued))
+}
+
+// HandleLoginPasskeyFinish completes a login with a passkey assertion
+func (h *Handler) HandleLoginPasskeyFinish(c *gin.Context) {
+	var req LoginPasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "loginPasskeyFinish")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	result, err := h.authService.FinishLoginPasskey(c.Request.Context(), req.MFAToken, req.ToAssertion())
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "loginPasskeyFinish")
+		h.respondLoginMFAError(c, err)
+		return
+	}
+
+	h.finishLoginMFA(c, result, "loginPasskeyFinish")
+}
+
+// HandleLoginTOTP completes a login with a TOTP or recovery code
+func (h *Handler) HandleLoginTOTP(c *gin.Context) {
+	var req LoginTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "loginTOTP")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	result, err := h.authService.FinishLoginTOTP(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "loginTOTP")
+		h.respondLoginMFAError(c, err)
+		return
+	}
+
+	h.finishLoginMFA(c, result, "loginTOTP")
+}
+
+// HandleLoginMFAEmail emails the code for a login waiting for its second factor
+func (h *Handler) HandleLoginMFAEmail(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "loginMFAEmail")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	result, err := h.authService.SendLoginEmailCode(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "loginMFAEmail")
+		h.respondLoginMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewLoginEmailCodeResponse(result, status.StatusEmailVerificationSent))
+}
+
+// HandleLoginMFAVerify completes a login with a TOTP code, recovery code or emailed code
+func (h *Handler) HandleLoginMFAVerify(c *gin.Context) {
+	var req LoginMFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "loginMFAVerify")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		return
+	}
+
+	result, err := h.authService.FinishLoginCode(c.Request.Context(), req.MFAToken, req.Method, req.Code)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "loginMFAVerify")
+		h.respondLoginMFAError(c, err)
+		return
+	}
+
+	h.finishLoginMFA(c, result, "loginMFAVerify")
+}
+
+// finishLoginMFA loads the user of a login that passed its second factor and completes it
+func (h *Handler) finishLoginMFA(c *gin.Context, result *auth.LoginMFAResult, route string) {
+	user, err := h.userService.GetUserById(c.Request.Context(), result.UserID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get user after successful second factor", route)
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to get user information", status.StatusInternalServerError))
+		return
+	}
+
+	h.completeLogin(c, user, result.ServerProof, srp.GetClientIPFromRequest(c.Request), route)
+}
+
+// respondLoginMFAError maps second factor errors to responses
+func (h *Handler) respondLoginMFAError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	apiStatusCode := status.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidInput), errors.Is(err, mfa.ErrInvalidInput):
+		statusCode = http.StatusBadRequest
+		apiStatusCode = status.StatusBadRequest
+	case errors.Is(err, auth.ErrLoginMFAExpired):
+		statusCode = http.StatusUnauthorized
+		apiStatusCode = status.StatusTokenExpired
+	case errors.Is(err, auth.ErrTooManyMFAAttempts):
+		statusCode = http.StatusTooManyRequests
+		apiStatusCode = status.StatusTooManyRequests
+	case errors.Is(err, auth.ErrMFAMethodNotAllowed), errors.Is(err, mfa.ErrMFAMethodUnavailable):
+		statusCode = http.StatusBadRequest
+		apiStatusCode = status.StatusMFAFailed
+	case errors.Is(err, mfa.ErrInvalidTOTPCode), errors.Is(err, mfa.ErrInvalidEmailCode), errors.Is(err, mfa.ErrEmailCodeExpired):
+		statusCode = http.StatusUnauthorized
+		apiStatusCode = status.StatusInvalidMFACode
+	case errors.Is(err, mfa.ErrRateLimitExceeded):
+		statusCode = http.StatusTooManyRequests
+		apiStatusCode = status.StatusTooManyRequests
+	case errors.Is(err, mfa.ErrFailedToSendEmail):
+		statusCode = http.StatusBadGateway
+		apiStatusCode = status.StatusMailServiceError
+	case errors.Is(err, mfa.ErrInvalidPasskeyResponse), errors.Is(err, mfa.ErrPasskeyChallengeExpired),
+		errors.Is(err, mfa.ErrPasskeyChallengeMismatch), errors.Is(err, mfa.ErrPasskeyOriginMismatch),
+		errors.Is(err, mfa.ErrUnsupportedPasskey), errors.Is(err, mfa.ErrInvalidPasskeySignature),
+		errors.Is(err, mfa.ErrPasskeyCloned), errors.Is(err, mfa.ErrPasskeyNotFound):
+		statusCode = http.StatusUnauthorized
+		apiStatusCode = status.StatusMFAFailed
+	}
+
+	c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatusCode))
+}
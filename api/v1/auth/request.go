@@ -1,6 +1,9 @@
 package auth
 
-import "cirrussync-api/internal/user"
+import (
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/user"
+)
 
 // LoginInitRequest represents the request to initialize SRP authentication
 type LoginInitRequest struct {
@@ -30,3 +33,88 @@ type ChangePasswordRequest struct {
 	NewSRPSalt     string `json:"newSrpSalt" binding:"required"`
 	NewSRPVerifier string `json:"newSrpVerifier" binding:"required"`
 }
+
+// PasswordResetConfirmRequest represents the request body for resetting a forgotten password with
+// the token from the password reset email
+type PasswordResetConfirmRequest struct {
+	Token       string                 `json:"token" binding:"required"`
+	SRPSalt     string                 `json:"srpSalt" binding:"required"`
+	SRPVerifier string                 `json:"srpVerifier" binding:"required"`
+	Keys        []KeyPassphraseRequest `json:"keys" binding:"required,min=1,max=50,dive"`
+}
+
+// UnlockAccountRequest represents the request body for unlocking an account with the token from the
+// lockout email
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RequestEmailChangeRequest represents the request body for moving the signed in user's account to a
+// new email address
+type RequestEmailChangeRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+}
+
+// ConfirmEmailChangeRequest represents the request body for confirming an email change with the token
+// from the verification email, carrying SRP credentials derived for the new address
+type ConfirmEmailChangeRequest struct {
+	Token       string `json:"token" binding:"required"`
+	SRPSalt     string `json:"srpSalt" binding:"required"`
+	SRPVerifier string `json:"srpVerifier" binding:"required"`
+}
+
+// AvailabilityRequest represents the query of an availability check; at least one of the two is required
+type AvailabilityRequest struct {
+	Username string `form:"username" binding:"omitempty,max=30"`
+	Email    string `form:"email" binding:"omitempty,max=100"`
+}
+
+// KeyPassphraseRequest represents a key passphrase re-encrypted for the new password
+type KeyPassphraseRequest struct {
+	ID                  string `json:"id" binding:"required"`
+	Passphrase          string `json:"passphrase" binding:"required"`
+	PassphraseSignature string `json:"passphraseSignature" binding:"required"`
+}
+
+// LoginMFARequest identifies the pending login a second factor is requested for
+type LoginMFARequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+}
+
+// LoginPasskeyRequest completes a pending login with the PublicKeyCredential returned by navigator.credentials.get
+type LoginPasskeyRequest struct {
+	MFAToken   string `json:"mfaToken" binding:"required"`
+	Credential struct {
+		ID       string `json:"id" binding:"required"`
+		Type     string `json:"type" binding:"required,eq=public-key"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+			AuthenticatorData string `json:"authenticatorData" binding:"required"`
+			Signature         string `json:"signature" binding:"required"`
+			UserHandle        string `json:"userHandle"`
+		} `json:"response" binding:"required"`
+	} `json:"credential" binding:"required"`
+}
+
+// ToAssertion converts the request into the service representation
+func (r LoginPasskeyRequest) ToAssertion() mfa.PasskeyAssertion {
+	return mfa.PasskeyAssertion{
+		ID:                r.Credential.ID,
+		ClientDataJSON:    r.Credential.Response.ClientDataJSON,
+		AuthenticatorData: r.Credential.Response.AuthenticatorData,
+		Signature:         r.Credential.Response.Signature,
+	}
+}
+
+// LoginTOTPRequest completes a pending login with a TOTP or recovery code
+type LoginTOTPRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// LoginMFAVerifyRequest completes a pending login with a TOTP code, recovery code or emailed code
+type LoginMFAVerifyRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Method   string `json:"method" binding:"required,oneof=totp recovery email"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -1,13 +1,17 @@
 package auth
 
 import (
+	"cirrussync-api/internal/auth"
 	"cirrussync-api/internal/jwt"
+	"cirrussync-api/internal/mfa"
 	"cirrussync-api/internal/models"
-	"strings"
-
-	"github.com/go-playground/validator/v10"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/validation"
 )
 
+// LOGIN_STATE_MFA_REQUIRED marks a login that passed SRP and waits for its second factor
+const LOGIN_STATE_MFA_REQUIRED = "mfa_required"
+
 // User represents a user in the response
 type User struct {
 	ID string `json:"id"`
@@ -24,10 +28,11 @@ type BaseResponse struct {
 	Code int16 `json:"code"`
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse represents an error response. Rejected payloads list every field error.
 type ErrorResponse struct {
 	BaseResponse
-	Detail string `json:"detail"`
+	Detail string                  `json:"detail"`
+	Errors []validation.FieldError `json:"errors,omitempty"`
 }
 
 // LoginInitResponse represents the response from SRP initialization
@@ -49,6 +54,33 @@ type LoginVerifyResponse struct {
 	User         User     `json:"user"`
 	ServerProof  string   `json:"serverProof"`
 	ExpiresIn    int64    `json:"expiresIn"`
+
+	// Sync pauses and bandwidth caps of the device signing in, when it has any
+	SyncSchedule *user.SyncSchedule `json:"syncSchedule,omitempty"`
+}
+
+// LoginMFARequiredResponse is returned after SRP verification when the account needs a second factor
+type LoginMFARequiredResponse struct {
+	BaseResponse
+	State       string   `json:"state"` // Always LOGIN_STATE_MFA_REQUIRED
+	MFAToken    string   `json:"mfaToken"`
+	Methods     []string `json:"methods"`
+	ServerProof string   `json:"serverProof"`
+	ExpiresAt   int64    `json:"expiresAt"`
+}
+
+// LoginEmailCodeResponse describes the code emailed for a pending login
+type LoginEmailCodeResponse struct {
+	BaseResponse
+	Email             string `json:"email"`
+	ExpiresAt         int64  `json:"expiresAt"`
+	RemainingRequests int    `json:"remainingRequests"`
+}
+
+// LoginPasskeyOptionsResponse carries the options for navigator.credentials.get
+type LoginPasskeyOptionsResponse struct {
+	BaseResponse
+	PublicKey *mfa.PasskeyRequestOptions `json:"publicKey"`
 }
 
 // SignupResponse represents the response from successful registration
@@ -63,6 +95,28 @@ type SuccessResponse struct {
 	Detail string `json:"detail"`
 }
 
+// LockoutStatusResponse represents the lockout state of the signed in user's account
+type LockoutStatusResponse struct {
+	BaseResponse
+	FailedAttempts int   `json:"failedAttempts"`
+	Locked         bool  `json:"locked"`
+	LockedUntil    int64 `json:"lockedUntil,omitempty"`
+	ThrottledUntil int64 `json:"throttledUntil,omitempty"`
+}
+
+// Availability represents whether a username or email address can be signed up with
+type Availability struct {
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"` // invalid, reserved or taken
+}
+
+// AvailabilityResponse represents the availability of the username and email address that were asked about
+type AvailabilityResponse struct {
+	BaseResponse
+	Username *Availability `json:"username,omitempty"`
+	Email    *Availability `json:"email,omitempty"`
+}
+
 // RefreshTokenResponse represents the response from token refresh
 type RefreshTokenResponse struct {
 	BaseResponse
@@ -75,17 +129,11 @@ type RefreshTokenResponse struct {
 	ExpiresIn    int64    `json:"expiresIn"`
 }
 
-// NewValidationError creates a new validation error response
+// NewValidationError creates a new validation error response with every field error of the payload
 func NewValidationError(err error, code int16) ErrorResponse {
-	if errs, ok := err.(validator.ValidationErrors); ok && len(errs) > 0 {
-		full := errs[0].Error()
-		parts := strings.SplitN(full, "Error:", 2)
-		if len(parts) == 2 {
-			return NewErrorResponse(strings.TrimSpace(parts[1]), code)
-		}
-		return NewErrorResponse(full, code)
-	}
-	return NewErrorResponse("Invalid request format", code)
+	response := NewErrorResponse(validation.Message(err), code)
+	response.Errors = validation.Errors(err)
+	return response
 }
 
 // NewErrorResponse creates a new error response
@@ -112,6 +160,7 @@ func NewLoginVerifyResponse(
 	user *models.User,
 	session *models.UserSession,
 	serverProof string,
+	syncSchedule *user.SyncSchedule,
 	code int16,
 ) LoginVerifyResponse {
 	return LoginVerifyResponse{
@@ -127,8 +176,39 @@ func NewLoginVerifyResponse(
 			ID:        session.ID,
 			ExpiresAt: session.ExpiresAt,
 		},
-		ServerProof: serverProof,
-		ExpiresIn:   token.ExpiresIn,
+		ServerProof:  serverProof,
+		ExpiresIn:    token.ExpiresIn,
+		SyncSchedule: syncSchedule,
+	}
+}
+
+// NewLoginMFARequiredResponse creates a new second factor required response
+func NewLoginMFARequiredResponse(challenge *auth.LoginMFAChallenge, serverProof string, code int16) LoginMFARequiredResponse {
+	return LoginMFARequiredResponse{
+		BaseResponse: BaseResponse{Code: code},
+		State:        LOGIN_STATE_MFA_REQUIRED,
+		MFAToken:     challenge.Token,
+		Methods:      challenge.Methods,
+		ServerProof:  serverProof,
+		ExpiresAt:    challenge.ExpiresAt,
+	}
+}
+
+// NewLoginEmailCodeResponse creates a new login email code response
+func NewLoginEmailCodeResponse(result *mfa.EmailCodeResult, code int16) LoginEmailCodeResponse {
+	return LoginEmailCodeResponse{
+		BaseResponse:      BaseResponse{Code: code},
+		Email:             result.MaskedEmail,
+		ExpiresAt:         result.ExpiresAt,
+		RemainingRequests: result.RemainingRequests,
+	}
+}
+
+// NewLoginPasskeyOptionsResponse creates a new passkey options response
+func NewLoginPasskeyOptionsResponse(options *mfa.PasskeyRequestOptions, code int16) LoginPasskeyOptionsResponse {
+	return LoginPasskeyOptionsResponse{
+		BaseResponse: BaseResponse{Code: code},
+		PublicKey:    options,
 	}
 }
 
@@ -142,6 +222,29 @@ func NewSignupResponse(userID string, code int16) SignupResponse {
 	}
 }
 
+// NewLockoutStatusResponse creates a new lockout status response
+func NewLockoutStatusResponse(lockoutStatus *auth.LockoutStatus, code int16) LockoutStatusResponse {
+	return LockoutStatusResponse{
+		BaseResponse:   BaseResponse{Code: code},
+		FailedAttempts: lockoutStatus.FailedAttempts,
+		Locked:         lockoutStatus.Locked,
+		LockedUntil:    lockoutStatus.LockedUntil,
+		ThrottledUntil: lockoutStatus.ThrottledUntil,
+	}
+}
+
+// NewAvailabilityResponse creates a new availability response
+func NewAvailabilityResponse(result *user.AvailabilityResult, code int16) AvailabilityResponse {
+	response := AvailabilityResponse{BaseResponse: BaseResponse{Code: code}}
+	if result.Username != nil {
+		response.Username = &Availability{Available: result.Username.Available, Reason: result.Username.Reason}
+	}
+	if result.Email != nil {
+		response.Email = &Availability{Available: result.Email.Available, Reason: result.Email.Reason}
+	}
+	return response
+}
+
 // NewSuccessResponse creates a new success response
 func NewSuccessResponse(message string, code int16) SuccessResponse {
 	return SuccessResponse{
@@ -11,7 +11,21 @@ func RegisterPublicRoutes(r *gin.RouterGroup, h *Handler) {
 	// Public routes - no authentication required
 	authGroup.POST("/login/init", h.HandleLoginInit)
 	authGroup.POST("/login/verify", h.HandleLoginVerify)
+
+	// Second factor of a login that passed SRP, authorized by its mfaToken
+	authGroup.POST("/login/mfa/webauthn/begin", h.HandleLoginPasskeyBegin)
+	authGroup.POST("/login/mfa/webauthn/finish", h.HandleLoginPasskeyFinish)
+	authGroup.POST("/login/mfa/totp", h.HandleLoginTOTP)
+	authGroup.POST("/mfa/email", h.HandleLoginMFAEmail)
+	authGroup.POST("/mfa/verify", h.HandleLoginMFAVerify)
 	authGroup.POST("/signup", h.HandleSignup)
+	authGroup.GET("/availability", h.HandleAvailability)
+
+	// Password reset, authorized by the token from the reset email
+	authGroup.POST("/password-reset/confirm", h.HandlePasswordResetConfirm)
+
+	// Account unlock, authorized by the token from the lockout email
+	authGroup.POST("/unlock", h.HandleUnlockAccount)
 }
 
 // RegisterProtectedRoutes registers all authentication routes
@@ -23,3 +37,17 @@ func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
 	authGroup.POST("/logout", h.HandleLogout)
 	authGroup.POST("/change-password", h.HandleChangePassword)
 }
+
+// RegisterSettingsRoutes registers the signed in user's login lockout settings
+func RegisterSettingsRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("security/lockout", h.HandleGetLockoutStatus)
+	r.DELETE("security/lockout", h.HandleUnlockOwnAccount)
+}
+
+// RegisterUserRoutes registers the routes that change the signed in user's email address
+func RegisterUserRoutes(r *gin.RouterGroup, h *Handler) {
+	// Send a verification link to the new address
+	r.POST("/@me/email", h.HandleRequestEmailChange)
+	// Move the account to the new address with the token from the verification link
+	r.POST("/@me/email/confirm", h.HandleConfirmEmailChange)
+}
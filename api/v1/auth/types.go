@@ -4,6 +4,7 @@ import (
 	"cirrussync-api/internal/auth"
 	"cirrussync-api/internal/jwt"
 	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/middleware"
 	"cirrussync-api/internal/session"
 	"cirrussync-api/internal/user"
 )
@@ -14,5 +15,6 @@ type Handler struct {
 	userService    *user.Service
 	jwtService     *jwt.JWTService
 	sessionService *session.Service
+	cookies        *middleware.Cookies
 	logger         *logger.Logger
 }
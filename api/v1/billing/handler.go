@@ -0,0 +1,257 @@
+package billing
+
+import (
+	"cirrussync-api/internal/middleware"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"cirrussync-api/internal/billing"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Stripe event payloads are well below this
+	maxWebhookBodyBytes = 512 * 1024
+	webhookTimeout      = 15 * time.Second
+	// Checkout and plan changes make several Stripe calls in a row
+	subscriptionTimeout = 30 * time.Second
+	// Seconds a client waits before retrying while its Stripe customer is being created
+	customerRetryAfter = "5"
+)
+
+// Handler handles billing API requests
+type Handler struct {
+	billingService *billing.Service
+	logger         *logger.Logger
+}
+
+// NewHandler creates a new billing handler
+func NewHandler(billingService *billing.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		billingService: billingService,
+		logger:         log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// handleServiceError maps service errors to appropriate HTTP responses
+func (h *Handler) handleServiceError(c *gin.Context, err error, route string) {
+	h.secureLog(c, err, "Error in "+route, route)
+
+	statusCode := http.StatusInternalServerError
+	apiStatus := status.StatusInternalServerError
+	message := err.Error()
+
+	switch {
+	case errors.Is(err, billing.ErrPlanNotFound),
+		errors.Is(err, billing.ErrNoActiveSubscription),
+		errors.Is(err, billing.ErrUserNotFound),
+		errors.Is(err, billing.ErrGiftCardNotFound):
+		statusCode = http.StatusNotFound
+		apiStatus = status.StatusNotFound
+
+	case errors.Is(err, billing.ErrSubscriptionExists),
+		errors.Is(err, billing.ErrPlanUnchanged),
+		errors.Is(err, billing.ErrPlanTooSmall),
+		errors.Is(err, billing.ErrGiftCardUsed),
+		errors.Is(err, billing.ErrGiftCardBusy):
+		statusCode = http.StatusConflict
+		apiStatus = status.StatusConflict
+
+	case errors.Is(err, billing.ErrInvalidBillingCycle),
+		errors.Is(err, billing.ErrPlanNotPurchasable):
+		statusCode = http.StatusBadRequest
+		apiStatus = status.StatusBadRequest
+
+	case errors.Is(err, billing.ErrGiftCardExpired):
+		statusCode = http.StatusGone
+		apiStatus = status.StatusBadRequest
+
+	case errors.Is(err, billing.ErrTooManyRedemptions):
+		statusCode = http.StatusTooManyRequests
+		apiStatus = status.StatusTooManyRequests
+
+	case errors.Is(err, billing.ErrBillingDisabled):
+		statusCode = http.StatusServiceUnavailable
+		apiStatus = status.StatusServiceUnavailable
+
+	case errors.Is(err, billing.ErrCustomerPending):
+		// The customer is created shortly after signup, so the client can simply retry
+		c.Header("Retry-After", customerRetryAfter)
+		statusCode = http.StatusServiceUnavailable
+		apiStatus = status.StatusServiceUnavailable
+
+	case errors.Is(err, billing.ErrPaymentProvider):
+		// Provider errors can carry account details, so only the generic message is returned
+		statusCode = http.StatusBadGateway
+		apiStatus = status.StatusPaymentGatewayError
+		message = billing.ErrPaymentProvider.Error()
+
+	default:
+		message = "Internal server error"
+	}
+
+	c.JSON(statusCode, NewErrorResponse(message, apiStatus, middleware.RequestID(c)))
+}
+
+// getUserID returns the authenticated user, responding with 401 if there is none
+func (h *Handler) getUserID(c *gin.Context) (string, bool) {
+	userIDInterface, exists := c.Get("userID")
+	userID, ok := userIDInterface.(string)
+	if !exists || !ok || userID == "" {
+		h.secureLog(c, session.ErrSessionNotFound, "Missing user in context", "getUserID")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(session.ErrSessionNotFound.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return "", false
+	}
+	return userID, true
+}
+
+// ListPlans handles listing the plans that can be purchased with their prices
+func (h *Handler) ListPlans(c *gin.Context) {
+	plans, err := h.billingService.ListPlans(c.Request.Context())
+	if err != nil {
+		h.handleServiceError(c, err, "listPlans")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPlansResponse(plans, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetCustomerStatus handles reporting whether the user's billing account is ready for purchases
+func (h *Handler) GetCustomerStatus(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	customerStatus, err := h.billingService.GetCustomerStatus(c.Request.Context(), userID)
+	if err != nil {
+		h.handleServiceError(c, err, "getCustomerStatus")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewCustomerStatusResponse(customerStatus, status.StatusOK, middleware.RequestID(c)))
+}
+
+// StartSubscription handles starting a Stripe checkout for a new subscription
+func (h *Handler) StartSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "startSubscription")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	checkout, err := h.billingService.StartSubscription(c.Request.Context(), userID, req.PlanID, req.BillingCycle)
+	if err != nil {
+		h.handleServiceError(c, err, "startSubscription")
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewCheckoutResponse(checkout, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// ChangeSubscription handles upgrading or downgrading the current subscription with proration
+func (h *Handler) ChangeSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "changeSubscription")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	// Once Stripe has changed the subscription, finish mirroring it even if the client disconnects
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), subscriptionTimeout)
+	defer cancel()
+
+	userPlan, err := h.billingService.ChangeSubscription(ctx, userID, req.PlanID, req.BillingCycle)
+	if err != nil {
+		h.handleServiceError(c, err, "changeSubscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSubscriptionResponse(userPlan, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RedeemGiftCard handles redeeming a gift card code into account credit
+func (h *Handler) RedeemGiftCard(c *gin.Context) {
+	var req RedeemGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "redeemGiftCard")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	card, err := h.billingService.RedeemGiftCard(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.handleServiceError(c, err, "redeemGiftCard")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewGiftCardRedemptionResponse(card, status.StatusOK, middleware.RequestID(c)))
+}
+
+// HandleStripeWebhook receives Stripe events. Deliveries are authenticated by their signature,
+// and any non-2xx response makes Stripe retry, so only failures worth retrying return 5xx.
+func (h *Handler) HandleStripeWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodyBytes+1))
+	if err != nil {
+		h.secureLog(c, err, "Failed to read webhook body", "stripeWebhook")
+		c.JSON(http.StatusBadRequest, NewErrorResponse("Invalid request body", status.StatusBadRequest, middleware.RequestID(c)))
+		return
+	}
+	if len(payload) > maxWebhookBodyBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, NewErrorResponse("Request body too large", status.StatusBadRequest, middleware.RequestID(c)))
+		return
+	}
+
+	// Finish applying the event even if Stripe gives up on the connection
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), webhookTimeout)
+	defer cancel()
+
+	err = h.billingService.HandleStripeWebhook(ctx, payload, c.GetHeader("Stripe-Signature"))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, NewWebhookResponse(status.StatusOK, middleware.RequestID(c)))
+	case errors.Is(err, billing.ErrInvalidSignature):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+	case errors.Is(err, billing.ErrInvalidPayload):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest, middleware.RequestID(c)))
+	case errors.Is(err, billing.ErrWebhookDisabled):
+		c.JSON(http.StatusServiceUnavailable, NewErrorResponse("Webhooks are not available", status.StatusServiceUnavailable, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Failed to process event", status.StatusPaymentGatewayError, middleware.RequestID(c)))
+	}
+}
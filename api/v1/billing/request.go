@@ -0,0 +1,12 @@
+package billing
+
+// SubscriptionRequest represents a request to subscribe to a plan or change the current subscription
+type SubscriptionRequest struct {
+	PlanID       string `json:"planId" binding:"required,max=10"`
+	BillingCycle string `json:"billingCycle" binding:"required,oneof=monthly yearly"`
+}
+
+// RedeemGiftCardRequest represents a request to redeem a gift card code
+type RedeemGiftCardRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
@@ -0,0 +1,211 @@
+package billing
+
+import (
+	"cirrussync-api/internal/billing"
+	"cirrussync-api/internal/models"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// WebhookResponse acknowledges a webhook delivery
+type WebhookResponse struct {
+	BaseResponse
+	Received bool `json:"received"`
+}
+
+// PlanData represents a purchasable plan in API responses
+type PlanData struct {
+	ID           string  `json:"id"`
+	PlanType     string  `json:"planType"`
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	Tier         int     `json:"tier"`
+	Features     string  `json:"features"`
+	MonthlyPrice float64 `json:"monthlyPrice"`
+	YearlyPrice  float64 `json:"yearlyPrice"`
+	StorageQuota int64   `json:"storageQuota"`
+	MaxDevices   int     `json:"maxDevices"`
+	MaxUsers     int     `json:"maxUsers"`
+	Popular      bool    `json:"popular"`
+	Monthly      bool    `json:"monthly"` // Whether the plan can be bought monthly
+	Yearly       bool    `json:"yearly"`  // Whether the plan can be bought yearly
+}
+
+// PlansResponse represents a response with the purchasable plans
+type PlansResponse struct {
+	BaseResponse
+	Plans []PlanData `json:"plans"`
+}
+
+// CheckoutResponse represents a response with a checkout to redirect the user to
+type CheckoutResponse struct {
+	BaseResponse
+	SessionID   string `json:"sessionId"`
+	CheckoutURL string `json:"checkoutUrl"`
+}
+
+// SubscriptionData represents a user's subscription in API responses
+type SubscriptionData struct {
+	ID                 string `json:"id"`
+	PlanID             string `json:"planId"`
+	PlanType           string `json:"planType"`
+	Status             string `json:"status"`
+	BillingCycle       string `json:"billingCycle"`
+	AutoRenew          bool   `json:"autoRenew"`
+	StorageQuota       int64  `json:"storageQuota"`
+	AdditionalStorage  int64  `json:"additionalStorage"`
+	CurrentPeriodStart int64  `json:"currentPeriodStart"`
+	CurrentPeriodEnd   int64  `json:"currentPeriodEnd"`
+}
+
+// SubscriptionResponse represents a response with a user's subscription
+type SubscriptionResponse struct {
+	BaseResponse
+	Subscription SubscriptionData `json:"subscription"`
+}
+
+// CustomerStatusResponse represents whether the user's billing account is ready for purchases
+type CustomerStatusResponse struct {
+	BaseResponse
+	Status string `json:"status"` // ready, pending, failed or none
+}
+
+// GiftCardRedemptionResponse represents a response to a redeemed gift card
+type GiftCardRedemptionResponse struct {
+	BaseResponse
+	Amount     int    `json:"amount"`
+	Currency   string `json:"currency"`
+	RedeemedAt int64  `json:"redeemedAt"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// NewWebhookResponse creates a new webhook acknowledgement
+func NewWebhookResponse(code int16, requestID string) WebhookResponse {
+	return WebhookResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Received: true,
+	}
+}
+
+// NewValidationError creates a validation error response
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Validation Error with requestId " + requestID,
+		},
+		Error: err.Error(),
+	}
+}
+
+// NewPlansResponse creates a new plans response
+func NewPlansResponse(plans []*models.Plan, code int16, requestID string) PlansResponse {
+	data := make([]PlanData, len(plans))
+	for i, plan := range plans {
+		data[i] = PlanData{
+			ID:           plan.ID,
+			PlanType:     plan.PlanType,
+			Name:         plan.Name,
+			Description:  plan.Description,
+			Tier:         plan.Tier,
+			Features:     plan.Features,
+			MonthlyPrice: plan.MonthlyPrice,
+			YearlyPrice:  plan.YearlyPrice,
+			StorageQuota: plan.StorageQuota,
+			MaxDevices:   plan.MaxDevices,
+			MaxUsers:     plan.MaxUsers,
+			Popular:      plan.Popular,
+			Monthly:      plan.StripeMonthlyPriceID != "",
+			Yearly:       plan.StripeYearlyPriceID != "",
+		}
+	}
+
+	return PlansResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Plans: data,
+	}
+}
+
+// NewCheckoutResponse creates a new checkout response
+func NewCheckoutResponse(session *billing.CheckoutSession, code int16, requestID string) CheckoutResponse {
+	return CheckoutResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		SessionID:   session.ID,
+		CheckoutURL: session.URL,
+	}
+}
+
+// NewSubscriptionResponse creates a new subscription response
+func NewSubscriptionResponse(userPlan *models.UserPlan, code int16, requestID string) SubscriptionResponse {
+	return SubscriptionResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Subscription: SubscriptionData{
+			ID:                 userPlan.ID,
+			PlanID:             userPlan.PlanID,
+			PlanType:           userPlan.PlanType,
+			Status:             userPlan.Status,
+			BillingCycle:       userPlan.BillingCycle,
+			AutoRenew:          userPlan.AutoRenew,
+			StorageQuota:       userPlan.StorageQuota,
+			AdditionalStorage:  userPlan.AdditionalStorage,
+			CurrentPeriodStart: userPlan.CurrentPeriodStart,
+			CurrentPeriodEnd:   userPlan.CurrentPeriodEnd,
+		},
+	}
+}
+
+// NewGiftCardRedemptionResponse creates a new gift card redemption response
+func NewGiftCardRedemptionResponse(card *models.GiftCard, code int16, requestID string) GiftCardRedemptionResponse {
+	return GiftCardRedemptionResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Amount:     card.Amount,
+		Currency:   card.Currency,
+		RedeemedAt: card.UsedAt,
+	}
+}
+
+// NewCustomerStatusResponse creates a new billing account status response
+func NewCustomerStatusResponse(customerStatus string, code int16, requestID string) CustomerStatusResponse {
+	return CustomerStatusResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Status: customerStatus,
+	}
+}
@@ -0,0 +1,43 @@
+package billing
+
+import (
+	"time"
+
+	"cirrussync-api/internal/cdn"
+	"cirrussync-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookPathPrefix is where payment provider webhooks are served. Webhooks are authenticated
+// by signature rather than by session, so CSRF protection does not apply under it.
+const WebhookPathPrefix = "/api/v1/billing/webhooks/"
+
+// planCatalogCachePolicy keeps the anonymous plan catalog at the CDN. Plans and prices rarely
+// change and are purged by surrogate key through the admin API when they do.
+var planCatalogCachePolicy = middleware.CachePolicy{
+	MaxAge:               5 * time.Minute,
+	SharedMaxAge:         time.Hour,
+	StaleWhileRevalidate: time.Minute,
+	SurrogateKeys:        []string{cdn.SURROGATE_KEY_PLANS},
+}
+
+// RegisterPublicRoutes registers billing routes that do not require a user session
+func RegisterPublicRoutes(r *gin.RouterGroup, h *Handler) {
+	billingGroup := r.Group("/billing")
+	{
+		billingGroup.POST("/webhooks/stripe", h.HandleStripeWebhook)
+		billingGroup.GET("/plans", middleware.CacheHeadersMiddleware(planCatalogCachePolicy), h.ListPlans)
+	}
+}
+
+// RegisterProtectedRoutes registers billing routes that act on the signed-in user
+func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
+	billingGroup := r.Group("", middleware.RequestBudgetMiddleware(middleware.FixedBudget(subscriptionTimeout)))
+	{
+		billingGroup.GET("/customer", h.GetCustomerStatus)
+		billingGroup.POST("/subscriptions", h.StartSubscription)
+		billingGroup.POST("/subscriptions/change", h.ChangeSubscription)
+		billingGroup.POST("/giftcards/redeem", h.RedeemGiftCard)
+	}
+}
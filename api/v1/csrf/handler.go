@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"cirrussync-api/internal/logger"
-	"cirrussync-api/internal/utils"
 	"cirrussync-api/pkg/status"
 
 	"github.com/gin-gonic/gin"
@@ -26,15 +25,13 @@ func NewHandler(logger *logger.Logger) *Handler {
 	}
 }
 
-// secureLog logs errors without sensitive data that might expose code
-func (h *Handler) secureLog(err error, message string, route string) {
-	// Generate request ID internally
-	requestID := utils.GenerateShortID()
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
 	// Log only necessary information, avoid including stack traces or request bodies
-	h.logger.WithFields(logrus.Fields{
-		"requestID": requestID,
-		"route":     route,
-		"errorMsg":  err.Error(),
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
 	}).Error(message)
 }
 
@@ -42,7 +39,7 @@ func (h *Handler) secureLog(err error, message string, route string) {
 func (h *Handler) HandleCSRFToken(c *gin.Context) {
 	token := csrf.Token(c.Request)
 	if token == "" {
-		h.secureLog(errors.New("returned empty token"), "Failed to generate CSRF token", "/csrf")
+		h.secureLog(c, errors.New("returned empty token"), "Failed to generate CSRF token", "/csrf")
 		c.JSON(http.StatusInternalServerError, NewErrorResponse(
 			status.StatusBadRequest,
 			"Internal server error, please try again later",
@@ -0,0 +1,123 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"context"
+	"net/http"
+
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetShareApproval handles turning membership approval on or off for a share
+func (h *Handler) SetShareApproval(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req SetShareApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setShareApproval")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	share, err := h.driveService.SetShareApprovalRequired(ctx, userID, shareID, *req.RequiresApproval)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setShareApproval")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewShareWithMembershipsResponse(share, nil, userID, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// GetPendingApprovals handles listing the members of a share waiting for approval
+func (h *Handler) GetPendingApprovals(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	memberships, err := h.driveService.GetPendingApprovals(ctx, userID, shareID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getPendingApprovals")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewMembershipsResponse(memberships, status.StatusOK, middleware.RequestID(c)))
+}
+
+// ApproveMembership handles approving a member waiting to join a share
+func (h *Handler) ApproveMembership(c *gin.Context) {
+	h.handleApprovalDecision(c, "approveMembership", h.driveService.ApproveMembership)
+}
+
+// RejectMembership handles rejecting a member waiting to join a share
+func (h *Handler) RejectMembership(c *gin.Context) {
+	h.handleApprovalDecision(c, "rejectMembership", h.driveService.RejectMembership)
+}
+
+// handleApprovalDecision reads the share and membership from the path and applies an admin's decision
+func (h *Handler) handleApprovalDecision(
+	c *gin.Context,
+	route string,
+	decide func(ctx context.Context, adminID, shareID, membershipID string) (*models.DriveShareMembership, error),
+) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and membership IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+	membershipID := c.Param("membershipID")
+	if err := h.validateRequestParam(membershipID, "MembershipID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	membership, err := decide(ctx, userID, shareID, membershipID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, route)
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewMembershipResponse(membership, status.StatusUpdated, middleware.RequestID(c)))
+}
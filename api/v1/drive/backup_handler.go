@@ -0,0 +1,191 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+	"strings"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// backupReadRoutes are share routes that read content despite not being GET requests
+var backupReadRoutes = []string{"/search", "/duplicates"}
+
+// CreateBackupSet handles registering a folder the desktop client backs up from a device
+func (h *Handler) CreateBackupSet(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Parse request body
+	var req CreateBackupSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createBackupSet")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	set, err := h.driveService.CreateBackupSet(c.Request.Context(), userID, &drive.BackupSetInput{
+		DeviceID:      req.DeviceID,
+		EncryptedName: req.EncryptedName,
+		MaxVersions:   req.MaxVersions,
+		RetentionDays: req.RetentionDays,
+		Share: drive.ShareKeys{
+			ShareKey:                 req.DriveShare.ShareKey,
+			SharePassphrase:          req.DriveShare.SharePassphrase,
+			SharePassphraseSignature: req.DriveShare.SharePassphraseSignature,
+		},
+		Member: drive.DriveShareMemberKeys{
+			KeyPacket:           req.DriveShareMembership.KeyPacket,
+			KeyPacketSignature:  req.DriveShareMembership.KeyPacketSignature,
+			SessionKeySignature: req.DriveShareMembership.SessionKeySignature,
+		},
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "createBackupSet")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewBackupSetResponse(set, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// ListBackupSets handles listing the caller's backup sets, optionally for one device
+func (h *Handler) ListBackupSets(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	sets, err := h.driveService.ListBackupSets(c.Request.Context(), userID, c.Query("deviceId"))
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "listBackupSets")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBackupSetsResponse(sets, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetBackupSet handles retrieving one of the caller's backup sets
+func (h *Handler) GetBackupSet(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	setID := c.Param("backupSetID")
+	if err := h.validateRequestParam(setID, "Backup set ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	set, err := h.driveService.GetBackupSet(c.Request.Context(), userID, setID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getBackupSet")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBackupSetResponse(set, status.StatusOK, middleware.RequestID(c)))
+}
+
+// UpdateBackupSetRules handles replacing the versioning and retention rules of a backup set
+func (h *Handler) UpdateBackupSetRules(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	setID := c.Param("backupSetID")
+	if err := h.validateRequestParam(setID, "Backup set ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req UpdateBackupRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updateBackupSetRules")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	set, err := h.driveService.UpdateBackupSetRules(c.Request.Context(), userID, setID, req.MaxVersions, *req.RetentionDays)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "updateBackupSetRules")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBackupSetResponse(set, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// DeleteBackupSet handles deleting a backup set and everything backed up to it in the background
+func (h *Handler) DeleteBackupSet(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	setID := c.Param("backupSetID")
+	if err := h.validateRequestParam(setID, "Backup set ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.driveService.DeleteBackupSet(c.Request.Context(), userID, setID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "deleteBackupSet")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, NewJobResponse(job, status.StatusAccepted, middleware.RequestID(c)))
+}
+
+// RequireBackupDevice keeps backup shares read-only outside the device that owns them. Share routes
+// that change content pass through it; reads and routes without a share are let through.
+func (h *Handler) RequireBackupDevice(c *gin.Context) {
+	shareID := c.Param("shareID")
+	if shareID == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		c.Next()
+		return
+	}
+	for _, suffix := range backupReadRoutes {
+		if strings.HasSuffix(c.FullPath(), suffix) {
+			c.Next()
+			return
+		}
+	}
+
+	// Unauthenticated requests are rejected by the handler itself
+	userID := c.GetString(userIDContextKey)
+	if userID == "" {
+		c.Next()
+		return
+	}
+
+	if err := h.driveService.CheckBackupWriteAccess(c.Request.Context(), userID, c.GetString("sessionID"), shareID); err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "requireBackupDevice")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
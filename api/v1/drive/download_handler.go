@@ -0,0 +1,83 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+	"strconv"
+
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DownloadFile handles returning presigned block URLs and revision metadata for a file
+func (h *Handler) DownloadFile(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and link IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	linkID := c.Param("linkID")
+	if err := h.validateRequestParam(linkID, "Link ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Optional revision ID, defaults to the active revision
+	revisionID := c.Query("revisionId")
+
+	ctx := c.Request.Context()
+
+	download, err := h.driveService.GetFileDownload(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "downloadFile")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	// Presigned URLs are short-lived and user specific
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewFileDownloadResponse(download, status.StatusFileDownloaded, middleware.RequestID(c)))
+}
+
+// GetThumbnail handles returning a thumbnail of a file's active revision
+func (h *Handler) GetThumbnail(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, ok := h.getLinkParams(c)
+	if !ok {
+		return
+	}
+
+	// Defaults to the small thumbnail
+	thumbnailType, err := strconv.Atoi(c.DefaultQuery("type", "1"))
+	if err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, "Invalid thumbnail type")
+		return
+	}
+
+	thumbnail, err := h.driveService.GetThumbnail(c.Request.Context(), userID, shareID, linkID, thumbnailType)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getThumbnail")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	// Contents are encrypted, but still only for this user
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.JSON(http.StatusOK, NewThumbnailResponse(thumbnail, status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,101 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVolumeEvents handles listing a volume's changes since the client's cursor
+func (h *Handler) GetVolumeEvents(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get volume ID from URL path
+	volumeID := c.Param("volumeID")
+	if err := h.validateRequestParam(volumeID, "VolumeID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, _ := h.getPaginationParams(c, drive.MAX_EVENTS_PER_PAGE, drive.MAX_EVENTS_PER_PAGE)
+
+	page, err := h.driveService.GetVolumeEvents(c.Request.Context(), userID, volumeID, c.Query("since"), limit)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getVolumeEvents")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewEventsResponse(page, status.StatusOK, middleware.RequestID(c)))
+}
+
+// WaitForVolumeEvents handles long-polling for a volume's changes, for clients that cannot hold a stream open
+func (h *Handler) WaitForVolumeEvents(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get volume ID from URL path
+	volumeID := c.Param("volumeID")
+	if err := h.validateRequestParam(volumeID, "VolumeID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	wait, ok := parseEventWait(c.Query("timeout"))
+	if !ok {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, "Timeout must be a duration such as 30s, up to 60s")
+		return
+	}
+
+	limit, _ := h.getPaginationParams(c, drive.MAX_EVENTS_PER_PAGE, drive.MAX_EVENTS_PER_PAGE)
+
+	page, err := h.driveService.WaitForVolumeEvents(c.Request.Context(), userID, volumeID, c.Query("since"), limit, wait)
+	if err != nil {
+		// The client went away while waiting; there is nobody to respond to
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "waitForVolumeEvents")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewEventsResponse(page, status.StatusOK, middleware.RequestID(c)))
+}
+
+// parseEventWait reads a long-poll timeout given as a duration ("30s") or whole seconds ("30")
+func parseEventWait(value string) (time.Duration, bool) {
+	if value == "" {
+		return drive.DEFAULT_EVENT_WAIT, true
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, false
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	if wait <= 0 || wait > drive.MAX_EVENT_WAIT {
+		return 0, false
+	}
+	return wait, true
+}
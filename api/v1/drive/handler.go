@@ -1,31 +1,30 @@
 package drive
 
 import (
+	"cirrussync-api/internal/middleware"
 	"context"
 	"errors"
 	"net/http"
 	"slices"
 	"strconv"
-	"time"
 
 	"cirrussync-api/internal/drive"
 	"cirrussync-api/internal/logger"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/quota"
 	"cirrussync-api/internal/user"
-	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/db"
 	"cirrussync-api/pkg/status"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-// Default constants for pagination and timeouts
+// Default constants for pagination
 const (
 	defaultLimit     = 100
 	maxLimit         = 500
 	defaultOffset    = 0
-	defaultTimeout   = 10 * time.Second
-	extendedTimeout  = 15 * time.Second
 	defaultSortBy    = "createdAt"
 	defaultSortDir   = "asc"
 	readPermission   = "user-read"
@@ -50,21 +49,19 @@ func NewHandler(driveService *drive.Service, userService *user.Service, log *log
 	}
 }
 
-// secureLog logs errors without sensitive data that might expose code or credentials
-func (h *Handler) secureLog(err error, message, route string) {
-	// Generate request ID internally
-	requestID := utils.GenerateShortID()
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
 	// Log only necessary information, avoid including stack traces or request bodies
-	h.logger.WithFields(logrus.Fields{
-		"requestID": requestID,
-		"route":     route,
-		"errorMsg":  err.Error(),
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
 	}).Error(message)
 }
 
 // handleServiceError maps service errors to appropriate HTTP responses
-func (h *Handler) handleServiceError(err error, route string) (int, int16, string) {
-	h.secureLog(err, "Error in "+route, route)
+func (h *Handler) handleServiceError(c *gin.Context, err error, route string) (int, int16, string) {
+	h.secureLog(c, err, "Error in "+route, route)
 
 	statusCode := http.StatusInternalServerError
 	apiStatus := status.StatusInternalServerError
@@ -75,7 +72,25 @@ func (h *Handler) handleServiceError(err error, route string) (int, int16, strin
 	case errors.Is(err, drive.ErrVolumeAlreadyExists),
 		errors.Is(err, drive.ErrRootShareAlreadyExists),
 		errors.Is(err, drive.ErrAllocationAlreadyExists),
-		errors.Is(err, drive.ErrNameConflict):
+		errors.Is(err, drive.ErrNameConflict),
+		errors.Is(err, drive.ErrSearchKeyVersionMismatch),
+		errors.Is(err, drive.ErrSearchReindexInProgress),
+		errors.Is(err, drive.ErrSearchReindexIncomplete),
+		errors.Is(err, drive.ErrFileNameConflict),
+		errors.Is(err, drive.ErrRevisionNotDraft),
+		errors.Is(err, drive.ErrUploadPaused),
+		errors.Is(err, drive.ErrUploadInterrupted),
+		errors.Is(err, drive.ErrTooManyBackupSets),
+		errors.Is(err, drive.ErrSlugTaken),
+		errors.Is(err, drive.ErrItemAlreadyInTrash),
+		errors.Is(err, drive.ErrItemNotInTrash),
+		errors.Is(err, drive.ErrParentInTrash),
+		errors.Is(err, drive.ErrShareNotLocked),
+		errors.Is(err, drive.ErrShareAlreadyRotated),
+		errors.Is(err, drive.ErrStaleRotationBatch),
+		errors.Is(err, drive.ErrShareLocked),
+		errors.Is(err, drive.ErrShareOwnerChanged),
+		errors.Is(err, drive.ErrMembershipAlreadyExists):
 		statusCode = http.StatusConflict
 		apiStatus = status.StatusConflict
 
@@ -83,13 +98,29 @@ func (h *Handler) handleServiceError(err error, route string) (int, int16, strin
 	case errors.Is(err, drive.ErrShareNotFound),
 		errors.Is(err, drive.ErrUserNotFound),
 		errors.Is(err, drive.ErrVolumeNotFound),
-		errors.Is(err, drive.ErrFolderNotFound):
+		errors.Is(err, drive.ErrFolderNotFound),
+		errors.Is(err, drive.ErrItemNotFound),
+		errors.Is(err, drive.ErrRevisionNotFound),
+		errors.Is(err, drive.ErrShareURLNotFound),
+		errors.Is(err, drive.ErrInvitationNotFound),
+		errors.Is(err, drive.ErrMembershipNotFound),
+		errors.Is(err, drive.ErrJobNotFound),
+		errors.Is(err, drive.ErrTagNotFound),
+		errors.Is(err, drive.ErrDeviceNotFound),
+		errors.Is(err, drive.ErrBackupSetNotFound),
+		errors.Is(err, drive.ErrKeyRotationNotFound),
+		errors.Is(err, drive.ErrShareNotInRotation),
+		errors.Is(err, drive.ErrThumbnailNotFound):
 		statusCode = http.StatusNotFound
 		apiStatus = status.StatusNotFound
 
 	// Permission errors
 	case errors.Is(err, drive.ErrUnauthorized),
-		errors.Is(err, drive.ErrInsufficientPermissions):
+		errors.Is(err, drive.ErrInsufficientPermissions),
+		errors.Is(err, drive.ErrReplicationNotAllowed),
+		errors.Is(err, drive.ErrBackupReadOnly),
+		errors.Is(err, drive.ErrCannotUnlockShare),
+		errors.Is(err, drive.ErrCannotChangeOwner):
 		statusCode = http.StatusForbidden
 		apiStatus = status.StatusForbidden
 
@@ -97,18 +128,86 @@ func (h *Handler) handleServiceError(err error, route string) (int, int16, strin
 	case errors.Is(err, drive.ErrStorageQuotaExceeded):
 		statusCode = http.StatusPaymentRequired
 		apiStatus = status.StatusStorageQuotaExceeded
+	case errors.Is(err, drive.ErrTooManyTags):
+		statusCode = http.StatusConflict
+		apiStatus = status.StatusConflict
+	case errors.Is(err, drive.ErrShareURLRateLimited):
+		statusCode = http.StatusTooManyRequests
+		apiStatus = status.StatusTooManyRequests
 
 	// Bad request errors
-	case errors.Is(err, drive.ErrNotAFolder):
+	case errors.Is(err, drive.ErrNotAFolder),
+		errors.Is(err, drive.ErrNotAFile),
+		errors.Is(err, drive.ErrTooManyCandidates),
+		errors.Is(err, drive.ErrTooManyItems),
+		errors.Is(err, drive.ErrCannotTrashRoot),
+		errors.Is(err, drive.ErrCannotMoveRoot),
+		errors.Is(err, drive.ErrCannotDeleteRoot),
+		errors.Is(err, drive.ErrInvalidEventCursor),
+		errors.Is(err, drive.ErrInvalidMoveTarget),
+		errors.Is(err, drive.ErrCannotCopyFolder),
+		errors.Is(err, drive.ErrInvalidXattrs),
+		errors.Is(err, drive.ErrInvalidFolderManifest),
+		errors.Is(err, drive.ErrInvalidSearchToken),
+		errors.Is(err, drive.ErrTooManySearchTokens),
+		errors.Is(err, drive.ErrInvalidSearchKeyVersion),
+		errors.Is(err, drive.ErrNoSearchReindex),
+		errors.Is(err, drive.ErrInvalidBlockList),
+		errors.Is(err, drive.ErrBlockTooLarge),
+		errors.Is(err, drive.ErrInvalidBlockChecksum),
+		errors.Is(err, drive.ErrInvalidThumbnail),
+		errors.Is(err, drive.ErrBlocksIncomplete),
+		errors.Is(err, drive.ErrBlockChecksumMismatch),
+		errors.Is(err, drive.ErrInvalidSlug),
+		errors.Is(err, drive.ErrSlugReserved),
+		errors.Is(err, drive.ErrInvalidQRCodeSize),
+		errors.Is(err, drive.ErrInvalidQRCodeFormat),
+		errors.Is(err, drive.ErrInvalidTagName),
+		errors.Is(err, drive.ErrInvalidTagColor),
+		errors.Is(err, drive.ErrTooManyItemTags),
+		errors.Is(err, drive.ErrTooManyTagFilters),
+		errors.Is(err, drive.ErrNoSearchCriteria),
+		errors.Is(err, drive.ErrInvalidBackupRules),
+		errors.Is(err, drive.ErrInvalidUnlockPacket),
+		errors.Is(err, drive.ErrNoPrimaryKey),
+		errors.Is(err, drive.ErrInvalidRotationBatch),
+		errors.Is(err, drive.ErrInvalidPermissions),
+		errors.Is(err, drive.ErrShareNotTransferable),
+		errors.Is(err, drive.ErrInvalidTransferPacket),
+		errors.Is(err, drive.ErrInvalidExpiry):
 		statusCode = http.StatusBadRequest
 		apiStatus = status.StatusBadRequest
 
+	// Expired resources
+	case errors.Is(err, drive.ErrShareURLExpired),
+		errors.Is(err, drive.ErrShareExpired),
+		errors.Is(err, drive.ErrPermissionExpired):
+		statusCode = http.StatusGone
+		apiStatus = status.StatusNotFound
+
+	// Storage backend errors
+	case errors.Is(err, drive.ErrStorageUnavailable),
+		errors.Is(err, drive.ErrUploadDeferred),
+		errors.Is(err, drive.ErrReplicationUnavailable),
+		errors.Is(err, drive.ErrRegionRoutingUnavailable):
+		statusCode = http.StatusServiceUnavailable
+		apiStatus = status.StatusServiceUnavailable
+
+	// The request used up its budget
+	case errors.Is(err, context.DeadlineExceeded):
+		statusCode = http.StatusGatewayTimeout
+		apiStatus = status.StatusRequestTimeout
+		message = "Request timed out"
+
 	// Creation errors - keep as internal server errors
 	case errors.Is(err, drive.ErrVolumeCreation),
 		errors.Is(err, drive.ErrAllocationCreation),
 		errors.Is(err, drive.ErrShareCreation),
 		errors.Is(err, drive.ErrMembershipCreation),
 		errors.Is(err, drive.ErrFolderCreation),
+		errors.Is(err, drive.ErrFileCreation),
+		errors.Is(err, drive.ErrShareURLCreation),
+		errors.Is(err, drive.ErrBackupSetDeleteFailed),
 		errors.Is(err, drive.ErrItemRetrieval):
 		// These remain as internal server errors
 	}
@@ -173,15 +272,15 @@ func (h *Handler) getPaginationParams(c *gin.Context, defaultLimitVal, maxLimitV
 	return limit, offset
 }
 
-// getSortingParams extracts and validates sorting parameters
-func (h *Handler) getSortingParams(c *gin.Context, validSortFields map[string]bool) (string, string) {
+// getSortingParams extracts sorting parameters, keeping only fields the column allowlist can sort on
+func (h *Handler) getSortingParams(c *gin.Context, columns db.Columns) (string, string) {
 	sortBy := defaultSortBy
 	sortDir := defaultSortDir
 
 	sortByParam := c.Query("sortBy")
 	sortDirParam := c.Query("sortDir")
 
-	if sortByParam != "" && validSortFields[sortByParam] {
+	if sortByParam != "" && columns.Sortable(sortByParam) {
 		sortBy = sortByParam
 	}
 
@@ -194,7 +293,33 @@ func (h *Handler) getSortingParams(c *gin.Context, validSortFields map[string]bo
 
 // respondWithError sends a standardized error response
 func (h *Handler) respondWithError(c *gin.Context, statusCode int, apiStatus int16, message string) {
-	c.JSON(statusCode, NewErrorResponse(message, apiStatus))
+	c.JSON(statusCode, NewErrorResponse(message, apiStatus, middleware.RequestID(c)))
+}
+
+// respondWithQuotaError sends a 402 with the user's usage numbers when err is a rejected storage charge.
+// It reports whether a response was sent.
+func (h *Handler) respondWithQuotaError(c *gin.Context, err error) bool {
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+
+	c.JSON(http.StatusPaymentRequired, NewQuotaExceededResponse(exceeded, status.StatusStorageQuotaExceeded, middleware.RequestID(c)))
+	return true
+}
+
+// respondWithDeferredUpload writes a deferred upload response if err is ErrUploadDeferred.
+// The upload is accepted but cannot proceed until storage is back, so the client keeps its blocks
+// queued and retries after the advertised delay.
+func (h *Handler) respondWithDeferredUpload(c *gin.Context, err error) bool {
+	if !errors.Is(err, drive.ErrUploadDeferred) {
+		return false
+	}
+
+	retryAfter := int64(h.driveService.StorageStatus().RetryAfter.Seconds())
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.JSON(http.StatusAccepted, NewUploadDeferredResponse(retryAfter, status.StatusAccepted, middleware.RequestID(c)))
+	return true
 }
 
 // validateRequestParam validates a required request parameter
@@ -214,14 +339,12 @@ func (h *Handler) CreateDriveVolume(c *gin.Context) {
 		return
 	}
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(c.Request.Context(), defaultTimeout)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	// Get user details
 	user, err := h.userService.GetUserById(ctx, userID)
 	if err != nil {
-		h.secureLog(err, "Failed to retrieve user", "createDrive")
+		h.secureLog(c, err, "Failed to retrieve user", "createDrive")
 		h.respondWithError(c, http.StatusInternalServerError, status.StatusInternalServerError, "Failed to retrieve user")
 		return
 	}
@@ -229,8 +352,8 @@ func (h *Handler) CreateDriveVolume(c *gin.Context) {
 	// Parse request body
 	var req CreateDriveRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "createDrive")
-		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed))
+		h.secureLog(c, err, "Invalid request format", "createDrive")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
 		return
 	}
 
@@ -247,12 +370,12 @@ func (h *Handler) CreateDriveVolume(c *gin.Context) {
 	)
 
 	if err != nil {
-		statusCode, apiStatus, message := h.handleServiceError(err, "createDrive")
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "createDrive")
 		h.respondWithError(c, statusCode, apiStatus, message)
 		return
 	}
 
-	c.JSON(http.StatusCreated, NewSuccessResponse("Drive structure created successfully", status.StatusCreated))
+	c.JSON(http.StatusCreated, NewSuccessResponse("Drive structure created successfully", status.StatusCreated, middleware.RequestID(c)))
 }
 
 // CreateDriveFolder handles creating a folder under a specific share
@@ -274,8 +397,8 @@ func (h *Handler) CreateDriveFolder(c *gin.Context) {
 	// Parse request body
 	var req CreateFolderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "createFolder")
-		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed))
+		h.secureLog(c, err, "Invalid request format", "createFolder")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
 		return
 	}
 
@@ -293,20 +416,67 @@ func (h *Handler) CreateDriveFolder(c *gin.Context) {
 		},
 	}
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(c.Request.Context(), defaultTimeout)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	// Call service to create folder
 	folder, err := h.driveService.CreateDriveFolder(ctx, userID, shareID, folderInput)
 	if err != nil {
-		statusCode, apiStatus, message := h.handleServiceError(err, "createFolder")
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "createFolder")
 		h.respondWithError(c, statusCode, apiStatus, message)
 		return
 	}
 
 	// Return created folder
-	c.JSON(http.StatusCreated, NewFolderResponse(folder, status.StatusCreated))
+	c.JSON(http.StatusCreated, NewFolderResponse(folder, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// BulkCreateFolders handles creating a tree of folders under one folder in a single request
+func (h *Handler) BulkCreateFolders(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req BulkCreateFoldersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "bulkCreateFolders")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	folders := make([]*drive.BulkFolder, len(req.Folders))
+	for i, folder := range req.Folders {
+		folders[i] = &drive.BulkFolder{
+			TempID:                  folder.TempID,
+			ParentTempID:            folder.ParentTempID,
+			Name:                    folder.Name,
+			Hash:                    folder.Hash,
+			SignatureEmail:          folder.SignatureEmail,
+			NodeKey:                 folder.NodeKey,
+			NodeHashKey:             folder.NodeHashKey,
+			NodePassphrase:          folder.NodePassphrase,
+			NodePassphraseSignature: folder.NodePassphraseSignature,
+		}
+	}
+
+	results, err := h.driveService.CreateFolderTree(c.Request.Context(), userID, shareID, req.ParentID, folders)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "bulkCreateFolders")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewBulkFoldersResponse(results, status.StatusCreated, middleware.RequestID(c)))
 }
 
 // GetUserShares handles the retrieval of shares for a user
@@ -321,20 +491,18 @@ func (h *Handler) GetUserShares(c *gin.Context) {
 	// Get pagination parameters
 	limit, offset := h.getPaginationParams(c, 50, 100)
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(c.Request.Context(), defaultTimeout)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	// Call service to get shares
 	shares, total, err := h.driveService.GetSharesByUserID(ctx, userID, limit, offset)
 	if err != nil {
-		statusCode, apiStatus, message := h.handleServiceError(err, "getUserShares")
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getUserShares")
 		h.respondWithError(c, statusCode, apiStatus, message)
 		return
 	}
 
 	// Return shares
-	c.JSON(http.StatusOK, NewSharesListResponse(shares, limit, offset, total, userID, status.StatusOK))
+	c.JSON(http.StatusOK, NewSharesListResponse(shares, limit, offset, total, userID, status.StatusOK, middleware.RequestID(c)))
 }
 
 // GetShareByID returns a share with all its memberships
@@ -353,20 +521,35 @@ func (h *Handler) GetShareByID(c *gin.Context) {
 		return
 	}
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(c.Request.Context(), defaultTimeout)
-	defer cancel()
+	ctx := c.Request.Context()
+
+	// The tag is read first so a concurrent change is never hidden behind it; without Redis none is sent
+	etag, err := h.driveService.ShareETag(ctx, shareID, userID)
+	if err != nil {
+		etag = ""
+	}
 
 	// Call service to get share with memberships
 	share, memberships, err := h.driveService.GetShareWithAllMemberships(ctx, shareID, userID)
 	if err != nil {
-		statusCode, apiStatus, message := h.handleServiceError(err, "getShareById")
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getShareById")
 		h.respondWithError(c, statusCode, apiStatus, message)
 		return
 	}
 
+	// Clients that hold the current version get no body
+	lastModified := share.ModifiedAt
+	for _, membership := range memberships {
+		lastModified = max(lastModified, membership.ModifiedAt)
+	}
+	middleware.SetValidators(c, etag, lastModified)
+	if middleware.ETagMatches(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	// Return share with memberships
-	c.JSON(http.StatusOK, NewShareWithMembershipsResponse(share, memberships, userID, status.StatusOK))
+	c.JSON(http.StatusOK, NewShareWithMembershipsResponse(share, memberships, userID, status.StatusOK, middleware.RequestID(c)))
 }
 
 // BatchGetShares handles retrieving multiple shares in a single request
@@ -381,8 +564,8 @@ func (h *Handler) BatchGetShares(c *gin.Context) {
 	// Parse request body
 	var req BatchSharesRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "batchGetShares")
-		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed))
+		h.secureLog(c, err, "Invalid request format", "batchGetShares")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
 		return
 	}
 
@@ -397,20 +580,18 @@ func (h *Handler) BatchGetShares(c *gin.Context) {
 		return
 	}
 
-	// Create a context with timeout - use extended timeout for batch operations
-	ctx, cancel := context.WithTimeout(c.Request.Context(), extendedTimeout)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	// Use the BatchGetSharesWithMemberships method from the improved service
 	sharesWithMemberships, err := h.driveService.BatchGetSharesWithMemberships(ctx, req.ShareIDs, userID)
 	if err != nil {
-		statusCode, apiStatus, message := h.handleServiceError(err, "batchGetShares")
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "batchGetShares")
 		h.respondWithError(c, statusCode, apiStatus, message)
 		return
 	}
 
 	// Return batch result
-	c.JSON(http.StatusOK, NewBatchSharesResponse(sharesWithMemberships, userID, status.StatusOK))
+	c.JSON(http.StatusOK, NewBatchSharesResponse(sharesWithMemberships, userID, status.StatusOK, middleware.RequestID(c)))
 }
 
 // GetLinkByID handles retrieving any link (file or folder) by its ID
@@ -432,13 +613,66 @@ func (h *Handler) GetLinkByID(c *gin.Context) {
 	// Call service method to get link by ID
 	item, err := h.driveService.GetLinkByID(c.Request.Context(), linkID, userID)
 	if err != nil {
-		statusCode, apiStatus, message := h.handleServiceError(err, "getLinkById")
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getLinkById")
 		h.respondWithError(c, statusCode, apiStatus, message)
 		return
 	}
 
 	// Return appropriate response based on item type
-	c.JSON(http.StatusOK, NewDriveItemResponse(item, status.StatusOK))
+	c.JSON(http.StatusOK, NewDriveItemResponse(item, status.StatusOK, middleware.RequestID(c)))
+}
+
+// BatchGetLinks handles retrieving multiple links in a single request
+func (h *Handler) BatchGetLinks(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Parse request body
+	var req BatchGetLinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "batchGetLinks")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	batch, err := h.driveService.BatchGetLinks(c.Request.Context(), req.LinkIDs, userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "batchGetLinks")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBatchLinksResponse(batch, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetItemPath handles returning the folders above an item up to the root of its share
+func (h *Handler) GetItemPath(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get link ID from URL path
+	linkID := c.Param("linkID")
+	if err := h.validateRequestParam(linkID, "Link ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	path, err := h.driveService.GetItemPath(c.Request.Context(), userID, linkID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getItemPath")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewItemPathResponse(path, status.StatusOK, middleware.RequestID(c)))
 }
 
 // GetFolderContents handles retrieving the contents of a folder
@@ -468,13 +702,19 @@ func (h *Handler) GetFolderContents(c *gin.Context) {
 	limit, offset := h.getPaginationParams(c, defaultLimit, maxLimit)
 
 	// Get sorting parameters
-	validSortFields := map[string]bool{
-		"createdAt":  true,
-		"modifiedAt": true,
-		"size":       true,
-		"type":       true,
+	sortBy, sortDir := h.getSortingParams(c, drive.ItemColumns)
+
+	// Optional tag filter, items must carry every tag
+	tagIDs := getQueryList(c, "tags")
+
+	// Tags change without touching the folder, so only unfiltered listings get a tag. It is read first
+	// so a concurrent change is never hidden behind it; without Redis none is sent.
+	etag := ""
+	if len(tagIDs) == 0 {
+		if etag, err = h.driveService.FolderContentsETag(c.Request.Context(), shareID, folderID, c.Request.URL.RawQuery); err != nil {
+			etag = ""
+		}
 	}
-	sortBy, sortDir := h.getSortingParams(c, validSortFields)
 
 	// Call service method to get folder contents
 	items, total, err := h.driveService.GetFolderContents(
@@ -482,6 +722,7 @@ func (h *Handler) GetFolderContents(c *gin.Context) {
 		shareID,
 		folderID,
 		userID,
+		tagIDs,
 		limit,
 		offset,
 		sortBy,
@@ -489,13 +730,24 @@ func (h *Handler) GetFolderContents(c *gin.Context) {
 	)
 
 	if err != nil {
-		statusCode, apiStatus, message := h.handleServiceError(err, "getFolderContents")
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getFolderContents")
 		h.respondWithError(c, statusCode, apiStatus, message)
 		return
 	}
 
+	// Clients that hold the current version get no body
+	var lastModified int64
+	for _, item := range items {
+		lastModified = max(lastModified, item.ModifiedAt)
+	}
+	middleware.SetValidators(c, etag, lastModified)
+	if middleware.ETagMatches(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	// Return folder contents
-	c.JSON(http.StatusOK, NewFolderContentsResponse(items, limit, offset, total, sortBy, sortDir, status.StatusOK))
+	c.JSON(http.StatusOK, NewFolderContentsResponse(items, limit, offset, total, sortBy, sortDir, status.StatusOK, middleware.RequestID(c)))
 }
 
 // Helper method to handle permission errors
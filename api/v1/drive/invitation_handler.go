@@ -0,0 +1,148 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InviteShareMember handles inviting a user to a share by email
+func (h *Handler) InviteShareMember(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req InviteShareMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "inviteShareMember")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	inviter, err := h.userService.GetUserById(ctx, userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to retrieve user", "inviteShareMember")
+		h.respondWithError(c, http.StatusInternalServerError, status.StatusInternalServerError, "Failed to retrieve user")
+		return
+	}
+
+	// The key packet is encrypted to the invitee's key, so they must already have an account
+	invitee, err := h.userService.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			err = drive.ErrUserNotFound
+		}
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "inviteShareMember")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	invitation, err := h.driveService.InviteShareMember(ctx, inviter, invitee, shareID, &models.DriveShareMembership{
+		Permissions:         req.Permissions,
+		KeyPacket:           req.KeyPacket,
+		KeyPacketSignature:  req.KeyPacketSignature,
+		SessionKeySignature: req.SessionKeySignature,
+		PermissionExpiresAt: req.PermissionExpiresAt,
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "inviteShareMember")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewInvitationResponse(invitation, status.StatusShareCreated, middleware.RequestID(c)))
+}
+
+// GetPendingInvitations handles listing the share invitations waiting for the user
+func (h *Handler) GetPendingInvitations(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	invitations, err := h.driveService.GetPendingInvitations(ctx, userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getPendingInvitations")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewInvitationsResponse(invitations, status.StatusOK, middleware.RequestID(c)))
+}
+
+// AcceptInvitation handles accepting a share invitation
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	invitationID := c.Param("invitationID")
+	if err := h.validateRequestParam(invitationID, "Invitation ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	invitation, err := h.driveService.AcceptInvitation(ctx, userID, invitationID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "acceptInvitation")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewInvitationResponse(invitation, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// DeclineInvitation handles declining a share invitation
+func (h *Handler) DeclineInvitation(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	invitationID := c.Param("invitationID")
+	if err := h.validateRequestParam(invitationID, "Invitation ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.driveService.DeclineInvitation(ctx, userID, invitationID); err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "declineInvitation")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Invitation declined", status.StatusUpdated, middleware.RequestID(c)))
+}
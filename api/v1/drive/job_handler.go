@@ -0,0 +1,67 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteFolder handles permanently deleting a folder and its contents in the background
+func (h *Handler) DeleteFolder(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and folder IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+	folderID := c.Param("folderID")
+	if err := h.validateRequestParam(folderID, "FolderID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.driveService.DeleteFolder(c.Request.Context(), userID, shareID, folderID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "deleteFolder")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, NewJobResponse(job, status.StatusAccepted, middleware.RequestID(c)))
+}
+
+// GetJob handles retrieving the status of a background job
+func (h *Handler) GetJob(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get job ID from URL path
+	jobID := c.Param("jobID")
+	if err := h.validateRequestParam(jobID, "JobID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.driveService.GetJob(c.Request.Context(), userID, jobID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getJob")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewJobResponse(job, status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,129 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartKeyRotation handles starting, or resuming, the rotation of the caller's shares to their new key
+func (h *Handler) StartKeyRotation(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	rotation, err := h.driveService.StartKeyRotation(c.Request.Context(), userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "startKeyRotation")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewKeyRotationResponse(rotation, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetKeyRotationStatus handles showing the progress of the caller's latest key rotation
+func (h *Handler) GetKeyRotationStatus(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	rotation, err := h.driveService.GetKeyRotationStatus(c.Request.Context(), userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getKeyRotationStatus")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewKeyRotationResponse(rotation, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetKeyRotationItems handles listing the next items of a share to re-wrap for the new key
+func (h *Handler) GetKeyRotationItems(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Get batch size, reusing the pagination limit parameter
+	limit, _ := h.getPaginationParams(c, drive.MAX_KEY_ROTATION_ITEMS, drive.MAX_KEY_ROTATION_ITEMS)
+
+	items, err := h.driveService.GetKeyRotationItems(c.Request.Context(), userID, shareID, limit)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getKeyRotationItems")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewKeyRotationItemsResponse(items, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RotateShareKeys handles storing the next part of a share re-wrapped for the owner's new key
+func (h *Handler) RotateShareKeys(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req RotateShareKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "rotateShareKeys")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	batch := drive.KeyRotationBatch{Items: make([]drive.RotatedNodePassphrase, len(req.Items))}
+	if req.SharePassphrase != "" || req.SharePassphraseSignature != "" || req.OwnerKeyPacket != "" || req.OwnerKeyPacketSignature != "" {
+		batch.ShareKeys = &drive.ShareUnlockKeys{
+			SharePassphrase:          req.SharePassphrase,
+			SharePassphraseSignature: req.SharePassphraseSignature,
+			OwnerKeyPacket:           req.OwnerKeyPacket,
+			OwnerKeyPacketSignature:  req.OwnerKeyPacketSignature,
+		}
+	}
+	for i, item := range req.Items {
+		batch.Items[i] = drive.RotatedNodePassphrase{
+			LinkID:                  item.LinkID,
+			NodePassphrase:          item.NodePassphrase,
+			NodePassphraseSignature: item.NodePassphraseSignature,
+		}
+	}
+
+	progress, err := h.driveService.RotateShareKeys(c.Request.Context(), userID, shareID, batch)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "rotateShareKeys")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewKeyRotationShareResponse(progress, status.StatusUpdated, middleware.RequestID(c)))
+}
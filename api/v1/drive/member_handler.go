@@ -0,0 +1,117 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateShareMember handles changing the permissions of a share member
+func (h *Handler) UpdateShareMember(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and membership IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+	memberID := c.Param("memberID")
+	if err := h.validateRequestParam(memberID, "MemberID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req UpdateShareMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updateShareMember")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	membership, err := h.driveService.UpdateShareMember(c.Request.Context(), userID, shareID, memberID, req.Permissions)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "updateShareMember")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewMembershipResponse(membership, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// RemoveShareMember handles revoking a member's access to a share
+func (h *Handler) RemoveShareMember(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and membership IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+	memberID := c.Param("memberID")
+	if err := h.validateRequestParam(memberID, "MemberID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.driveService.RemoveShareMember(c.Request.Context(), userID, shareID, memberID); err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "removeShareMember")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Member removed", status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// TransferShareOwnership handles making a member the owner of a share
+func (h *Handler) TransferShareOwnership(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req TransferShareOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "transferShareOwnership")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	share, err := h.driveService.TransferShareOwnership(c.Request.Context(), userID, shareID, req.MembershipID, drive.ShareTransferKeys{
+		SharePassphrase:          req.SharePassphrase,
+		SharePassphraseSignature: req.SharePassphraseSignature,
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "transferShareOwnership")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewShareWithMembershipsResponse(share, nil, userID, status.StatusUpdated, middleware.RequestID(c)))
+}
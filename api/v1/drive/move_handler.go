@@ -0,0 +1,240 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"context"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RenameItem handles renaming a file or folder within its folder
+func (h *Handler) RenameItem(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, ok := h.getLinkParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req RenameItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "renameItem")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	item, err := h.driveService.RenameItem(c.Request.Context(), userID, shareID, linkID, &drive.ItemRename{
+		Name:               req.Name,
+		Hash:               req.Hash,
+		NameSignatureEmail: req.NameSignatureEmail,
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "renameItem")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDriveItemResponse(item, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// MoveItem handles moving a file or folder to another folder of the same share
+func (h *Handler) MoveItem(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, ok := h.getLinkParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req MoveItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "moveItem")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	item, err := h.driveService.MoveItem(c.Request.Context(), userID, shareID, linkID, &drive.ItemMove{
+		ParentID:                req.ParentID,
+		Name:                    req.Name,
+		Hash:                    req.Hash,
+		NameSignatureEmail:      req.NameSignatureEmail,
+		NodePassphrase:          req.NodePassphrase,
+		NodePassphraseSignature: req.NodePassphraseSignature,
+		SignatureEmail:          req.SignatureEmail,
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "moveItem")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDriveItemResponse(item, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// CopyItem handles copying a file or folder into a folder of another share
+func (h *Handler) CopyItem(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, ok := h.getLinkParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req CopyItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "copyItem")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	item, err := h.driveService.CopyItemToShare(c.Request.Context(), userID, shareID, linkID, &drive.ItemShareCopy{
+		TargetShareID:           req.TargetShareID,
+		ParentID:                req.ParentID,
+		Name:                    req.Name,
+		Hash:                    req.Hash,
+		NameSignatureEmail:      req.NameSignatureEmail,
+		NodeKey:                 req.NodeKey,
+		NodePassphrase:          req.NodePassphrase,
+		NodePassphraseSignature: req.NodePassphraseSignature,
+		SignatureEmail:          req.SignatureEmail,
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "copyItem")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewDriveItemResponse(item, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// BatchMoveItems handles moving several links of a share into one folder
+func (h *Handler) BatchMoveItems(c *gin.Context) {
+	h.handleBatchMove(c, "batchMoveItems", h.driveService.BatchMoveItems)
+}
+
+// BatchCopyItems handles copying several files of a share into one folder
+func (h *Handler) BatchCopyItems(c *gin.Context) {
+	h.handleBatchMove(c, "batchCopyItems", h.driveService.BatchCopyItems)
+}
+
+// handleBatchMove binds a batch move or copy request, runs the operation and reports per-link outcomes
+func (h *Handler) handleBatchMove(
+	c *gin.Context,
+	route string,
+	operation func(ctx context.Context, userID, shareID, parentID string, entries []*drive.BatchItemMove) ([]*drive.ItemResult, error),
+) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req BatchMoveItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", route)
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	entries := make([]*drive.BatchItemMove, len(req.Items))
+	for i, item := range req.Items {
+		entries[i] = &drive.BatchItemMove{
+			LinkID:                  item.LinkID,
+			Name:                    item.Name,
+			Hash:                    item.Hash,
+			NameSignatureEmail:      item.NameSignatureEmail,
+			NodePassphrase:          item.NodePassphrase,
+			NodePassphraseSignature: item.NodePassphraseSignature,
+			SignatureEmail:          item.SignatureEmail,
+		}
+	}
+
+	results, err := operation(c.Request.Context(), userID, shareID, req.ParentID, entries)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, route)
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBatchResultsResponse(h.batchItemResponses(c, results, route), status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// getLinkParams reads and validates the share and link IDs from the URL path
+func (h *Handler) getLinkParams(c *gin.Context) (string, string, bool) {
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	linkID := c.Param("linkID")
+	if err := h.validateRequestParam(linkID, "Link ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	return shareID, linkID, true
+}
+
+// SetItemXattrs handles setting or clearing the encrypted extended attributes of a file or folder
+func (h *Handler) SetItemXattrs(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, ok := h.getLinkParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body; a null xattr clears the attributes
+	var req SetItemXattrsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setItemXattrs")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	item, err := h.driveService.SetItemXattrs(c.Request.Context(), userID, shareID, linkID, req.Xattr)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setItemXattrs")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDriveItemResponse(item, status.StatusUpdated, middleware.RequestID(c)))
+}
@@ -0,0 +1,34 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRecentItems handles listing the files the user recently opened or that were recently modified
+// in any of the user's shares
+func (h *Handler) ListRecentItems(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get pagination parameters
+	limit, offset := h.getPaginationParams(c, defaultLimit, maxLimit)
+
+	items, total, err := h.driveService.ListRecentItems(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "listRecentItems")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewRecentItemsResponse(items, limit, offset, total, status.StatusOK, middleware.RequestID(c)))
+}
@@ -21,6 +21,7 @@ type DriveShareWrapper struct {
 	ShareKey                 string `json:"shareKey"`
 	SharePassphrase          string `json:"sharePassphrase"`
 	SharePassphraseSignature string `json:"sharePassphraseSignature"`
+	ExpiresAt                *int64 `json:"expiresAt"`
 }
 
 // ToModel converts the wrapper to a models.DriveShare
@@ -29,6 +30,7 @@ func (dsw *DriveShareWrapper) ToModel() *models.DriveShare {
 		ShareKey:                 dsw.ShareKey,
 		SharePassphrase:          dsw.SharePassphrase,
 		SharePassphraseSignature: dsw.SharePassphraseSignature,
+		ExpiresAt:                dsw.ExpiresAt,
 	}
 }
 
@@ -103,11 +105,280 @@ func (r *CreateDriveRequest) ToModelRequest() struct {
 // CreateFolderRequest represents a request to create a new folder
 type CreateFolderRequest struct {
 	Name                    string  `json:"name" binding:"required"`
-	Hash                    string  `json:"hash" binding:"required"`
-	ParentId                *string `json:"parentId" binding:"required"`
+	Hash                    string  `json:"hash" binding:"required,hash"`
+	ParentId                *string `json:"parentId" binding:"required,linkid"`
 	SignatureEmail          string  `json:"signatureEmail" binding:"required"`
 	NodeKey                 string  `json:"nodeKey" binding:"required"`
 	NodeHashKey             string  `json:"nodeHashKey" binding:"required"`
 	NodePassphrase          string  `json:"nodePassphrase" binding:"required"`
 	NodePassphraseSignature string  `json:"nodePassphraseSignature" binding:"required"`
 }
+
+// BulkFolderRequest represents one folder of a bulk folder creation. Folders refer to their parent by
+// its temporary ID in the same request; folders without one go into the target folder.
+type BulkFolderRequest struct {
+	TempID                  string `json:"tempId" binding:"required,max=64"`
+	ParentTempID            string `json:"parentTempId" binding:"omitempty,max=64"`
+	Name                    string `json:"name" binding:"required"`
+	Hash                    string `json:"hash" binding:"required,hash"`
+	SignatureEmail          string `json:"signatureEmail" binding:"required"`
+	NodeKey                 string `json:"nodeKey" binding:"required"`
+	NodeHashKey             string `json:"nodeHashKey" binding:"required"`
+	NodePassphrase          string `json:"nodePassphrase" binding:"required"`
+	NodePassphraseSignature string `json:"nodePassphraseSignature" binding:"required"`
+}
+
+// BulkCreateFoldersRequest represents a request to create a tree of folders under one folder
+type BulkCreateFoldersRequest struct {
+	ParentID string              `json:"parentId" binding:"required,linkid"`
+	Folders  []BulkFolderRequest `json:"folders" binding:"required,min=1,max=1000,dive"`
+}
+
+// SetItemXattrsRequest represents a request to set or clear an item's encrypted extended attributes
+type SetItemXattrsRequest struct {
+	Xattr *string `json:"xattr" binding:"omitempty,min=1,max=65536"`
+}
+
+// SetSearchTokensRequest represents a request to store encrypted name tokens for an item
+type SetSearchTokensRequest struct {
+	KeyVersion int      `json:"keyVersion" binding:"required,min=1"`
+	Tokens     []string `json:"tokens" binding:"required,max=64"`
+}
+
+// SearchRequest represents an encrypted token search within a share, optionally narrowed to tagged items
+type SearchRequest struct {
+	Tokens []string `json:"tokens" binding:"max=16"`
+	TagIDs []string `json:"tagIds" binding:"max=8"`
+}
+
+// StartSearchKeyRotationRequest represents a request to rotate the search token key
+type StartSearchKeyRotationRequest struct {
+	NewKeyVersion int `json:"newKeyVersion" binding:"required,min=2"`
+}
+
+// CreateFileRequest represents a request to register a new file before its blocks are uploaded
+type CreateFileRequest struct {
+	Name                    string                `json:"name" binding:"required"`
+	Hash                    string                `json:"hash" binding:"required,hash"`
+	ParentId                *string               `json:"parentId" binding:"required,linkid"`
+	MimeType                *string               `json:"mimeType" binding:"omitempty,max=100"`
+	SignatureEmail          string                `json:"signatureEmail" binding:"required"`
+	NodeKey                 string                `json:"nodeKey" binding:"required"`
+	NodePassphrase          string                `json:"nodePassphrase" binding:"required"`
+	NodePassphraseSignature string                `json:"nodePassphraseSignature" binding:"required"`
+	FileProperties          FilePropertiesWrapper `json:"fileProperties" binding:"required"`
+}
+
+// BlockUploadRequestItem describes a single block the client intends to upload
+type BlockUploadRequestItem struct {
+	Index              int    `json:"index" binding:"min=0"`
+	Size               int64  `json:"size" binding:"required,min=1"`
+	Hash               string `json:"hash" binding:"required,hash"`
+	ChecksumSHA256     string `json:"checksumSha256" binding:"omitempty,base64,len=44"`
+	KeyPacket          string `json:"keyPacket"`
+	KeyPacketSignature string `json:"keyPacketSignature"`
+}
+
+// RequestBlockUploadsRequest represents a request for presigned block upload URLs
+type RequestBlockUploadsRequest struct {
+	Blocks []BlockUploadRequestItem `json:"blocks" binding:"required,min=1,max=100,dive"`
+}
+
+// RequestThumbnailUploadRequest represents a request for a presigned thumbnail upload URL.
+// Type is 1 (small), 2 (medium) or 3 (large); size is the encrypted thumbnail size in bytes.
+type RequestThumbnailUploadRequest struct {
+	Type               int    `json:"type" binding:"required,min=1,max=3"`
+	Size               int64  `json:"size" binding:"required,min=1,max=524288"`
+	Hash               string `json:"hash" binding:"required,hash"`
+	ThumbnailSignature string `json:"thumbnailSignature"`
+}
+
+// CommitRevisionRequest represents a request to finalize a file revision
+type CommitRevisionRequest struct {
+	BlockCount        int    `json:"blockCount" binding:"required,min=1"`
+	SignatureEmail    string `json:"signatureEmail"`
+	ManifestSignature string `json:"manifestSignature" binding:"required"`
+}
+
+// CreateShareURLRequest represents a request to create a public link for an item
+type CreateShareURLRequest struct {
+	SharePasswordSalt        string  `json:"sharePasswordSalt" binding:"required"`
+	SharePassphraseKeyPacket string  `json:"sharePassphraseKeyPacket" binding:"required"`
+	ExpiresAt                *int64  `json:"expiresAt"`
+	Slug                     *string `json:"slug" binding:"omitempty,min=3,max=48"`
+}
+
+// SetShareURLSlugRequest represents a request to set or clear a public link's vanity slug
+type SetShareURLSlugRequest struct {
+	Slug *string `json:"slug" binding:"omitempty,min=3,max=48"`
+}
+
+// ContentCandidateRequestItem represents a client-computed content hash for a file about to be uploaded
+type ContentCandidateRequestItem struct {
+	ContentHash string `json:"contentHash" binding:"required,hash"`
+	Size        int64  `json:"size" binding:"min=0"`
+}
+
+// CheckDuplicatesRequest represents a pre-upload duplicate check for a folder
+type CheckDuplicatesRequest struct {
+	Candidates []ContentCandidateRequestItem `json:"candidates" binding:"required,min=1,max=500,dive"`
+}
+
+// BatchLinksRequest represents a batch operation over links of a share
+type BatchLinksRequest struct {
+	LinkIDs []string `json:"linkIds" binding:"required,min=1,max=100,dive,required,linkid"`
+}
+
+// BatchGetLinksRequest represents a request to look up many links, of any shares, at once
+type BatchGetLinksRequest struct {
+	LinkIDs []string `json:"linkIds" binding:"required,min=1,max=200,dive,required,linkid"`
+}
+
+// RenameItemRequest represents a request to rename an item within its folder
+type RenameItemRequest struct {
+	Name               string `json:"name" binding:"required"`
+	Hash               string `json:"hash" binding:"required,hash"`
+	NameSignatureEmail string `json:"nameSignatureEmail" binding:"required"`
+}
+
+// MoveItemRequest represents a request to move an item to another folder
+type MoveItemRequest struct {
+	ParentID                string `json:"parentId" binding:"required,linkid"`
+	Name                    string `json:"name" binding:"required"`
+	Hash                    string `json:"hash" binding:"required,hash"`
+	NameSignatureEmail      string `json:"nameSignatureEmail" binding:"required"`
+	NodePassphrase          string `json:"nodePassphrase" binding:"required"`
+	NodePassphraseSignature string `json:"nodePassphraseSignature" binding:"required"`
+	SignatureEmail          string `json:"signatureEmail" binding:"required"`
+}
+
+// BatchMoveItemsRequest represents a request to move or copy several links of a share into one folder
+type BatchMoveItemsRequest struct {
+	ParentID string                 `json:"parentId" binding:"required,linkid"`
+	Items    []BatchMoveItemRequest `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+// BatchMoveItemRequest represents one link of a batch move or copy, with its name and node passphrase
+// re-encrypted for the destination folder
+type BatchMoveItemRequest struct {
+	LinkID                  string `json:"linkId" binding:"required,linkid"`
+	Name                    string `json:"name" binding:"required"`
+	Hash                    string `json:"hash" binding:"required,hash"`
+	NameSignatureEmail      string `json:"nameSignatureEmail" binding:"required"`
+	NodePassphrase          string `json:"nodePassphrase" binding:"required"`
+	NodePassphraseSignature string `json:"nodePassphraseSignature" binding:"required"`
+	SignatureEmail          string `json:"signatureEmail" binding:"required"`
+}
+
+// CopyItemRequest represents a request to copy an item into a folder of another share, with its
+// node key re-locked and its name and passphrase re-encrypted for the destination folder
+type CopyItemRequest struct {
+	TargetShareID           string `json:"targetShareId" binding:"required"`
+	ParentID                string `json:"parentId" binding:"required,linkid"`
+	Name                    string `json:"name" binding:"required"`
+	Hash                    string `json:"hash" binding:"required,hash"`
+	NameSignatureEmail      string `json:"nameSignatureEmail" binding:"required"`
+	NodeKey                 string `json:"nodeKey" binding:"required"`
+	NodePassphrase          string `json:"nodePassphrase" binding:"required"`
+	NodePassphraseSignature string `json:"nodePassphraseSignature" binding:"required"`
+	SignatureEmail          string `json:"signatureEmail" binding:"required"`
+}
+
+// SetShareApprovalRequest represents a request to turn membership approval on or off for a share
+type SetShareApprovalRequest struct {
+	RequiresApproval *bool `json:"requiresApproval" binding:"required"`
+}
+
+// UnlockShareRequest represents a request to unlock a share with its passphrase re-wrapped for the
+// owner's new key
+type UnlockShareRequest struct {
+	SharePassphrase          string `json:"sharePassphrase" binding:"required"`
+	SharePassphraseSignature string `json:"sharePassphraseSignature" binding:"required"`
+	OwnerKeyPacket           string `json:"ownerKeyPacket" binding:"required"`
+	OwnerKeyPacketSignature  string `json:"ownerKeyPacketSignature" binding:"required"`
+}
+
+// RotateShareKeysRequest represents the next part of a share re-wrapped for the owner's new key. The
+// share passphrase fields are sent together, or left out to only re-wrap items.
+type RotateShareKeysRequest struct {
+	SharePassphrase          string                      `json:"sharePassphrase"`
+	SharePassphraseSignature string                      `json:"sharePassphraseSignature"`
+	OwnerKeyPacket           string                      `json:"ownerKeyPacket"`
+	OwnerKeyPacketSignature  string                      `json:"ownerKeyPacketSignature"`
+	Items                    []RotatedNodePassphraseData `json:"items" binding:"max=100,dive"`
+}
+
+// RotatedNodePassphraseData is an item's node passphrase re-wrapped during a key rotation
+type RotatedNodePassphraseData struct {
+	LinkID                  string `json:"linkId" binding:"required,linkid"`
+	NodePassphrase          string `json:"nodePassphrase" binding:"required"`
+	NodePassphraseSignature string `json:"nodePassphraseSignature" binding:"required"`
+}
+
+// UpdateShareMemberRequest represents a request to change the permissions of a share member
+type UpdateShareMemberRequest struct {
+	Permissions int `json:"permissions" binding:"required,permissions"`
+}
+
+// TransferShareOwnershipRequest represents a request to make a member the owner of a share, with the
+// share passphrase re-wrapped for the new owner's key
+type TransferShareOwnershipRequest struct {
+	MembershipID             string `json:"membershipId" binding:"required"`
+	SharePassphrase          string `json:"sharePassphrase" binding:"required"`
+	SharePassphraseSignature string `json:"sharePassphraseSignature" binding:"required"`
+}
+
+// InviteShareMemberRequest represents a request to invite a user to a share by email
+type InviteShareMemberRequest struct {
+	Email               string `json:"email" binding:"required,email,max=100"`
+	Permissions         int    `json:"permissions" binding:"required,permissions"`
+	KeyPacket           string `json:"keyPacket" binding:"required"`
+	KeyPacketSignature  string `json:"keyPacketSignature" binding:"required"`
+	SessionKeySignature string `json:"sessionKeySignature"`
+	PermissionExpiresAt *int64 `json:"permissionExpiresAt"` // Unix time the member loses access, omitted for no expiry
+}
+
+// SetVolumeReplicationRequest represents a request to turn cross-region replication on or off for a volume
+type SetVolumeReplicationRequest struct {
+	CrossRegionReplication *bool `json:"crossRegionReplication" binding:"required"`
+}
+
+// TagRequest represents a request to create or update a tag
+type TagRequest struct {
+	EncryptedName string `json:"encryptedName" binding:"required,max=1024"`
+	Color         string `json:"color" binding:"omitempty,len=7"`
+}
+
+// SetItemTagsRequest represents a request to replace the caller's tags on an item
+type SetItemTagsRequest struct {
+	TagIDs []string `json:"tagIds" binding:"max=32"`
+}
+
+// SetExpiryRequest represents a request to set, renew or clear the expiry of a share or public link
+type SetExpiryRequest struct {
+	ExpiresAt *int64 `json:"expiresAt" binding:"omitempty,min=1"`
+}
+
+// CreateBackupSetRequest represents a request to register a folder the desktop client backs up.
+// Rules left out use the defaults.
+type CreateBackupSetRequest struct {
+	DeviceID             string                      `json:"deviceId" binding:"required"`
+	EncryptedName        string                      `json:"encryptedName" binding:"required"`
+	MaxVersions          *int                        `json:"maxVersions" binding:"omitempty,min=1,max=100"`
+	RetentionDays        *int                        `json:"retentionDays" binding:"omitempty,min=0,max=3650"`
+	DriveShare           DriveShareWrapper           `json:"driveShare" binding:"required"`
+	DriveShareMembership DriveShareMembershipWrapper `json:"driveShareMembership" binding:"required"`
+}
+
+// UpdateBackupRulesRequest represents a request to replace the versioning and retention rules of a backup set
+type UpdateBackupRulesRequest struct {
+	MaxVersions   int  `json:"maxVersions" binding:"required,min=1,max=100"`
+	RetentionDays *int `json:"retentionDays" binding:"required,min=0,max=3650"`
+}
+
+// AcknowledgeSyncCursorRequest represents a request to record the event cursor up to which a
+// device has applied a volume's changes
+type AcknowledgeSyncCursorRequest struct {
+	DeviceID string `json:"deviceId" binding:"required"`
+	Cursor   string `json:"cursor" binding:"required,max=128"`
+}
@@ -3,11 +3,9 @@ package drive
 import (
 	"cirrussync-api/internal/drive"
 	"cirrussync-api/internal/models"
-	"cirrussync-api/internal/utils"
-	"strings"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/pkg/validation"
 	"sync"
-
-	"github.com/go-playground/validator/v10"
 )
 
 // BaseResponse represents the base structure for all API responses
@@ -16,10 +14,21 @@ type BaseResponse struct {
 	Detail string `json:"detail"`
 }
 
-// ErrorResponse represents an API error response
+// ErrorResponse represents an API error response. Rejected payloads list every field error.
 type ErrorResponse struct {
 	BaseResponse
-	Error string `json:"error,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+	Errors []validation.FieldError `json:"errors,omitempty"`
+}
+
+// QuotaExceededResponse represents a rejected storage charge with the usage it was checked against
+type QuotaExceededResponse struct {
+	BaseResponse
+	Error          string `json:"error"`
+	UsedBytes      int64  `json:"usedBytes"`
+	LimitBytes     int64  `json:"limitBytes"`
+	RemainingBytes int64  `json:"remainingBytes"`
+	RequestedBytes int64  `json:"requestedBytes"`
 }
 
 // SuccessResponse represents a simple success message
@@ -35,50 +44,58 @@ type DriveResponse struct {
 }
 
 // NewErrorResponse creates a new error response
-func NewErrorResponse(message string, code int16) ErrorResponse {
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
 	return ErrorResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Error with requestId " + utils.GenerateShortID(),
+			Detail: "Error with requestId " + requestID,
 		},
 		Error: message,
 	}
 }
 
+// NewQuotaExceededResponse creates a new storage quota exceeded response
+func NewQuotaExceededResponse(exceeded *quota.ExceededError, code int16, requestID string) QuotaExceededResponse {
+	return QuotaExceededResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error:          quota.ErrStorageQuotaExceeded.Error(),
+		UsedBytes:      exceeded.UsedBytes,
+		LimitBytes:     exceeded.LimitBytes,
+		RemainingBytes: exceeded.RemainingBytes(),
+		RequestedBytes: exceeded.RequestedBytes,
+	}
+}
+
 // NewSuccessResponse creates a new success response
-func NewSuccessResponse(message string, code int16) SuccessResponse {
+func NewSuccessResponse(message string, code int16, requestID string) SuccessResponse {
 	return SuccessResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Message: message,
 	}
 }
 
 // NewDriveResponse creates a new drive data response
-func NewDriveResponse(drive interface{}, code int16) DriveResponse {
+func NewDriveResponse(drive interface{}, code int16, requestID string) DriveResponse {
 	return DriveResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Drive: drive,
 	}
 }
 
-// NewValidationError creates a validation error response
-func NewValidationError(err error, code int16) ErrorResponse {
-	if errs, ok := err.(validator.ValidationErrors); ok && len(errs) > 0 {
-		full := errs[0].Error()
-		parts := strings.SplitN(full, "Error:", 2)
-		message := full
-		if len(parts) == 2 {
-			message = strings.TrimSpace(parts[1])
-		}
-		return NewErrorResponse(message, code)
-	}
-	return NewErrorResponse("Invalid request format", code)
+// NewValidationError creates a validation error response with every field error of the payload
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	response := NewErrorResponse(validation.Message(err), code, requestID)
+	response.Errors = validation.Errors(err)
+	return response
 }
 
 // FileProperties represents file-specific properties
@@ -146,6 +163,8 @@ type DriveItemResponseData struct {
 	CreatedAt               int64             `json:"createdAt"`
 	ModifiedAt              int64             `json:"modifiedAt"`
 	IsTrashed               bool              `json:"isTrashed"`
+	TrashedAt               *int64            `json:"trashedAt,omitempty"`
+	PurgeAt                 *int64            `json:"purgeAt,omitempty"`
 	Permissions             int               `json:"permissions"`
 	PermissionExpiresAt     *int64            `json:"permissionExpiresAt,omitempty"`
 	ExpirationTime          *int64            `json:"expirationTime,omitempty"`
@@ -174,12 +193,12 @@ type FolderResponse struct {
 }
 
 // NewDriveItemResponse creates a response based on the item type
-func NewDriveItemResponse(item *models.DriveItem, code int16) interface{} {
+func NewDriveItemResponse(item *models.DriveItem, code int16, requestID string) interface{} {
 	if item == nil {
 		return ErrorResponse{
 			BaseResponse: BaseResponse{
 				Code:   code,
-				Detail: "Error with requestId " + utils.GenerateShortID(),
+				Detail: "Error with requestId " + requestID,
 			},
 			Error: "Item not found",
 		}
@@ -191,7 +210,7 @@ func NewDriveItemResponse(item *models.DriveItem, code int16) interface{} {
 		return FolderResponse{
 			BaseResponse: BaseResponse{
 				Code:   code,
-				Detail: "Success with requestId " + utils.GenerateShortID(),
+				Detail: "Success with requestId " + requestID,
 			},
 			Folder: convertToDriveItemResponseData(item),
 		}
@@ -199,7 +218,7 @@ func NewDriveItemResponse(item *models.DriveItem, code int16) interface{} {
 		return FileResponse{
 			BaseResponse: BaseResponse{
 				Code:   code,
-				Detail: "Success with requestId " + utils.GenerateShortID(),
+				Detail: "Success with requestId " + requestID,
 			},
 			File: convertToDriveItemResponseData(item),
 		}
@@ -207,7 +226,7 @@ func NewDriveItemResponse(item *models.DriveItem, code int16) interface{} {
 		return DriveResponse{
 			BaseResponse: BaseResponse{
 				Code:   code,
-				Detail: "Success with requestId " + utils.GenerateShortID(),
+				Detail: "Success with requestId " + requestID,
 			},
 			Drive: convertToDriveItemResponseData(item),
 		}
@@ -215,11 +234,11 @@ func NewDriveItemResponse(item *models.DriveItem, code int16) interface{} {
 }
 
 // For backward compatibility
-func NewFolderResponse(folder *models.DriveItem, code int16) FolderResponse {
+func NewFolderResponse(folder *models.DriveItem, code int16, requestID string) FolderResponse {
 	return FolderResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Folder: convertToDriveItemResponseData(folder),
 	}
@@ -242,7 +261,7 @@ type PaginationData struct {
 }
 
 // NewFolderContentsResponse creates a new folder contents response
-func NewFolderContentsResponse(items []*models.DriveItem, limit, offset, total int, sortBy, sortDir string, code int16) FolderContentsResponse {
+func NewFolderContentsResponse(items []*models.DriveItem, limit, offset, total int, sortBy, sortDir string, code int16, requestID string) FolderContentsResponse {
 	// For small datasets, process sequentially to avoid goroutine overhead
 	if len(items) < 50 {
 		responseItems := make([]*DriveItemResponseData, len(items))
@@ -253,7 +272,7 @@ func NewFolderContentsResponse(items []*models.DriveItem, limit, offset, total i
 		return FolderContentsResponse{
 			BaseResponse: BaseResponse{
 				Code:   code,
-				Detail: "Success with requestId " + utils.GenerateShortID(),
+				Detail: "Success with requestId " + requestID,
 			},
 			Items: responseItems,
 			Pagination: PaginationData{
@@ -305,7 +324,7 @@ func NewFolderContentsResponse(items []*models.DriveItem, limit, offset, total i
 	return FolderContentsResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Items: responseItems,
 		Pagination: PaginationData{
@@ -336,6 +355,7 @@ func convertToDriveItemResponseData(item *models.DriveItem) *DriveItemResponseDa
 		MimeType:                item.MimeType,
 		Hash:                    item.Hash,
 		Size:                    item.Size,
+		TotalSize:               item.TotalSize,
 		SignatureEmail:          item.SignatureEmail,
 		NodeKey:                 item.NodeKey,
 		NodePassphrase:          item.NodePassphrase,
@@ -344,7 +364,10 @@ func convertToDriveItemResponseData(item *models.DriveItem) *DriveItemResponseDa
 		ModifiedAt:              item.ModifiedAt,
 		Permissions:             item.Permissions,
 		IsTrashed:               item.IsTrashed,
+		TrashedAt:               item.TrashedAt,
+		PurgeAt:                 item.PurgeAt,
 		IsShared:                item.IsShared,
+		XAttr:                   item.Xattrs,
 	}
 
 	// Add type-specific properties based on the item type
@@ -385,6 +408,7 @@ type MembershipResponseData struct {
 	CreatedAt           int64  `json:"createdAt"`
 	ModifiedAt          int64  `json:"modifiedAt"`
 	CanUnlock           *bool  `json:"canUnlock"`
+	PermissionExpiresAt *int64 `json:"permissionExpiresAt,omitempty"`
 }
 
 // convertToMembershipResponseData converts a DriveShareMembership model to response data
@@ -412,6 +436,7 @@ func convertToMembershipResponseData(membership *models.DriveShareMembership) *M
 		CreatedAt:           membership.CreatedAt,
 		ModifiedAt:          membership.ModifiedAt,
 		CanUnlock:           canUnlock,
+		PermissionExpiresAt: membership.PermissionExpiresAt,
 	}
 }
 
@@ -424,6 +449,7 @@ type ShareResponseData struct {
 	State                    int                       `json:"state"`
 	Creator                  string                    `json:"creator"`
 	Locked                   bool                      `json:"locked"`
+	LockedAt                 *int64                    `json:"lockedAt,omitempty"`
 	CreatedAt                int64                     `json:"createdAt"`
 	ModifiedAt               int64                     `json:"modifiedAt"`
 	LinkId                   string                    `json:"linkId"`
@@ -433,6 +459,8 @@ type ShareResponseData struct {
 	ShareKey                 string                    `json:"shareKey,omitempty"`
 	SharePassphrase          string                    `json:"sharePassphrase,omitempty"`
 	SharePassphraseSignature string                    `json:"sharePassphraseSignature,omitempty"`
+	RequiresApproval         bool                      `json:"requiresApproval"`
+	ExpiresAt                *int64                    `json:"expiresAt,omitempty"`
 	IsOwner                  bool                      `json:"isOwner"`
 	Memberships              []*MembershipResponseData `json:"memberships,omitempty"`
 }
@@ -445,7 +473,7 @@ type SharesListResponse struct {
 }
 
 // NewSharesListResponse creates a response for a list of shares
-func NewSharesListResponse(shares []*models.DriveShare, limit, offset, total int, userID string, code int16) SharesListResponse {
+func NewSharesListResponse(shares []*models.DriveShare, limit, offset, total int, userID string, code int16, requestID string) SharesListResponse {
 	responseShares := make([]*ShareResponseData, 0, len(shares))
 
 	for _, share := range shares {
@@ -465,7 +493,7 @@ func NewSharesListResponse(shares []*models.DriveShare, limit, offset, total int
 	return SharesListResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Shares: responseShares,
 		Pagination: PaginationData{
@@ -490,6 +518,7 @@ func convertToShareResponseData(share *models.DriveShare) *ShareResponseData {
 		State:                    share.State,
 		Creator:                  share.Creator,
 		Locked:                   share.Locked,
+		LockedAt:                 share.LockedAt,
 		CreatedAt:                share.CreatedAt,
 		ModifiedAt:               share.ModifiedAt,
 		LinkId:                   share.LinkID,
@@ -499,6 +528,8 @@ func convertToShareResponseData(share *models.DriveShare) *ShareResponseData {
 		ShareKey:                 share.ShareKey,
 		SharePassphrase:          share.SharePassphrase,
 		SharePassphraseSignature: share.SharePassphraseSignature,
+		RequiresApproval:         share.RequiresApproval,
+		ExpiresAt:                share.ExpiresAt,
 		IsOwner:                  false, // Will be set based on current user
 	}
 }
@@ -510,7 +541,7 @@ type ShareWithMembershipsResponse struct {
 }
 
 // NewShareWithMembershipsResponse creates a response with a share and its memberships
-func NewShareWithMembershipsResponse(share *models.DriveShare, memberships []*models.DriveShareMembership, userID string, code int16) ShareWithMembershipsResponse {
+func NewShareWithMembershipsResponse(share *models.DriveShare, memberships []*models.DriveShareMembership, userID string, code int16, requestID string) ShareWithMembershipsResponse {
 	// Convert memberships directly without duplicate checking
 	responseMembers := make([]*MembershipResponseData, 0, len(memberships))
 
@@ -534,12 +565,40 @@ func NewShareWithMembershipsResponse(share *models.DriveShare, memberships []*mo
 	return ShareWithMembershipsResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Share: shareData,
 	}
 }
 
+// BatchLinksResponse represents a response for multiple links, sorted by whether they were found
+type BatchLinksResponse struct {
+	BaseResponse
+	Links     map[string]*DriveItemResponseData `json:"links"`
+	Missing   []string                          `json:"missing"`
+	Forbidden []string                          `json:"forbidden"`
+	Count     int                               `json:"count"`
+}
+
+// NewBatchLinksResponse creates a response for a batch link lookup
+func NewBatchLinksResponse(batch *drive.LinkBatch, code int16, requestID string) BatchLinksResponse {
+	links := make(map[string]*DriveItemResponseData, len(batch.Found))
+	for linkID, item := range batch.Found {
+		links[linkID] = convertToDriveItemResponseData(item)
+	}
+
+	return BatchLinksResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Links:     links,
+		Missing:   batch.Missing,
+		Forbidden: batch.Forbidden,
+		Count:     len(links),
+	}
+}
+
 // BatchSharesRequest is the request structure for batch retrieving shares
 type BatchSharesRequest struct {
 	ShareIDs []string `json:"shareIds" binding:"required,min=1,max=50"`
@@ -553,7 +612,7 @@ type BatchSharesResponse struct {
 }
 
 // NewBatchSharesResponse creates a response for multiple shares with their memberships
-func NewBatchSharesResponse(sharesWithMemberships map[string]*drive.ShareWithMemberships, userID string, code int16) BatchSharesResponse {
+func NewBatchSharesResponse(sharesWithMemberships map[string]*drive.ShareWithMemberships, userID string, code int16, requestID string) BatchSharesResponse {
 	// Convert to response format
 	responseShares := make(map[string]*ShareResponseData)
 
@@ -588,9 +647,1297 @@ func NewBatchSharesResponse(sharesWithMemberships map[string]*drive.ShareWithMem
 	return BatchSharesResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Shares: responseShares,
 		Count:  len(responseShares),
 	}
 }
+
+// SearchKeyStateResponseData represents a user's search key state
+type SearchKeyStateResponseData struct {
+	KeyVersion         int    `json:"keyVersion"`
+	PendingKeyVersion  *int   `json:"pendingKeyVersion,omitempty"`
+	ReindexStartedAt   *int64 `json:"reindexStartedAt,omitempty"`
+	ReindexCompletedAt *int64 `json:"reindexCompletedAt,omitempty"`
+}
+
+// SearchKeyStateResponse represents the search key state response
+type SearchKeyStateResponse struct {
+	BaseResponse
+	SearchKey SearchKeyStateResponseData `json:"searchKey"`
+}
+
+// ReindexBatchResponse represents the items a client must re-tokenize
+type ReindexBatchResponse struct {
+	BaseResponse
+	Items  []*DriveItemResponseData `json:"items"`
+	Status *drive.ReindexStatus     `json:"status"`
+}
+
+// ReindexStatusResponse represents the status of a search key rotation
+type ReindexStatusResponse struct {
+	BaseResponse
+	Status *drive.ReindexStatus `json:"status"`
+}
+
+// NewSearchKeyStateResponse creates a new search key state response
+func NewSearchKeyStateResponse(state *models.DriveSearchKeyState, code int16, requestID string) SearchKeyStateResponse {
+	return SearchKeyStateResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		SearchKey: SearchKeyStateResponseData{
+			KeyVersion:         state.KeyVersion,
+			PendingKeyVersion:  state.PendingKeyVersion,
+			ReindexStartedAt:   state.ReindexStartedAt,
+			ReindexCompletedAt: state.ReindexCompletedAt,
+		},
+	}
+}
+
+// NewReindexBatchResponse creates a new reindex batch response
+func NewReindexBatchResponse(items []*models.DriveItem, status *drive.ReindexStatus, code int16, requestID string) ReindexBatchResponse {
+	responseItems := make([]*DriveItemResponseData, len(items))
+	for i, item := range items {
+		responseItems[i] = convertToDriveItemResponseData(item)
+	}
+
+	return ReindexBatchResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Items:  responseItems,
+		Status: status,
+	}
+}
+
+// NewReindexStatusResponse creates a new reindex status response
+func NewReindexStatusResponse(status *drive.ReindexStatus, code int16, requestID string) ReindexStatusResponse {
+	return ReindexStatusResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Status: status,
+	}
+}
+
+// RevisionResponseData represents a file revision
+type RevisionResponseData struct {
+	ID             string `json:"id"`
+	ItemId         string `json:"itemId"`
+	Size           int64  `json:"size"`
+	State          int    `json:"state"`
+	SignatureEmail string `json:"signatureEmail"`
+	CreatedAt      int64  `json:"createdAt"`
+	ModifiedAt     int64  `json:"modifiedAt"`
+	PausedAt       *int64 `json:"pausedAt"`
+}
+
+// RevisionResponse represents the response for an upload state change of a draft revision
+type RevisionResponse struct {
+	BaseResponse
+	Revision RevisionResponseData `json:"revision"`
+}
+
+// CreateFileResponse represents the response for a newly registered file
+type CreateFileResponse struct {
+	BaseResponse
+	File     *DriveItemResponseData `json:"file"`
+	Revision RevisionResponseData   `json:"revision"`
+}
+
+// BlockUploadResponseData represents a presigned upload target for a block
+type BlockUploadResponseData struct {
+	BlockId   string                        `json:"blockId"`
+	Index     int                           `json:"index"`
+	UploadURL string                        `json:"uploadUrl,omitempty"`
+	Headers   map[string]string             `json:"headers,omitempty"`
+	Parts     []BlockUploadPartResponseData `json:"parts,omitempty"`
+}
+
+// BlockUploadPartResponseData represents a presigned upload target for one part of a block uploaded in
+// parts. Parts are uploaded in any order and assembled when the revision is committed.
+type BlockUploadPartResponseData struct {
+	Number    int               `json:"number"`
+	Size      int64             `json:"size"`
+	UploadURL string            `json:"uploadUrl"`
+	Headers   map[string]string `json:"headers"`
+}
+
+// BlockUploadsResponse represents the response for a block upload request
+type BlockUploadsResponse struct {
+	BaseResponse
+	Blocks []BlockUploadResponseData `json:"blocks"`
+}
+
+// UploadStatusResponse represents the blocks of a draft revision storage has received
+type UploadStatusResponse struct {
+	BaseResponse
+	Revision RevisionResponseData `json:"revision"`
+	Received []int                `json:"received"`
+	Missing  []int                `json:"missing"`
+	Corrupt  []int                `json:"corrupt"`
+	Verified bool                 `json:"verified"`
+}
+
+// ThumbnailUploadResponse represents a presigned upload target for a revision thumbnail.
+// All headers must be sent with the upload; they include the encryption key for organizations
+// with a customer-managed key.
+type ThumbnailUploadResponse struct {
+	BaseResponse
+	Type      int               `json:"type"`
+	UploadURL string            `json:"uploadUrl"`
+	Headers   map[string]string `json:"headers"`
+}
+
+// NewCreateFileResponse creates a new create file response
+func NewCreateFileResponse(file *models.DriveItem, revision *models.FileRevision, code int16, requestID string) CreateFileResponse {
+	return CreateFileResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		File:     convertToDriveItemResponseData(file),
+		Revision: convertToRevisionResponseData(revision),
+	}
+}
+
+// NewRevisionResponse creates a new revision response
+func NewRevisionResponse(revision *models.FileRevision, code int16, requestID string) RevisionResponse {
+	return RevisionResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Revision: convertToRevisionResponseData(revision),
+	}
+}
+
+// convertToRevisionResponseData converts a file revision to its response data
+func convertToRevisionResponseData(revision *models.FileRevision) RevisionResponseData {
+	return RevisionResponseData{
+		ID:             revision.ID,
+		ItemId:         revision.ItemID,
+		Size:           revision.Size,
+		State:          revision.State,
+		SignatureEmail: revision.SignatureEmail,
+		CreatedAt:      revision.CreatedAt,
+		ModifiedAt:     revision.ModifiedAt,
+		PausedAt:       revision.PausedAt,
+	}
+}
+
+// NewBlockUploadsResponse creates a new block uploads response
+func NewBlockUploadsResponse(uploads []*drive.BlockUploadURL, code int16, requestID string) BlockUploadsResponse {
+	blocks := make([]BlockUploadResponseData, len(uploads))
+	for i, upload := range uploads {
+		blocks[i] = BlockUploadResponseData{
+			BlockId:   upload.BlockID,
+			Index:     upload.Index,
+			UploadURL: upload.UploadURL,
+			Headers:   upload.Headers,
+		}
+		for _, part := range upload.Parts {
+			blocks[i].Parts = append(blocks[i].Parts, BlockUploadPartResponseData{
+				Number:    part.Number,
+				Size:      part.Size,
+				UploadURL: part.URL,
+				Headers:   part.Headers,
+			})
+		}
+	}
+
+	return BlockUploadsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Blocks: blocks,
+	}
+}
+
+// NewUploadStatusResponse creates a new upload status response
+func NewUploadStatusResponse(uploadStatus *drive.UploadStatus, code int16, requestID string) UploadStatusResponse {
+	return UploadStatusResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Revision: convertToRevisionResponseData(uploadStatus.Revision),
+		Received: uploadStatus.Received,
+		Missing:  uploadStatus.Missing,
+		Corrupt:  uploadStatus.Corrupt,
+		Verified: uploadStatus.Verified,
+	}
+}
+
+// NewThumbnailUploadResponse creates a new thumbnail upload response
+func NewThumbnailUploadResponse(upload *drive.ThumbnailUploadURL, code int16, requestID string) ThumbnailUploadResponse {
+	return ThumbnailUploadResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Type:      upload.Type,
+		UploadURL: upload.UploadURL,
+		Headers:   upload.Headers,
+	}
+}
+
+// NewFileResponse creates a new file response
+func NewFileResponse(file *models.DriveItem, code int16, requestID string) FileResponse {
+	return FileResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		File: convertToDriveItemResponseData(file),
+	}
+}
+
+// ShareURLResponseData represents a public link as seen by its owner
+type ShareURLResponseData struct {
+	ID          string  `json:"id"`
+	ShareId     string  `json:"shareId"`
+	LinkId      string  `json:"linkId"`
+	Token       string  `json:"token"`
+	Slug        *string `json:"slug,omitempty"`
+	URL         string  `json:"url"`
+	Permissions int     `json:"permissions"`
+	NumAccesses int     `json:"numAccesses"`
+	ExpiresAt   *int64  `json:"expiresAt,omitempty"`
+	CreatedAt   int64   `json:"createdAt"`
+}
+
+// ShareURLResponse represents a public link response
+type ShareURLResponse struct {
+	BaseResponse
+	ShareURL ShareURLResponseData `json:"shareUrl"`
+}
+
+// PublicShareURLResponseData represents what an anonymous visitor needs to open a public link
+type PublicShareURLResponseData struct {
+	ShareId                  string `json:"shareId"`
+	LinkId                   string `json:"linkId"`
+	Permissions              int    `json:"permissions"`
+	SharePasswordSalt        string `json:"sharePasswordSalt"`
+	SharePassphraseKeyPacket string `json:"sharePassphraseKeyPacket"`
+	ExpiresAt                *int64 `json:"expiresAt,omitempty"`
+}
+
+// PublicShareURLResponse represents a resolved public link response
+type PublicShareURLResponse struct {
+	BaseResponse
+	ShareURL PublicShareURLResponseData `json:"shareUrl"`
+}
+
+// NewShareURLResponse creates a new public link response
+func NewShareURLResponse(shareURL *models.DriveShareURL, address string, code int16, requestID string) ShareURLResponse {
+	return ShareURLResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		ShareURL: ShareURLResponseData{
+			ID:          shareURL.ID,
+			ShareId:     shareURL.ShareID,
+			LinkId:      shareURL.ItemID,
+			Token:       shareURL.Token,
+			Slug:        shareURL.Slug,
+			URL:         address,
+			Permissions: shareURL.Permissions,
+			NumAccesses: shareURL.NumAccesses,
+			ExpiresAt:   shareURL.ExpiresAt,
+			CreatedAt:   shareURL.CreatedAt,
+		},
+	}
+}
+
+// NewPublicShareURLResponse creates a new resolved public link response
+func NewPublicShareURLResponse(shareURL *models.DriveShareURL, code int16, requestID string) PublicShareURLResponse {
+	return PublicShareURLResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		ShareURL: PublicShareURLResponseData{
+			ShareId:                  shareURL.ShareID,
+			LinkId:                   shareURL.ItemID,
+			Permissions:              shareURL.Permissions,
+			SharePasswordSalt:        shareURL.SharePasswordSalt,
+			SharePassphraseKeyPacket: shareURL.SharePassphraseKeyPacket,
+			ExpiresAt:                shareURL.ExpiresAt,
+		},
+	}
+}
+
+// BlockDownloadResponseData represents a presigned download source for a block
+type BlockDownloadResponseData struct {
+	Index              int    `json:"index"`
+	Size               int64  `json:"size"`
+	Hash               string `json:"hash"`
+	KeyPacket          string `json:"keyPacket,omitempty"`
+	KeyPacketSignature string `json:"keyPacketSignature,omitempty"`
+	DownloadURL        string `json:"downloadUrl"`
+}
+
+// DownloadRevisionResponseData represents the revision being downloaded
+type DownloadRevisionResponseData struct {
+	RevisionResponseData
+	ManifestSignature string `json:"manifestSignature"`
+}
+
+// FileDownloadResponse represents the response for a file download request
+type FileDownloadResponse struct {
+	BaseResponse
+	File      *DriveItemResponseData       `json:"file"`
+	Revision  DownloadRevisionResponseData `json:"revision"`
+	Blocks    []BlockDownloadResponseData  `json:"blocks"`
+	ExpiresAt int64                        `json:"expiresAt"`
+}
+
+// NewFileDownloadResponse creates a new file download response
+func NewFileDownloadResponse(download *drive.FileDownload, code int16, requestID string) FileDownloadResponse {
+	blocks := make([]BlockDownloadResponseData, len(download.Blocks))
+	for i, block := range download.Blocks {
+		blocks[i] = BlockDownloadResponseData{
+			Index:              block.Index,
+			Size:               block.Size,
+			Hash:               block.Hash,
+			KeyPacket:          block.KeyPacket,
+			KeyPacketSignature: block.KeyPacketSignature,
+			DownloadURL:        block.DownloadURL,
+		}
+	}
+
+	revision := download.Revision
+	return FileDownloadResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		File: convertToDriveItemResponseData(download.File),
+		Revision: DownloadRevisionResponseData{
+			RevisionResponseData: RevisionResponseData{
+				ID:             revision.ID,
+				ItemId:         revision.ItemID,
+				Size:           revision.Size,
+				State:          revision.State,
+				SignatureEmail: revision.SignatureEmail,
+				CreatedAt:      revision.CreatedAt,
+			},
+			ManifestSignature: revision.ManifestSignature,
+		},
+		Blocks:    blocks,
+		ExpiresAt: download.ExpiresAt,
+	}
+}
+
+// DuplicateResponseData represents a candidate that already exists in the folder
+type DuplicateResponseData struct {
+	ContentHash string `json:"contentHash"`
+	Size        int64  `json:"size"`
+	LinkId      string `json:"linkId"`
+}
+
+// DuplicatesResponse represents the result of a pre-upload duplicate check
+type DuplicatesResponse struct {
+	BaseResponse
+	Duplicates []DuplicateResponseData `json:"duplicates"`
+}
+
+// NewDuplicatesResponse creates a new duplicates response
+func NewDuplicatesResponse(matches []*drive.DuplicateMatch, code int16, requestID string) DuplicatesResponse {
+	duplicates := make([]DuplicateResponseData, len(matches))
+	for i, match := range matches {
+		duplicates[i] = DuplicateResponseData{
+			ContentHash: match.ContentHash,
+			Size:        match.Size,
+			LinkId:      match.LinkID,
+		}
+	}
+
+	return DuplicatesResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Duplicates: duplicates,
+	}
+}
+
+// BatchItemResponseData represents the outcome of a batch operation for one link
+type BatchItemResponseData struct {
+	LinkId        string `json:"linkId"`
+	CreatedLinkId string `json:"createdLinkId,omitempty"`
+	Code          int16  `json:"code"`
+	Error         string `json:"error,omitempty"`
+}
+
+// BatchResultsResponse represents the per-link outcomes of a batch operation
+type BatchResultsResponse struct {
+	BaseResponse
+	Responses []BatchItemResponseData `json:"responses"`
+}
+
+// EmptyTrashResponse represents the result of emptying the trash
+type EmptyTrashResponse struct {
+	BaseResponse
+	DeletedItems  int64 `json:"deletedItems"`
+	ReleasedBytes int64 `json:"releasedBytes"`
+}
+
+// NewBatchResultsResponse creates a new batch results response
+func NewBatchResultsResponse(responses []BatchItemResponseData, code int16, requestID string) BatchResultsResponse {
+	return BatchResultsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Responses: responses,
+	}
+}
+
+// NewEmptyTrashResponse creates a new empty trash response
+func NewEmptyTrashResponse(result *drive.EmptyTrashResult, code int16, requestID string) EmptyTrashResponse {
+	return EmptyTrashResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		DeletedItems:  result.DeletedItems,
+		ReleasedBytes: result.ReleasedBytes,
+	}
+}
+
+// InvitationResponse represents a share invitation response
+type InvitationResponse struct {
+	BaseResponse
+	Invitation *MembershipResponseData `json:"invitation"`
+}
+
+// InvitationsResponse represents a list of pending share invitations
+type InvitationsResponse struct {
+	BaseResponse
+	Invitations []*MembershipResponseData `json:"invitations"`
+}
+
+// NewInvitationResponse creates a new share invitation response
+func NewInvitationResponse(invitation *models.DriveShareMembership, code int16, requestID string) InvitationResponse {
+	return InvitationResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Invitation: convertToMembershipResponseData(invitation),
+	}
+}
+
+// NewInvitationsResponse creates a new pending share invitations response
+func NewInvitationsResponse(invitations []*models.DriveShareMembership, code int16, requestID string) InvitationsResponse {
+	data := make([]*MembershipResponseData, len(invitations))
+	for i, invitation := range invitations {
+		data[i] = convertToMembershipResponseData(invitation)
+	}
+
+	return InvitationsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Invitations: data,
+	}
+}
+
+// JobResponseData represents a background job in responses
+type JobResponseData struct {
+	ID          string           `json:"id"`
+	Type        string           `json:"type"`
+	State       string           `json:"state"`
+	Total       int64            `json:"total"`
+	Processed   int64            `json:"processed"`
+	Result      map[string]int64 `json:"result,omitempty"`
+	Error       *string          `json:"error,omitempty"`
+	StartedAt   *int64           `json:"startedAt,omitempty"`
+	CompletedAt *int64           `json:"completedAt,omitempty"`
+	CreatedAt   int64            `json:"createdAt"`
+	ModifiedAt  int64            `json:"modifiedAt"`
+}
+
+// JobResponse represents a background job status response
+type JobResponse struct {
+	BaseResponse
+	Job *JobResponseData `json:"job"`
+}
+
+// NewJobResponse creates a new background job status response
+func NewJobResponse(job *models.Job, code int16, requestID string) JobResponse {
+	return JobResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Job: &JobResponseData{
+			ID:          job.ID,
+			Type:        job.Type,
+			State:       job.State,
+			Total:       job.Total,
+			Processed:   job.Processed,
+			Result:      job.Result,
+			Error:       job.Error,
+			StartedAt:   job.StartedAt,
+			CompletedAt: job.CompletedAt,
+			CreatedAt:   job.CreatedAt,
+			ModifiedAt:  job.ModifiedAt,
+		},
+	}
+}
+
+// EventResponseData represents a drive change event in responses
+type EventResponseData struct {
+	Type      int     `json:"type"`
+	LinkID    string  `json:"linkId"`
+	LinkType  int     `json:"linkType"`
+	ShareID   string  `json:"shareId"`
+	ParentID  *string `json:"parentId,omitempty"`
+	CreatedAt int64   `json:"createdAt"`
+}
+
+// EventsResponse represents a page of drive change events
+type EventsResponse struct {
+	BaseResponse
+	Events []EventResponseData `json:"events"`
+	Cursor string              `json:"cursor"`
+	More   bool                `json:"more"`
+}
+
+// NewEventsResponse creates a new drive events response
+func NewEventsResponse(page *drive.EventPage, code int16, requestID string) EventsResponse {
+	events := make([]EventResponseData, len(page.Events))
+	for i, event := range page.Events {
+		events[i] = EventResponseData{
+			Type:      event.Type,
+			LinkID:    event.LinkID,
+			LinkType:  event.LinkType,
+			ShareID:   event.ShareID,
+			ParentID:  event.ParentID,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+
+	return EventsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Events: events,
+		Cursor: page.Cursor,
+		More:   page.More,
+	}
+}
+
+// MembershipResponse represents a single share membership response
+type MembershipResponse struct {
+	BaseResponse
+	Membership *MembershipResponseData `json:"membership"`
+}
+
+// MembershipsResponse represents a list of share memberships
+type MembershipsResponse struct {
+	BaseResponse
+	Memberships []*MembershipResponseData `json:"memberships"`
+}
+
+// NewMembershipResponse creates a new share membership response
+func NewMembershipResponse(membership *models.DriveShareMembership, code int16, requestID string) MembershipResponse {
+	return MembershipResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Membership: convertToMembershipResponseData(membership),
+	}
+}
+
+// NewMembershipsResponse creates a new share memberships list response
+func NewMembershipsResponse(memberships []*models.DriveShareMembership, code int16, requestID string) MembershipsResponse {
+	data := make([]*MembershipResponseData, len(memberships))
+	for i, membership := range memberships {
+		data[i] = convertToMembershipResponseData(membership)
+	}
+
+	return MembershipsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Memberships: data,
+	}
+}
+
+// StorageUsageResponseData represents the bytes stored in one storage class and placement
+type StorageUsageResponseData struct {
+	StorageClass      string `json:"storageClass"`
+	Region            string `json:"region"`
+	ReplicationRegion string `json:"replicationRegion,omitempty"`
+	Bytes             int64  `json:"bytes"`
+	BlockCount        int64  `json:"blockCount"`
+}
+
+// VolumeStorageResponse represents the storage composition of a volume
+type VolumeStorageResponse struct {
+	BaseResponse
+	VolumeID               string                     `json:"volumeId"`
+	TotalBytes             int64                      `json:"totalBytes"`
+	StandardBytes          int64                      `json:"standardBytes"`
+	ArchivedBytes          int64                      `json:"archivedBytes"`
+	ReplicatedBytes        int64                      `json:"replicatedBytes"`
+	CrossRegionReplication bool                       `json:"crossRegionReplication"`
+	Usage                  []StorageUsageResponseData `json:"usage"`
+}
+
+// NewVolumeStorageResponse creates a new volume storage response
+func NewVolumeStorageResponse(report *drive.VolumeStorageReport, code int16, requestID string) VolumeStorageResponse {
+	usage := make([]StorageUsageResponseData, len(report.Usage))
+	for i, u := range report.Usage {
+		usage[i] = StorageUsageResponseData{
+			StorageClass:      u.StorageClass,
+			Region:            u.StorageRegion,
+			ReplicationRegion: u.ReplicationRegion,
+			Bytes:             u.Bytes,
+			BlockCount:        u.BlockCount,
+		}
+	}
+
+	return VolumeStorageResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		VolumeID:               report.VolumeID,
+		TotalBytes:             report.TotalBytes,
+		StandardBytes:          report.StandardBytes,
+		ArchivedBytes:          report.ArchivedBytes,
+		ReplicatedBytes:        report.ReplicatedBytes,
+		CrossRegionReplication: report.CrossRegionReplication,
+		Usage:                  usage,
+	}
+}
+
+// VolumeReplicationResponse represents a volume's replication setting
+type VolumeReplicationResponse struct {
+	BaseResponse
+	VolumeID               string `json:"volumeId"`
+	CrossRegionReplication bool   `json:"crossRegionReplication"`
+}
+
+// NewVolumeReplicationResponse creates a new volume replication response
+func NewVolumeReplicationResponse(volume *models.DriveVolume, code int16, requestID string) VolumeReplicationResponse {
+	return VolumeReplicationResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		VolumeID:               volume.ID,
+		CrossRegionReplication: volume.CrossRegionReplication,
+	}
+}
+
+// RegionEndpointResponseData represents a regional endpoint in API responses
+type RegionEndpointResponseData struct {
+	Region  string `json:"region"`
+	APIHost string `json:"apiHost"`
+	CDNHost string `json:"cdnHost,omitempty"`
+}
+
+// VolumeEndpointsResponse represents the regional endpoints a client should use for a volume
+type VolumeEndpointsResponse struct {
+	BaseResponse
+	VolumeID        string                     `json:"volumeId"`
+	ClientContinent string                     `json:"clientContinent,omitempty"`
+	Upload          RegionEndpointResponseData `json:"upload"`
+	Download        RegionEndpointResponseData `json:"download"`
+}
+
+// NewVolumeEndpointsResponse creates a new volume endpoints response
+func NewVolumeEndpointsResponse(endpoints *drive.VolumeEndpoints, code int16, requestID string) VolumeEndpointsResponse {
+	return VolumeEndpointsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		VolumeID:        endpoints.VolumeID,
+		ClientContinent: endpoints.ClientContinent,
+		Upload: RegionEndpointResponseData{
+			Region:  endpoints.Upload.Region,
+			APIHost: endpoints.Upload.APIHost,
+			CDNHost: endpoints.Upload.CDNHost,
+		},
+		Download: RegionEndpointResponseData{
+			Region:  endpoints.Download.Region,
+			APIHost: endpoints.Download.APIHost,
+			CDNHost: endpoints.Download.CDNHost,
+		},
+	}
+}
+
+// TagResponseData represents a tag in API responses
+type TagResponseData struct {
+	ID            string `json:"id"`
+	EncryptedName string `json:"encryptedName"`
+	Color         string `json:"color,omitempty"`
+	CreatedAt     int64  `json:"createdAt"`
+	ModifiedAt    int64  `json:"modifiedAt"`
+}
+
+// TagResponse represents a response with a single tag
+type TagResponse struct {
+	BaseResponse
+	Tag TagResponseData `json:"tag"`
+}
+
+// TagsResponse represents a response with the user's tags
+type TagsResponse struct {
+	BaseResponse
+	Tags []TagResponseData `json:"tags"`
+}
+
+// ItemTagsResponse represents the caller's tags on an item
+type ItemTagsResponse struct {
+	BaseResponse
+	LinkID string   `json:"linkId"`
+	TagIDs []string `json:"tagIds"`
+}
+
+// newTagResponseData converts a tag to its response form
+func newTagResponseData(tag *models.DriveTag) TagResponseData {
+	return TagResponseData{
+		ID:            tag.ID,
+		EncryptedName: tag.EncryptedName,
+		Color:         tag.Color,
+		CreatedAt:     tag.CreatedAt,
+		ModifiedAt:    tag.ModifiedAt,
+	}
+}
+
+// NewTagResponse creates a new tag response
+func NewTagResponse(tag *models.DriveTag, code int16, requestID string) TagResponse {
+	return TagResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Tag: newTagResponseData(tag),
+	}
+}
+
+// NewTagsResponse creates a new tags response
+func NewTagsResponse(tags []*models.DriveTag, code int16, requestID string) TagsResponse {
+	data := make([]TagResponseData, len(tags))
+	for i, tag := range tags {
+		data[i] = newTagResponseData(tag)
+	}
+
+	return TagsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Tags: data,
+	}
+}
+
+// NewItemTagsResponse creates a new item tags response
+func NewItemTagsResponse(linkID string, tagIDs []string, code int16, requestID string) ItemTagsResponse {
+	return ItemTagsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		LinkID: linkID,
+		TagIDs: tagIDs,
+	}
+}
+
+// BackupSetResponseData represents a backup set in API responses
+type BackupSetResponseData struct {
+	ID            string `json:"id"`
+	DeviceID      string `json:"deviceId"`
+	ShareID       string `json:"shareId"`
+	RootLinkID    string `json:"rootLinkId"`
+	EncryptedName string `json:"encryptedName"`
+	MaxVersions   int    `json:"maxVersions"`
+	RetentionDays int    `json:"retentionDays"`
+	LastBackupAt  *int64 `json:"lastBackupAt"`
+	CreatedAt     int64  `json:"createdAt"`
+	ModifiedAt    int64  `json:"modifiedAt"`
+}
+
+// BackupSetResponse represents a response with a single backup set
+type BackupSetResponse struct {
+	BaseResponse
+	BackupSet BackupSetResponseData `json:"backupSet"`
+}
+
+// BackupSetsResponse represents a response with the user's backup sets
+type BackupSetsResponse struct {
+	BaseResponse
+	BackupSets []BackupSetResponseData `json:"backupSets"`
+}
+
+// newBackupSetResponseData converts a backup set to its response form
+func newBackupSetResponseData(set *models.DriveBackupSet) BackupSetResponseData {
+	return BackupSetResponseData{
+		ID:            set.ID,
+		DeviceID:      set.DeviceID,
+		ShareID:       set.ShareID,
+		RootLinkID:    set.Share.LinkID,
+		EncryptedName: set.EncryptedName,
+		MaxVersions:   set.MaxVersions,
+		RetentionDays: set.RetentionDays,
+		LastBackupAt:  set.LastBackupAt,
+		CreatedAt:     set.CreatedAt,
+		ModifiedAt:    set.ModifiedAt,
+	}
+}
+
+// NewBackupSetResponse creates a new backup set response
+func NewBackupSetResponse(set *models.DriveBackupSet, code int16, requestID string) BackupSetResponse {
+	return BackupSetResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		BackupSet: newBackupSetResponseData(set),
+	}
+}
+
+// NewBackupSetsResponse creates a new backup sets response
+func NewBackupSetsResponse(sets []*models.DriveBackupSet, code int16, requestID string) BackupSetsResponse {
+	data := make([]BackupSetResponseData, len(sets))
+	for i, set := range sets {
+		data[i] = newBackupSetResponseData(set)
+	}
+
+	return BackupSetsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		BackupSets: data,
+	}
+}
+
+// BulkFolderResponseData maps a folder of a bulk creation to its link in API responses
+type BulkFolderResponseData struct {
+	TempID  string `json:"tempId"`
+	LinkID  string `json:"linkId"`
+	Created bool   `json:"created"`
+}
+
+// BulkFoldersResponse represents a response with the links of a bulk folder creation
+type BulkFoldersResponse struct {
+	BaseResponse
+	Folders []BulkFolderResponseData `json:"folders"`
+}
+
+// NewBulkFoldersResponse creates a new bulk folders response
+func NewBulkFoldersResponse(results []*drive.BulkFolderResult, code int16, requestID string) BulkFoldersResponse {
+	folders := make([]BulkFolderResponseData, len(results))
+	for i, result := range results {
+		folders[i] = BulkFolderResponseData{
+			TempID:  result.TempID,
+			LinkID:  result.LinkID,
+			Created: result.Created,
+		}
+	}
+
+	return BulkFoldersResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Folders: folders,
+	}
+}
+
+// DeviceSyncStateResponseData represents where a device is in a volume's change log in API responses
+type DeviceSyncStateResponseData struct {
+	DeviceID       string `json:"deviceId"`
+	Cursor         string `json:"cursor"`
+	PendingEvents  int64  `json:"pendingEvents"`
+	AcknowledgedAt int64  `json:"acknowledgedAt"`
+}
+
+// VolumeSyncStateResponseData represents the sync state of a volume's devices in API responses
+type VolumeSyncStateResponseData struct {
+	VolumeID string                        `json:"volumeId"`
+	Cursor   string                        `json:"cursor"`
+	Devices  []DeviceSyncStateResponseData `json:"devices"`
+}
+
+// DeviceSyncStateResponse represents a response with a device's acknowledged sync cursor
+type DeviceSyncStateResponse struct {
+	BaseResponse
+	VolumeID string                      `json:"volumeId"`
+	Device   DeviceSyncStateResponseData `json:"device"`
+}
+
+// SyncStateResponse represents a response with the sync state of the user's devices
+type SyncStateResponse struct {
+	BaseResponse
+	Volumes []VolumeSyncStateResponseData `json:"volumes"`
+}
+
+// newDeviceSyncStateResponseData converts a device's sync state to its response form
+func newDeviceSyncStateResponseData(device *drive.DeviceSyncState) DeviceSyncStateResponseData {
+	return DeviceSyncStateResponseData{
+		DeviceID:       device.DeviceID,
+		Cursor:         device.Cursor,
+		PendingEvents:  device.PendingEvents,
+		AcknowledgedAt: device.AcknowledgedAt,
+	}
+}
+
+// NewDeviceSyncStateResponse creates a new device sync state response
+func NewDeviceSyncStateResponse(volumeID string, device *drive.DeviceSyncState, code int16, requestID string) DeviceSyncStateResponse {
+	return DeviceSyncStateResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		VolumeID: volumeID,
+		Device:   newDeviceSyncStateResponseData(device),
+	}
+}
+
+// NewSyncStateResponse creates a new sync state response
+func NewSyncStateResponse(state *drive.SyncState, code int16, requestID string) SyncStateResponse {
+	volumes := make([]VolumeSyncStateResponseData, len(state.Volumes))
+	for i, volume := range state.Volumes {
+		devices := make([]DeviceSyncStateResponseData, len(volume.Devices))
+		for j, device := range volume.Devices {
+			devices[j] = newDeviceSyncStateResponseData(device)
+		}
+		volumes[i] = VolumeSyncStateResponseData{
+			VolumeID: volume.VolumeID,
+			Cursor:   volume.Cursor,
+			Devices:  devices,
+		}
+	}
+
+	return SyncStateResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Volumes: volumes,
+	}
+}
+
+// LockedSharesResponse represents a response with the locked shares the user can unlock
+type LockedSharesResponse struct {
+	BaseResponse
+	Shares []*ShareResponseData `json:"shares"`
+}
+
+// NewLockedSharesResponse creates a response with locked shares, each with the user's membership
+func NewLockedSharesResponse(shares []*drive.ShareWithMemberships, userID string, code int16, requestID string) LockedSharesResponse {
+	data := make([]*ShareResponseData, 0, len(shares))
+	for _, shareWithMemberships := range shares {
+		shareData := convertToShareResponseData(shareWithMemberships.Share)
+		shareData.IsOwner = shareWithMemberships.Share.UserID == userID
+		for _, membership := range shareWithMemberships.Memberships {
+			shareData.Memberships = append(shareData.Memberships, convertToMembershipResponseData(membership))
+		}
+		data = append(data, shareData)
+	}
+
+	return LockedSharesResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Shares: data,
+	}
+}
+
+// UploadDeferredResponse tells the client an upload step was accepted but must wait for storage to recover
+type UploadDeferredResponse struct {
+	BaseResponse
+	Deferred   bool  `json:"deferred"`
+	RetryAfter int64 `json:"retryAfter"` // Seconds
+}
+
+// ThumbnailResponse represents the encrypted content of a file thumbnail
+type ThumbnailResponse struct {
+	BaseResponse
+	Type               int    `json:"type"`
+	Data               []byte `json:"data"` // Base64 in JSON
+	Hash               string `json:"hash"`
+	ThumbnailSignature string `json:"thumbnailSignature"`
+}
+
+// NewUploadDeferredResponse creates a new deferred upload response
+func NewUploadDeferredResponse(retryAfter int64, code int16, requestID string) UploadDeferredResponse {
+	return UploadDeferredResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Deferred:   true,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewThumbnailResponse creates a new thumbnail response
+func NewThumbnailResponse(thumbnail *drive.Thumbnail, code int16, requestID string) ThumbnailResponse {
+	return ThumbnailResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Type:               thumbnail.Type,
+		Data:               thumbnail.Data,
+		Hash:               thumbnail.Hash,
+		ThumbnailSignature: thumbnail.Signature,
+	}
+}
+
+// RecentItemResponseData represents a recent file with the activity that made it recent
+type RecentItemResponseData struct {
+	*DriveItemResponseData
+	Activity   string `json:"activity"` // opened or modified
+	ActivityAt int64  `json:"activityAt"`
+}
+
+// RecentItemsResponse represents a page of recently opened or modified files
+type RecentItemsResponse struct {
+	BaseResponse
+	Items      []*RecentItemResponseData `json:"items"`
+	Pagination PaginationData            `json:"pagination"`
+}
+
+// NewRecentItemsResponse creates a new recent items response
+func NewRecentItemsResponse(items []*drive.RecentItem, limit, offset, total int, code int16, requestID string) RecentItemsResponse {
+	responseItems := make([]*RecentItemResponseData, len(items))
+	for i, item := range items {
+		responseItems[i] = &RecentItemResponseData{
+			DriveItemResponseData: convertToDriveItemResponseData(item.Item),
+			Activity:              item.Activity,
+			ActivityAt:            item.ActivityAt,
+		}
+	}
+
+	return RecentItemsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Items: responseItems,
+		Pagination: PaginationData{
+			Limit:      limit,
+			Offset:     offset,
+			TotalItems: total,
+		},
+	}
+}
+
+// ItemPathResponse represents the folders from the root of a share down to an item's parent
+type ItemPathResponse struct {
+	BaseResponse
+	Item *DriveItemResponseData   `json:"item"`
+	Path []*DriveItemResponseData `json:"path"` // Root first
+}
+
+// NewItemPathResponse creates a new item path response
+func NewItemPathResponse(path *drive.ItemPath, code int16, requestID string) ItemPathResponse {
+	ancestors := make([]*DriveItemResponseData, len(path.Ancestors))
+	for i, ancestor := range path.Ancestors {
+		ancestors[i] = convertToDriveItemResponseData(ancestor)
+	}
+
+	return ItemPathResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Item: convertToDriveItemResponseData(path.Item),
+		Path: ancestors,
+	}
+}
+
+// ShareUsageData represents the size of one of the user's shares
+type ShareUsageData struct {
+	ShareID   string `json:"shareId"`
+	VolumeID  string `json:"volumeId"`
+	LinkID    string `json:"linkId"`
+	Type      int    `json:"type"`
+	TotalSize int64  `json:"totalSize"`
+	Pending   bool   `json:"pending"` // Recent changes are not counted yet
+}
+
+// ShareUsageResponse represents the sizes of the user's shares
+type ShareUsageResponse struct {
+	BaseResponse
+	Shares []*ShareUsageData `json:"shares"`
+}
+
+// FolderUsageResponse represents a folder's size with its children, largest first
+type FolderUsageResponse struct {
+	BaseResponse
+	Folder     *DriveItemResponseData   `json:"folder"`
+	Items      []*DriveItemResponseData `json:"items"`
+	Pending    bool                     `json:"pending"` // Recent changes are not counted yet
+	Pagination PaginationData           `json:"pagination"`
+}
+
+// NewShareUsageResponse creates a new share usage response
+func NewShareUsageResponse(usage []*drive.ShareUsage, code int16, requestID string) ShareUsageResponse {
+	shares := make([]*ShareUsageData, len(usage))
+	for i, share := range usage {
+		shares[i] = &ShareUsageData{
+			ShareID:   share.Share.ID,
+			VolumeID:  share.Share.VolumeID,
+			LinkID:    share.Share.LinkID,
+			Type:      share.Share.Type,
+			TotalSize: share.TotalSize,
+			Pending:   share.Pending,
+		}
+	}
+
+	return ShareUsageResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Shares: shares,
+	}
+}
+
+// NewFolderUsageResponse creates a new folder usage response
+func NewFolderUsageResponse(usage *drive.FolderUsage, limit, offset int, code int16, requestID string) FolderUsageResponse {
+	items := make([]*DriveItemResponseData, len(usage.Children))
+	for i, child := range usage.Children {
+		items[i] = convertToDriveItemResponseData(child)
+	}
+
+	return FolderUsageResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Folder:  convertToDriveItemResponseData(usage.Folder),
+		Items:   items,
+		Pending: usage.Pending,
+		Pagination: PaginationData{
+			Limit:      limit,
+			Offset:     offset,
+			TotalItems: usage.Total,
+		},
+	}
+}
+
+// KeyRotationShareData represents the progress of a key rotation in one share
+type KeyRotationShareData struct {
+	ShareID           string `json:"shareId"`
+	PassphraseRotated bool   `json:"passphraseRotated"`
+	ItemsRotated      int64  `json:"itemsRotated"`
+	ItemsRemaining    int64  `json:"itemsRemaining"`
+	ItemCursor        string `json:"itemCursor"`
+	CompletedAt       *int64 `json:"completedAt"`
+}
+
+// KeyRotationData represents a key rotation with the progress of each of its shares
+type KeyRotationData struct {
+	ID          string                 `json:"id"`
+	KeyID       string                 `json:"keyId"`
+	State       int                    `json:"state"` // 1=in progress, 2=completed, 3=superseded
+	CreatedAt   int64                  `json:"createdAt"`
+	CompletedAt *int64                 `json:"completedAt"`
+	Shares      []KeyRotationShareData `json:"shares"`
+}
+
+// KeyRotationResponse represents a response with the status of a key rotation
+type KeyRotationResponse struct {
+	BaseResponse
+	Rotation KeyRotationData `json:"rotation"`
+}
+
+// KeyRotationShareResponse represents a response with a share's key rotation progress
+type KeyRotationShareResponse struct {
+	BaseResponse
+	Share KeyRotationShareData `json:"share"`
+}
+
+// KeyRotationItemsResponse represents the next items a client must re-wrap for a key rotation
+type KeyRotationItemsResponse struct {
+	BaseResponse
+	Items []*DriveItemResponseData `json:"items"`
+}
+
+// newKeyRotationShareData converts a share's key rotation progress to its response form
+func newKeyRotationShareData(share *drive.KeyRotationShareStatus) KeyRotationShareData {
+	return KeyRotationShareData{
+		ShareID:           share.Progress.ShareID,
+		PassphraseRotated: share.Progress.PassphraseRotated,
+		ItemsRotated:      share.Progress.ItemsRotated,
+		ItemsRemaining:    share.ItemsRemaining,
+		ItemCursor:        share.Progress.ItemCursor,
+		CompletedAt:       share.Progress.CompletedAt,
+	}
+}
+
+// NewKeyRotationResponse creates a new key rotation status response
+func NewKeyRotationResponse(rotation *drive.KeyRotationStatus, code int16, requestID string) KeyRotationResponse {
+	shares := make([]KeyRotationShareData, len(rotation.Shares))
+	for i, share := range rotation.Shares {
+		shares[i] = newKeyRotationShareData(share)
+	}
+
+	return KeyRotationResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Rotation: KeyRotationData{
+			ID:          rotation.Rotation.ID,
+			KeyID:       rotation.Rotation.KeyID,
+			State:       rotation.Rotation.State,
+			CreatedAt:   rotation.Rotation.CreatedAt,
+			CompletedAt: rotation.Rotation.CompletedAt,
+			Shares:      shares,
+		},
+	}
+}
+
+// NewKeyRotationShareResponse creates a new share key rotation progress response
+func NewKeyRotationShareResponse(share *drive.KeyRotationShareStatus, code int16, requestID string) KeyRotationShareResponse {
+	return KeyRotationShareResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Share: newKeyRotationShareData(share),
+	}
+}
+
+// NewKeyRotationItemsResponse creates a new key rotation items response
+func NewKeyRotationItemsResponse(items []*models.DriveItem, code int16, requestID string) KeyRotationItemsResponse {
+	responseItems := make([]*DriveItemResponseData, len(items))
+	for i, item := range items {
+		responseItems[i] = convertToDriveItemResponseData(item)
+	}
+
+	return KeyRotationItemsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Items: responseItems,
+	}
+}
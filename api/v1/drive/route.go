@@ -1,16 +1,147 @@
 package drive
 
 import (
+	"time"
+
+	"cirrussync-api/internal/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
+// publicLinkCachePolicy keeps anonymous public link traffic at the CDN. Responses can be purged
+// by surrogate key through the admin API, so the shared lifetime can be longer than the browser's.
+var publicLinkCachePolicy = middleware.CachePolicy{
+	MaxAge:               time.Minute,
+	SharedMaxAge:         5 * time.Minute,
+	StaleWhileRevalidate: 30 * time.Second,
+	SurrogateKeys:        []string{"share-urls"},
+}
+
+// RegisterPublicRoutes registers drive routes that do not require authentication
+func RegisterPublicRoutes(r *gin.RouterGroup, h *Handler) {
+	urlsGroup := r.Group("/urls")
+
+	// Public links are opened by anonymous visitors
+	urlsGroup.GET("/:token", middleware.RequestBudgetMiddleware(h.driveService.DefaultRequestBudget), middleware.CacheHeadersMiddleware(publicLinkCachePolicy), h.ResolveShareURL)
+}
+
+// RegisterProtectedRoutes registers drive routes for authenticated users. Each route belongs to a
+// budget class that sets the deadline of the whole request. Content of backup shares can only be
+// changed from the device the backup belongs to.
 func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
-	driveGroup := r.Group("")
+	// Single-entity operations
+	driveGroup := r.Group("", middleware.RequestBudgetMiddleware(h.driveService.DefaultRequestBudget), h.RequireBackupDevice)
+	// Multi-step or batch operations, such as presigning many blocks or purging a large trash
+	batchGroup := r.Group("", middleware.RequestBudgetMiddleware(h.driveService.ExtendedRequestBudget), h.RequireBackupDevice)
+
+	// Long-polling is bounded by the wait the client asks for instead
+	r.GET("/volumes/:volumeID/events/wait", middleware.RequestBudgetMiddleware(h.driveService.EventWaitRequestBudget), h.WaitForVolumeEvents)
+
 	driveGroup.POST("/volumes/create", h.CreateDriveVolume)
+	driveGroup.GET("/volumes/:volumeID/events", h.GetVolumeEvents)
+	driveGroup.PUT("/volumes/:volumeID/sync/cursor", h.AcknowledgeSyncCursor)
+	driveGroup.GET("/sync/state", h.GetSyncState)
+	batchGroup.GET("/volumes/:volumeID/storage", h.GetVolumeStorage)
+	driveGroup.PUT("/volumes/:volumeID/replication", h.SetVolumeReplication)
+	driveGroup.GET("/shares/:shareID/endpoints", h.GetVolumeEndpoints)
 	driveGroup.POST("/shares/:shareID/folders/create", h.CreateDriveFolder)
+	batchGroup.POST("/shares/:shareID/folders/bulk", h.BulkCreateFolders)
 	driveGroup.GET("/shares", h.GetUserShares)
+	driveGroup.GET("/recent", h.ListRecentItems)
+	driveGroup.GET("/usage", h.GetDriveUsage)
 	driveGroup.GET("/shares/:shareID", h.GetShareByID)
 	driveGroup.GET("/shares/:shareID/links/:linkID", h.GetLinkByID)
+	driveGroup.GET("/links/:linkID/path", h.GetItemPath)
+	batchGroup.POST("/links/batch", h.BatchGetLinks)
 	driveGroup.GET("/shares/:shareID/folders/:folderID/children", h.GetFolderContents)
-	driveGroup.GET("/shares/:shareID/links/:linkID/rename", h.GetFolderContents)
+	driveGroup.GET("/shares/:shareID/trash", h.ListTrash)
+	driveGroup.PUT("/shares/:shareID/links/:linkID/rename", h.RenameItem)
+	driveGroup.PUT("/shares/:shareID/links/:linkID/move", h.MoveItem)
+	driveGroup.PATCH("/shares/:shareID/links/:linkID/xattr", h.SetItemXattrs)
+	batchGroup.POST("/shares/:shareID/links/batch-move", h.BatchMoveItems)
+	batchGroup.POST("/shares/:shareID/links/batch-copy", h.BatchCopyItems)
+	batchGroup.POST("/shares/:shareID/links/:linkID/copy", h.CopyItem)
+
+	// File uploads
+	driveGroup.POST("/shares/:shareID/files", h.CreateDriveFile)
+	batchGroup.POST("/shares/:shareID/files/:linkID/revisions/:revisionID/blocks", h.RequestBlockUploads)
+	driveGroup.POST("/shares/:shareID/files/:linkID/revisions/:revisionID/thumbnails", h.RequestThumbnailUpload)
+	batchGroup.POST("/shares/:shareID/files/:linkID/revisions/:revisionID/commit", h.CommitRevision)
+	driveGroup.POST("/shares/:shareID/files/:linkID/revisions/:revisionID/pause", h.PauseUpload)
+	driveGroup.POST("/shares/:shareID/files/:linkID/revisions/:revisionID/resume", h.ResumeUpload)
+	driveGroup.DELETE("/shares/:shareID/files/:linkID/revisions/:revisionID", h.CancelUpload)
+	batchGroup.GET("/uploads/:sessionID/status", h.GetUploadStatus)
+	batchGroup.GET("/shares/:shareID/files/:linkID/download", h.DownloadFile)
+	driveGroup.GET("/shares/:shareID/files/:linkID/thumbnail", h.GetThumbnail)
+	driveGroup.POST("/shares/:shareID/folders/:folderID/duplicates", h.CheckDuplicates)
+
+	// Trash
+	batchGroup.POST("/shares/:shareID/trash", h.TrashItems)
+	batchGroup.POST("/shares/:shareID/restore", h.RestoreItems)
+	batchGroup.DELETE("/shares/:shareID/trash", h.EmptyTrash)
+
+	// Background jobs
+	driveGroup.DELETE("/shares/:shareID/folders/:folderID", h.DeleteFolder)
+	driveGroup.GET("/jobs/:jobID", h.GetJob)
+
+	// Share invitations
+	driveGroup.POST("/shares/:shareID/invitations", h.InviteShareMember)
+	driveGroup.GET("/invitations", h.GetPendingInvitations)
+	driveGroup.POST("/invitations/:invitationID/accept", h.AcceptInvitation)
+	driveGroup.POST("/invitations/:invitationID/decline", h.DeclineInvitation)
+
+	// Membership approval
+	driveGroup.PUT("/shares/:shareID/approval", h.SetShareApproval)
+	driveGroup.GET("/shares/:shareID/approvals", h.GetPendingApprovals)
+	driveGroup.POST("/shares/:shareID/approvals/:membershipID/approve", h.ApproveMembership)
+	driveGroup.POST("/shares/:shareID/approvals/:membershipID/reject", h.RejectMembership)
+
+	// Member management
+	driveGroup.PUT("/shares/:shareID/members/:memberID", h.UpdateShareMember)
+	driveGroup.DELETE("/shares/:shareID/members/:memberID", h.RemoveShareMember)
+	driveGroup.POST("/shares/:shareID/transfer-ownership", h.TransferShareOwnership)
+
+	// Locked shares, unlocked by an admin member after the owner's keys were replaced
+	driveGroup.GET("/shares/locked", h.GetLockedShares)
+	driveGroup.POST("/shares/:shareID/unlock", h.UnlockShare)
+
+	// Key rotation, re-wrapping the passphrases of owned shares for the owner's new key in batches
+	driveGroup.POST("/key-rotation", h.StartKeyRotation)
+	driveGroup.GET("/key-rotation", h.GetKeyRotationStatus)
+	batchGroup.GET("/key-rotation/shares/:shareID/items", h.GetKeyRotationItems)
+	batchGroup.POST("/key-rotation/shares/:shareID", h.RotateShareKeys)
+
+	// Expiry
+	driveGroup.PUT("/shares/:shareID/expiry", h.SetShareExpiry)
+	driveGroup.PUT("/shares/:shareID/urls/:urlID/expiry", h.SetShareURLExpiry)
+
+	// Public links
+	driveGroup.POST("/shares/:shareID/links/:linkID/urls", h.CreateShareURL)
+	driveGroup.GET("/shares/:shareID/urls/:urlID", h.GetShareURL)
+	driveGroup.PUT("/shares/:shareID/urls/:urlID/slug", h.SetShareURLSlug)
+	driveGroup.GET("/shares/:shareID/urls/:urlID/qr", h.GetShareURLQRCode)
+
+	// Encrypted search
+	driveGroup.PUT("/shares/:shareID/links/:linkID/search-tokens", h.SetItemSearchTokens)
+	driveGroup.POST("/shares/:shareID/search", h.SearchItems)
+	batchGroup.GET("/search", h.SearchAllItems)
+	driveGroup.GET("/search/key", h.GetSearchKeyState)
+	driveGroup.POST("/search/key/rotate", h.StartSearchKeyRotation)
+	batchGroup.GET("/search/reindex", h.GetReindexBatch)
+	batchGroup.POST("/search/key/rotate/complete", h.CompleteSearchKeyRotation)
+
+	// Tags
+	driveGroup.GET("/tags", h.ListTags)
+	driveGroup.POST("/tags", h.CreateTag)
+	driveGroup.PUT("/tags/:tagID", h.UpdateTag)
+	driveGroup.DELETE("/tags/:tagID", h.DeleteTag)
+	driveGroup.GET("/shares/:shareID/links/:linkID/tags", h.GetItemTags)
+	driveGroup.PUT("/shares/:shareID/links/:linkID/tags", h.SetItemTags)
+
+	// Device backups, browsed through the share routes of each set's backup share
+	driveGroup.GET("/backups", h.ListBackupSets)
+	driveGroup.POST("/backups", h.CreateBackupSet)
+	driveGroup.GET("/backups/:backupSetID", h.GetBackupSet)
+	driveGroup.PUT("/backups/:backupSetID/rules", h.UpdateBackupSetRules)
+	driveGroup.DELETE("/backups/:backupSetID", h.DeleteBackupSet)
 }
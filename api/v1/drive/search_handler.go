@@ -0,0 +1,218 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetItemSearchTokens handles storing encrypted name tokens for an item
+func (h *Handler) SetItemSearchTokens(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and link IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	linkID := c.Param("linkID")
+	if err := h.validateRequestParam(linkID, "Link ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req SetSearchTokensRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setItemSearchTokens")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	err = h.driveService.SetItemSearchTokens(ctx, userID, shareID, linkID, req.KeyVersion, req.Tokens)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setItemSearchTokens")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Search tokens updated successfully", status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// SearchItems handles searching a share by encrypted name tokens
+func (h *Handler) SearchItems(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "searchItems")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Get pagination parameters
+	limit, offset := h.getPaginationParams(c, defaultLimit, maxLimit)
+
+	ctx := c.Request.Context()
+
+	items, total, err := h.driveService.SearchItems(ctx, userID, shareID, req.Tokens, req.TagIDs, limit, offset)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "searchItems")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewFolderContentsResponse(items, limit, offset, total, "modifiedAt", "desc", status.StatusOK, middleware.RequestID(c)))
+}
+
+// SearchAllItems handles searching every readable share by encrypted name tokens and tags.
+// Tokens and tag IDs are passed as comma-separated or repeated hashes and tags query parameters.
+func (h *Handler) SearchAllItems(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	tokens := getQueryList(c, "hashes")
+	tagIDs := getQueryList(c, "tags")
+	if len(tokens) == 0 && len(tagIDs) == 0 {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, "At least one search hash or tag is required")
+		return
+	}
+
+	// Get pagination parameters
+	limit, offset := h.getPaginationParams(c, defaultLimit, maxLimit)
+
+	ctx := c.Request.Context()
+
+	items, total, err := h.driveService.SearchAllItems(ctx, userID, tokens, tagIDs, limit, offset)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "searchAllItems")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewFolderContentsResponse(items, limit, offset, total, "modifiedAt", "desc", status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetSearchKeyState handles retrieving the user's search key state
+func (h *Handler) GetSearchKeyState(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	state, err := h.driveService.GetSearchKeyState(ctx, userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getSearchKeyState")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSearchKeyStateResponse(state, status.StatusOK, middleware.RequestID(c)))
+}
+
+// StartSearchKeyRotation handles starting a search key rotation
+func (h *Handler) StartSearchKeyRotation(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Parse request body
+	var req StartSearchKeyRotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "startSearchKeyRotation")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	state, err := h.driveService.StartSearchKeyRotation(ctx, userID, req.NewKeyVersion)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "startSearchKeyRotation")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, NewSearchKeyStateResponse(state, status.StatusAccepted, middleware.RequestID(c)))
+}
+
+// GetReindexBatch handles listing items that still need tokens under the pending key
+func (h *Handler) GetReindexBatch(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get batch size, reusing the pagination limit parameter
+	limit, _ := h.getPaginationParams(c, defaultLimit, maxLimit)
+
+	ctx := c.Request.Context()
+
+	items, reindexStatus, err := h.driveService.GetReindexBatch(ctx, userID, limit)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getReindexBatch")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewReindexBatchResponse(items, reindexStatus, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CompleteSearchKeyRotation handles activating the pending search key
+func (h *Handler) CompleteSearchKeyRotation(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	reindexStatus, err := h.driveService.CompleteSearchKeyRotation(ctx, userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "completeSearchKeyRotation")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewReindexStatusResponse(reindexStatus, status.StatusUpdated, middleware.RequestID(c)))
+}
@@ -0,0 +1,80 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetShareExpiry handles setting, renewing or clearing when a share's members and public links lose access
+func (h *Handler) SetShareExpiry(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req SetExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setShareExpiry")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	share, err := h.driveService.SetShareExpiry(ctx, userID, shareID, req.ExpiresAt)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setShareExpiry")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewShareWithMembershipsResponse(share, nil, userID, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// SetShareURLExpiry handles setting, renewing or clearing the expiry of a public link
+func (h *Handler) SetShareURLExpiry(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, urlID, ok := h.getShareURLParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req SetExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setShareURLExpiry")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	shareURL, err := h.driveService.SetShareURLExpiry(ctx, userID, shareID, urlID, req.ExpiresAt)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setShareURLExpiry")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewShareURLResponse(shareURL, h.driveService.ShareURLAddress(shareURL), status.StatusUpdated, middleware.RequestID(c)))
+}
@@ -0,0 +1,69 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLockedShares handles listing the locked shares the caller owns or can unlock
+func (h *Handler) GetLockedShares(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shares, err := h.driveService.GetLockedShares(c.Request.Context(), userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getLockedShares")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewLockedSharesResponse(shares, userID, status.StatusOK, middleware.RequestID(c)))
+}
+
+// UnlockShare handles unlocking a share with its passphrase re-wrapped for the owner's new key
+func (h *Handler) UnlockShare(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req UnlockShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "unlockShare")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	share, err := h.driveService.UnlockShare(c.Request.Context(), userID, shareID, drive.ShareUnlockKeys{
+		SharePassphrase:          req.SharePassphrase,
+		SharePassphraseSignature: req.SharePassphraseSignature,
+		OwnerKeyPacket:           req.OwnerKeyPacket,
+		OwnerKeyPacketSignature:  req.OwnerKeyPacketSignature,
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "unlockShare")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewShareWithMembershipsResponse(share, nil, userID, status.StatusUpdated, middleware.RequestID(c)))
+}
@@ -0,0 +1,200 @@
+package drive
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cirrussync-api/internal/cdn"
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/middleware"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateShareURL handles creating a public link for an item
+func (h *Handler) CreateShareURL(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and link IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	linkID := c.Param("linkID")
+	if err := h.validateRequestParam(linkID, "Link ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req CreateShareURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createShareURL")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	shareURL, err := h.driveService.CreateShareURL(ctx, userID, shareID, linkID, &models.DriveShareURL{
+		SharePasswordSalt:        req.SharePasswordSalt,
+		SharePassphraseKeyPacket: req.SharePassphraseKeyPacket,
+		ExpiresAt:                req.ExpiresAt,
+		Slug:                     req.Slug,
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "createShareURL")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewShareURLResponse(shareURL, h.driveService.ShareURLAddress(shareURL), status.StatusShareCreated, middleware.RequestID(c)))
+}
+
+// GetShareURL handles retrieving a public link
+func (h *Handler) GetShareURL(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, urlID, ok := h.getShareURLParams(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	shareURL, err := h.driveService.GetShareURL(ctx, userID, shareID, urlID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getShareURL")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewShareURLResponse(shareURL, h.driveService.ShareURLAddress(shareURL), status.StatusOK, middleware.RequestID(c)))
+}
+
+// SetShareURLSlug handles assigning or clearing a public link's vanity slug
+func (h *Handler) SetShareURLSlug(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, urlID, ok := h.getShareURLParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req SetShareURLSlugRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setShareURLSlug")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	shareURL, err := h.driveService.SetShareURLSlug(ctx, userID, shareID, urlID, req.Slug)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setShareURLSlug")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewShareURLResponse(shareURL, h.driveService.ShareURLAddress(shareURL), status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// GetShareURLQRCode handles rendering a QR code image for a public link
+func (h *Handler) GetShareURLQRCode(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, urlID, ok := h.getShareURLParams(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", drive.QR_FORMAT_PNG)
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(drive.DEFAULT_QR_SIZE)))
+	if err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, drive.ErrInvalidQRCodeSize.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	image, contentType, err := h.driveService.GetShareURLQRCode(ctx, userID, shareID, urlID, format, size)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getShareURLQRCode")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	// The code only changes when the slug does, so allow short private caching
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// ResolveShareURL handles anonymous lookup of a public link by token or slug
+func (h *Handler) ResolveShareURL(c *gin.Context) {
+	token := c.Param("token")
+	if err := h.validateRequestParam(token, "Token"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	shareURL, err := h.driveService.ResolveShareURL(ctx, token)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "resolveShareURL")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	// Tag the response so it can be purged, and never let a cached copy outlive the link
+	middleware.AddSurrogateKeys(c, cdn.SURROGATE_KEY_SHARE_URL+shareURL.ID, cdn.SURROGATE_KEY_SHARE+shareURL.ShareID)
+	if shareURL.ExpiresAt != nil && *shareURL.ExpiresAt-time.Now().Unix() < int64(publicLinkCachePolicy.SharedMaxAge.Seconds()) {
+		c.Header("Cache-Control", "no-store")
+	}
+
+	c.JSON(http.StatusOK, NewPublicShareURLResponse(shareURL, status.StatusOK, middleware.RequestID(c)))
+}
+
+// getShareURLParams reads and validates the share and public link IDs from the URL path
+func (h *Handler) getShareURLParams(c *gin.Context) (string, string, bool) {
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	urlID := c.Param("urlID")
+	if err := h.validateRequestParam(urlID, "URL ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	return shareID, urlID, true
+}
@@ -0,0 +1,151 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/srp"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVolumeStorage handles reporting how a volume's blocks are stored
+func (h *Handler) GetVolumeStorage(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get volume ID from URL path
+	volumeID := c.Param("volumeID")
+	if err := h.validateRequestParam(volumeID, "VolumeID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	report, err := h.driveService.GetVolumeStorageReport(ctx, userID, volumeID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getVolumeStorage")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewVolumeStorageResponse(report, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetDriveUsage handles reporting drive usage: the size of each of the user's shares, or with a
+// shareId the size of a folder of that share, its root by default, broken down by child
+func (h *Handler) GetDriveUsage(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	shareID := c.Query("shareId")
+	if shareID == "" {
+		usage, err := h.driveService.GetShareUsage(ctx, userID)
+		if err != nil {
+			statusCode, apiStatus, message := h.handleServiceError(c, err, "getDriveUsage")
+			h.respondWithError(c, statusCode, apiStatus, message)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewShareUsageResponse(usage, status.StatusOK, middleware.RequestID(c)))
+		return
+	}
+
+	// Get pagination parameters
+	limit, offset := h.getPaginationParams(c, defaultLimit, maxLimit)
+
+	usage, err := h.driveService.GetFolderUsage(ctx, userID, shareID, c.Query("folderId"), limit, offset)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getDriveUsage")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewFolderUsageResponse(usage, limit, offset, status.StatusOK, middleware.RequestID(c)))
+}
+
+// SetVolumeReplication handles turning cross-region replication of new blocks on or off
+func (h *Handler) SetVolumeReplication(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get volume ID from URL path
+	volumeID := c.Param("volumeID")
+	if err := h.validateRequestParam(volumeID, "VolumeID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req SetVolumeReplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setVolumeReplication")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	volume, err := h.driveService.SetVolumeReplication(ctx, userID, volumeID, *req.CrossRegionReplication)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setVolumeReplication")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewVolumeReplicationResponse(volume, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// continentHeader carries the client's continent when the CDN in front of the API adds visitor
+// location headers
+const continentHeader = "CF-IPContinent"
+
+// GetVolumeEndpoints handles returning the regional endpoints a client should use for a share's volume
+func (h *Handler) GetVolumeEndpoints(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	endpoints, err := h.driveService.GetVolumeEndpoints(ctx, userID, shareID, drive.ClientLocation{
+		IPAddress: srp.GetClientIPFromRequest(c.Request),
+		Continent: c.GetHeader(continentHeader),
+	})
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getVolumeEndpoints")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	// The answer depends on where the client connects from
+	c.Header("Vary", continentHeader)
+	c.JSON(http.StatusOK, NewVolumeEndpointsResponse(endpoints, status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,64 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AcknowledgeSyncCursor handles recording how far one of the caller's devices has synced a volume
+func (h *Handler) AcknowledgeSyncCursor(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get volume ID from URL path
+	volumeID := c.Param("volumeID")
+	if err := h.validateRequestParam(volumeID, "VolumeID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req AcknowledgeSyncCursorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "acknowledgeSyncCursor")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	device, err := h.driveService.AcknowledgeSyncCursor(c.Request.Context(), userID, volumeID, req.DeviceID, req.Cursor)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "acknowledgeSyncCursor")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeviceSyncStateResponse(volumeID, device, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// GetSyncState handles listing where each of the caller's devices is in syncing their volumes
+func (h *Handler) GetSyncState(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	state, err := h.driveService.GetSyncState(c.Request.Context(), userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getSyncState")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewSyncStateResponse(state, status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,200 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+	"strings"
+
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTags handles listing the caller's tags
+func (h *Handler) ListTags(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	tags, err := h.driveService.ListTags(c.Request.Context(), userID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "listTags")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewTagsResponse(tags, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CreateTag handles creating a tag with a client-encrypted label
+func (h *Handler) CreateTag(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Parse request body
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createTag")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	tag, err := h.driveService.CreateTag(c.Request.Context(), userID, req.EncryptedName, req.Color)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "createTag")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewTagResponse(tag, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// UpdateTag handles replacing a tag's label and color
+func (h *Handler) UpdateTag(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	tagID := c.Param("tagID")
+	if err := h.validateRequestParam(tagID, "Tag ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updateTag")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	tag, err := h.driveService.UpdateTag(c.Request.Context(), userID, tagID, req.EncryptedName, req.Color)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "updateTag")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewTagResponse(tag, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// DeleteTag handles deleting a tag and detaching it from all items
+func (h *Handler) DeleteTag(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	tagID := c.Param("tagID")
+	if err := h.validateRequestParam(tagID, "Tag ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.driveService.DeleteTag(c.Request.Context(), userID, tagID); err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "deleteTag")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Tag deleted", status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// GetItemTags handles retrieving the caller's tags on an item
+func (h *Handler) GetItemTags(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, ok := h.getShareAndLinkParams(c)
+	if !ok {
+		return
+	}
+
+	tagIDs, err := h.driveService.GetItemTags(c.Request.Context(), userID, shareID, linkID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getItemTags")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewItemTagsResponse(linkID, tagIDs, status.StatusOK, middleware.RequestID(c)))
+}
+
+// SetItemTags handles replacing the caller's tags on an item
+func (h *Handler) SetItemTags(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, ok := h.getShareAndLinkParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req SetItemTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setItemTags")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	tagIDs, err := h.driveService.SetItemTags(c.Request.Context(), userID, shareID, linkID, req.TagIDs)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "setItemTags")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewItemTagsResponse(linkID, tagIDs, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// getShareAndLinkParams validates the share and link IDs in the URL path
+func (h *Handler) getShareAndLinkParams(c *gin.Context) (string, string, bool) {
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	linkID := c.Param("linkID")
+	if err := h.validateRequestParam(linkID, "Link ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	return shareID, linkID, true
+}
+
+// getQueryList collects a list passed as comma-separated or repeated query parameters
+func getQueryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, value := range c.QueryArray(key) {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
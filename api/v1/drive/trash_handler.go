@@ -0,0 +1,133 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"context"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashItems handles moving a batch of links to the trash
+func (h *Handler) TrashItems(c *gin.Context) {
+	h.handleBatchLinks(c, "trashItems", h.driveService.TrashItems)
+}
+
+// RestoreItems handles restoring a batch of links from the trash
+func (h *Handler) RestoreItems(c *gin.Context) {
+	h.handleBatchLinks(c, "restoreItems", h.driveService.RestoreItems)
+}
+
+// ListTrash handles listing the items trashed in a share with the time each one is purged at
+func (h *Handler) ListTrash(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Get pagination parameters
+	limit, offset := h.getPaginationParams(c, defaultLimit, maxLimit)
+
+	items, total, err := h.driveService.ListTrash(c.Request.Context(), userID, shareID, limit, offset)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "listTrash")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewFolderContentsResponse(items, limit, offset, total, "trashedAt", "desc", status.StatusOK, middleware.RequestID(c)))
+}
+
+// EmptyTrash handles permanently deleting everything in a share's trash
+func (h *Handler) EmptyTrash(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	result, err := h.driveService.EmptyTrash(ctx, userID, shareID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "emptyTrash")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewEmptyTrashResponse(result, status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// handleBatchLinks binds a batch links request, runs the operation and reports per-link outcomes
+func (h *Handler) handleBatchLinks(
+	c *gin.Context,
+	route string,
+	operation func(ctx context.Context, userID, shareID string, linkIDs []string) ([]*drive.ItemResult, error),
+) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req BatchLinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", route)
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	results, err := operation(ctx, userID, shareID, req.LinkIDs)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, route)
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBatchResultsResponse(h.batchItemResponses(c, results, route), status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// batchItemResponses converts per-link results into response data using the same error mapping as single operations
+func (h *Handler) batchItemResponses(c *gin.Context, results []*drive.ItemResult, route string) []BatchItemResponseData {
+	responses := make([]BatchItemResponseData, len(results))
+	for i, result := range results {
+		responses[i] = BatchItemResponseData{LinkId: result.LinkID, CreatedLinkId: result.CreatedLinkID, Code: status.StatusOK}
+		if result.Err != nil {
+			_, apiStatus, message := h.handleServiceError(c, result.Err, route)
+			responses[i].Code = apiStatus
+			responses[i].Error = message
+		}
+	}
+	return responses
+}
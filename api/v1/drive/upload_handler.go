@@ -0,0 +1,372 @@
+package drive
+
+import (
+	"cirrussync-api/internal/middleware"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateDriveFile handles registering a new file under a specific share
+func (h *Handler) CreateDriveFile(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share ID from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req CreateFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createFile")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Convert request to drive item
+	fileInput := &models.DriveItem{
+		ParentID:                req.ParentId,
+		Name:                    req.Name,
+		Hash:                    req.Hash,
+		MimeType:                req.MimeType,
+		SignatureEmail:          req.SignatureEmail,
+		NodeKey:                 req.NodeKey,
+		NodePassphrase:          req.NodePassphrase,
+		NodePassphraseSignature: req.NodePassphraseSignature,
+		FileProperties:          req.FileProperties.ToModel(),
+	}
+
+	ctx := c.Request.Context()
+
+	file, revision, err := h.driveService.CreateFile(ctx, userID, shareID, fileInput)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "createFile")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewCreateFileResponse(file, revision, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// RequestBlockUploads handles issuing presigned upload URLs for blocks of a draft revision
+func (h *Handler) RequestBlockUploads(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, revisionID, ok := h.getRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req RequestBlockUploadsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "requestBlockUploads")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	blocks := make([]*models.FileBlock, len(req.Blocks))
+	for i, block := range req.Blocks {
+		blocks[i] = &models.FileBlock{
+			Index:              block.Index,
+			Size:               block.Size,
+			Hash:               block.Hash,
+			ChecksumSHA256:     block.ChecksumSHA256,
+			KeyPacket:          block.KeyPacket,
+			KeyPacketSignature: block.KeyPacketSignature,
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	uploads, err := h.driveService.RequestBlockUploads(ctx, userID, shareID, linkID, revisionID, blocks)
+	if err != nil {
+		if h.respondWithQuotaError(c, err) || h.respondWithDeferredUpload(c, err) {
+			return
+		}
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "requestBlockUploads")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBlockUploadsResponse(uploads, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RequestThumbnailUpload handles issuing a presigned upload URL for a thumbnail of a draft revision
+func (h *Handler) RequestThumbnailUpload(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, revisionID, ok := h.getRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req RequestThumbnailUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "requestThumbnailUpload")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	upload, err := h.driveService.RequestThumbnailUpload(ctx, userID, shareID, linkID, revisionID, &models.DriveThumbnail{
+		Type:               req.Type,
+		Size:               req.Size,
+		Hash:               req.Hash,
+		ThumbnailSignature: req.ThumbnailSignature,
+	})
+	if err != nil {
+		if h.respondWithDeferredUpload(c, err) {
+			return
+		}
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "requestThumbnailUpload")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewThumbnailUploadResponse(upload, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CommitRevision handles finalizing a draft revision once all blocks are uploaded
+func (h *Handler) CommitRevision(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, revisionID, ok := h.getRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req CommitRevisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "commitRevision")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	file, err := h.driveService.CommitRevision(ctx, userID, shareID, linkID, revisionID, &drive.RevisionCommit{
+		BlockCount:        req.BlockCount,
+		SignatureEmail:    req.SignatureEmail,
+		ManifestSignature: req.ManifestSignature,
+	})
+	if err != nil {
+		if h.respondWithQuotaError(c, err) || h.respondWithDeferredUpload(c, err) {
+			return
+		}
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "commitRevision")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewFileResponse(file, status.StatusFileUploaded, middleware.RequestID(c)))
+}
+
+// PauseUpload handles pausing the upload of a draft revision
+func (h *Handler) PauseUpload(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, revisionID, ok := h.getRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	revision, err := h.driveService.PauseUpload(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "pauseUpload")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewRevisionResponse(revision, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// ResumeUpload handles resuming a paused upload of a draft revision
+func (h *Handler) ResumeUpload(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, revisionID, ok := h.getRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	revision, err := h.driveService.ResumeUpload(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "resumeUpload")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewRevisionResponse(revision, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// GetUploadStatus handles reporting which blocks of a draft revision were received, so an
+// interrupted upload can be resumed
+func (h *Handler) GetUploadStatus(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// The upload session is the draft revision
+	revisionID := c.Param("sessionID")
+	if err := h.validateRequestParam(revisionID, "SessionID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	uploadStatus, err := h.driveService.GetUploadStatus(ctx, userID, revisionID)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "getUploadStatus")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUploadStatusResponse(uploadStatus, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CancelUpload handles discarding a draft revision and its uploaded blocks
+func (h *Handler) CancelUpload(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, writePermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	shareID, linkID, revisionID, ok := h.getRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.driveService.CancelUpload(ctx, userID, shareID, linkID, revisionID); err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "cancelUpload")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Upload cancelled", status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// getRevisionParams reads and validates the share, link and revision IDs from the URL path
+func (h *Handler) getRevisionParams(c *gin.Context) (string, string, string, bool) {
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", "", false
+	}
+
+	linkID := c.Param("linkID")
+	if err := h.validateRequestParam(linkID, "Link ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", "", false
+	}
+
+	revisionID := c.Param("revisionID")
+	if err := h.validateRequestParam(revisionID, "Revision ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return "", "", "", false
+	}
+
+	return shareID, linkID, revisionID, true
+}
+
+// CheckDuplicates handles the pre-upload check that reports which files already exist in a folder
+func (h *Handler) CheckDuplicates(c *gin.Context) {
+	// Check user permissions
+	userID, err := h.getUserIDAndCheckPermission(c, readPermission)
+	if err != nil {
+		h.handlePermissionError(c, err)
+		return
+	}
+
+	// Get share and folder IDs from URL path
+	shareID := c.Param("shareID")
+	if err := h.validateRequestParam(shareID, "ShareID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	folderID := c.Param("folderID")
+	if err := h.validateRequestParam(folderID, "Folder ID"); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, status.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse request body
+	var req CheckDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "checkDuplicates")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	candidates := make([]drive.ContentCandidate, len(req.Candidates))
+	for i, candidate := range req.Candidates {
+		candidates[i] = drive.ContentCandidate{
+			ContentHash: candidate.ContentHash,
+			Size:        candidate.Size,
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	matches, err := h.driveService.FindDuplicateFiles(ctx, userID, shareID, folderID, candidates)
+	if err != nil {
+		statusCode, apiStatus, message := h.handleServiceError(c, err, "checkDuplicates")
+		h.respondWithError(c, statusCode, apiStatus, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDuplicatesResponse(matches, status.StatusOK, middleware.RequestID(c)))
+}
@@ -26,10 +26,11 @@ func NewHandler(service *mfa.Service, logger *logger.Logger) *Handler {
 	}
 }
 
-// secureLog logs errors without sensitive data
-func (h *Handler) secureLog(err error, message string, route string) {
-	// Use the logger to log errors
-	h.logger.WithFields(logrus.Fields{
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"route":    route,
 		"errorMsg": err.Error(),
 	}).Error(message)
@@ -62,6 +63,41 @@ func (h *Handler) handleErrorResponse(c *gin.Context, err error, result *mfa.Ema
 	case errors.Is(err, mfa.ErrTOTPSetupInProgress) || errors.Is(err, mfa.ErrTOTPOperationInProgress):
 		statusCode = http.StatusConflict
 		apiStatus = status.StatusConflict
+	case errors.Is(err, mfa.ErrInvalidPasskeyResponse) || errors.Is(err, mfa.ErrPasskeyChallengeMismatch) ||
+		errors.Is(err, mfa.ErrPasskeyOriginMismatch) || errors.Is(err, mfa.ErrUnsupportedPasskey) ||
+		errors.Is(err, mfa.ErrPasskeyChallengeExpired) || errors.Is(err, mfa.ErrMFAMethodUnavailable):
+		statusCode = http.StatusBadRequest
+		apiStatus = status.StatusMFAFailed
+	case errors.Is(err, mfa.ErrInvalidPasskeySignature) || errors.Is(err, mfa.ErrPasskeyCloned):
+		statusCode = http.StatusUnauthorized
+		apiStatus = status.StatusMFAFailed
+	case errors.Is(err, mfa.ErrPasskeyNotFound):
+		statusCode = http.StatusNotFound
+		apiStatus = status.StatusNotFound
+	case errors.Is(err, mfa.ErrPasskeyAlreadyRegistered) || errors.Is(err, mfa.ErrPasskeyOperationInProgress):
+		statusCode = http.StatusConflict
+		apiStatus = status.StatusConflict
+	case errors.Is(err, mfa.ErrPasskeyLimitReached):
+		statusCode = http.StatusForbidden
+		apiStatus = status.StatusForbidden
+	case errors.Is(err, mfa.ErrInvalidPhoneNumber) || errors.Is(err, mfa.ErrPhoneMFANotEnabled):
+		statusCode = http.StatusBadRequest
+		apiStatus = status.StatusBadRequest
+	case errors.Is(err, mfa.ErrInvalidSMSCode) || errors.Is(err, mfa.ErrSMSCodeExpired):
+		statusCode = http.StatusBadRequest
+		apiStatus = status.StatusMFAFailed
+	case errors.Is(err, mfa.ErrTooManySMSAttempts):
+		statusCode = http.StatusTooManyRequests
+		apiStatus = status.StatusTooManyRequests
+	case errors.Is(err, mfa.ErrPhoneNumberInUse) || errors.Is(err, mfa.ErrPhoneMFAAlreadyEnabled):
+		statusCode = http.StatusConflict
+		apiStatus = status.StatusConflict
+	case errors.Is(err, mfa.ErrSMSUnavailable):
+		statusCode = http.StatusServiceUnavailable
+		apiStatus = status.StatusInternalServerError
+	case errors.Is(err, mfa.ErrFailedToSendSMS):
+		statusCode = http.StatusBadGateway
+		apiStatus = status.StatusInternalServerError
 	}
 
 	ErrorResponse(c, statusCode, apiStatus, message)
@@ -71,7 +107,7 @@ func (h *Handler) handleErrorResponse(c *gin.Context, err error, result *mfa.Ema
 func (h *Handler) HandleSendVerification(c *gin.Context) {
 	var req SendVerificationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "sendVerification")
+		h.secureLog(c, err, "Invalid request format", "sendVerification")
 		ValidationErrorResponse(c, err)
 		return
 	}
@@ -79,7 +115,7 @@ func (h *Handler) HandleSendVerification(c *gin.Context) {
 	// Call service with intent parameter
 	result, err := h.service.SendVerificationEmail(c.Request.Context(), req.Email, req.Username, req.Intent)
 	if err != nil {
-		h.secureLog(err, "Failed to send verification email", "sendVerification")
+		h.secureLog(c, err, "Failed to send verification email", "sendVerification")
 		h.handleErrorResponse(c, err, result)
 		return
 	}
@@ -148,7 +184,7 @@ func (h *Handler) HandleCheckVerification(c *gin.Context) {
 
 	verified, err := h.service.IsEmailVerified(c.Request.Context(), email)
 	if err != nil {
-		h.secureLog(err, "Failed to check email verification", "checkVerification")
+		h.secureLog(c, err, "Failed to check email verification", "checkVerification")
 		h.handleErrorResponse(c, err, nil)
 		return
 	}
@@ -163,14 +199,14 @@ func (h *Handler) HandleCheckVerification(c *gin.Context) {
 func (h *Handler) HandleGenerateTOTP(c *gin.Context) {
 	var req GenerateTOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "generateTOTP")
+		h.secureLog(c, err, "Invalid request format", "generateTOTP")
 		ValidationErrorResponse(c, err)
 		return
 	}
 
 	totpData, err := h.service.EnableTOTP(c.Request.Context(), req.UserID)
 	if err != nil {
-		h.secureLog(err, "Failed to generate TOTP", "generateTOTP")
+		h.secureLog(c, err, "Failed to generate TOTP", "generateTOTP")
 		h.handleErrorResponse(c, err, nil)
 		return
 	}
@@ -186,14 +222,14 @@ func (h *Handler) HandleGenerateTOTP(c *gin.Context) {
 func (h *Handler) HandleVerifyTOTP(c *gin.Context) {
 	var req VerifyTOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "verifyTOTP")
+		h.secureLog(c, err, "Invalid request format", "verifyTOTP")
 		ValidationErrorResponse(c, err)
 		return
 	}
 
 	valid, err := h.service.VerifyTOTP(c.Request.Context(), req.UserID, req.Code)
 	if err != nil {
-		h.secureLog(err, "Failed to verify TOTP", "verifyTOTP")
+		h.secureLog(c, err, "Failed to verify TOTP", "verifyTOTP")
 		h.handleErrorResponse(c, err, nil)
 		return
 	}
@@ -212,14 +248,14 @@ func (h *Handler) HandleVerifyTOTP(c *gin.Context) {
 func (h *Handler) HandleValidateTOTP(c *gin.Context) {
 	var req ValidateTOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "validateTOTP")
+		h.secureLog(c, err, "Invalid request format", "validateTOTP")
 		ValidationErrorResponse(c, err)
 		return
 	}
 
 	valid, err := h.service.ValidateTOTPCode(c.Request.Context(), req.UserID, req.Code)
 	if err != nil {
-		h.secureLog(err, "Failed to validate TOTP", "validateTOTP")
+		h.secureLog(c, err, "Failed to validate TOTP", "validateTOTP")
 		h.handleErrorResponse(c, err, nil)
 		return
 	}
@@ -237,14 +273,14 @@ func (h *Handler) HandleValidateTOTP(c *gin.Context) {
 func (h *Handler) HandleDisableTOTP(c *gin.Context) {
 	var req DisableTOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "disableTOTP")
+		h.secureLog(c, err, "Invalid request format", "disableTOTP")
 		ValidationErrorResponse(c, err)
 		return
 	}
 
 	err := h.service.DisableTOTP(c.Request.Context(), req.UserID, req.ConfirmationCode)
 	if err != nil {
-		h.secureLog(err, "Failed to disable TOTP", "disableTOTP")
+		h.secureLog(c, err, "Failed to disable TOTP", "disableTOTP")
 		h.handleErrorResponse(c, err, nil)
 		return
 	}
@@ -39,3 +39,30 @@ type DisableTOTPRequest struct {
 	UserID           string `json:"userId" binding:"required"`
 	ConfirmationCode string `json:"confirmationCode"`
 }
+
+// PasskeyRegistrationRequest carries the PublicKeyCredential returned by navigator.credentials.create
+type PasskeyRegistrationRequest struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type" binding:"required,eq=public-key"`
+	Name     string `json:"name" binding:"max=100"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+		AttestationObject string   `json:"attestationObject" binding:"required"`
+		Transports        []string `json:"transports" binding:"max=8"`
+	} `json:"response" binding:"required"`
+}
+
+// SetPreferredMethodRequest represents a request to change the preferred MFA method
+type SetPreferredMethodRequest struct {
+	Method string `json:"method" binding:"required,oneof=webauthn totp"`
+}
+
+// StartPhoneEnrollmentRequest represents a request to text an enrollment code to a phone number
+type StartPhoneEnrollmentRequest struct {
+	PhoneNumber string `json:"phoneNumber" binding:"required,max=32"`
+}
+
+// SMSCodeRequest represents a request carrying a code received by text message
+type SMSCodeRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
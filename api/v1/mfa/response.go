@@ -1,6 +1,7 @@
 package mfa
 
 import (
+	"cirrussync-api/internal/models"
 	"cirrussync-api/pkg/status"
 	"net/http"
 	"strings"
@@ -105,3 +106,49 @@ func SuccessResponse(c *gin.Context, data interface{}, message string) {
 		Message: message,
 	})
 }
+
+// PasskeyResponseData represents a registered passkey
+type PasskeyResponseData struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	AAGUID     string   `json:"aaguid,omitempty"`
+	Transports []string `json:"transports"`
+	CreatedAt  int64    `json:"createdAt"`
+	LastUsed   *int64   `json:"lastUsed"`
+}
+
+// PasskeysResponseData represents the passkeys of a user
+type PasskeysResponseData struct {
+	Passkeys []PasskeyResponseData `json:"passkeys"`
+}
+
+// LoginMethodsResponseData represents the second factors available at login, preferred first
+type LoginMethodsResponseData struct {
+	Methods []string `json:"methods"`
+}
+
+// SMSCodeResponseData represents a verification code that was just texted
+type SMSCodeResponseData struct {
+	PhoneNumber       string `json:"phoneNumber"`
+	ExpiresAt         int64  `json:"expiresAt"`
+	RemainingRequests int    `json:"remainingRequests"`
+}
+
+// PhoneMFAStatusResponseData represents the state of the phone second factor
+type PhoneMFAStatusResponseData struct {
+	Available   bool   `json:"available"`
+	Enabled     bool   `json:"enabled"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+}
+
+// NewPasskeyResponseData converts a stored passkey for the API
+func NewPasskeyResponseData(credential models.WebAuthnCredential) PasskeyResponseData {
+	return PasskeyResponseData{
+		ID:         credential.ID,
+		Name:       credential.Name,
+		AAGUID:     credential.AAGUID,
+		Transports: credential.Transports,
+		CreatedAt:  credential.CreatedAt,
+		LastUsed:   credential.LastUsed,
+	}
+}
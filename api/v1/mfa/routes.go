@@ -21,3 +21,25 @@ func RegisterProtectedRoutes(r *gin.RouterGroup, handler *Handler) {
 	mfa.POST("/totp/validate", handler.HandleValidateTOTP)
 	mfa.POST("/totp/disable", handler.HandleDisableTOTP)
 }
+
+// RegisterAuthenticatedRoutes registers MFA routes that act on the signed in user
+func RegisterAuthenticatedRoutes(r *gin.RouterGroup, handler *Handler) {
+	mfa := r.Group("/mfa")
+
+	// Passkey routes
+	mfa.POST("/webauthn/register/begin", handler.HandleBeginPasskeyRegistration)
+	mfa.POST("/webauthn/register/finish", handler.HandleFinishPasskeyRegistration)
+	mfa.GET("/webauthn/credentials", handler.HandleListPasskeys)
+	mfa.DELETE("/webauthn/credentials/:credentialID", handler.HandleDeletePasskey)
+
+	// SMS routes
+	mfa.GET("/sms", handler.HandleGetPhoneMFAStatus)
+	mfa.POST("/sms/enroll", handler.HandleStartPhoneEnrollment)
+	mfa.POST("/sms/enroll/verify", handler.HandleVerifyPhoneEnrollment)
+	mfa.POST("/sms/disable", handler.HandleStartPhoneMFADisable)
+	mfa.POST("/sms/disable/verify", handler.HandleDisablePhoneMFA)
+
+	// Login method ordering
+	mfa.GET("/methods", handler.HandleGetLoginMethods)
+	mfa.PUT("/methods/preferred", handler.HandleSetPreferredMethod)
+}
@@ -0,0 +1,124 @@
+package mfa
+
+import (
+	"cirrussync-api/internal/mfa"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetPhoneMFAStatus reports whether the signed in user has the phone second factor turned on
+func (h *Handler) HandleGetPhoneMFAStatus(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	phoneStatus, err := h.service.GetPhoneMFAStatus(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get phone MFA status", "getPhoneMFAStatus")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, PhoneMFAStatusResponseData{
+		Available:   phoneStatus.Available,
+		Enabled:     phoneStatus.Enabled,
+		PhoneNumber: phoneStatus.MaskedPhoneNumber,
+	}, "Phone MFA status retrieved successfully")
+}
+
+// HandleStartPhoneEnrollment texts an enrollment code to the phone number the user wants to use
+func (h *Handler) HandleStartPhoneEnrollment(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req StartPhoneEnrollmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "startPhoneEnrollment")
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	result, err := h.service.StartPhoneEnrollment(c.Request.Context(), userID, req.PhoneNumber)
+	if err != nil {
+		h.secureLog(c, err, "Failed to start phone enrollment", "startPhoneEnrollment")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, newSMSCodeResponseData(result), "Verification code sent successfully")
+}
+
+// HandleVerifyPhoneEnrollment checks the enrollment code and turns on the phone second factor
+func (h *Handler) HandleVerifyPhoneEnrollment(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SMSCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "verifyPhoneEnrollment")
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	if err := h.service.VerifyPhoneEnrollment(c.Request.Context(), userID, req.Code); err != nil {
+		h.secureLog(c, err, "Failed to verify phone enrollment", "verifyPhoneEnrollment")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, nil, "Phone MFA enabled successfully")
+}
+
+// HandleStartPhoneMFADisable texts a code confirming the phone second factor should be turned off
+func (h *Handler) HandleStartPhoneMFADisable(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.StartPhoneMFADisable(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to start disabling phone MFA", "startPhoneMFADisable")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, newSMSCodeResponseData(result), "Verification code sent successfully")
+}
+
+// HandleDisablePhoneMFA checks the confirmation code and turns off the phone second factor
+func (h *Handler) HandleDisablePhoneMFA(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SMSCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "disablePhoneMFA")
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	if err := h.service.DisablePhoneMFA(c.Request.Context(), userID, req.Code); err != nil {
+		h.secureLog(c, err, "Failed to disable phone MFA", "disablePhoneMFA")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, nil, "Phone MFA disabled successfully")
+}
+
+// newSMSCodeResponseData converts a sent code for the API
+func newSMSCodeResponseData(result *mfa.SMSCodeResult) SMSCodeResponseData {
+	return SMSCodeResponseData{
+		PhoneNumber:       result.MaskedPhoneNumber,
+		ExpiresAt:         result.ExpiresAt,
+		RemainingRequests: result.RemainingRequests,
+	}
+}
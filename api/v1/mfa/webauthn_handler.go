@@ -0,0 +1,155 @@
+package mfa
+
+import (
+	"net/http"
+
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleBeginPasskeyRegistration issues the options for registering a new passkey
+func (h *Handler) HandleBeginPasskeyRegistration(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	options, err := h.service.BeginPasskeyRegistration(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to begin passkey registration", "beginPasskeyRegistration")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, options, "Passkey registration started")
+}
+
+// HandleFinishPasskeyRegistration verifies and stores a newly created passkey
+func (h *Handler) HandleFinishPasskeyRegistration(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req PasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "finishPasskeyRegistration")
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	credential, err := h.service.FinishPasskeyRegistration(c.Request.Context(), userID, mfa.PasskeyRegistration{
+		ID:                req.ID,
+		ClientDataJSON:    req.Response.ClientDataJSON,
+		AttestationObject: req.Response.AttestationObject,
+		Transports:        req.Response.Transports,
+		Name:              req.Name,
+	})
+	if err != nil {
+		h.secureLog(c, err, "Failed to finish passkey registration", "finishPasskeyRegistration")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, NewPasskeyResponseData(*credential), "Passkey registered successfully")
+}
+
+// HandleListPasskeys lists the passkeys of the signed in user
+func (h *Handler) HandleListPasskeys(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	credentials, err := h.service.ListPasskeys(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to list passkeys", "listPasskeys")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	// Convert to []PasskeyResponseData
+	passkeys := make([]PasskeyResponseData, len(credentials))
+	for i, credential := range credentials {
+		passkeys[i] = NewPasskeyResponseData(credential)
+	}
+
+	SuccessResponse(c, PasskeysResponseData{Passkeys: passkeys}, "Passkeys retrieved successfully")
+}
+
+// HandleDeletePasskey removes one of the signed in user's passkeys
+func (h *Handler) HandleDeletePasskey(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePasskey(c.Request.Context(), userID, c.Param("credentialID")); err != nil {
+		h.secureLog(c, err, "Failed to delete passkey", "deletePasskey")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, nil, "Passkey removed successfully")
+}
+
+// HandleGetLoginMethods returns the second factors offered at login in fallback order
+func (h *Handler) HandleGetLoginMethods(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	methods, err := h.service.GetLoginMethods(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get login methods", "getLoginMethods")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, LoginMethodsResponseData{Methods: methods}, "Login methods retrieved successfully")
+}
+
+// HandleSetPreferredMethod changes which second factor is offered first at login
+func (h *Handler) HandleSetPreferredMethod(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SetPreferredMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setPreferredMethod")
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.SetPreferredMethod(ctx, userID, req.Method); err != nil {
+		h.secureLog(c, err, "Failed to set preferred method", "setPreferredMethod")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	methods, err := h.service.GetLoginMethods(ctx, userID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get login methods", "setPreferredMethod")
+		h.handleErrorResponse(c, err, nil)
+		return
+	}
+
+	SuccessResponse(c, LoginMethodsResponseData{Methods: methods}, "Preferred method updated successfully")
+}
+
+// getUserID reads the authenticated user from the context, answering 401 when it is missing
+func (h *Handler) getUserID(c *gin.Context) (string, bool) {
+	userID, ok := c.Get("userID")
+	if id, isString := userID.(string); ok && isString && id != "" {
+		return id, true
+	}
+
+	ErrorResponse(c, http.StatusUnauthorized, status.StatusUnauthorized, "Unauthorized")
+	return "", false
+}
@@ -0,0 +1,102 @@
+package notifications
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/notification"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler handles in-app notification requests
+type Handler struct {
+	notificationService *notification.Service
+	logger              *logger.Logger
+}
+
+// NewHandler creates a new notification handler
+func NewHandler(notificationService *notification.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		notificationService: notificationService,
+		logger:              log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// ListNotifications handles listing the current user's newest notifications, with ?unread=true only
+// the unread ones. The response also counts all unread notifications, for badges.
+func (h *Handler) ListNotifications(c *gin.Context) {
+	var query ListNotificationsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.secureLog(c, err, "Invalid request format", "listNotifications")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	inbox, err := h.notificationService.GetInbox(c.Request.Context(), userID, query.Unread, query.Limit)
+	if err != nil {
+		h.secureLog(c, err, "Failed to list notifications", "listNotifications")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewNotificationsResponse(inbox, status.StatusOK, middleware.RequestID(c)))
+}
+
+// MarkRead handles marking one of the current user's notifications as read
+func (h *Handler) MarkRead(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	if err := h.notificationService.MarkRead(c.Request.Context(), userID, c.Param("notificationID")); err != nil {
+		h.secureLog(c, err, "Failed to mark notification as read", "markNotificationRead")
+		if errors.Is(err, notification.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(status.StatusOK, middleware.RequestID(c)))
+}
+
+// MarkAllRead handles marking all of the current user's notifications as read
+func (h *Handler) MarkAllRead(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	if err := h.notificationService.MarkAllRead(c.Request.Context(), userID); err != nil {
+		h.secureLog(c, err, "Failed to mark notifications as read", "markAllNotificationsRead")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,7 @@
+package notifications
+
+// ListNotificationsQuery represents the filters of a notification listing
+type ListNotificationsQuery struct {
+	Unread bool `form:"unread"`
+	Limit  int  `form:"limit" binding:"omitempty,min=1,max=100"`
+}
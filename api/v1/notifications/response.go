@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"cirrussync-api/internal/notification"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// NewValidationError creates a validation error response
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Validation Error with requestId " + requestID,
+		},
+		Error: err.Error(),
+	}
+}
+
+// NewSuccessResponse creates a response without data
+func NewSuccessResponse(code int16, requestID string) BaseResponse {
+	return BaseResponse{
+		Code:   code,
+		Detail: "Success with requestId " + requestID,
+	}
+}
+
+// NotificationData represents an in-app notification
+type NotificationData struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Data      map[string]string `json:"data,omitempty"`
+	ReadAt    *int64            `json:"readAt,omitempty"`
+	CreatedAt int64             `json:"createdAt"`
+}
+
+// NotificationsResponse represents the user's newest notifications, newest first
+type NotificationsResponse struct {
+	BaseResponse
+	Notifications []NotificationData `json:"notifications"`
+	Unread        int64              `json:"unread"`
+}
+
+// NewNotificationsResponse creates a new notifications response
+func NewNotificationsResponse(inbox *notification.Inbox, code int16, requestID string) NotificationsResponse {
+	data := make([]NotificationData, len(inbox.Notifications))
+	for i, n := range inbox.Notifications {
+		data[i] = NotificationData{
+			ID:        n.ID,
+			Type:      n.Type,
+			Data:      n.Data,
+			ReadAt:    n.ReadAt,
+			CreatedAt: n.CreatedAt,
+		}
+	}
+
+	return NotificationsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Notifications: data,
+		Unread:        inbox.Unread,
+	}
+}
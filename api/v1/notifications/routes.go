@@ -0,0 +1,12 @@
+package notifications
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterUserRoutes registers the current user's notification routes on the users group
+func RegisterUserRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/@me/notifications", h.ListNotifications)
+	r.POST("/@me/notifications/read", h.MarkAllRead)
+	r.POST("/@me/notifications/:notificationID/read", h.MarkRead)
+}
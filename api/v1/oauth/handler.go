@@ -0,0 +1,322 @@
+package oauth
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/oauth"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/srp"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler handles OAuth provider API requests
+type Handler struct {
+	oauthService *oauth.Service
+	logger       *logger.Logger
+}
+
+// NewHandler creates a new OAuth handler
+func NewHandler(oauthService *oauth.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		oauthService: oauthService,
+		logger:       log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// handleServiceError maps service errors of the consent API to appropriate HTTP responses
+func (h *Handler) handleServiceError(c *gin.Context, err error, route string) {
+	statusCode := http.StatusInternalServerError
+	apiStatus := status.StatusInternalServerError
+	message := "Failed to process authorization request"
+
+	var oauthErr *oauth.Error
+	switch {
+	case errors.Is(err, oauth.ErrClientNotFound),
+		errors.Is(err, oauth.ErrConsentNotFound):
+		statusCode = http.StatusNotFound
+		apiStatus = status.StatusNotFound
+		message = err.Error()
+
+	case errors.Is(err, oauth.ErrClientDisabled),
+		errors.Is(err, oauth.ErrRedirectURIMismatch),
+		errors.As(err, &oauthErr):
+		statusCode = http.StatusBadRequest
+		apiStatus = status.StatusBadRequest
+		message = err.Error()
+
+	default:
+		h.secureLog(c, err, "Error in "+route, route)
+	}
+
+	c.JSON(statusCode, NewErrorResponse(message, apiStatus, middleware.RequestID(c)))
+}
+
+// respondWithProtocolError answers an OAuth client with an RFC 6749 error. Failed client
+// authentication is a 401 with a Basic challenge; anything unexpected is a server_error.
+func (h *Handler) respondWithProtocolError(c *gin.Context, err error, route string) {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		h.secureLog(c, err, "Error in "+route, route)
+		c.JSON(http.StatusInternalServerError, ProtocolErrorResponse{Error: "server_error"})
+		return
+	}
+
+	if oauthErr == oauth.ErrInvalidClient {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		c.JSON(http.StatusUnauthorized, NewProtocolErrorResponse(oauthErr))
+		return
+	}
+	c.JSON(http.StatusBadRequest, NewProtocolErrorResponse(oauthErr))
+}
+
+// GetDiscovery handles publishing the OpenID Connect discovery document
+func (h *Handler) GetDiscovery(c *gin.Context) {
+	c.JSON(http.StatusOK, NewDiscoveryResponse(h.oauthService.GetProviderMetadata()))
+}
+
+// GetJWKS handles publishing the keys ID tokens are signed with
+func (h *Handler) GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, JWKSResponse{Keys: h.oauthService.GetJWKS()})
+}
+
+// Token handles exchanging an authorization code or refresh token for tokens
+func (h *Handler) Token(c *gin.Context) {
+	// Tokens must never be stored by caches along the way
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondWithProtocolError(c, oauth.ErrInvalidRequest, "oauthToken")
+		return
+	}
+	clientID, clientSecret := clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	tokens, err := h.oauthService.Exchange(c.Request.Context(), oauth.TokenRequest{
+		GrantType:    req.GrantType,
+		Code:         req.Code,
+		RedirectURI:  req.RedirectURI,
+		CodeVerifier: req.CodeVerifier,
+		RefreshToken: req.RefreshToken,
+		Scope:        req.Scope,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+	if err != nil {
+		h.respondWithProtocolError(c, err, "oauthToken")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewTokenResponse(tokens))
+}
+
+// Introspect handles describing a token to the client it was issued to
+func (h *Handler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var req TokenLookupRequest
+	if err := c.ShouldBind(&req); err != nil || req.Token == "" {
+		h.respondWithProtocolError(c, oauth.ErrInvalidRequest, "oauthIntrospect")
+		return
+	}
+	clientID, clientSecret := clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	introspection, err := h.oauthService.Introspect(c.Request.Context(), oauth.ClientCredentials{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}, req.Token, req.TokenTypeHint)
+	if err != nil {
+		h.respondWithProtocolError(c, err, "oauthIntrospect")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewIntrospectionResponse(introspection))
+}
+
+// Revoke handles revoking a token at the request of the client it was issued to. Unknown tokens
+// are answered with success, as RFC 7009 requires.
+func (h *Handler) Revoke(c *gin.Context) {
+	var req TokenLookupRequest
+	if err := c.ShouldBind(&req); err != nil || req.Token == "" {
+		h.respondWithProtocolError(c, oauth.ErrInvalidRequest, "oauthRevoke")
+		return
+	}
+	clientID, clientSecret := clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	err := h.oauthService.Revoke(c.Request.Context(), oauth.ClientCredentials{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}, req.Token, req.TokenTypeHint)
+	if err != nil {
+		h.respondWithProtocolError(c, err, "oauthRevoke")
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// GetUserInfo handles returning the OpenID Connect claims of the access token's user
+func (h *Handler) GetUserInfo(c *gin.Context) {
+	identity, ok := c.Get("oauthIdentity")
+	tokenIdentity, isIdentity := identity.(*oauth.TokenIdentity)
+	if !ok || !isIdentity {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, ProtocolErrorResponse{Error: "invalid_token"})
+		return
+	}
+
+	info, err := h.oauthService.GetUserInfo(c.Request.Context(), tokenIdentity)
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrInvalidScope):
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			c.JSON(http.StatusForbidden, ProtocolErrorResponse{Error: "insufficient_scope"})
+		case errors.Is(err, oauth.ErrInvalidAccessToken):
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, ProtocolErrorResponse{Error: "invalid_token"})
+		default:
+			h.respondWithProtocolError(c, err, "oauthUserInfo")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUserInfoResponse(info))
+}
+
+// GetAuthorizationPrompt handles describing an authorization request for the consent screen
+func (h *Handler) GetAuthorizationPrompt(c *gin.Context) {
+	var query AuthorizationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.secureLog(c, err, "Invalid request format", "getAuthorizationPrompt")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	prompt, err := h.oauthService.GetAuthorizationPrompt(c.Request.Context(), userID, oauth.AuthorizationRequest{
+		ClientID:            query.ClientID,
+		RedirectURI:         query.RedirectURI,
+		Scope:               query.Scope,
+		State:               query.State,
+		CodeChallenge:       query.CodeChallenge,
+		CodeChallengeMethod: query.CodeChallengeMethod,
+		Nonce:               query.Nonce,
+	})
+	if err != nil {
+		h.handleServiceError(c, err, "getAuthorizationPrompt")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewAuthorizationPromptResponse(prompt, status.StatusOK, middleware.RequestID(c)))
+}
+
+// Authorize handles the user approving or denying an authorization request
+func (h *Handler) Authorize(c *gin.Context) {
+	var req AuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "authorize")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	redirectURL, err := h.oauthService.Authorize(c.Request.Context(), userID, oauth.AuthorizationRequest{
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+	}, *req.Approve, srp.GetClientIPFromRequest(c.Request))
+	if err != nil {
+		h.handleServiceError(c, err, "authorize")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewAuthorizeResponse(redirectURL, status.StatusOK, middleware.RequestID(c)))
+}
+
+// ListAuthorizedApps handles listing the applications the caller granted access to
+func (h *Handler) ListAuthorizedApps(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	apps, err := h.oauthService.ListAuthorizedApps(c.Request.Context(), userID)
+	if err != nil {
+		h.handleServiceError(c, err, "listAuthorizedApps")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewAuthorizedAppsResponse(apps, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RevokeAuthorization handles the caller withdrawing an application's access, which revokes every
+// token issued to it for the caller. Responds with the applications that still have access.
+func (h *Handler) RevokeAuthorization(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.oauthService.RevokeAuthorization(c.Request.Context(), userID, c.Param("clientID"), srp.GetClientIPFromRequest(c.Request))
+	if err != nil {
+		h.handleServiceError(c, err, "revokeAuthorization")
+		return
+	}
+
+	apps, err := h.oauthService.ListAuthorizedApps(c.Request.Context(), userID)
+	if err != nil {
+		h.handleServiceError(c, err, "revokeAuthorization")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewAuthorizedAppsResponse(apps, status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// getUserID reads the authenticated user from the context, responding with 401 when it is missing
+func (h *Handler) getUserID(c *gin.Context) (string, bool) {
+	userIDInterface, exists := c.Get("userID")
+	userID, ok := userIDInterface.(string)
+	if !exists || !ok || userID == "" {
+		h.secureLog(c, session.ErrSessionNotFound, "Missing user in context", "getUserID")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(session.ErrSessionNotFound.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return "", false
+	}
+	return userID, true
+}
+
+// clientCredentials reads the client's credentials from HTTP Basic authentication, falling back
+// to the form for clients that send them there
+func clientCredentials(c *gin.Context, formClientID, formClientSecret string) (string, string) {
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		return clientID, clientSecret
+	}
+	return formClientID, formClientSecret
+}
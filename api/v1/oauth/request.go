@@ -0,0 +1,45 @@
+package oauth
+
+// AuthorizationQuery represents the authorization request the consent screen was opened with
+type AuthorizationQuery struct {
+	ClientID            string `form:"client_id" binding:"required,max=64"`
+	RedirectURI         string `form:"redirect_uri" binding:"required,max=2048"`
+	ResponseType        string `form:"response_type" binding:"required,eq=code"`
+	Scope               string `form:"scope" binding:"required,max=512"`
+	State               string `form:"state" binding:"omitempty,max=512"`
+	CodeChallenge       string `form:"code_challenge" binding:"required,len=43"`
+	CodeChallengeMethod string `form:"code_challenge_method" binding:"required"`
+	Nonce               string `form:"nonce" binding:"omitempty,max=512"`
+}
+
+// AuthorizeRequest represents the user's answer to an authorization request
+type AuthorizeRequest struct {
+	ClientID            string `json:"clientId" binding:"required,max=64"`
+	RedirectURI         string `json:"redirectUri" binding:"required,max=2048"`
+	Scope               string `json:"scope" binding:"required,max=512"`
+	State               string `json:"state" binding:"omitempty,max=512"`
+	CodeChallenge       string `json:"codeChallenge" binding:"required,len=43"`
+	CodeChallengeMethod string `json:"codeChallengeMethod" binding:"required"`
+	Nonce               string `json:"nonce" binding:"omitempty,max=512"`
+	Approve             *bool  `json:"approve" binding:"required"`
+}
+
+// TokenRequest represents a form-encoded request to the token endpoint
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// TokenLookupRequest represents a form-encoded introspection or revocation request
+type TokenLookupRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
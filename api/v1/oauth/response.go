@@ -0,0 +1,259 @@
+package oauth
+
+import (
+	"strings"
+
+	"cirrussync-api/internal/jwt"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/oauth"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// NewValidationError creates a validation error response
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Validation Error with requestId " + requestID,
+		},
+		Error: err.Error(),
+	}
+}
+
+// ClientData represents the public details of an OAuth client shown to users
+type ClientData struct {
+	ClientID    string `json:"clientId"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	HomepageURL string `json:"homepageUrl,omitempty"`
+}
+
+// AuthorizationPromptResponse represents what the consent screen shows the user
+type AuthorizationPromptResponse struct {
+	BaseResponse
+	Client         ClientData `json:"client"`
+	Scopes         []string   `json:"scopes"`
+	ConsentGranted bool       `json:"consentGranted"`
+}
+
+// AuthorizeResponse represents where to send the user after answering an authorization request
+type AuthorizeResponse struct {
+	BaseResponse
+	RedirectURL string `json:"redirectUrl"`
+}
+
+// AuthorizedAppData represents an application the user granted access to
+type AuthorizedAppData struct {
+	Client    ClientData `json:"client"`
+	Scopes    []string   `json:"scopes"`
+	GrantedAt int64      `json:"grantedAt"`
+}
+
+// AuthorizedAppsResponse represents the applications the user granted access to
+type AuthorizedAppsResponse struct {
+	BaseResponse
+	Apps []AuthorizedAppData `json:"apps"`
+}
+
+// ProtocolErrorResponse represents an error returned to OAuth clients, following RFC 6749
+type ProtocolErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// TokenResponse represents tokens issued to an OAuth client, following RFC 6749
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// IntrospectionResponse represents a token described to its client, following RFC 7662
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// UserInfoResponse represents the OpenID Connect claims about the token's user
+type UserInfoResponse struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+}
+
+// JWKSResponse represents the keys ID tokens are signed with
+type JWKSResponse struct {
+	Keys []jwt.JWK `json:"keys"`
+}
+
+// DiscoveryResponse represents the OpenID Connect discovery document
+type DiscoveryResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// newClientData converts an OAuth client to the details shown to users
+func newClientData(client *models.OAuthClient) ClientData {
+	return ClientData{
+		ClientID:    client.ClientID,
+		Name:        client.Name,
+		Description: client.Description,
+		HomepageURL: client.HomepageURL,
+	}
+}
+
+// NewAuthorizationPromptResponse creates a new authorization prompt response
+func NewAuthorizationPromptResponse(prompt *oauth.AuthorizationPrompt, code int16, requestID string) AuthorizationPromptResponse {
+	return AuthorizationPromptResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Client:         newClientData(prompt.Client),
+		Scopes:         prompt.Scopes,
+		ConsentGranted: prompt.ConsentGranted,
+	}
+}
+
+// NewAuthorizeResponse creates a new authorize response
+func NewAuthorizeResponse(redirectURL string, code int16, requestID string) AuthorizeResponse {
+	return AuthorizeResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		RedirectURL: redirectURL,
+	}
+}
+
+// NewAuthorizedAppsResponse creates a new authorized apps response
+func NewAuthorizedAppsResponse(apps []*oauth.AuthorizedApp, code int16, requestID string) AuthorizedAppsResponse {
+	data := make([]AuthorizedAppData, len(apps))
+	for i, app := range apps {
+		data[i] = AuthorizedAppData{
+			Client:    newClientData(app.Client),
+			Scopes:    app.Scopes,
+			GrantedAt: app.GrantedAt,
+		}
+	}
+
+	return AuthorizedAppsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Apps: data,
+	}
+}
+
+// NewProtocolErrorResponse creates an error response for OAuth clients
+func NewProtocolErrorResponse(err *oauth.Error) ProtocolErrorResponse {
+	return ProtocolErrorResponse{
+		Error:            err.Code,
+		ErrorDescription: err.Description,
+	}
+}
+
+// NewTokenResponse creates a new token response
+func NewTokenResponse(tokens *oauth.TokenSet) TokenResponse {
+	return TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		Scope:        strings.Join(tokens.Scopes, " "),
+		IDToken:      tokens.IDToken,
+	}
+}
+
+// NewIntrospectionResponse creates a new introspection response
+func NewIntrospectionResponse(introspection *oauth.Introspection) IntrospectionResponse {
+	if !introspection.Active {
+		return IntrospectionResponse{Active: false}
+	}
+
+	return IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(introspection.Scopes, " "),
+		ClientID:  introspection.ClientID,
+		Subject:   introspection.UserID,
+		Username:  introspection.Username,
+		TokenType: introspection.TokenType,
+		ExpiresAt: introspection.ExpiresAt,
+		IssuedAt:  introspection.IssuedAt,
+	}
+}
+
+// NewUserInfoResponse creates a new user info response
+func NewUserInfoResponse(info *oauth.UserInfo) UserInfoResponse {
+	return UserInfoResponse{
+		Subject:           info.Subject,
+		Email:             info.Email,
+		EmailVerified:     info.EmailVerified,
+		PreferredUsername: info.PreferredUsername,
+	}
+}
+
+// NewDiscoveryResponse creates a new discovery response
+func NewDiscoveryResponse(metadata oauth.ProviderMetadata) DiscoveryResponse {
+	return DiscoveryResponse{
+		Issuer:                            metadata.Issuer,
+		AuthorizationEndpoint:             metadata.AuthorizationEndpoint,
+		TokenEndpoint:                     metadata.TokenEndpoint,
+		IntrospectionEndpoint:             metadata.IntrospectionEndpoint,
+		RevocationEndpoint:                metadata.RevocationEndpoint,
+		UserInfoEndpoint:                  metadata.UserInfoEndpoint,
+		JWKSURI:                           metadata.JWKSURI,
+		ScopesSupported:                   metadata.ScopesSupported,
+		ResponseTypesSupported:            metadata.ResponseTypesSupported,
+		GrantTypesSupported:               metadata.GrantTypesSupported,
+		CodeChallengeMethodsSupported:     metadata.CodeChallengeMethodsSupported,
+		TokenEndpointAuthMethodsSupported: metadata.TokenEndpointAuthMethodsSupported,
+		SubjectTypesSupported:             metadata.SubjectTypesSupported,
+		IDTokenSigningAlgValuesSupported:  metadata.IDTokenSigningAlgValuesSupported,
+	}
+}
@@ -0,0 +1,61 @@
+package oauth
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientPathPrefix is where the endpoints called by OAuth clients are served. Clients authenticate
+// with their own credentials or tokens rather than a session, so CSRF protection does not apply.
+const ClientPathPrefix = "/api/v1/oauth/"
+
+// clientPaths are the endpoints under ClientPathPrefix called by OAuth clients
+var clientPaths = []string{"token", "introspect", "revoke", "userinfo", "jwks"}
+
+// IsClientPath reports whether a request path is an endpoint called by OAuth clients
+func IsClientPath(path string) bool {
+	endpoint, ok := strings.CutPrefix(path, ClientPathPrefix)
+	if !ok {
+		return false
+	}
+	for _, clientPath := range clientPaths {
+		if endpoint == clientPath {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterDiscoveryRoutes registers the OpenID Connect discovery document, which clients look
+// for at the root of the issuer
+func RegisterDiscoveryRoutes(r *gin.Engine, h *Handler) {
+	r.GET("/.well-known/openid-configuration", h.GetDiscovery)
+}
+
+// RegisterClientRoutes registers the endpoints called by OAuth clients
+func RegisterClientRoutes(r *gin.RouterGroup, h *Handler, tokenAuth gin.HandlerFunc) {
+	oauthGroup := r.Group("/oauth")
+	{
+		oauthGroup.GET("/jwks", h.GetJWKS)
+		oauthGroup.POST("/token", h.Token)
+		oauthGroup.POST("/introspect", h.Introspect)
+		oauthGroup.POST("/revoke", h.Revoke)
+
+		// OpenID Connect allows both methods for the user info endpoint
+		oauthGroup.GET("/userinfo", tokenAuth, h.GetUserInfo)
+		oauthGroup.POST("/userinfo", tokenAuth, h.GetUserInfo)
+	}
+}
+
+// RegisterConsentRoutes registers the routes the consent screen and account settings use
+func RegisterConsentRoutes(r *gin.RouterGroup, h *Handler) {
+	consentGroup := r.Group("")
+	{
+		consentGroup.GET("/authorize", h.GetAuthorizationPrompt)
+		consentGroup.POST("/authorize", h.Authorize)
+
+		consentGroup.GET("/authorizations", h.ListAuthorizedApps)
+		consentGroup.DELETE("/authorizations/:clientID", h.RevokeAuthorization)
+	}
+}
@@ -0,0 +1,416 @@
+package org
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/org"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxMemberImportSize bounds the body of a share member import; key packets make rows around 2 KiB
+const maxMemberImportSize = 4 << 20
+
+// Handler handles organization API requests
+type Handler struct {
+	orgService *org.Service
+	logger     *logger.Logger
+}
+
+// NewHandler creates a new organization handler
+func NewHandler(orgService *org.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		orgService: orgService,
+		logger:     log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// handleServiceError maps service errors to appropriate HTTP responses
+func (h *Handler) handleServiceError(c *gin.Context, err error, route string) {
+	h.secureLog(c, err, "Error in "+route, route)
+
+	statusCode := http.StatusInternalServerError
+	apiStatus := status.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, org.ErrOrganizationNotFound),
+		errors.Is(err, org.ErrServiceAccountNotFound),
+		errors.Is(err, org.ErrAccessTokenNotFound),
+		errors.Is(err, org.ErrUserNotFound),
+		errors.Is(err, org.ErrEncryptionKeyNotFound),
+		errors.Is(err, drive.ErrShareNotFound):
+		statusCode = http.StatusNotFound
+		apiStatus = status.StatusNotFound
+
+	case errors.Is(err, org.ErrNotOrganizationAdmin),
+		errors.Is(err, drive.ErrUnauthorized),
+		errors.Is(err, drive.ErrInsufficientPermissions):
+		statusCode = http.StatusForbidden
+		apiStatus = status.StatusForbidden
+
+	case errors.Is(err, org.ErrMemberAlreadyExists),
+		errors.Is(err, org.ErrServiceAccountDisabled),
+		errors.Is(err, org.ErrTooManyAccessTokens),
+		errors.Is(err, drive.ErrMembershipAlreadyExists):
+		statusCode = http.StatusConflict
+		apiStatus = status.StatusConflict
+
+	case errors.Is(err, org.ErrInvalidRole),
+		errors.Is(err, drive.ErrInvalidBulkInvitations),
+		errors.Is(err, org.ErrInvalidScopes),
+		errors.Is(err, org.ErrInvalidExpiration),
+		errors.Is(err, org.ErrInvalidEncryptionKey):
+		statusCode = http.StatusBadRequest
+		apiStatus = status.StatusBadRequest
+
+	case errors.Is(err, org.ErrEncryptionKeyUnusable):
+		statusCode = http.StatusUnprocessableEntity
+		apiStatus = status.StatusValidationFailed
+
+	case errors.Is(err, org.ErrEncryptionKeyUnavailable),
+		errors.Is(err, org.ErrEncryptionKeyVerification):
+		statusCode = http.StatusServiceUnavailable
+		apiStatus = status.StatusServiceUnavailable
+	}
+
+	c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatus, middleware.RequestID(c)))
+}
+
+// CreateOrganization handles creating an organization administered by the caller
+func (h *Handler) CreateOrganization(c *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createOrganization")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	organization, err := h.orgService.CreateOrganization(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		h.handleServiceError(c, err, "createOrganization")
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewOrganizationResponse(organization, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// AddMember handles adding a user to an organization
+func (h *Handler) AddMember(c *gin.Context) {
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "addOrganizationMember")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	member, err := h.orgService.AddMember(c.Request.Context(), userID, c.Param("orgID"), req.UserID, req.Role)
+	if err != nil {
+		h.handleServiceError(c, err, "addOrganizationMember")
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewMemberResponse(member, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// ListServiceAccounts handles listing an organization's service accounts
+func (h *Handler) ListServiceAccounts(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.orgService.ListServiceAccounts(c.Request.Context(), userID, c.Param("orgID"))
+	if err != nil {
+		h.handleServiceError(c, err, "listServiceAccounts")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewServiceAccountsResponse(accounts, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CreateServiceAccount handles creating a service account with its key
+func (h *Handler) CreateServiceAccount(c *gin.Context) {
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createServiceAccount")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	key := &models.UserKey{
+		PublicKey:           req.Key.PublicKey,
+		PrivateKey:          req.Key.PrivateKey,
+		Passphrase:          req.Key.Passphrase,
+		PassphraseSignature: req.Key.PassphraseSignature,
+		Fingerprint:         req.Key.Fingerprint,
+		Version:             req.Key.Version,
+	}
+
+	account, err := h.orgService.CreateServiceAccount(c.Request.Context(), userID, c.Param("orgID"), req.Name, req.Description, key)
+	if err != nil {
+		h.handleServiceError(c, err, "createServiceAccount")
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewServiceAccountResponse(account, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// DisableServiceAccount handles disabling a service account and revoking its tokens
+func (h *Handler) DisableServiceAccount(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.orgService.DisableServiceAccount(c.Request.Context(), userID, c.Param("orgID"), c.Param("accountID"))
+	if err != nil {
+		h.handleServiceError(c, err, "disableServiceAccount")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Service account disabled", status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// AddShareMembership handles adding a service account to a share
+func (h *Handler) AddShareMembership(c *gin.Context) {
+	var req AddShareMembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "addServiceAccountMembership")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, err := h.orgService.AddServiceAccountToShare(
+		c.Request.Context(),
+		userID,
+		c.Param("orgID"),
+		c.Param("accountID"),
+		req.ShareID,
+		&models.DriveShareMembership{
+			Permissions:         req.Permissions,
+			KeyPacket:           req.KeyPacket,
+			KeyPacketSignature:  req.KeyPacketSignature,
+			SessionKeySignature: req.SessionKeySignature,
+		},
+	)
+	if err != nil {
+		h.handleServiceError(c, err, "addServiceAccountMembership")
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewMembershipResponse(membership, status.StatusShareCreated, middleware.RequestID(c)))
+}
+
+// ImportShareMembers handles inviting a list of users to a share of the organization, sent either as
+// JSON or as a CSV file with a header row
+func (h *Handler) ImportShareMembers(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMemberImportSize)
+
+	var invitations []drive.BulkInvitation
+	if c.ContentType() == "text/csv" {
+		parsed, err := parseMemberImportCSV(c.Request.Body)
+		if err != nil {
+			h.secureLog(c, err, "Invalid CSV import", "importShareMembers")
+			c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		invitations = parsed
+	} else {
+		var req ImportShareMembersRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.secureLog(c, err, "Invalid request format", "importShareMembers")
+			c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+			return
+		}
+		invitations = req.toBulkInvitations()
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.orgService.ImportShareMembers(c.Request.Context(), userID, c.Param("orgID"), c.Param("shareID"), invitations)
+	if err != nil {
+		h.handleServiceError(c, err, "importShareMembers")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewImportShareMembersResponse(results, status.StatusOK, middleware.RequestID(c)))
+}
+
+// ListAccessTokens handles listing a service account's access tokens
+func (h *Handler) ListAccessTokens(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.orgService.ListAccessTokens(c.Request.Context(), userID, c.Param("orgID"), c.Param("accountID"))
+	if err != nil {
+		h.handleServiceError(c, err, "listAccessTokens")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewAccessTokensResponse(tokens, status.StatusOK, middleware.RequestID(c)))
+}
+
+// CreateAccessToken handles issuing an access token for a service account
+func (h *Handler) CreateAccessToken(c *gin.Context) {
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "createAccessToken")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	token, secret, err := h.orgService.CreateAccessToken(
+		c.Request.Context(),
+		userID,
+		c.Param("orgID"),
+		c.Param("accountID"),
+		req.Name,
+		req.Scopes,
+		req.ExpiresInDays,
+	)
+	if err != nil {
+		h.handleServiceError(c, err, "createAccessToken")
+		return
+	}
+
+	// The secret is only ever shown in this response
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, NewCreatedAccessTokenResponse(token, secret, status.StatusCreated, middleware.RequestID(c)))
+}
+
+// RevokeAccessToken handles revoking a service account's access token
+func (h *Handler) RevokeAccessToken(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.orgService.RevokeAccessToken(c.Request.Context(), userID, c.Param("orgID"), c.Param("accountID"), c.Param("tokenID"))
+	if err != nil {
+		h.handleServiceError(c, err, "revokeAccessToken")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Access token revoked", status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// getUserID extracts the authenticated user ID, responding with 401 when it is missing
+func (h *Handler) getUserID(c *gin.Context) (string, bool) {
+	userIDInterface, exists := c.Get("userID")
+	userID, ok := userIDInterface.(string)
+	if !exists || !ok || userID == "" {
+		h.secureLog(c, session.ErrSessionNotFound, "Missing user in context", "getUserID")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(session.ErrSessionNotFound.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return "", false
+	}
+	return userID, true
+}
+
+// GetEncryptionKeys handles listing an organization's customer-managed encryption keys
+func (h *Handler) GetEncryptionKeys(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.orgService.ListEncryptionKeys(c.Request.Context(), userID, c.Param("orgID"))
+	if err != nil {
+		h.handleServiceError(c, err, "getEncryptionKeys")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewEncryptionKeysResponse(keys, status.StatusOK, middleware.RequestID(c)))
+}
+
+// SetEncryptionKey handles setting or rotating an organization's customer-managed KMS key.
+//
+// The key must allow the CirrusSync storage account kms:GenerateDataKey and kms:Decrypt; it is
+// verified before it is accepted. New thumbnails of the organization owner are encrypted with it.
+// Setting a different key rotates: objects written earlier stay encrypted with their original key,
+// so keep replaced keys enabled. Disabling a key or revoking CirrusSync's grant in KMS makes every
+// object encrypted with it unreadable until access is restored; nothing is deleted.
+func (h *Handler) SetEncryptionKey(c *gin.Context) {
+	var req SetEncryptionKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "setEncryptionKey")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.orgService.SetEncryptionKey(c.Request.Context(), userID, c.Param("orgID"), req.KeyARN)
+	if err != nil {
+		h.handleServiceError(c, err, "setEncryptionKey")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewEncryptionKeyResponse(key, status.StatusOK, middleware.RequestID(c)))
+}
+
+// DisableEncryptionKey handles stopping the use of an organization's customer-managed key.
+// New objects use the default storage encryption; existing objects still need the key to be read.
+func (h *Handler) DisableEncryptionKey(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.DisableEncryptionKey(c.Request.Context(), userID, c.Param("orgID")); err != nil {
+		h.handleServiceError(c, err, "disableEncryptionKey")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Encryption key disabled", status.StatusOK, middleware.RequestID(c)))
+}
@@ -0,0 +1,137 @@
+package org
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"cirrussync-api/internal/drive"
+)
+
+// Columns of a CSV member import; the header row may list them in any order
+var memberImportColumns = []string{"email", "preset", "keyPacket", "keyPacketSignature", "sessionKeySignature"}
+
+// errInvalidMemberImportCSV is returned for CSV imports that cannot be read
+var errInvalidMemberImportCSV = errors.New("CSV must have a header row with email, preset, keyPacket, keyPacketSignature and sessionKeySignature columns")
+
+// CreateOrganizationRequest represents a request to create an organization
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// AddMemberRequest represents a request to add a user to an organization
+type AddMemberRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	Role   int    `json:"role" binding:"required,oneof=1 2"`
+}
+
+// ServiceAccountKey represents the client-generated key of a service account
+type ServiceAccountKey struct {
+	PublicKey           string `json:"publicKey" binding:"required"`
+	PrivateKey          string `json:"privateKey" binding:"required"`
+	Passphrase          string `json:"passphrase" binding:"required"`
+	PassphraseSignature string `json:"passphraseSignature" binding:"required"`
+	Fingerprint         string `json:"fingerprint" binding:"required"`
+	Version             int    `json:"version" binding:"required,min=1"`
+}
+
+// CreateServiceAccountRequest represents a request to create a service account
+type CreateServiceAccountRequest struct {
+	Name        string            `json:"name" binding:"required,max=100"`
+	Description string            `json:"description" binding:"max=255"`
+	Key         ServiceAccountKey `json:"key" binding:"required"`
+}
+
+// CreateAccessTokenRequest represents a request to issue an access token
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+// AddShareMembershipRequest represents a request to add a service account to a share
+type AddShareMembershipRequest struct {
+	ShareID             string `json:"shareId" binding:"required"`
+	Permissions         int    `json:"permissions" binding:"required,min=1"`
+	KeyPacket           string `json:"keyPacket" binding:"required"`
+	KeyPacketSignature  string `json:"keyPacketSignature" binding:"required"`
+	SessionKeySignature string `json:"sessionKeySignature" binding:"required"`
+}
+
+// SetEncryptionKeyRequest represents a request to set or rotate an organization's customer-managed KMS key
+type SetEncryptionKeyRequest struct {
+	KeyARN string `json:"keyArn" binding:"required,max=2048"`
+}
+
+// ImportShareMemberRow represents one member of a share member import. Rows are validated one by
+// one so a bad row is reported without failing the import.
+type ImportShareMemberRow struct {
+	Email               string `json:"email"`
+	Preset              string `json:"preset"`
+	KeyPacket           string `json:"keyPacket"`
+	KeyPacketSignature  string `json:"keyPacketSignature"`
+	SessionKeySignature string `json:"sessionKeySignature"`
+}
+
+// ImportShareMembersRequest represents a request to invite a list of users to a share
+type ImportShareMembersRequest struct {
+	Members []ImportShareMemberRow `json:"members" binding:"required,min=1,max=500"`
+}
+
+// toBulkInvitations converts the rows of an import to drive invitations
+func (r ImportShareMembersRequest) toBulkInvitations() []drive.BulkInvitation {
+	invitations := make([]drive.BulkInvitation, len(r.Members))
+	for i, member := range r.Members {
+		invitations[i] = drive.BulkInvitation(member)
+	}
+	return invitations
+}
+
+// parseMemberImportCSV reads a CSV member import into drive invitations
+func parseMemberImportCSV(body io.Reader) ([]drive.BulkInvitation, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errInvalidMemberImportCSV
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	indexes := make([]int, len(memberImportColumns))
+	for i, name := range memberImportColumns {
+		index, ok := columns[strings.ToLower(name)]
+		if !ok {
+			return nil, errInvalidMemberImportCSV
+		}
+		indexes[i] = index
+	}
+
+	var invitations []drive.BulkInvitation
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errInvalidMemberImportCSV
+		}
+		if len(invitations) == drive.MAX_BULK_INVITATIONS {
+			return nil, drive.ErrInvalidBulkInvitations
+		}
+
+		invitations = append(invitations, drive.BulkInvitation{
+			Email:               record[indexes[0]],
+			Preset:              strings.ToLower(strings.TrimSpace(record[indexes[1]])),
+			KeyPacket:           record[indexes[2]],
+			KeyPacketSignature:  record[indexes[3]],
+			SessionKeySignature: record[indexes[4]],
+		})
+	}
+
+	return invitations, nil
+}
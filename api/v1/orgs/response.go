@@ -0,0 +1,389 @@
+package org
+
+import (
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/models"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// SuccessResponse represents a simple success message
+type SuccessResponse struct {
+	BaseResponse
+	Message string `json:"message,omitempty"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// NewSuccessResponse creates a new success response
+func NewSuccessResponse(message string, code int16, requestID string) SuccessResponse {
+	return SuccessResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Message: message,
+	}
+}
+
+// NewValidationError creates a validation error response
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Validation Error with requestId " + requestID,
+		},
+		Error: err.Error(),
+	}
+}
+
+// OrganizationData represents an organization in API responses
+type OrganizationData struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	OwnerID   string `json:"ownerId"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// OrganizationResponse represents a response with an organization
+type OrganizationResponse struct {
+	BaseResponse
+	Organization OrganizationData `json:"organization"`
+}
+
+// NewOrganizationResponse creates a new organization response
+func NewOrganizationResponse(organization *models.Organization, code int16, requestID string) OrganizationResponse {
+	return OrganizationResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Organization: OrganizationData{
+			ID:        organization.ID,
+			Name:      organization.Name,
+			OwnerID:   organization.OwnerID,
+			CreatedAt: organization.CreatedAt,
+		},
+	}
+}
+
+// MemberData represents an organization member in API responses
+type MemberData struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	Role   int    `json:"role"`
+}
+
+// MemberResponse represents a response with an organization member
+type MemberResponse struct {
+	BaseResponse
+	Member MemberData `json:"member"`
+}
+
+// NewMemberResponse creates a new member response
+func NewMemberResponse(member *models.OrganizationMember, code int16, requestID string) MemberResponse {
+	return MemberResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Member: MemberData{
+			ID:     member.ID,
+			UserID: member.UserID,
+			Role:   member.Role,
+		},
+	}
+}
+
+// ServiceAccountData represents a service account in API responses
+type ServiceAccountData struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatedBy   string `json:"createdBy"`
+	State       int    `json:"state"`
+	CreatedAt   int64  `json:"createdAt"`
+}
+
+// ServiceAccountResponse represents a response with a service account
+type ServiceAccountResponse struct {
+	BaseResponse
+	ServiceAccount ServiceAccountData `json:"serviceAccount"`
+}
+
+// ServiceAccountsResponse represents a response with a list of service accounts
+type ServiceAccountsResponse struct {
+	BaseResponse
+	ServiceAccounts []ServiceAccountData `json:"serviceAccounts"`
+}
+
+// convertServiceAccount converts a service account model to response data
+func convertServiceAccount(account *models.ServiceAccount) ServiceAccountData {
+	return ServiceAccountData{
+		ID:          account.ID,
+		UserID:      account.UserID,
+		Name:        account.Name,
+		Description: account.Description,
+		CreatedBy:   account.CreatedBy,
+		State:       account.State,
+		CreatedAt:   account.CreatedAt,
+	}
+}
+
+// NewServiceAccountResponse creates a new service account response
+func NewServiceAccountResponse(account *models.ServiceAccount, code int16, requestID string) ServiceAccountResponse {
+	return ServiceAccountResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		ServiceAccount: convertServiceAccount(account),
+	}
+}
+
+// NewServiceAccountsResponse creates a new service accounts list response
+func NewServiceAccountsResponse(accounts []*models.ServiceAccount, code int16, requestID string) ServiceAccountsResponse {
+	data := make([]ServiceAccountData, len(accounts))
+	for i, account := range accounts {
+		data[i] = convertServiceAccount(account)
+	}
+
+	return ServiceAccountsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		ServiceAccounts: data,
+	}
+}
+
+// AccessTokenData represents an access token in API responses. The secret is never included.
+type AccessTokenData struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedBy  string   `json:"createdBy"`
+	CreatedAt  int64    `json:"createdAt"`
+	ExpiresAt  *int64   `json:"expiresAt"`
+	LastUsedAt *int64   `json:"lastUsedAt"`
+	LastUsedIP *string  `json:"lastUsedIp"`
+	RevokedAt  *int64   `json:"revokedAt"`
+}
+
+// AccessTokensResponse represents a response with a list of access tokens
+type AccessTokensResponse struct {
+	BaseResponse
+	Tokens []AccessTokenData `json:"tokens"`
+}
+
+// CreatedAccessTokenResponse represents a response with a newly issued access token
+type CreatedAccessTokenResponse struct {
+	BaseResponse
+	Token  AccessTokenData `json:"token"`
+	Secret string          `json:"secret"`
+}
+
+// convertAccessToken converts an access token model to response data
+func convertAccessToken(token *models.AccessToken) AccessTokenData {
+	return AccessTokenData{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		CreatedBy:  token.CreatedBy,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		LastUsedIP: token.LastUsedIP,
+		RevokedAt:  token.RevokedAt,
+	}
+}
+
+// NewAccessTokensResponse creates a new access tokens list response
+func NewAccessTokensResponse(tokens []*models.AccessToken, code int16, requestID string) AccessTokensResponse {
+	data := make([]AccessTokenData, len(tokens))
+	for i, token := range tokens {
+		data[i] = convertAccessToken(token)
+	}
+
+	return AccessTokensResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Tokens: data,
+	}
+}
+
+// NewCreatedAccessTokenResponse creates a response carrying a token secret, shown only once
+func NewCreatedAccessTokenResponse(token *models.AccessToken, secret string, code int16, requestID string) CreatedAccessTokenResponse {
+	return CreatedAccessTokenResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Token:  convertAccessToken(token),
+		Secret: secret,
+	}
+}
+
+// MembershipResponse represents a response with a share membership
+type MembershipResponse struct {
+	BaseResponse
+	MembershipID string `json:"membershipId"`
+	ShareID      string `json:"shareId"`
+	UserID       string `json:"userId"`
+	Permissions  int    `json:"permissions"`
+}
+
+// NewMembershipResponse creates a new share membership response
+func NewMembershipResponse(membership *models.DriveShareMembership, code int16, requestID string) MembershipResponse {
+	return MembershipResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		MembershipID: membership.ID,
+		ShareID:      membership.ShareID,
+		UserID:       membership.UserID,
+		Permissions:  membership.Permissions,
+	}
+}
+
+// Share member import row outcomes
+const (
+	IMPORT_ROW_INVITED = "invited"
+	IMPORT_ROW_FAILED  = "failed"
+)
+
+// ImportShareMemberResult represents the outcome of one row of a share member import
+type ImportShareMemberResult struct {
+	Row          int    `json:"row"`
+	Email        string `json:"email"`
+	Status       string `json:"status"`
+	InvitationID string `json:"invitationId,omitempty"`
+	Permissions  int    `json:"permissions,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ImportShareMembersResponse represents the per-row results of a share member import
+type ImportShareMembersResponse struct {
+	BaseResponse
+	Invited int                       `json:"invited"`
+	Failed  int                       `json:"failed"`
+	Results []ImportShareMemberResult `json:"results"`
+}
+
+// NewImportShareMembersResponse creates a new share member import response
+func NewImportShareMembersResponse(results []*drive.BulkInvitationResult, code int16, requestID string) ImportShareMembersResponse {
+	response := ImportShareMembersResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Results: make([]ImportShareMemberResult, len(results)),
+	}
+
+	for i, result := range results {
+		row := ImportShareMemberResult{
+			Row:    result.Row,
+			Email:  result.Email,
+			Status: IMPORT_ROW_INVITED,
+		}
+		if result.Err != nil {
+			row.Status = IMPORT_ROW_FAILED
+			row.Error = result.Err.Error()
+			response.Failed++
+		} else {
+			row.InvitationID = result.Invitation.ID
+			row.Permissions = result.Invitation.Permissions
+			response.Invited++
+		}
+		response.Results[i] = row
+	}
+
+	return response
+}
+
+// EncryptionKeyData represents a customer-managed encryption key of an organization
+type EncryptionKeyData struct {
+	ID         string `json:"id"`
+	KeyARN     string `json:"keyArn"`
+	State      int    `json:"state"`
+	CreatedBy  string `json:"createdBy"`
+	VerifiedAt int64  `json:"verifiedAt"`
+	RetiredAt  *int64 `json:"retiredAt,omitempty"`
+	CreatedAt  int64  `json:"createdAt"`
+}
+
+// EncryptionKeyResponse represents an organization's active encryption key
+type EncryptionKeyResponse struct {
+	BaseResponse
+	Key EncryptionKeyData `json:"key"`
+}
+
+// EncryptionKeysResponse represents an organization's active key and the keys it replaced
+type EncryptionKeysResponse struct {
+	BaseResponse
+	Keys []EncryptionKeyData `json:"keys"`
+}
+
+func convertEncryptionKey(key *models.OrganizationEncryptionKey) EncryptionKeyData {
+	return EncryptionKeyData{
+		ID:         key.ID,
+		KeyARN:     key.KeyARN,
+		State:      key.State,
+		CreatedBy:  key.CreatedBy,
+		VerifiedAt: key.VerifiedAt,
+		RetiredAt:  key.RetiredAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// NewEncryptionKeyResponse creates a new encryption key response
+func NewEncryptionKeyResponse(key *models.OrganizationEncryptionKey, code int16, requestID string) EncryptionKeyResponse {
+	return EncryptionKeyResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Key: convertEncryptionKey(key),
+	}
+}
+
+// NewEncryptionKeysResponse creates a new encryption keys response
+func NewEncryptionKeysResponse(keys []*models.OrganizationEncryptionKey, code int16, requestID string) EncryptionKeysResponse {
+	data := make([]EncryptionKeyData, len(keys))
+	for i, key := range keys {
+		data[i] = convertEncryptionKey(key)
+	}
+
+	return EncryptionKeysResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Keys: data,
+	}
+}
@@ -0,0 +1,31 @@
+package org
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterProtectedRoutes registers organization routes
+func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
+	orgGroup := r.Group("")
+	{
+		orgGroup.POST("", h.CreateOrganization)
+		orgGroup.POST("/:orgID/members", h.AddMember)
+		orgGroup.POST("/:orgID/shares/:shareID/members/import", h.ImportShareMembers)
+
+		// Service accounts
+		orgGroup.GET("/:orgID/service-accounts", h.ListServiceAccounts)
+		orgGroup.POST("/:orgID/service-accounts", h.CreateServiceAccount)
+		orgGroup.DELETE("/:orgID/service-accounts/:accountID", h.DisableServiceAccount)
+		orgGroup.POST("/:orgID/service-accounts/:accountID/memberships", h.AddShareMembership)
+
+		// Access tokens
+		orgGroup.GET("/:orgID/service-accounts/:accountID/tokens", h.ListAccessTokens)
+		orgGroup.POST("/:orgID/service-accounts/:accountID/tokens", h.CreateAccessToken)
+		orgGroup.DELETE("/:orgID/service-accounts/:accountID/tokens/:tokenID", h.RevokeAccessToken)
+
+		// Customer-managed encryption keys
+		orgGroup.GET("/:orgID/encryption-keys", h.GetEncryptionKeys)
+		orgGroup.PUT("/:orgID/encryption-key", h.SetEncryptionKey)
+		orgGroup.DELETE("/:orgID/encryption-key", h.DisableEncryptionKey)
+	}
+}
@@ -0,0 +1,152 @@
+package security
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler handles security event requests
+type Handler struct {
+	securityService *security.Service
+	logger          *logger.Logger
+}
+
+// NewHandler creates a new security event handler
+func NewHandler(securityService *security.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		securityService: securityService,
+		logger:          log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// ListEvents handles listing the current user's security events, newest first.
+// Events can be filtered by type, with eventType repeated for several types, and by a time range.
+func (h *Handler) ListEvents(c *gin.Context) {
+	var query ListEventsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.secureLog(c, err, "Invalid request format", "listSecurityEvents")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	page, err := h.securityService.ListEvents(c.Request.Context(), userID, security.EventFilter{
+		EventTypes: query.EventTypes,
+		Since:      query.Since,
+		Until:      query.Until,
+		Page:       query.Page,
+		Limit:      query.Limit,
+	})
+	if err != nil {
+		h.secureLog(c, err, "Failed to list security events", "listSecurityEvents")
+		switch {
+		case errors.Is(err, security.ErrInvalidTimeRange), errors.Is(err, security.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest, middleware.RequestID(c)))
+		default:
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		}
+		return
+	}
+
+	// Events carry IP addresses, keep them out of shared caches
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewSecurityEventsResponse(page, status.StatusOK, middleware.RequestID(c)))
+}
+
+// ExportEvents handles requesting a downloadable report of the current user's security events.
+// The report is generated in the background and the user is emailed when it is ready.
+func (h *Handler) ExportEvents(c *gin.Context) {
+	var req ExportEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "exportSecurityEvents")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	download, err := h.securityService.RequestExport(c.Request.Context(), userID, security.ExportRequest{
+		Format:     req.Format,
+		EventTypes: req.EventTypes,
+		Since:      req.Since,
+		Until:      req.Until,
+	})
+	if err != nil {
+		h.secureLog(c, err, "Failed to request security event report", "exportSecurityEvents")
+		h.respondWithExportError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusAccepted, NewSecurityReportResponse(download, status.StatusAccepted, middleware.RequestID(c)))
+}
+
+// GetEventExport handles retrieving the status of a security event report, and its download link
+// once it is ready
+func (h *Handler) GetEventExport(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	downloadID := c.Param("downloadID")
+	if downloadID == "" {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("Report ID is required", status.StatusBadRequest, middleware.RequestID(c)))
+		return
+	}
+
+	download, err := h.securityService.GetExport(c.Request.Context(), userID, downloadID)
+	if err != nil {
+		h.secureLog(c, err, "Failed to get security event report", "getSecurityEventExport")
+		h.respondWithExportError(c, err)
+		return
+	}
+
+	// The download link grants access to the report, keep it out of shared caches
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewSecurityReportResponse(download, status.StatusOK, middleware.RequestID(c)))
+}
+
+// respondWithExportError maps security report errors to responses
+func (h *Handler) respondWithExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, security.ErrInvalidExportFormat), errors.Is(err, security.ErrInvalidTimeRange), errors.Is(err, security.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest, middleware.RequestID(c)))
+	case errors.Is(err, security.ErrExportNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+	case errors.Is(err, security.ErrExportInProgress):
+		c.JSON(http.StatusConflict, NewErrorResponse(err.Error(), status.StatusConflict, middleware.RequestID(c)))
+	case errors.Is(err, security.ErrExportsUnavailable):
+		c.JSON(http.StatusServiceUnavailable, NewErrorResponse(err.Error(), status.StatusServiceUnavailable, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+	}
+}
@@ -0,0 +1,18 @@
+package security
+
+// ListEventsQuery represents the filters of a security events listing. Times are Unix seconds.
+type ListEventsQuery struct {
+	EventTypes []string `form:"eventType" binding:"omitempty,max=10,dive,min=1,max=50"`
+	Since      int64    `form:"since" binding:"omitempty,min=0"`
+	Until      int64    `form:"until" binding:"omitempty,min=0"`
+	Page       int      `form:"page" binding:"omitempty,min=1"`
+	Limit      int      `form:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+// ExportEventsRequest represents a request for a downloadable report of security events. Times are Unix seconds.
+type ExportEventsRequest struct {
+	Format     string   `json:"format" binding:"required,oneof=csv json"`
+	EventTypes []string `json:"eventTypes" binding:"omitempty,max=10,dive,min=1,max=50"`
+	Since      int64    `json:"since" binding:"omitempty,min=0"`
+	Until      int64    `json:"until" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,128 @@
+package security
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"encoding/json"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// NewValidationError creates a validation error response
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Validation Error with requestId " + requestID,
+		},
+		Error: err.Error(),
+	}
+}
+
+// SecurityEventData represents a recorded security event
+type SecurityEventData struct {
+	ID        string          `json:"id"`
+	EventType string          `json:"eventType"`
+	Success   bool            `json:"success"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt int64           `json:"createdAt"`
+}
+
+// SecurityEventsResponse represents a page of the user's security events, newest first
+type SecurityEventsResponse struct {
+	BaseResponse
+	Events []SecurityEventData `json:"events"`
+	Page   int                 `json:"page"`
+	Limit  int                 `json:"limit"`
+	Total  int64               `json:"total"`
+}
+
+// NewSecurityEventsResponse creates a new security events response
+func NewSecurityEventsResponse(page *security.EventPage, code int16, requestID string) SecurityEventsResponse {
+	events := make([]SecurityEventData, len(page.Events))
+	for i, event := range page.Events {
+		events[i] = SecurityEventData{
+			ID:        event.ID,
+			EventType: event.EventType,
+			Success:   event.Success,
+			Metadata:  event.AdditionalMetadata,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+
+	return SecurityEventsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Events: events,
+		Page:   page.Page,
+		Limit:  page.Limit,
+		Total:  page.Total,
+	}
+}
+
+// SecurityReportData represents a requested security event report
+type SecurityReportData struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Format      string `json:"format"`
+	Progress    int    `json:"progress"`
+	FileName    string `json:"fileName"`
+	FileSize    string `json:"fileSize,omitempty"`
+	DownloadURL string `json:"downloadUrl,omitempty"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   int64  `json:"createdAt"`
+	CompletedAt int64  `json:"completedAt,omitempty"`
+	ExpiresAt   int64  `json:"expiresAt,omitempty"`
+}
+
+// SecurityReportResponse represents a response with a security event report
+type SecurityReportResponse struct {
+	BaseResponse
+	Report SecurityReportData `json:"report"`
+}
+
+// NewSecurityReportResponse creates a new security event report response
+func NewSecurityReportResponse(download *models.SecurityEventDownload, code int16, requestID string) SecurityReportResponse {
+	return SecurityReportResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Report: SecurityReportData{
+			ID:          download.ID,
+			Status:      download.Status,
+			Format:      download.Format,
+			Progress:    download.Progress,
+			FileName:    download.FileName,
+			FileSize:    download.FileSize,
+			DownloadURL: download.DownloadURL,
+			Error:       download.ErrorMessage,
+			CreatedAt:   download.CreatedAt,
+			CompletedAt: download.CompletedAt,
+			ExpiresAt:   download.ExpiresAt,
+		},
+	}
+}
@@ -0,0 +1,12 @@
+package security
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterUserRoutes registers the current user's security event routes on the users group
+func RegisterUserRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/@me/security/events", h.ListEvents)
+	r.POST("/@me/security/events/export", h.ExportEvents)
+	r.GET("/@me/security/events/exports/:downloadID", h.GetEventExport)
+}
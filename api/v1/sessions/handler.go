@@ -1,11 +1,11 @@
 package session
 
 import (
+	"cirrussync-api/internal/middleware"
 	"net/http"
 
 	"cirrussync-api/internal/logger"
 	"cirrussync-api/internal/session"
-	"cirrussync-api/internal/utils"
 	"cirrussync-api/pkg/status"
 
 	"github.com/gin-gonic/gin"
@@ -15,27 +15,26 @@ import (
 // Handler handles session-related requests
 type Handler struct {
 	sessionService *session.Service
+	cookies        *middleware.Cookies
 	logger         *logger.Logger
 }
 
 // NewHandler creates a new session handler
-func NewHandler(sessionService *session.Service, log *logger.Logger) *Handler {
+func NewHandler(sessionService *session.Service, cookies *middleware.Cookies, log *logger.Logger) *Handler {
 	return &Handler{
 		sessionService: sessionService,
+		cookies:        cookies,
 		logger:         log,
 	}
 }
 
-// secureLog logs errors without sensitive data that might expose code or credentials
-func (h *Handler) secureLog(err error, message string, route string) {
-	// Generate request ID internally
-	requestID := utils.GenerateShortID()
-
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
 	// Log only necessary information, avoid including stack traces or request bodies
-	h.logger.WithFields(logrus.Fields{
-		"requestID": requestID,
-		"route":     route,
-		"errorMsg":  err.Error(),
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
 	}).Error(message)
 }
 
@@ -44,23 +43,23 @@ func (h *Handler) GetCurrentSession(c *gin.Context) {
 	// Get session ID from context (set by auth middleware)
 	sessionID, exists := c.Get("sessionID")
 	if !exists {
-		h.secureLog(session.ErrSessionNotFound, "Session ID not found in context", "getSession")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("Session not found", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrSessionNotFound, "Session ID not found in context", "getSession")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("Session not found", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Convert to string
 	sessionIDStr, ok := sessionID.(string)
 	if !ok {
-		h.secureLog(session.ErrInvalidInput, "Invalid session ID format", "getSession")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid session format", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "Invalid session ID format", "getSession")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid session format", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Get session from service
 	userSession, err := h.sessionService.GetSessionByID(c, sessionIDStr)
 	if err != nil {
-		h.secureLog(err, err.Error(), "getSession")
+		h.secureLog(c, err, err.Error(), "getSession")
 
 		statusCode := http.StatusInternalServerError
 		apiStatus := status.StatusInternalServerError
@@ -77,12 +76,12 @@ func (h *Handler) GetCurrentSession(c *gin.Context) {
 			apiStatus = status.StatusInvalidSession
 		}
 
-		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatus))
+		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatus, middleware.RequestID(c)))
 		return
 	}
 
 	// Return the session information
-	c.JSON(http.StatusOK, NewSessionResponse(userSession, status.StatusOK))
+	c.JSON(http.StatusOK, NewSessionResponse(userSession, status.StatusOK, middleware.RequestID(c)))
 }
 
 // InvalidateSession invalidates the current session (logout)
@@ -90,23 +89,23 @@ func (h *Handler) InvalidateSession(c *gin.Context) {
 	// Get session ID from context
 	sessionID, exists := c.Get("sessionID")
 	if !exists {
-		h.secureLog(session.ErrSessionNotFound, "Session ID not found in context", "invalidateSession")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("Session not found", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrSessionNotFound, "Session ID not found in context", "invalidateSession")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("Session not found", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Convert to string
 	sessionIDStr, ok := sessionID.(string)
 	if !ok {
-		h.secureLog(session.ErrInvalidInput, "Invalid session ID format", "invalidateSession")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid session format", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "Invalid session ID format", "invalidateSession")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid session format", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Invalidate session
 	err := h.sessionService.InvalidateSession(c, sessionIDStr)
 	if err != nil {
-		h.secureLog(err, err.Error(), "invalidateSession")
+		h.secureLog(c, err, err.Error(), "invalidateSession")
 
 		statusCode := http.StatusInternalServerError
 		apiStatus := status.StatusInternalServerError
@@ -120,16 +119,14 @@ func (h *Handler) InvalidateSession(c *gin.Context) {
 			apiStatus = status.StatusBadRequest
 		}
 
-		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatus))
+		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatus, middleware.RequestID(c)))
 		return
 	}
 
 	// Clear cookies if they exist
-	c.SetCookie("sessionID", "", -1, "/", "localhost", false, true)
-	c.SetCookie("accessToken", "", -1, "/", "localhost", false, true)
-	c.SetCookie("refreshToken", "", -1, "/", "localhost", false, true)
+	h.cookies.ClearSession(c)
 
-	c.JSON(http.StatusOK, NewSuccessResponse("Session invalidated successfully", status.StatusOK))
+	c.JSON(http.StatusOK, NewSuccessResponse("Session invalidated successfully", status.StatusOK, middleware.RequestID(c)))
 }
 
 // GetAllActiveSessions retrieves all active sessions for the current user
@@ -137,29 +134,29 @@ func (h *Handler) GetAllActiveSessions(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("userID")
 	if !exists {
-		h.secureLog(session.ErrInvalidInput, "User ID not found in context", "getAllSessions")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "User ID not found in context", "getAllSessions")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Convert to string
 	userIDStr, ok := userID.(string)
 	if !ok {
-		h.secureLog(session.ErrInvalidInput, "Invalid user ID format", "getAllSessions")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid user ID format", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "Invalid user ID format", "getAllSessions")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid user ID format", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Get all user sessions
 	sessions, err := h.sessionService.GetUserSessions(c, userIDStr)
 	if err != nil {
-		h.secureLog(err, err.Error(), "getAllSessions")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "getAllSessions")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Return all active sessions
-	c.JSON(http.StatusOK, NewSessionsListResponse(sessions, status.StatusOK))
+	c.JSON(http.StatusOK, NewSessionsListResponse(sessions, c.GetString("sessionID"), status.StatusOK, middleware.RequestID(c)))
 }
 
 // InvalidateAllSessions invalidates all sessions for the current user
@@ -167,33 +164,31 @@ func (h *Handler) InvalidateAllSessions(c *gin.Context) {
 	// Get user ID from context
 	userID, exists := c.Get("userID")
 	if !exists {
-		h.secureLog(session.ErrInvalidInput, "User ID not found in context", "invalidateAllSessions")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "User ID not found in context", "invalidateAllSessions")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Convert to string
 	userIDStr, ok := userID.(string)
 	if !ok {
-		h.secureLog(session.ErrInvalidInput, "Invalid user ID format", "invalidateAllSessions")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid user ID format", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "Invalid user ID format", "invalidateAllSessions")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid user ID format", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Invalidate all sessions
 	err := h.sessionService.InvalidateAllUserSessions(c, userIDStr)
 	if err != nil {
-		h.secureLog(err, err.Error(), "invalidateAllSessions")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "invalidateAllSessions")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Clear cookies if they exist
-	c.SetCookie("sessionID", "", -1, "/", "localhost", false, true)
-	c.SetCookie("accessToken", "", -1, "/", "localhost", false, true)
-	c.SetCookie("refreshToken", "", -1, "/", "localhost", false, true)
+	h.cookies.ClearSession(c)
 
-	c.JSON(http.StatusOK, NewSuccessResponse("All sessions invalidated successfully", status.StatusOK))
+	c.JSON(http.StatusOK, NewSuccessResponse("All sessions invalidated successfully", status.StatusOK, middleware.RequestID(c)))
 }
 
 // InvalidateSessionByID invalidates a specific session by ID
@@ -201,31 +196,31 @@ func (h *Handler) InvalidateSessionByID(c *gin.Context) {
 	// Get session ID from URL parameters
 	sessionID := c.Param("id")
 	if sessionID == "" {
-		h.secureLog(session.ErrInvalidInput, "Session ID not provided", "invalidateSessionById")
-		c.JSON(http.StatusBadRequest, NewErrorResponse("Session ID required", status.StatusBadRequest))
+		h.secureLog(c, session.ErrInvalidInput, "Session ID not provided", "invalidateSessionById")
+		c.JSON(http.StatusBadRequest, NewErrorResponse("Session ID required", status.StatusBadRequest, middleware.RequestID(c)))
 		return
 	}
 
 	// Get user ID from context
 	userID, exists := c.Get("userID")
 	if !exists {
-		h.secureLog(session.ErrInvalidInput, "User ID not found in context", "invalidateSessionById")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "User ID not found in context", "invalidateSessionById")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Convert to string
 	userIDStr, ok := userID.(string)
 	if !ok {
-		h.secureLog(session.ErrInvalidInput, "Invalid user ID format", "invalidateSessionById")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid user ID format", status.StatusUnauthorized))
+		h.secureLog(c, session.ErrInvalidInput, "Invalid user ID format", "invalidateSessionById")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("Invalid user ID format", status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Verify the session belongs to the user
 	currentSession, err := h.sessionService.GetSessionByID(c, sessionID)
 	if err != nil {
-		h.secureLog(err, err.Error(), "invalidateSessionById")
+		h.secureLog(c, err, err.Error(), "invalidateSessionById")
 
 		statusCode := http.StatusInternalServerError
 		apiStatus := status.StatusInternalServerError
@@ -242,32 +237,82 @@ func (h *Handler) InvalidateSessionByID(c *gin.Context) {
 			apiStatus = status.StatusInvalidSession
 		}
 
-		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatus))
+		c.JSON(statusCode, NewErrorResponse(err.Error(), apiStatus, middleware.RequestID(c)))
 		return
 	}
 
 	// Check if session belongs to user
 	if currentSession.UserID != userIDStr {
-		h.secureLog(session.ErrUnauthorized, "Unauthorized session access", "invalidateSessionById")
-		c.JSON(http.StatusForbidden, NewErrorResponse("You do not have permission to invalidate this session", status.StatusForbidden))
+		h.secureLog(c, session.ErrUnauthorized, "Unauthorized session access", "invalidateSessionById")
+		c.JSON(http.StatusForbidden, NewErrorResponse("You do not have permission to invalidate this session", status.StatusForbidden, middleware.RequestID(c)))
 		return
 	}
 
-	// Invalidate the session
-	err = h.sessionService.InvalidateSession(c, sessionID)
+	// Revoke the session
+	err = h.sessionService.RevokeUserSession(c, userIDStr, sessionID)
 	if err != nil {
-		h.secureLog(err, err.Error(), "invalidateSessionById")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "invalidateSessionById")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Clear cookies if the invalidated session is the current one
 	currentSessionID, _ := c.Get("sessionID")
 	if currentSessionID == sessionID {
-		c.SetCookie("sessionID", "", -1, "/", "localhost", false, true)
-		c.SetCookie("accessToken", "", -1, "/", "localhost", false, true)
-		c.SetCookie("refreshToken", "", -1, "/", "localhost", false, true)
+		h.cookies.ClearSession(c)
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Session invalidated successfully", status.StatusOK, middleware.RequestID(c)))
+}
+
+// RevokeMySession revokes one of the current user's sessions. Tokens of the session are
+// rejected from the next request on.
+func (h *Handler) RevokeMySession(c *gin.Context) {
+	sessionID := c.Param("sessionID")
+	userID := c.GetString("userID")
+	if userID == "" {
+		h.secureLog(c, session.ErrInvalidInput, "User ID not found in context", "revokeMySession")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	if err := h.sessionService.RevokeUserSession(c.Request.Context(), userID, sessionID); err != nil {
+		h.secureLog(c, err, err.Error(), "revokeMySession")
+		switch err {
+		case session.ErrSessionNotFound:
+			c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+		case session.ErrInvalidInput:
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest, middleware.RequestID(c)))
+		default:
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		}
+		return
+	}
+
+	// Clear cookies if the revoked session is the current one
+	if sessionID == c.GetString("sessionID") {
+		h.cookies.ClearSession(c)
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Session revoked successfully", status.StatusOK, middleware.RequestID(c)))
+}
+
+// RevokeOtherSessions revokes every session of the current user except the one making the request
+func (h *Handler) RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetString("userID")
+	sessionID := c.GetString("sessionID")
+	if userID == "" || sessionID == "" {
+		h.secureLog(c, session.ErrInvalidInput, "User or session ID not found in context", "revokeOtherSessions")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("User not authenticated", status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	revoked, err := h.sessionService.RevokeOtherUserSessions(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "revokeOtherSessions")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("Internal server error", status.StatusInternalServerError, middleware.RequestID(c)))
+		return
 	}
 
-	c.JSON(http.StatusOK, NewSuccessResponse("Session invalidated successfully", status.StatusOK))
+	c.JSON(http.StatusOK, NewRevokedSessionsResponse(revoked, status.StatusOK, middleware.RequestID(c)))
 }
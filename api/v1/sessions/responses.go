@@ -2,7 +2,6 @@ package session
 
 import (
 	"cirrussync-api/internal/models"
-	"cirrussync-api/internal/utils"
 	"time"
 )
 
@@ -33,6 +32,9 @@ type SessionData struct {
 	AppVersion string `json:"appVersion"`
 	IPAddress  string `json:"ipAddress"`
 	UserAgent  string `json:"userAgent"`
+	Country    string `json:"country,omitempty"`
+	City       string `json:"city,omitempty"`
+	Network    string `json:"network,omitempty"` // Organization the IP address belongs to
 	ExpiresAt  int64  `json:"expiresAt"`
 	CreatedAt  int64  `json:"createdAt"`
 	LastActive int64  `json:"lastActive"`
@@ -52,50 +54,47 @@ type SessionsListResponse struct {
 	Sessions []SessionData `json:"sessions"`
 }
 
+// RevokedSessionsResponse represents the result of revoking several sessions
+type RevokedSessionsResponse struct {
+	BaseResponse
+	Revoked int `json:"revoked"`
+}
+
 // NewErrorResponse creates a new error response
-func NewErrorResponse(message string, code int16) ErrorResponse {
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
 	return ErrorResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Error with requestId " + utils.GenerateShortID(),
+			Detail: "Error with requestId " + requestID,
 		},
 		Error: message,
 	}
 }
 
 // NewSuccessResponse creates a new success response
-func NewSuccessResponse(message string, code int16) SuccessResponse {
+func NewSuccessResponse(message string, code int16, requestID string) SuccessResponse {
 	return SuccessResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Message: message,
 	}
 }
 
 // NewSessionResponse creates a new session response
-func NewSessionResponse(session *models.UserSession, code int16) SessionResponse {
+func NewSessionResponse(session *models.UserSession, code int16, requestID string) SessionResponse {
 	return SessionResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Session: convertModelSessionToResponse(session, session.ID),
 	}
 }
 
-// NewSessionsListResponse creates a new sessions list response
-func NewSessionsListResponse(sessions []*models.UserSession, code int16) SessionsListResponse {
-	// Get current session ID from the first session that has IsValid=true
-	// This is a placeholder - in real implementation, you'd get this from context
-	var currentSessionID string
-	for _, s := range sessions {
-		if s.IsValid && s.ExpiresAt > time.Now().Unix() {
-			currentSessionID = s.ID
-			break
-		}
-	}
+// NewSessionsListResponse creates a new sessions list response, flagging the session making the request
+func NewSessionsListResponse(sessions []*models.UserSession, currentSessionID string, code int16, requestID string) SessionsListResponse {
 	sessionDataList := make([]SessionData, len(sessions))
 	for i, session := range sessions {
 		sessionDataList[i] = convertModelSessionToResponse(session, currentSessionID)
@@ -103,12 +102,23 @@ func NewSessionsListResponse(sessions []*models.UserSession, code int16) Session
 	return SessionsListResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Sessions: sessionDataList,
 	}
 }
 
+// NewRevokedSessionsResponse creates a response reporting how many sessions were revoked
+func NewRevokedSessionsResponse(count int, code int16, requestID string) RevokedSessionsResponse {
+	return RevokedSessionsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Revoked: count,
+	}
+}
+
 // Helper function to convert model session to response session data
 func convertModelSessionToResponse(session *models.UserSession, currentSessionID string) SessionData {
 	return SessionData{
@@ -119,10 +129,22 @@ func convertModelSessionToResponse(session *models.UserSession, currentSessionID
 		AppVersion: session.AppVersion,
 		IPAddress:  session.IPAddress,
 		UserAgent:  session.UserAgent,
+		Country:    session.Country,
+		City:       session.City,
+		Network:    session.ASOrganization,
 		ExpiresAt:  session.ExpiresAt,
 		CreatedAt:  session.CreatedAt,
-		LastActive: session.ModifiedAt,
+		LastActive: lastActive(session),
 		IsActive:   session.IsValid && session.ExpiresAt > time.Now().Unix(),
 		IsCurrent:  session.ID == currentSessionID,
 	}
 }
+
+// lastActive returns when a session was last used, falling back to its last change for
+// sessions that never recorded activity
+func lastActive(session *models.UserSession) int64 {
+	if session.LastActive > session.ModifiedAt {
+		return session.LastActive
+	}
+	return session.ModifiedAt
+}
@@ -24,3 +24,18 @@ func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
 		sessionGroup.DELETE("/:id", h.InvalidateSessionByID)
 	}
 }
+
+// RegisterUserRoutes registers the current user's session routes on the users group
+func RegisterUserRoutes(r *gin.RouterGroup, h *Handler) {
+	sessions := r.Group("/@me/sessions")
+	{
+		// List active sessions
+		sessions.GET("", h.GetAllActiveSessions)
+
+		// Revoke all sessions except the current one
+		sessions.DELETE("", h.RevokeOtherSessions)
+
+		// Revoke a specific session
+		sessions.DELETE("/:sessionID", h.RevokeMySession)
+	}
+}
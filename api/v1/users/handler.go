@@ -2,12 +2,15 @@ package user
 
 import (
 	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/middleware"
 	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/srp"
 	"cirrussync-api/internal/user"
-	"cirrussync-api/internal/utils"
 	"cirrussync-api/pkg/status"
 	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -16,26 +19,26 @@ import (
 // Handler handles user requests
 type Handler struct {
 	userService *user.Service
+	cookies     *middleware.Cookies
 	logger      *logger.Logger
 }
 
 // NewHandler creates a new user handler
-func NewHandler(userService *user.Service, log *logger.Logger) *Handler {
+func NewHandler(userService *user.Service, cookies *middleware.Cookies, log *logger.Logger) *Handler {
 	return &Handler{
 		userService: userService,
+		cookies:     cookies,
 		logger:      log,
 	}
 }
 
-// secureLog logs errors without sensitive data that might expose code or credentials
-func (h *Handler) secureLog(err error, message string, route string) {
-	// Generate request ID internally
-	requestID := utils.GenerateShortID()
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
 	// Log only necessary information, avoid including stack traces or request bodies
-	h.logger.WithFields(logrus.Fields{
-		"requestID": requestID,
-		"route":     route,
-		"errorMsg":  err.Error(),
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
 	}).Error(message)
 }
 
@@ -44,86 +47,86 @@ func (h *Handler) GetUser(c *gin.Context) {
 	// Get and validate user ID from context
 	userID, err := h.getUserIDFromContext(c)
 	if err != nil {
-		h.secureLog(err, err.Error(), "getUser")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized))
+		h.secureLog(c, err, err.Error(), "getUser")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Get user from service - returns our custom User type directly
 	responseUser, err := h.userService.GetUser(context.Background(), userID)
 	if err != nil {
-		h.secureLog(err, err.Error(), "getUser")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "getUser")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Return user profile
-	c.JSON(http.StatusOK, NewSuccessResponse("", responseUser, status.StatusOK))
+	c.JSON(http.StatusOK, NewSuccessResponse("", responseUser, status.StatusOK, middleware.RequestID(c)))
 }
 
 // UpdateProfile handles updating a user's profile
 func (h *Handler) UpdateProfile(c *gin.Context) {
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "updateProfile")
-		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		h.secureLog(c, err, "Invalid request format", "updateProfile")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
 		return
 	}
 
 	// Get and validate user ID from context
 	userID, err := h.getUserIDFromContext(c)
 	if err != nil {
-		h.secureLog(err, err.Error(), "updateProfile")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized))
+		h.secureLog(c, err, err.Error(), "updateProfile")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
 	// Create updates map
 	updates := make(map[string]interface{})
 	if req.DisplayName != "" {
-		updates["DisplayName"] = req.DisplayName
+		updates["display_name"] = req.DisplayName
 	}
 	if req.PhoneNumber != "" {
-		updates["PhoneNumber"] = req.PhoneNumber
+		updates["phone_number"] = req.PhoneNumber
 	}
 	if req.CompanyName != "" {
-		updates["CompanyName"] = req.CompanyName
+		updates["company_name"] = req.CompanyName
 	}
 
 	// Update user
-	_, err = h.userService.UpdateUser(context.Background(), userID, updates)
+	_, err = h.userService.UpdateUser(c.Request.Context(), userID, updates)
 	if err != nil {
-		h.secureLog(err, err.Error(), "updateProfile")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "updateProfile")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Get updated user with all calculated fields
-	updatedUser, err := h.userService.GetUser(context.Background(), userID)
+	updatedUser, err := h.userService.GetUser(c.Request.Context(), userID)
 	if err != nil {
-		h.secureLog(err, err.Error(), "updateProfile")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "updateProfile")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Return updated user profile
-	c.JSON(http.StatusOK, NewSuccessResponse("Profile updated successfully", updatedUser, status.StatusOK))
+	c.JSON(http.StatusOK, NewSuccessResponse("Profile updated successfully", updatedUser, status.StatusOK, middleware.RequestID(c)))
 }
 
 // AddUserKey handles adding a new key for a user
 func (h *Handler) AddUserKey(c *gin.Context) {
 	var req AddKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.secureLog(err, "Invalid request format", "addKey")
-		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed))
+		h.secureLog(c, err, "Invalid request format", "addKey")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
 		return
 	}
 
 	// Get and validate user ID from context
 	userID, err := h.getUserIDFromContext(c)
 	if err != nil {
-		h.secureLog(err, err.Error(), "addKey")
-		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized))
+		h.secureLog(c, err, err.Error(), "addKey")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
 		return
 	}
 
@@ -140,21 +143,303 @@ func (h *Handler) AddUserKey(c *gin.Context) {
 	// Add key
 	_, err = h.userService.AddUserKey(context.Background(), userID, key)
 	if err != nil {
-		h.secureLog(err, err.Error(), "addKey")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "addKey")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Get updated user with all calculated fields
-	updatedUser, err := h.userService.GetUser(context.Background(), userID)
+	updatedUser, err := h.userService.GetUser(c.Request.Context(), userID)
 	if err != nil {
-		h.secureLog(err, err.Error(), "addKey")
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError))
+		h.secureLog(c, err, err.Error(), "addKey")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
 		return
 	}
 
 	// Return updated user profile
-	c.JSON(http.StatusOK, NewSuccessResponse("Key added successfully", updatedUser, status.StatusOK))
+	c.JSON(http.StatusOK, NewSuccessResponse("Key added successfully", updatedUser, status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetConsents handles retrieving the user's consent and privacy settings
+func (h *Handler) GetConsents(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "getConsents")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	consents, err := h.userService.GetConsents(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "getConsents")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewConsentsResponse(consents, status.StatusOK, middleware.RequestID(c)))
+}
+
+// UpdateConsents handles granting or revoking consent flags
+func (h *Handler) UpdateConsents(c *gin.Context) {
+	var req UpdateConsentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updateConsents")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updateConsents")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	updates := make([]user.ConsentUpdate, len(req.Consents))
+	for i, consent := range req.Consents {
+		updates[i] = user.ConsentUpdate{
+			Type:          consent.Type,
+			Granted:       *consent.Granted,
+			PolicyVersion: consent.PolicyVersion,
+		}
+	}
+
+	consents, err := h.userService.UpdateConsents(c.Request.Context(), userID, updates, srp.GetClientIPFromRequest(c.Request), c.Request.UserAgent())
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updateConsents")
+		switch {
+		case errors.Is(err, user.ErrInvalidConsentType), errors.Is(err, user.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest, middleware.RequestID(c)))
+		case errors.Is(err, user.ErrPolicyVersionMismatch):
+			c.JSON(http.StatusConflict, NewErrorResponse(err.Error(), status.StatusConflict, middleware.RequestID(c)))
+		default:
+			c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, NewConsentsResponse(consents, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// ExportSecuritySettings handles exporting a signed snapshot of the user's devices, sessions,
+// MFA methods and recent security events
+func (h *Handler) ExportSecuritySettings(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "exportSecuritySettings")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	export, err := h.userService.ExportSecuritySettings(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "exportSecuritySettings")
+		switch {
+		case errors.Is(err, user.ErrSecurityExportUnavailable):
+			c.JSON(http.StatusServiceUnavailable, NewErrorResponse(err.Error(), status.StatusServiceUnavailable, middleware.RequestID(c)))
+		default:
+			c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+		}
+		return
+	}
+
+	// Snapshots hold session IPs and device details, keep them out of shared caches
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, NewSecurityExportResponse(export, status.StatusOK, middleware.RequestID(c)))
+}
+
+// ListDevices handles listing the devices registered to the user
+func (h *Handler) ListDevices(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "listDevices")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	devices, err := h.userService.ListDevices(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "listDevices")
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDevicesResponse(devices, status.StatusOK, middleware.RequestID(c)))
+}
+
+// TrustDevice handles trusting the device of the current session. The signed trust token is set as
+// a cookie scoped to the auth routes, where the next login from this device presents it to skip the
+// second factor.
+func (h *Handler) TrustDevice(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "trustDevice")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	device, trust, err := h.userService.TrustDevice(c.Request.Context(), userID, c.Param("deviceID"), c.GetString("sessionID"))
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "trustDevice")
+		h.handleDeviceError(c, err)
+		return
+	}
+
+	h.cookies.Set(c, middleware.DeviceTrustCookie, trust.Token, int(trust.ExpiresAt-time.Now().Unix()))
+	c.JSON(http.StatusOK, NewDeviceResponse(device, trust.ExpiresAt, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// UntrustDevice handles withdrawing trust from a device, which has to complete the second factor again at its next login
+func (h *Handler) UntrustDevice(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "untrustDevice")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	device, err := h.userService.UntrustDevice(c.Request.Context(), userID, c.Param("deviceID"))
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "untrustDevice")
+		h.handleDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeviceResponse(device, 0, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// RenameDevice handles renaming a registered device
+func (h *Handler) RenameDevice(c *gin.Context) {
+	var req RenameDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "renameDevice")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "renameDevice")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	device, err := h.userService.RenameDevice(c.Request.Context(), userID, c.Param("deviceID"), req.Name)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "renameDevice")
+		h.handleDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeviceResponse(device, 0, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// UpdateSyncSchedule handles replacing the sync pauses and bandwidth caps of a device. Desktop
+// clients pick up the change from the drive change feed.
+func (h *Handler) UpdateSyncSchedule(c *gin.Context) {
+	var req UpdateSyncScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updateSyncSchedule")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updateSyncSchedule")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	schedule := &user.SyncSchedule{
+		TimeZone:          req.TimeZone,
+		UploadLimitKbps:   req.UploadLimitKbps,
+		DownloadLimitKbps: req.DownloadLimitKbps,
+		Rules:             make([]user.SyncRule, len(req.Rules)),
+	}
+	for i, rule := range req.Rules {
+		schedule.Rules[i] = user.SyncRule{
+			Days:              rule.Days,
+			Start:             rule.Start,
+			End:               rule.End,
+			Action:            rule.Action,
+			MeteredOnly:       rule.MeteredOnly,
+			UploadLimitKbps:   rule.UploadLimitKbps,
+			DownloadLimitKbps: rule.DownloadLimitKbps,
+		}
+	}
+
+	device, err := h.userService.UpdateSyncSchedule(c.Request.Context(), userID, c.Param("deviceID"), schedule)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updateSyncSchedule")
+		h.handleDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeviceResponse(device, 0, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// ClearSyncSchedule handles removing the sync schedule of a device, which then syncs without limits
+func (h *Handler) ClearSyncSchedule(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "clearSyncSchedule")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	device, err := h.userService.UpdateSyncSchedule(c.Request.Context(), userID, c.Param("deviceID"), nil)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "clearSyncSchedule")
+		h.handleDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeviceResponse(device, 0, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// RemoveDevice handles removing a registered device along with its trust
+func (h *Handler) RemoveDevice(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "removeDevice")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	if err := h.userService.RemoveDevice(c.Request.Context(), userID, c.Param("deviceID")); err != nil {
+		h.secureLog(c, err, err.Error(), "removeDevice")
+		h.handleDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSimpleResponse("Device removed successfully", status.StatusDeleted, middleware.RequestID(c)))
+}
+
+// handleDeviceError maps device management errors to responses
+func (h *Handler) handleDeviceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, user.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse(err.Error(), status.StatusNotFound, middleware.RequestID(c)))
+	case errors.Is(err, user.ErrNotCurrentDevice):
+		c.JSON(http.StatusForbidden, NewErrorResponse(err.Error(), status.StatusForbidden, middleware.RequestID(c)))
+	case errors.Is(err, user.ErrInvalidDeviceName), errors.Is(err, user.ErrInvalidSyncSchedule):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest, middleware.RequestID(c)))
+	case errors.Is(err, user.ErrDeviceTrustUnavailable):
+		c.JSON(http.StatusServiceUnavailable, NewErrorResponse(err.Error(), status.StatusServiceUnavailable, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+	}
 }
 
 // Helper function to extract and validate user ID from context
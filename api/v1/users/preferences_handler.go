@@ -0,0 +1,123 @@
+package user
+
+import (
+	"cirrussync-api/internal/middleware"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/status"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPreferences handles retrieving the user's theme, language and time zone
+func (h *Handler) GetPreferences(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "getPreferences")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	preferences, err := h.userService.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "getPreferences")
+		h.handlePreferencesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPreferencesResponse(preferences, status.StatusOK, middleware.RequestID(c)))
+}
+
+// UpdatePreferences handles changing the user's theme, language or time zone
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updatePreferences")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updatePreferences")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	preferences, err := h.userService.UpdatePreferences(c.Request.Context(), userID, user.PreferencesUpdate{
+		ThemeMode: req.ThemeMode,
+		Language:  req.Language,
+		Timezone:  req.Timezone,
+	})
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updatePreferences")
+		h.handlePreferencesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewPreferencesResponse(preferences, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// GetNotificationPreferences handles retrieving how the user wants to be notified
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "getNotificationPreferences")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	notifications, err := h.userService.GetNotificationPreferences(c.Request.Context(), userID)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "getNotificationPreferences")
+		h.handlePreferencesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewNotificationPreferencesResponse(notifications, status.StatusOK, middleware.RequestID(c)))
+}
+
+// UpdateNotificationPreferences handles changing how the user wants to be notified
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "updateNotificationPreferences")
+		c.JSON(http.StatusUnprocessableEntity, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	// Get and validate user ID from context
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updateNotificationPreferences")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return
+	}
+
+	notifications, err := h.userService.UpdateNotificationPreferences(c.Request.Context(), userID, user.NotificationPreferencesUpdate{
+		Email:    req.Email,
+		Push:     req.Push,
+		Security: req.Security,
+	})
+	if err != nil {
+		h.secureLog(c, err, err.Error(), "updateNotificationPreferences")
+		h.handlePreferencesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewNotificationPreferencesResponse(notifications, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// handlePreferencesError maps preferences errors to responses
+func (h *Handler) handlePreferencesError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, user.ErrInvalidPreferences), errors.Is(err, user.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error(), status.StatusBadRequest, middleware.RequestID(c)))
+	default:
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error(), status.StatusInternalServerError, middleware.RequestID(c)))
+	}
+}
@@ -1,10 +1,11 @@
 package user
 
-// UpdateProfileRequest represents a request to update a user's profile
+// UpdateProfileRequest represents a request to update a user's profile. The email address is changed
+// through the email change flow instead.
 type UpdateProfileRequest struct {
-	DisplayName string `json:"displayName"`
-	PhoneNumber string `json:"phoneNumber"`
-	CompanyName string `json:"companyName"`
+	DisplayName string `json:"displayName" binding:"max=50"`
+	PhoneNumber string `json:"phoneNumber" binding:"max=50"`
+	CompanyName string `json:"companyName" binding:"max=100"`
 }
 
 // AddKeyRequest represents a request to add a new key
@@ -45,17 +46,11 @@ type VerifyPhoneRequest struct {
 	Code string `json:"code" binding:"required"`
 }
 
-// UpdateEmailRequest represents a request to update email
-type UpdateEmailRequest struct {
-	Email       string `json:"email" binding:"required"`
-	SRPVerifier string `json:"srpVerifier" binding:"required"`
-}
-
-// UpdatePreferencesRequest represents a request to update preferences
+// UpdatePreferencesRequest represents a request to update preferences. Fields left out are unchanged.
 type UpdatePreferencesRequest struct {
-	ThemeMode string `json:"themeMode"`
-	Language  string `json:"language"`
-	Timezone  string `json:"timezone"`
+	ThemeMode *string `json:"themeMode" binding:"omitempty,oneof=system light dark"`
+	Language  *string `json:"language" binding:"omitempty,oneof=en de es fr"`
+	Timezone  *string `json:"timezone" binding:"omitempty,max=50"`
 }
 
 // UpdateSecuritySettingsRequest represents a request to update security settings
@@ -65,3 +60,48 @@ type UpdateSecuritySettingsRequest struct {
 	SuspiciousActivityDetection bool `json:"suspiciousActivityDetection"`
 	DetailedEvents              bool `json:"detailedEvents"`
 }
+
+// ConsentUpdateRequest represents a change to a single consent flag
+type ConsentUpdateRequest struct {
+	Type          string `json:"type" binding:"required,oneof=analytics product_emails breach_monitoring"`
+	Granted       *bool  `json:"granted" binding:"required"`
+	PolicyVersion string `json:"policyVersion"`
+}
+
+// UpdateConsentsRequest represents a request to update consent flags
+type UpdateConsentsRequest struct {
+	Consents []ConsentUpdateRequest `json:"consents" binding:"required,min=1,max=3,dive"`
+}
+
+// RenameDeviceRequest represents a request to rename a registered device
+type RenameDeviceRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// UpdateSyncScheduleRequest represents the sync pauses and bandwidth caps of a device. Caps are in
+// kilobits per second, 0 is unlimited.
+type UpdateSyncScheduleRequest struct {
+	TimeZone          string            `json:"timeZone" binding:"omitempty,max=64"`
+	UploadLimitKbps   int               `json:"uploadLimitKbps" binding:"min=0"`
+	DownloadLimitKbps int               `json:"downloadLimitKbps" binding:"min=0"`
+	Rules             []SyncRuleRequest `json:"rules" binding:"max=20,dive"`
+}
+
+// SyncRuleRequest represents a weekly window during which sync is paused or capped
+type SyncRuleRequest struct {
+	Days              []int  `json:"days" binding:"required,min=1,max=7,dive,min=0,max=6"`
+	Start             string `json:"start" binding:"required,len=5"`
+	End               string `json:"end" binding:"required,len=5"`
+	Action            string `json:"action" binding:"required,oneof=pause limit"`
+	MeteredOnly       bool   `json:"meteredOnly"`
+	UploadLimitKbps   int    `json:"uploadLimitKbps" binding:"min=0"`
+	DownloadLimitKbps int    `json:"downloadLimitKbps" binding:"min=0"`
+}
+
+// UpdateNotificationPreferencesRequest represents a change to how the user is notified. Fields left
+// out are unchanged.
+type UpdateNotificationPreferencesRequest struct {
+	Email    *bool `json:"email"`
+	Push     *bool `json:"push"`
+	Security *bool `json:"security"`
+}
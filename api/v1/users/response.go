@@ -1,8 +1,10 @@
 package user
 
 import (
+	"cirrussync-api/internal/models"
 	"cirrussync-api/internal/user"
-	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/validation"
+	"encoding/json"
 )
 
 // UserResponse is the complete response structure for user-related API responses
@@ -11,42 +13,44 @@ type UserResponse struct {
 	User user.User `json:"user"`
 }
 
-// ErrorResponse represents an API error response
+// ErrorResponse represents an API error response. Rejected payloads list every field error.
 type ErrorResponse struct {
 	BaseResponse
-	Detail string `json:"detail,omitempty"`
+	Detail string                  `json:"detail,omitempty"`
+	Errors []validation.FieldError `json:"errors,omitempty"`
 }
 
 // NewSuccessResponse creates a success response with user data
-func NewSuccessResponse(message string, user user.User, code int16) UserResponse {
+func NewSuccessResponse(message string, user user.User, code int16, requestID string) UserResponse {
 	return UserResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		User: user,
 	}
 }
 
 // NewErrorResponse creates a new error response
-func NewErrorResponse(message string, code int16) ErrorResponse {
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
 	return ErrorResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Error with requestId " + utils.GenerateShortID(),
+			Detail: "Error with requestId " + requestID,
 		},
 		Detail: message,
 	}
 }
 
-// NewValidationError creates a validation error response
-func NewValidationError(err error, code int16) ErrorResponse {
+// NewValidationError creates a validation error response with every field error of the payload
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
 	return ErrorResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Validation Error with requestId " + utils.GenerateShortID(),
+			Detail: "Validation Error with requestId " + requestID,
 		},
-		Detail: err.Error(),
+		Detail: validation.Message(err),
+		Errors: validation.Errors(err),
 	}
 }
 
@@ -57,11 +61,11 @@ type RegisterResponse struct {
 }
 
 // NewRegisterResponse creates a new registration response
-func NewRegisterResponse(userID string, code int16) RegisterResponse {
+func NewRegisterResponse(userID string, code int16, requestID string) RegisterResponse {
 	return RegisterResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Registration successful with requestId " + utils.GenerateShortID(),
+			Detail: "Registration successful with requestId " + requestID,
 		},
 		UserID: userID,
 	}
@@ -74,11 +78,11 @@ type SimpleResponse struct {
 }
 
 // NewSimpleResponse creates a new simple response
-func NewSimpleResponse(message string, code int16) SimpleResponse {
+func NewSimpleResponse(message string, code int16, requestID string) SimpleResponse {
 	return SimpleResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Message: message,
 	}
@@ -91,11 +95,11 @@ type KeyResponse struct {
 }
 
 // NewKeyResponse creates a new key response
-func NewKeyResponse(key Key, code int16) KeyResponse {
+func NewKeyResponse(key Key, code int16, requestID string) KeyResponse {
 	return KeyResponse{
 		BaseResponse: BaseResponse{
 			Code:   code,
-			Detail: "Success with requestId " + utils.GenerateShortID(),
+			Detail: "Success with requestId " + requestID,
 		},
 		Key: key,
 	}
@@ -104,9 +108,10 @@ func NewKeyResponse(key Key, code int16) KeyResponse {
 // PreferencesResponse represents a response with user preferences
 type PreferencesResponse struct {
 	BaseResponse
-	ThemeMode string `json:"themeMode"`
-	Language  string `json:"language"`
-	Timezone  string `json:"timezone"`
+	ThemeMode  string `json:"themeMode"`
+	Language   string `json:"language"`
+	Timezone   string `json:"timezone"`
+	ModifiedAt int64  `json:"modifiedAt"`
 }
 
 // SecuritySettingsResponse represents a response with security settings
@@ -117,3 +122,173 @@ type SecuritySettingsResponse struct {
 	SuspiciousActivityDetection bool `json:"suspiciousActivityDetection"`
 	DetailedEvents              bool `json:"detailedEvents"`
 }
+
+// Consent represents a single consent flag
+type Consent struct {
+	Type          string `json:"type"`
+	Granted       bool   `json:"granted"`
+	PolicyVersion string `json:"policyVersion,omitempty"`
+	GrantedAt     *int64 `json:"grantedAt"`
+	RevokedAt     *int64 `json:"revokedAt"`
+}
+
+// ConsentsResponse represents a response with the user's consent flags
+type ConsentsResponse struct {
+	BaseResponse
+	Consents             []Consent `json:"consents"`
+	CurrentPolicyVersion string    `json:"currentPolicyVersion"`
+}
+
+// NewConsentsResponse creates a new consents response
+func NewConsentsResponse(consents []models.UserConsent, code int16, requestID string) ConsentsResponse {
+	items := make([]Consent, len(consents))
+	for i, consent := range consents {
+		items[i] = Consent{
+			Type:          consent.ConsentType,
+			Granted:       consent.Granted,
+			PolicyVersion: consent.PolicyVersion,
+			GrantedAt:     consent.GrantedAt,
+			RevokedAt:     consent.RevokedAt,
+		}
+	}
+
+	return ConsentsResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Consents:             items,
+		CurrentPolicyVersion: user.PRIVACY_POLICY_VERSION,
+	}
+}
+
+// SecurityExportResponse represents a signed snapshot of the user's security state
+type SecurityExportResponse struct {
+	BaseResponse
+	Export    json.RawMessage `json:"export"`
+	Signature string          `json:"signature"`
+	Algorithm string          `json:"algorithm"`
+	SignedAt  int64           `json:"signedAt"`
+}
+
+// NewSecurityExportResponse creates a new security export response
+func NewSecurityExportResponse(export *user.SignedSecurityExport, code int16, requestID string) SecurityExportResponse {
+	return SecurityExportResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Export:    export.Export,
+		Signature: export.Signature,
+		Algorithm: export.Algorithm,
+		SignedAt:  export.SignedAt,
+	}
+}
+
+// Device represents a device registered to the user
+type Device struct {
+	ID         string `json:"id"`
+	DeviceID   string `json:"deviceId"`
+	DeviceName string `json:"deviceName"`
+	DeviceType string `json:"deviceType"`
+	Trusted    bool   `json:"trusted"`
+	LastUsed   int64  `json:"lastUsed"`
+	CreatedAt  int64  `json:"createdAt"`
+
+	SyncSchedule           *user.SyncSchedule `json:"syncSchedule"`
+	SyncScheduleModifiedAt int64              `json:"syncScheduleModifiedAt,omitempty"`
+}
+
+// DevicesResponse represents a response with the user's registered devices
+type DevicesResponse struct {
+	BaseResponse
+	Devices []Device `json:"devices"`
+}
+
+// DeviceResponse represents a response with a single registered device
+type DeviceResponse struct {
+	BaseResponse
+	Device         Device `json:"device"`
+	TrustExpiresAt int64  `json:"trustExpiresAt,omitempty"`
+}
+
+// newDevice converts a registered device into its response form
+func newDevice(device models.UserDevice) Device {
+	return Device{
+		ID:         device.ID,
+		DeviceID:   device.DeviceID,
+		DeviceName: device.DeviceName,
+		DeviceType: device.DeviceType,
+		Trusted:    device.Trusted,
+		LastUsed:   device.LastUsed,
+		CreatedAt:  device.CreatedAt,
+
+		SyncSchedule:           user.DecodeSyncSchedule(&device),
+		SyncScheduleModifiedAt: device.SyncScheduleModifiedAt,
+	}
+}
+
+// NewDevicesResponse creates a new devices response
+func NewDevicesResponse(devices []models.UserDevice, code int16, requestID string) DevicesResponse {
+	items := make([]Device, len(devices))
+	for i, device := range devices {
+		items[i] = newDevice(device)
+	}
+
+	return DevicesResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Devices: items,
+	}
+}
+
+// NewDeviceResponse creates a new device response, with the trust expiry when the device was just trusted
+func NewDeviceResponse(device *models.UserDevice, trustExpiresAt int64, code int16, requestID string) DeviceResponse {
+	return DeviceResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Device:         newDevice(*device),
+		TrustExpiresAt: trustExpiresAt,
+	}
+}
+
+// NewPreferencesResponse creates a new preferences response
+func NewPreferencesResponse(preferences *models.UserPreferences, code int16, requestID string) PreferencesResponse {
+	return PreferencesResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		ThemeMode:  preferences.ThemeMode,
+		Language:   preferences.Language,
+		Timezone:   preferences.Timezone,
+		ModifiedAt: preferences.ModifiedAt,
+	}
+}
+
+// NotificationPreferencesResponse represents a response with the user's notification preferences
+type NotificationPreferencesResponse struct {
+	BaseResponse
+	Email      bool  `json:"email"`
+	Push       bool  `json:"push"`
+	Security   bool  `json:"security"`
+	ModifiedAt int64 `json:"modifiedAt"`
+}
+
+// NewNotificationPreferencesResponse creates a new notification preferences response
+func NewNotificationPreferencesResponse(notifications *models.UserNotifications, code int16, requestID string) NotificationPreferencesResponse {
+	return NotificationPreferencesResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Email:      notifications.Email,
+		Push:       notifications.Push,
+		Security:   notifications.Security,
+		ModifiedAt: notifications.ModifiedAt,
+	}
+}
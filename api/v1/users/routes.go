@@ -7,4 +7,23 @@ import (
 func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
 	user := r.Group("/")
 	user.GET("@me", h.GetUser)
+	user.PATCH("@me", h.UpdateProfile)
+	user.GET("@me/consents", h.GetConsents)
+	user.PUT("@me/consents", h.UpdateConsents)
+	user.GET("@me/preferences", h.GetPreferences)
+	user.PATCH("@me/preferences", h.UpdatePreferences)
+	user.GET("@me/preferences/notifications", h.GetNotificationPreferences)
+	user.PATCH("@me/preferences/notifications", h.UpdateNotificationPreferences)
+	user.GET("@me/devices", h.ListDevices)
+	user.PATCH("@me/devices/:deviceID", h.RenameDevice)
+	user.DELETE("@me/devices/:deviceID", h.RemoveDevice)
+	user.POST("@me/devices/:deviceID/trust", h.TrustDevice)
+	user.DELETE("@me/devices/:deviceID/trust", h.UntrustDevice)
+	user.PUT("@me/devices/:deviceID/sync-schedule", h.UpdateSyncSchedule)
+	user.DELETE("@me/devices/:deviceID/sync-schedule", h.ClearSyncSchedule)
+}
+
+func RegisterSettingsRoutes(r *gin.RouterGroup, h *Handler) {
+	settings := r.Group("/")
+	settings.GET("security/export", h.ExportSecuritySettings)
 }
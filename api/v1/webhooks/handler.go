@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"cirrussync-api/internal/middleware"
+	"errors"
+	"net/http"
+
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/webhook"
+	"cirrussync-api/pkg/status"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler handles webhook API requests
+type Handler struct {
+	webhookService *webhook.Service
+	logger         *logger.Logger
+}
+
+// NewHandler creates a new webhook handler
+func NewHandler(webhookService *webhook.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		webhookService: webhookService,
+		logger:         log,
+	}
+}
+
+// secureLog logs errors without sensitive data that might expose code or credentials.
+// The entry carries the request ID the response reports.
+func (h *Handler) secureLog(c *gin.Context, err error, message string, route string) {
+	// Log only necessary information, avoid including stack traces or request bodies
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
+	}).Error(message)
+}
+
+// handleServiceError maps service errors to appropriate HTTP responses
+func (h *Handler) handleServiceError(c *gin.Context, err error, route string) {
+	h.secureLog(c, err, "Error in "+route, route)
+
+	statusCode := http.StatusInternalServerError
+	apiStatus := status.StatusInternalServerError
+	message := "Failed to process webhook request"
+
+	switch {
+	case errors.Is(err, webhook.ErrInvalidInput),
+		errors.Is(err, webhook.ErrInvalidURL),
+		errors.Is(err, webhook.ErrForbiddenURL):
+		statusCode = http.StatusBadRequest
+		apiStatus = status.StatusBadRequest
+		message = err.Error()
+
+	case errors.Is(err, webhook.ErrTooManyTests):
+		statusCode = http.StatusTooManyRequests
+		apiStatus = status.StatusTooManyRequests
+		message = err.Error()
+	}
+
+	c.JSON(statusCode, NewErrorResponse(message, apiStatus, middleware.RequestID(c)))
+}
+
+// GetSigningInfo handles publishing the signing scheme of deliveries
+func (h *Handler) GetSigningInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, NewSigningInfoResponse(h.webhookService.GetSigningInfo(), status.StatusOK, middleware.RequestID(c)))
+}
+
+// GetSecret handles getting the caller's signing secret, creating it on first use
+func (h *Handler) GetSecret(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	secret, err := h.webhookService.GetSecret(c.Request.Context(), userID)
+	if err != nil {
+		h.handleServiceError(c, err, "getWebhookSecret")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSecretResponse(secret, status.StatusOK, middleware.RequestID(c)))
+}
+
+// RotateSecret handles replacing the caller's signing secret
+func (h *Handler) RotateSecret(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	secret, err := h.webhookService.RotateSecret(c.Request.Context(), userID)
+	if err != nil {
+		h.handleServiceError(c, err, "rotateWebhookSecret")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSecretResponse(secret, status.StatusUpdated, middleware.RequestID(c)))
+}
+
+// SendTestEvent handles delivering a signed test event to a receiver
+func (h *Handler) SendTestEvent(c *gin.Context) {
+	var req TestDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.secureLog(c, err, "Invalid request format", "sendWebhookTest")
+		c.JSON(http.StatusBadRequest, NewValidationError(err, status.StatusValidationFailed, middleware.RequestID(c)))
+		return
+	}
+
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.SendTestEvent(c.Request.Context(), userID, req.URL)
+	if err != nil {
+		h.handleServiceError(c, err, "sendWebhookTest")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewTestDeliveryResponse(delivery, status.StatusOK, middleware.RequestID(c)))
+}
+
+// getUserID reads the authenticated user from the context, responding with 401 when it is missing
+func (h *Handler) getUserID(c *gin.Context) (string, bool) {
+	userIDInterface, exists := c.Get("userID")
+	userID, ok := userIDInterface.(string)
+	if !exists || !ok || userID == "" {
+		h.secureLog(c, session.ErrSessionNotFound, "Missing user in context", "getUserID")
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(session.ErrSessionNotFound.Error(), status.StatusUnauthorized, middleware.RequestID(c)))
+		return "", false
+	}
+	return userID, true
+}
@@ -0,0 +1,6 @@
+package webhook
+
+// TestDeliveryRequest represents a request to deliver a test event
+type TestDeliveryRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048"`
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"cirrussync-api/internal/webhook"
+)
+
+// BaseResponse represents the base structure for all API responses
+type BaseResponse struct {
+	Code   int16  `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	BaseResponse
+	Error string `json:"error,omitempty"`
+}
+
+// NewErrorResponse creates a new error response
+func NewErrorResponse(message string, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Error with requestId " + requestID,
+		},
+		Error: message,
+	}
+}
+
+// NewValidationError creates a validation error response
+func NewValidationError(err error, code int16, requestID string) ErrorResponse {
+	return ErrorResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Validation Error with requestId " + requestID,
+		},
+		Error: err.Error(),
+	}
+}
+
+// SigningInfoResponse describes how webhook deliveries are signed
+type SigningInfoResponse struct {
+	BaseResponse
+	Header           string `json:"header"`
+	Algorithm        string `json:"algorithm"`
+	Scheme           string `json:"scheme"`
+	SignedPayload    string `json:"signedPayload"`
+	ToleranceSeconds int64  `json:"toleranceSeconds"`
+	EventIDHeader    string `json:"eventIdHeader"`
+	EventTypeHeader  string `json:"eventTypeHeader"`
+}
+
+// NewSigningInfoResponse creates a new signing info response
+func NewSigningInfoResponse(info webhook.SigningInfo, code int16, requestID string) SigningInfoResponse {
+	return SigningInfoResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Header:           info.Header,
+		Algorithm:        info.Algorithm,
+		Scheme:           info.Scheme,
+		SignedPayload:    info.SignedPayload,
+		ToleranceSeconds: info.ToleranceSeconds,
+		EventIDHeader:    info.EventIDHeader,
+		EventTypeHeader:  info.EventTypeHeader,
+	}
+}
+
+// SecretResponse carries the signing secret of the caller
+type SecretResponse struct {
+	BaseResponse
+	Secret string `json:"secret"`
+}
+
+// NewSecretResponse creates a new secret response
+func NewSecretResponse(secret string, code int16, requestID string) SecretResponse {
+	return SecretResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		Secret: secret,
+	}
+}
+
+// TestDeliveryResponse describes the outcome of a test delivery
+type TestDeliveryResponse struct {
+	BaseResponse
+	EventID    string `json:"eventId"`
+	URL        string `json:"url"`
+	Signature  string `json:"signature"`
+	StatusCode int    `json:"statusCode"`
+	Success    bool   `json:"success"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewTestDeliveryResponse creates a new test delivery response
+func NewTestDeliveryResponse(delivery *webhook.TestDelivery, code int16, requestID string) TestDeliveryResponse {
+	return TestDeliveryResponse{
+		BaseResponse: BaseResponse{
+			Code:   code,
+			Detail: "Success with requestId " + requestID,
+		},
+		EventID:    delivery.EventID,
+		URL:        delivery.URL,
+		Signature:  delivery.Signature,
+		StatusCode: delivery.StatusCode,
+		Success:    delivery.Success,
+		DurationMs: delivery.DurationMs,
+		Error:      delivery.Error,
+	}
+}
@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPublicRoutes registers webhook routes that need no authentication
+func RegisterPublicRoutes(r *gin.RouterGroup, h *Handler) {
+	webhookGroup := r.Group("/webhooks")
+	{
+		// How deliveries are signed, for integrators implementing verification
+		webhookGroup.GET("/signing", h.GetSigningInfo)
+	}
+}
+
+// RegisterProtectedRoutes registers webhook routes
+func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
+	webhookGroup := r.Group("")
+	{
+		webhookGroup.GET("/secret", h.GetSecret)
+		webhookGroup.POST("/secret/rotate", h.RotateSecret)
+
+		// Deliver a signed test event to a receiver
+		webhookGroup.POST("/test", h.SendTestEvent)
+	}
+}
@@ -14,10 +14,16 @@ import (
 	"cirrussync-api/pkg/db"
 	"cirrussync-api/pkg/redis"
 	"cirrussync-api/pkg/s3"
+	"cirrussync-api/pkg/tracing"
 	"cirrussync-api/router"
 )
 
 func main() {
+	// Migration subcommands run against the database and exit without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -29,58 +35,30 @@ func main() {
 	log.Println("Loading configuration...")
 	appConfig := config.LoadConfig()
 
+	// Initialize tracing before the clients it instruments
+	shutdownTracing, err := tracing.Init(ctx, config.LoadTracingConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Initialize database
 	log.Println("Initializing database connection...")
-	err := db.Initialize(appConfig.Database)
+	err = db.Initialize(appConfig.Database)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
 	// Run migrations if enabled
+	migrationCfg := newMigrationConfig(appConfig.Database)
 	if appConfig.Database.MigrateOnBoot {
 		log.Println("Running database migrations...")
-		migrationCfg := db.NewMigrationConfig()
 
 		// Adjust migration settings based on environment
 		if appConfig.IsDevelopment() {
 			migrationCfg.AutoMigrateModels = true
 
 			// Auto-migrate models instead of SQL migrations in development
-			err = db.RunMigrations(migrationCfg, &models.User{},
-				&models.UserSRP{},
-				&models.UserCredit{},
-				&models.UserSecurityEvent{},
-				&models.SecurityEventDownload{},
-				&models.UserSecuritySettings{},
-				&models.UserKey{},
-				&models.UserRecoveryKit{},
-				&models.UserSession{},
-				&models.VolumeAllocation{},
-				&models.UserStorage{},
-				&models.UserDevice{},
-				&models.EmailMethods{},
-				&models.PhoneMethods{},
-				&models.TOTPMethods{},
-				&models.UserMFASettings{},
-				&models.UserNotifications{},
-				&models.UserPreferences{},
-
-				// Billing models
-				&models.BillingInfo{},
-				&models.Plan{},
-				&models.UserPlan{},
-				&models.UserBilling{},
-				&models.UserPaymentMethod{},
-				&models.GiftCard{},
-
-				// Drive models
-				&models.DriveVolume{},
-				&models.DriveShare{},
-				&models.DriveShareMembership{},
-				&models.DriveItem{},
-				&models.FileRevision{},
-				&models.DriveThumbnail{},
-				&models.FileBlock{})
+			err = db.RunMigrations(migrationCfg, models.AutoMigrateModels()...)
 		} else {
 			// Use SQL migrations in production
 			err = db.RunMigrations(migrationCfg)
@@ -89,6 +67,11 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to run database migrations: %v", err)
 		}
+	} else if !appConfig.IsDevelopment() {
+		// Migrations run separately must not leave destructive ones pending behind the server's back
+		if err := db.CheckPendingMigrations(migrationCfg); err != nil {
+			log.Fatalf("Refusing to start: %v", err)
+		}
 	}
 
 	// Initialize Redis connection
@@ -109,8 +92,6 @@ func main() {
 	}
 	log.Printf("S3 client initialized with bucket: %s", appConfig.S3.BucketName)
 
-	// Initialize HTTP server, background workers, etc.
-
 	// Get the database connection for the router
 	database := db.GetDB()
 
@@ -118,10 +99,20 @@ func main() {
 	log.Println("Setting up router...")
 	ginEngine, _ := router.SetupRouter(database)
 
-	// Create server with Gin handler
+	// Start background job workers
+	if err := router.StartBackgroundJobs(ctx); err != nil {
+		log.Fatalf("Failed to start background jobs: %v", err)
+	}
+
+	// Create server with Gin handler. Connection timeouts keep slow clients from holding on to
+	// connections; the deadlines of the work behind each request are set per route by the router.
 	srv := &http.Server{
-		Addr:    appConfig.Host + ":" + appConfig.Port,
-		Handler: ginEngine,
+		Addr:              appConfig.Host + ":" + appConfig.Port,
+		Handler:           ginEngine,
+		ReadHeaderTimeout: time.Duration(appConfig.ReadHeaderTimeout) * time.Second,
+		ReadTimeout:       time.Duration(appConfig.ReadTimeout) * time.Second,
+		WriteTimeout:      time.Duration(appConfig.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(appConfig.IdleTimeout) * time.Second,
 	}
 
 	// Start server in goroutine
@@ -146,6 +137,11 @@ func main() {
 		log.Printf("Server shutdown error: %v", err)
 	}
 
+	// Flush the spans of the last requests
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Tracing shutdown error: %v", err)
+	}
+
 	// Perform other cleanup
 	gracefulShutdown(shutdownTimeout)
 }
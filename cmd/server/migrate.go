@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/db"
+)
+
+const migrateUsage = `Usage: server migrate <command> [flags]
+
+Commands:
+  up      Apply pending migrations
+  down    Roll back applied migrations, newest first
+  status  List migrations and whether they were applied
+
+Flags:
+`
+
+// newMigrationConfig creates the SQL migration settings of the configured database
+func newMigrationConfig(cfg *config.DatabaseConfig) *db.MigrationConfig {
+	migrationCfg := db.NewMigrationConfig()
+	migrationCfg.MigrationsPath = cfg.MigrationsPath
+	migrationCfg.AllowDestructive = cfg.AllowDestructiveMigrations
+	return migrationCfg
+}
+
+// runMigrateCommand runs a migrate subcommand against the configured database and returns the exit code
+func runMigrateCommand(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	steps := flags.Int("steps", 0, "Number of migrations to apply, all when 0; number to roll back, 1 when 0")
+	target := flags.Uint("to", 0, "Version to migrate up to, the latest when 0")
+	path := flags.String("path", "", "Directory of migration files, DB_MIGRATIONS_PATH when empty")
+	allowDestructive := flags.Bool("allow-destructive", false, "Apply migrations that drop or rewrite data")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return 2
+	}
+	command := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	appConfig := config.LoadConfig()
+	if err := db.Initialize(appConfig.Database); err != nil {
+		log.Printf("Failed to initialize database: %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	migrationCfg := newMigrationConfig(appConfig.Database)
+	if *path != "" {
+		migrationCfg.MigrationsPath = *path
+	}
+	migrationCfg.TargetVersion = *target
+	migrationCfg.AllowDestructive = migrationCfg.AllowDestructive || *allowDestructive
+
+	var err error
+	switch command {
+	case "up":
+		err = db.MigrateUp(migrationCfg, *steps)
+	case "down":
+		err = db.MigrateDown(migrationCfg, max(*steps, 1))
+	case "status":
+		err = printMigrationStatus(migrationCfg)
+	default:
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		log.Printf("Migrate %s failed: %v", command, err)
+		return 1
+	}
+
+	return 0
+}
+
+// printMigrationStatus prints every migration with whether it was applied and is destructive
+func printMigrationStatus(cfg *db.MigrationConfig) error {
+	status, err := db.GetMigrationStatus(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current version: %d", status.Version)
+	if status.Dirty {
+		fmt.Print(" (dirty)")
+	}
+	fmt.Printf(", %d pending\n\n", len(status.Pending()))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT\tDESTRUCTIVE\tREVERSIBLE")
+	for _, migration := range status.Migrations {
+		state, appliedAt := "pending", "-"
+		if migration.Applied {
+			state = "applied"
+		}
+		if migration.AppliedAt != nil {
+			appliedAt = migration.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\t%t\n", migration.Version, migration.Name, state, appliedAt, migration.Destructive, migration.Reversible)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,275 @@
+package account
+
+import (
+	"cirrussync-api/internal/billing"
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/user"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// JOB_TYPE_ACCOUNT_PURGE deletes everything an account owns once its deletion grace period ended
+	JOB_TYPE_ACCOUNT_PURGE = "account.purge"
+
+	// JOB_TYPE_ACCOUNT_DELETIONS warns accounts whose deletion is near and queues the purge of accounts
+	// whose grace period ended
+	JOB_TYPE_ACCOUNT_DELETIONS = "account.deletions"
+
+	// DELETION_CHECK_INTERVAL is how often accounts due for a deletion warning or purge are looked for
+	DELETION_CHECK_INTERVAL = time.Hour
+
+	// DELETION_BATCH_SIZE bounds how many accounts are warned or queued for purge per lookup
+	DELETION_BATCH_SIZE = 100
+
+	// deletionCancelPrefix prefixes the cancellation tokens sent with deletion emails
+	deletionCancelPrefix = "account_deletion_cancel:"
+)
+
+// DELETION_WARNING_LEADS are how long before the purge an account is warned again, longest first
+var DELETION_WARNING_LEADS = []time.Duration{7 * 24 * time.Hour, 24 * time.Hour}
+
+// deletionCancelToken is the pending deletion a cancellation token cancels
+type deletionCancelToken struct {
+	UserID      string `json:"userId"`
+	ScheduledAt int64  `json:"scheduledAt"`
+}
+
+// SetBillingService configures how the subscription of a purged account is ended
+func (s *Service) SetBillingService(billingService *billing.Service) {
+	s.billingService = billingService
+}
+
+// SetJobService configures the job service that purges accounts and registers the deletion and purge handlers
+func (s *Service) SetJobService(jobService *jobs.Service) {
+	s.jobService = jobService
+	jobService.Register(JOB_TYPE_ACCOUNT_PURGE, s.runAccountPurgeJob)
+	jobService.Register(JOB_TYPE_ACCOUNT_DELETIONS, s.runDeletionsJob)
+}
+
+// SetDeletionMailer configures how users are told about the pending deletion of their account.
+// Without a mailer deletions can only be cancelled by an administrator.
+func (s *Service) SetDeletionMailer(mailer DeletionMailer) {
+	s.mailer = mailer
+}
+
+// RequestDeletion schedules the deletion of the user's account at the end of the grace period. The
+// account is signed out everywhere and cannot sign in again; the emailed link cancels the deletion.
+func (s *Service) RequestDeletion(ctx context.Context, userID, ipAddress string) (int64, error) {
+	if userID == "" {
+		return 0, ErrInvalidInput
+	}
+
+	account, err := s.userService.ScheduleDeletion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.sessionService.InvalidateAllUserSessions(ctx, userID); err != nil {
+		s.logger.Errorf("Failed to revoke sessions of account %s scheduled for deletion: %v", userID, err)
+	}
+
+	if err := s.sendDeletionEmail(ctx, account.ID, account.Email, account.DeletionScheduledAt); err != nil {
+		s.logger.Errorf("Failed to send deletion email for account %s: %v", userID, err)
+	}
+
+	s.securityService.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_ACCOUNT_DELETION_SCHEDULED,
+		Success:   true,
+		IPAddress: ipAddress,
+		Metadata:  map[string]any{"scheduledAt": account.DeletionScheduledAt},
+	})
+
+	return account.DeletionScheduledAt, nil
+}
+
+// CancelDeletion cancels the pending deletion a token from a deletion email was issued for. Tokens of
+// an earlier deletion request that was already cancelled do not cancel a later one.
+func (s *Service) CancelDeletion(ctx context.Context, token, ipAddress string) error {
+	if token == "" {
+		return ErrInvalidCancelToken
+	}
+
+	var pending deletionCancelToken
+	if err := s.redisClient.GetJSON(ctx, deletionCancelPrefix+token, &pending); err != nil {
+		return ErrInvalidCancelToken
+	}
+
+	account, err := s.userService.GetUserById(ctx, pending.UserID)
+	if err != nil {
+		return ErrInvalidCancelToken
+	}
+	if account.DeletionScheduledAt == 0 || account.DeletionScheduledAt != pending.ScheduledAt {
+		return ErrInvalidCancelToken
+	}
+
+	if _, err := s.userService.CancelDeletion(ctx, pending.UserID); err != nil {
+		if errors.Is(err, user.ErrNoDeletionPending) {
+			return ErrInvalidCancelToken
+		}
+		return err
+	}
+
+	_, _ = s.redisClient.Delete(ctx, deletionCancelPrefix+token)
+
+	s.securityService.Record(ctx, security.Event{
+		UserID:    pending.UserID,
+		EventType: security.EVENT_ACCOUNT_DELETION_CANCELLED,
+		Success:   true,
+		IPAddress: ipAddress,
+	})
+
+	return nil
+}
+
+// sendDeletionEmail emails a user about the pending deletion of their account with a new link that
+// cancels it. The link stays valid until the account is purged.
+func (s *Service) sendDeletionEmail(ctx context.Context, userID, email string, scheduledAt int64) error {
+	if s.mailer == nil {
+		return nil
+	}
+
+	token, err := generateCancelToken()
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(time.Unix(scheduledAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.redisClient.SetJSON(ctx, deletionCancelPrefix+token, deletionCancelToken{UserID: userID, ScheduledAt: scheduledAt}, ttl); err != nil {
+		return fmt.Errorf("failed to store cancellation token: %w", err)
+	}
+
+	return s.mailer.SendAccountDeletionEmail(email, scheduledAt, token)
+}
+
+// generateCancelToken creates a random token for a deletion cancellation link
+func generateCancelToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// StartDeletionScheduler queues a job every check interval that warns accounts whose deletion is near
+// and queues a purge job for each account whose grace period ended, until ctx is cancelled
+func (s *Service) StartDeletionScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.Schedule(ctx, "account_deletions", DELETION_CHECK_INTERVAL, JOB_TYPE_ACCOUNT_DELETIONS, nil)
+}
+
+// runDeletionsJob warns accounts whose deletion is near and queues the purge of accounts whose grace
+// period ended. Warned accounts are marked and purges are locked per account, so running it again is safe.
+func (s *Service) runDeletionsJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	s.sendDeletionWarnings(ctx)
+	s.queueAccountPurges(ctx)
+
+	return ctx.Err()
+}
+
+// sendDeletionWarnings warns every account that entered a warning lead of its deletion since it was
+// last warned. Only the shortest lead an account entered is announced.
+func (s *Service) sendDeletionWarnings(ctx context.Context) {
+	if s.mailer == nil {
+		return
+	}
+
+	for i := len(DELETION_WARNING_LEADS) - 1; i >= 0; i-- {
+		accounts, err := s.userService.GetDeletionsToWarn(ctx, DELETION_WARNING_LEADS[i], DELETION_BATCH_SIZE)
+		if err != nil {
+			s.logger.Errorf("Failed to load accounts to warn of their deletion: %v", err)
+			return
+		}
+
+		for _, account := range accounts {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := s.sendDeletionEmail(ctx, account.ID, account.Email, account.DeletionScheduledAt); err != nil {
+				s.logger.Errorf("Failed to warn account %s of its deletion: %v", account.ID, err)
+				continue
+			}
+			if err := s.userService.MarkDeletionWarned(ctx, account.ID, account.DeletionScheduledAt); err != nil {
+				s.logger.Errorf("Failed to record deletion warning of account %s: %v", account.ID, err)
+			}
+		}
+	}
+}
+
+// queueAccountPurges queues a purge job for each account whose grace period ended. A job that used up
+// its retries leaves the account pending, so it is queued again once its lock expired.
+func (s *Service) queueAccountPurges(ctx context.Context) {
+	userIDs, err := s.userService.GetDeletionsDue(ctx, DELETION_BATCH_SIZE)
+	if err != nil {
+		s.logger.Errorf("Failed to load accounts due for deletion: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		acquired, err := s.redisClient.AcquireLock(ctx, "account_purge:"+userID, DELETION_CHECK_INTERVAL, 1, 0)
+		if err != nil {
+			s.logger.Errorf("Failed to acquire purge lock of account %s: %v", userID, err)
+			continue
+		}
+		if !acquired {
+			continue
+		}
+
+		if _, err := s.jobService.Enqueue(ctx, userID, JOB_TYPE_ACCOUNT_PURGE, nil); err != nil {
+			s.logger.Errorf("Failed to queue purge of account %s: %v", userID, err)
+		}
+	}
+}
+
+// runAccountPurgeJob ends the subscription of an account whose grace period ended and deletes its
+// drive, stored blocks, sessions and personal data. Every step skips what an interrupted run already
+// deleted, so the job is safe to run again.
+func (s *Service) runAccountPurgeJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	account, err := s.userService.GetUserById(ctx, job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load account: %w", err)
+	}
+	// The deletion may have been cancelled since the job was queued
+	if account.DeletionScheduledAt == 0 || account.DeletionScheduledAt > time.Now().Unix() {
+		return nil
+	}
+
+	if s.billingService != nil {
+		if err := s.billingService.CancelSubscriptionForDeletion(ctx, job.UserID); err != nil && !errors.Is(err, billing.ErrBillingDisabled) {
+			return fmt.Errorf("failed to cancel subscription: %w", err)
+		}
+	}
+	progress(1, 4, nil)
+
+	deletedItems, err := s.driveService.PurgeUserDrive(ctx, job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to purge drive: %w", err)
+	}
+	progress(2, 4, map[string]int64{"deletedItems": deletedItems})
+
+	if err := s.sessionService.InvalidateAllUserSessions(ctx, job.UserID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	progress(3, 4, map[string]int64{"deletedItems": deletedItems})
+
+	if err := s.userService.PurgeUser(ctx, job.UserID); err != nil {
+		return fmt.Errorf("failed to purge personal data: %w", err)
+	}
+	progress(4, 4, map[string]int64{"deletedItems": deletedItems})
+
+	s.logger.Infof("Purged account %s after its deletion grace period ended", job.UserID)
+	return nil
+}
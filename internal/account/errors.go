@@ -0,0 +1,9 @@
+package account
+
+import "errors"
+
+// Common errors
+var (
+	ErrInvalidInput       = errors.New("Invalid input parameters")
+	ErrInvalidCancelToken = errors.New("Invalid or expired cancellation token")
+)
@@ -0,0 +1,119 @@
+package account
+
+import (
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// SUMMARY_CACHE_EXPIRATION bounds how stale a dashboard summary can be. It is short enough
+	// that changes show up on the next refresh, so nothing invalidates it explicitly.
+	SUMMARY_CACHE_EXPIRATION = 30 * time.Second
+
+	// RECENT_SECURITY_EVENTS_LIMIT is how many of the newest security events a summary carries
+	RECENT_SECURITY_EVENTS_LIMIT = 5
+)
+
+// NewService creates a new account service
+func NewService(quotaService *quota.Service, securityService *security.Service, sessionService *session.Service, userService *user.Service, driveService *drive.Service, redisClient *redis.Client, logger *logger.Logger) *Service {
+	return &Service{
+		quotaService:    quotaService,
+		securityService: securityService,
+		sessionService:  sessionService,
+		userService:     userService,
+		driveService:    driveService,
+		redisClient:     redisClient,
+		logger:          logger,
+	}
+}
+
+// GetSummary returns the user's storage usage, newest security events, active session count,
+// plan and pending invitations. The parts are loaded in parallel and the result is cached briefly.
+func (s *Service) GetSummary(ctx context.Context, userID string) (*Summary, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	// Check cache first
+	cacheKey := fmt.Sprintf("account_summary:%s", userID)
+	var cached Summary
+	if err := s.redisClient.GetJSON(ctx, cacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	summary := &Summary{GeneratedAt: time.Now().Unix()}
+	g, gCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		usage, err := s.quotaService.GetUsage(gCtx, userID)
+		if errors.Is(err, quota.ErrAllocationNotFound) {
+			// Users who have not set up their drive yet store nothing
+			limit, err := s.quotaService.GetLimit(gCtx, userID)
+			if err != nil {
+				return fmt.Errorf("failed to load storage limit: %w", err)
+			}
+			usage = &quota.Usage{LimitBytes: limit}
+		} else if err != nil {
+			return fmt.Errorf("failed to load storage usage: %w", err)
+		}
+		summary.Storage = *usage
+		return nil
+	})
+
+	g.Go(func() error {
+		page, err := s.securityService.ListEvents(gCtx, userID, security.EventFilter{Limit: RECENT_SECURITY_EVENTS_LIMIT})
+		if err != nil {
+			return fmt.Errorf("failed to load security events: %w", err)
+		}
+		summary.RecentSecurityEvents = page.Events
+		return nil
+	})
+
+	g.Go(func() error {
+		sessions, err := s.sessionService.GetUserSessions(gCtx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to load sessions: %w", err)
+		}
+		summary.ActiveSessions = len(sessions)
+		return nil
+	})
+
+	g.Go(func() error {
+		planStatus, err := s.userService.GetPlanStatus(gCtx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to load plan: %w", err)
+		}
+		summary.Plan = *planStatus
+		return nil
+	})
+
+	g.Go(func() error {
+		invitations, err := s.driveService.GetPendingInvitations(gCtx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to load invitations: %w", err)
+		}
+		summary.PendingInvitations = len(invitations)
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	if err := s.redisClient.SetJSON(ctx, cacheKey, summary, SUMMARY_CACHE_EXPIRATION); err != nil {
+		s.logger.Errorf("Failed to cache account summary for user %s: %v", userID, err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,45 @@
+package account
+
+import (
+	"cirrussync-api/internal/billing"
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/redis"
+)
+
+// Service assembles account wide overviews from the services that own each part
+type Service struct {
+	quotaService    *quota.Service
+	securityService *security.Service
+	sessionService  *session.Service
+	userService     *user.Service
+	driveService    *drive.Service
+	redisClient     *redis.Client
+	logger          *logger.Logger
+
+	// Account deletion; purges are not run without a job service
+	billingService *billing.Service
+	jobService     *jobs.Service
+	mailer         DeletionMailer
+}
+
+// DeletionMailer sends the emails announcing a pending account deletion, each with a link that cancels it
+type DeletionMailer interface {
+	SendAccountDeletionEmail(email string, scheduledAt int64, cancelToken string) error
+}
+
+// Summary is everything the web dashboard shows about an account at a glance
+type Summary struct {
+	Storage              quota.Usage
+	RecentSecurityEvents []models.UserSecurityEvent
+	ActiveSessions       int
+	Plan                 user.PlanStatus
+	PendingInvitations   int // Share invitations waiting for an answer; the only notifications the API keeps
+	GeneratedAt          int64
+}
@@ -0,0 +1,11 @@
+package admin
+
+import "errors"
+
+// Common errors
+var (
+	ErrUnknownPermission = errors.New("Unknown admin permission")
+	ErrUserNotFound      = errors.New("User not found")
+	ErrNotAdmin          = errors.New("Permissions can only be granted to admin users")
+	ErrCannotChangeSelf  = errors.New("Admins cannot change their own permissions")
+)
@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository interface for admin permission and audit operations
+type Repository interface {
+	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+	GetPermissions(ctx context.Context, userID string) ([]models.AdminPermission, error)
+	GrantPermission(ctx context.Context, permission *models.AdminPermission) error
+	RevokePermission(ctx context.Context, userID, permission string) (bool, error)
+	CreateAuditEntry(ctx context.Context, entry *models.AdminAuditLog) error
+	GetAuditEntries(ctx context.Context, filter AuditFilter) ([]models.AdminAuditLog, error)
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new admin repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// GetUserByID retrieves a user by ID
+func (r *repo) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Where("id = ?", userID).
+		First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetPermissions retrieves the permissions granted to an admin user
+func (r *repo) GetPermissions(ctx context.Context, userID string) ([]models.AdminPermission, error) {
+	var permissions []models.AdminPermission
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("permission ASC").
+		Find(&permissions).Error
+	return permissions, err
+}
+
+// GrantPermission stores a permission grant. Granting a permission the user already holds is a no-op.
+func (r *repo) GrantPermission(ctx context.Context, permission *models.AdminPermission) error {
+	return r.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "permission"}},
+			DoNothing: true,
+		}).
+		Create(permission).Error
+}
+
+// RevokePermission removes a permission grant and reports whether the user held it
+func (r *repo) RevokePermission(ctx context.Context, userID, permission string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND permission = ?", userID, permission).
+		Delete(&models.AdminPermission{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateAuditEntry appends an entry to the admin audit log
+func (r *repo) CreateAuditEntry(ctx context.Context, entry *models.AdminAuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// GetAuditEntries retrieves admin audit log entries, newest first
+func (r *repo) GetAuditEntries(ctx context.Context, filter AuditFilter) ([]models.AdminAuditLog, error) {
+	var entries []models.AdminAuditLog
+	query := r.db.WithContext(ctx).
+		Where("created_at >= ?", filter.Since)
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Before > 0 {
+		query = query.Where("created_at < ?", filter.Before)
+	}
+
+	err := query.
+		Order("created_at DESC").
+		Limit(filter.Limit).
+		Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,197 @@
+package admin
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Admin permissions. Each admin endpoint requires exactly one of them.
+const (
+	PERMISSION_SUPPORT_READ   = "support.read"   // Read account, usage and support data
+	PERMISSION_BILLING_MANAGE = "billing.manage" // Issue credits and gift cards, change billing state
+	PERMISSION_MODERATION_ACT = "moderation.act" // Act on abusive content and accounts
+	PERMISSION_INFRA_OPERATE  = "infra.operate"  // Tune runtime settings, purge caches, read service metrics
+)
+
+// Roles carried in access tokens
+const (
+	ROLE_ADMIN      = "admin"
+	ROLE_SUPERADMIN = "superadmin" // Holds every permission and is the only role that grants them
+)
+
+const (
+	// permissionsCacheExpiry bounds how long a revoked permission can linger on other instances
+	permissionsCacheExpiry = 5 * time.Minute
+
+	DEFAULT_AUDIT_LIMIT = 100
+	MAX_AUDIT_LIMIT     = 500
+)
+
+// Permissions lists every admin permission
+var Permissions = []string{
+	PERMISSION_SUPPORT_READ,
+	PERMISSION_BILLING_MANAGE,
+	PERMISSION_MODERATION_ACT,
+	PERMISSION_INFRA_OPERATE,
+}
+
+// NewService creates a new admin service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// IsPermission reports whether a string names an admin permission
+func IsPermission(permission string) bool {
+	return slices.Contains(Permissions, permission)
+}
+
+// GetPermissions returns the permissions an admin holds. Superadmins hold all of them.
+func (s *Service) GetPermissions(ctx context.Context, userID string, roles []string) ([]string, error) {
+	if slices.Contains(roles, ROLE_SUPERADMIN) {
+		return slices.Clone(Permissions), nil
+	}
+	if !slices.Contains(roles, ROLE_ADMIN) {
+		return []string{}, nil
+	}
+
+	// Try to get from cache first
+	var permissions []string
+	if err := s.redisClient.GetJSON(ctx, redisKeyForPermissions(userID), &permissions); err == nil && permissions != nil {
+		return permissions, nil
+	}
+
+	grants, err := s.repo.GetPermissions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin permissions: %w", err)
+	}
+
+	permissions = make([]string, 0, len(grants))
+	for _, grant := range grants {
+		// Grants of permissions that were since retired are ignored
+		if IsPermission(grant.Permission) {
+			permissions = append(permissions, grant.Permission)
+		}
+	}
+
+	if err := s.redisClient.SetJSON(ctx, redisKeyForPermissions(userID), permissions, permissionsCacheExpiry); err != nil {
+		s.logger.Warnf("Failed to cache admin permissions of %s: %v", userID, err)
+	}
+
+	return permissions, nil
+}
+
+// HasPermission reports whether an admin holds a permission
+func (s *Service) HasPermission(ctx context.Context, userID string, roles []string, permission string) (bool, error) {
+	permissions, err := s.GetPermissions(ctx, userID, roles)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(permissions, permission), nil
+}
+
+// GetUserPermissions returns the permissions of any user, looked up by ID
+func (s *Service) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPermissions(ctx, user.ID, user.Roles)
+}
+
+// GrantPermission gives an admin user a permission. Only superadmins may call it, which the
+// route enforces; admins cannot change their own permissions either way.
+func (s *Service) GrantPermission(ctx context.Context, actorID, userID, permission string) ([]string, error) {
+	if !IsPermission(permission) {
+		return nil, ErrUnknownPermission
+	}
+	if actorID == userID {
+		return nil, ErrCannotChangeSelf
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(user.Roles, ROLE_ADMIN) {
+		return nil, ErrNotAdmin
+	}
+
+	err = s.repo.GrantPermission(ctx, &models.AdminPermission{
+		UserID:     userID,
+		Permission: permission,
+		GrantedBy:  actorID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant admin permission: %w", err)
+	}
+
+	s.invalidatePermissions(ctx, userID)
+	return s.GetPermissions(ctx, user.ID, user.Roles)
+}
+
+// RevokePermission takes a permission away from an admin user. Revoking one they do not hold is a no-op.
+func (s *Service) RevokePermission(ctx context.Context, actorID, userID, permission string) ([]string, error) {
+	if !IsPermission(permission) {
+		return nil, ErrUnknownPermission
+	}
+	if actorID == userID {
+		return nil, ErrCannotChangeSelf
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.RevokePermission(ctx, userID, permission); err != nil {
+		return nil, fmt.Errorf("failed to revoke admin permission: %w", err)
+	}
+
+	s.invalidatePermissions(ctx, userID)
+	return s.GetPermissions(ctx, user.ID, user.Roles)
+}
+
+// RecordRequest appends a handled admin API request to the audit log.
+// Failures are logged rather than returned since the response has already been sent.
+func (s *Service) RecordRequest(ctx context.Context, entry *models.AdminAuditLog) {
+	if err := s.repo.CreateAuditEntry(ctx, entry); err != nil {
+		s.logger.Errorf("Failed to write admin audit entry for %s %s by %s: %v", entry.Method, entry.Path, entry.ActorID, err)
+	}
+}
+
+// GetAuditLog returns admin audit log entries, newest first
+func (s *Service) GetAuditLog(ctx context.Context, filter AuditFilter) ([]models.AdminAuditLog, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DEFAULT_AUDIT_LIMIT
+	}
+	if filter.Limit > MAX_AUDIT_LIMIT {
+		filter.Limit = MAX_AUDIT_LIMIT
+	}
+
+	entries, err := s.repo.GetAuditEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// invalidatePermissions drops cached permissions so a change applies to the next request
+func (s *Service) invalidatePermissions(ctx context.Context, userID string) {
+	if _, err := s.redisClient.Delete(ctx, redisKeyForPermissions(userID)); err != nil {
+		s.logger.Warnf("Failed to invalidate admin permissions of %s: %v", userID, err)
+	}
+}
+
+// redisKeyForPermissions returns the cache key of an admin's permissions
+func redisKeyForPermissions(userID string) string {
+	return "admin:permissions:" + userID
+}
@@ -0,0 +1,21 @@
+package admin
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/redis"
+)
+
+// Service manages the capabilities of admin users and the admin audit log
+type Service struct {
+	repo        Repository
+	redisClient *redis.Client
+	logger      *logger.Logger
+}
+
+// AuditFilter narrows an admin audit log query
+type AuditFilter struct {
+	ActorID string
+	Since   int64
+	Before  int64 // Exclusive upper bound on CreatedAt, used to page backwards
+	Limit   int
+}
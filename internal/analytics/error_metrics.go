@@ -0,0 +1,205 @@
+package analytics
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// HOUR_FORMAT is how error metric hours are written in keys, rows and query parameters (UTC)
+	HOUR_FORMAT = "2006-01-02T15"
+
+	// MAX_ERROR_RANGE_HOURS bounds how many hours one error metrics query may span
+	MAX_ERROR_RANGE_HOURS = 31 * 24
+
+	// maxErrorRouteLength bounds route patterns so a single counter field stays small
+	maxErrorRouteLength = 200
+)
+
+// RecordError counts one rejected or failed request of a route pattern, answered with an HTTP status
+// and API error code, towards the current hour. No user or request detail is kept.
+func (s *Service) RecordError(ctx context.Context, route, clientVersion string, httpStatus, errorCode int) error {
+	if !s.errorsConfig.Enabled || route == "" || len(route) > maxErrorRouteLength {
+		return nil
+	}
+
+	hour := time.Now().UTC().Format(HOUR_FORMAT)
+	hourKey := redisKeyForErrorHour(hour)
+	field := errorBucketName(route, NormalizeClientVersion(clientVersion), httpStatus, errorCode)
+
+	_, err := s.redisClient.Pipeline(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HIncrBy(ctx, hourKey, field, 1)
+		pipe.Expire(ctx, hourKey, s.errorsConfig.Retention)
+		pipe.SAdd(ctx, redisKeyForErrorHours(), hour)
+		return nil
+	})
+	return err
+}
+
+// GetHourlyErrors returns the flushed error counts between two hours inclusive, narrowed by the filter.
+// Hours still being counted are not included; see GetLiveErrors.
+func (s *Service) GetHourlyErrors(ctx context.Context, from, to string, filter ErrorFilter) ([]models.ErrorMetric, error) {
+	fromHour, err := time.Parse(HOUR_FORMAT, from)
+	if err != nil {
+		return nil, ErrInvalidHour
+	}
+	toHour, err := time.Parse(HOUR_FORMAT, to)
+	if err != nil {
+		return nil, ErrInvalidHour
+	}
+	if toHour.Before(fromHour) || toHour.Sub(fromHour) > MAX_ERROR_RANGE_HOURS*time.Hour {
+		return nil, ErrInvalidRange
+	}
+
+	metrics, err := s.repo.GetHourlyErrors(ctx, from, to, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load error metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// GetLiveErrors returns the error counters of every hour not flushed yet, narrowed by the filter.
+// This includes the current hour, so spikes show up while they happen.
+func (s *Service) GetLiveErrors(ctx context.Context, filter ErrorFilter) ([]ErrorCount, error) {
+	hours, err := s.redisClient.SMembers(ctx, redisKeyForErrorHours())
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(hours)
+
+	result := make([]ErrorCount, 0)
+	for _, hour := range hours {
+		counts, err := s.readErrorHour(ctx, hour)
+		if err != nil {
+			return nil, err
+		}
+		for _, count := range counts {
+			if filter.matches(count) {
+				result = append(result, count)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// flushErrorHours stores the counters of finished hours and then drops them from Redis.
+// It runs as part of the usage metrics flush pass, under the same lock.
+func (s *Service) flushErrorHours(ctx context.Context) {
+	hours, err := s.redisClient.SMembers(ctx, redisKeyForErrorHours())
+	if err != nil {
+		s.logger.Errorf("Failed to list error metric hours: %v", err)
+		return
+	}
+
+	// Only hours that ended before the grace period are final
+	cutoff := time.Now().UTC().Add(-usageFlushGrace).Format(HOUR_FORMAT)
+	sort.Strings(hours)
+	for _, hour := range hours {
+		if hour >= cutoff {
+			continue
+		}
+		if err := s.flushErrorHour(ctx, hour); err != nil {
+			s.logger.Errorf("Failed to flush error metrics of %s: %v", hour, err)
+		}
+	}
+}
+
+// flushErrorHour stores a finished hour's counters and then drops them from Redis
+func (s *Service) flushErrorHour(ctx context.Context, hour string) error {
+	counts, err := s.readErrorHour(ctx, hour)
+	if err != nil {
+		return err
+	}
+
+	metrics := make([]*models.ErrorMetric, len(counts))
+	for i, count := range counts {
+		metrics[i] = &models.ErrorMetric{
+			Hour:          count.Hour,
+			Route:         count.Route,
+			ClientVersion: count.ClientVersion,
+			HTTPStatus:    count.HTTPStatus,
+			ErrorCode:     count.ErrorCode,
+			Count:         count.Count,
+		}
+	}
+	if err := s.repo.UpsertHourlyErrors(ctx, metrics); err != nil {
+		return err
+	}
+
+	if _, err := s.redisClient.DeleteMany(ctx, redisKeyForErrorHour(hour)); err != nil {
+		return err
+	}
+	_, err = s.redisClient.SRem(ctx, redisKeyForErrorHours(), hour)
+	return err
+}
+
+// readErrorHour reads the counters of every route, client version and error seen during an hour
+func (s *Service) readErrorHour(ctx context.Context, hour string) ([]ErrorCount, error) {
+	fields, err := s.redisClient.HGetAll(ctx, redisKeyForErrorHour(hour))
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]string, 0, len(fields))
+	for bucket := range fields {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+
+	counts := make([]ErrorCount, 0, len(buckets))
+	for _, bucket := range buckets {
+		parts := strings.Split(bucket, "|")
+		if len(parts) != 4 {
+			continue
+		}
+		httpStatus, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+		errorCode, err := strconv.Atoi(parts[3])
+		if err != nil {
+			continue
+		}
+		count, _ := strconv.ParseInt(fields[bucket], 10, 64)
+
+		counts = append(counts, ErrorCount{
+			Hour:          hour,
+			Route:         parts[0],
+			ClientVersion: parts[1],
+			HTTPStatus:    httpStatus,
+			ErrorCode:     errorCode,
+			Count:         count,
+		})
+	}
+
+	return counts, nil
+}
+
+// matches reports whether a live counter passes the filter
+func (f ErrorFilter) matches(count ErrorCount) bool {
+	return (f.Route == "" || f.Route == count.Route) &&
+		(f.ClientVersion == "" || f.ClientVersion == count.ClientVersion) &&
+		(f.ErrorCode == 0 || f.ErrorCode == count.ErrorCode)
+}
+
+// errorBucketName joins a route, client version, HTTP status and error code into an hour hash field
+func errorBucketName(route, clientVersion string, httpStatus, errorCode int) string {
+	return route + "|" + clientVersion + "|" + strconv.Itoa(httpStatus) + "|" + strconv.Itoa(errorCode)
+}
+
+// Redis keys for error counters
+func redisKeyForErrorHours() string {
+	return "errors:hours"
+}
+
+func redisKeyForErrorHour(hour string) string {
+	return "errors:" + hour
+}
@@ -0,0 +1,10 @@
+package analytics
+
+import "errors"
+
+// Common errors
+var (
+	ErrInvalidDay   = errors.New("Day must be formatted as YYYY-MM-DD")
+	ErrInvalidRange = errors.New("Invalid usage date range")
+	ErrInvalidHour  = errors.New("Hour must be formatted as YYYY-MM-DDTHH")
+)
@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository interface for usage and error metric operations
+type Repository interface {
+	UpsertDailyUsage(ctx context.Context, metrics []*models.UsageMetric) error
+	GetDailyUsage(ctx context.Context, from, to, routeFamily string) ([]models.UsageMetric, error)
+	UpsertHourlyErrors(ctx context.Context, metrics []*models.ErrorMetric) error
+	GetHourlyErrors(ctx context.Context, from, to string, filter ErrorFilter) ([]models.ErrorMetric, error)
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new usage metrics repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// UpsertDailyUsage stores flushed day counters. Counts replace earlier values, so flushing a day twice is harmless.
+func (r *repo) UpsertDailyUsage(ctx context.Context, metrics []*models.UsageMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	for _, metric := range metrics {
+		metric.ModifiedAt = now
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "day"}, {Name: "route_family"}, {Name: "client_version"}},
+		DoUpdates: clause.AssignmentColumns([]string{"unique_users", "requests", "modified_at"}),
+	}).Create(&metrics).Error
+}
+
+// GetDailyUsage retrieves flushed usage between two days inclusive, optionally for a single route family
+func (r *repo) GetDailyUsage(ctx context.Context, from, to, routeFamily string) ([]models.UsageMetric, error) {
+	var metrics []models.UsageMetric
+	query := r.db.WithContext(ctx).
+		Where("day >= ? AND day <= ?", from, to)
+	if routeFamily != "" {
+		query = query.Where("route_family = ?", routeFamily)
+	}
+
+	err := query.
+		Order("day ASC, route_family ASC, client_version ASC").
+		Find(&metrics).Error
+	return metrics, err
+}
+
+// UpsertHourlyErrors stores flushed hour counters. Like usage, counts replace earlier values.
+func (r *repo) UpsertHourlyErrors(ctx context.Context, metrics []*models.ErrorMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	for _, metric := range metrics {
+		metric.ModifiedAt = now
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hour"}, {Name: "route"}, {Name: "client_version"}, {Name: "http_status"}, {Name: "error_code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"count", "modified_at"}),
+	}).Create(&metrics).Error
+}
+
+// GetHourlyErrors retrieves flushed error counts between two hours inclusive, narrowed by the filter
+func (r *repo) GetHourlyErrors(ctx context.Context, from, to string, filter ErrorFilter) ([]models.ErrorMetric, error) {
+	var metrics []models.ErrorMetric
+	query := r.db.WithContext(ctx).
+		Where("hour >= ? AND hour <= ?", from, to)
+	if filter.Route != "" {
+		query = query.Where("route = ?", filter.Route)
+	}
+	if filter.ClientVersion != "" {
+		query = query.Where("client_version = ?", filter.ClientVersion)
+	}
+	if filter.ErrorCode != 0 {
+		query = query.Where("error_code = ?", filter.ErrorCode)
+	}
+
+	err := query.
+		Order("hour ASC, route ASC, client_version ASC, error_code ASC").
+		Find(&metrics).Error
+	return metrics, err
+}
@@ -0,0 +1,326 @@
+package analytics
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// DAY_FORMAT is how usage days are written in keys, rows and query parameters (UTC)
+	DAY_FORMAT = "2006-01-02"
+
+	// UNKNOWN_CLIENT_VERSION is counted for clients that send no usable version
+	UNKNOWN_CLIENT_VERSION = "unknown"
+
+	// MAX_USAGE_RANGE_DAYS bounds how many days one usage query may span
+	MAX_USAGE_RANGE_DAYS = 366
+
+	// usageFlushGrace keeps a finished day in Redis a little longer so requests still in flight at midnight are counted
+	usageFlushGrace = 10 * time.Minute
+
+	// maxClientVersionLength bounds client supplied versions so they cannot blow up the number of counters
+	maxClientVersionLength = 32
+)
+
+// NewService creates a new usage and error metrics service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger, cfg *config.UsageMetricsConfig, errorsCfg *config.ErrorMetricsConfig, consent ConsentChecker) *Service {
+	return &Service{
+		repo:         repo,
+		redisClient:  redisClient,
+		logger:       logger,
+		config:       cfg,
+		errorsConfig: errorsCfg,
+		consent:      consent,
+	}
+}
+
+// Record counts one request of a user towards the usage of a route family from a client version.
+// Nothing is stored for users who have not granted analytics consent. The user is only kept as a
+// salted hash inside a HyperLogLog, and the salt is discarded when the day is flushed.
+func (s *Service) Record(ctx context.Context, userID, routeFamily, clientVersion string) error {
+	if !s.config.Enabled || userID == "" || routeFamily == "" {
+		return nil
+	}
+	if !s.consent.HasConsent(ctx, userID, user.CONSENT_ANALYTICS) {
+		return nil
+	}
+
+	day := time.Now().UTC().Format(DAY_FORMAT)
+	salt, err := s.daySalt(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	bucket := bucketName(routeFamily, NormalizeClientVersion(clientVersion))
+	usersKey := redisKeyForUsers(day, bucket)
+	requestsKey := redisKeyForRequests(day, bucket)
+	indexKey := redisKeyForDayIndex(day)
+
+	_, err = s.redisClient.Pipeline(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.PFAdd(ctx, usersKey, anonymize(userID, salt))
+		pipe.Incr(ctx, requestsKey)
+		pipe.SAdd(ctx, indexKey, bucket)
+		pipe.SAdd(ctx, redisKeyForDays(), day)
+		for _, key := range []string{usersKey, requestsKey, indexKey} {
+			pipe.Expire(ctx, key, s.config.Retention)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetDailyUsage returns the flushed usage between two days inclusive, optionally for a single route family.
+// Days still being counted are not included; see GetTodayUsage.
+func (s *Service) GetDailyUsage(ctx context.Context, from, to, routeFamily string) ([]models.UsageMetric, error) {
+	fromDay, err := time.Parse(DAY_FORMAT, from)
+	if err != nil {
+		return nil, ErrInvalidDay
+	}
+	toDay, err := time.Parse(DAY_FORMAT, to)
+	if err != nil {
+		return nil, ErrInvalidDay
+	}
+	if toDay.Before(fromDay) || toDay.Sub(fromDay) > MAX_USAGE_RANGE_DAYS*24*time.Hour {
+		return nil, ErrInvalidRange
+	}
+
+	metrics, err := s.repo.GetDailyUsage(ctx, from, to, routeFamily)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// GetTodayUsage returns the live counters of the current UTC day
+func (s *Service) GetTodayUsage(ctx context.Context) ([]UsageCount, error) {
+	return s.readDay(ctx, time.Now().UTC().Format(DAY_FORMAT))
+}
+
+// StartFlushScheduler moves the usage counters of finished days and the error counters of finished
+// hours into the database until ctx is cancelled.
+// Instances take turns through a Redis lock.
+func (s *Service) StartFlushScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.config.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			s.runFlushPass(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runFlushPass runs one flush unless another instance is already running one
+func (s *Service) runFlushPass(ctx context.Context) {
+	lockName := "usage_metrics_flush"
+	acquired, err := s.redisClient.AcquireLock(ctx, lockName, s.config.FlushInterval, 1, 0)
+	if err != nil {
+		s.logger.Errorf("Failed to acquire usage metrics flush lock: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	// Release lock when done
+	defer func() {
+		if _, err := s.redisClient.ReleaseLock(context.Background(), lockName); err != nil {
+			s.logger.Errorf("Failed to release lock %s: %v", lockName, err)
+		}
+	}()
+
+	days, err := s.redisClient.SMembers(ctx, redisKeyForDays())
+	if err != nil {
+		s.logger.Errorf("Failed to list usage metric days: %v", err)
+		return
+	}
+
+	// Only days that ended before the grace period are final
+	cutoff := time.Now().UTC().Add(-usageFlushGrace).Format(DAY_FORMAT)
+	sort.Strings(days)
+	for _, day := range days {
+		if day >= cutoff {
+			continue
+		}
+		if err := s.flushDay(ctx, day); err != nil {
+			s.logger.Errorf("Failed to flush usage metrics of %s: %v", day, err)
+		}
+	}
+
+	s.flushErrorHours(ctx)
+}
+
+// flushDay stores a finished day's counters and then drops them and the day's salt from Redis
+func (s *Service) flushDay(ctx context.Context, day string) error {
+	counts, err := s.readDay(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	metrics := make([]*models.UsageMetric, len(counts))
+	for i, count := range counts {
+		metrics[i] = &models.UsageMetric{
+			Day:           count.Day,
+			RouteFamily:   count.RouteFamily,
+			ClientVersion: count.ClientVersion,
+			UniqueUsers:   count.UniqueUsers,
+			Requests:      count.Requests,
+		}
+	}
+	if err := s.repo.UpsertDailyUsage(ctx, metrics); err != nil {
+		return err
+	}
+
+	keys := []string{redisKeyForDayIndex(day), redisKeyForSalt(day)}
+	for _, count := range counts {
+		bucket := bucketName(count.RouteFamily, count.ClientVersion)
+		keys = append(keys, redisKeyForUsers(day, bucket), redisKeyForRequests(day, bucket))
+	}
+	if _, err := s.redisClient.DeleteMany(ctx, keys...); err != nil {
+		return err
+	}
+	_, err = s.redisClient.SRem(ctx, redisKeyForDays(), day)
+	return err
+}
+
+// readDay reads the counters of every route family and client version seen on a day
+func (s *Service) readDay(ctx context.Context, day string) ([]UsageCount, error) {
+	buckets, err := s.redisClient.SMembers(ctx, redisKeyForDayIndex(day))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(buckets)
+
+	counts := make([]UsageCount, 0, len(buckets))
+	for _, bucket := range buckets {
+		routeFamily, clientVersion, ok := strings.Cut(bucket, "|")
+		if !ok {
+			continue
+		}
+
+		uniqueUsers, err := s.redisClient.PFCount(ctx, redisKeyForUsers(day, bucket))
+		if err != nil {
+			return nil, err
+		}
+		value, err := s.redisClient.Get(ctx, redisKeyForRequests(day, bucket))
+		if err != nil {
+			return nil, err
+		}
+		requests, _ := strconv.ParseInt(value, 10, 64)
+
+		counts = append(counts, UsageCount{
+			Day:           day,
+			RouteFamily:   routeFamily,
+			ClientVersion: clientVersion,
+			UniqueUsers:   uniqueUsers,
+			Requests:      requests,
+		})
+	}
+
+	return counts, nil
+}
+
+// daySalt returns the random salt users are hashed with on a day, creating it on first use.
+// Every instance shares the salt so a user is counted once per day across the cluster.
+func (s *Service) daySalt(ctx context.Context, day string) (string, error) {
+	s.saltMu.Lock()
+	defer s.saltMu.Unlock()
+
+	if s.saltDay == day {
+		return s.salt, nil
+	}
+
+	saltBytes := make([]byte, 32)
+	if _, err := rand.Read(saltBytes); err != nil {
+		return "", err
+	}
+
+	key := redisKeyForSalt(day)
+	if _, err := s.redisClient.SetNX(ctx, key, hex.EncodeToString(saltBytes), s.config.Retention); err != nil {
+		return "", err
+	}
+	// Another instance may have created it first
+	salt, err := s.redisClient.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if salt == "" {
+		return "", fmt.Errorf("usage metrics salt for %s is missing", day)
+	}
+
+	s.saltDay = day
+	s.salt = salt
+	return salt, nil
+}
+
+// NormalizeClientVersion reduces a client supplied version to a short, safe label
+func NormalizeClientVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if version == "" || len(version) > maxClientVersionLength {
+		return UNKNOWN_CLIENT_VERSION
+	}
+
+	for _, r := range version {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '+':
+		default:
+			return UNKNOWN_CLIENT_VERSION
+		}
+	}
+
+	return version
+}
+
+// anonymize replaces a user ID with a keyed hash that cannot be reversed once the salt is gone
+func anonymize(userID, salt string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// bucketName joins a route family and client version into a day index member
+func bucketName(routeFamily, clientVersion string) string {
+	return routeFamily + "|" + clientVersion
+}
+
+// Redis keys for usage counters
+func redisKeyForDays() string {
+	return "usage:days"
+}
+
+func redisKeyForDayIndex(day string) string {
+	return "usage:" + day + ":index"
+}
+
+func redisKeyForSalt(day string) string {
+	return "usage:" + day + ":salt"
+}
+
+func redisKeyForUsers(day, bucket string) string {
+	return "usage:" + day + ":users:" + bucket
+}
+
+func redisKeyForRequests(day, bucket string) string {
+	return "usage:" + day + ":requests:" + bucket
+}
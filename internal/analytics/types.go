@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"sync"
+)
+
+// ConsentChecker reports whether a user currently grants a consent type
+type ConsentChecker interface {
+	HasConsent(ctx context.Context, userID, consentType string) bool
+}
+
+// Service counts anonymized feature usage and API error codes in Redis and flushes finished periods into the database
+type Service struct {
+	repo         Repository
+	redisClient  *redis.Client
+	logger       *logger.Logger
+	config       *config.UsageMetricsConfig
+	errorsConfig *config.ErrorMetricsConfig
+	consent      ConsentChecker
+
+	// The salt of the current day is cached so recording does not read it from Redis every time
+	saltMu  sync.Mutex
+	saltDay string
+	salt    string
+}
+
+// UsageCount is the usage of one route family from one client version during a day
+type UsageCount struct {
+	Day           string
+	RouteFamily   string
+	ClientVersion string
+	UniqueUsers   int64
+	Requests      int64
+}
+
+// ErrorCount is how often one route answered a client version with an HTTP status and API error code during an hour
+type ErrorCount struct {
+	Hour          string
+	Route         string
+	ClientVersion string
+	HTTPStatus    int
+	ErrorCode     int
+	Count         int64
+}
+
+// ErrorFilter narrows an error metrics query. Empty fields match everything.
+type ErrorFilter struct {
+	Route         string
+	ClientVersion string
+	ErrorCode     int
+}
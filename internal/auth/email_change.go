@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/security"
+	"context"
+	"time"
+)
+
+// emailChangeLockTTL bounds how long one email change holds its token against concurrent use
+const emailChangeLockTTL = 30 * time.Second
+
+// EmailChange carries the confirmation of an email change, with SRP credentials the client derived
+// for the new address
+type EmailChange struct {
+	Token       string
+	SRPSalt     string
+	SRPVerifier string
+	SessionID   string // Session that confirms the change; every other session is revoked
+	IPAddress   string
+}
+
+// RequestEmailChange emails a link that confirms moving the user's account to a new address to that
+// address. The account keeps its current address until the change is confirmed.
+func (s *Service) RequestEmailChange(ctx context.Context, userID, newEmail string) (*mfa.EmailVerificationResult, error) {
+	account, err := s.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := s.userService.CheckNewEmail(ctx, userID, newEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.mfaService.SendEmailChangeVerification(ctx, email, account.Username)
+}
+
+// ConfirmEmailChange moves the signed in user's account to the address an email change token was sent
+// to. The token is used up, every other session of the account is revoked, the previous address is
+// told about the change and the change is recorded as a security event.
+func (s *Service) ConfirmEmailChange(ctx context.Context, userID string, change EmailChange) error {
+	if userID == "" || change.Token == "" || change.SessionID == "" {
+		return ErrInvalidInput
+	}
+
+	// Hold the token so two requests cannot both change the address with it
+	lockName := "email_change:" + change.Token
+	acquired, err := s.redisClient.AcquireLock(ctx, lockName, emailChangeLockTTL, 1, 0)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrInvalidEmailChangeToken
+	}
+	defer s.redisClient.ReleaseLock(context.WithoutCancel(ctx), lockName)
+
+	newEmail, username, err := s.mfaService.EmailChangeRequest(ctx, change.Token)
+	if err != nil {
+		return ErrInvalidEmailChangeToken
+	}
+
+	account, err := s.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// Tokens only change the address of the account that asked for them
+	if account.Username != username {
+		return ErrInvalidEmailChangeToken
+	}
+	previousEmail := account.Email
+
+	// The address may have been taken since the verification email was sent
+	email, err := s.userService.CheckNewEmail(ctx, userID, newEmail)
+	if err != nil {
+		return err
+	}
+
+	userSRP, err := s.srpService.GetUserSRPByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.srpService.ChangeEmail(ctx, userSRP, email, change.SRPSalt, change.SRPVerifier); err != nil {
+		s.recordEmailChange(ctx, userID, change.IPAddress, false)
+		return err
+	}
+
+	if err := s.mfaService.ConsumeEmailChangeToken(ctx, change.Token); err != nil {
+		s.logger.Error("Failed to use up email change token", "userID", userID, "error", err)
+	}
+
+	s.userService.EmailChanged(ctx, userID, previousEmail, email)
+
+	// Whoever else was signed in has to sign in with the new address
+	if s.sessionService != nil {
+		if _, err := s.sessionService.RevokeOtherUserSessions(ctx, userID, change.SessionID); err != nil {
+			s.logger.Error("Failed to revoke other sessions after email change", "userID", userID, "error", err)
+		}
+	}
+
+	if err := s.mfaService.SendEmailChangedEmail(previousEmail, email, userSRP.ModifiedAt); err != nil {
+		s.logger.Error("Failed to notify previous address of email change", "userID", userID, "error", err)
+	}
+
+	s.recordEmailChange(ctx, userID, change.IPAddress, true)
+
+	return nil
+}
+
+// recordEmailChange records an email change attempt as a security event of the user
+func (s *Service) recordEmailChange(ctx context.Context, userID, ipAddress string, success bool) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_EMAIL_CHANGED,
+		Success:   success,
+		IPAddress: ipAddress,
+	})
+}
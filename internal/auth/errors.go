@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"time"
 )
 
 // Custom error types for the auth package
@@ -23,4 +24,43 @@ var (
 
 	// ErrUsernameAlreadyExists indicates the username is already in use
 	ErrUsernameAlreadyExists = errors.New("Username already exists")
+
+	// ErrLoginMFAExpired indicates the second login step is unknown, finished or timed out
+	ErrLoginMFAExpired = errors.New("Login verification has expired, please sign in again")
+
+	// ErrTooManyMFAAttempts indicates the second login step failed too often and was cancelled
+	ErrTooManyMFAAttempts = errors.New("Too many failed verification attempts, please sign in again")
+
+	// ErrMFAMethodNotAllowed indicates the chosen second factor is not set up for the account
+	ErrMFAMethodNotAllowed = errors.New("Verification method is not available for this account")
+
+	// ErrInvalidResetToken indicates the password reset link is unknown, used or expired
+	ErrInvalidResetToken = errors.New("Password reset link is invalid or has expired")
+
+	// ErrLoginThrottled indicates too many failed logins to try again yet
+	ErrLoginThrottled = errors.New("Too many failed sign in attempts, please wait before trying again")
+
+	// ErrAccountLocked indicates the account was locked after too many failed logins
+	ErrAccountLocked = errors.New("Account is temporarily locked after too many failed sign in attempts")
+
+	// ErrInvalidUnlockToken indicates the account unlock link is unknown, used or expired
+	ErrInvalidUnlockToken = errors.New("Account unlock link is invalid or has expired")
+
+	// ErrInvalidEmailChangeToken indicates the email change link is unknown, used, expired or was sent
+	// for another account
+	ErrInvalidEmailChangeToken = errors.New("Email change link is invalid or has expired")
 )
+
+// LockoutError refuses a login attempt until RetryAfter passed. Err is ErrLoginThrottled or ErrAccountLocked.
+type LockoutError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LockoutError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/config"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	// Redis key prefixes
+	loginFailuresPrefix   = "auth:lockout:failures:"   // Failed logins of an account by user ID
+	loginThrottlePrefix   = "auth:lockout:throttle:"   // Delay before an account's next attempt by user ID
+	accountLockPrefix     = "auth:lockout:locked:"     // Locked accounts by user ID
+	accountUnlockPrefix   = "auth:lockout:unlock:"     // User IDs by the unlock token from the lockout email
+	ipLoginFailuresPrefix = "auth:lockout:ip:"         // Failed logins from an IP address, across accounts
+	ipBlockPrefix         = "auth:lockout:ip_blocked:" // Blocked IP addresses
+)
+
+// LockoutMailer sends the email that tells a user their account was locked
+type LockoutMailer interface {
+	SendAccountLockedEmail(userID string, failedAttempts int, lockDuration time.Duration, unlockToken string) error
+}
+
+// LockoutStatus is the lockout state of an account, as shown in its security settings
+type LockoutStatus struct {
+	FailedAttempts int
+	Locked         bool
+	LockedUntil    int64 // Zero unless locked
+	ThrottledUntil int64 // When the next login may be attempted, zero when it may be attempted now
+}
+
+// accountLock is a locked account
+type accountLock struct {
+	LockedAt       int64  `json:"lockedAt"`
+	LockedUntil    int64  `json:"lockedUntil"`
+	FailedAttempts int    `json:"failedAttempts"`
+	IPAddress      string `json:"ipAddress"`
+}
+
+// SetLockoutMailer configures how users are told that their account was locked. Without a mailer
+// locked accounts unlock when the lock expires or from the security settings of a signed in session.
+func (s *Service) SetLockoutMailer(mailer LockoutMailer) {
+	s.lockoutMailer = mailer
+}
+
+// checkLoginAllowed refuses a login attempt from a blocked IP address, for a locked account or
+// before the delay after the account's last failure passed. userID is empty for unknown accounts.
+func (s *Service) checkLoginAllowed(ctx context.Context, userID, ipAddress string) error {
+	if retryAfter := s.remaining(ctx, ipBlockPrefix+ipAddress); retryAfter > 0 {
+		return &LockoutError{Err: ErrLoginThrottled, RetryAfter: retryAfter}
+	}
+	if userID == "" {
+		return nil
+	}
+
+	if retryAfter := s.remaining(ctx, accountLockPrefix+userID); retryAfter > 0 {
+		return &LockoutError{Err: ErrAccountLocked, RetryAfter: retryAfter}
+	}
+	if retryAfter := s.remaining(ctx, loginThrottlePrefix+userID); retryAfter > 0 {
+		return &LockoutError{Err: ErrLoginThrottled, RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// remaining returns how long a lockout key lives on, zero when it does not exist. Lockouts fail open,
+// so a Redis outage does not keep everyone from signing in.
+func (s *Service) remaining(ctx context.Context, key string) time.Duration {
+	ttl, err := s.redisClient.TTL(ctx, key)
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// recordLoginFailure counts a failed login against the IP address and, when known, the account.
+// Failures beyond the free attempts delay the next attempt, doubling the delay each time, and
+// reaching the threshold locks the account and emails its owner an unlock link.
+func (s *Service) recordLoginFailure(ctx context.Context, userID, ipAddress string) {
+	cfg := s.lockoutConfig
+
+	ipFailures, err := s.redisClient.IncrWithExpiry(ctx, ipLoginFailuresPrefix+ipAddress, 1, cfg.FailureWindow)
+	if err != nil {
+		s.logger.Errorf("Failed to count failed login of IP address %s: %v", ipAddress, err)
+	} else if ipFailures >= int64(cfg.IPThreshold) {
+		if err := s.redisClient.Set(ctx, ipBlockPrefix+ipAddress, ipFailures, cfg.IPBlockDuration); err != nil {
+			s.logger.Errorf("Failed to block IP address %s after failed logins: %v", ipAddress, err)
+		}
+		_, _ = s.redisClient.Delete(ctx, ipLoginFailuresPrefix+ipAddress)
+		s.logger.Warnf("Blocked IP address %s after %d failed logins", ipAddress, ipFailures)
+	}
+
+	if userID == "" {
+		return
+	}
+
+	// Failures are remembered for the window after the last one rather than the first
+	key := loginFailuresPrefix + userID
+	failures, err := s.redisClient.IncrWithExpiry(ctx, key, 1, cfg.FailureWindow)
+	if err != nil {
+		s.logger.Errorf("Failed to count failed login of account %s: %v", userID, err)
+		return
+	}
+	_, _ = s.redisClient.Expire(ctx, key, cfg.FailureWindow)
+
+	if failures >= int64(cfg.AccountThreshold) {
+		s.lockAccount(ctx, userID, int(failures), ipAddress)
+		return
+	}
+
+	if excess := int(failures) - cfg.FreeAttempts; excess > 0 {
+		if err := s.redisClient.Set(ctx, loginThrottlePrefix+userID, failures, loginDelay(cfg, excess)); err != nil {
+			s.logger.Errorf("Failed to delay next login attempt of account %s: %v", userID, err)
+		}
+	}
+}
+
+// loginDelay returns the delay after the given number of failures beyond the free attempts
+func loginDelay(cfg *config.LoginLockoutConfig, excess int) time.Duration {
+	delay := cfg.BaseDelay
+	for i := 1; i < excess && delay < cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, cfg.MaxDelay)
+}
+
+// lockAccount locks an account for the lock duration and emails its owner an unlock link. The
+// failure count starts over, so an account that is unlocked gets its free attempts back.
+func (s *Service) lockAccount(ctx context.Context, userID string, failures int, ipAddress string) {
+	cfg := s.lockoutConfig
+	now := time.Now()
+
+	lock, err := json.Marshal(accountLock{
+		LockedAt:       now.Unix(),
+		LockedUntil:    now.Add(cfg.LockDuration).Unix(),
+		FailedAttempts: failures,
+		IPAddress:      ipAddress,
+	})
+	if err != nil {
+		return
+	}
+
+	// Concurrent failures must not lock the account twice and send two emails
+	locked, err := s.redisClient.SetNX(ctx, accountLockPrefix+userID, string(lock), cfg.LockDuration)
+	if err != nil {
+		s.logger.Errorf("Failed to lock account %s after failed logins: %v", userID, err)
+		return
+	}
+	_, _ = s.redisClient.DeleteMany(ctx, loginFailuresPrefix+userID, loginThrottlePrefix+userID)
+	if !locked {
+		return
+	}
+
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_ACCOUNT_LOCKED,
+		Success:   true,
+		IPAddress: ipAddress,
+		Metadata:  map[string]any{"failedAttempts": failures, "lockedUntil": now.Add(cfg.LockDuration).Unix()},
+	})
+
+	if s.lockoutMailer == nil {
+		return
+	}
+
+	token := utils.GenerateID()
+	if err := s.redisClient.Set(ctx, accountUnlockPrefix+token, userID, cfg.LockDuration); err != nil {
+		s.logger.Errorf("Failed to store unlock token of account %s: %v", userID, err)
+		return
+	}
+	if err := s.lockoutMailer.SendAccountLockedEmail(userID, failures, cfg.LockDuration, token); err != nil {
+		s.logger.Errorf("Failed to send lockout email of account %s: %v", userID, err)
+	}
+}
+
+// resetLoginFailures forgets the failed logins of an account after it signed in
+func (s *Service) resetLoginFailures(ctx context.Context, userID string) {
+	_, _ = s.redisClient.DeleteMany(ctx, loginFailuresPrefix+userID, loginThrottlePrefix+userID)
+}
+
+// UnlockAccount unlocks the account the unlock link from a lockout email was sent for. Tokens are
+// used up by the unlock and expire with the lock they were issued for.
+func (s *Service) UnlockAccount(ctx context.Context, token, ipAddress string) error {
+	if token == "" {
+		return ErrInvalidUnlockToken
+	}
+
+	userID, err := s.redisClient.Get(ctx, accountUnlockPrefix+token)
+	if err != nil {
+		return err
+	}
+	if userID == "" {
+		return ErrInvalidUnlockToken
+	}
+
+	if _, err := s.redisClient.Delete(ctx, accountUnlockPrefix+token); err != nil {
+		return err
+	}
+
+	return s.unlock(ctx, userID, ipAddress, "email")
+}
+
+// UnlockOwnAccount lifts the lock of the signed in user's account, which only stops new logins
+func (s *Service) UnlockOwnAccount(ctx context.Context, userID, ipAddress string) error {
+	if userID == "" {
+		return ErrInvalidInput
+	}
+	return s.unlock(ctx, userID, ipAddress, "settings")
+}
+
+// unlock clears the lock, delay and failure count of an account and records the unlock
+func (s *Service) unlock(ctx context.Context, userID, ipAddress, method string) error {
+	removed, err := s.redisClient.DeleteMany(ctx, accountLockPrefix+userID, loginFailuresPrefix+userID, loginThrottlePrefix+userID)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return nil
+	}
+
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_ACCOUNT_UNLOCKED,
+		Success:   true,
+		IPAddress: ipAddress,
+		Metadata:  map[string]any{"method": method},
+	})
+
+	return nil
+}
+
+// GetLockoutStatus returns the failed logins, lock and login delay of a user's account
+func (s *Service) GetLockoutStatus(ctx context.Context, userID string) (*LockoutStatus, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	lockoutStatus := &LockoutStatus{}
+
+	failures, err := s.redisClient.Get(ctx, loginFailuresPrefix+userID)
+	if err != nil {
+		return nil, err
+	}
+	if failures != "" {
+		lockoutStatus.FailedAttempts, _ = strconv.Atoi(failures)
+	}
+
+	var lock accountLock
+	if err := s.redisClient.GetJSON(ctx, accountLockPrefix+userID, &lock); err == nil && lock.LockedUntil > time.Now().Unix() {
+		lockoutStatus.Locked = true
+		lockoutStatus.LockedUntil = lock.LockedUntil
+		lockoutStatus.FailedAttempts = lock.FailedAttempts
+	}
+
+	if delay := s.remaining(ctx, loginThrottlePrefix+userID); delay > 0 {
+		lockoutStatus.ThrottledUntil = time.Now().Add(delay).Unix()
+	}
+
+	return lockoutStatus, nil
+}
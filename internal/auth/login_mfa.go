@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/utils"
+	"context"
+	"slices"
+	"time"
+)
+
+const (
+	// Redis key prefixes
+	loginMFAPrefix         = "auth:login_mfa:"          // Pending second login step by token
+	loginMFAAttemptsPrefix = "auth:login_mfa:attempts:" // Failed second step attempts by token
+
+	loginMFATimeout     = 5 * time.Minute
+	maxLoginMFAAttempts = 5
+
+	// LOGIN_MFA_RECOVERY selects a TOTP recovery code when completing a login with a code
+	LOGIN_MFA_RECOVERY = "recovery"
+)
+
+// LoginMFAChallenge is handed out after SRP verification when the account needs a second factor
+type LoginMFAChallenge struct {
+	Token     string
+	Methods   []string // Preferred method first, then the fallbacks
+	ExpiresAt int64
+}
+
+// LoginMFAResult identifies the login completed by the second step
+type LoginMFAResult struct {
+	UserID      string
+	ServerProof string
+}
+
+// pendingLogin is a login that passed SRP and waits for its second factor
+type pendingLogin struct {
+	UserID      string   `json:"userId"`
+	ServerProof string   `json:"serverProof"`
+	Methods     []string `json:"methods"`
+}
+
+// StartLoginMFA decides whether a login that passed SRP needs a second factor.
+// Accounts with a passkey or TOTP must complete one of their methods before tokens are issued.
+// Accounts whose security settings require a second factor but have none set up get an
+// emailed code instead. For all other accounts, and for logins from a device the user trusts,
+// it returns nil and login completes right away.
+func (s *Service) StartLoginMFA(ctx context.Context, userID, serverProof, deviceUID, deviceTrust string) (*LoginMFAChallenge, error) {
+	trusted, err := s.userService.IsDeviceTrusted(ctx, userID, deviceUID, deviceTrust)
+	if err != nil {
+		return nil, err
+	}
+	if trusted {
+		return nil, nil
+	}
+
+	methods, err := s.mfaService.GetLoginMethods(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(methods) == 0 {
+		required, err := s.userService.IsTwoFactorRequired(ctx, userID)
+		if err != nil || !required {
+			return nil, err
+		}
+		methods = []string{mfa.MFA_METHOD_EMAIL}
+	}
+
+	token := utils.GenerateID()
+	pending := pendingLogin{UserID: userID, ServerProof: serverProof, Methods: methods}
+	if err := s.redisClient.SetJSON(ctx, loginMFAPrefix+token, pending, loginMFATimeout); err != nil {
+		return nil, err
+	}
+
+	return &LoginMFAChallenge{
+		Token:     token,
+		Methods:   methods,
+		ExpiresAt: time.Now().Add(loginMFATimeout).Unix(),
+	}, nil
+}
+
+// BeginLoginPasskey issues the passkey challenge for a pending login
+func (s *Service) BeginLoginPasskey(ctx context.Context, token string) (*mfa.PasskeyRequestOptions, error) {
+	pending, err := s.getPendingLogin(ctx, token, mfa.MFA_METHOD_WEBAUTHN)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.mfaService.BeginPasskeyLogin(ctx, pending.UserID, token)
+}
+
+// FinishLoginPasskey completes a pending login with a passkey assertion
+func (s *Service) FinishLoginPasskey(ctx context.Context, token string, assertion mfa.PasskeyAssertion) (*LoginMFAResult, error) {
+	pending, err := s.getPendingLogin(ctx, token, mfa.MFA_METHOD_WEBAUTHN)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaService.FinishPasskeyLogin(ctx, pending.UserID, token, assertion); err != nil {
+		return nil, s.failLoginMFA(ctx, token, pending.UserID, err)
+	}
+
+	return s.completeLoginMFA(ctx, token, pending)
+}
+
+// FinishLoginTOTP completes a pending login with a TOTP or recovery code
+func (s *Service) FinishLoginTOTP(ctx context.Context, token, code string) (*LoginMFAResult, error) {
+	return s.FinishLoginCode(ctx, token, mfa.MFA_METHOD_TOTP, code)
+}
+
+// SendLoginEmailCode emails the code for a pending login that is offered the email method
+func (s *Service) SendLoginEmailCode(ctx context.Context, token string) (*mfa.EmailCodeResult, error) {
+	pending, err := s.getPendingLogin(ctx, token, mfa.MFA_METHOD_EMAIL)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.mfaService.SendLoginEmailCode(ctx, pending.UserID, token)
+}
+
+// FinishLoginCode completes a pending login with a code of one of its methods.
+// Recovery codes are checked by the TOTP method they belong to.
+func (s *Service) FinishLoginCode(ctx context.Context, token, method, code string) (*LoginMFAResult, error) {
+	if code == "" {
+		return nil, ErrInvalidInput
+	}
+	if method == LOGIN_MFA_RECOVERY {
+		method = mfa.MFA_METHOD_TOTP
+	}
+
+	pending, err := s.getPendingLogin(ctx, token, method)
+	if err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case mfa.MFA_METHOD_TOTP:
+		var valid bool
+		valid, err = s.mfaService.ValidateTOTPCode(ctx, pending.UserID, code)
+		if err == nil && !valid {
+			err = mfa.ErrInvalidTOTPCode
+		}
+	case mfa.MFA_METHOD_EMAIL:
+		err = s.mfaService.VerifyLoginEmailCode(ctx, pending.UserID, token, code)
+	default:
+		return nil, ErrMFAMethodNotAllowed
+	}
+	if err != nil {
+		return nil, s.failLoginMFA(ctx, token, pending.UserID, err)
+	}
+
+	return s.completeLoginMFA(ctx, token, pending)
+}
+
+// getPendingLogin loads a pending login and checks the method was offered for it
+func (s *Service) getPendingLogin(ctx context.Context, token, method string) (*pendingLogin, error) {
+	if token == "" {
+		return nil, ErrInvalidInput
+	}
+
+	var pending pendingLogin
+	if err := s.redisClient.GetJSON(ctx, loginMFAPrefix+token, &pending); err != nil || pending.UserID == "" {
+		return nil, ErrLoginMFAExpired
+	}
+	if !slices.Contains(pending.Methods, method) {
+		return nil, ErrMFAMethodNotAllowed
+	}
+
+	return &pending, nil
+}
+
+// failLoginMFA records and counts a failed second step and cancels the login once the limit is reached
+func (s *Service) failLoginMFA(ctx context.Context, token, userID string, cause error) error {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_LOGIN_FAILED,
+		Success:   false,
+		Metadata:  map[string]any{"reason": "invalid_second_factor"},
+	})
+
+	attemptsKey := loginMFAAttemptsPrefix + token
+	attempts, err := s.redisClient.Incr(ctx, attemptsKey)
+	if err != nil {
+		return cause
+	}
+	if attempts == 1 {
+		_, _ = s.redisClient.Expire(ctx, attemptsKey, loginMFATimeout)
+	}
+
+	if attempts >= maxLoginMFAAttempts {
+		_, _ = s.redisClient.DeleteMany(ctx, loginMFAPrefix+token, attemptsKey)
+		return ErrTooManyMFAAttempts
+	}
+	return cause
+}
+
+// completeLoginMFA consumes the pending login so the second step cannot be replayed
+func (s *Service) completeLoginMFA(ctx context.Context, token string, pending *pendingLogin) (*LoginMFAResult, error) {
+	deleted, err := s.redisClient.Delete(ctx, loginMFAPrefix+token)
+	if err != nil || !deleted {
+		return nil, ErrLoginMFAExpired
+	}
+	_, _ = s.redisClient.Delete(ctx, loginMFAAttemptsPrefix+token)
+
+	return &LoginMFAResult{UserID: pending.UserID, ServerProof: pending.ServerProof}, nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/srp"
+	"context"
+	"time"
+)
+
+// passwordResetLockTTL bounds how long one reset holds its token against concurrent use
+const passwordResetLockTTL = 30 * time.Second
+
+// PasswordReset carries the new credentials of a password reset, all generated by the client
+type PasswordReset struct {
+	Token       string
+	SRPSalt     string
+	SRPVerifier string
+	Keys        []srp.KeyPassphrase
+	IPAddress   string
+}
+
+// SetSessionService lets a password reset sign the account out everywhere and an email change sign
+// out the account's other sessions
+func (s *Service) SetSessionService(sessionService *session.Service) {
+	s.sessionService = sessionService
+}
+
+// ResetPassword replaces the credentials of the account a password reset email was sent to. The
+// token is used up by a successful reset, every session of the account is revoked and the reset is
+// recorded as a security event.
+func (s *Service) ResetPassword(ctx context.Context, reset PasswordReset) error {
+	if err := srp.ValidateCredentials(reset.SRPSalt, reset.SRPVerifier, reset.Keys); err != nil {
+		return ErrInvalidInput
+	}
+
+	// Hold the token so two requests cannot both reset with it
+	lockName := "password_reset:" + reset.Token
+	acquired, err := s.redisClient.AcquireLock(ctx, lockName, passwordResetLockTTL, 1, 0)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrInvalidResetToken
+	}
+	defer s.redisClient.ReleaseLock(context.WithoutCancel(ctx), lockName)
+
+	email, err := s.mfaService.PasswordResetEmail(ctx, reset.Token)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	userSRP, err := s.srpService.GetUserSRPByEmail(email)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.srpService.ResetCredentials(ctx, userSRP, reset.SRPSalt, reset.SRPVerifier, reset.Keys); err != nil {
+		s.recordPasswordReset(ctx, userSRP.UserID, reset.IPAddress, false)
+		return err
+	}
+
+	// The credentials are already replaced, a token that outlives them cannot unlock anything new
+	if err := s.mfaService.ConsumePasswordResetToken(ctx, reset.Token); err != nil {
+		s.logger.Error("Failed to use up password reset token", "userID", userSRP.UserID, "error", err)
+	}
+
+	if s.sessionService != nil {
+		if err := s.sessionService.InvalidateAllUserSessions(ctx, userSRP.UserID); err != nil {
+			s.logger.Error("Failed to revoke sessions after password reset", "userID", userSRP.UserID, "error", err)
+		}
+	}
+
+	// Whoever guessed at the old password has nothing left to guess
+	if err := s.unlock(ctx, userSRP.UserID, reset.IPAddress, "password_reset"); err != nil {
+		s.logger.Errorf("Failed to unlock account %s after password reset: %v", userSRP.UserID, err)
+	}
+
+	s.recordPasswordReset(ctx, userSRP.UserID, reset.IPAddress, true)
+
+	return nil
+}
+
+// recordPasswordReset records a password reset attempt as a security event of the user
+func (s *Service) recordPasswordReset(ctx context.Context, userID, ipAddress string, success bool) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_PASSWORD_RESET,
+		Success:   success,
+		IPAddress: ipAddress,
+	})
+}
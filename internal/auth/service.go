@@ -2,18 +2,32 @@ package auth
 
 import (
 	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/mfa"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/session"
 	"cirrussync-api/internal/srp"
 	"cirrussync-api/internal/user"
+	"cirrussync-api/pkg/config"
 	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/tracing"
 	"context"
+	"errors"
 )
 
 // Service handles authentication operations
 type Service struct {
 	srpService  *srp.Service
 	userService *user.Service
+	mfaService  *mfa.Service
+	redisClient *redis.Client
 	logger      *logger.Logger
+
+	securityEvents *security.Service
+	sessionService *session.Service
+
+	lockoutConfig *config.LoginLockoutConfig
+	lockoutMailer LockoutMailer
 }
 
 // NewService creates a new auth service
@@ -22,6 +36,8 @@ func NewService(
 	logger *logger.Logger,
 	srpRepo srp.Repository,
 	userService *user.Service,
+	mfaService *mfa.Service,
+	lockoutConfig *config.LoginLockoutConfig,
 ) *Service {
 	// Create SRP service
 	srpService := srp.NewService(srpRepo, redisClient, logger)
@@ -29,10 +45,19 @@ func NewService(
 	return &Service{
 		srpService:  srpService,
 		userService: userService,
+		mfaService:  mfaService,
+		redisClient: redisClient,
 		logger:      logger,
+
+		lockoutConfig: lockoutConfig,
 	}
 }
 
+// SetSecurityEvents records logins and password changes as security events
+func (s *Service) SetSecurityEvents(events *security.Service) {
+	s.securityEvents = events
+}
+
 // CreateUser delegates user creation to the user service
 func (s *Service) CreateUser(ctx context.Context, email, username string, key user.UserKey) (*models.User, error) {
 	// Delegate to user service
@@ -41,12 +66,61 @@ func (s *Service) CreateUser(ctx context.Context, email, username string, key us
 
 // LoginInit initiates SRP authentication
 func (s *Service) LoginInit(ctx context.Context, email, clientPublic, ipAddress, userAgent string) (*srp.InitResponse, error) {
+	ctx, span := tracing.Start(ctx, "auth.LoginInit")
+	defer span.End()
+
+	// Whether the account is locked is only told once a proof is sent, so it does not reveal accounts
+	if err := s.checkLoginAllowed(ctx, "", ipAddress); err != nil {
+		return nil, err
+	}
+
 	return s.srpService.InitAuthentication(ctx, email, clientPublic, ipAddress, userAgent)
 }
 
-// LoginVerify verifies SRP proof and completes authentication
+// LoginVerify verifies SRP proof and completes authentication. Attempts from a blocked IP address,
+// for a locked account or too soon after a failure are refused before the proof is checked, so the
+// answer does not tell whether the password was right.
 func (s *Service) LoginVerify(ctx context.Context, sessionID, clientProof, ipAddress string) (*srp.VerifyResponse, *models.UserSRP, error) {
-	return s.srpService.VerifyAuthentication(ctx, sessionID, clientProof, ipAddress)
+	ctx, span := tracing.Start(ctx, "auth.LoginVerify")
+	defer span.End()
+
+	// The SRP session outlives a wrong proof, so the account it was started for is still known
+	userID := s.srpService.SessionUserID(ctx, sessionID)
+	if err := s.checkLoginAllowed(ctx, userID, ipAddress); err != nil {
+		return nil, nil, err
+	}
+
+	response, userSRP, err := s.srpService.VerifyAuthentication(ctx, sessionID, clientProof, ipAddress)
+	if errors.Is(err, srp.ErrInvalidClientProof) {
+		s.securityEvents.Record(ctx, security.Event{
+			UserID:    userID,
+			EventType: security.EVENT_LOGIN_FAILED,
+			Success:   false,
+			IPAddress: ipAddress,
+			Metadata:  map[string]any{"reason": "invalid_password"},
+		})
+		s.recordLoginFailure(ctx, userID, ipAddress)
+	}
+	// Proofs for sessions that were never started, like those of unknown emails, count against the IP address
+	if errors.Is(err, srp.ErrInvalidSession) {
+		s.recordLoginFailure(ctx, "", ipAddress)
+	}
+	if err == nil {
+		s.resetLoginFailures(ctx, userSRP.UserID)
+	}
+
+	return response, userSRP, err
+}
+
+// RecordLogin records a completed login, after every required factor, as a security event of the user
+func (s *Service) RecordLogin(ctx context.Context, userID, sessionID, ipAddress string) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_LOGIN_SUCCEEDED,
+		Success:   true,
+		IPAddress: ipAddress,
+		Metadata:  map[string]any{"sessionId": sessionID},
+	})
 }
 
 // RegisterSRP registers SRP credentials for a user
@@ -65,14 +139,27 @@ func (s *Service) ChangePassword(ctx context.Context, userID, email, oldSalt, ol
 	// Critical security check: Ensure provided old salt matches stored salt
 	// This is to prevent attackers from bypassing verification
 	if userSRP.Salt != oldSalt {
+		s.recordPasswordChange(ctx, userID, false)
 		return ErrInvalidCredentials
 	}
 
 	// Verify old verifier
 	if userSRP.Verifier != oldVerifier {
+		s.recordPasswordChange(ctx, userID, false)
 		return ErrInvalidCredentials
 	}
 
 	// Store new SRP credentials
-	return s.srpService.RegisterSRPCredentials(ctx, userID, email, newSalt, newVerifier)
+	err = s.srpService.RegisterSRPCredentials(ctx, userID, email, newSalt, newVerifier)
+	s.recordPasswordChange(ctx, userID, err == nil)
+	return err
+}
+
+// recordPasswordChange records a password change attempt as a security event of the user
+func (s *Service) recordPasswordChange(ctx context.Context, userID string, success bool) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_PASSWORD_CHANGED,
+		Success:   success,
+	})
 }
@@ -0,0 +1,50 @@
+package billing
+
+import (
+	"context"
+
+	"cirrussync-api/internal/payments"
+)
+
+// SetPaymentsService lets ensureCustomer wait for customers queued at signup and, when Stripe is
+// configured, makes billing the customer provider of the payments outbox
+func (s *Service) SetPaymentsService(paymentsService *payments.Service) {
+	s.payments = paymentsService
+	if s.stripe != nil {
+		paymentsService.SetCustomerProvider(s)
+	}
+}
+
+// CreateCustomer creates the user's Stripe customer. Repeated calls for the same user
+// return the same customer, so it is safe to retry.
+func (s *Service) CreateCustomer(ctx context.Context, userID, email string) (string, error) {
+	if s.stripe == nil {
+		return "", ErrBillingDisabled
+	}
+
+	customer, err := s.stripe.createCustomer(ctx, userID, email)
+	if err != nil {
+		return "", err
+	}
+	return customer.ID, nil
+}
+
+// GetCustomerStatus reports whether the user's Stripe customer is ready for purchases
+func (s *Service) GetCustomerStatus(ctx context.Context, userID string) (string, error) {
+	if s.stripe == nil {
+		return "", ErrBillingDisabled
+	}
+
+	if s.payments != nil {
+		return s.payments.GetCustomerStatus(ctx, userID)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if payments.HasCustomer(user.StripeCustomerID) {
+		return payments.CUSTOMER_STATUS_READY, nil
+	}
+	return payments.CUSTOMER_STATUS_NONE, nil
+}
@@ -0,0 +1,31 @@
+package billing
+
+import "errors"
+
+// Common errors
+var (
+	ErrInvalidSignature      = errors.New("Invalid webhook signature")
+	ErrWebhookDisabled       = errors.New("Webhook signing secret is not configured")
+	ErrInvalidPayload        = errors.New("Invalid webhook payload")
+	ErrCustomerNotFound      = errors.New("No user for customer")
+	ErrUserNotFound          = errors.New("User not found")
+	ErrPlanNotFound          = errors.New("Plan not found")
+	ErrUserPlanNotFound      = errors.New("User plan not found")
+	ErrBillingRecordNotFound = errors.New("Billing record not found")
+	ErrPaymentMethodNotFound = errors.New("Payment method not found")
+	ErrBillingDisabled       = errors.New("Payments are not configured")
+	ErrPaymentProvider       = errors.New("Payment provider request failed")
+	ErrInvalidBillingCycle   = errors.New("Billing cycle must be monthly or yearly")
+	ErrPlanNotPurchasable    = errors.New("Plan cannot be purchased with this billing cycle")
+	ErrSubscriptionExists    = errors.New("An active subscription already exists, change it instead")
+	ErrNoActiveSubscription  = errors.New("No active subscription")
+	ErrPlanUnchanged         = errors.New("Subscription is already on this plan")
+	ErrPlanTooSmall          = errors.New("Current storage usage exceeds the plan's storage")
+	ErrCustomerPending       = errors.New("Billing account is still being set up, try again shortly")
+	ErrGiftCardNotFound      = errors.New("Gift card not found")
+	ErrGiftCardUsed          = errors.New("Gift card has already been redeemed")
+	ErrGiftCardExpired       = errors.New("Gift card has expired")
+	ErrGiftCardBusy          = errors.New("Gift card is being redeemed, try again")
+	ErrTooManyRedemptions    = errors.New("Too many redemption attempts, try again later")
+	ErrInvalidGiftCardBatch  = errors.New("Invalid gift card batch")
+)
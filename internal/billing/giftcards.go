@@ -0,0 +1,216 @@
+package billing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Gift card limits
+const (
+	MAX_GIFT_CARD_BATCH          = 500
+	MAX_GIFT_CARD_AMOUNT         = 1000000
+	MAX_REDEMPTION_ATTEMPTS      = 10
+	REDEMPTION_ATTEMPTS_WINDOW   = time.Hour
+	GIFT_CARD_SOURCE             = "gift_card"
+	giftCardCodeLength           = 16
+	giftCardCodeGroup            = 4
+	giftCardCodeAlphabet         = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	giftCardRedemptionLockExpiry = 10 * time.Second
+)
+
+// RedeemGiftCard credits a gift card's amount to the user and consumes the card.
+// Redemptions of the same code are serialized under a Redis lock, and the card is only
+// claimed in the database while it is still unused.
+func (s *Service) RedeemGiftCard(ctx context.Context, userID, code string) (*models.GiftCard, error) {
+	attemptsKey := fmt.Sprintf("giftcard_attempts:%s", userID)
+	if attempts, err := s.redisClient.Get(ctx, attemptsKey); err == nil {
+		if count, _ := strconv.Atoi(attempts); count >= MAX_REDEMPTION_ATTEMPTS {
+			return nil, ErrTooManyRedemptions
+		}
+	}
+
+	card, err := s.redeemGiftCard(ctx, userID, code)
+	if errors.Is(err, ErrGiftCardNotFound) || errors.Is(err, ErrGiftCardUsed) || errors.Is(err, ErrGiftCardExpired) {
+		// Count failed attempts so codes cannot be guessed
+		if count, incrErr := s.redisClient.Incr(ctx, attemptsKey); incrErr == nil && count == 1 {
+			_, _ = s.redisClient.Expire(ctx, attemptsKey, REDEMPTION_ATTEMPTS_WINDOW)
+		}
+	}
+	return card, err
+}
+
+// redeemGiftCard consumes a gift card under the code's redemption lock
+func (s *Service) redeemGiftCard(ctx context.Context, userID, code string) (*models.GiftCard, error) {
+	value, ok := normalizeGiftCardCode(code)
+	if !ok {
+		return nil, ErrGiftCardNotFound
+	}
+
+	lockName := fmt.Sprintf("giftcard_redeem:%s", value)
+	acquired, err := s.redisClient.AcquireLock(ctx, lockName, giftCardRedemptionLockExpiry, 3, 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrGiftCardBusy
+	}
+
+	// Release lock when done
+	defer func() {
+		if _, err := s.redisClient.ReleaseLock(ctx, lockName); err != nil {
+			s.logger.Errorf("Failed to release lock %s: %v", lockName, err)
+		}
+	}()
+
+	card, err := s.repo.GetGiftCardByValue(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	if card.IsUsed {
+		return nil, ErrGiftCardUsed
+	}
+	now := time.Now().Unix()
+	if card.ExpirationDate > 0 && card.ExpirationDate <= now {
+		return nil, ErrGiftCardExpired
+	}
+
+	metadata, err := json.Marshal(map[string]string{"currency": card.Currency})
+	if err != nil {
+		return nil, err
+	}
+
+	credit := &models.UserCredit{
+		UserID:             userID,
+		Amount:             card.Amount,
+		Status:             "active",
+		Description:        "Gift card redemption",
+		TransactionID:      card.ID,
+		Type:               "credit",
+		Source:             GIFT_CARD_SOURCE,
+		AdditionalMetadata: metadata,
+		Active:             true,
+	}
+	if err := s.repo.RedeemGiftCard(ctx, card.ID, credit); err != nil {
+		return nil, err
+	}
+
+	card.IsUsed = true
+	card.UsedBy = userID
+	card.UsedAt = now
+
+	s.logger.WithFields(logrus.Fields{
+		"giftCardId": card.ID,
+		"userId":     userID,
+		"amount":     card.Amount,
+		"currency":   card.Currency,
+	}).Info("Gift card redeemed")
+
+	return card, nil
+}
+
+// GenerateGiftCards creates a batch of unused gift cards with random codes
+func (s *Service) GenerateGiftCards(ctx context.Context, adminID string, batch GiftCardBatch) ([]*models.GiftCard, error) {
+	if batch.Count < 1 || batch.Count > MAX_GIFT_CARD_BATCH ||
+		batch.Amount < 1 || batch.Amount > MAX_GIFT_CARD_AMOUNT || len(batch.Currency) != 3 {
+		return nil, ErrInvalidGiftCardBatch
+	}
+	now := time.Now().Unix()
+	if batch.ExpiresAt != 0 && batch.ExpiresAt <= now {
+		return nil, ErrInvalidGiftCardBatch
+	}
+
+	batchID := utils.GenerateShortID()
+	metadata, err := json.Marshal(map[string]string{"batchId": batchID})
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]*models.GiftCard, batch.Count)
+	for i := range cards {
+		code, err := generateGiftCardCode()
+		if err != nil {
+			return nil, err
+		}
+		cards[i] = &models.GiftCard{
+			ID:                 utils.GeneratePrefixedID("gift"),
+			Value:              code,
+			Amount:             batch.Amount,
+			Currency:           strings.ToUpper(batch.Currency),
+			CreatedBy:          adminID,
+			CreatedAt:          now,
+			ExpirationDate:     batch.ExpiresAt,
+			AdditionalMetadata: metadata,
+		}
+	}
+
+	if err := s.repo.CreateGiftCards(ctx, cards); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"batchId":  batchID,
+		"adminId":  adminID,
+		"count":    batch.Count,
+		"amount":   batch.Amount,
+		"currency": batch.Currency,
+	}).Info("Gift cards generated")
+
+	return cards, nil
+}
+
+// generateGiftCardCode returns a random code in the XXXX-XXXX-XXXX-XXXX format.
+// The alphabet leaves out characters that are easy to misread.
+func generateGiftCardCode() (string, error) {
+	random := make([]byte, giftCardCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	// The alphabet has 32 characters, so masking the byte keeps the choice uniform
+	raw := make([]byte, giftCardCodeLength)
+	for i, b := range random {
+		raw[i] = giftCardCodeAlphabet[b&31]
+	}
+	return formatGiftCardCode(string(raw)), nil
+}
+
+// normalizeGiftCardCode accepts a code typed with any case, spaces or dashes and
+// returns it in its stored format
+func normalizeGiftCardCode(code string) (string, bool) {
+	raw := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+
+	if len(raw) != giftCardCodeLength {
+		return "", false
+	}
+	for _, r := range raw {
+		if !strings.ContainsRune(giftCardCodeAlphabet, r) {
+			return "", false
+		}
+	}
+	return formatGiftCardCode(raw), true
+}
+
+// formatGiftCardCode splits a raw code into dash separated groups
+func formatGiftCardCode(raw string) string {
+	groups := make([]string, 0, len(raw)/giftCardCodeGroup)
+	for i := 0; i < len(raw); i += giftCardCodeGroup {
+		groups = append(groups, raw[i:i+giftCardCodeGroup])
+	}
+	return strings.Join(groups, "-")
+}
@@ -0,0 +1,304 @@
+package billing
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/db"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository interface for billing operations
+type Repository interface {
+	// WithTransaction runs fn in one transaction carried by the context it receives
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	GetUserIDByCustomerID(ctx context.Context, customerID string) (string, error)
+	GetPlanByID(ctx context.Context, planID string) (*models.Plan, error)
+	GetUserPlanByExternalReference(ctx context.Context, reference string) (*models.UserPlan, error)
+	SaveUserPlan(ctx context.Context, userPlan *models.UserPlan) error
+	GetBillingRecordByExternalReference(ctx context.Context, reference string) (*models.UserBilling, error)
+	SaveBillingRecord(ctx context.Context, record *models.UserBilling) error
+	GetPaymentMethodByExternalReference(ctx context.Context, reference string) (*models.UserPaymentMethod, error)
+	SavePaymentMethod(ctx context.Context, method *models.UserPaymentMethod) error
+	GetPlanStorageQuota(ctx context.Context, userID string) (int64, bool, error)
+	UpdateUserStorageLimit(ctx context.Context, userID string, planSpace int64) error
+
+	// Checkout and plan change methods
+	GetAvailablePlans(ctx context.Context) ([]*models.Plan, error)
+	GetActiveSubscription(ctx context.Context, userID string) (*models.UserPlan, error)
+	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+	SetStripeCustomerID(ctx context.Context, userID, customerID string) error
+
+	// Gift card methods
+	GetGiftCardByValue(ctx context.Context, value string) (*models.GiftCard, error)
+	CreateGiftCards(ctx context.Context, cards []*models.GiftCard) error
+	RedeemGiftCard(ctx context.Context, cardID string, credit *models.UserCredit) error
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new billing repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// WithTransaction runs fn in one transaction carried by the context it receives. Queries of any
+// repository that resolve their connection from that context run in it.
+func (r *repo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return db.RunInTx(ctx, r.db, fn)
+}
+
+// GetUserIDByCustomerID retrieves the user a Stripe customer belongs to
+func (r *repo) GetUserIDByCustomerID(ctx context.Context, customerID string) (string, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Select("id").
+		Where("stripe_customer_id = ?", customerID).
+		First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrCustomerNotFound
+		}
+		return "", err
+	}
+	return user.ID, nil
+}
+
+// GetPlanByID retrieves a plan by ID
+func (r *repo) GetPlanByID(ctx context.Context, planID string) (*models.Plan, error) {
+	var plan models.Plan
+	err := r.db.WithContext(ctx).
+		Where("id = ?", planID).
+		First(&plan).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetUserPlanByExternalReference retrieves the user plan backed by a provider subscription
+func (r *repo) GetUserPlanByExternalReference(ctx context.Context, reference string) (*models.UserPlan, error) {
+	var userPlan models.UserPlan
+	err := r.db.WithContext(ctx).
+		Where("external_reference = ?", reference).
+		First(&userPlan).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserPlanNotFound
+		}
+		return nil, err
+	}
+	return &userPlan, nil
+}
+
+// SaveUserPlan creates or updates a user plan
+func (r *repo) SaveUserPlan(ctx context.Context, userPlan *models.UserPlan) error {
+	return db.Conn(ctx, r.db).Omit("User", "Plan", "BillingRecords").Save(userPlan).Error
+}
+
+// GetPlanStorageQuota retrieves the largest storage quota among a user's active plans, including
+// purchased add-on storage. The boolean is false when the user has no active plan. It reads through
+// the transaction ctx carries, so a plan saved in it is seen.
+func (r *repo) GetPlanStorageQuota(ctx context.Context, userID string) (int64, bool, error) {
+	var quota *int64
+	err := db.Conn(ctx, r.db).
+		Model(&models.UserPlan{}).
+		Select("MAX(storage_quota + additional_storage)").
+		Where("user_id = ? AND status = ?", userID, PLAN_STATUS_ACTIVE).
+		Scan(&quota).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if quota == nil {
+		return 0, false, nil
+	}
+	return *quota, true, nil
+}
+
+// GetBillingRecordByExternalReference retrieves the billing record of a provider invoice
+func (r *repo) GetBillingRecordByExternalReference(ctx context.Context, reference string) (*models.UserBilling, error) {
+	var record models.UserBilling
+	err := r.db.WithContext(ctx).
+		Where("external_reference = ?", reference).
+		First(&record).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBillingRecordNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SaveBillingRecord creates or updates a billing record
+func (r *repo) SaveBillingRecord(ctx context.Context, record *models.UserBilling) error {
+	return r.db.WithContext(ctx).Omit("User", "Plan").Save(record).Error
+}
+
+// GetPaymentMethodByExternalReference retrieves a stored provider payment method
+func (r *repo) GetPaymentMethodByExternalReference(ctx context.Context, reference string) (*models.UserPaymentMethod, error) {
+	var method models.UserPaymentMethod
+	err := r.db.WithContext(ctx).
+		Where("external_reference = ?", reference).
+		First(&method).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentMethodNotFound
+		}
+		return nil, err
+	}
+	return &method, nil
+}
+
+// SavePaymentMethod creates or updates a payment method
+func (r *repo) SavePaymentMethod(ctx context.Context, method *models.UserPaymentMethod) error {
+	return r.db.WithContext(ctx).Omit("User").Save(method).Error
+}
+
+// UpdateUserStorageLimit sets the storage a user's plan grants, keeping space granted by shared volumes on top
+func (r *repo) UpdateUserStorageLimit(ctx context.Context, userID string, planSpace int64) error {
+	result := db.Conn(ctx, r.db).
+		Model(&models.UserStorage{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{
+			"base_plan_space": planSpace,
+			"max_space":       gorm.Expr("? + shared_space", planSpace),
+			"modified_at":     time.Now().Unix(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	return db.Conn(ctx, r.db).Omit("User").Create(&models.UserStorage{
+		UserID:        userID,
+		MaxSpace:      planSpace,
+		BasePlanSpace: planSpace,
+	}).Error
+}
+
+// GetAvailablePlans retrieves the plans that can be purchased, cheapest tier first
+func (r *repo) GetAvailablePlans(ctx context.Context) ([]*models.Plan, error) {
+	var plans []models.Plan
+	err := r.db.WithContext(ctx).
+		Where("available = ?", true).
+		Order("tier ASC, monthly_price ASC").
+		Find(&plans).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*models.Plan
+	result := make([]*models.Plan, len(plans))
+	for i := range plans {
+		result[i] = &plans[i]
+	}
+
+	return result, nil
+}
+
+// GetActiveSubscription retrieves the user's active plan that is backed by a provider subscription
+func (r *repo) GetActiveSubscription(ctx context.Context, userID string) (*models.UserPlan, error) {
+	var userPlan models.UserPlan
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ? AND external_reference <> ''", userID, "active").
+		Order("current_period_start DESC").
+		First(&userPlan).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoActiveSubscription
+		}
+		return nil, err
+	}
+	return &userPlan, nil
+}
+
+// GetUserByID retrieves the user fields needed to bill a user
+func (r *repo) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Select("id", "email", "stripe_customer_id").
+		Where("id = ?", userID).
+		First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetStripeCustomerID links a user to their Stripe customer
+func (r *repo) SetStripeCustomerID(ctx context.Context, userID, customerID string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("stripe_customer_id", customerID).Error
+}
+
+// GetGiftCardByValue retrieves a gift card by its code
+func (r *repo) GetGiftCardByValue(ctx context.Context, value string) (*models.GiftCard, error) {
+	var card models.GiftCard
+	err := r.db.WithContext(ctx).
+		Where("value = ?", value).
+		First(&card).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGiftCardNotFound
+		}
+		return nil, err
+	}
+	return &card, nil
+}
+
+// CreateGiftCards stores a batch of gift cards in one transaction
+func (r *repo) CreateGiftCards(ctx context.Context, cards []*models.GiftCard) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Omit("Creator", "Redeemer").CreateInBatches(cards, 100).Error
+	})
+}
+
+// RedeemGiftCard marks a gift card used by the credit's user and records the credit in one transaction.
+// The card is only claimed while it is still unused, so a card can never be credited twice.
+func (r *repo) RedeemGiftCard(ctx context.Context, cardID string, credit *models.UserCredit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.GiftCard{}).
+			Where("id = ? AND is_used = ?", cardID, false).
+			Updates(map[string]any{
+				"is_used": true,
+				"used_by": credit.UserID,
+				"used_at": time.Now().Unix(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrGiftCardUsed
+		}
+
+		return tx.Omit("User").Create(credit).Error
+	})
+}
@@ -0,0 +1,409 @@
+package billing
+
+import (
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Stripe event types handled by the webhook
+const (
+	EVENT_SUBSCRIPTION_CREATED    = "customer.subscription.created"
+	EVENT_SUBSCRIPTION_UPDATED    = "customer.subscription.updated"
+	EVENT_SUBSCRIPTION_DELETED    = "customer.subscription.deleted"
+	EVENT_INVOICE_PAID            = "invoice.paid"
+	EVENT_INVOICE_PAYMENT_FAILED  = "invoice.payment_failed"
+	EVENT_PAYMENT_METHOD_ATTACHED = "payment_method.attached"
+	EVENT_PAYMENT_METHOD_UPDATED  = "payment_method.updated"
+	EVENT_PAYMENT_METHOD_DETACHED = "payment_method.detached"
+)
+
+// User plan states
+const (
+	PLAN_STATUS_ACTIVE     = "active"
+	PLAN_STATUS_INCOMPLETE = "incomplete"
+	PLAN_STATUS_SUSPENDED  = "suspended"
+	PLAN_STATUS_PAUSED     = "paused"
+	PLAN_STATUS_CANCELED   = "canceled"
+)
+
+// Billing record states
+const (
+	BILLING_STATUS_PAID   = "paid"
+	BILLING_STATUS_FAILED = "failed"
+)
+
+// PROCESSED_EVENT_EXPIRATION covers Stripe's retry window, so redelivered events are skipped
+const PROCESSED_EVENT_EXPIRATION = 72 * time.Hour
+
+// EVENT_CLAIM_EXPIRATION bounds how long an event stays claimed by a delivery that never finished,
+// for instance because the instance applying it went down
+const EVENT_CLAIM_EXPIRATION = 5 * time.Minute
+
+// NewService creates a new billing service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger, cfg *config.BillingConfig, quotaService *quota.Service, driveService *drive.Service) *Service {
+	return &Service{
+		repo:         repo,
+		redisClient:  redisClient,
+		logger:       logger,
+		config:       cfg,
+		quotaService: quotaService,
+		driveService: driveService,
+		stripe:       newStripeClient(cfg),
+	}
+}
+
+// HandleStripeWebhook verifies a Stripe webhook delivery and applies the event.
+// Events for unknown customers or of unhandled types are acknowledged without changes,
+// while storage errors are returned so Stripe retries the delivery.
+func (s *Service) HandleStripeWebhook(ctx context.Context, payload []byte, signatureHeader string) error {
+	if s.config == nil || s.config.StripeWebhookSecret == "" {
+		return ErrWebhookDisabled
+	}
+
+	if err := verifyStripeSignature(payload, signatureHeader, s.config.StripeWebhookSecret, s.config.StripeWebhookTolerance, time.Now()); err != nil {
+		return err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil || event.ID == "" {
+		return ErrInvalidPayload
+	}
+
+	// Stripe delivers at least once, and may deliver the same event concurrently. Only the delivery
+	// that claims the event applies it.
+	processedKey := fmt.Sprintf("stripe_event:%s", event.ID)
+	claimed, err := s.redisClient.SetNX(ctx, processedKey, event.Type, EVENT_CLAIM_EXPIRATION)
+	if err != nil {
+		return fmt.Errorf("failed to claim Stripe event: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	err = s.applyEvent(ctx, &event)
+	if errors.Is(err, ErrCustomerNotFound) {
+		s.logger.WithFields(logrus.Fields{
+			"eventId":   event.ID,
+			"eventType": event.Type,
+		}).Warn("Ignoring Stripe event for unknown customer")
+		err = nil
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to apply Stripe event %s (%s): %v", event.ID, event.Type, err)
+		// Let Stripe's retry apply the event
+		if _, delErr := s.redisClient.Delete(context.WithoutCancel(ctx), processedKey); delErr != nil {
+			s.logger.Errorf("Failed to release Stripe event %s: %v", event.ID, delErr)
+		}
+		return err
+	}
+
+	_ = s.redisClient.Set(ctx, processedKey, event.Type, PROCESSED_EVENT_EXPIRATION)
+
+	return nil
+}
+
+// applyEvent dispatches an event to its handler
+func (s *Service) applyEvent(ctx context.Context, event *stripeEvent) error {
+	switch event.Type {
+	case EVENT_SUBSCRIPTION_CREATED, EVENT_SUBSCRIPTION_UPDATED, EVENT_SUBSCRIPTION_DELETED:
+		var subscription stripeSubscription
+		if err := json.Unmarshal(event.Data.Object, &subscription); err != nil {
+			return ErrInvalidPayload
+		}
+		return s.syncSubscription(ctx, &subscription)
+
+	case EVENT_INVOICE_PAID, EVENT_INVOICE_PAYMENT_FAILED:
+		var invoice stripeInvoice
+		if err := json.Unmarshal(event.Data.Object, &invoice); err != nil {
+			return ErrInvalidPayload
+		}
+		return s.recordInvoice(ctx, &invoice, event.Type == EVENT_INVOICE_PAID)
+
+	case EVENT_PAYMENT_METHOD_ATTACHED, EVENT_PAYMENT_METHOD_UPDATED, EVENT_PAYMENT_METHOD_DETACHED:
+		var method stripePaymentMethod
+		if err := json.Unmarshal(event.Data.Object, &method); err != nil {
+			return ErrInvalidPayload
+		}
+		return s.syncPaymentMethod(ctx, &method, event.Type == EVENT_PAYMENT_METHOD_DETACHED)
+	}
+
+	return nil
+}
+
+// syncSubscription mirrors a Stripe subscription into the user plan it backs
+func (s *Service) syncSubscription(ctx context.Context, subscription *stripeSubscription) error {
+	userID, err := s.repo.GetUserIDByCustomerID(ctx, subscription.Customer)
+	if err != nil {
+		return err
+	}
+
+	userPlan, err := s.repo.GetUserPlanByExternalReference(ctx, subscription.ID)
+	if err != nil {
+		if !errors.Is(err, ErrUserPlanNotFound) {
+			return err
+		}
+		userPlan = &models.UserPlan{UserID: userID, ExternalReference: subscription.ID}
+	}
+
+	var price *stripePrice
+	if len(subscription.Items.Data) > 0 {
+		price = &subscription.Items.Data[0].Price
+	}
+
+	plan, err := s.repo.GetPlanByID(ctx, subscriptionPlanID(subscription, price))
+	if err != nil {
+		return err
+	}
+
+	userPlan.PlanID = plan.ID
+	userPlan.PlanType = plan.PlanType
+	userPlan.StorageQuota = plan.StorageQuota
+	userPlan.AutoRenew = !subscription.CancelAtPeriodEnd
+	userPlan.Status, userPlan.Delinquent = planStatus(subscription.Status)
+	if userPlan.Delinquent && userPlan.DelinquentAt == 0 {
+		userPlan.DelinquentAt = time.Now().Unix()
+		userPlan.DelinquentReason = subscription.Status
+	} else if !userPlan.Delinquent {
+		userPlan.DelinquentAt = 0
+		userPlan.DelinquentReason = ""
+	}
+
+	if price != nil && price.Recurring != nil && price.Recurring.Interval == "year" {
+		userPlan.BillingCycle = "yearly"
+	} else {
+		userPlan.BillingCycle = "monthly"
+	}
+
+	// Newer API versions report the billing period per item
+	userPlan.CurrentPeriodStart = subscription.CurrentPeriodStart
+	userPlan.CurrentPeriodEnd = subscription.CurrentPeriodEnd
+	if userPlan.CurrentPeriodStart == 0 && len(subscription.Items.Data) > 0 {
+		userPlan.CurrentPeriodStart = subscription.Items.Data[0].CurrentPeriodStart
+		userPlan.CurrentPeriodEnd = subscription.Items.Data[0].CurrentPeriodEnd
+	}
+
+	if subscription.TrialStart != nil {
+		userPlan.TrialStart = *subscription.TrialStart
+	}
+	if subscription.TrialEnd != nil {
+		userPlan.TrialEnd = *subscription.TrialEnd
+	}
+	if userPlan.Status == PLAN_STATUS_CANCELED {
+		userPlan.CanceledAt = time.Now().Unix()
+		if subscription.CanceledAt != nil {
+			userPlan.CanceledAt = *subscription.CanceledAt
+		}
+		if subscription.CancellationDetails != nil {
+			userPlan.CancellationReason = subscription.CancellationDetails.Reason
+		}
+	}
+
+	// The plan and the storage it grants change together, or not at all
+	err = s.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.SaveUserPlan(ctx, userPlan); err != nil {
+			return fmt.Errorf("failed to save user plan: %w", err)
+		}
+		return s.syncStorageLimit(ctx, userID)
+	})
+	if err != nil {
+		// The limit may have been cached from the plan that was rolled back
+		s.quotaService.InvalidateLimit(ctx, userID)
+		return err
+	}
+
+	return nil
+}
+
+// recordInvoice stores a paid or failed invoice as a billing record and updates the plan's payment standing
+func (s *Service) recordInvoice(ctx context.Context, invoice *stripeInvoice, paid bool) error {
+	userID, err := s.repo.GetUserIDByCustomerID(ctx, invoice.Customer)
+	if err != nil {
+		return err
+	}
+
+	record, err := s.repo.GetBillingRecordByExternalReference(ctx, invoice.ID)
+	if err != nil {
+		if !errors.Is(err, ErrBillingRecordNotFound) {
+			return err
+		}
+		record = &models.UserBilling{UserID: userID, ExternalReference: invoice.ID}
+	}
+
+	// A late failure notice must not overwrite a payment that already went through
+	if !paid && record.Status == BILLING_STATUS_PAID {
+		return nil
+	}
+
+	subscriptionID := invoice.Subscription
+	if subscriptionID == "" && invoice.Parent != nil && invoice.Parent.SubscriptionDetails != nil {
+		subscriptionID = invoice.Parent.SubscriptionDetails.Subscription
+	}
+
+	var userPlan *models.UserPlan
+	if subscriptionID != "" {
+		userPlan, err = s.repo.GetUserPlanByExternalReference(ctx, subscriptionID)
+		if err != nil && !errors.Is(err, ErrUserPlanNotFound) {
+			return err
+		}
+	}
+
+	record.Currency = strings.ToUpper(invoice.Currency)
+	record.PeriodStart = invoice.PeriodStart
+	record.PeriodEnd = invoice.PeriodEnd
+	record.InvoiceURL = invoice.HostedInvoiceURL
+	record.ReceiptURL = invoice.InvoicePDF
+	if invoice.Description != nil {
+		record.Description = *invoice.Description
+	}
+	if userPlan != nil {
+		record.PlanID = userPlan.ID
+	}
+	if paid {
+		record.Status = BILLING_STATUS_PAID
+		record.Amount = float64(invoice.AmountPaid) / 100
+	} else {
+		record.Status = BILLING_STATUS_FAILED
+		record.Amount = float64(invoice.AmountDue) / 100
+	}
+
+	if err := s.repo.SaveBillingRecord(ctx, record); err != nil {
+		return fmt.Errorf("failed to save billing record: %w", err)
+	}
+
+	if userPlan == nil {
+		return nil
+	}
+
+	// Payment standing follows the latest invoice
+	if paid {
+		userPlan.Delinquent = false
+		userPlan.DelinquentAt = 0
+		userPlan.DelinquentReason = ""
+	} else if !userPlan.Delinquent {
+		userPlan.Delinquent = true
+		userPlan.DelinquentAt = time.Now().Unix()
+		userPlan.DelinquentReason = "payment_failed"
+	}
+
+	if err := s.repo.SaveUserPlan(ctx, userPlan); err != nil {
+		return fmt.Errorf("failed to save user plan: %w", err)
+	}
+	return nil
+}
+
+// syncPaymentMethod mirrors a payment method attached to, updated on or detached from a customer
+func (s *Service) syncPaymentMethod(ctx context.Context, method *stripePaymentMethod, detached bool) error {
+	existing, err := s.repo.GetPaymentMethodByExternalReference(ctx, method.ID)
+	if err != nil && !errors.Is(err, ErrPaymentMethodNotFound) {
+		return err
+	}
+
+	if detached {
+		// Detached methods no longer carry the customer, so only known methods can be updated
+		if existing == nil {
+			return nil
+		}
+		existing.Active = false
+		existing.IsDefault = false
+		return s.repo.SavePaymentMethod(ctx, existing)
+	}
+
+	if existing == nil {
+		userID, err := s.repo.GetUserIDByCustomerID(ctx, method.Customer)
+		if err != nil {
+			return err
+		}
+		existing = &models.UserPaymentMethod{UserID: userID, ExternalReference: method.ID}
+	}
+
+	existing.Type = method.Type
+	existing.Active = true
+	if method.Card != nil {
+		existing.Brand = method.Card.Brand
+		existing.Last4 = method.Card.Last4
+		existing.ExpMonth = method.Card.ExpMonth
+		existing.ExpYear = method.Card.ExpYear
+	}
+
+	address := method.BillingDetails.Address
+	existing.BillingName = method.BillingDetails.Name
+	existing.BillingCountry = address.Country
+	existing.BillingPostalCode = address.PostalCode
+	existing.BillingAddress = strings.Join(nonEmpty(address.Line1, address.Line2, address.City, address.State), ", ")
+
+	return s.repo.SavePaymentMethod(ctx, existing)
+}
+
+// syncStorageLimit updates the user's stored storage limit and drive allocation after a plan change and drops the cached quota
+func (s *Service) syncStorageLimit(ctx context.Context, userID string) error {
+	s.quotaService.InvalidateLimit(ctx, userID)
+
+	// The plan is read in the transaction it was saved in; the quota service reads committed plans
+	planQuota, ok, err := s.repo.GetPlanStorageQuota(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan storage quota: %w", err)
+	}
+	limit := quota.PlanLimit(planQuota, ok)
+
+	if err := s.repo.UpdateUserStorageLimit(ctx, userID, limit); err != nil {
+		return fmt.Errorf("failed to update storage limit: %w", err)
+	}
+
+	return s.driveService.ReconcileStorageAllocation(ctx, userID, limit)
+}
+
+// subscriptionPlanID finds the plan a subscription is for: explicit metadata first, then the price's lookup key
+func subscriptionPlanID(subscription *stripeSubscription, price *stripePrice) string {
+	if planID := subscription.Metadata["plan_id"]; planID != "" {
+		return planID
+	}
+	if price == nil {
+		return ""
+	}
+	if planID := price.Metadata["plan_id"]; planID != "" {
+		return planID
+	}
+	return price.LookupKey
+}
+
+// planStatus maps a Stripe subscription status to a plan status and whether payment is overdue.
+// Past-due subscriptions keep their plan during Stripe's retry period.
+func planStatus(subscriptionStatus string) (string, bool) {
+	switch subscriptionStatus {
+	case "active", "trialing":
+		return PLAN_STATUS_ACTIVE, false
+	case "past_due":
+		return PLAN_STATUS_ACTIVE, true
+	case "unpaid":
+		return PLAN_STATUS_SUSPENDED, true
+	case "paused":
+		return PLAN_STATUS_PAUSED, false
+	case "incomplete":
+		return PLAN_STATUS_INCOMPLETE, false
+	default:
+		return PLAN_STATUS_CANCELED, false
+	}
+}
+
+// nonEmpty returns the values that are not empty
+func nonEmpty(values ...string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
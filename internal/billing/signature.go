@@ -0,0 +1,59 @@
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifyStripeSignature checks a Stripe-Signature header against the raw payload.
+// The header carries a timestamp and one or more v1 signatures, any of which may match
+// while the endpoint secret is being rolled.
+func verifyStripeSignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	var timestamp int64
+	var signatures [][]byte
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			timestamp = parsed
+		case "v1":
+			if signature, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	// Reject replays of old deliveries
+	if tolerance > 0 && now.Sub(time.Unix(timestamp, 0)).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		if hmac.Equal(expected, signature) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
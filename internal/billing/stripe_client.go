@@ -0,0 +1,127 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cirrussync-api/pkg/config"
+)
+
+// Stripe responses are small JSON documents
+const maxStripeResponseBytes = 1024 * 1024
+
+// stripeClient calls the parts of the Stripe REST API used for checkout and plan changes
+type stripeClient struct {
+	secretKey  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// newStripeClient creates a Stripe API client, or nil if no API key is configured
+func newStripeClient(cfg *config.BillingConfig) *stripeClient {
+	if cfg == nil || cfg.StripeSecretKey == "" {
+		return nil
+	}
+	return &stripeClient{
+		secretKey:  cfg.StripeSecretKey,
+		baseURL:    strings.TrimRight(cfg.StripeAPIBase, "/"),
+		httpClient: &http.Client{Timeout: cfg.StripeTimeout},
+	}
+}
+
+// createCustomer creates a Stripe customer for a user
+func (c *stripeClient) createCustomer(ctx context.Context, userID, email string) (*stripeCustomer, error) {
+	form := url.Values{}
+	form.Set("email", email)
+	form.Set("metadata[user_id]", userID)
+
+	var customer stripeCustomer
+	// Retried signups must not create duplicate customers
+	if err := c.do(ctx, http.MethodPost, "/v1/customers", form, "customer-"+userID, &customer); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// createCheckoutSession creates a hosted checkout that starts a subscription to a price
+func (c *stripeClient) createCheckoutSession(ctx context.Context, form url.Values) (*stripeCheckoutSession, error) {
+	var session stripeCheckoutSession
+	if err := c.do(ctx, http.MethodPost, "/v1/checkout/sessions", form, "", &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// getSubscription retrieves a subscription
+func (c *stripeClient) getSubscription(ctx context.Context, subscriptionID string) (*stripeSubscription, error) {
+	var subscription stripeSubscription
+	if err := c.do(ctx, http.MethodGet, "/v1/subscriptions/"+url.PathEscape(subscriptionID), nil, "", &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// updateSubscription updates a subscription and returns its new state
+func (c *stripeClient) updateSubscription(ctx context.Context, subscriptionID string, form url.Values) (*stripeSubscription, error) {
+	var subscription stripeSubscription
+	if err := c.do(ctx, http.MethodPost, "/v1/subscriptions/"+url.PathEscape(subscriptionID), form, "", &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// cancelSubscription ends a subscription immediately and returns its final state
+func (c *stripeClient) cancelSubscription(ctx context.Context, subscriptionID string) (*stripeSubscription, error) {
+	var subscription stripeSubscription
+	if err := c.do(ctx, http.MethodDelete, "/v1/subscriptions/"+url.PathEscape(subscriptionID), nil, "cancel-"+subscriptionID, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// do sends a form-encoded request to the Stripe API and decodes the JSON response into result
+func (c *stripeClient) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, result any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to build Stripe request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentProvider, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxStripeResponseBytes))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentProvider, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var stripeErr stripeError
+		_ = json.Unmarshal(data, &stripeErr)
+		return fmt.Errorf("%w: status %d: %s", ErrPaymentProvider, resp.StatusCode, stripeErr.Error.Message)
+	}
+
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrPaymentProvider, err)
+	}
+	return nil
+}
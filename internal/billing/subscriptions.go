@@ -0,0 +1,223 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/payments"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Billing cycles a plan can be purchased with
+const (
+	BILLING_CYCLE_MONTHLY = "monthly"
+	BILLING_CYCLE_YEARLY  = "yearly"
+)
+
+// ListPlans returns the plans that can be purchased
+func (s *Service) ListPlans(ctx context.Context) ([]*models.Plan, error) {
+	return s.repo.GetAvailablePlans(ctx)
+}
+
+// StartSubscription creates a Stripe checkout for a new subscription to a plan.
+// The plan is applied once Stripe reports the subscription through the webhook.
+func (s *Service) StartSubscription(ctx context.Context, userID, planID, billingCycle string) (*CheckoutSession, error) {
+	if s.stripe == nil {
+		return nil, ErrBillingDisabled
+	}
+
+	plan, priceID, err := s.purchasablePlan(ctx, planID, billingCycle)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetActiveSubscription(ctx, userID); err == nil {
+		return nil, ErrSubscriptionExists
+	} else if !errors.Is(err, ErrNoActiveSubscription) {
+		return nil, err
+	}
+
+	customerID, err := s.ensureCustomer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("mode", "subscription")
+	form.Set("customer", customerID)
+	form.Set("client_reference_id", userID)
+	form.Set("line_items[0][price]", priceID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", s.config.CheckoutSuccessURL)
+	form.Set("cancel_url", s.config.CheckoutCancelURL)
+	// The webhook maps the subscription back to the plan through its metadata
+	form.Set("subscription_data[metadata][plan_id]", plan.ID)
+	form.Set("subscription_data[metadata][user_id]", userID)
+
+	session, err := s.stripe.createCheckoutSession(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckoutSession{ID: session.ID, URL: session.URL}, nil
+}
+
+// ChangeSubscription moves the user's subscription to another plan or billing cycle.
+// Stripe prorates the difference on the next invoice, and the new plan and storage
+// allocation apply immediately.
+func (s *Service) ChangeSubscription(ctx context.Context, userID, planID, billingCycle string) (*models.UserPlan, error) {
+	if s.stripe == nil {
+		return nil, ErrBillingDisabled
+	}
+
+	plan, priceID, err := s.purchasablePlan(ctx, planID, billingCycle)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetActiveSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current.PlanID == plan.ID && current.BillingCycle == billingCycle {
+		return nil, ErrPlanUnchanged
+	}
+
+	// A downgrade must leave room for what is already stored
+	usage, err := s.quotaService.GetUsage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if usage.UsedBytes > plan.StorageQuota+current.AdditionalStorage {
+		return nil, ErrPlanTooSmall
+	}
+
+	subscription, err := s.stripe.getSubscription(ctx, current.ExternalReference)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscription.Items.Data) == 0 {
+		return nil, fmt.Errorf("%w: subscription %s has no items", ErrPaymentProvider, subscription.ID)
+	}
+
+	form := url.Values{}
+	form.Set("items[0][id]", subscription.Items.Data[0].ID)
+	form.Set("items[0][price]", priceID)
+	form.Set("proration_behavior", "create_prorations")
+	form.Set("metadata[plan_id]", plan.ID)
+
+	updated, err := s.stripe.updateSubscription(ctx, subscription.ID, form)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the change now rather than waiting for the webhook, which repeats it harmlessly
+	if err := s.syncSubscription(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":       userID,
+		"fromPlan":     current.PlanID,
+		"toPlan":       plan.ID,
+		"billingCycle": billingCycle,
+	}).Info("Subscription plan changed")
+
+	return s.repo.GetUserPlanByExternalReference(ctx, updated.ID)
+}
+
+// CancelSubscriptionForDeletion ends the subscription of an account being deleted right away rather
+// than at the end of its period, so it is not billed again. Accounts without a subscription need nothing.
+func (s *Service) CancelSubscriptionForDeletion(ctx context.Context, userID string) error {
+	current, err := s.repo.GetActiveSubscription(ctx, userID)
+	if errors.Is(err, ErrNoActiveSubscription) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.stripe == nil {
+		return ErrBillingDisabled
+	}
+
+	canceled, err := s.stripe.cancelSubscription(ctx, current.ExternalReference)
+	if err != nil {
+		return err
+	}
+
+	// Record the cancellation now rather than waiting for the webhook, which repeats it harmlessly
+	if err := s.syncSubscription(ctx, canceled); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":         userID,
+		"subscriptionId": canceled.ID,
+	}).Info("Subscription canceled for account deletion")
+
+	return nil
+}
+
+// purchasablePlan loads an available plan and the Stripe price for a billing cycle
+func (s *Service) purchasablePlan(ctx context.Context, planID, billingCycle string) (*models.Plan, string, error) {
+	plan, err := s.repo.GetPlanByID(ctx, planID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !plan.Available {
+		return nil, "", ErrPlanNotFound
+	}
+
+	var priceID string
+	switch billingCycle {
+	case BILLING_CYCLE_MONTHLY:
+		priceID = plan.StripeMonthlyPriceID
+	case BILLING_CYCLE_YEARLY:
+		priceID = plan.StripeYearlyPriceID
+	default:
+		return nil, "", ErrInvalidBillingCycle
+	}
+	if priceID == "" {
+		return nil, "", ErrPlanNotPurchasable
+	}
+
+	return plan, priceID, nil
+}
+
+// ensureCustomer returns the user's Stripe customer. New accounts get theirs from the payments
+// outbox shortly after signup; older accounts and ones whose creation failed get it on first purchase.
+func (s *Service) ensureCustomer(ctx context.Context, userID string) (string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if payments.HasCustomer(user.StripeCustomerID) {
+		return user.StripeCustomerID, nil
+	}
+
+	if s.payments != nil {
+		customerStatus, err := s.payments.GetCustomerStatus(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		if customerStatus == payments.CUSTOMER_STATUS_PENDING {
+			return "", ErrCustomerPending
+		}
+	}
+
+	customerID, err := s.CreateCustomer(ctx, userID, user.Email)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.SetStripeCustomerID(ctx, userID, customerID); err != nil {
+		return "", fmt.Errorf("failed to save Stripe customer: %w", err)
+	}
+
+	return customerID, nil
+}
@@ -0,0 +1,148 @@
+package billing
+
+import (
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/payments"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"encoding/json"
+)
+
+// Service keeps plans, billing records and payment methods in sync with the payment provider
+type Service struct {
+	repo         Repository
+	redisClient  *redis.Client
+	logger       *logger.Logger
+	config       *config.BillingConfig
+	quotaService *quota.Service
+	driveService *drive.Service
+	stripe       *stripeClient
+	payments     *payments.Service
+}
+
+// CheckoutSession is a hosted Stripe checkout the user is redirected to
+type CheckoutSession struct {
+	ID  string
+	URL string
+}
+
+// GiftCardBatch describes a batch of gift cards to generate. Amount is in the currency's
+// smallest unit and ExpiresAt is a unix timestamp, 0 for cards that never expire.
+type GiftCardBatch struct {
+	Count     int
+	Amount    int
+	Currency  string
+	ExpiresAt int64
+}
+
+// stripeCheckoutSession is the part of a created Stripe checkout session we return
+type stripeCheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// stripeCustomer is the part of a created Stripe customer we store
+type stripeCustomer struct {
+	ID string `json:"id"`
+}
+
+// stripeError is the error body returned by the Stripe API
+type stripeError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// stripeEvent is the envelope of a Stripe webhook event
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// stripePrice is the part of a Stripe price the plan mapping needs
+type stripePrice struct {
+	ID        string            `json:"id"`
+	LookupKey string            `json:"lookup_key"`
+	Metadata  map[string]string `json:"metadata"`
+	Recurring *struct {
+		Interval string `json:"interval"`
+	} `json:"recurring"`
+}
+
+// stripeSubscription is the part of a Stripe subscription we track
+type stripeSubscription struct {
+	ID                  string            `json:"id"`
+	Customer            string            `json:"customer"`
+	Status              string            `json:"status"`
+	CancelAtPeriodEnd   bool              `json:"cancel_at_period_end"`
+	CurrentPeriodStart  int64             `json:"current_period_start"`
+	CurrentPeriodEnd    int64             `json:"current_period_end"`
+	TrialStart          *int64            `json:"trial_start"`
+	TrialEnd            *int64            `json:"trial_end"`
+	CanceledAt          *int64            `json:"canceled_at"`
+	Metadata            map[string]string `json:"metadata"`
+	CancellationDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"cancellation_details"`
+	Items struct {
+		Data []struct {
+			ID                 string      `json:"id"`
+			Price              stripePrice `json:"price"`
+			CurrentPeriodStart int64       `json:"current_period_start"`
+			CurrentPeriodEnd   int64       `json:"current_period_end"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// stripeInvoice is the part of a Stripe invoice recorded as a billing record
+type stripeInvoice struct {
+	ID               string  `json:"id"`
+	Customer         string  `json:"customer"`
+	Subscription     string  `json:"subscription"`
+	Status           string  `json:"status"`
+	AmountPaid       int64   `json:"amount_paid"`
+	AmountDue        int64   `json:"amount_due"`
+	Currency         string  `json:"currency"`
+	Description      *string `json:"description"`
+	PeriodStart      int64   `json:"period_start"`
+	PeriodEnd        int64   `json:"period_end"`
+	HostedInvoiceURL string  `json:"hosted_invoice_url"`
+	InvoicePDF       string  `json:"invoice_pdf"`
+	Parent           *struct {
+		SubscriptionDetails *struct {
+			Subscription string `json:"subscription"`
+		} `json:"subscription_details"`
+	} `json:"parent"`
+}
+
+// stripePaymentMethod is the part of a Stripe payment method stored for display
+type stripePaymentMethod struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Customer string `json:"customer"`
+	Card     *struct {
+		Brand    string `json:"brand"`
+		Last4    string `json:"last4"`
+		ExpMonth int    `json:"exp_month"`
+		ExpYear  int    `json:"exp_year"`
+	} `json:"card"`
+	BillingDetails struct {
+		Name    string `json:"name"`
+		Address struct {
+			Line1      string `json:"line1"`
+			Line2      string `json:"line2"`
+			City       string `json:"city"`
+			State      string `json:"state"`
+			PostalCode string `json:"postal_code"`
+			Country    string `json:"country"`
+		} `json:"address"`
+	} `json:"billing_details"`
+}
@@ -0,0 +1,10 @@
+package cdn
+
+import "errors"
+
+// Common errors
+var (
+	ErrPurgeNotConfigured  = errors.New("CDN purging is not configured")
+	ErrInvalidSurrogateKey = errors.New("Surrogate keys may only contain letters, digits, ':', '-', '_' and '.'")
+	ErrPurgeFailed         = errors.New("Failed to purge CDN cache")
+)
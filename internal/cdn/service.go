@@ -0,0 +1,96 @@
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/config"
+)
+
+// Surrogate key prefixes for cached resources. Public link resolution and the plan catalog are
+// the anonymous resources served with shared cache headers; avatars and release manifests are not
+// served by this API, so they have no keys.
+const (
+	SURROGATE_KEY_SHARE_URL = "share-url:"
+	SURROGATE_KEY_SHARE     = "share:"
+)
+
+// SURROGATE_KEY_PLANS tags the plan catalog, so it can be purged after plans or prices change
+const SURROGATE_KEY_PLANS = "plans"
+
+// Surrogate keys end up in a header and a URL path, so keep them to a safe character set
+var surrogateKeyRegex = regexp.MustCompile(`^[A-Za-z0-9:._-]{1,128}$`)
+
+// Service purges cached responses from the CDN by surrogate key
+type Service struct {
+	config     *config.CDNConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewService creates a new CDN service
+func NewService(cfg *config.CDNConfig, logger *logger.Logger) *Service {
+	return &Service{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		logger:     logger,
+	}
+}
+
+// IsEnabled reports whether purge requests can be sent
+func (s *Service) IsEnabled() bool {
+	return s.config.PurgeURL != ""
+}
+
+// PurgeKeys purges every cached response tagged with one of the keys.
+// All keys are validated before any purge request is sent.
+func (s *Service) PurgeKeys(ctx context.Context, keys []string) error {
+	if !s.IsEnabled() {
+		return ErrPurgeNotConfigured
+	}
+
+	for _, key := range keys {
+		if !surrogateKeyRegex.MatchString(key) {
+			return ErrInvalidSurrogateKey
+		}
+	}
+
+	for _, key := range keys {
+		if err := s.purgeKey(ctx, key); err != nil {
+			s.logger.Errorf("Failed to purge surrogate key %s: %v", key, err)
+			return ErrPurgeFailed
+		}
+	}
+
+	return nil
+}
+
+// purgeKey sends a single purge request
+func (s *Service) purgeKey(ctx context.Context, key string) error {
+	endpoint := strings.TrimRight(s.config.PurgeURL, "/") + "/" + url.PathEscape(key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if s.config.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.APIToken)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("purge request returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
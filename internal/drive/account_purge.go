@@ -0,0 +1,53 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+)
+
+// PurgeUserDrive permanently deletes everything a user stores: the items of every share they own
+// with their stored blocks, then the shares, their volume and their memberships in other users'
+// shares. It returns the number of items deleted. An interrupted purge continues where it stopped
+// when run again.
+func (s *Service) PurgeUserDrive(ctx context.Context, userID string) (int64, error) {
+	shareIDs, err := s.repo.GetOwnedShareIDs(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load shares: %w", err)
+	}
+
+	var deleted int64
+	for _, shareID := range shareIDs {
+		for {
+			if ctx.Err() != nil {
+				return deleted, ctx.Err()
+			}
+
+			itemIDs, err := s.repo.GetLeafItemIDsByShareID(ctx, shareID, GC_BATCH_SIZE)
+			if err != nil {
+				return deleted, fmt.Errorf("failed to load items of share %s: %w", shareID, err)
+			}
+			if len(itemIDs) == 0 {
+				break
+			}
+
+			purged, err := s.repo.PurgeItems(ctx, itemIDs)
+			if err != nil {
+				return deleted, fmt.Errorf("failed to purge items of share %s: %w", shareID, err)
+			}
+			// Blocks that fail to delete are left to garbage collection
+			s.deleteStoredObjects(ctx, purged.StoragePaths)
+			deleted += purged.ItemCount
+		}
+	}
+
+	if err := s.repo.DeleteUserDriveRecords(ctx, userID); err != nil {
+		return deleted, fmt.Errorf("failed to delete drive records: %w", err)
+	}
+
+	for _, shareID := range shareIDs {
+		s.invalidateShareCaches(ctx, shareID)
+	}
+	s.invalidateUserCaches(ctx, userID)
+
+	return deleted, nil
+}
@@ -0,0 +1,138 @@
+// internal/drive/approval.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SetShareApprovalRequired turns the approval step for new members on or off. Only the share owner may change it.
+// Turning it off does not activate memberships that are already waiting; admins still decide on those.
+func (s *Service) SetShareApprovalRequired(ctx context.Context, userID, shareID string, required bool) (*models.DriveShare, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if share.UserID != userID {
+		return nil, ErrInsufficientPermissions
+	}
+
+	if err := s.repo.SetShareRequiresApproval(ctx, shareID, required); err != nil {
+		return nil, fmt.Errorf("failed to update share approval setting: %w", err)
+	}
+
+	s.invalidateShareCaches(ctx, shareID)
+
+	share.RequiresApproval = required
+	return share, nil
+}
+
+// GetPendingApprovals retrieves the accepted memberships of a share that wait for an admin's approval
+func (s *Service) GetPendingApprovals(ctx context.Context, adminID, shareID string) ([]*models.DriveShareMembership, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, adminID, shareID, ADMIN_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMembershipsByShareIDAndState(ctx, shareID, MEMBERSHIP_STATE_AWAITING_APPROVAL)
+}
+
+// ApproveMembership activates a membership waiting for approval, granting the member access to the share
+func (s *Service) ApproveMembership(ctx context.Context, adminID, shareID, membershipID string) (*models.DriveShareMembership, error) {
+	return s.decideApproval(ctx, adminID, shareID, membershipID, MEMBERSHIP_STATE_ACTIVE)
+}
+
+// RejectMembership turns down a membership waiting for approval. The user can be invited again later.
+func (s *Service) RejectMembership(ctx context.Context, adminID, shareID, membershipID string) (*models.DriveShareMembership, error) {
+	return s.decideApproval(ctx, adminID, shareID, membershipID, MEMBERSHIP_STATE_REJECTED)
+}
+
+// decideApproval moves a membership waiting for approval to its final state
+func (s *Service) decideApproval(ctx context.Context, adminID, shareID, membershipID string, state int) (*models.DriveShareMembership, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, adminID, shareID, ADMIN_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	membership, err := s.repo.GetMembershipByID(ctx, membershipID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Memberships of other shares, or not waiting for approval, are reported as missing
+	if membership.ShareID != shareID || membership.State != MEMBERSHIP_STATE_AWAITING_APPROVAL {
+		return nil, ErrMembershipNotFound
+	}
+
+	if err := s.repo.UpdateMembershipState(ctx, membership.ID, state); err != nil {
+		s.logger.Errorf("Failed to update membership %s: %v", membership.ID, err)
+		return nil, err
+	}
+	membership.State = state
+
+	s.invalidateMembershipCaches(ctx, shareID, membership.UserID)
+
+	action := "approved"
+	if state == MEMBERSHIP_STATE_REJECTED {
+		action = "rejected"
+	}
+	s.recordShareMembershipChange(ctx, adminID, action, membership)
+
+	return membership, nil
+}
+
+// notifyShareAdmins emails every admin of the share that a member is waiting for approval
+func (s *Service) notifyShareAdmins(ctx context.Context, membership *models.DriveShareMembership) {
+	if s.mailer == nil {
+		s.logger.Debugf("No invitation mailer configured, skipping approval request for membership %s", membership.ID)
+		return
+	}
+
+	share, err := s.GetShareByID(ctx, membership.ShareID)
+	if err != nil {
+		s.logger.Errorf("Failed to load share %s for approval request: %v", membership.ShareID, err)
+		return
+	}
+
+	admins, err := s.repo.GetShareAdmins(ctx, share)
+	if err != nil {
+		s.logger.Errorf("Failed to load admins of share %s: %v", share.ID, err)
+		return
+	}
+
+	memberName := ""
+	member, err := s.repo.GetUserByID(ctx, membership.UserID)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		s.logger.Errorf("Failed to load member %s for approval request: %v", membership.UserID, err)
+	}
+	if member != nil {
+		memberName = member.DisplayName
+		if memberName == "" {
+			memberName = member.Username
+		}
+	}
+
+	// Send asynchronously so a slow mail server does not hold up the request
+	for _, admin := range admins {
+		go func(email string) {
+			if err := s.mailer.SendMembershipApprovalEmail(email, memberName, share.ID); err != nil {
+				s.logger.Errorf("Failed to send approval request for membership %s: %v", membership.ID, err)
+			}
+		}(admin.Email)
+	}
+}
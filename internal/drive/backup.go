@@ -0,0 +1,401 @@
+// internal/drive/backup.go
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Backup set states
+const (
+	BACKUP_SET_STATE_ACTIVE   = 1
+	BACKUP_SET_STATE_DELETING = 2 // Hidden while a background job deletes its contents
+)
+
+// Backup limits and default rules
+const (
+	MAX_BACKUP_SETS_PER_DEVICE    = 20
+	BACKUP_DEFAULT_MAX_VERSIONS   = 10
+	BACKUP_MAX_VERSIONS_LIMIT     = 100
+	BACKUP_DEFAULT_RETENTION_DAYS = 30
+	BACKUP_MAX_RETENTION_DAYS     = 3650
+	BACKUP_RETENTION_PAGE_SIZE    = 100 // Backup sets loaded per page by the retention job
+	BACKUP_PRUNE_BATCH_SIZE       = 500 // Previous revisions purged per transaction
+)
+
+// Background jobs of the backup subsystem
+const (
+	JOB_TYPE_BACKUP_SET_DELETE = "drive.backup_set_delete"
+	JOB_TYPE_BACKUP_RETENTION  = "drive.backup_retention"
+)
+
+// BackupSetInput describes a backup set registered by the desktop client. Rules left nil use the defaults.
+type BackupSetInput struct {
+	DeviceID      string
+	EncryptedName string
+	MaxVersions   *int
+	RetentionDays *int
+	Share         ShareKeys
+	Member        DriveShareMemberKeys
+}
+
+// CreateBackupSet registers a folder the desktop client backs up from one of the user's devices.
+// The set gets a backup share of its own; the client creates its root folder in it next.
+func (s *Service) CreateBackupSet(ctx context.Context, userID string, input *BackupSetInput) (*models.DriveBackupSet, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	maxVersions := BACKUP_DEFAULT_MAX_VERSIONS
+	if input.MaxVersions != nil {
+		maxVersions = *input.MaxVersions
+	}
+	retentionDays := BACKUP_DEFAULT_RETENTION_DAYS
+	if input.RetentionDays != nil {
+		retentionDays = *input.RetentionDays
+	}
+	if err := validateBackupRules(maxVersions, retentionDays); err != nil {
+		return nil, err
+	}
+
+	device, err := s.repo.GetUserDevice(ctx, userID, input.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountBackupSetsByDeviceID(ctx, device.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MAX_BACKUP_SETS_PER_DEVICE {
+		return nil, ErrTooManyBackupSets
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Backups are stored on the user's own volume, so they count towards the same quota
+	volume, err := s.repo.GetVolumeByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	share := &models.DriveShare{
+		ID:                       utils.GenerateLinkID(),
+		VolumeID:                 volume.ID,
+		UserID:                   userID,
+		Type:                     SHARE_TYPE_BACKUP,
+		State:                    1, // Active
+		Creator:                  user.Email,
+		LinkID:                   utils.GenerateLinkID(),
+		ShareKey:                 input.Share.ShareKey,
+		SharePassphrase:          input.Share.SharePassphrase,
+		SharePassphraseSignature: input.Share.SharePassphraseSignature,
+	}
+
+	membership := &models.DriveShareMembership{
+		ShareID:             share.ID,
+		UserID:              userID,
+		MemberID:            userID,
+		Inviter:             user.Email,
+		State:               MEMBERSHIP_STATE_ACTIVE,
+		Permissions:         MEMBERSHIP_DEFAULT,
+		KeyPacket:           input.Member.KeyPacket,
+		KeyPacketSignature:  input.Member.KeyPacketSignature,
+		SessionKeySignature: input.Member.SessionKeySignature,
+	}
+
+	set := &models.DriveBackupSet{
+		UserID:        userID,
+		DeviceID:      device.ID,
+		ShareID:       share.ID,
+		EncryptedName: input.EncryptedName,
+		State:         BACKUP_SET_STATE_ACTIVE,
+		MaxVersions:   maxVersions,
+		RetentionDays: retentionDays,
+	}
+
+	if err := s.repo.CreateBackupSet(ctx, share, membership, set); err != nil {
+		s.logger.Errorf("Failed to create backup set for user %s: %v", userID, err)
+		return nil, ErrShareCreation
+	}
+	set.Share = *share
+
+	s.invalidateUserCaches(ctx, userID)
+
+	return set, nil
+}
+
+// ListBackupSets returns the user's backup sets, optionally limited to one device
+func (s *Service) ListBackupSets(ctx context.Context, userID, deviceID string) ([]*models.DriveBackupSet, error) {
+	return s.repo.GetBackupSetsByUserID(ctx, userID, deviceID)
+}
+
+// GetBackupSet returns one of the user's backup sets
+func (s *Service) GetBackupSet(ctx context.Context, userID, setID string) (*models.DriveBackupSet, error) {
+	return s.getOwnedBackupSet(ctx, userID, setID)
+}
+
+// UpdateBackupSetRules replaces how many versions a backup set keeps and for how long. Versions
+// falling outside the new rules are removed by the next retention pass.
+func (s *Service) UpdateBackupSetRules(ctx context.Context, userID, setID string, maxVersions, retentionDays int) (*models.DriveBackupSet, error) {
+	if err := validateBackupRules(maxVersions, retentionDays); err != nil {
+		return nil, err
+	}
+
+	set, err := s.getOwnedBackupSet(ctx, userID, setID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateBackupSetRules(ctx, set.ID, maxVersions, retentionDays); err != nil {
+		return nil, err
+	}
+
+	set.MaxVersions = maxVersions
+	set.RetentionDays = retentionDays
+	set.ModifiedAt = time.Now().Unix()
+
+	return set, nil
+}
+
+// DeleteBackupSet hides a backup set immediately and queues the permanent deletion of its share
+// and everything backed up to it
+func (s *Service) DeleteBackupSet(ctx context.Context, userID, setID string) (*models.Job, error) {
+	if s.jobService == nil {
+		return nil, ErrBackupSetDeleteFailed
+	}
+
+	set, err := s.getOwnedBackupSet(ctx, userID, setID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MarkBackupSetDeleting(ctx, set.ID); err != nil {
+		return nil, fmt.Errorf("failed to mark backup set for deletion: %w", err)
+	}
+
+	s.invalidateShareCaches(ctx, set.ShareID)
+	s.invalidateUserCaches(ctx, userID)
+
+	// The folder delete payload lets the job reuse the subtree purge from the share's root folder
+	job, err := s.jobService.Enqueue(ctx, userID, JOB_TYPE_BACKUP_SET_DELETE, map[string]string{
+		"backupSetId": set.ID,
+		"shareId":     set.ShareID,
+		"folderId":    set.Share.LinkID,
+		"ownerId":     set.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// CheckBackupWriteAccess rejects changes to a backup share unless they come from a session on the
+// device that owns the backup set. Backups are browsable everywhere else, but read-only.
+func (s *Service) CheckBackupWriteAccess(ctx context.Context, userID, sessionID, shareID string) error {
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if share.Type != SHARE_TYPE_BACKUP {
+		return nil
+	}
+
+	set, err := s.repo.GetBackupSetByShareID(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if set.State != BACKUP_SET_STATE_ACTIVE {
+		return ErrBackupSetNotFound
+	}
+	if set.UserID != userID || sessionID == "" {
+		return ErrBackupReadOnly
+	}
+
+	device, err := s.repo.GetUserDevice(ctx, set.UserID, set.DeviceID)
+	if err != nil {
+		if errors.Is(err, ErrDeviceNotFound) {
+			return ErrBackupReadOnly
+		}
+		return err
+	}
+
+	deviceUID, err := s.repo.GetSessionDeviceUID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, ErrDeviceNotFound) {
+			return ErrBackupReadOnly
+		}
+		return err
+	}
+	if deviceUID != device.DeviceID {
+		return ErrBackupReadOnly
+	}
+
+	return nil
+}
+
+// recordBackupActivity notes a committed file on the backup set stored in the share, if any
+func (s *Service) recordBackupActivity(ctx context.Context, share *models.DriveShare) {
+	if share.Type != SHARE_TYPE_BACKUP {
+		return
+	}
+
+	if err := s.repo.TouchBackupSet(ctx, share.ID, time.Now().Unix()); err != nil {
+		s.logger.Errorf("Failed to record backup activity for share %s: %v", share.ID, err)
+	}
+}
+
+// getOwnedBackupSet loads a backup set of the user that is not being deleted
+func (s *Service) getOwnedBackupSet(ctx context.Context, userID, setID string) (*models.DriveBackupSet, error) {
+	set, err := s.repo.GetBackupSetByID(ctx, setID)
+	if err != nil {
+		return nil, err
+	}
+	if set.UserID != userID || set.State != BACKUP_SET_STATE_ACTIVE {
+		return nil, ErrBackupSetNotFound
+	}
+
+	share, err := s.GetShareByID(ctx, set.ShareID)
+	if err != nil {
+		return nil, err
+	}
+	set.Share = *share
+
+	return set, nil
+}
+
+// validateBackupRules checks the versioning and retention rules of a backup set
+func validateBackupRules(maxVersions, retentionDays int) error {
+	if maxVersions < 1 || maxVersions > BACKUP_MAX_VERSIONS_LIMIT {
+		return ErrInvalidBackupRules
+	}
+	if retentionDays < 0 || retentionDays > BACKUP_MAX_RETENTION_DAYS {
+		return ErrInvalidBackupRules
+	}
+	return nil
+}
+
+// runBackupSetDeleteJob purges everything backed up to a set, then deletes the set and its share.
+// The purge continues where an interrupted run stopped, so running it again is safe.
+func (s *Service) runBackupSetDeleteJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if err := s.runFolderDeleteJob(ctx, job, progress); err != nil {
+		return err
+	}
+
+	shareID := job.Payload["shareId"]
+	if err := s.repo.DeleteBackupShare(ctx, job.Payload["backupSetId"], shareID); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.logger.Errorf("Failed to delete backup share %s: %v", shareID, err)
+		return ErrBackupSetDeleteFailed
+	}
+
+	s.invalidateShareCaches(ctx, shareID)
+	s.invalidateUserCaches(ctx, job.Payload["ownerId"])
+
+	return nil
+}
+
+// StartBackupRetentionScheduler queues a retention job every interval until ctx is cancelled. The
+// job removes previous file versions that fall outside the rules of their backup set.
+func (s *Service) StartBackupRetentionScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.Schedule(ctx, "backup_retention_pass", s.backups.retentionInterval, JOB_TYPE_BACKUP_RETENTION, nil)
+}
+
+// runBackupRetentionJob applies the versioning and retention rules of every backup set. Versions
+// already removed by an interrupted run are not found again, so running it again is safe.
+func (s *Service) runBackupRetentionJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	result := map[string]int64{
+		"prunedRevisions": job.Result["prunedRevisions"],
+		"releasedBytes":   job.Result["releasedBytes"],
+	}
+	var checkedSets int64
+
+	afterID := ""
+	for {
+		sets, err := s.repo.GetActiveBackupSets(ctx, afterID, BACKUP_RETENTION_PAGE_SIZE)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to load backup sets: %w", err)
+		}
+		if len(sets) == 0 {
+			break
+		}
+
+		for _, set := range sets {
+			if err := s.pruneBackupSet(ctx, set, result); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.logger.Errorf("Failed to apply retention to backup set %s: %v", set.ID, err)
+			}
+
+			checkedSets++
+			progress(checkedSets, 0, result)
+		}
+
+		afterID = sets[len(sets)-1].ID
+	}
+
+	if result["prunedRevisions"] > 0 {
+		s.logger.Infof("Removed %d previous versions from backups", result["prunedRevisions"])
+	}
+
+	return nil
+}
+
+// pruneBackupSet removes the previous versions of a backup set's files that fall outside its rules,
+// releasing their storage to the set's owner
+func (s *Service) pruneBackupSet(ctx context.Context, set *models.DriveBackupSet, result map[string]int64) error {
+	// Without a retention period only the version count limits previous versions
+	var createdBefore int64
+	if set.RetentionDays > 0 {
+		createdBefore = time.Now().AddDate(0, 0, -set.RetentionDays).Unix()
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		revisionIDs, err := s.repo.GetPrunableRevisionIDs(ctx, set.ShareID, set.MaxVersions, createdBefore, BACKUP_PRUNE_BATCH_SIZE)
+		if err != nil {
+			return err
+		}
+		if len(revisionIDs) == 0 {
+			return nil
+		}
+
+		purged, err := s.repo.PurgeRevisions(ctx, revisionIDs)
+		if err != nil {
+			return err
+		}
+
+		_ = s.adjustStorageUsed(context.Background(), set.UserID, -purged.FileBytes)
+		go s.deleteStoredObjects(context.WithoutCancel(ctx), purged.StoragePaths)
+
+		result["prunedRevisions"] += int64(len(revisionIDs))
+		result["releasedBytes"] += purged.FileBytes
+
+		// Revisions committed over since they were listed are skipped, and are not found again
+		if len(revisionIDs) < BACKUP_PRUNE_BATCH_SIZE {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,421 @@
+// internal/drive/batch_move.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	utils "cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/s3"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// BatchItemMove holds one item of a batch move or copy. The name and node passphrase are
+// re-encrypted client-side with the destination folder's key, as for a single move.
+type BatchItemMove struct {
+	LinkID                  string
+	Name                    string
+	Hash                    string
+	NameSignatureEmail      string
+	NodePassphrase          string
+	NodePassphraseSignature string
+	SignatureEmail          string
+}
+
+// batchCandidate is an item of a batch that passed validation so far
+type batchCandidate struct {
+	index int
+	item  *models.DriveItem
+	entry *BatchItemMove
+}
+
+// BatchMoveItems re-links several items of a share under one folder of the same share.
+// Every item gets its own result; the valid ones are moved together in one transaction.
+func (s *Service) BatchMoveItems(ctx context.Context, userID, shareID, parentID string, moves []*BatchItemMove) ([]*ItemResult, error) {
+	share, items, err := s.prepareBatch(ctx, userID, shareID, batchLinkIDs(moves))
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := s.getBatchDestination(ctx, shareID, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A folder cannot be moved into itself or any of its descendants
+	ancestors, err := s.repo.GetAncestorIDs(ctx, destination.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve destination path: %w", err)
+	}
+
+	results := make([]*ItemResult, len(moves))
+	candidates := make([]*batchCandidate, 0, len(moves))
+	// Names claimed by earlier items in this batch
+	claimed := make(map[string]bool)
+
+	for i, move := range moves {
+		item, ok := items[move.LinkID]
+		switch {
+		case !ok || !isLiveItemOfShare(item, shareID):
+			results[i] = &ItemResult{LinkID: move.LinkID, Err: ErrItemNotFound}
+		case item.ID == share.LinkID || item.ParentID == nil:
+			results[i] = &ItemResult{LinkID: move.LinkID, Err: ErrCannotMoveRoot}
+		case item.Type == 1 && (destination.ID == item.ID || slices.Contains(ancestors, item.ID)):
+			results[i] = &ItemResult{LinkID: move.LinkID, Err: ErrInvalidMoveTarget}
+		case claimed[move.Hash]:
+			results[i] = &ItemResult{LinkID: move.LinkID, Err: ErrFileNameConflict}
+		default:
+			claimed[move.Hash] = true
+			candidates = append(candidates, &batchCandidate{index: i, item: item, entry: move})
+		}
+	}
+
+	// Moving within the same folder under the same name is not a conflict with itself
+	conflicts := s.checkBatchNameConflicts(ctx, destination.ID, candidates, func(candidate *batchCandidate) bool {
+		return *candidate.item.ParentID != destination.ID || candidate.entry.Hash != candidate.item.Hash
+	})
+
+	moved := make([]*models.DriveItem, 0, len(candidates))
+	movedIDs := make([]string, 0, len(candidates))
+	folders := map[string]bool{destination.ID: true}
+
+	for i, candidate := range candidates {
+		if conflicts[i] != nil {
+			results[candidate.index] = &ItemResult{LinkID: candidate.entry.LinkID, Err: conflicts[i]}
+			continue
+		}
+
+		item := candidate.item
+		folders[*item.ParentID] = true
+		if item.Type == 1 {
+			folders[item.ID] = true
+		}
+
+		item.ParentID = &destination.ID
+		item.Name = candidate.entry.Name
+		item.Hash = candidate.entry.Hash
+		item.NameSignatureEmail = candidate.entry.NameSignatureEmail
+		item.NodePassphrase = candidate.entry.NodePassphrase
+		item.NodePassphraseSignature = candidate.entry.NodePassphraseSignature
+		item.SignatureEmail = candidate.entry.SignatureEmail
+
+		results[candidate.index] = &ItemResult{LinkID: item.ID}
+		moved = append(moved, item)
+		movedIDs = append(movedIDs, item.ID)
+	}
+
+	if err := s.repo.UpdateItemLocations(ctx, moved); err != nil {
+		return nil, fmt.Errorf("failed to move items: %w", err)
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_MOVE, moved...)
+
+	s.invalidateBatchCaches(ctx, movedIDs, folders)
+	s.invalidatePathCaches(ctx, shareID)
+
+	return results, nil
+}
+
+// BatchCopyItems copies several files of a share into one folder of the same share.
+// Each copy gets the active revision of its source, with the stored blocks copied server-side,
+// and is charged to the share owner. Every item gets its own result; the copies that could be
+// stored are created together in one transaction.
+func (s *Service) BatchCopyItems(ctx context.Context, userID, shareID, parentID string, copies []*BatchItemMove) ([]*ItemResult, error) {
+	share, items, err := s.prepareBatch(ctx, userID, shareID, batchLinkIDs(copies))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.storage == nil || s.storageDegraded() {
+		return nil, ErrStorageUnavailable
+	}
+
+	destination, err := s.getBatchDestination(ctx, shareID, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ItemResult, len(copies))
+	candidates := make([]*batchCandidate, 0, len(copies))
+	// Names claimed by earlier items in this batch
+	claimed := make(map[string]bool)
+
+	for i, entry := range copies {
+		item, ok := items[entry.LinkID]
+		switch {
+		case !ok || !isLiveItemOfShare(item, shareID):
+			results[i] = &ItemResult{LinkID: entry.LinkID, Err: ErrItemNotFound}
+		case item.Type != 2:
+			results[i] = &ItemResult{LinkID: entry.LinkID, Err: ErrCannotCopyFolder}
+		case claimed[entry.Hash]:
+			results[i] = &ItemResult{LinkID: entry.LinkID, Err: ErrFileNameConflict}
+		default:
+			claimed[entry.Hash] = true
+			candidates = append(candidates, &batchCandidate{index: i, item: item, entry: entry})
+		}
+	}
+
+	// A copy always adds a name to the destination, even next to its source
+	conflicts := s.checkBatchNameConflicts(ctx, destination.ID, candidates, func(*batchCandidate) bool {
+		return true
+	})
+
+	fileCopies := make([]*FileCopy, len(candidates))
+	sourceBlocks := make([][]*models.FileBlock, len(candidates))
+	var totalSize int64
+	for i, candidate := range candidates {
+		if conflicts[i] != nil {
+			results[candidate.index] = &ItemResult{LinkID: candidate.entry.LinkID, Err: conflicts[i]}
+			continue
+		}
+
+		fileCopy, sources, err := s.prepareFileCopy(ctx, share, destination, candidate)
+		if err != nil {
+			results[candidate.index] = &ItemResult{LinkID: candidate.entry.LinkID, Err: err}
+			continue
+		}
+		fileCopies[i] = fileCopy
+		sourceBlocks[i] = sources
+		totalSize += fileCopy.Revision.Size
+	}
+
+	// The copies are charged up front, like a commit, so concurrent requests cannot exceed the plan limit
+	if totalSize > 0 {
+		if err := s.quota.Consume(ctx, share.UserID, totalSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy the stored blocks before any row references them
+	copyErrors := s.copyStoredBlocks(ctx, fileCopies, sourceBlocks)
+
+	created := make([]*FileCopy, 0, len(candidates))
+	copiedPaths := make([]string, 0)
+	var failedSize int64
+	for i, candidate := range candidates {
+		fileCopy := fileCopies[i]
+		if fileCopy == nil {
+			continue
+		}
+		if copyErrors[i] != nil {
+			failedSize += fileCopy.Revision.Size
+			results[candidate.index] = &ItemResult{LinkID: candidate.entry.LinkID, Err: copyErrors[i]}
+			continue
+		}
+
+		results[candidate.index] = &ItemResult{LinkID: candidate.entry.LinkID, CreatedLinkID: fileCopy.Item.ID}
+		created = append(created, fileCopy)
+		for _, block := range fileCopy.Blocks {
+			copiedPaths = append(copiedPaths, block.StoragePath)
+		}
+	}
+
+	if err := s.repo.CreateFileCopies(ctx, created); err != nil {
+		// Give back the charge and drop the orphaned objects (can be done asynchronously)
+		s.updateStorageUsed(context.Background(), share.UserID, -totalSize)
+		go s.deleteStoredObjects(context.WithoutCancel(ctx), copiedPaths)
+		return nil, fmt.Errorf("failed to copy items: %w", err)
+	}
+
+	if failedSize > 0 {
+		s.updateStorageUsed(context.Background(), share.UserID, -failedSize)
+	}
+
+	createdItems := make([]*models.DriveItem, len(created))
+	createdIDs := make([]string, len(created))
+	for i, fileCopy := range created {
+		createdItems[i] = fileCopy.Item
+		createdIDs[i] = fileCopy.Item.ID
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_CREATE, createdItems...)
+	if len(created) > 0 {
+		s.recordBackupActivity(ctx, share)
+	}
+
+	s.invalidateBatchCaches(ctx, createdIDs, map[string]bool{destination.ID: true})
+
+	return results, nil
+}
+
+// getBatchDestination loads the live folder of a share that a batch moves or copies items into
+func (s *Service) getBatchDestination(ctx context.Context, shareID, parentID string) (*models.DriveItem, error) {
+	destination, err := s.repo.GetFolderByID(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if destination.ShareID != shareID {
+		return nil, ErrFolderNotFound
+	}
+	if destination.Type != 1 {
+		return nil, ErrNotAFolder
+	}
+	if destination.IsTrashed {
+		return nil, ErrParentInTrash
+	}
+	return destination, nil
+}
+
+// checkBatchNameConflicts checks in parallel whether the new names of batch candidates are taken in the
+// destination folder. The result holds ErrFileNameConflict, a lookup error or nil for each candidate;
+// candidates for which needsCheck is false are not looked up.
+func (s *Service) checkBatchNameConflicts(ctx context.Context, folderID string, candidates []*batchCandidate, needsCheck func(*batchCandidate) bool) []error {
+	conflicts := make([]error, len(candidates))
+
+	g := new(errgroup.Group)
+	g.SetLimit(s.maxConcurrency())
+
+	for i, candidate := range candidates {
+		if !needsCheck(candidate) {
+			continue
+		}
+		g.Go(func() error {
+			exists, err := s.checkNameExists(ctx, folderID, candidate.entry.Hash)
+			switch {
+			case err != nil:
+				conflicts[i] = err
+			case exists:
+				conflicts[i] = ErrFileNameConflict
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return conflicts
+}
+
+// prepareFileCopy builds the item, active revision and blocks of a copy of a file into a folder, and returns
+// them with the source blocks in the same order
+func (s *Service) prepareFileCopy(ctx context.Context, share *models.DriveShare, destination *models.DriveItem, candidate *batchCandidate) (*FileCopy, []*models.FileBlock, error) {
+	source := candidate.item
+
+	item := &models.DriveItem{
+		ID:                      utils.GenerateLinkID(),
+		ParentID:                &destination.ID,
+		ShareID:                 source.ShareID,
+		VolumeID:                source.VolumeID,
+		Type:                    2, // File
+		Name:                    candidate.entry.Name,
+		Hash:                    candidate.entry.Hash,
+		NameSignatureEmail:      candidate.entry.NameSignatureEmail,
+		State:                   ITEM_STATE_ACTIVE,
+		MimeType:                source.MimeType,
+		NodeKey:                 source.NodeKey,
+		NodePassphrase:          candidate.entry.NodePassphrase,
+		NodePassphraseSignature: candidate.entry.NodePassphraseSignature,
+		SignatureEmail:          candidate.entry.SignatureEmail,
+		FileProperties:          source.FileProperties,
+		Permissions:             RW_PERMISSIONS,
+	}
+
+	return s.prepareRevisionCopy(ctx, share, source, item)
+}
+
+// prepareRevisionCopy builds the active revision and blocks of a file copy, stored under the paths of the
+// share owner the copy belongs to, and returns them with the source blocks in the same order. New IDs are
+// assigned up front so the blocks can be copied to their final storage paths.
+func (s *Service) prepareRevisionCopy(ctx context.Context, share *models.DriveShare, source, item *models.DriveItem) (*FileCopy, []*models.FileBlock, error) {
+	revision, err := s.repo.GetActiveRevisionByItemID(ctx, source.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, err := s.repo.GetBlocksByRevisionID(ctx, revision.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load blocks: %w", err)
+	}
+
+	item.Size = revision.Size
+
+	revisionCopy := &models.FileRevision{
+		ID:                utils.GenerateLinkID(),
+		ItemID:            item.ID,
+		Size:              revision.Size,
+		State:             REVISION_STATE_ACTIVE,
+		SignatureEmail:    revision.SignatureEmail,
+		ManifestSignature: revision.ManifestSignature,
+	}
+
+	blockCopies := make([]*models.FileBlock, len(blocks))
+	for i, block := range blocks {
+		blockCopies[i] = &models.FileBlock{
+			RevisionID:         revisionCopy.ID,
+			Index:              block.Index,
+			Size:               block.Size,
+			Hash:               block.Hash,
+			ChecksumSHA256:     block.ChecksumSHA256,
+			StoragePath:        s3.FileBlockPath(share.UserID, item.VolumeID, item.ID, revisionCopy.ID, block.Index),
+			StorageBucket:      s.storage.BucketName(),
+			StorageRegion:      s.storage.Region(),
+			StorageClass:       STORAGE_CLASS_STANDARD,
+			ReplicationRegion:  block.ReplicationRegion,
+			KeyPacket:          block.KeyPacket,
+			KeyPacketSignature: block.KeyPacketSignature,
+			UploadComplete:     true,
+			UploadTime:         block.UploadTime,
+		}
+	}
+
+	return &FileCopy{Item: item, Revision: revisionCopy, Blocks: blockCopies}, blocks, nil
+}
+
+// copyStoredBlocks copies the stored blocks of every prepared file copy in parallel. A copy whose blocks
+// could not all be copied gets ErrStorageUnavailable, and the blocks it did copy are deleted again.
+func (s *Service) copyStoredBlocks(ctx context.Context, fileCopies []*FileCopy, sourceBlocks [][]*models.FileBlock) []error {
+	copyErrors := make([]error, len(fileCopies))
+	var mu sync.Mutex
+
+	g := new(errgroup.Group)
+	g.SetLimit(s.maxConcurrency())
+
+	for i, fileCopy := range fileCopies {
+		if fileCopy == nil {
+			continue
+		}
+		for j, block := range fileCopy.Blocks {
+			source := sourceBlocks[i][j]
+			g.Go(func() error {
+				if err := s.storage.CopyObject(ctx, source.StoragePath, block.StoragePath); err != nil {
+					s.logger.Errorf("Failed to copy block %s: %v", source.ID, err)
+					mu.Lock()
+					copyErrors[i] = ErrStorageUnavailable
+					mu.Unlock()
+				}
+				return nil
+			})
+		}
+	}
+	_ = g.Wait()
+
+	// Drop what was copied of the files that failed
+	for i, fileCopy := range fileCopies {
+		if fileCopy == nil || copyErrors[i] == nil {
+			continue
+		}
+		paths := make([]string, len(fileCopy.Blocks))
+		for j, block := range fileCopy.Blocks {
+			paths[j] = block.StoragePath
+		}
+		go s.deleteStoredObjects(context.WithoutCancel(ctx), paths)
+	}
+
+	return copyErrors
+}
+
+// isLiveItemOfShare reports whether an item belongs to a share and is neither trashed, a draft nor being deleted
+func isLiveItemOfShare(item *models.DriveItem, shareID string) bool {
+	return item.ShareID == shareID && !item.IsTrashed &&
+		item.State != ITEM_STATE_DRAFT && item.State != ITEM_STATE_DELETING
+}
+
+// batchLinkIDs returns the link IDs of a batch move or copy in request order
+func batchLinkIDs(entries []*BatchItemMove) []string {
+	linkIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		linkIDs[i] = entry.LinkID
+	}
+	return linkIDs
+}
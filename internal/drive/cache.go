@@ -0,0 +1,204 @@
+package drive
+
+import (
+	"cirrussync-api/pkg/config"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache types, named by the prefix of their keys
+const (
+	CACHE_FOLDER_CONTENTS   = "folder_contents"
+	CACHE_FOLDER_HASH       = "folder_hash"
+	CACHE_NAME_HASH         = "name_hash"
+	CACHE_PERMISSION        = "perm"
+	CACHE_SHARE_MEMBERSHIPS = "share_with_memberships"
+	CACHE_LINK_PATH         = "link_path"
+)
+
+// Entities whose cache version is part of the keys of the entries derived from them
+const (
+	cacheScopeFolder = "folder"
+	cacheScopeShare  = "share"
+	cacheScopeUser   = "user"
+	cacheScopeTree   = "tree" // The folder tree of a share
+)
+
+// errCacheBypassed is returned for lookups of entries whose key could not be versioned
+var errCacheBypassed = errors.New("cache bypassed")
+
+// cacheScope names one entity an entry is derived from
+type cacheScope struct {
+	kind string
+	id   string
+}
+
+// cacheSettings is the time to live of each cache type
+type cacheSettings struct {
+	defaultTTL time.Duration
+	ttls       map[string]time.Duration // TTL per cache type, where it differs from the default
+}
+
+// newCacheSettings builds the TTL policy of every cache type from the drive configuration
+func newCacheSettings(cfg *config.DriveConfig) cacheSettings {
+	settings := cacheSettings{
+		defaultTTL: CACHE_EXPIRATION,
+		ttls: map[string]time.Duration{
+			// Name checks guard creates, so they are only trusted briefly
+			CACHE_FOLDER_HASH: 5 * time.Minute,
+			CACHE_NAME_HASH:   5 * time.Minute,
+		},
+	}
+
+	if cfg != nil {
+		if cfg.CacheTTL > 0 {
+			settings.defaultTTL = cfg.CacheTTL
+		}
+		for cache, ttl := range cfg.CacheTTLs {
+			if ttl > 0 {
+				settings.ttls[cache] = ttl
+			}
+		}
+	}
+
+	return settings
+}
+
+// ttl returns the time to live of a cache type
+func (c cacheSettings) ttl(cache string) time.Duration {
+	if ttl, ok := c.ttls[cache]; ok {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// maxTTL returns the longest time to live of any cache type
+func (c cacheSettings) maxTTL() time.Duration {
+	longest := c.defaultTTL
+	for _, ttl := range c.ttls {
+		longest = max(longest, ttl)
+	}
+	return longest
+}
+
+// cacheVersionKey holds the current cache version of an entity
+func cacheVersionKey(scope cacheScope) string {
+	return fmt.Sprintf("cache_version:%s:%s", scope.kind, scope.id)
+}
+
+// versionedKey appends the current cache version of each scope to a key, so bumping any of them moves
+// readers to keys nothing was cached under yet. It returns an empty key, which getCached and setCached
+// bypass, when the versions cannot be read.
+func (s *Service) versionedKey(ctx context.Context, key string, scopes ...cacheScope) string {
+	versionKeys := make([]string, len(scopes))
+	for i, scope := range scopes {
+		versionKeys[i] = cacheVersionKey(scope)
+	}
+
+	values, err := s.redisClient.MGet(ctx, versionKeys)
+	if err != nil {
+		return ""
+	}
+
+	versions := make([]string, len(values))
+	for i, value := range values {
+		version, _ := value.(string)
+		if version == "" {
+			version = "0"
+		}
+		versions[i] = version
+	}
+
+	return key + ":v" + strings.Join(versions, ".")
+}
+
+// bumpCacheVersion invalidates every entry derived from an entity by giving it a new cache version.
+// Versions are unique timestamps rather than increments, so a version key that expired and was
+// created again never repeats an earlier version. It outlives every entry cached under it.
+func (s *Service) bumpCacheVersion(ctx context.Context, scope cacheScope) {
+	version := strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err := s.redisClient.Set(ctx, cacheVersionKey(scope), version, s.cache.maxTTL()); err != nil {
+		s.logger.Errorf("Failed to bump cache version of %s %s: %v", scope.kind, scope.id, err)
+	}
+}
+
+// entityTag returns a weak ETag for a representation derived from the given scopes, which changes
+// whenever any of their cache versions is bumped. Scopes without a version are given a fresh one, so
+// a version that expired never brings back a tag handed out before.
+func (s *Service) entityTag(ctx context.Context, representation string, scopes ...cacheScope) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(representation))
+
+	for _, scope := range scopes {
+		key := cacheVersionKey(scope)
+		version, err := s.redisClient.Get(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		if version == "" {
+			fresh := strconv.FormatInt(time.Now().UnixNano(), 36)
+			set, err := s.redisClient.SetNX(ctx, key, fresh, s.cache.maxTTL())
+			if err != nil {
+				return "", err
+			}
+			version = fresh
+			if !set {
+				// Another request created the version first
+				if version, err = s.redisClient.Get(ctx, key); err != nil {
+					return "", err
+				}
+			}
+		}
+		fmt.Fprintf(hash, "\x00%s:%s:%s", scope.kind, scope.id, version)
+	}
+
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, nil
+}
+
+// FolderContentsETag returns the ETag of a folder listing, which changes whenever a child of the folder
+// does. The query holds the listing's paging and sorting. Read it before the listing, so a change made
+// in between moves the tag past the listing rather than the other way around.
+func (s *Service) FolderContentsETag(ctx context.Context, shareID, folderID, query string) (string, error) {
+	return s.entityTag(ctx, fmt.Sprintf("folder_contents:%s:%s?%s", shareID, folderID, query),
+		cacheScope{cacheScopeFolder, folderID})
+}
+
+// ShareETag returns the ETag of a share with its memberships as one user sees them. Read it before
+// the share, like FolderContentsETag.
+func (s *Service) ShareETag(ctx context.Context, shareID, userID string) (string, error) {
+	return s.entityTag(ctx, fmt.Sprintf("share_with_memberships:%s:%s", shareID, userID),
+		cacheScope{cacheScopeShare, shareID}, cacheScope{cacheScopeUser, userID})
+}
+
+// setCached writes a value to the cache with the time to live of the cache named by the key's prefix.
+// Nothing is written under an empty key, which versionedKey leaves when the cache must be bypassed.
+func (s *Service) setCached(ctx context.Context, cacheKey string, value any) {
+	if cacheKey == "" {
+		return
+	}
+	cache, _, _ := strings.Cut(cacheKey, ":")
+	_ = s.redisClient.SetJSON(ctx, cacheKey, value, s.cache.ttl(cache))
+}
+
+// setCachedUntil writes a value to the cache like setCached, but lets it expire no later than
+// expiresAt, a Unix time, when one is given. Values already past it are not cached.
+func (s *Service) setCachedUntil(ctx context.Context, cacheKey string, value any, expiresAt *int64) {
+	if cacheKey == "" {
+		return
+	}
+	cache, _, _ := strings.Cut(cacheKey, ":")
+	ttl := s.cache.ttl(cache)
+	if expiresAt != nil {
+		ttl = min(ttl, time.Until(time.Unix(*expiresAt, 0)))
+		if ttl <= 0 {
+			return
+		}
+	}
+	_ = s.redisClient.SetJSON(ctx, cacheKey, value, ttl)
+}
@@ -0,0 +1,123 @@
+// internal/drive/download.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DOWNLOAD_URL_EXPIRATION is how long presigned block download URLs stay valid
+const DOWNLOAD_URL_EXPIRATION = 15 * time.Minute
+
+// BlockDownloadURL is a presigned download source for a single file block
+type BlockDownloadURL struct {
+	Index              int
+	Size               int64
+	Hash               string
+	KeyPacket          string
+	KeyPacketSignature string
+	DownloadURL        string
+}
+
+// FileDownload holds everything a client needs to fetch and reassemble an encrypted file
+type FileDownload struct {
+	File      *models.DriveItem
+	Revision  *models.FileRevision
+	Blocks    []*BlockDownloadURL
+	ExpiresAt int64
+}
+
+// GetFileDownload returns the revision metadata and ordered presigned block URLs for a file.
+// When revisionID is empty the active revision is used.
+func (s *Service) GetFileDownload(ctx context.Context, userID, shareID, linkID, revisionID string) (*FileDownload, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if s.storage == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	item, err := s.repo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if item.ShareID != shareID || item.IsTrashed {
+		return nil, ErrItemNotFound
+	}
+	if item.Type != 2 {
+		return nil, ErrNotAFile
+	}
+
+	var revision *models.FileRevision
+	if revisionID == "" {
+		revision, err = s.repo.GetActiveRevisionByItemID(ctx, item.ID)
+	} else {
+		revision, err = s.repo.GetRevisionByID(ctx, revisionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Drafts are still being uploaded and cannot be downloaded
+	if revision.ItemID != item.ID || revision.State == REVISION_STATE_DRAFT {
+		return nil, ErrRevisionNotFound
+	}
+
+	blocks, err := s.repo.GetBlocksByRevisionID(ctx, revision.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision blocks: %w", err)
+	}
+
+	// Presign block downloads with bounded concurrency
+	downloads := make([]*BlockDownloadURL, len(blocks))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.maxConcurrency())
+
+	for i, block := range blocks {
+		i, block := i, block
+		g.Go(func() error {
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+
+			downloadURL, err := s.storage.GetDownloadPresignedURL(block.StoragePath, DOWNLOAD_URL_EXPIRATION)
+			if err != nil {
+				return err
+			}
+
+			downloads[i] = &BlockDownloadURL{
+				Index:              block.Index,
+				Size:               block.Size,
+				Hash:               block.Hash,
+				KeyPacket:          block.KeyPacket,
+				KeyPacketSignature: block.KeyPacketSignature,
+				DownloadURL:        downloadURL,
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		s.logger.Errorf("Failed to presign block downloads for revision %s: %v", revision.ID, err)
+		return nil, ErrStorageUnavailable
+	}
+
+	s.recordItemOpened(ctx, userID, item.ID)
+
+	return &FileDownload{
+		File:      item,
+		Revision:  revision,
+		Blocks:    downloads,
+		ExpiresAt: time.Now().Add(DOWNLOAD_URL_EXPIRATION).Unix(),
+	}, nil
+}
@@ -0,0 +1,95 @@
+// internal/drive/duplicates.go
+package drive
+
+import (
+	"context"
+	"fmt"
+)
+
+// MAX_DUPLICATE_CANDIDATES bounds a single pre-upload duplicate check
+const MAX_DUPLICATE_CANDIDATES = 500
+
+// ContentCandidate is a client-computed content hash and size for a file about to be uploaded
+type ContentCandidate struct {
+	ContentHash string
+	Size        int64
+}
+
+// DuplicateMatch links a candidate to the existing file that already holds the same content
+type DuplicateMatch struct {
+	ContentHash string
+	Size        int64
+	LinkID      string
+}
+
+// FindDuplicateFiles reports which candidates already exist in the target folder so clients can skip uploading them.
+// A candidate matches only when both the content hash and the size are equal.
+func (s *Service) FindDuplicateFiles(ctx context.Context, userID, shareID, folderID string, candidates []ContentCandidate) ([]*DuplicateMatch, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if len(candidates) > MAX_DUPLICATE_CANDIDATES {
+		return nil, ErrTooManyCandidates
+	}
+	if len(candidates) == 0 {
+		return []*DuplicateMatch{}, nil
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	folder, err := s.repo.GetFolderByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder.ShareID != shareID || folder.IsTrashed {
+		return nil, ErrFolderNotFound
+	}
+	if folder.Type != 1 {
+		return nil, ErrNotAFolder
+	}
+
+	// Deduplicate hashes before querying
+	hashes := make([]string, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		if !seen[candidate.ContentHash] {
+			seen[candidate.ContentHash] = true
+			hashes = append(hashes, candidate.ContentHash)
+		}
+	}
+
+	files, err := s.repo.GetFilesByContentHashes(ctx, folderID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up content hashes: %w", err)
+	}
+
+	// Index existing files by hash and size
+	type contentKey struct {
+		hash string
+		size int64
+	}
+	existing := make(map[contentKey]string, len(files))
+	for _, file := range files {
+		if file.FileProperties == nil {
+			continue
+		}
+		existing[contentKey{file.FileProperties.ContentHash, file.Size}] = file.ID
+	}
+
+	matches := make([]*DuplicateMatch, 0)
+	for _, candidate := range candidates {
+		if linkID, ok := existing[contentKey{candidate.ContentHash, candidate.Size}]; ok {
+			matches = append(matches, &DuplicateMatch{
+				ContentHash: candidate.ContentHash,
+				Size:        candidate.Size,
+				LinkID:      linkID,
+			})
+		}
+	}
+
+	return matches, nil
+}
@@ -1,6 +1,9 @@
 package drive
 
-import "errors"
+import (
+	"cirrussync-api/internal/quota"
+	"errors"
+)
 
 // Common errors
 var (
@@ -19,13 +22,122 @@ var (
 	ErrRootShareAlreadyExists  = errors.New("User already has a root share")
 	ErrAllocationAlreadyExists = errors.New("User already has an allocation")
 	ErrMembershipAlreadyExists = errors.New("User already has a share membership")
+	ErrInvitationNotFound      = errors.New("Invitation not found")
 	ErrNameConflict            = errors.New("A folder with this name already exists in this location")
 	ErrShareNotFound           = errors.New("Share not found")
 	ErrUnauthorized            = errors.New("You don't have permission to create folders in this share")
 	ErrInsufficientPermissions = errors.New("You don't have sufficient permissions for this operation")
-	ErrStorageQuotaExceeded    = errors.New("Storage quota exceeded")
+	ErrStorageQuotaExceeded    = quota.ErrStorageQuotaExceeded
 
 	ErrItemNotFound   = errors.New("Link not found")
 	ErrFolderNotFound = errors.New("Folder not found")
 	ErrNotAFolder     = errors.New("Item is not a folder")
+
+	ErrInvalidRuntimeSettings = errors.New("Runtime settings are out of the allowed range")
+
+	ErrInvalidXattrs = errors.New("Extended attributes must be a non-empty encrypted blob of at most 64 KiB")
+
+	ErrInvalidFolderManifest = errors.New("Invalid folder manifest")
+
+	ErrInvalidSearchToken       = errors.New("Search tokens must be 16-128 hex or base64url characters")
+	ErrTooManySearchTokens      = errors.New("Too many search tokens in request")
+	ErrSearchKeyVersionMismatch = errors.New("Search tokens were computed with an inactive key version")
+	ErrSearchKeyStateNotFound   = errors.New("Search key state not found")
+	ErrInvalidSearchKeyVersion  = errors.New("New search key version must be greater than the active version")
+	ErrSearchReindexInProgress  = errors.New("A search key rotation is already in progress")
+	ErrNoSearchReindex          = errors.New("No search key rotation is in progress")
+	ErrSearchReindexIncomplete  = errors.New("Some items have not been reindexed with the new search key")
+
+	ErrFileCreation          = errors.New("Failed to create file")
+	ErrFileNameConflict      = errors.New("An item with this name already exists in this location")
+	ErrRevisionNotFound      = errors.New("Revision not found")
+	ErrRevisionNotDraft      = errors.New("Revision has already been committed")
+	ErrInvalidBlockList      = errors.New("Block list is empty, too large or contains invalid indexes")
+	ErrBlockTooLarge         = errors.New("Block exceeds the share block size")
+	ErrInvalidBlockChecksum  = errors.New("Block checksum must be a base64-encoded SHA-256 digest")
+	ErrInvalidThumbnail      = errors.New("Thumbnail type must be 1-3 and its size at most 512 KiB")
+	ErrThumbnailNotFound     = errors.New("Thumbnail not found")
+	ErrBlocksIncomplete      = errors.New("Not all blocks of the revision have been uploaded")
+	ErrBlockChecksumMismatch = errors.New("A stored block does not match its checksum, upload it again")
+	ErrStorageUnavailable    = errors.New("File storage is currently unavailable")
+	ErrUploadDeferred        = errors.New("File storage is temporarily unavailable, keep the blocks and retry the upload later")
+	ErrNotAFile              = errors.New("Item is not a file")
+	ErrTooManyCandidates     = errors.New("Too many content hashes in request")
+
+	ErrTooManyItems       = errors.New("Too many items in request")
+	ErrCannotTrashRoot    = errors.New("The root folder of a share cannot be trashed")
+	ErrItemAlreadyInTrash = errors.New("Item is already in the trash")
+	ErrItemNotInTrash     = errors.New("Item is not in the trash")
+	ErrParentInTrash      = errors.New("The parent folder is in the trash")
+
+	ErrCannotDeleteRoot   = errors.New("The root folder of a share cannot be deleted")
+	ErrJobNotFound        = errors.New("Job not found")
+	ErrFolderDeleteFailed = errors.New("Failed to delete folder contents")
+
+	ErrInvalidEventCursor = errors.New("Invalid event cursor")
+
+	ErrReplicationNotAllowed  = errors.New("Cross-region replication requires a business plan")
+	ErrReplicationUnavailable = errors.New("Cross-region replication is not available")
+
+	ErrRegionRoutingUnavailable = errors.New("No regional endpoints are configured")
+
+	ErrCannotMoveRoot    = errors.New("The root folder of a share cannot be renamed or moved")
+	ErrInvalidMoveTarget = errors.New("A folder cannot be moved into itself or one of its subfolders")
+	ErrCannotCopyFolder  = errors.New("Only files can be copied")
+
+	ErrShareURLNotFound    = errors.New("Public link not found")
+	ErrShareURLExpired     = errors.New("Public link has expired")
+	ErrShareExpired        = errors.New("Share has expired")
+	ErrInvalidExpiry       = errors.New("Expiry must be in the future")
+	ErrPermissionExpired   = errors.New("Your access to this share has expired")
+	ErrShareURLCreation    = errors.New("Failed to create public link")
+	ErrShareURLRateLimited = errors.New("Daily public link limit reached, please try again tomorrow")
+	ErrInvalidSlug         = errors.New("Slug must be 3-48 lowercase letters, digits or hyphens and cannot start or end with a hyphen")
+	ErrSlugReserved        = errors.New("This slug is reserved")
+	ErrSlugTaken           = errors.New("This slug is already in use")
+	ErrInvalidQRCodeSize   = errors.New("QR code size must be between 64 and 1024 pixels")
+	ErrInvalidQRCodeFormat = errors.New("QR code format must be png or svg")
+
+	ErrTagNotFound       = errors.New("Tag not found")
+	ErrTooManyTags       = errors.New("Tag limit reached")
+	ErrTooManyItemTags   = errors.New("Too many tags on item")
+	ErrTooManyTagFilters = errors.New("Too many tags to filter by")
+	ErrInvalidTagName    = errors.New("Tag name must be a non-empty encrypted label")
+	ErrInvalidTagColor   = errors.New("Tag color must be in #rrggbb format")
+	ErrNoSearchCriteria  = errors.New("At least one search token or tag is required")
+
+	ErrInvalidIntegrityState = errors.New("Integrity issue state must be open or resolved")
+
+	ErrUploadPaused      = errors.New("Upload is paused, resume it to continue")
+	ErrUploadInterrupted = errors.New("Upload was paused or cancelled before it could be committed")
+
+	ErrDeviceNotFound        = errors.New("Device not found")
+	ErrBackupSetNotFound     = errors.New("Backup set not found")
+	ErrTooManyBackupSets     = errors.New("Backup set limit reached for this device")
+	ErrInvalidBackupRules    = errors.New("Backups must keep 1-100 versions and retain previous versions for 0-3650 days")
+	ErrBackupReadOnly        = errors.New("Backups can only be changed from the device they belong to")
+	ErrBackupSetDeleteFailed = errors.New("Failed to delete backup set")
+
+	ErrShareNotLocked      = errors.New("Share is not locked")
+	ErrCannotUnlockShare   = errors.New("Only share admins chosen when the share was locked can unlock it")
+	ErrInvalidUnlockPacket = errors.New("Unlocking requires the share passphrase and owner key packet, both signed")
+
+	ErrNoPrimaryKey         = errors.New("User has no primary key to rotate to")
+	ErrKeyRotationNotFound  = errors.New("No key rotation in progress")
+	ErrShareNotInRotation   = errors.New("Share is not part of the key rotation")
+	ErrShareAlreadyRotated  = errors.New("Share was already rotated to the new key")
+	ErrInvalidRotationBatch = errors.New("Key rotation batches need a signed share passphrase and owner key packet, signed node passphrases, or both")
+	ErrStaleRotationBatch   = errors.New("Key rotation batch does not match the pending items, fetch them again")
+
+	ErrInvalidPermissions    = errors.New("Permissions must combine read, write, execute, share and admin")
+	ErrCannotChangeOwner     = errors.New("The share owner's membership cannot be changed or removed")
+	ErrShareNotTransferable  = errors.New("Backup shares cannot be transferred")
+	ErrShareLocked           = errors.New("Share is locked, unlock it first")
+	ErrShareOwnerChanged     = errors.New("Share owner changed, reload the share and try again")
+	ErrInvalidTransferPacket = errors.New("Transferring ownership requires the share passphrase re-wrapped for the new owner, signed")
+
+	ErrInvalidBulkInvitations  = errors.New("Bulk invitations must list between 1 and 500 members")
+	ErrInvalidPermissionPreset = errors.New("Permission preset must be viewer, editor or manager")
+	ErrInvalidInvitation       = errors.New("Invitation requires an email and a signed key packet")
+	ErrDuplicateInvitation     = errors.New("Email is listed more than once")
 )
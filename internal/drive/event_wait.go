@@ -0,0 +1,165 @@
+// internal/drive/event_wait.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DRIVE_EVENTS_CHANNEL_PREFIX is the Redis channel a volume's new events are announced on, followed by the volume ID
+const DRIVE_EVENTS_CHANNEL_PREFIX = "drive:events:"
+
+const (
+	// Default and maximum time a long-poll request waits for an event
+	DEFAULT_EVENT_WAIT = 30 * time.Second
+	MAX_EVENT_WAIT     = 60 * time.Second
+
+	// How often a waiting request checks the database, in case an announcement was missed
+	EVENT_WAIT_POLL_INTERVAL = 10 * time.Second
+)
+
+// eventNotifier fans Redis event announcements out to the requests waiting on this instance.
+// A single pattern subscription is shared by all waiters so long-polling does not cost a Redis connection each.
+type eventNotifier struct {
+	mu         sync.Mutex
+	waiters    map[string]map[chan struct{}]struct{}
+	subscribed bool
+}
+
+// newEventNotifier creates a notifier with no waiters
+func newEventNotifier() *eventNotifier {
+	return &eventNotifier{waiters: make(map[string]map[chan struct{}]struct{})}
+}
+
+// WaitForVolumeEvents returns the volume's events after the cursor as soon as there are any,
+// or an empty page with the same cursor once the wait times out.
+func (s *Service) WaitForVolumeEvents(ctx context.Context, userID, volumeID, cursor string, limit int, wait time.Duration) (*EventPage, error) {
+	// Without a cursor there is nothing to wait for; hand out the current position
+	if cursor == "" {
+		return s.GetVolumeEvents(ctx, userID, volumeID, cursor, limit)
+	}
+
+	if wait <= 0 || wait > MAX_EVENT_WAIT {
+		wait = DEFAULT_EVENT_WAIT
+	}
+
+	// Listen before checking so an event recorded in between is not missed
+	notify := s.watchVolume(volumeID)
+	defer s.unwatchVolume(volumeID, notify)
+
+	page, err := s.GetVolumeEvents(ctx, userID, volumeID, cursor, limit)
+	if err != nil || len(page.Events) > 0 {
+		return page, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(EVENT_WAIT_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return page, nil
+		case <-notify:
+		case <-ticker.C:
+		}
+
+		page, err = s.GetVolumeEvents(ctx, userID, volumeID, cursor, limit)
+		if err != nil || len(page.Events) > 0 {
+			return page, err
+		}
+	}
+}
+
+// announceEvents tells every instance that the volumes have new events
+func (s *Service) announceEvents(ctx context.Context, events []*models.DriveEvent) {
+	volumes := make(map[string]bool)
+	for _, event := range events {
+		volumes[event.VolumeID] = true
+	}
+
+	for volumeID := range volumes {
+		if _, err := s.redisClient.Publish(ctx, DRIVE_EVENTS_CHANNEL_PREFIX+volumeID, "1"); err != nil {
+			s.logger.Errorf("Failed to announce events for volume %s: %v", volumeID, err)
+		}
+	}
+}
+
+// watchVolume registers a waiter for the volume's announcements
+func (s *Service) watchVolume(volumeID string) chan struct{} {
+	s.ensureEventSubscription()
+
+	notify := make(chan struct{}, 1)
+
+	s.notifier.mu.Lock()
+	defer s.notifier.mu.Unlock()
+	if s.notifier.waiters[volumeID] == nil {
+		s.notifier.waiters[volumeID] = make(map[chan struct{}]struct{})
+	}
+	s.notifier.waiters[volumeID][notify] = struct{}{}
+
+	return notify
+}
+
+// unwatchVolume removes a waiter
+func (s *Service) unwatchVolume(volumeID string, notify chan struct{}) {
+	s.notifier.mu.Lock()
+	defer s.notifier.mu.Unlock()
+
+	delete(s.notifier.waiters[volumeID], notify)
+	if len(s.notifier.waiters[volumeID]) == 0 {
+		delete(s.notifier.waiters, volumeID)
+	}
+}
+
+// ensureEventSubscription starts the shared subscription on first use. If Redis is unavailable,
+// waiters still find new events through their periodic database check.
+func (s *Service) ensureEventSubscription() {
+	s.notifier.mu.Lock()
+	defer s.notifier.mu.Unlock()
+	if s.notifier.subscribed {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout())
+	defer cancel()
+
+	pubsub, err := s.redisClient.PSubscribe(subCtx, DRIVE_EVENTS_CHANNEL_PREFIX+"*")
+	if err != nil {
+		s.logger.Errorf("Failed to subscribe to drive event announcements: %v", err)
+		return
+	}
+	s.notifier.subscribed = true
+
+	go func() {
+		// The channel reconnects on its own and only closes if the subscription is closed
+		for message := range pubsub.Channel() {
+			volumeID := strings.TrimPrefix(message.Channel, DRIVE_EVENTS_CHANNEL_PREFIX)
+			s.notifyWaiters(volumeID)
+		}
+
+		s.notifier.mu.Lock()
+		s.notifier.subscribed = false
+		s.notifier.mu.Unlock()
+	}()
+}
+
+// notifyWaiters wakes every request waiting on the volume
+func (s *Service) notifyWaiters(volumeID string) {
+	s.notifier.mu.Lock()
+	defer s.notifier.mu.Unlock()
+
+	for notify := range s.notifier.waiters[volumeID] {
+		// A pending wake-up is as good as a second one
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
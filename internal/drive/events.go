@@ -0,0 +1,182 @@
+// internal/drive/events.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// Drive event types
+const (
+	EVENT_TYPE_CREATE  = 1
+	EVENT_TYPE_UPDATE  = 2
+	EVENT_TYPE_MOVE    = 3
+	EVENT_TYPE_TRASH   = 4
+	EVENT_TYPE_RESTORE = 5
+	EVENT_TYPE_DELETE  = 6
+
+	// EVENT_TYPE_DEVICE_SETTINGS tells desktop clients that the sync settings of the device whose ID
+	// is in the event's LinkID changed and should be fetched again
+	EVENT_TYPE_DEVICE_SETTINGS = 7
+)
+
+// MAX_EVENTS_PER_PAGE bounds the number of events returned per request
+const MAX_EVENTS_PER_PAGE = 500
+
+// eventCursorPrefix versions the cursor format so it can change without breaking stored cursors
+const eventCursorPrefix = "e1:"
+
+// EventPage is a batch of volume events and the cursor to continue from
+type EventPage struct {
+	Events []*models.DriveEvent
+	Cursor string
+	More   bool
+}
+
+// GetVolumeEvents returns the volume's events after the cursor, oldest first.
+// Without a cursor no events are returned, only a cursor marking the present, so a new client
+// lists the drive once and then syncs from that point on.
+func (s *Service) GetVolumeEvents(ctx context.Context, userID, volumeID, cursor string, limit int) (*EventPage, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	volume, err := s.repo.GetVolumeByID(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not reveal other users' volumes
+	if volume.UserID != userID {
+		return nil, ErrVolumeNotFound
+	}
+
+	if cursor == "" {
+		latestID, err := s.repo.GetLatestEventID(ctx, volumeID)
+		if err != nil {
+			return nil, err
+		}
+		return &EventPage{Events: []*models.DriveEvent{}, Cursor: encodeEventCursor(latestID)}, nil
+	}
+
+	sinceID, err := decodeEventCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > MAX_EVENTS_PER_PAGE {
+		limit = MAX_EVENTS_PER_PAGE
+	}
+
+	// Fetch one extra event to know whether more remain
+	events, err := s.repo.GetEventsSince(ctx, volumeID, sinceID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(events) > limit
+	if more {
+		events = events[:limit]
+	}
+
+	lastID := sinceID
+	if len(events) > 0 {
+		lastID = events[len(events)-1].ID
+	}
+
+	return &EventPage{Events: events, Cursor: encodeEventCursor(lastID), More: more}, nil
+}
+
+// recordEvents appends an event for each item to its volume's log.
+// Failures are logged rather than returned since the change itself has already been made.
+func (s *Service) recordEvents(ctx context.Context, eventType int, items ...*models.DriveItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	events := make([]*models.DriveEvent, len(items))
+	for i, item := range items {
+		events[i] = &models.DriveEvent{
+			VolumeID: item.VolumeID,
+			ShareID:  item.ShareID,
+			LinkID:   item.ID,
+			ParentID: item.ParentID,
+			Type:     eventType,
+			LinkType: item.Type,
+		}
+	}
+
+	// Do not lose the event when the request is cancelled right after the change
+	opCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.defaultTimeout())
+	defer cancel()
+
+	if err := s.repo.CreateEvents(opCtx, events); err != nil {
+		s.logger.Errorf("Failed to record %d drive events: %v", len(events), err)
+		return
+	}
+
+	s.announceEvents(opCtx, events)
+	s.markFolderSizesStale(opCtx, events)
+}
+
+// RecordDeviceSettingsChange appends an event to the user's drive volume announcing that a device's
+// sync settings changed. Users without a drive have no clients syncing and nothing is recorded.
+func (s *Service) RecordDeviceSettingsChange(ctx context.Context, userID, deviceID string) {
+	// Do not lose the event when the request is cancelled right after the change
+	opCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.defaultTimeout())
+	defer cancel()
+
+	shares, err := s.repo.GetSharesByUserID(opCtx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to load shares of user %s for a device settings event: %v", userID, err)
+		return
+	}
+
+	for _, share := range shares {
+		if share.Type != SHARE_TYPE_ROOT {
+			continue
+		}
+
+		events := []*models.DriveEvent{{
+			VolumeID: share.VolumeID,
+			ShareID:  share.ID,
+			LinkID:   deviceID,
+			Type:     EVENT_TYPE_DEVICE_SETTINGS,
+		}}
+		if err := s.repo.CreateEvents(opCtx, events); err != nil {
+			s.logger.Errorf("Failed to record device settings event for user %s: %v", userID, err)
+			return
+		}
+
+		s.announceEvents(opCtx, events)
+		return
+	}
+}
+
+// encodeEventCursor turns an event ID into an opaque cursor
+func encodeEventCursor(eventID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(eventCursorPrefix + strconv.FormatInt(eventID, 10)))
+}
+
+// decodeEventCursor recovers the event ID from a cursor
+func decodeEventCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidEventCursor
+	}
+
+	value, ok := strings.CutPrefix(string(raw), eventCursorPrefix)
+	if !ok {
+		return 0, ErrInvalidEventCursor
+	}
+
+	eventID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || eventID < 0 {
+		return 0, ErrInvalidEventCursor
+	}
+	return eventID, nil
+}
@@ -0,0 +1,232 @@
+// internal/drive/folder_bulk.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	utils "cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/tracing"
+	"context"
+	"fmt"
+)
+
+// MAX_BULK_FOLDERS bounds the number of folders one manifest may describe. Larger trees are sent
+// in several manifests, parents first.
+const MAX_BULK_FOLDERS = 1000
+
+// BulkFolder is one folder of a manifest. Folders refer to their parent by the temporary ID the
+// client gave it in the same manifest; folders without a parent go into the target folder.
+type BulkFolder struct {
+	TempID                  string
+	ParentTempID            string
+	Name                    string
+	Hash                    string
+	SignatureEmail          string
+	NodeKey                 string
+	NodeHashKey             string
+	NodePassphrase          string
+	NodePassphraseSignature string
+}
+
+// BulkFolderResult maps a folder of a manifest to its link
+type BulkFolderResult struct {
+	TempID  string
+	LinkID  string
+	Created bool // False when a folder with the same name already existed and was reused
+}
+
+// CreateFolderTree creates the folders of a manifest under a folder of a share in one transaction,
+// parents before their children. A folder whose name is already taken by a folder in the same
+// place is reused instead of created, so a manifest can be sent again after a lost response or a
+// failed request and picks up where the tree left off.
+func (s *Service) CreateFolderTree(ctx context.Context, userID, shareID, parentID string, folders []*BulkFolder) ([]*BulkFolderResult, error) {
+	ctx, span := tracing.Start(ctx, "drive.CreateFolderTree")
+	defer span.End()
+
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if len(folders) == 0 || len(folders) > MAX_BULK_FOLDERS {
+		return nil, ErrTooManyItems
+	}
+
+	ordered, err := orderFolderManifest(folders)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, WRITE_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	// Folders count against the quota of the share owner, not of the member creating them
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := s.getBatchDestination(ctx, shareID, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	linkIDs := make(map[string]string, len(ordered))
+	created := make(map[string]bool, len(ordered))
+	// Name hashes of the folders of the manifest by the temporary ID of their parent
+	childHashes := make(map[string][]string)
+	for _, folder := range ordered {
+		childHashes[folder.ParentTempID] = append(childHashes[folder.ParentTempID], folder.Hash)
+	}
+	// Folders of existing parents by name hash, loaded once per parent
+	existingChildren := make(map[string]map[string]*models.DriveItem)
+
+	results := make([]*BulkFolderResult, 0, len(ordered))
+	items := make([]*models.DriveItem, 0, len(ordered))
+	// Existing folders that get new children
+	touched := make(map[string]bool)
+
+	for _, folder := range ordered {
+		parentLinkID := destination.ID
+		parentCreated := false
+		if folder.ParentTempID != "" {
+			parentLinkID = linkIDs[folder.ParentTempID]
+			parentCreated = created[folder.ParentTempID]
+		}
+
+		// Folders created by this manifest have no children yet, so only existing parents are looked up
+		if !parentCreated {
+			children, ok := existingChildren[parentLinkID]
+			if !ok {
+				children, err = s.loadChildrenByHash(ctx, parentLinkID, childHashes[folder.ParentTempID])
+				if err != nil {
+					return nil, fmt.Errorf("failed to check for name conflicts: %w", err)
+				}
+				existingChildren[parentLinkID] = children
+			}
+
+			if existing, ok := children[folder.Hash]; ok {
+				if existing.Type != 1 || !isLiveItemOfShare(existing, shareID) {
+					return nil, fmt.Errorf("%w: folder %s", ErrFileNameConflict, folder.TempID)
+				}
+				linkIDs[folder.TempID] = existing.ID
+				results = append(results, &BulkFolderResult{TempID: folder.TempID, LinkID: existing.ID})
+				continue
+			}
+			touched[parentLinkID] = true
+		}
+
+		item := &models.DriveItem{
+			ID:                      utils.GenerateLinkID(),
+			ParentID:                &parentLinkID,
+			ShareID:                 shareID,
+			VolumeID:                destination.VolumeID,
+			Type:                    1, // Folder
+			Name:                    folder.Name,
+			Hash:                    folder.Hash,
+			SignatureEmail:          folder.SignatureEmail,
+			NodeKey:                 folder.NodeKey,
+			NodePassphrase:          folder.NodePassphrase,
+			NodePassphraseSignature: folder.NodePassphraseSignature,
+			Permissions:             RWX_PERMISSIONS,
+			FolderProperties: &models.FolderProperties{
+				NodeHashKey: folder.NodeHashKey,
+			},
+		}
+
+		linkIDs[folder.TempID] = item.ID
+		created[folder.TempID] = true
+		items = append(items, item)
+		results = append(results, &BulkFolderResult{TempID: folder.TempID, LinkID: item.ID, Created: true})
+	}
+
+	if len(items) == 0 {
+		return results, nil
+	}
+
+	size := int64(len(items)) * FOLDER_METADATA_BYTES
+	if err := s.CheckStorageQuota(ctx, share.UserID, size); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateItems(ctx, items); err != nil {
+		s.logger.Errorf("Failed to create %d folders in share %s: %v", len(items), shareID, err)
+		return nil, ErrFolderCreation
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_CREATE, items...)
+
+	// Update storage used (can be done asynchronously)
+	s.updateStorageUsed(context.WithoutCancel(ctx), share.UserID, size)
+
+	for folderID := range touched {
+		s.invalidateFolderCaches(ctx, folderID)
+	}
+
+	return results, nil
+}
+
+// loadChildrenByHash returns the items in a folder that have one of hashes, by their name hash
+func (s *Service) loadChildrenByHash(ctx context.Context, folderID string, hashes []string) (map[string]*models.DriveItem, error) {
+	items, err := s.repo.GetFolderChildrenByHashes(ctx, folderID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string]*models.DriveItem, len(items))
+	for _, item := range items {
+		children[item.Hash] = item
+	}
+	return children, nil
+}
+
+// orderFolderManifest checks that a manifest describes a tree and returns its folders with every
+// parent before its children. Temporary IDs must be unique, parents must be in the manifest, and
+// no two folders may share a name in the same parent.
+func orderFolderManifest(folders []*BulkFolder) ([]*BulkFolder, error) {
+	byTempID := make(map[string]*BulkFolder, len(folders))
+	for _, folder := range folders {
+		if folder.TempID == "" {
+			return nil, fmt.Errorf("%w: every folder needs a temporary ID", ErrInvalidFolderManifest)
+		}
+		if _, ok := byTempID[folder.TempID]; ok {
+			return nil, fmt.Errorf("%w: temporary ID %s is used more than once", ErrInvalidFolderManifest, folder.TempID)
+		}
+		byTempID[folder.TempID] = folder
+	}
+
+	children := make(map[string][]*BulkFolder, len(folders))
+	names := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		if folder.ParentTempID != "" {
+			if _, ok := byTempID[folder.ParentTempID]; !ok {
+				return nil, fmt.Errorf("%w: parent %s of folder %s is not in the manifest", ErrInvalidFolderManifest, folder.ParentTempID, folder.TempID)
+			}
+		}
+
+		name := folder.ParentTempID + "/" + folder.Hash
+		if names[name] {
+			return nil, fmt.Errorf("%w: folder %s", ErrNameConflict, folder.TempID)
+		}
+		names[name] = true
+
+		children[folder.ParentTempID] = append(children[folder.ParentTempID], folder)
+	}
+
+	// Walk the tree from the target folder; folders never reached are part of a cycle
+	ordered := make([]*BulkFolder, 0, len(folders))
+	queue := []string{""}
+	for len(queue) > 0 {
+		tempID := queue[0]
+		queue = queue[1:]
+		for _, child := range children[tempID] {
+			ordered = append(ordered, child)
+			queue = append(queue, child.TempID)
+		}
+	}
+	if len(ordered) != len(folders) {
+		return nil, fmt.Errorf("%w: folders cannot contain themselves", ErrInvalidFolderManifest)
+	}
+
+	return ordered, nil
+}
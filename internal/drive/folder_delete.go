@@ -0,0 +1,183 @@
+// internal/drive/folder_delete.go
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// JOB_TYPE_FOLDER_DELETE deletes a folder and its whole subtree in the background
+const JOB_TYPE_FOLDER_DELETE = "drive.folder_delete"
+
+// FOLDER_DELETE_CHUNK_SIZE is how many items are purged per transaction. Storage usage is
+// released after every chunk, so an interrupted job leaves the quota consistent with what remains.
+const FOLDER_DELETE_CHUNK_SIZE = 500
+
+// SetJobService enables operations that run as background jobs, such as recursive folder deletion,
+// the cleanup of abandoned uploads, the retention rules of backups, the purge of old trash, the
+// storage lifecycle of blocks, share expiry, the storage integrity audit and storage usage updates
+func (s *Service) SetJobService(jobService *jobs.Service) {
+	s.jobService = jobService
+	jobService.Register(JOB_TYPE_FOLDER_DELETE, s.runFolderDeleteJob)
+	jobService.Register(JOB_TYPE_UPLOAD_CLEANUP, s.runUploadCleanupJob)
+	jobService.Register(JOB_TYPE_BACKUP_SET_DELETE, s.runBackupSetDeleteJob)
+	jobService.Register(JOB_TYPE_BACKUP_RETENTION, s.runBackupRetentionJob)
+	jobService.Register(JOB_TYPE_TRASH_PURGE, s.runTrashPurgeJob)
+	jobService.Register(JOB_TYPE_INVITATION_EMAILS, s.runInvitationEmailsJob)
+	jobService.Register(JOB_TYPE_SANDBOX_RESET, s.runSandboxResetJob)
+	jobService.Register(JOB_TYPE_FOLDER_SIZES, s.runFolderSizesJob)
+	jobService.Register(JOB_TYPE_STORAGE_LIFECYCLE, s.runStorageLifecycleJob)
+	jobService.Register(JOB_TYPE_GARBAGE_COLLECTION, s.runGarbageCollectionJob)
+	jobService.Register(JOB_TYPE_STORAGE_ADJUST, s.runStorageAdjustJob)
+	jobService.Register(JOB_TYPE_SHARE_EXPIRY, s.runShareExpiryJob)
+	jobService.Register(JOB_TYPE_INTEGRITY_AUDIT, s.runIntegrityAuditJob)
+}
+
+// DeleteFolder hides a folder immediately and queues the permanent deletion of it and everything below it
+func (s *Service) DeleteFolder(ctx context.Context, userID, shareID, folderID string) (*models.Job, error) {
+	if s.jobService == nil {
+		return nil, ErrFolderDeleteFailed
+	}
+
+	folder, share, err := s.getDeletableFolder(ctx, userID, shareID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MarkItemDeleting(ctx, folder.ID); err != nil {
+		return nil, fmt.Errorf("failed to mark folder for deletion: %w", err)
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_TRASH, folder)
+	s.invalidateLinkCache(ctx, folder.ID)
+	if folder.ParentID != nil {
+		s.invalidateFolderCaches(ctx, *folder.ParentID)
+	}
+	s.invalidatePathCaches(ctx, shareID)
+
+	// Storage is released to the share owner, who is charged for the share's contents
+	job, err := s.jobService.Enqueue(ctx, userID, JOB_TYPE_FOLDER_DELETE, map[string]string{
+		"shareId":  shareID,
+		"folderId": folder.ID,
+		"ownerId":  share.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetJob retrieves the status of a background job started by the user
+func (s *Service) GetJob(ctx context.Context, userID, jobID string) (*models.Job, error) {
+	if s.jobService == nil {
+		return nil, ErrJobNotFound
+	}
+
+	job, err := s.jobService.GetJob(ctx, userID, jobID)
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// getDeletableFolder checks write access and loads a live, non-root folder of the share
+func (s *Service) getDeletableFolder(ctx context.Context, userID, shareID, folderID string) (*models.DriveItem, *models.DriveShare, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, WRITE_PERMISSION); err != nil {
+		return nil, nil, err
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	folder, err := s.repo.GetLinkByID(ctx, folderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if folder.ShareID != shareID || folder.State == ITEM_STATE_DELETING {
+		return nil, nil, ErrItemNotFound
+	}
+	if folder.Type != 1 {
+		return nil, nil, ErrNotAFolder
+	}
+	if folder.ID == share.LinkID {
+		return nil, nil, ErrCannotDeleteRoot
+	}
+
+	return folder, share, nil
+}
+
+// runFolderDeleteJob purges a folder's subtree deepest first, releasing storage as it goes.
+// Running it again after an interruption continues with whatever is left.
+func (s *Service) runFolderDeleteJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	folderID := job.Payload["folderId"]
+	ownerID := job.Payload["ownerId"]
+
+	items, err := s.repo.GetSubtreeItems(ctx, folderID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.logger.Errorf("Failed to load subtree of folder %s: %v", folderID, err)
+		return ErrFolderDeleteFailed
+	}
+
+	// Counters carry over from earlier attempts
+	result := map[string]int64{
+		"deletedItems":  job.Result["deletedItems"],
+		"releasedBytes": job.Result["releasedBytes"],
+	}
+	total := result["deletedItems"] + int64(len(items))
+	progress(result["deletedItems"], total, result)
+
+	for start := 0; start < len(items); start += FOLDER_DELETE_CHUNK_SIZE {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		end := min(start+FOLDER_DELETE_CHUNK_SIZE, len(items))
+		chunk := items[start:end]
+		chunkIDs := make([]string, len(chunk))
+		for i, item := range chunk {
+			chunkIDs[i] = item.ID
+		}
+
+		purged, err := s.repo.PurgeItems(ctx, chunkIDs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Errorf("Failed to purge items of folder %s: %v", folderID, err)
+			return ErrFolderDeleteFailed
+		}
+
+		releasedBytes := purged.FileBytes + purged.FolderCount*FOLDER_METADATA_BYTES
+		_ = s.adjustStorageUsed(context.Background(), ownerID, -releasedBytes)
+		go s.deleteStoredObjects(context.WithoutCancel(ctx), purged.StoragePaths)
+
+		s.recordEvents(ctx, EVENT_TYPE_DELETE, chunk...)
+		for _, itemID := range chunkIDs {
+			s.invalidateLinkCache(ctx, itemID)
+		}
+
+		result["deletedItems"] += purged.ItemCount
+		result["releasedBytes"] += releasedBytes
+		progress(result["deletedItems"], total, result)
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// JOB_TYPE_FOLDER_SIZES recomputes the cumulative folder sizes of shares that changed
+const JOB_TYPE_FOLDER_SIZES = "drive.folder_sizes"
+
+// FOLDER_SIZE_BACKFILL_BATCH_SIZE is how many shares are queued per page of the backfill
+const FOLDER_SIZE_BACKFILL_BATCH_SIZE = 500
+
+// folderSizesStaleKey is the set of shares whose folder sizes are out of date
+const folderSizesStaleKey = "folder_sizes_stale"
+
+// folderSizesBackfilledKey marks that every existing share was queued for its first recompute
+const folderSizesBackfilledKey = "folder_sizes_backfilled"
+
+// ShareUsage is the size of one of the user's shares
+type ShareUsage struct {
+	Share     *models.DriveShare
+	TotalSize int64
+	Pending   bool // Changes are waiting for the next recompute
+}
+
+// FolderUsage is a folder's size with a page of its children, largest first
+type FolderUsage struct {
+	Folder   *models.DriveItem
+	Children []*models.DriveItem
+	Total    int
+	Pending  bool // Changes are waiting for the next recompute
+}
+
+// markFolderSizesStale queues the shares of changed items for the next folder size recompute.
+// Every change to a drive is recorded as an event, so this sees every change that moves bytes.
+func (s *Service) markFolderSizesStale(ctx context.Context, events []*models.DriveEvent) {
+	shareIDs := make([]any, 0, len(events))
+	for _, event := range events {
+		if event.ShareID != "" {
+			shareIDs = append(shareIDs, event.ShareID)
+		}
+	}
+	if len(shareIDs) == 0 {
+		return
+	}
+
+	if _, err := s.redisClient.SAdd(ctx, folderSizesStaleKey, shareIDs...); err != nil {
+		s.logger.Warnf("Failed to mark folder sizes stale: %v", err)
+	}
+}
+
+// StartFolderSizeScheduler queues a recompute job every interval in which shares changed until ctx
+// is cancelled. Shares that existed before folder sizes were kept are queued once, by whichever
+// instance starts first.
+func (s *Service) StartFolderSizeScheduler(ctx context.Context) {
+	go s.backfillFolderSizes(ctx)
+
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.ScheduleWhen(ctx, "folder_sizes_pass", s.folderSizes.interval, JOB_TYPE_FOLDER_SIZES, nil, s.hasStaleFolderSizes)
+}
+
+// backfillFolderSizes marks every active share stale once, so folders never changed since sizes
+// were introduced get theirs too
+func (s *Service) backfillFolderSizes(ctx context.Context) {
+	first, err := s.redisClient.SetNX(ctx, folderSizesBackfilledKey, time.Now().Unix(), 0)
+	if err != nil {
+		s.logger.Errorf("Failed to check folder size backfill: %v", err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	for _, shareType := range []int{SHARE_TYPE_ROOT, SHARE_TYPE_BACKUP} {
+		afterID := ""
+		for {
+			shares, err := s.repo.GetSharesByTypeAfter(ctx, shareType, afterID, FOLDER_SIZE_BACKFILL_BATCH_SIZE)
+			if err != nil {
+				s.logger.Errorf("Failed to load shares for folder size backfill: %v", err)
+				// Let the next start try again
+				_, _ = s.redisClient.Delete(context.WithoutCancel(ctx), folderSizesBackfilledKey)
+				return
+			}
+			if len(shares) == 0 {
+				break
+			}
+
+			shareIDs := make([]any, len(shares))
+			for i, share := range shares {
+				shareIDs[i] = share.ID
+			}
+			if _, err := s.redisClient.SAdd(ctx, folderSizesStaleKey, shareIDs...); err != nil {
+				s.logger.Errorf("Failed to queue shares for folder size backfill: %v", err)
+				_, _ = s.redisClient.Delete(context.WithoutCancel(ctx), folderSizesBackfilledKey)
+				return
+			}
+
+			afterID = shares[len(shares)-1].ID
+		}
+	}
+}
+
+// hasStaleFolderSizes reports whether any share changed since its folder sizes were last recomputed
+func (s *Service) hasStaleFolderSizes(ctx context.Context) bool {
+	stale, err := s.redisClient.SCard(ctx, folderSizesStaleKey)
+	if err != nil {
+		s.logger.Errorf("Failed to count shares with stale folder sizes: %v", err)
+		return false
+	}
+	return stale > 0
+}
+
+// runFolderSizesJob recomputes the folder sizes of every stale share. A share is taken off the stale
+// set before its recompute, so changes made during it mark it stale again for the next run.
+func (s *Service) runFolderSizesJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	shareIDs, err := s.redisClient.SMembers(ctx, folderSizesStaleKey)
+	if err != nil {
+		return fmt.Errorf("failed to load shares with stale folder sizes: %w", err)
+	}
+
+	result := map[string]int64{"recomputedShares": job.Result["recomputedShares"]}
+
+	for _, shareID := range shareIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if _, err := s.redisClient.SRem(ctx, folderSizesStaleKey, shareID); err != nil {
+			return fmt.Errorf("failed to update stale folder sizes: %w", err)
+		}
+
+		if err := s.repo.RecomputeFolderSizes(ctx, shareID); err != nil {
+			s.logger.Errorf("Failed to recompute folder sizes of share %s: %v", shareID, err)
+			// Leave it for the next run
+			_, _ = s.redisClient.SAdd(context.WithoutCancel(ctx), folderSizesStaleKey, shareID)
+			continue
+		}
+
+		result["recomputedShares"]++
+		progress(result["recomputedShares"], int64(len(shareIDs)), result)
+	}
+
+	return nil
+}
+
+// GetShareUsage returns the size of every share the user owns
+func (s *Service) GetShareUsage(ctx context.Context, userID string) ([]*ShareUsage, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	shares, err := s.repo.GetSharesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shares: %w", err)
+	}
+
+	usage := make([]*ShareUsage, 0, len(shares))
+	for _, share := range shares {
+		root, err := s.repo.GetRootFolderByShareID(ctx, share.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		usage = append(usage, &ShareUsage{
+			Share:     share,
+			TotalSize: root.TotalSize,
+			Pending:   s.folderSizesPending(ctx, share.ID),
+		})
+	}
+
+	return usage, nil
+}
+
+// GetFolderUsage returns the size of a folder of a share, the share's root without a folder ID,
+// with a page of its children, largest first
+func (s *Service) GetFolderUsage(ctx context.Context, userID, shareID, folderID string, limit, offset int) (*FolderUsage, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	var folder *models.DriveItem
+	var err error
+	if folderID == "" {
+		folder, err = s.repo.GetRootFolderByShareID(ctx, shareID)
+	} else {
+		folder, err = s.repo.GetFolderByID(ctx, folderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if folder.Type != 1 || folder.ShareID != shareID || folder.IsTrashed || folder.State != ITEM_STATE_ACTIVE {
+		return nil, ErrFolderNotFound
+	}
+
+	children, total, err := s.repo.GetFolderUsage(ctx, folder.ID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load folder usage: %w", err)
+	}
+
+	return &FolderUsage{
+		Folder:   folder,
+		Children: children,
+		Total:    total,
+		Pending:  s.folderSizesPending(ctx, shareID),
+	}, nil
+}
+
+// folderSizesPending reports whether changes to a share are waiting for the next recompute
+func (s *Service) folderSizesPending(ctx context.Context, shareID string) bool {
+	pending, err := s.redisClient.SIsMember(ctx, folderSizesStaleKey, shareID)
+	return err == nil && pending
+}
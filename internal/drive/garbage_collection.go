@@ -0,0 +1,264 @@
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// JOB_TYPE_GARBAGE_COLLECTION deletes stored blocks no row refers to, block rows whose revision was
+// deleted and items whose share or volume is gone
+const JOB_TYPE_GARBAGE_COLLECTION = "drive.garbage_collection"
+
+const (
+	// GC_BATCH_SIZE is how many dangling rows are removed per batch
+	GC_BATCH_SIZE = 500
+	// ORPHAN_SCAN_PAGES bounds how many listing pages of the bucket one run checks for orphaned blocks
+	ORPHAN_SCAN_PAGES = 20
+	// ORPHAN_SCAN_PAGE_SIZE is how many objects are listed per page of the orphan scan
+	ORPHAN_SCAN_PAGE_SIZE = 1000
+)
+
+// orphanScanCursorKey holds the key the orphan scan of the bucket continues after on the next run
+const orphanScanCursorKey = "storage_orphan_scan_cursor"
+
+// gcReportKey holds the outcome of the last garbage collection and the totals of every run
+const gcReportKey = "storage_gc_report"
+
+// GarbageCollectionStats counts what garbage collection removed
+type GarbageCollectionStats struct {
+	OrphanedObjects int64 `json:"orphanedObjects"`
+	DanglingBlocks  int64 `json:"danglingBlocks"`
+	DanglingItems   int64 `json:"danglingItems"`
+	ReclaimedBytes  int64 `json:"reclaimedBytes"`
+}
+
+// GarbageCollectionReport is the outcome of the last garbage collection and the totals of every run
+type GarbageCollectionReport struct {
+	LastRunAt int64                  `json:"lastRunAt"`
+	LastRun   GarbageCollectionStats `json:"lastRun"`
+	Total     GarbageCollectionStats `json:"total"`
+}
+
+// StartGarbageCollectionScheduler queues a garbage collection job every interval until ctx is
+// cancelled. Intervals in which storage is unreachable are skipped.
+func (s *Service) StartGarbageCollectionScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.ScheduleWhen(ctx, "garbage_collection_pass", s.gc.interval, JOB_TYPE_GARBAGE_COLLECTION, nil, s.storageReachable)
+}
+
+// runGarbageCollectionJob removes dangling rows before looking for orphaned objects, so the objects of
+// the rows it removed are already gone by the time the bucket is scanned. What was removed is added
+// to the metrics and the report whether or not the run completes.
+func (s *Service) runGarbageCollectionJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if s.storage == nil {
+		return ErrStorageUnavailable
+	}
+
+	stats := &GarbageCollectionStats{}
+	defer s.recordGarbageCollection(context.WithoutCancel(ctx), stats)
+
+	report := func() map[string]int64 {
+		return map[string]int64{
+			"danglingItems":   job.Result["danglingItems"] + stats.DanglingItems,
+			"danglingBlocks":  job.Result["danglingBlocks"] + stats.DanglingBlocks,
+			"orphanedObjects": job.Result["orphanedObjects"] + stats.OrphanedObjects,
+			"reclaimedBytes":  job.Result["reclaimedBytes"] + stats.ReclaimedBytes,
+		}
+	}
+
+	if err := s.collectDanglingItems(ctx, stats); err != nil {
+		return err
+	}
+	progress(1, 3, report())
+
+	if err := s.collectDanglingBlocks(ctx, stats); err != nil {
+		return err
+	}
+	progress(2, 3, report())
+
+	if err := s.collectOrphanedObjects(ctx, stats); err != nil {
+		return err
+	}
+	progress(3, 3, report())
+
+	return nil
+}
+
+// collectDanglingItems purges items whose share or volume no longer exists, with everything stored for
+// them. Nobody can be charged for them anymore, so no storage is released.
+func (s *Service) collectDanglingItems(ctx context.Context, stats *GarbageCollectionStats) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		itemIDs, err := s.repo.GetDanglingItemIDs(ctx, GC_BATCH_SIZE)
+		if err != nil {
+			return fmt.Errorf("failed to load dangling items: %w", err)
+		}
+		if len(itemIDs) == 0 {
+			return nil
+		}
+
+		purged, err := s.repo.PurgeItems(ctx, itemIDs)
+		if err != nil {
+			return fmt.Errorf("failed to purge dangling items: %w", err)
+		}
+		s.deleteStoredObjects(ctx, purged.StoragePaths)
+
+		stats.DanglingItems += purged.ItemCount
+		stats.ReclaimedBytes += purged.FileBytes
+		metrics.StorageGCRemoved.Add(float64(purged.ItemCount), metrics.GC_DANGLING_ITEM)
+		metrics.StorageGCReclaimedBytes.Add(float64(purged.FileBytes), metrics.GC_DANGLING_ITEM)
+	}
+}
+
+// collectDanglingBlocks deletes blocks whose revision no longer exists, with their stored objects
+func (s *Service) collectDanglingBlocks(ctx context.Context, stats *GarbageCollectionStats) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		blocks, err := s.repo.GetDanglingBlocks(ctx, GC_BATCH_SIZE)
+		if err != nil {
+			return fmt.Errorf("failed to load dangling blocks: %w", err)
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+
+		blockIDs := make([]string, len(blocks))
+		paths := make([]string, 0, len(blocks))
+		var bytes int64
+		for i, block := range blocks {
+			blockIDs[i] = block.ID
+			if block.StoragePath != "" {
+				paths = append(paths, block.StoragePath)
+			}
+			bytes += block.Size
+		}
+
+		if err := s.repo.DeleteBlocks(ctx, blockIDs); err != nil {
+			return fmt.Errorf("failed to delete dangling blocks: %w", err)
+		}
+		s.deleteStoredObjects(ctx, paths)
+
+		stats.DanglingBlocks += int64(len(blocks))
+		stats.ReclaimedBytes += bytes
+		metrics.StorageGCRemoved.Add(float64(len(blocks)), metrics.GC_DANGLING_BLOCK)
+		metrics.StorageGCReclaimedBytes.Add(float64(bytes), metrics.GC_DANGLING_BLOCK)
+	}
+}
+
+// collectOrphanedObjects checks the next pages of the bucket for stored blocks no row refers to, which
+// a purge that could not reach storage leaves behind, and deletes the ones older than the grace period.
+// Younger blocks may belong to an upload or copy whose rows are still being written. The scan continues
+// from where the previous run stopped and starts over after the last page.
+func (s *Service) collectOrphanedObjects(ctx context.Context, stats *GarbageCollectionStats) error {
+	cursor, err := s.redisClient.Get(ctx, orphanScanCursorKey)
+	if err != nil {
+		cursor = ""
+	}
+
+	graceCutoff := time.Now().Add(-s.gc.orphanGrace)
+
+	for page := 0; page < ORPHAN_SCAN_PAGES; page++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		objects, next, err := s.storage.ListObjectsPage(ctx, storagePrefix, cursor, ORPHAN_SCAN_PAGE_SIZE)
+		if err != nil {
+			return fmt.Errorf("failed to list stored objects: %w", err)
+		}
+
+		sizes := make(map[string]int64, len(objects))
+		paths := make([]string, 0, len(objects))
+		for _, object := range objects {
+			if strings.Contains(object.Key, "/files/") && strings.Contains(object.Key, "/block_") && object.LastModified.Before(graceCutoff) {
+				paths = append(paths, object.Key)
+				sizes[object.Key] = object.Size
+			}
+		}
+
+		if len(paths) > 0 {
+			known, err := s.repo.GetExistingBlockPaths(ctx, paths)
+			if err != nil {
+				return fmt.Errorf("failed to look up stored blocks: %w", err)
+			}
+
+			for _, path := range paths {
+				if known[path] {
+					continue
+				}
+				if err := s.storage.DeleteObject(ctx, path); err != nil {
+					s.logger.Warnf("Failed to delete orphaned block %s: %v", path, err)
+					continue
+				}
+
+				stats.OrphanedObjects++
+				stats.ReclaimedBytes += sizes[path]
+				metrics.StorageGCRemoved.Inc(metrics.GC_ORPHANED_OBJECT)
+				metrics.StorageGCReclaimedBytes.Add(float64(sizes[path]), metrics.GC_ORPHANED_OBJECT)
+			}
+		}
+
+		cursor = next
+		if err := s.redisClient.Set(ctx, orphanScanCursorKey, cursor, 0); err != nil {
+			s.logger.Warnf("Failed to save orphan scan cursor: %v", err)
+		}
+		if cursor == "" {
+			break
+		}
+	}
+
+	return nil
+}
+
+// recordGarbageCollection stores the outcome of a run in the report. Only one run is queued per
+// interval, so runs do not update the report concurrently.
+func (s *Service) recordGarbageCollection(ctx context.Context, stats *GarbageCollectionStats) {
+	report, err := s.GetGarbageCollectionReport(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to load garbage collection report: %v", err)
+		return
+	}
+
+	report.LastRunAt = time.Now().Unix()
+	report.LastRun = *stats
+	report.Total.OrphanedObjects += stats.OrphanedObjects
+	report.Total.DanglingBlocks += stats.DanglingBlocks
+	report.Total.DanglingItems += stats.DanglingItems
+	report.Total.ReclaimedBytes += stats.ReclaimedBytes
+
+	if err := s.redisClient.SetJSON(ctx, gcReportKey, report, 0); err != nil {
+		s.logger.Errorf("Failed to save garbage collection report: %v", err)
+	}
+
+	if stats.OrphanedObjects+stats.DanglingBlocks+stats.DanglingItems > 0 {
+		s.logger.Infof("Garbage collection removed %d orphaned objects, %d dangling blocks and %d dangling items, reclaiming %d bytes",
+			stats.OrphanedObjects, stats.DanglingBlocks, stats.DanglingItems, stats.ReclaimedBytes)
+	}
+}
+
+// GetGarbageCollectionReport returns the outcome of the last garbage collection and the totals of
+// every run. The report is empty until the first run.
+func (s *Service) GetGarbageCollectionReport(ctx context.Context) (*GarbageCollectionReport, error) {
+	report := &GarbageCollectionReport{}
+	if err := s.redisClient.GetJSON(ctx, gcReportKey, report); err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, err
+	}
+	return report, nil
+}
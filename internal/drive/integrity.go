@@ -0,0 +1,235 @@
+// internal/drive/integrity.go
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/s3"
+	"context"
+	"errors"
+	"time"
+)
+
+// JOB_TYPE_INTEGRITY_AUDIT samples stored file blocks and compares them to their database records
+const JOB_TYPE_INTEGRITY_AUDIT = "drive.integrity_audit"
+
+// Storage integrity issue kinds and states
+const (
+	INTEGRITY_ISSUE_MISSING           = "missing"
+	INTEGRITY_ISSUE_SIZE_MISMATCH     = "size_mismatch"
+	INTEGRITY_ISSUE_CHECKSUM_MISMATCH = "checksum_mismatch"
+
+	INTEGRITY_ISSUE_STATE_OPEN     = "open"
+	INTEGRITY_ISSUE_STATE_RESOLVED = "resolved"
+
+	// INTEGRITY_NOTIFY_BATCH_SIZE bounds how many irrecoverable issues one pass notifies owners about
+	INTEGRITY_NOTIFY_BATCH_SIZE = 500
+
+	// INTEGRITY_REPORT_WINDOW is how far back the report counts resolved issues
+	INTEGRITY_REPORT_WINDOW = 30 * 24 * time.Hour
+)
+
+// StartIntegrityAuditScheduler queues an integrity audit job every interval until ctx is cancelled
+func (s *Service) StartIntegrityAuditScheduler(ctx context.Context) {
+	if s.integrity.sampleSize <= 0 {
+		s.logger.Info("Storage integrity audit disabled")
+		return
+	}
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.Schedule(ctx, "storage_integrity_audit", s.integrity.interval, JOB_TYPE_INTEGRITY_AUDIT, nil)
+}
+
+// runIntegrityAuditJob audits one sample of revisions. Issues are recorded per block and resolved
+// once a block checks out again, so running it again only draws a fresh sample.
+func (s *Service) runIntegrityAuditJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if s.storage == nil {
+		return ErrStorageUnavailable
+	}
+
+	// Recheck known problems first so fixed blocks get resolved, then a fresh random sample
+	revisions, err := s.repo.GetRevisionsWithOpenIntegrityIssues(ctx, s.integrity.sampleSize)
+	if err != nil {
+		s.logger.Errorf("Failed to load revisions with open integrity issues: %v", err)
+	}
+	sampled, err := s.repo.SampleActiveRevisions(ctx, s.integrity.sampleSize)
+	if err != nil {
+		s.logger.Errorf("Failed to sample revisions for integrity audit: %v", err)
+	}
+	revisions = append(revisions, sampled...)
+
+	checked, found := 0, 0
+	seen := make(map[string]bool)
+	owners := make(map[string]string)
+	for _, revision := range revisions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if seen[revision.ID] {
+			continue
+		}
+		seen[revision.ID] = true
+
+		blocks, issues := s.auditRevision(ctx, revision, owners)
+		checked += blocks
+		found += issues
+	}
+
+	if found > 0 {
+		s.logger.Warnf("Integrity audit checked %d blocks of %d revisions, found %d discrepancies", checked, len(seen), found)
+	} else {
+		s.logger.Infof("Integrity audit checked %d blocks of %d revisions", checked, len(seen))
+	}
+
+	s.notifyIrrecoverableIssues(ctx)
+
+	return ctx.Err()
+}
+
+// auditRevision checks every uploaded block of a revision against storage. It returns how many
+// blocks were checked and how many discrepancies were recorded.
+func (s *Service) auditRevision(ctx context.Context, revision *models.FileRevision, owners map[string]string) (int, int) {
+	ownerID, ok := owners[revision.Item.VolumeID]
+	if !ok {
+		volume, err := s.repo.GetVolumeByID(ctx, revision.Item.VolumeID)
+		if err != nil {
+			s.logger.Errorf("Failed to load volume %s for integrity audit: %v", revision.Item.VolumeID, err)
+			return 0, 0
+		}
+		ownerID = volume.UserID
+		owners[revision.Item.VolumeID] = ownerID
+	}
+
+	now := time.Now().Unix()
+	checked, found := 0, 0
+	var intact []string
+	for i := range revision.Blocks {
+		block := &revision.Blocks[i]
+
+		info, err := s.storage.HeadObject(ctx, block.StoragePath)
+		if err != nil && !errors.Is(err, s3.ErrObjectNotFound) {
+			// Storage being unreachable says nothing about the block
+			s.logger.Errorf("Failed to check block %s: %v", block.ID, err)
+			continue
+		}
+		checked++
+
+		issue := blockIntegrityIssue(block, info)
+		if issue == nil {
+			intact = append(intact, block.ID)
+			continue
+		}
+
+		issue.RevisionID = revision.ID
+		issue.ItemID = revision.ItemID
+		issue.VolumeID = revision.Item.VolumeID
+		issue.UserID = ownerID
+		issue.LastCheckedAt = now
+		if err := s.repo.RecordIntegrityIssue(ctx, issue); err != nil {
+			s.logger.Errorf("Failed to record integrity issue of block %s: %v", block.ID, err)
+			continue
+		}
+		found++
+	}
+
+	resolved, err := s.repo.ResolveIntegrityIssues(ctx, intact, now)
+	if err != nil {
+		s.logger.Errorf("Failed to resolve integrity issues of revision %s: %v", revision.ID, err)
+	} else if resolved > 0 {
+		s.logger.Infof("Resolved %d integrity issues of revision %s", resolved, revision.ID)
+	}
+
+	return checked, found
+}
+
+// blockIntegrityIssue compares a block to its stored object, which is nil if the object is missing.
+// The checksum is only compared when both sides recorded one. It returns nil if they match.
+func blockIntegrityIssue(block *models.FileBlock, info *s3.ObjectInfo) *models.StorageIntegrityIssue {
+	issue := &models.StorageIntegrityIssue{
+		BlockID:          block.ID,
+		StoragePath:      block.StoragePath,
+		ExpectedSize:     block.Size,
+		ExpectedChecksum: block.ChecksumSHA256,
+		Recoverable:      block.ReplicationRegion != "",
+		State:            INTEGRITY_ISSUE_STATE_OPEN,
+	}
+
+	switch {
+	case info == nil:
+		issue.Kind = INTEGRITY_ISSUE_MISSING
+		return issue
+	case info.Size != block.Size:
+		issue.Kind = INTEGRITY_ISSUE_SIZE_MISMATCH
+	case block.ChecksumSHA256 != "" && info.ChecksumSHA256 != "" && info.ChecksumSHA256 != block.ChecksumSHA256:
+		issue.Kind = INTEGRITY_ISSUE_CHECKSUM_MISMATCH
+	default:
+		return nil
+	}
+
+	issue.ActualSize = info.Size
+	issue.ActualChecksum = info.ChecksumSHA256
+	issue.ETag = info.ETag
+	return issue
+}
+
+// notifyIrrecoverableIssues emails owners once about blocks that no replica can restore
+func (s *Service) notifyIrrecoverableIssues(ctx context.Context) {
+	issues, err := s.repo.GetUnnotifiedIrrecoverableIssues(ctx, INTEGRITY_NOTIFY_BATCH_SIZE)
+	if err != nil {
+		s.logger.Errorf("Failed to load irrecoverable integrity issues: %v", err)
+		return
+	}
+	if len(issues) == 0 {
+		return
+	}
+
+	issueIDs := make(map[string][]string)
+	files := make(map[string]map[string]bool)
+	for _, issue := range issues {
+		issueIDs[issue.UserID] = append(issueIDs[issue.UserID], issue.ID)
+		if files[issue.UserID] == nil {
+			files[issue.UserID] = make(map[string]bool)
+		}
+		files[issue.UserID][issue.ItemID] = true
+	}
+
+	now := time.Now().Unix()
+	for userID, ids := range issueIDs {
+		// Mark first so a crash can at worst skip a notice, never send it twice
+		if err := s.repo.MarkIntegrityIssuesNotified(ctx, ids, now); err != nil {
+			s.logger.Errorf("Failed to mark integrity issues of user %s notified: %v", userID, err)
+			continue
+		}
+		if s.mailer == nil {
+			continue
+		}
+
+		user, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			s.logger.Errorf("Failed to load user %s for corruption notice: %v", userID, err)
+			continue
+		}
+		if err := s.mailer.SendStorageCorruptionEmail(user.Email, len(files[userID])); err != nil {
+			s.logger.Errorf("Failed to send corruption notice to user %s: %v", userID, err)
+		}
+	}
+}
+
+// GetIntegrityReport summarizes storage integrity issues and lists those matching the filter
+func (s *Service) GetIntegrityReport(ctx context.Context, filter IntegrityFilter) (*IntegrityReport, error) {
+	if filter.State != "" && filter.State != INTEGRITY_ISSUE_STATE_OPEN && filter.State != INTEGRITY_ISSUE_STATE_RESOLVED {
+		return nil, ErrInvalidIntegrityState
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	since := time.Now().Add(-INTEGRITY_REPORT_WINDOW).Unix()
+	report, err := s.repo.GetIntegrityReport(ctx, filter, since)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,117 @@
+// internal/drive/invitation.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+)
+
+// InvitationMailer delivers share invitation emails, membership approval requests, share expiry notices
+// and storage corruption notices
+type InvitationMailer interface {
+	SendShareInvitationEmail(email, inviterName, invitationID string) error
+	SendMembershipApprovalEmail(email, memberName, shareID string) error
+	SendShareExpiryEmail(email, shareID string, expiresAt int64) error
+	SendStorageCorruptionEmail(email string, fileCount int) error
+}
+
+// SetInvitationMailer configures how invitees are notified. Without a mailer invitations
+// are still created and can be accepted from the invitee's pending invitations list.
+func (s *Service) SetInvitationMailer(mailer InvitationMailer) {
+	s.mailer = mailer
+}
+
+// InviteShareMember creates a pending membership for the invitee and emails them about it.
+// The membership grants no access until the invitee accepts it.
+func (s *Service) InviteShareMember(ctx context.Context, inviter, invitee *models.User, shareID string, membership *models.DriveShareMembership) (*models.DriveShareMembership, error) {
+	membership.UserID = invitee.ID
+
+	invitation, err := s.addShareMember(ctx, inviter.ID, shareID, membership, MEMBERSHIP_STATE_PENDING)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.mailer == nil {
+		s.logger.Debugf("No invitation mailer configured, skipping email for invitation %s", invitation.ID)
+		return invitation, nil
+	}
+
+	inviterName := userDisplayName(inviter)
+
+	// Send asynchronously so a slow mail server does not hold up the request
+	go func(email, invitationID string) {
+		if err := s.mailer.SendShareInvitationEmail(email, inviterName, invitationID); err != nil {
+			s.logger.Errorf("Failed to send share invitation email for invitation %s: %v", invitationID, err)
+		}
+	}(invitee.Email, invitation.ID)
+
+	return invitation, nil
+}
+
+// GetPendingInvitations retrieves the invitations waiting for a user to accept or decline
+func (s *Service) GetPendingInvitations(ctx context.Context, userID string) ([]*models.DriveShareMembership, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return s.repo.GetMembershipsByUserIDAndState(ctx, userID, MEMBERSHIP_STATE_PENDING)
+}
+
+// AcceptInvitation activates a pending membership, granting the user access to the share.
+// On shares that require approval the membership instead waits for a share admin to approve it.
+func (s *Service) AcceptInvitation(ctx context.Context, userID, invitationID string) (*models.DriveShareMembership, error) {
+	return s.respondToInvitation(ctx, userID, invitationID, MEMBERSHIP_STATE_ACTIVE)
+}
+
+// DeclineInvitation declines a pending membership. The share can invite the user again later.
+func (s *Service) DeclineInvitation(ctx context.Context, userID, invitationID string) error {
+	_, err := s.respondToInvitation(ctx, userID, invitationID, MEMBERSHIP_STATE_DECLINED)
+	return err
+}
+
+// respondToInvitation moves one of the user's pending invitations to its final state
+func (s *Service) respondToInvitation(ctx context.Context, userID, invitationID string, state int) (*models.DriveShareMembership, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	invitation, err := s.repo.GetMembershipByID(ctx, invitationID)
+	if err != nil {
+		if errors.Is(err, ErrMembershipNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	// Invitations addressed to someone else are reported as missing
+	if invitation.UserID != userID || invitation.State != MEMBERSHIP_STATE_PENDING {
+		return nil, ErrInvitationNotFound
+	}
+
+	if state == MEMBERSHIP_STATE_ACTIVE {
+		share, err := s.GetShareByID(ctx, invitation.ShareID)
+		if err != nil {
+			return nil, err
+		}
+		if share.RequiresApproval {
+			state = MEMBERSHIP_STATE_AWAITING_APPROVAL
+		}
+	}
+
+	if err := s.repo.UpdateMembershipState(ctx, invitation.ID, state); err != nil {
+		s.logger.Errorf("Failed to update invitation %s: %v", invitation.ID, err)
+		return nil, err
+	}
+	invitation.State = state
+
+	s.invalidateMembershipCaches(ctx, invitation.ShareID, userID)
+
+	if state == MEMBERSHIP_STATE_AWAITING_APPROVAL {
+		s.notifyShareAdmins(ctx, invitation)
+	}
+
+	return invitation, nil
+}
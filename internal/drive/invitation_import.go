@@ -0,0 +1,229 @@
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JOB_TYPE_INVITATION_EMAILS emails the invitees of one batch of bulk invitations
+const JOB_TYPE_INVITATION_EMAILS = "drive.invitation_emails"
+
+// Bulk invitation limits
+const (
+	MAX_BULK_INVITATIONS        = 500
+	INVITATION_EMAIL_BATCH_SIZE = 50 // Invitations emailed by one job
+)
+
+// Permission presets bulk invitations grant instead of raw permission bits
+const (
+	PERMISSION_PRESET_VIEWER  = "viewer"
+	PERMISSION_PRESET_EDITOR  = "editor"
+	PERMISSION_PRESET_MANAGER = "manager"
+)
+
+// PermissionPresets maps each preset to the permissions it grants
+var PermissionPresets = map[string]int{
+	PERMISSION_PRESET_VIEWER:  READ_PERMISSION | EXECUTE_PERMISSION,
+	PERMISSION_PRESET_EDITOR:  RWX_PERMISSIONS,
+	PERMISSION_PRESET_MANAGER: RWX_PERMISSIONS | SHARE_PERMISSION,
+}
+
+// BulkInvitation is one row of a bulk invitation. The key packet must be encrypted for the
+// invitee's key by the inviter's client, as for a single invitation.
+type BulkInvitation struct {
+	Email               string
+	Preset              string
+	KeyPacket           string
+	KeyPacketSignature  string
+	SessionKeySignature string
+}
+
+// BulkInvitationResult is the outcome of one row of a bulk invitation. Invitation is set when the
+// row was invited, Err when it was not.
+type BulkInvitationResult struct {
+	Row        int // 1-based position in the request
+	Email      string
+	Invitation *models.DriveShareMembership
+	Err        error
+}
+
+// InviteShareMembers invites every listed user to a share, reporting each row separately so one bad
+// row does not fail the others. Invitees are emailed in batches from the job queue.
+func (s *Service) InviteShareMembers(ctx context.Context, inviterID, shareID string, invitations []BulkInvitation) ([]*BulkInvitationResult, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if len(invitations) == 0 || len(invitations) > MAX_BULK_INVITATIONS {
+		return nil, ErrInvalidBulkInvitations
+	}
+
+	// Problems with the share itself apply to every row
+	if err := s.CheckSharePermissions(ctx, inviterID, shareID, SHARE_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	inviter, err := s.repo.GetUserByID(ctx, inviterID)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make([]string, 0, len(invitations))
+	for i := range invitations {
+		invitations[i].Email = strings.ToLower(strings.TrimSpace(invitations[i].Email))
+		if invitations[i].Email != "" {
+			emails = append(emails, invitations[i].Email)
+		}
+	}
+
+	users, err := s.repo.GetUsersByEmails(ctx, emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up invitees: %w", err)
+	}
+
+	results := make([]*BulkInvitationResult, len(invitations))
+	invited := make([]string, 0, len(invitations))
+	seen := make(map[string]bool, len(invitations))
+
+	for i, row := range invitations {
+		result := &BulkInvitationResult{Row: i + 1, Email: row.Email}
+		results[i] = result
+
+		if ctx.Err() != nil {
+			result.Err = ctx.Err()
+			continue
+		}
+
+		permissions, ok := PermissionPresets[row.Preset]
+		switch {
+		case row.Email == "" || row.KeyPacket == "" || row.KeyPacketSignature == "" || row.SessionKeySignature == "":
+			result.Err = ErrInvalidInvitation
+		case seen[row.Email]:
+			result.Err = ErrDuplicateInvitation
+		case !ok:
+			result.Err = ErrInvalidPermissionPreset
+		case users[row.Email] == nil:
+			result.Err = ErrUserNotFound
+		}
+		if result.Err != nil {
+			continue
+		}
+		seen[row.Email] = true
+
+		result.Invitation, result.Err = s.addShareMember(ctx, inviterID, shareID, &models.DriveShareMembership{
+			UserID:              users[row.Email].ID,
+			Permissions:         permissions,
+			KeyPacket:           row.KeyPacket,
+			KeyPacketSignature:  row.KeyPacketSignature,
+			SessionKeySignature: row.SessionKeySignature,
+		}, MEMBERSHIP_STATE_PENDING)
+		if result.Err == nil {
+			invited = append(invited, result.Invitation.ID)
+		}
+	}
+
+	s.queueInvitationEmails(context.WithoutCancel(ctx), inviter, invited)
+
+	return results, nil
+}
+
+// queueInvitationEmails queues a job per batch of invitations to email their invitees, so a large
+// import does not flood the mail server from a single request
+func (s *Service) queueInvitationEmails(ctx context.Context, inviter *models.User, invitationIDs []string) {
+	if len(invitationIDs) == 0 {
+		return
+	}
+	if s.mailer == nil {
+		s.logger.Debugf("No invitation mailer configured, skipping email for %d invitations", len(invitationIDs))
+		return
+	}
+
+	inviterName := userDisplayName(inviter)
+
+	for start := 0; start < len(invitationIDs); start += INVITATION_EMAIL_BATCH_SIZE {
+		batch := invitationIDs[start:min(start+INVITATION_EMAIL_BATCH_SIZE, len(invitationIDs))]
+
+		if s.jobService == nil {
+			go s.sendInvitationEmails(ctx, inviterName, batch, nil)
+			continue
+		}
+
+		payload := map[string]string{
+			"inviterName":   inviterName,
+			"invitationIds": strings.Join(batch, ","),
+		}
+		if _, err := s.jobService.Enqueue(ctx, inviter.ID, JOB_TYPE_INVITATION_EMAILS, payload); err != nil {
+			s.logger.Errorf("Failed to queue emails for %d invitations: %v", len(batch), err)
+		}
+	}
+}
+
+// runInvitationEmailsJob emails the invitees of one batch. Failed emails are logged rather than
+// retried, since retrying the job would email the rest of the batch twice.
+func (s *Service) runInvitationEmailsJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if s.mailer == nil {
+		return nil
+	}
+
+	invitationIDs := strings.Split(job.Payload["invitationIds"], ",")
+	s.sendInvitationEmails(ctx, job.Payload["inviterName"], invitationIDs, progress)
+
+	return ctx.Err()
+}
+
+// sendInvitationEmails emails the invitees of invitations that are still pending
+func (s *Service) sendInvitationEmails(ctx context.Context, inviterName string, invitationIDs []string, progress jobs.ProgressFunc) {
+	result := map[string]int64{"sent": 0, "failed": 0}
+
+	for i, invitationID := range invitationIDs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := s.sendInvitationEmail(ctx, inviterName, invitationID); err != nil {
+			s.logger.Errorf("Failed to send share invitation email for invitation %s: %v", invitationID, err)
+			result["failed"]++
+		} else {
+			result["sent"]++
+		}
+
+		if progress != nil {
+			progress(int64(i+1), int64(len(invitationIDs)), result)
+		}
+	}
+}
+
+// sendInvitationEmail emails the invitee of one invitation, skipping invitations answered or
+// withdrawn since they were created
+func (s *Service) sendInvitationEmail(ctx context.Context, inviterName, invitationID string) error {
+	invitation, err := s.repo.GetMembershipByID(ctx, invitationID)
+	if errors.Is(err, ErrMembershipNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if invitation.State != MEMBERSHIP_STATE_PENDING {
+		return nil
+	}
+
+	invitee, err := s.repo.GetUserByID(ctx, invitation.UserID)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.SendShareInvitationEmail(invitee.Email, inviterName, invitation.ID)
+}
+
+// userDisplayName returns the name a user is shown as to others
+func userDisplayName(user *models.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Username
+}
@@ -0,0 +1,298 @@
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"context"
+	"time"
+)
+
+const (
+	// Key rotation states
+	KEY_ROTATION_STATE_IN_PROGRESS = 1
+	KEY_ROTATION_STATE_COMPLETED   = 2
+	KEY_ROTATION_STATE_SUPERSEDED  = 3
+
+	// MAX_KEY_ROTATION_ITEMS bounds how many node passphrases one key rotation batch re-wraps
+	MAX_KEY_ROTATION_ITEMS = 100
+)
+
+// KeyRotationStatus is a key rotation with the progress of each of its shares
+type KeyRotationStatus struct {
+	Rotation *models.DriveKeyRotation
+	Shares   []*KeyRotationShareStatus
+}
+
+// KeyRotationShareStatus is the progress of a key rotation in one share
+type KeyRotationShareStatus struct {
+	Progress       *models.DriveKeyRotationShare
+	ItemsRemaining int64
+}
+
+// RotatedNodePassphrase is an item's node passphrase re-wrapped for the owner's new key
+type RotatedNodePassphrase struct {
+	LinkID                  string
+	NodePassphrase          string
+	NodePassphraseSignature string
+}
+
+// KeyRotationBatch is the next part of a share re-wrapped for the owner's new key: the share
+// passphrase with the owner's key packet, the node passphrases of the next pending items, or both
+type KeyRotationBatch struct {
+	ShareKeys *ShareUnlockKeys
+	Items     []RotatedNodePassphrase
+}
+
+// StartKeyRotation starts moving the user's shares to their primary key, or returns the rotation in
+// progress when it already targets that key. A rotation for an older key is superseded, since its
+// passphrases would be re-wrapped for a key the user replaced.
+func (s *Service) StartKeyRotation(ctx context.Context, userID string) (*KeyRotationStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	keyID, err := s.repo.GetPrimaryUserKeyID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetLatestKeyRotation(ctx, userID)
+	if err != nil && err != ErrKeyRotationNotFound {
+		return nil, err
+	}
+	if current != nil && current.State == KEY_ROTATION_STATE_IN_PROGRESS && current.KeyID == keyID {
+		return s.keyRotationStatus(ctx, current)
+	}
+
+	shares, err := s.repo.GetSharesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	shareIDs := make([]string, len(shares))
+	for i, share := range shares {
+		shareIDs[i] = share.ID
+	}
+
+	rotation := &models.DriveKeyRotation{
+		UserID: userID,
+		KeyID:  keyID,
+		State:  KEY_ROTATION_STATE_IN_PROGRESS,
+	}
+	if len(shareIDs) == 0 {
+		now := time.Now().Unix()
+		rotation.State = KEY_ROTATION_STATE_COMPLETED
+		rotation.CompletedAt = &now
+	}
+	if err := s.repo.CreateKeyRotation(ctx, rotation, shareIDs); err != nil {
+		s.logger.Errorf("Failed to start key rotation of user %s: %v", userID, err)
+		return nil, err
+	}
+
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_KEY_ROTATION_STARTED,
+		Success:   true,
+		Metadata: map[string]any{
+			"rotationId": rotation.ID,
+			"keyId":      keyID,
+			"shares":     len(shareIDs),
+		},
+	})
+
+	return s.keyRotationStatus(ctx, rotation)
+}
+
+// GetKeyRotationStatus returns the user's latest key rotation with the progress of each share
+func (s *Service) GetKeyRotationStatus(ctx context.Context, userID string) (*KeyRotationStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	rotation, err := s.repo.GetLatestKeyRotation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.keyRotationStatus(ctx, rotation)
+}
+
+// keyRotationStatus loads the progress of each share of a rotation
+func (s *Service) keyRotationStatus(ctx context.Context, rotation *models.DriveKeyRotation) (*KeyRotationStatus, error) {
+	progress, err := s.repo.GetKeyRotationShares(ctx, rotation.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := s.repo.CountKeyRotationItemsRemaining(ctx, rotation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &KeyRotationStatus{Rotation: rotation, Shares: make([]*KeyRotationShareStatus, len(progress))}
+	for i, shareProgress := range progress {
+		status.Shares[i] = &KeyRotationShareStatus{Progress: shareProgress}
+		if shareProgress.CompletedAt == nil {
+			status.Shares[i].ItemsRemaining = remaining[shareProgress.ShareID]
+		}
+	}
+
+	return status, nil
+}
+
+// GetKeyRotationItems returns the next items of a share whose node passphrases the rotation in
+// progress has not re-wrapped yet, in the order they must be submitted
+func (s *Service) GetKeyRotationItems(ctx context.Context, userID, shareID string, limit int) ([]*models.DriveItem, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	_, progress, err := s.activeKeyRotationShare(ctx, userID, shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetItemsAfterID(ctx, shareID, progress.ItemCursor, min(limit, MAX_KEY_ROTATION_ITEMS))
+}
+
+// RotateShareKeys stores the next part of a share re-wrapped for the owner's new key. Items must be
+// exactly the next pending ones returned by GetKeyRotationItems; when items were added since, the
+// batch is refused and the client fetches them again. Re-wrapping the share passphrase also unlocks
+// a share locked when the key was replaced. The rotation completes with its last share.
+func (s *Service) RotateShareKeys(ctx context.Context, userID, shareID string, batch KeyRotationBatch) (*KeyRotationShareStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if batch.ShareKeys == nil && len(batch.Items) == 0 {
+		return nil, ErrInvalidRotationBatch
+	}
+	if len(batch.Items) > MAX_KEY_ROTATION_ITEMS {
+		return nil, ErrTooManyItems
+	}
+	if keys := batch.ShareKeys; keys != nil && (keys.SharePassphrase == "" || keys.SharePassphraseSignature == "" ||
+		keys.OwnerKeyPacket == "" || keys.OwnerKeyPacketSignature == "") {
+		return nil, ErrInvalidRotationBatch
+	}
+	for _, item := range batch.Items {
+		if item.LinkID == "" || item.NodePassphrase == "" || item.NodePassphraseSignature == "" {
+			return nil, ErrInvalidRotationBatch
+		}
+	}
+
+	rotation, progress, err := s.activeKeyRotationShare(ctx, userID, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if progress.CompletedAt != nil {
+		return nil, ErrShareAlreadyRotated
+	}
+
+	share, err := s.repo.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The batch must cover the pending items in order, so the cursor never skips one
+	var items []*models.DriveItem
+	if len(batch.Items) > 0 {
+		items, err = s.repo.GetItemsAfterID(ctx, shareID, progress.ItemCursor, len(batch.Items))
+		if err != nil {
+			return nil, err
+		}
+		if len(items) != len(batch.Items) {
+			return nil, ErrStaleRotationBatch
+		}
+		pending := make(map[string]bool, len(items))
+		for _, item := range items {
+			pending[item.ID] = true
+		}
+		for _, item := range batch.Items {
+			if !pending[item.LinkID] {
+				return nil, ErrStaleRotationBatch
+			}
+			delete(pending, item.LinkID)
+		}
+	}
+
+	cursor := progress.ItemCursor
+	if len(items) > 0 {
+		cursor = items[len(items)-1].ID
+	}
+	if err := s.repo.ApplyKeyRotationBatch(ctx, share, progress, batch, cursor); err != nil {
+		if err != ErrStaleRotationBatch {
+			s.logger.Errorf("Failed to rotate keys of share %s: %v", shareID, err)
+		}
+		return nil, err
+	}
+
+	if batch.ShareKeys != nil {
+		s.invalidateShareMembershipCaches(ctx, shareID)
+		s.invalidateUserCaches(ctx, userID)
+		if rootFolder, err := s.repo.GetRootFolderByShareID(ctx, shareID); err == nil {
+			s.recordEvents(ctx, EVENT_TYPE_UPDATE, rootFolder)
+		}
+	}
+	if len(items) > 0 {
+		linkIDs := make([]string, len(items))
+		parents := make(map[string]bool)
+		for i, item := range items {
+			linkIDs[i] = item.ID
+			if item.ParentID != nil {
+				parents[*item.ParentID] = true
+			}
+		}
+		s.invalidateBatchCaches(ctx, linkIDs, parents)
+		s.recordEvents(ctx, EVENT_TYPE_UPDATE, items...)
+	}
+
+	remaining, err := s.repo.CountKeyRotationItemsRemaining(ctx, rotation.ID)
+	if err != nil {
+		return nil, err
+	}
+	status := &KeyRotationShareStatus{Progress: progress, ItemsRemaining: remaining[shareID]}
+
+	if progress.CompletedAt != nil {
+		s.completeKeyRotation(ctx, rotation)
+	}
+
+	return status, nil
+}
+
+// activeKeyRotationShare returns the user's rotation in progress and its progress in a share
+func (s *Service) activeKeyRotationShare(ctx context.Context, userID, shareID string) (*models.DriveKeyRotation, *models.DriveKeyRotationShare, error) {
+	rotation, err := s.repo.GetLatestKeyRotation(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rotation.State != KEY_ROTATION_STATE_IN_PROGRESS {
+		return nil, nil, ErrKeyRotationNotFound
+	}
+
+	progress, err := s.repo.GetKeyRotationShare(ctx, rotation.ID, shareID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rotation, progress, nil
+}
+
+// completeKeyRotation marks a rotation completed once none of its shares that still exist are pending
+func (s *Service) completeKeyRotation(ctx context.Context, rotation *models.DriveKeyRotation) {
+	completed, err := s.repo.CompleteKeyRotation(ctx, rotation.ID, time.Now().Unix())
+	if err != nil {
+		s.logger.Errorf("Failed to complete key rotation %s: %v", rotation.ID, err)
+		return
+	}
+	if !completed {
+		return
+	}
+
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    rotation.UserID,
+		EventType: security.EVENT_KEY_ROTATION_COMPLETED,
+		Success:   true,
+		Metadata: map[string]any{
+			"rotationId": rotation.ID,
+			"keyId":      rotation.KeyID,
+		},
+	})
+}
@@ -0,0 +1,88 @@
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/tracing"
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MAX_BATCH_LINKS bounds how many links one batch lookup resolves
+const MAX_BATCH_LINKS = 200
+
+// LinkBatch sorts the links of a batch lookup by outcome. Links of shares that do not exist are
+// reported missing, like links that do not exist, so the lookup does not reveal them.
+type LinkBatch struct {
+	Found     map[string]*models.DriveItem
+	Missing   []string
+	Forbidden []string // Links in shares the user cannot read
+}
+
+// BatchGetLinks resolves many links in one query, checking the user's read permission once per share
+func (s *Service) BatchGetLinks(ctx context.Context, linkIDs []string, userID string) (*LinkBatch, error) {
+	ctx, span := tracing.Start(ctx, "drive.BatchGetLinks", attribute.Int("drive.link_count", len(linkIDs)))
+	defer span.End()
+
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if len(linkIDs) > MAX_BATCH_LINKS {
+		return nil, ErrTooManyItems
+	}
+
+	// Deduplicate IDs before querying
+	uniqueIDs := make([]string, 0, len(linkIDs))
+	seen := make(map[string]bool, len(linkIDs))
+	for _, linkID := range linkIDs {
+		if linkID != "" && !seen[linkID] {
+			seen[linkID] = true
+			uniqueIDs = append(uniqueIDs, linkID)
+		}
+	}
+
+	items, err := s.repo.BatchGetLinksByIDs(ctx, uniqueIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every share is checked once, however many of the links it holds
+	readable := make(map[string]error)
+	for _, item := range items {
+		if _, checked := readable[item.ShareID]; checked {
+			continue
+		}
+		err := s.CheckSharePermissions(ctx, userID, item.ShareID, READ_PERMISSION)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		readable[item.ShareID] = err
+	}
+
+	batch := &LinkBatch{
+		Found:     make(map[string]*models.DriveItem, len(items)),
+		Missing:   []string{},
+		Forbidden: []string{},
+	}
+	for _, linkID := range uniqueIDs {
+		item, ok := items[linkID]
+		if !ok {
+			batch.Missing = append(batch.Missing, linkID)
+			continue
+		}
+
+		switch err := readable[item.ShareID]; {
+		case err == nil:
+			batch.Found[linkID] = item
+		case errors.Is(err, ErrShareNotFound):
+			batch.Missing = append(batch.Missing, linkID)
+		default:
+			batch.Forbidden = append(batch.Forbidden, linkID)
+		}
+	}
+
+	return batch, nil
+}
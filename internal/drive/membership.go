@@ -0,0 +1,307 @@
+// internal/drive/membership.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Share membership states
+const (
+	MEMBERSHIP_STATE_ACTIVE             = 1
+	MEMBERSHIP_STATE_PENDING            = 2 // Invited, waiting for the invitee
+	MEMBERSHIP_STATE_DECLINED           = 3
+	MEMBERSHIP_STATE_AWAITING_APPROVAL  = 4 // Accepted, waiting for a share admin
+	MEMBERSHIP_STATE_REJECTED           = 5
+	MEMBERSHIP_STATE_EXPIRED            = 6 // Lost access when the share expired, restored when it is renewed
+	MEMBERSHIP_STATE_PERMISSION_EXPIRED = 7 // Lost access when the membership's own permissions expired
+)
+
+// AddShareMember grants a user access to a share, until the membership's permission expiry if it has one.
+// The inviter needs share permission and cannot grant permissions they do not hold themselves.
+// The key packet must already be encrypted for the new member by the inviter's client.
+func (s *Service) AddShareMember(ctx context.Context, inviterID, shareID string, membership *models.DriveShareMembership) (*models.DriveShareMembership, error) {
+	return s.addShareMember(ctx, inviterID, shareID, membership, MEMBERSHIP_STATE_ACTIVE)
+}
+
+// addShareMember creates a membership in the given state after checking the inviter's permissions.
+// A previously declined, rejected or permission-expired membership is reused so the user can be
+// invited again.
+func (s *Service) addShareMember(ctx context.Context, inviterID, shareID string, membership *models.DriveShareMembership, state int) (*models.DriveShareMembership, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if membership.PermissionExpiresAt != nil && *membership.PermissionExpiresAt <= time.Now().Unix() {
+		return nil, ErrInvalidExpiry
+	}
+
+	if err := s.CheckSharePermissions(ctx, inviterID, shareID, SHARE_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.UserID == share.UserID {
+		return nil, ErrMembershipAlreadyExists
+	}
+
+	// Owners hold every permission, members only what their own membership grants
+	inviterPermissions, err := s.getGrantablePermissions(ctx, share, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.Permissions&^inviterPermissions != 0 {
+		return nil, ErrInsufficientPermissions
+	}
+
+	existing, err := s.repo.GetMembershipAnyState(ctx, shareID, membership.UserID)
+	if err != nil && !errors.Is(err, ErrMembershipNotFound) {
+		return nil, fmt.Errorf("failed to check existing membership: %w", err)
+	}
+	if existing != nil && existing.State != MEMBERSHIP_STATE_DECLINED && existing.State != MEMBERSHIP_STATE_REJECTED &&
+		existing.State != MEMBERSHIP_STATE_PERMISSION_EXPIRED {
+		return nil, ErrMembershipAlreadyExists
+	}
+
+	membership.ShareID = shareID
+	membership.MemberID = membership.UserID
+	membership.Inviter = inviterID
+	membership.State = state
+
+	if existing != nil {
+		membership.ID = existing.ID
+		membership.CreatedAt = existing.CreatedAt
+		err = s.repo.UpdateMembership(ctx, membership)
+	} else {
+		err = s.repo.CreateMembership(ctx, membership)
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to add member to share %s: %v", shareID, err)
+		return nil, ErrMembershipCreation
+	}
+
+	s.invalidateMembershipCaches(ctx, shareID, membership.UserID)
+	s.recordShareMembershipChange(ctx, inviterID, "granted", membership)
+
+	return membership, nil
+}
+
+// ShareTransferKeys is the share passphrase re-wrapped for the new owner's key by the client of the
+// admin transferring the share
+type ShareTransferKeys struct {
+	SharePassphrase          string
+	SharePassphraseSignature string
+}
+
+// UpdateShareMember changes the permissions of a share member. The admin cannot grant permissions they
+// do not hold themselves, and the owner's own membership cannot be changed.
+func (s *Service) UpdateShareMember(ctx context.Context, adminID, shareID, membershipID string, permissions int) (*models.DriveShareMembership, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if permissions <= 0 || permissions&^ALL_PERMISSIONS != 0 {
+		return nil, ErrInvalidPermissions
+	}
+
+	share, membership, err := s.getManagedMembership(ctx, adminID, shareID, membershipID)
+	if err != nil {
+		return nil, err
+	}
+
+	adminPermissions, err := s.getGrantablePermissions(ctx, share, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if permissions&^adminPermissions != 0 {
+		return nil, ErrInsufficientPermissions
+	}
+
+	if err := s.repo.UpdateMembershipPermissions(ctx, membership.ID, permissions); err != nil {
+		s.logger.Errorf("Failed to update permissions of membership %s: %v", membership.ID, err)
+		return nil, err
+	}
+	membership.Permissions = permissions
+
+	s.invalidateMembershipCaches(ctx, shareID, membership.UserID)
+	s.recordShareMembershipChange(ctx, adminID, "updated", membership)
+	s.recordShareRootEvent(ctx, shareID)
+
+	return membership, nil
+}
+
+// RemoveShareMember revokes a member's access to a share. Invitations that were not answered yet are
+// withdrawn the same way. The owner's own membership cannot be removed.
+func (s *Service) RemoveShareMember(ctx context.Context, adminID, shareID, membershipID string) error {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	_, membership, err := s.getManagedMembership(ctx, adminID, shareID, membershipID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteMembership(ctx, membership.ID); err != nil {
+		s.logger.Errorf("Failed to remove membership %s: %v", membership.ID, err)
+		return err
+	}
+
+	s.invalidateMembershipCaches(ctx, shareID, membership.UserID)
+	s.recordShareMembershipChange(ctx, adminID, "removed", membership)
+	s.recordShareRootEvent(ctx, shareID)
+
+	return nil
+}
+
+// TransferShareOwnership makes an active member the owner of a share. The new owner's membership is
+// granted every permission and the previous owner keeps their membership with its permissions. The
+// share stays on its volume, so its files keep counting against the volume owner's storage.
+func (s *Service) TransferShareOwnership(ctx context.Context, adminID, shareID, membershipID string, keys ShareTransferKeys) (*models.DriveShare, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if keys.SharePassphrase == "" || keys.SharePassphraseSignature == "" {
+		return nil, ErrInvalidTransferPacket
+	}
+
+	share, membership, err := s.getManagedMembership(ctx, adminID, shareID, membershipID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.State != MEMBERSHIP_STATE_ACTIVE {
+		return nil, ErrMembershipNotFound
+	}
+	if share.Type == SHARE_TYPE_BACKUP {
+		return nil, ErrShareNotTransferable
+	}
+	if share.Locked {
+		return nil, ErrShareLocked
+	}
+
+	previousOwnerID := share.UserID
+	if err := s.repo.TransferShareOwnership(ctx, share, membership.UserID, keys); err != nil {
+		if err != ErrShareOwnerChanged {
+			s.logger.Errorf("Failed to transfer ownership of share %s: %v", shareID, err)
+		}
+		return nil, err
+	}
+	share.UserID = membership.UserID
+	share.SharePassphrase = keys.SharePassphrase
+	share.SharePassphraseSignature = keys.SharePassphraseSignature
+	membership.Permissions = ALL_PERMISSIONS
+
+	s.invalidateMembershipCaches(ctx, shareID, membership.UserID)
+	s.invalidateMembershipCaches(ctx, shareID, previousOwnerID)
+
+	s.recordShareMembershipChange(ctx, adminID, "ownership_transferred", membership)
+	if adminID != previousOwnerID {
+		s.recordShareMembershipChange(ctx, previousOwnerID, "ownership_transferred", membership)
+	}
+	s.recordShareRootEvent(ctx, shareID)
+
+	return share, nil
+}
+
+// getManagedMembership checks that the admin may manage the members of a share and loads one of its
+// memberships other than the owner's. Memberships of other shares, or that were declined or
+// rejected, are reported as missing.
+func (s *Service) getManagedMembership(ctx context.Context, adminID, shareID, membershipID string) (*models.DriveShare, *models.DriveShareMembership, error) {
+	if err := s.CheckSharePermissions(ctx, adminID, shareID, ADMIN_PERMISSION); err != nil {
+		return nil, nil, err
+	}
+
+	// Read past the cache, the owner decides which membership can be managed
+	share, err := s.repo.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	membership, err := s.repo.GetMembershipByID(ctx, membershipID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if membership.ShareID != shareID ||
+		membership.State == MEMBERSHIP_STATE_DECLINED || membership.State == MEMBERSHIP_STATE_REJECTED {
+		return nil, nil, ErrMembershipNotFound
+	}
+	if membership.UserID == share.UserID {
+		return nil, nil, ErrCannotChangeOwner
+	}
+
+	return share, membership, nil
+}
+
+// getGrantablePermissions returns the permissions a user can grant on a share: every permission for
+// the owner, and what their own membership grants for members
+func (s *Service) getGrantablePermissions(ctx context.Context, share *models.DriveShare, userID string) (int, error) {
+	if share.UserID == userID {
+		return ALL_PERMISSIONS, nil
+	}
+
+	membership, err := s.GetMembershipByShareAndUserID(ctx, share.ID, userID)
+	if err != nil {
+		return 0, ErrInsufficientPermissions
+	}
+	return membership.Permissions, nil
+}
+
+// recordShareRootEvent announces a change to a share on the change feed of its volume, so clients
+// reload its members and owner
+func (s *Service) recordShareRootEvent(ctx context.Context, shareID string) {
+	rootFolder, err := s.repo.GetRootFolderByShareID(ctx, shareID)
+	if err != nil {
+		s.logger.Errorf("Failed to load root folder of share %s for a change event: %v", shareID, err)
+		return
+	}
+	s.recordEvents(ctx, EVENT_TYPE_UPDATE, rootFolder)
+}
+
+// SetSecurityEvents records share permission changes as security events of the user making them
+func (s *Service) SetSecurityEvents(events *security.Service) {
+	s.securityEvents = events
+}
+
+// recordShareMembershipChange records a change to who can access a share and with which permissions
+func (s *Service) recordShareMembershipChange(ctx context.Context, actorID, action string, membership *models.DriveShareMembership) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    actorID,
+		EventType: security.EVENT_SHARE_PERMISSION_CHANGED,
+		Success:   true,
+		Metadata: map[string]any{
+			"action":       action,
+			"shareId":      membership.ShareID,
+			"membershipId": membership.ID,
+			"memberId":     membership.UserID,
+			"permissions":  membership.Permissions,
+		},
+	})
+}
+
+// invalidateMembershipCaches invalidates caches affected by a membership being added or changing state
+func (s *Service) invalidateMembershipCaches(ctx context.Context, shareID, userID string) {
+	membershipCacheKey := fmt.Sprintf("membership:%s:%s", shareID, userID)
+	if _, err := s.redisClient.Delete(ctx, membershipCacheKey); err != nil {
+		s.logger.Errorf("Failed to delete membership cache for share %s: %v", shareID, err)
+	}
+
+	s.invalidateShareCaches(ctx, shareID)
+	s.invalidateUserCaches(ctx, userID)
+}
+
+// membershipExpired reports whether a membership is past its permission expiry
+func membershipExpired(membership *models.DriveShareMembership) bool {
+	return membership.PermissionExpiresAt != nil && *membership.PermissionExpiresAt <= time.Now().Unix()
+}
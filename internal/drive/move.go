@@ -0,0 +1,172 @@
+// internal/drive/move.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// ItemRename holds the client-encrypted name of an item under its current parent
+type ItemRename struct {
+	Name               string
+	Hash               string
+	NameSignatureEmail string
+}
+
+// ItemMove holds the data needed to re-link an item under a new parent.
+// The name and node passphrase are re-encrypted client-side with the destination folder's key.
+type ItemMove struct {
+	ParentID                string
+	Name                    string
+	Hash                    string
+	NameSignatureEmail      string
+	NodePassphrase          string
+	NodePassphraseSignature string
+	SignatureEmail          string
+}
+
+// RenameItem changes the encrypted name of an item within its folder
+func (s *Service) RenameItem(ctx context.Context, userID, shareID, linkID string, rename *ItemRename) (*models.DriveItem, error) {
+	item, share, err := s.getMovableItem(ctx, userID, shareID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == share.LinkID || item.ParentID == nil {
+		return nil, ErrCannotMoveRoot
+	}
+
+	// Re-encrypting the same name keeps its hash, which is not a conflict
+	if rename.Hash != item.Hash {
+		exists, err := s.checkNameExists(ctx, *item.ParentID, rename.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrFileNameConflict
+		}
+	}
+
+	item.Name = rename.Name
+	item.Hash = rename.Hash
+	item.NameSignatureEmail = rename.NameSignatureEmail
+
+	if err := s.repo.UpdateItemLocation(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to rename item: %w", err)
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_UPDATE, item)
+	s.invalidateMovedItemCaches(ctx, item, *item.ParentID)
+
+	return item, nil
+}
+
+// MoveItem re-links an item under another folder of the same share
+func (s *Service) MoveItem(ctx context.Context, userID, shareID, linkID string, move *ItemMove) (*models.DriveItem, error) {
+	item, share, err := s.getMovableItem(ctx, userID, shareID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == share.LinkID || item.ParentID == nil {
+		return nil, ErrCannotMoveRoot
+	}
+
+	sourceParentID := *item.ParentID
+
+	destination, err := s.repo.GetFolderByID(ctx, move.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if destination.ShareID != shareID {
+		return nil, ErrFolderNotFound
+	}
+	if destination.Type != 1 {
+		return nil, ErrNotAFolder
+	}
+	if destination.IsTrashed {
+		return nil, ErrParentInTrash
+	}
+
+	// A folder cannot be moved into itself or any of its descendants
+	if item.Type == 1 {
+		ancestors, err := s.repo.GetAncestorIDs(ctx, destination.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve destination path: %w", err)
+		}
+		if destination.ID == item.ID || slices.Contains(ancestors, item.ID) {
+			return nil, ErrInvalidMoveTarget
+		}
+	}
+
+	// Moving within the same folder under the same name is not a conflict with itself
+	if destination.ID != sourceParentID || move.Hash != item.Hash {
+		exists, err := s.checkNameExists(ctx, destination.ID, move.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrFileNameConflict
+		}
+	}
+
+	item.ParentID = &destination.ID
+	item.Name = move.Name
+	item.Hash = move.Hash
+	item.NameSignatureEmail = move.NameSignatureEmail
+	item.NodePassphrase = move.NodePassphrase
+	item.NodePassphraseSignature = move.NodePassphraseSignature
+	item.SignatureEmail = move.SignatureEmail
+
+	if err := s.repo.UpdateItemLocation(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to move item: %w", err)
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_MOVE, item)
+	s.invalidateMovedItemCaches(ctx, item, sourceParentID, destination.ID)
+
+	return item, nil
+}
+
+// getMovableItem checks write access and loads a live item of the share
+func (s *Service) getMovableItem(ctx context.Context, userID, shareID, linkID string) (*models.DriveItem, *models.DriveShare, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, WRITE_PERMISSION); err != nil {
+		return nil, nil, err
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	item, err := s.repo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if item.ShareID != shareID || item.IsTrashed || item.State == ITEM_STATE_DRAFT {
+		return nil, nil, ErrItemNotFound
+	}
+
+	return item, share, nil
+}
+
+// invalidateMovedItemCaches drops the cached item and the contents of every folder it left or entered
+func (s *Service) invalidateMovedItemCaches(ctx context.Context, item *models.DriveItem, folderIDs ...string) {
+	s.invalidateLinkCache(ctx, item.ID)
+	if item.Type == 1 {
+		s.invalidateFolderCaches(ctx, item.ID)
+		s.invalidatePathCaches(ctx, item.ShareID)
+	} else if cacheKey := s.pathCacheKey(ctx, item.ShareID, item.ID); cacheKey != "" {
+		if _, err := s.redisClient.Delete(ctx, cacheKey); err != nil {
+			s.logger.Errorf("Failed to delete path cache for link %s: %v", item.ID, err)
+		}
+	}
+	for _, folderID := range folderIDs {
+		s.invalidateFolderCaches(ctx, folderID)
+	}
+}
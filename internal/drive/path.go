@@ -0,0 +1,65 @@
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+)
+
+// ItemPath is the chain of folders from the root of a share down to an item's parent
+type ItemPath struct {
+	Item      *models.DriveItem
+	Ancestors []*models.DriveItem
+}
+
+// GetItemPath returns the folders above an item up to the root of its share, root first, so clients
+// can show breadcrumbs without walking parent IDs one request at a time
+func (s *Service) GetItemPath(ctx context.Context, userID, linkID string) (*ItemPath, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Checks read permission on the item's share
+	item, err := s.GetLinkByID(ctx, linkID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if item.IsTrashed || item.State != ITEM_STATE_ACTIVE {
+		return nil, ErrItemNotFound
+	}
+
+	cacheKey := s.pathCacheKey(ctx, item.ShareID, item.ID)
+	var ancestors []*models.DriveItem
+	if err := s.getCached(ctx, cacheKey, &ancestors); err == nil {
+		return &ItemPath{Item: item, Ancestors: ancestors}, nil
+	}
+
+	ancestors, err = s.repo.GetAncestors(ctx, item.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve item path: %w", err)
+	}
+
+	// Items below a trashed folder are in the trash with it
+	for _, ancestor := range ancestors {
+		if ancestor.IsTrashed || ancestor.State != ITEM_STATE_ACTIVE {
+			return nil, ErrItemNotFound
+		}
+	}
+
+	s.setCached(ctx, cacheKey, ancestors)
+
+	return &ItemPath{Item: item, Ancestors: ancestors}, nil
+}
+
+// pathCacheKey is the cached path of an item, versioned with the folder tree of its share so a change
+// to any folder of the share drops every path running through it
+func (s *Service) pathCacheKey(ctx context.Context, shareID, linkID string) string {
+	return s.versionedKey(ctx, fmt.Sprintf("link_path:%s:%s", shareID, linkID), cacheScope{cacheScopeTree, shareID})
+}
+
+// invalidatePathCaches drops the cached paths of every item of a share, after folders of the share
+// were renamed, moved, trashed or restored
+func (s *Service) invalidatePathCaches(ctx context.Context, shareID string) {
+	s.bumpCacheVersion(ctx, cacheScope{cacheScopeTree, shareID})
+}
@@ -0,0 +1,81 @@
+// internal/drive/qrcode.go
+package drive
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// QR code output formats
+const (
+	QR_FORMAT_PNG = "png"
+	QR_FORMAT_SVG = "svg"
+
+	MIN_QR_SIZE     = 64
+	MAX_QR_SIZE     = 1024
+	DEFAULT_QR_SIZE = 256
+
+	// Quiet zone around the code in modules, as recommended by the QR spec
+	qrQuietZone = 4
+)
+
+// renderQRCode encodes content as a QR code in the requested format and returns the bytes and content type
+func renderQRCode(content, format string, size int) ([]byte, string, error) {
+	if size < MIN_QR_SIZE || size > MAX_QR_SIZE {
+		return nil, "", ErrInvalidQRCodeSize
+	}
+
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	switch format {
+	case QR_FORMAT_PNG:
+		scaled, err := barcode.Scale(code, size, size)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scale QR code: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, scaled); err != nil {
+			return nil, "", fmt.Errorf("failed to encode QR code PNG: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+
+	case QR_FORMAT_SVG:
+		return renderQRCodeSVG(code, size), "image/svg+xml", nil
+
+	default:
+		return nil, "", ErrInvalidQRCodeFormat
+	}
+}
+
+// renderQRCodeSVG draws one rect per dark module, scaled to size by the viewBox
+func renderQRCodeSVG(code barcode.Barcode, size int) []byte {
+	bounds := code.Bounds()
+	modules := bounds.Dx() + 2*qrQuietZone
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`, modules, modules)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, _, _, _ := code.At(x, y).RGBA()
+			if r != 0 {
+				continue
+			}
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="1" height="1" fill="#000000"/>`,
+				x-bounds.Min.X+qrQuietZone, y-bounds.Min.Y+qrQuietZone)
+		}
+	}
+
+	buf.WriteString("</svg>")
+	return buf.Bytes()
+}
@@ -0,0 +1,168 @@
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// MAX_RECENT_ITEMS bounds how many recent files are kept per user and can be paged through
+const MAX_RECENT_ITEMS = 500
+
+// Kinds of activity that make a file recent
+const (
+	RECENT_ACTIVITY_OPENED   = "opened"
+	RECENT_ACTIVITY_MODIFIED = "modified"
+)
+
+// RecentItem is a file with the latest activity that made it recent
+type RecentItem struct {
+	Item       *models.DriveItem
+	Activity   string
+	ActivityAt int64
+}
+
+// recentItemsKey is the sorted set of the files a user opened, scored by when they were last opened
+func recentItemsKey(userID string) string {
+	return fmt.Sprintf("recent_items:%s", userID)
+}
+
+// recordItemOpened marks a file as opened by the user. Entries past the retention window or
+// beyond the per-user bound are dropped on the way.
+func (s *Service) recordItemOpened(ctx context.Context, userID, itemID string) {
+	now := time.Now()
+	key := recentItemsKey(userID)
+	cutoff := now.Add(-s.recent.retention).Unix()
+
+	_, err := s.redisClient.Pipeline(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, goredis.Z{Score: float64(now.Unix()), Member: itemID})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(cutoff, 10))
+		pipe.ZRemRangeByRank(ctx, key, 0, -(MAX_RECENT_ITEMS + 1))
+		pipe.Expire(ctx, key, s.recent.retention)
+		return nil
+	})
+	if err != nil {
+		s.logger.Warnf("Failed to record opened file %s: %v", itemID, err)
+	}
+}
+
+// ListRecentItems returns a page of the files the user opened or that were modified in any share the
+// user is a member of, within the retention window and most recent first. The total counts at most
+// MAX_RECENT_ITEMS files.
+func (s *Service) ListRecentItems(ctx context.Context, userID string, limit, offset int) ([]*RecentItem, int, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, 0, ctx.Err()
+	}
+
+	shareIDs, err := s.accessibleShareIDs(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	since := time.Now().Add(-s.recent.retention).Unix()
+
+	modified, err := s.repo.GetRecentlyModifiedFiles(ctx, shareIDs, since, MAX_RECENT_ITEMS)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load recently modified files: %w", err)
+	}
+
+	opened, err := s.recentlyOpenedFiles(ctx, userID, shareIDs, since)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// A file both opened and modified is listed once, by its latest activity
+	byID := make(map[string]*RecentItem, len(modified)+len(opened))
+	for _, item := range modified {
+		byID[item.ID] = &RecentItem{Item: item, Activity: RECENT_ACTIVITY_MODIFIED, ActivityAt: item.ModifiedAt}
+	}
+	for _, recent := range opened {
+		if existing, ok := byID[recent.Item.ID]; !ok || recent.ActivityAt > existing.ActivityAt {
+			byID[recent.Item.ID] = recent
+		}
+	}
+
+	recent := make([]*RecentItem, 0, len(byID))
+	for _, item := range byID {
+		recent = append(recent, item)
+	}
+	slices.SortFunc(recent, func(a, b *RecentItem) int {
+		if a.ActivityAt != b.ActivityAt {
+			return cmp.Compare(b.ActivityAt, a.ActivityAt)
+		}
+		return strings.Compare(a.Item.ID, b.Item.ID)
+	})
+	if len(recent) > MAX_RECENT_ITEMS {
+		recent = recent[:MAX_RECENT_ITEMS]
+	}
+
+	total := len(recent)
+	if offset >= total {
+		return []*RecentItem{}, total, nil
+	}
+	return recent[offset:min(offset+limit, total)], total, nil
+}
+
+// recentlyOpenedFiles loads the files the user opened since a time that are still live and still in
+// one of the shares. Files the user has lost access to are left out.
+func (s *Service) recentlyOpenedFiles(ctx context.Context, userID string, shareIDs []string, since int64) ([]*RecentItem, error) {
+	entries, err := s.redisClient.ZRevRangeByScoreWithScores(ctx, recentItemsKey(userID), "+inf", strconv.FormatInt(since, 10), 0, MAX_RECENT_ITEMS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recently opened files: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	itemIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if itemID, ok := entry.Member.(string); ok {
+			itemIDs = append(itemIDs, itemID)
+		}
+	}
+
+	items, err := s.repo.BatchGetItemsByIDs(ctx, itemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recently opened files: %w", err)
+	}
+
+	opened := make([]*RecentItem, 0, len(entries))
+	for _, entry := range entries {
+		itemID, _ := entry.Member.(string)
+		item, ok := items[itemID]
+		if !ok || item.IsTrashed || item.State != ITEM_STATE_ACTIVE || !slices.Contains(shareIDs, item.ShareID) {
+			continue
+		}
+		opened = append(opened, &RecentItem{Item: item, Activity: RECENT_ACTIVITY_OPENED, ActivityAt: int64(entry.Score)})
+	}
+
+	return opened, nil
+}
+
+// accessibleShareIDs returns the IDs of the active shares the user owns or is an active member of
+func (s *Service) accessibleShareIDs(ctx context.Context, userID string) ([]string, error) {
+	owned, err := s.repo.GetSharesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shares: %w", err)
+	}
+	joined, err := s.repo.GetSharesByMemberID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shares: %w", err)
+	}
+
+	shareIDs := make([]string, 0, len(owned)+len(joined))
+	for _, share := range append(owned, joined...) {
+		if !slices.Contains(shareIDs, share.ID) {
+			shareIDs = append(shareIDs, share.ID)
+		}
+	}
+	return shareIDs, nil
+}
@@ -0,0 +1,103 @@
+package drive
+
+import (
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/geoip"
+	"context"
+	"slices"
+)
+
+// regionRouting holds the regional endpoints clients are pointed at and where client addresses are
+// located
+type regionRouting struct {
+	endpoints []config.RegionEndpoint
+	locator   *geoip.Reader // nil when no GeoIP database is configured
+}
+
+// ClientLocation is what is known about where a client connects from. Continent is a hint from a
+// CDN location header, used when the address is not in the GeoIP database.
+type ClientLocation struct {
+	IPAddress string
+	Continent string
+}
+
+// VolumeEndpoints are the regional endpoints a client should use for a share's volume
+type VolumeEndpoints struct {
+	VolumeID        string
+	ClientContinent string // Empty when the client could not be located
+	Upload          config.RegionEndpoint
+	Download        config.RegionEndpoint
+}
+
+// SetRegionRouting configures the regional endpoints clients are routed to. Without a locator,
+// clients are located from the continent hint only.
+func (s *Service) SetRegionRouting(endpoints []config.RegionEndpoint, locator *geoip.Reader) {
+	s.regions = regionRouting{endpoints: endpoints, locator: locator}
+}
+
+// GetVolumeEndpoints returns the endpoints a client should upload to and download from for a
+// share's volume. Uploads go to the region new blocks are written in. Downloads go to the region
+// holding the volume's data that is closest to the client, which is a replica region only when the
+// volume replicates its blocks.
+func (s *Service) GetVolumeEndpoints(ctx context.Context, userID, shareID string, location ClientLocation) (*VolumeEndpoints, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	if s.storage == nil || len(s.regions.endpoints) == 0 {
+		return nil, ErrRegionRoutingUnavailable
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := s.repo.GetVolumeByID(ctx, share.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, ok := s.regionEndpoint(s.storage.Region())
+	if !ok {
+		return nil, ErrRegionRoutingUnavailable
+	}
+
+	continent := s.regions.locator.Continent(location.IPAddress)
+	if continent == "" {
+		continent = geoip.NormalizeContinent(location.Continent)
+	}
+
+	endpoints := &VolumeEndpoints{
+		VolumeID:        volume.ID,
+		ClientContinent: continent,
+		Upload:          primary,
+		Download:        primary,
+	}
+
+	// Replicated volumes can be read from the replica region when it is closer
+	if continent != "" && !slices.Contains(primary.Continents, continent) && volume.CrossRegionReplication {
+		if replica, ok := s.regionEndpoint(s.storage.ReplicaRegion()); ok && slices.Contains(replica.Continents, continent) {
+			endpoints.Download = replica
+		}
+	}
+
+	return endpoints, nil
+}
+
+// regionEndpoint returns the configured endpoint of a storage region
+func (s *Service) regionEndpoint(region string) (config.RegionEndpoint, bool) {
+	if region == "" {
+		return config.RegionEndpoint{}, false
+	}
+	for _, endpoint := range s.regions.endpoints {
+		if endpoint.Region == region {
+			return endpoint, true
+		}
+	}
+	return config.RegionEndpoint{}, false
+}
@@ -6,9 +6,12 @@ import (
 	"cirrussync-api/pkg/db"
 	"context"
 	"errors"
+	"strings"
 	"sync"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository interface for drive operations
@@ -19,6 +22,11 @@ type Repository interface {
 	CreateShare(ctx context.Context, share *models.DriveShare) error
 	CreateMembership(ctx context.Context, membership *models.DriveShareMembership) error
 	CreateItem(ctx context.Context, item *models.DriveItem) error
+	CreateItems(ctx context.Context, items []*models.DriveItem) error
+
+	// WithTransaction runs fn with a repository bound to one transaction, which commits when fn
+	// returns nil and rolls back otherwise
+	WithTransaction(ctx context.Context, fn func(tx Repository) error) error
 
 	// Deletion methods
 	DeleteVolume(ctx context.Context, volumeID string) error
@@ -39,18 +47,22 @@ type Repository interface {
 	GetAllocationByUserID(ctx context.Context, userID string) (*models.VolumeAllocation, error)
 	GetVolumeByUserID(ctx context.Context, userID string) (*models.DriveVolume, error)
 	GetRootFolderByShareID(ctx context.Context, shareID string) (*models.DriveItem, error)
+	GetSharesByTypeAfter(ctx context.Context, shareType int, afterID string, limit int) ([]*models.DriveShare, error)
+	GetRecentlyModifiedFiles(ctx context.Context, shareIDs []string, since int64, limit int) ([]*models.DriveItem, error)
 
 	// Update methods
 	UpdateAllocation(ctx context.Context, allocation *models.VolumeAllocation) error
 
 	// Get collections
 	GetFolderContents(ctx context.Context, folderID string) ([]*models.DriveItem, error)
+	GetFolderChildrenByHashes(ctx context.Context, folderID string, hashes []string) ([]*models.DriveItem, error)
 	GetSharesByUserID(ctx context.Context, userID string) ([]*models.DriveShare, error)
 	GetSharesByMemberID(ctx context.Context, userID string) ([]*models.DriveShare, error)
 	GetMembershipsByShareID(ctx context.Context, shareID string) ([]*models.DriveShareMembership, error)
 	GetFolderContentsPaginated(
 		ctx context.Context,
 		folderID string,
+		tagIDs []string,
 		limit,
 		offset int,
 		sortBy,
@@ -61,6 +73,179 @@ type Repository interface {
 	BatchGetMembershipsByShareIDs(ctx context.Context, shareIDs []string) (map[string][]*models.DriveShareMembership, error)
 	BatchGetSharesByIDs(ctx context.Context, shareIDs []string) (map[string]*models.DriveShare, error)
 	BatchGetFoldersByIDs(ctx context.Context, folderIDs []string) (map[string]*models.DriveItem, error)
+	BatchGetLinksByIDs(ctx context.Context, linkIDs []string) (map[string]*models.DriveItem, error)
+
+	// Search token methods
+	ReplaceItemSearchTokens(ctx context.Context, itemID, userID string, keyVersion int, tokens []*models.DriveSearchToken) error
+	SearchItemsByTokens(
+		ctx context.Context,
+		userID,
+		shareID string,
+		keyVersion int,
+		tokens,
+		tagIDs []string,
+		limit,
+		offset int,
+	) ([]*models.DriveItem, int, error)
+	SearchReadableItemsByTokens(ctx context.Context, userID string, keyVersion int, tokens, tagIDs []string, limit, offset int) ([]*models.DriveItem, int, error)
+	GetSearchKeyState(ctx context.Context, userID string) (*models.DriveSearchKeyState, error)
+	CreateSearchKeyState(ctx context.Context, state *models.DriveSearchKeyState) error
+	UpdateSearchKeyState(ctx context.Context, state *models.DriveSearchKeyState) error
+	GetItemsPendingReindex(ctx context.Context, userID string, fromVersion, toVersion, limit int) ([]*models.DriveItem, int, error)
+	DeleteSearchTokensByVersion(ctx context.Context, userID string, keyVersion, limit int) (int64, error)
+
+	// File upload methods
+	CreateFileWithRevision(ctx context.Context, item *models.DriveItem, revision *models.FileRevision) error
+	GetRevisionByID(ctx context.Context, revisionID string) (*models.FileRevision, error)
+	GetActiveRevisionByItemID(ctx context.Context, itemID string) (*models.FileRevision, error)
+	GetBlocksByRevisionID(ctx context.Context, revisionID string) ([]*models.FileBlock, error)
+	ReplaceRevisionBlocks(ctx context.Context, revisionID string, blocks []*models.FileBlock) error
+	MarkBlocksReceived(ctx context.Context, revisionID string, blockIDs []string, receivedAt int64) error
+	GetTrashedBlocks(ctx context.Context, storageClass string, trashedBefore int64, limit int) ([]*models.FileBlock, error)
+	GetRestoredBlocks(ctx context.Context, storageClass string, limit int) ([]*models.FileBlock, error)
+	SetBlocksStorageClass(ctx context.Context, blockIDs []string, storageClass string) error
+	GetExistingBlockPaths(ctx context.Context, paths []string) (map[string]bool, error)
+	GetDanglingBlocks(ctx context.Context, limit int) ([]*models.FileBlock, error)
+	DeleteBlocks(ctx context.Context, blockIDs []string) error
+	GetDanglingItemIDs(ctx context.Context, limit int) ([]string, error)
+	GetOwnedShareIDs(ctx context.Context, userID string) ([]string, error)
+	GetLeafItemIDsByShareID(ctx context.Context, shareID string, limit int) ([]string, error)
+	DeleteUserDriveRecords(ctx context.Context, userID string) error
+	CommitRevision(ctx context.Context, item *models.DriveItem, revision *models.FileRevision) error
+	SetRevisionPaused(ctx context.Context, revisionID string, pausedAt *int64) error
+	DiscardDraftRevision(ctx context.Context, revisionID string) (*PurgeResult, error)
+	GetAbandonedDraftRevisions(ctx context.Context, inactiveSince int64, limit int) ([]*models.FileRevision, error)
+	ReplaceThumbnail(ctx context.Context, thumbnail *models.DriveThumbnail) error
+	GetThumbnailByRevisionID(ctx context.Context, revisionID string, thumbnailType int) (*models.DriveThumbnail, error)
+
+	// Trash methods
+	BatchGetItemsByIDs(ctx context.Context, itemIDs []string) (map[string]*models.DriveItem, error)
+	SetItemsTrashed(ctx context.Context, itemIDs []string, trashed bool, purgeAt *int64) error
+	GetTrashedTree(ctx context.Context, shareID string) ([]*models.DriveItem, error)
+	GetTrashedItems(ctx context.Context, shareID string, limit, offset int) ([]*models.DriveItem, int, error)
+	GetTrashDueForPurge(ctx context.Context, now int64, limit int) ([]*models.DriveItem, error)
+	GetTrashedSubtrees(ctx context.Context, itemIDs []string) ([]*models.DriveItem, error)
+	PurgeItems(ctx context.Context, itemIDs []string) (*PurgeResult, error)
+	MarkItemDeleting(ctx context.Context, itemID string) error
+	GetSubtreeItems(ctx context.Context, folderID string) ([]*models.DriveItem, error)
+
+	// Duplicate detection methods
+	GetFilesByContentHashes(ctx context.Context, folderID string, contentHashes []string) ([]*models.DriveItem, error)
+
+	// Public link methods
+	CreateShareURL(ctx context.Context, shareURL *models.DriveShareURL) error
+	GetShareURLByID(ctx context.Context, urlID string) (*models.DriveShareURL, error)
+	GetShareURLByTokenOrSlug(ctx context.Context, token, slug string) (*models.DriveShareURL, error)
+	UpdateShareURL(ctx context.Context, shareURL *models.DriveShareURL) error
+	IncrementShareURLAccesses(ctx context.Context, urlID string) error
+
+	// Event methods
+	GetVolumeByID(ctx context.Context, volumeID string) (*models.DriveVolume, error)
+	CreateEvents(ctx context.Context, events []*models.DriveEvent) error
+	GetEventsSince(ctx context.Context, volumeID string, sinceID int64, limit int) ([]*models.DriveEvent, error)
+	GetLatestEventID(ctx context.Context, volumeID string) (int64, error)
+
+	// Rename and move methods
+	UpdateItemLocation(ctx context.Context, item *models.DriveItem) error
+	UpdateItemLocations(ctx context.Context, items []*models.DriveItem) error
+	UpdateItemXattrs(ctx context.Context, item *models.DriveItem) error
+	CreateFileCopies(ctx context.Context, copies []*FileCopy) error
+	GetLiveSubtree(ctx context.Context, folderID string, limit int) ([]*models.DriveItem, error)
+	GetAncestorIDs(ctx context.Context, folderID string) ([]string, error)
+	GetAncestors(ctx context.Context, itemID string) ([]*models.DriveItem, error)
+	RecomputeFolderSizes(ctx context.Context, shareID string) error
+	GetFolderUsage(ctx context.Context, folderID string, limit, offset int) ([]*models.DriveItem, int, error)
+
+	// Invitation methods
+	GetMembershipAnyState(ctx context.Context, shareID, userID string) (*models.DriveShareMembership, error)
+	GetMembershipByID(ctx context.Context, membershipID string) (*models.DriveShareMembership, error)
+	GetMembershipsByUserIDAndState(ctx context.Context, userID string, state int) ([]*models.DriveShareMembership, error)
+	UpdateMembership(ctx context.Context, membership *models.DriveShareMembership) error
+	UpdateMembershipState(ctx context.Context, membershipID string, state int) error
+	GetMembershipsByShareIDAndState(ctx context.Context, shareID string, state int) ([]*models.DriveShareMembership, error)
+	SetShareRequiresApproval(ctx context.Context, shareID string, required bool) error
+	UpdateMembershipPermissions(ctx context.Context, membershipID string, permissions int) error
+	DeleteMembership(ctx context.Context, membershipID string) error
+	TransferShareOwnership(ctx context.Context, share *models.DriveShare, newOwnerID string, keys ShareTransferKeys) error
+	GetShareAdmins(ctx context.Context, share *models.DriveShare) ([]*models.User, error)
+	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+	GetUsersByEmails(ctx context.Context, emails []string) (map[string]*models.User, error)
+	GetActivePlanTier(ctx context.Context, userID string) (int, error)
+	CreateSecurityEvent(ctx context.Context, event *models.UserSecurityEvent) error
+
+	// Share lock methods
+	LockSharesByUserID(ctx context.Context, userID string, lockedAt int64) ([]string, error)
+	GetUnlockableMemberships(ctx context.Context, userID string) ([]*models.DriveShareMembership, error)
+	UnlockShare(ctx context.Context, share *models.DriveShare, keys ShareUnlockKeys) error
+
+	// Key rotation methods
+	GetPrimaryUserKeyID(ctx context.Context, userID string) (string, error)
+	GetLatestKeyRotation(ctx context.Context, userID string) (*models.DriveKeyRotation, error)
+	CreateKeyRotation(ctx context.Context, rotation *models.DriveKeyRotation, shareIDs []string) error
+	GetKeyRotationShares(ctx context.Context, rotationID string) ([]*models.DriveKeyRotationShare, error)
+	GetKeyRotationShare(ctx context.Context, rotationID, shareID string) (*models.DriveKeyRotationShare, error)
+	CountKeyRotationItemsRemaining(ctx context.Context, rotationID string) (map[string]int64, error)
+	GetItemsAfterID(ctx context.Context, shareID, afterID string, limit int) ([]*models.DriveItem, error)
+	ApplyKeyRotationBatch(ctx context.Context, share *models.DriveShare, progress *models.DriveKeyRotationShare, batch KeyRotationBatch, cursor string) error
+	CompleteKeyRotation(ctx context.Context, rotationID string, completedAt int64) (bool, error)
+
+	// Storage composition methods
+	GetVolumeStorageUsage(ctx context.Context, volumeID string) ([]*StorageUsage, error)
+	SetVolumeReplication(ctx context.Context, volumeID string, enabled bool) error
+	GetActivePlanTypes(ctx context.Context, userID string) ([]string, error)
+	ResizeOwnerStorage(ctx context.Context, volumeID, allocationID string, size int64) error
+
+	// Tag methods
+	GetTagsByUserID(ctx context.Context, userID string) ([]*models.DriveTag, error)
+	GetTagByID(ctx context.Context, tagID string) (*models.DriveTag, error)
+	CountTagsByUserID(ctx context.Context, userID string) (int64, error)
+	CountUserTagsByIDs(ctx context.Context, userID string, tagIDs []string) (int64, error)
+	CreateTag(ctx context.Context, tag *models.DriveTag) error
+	UpdateTag(ctx context.Context, tag *models.DriveTag) error
+	DeleteTag(ctx context.Context, tagID string) error
+	GetItemTagIDs(ctx context.Context, itemID, userID string) ([]string, error)
+	ReplaceItemTags(ctx context.Context, itemID, userID string, itemTags []*models.DriveItemTag) error
+
+	// Share expiry methods
+	SetShareExpiry(ctx context.Context, shareID string, expiresAt *int64) error
+	GetSharesDueForExpiryNotice(ctx context.Context, now, noticeUntil int64, limit int) ([]*models.DriveShare, error)
+	MarkShareExpiryNotified(ctx context.Context, shareID string, notifiedAt int64) (bool, error)
+	GetActiveShareMembers(ctx context.Context, shareID string) ([]*models.User, error)
+	GetExpiredShareIDs(ctx context.Context, now int64, limit int) ([]string, error)
+	ExpireShareAccess(ctx context.Context, shareID string) ([]string, error)
+	RestoreShareAccess(ctx context.Context, shareID string, now int64) ([]string, error)
+	ExpireShareURLs(ctx context.Context, now int64, limit int) ([]*models.DriveShareURL, error)
+	ExpireMemberships(ctx context.Context, now int64, limit int) ([]*models.DriveShareMembership, error)
+
+	// Storage integrity methods
+	SampleActiveRevisions(ctx context.Context, limit int) ([]*models.FileRevision, error)
+	GetRevisionsWithOpenIntegrityIssues(ctx context.Context, limit int) ([]*models.FileRevision, error)
+	RecordIntegrityIssue(ctx context.Context, issue *models.StorageIntegrityIssue) error
+	ResolveIntegrityIssues(ctx context.Context, blockIDs []string, now int64) (int64, error)
+	GetUnnotifiedIrrecoverableIssues(ctx context.Context, limit int) ([]*models.StorageIntegrityIssue, error)
+	MarkIntegrityIssuesNotified(ctx context.Context, issueIDs []string, now int64) error
+	GetIntegrityReport(ctx context.Context, filter IntegrityFilter, since int64) (*IntegrityReport, error)
+
+	// Backup methods
+	GetUserDevice(ctx context.Context, userID, deviceID string) (*models.UserDevice, error)
+	GetSessionDeviceUID(ctx context.Context, sessionID string) (string, error)
+	CreateBackupSet(ctx context.Context, share *models.DriveShare, membership *models.DriveShareMembership, set *models.DriveBackupSet) error
+	GetBackupSetByID(ctx context.Context, setID string) (*models.DriveBackupSet, error)
+	GetBackupSetByShareID(ctx context.Context, shareID string) (*models.DriveBackupSet, error)
+	GetBackupSetsByUserID(ctx context.Context, userID, deviceID string) ([]*models.DriveBackupSet, error)
+	CountBackupSetsByDeviceID(ctx context.Context, deviceID string) (int64, error)
+	UpdateBackupSetRules(ctx context.Context, setID string, maxVersions, retentionDays int) error
+	MarkBackupSetDeleting(ctx context.Context, setID string) error
+	TouchBackupSet(ctx context.Context, shareID string, backupAt int64) error
+	GetActiveBackupSets(ctx context.Context, afterID string, limit int) ([]*models.DriveBackupSet, error)
+	GetPrunableRevisionIDs(ctx context.Context, shareID string, maxVersions int, createdBefore int64, limit int) ([]string, error)
+	PurgeRevisions(ctx context.Context, revisionIDs []string) (*PurgeResult, error)
+	DeleteBackupShare(ctx context.Context, setID, shareID string) error
+
+	// Sync state methods
+	UpsertSyncCursor(ctx context.Context, cursor *models.DriveSyncCursor) error
+	GetSyncCursorsByUserID(ctx context.Context, userID string) ([]*models.DriveSyncCursor, error)
+	CountEventsSince(ctx context.Context, volumeID string, sinceID int64, limit int) (int64, error)
 }
 
 // repo implements the Repository interface
@@ -71,6 +256,9 @@ type repo struct {
 	shareRepo      db.Repository[models.DriveShare]
 	membershipRepo db.Repository[models.DriveShareMembership]
 	itemRepo       db.Repository[models.DriveItem]
+	searchKeyRepo  db.Repository[models.DriveSearchKeyState]
+	revisionRepo   db.Repository[models.FileRevision]
+	shareURLRepo   db.Repository[models.DriveShareURL]
 	mutex          sync.Mutex // For operations that need synchronization
 }
 
@@ -83,9 +271,20 @@ func NewRepository(database *gorm.DB) Repository {
 		shareRepo:      db.NewRepositoryWithDB[models.DriveShare](database),
 		membershipRepo: db.NewRepositoryWithDB[models.DriveShareMembership](database),
 		itemRepo:       db.NewRepositoryWithDB[models.DriveItem](database),
+		searchKeyRepo:  db.NewRepositoryWithDB[models.DriveSearchKeyState](database),
+		revisionRepo:   db.NewRepositoryWithDB[models.FileRevision](database),
+		shareURLRepo:   db.NewRepositoryWithDB[models.DriveShareURL](database),
 	}
 }
 
+// WithTransaction runs fn with a repository bound to one transaction. When ctx already carries a
+// transaction, the repository is bound to it instead.
+func (r *repo) WithTransaction(ctx context.Context, fn func(tx Repository) error) error {
+	return db.RunInTx(ctx, r.db, func(ctx context.Context) error {
+		return fn(NewRepository(db.Conn(ctx, r.db)))
+	})
+}
+
 // CreateVolume creates a new drive volume
 func (r *repo) CreateVolume(ctx context.Context, volume *models.DriveVolume) error {
 	return r.volumeRepo.Create(ctx, volume)
@@ -111,6 +310,16 @@ func (r *repo) CreateItem(ctx context.Context, item *models.DriveItem) error {
 	return r.itemRepo.Create(ctx, item)
 }
 
+// CreateItems creates drive items in one transaction, in the given order so parents precede their children
+func (r *repo) CreateItems(ctx context.Context, items []*models.DriveItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(items, 100).Error
+	})
+}
+
 // DeleteVolume deletes a volume by ID
 func (r *repo) DeleteVolume(ctx context.Context, volumeID string) error {
 	return r.volumeRepo.Delete(ctx, volumeID)
@@ -233,6 +442,23 @@ func (r *repo) GetFolderContents(ctx context.Context, folderID string) ([]*model
 	return result, nil
 }
 
+// GetFolderChildrenByHashes retrieves the items within a folder whose name hash is one of hashes
+func (r *repo) GetFolderChildrenByHashes(ctx context.Context, folderID string, hashes []string) ([]*models.DriveItem, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	var items []*models.DriveItem
+	err := r.db.WithContext(ctx).
+		Where("parent_id = ? AND is_trashed = ? AND hash IN ?", folderID, false, hashes).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
 // GetShareByID retrieves a drive share by its ID
 func (r *repo) GetShareByID(ctx context.Context, shareID string) (*models.DriveShare, error) {
 	var share models.DriveShare
@@ -249,11 +475,11 @@ func (r *repo) GetShareByID(ctx context.Context, shareID string) (*models.DriveS
 	return &share, nil
 }
 
-// GetMembershipByShareAndUserID retrieves a user's membership for a specific share
+// GetMembershipByShareAndUserID retrieves a user's active membership for a specific share
 func (r *repo) GetMembershipByShareAndUserID(ctx context.Context, shareID, userID string) (*models.DriveShareMembership, error) {
 	var membership models.DriveShareMembership
 	err := r.db.WithContext(ctx).
-		Where("share_id = ? AND user_id = ?", shareID, userID).
+		Where("share_id = ? AND user_id = ? AND state = ?", shareID, userID, MEMBERSHIP_STATE_ACTIVE).
 		First(&membership).Error
 
 	if err != nil {
@@ -296,6 +522,22 @@ func (r *repo) GetAllocationByUserID(ctx context.Context, userID string) (*model
 	return &allocation, nil
 }
 
+// GetVolumeByID retrieves a drive volume by ID
+func (r *repo) GetVolumeByID(ctx context.Context, volumeID string) (*models.DriveVolume, error) {
+	var volume models.DriveVolume
+	err := r.db.WithContext(ctx).
+		Where("id = ?", volumeID).
+		First(&volume).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVolumeNotFound
+		}
+		return nil, err
+	}
+	return &volume, nil
+}
+
 // GetVolumeByUserID retrieves a user's drive volume
 func (r *repo) GetVolumeByUserID(ctx context.Context, userID string) (*models.DriveVolume, error) {
 	var volume models.DriveVolume
@@ -479,6 +721,32 @@ func (r *repo) BatchGetFoldersByIDs(ctx context.Context, folderIDs []string) (ma
 	return result, nil
 }
 
+// BatchGetLinksByIDs retrieves multiple links, trashed or not, by IDs in one query
+func (r *repo) BatchGetLinksByIDs(ctx context.Context, linkIDs []string) (map[string]*models.DriveItem, error) {
+	if len(linkIDs) == 0 {
+		return make(map[string]*models.DriveItem), nil
+	}
+
+	var items []models.DriveItem
+
+	err := r.db.WithContext(ctx).
+		Where("id IN ?", linkIDs).
+		Find(&items).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Map links by ID for quick lookup
+	result := make(map[string]*models.DriveItem, len(items))
+
+	for i := range items {
+		result[items[i].ID] = &items[i]
+	}
+
+	return result, nil
+}
+
 // Repository function to get a link by ID
 func (r *repo) GetLinkByID(ctx context.Context, linkID string) (*models.DriveItem, error) {
 	// Find the drive item
@@ -511,6 +779,7 @@ func (r *repo) GetFolderByID(ctx context.Context, folderID string) (*models.Driv
 func (r *repo) GetFolderContentsPaginated(
 	ctx context.Context,
 	folderID string,
+	tagIDs []string,
 	limit,
 	offset int,
 	sortBy,
@@ -523,26 +792,34 @@ func (r *repo) GetFolderContentsPaginated(
 
 	resultChan := make(chan struct{}, 2)
 
-	// Map API sort fields to DB column names
-	columnMap := map[string]string{
-		"createdAt":  "created_at",
-		"modifiedAt": "modified_at",
-		"size":       "size",
-		"type":       "type",
+	// Stable sorting: folder-first + column + id. Unknown sort fields fall back to the creation time.
+	if !ItemColumns.Sortable(sortBy) {
+		sortBy = "createdAt"
 	}
-
-	sqlColumn, exists := columnMap[sortBy]
-	if !exists {
-		sqlColumn = "created_at"
+	builder := db.NewQueryBuilder(ItemColumns).
+		Where("parentId", db.OpEq, folderID).
+		Where("isTrashed", db.OpEq, false).
+		Where("state", db.OpNe, ITEM_STATE_DRAFT).
+		OrderBy("type", db.SortDesc).
+		OrderBy(sortBy, db.ParseSortDirection(sortDir)).
+		OrderBy("id", db.SortAsc)
+
+	countQuery, err := builder.ApplyFilters(r.itemRepo.DB().WithContext(ctx).Model(&models.DriveItem{}))
+	if err != nil {
+		return nil, 0, err
+	}
+	query, err := builder.Apply(r.itemRepo.DB().WithContext(ctx).Model(&models.DriveItem{}))
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Count total items
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		countQuery := r.itemRepo.DB().WithContext(ctx).
-			Model(&models.DriveItem{}).
-			Where("parent_id = ? AND is_trashed = ?", folderID, false)
+		if len(tagIDs) > 0 {
+			countQuery = countQuery.Where("id IN (?)", r.taggedItemIDs(ctx, tagIDs))
+		}
 
 		if err := countQuery.Count(&total).Error; err != nil {
 			countErr = err
@@ -554,15 +831,8 @@ func (r *repo) GetFolderContentsPaginated(
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		query := r.itemRepo.DB().WithContext(ctx).
-			Model(&models.DriveItem{}).
-			Where("parent_id = ? AND is_trashed = ?", folderID, false)
-
-		// Stable sorting: folder-first + column + id
-		if sortDir == "desc" {
-			query = query.Order("type DESC").Order(sqlColumn + " DESC").Order("id ASC")
-		} else {
-			query = query.Order("type DESC").Order(sqlColumn + " ASC").Order("id ASC")
+		if len(tagIDs) > 0 {
+			query = query.Where("id IN (?)", r.taggedItemIDs(ctx, tagIDs))
 		}
 
 		// Proper offset logic: offset as page number
@@ -604,6 +874,32 @@ func (r *repo) GetFolderContentsPaginated(
 	return result, int(total), nil
 }
 
+// GetSharesByTypeAfter retrieves active shares of a type in ID order, starting after afterID
+func (r *repo) GetSharesByTypeAfter(ctx context.Context, shareType int, afterID string, limit int) ([]*models.DriveShare, error) {
+	var shares []*models.DriveShare
+	err := r.db.WithContext(ctx).
+		Where("type = ? AND state = ? AND id > ?", shareType, 1, afterID). // State 1 = active
+		Order("id ASC").
+		Limit(limit).
+		Find(&shares).Error
+	return shares, err
+}
+
+// GetRecentlyModifiedFiles retrieves live files of the shares modified since a time, most recent first
+func (r *repo) GetRecentlyModifiedFiles(ctx context.Context, shareIDs []string, since int64, limit int) ([]*models.DriveItem, error) {
+	var items []*models.DriveItem
+	if len(shareIDs) == 0 {
+		return items, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("share_id IN ? AND type = ? AND state = ? AND is_trashed = ? AND modified_at >= ?", shareIDs, 2, ITEM_STATE_ACTIVE, false, since).
+		Order("modified_at DESC").
+		Limit(limit).
+		Find(&items).Error
+	return items, err
+}
+
 // Repository function to get root folder for a share
 func (r *repo) GetRootFolderByShareID(ctx context.Context, shareID string) (*models.DriveItem, error) {
 	// Find the root folder for this share (parent_id IS NULL)
@@ -621,3 +917,2645 @@ func (r *repo) GetRootFolderByShareID(ctx context.Context, shareID string) (*mod
 
 	return &rootFolder, nil
 }
+
+// ReplaceItemSearchTokens atomically replaces a user's search tokens for an item at a key version
+func (r *repo) ReplaceItemSearchTokens(ctx context.Context, itemID, userID string, keyVersion int, tokens []*models.DriveSearchToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("item_id = ? AND user_id = ? AND key_version = ?", itemID, userID, keyVersion).
+			Delete(&models.DriveSearchToken{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(tokens) == 0 {
+			return nil
+		}
+
+		return tx.Create(&tokens).Error
+	})
+}
+
+// SearchItemsByTokens finds items in a share whose tokens match all of the given tokens
+func (r *repo) SearchItemsByTokens(
+	ctx context.Context,
+	userID,
+	shareID string,
+	keyVersion int,
+	tokens,
+	tagIDs []string,
+	limit,
+	offset int,
+) ([]*models.DriveItem, int, error) {
+	filter := func() *gorm.DB {
+		query := r.db.WithContext(ctx).
+			Model(&models.DriveItem{}).
+			Where("share_id = ? AND is_trashed = ?", shareID, false)
+		if len(tokens) > 0 {
+			// Items must match every query token (AND semantics)
+			matching := r.db.WithContext(ctx).
+				Model(&models.DriveSearchToken{}).
+				Select("item_id").
+				Where("user_id = ? AND share_id = ? AND key_version = ? AND token IN ?", userID, shareID, keyVersion, tokens).
+				Group("item_id").
+				Having("COUNT(DISTINCT token) = ?", len(tokens))
+			query = query.Where("id IN (?)", matching)
+		}
+		if len(tagIDs) > 0 {
+			query = query.Where("id IN (?)", r.taggedItemIDs(ctx, tagIDs))
+		}
+		return query
+	}
+
+	var total int64
+	if err := filter().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []models.DriveItem
+	err := filter().
+		Order("type DESC").Order("modified_at DESC").Order("id ASC").
+		Offset(offset * limit).Limit(limit).
+		Find(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+
+	return result, int(total), nil
+}
+
+// SearchReadableItemsByTokens finds items in any share the user owns or can read as an active member
+// whose tokens match all of the given tokens
+func (r *repo) SearchReadableItemsByTokens(
+	ctx context.Context,
+	userID string,
+	keyVersion int,
+	tokens,
+	tagIDs []string,
+	limit,
+	offset int,
+) ([]*models.DriveItem, int, error) {
+	filter := func() *gorm.DB {
+		ownedShares := r.db.Model(&models.DriveShare{}).
+			Select("id").
+			Where("user_id = ? AND state = ?", userID, 1) // State 1 = active
+		memberShares := r.db.Model(&models.DriveShareMembership{}).
+			Select("share_id").
+			Where("user_id = ? AND state = ? AND permissions & ? <> 0", userID, MEMBERSHIP_STATE_ACTIVE, READ_PERMISSION)
+
+		query := r.db.WithContext(ctx).
+			Model(&models.DriveItem{}).
+			Where("is_trashed = ? AND state = ?", false, ITEM_STATE_ACTIVE).
+			Where("share_id IN (?) OR share_id IN (?)", ownedShares, memberShares)
+		if len(tokens) > 0 {
+			// Items must match every query token (AND semantics)
+			matching := r.db.WithContext(ctx).
+				Model(&models.DriveSearchToken{}).
+				Select("item_id").
+				Where("user_id = ? AND key_version = ? AND token IN ?", userID, keyVersion, tokens).
+				Group("item_id").
+				Having("COUNT(DISTINCT token) = ?", len(tokens))
+			query = query.Where("id IN (?)", matching)
+		}
+		if len(tagIDs) > 0 {
+			query = query.Where("id IN (?)", r.taggedItemIDs(ctx, tagIDs))
+		}
+		return query
+	}
+
+	var total int64
+	if err := filter().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []models.DriveItem
+	err := filter().
+		Order("type DESC").Order("modified_at DESC").Order("id ASC").
+		Offset(offset * limit).Limit(limit).
+		Find(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+
+	return result, int(total), nil
+}
+
+// GetSearchKeyState retrieves a user's search key state
+func (r *repo) GetSearchKeyState(ctx context.Context, userID string) (*models.DriveSearchKeyState, error) {
+	var state models.DriveSearchKeyState
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&state).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSearchKeyStateNotFound
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+// CreateSearchKeyState creates a new search key state
+func (r *repo) CreateSearchKeyState(ctx context.Context, state *models.DriveSearchKeyState) error {
+	return r.searchKeyRepo.Create(ctx, state)
+}
+
+// UpdateSearchKeyState updates a search key state
+func (r *repo) UpdateSearchKeyState(ctx context.Context, state *models.DriveSearchKeyState) error {
+	return r.searchKeyRepo.Update(ctx, state)
+}
+
+// GetItemsPendingReindex retrieves items indexed under the old key version but not the new one
+func (r *repo) GetItemsPendingReindex(ctx context.Context, userID string, fromVersion, toVersion, limit int) ([]*models.DriveItem, int, error) {
+	indexedOld := r.db.WithContext(ctx).
+		Model(&models.DriveSearchToken{}).
+		Select("item_id").
+		Where("user_id = ? AND key_version = ?", userID, fromVersion)
+
+	indexedNew := r.db.WithContext(ctx).
+		Model(&models.DriveSearchToken{}).
+		Select("item_id").
+		Where("user_id = ? AND key_version = ?", userID, toVersion)
+
+	pending := func() *gorm.DB {
+		return r.db.WithContext(ctx).
+			Model(&models.DriveItem{}).
+			Where("id IN (?) AND id NOT IN (?) AND is_trashed = ?", indexedOld, indexedNew, false)
+	}
+
+	var total int64
+	if err := pending().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []models.DriveItem
+	if err := pending().Order("id ASC").Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+
+	return result, int(total), nil
+}
+
+// DeleteSearchTokensByVersion deletes up to limit search tokens for a user at a key version
+func (r *repo) DeleteSearchTokensByVersion(ctx context.Context, userID string, keyVersion, limit int) (int64, error) {
+	batch := r.db.WithContext(ctx).
+		Model(&models.DriveSearchToken{}).
+		Select("id").
+		Where("user_id = ? AND key_version = ?", userID, keyVersion).
+		Limit(limit)
+
+	result := r.db.WithContext(ctx).
+		Where("id IN (?)", batch).
+		Delete(&models.DriveSearchToken{})
+
+	return result.RowsAffected, result.Error
+}
+
+// CreateFileWithRevision creates a draft file item together with its first revision
+func (r *repo) CreateFileWithRevision(ctx context.Context, item *models.DriveItem, revision *models.FileRevision) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+
+		revision.ItemID = item.ID
+		return tx.Create(revision).Error
+	})
+}
+
+// GetRevisionByID retrieves a file revision by ID
+func (r *repo) GetRevisionByID(ctx context.Context, revisionID string) (*models.FileRevision, error) {
+	revision, err := r.revisionRepo.FindByID(ctx, revisionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// GetActiveRevisionByItemID retrieves the current revision of a file
+func (r *repo) GetActiveRevisionByItemID(ctx context.Context, itemID string) (*models.FileRevision, error) {
+	var revision models.FileRevision
+	err := r.db.WithContext(ctx).
+		Where("item_id = ? AND state = ?", itemID, REVISION_STATE_ACTIVE).
+		Order("created_at DESC").
+		First(&revision).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ReplaceThumbnail stores a revision's thumbnail, replacing any previous one of the same type
+func (r *repo) ReplaceThumbnail(ctx context.Context, thumbnail *models.DriveThumbnail) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("revision_id = ? AND type = ?", thumbnail.RevisionID, thumbnail.Type).
+			Delete(&models.DriveThumbnail{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(thumbnail).Error
+	})
+}
+
+// GetThumbnailByRevisionID retrieves the thumbnail of a type recorded for a revision
+func (r *repo) GetThumbnailByRevisionID(ctx context.Context, revisionID string, thumbnailType int) (*models.DriveThumbnail, error) {
+	var thumbnail models.DriveThumbnail
+	err := r.db.WithContext(ctx).
+		Where("revision_id = ? AND type = ?", revisionID, thumbnailType).
+		Order("created_at DESC").
+		First(&thumbnail).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThumbnailNotFound
+		}
+		return nil, err
+	}
+	return &thumbnail, nil
+}
+
+// GetBlocksByRevisionID retrieves all blocks of a revision ordered by index
+func (r *repo) GetBlocksByRevisionID(ctx context.Context, revisionID string) ([]*models.FileBlock, error) {
+	var blocks []models.FileBlock
+	err := r.db.WithContext(ctx).
+		Where("revision_id = ?", revisionID).
+		Order(`"index" ASC`).
+		Find(&blocks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*FileBlock
+	result := make([]*models.FileBlock, len(blocks))
+	for i := range blocks {
+		result[i] = &blocks[i]
+	}
+
+	return result, nil
+}
+
+// ReplaceRevisionBlocks creates blocks for a revision, replacing any existing blocks at the same indexes,
+// and records the upload activity on the revision
+func (r *repo) ReplaceRevisionBlocks(ctx context.Context, revisionID string, blocks []*models.FileBlock) error {
+	indexes := make([]int, len(blocks))
+	for i, block := range blocks {
+		indexes[i] = block.Index
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(`revision_id = ? AND "index" IN ?`, revisionID, indexes).
+			Delete(&models.FileBlock{}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(&blocks).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.FileRevision{}).
+			Where("id = ?", revisionID).
+			Update("modified_at", time.Now().Unix()).Error
+	})
+}
+
+// MarkBlocksReceived records that blocks of a revision were found in storage and verified
+func (r *repo) MarkBlocksReceived(ctx context.Context, revisionID string, blockIDs []string, receivedAt int64) error {
+	if len(blockIDs) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&models.FileBlock{}).
+		Where("revision_id = ? AND id IN ?", revisionID, blockIDs).
+		Updates(map[string]interface{}{"upload_complete": true, "upload_time": receivedAt}).Error
+}
+
+// GetTrashedBlocks retrieves up to limit uploaded blocks in a storage class of the files trashed before a
+// time, directly or with a folder above them
+func (r *repo) GetTrashedBlocks(ctx context.Context, storageClass string, trashedBefore int64, limit int) ([]*models.FileBlock, error) {
+	var blocks []*models.FileBlock
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM drive_items WHERE is_trashed = ? AND trashed_at <= ? AND state <> ?
+			UNION
+			SELECT child.id FROM drive_items child JOIN tree ON child.parent_id = tree.id
+		)
+		SELECT b.* FROM file_blocks b
+		JOIN file_revisions rev ON rev.id = b.revision_id
+		WHERE rev.item_id IN (SELECT id FROM tree) AND b.storage_class = ? AND b.upload_complete = ?
+		ORDER BY b.id
+		LIMIT ?`, true, trashedBefore, ITEM_STATE_DELETING, storageClass, true, limit).
+		Scan(&blocks).Error
+
+	return blocks, err
+}
+
+// GetRestoredBlocks retrieves up to limit blocks in a storage class whose files are no longer in the trash
+func (r *repo) GetRestoredBlocks(ctx context.Context, storageClass string, limit int) ([]*models.FileBlock, error) {
+	var blocks []*models.FileBlock
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM drive_items WHERE is_trashed = ?
+			UNION
+			SELECT child.id FROM drive_items child JOIN tree ON child.parent_id = tree.id
+		)
+		SELECT b.* FROM file_blocks b
+		JOIN file_revisions rev ON rev.id = b.revision_id
+		JOIN drive_items item ON item.id = rev.item_id
+		WHERE b.storage_class = ? AND item.state <> ? AND item.id NOT IN (SELECT id FROM tree)
+		ORDER BY b.id
+		LIMIT ?`, true, storageClass, ITEM_STATE_DELETING, limit).
+		Scan(&blocks).Error
+
+	return blocks, err
+}
+
+// SetBlocksStorageClass records the storage class blocks were moved to
+func (r *repo) SetBlocksStorageClass(ctx context.Context, blockIDs []string, storageClass string) error {
+	if len(blockIDs) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&models.FileBlock{}).
+		Where("id IN ?", blockIDs).
+		Update("storage_class", storageClass).Error
+}
+
+// GetExistingBlockPaths reports which of the given storage paths a block refers to
+func (r *repo) GetExistingBlockPaths(ctx context.Context, paths []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(paths))
+	if len(paths) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := r.db.WithContext(ctx).
+		Model(&models.FileBlock{}).
+		Where("storage_path IN ?", paths).
+		Distinct().
+		Pluck("storage_path", &found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range found {
+		existing[path] = true
+	}
+	return existing, nil
+}
+
+// GetDanglingBlocks retrieves up to limit blocks whose revision no longer exists
+func (r *repo) GetDanglingBlocks(ctx context.Context, limit int) ([]*models.FileBlock, error) {
+	var blocks []*models.FileBlock
+	err := r.db.WithContext(ctx).
+		Where("NOT EXISTS (SELECT 1 FROM file_revisions rev WHERE rev.id = file_blocks.revision_id)").
+		Order("id").
+		Limit(limit).
+		Find(&blocks).Error
+
+	return blocks, err
+}
+
+// DeleteBlocks permanently deletes block rows
+func (r *repo) DeleteBlocks(ctx context.Context, blockIDs []string) error {
+	if len(blockIDs) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).
+		Where("id IN ?", blockIDs).
+		Delete(&models.FileBlock{}).Error
+}
+
+// GetDanglingItemIDs retrieves up to limit items whose share or volume no longer exists. Only items
+// without children are returned, so a tree is removed from its leaves up, one batch after another.
+func (r *repo) GetDanglingItemIDs(ctx context.Context, limit int) ([]string, error) {
+	var itemIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where(`(NOT EXISTS (SELECT 1 FROM drive_shares share WHERE share.id = drive_items.share_id)
+			OR NOT EXISTS (SELECT 1 FROM drive_volumes volume WHERE volume.id = drive_items.volume_id))
+			AND NOT EXISTS (SELECT 1 FROM drive_items child WHERE child.parent_id = drive_items.id)`).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &itemIDs).Error
+
+	return itemIDs, err
+}
+
+// GetOwnedShareIDs retrieves the IDs of every share a user owns, whatever its state
+func (r *repo) GetOwnedShareIDs(ctx context.Context, userID string) ([]string, error) {
+	var shareIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveShare{}).
+		Where("user_id = ?", userID).
+		Pluck("id", &shareIDs).Error
+
+	return shareIDs, err
+}
+
+// GetLeafItemIDsByShareID retrieves up to limit items of a share without children, so a share is
+// emptied from its leaves up, one batch after another
+func (r *repo) GetLeafItemIDsByShareID(ctx context.Context, shareID string, limit int) ([]string, error) {
+	var itemIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where("share_id = ? AND NOT EXISTS (SELECT 1 FROM drive_items child WHERE child.parent_id = drive_items.id)", shareID).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &itemIDs).Error
+
+	return itemIDs, err
+}
+
+// DeleteUserDriveRecords deletes what is left of a user's drive once the items of their shares are
+// purged: their shares with every membership and public link, their volume with its allocations and
+// change log, their memberships in other users' shares and their tags, search keys, backup sets,
+// sync cursors and integrity issues
+func (r *repo) DeleteUserDriveRecords(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		shareIDs := tx.Model(&models.DriveShare{}).Select("id").Where("user_id = ?", userID)
+		volumeIDs := tx.Model(&models.DriveVolume{}).Select("id").Where("user_id = ?", userID)
+
+		if err := tx.Where("share_id IN (?) OR creator_id = ?", shareIDs, userID).Delete(&models.DriveShareURL{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("share_id IN (?) OR user_id = ?", shareIDs, userID).Delete(&models.DriveShareMembership{}).Error; err != nil {
+			return err
+		}
+
+		perUser := []interface{}{
+			&models.DriveItemTag{},
+			&models.DriveTag{},
+			&models.DriveSearchToken{},
+			&models.DriveSearchKeyState{},
+			&models.DriveBackupSet{},
+			&models.DriveSyncCursor{},
+			&models.StorageIntegrityIssue{},
+		}
+		for _, model := range perUser {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.DriveShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("volume_id IN (?)", volumeIDs).Delete(&models.DriveEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("volume_id IN (?) OR user_id = ?", volumeIDs, userID).Delete(&models.VolumeAllocation{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.DriveVolume{}).Error
+	})
+}
+
+// CommitRevision activates a draft revision, marks its blocks uploaded and obsoletes the previous revision
+func (r *repo) CommitRevision(ctx context.Context, item *models.DriveItem, revision *models.FileRevision) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Unix()
+
+		// Only a draft that is still uploading is committed, so a concurrent pause or cancel wins.
+		// The revision is claimed first so this locks rows in the same order as DiscardDraftRevision.
+		committed := tx.Model(&models.FileRevision{}).
+			Where("id = ? AND state = ? AND paused_at IS NULL", revision.ID, REVISION_STATE_DRAFT).
+			Updates(map[string]interface{}{
+				"size":               revision.Size,
+				"state":              revision.State,
+				"manifest_signature": revision.ManifestSignature,
+				"signature_email":    revision.SignatureEmail,
+				"modified_at":        now,
+			})
+		if committed.Error != nil {
+			return committed.Error
+		}
+		if committed.RowsAffected == 0 {
+			return ErrUploadInterrupted
+		}
+
+		err := tx.Model(&models.FileBlock{}).
+			Where("revision_id = ?", revision.ID).
+			Updates(map[string]interface{}{"upload_complete": true, "upload_time": now}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.FileRevision{}).
+			Where("item_id = ? AND id <> ? AND state = ?", item.ID, revision.ID, REVISION_STATE_ACTIVE).
+			Update("state", REVISION_STATE_OBSOLETE).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Save(item).Error
+	})
+}
+
+// SetRevisionPaused pauses or, with a nil pausedAt, resumes the upload of a draft revision
+func (r *repo) SetRevisionPaused(ctx context.Context, revisionID string, pausedAt *int64) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.FileRevision{}).
+		Where("id = ? AND state = ?", revisionID, REVISION_STATE_DRAFT).
+		Updates(map[string]interface{}{"paused_at": pausedAt, "modified_at": time.Now().Unix()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRevisionNotDraft
+	}
+	return nil
+}
+
+// DiscardDraftRevision deletes a draft revision with its blocks and thumbnails. When its item has
+// never been committed the item is purged as well. Both are checked under a row lock, so a revision
+// or item committed concurrently is left alone. The result holds the storage paths of the deleted
+// objects and an item count of 1 if the item went too.
+func (r *repo) DiscardDraftRevision(ctx context.Context, revisionID string) (*PurgeResult, error) {
+	result := &PurgeResult{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var revision models.FileRevision
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND state = ?", revisionID, REVISION_STATE_DRAFT).
+			First(&revision).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRevisionNotDraft
+			}
+			return err
+		}
+
+		var item models.DriveItem
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", revision.ItemID).
+			First(&item).Error
+		if err != nil {
+			return err
+		}
+
+		if item.State == ITEM_STATE_DRAFT {
+			return purgeItems(tx, []string{item.ID}, result)
+		}
+
+		err = tx.Model(&models.FileBlock{}).
+			Where("revision_id = ? AND storage_path <> ''", revisionID).
+			Pluck("storage_path", &result.StoragePaths).Error
+		if err != nil {
+			return err
+		}
+
+		var thumbnailPaths []string
+		err = tx.Model(&models.DriveThumbnail{}).
+			Where("revision_id = ? AND storage_path <> ''", revisionID).
+			Pluck("storage_path", &thumbnailPaths).Error
+		if err != nil {
+			return err
+		}
+		result.StoragePaths = append(result.StoragePaths, thumbnailPaths...)
+
+		// Delete dependents before the revision itself
+		if err := tx.Where("revision_id = ?", revisionID).Delete(&models.FileBlock{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("revision_id = ?", revisionID).Delete(&models.DriveThumbnail{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", revisionID).Delete(&models.FileRevision{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetAbandonedDraftRevisions loads draft revisions without upload activity since the given time, with their item
+func (r *repo) GetAbandonedDraftRevisions(ctx context.Context, inactiveSince int64, limit int) ([]*models.FileRevision, error) {
+	var revisions []*models.FileRevision
+	err := r.db.WithContext(ctx).
+		Preload("Item").
+		Where("state = ? AND created_at < ? AND modified_at < ?", REVISION_STATE_DRAFT, inactiveSince, inactiveSince).
+		Order("modified_at ASC").
+		Limit(limit).
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// CreateShareURL creates a public link and bumps the item's link counter
+func (r *repo) CreateShareURL(ctx context.Context, shareURL *models.DriveShareURL) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(shareURL).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.DriveItem{}).
+			Where("id = ?", shareURL.ItemID).
+			UpdateColumn("nb_urls", gorm.Expr("nb_urls + 1")).Error
+	})
+}
+
+// GetShareURLByID retrieves a public link by ID
+func (r *repo) GetShareURLByID(ctx context.Context, urlID string) (*models.DriveShareURL, error) {
+	shareURL, err := r.shareURLRepo.FindByID(ctx, urlID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareURLNotFound
+		}
+		return nil, err
+	}
+
+	return shareURL, nil
+}
+
+// GetShareURLByTokenOrSlug retrieves a public link by its token or vanity slug
+func (r *repo) GetShareURLByTokenOrSlug(ctx context.Context, token, slug string) (*models.DriveShareURL, error) {
+	var shareURL models.DriveShareURL
+	err := r.db.WithContext(ctx).
+		Where("token = ? OR slug = ?", token, slug).
+		First(&shareURL).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareURLNotFound
+		}
+		return nil, err
+	}
+	return &shareURL, nil
+}
+
+// UpdateShareURL updates a public link
+func (r *repo) UpdateShareURL(ctx context.Context, shareURL *models.DriveShareURL) error {
+	return r.shareURLRepo.Update(ctx, shareURL)
+}
+
+// IncrementShareURLAccesses atomically increments a public link's access counter
+func (r *repo) IncrementShareURLAccesses(ctx context.Context, urlID string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveShareURL{}).
+		Where("id = ?", urlID).
+		UpdateColumn("num_accesses", gorm.Expr("num_accesses + 1")).Error
+}
+
+// GetFilesByContentHashes retrieves active files in a folder whose content hash is in the given set
+func (r *repo) GetFilesByContentHashes(ctx context.Context, folderID string, contentHashes []string) ([]*models.DriveItem, error) {
+	var items []models.DriveItem
+	err := r.db.WithContext(ctx).
+		Where("parent_id = ? AND type = ? AND is_trashed = ? AND state = ?", folderID, 2, false, ITEM_STATE_ACTIVE).
+		Where("file_properties->>'content_hash' IN ?", contentHashes).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+
+	return result, nil
+}
+
+// BatchGetItemsByIDs retrieves multiple drive items by IDs, including trashed ones
+func (r *repo) BatchGetItemsByIDs(ctx context.Context, itemIDs []string) (map[string]*models.DriveItem, error) {
+	if len(itemIDs) == 0 {
+		return make(map[string]*models.DriveItem), nil
+	}
+
+	var items []models.DriveItem
+	err := r.db.WithContext(ctx).
+		Where("id IN ?", itemIDs).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Map items by ID for quick lookup
+	result := make(map[string]*models.DriveItem, len(items))
+	for i := range items {
+		result[items[i].ID] = &items[i]
+	}
+
+	return result, nil
+}
+
+// SetItemsTrashed moves items to or out of the trash
+func (r *repo) SetItemsTrashed(ctx context.Context, itemIDs []string, trashed bool, purgeAt *int64) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"is_trashed":  trashed,
+		"trashed_at":  nil,
+		"purge_at":    nil,
+		"modified_at": time.Now().Unix(),
+	}
+	if trashed {
+		updates["trashed_at"] = time.Now().Unix()
+		updates["purge_at"] = purgeAt
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where("id IN ?", itemIDs).
+		Updates(updates).Error
+}
+
+// GetTrashedTree retrieves the trashed items of a share together with all of their descendants
+func (r *repo) GetTrashedTree(ctx context.Context, shareID string) ([]*models.DriveItem, error) {
+	var items []models.DriveItem
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT * FROM drive_items WHERE share_id = ? AND is_trashed = ? AND state <> ?
+			UNION
+			SELECT child.* FROM drive_items child JOIN tree ON child.parent_id = tree.id
+		)
+		SELECT * FROM tree`, shareID, true, ITEM_STATE_DELETING).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+
+	return result, nil
+}
+
+// GetTrashedItems retrieves a page of the items trashed in a share, most recently trashed first.
+// Descendants of trashed folders are not listed, they are restored and purged with their folder.
+func (r *repo) GetTrashedItems(ctx context.Context, shareID string, limit, offset int) ([]*models.DriveItem, int, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where("share_id = ? AND is_trashed = ? AND state <> ?", shareID, true, ITEM_STATE_DELETING)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []*models.DriveItem
+	err := query.
+		Order("trashed_at DESC, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, int(total), nil
+}
+
+// GetTrashDueForPurge retrieves up to limit trashed items whose retention ended by now
+func (r *repo) GetTrashDueForPurge(ctx context.Context, now int64, limit int) ([]*models.DriveItem, error) {
+	var items []*models.DriveItem
+	err := r.db.WithContext(ctx).
+		Where("purge_at <= ? AND is_trashed = ? AND state <> ?", now, true, ITEM_STATE_DELETING).
+		Order("purge_at").
+		Limit(limit).
+		Find(&items).Error
+
+	return items, err
+}
+
+// GetTrashedSubtrees retrieves the given trashed items together with all of their descendants
+func (r *repo) GetTrashedSubtrees(ctx context.Context, itemIDs []string) ([]*models.DriveItem, error) {
+	if len(itemIDs) == 0 {
+		return nil, nil
+	}
+
+	var items []models.DriveItem
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT * FROM drive_items WHERE id IN ? AND is_trashed = ? AND state <> ?
+			UNION
+			SELECT child.* FROM drive_items child JOIN tree ON child.parent_id = tree.id
+		)
+		SELECT * FROM tree`, itemIDs, true, ITEM_STATE_DELETING).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+
+	return result, nil
+}
+
+// PurgeItems permanently deletes items and everything stored for them.
+// It returns the object keys to remove from storage and the bytes that were accounted to the items.
+func (r *repo) PurgeItems(ctx context.Context, itemIDs []string) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	if len(itemIDs) == 0 {
+		return result, nil
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return purgeItems(tx, itemIDs, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// purgeItems deletes items and everything that belongs to them within a transaction, adding what
+// was deleted to result
+func purgeItems(tx *gorm.DB, itemIDs []string, result *PurgeResult) error {
+	revisionIDs := tx.Model(&models.FileRevision{}).Select("id").Where("item_id IN ?", itemIDs)
+
+	// Draft revisions were never accounted for
+	err := tx.Model(&models.FileRevision{}).
+		Select("COALESCE(SUM(size), 0)").
+		Where("item_id IN ? AND state <> ?", itemIDs, REVISION_STATE_DRAFT).
+		Scan(&result.FileBytes).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.DriveItem{}).
+		Where("id IN ? AND type = ?", itemIDs, 1).
+		Count(&result.FolderCount).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.FileBlock{}).
+		Where("revision_id IN (?) AND storage_path <> ''", revisionIDs).
+		Pluck("storage_path", &result.StoragePaths).Error
+	if err != nil {
+		return err
+	}
+
+	var thumbnailPaths []string
+	err = tx.Model(&models.DriveThumbnail{}).
+		Where("revision_id IN (?) AND storage_path <> ''", revisionIDs).
+		Pluck("storage_path", &thumbnailPaths).Error
+	if err != nil {
+		return err
+	}
+	result.StoragePaths = append(result.StoragePaths, thumbnailPaths...)
+
+	// Delete dependents before the items themselves
+	if err := tx.Where("revision_id IN (?)", revisionIDs).Delete(&models.FileBlock{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("revision_id IN (?)", revisionIDs).Delete(&models.DriveThumbnail{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id IN ?", itemIDs).Delete(&models.FileRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id IN ?", itemIDs).Delete(&models.DriveSearchToken{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id IN ?", itemIDs).Delete(&models.DriveItemTag{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id IN ?", itemIDs).Delete(&models.DriveShareURL{}).Error; err != nil {
+		return err
+	}
+
+	deleted := tx.Where("id IN ?", itemIDs).Delete(&models.DriveItem{})
+	if deleted.Error != nil {
+		return deleted.Error
+	}
+	result.ItemCount = deleted.RowsAffected
+
+	return nil
+}
+
+// MarkItemDeleting hides an item while a background job deletes it and its subtree
+func (r *repo) MarkItemDeleting(ctx context.Context, itemID string) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where("id = ?", itemID).
+		Updates(map[string]interface{}{
+			"is_trashed":  true,
+			"trashed_at":  now,
+			"state":       ITEM_STATE_DELETING,
+			"modified_at": now,
+		}).Error
+}
+
+// GetSubtreeItems retrieves a folder and everything below it, deepest first.
+// Only the columns needed to delete the items and report their removal are loaded.
+func (r *repo) GetSubtreeItems(ctx context.Context, folderID string) ([]*models.DriveItem, error) {
+	var items []models.DriveItem
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id, parent_id, share_id, volume_id, type, 0 AS depth FROM drive_items WHERE id = ?
+			UNION ALL
+			SELECT d.id, d.parent_id, d.share_id, d.volume_id, d.type, s.depth + 1 FROM drive_items d
+			INNER JOIN subtree s ON d.parent_id = s.id
+		)
+		SELECT id, parent_id, share_id, volume_id, type FROM subtree ORDER BY depth DESC`, folderID).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+	return result, nil
+}
+
+// CreateEvents appends change events to their volumes' logs
+func (r *repo) CreateEvents(ctx context.Context, events []*models.DriveEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(&events, MAX_EVENTS_PER_PAGE).Error
+}
+
+// GetEventsSince retrieves a volume's events after the given event ID, oldest first
+func (r *repo) GetEventsSince(ctx context.Context, volumeID string, sinceID int64, limit int) ([]*models.DriveEvent, error) {
+	var events []models.DriveEvent
+	err := r.db.WithContext(ctx).
+		Where("volume_id = ? AND id > ?", volumeID, sinceID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveEvent
+	result := make([]*models.DriveEvent, len(events))
+	for i := range events {
+		result[i] = &events[i]
+	}
+	return result, nil
+}
+
+// GetLatestEventID retrieves the ID of a volume's most recent event, or 0 if it has none
+func (r *repo) GetLatestEventID(ctx context.Context, volumeID string) (int64, error) {
+	var latestID int64
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveEvent{}).
+		Select("COALESCE(MAX(id), 0)").
+		Where("volume_id = ?", volumeID).
+		Scan(&latestID).Error
+	return latestID, err
+}
+
+// UpdateItemLocation persists an item's parent and its encrypted name and passphrase
+func (r *repo) UpdateItemLocation(ctx context.Context, item *models.DriveItem) error {
+	item.ModifiedAt = time.Now().Unix()
+	return r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where("id = ?", item.ID).
+		Updates(map[string]interface{}{
+			"parent_id":                 item.ParentID,
+			"name":                      item.Name,
+			"hash":                      item.Hash,
+			"name_signature_email":      item.NameSignatureEmail,
+			"node_passphrase":           item.NodePassphrase,
+			"node_passphrase_signature": item.NodePassphraseSignature,
+			"signature_email":           item.SignatureEmail,
+			"modified_at":               item.ModifiedAt,
+		}).Error
+}
+
+// UpdateItemXattrs persists an item's encrypted extended attributes
+func (r *repo) UpdateItemXattrs(ctx context.Context, item *models.DriveItem) error {
+	item.ModifiedAt = time.Now().Unix()
+	return r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where("id = ?", item.ID).
+		Updates(map[string]interface{}{
+			"xattrs":      item.Xattrs,
+			"modified_at": item.ModifiedAt,
+		}).Error
+}
+
+// UpdateItemLocations persists the parent, encrypted name and passphrase of several items in one transaction
+func (r *repo) UpdateItemLocations(ctx context.Context, items []*models.DriveItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			item.ModifiedAt = now
+			err := tx.Model(&models.DriveItem{}).
+				Where("id = ?", item.ID).
+				Updates(map[string]interface{}{
+					"parent_id":                 item.ParentID,
+					"name":                      item.Name,
+					"hash":                      item.Hash,
+					"name_signature_email":      item.NameSignatureEmail,
+					"node_passphrase":           item.NodePassphrase,
+					"node_passphrase_signature": item.NodePassphraseSignature,
+					"signature_email":           item.SignatureEmail,
+					"modified_at":               item.ModifiedAt,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateFileCopies creates copied items with the active revisions and blocks of files in one transaction.
+// Parents must come before their children.
+func (r *repo) CreateFileCopies(ctx context.Context, copies []*FileCopy) error {
+	if len(copies) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, fileCopy := range copies {
+			if err := tx.Create(fileCopy.Item).Error; err != nil {
+				return err
+			}
+			if fileCopy.Revision == nil {
+				continue
+			}
+			if err := tx.Create(fileCopy.Revision).Error; err != nil {
+				return err
+			}
+			if len(fileCopy.Blocks) > 0 {
+				if err := tx.Create(fileCopy.Blocks).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// GetLiveSubtree retrieves a folder and every descendant that is not trashed, a draft or being deleted,
+// parents before their children. Trashed folders are left out with their contents.
+func (r *repo) GetLiveSubtree(ctx context.Context, folderID string, limit int) ([]*models.DriveItem, error) {
+	var items []models.DriveItem
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM drive_items WHERE id = ?
+			UNION ALL
+			SELECT d.id, s.depth + 1 FROM drive_items d
+			INNER JOIN subtree s ON d.parent_id = s.id
+			WHERE d.is_trashed = false AND d.state = ?
+		)
+		SELECT d.* FROM drive_items d
+		INNER JOIN subtree s ON d.id = s.id
+		ORDER BY s.depth, d.id
+		LIMIT ?`, folderID, ITEM_STATE_ACTIVE, limit).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+	return result, nil
+}
+
+// GetAncestorIDs retrieves the IDs of every folder above the given folder, nearest first
+func (r *repo) GetAncestorIDs(ctx context.Context, folderID string) ([]string, error) {
+	var ancestorIDs []string
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM drive_items WHERE id = ?
+			UNION ALL
+			SELECT d.id, d.parent_id, a.depth + 1 FROM drive_items d
+			INNER JOIN ancestors a ON d.id = a.parent_id
+		)
+		SELECT id FROM ancestors WHERE depth > 0 ORDER BY depth`, folderID).
+		Scan(&ancestorIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return ancestorIDs, nil
+}
+
+// GetAncestors retrieves the folders above an item up to the root of its share, root first
+func (r *repo) GetAncestors(ctx context.Context, itemID string) ([]*models.DriveItem, error) {
+	var items []models.DriveItem
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM drive_items WHERE id = ?
+			UNION ALL
+			SELECT d.id, d.parent_id, a.depth + 1 FROM drive_items d
+			INNER JOIN ancestors a ON d.id = a.parent_id
+		)
+		SELECT d.* FROM drive_items d
+		INNER JOIN ancestors a ON d.id = a.id
+		WHERE a.depth > 0
+		ORDER BY a.depth DESC`, itemID).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveItem
+	result := make([]*models.DriveItem, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+	return result, nil
+}
+
+// RecomputeFolderSizes sets the total size of every folder of a share to the bytes of the live files
+// below it. Trashed folders and their contents count for nothing.
+func (r *repo) RecomputeFolderSizes(ctx context.Context, shareID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE drive_items SET total_size = 0
+			WHERE share_id = ? AND type = 1 AND total_size <> 0`, shareID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			WITH RECURSIVE tree AS (
+				SELECT id AS folder_id, id FROM drive_items
+				WHERE share_id = ? AND type = 1 AND state = ? AND is_trashed = false
+				UNION ALL
+				SELECT t.folder_id, d.id FROM drive_items d
+				INNER JOIN tree t ON d.parent_id = t.id
+				WHERE d.state = ? AND d.is_trashed = false
+			),
+			sizes AS (
+				SELECT t.folder_id, COALESCE(SUM(d.size) FILTER (WHERE d.type = 2), 0) AS total
+				FROM tree t INNER JOIN drive_items d ON d.id = t.id
+				GROUP BY t.folder_id
+			)
+			UPDATE drive_items SET total_size = sizes.total
+			FROM sizes WHERE drive_items.id = sizes.folder_id`,
+			shareID, ITEM_STATE_ACTIVE, ITEM_STATE_ACTIVE).Error
+	})
+}
+
+// GetFolderUsage retrieves a page of the live children of a folder, largest first. Folders are
+// ranked by their total size and files by their own.
+func (r *repo) GetFolderUsage(ctx context.Context, folderID string, limit, offset int) ([]*models.DriveItem, int, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.DriveItem{}).
+		Where("parent_id = ? AND state = ? AND is_trashed = ?", folderID, ITEM_STATE_ACTIVE, false)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []*models.DriveItem
+	err := query.
+		Order("CASE WHEN type = 1 THEN total_size ELSE size END DESC").
+		Order("id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, int(total), nil
+}
+
+// GetMembershipAnyState retrieves a user's membership for a share whether it is active, pending or declined
+func (r *repo) GetMembershipAnyState(ctx context.Context, shareID, userID string) (*models.DriveShareMembership, error) {
+	var membership models.DriveShareMembership
+	err := r.db.WithContext(ctx).
+		Where("share_id = ? AND user_id = ?", shareID, userID).
+		First(&membership).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMembershipNotFound
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// GetMembershipByID retrieves a membership by its ID
+func (r *repo) GetMembershipByID(ctx context.Context, membershipID string) (*models.DriveShareMembership, error) {
+	var membership models.DriveShareMembership
+	err := r.db.WithContext(ctx).
+		Where("id = ?", membershipID).
+		First(&membership).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMembershipNotFound
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// GetMembershipsByUserIDAndState retrieves a user's memberships in the given state, newest first
+func (r *repo) GetMembershipsByUserIDAndState(ctx context.Context, userID string, state int) ([]*models.DriveShareMembership, error) {
+	var memberships []*models.DriveShareMembership
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND state = ?", userID, state).
+		Order("created_at DESC").
+		Find(&memberships).Error
+
+	return memberships, err
+}
+
+// UpdateMembership updates a membership
+func (r *repo) UpdateMembership(ctx context.Context, membership *models.DriveShareMembership) error {
+	return r.membershipRepo.Update(ctx, membership)
+}
+
+// UpdateMembershipState transitions a membership to a new state
+func (r *repo) UpdateMembershipState(ctx context.Context, membershipID string, state int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveShareMembership{}).
+		Where("id = ?", membershipID).
+		Updates(map[string]interface{}{
+			"state":       state,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// GetMembershipsByShareIDAndState retrieves a share's memberships in the given state, oldest first
+func (r *repo) GetMembershipsByShareIDAndState(ctx context.Context, shareID string, state int) ([]*models.DriveShareMembership, error) {
+	var memberships []*models.DriveShareMembership
+	err := r.db.WithContext(ctx).
+		Where("share_id = ? AND state = ?", shareID, state).
+		Order("modified_at ASC").
+		Find(&memberships).Error
+
+	return memberships, err
+}
+
+// SetShareRequiresApproval turns the membership approval step on or off for a share
+func (r *repo) SetShareRequiresApproval(ctx context.Context, shareID string, required bool) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveShare{}).
+		Where("id = ?", shareID).
+		Updates(map[string]interface{}{
+			"requires_approval": required,
+			"modified_at":       time.Now().Unix(),
+		}).Error
+}
+
+// UpdateMembershipPermissions replaces the permissions a membership grants
+func (r *repo) UpdateMembershipPermissions(ctx context.Context, membershipID string, permissions int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveShareMembership{}).
+		Where("id = ?", membershipID).
+		Updates(map[string]interface{}{
+			"permissions": permissions,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// DeleteMembership deletes a membership, revoking the member's access to the share
+func (r *repo) DeleteMembership(ctx context.Context, membershipID string) error {
+	return r.db.WithContext(ctx).
+		Where("id = ?", membershipID).
+		Delete(&models.DriveShareMembership{}).Error
+}
+
+// TransferShareOwnership makes a member the owner of a share, storing the share passphrase
+// re-wrapped for them, and grants their membership every permission. Returns ErrShareOwnerChanged
+// if the share changed owner since it was loaded.
+func (r *repo) TransferShareOwnership(ctx context.Context, share *models.DriveShare, newOwnerID string, keys ShareTransferKeys) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.DriveShare{}).
+			Where("id = ? AND user_id = ?", share.ID, share.UserID).
+			Updates(map[string]interface{}{
+				"user_id":                    newOwnerID,
+				"share_passphrase":           keys.SharePassphrase,
+				"share_passphrase_signature": keys.SharePassphraseSignature,
+				"modified_at":                now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrShareOwnerChanged
+		}
+
+		return tx.Model(&models.DriveShareMembership{}).
+			Where("share_id = ? AND user_id = ?", share.ID, newOwnerID).
+			Updates(map[string]interface{}{
+				"permissions": ALL_PERMISSIONS,
+				"modified_at": now,
+			}).Error
+	})
+}
+
+// LockSharesByUserID locks the user's active shares that are not locked yet and marks which of
+// their active members can unlock them, returning the IDs of the shares it locked
+func (r *repo) LockSharesByUserID(ctx context.Context, userID string, lockedAt int64) ([]string, error) {
+	var shareIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DriveShare{}).
+			Where("user_id = ? AND state = ? AND locked = ?", userID, 1, false). // State 1 = active
+			Pluck("id", &shareIDs).Error; err != nil {
+			return err
+		}
+		if len(shareIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.DriveShare{}).
+			Where("id IN ?", shareIDs).
+			Updates(map[string]interface{}{
+				"locked":      true,
+				"locked_at":   lockedAt,
+				"modified_at": lockedAt,
+			}).Error; err != nil {
+			return err
+		}
+
+		// Admins keep their own key packets and can re-wrap the passphrase for the owner
+		return tx.Model(&models.DriveShareMembership{}).
+			Where("share_id IN ? AND user_id <> ? AND state = ?", shareIDs, userID, MEMBERSHIP_STATE_ACTIVE).
+			Updates(map[string]interface{}{
+				"can_unlock":  gorm.Expr("permissions & ? <> 0", ADMIN_PERMISSION),
+				"modified_at": lockedAt,
+			}).Error
+	})
+
+	return shareIDs, err
+}
+
+// GetUnlockableMemberships retrieves the user's active memberships of locked shares they own or
+// were marked as able to unlock
+func (r *repo) GetUnlockableMemberships(ctx context.Context, userID string) ([]*models.DriveShareMembership, error) {
+	lockedShares := r.db.Model(&models.DriveShare{}).
+		Select("id").
+		Where("locked = ? AND state = ?", true, 1) // State 1 = active
+	ownedShares := r.db.Model(&models.DriveShare{}).
+		Select("id").
+		Where("user_id = ?", userID)
+
+	var memberships []models.DriveShareMembership
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND state = ? AND share_id IN (?)", userID, MEMBERSHIP_STATE_ACTIVE, lockedShares).
+		Where("can_unlock = ? OR share_id IN (?)", true, ownedShares).
+		Order("created_at ASC").
+		Find(&memberships).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.DriveShareMembership, len(memberships))
+	for i := range memberships {
+		result[i] = &memberships[i]
+	}
+
+	return result, nil
+}
+
+// UnlockShare replaces a locked share's passphrase and its owner's key packet, then clears the lock
+// and every member's unlock flag. Returns ErrShareNotLocked if another unlock got there first.
+func (r *repo) UnlockShare(ctx context.Context, share *models.DriveShare, keys ShareUnlockKeys) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.DriveShare{}).
+			Where("id = ? AND locked = ?", share.ID, true).
+			Updates(map[string]interface{}{
+				"locked":                     false,
+				"locked_at":                  nil,
+				"share_passphrase":           keys.SharePassphrase,
+				"share_passphrase_signature": keys.SharePassphraseSignature,
+				"modified_at":                now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrShareNotLocked
+		}
+
+		if err := tx.Model(&models.DriveShareMembership{}).
+			Where("share_id = ? AND user_id = ? AND state = ?", share.ID, share.UserID, MEMBERSHIP_STATE_ACTIVE).
+			Updates(map[string]interface{}{
+				"key_packet":           keys.OwnerKeyPacket,
+				"key_packet_signature": keys.OwnerKeyPacketSignature,
+				"modified_at":          now,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.DriveShareMembership{}).
+			Where("share_id = ? AND can_unlock IS NOT NULL", share.ID).
+			Updates(map[string]interface{}{
+				"can_unlock":  nil,
+				"modified_at": now,
+			}).Error
+	})
+}
+
+// GetPrimaryUserKeyID retrieves the ID of the user's active primary key
+func (r *repo) GetPrimaryUserKeyID(ctx context.Context, userID string) (string, error) {
+	var key models.UserKey
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND \"primary\" = ? AND active = ?", userID, true, true).
+		Order("created_at DESC").
+		First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNoPrimaryKey
+	}
+	if err != nil {
+		return "", err
+	}
+	return key.ID, nil
+}
+
+// GetLatestKeyRotation retrieves the user's most recently started key rotation
+func (r *repo) GetLatestKeyRotation(ctx context.Context, userID string) (*models.DriveKeyRotation, error) {
+	var rotation models.DriveKeyRotation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&rotation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyRotationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rotation, nil
+}
+
+// CreateKeyRotation supersedes the user's rotation in progress and creates a new one tracking the
+// given shares
+func (r *repo) CreateKeyRotation(ctx context.Context, rotation *models.DriveKeyRotation, shareIDs []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DriveKeyRotation{}).
+			Where("user_id = ? AND state = ?", rotation.UserID, KEY_ROTATION_STATE_IN_PROGRESS).
+			Updates(map[string]interface{}{
+				"state":       KEY_ROTATION_STATE_SUPERSEDED,
+				"modified_at": time.Now().Unix(),
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(rotation).Error; err != nil {
+			return err
+		}
+		if len(shareIDs) == 0 {
+			return nil
+		}
+
+		progress := make([]*models.DriveKeyRotationShare, len(shareIDs))
+		for i, shareID := range shareIDs {
+			progress[i] = &models.DriveKeyRotationShare{RotationID: rotation.ID, ShareID: shareID}
+		}
+		return tx.Create(&progress).Error
+	})
+}
+
+// GetKeyRotationShares retrieves the progress of every share of a key rotation
+func (r *repo) GetKeyRotationShares(ctx context.Context, rotationID string) ([]*models.DriveKeyRotationShare, error) {
+	var progress []*models.DriveKeyRotationShare
+	err := r.db.WithContext(ctx).
+		Where("rotation_id = ?", rotationID).
+		Order("share_id ASC").
+		Find(&progress).Error
+	return progress, err
+}
+
+// GetKeyRotationShare retrieves the progress of a key rotation in one share
+func (r *repo) GetKeyRotationShare(ctx context.Context, rotationID, shareID string) (*models.DriveKeyRotationShare, error) {
+	var progress models.DriveKeyRotationShare
+	err := r.db.WithContext(ctx).
+		Where("rotation_id = ? AND share_id = ?", rotationID, shareID).
+		First(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrShareNotInRotation
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// CountKeyRotationItemsRemaining counts the items past the cursor of each share of a key rotation
+func (r *repo) CountKeyRotationItemsRemaining(ctx context.Context, rotationID string) (map[string]int64, error) {
+	var rows []struct {
+		ShareID string
+		Count   int64
+	}
+	err := r.db.WithContext(ctx).
+		Table("drive_key_rotation_shares AS p").
+		Select("p.share_id, COUNT(i.id) AS count").
+		Joins("JOIN drive_items AS i ON i.share_id = p.share_id AND i.id > p.item_cursor").
+		Where("p.rotation_id = ?", rotationID).
+		Group("p.share_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		remaining[row.ShareID] = row.Count
+	}
+	return remaining, nil
+}
+
+// GetItemsAfterID retrieves the items of a share following an item ID, in ID order
+func (r *repo) GetItemsAfterID(ctx context.Context, shareID, afterID string, limit int) ([]*models.DriveItem, error) {
+	var items []*models.DriveItem
+	err := r.db.WithContext(ctx).
+		Where("share_id = ? AND id > ?", shareID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&items).Error
+	return items, err
+}
+
+// ApplyKeyRotationBatch stores re-wrapped share and node passphrases and moves the share's rotation
+// progress past them. The share passphrase also clears a lock placed when the key was replaced.
+// Returns ErrStaleRotationBatch when another batch moved the progress first.
+func (r *repo) ApplyKeyRotationBatch(ctx context.Context, share *models.DriveShare, progress *models.DriveKeyRotationShare, batch KeyRotationBatch, cursor string) error {
+	now := time.Now().Unix()
+	passphraseRotated := progress.PassphraseRotated || batch.ShareKeys != nil
+	var completedAt *int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if keys := batch.ShareKeys; keys != nil {
+			if err := tx.Model(&models.DriveShare{}).
+				Where("id = ?", share.ID).
+				Updates(map[string]interface{}{
+					"locked":                     false,
+					"locked_at":                  nil,
+					"share_passphrase":           keys.SharePassphrase,
+					"share_passphrase_signature": keys.SharePassphraseSignature,
+					"modified_at":                now,
+				}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.DriveShareMembership{}).
+				Where("share_id = ? AND user_id = ? AND state = ?", share.ID, share.UserID, MEMBERSHIP_STATE_ACTIVE).
+				Updates(map[string]interface{}{
+					"key_packet":           keys.OwnerKeyPacket,
+					"key_packet_signature": keys.OwnerKeyPacketSignature,
+					"modified_at":          now,
+				}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.DriveShareMembership{}).
+				Where("share_id = ? AND can_unlock IS NOT NULL", share.ID).
+				Updates(map[string]interface{}{
+					"can_unlock":  nil,
+					"modified_at": now,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, item := range batch.Items {
+			if err := tx.Model(&models.DriveItem{}).
+				Where("id = ? AND share_id = ?", item.LinkID, share.ID).
+				Updates(map[string]interface{}{
+					"node_passphrase":           item.NodePassphrase,
+					"node_passphrase_signature": item.NodePassphraseSignature,
+					"modified_at":               now,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		var pending int64
+		if err := tx.Model(&models.DriveItem{}).
+			Where("share_id = ? AND id > ?", share.ID, cursor).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if passphraseRotated && pending == 0 {
+			completedAt = &now
+		}
+
+		result := tx.Model(&models.DriveKeyRotationShare{}).
+			Where("id = ? AND item_cursor = ? AND completed_at IS NULL", progress.ID, progress.ItemCursor).
+			Updates(map[string]interface{}{
+				"passphrase_rotated": passphraseRotated,
+				"items_rotated":      gorm.Expr("items_rotated + ?", len(batch.Items)),
+				"item_cursor":        cursor,
+				"completed_at":       completedAt,
+				"modified_at":        now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStaleRotationBatch
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	progress.PassphraseRotated = passphraseRotated
+	progress.ItemsRotated += int64(len(batch.Items))
+	progress.ItemCursor = cursor
+	progress.CompletedAt = completedAt
+	progress.ModifiedAt = now
+	return nil
+}
+
+// CompleteKeyRotation marks a key rotation completed when every share it tracks that is still active
+// was rotated. Returns whether the rotation was completed by this call.
+func (r *repo) CompleteKeyRotation(ctx context.Context, rotationID string, completedAt int64) (bool, error) {
+	pendingShares := r.db.Model(&models.DriveKeyRotationShare{}).
+		Select("1").
+		Joins("JOIN drive_shares ON drive_shares.id = drive_key_rotation_shares.share_id").
+		Where("drive_key_rotation_shares.rotation_id = drive_key_rotations.id AND drive_key_rotation_shares.completed_at IS NULL AND drive_shares.state = ?", 1) // State 1 = active
+
+	result := r.db.WithContext(ctx).
+		Model(&models.DriveKeyRotation{}).
+		Where("id = ? AND state = ? AND NOT EXISTS (?)", rotationID, KEY_ROTATION_STATE_IN_PROGRESS, pendingShares).
+		Updates(map[string]interface{}{
+			"state":        KEY_ROTATION_STATE_COMPLETED,
+			"completed_at": completedAt,
+			"modified_at":  completedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetShareAdmins retrieves the share owner and every active member holding admin permission
+func (r *repo) GetShareAdmins(ctx context.Context, share *models.DriveShare) ([]*models.User, error) {
+	adminIDs := r.db.Model(&models.DriveShareMembership{}).
+		Select("user_id").
+		Where("share_id = ? AND state = ? AND permissions & ? <> 0", share.ID, MEMBERSHIP_STATE_ACTIVE, ADMIN_PERMISSION)
+
+	var admins []*models.User
+	err := r.db.WithContext(ctx).
+		Where("active = ?", true).
+		Where("id = ? OR id IN (?)", share.UserID, adminIDs).
+		Find(&admins).Error
+
+	return admins, err
+}
+
+// GetUserByID retrieves a user by ID
+func (r *repo) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Where("id = ?", userID).
+		First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUsersByEmails retrieves the users with the given lowercase emails, keyed by lowercase email
+func (r *repo) GetUsersByEmails(ctx context.Context, emails []string) (map[string]*models.User, error) {
+	result := make(map[string]*models.User, len(emails))
+	if len(emails) == 0 {
+		return result, nil
+	}
+
+	var users []*models.User
+	err := r.db.WithContext(ctx).
+		Where("LOWER(email) IN ?", emails).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		result[strings.ToLower(user.Email)] = user
+	}
+	return result, nil
+}
+
+// GetActivePlanTier retrieves the highest tier among a user's active plans, or zero when the user has none
+func (r *repo) GetActivePlanTier(ctx context.Context, userID string) (int, error) {
+	var tier int
+	err := r.db.WithContext(ctx).
+		Model(&models.UserPlan{}).
+		Select("COALESCE(MAX(plans.tier), 0)").
+		Joins("JOIN plans ON plans.id = users_plans.plan_id").
+		Where("users_plans.user_id = ? AND users_plans.status = ?", userID, "active").
+		Scan(&tier).Error
+
+	return tier, err
+}
+
+// CreateSecurityEvent records a security event against a user
+func (r *repo) CreateSecurityEvent(ctx context.Context, event *models.UserSecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// GetVolumeStorageUsage sums the stored blocks of a volume by storage class and region
+func (r *repo) GetVolumeStorageUsage(ctx context.Context, volumeID string) ([]*StorageUsage, error) {
+	var usage []StorageUsage
+	err := r.db.WithContext(ctx).
+		Table("file_blocks").
+		Select("file_blocks.storage_class, file_blocks.storage_region, file_blocks.replication_region, "+
+			"SUM(file_blocks.size) AS bytes, COUNT(*) AS block_count").
+		Joins("JOIN file_revisions ON file_revisions.id = file_blocks.revision_id").
+		Joins("JOIN drive_items ON drive_items.id = file_revisions.item_id").
+		Where("drive_items.volume_id = ?", volumeID).
+		Group("file_blocks.storage_class, file_blocks.storage_region, file_blocks.replication_region").
+		Scan(&usage).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*StorageUsage
+	result := make([]*StorageUsage, len(usage))
+	for i := range usage {
+		result[i] = &usage[i]
+	}
+
+	return result, nil
+}
+
+// SetVolumeReplication turns cross-region replication of new blocks on or off for a volume
+func (r *repo) SetVolumeReplication(ctx context.Context, volumeID string, enabled bool) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.DriveVolume{}).
+		Where("id = ?", volumeID).
+		Updates(map[string]any{
+			"cross_region_replication": enabled,
+			"updated_at":               time.Now().Unix(),
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVolumeNotFound
+	}
+	return nil
+}
+
+// GetActivePlanTypes retrieves the plan types of a user's active plans
+func (r *repo) GetActivePlanTypes(ctx context.Context, userID string) ([]string, error) {
+	var planTypes []string
+	err := r.db.WithContext(ctx).
+		Model(&models.UserPlan{}).
+		Where("user_id = ? AND status = ?", userID, "active").
+		Pluck("plan_type", &planTypes).Error
+
+	return planTypes, err
+}
+
+// ResizeOwnerStorage sets a volume's size and its owner's allocated size together.
+// Only the size columns are written so concurrent usage accounting is not overwritten.
+// It joins the transaction ctx carries, if any.
+func (r *repo) ResizeOwnerStorage(ctx context.Context, volumeID, allocationID string, size int64) error {
+	now := time.Now().Unix()
+	return db.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DriveVolume{}).
+			Where("id = ?", volumeID).
+			Updates(map[string]any{
+				"size":       size,
+				"updated_at": now,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.VolumeAllocation{}).
+			Where("id = ?", allocationID).
+			Updates(map[string]any{
+				"allocated_size": size,
+				"modified_at":    now,
+			}).Error
+	})
+}
+
+// taggedItemIDs selects the items that carry every one of the tags (AND semantics)
+func (r *repo) taggedItemIDs(ctx context.Context, tagIDs []string) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveItemTag{}).
+		Select("item_id").
+		Where("tag_id IN ?", tagIDs).
+		Group("item_id").
+		Having("COUNT(DISTINCT tag_id) = ?", len(tagIDs))
+}
+
+// GetTagsByUserID retrieves a user's tags, oldest first
+func (r *repo) GetTagsByUserID(ctx context.Context, userID string) ([]*models.DriveTag, error) {
+	var tags []models.DriveTag
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").Order("id ASC").
+		Find(&tags).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*DriveTag
+	result := make([]*models.DriveTag, len(tags))
+	for i := range tags {
+		result[i] = &tags[i]
+	}
+
+	return result, nil
+}
+
+// GetTagByID retrieves a tag by ID
+func (r *repo) GetTagByID(ctx context.Context, tagID string) (*models.DriveTag, error) {
+	var tag models.DriveTag
+	err := r.db.WithContext(ctx).
+		Where("id = ?", tagID).
+		First(&tag).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// CountTagsByUserID counts a user's tags
+func (r *repo) CountTagsByUserID(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveTag{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error
+
+	return count, err
+}
+
+// CountUserTagsByIDs counts how many of the given tags belong to the user
+func (r *repo) CountUserTagsByIDs(ctx context.Context, userID string, tagIDs []string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveTag{}).
+		Where("user_id = ? AND id IN ?", userID, tagIDs).
+		Count(&count).Error
+
+	return count, err
+}
+
+// CreateTag creates a new tag
+func (r *repo) CreateTag(ctx context.Context, tag *models.DriveTag) error {
+	return r.db.WithContext(ctx).Omit("Items").Create(tag).Error
+}
+
+// UpdateTag updates a tag's label and color
+func (r *repo) UpdateTag(ctx context.Context, tag *models.DriveTag) error {
+	return r.db.WithContext(ctx).
+		Model(tag).
+		Select("encrypted_name", "color", "modified_at").
+		Updates(tag).Error
+}
+
+// DeleteTag deletes a tag and detaches it from every item
+func (r *repo) DeleteTag(ctx context.Context, tagID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", tagID).Delete(&models.DriveItemTag{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ?", tagID).Delete(&models.DriveTag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTagNotFound
+		}
+		return nil
+	})
+}
+
+// GetItemTagIDs retrieves the IDs of a user's tags on an item
+func (r *repo) GetItemTagIDs(ctx context.Context, itemID, userID string) ([]string, error) {
+	tagIDs := []string{}
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveItemTag{}).
+		Where("item_id = ? AND user_id = ?", itemID, userID).
+		Order("created_at ASC").
+		Pluck("tag_id", &tagIDs).Error
+
+	return tagIDs, err
+}
+
+// ReplaceItemTags atomically replaces a user's tags on an item
+func (r *repo) ReplaceItemTags(ctx context.Context, itemID, userID string, itemTags []*models.DriveItemTag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("item_id = ? AND user_id = ?", itemID, userID).
+			Delete(&models.DriveItemTag{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(itemTags) == 0 {
+			return nil
+		}
+
+		return tx.Omit("Tag", "Item").Create(&itemTags).Error
+	})
+}
+
+// SetShareExpiry sets or clears when a share's members and public links lose access.
+// A new expiry date gets a new advance notice.
+func (r *repo) SetShareExpiry(ctx context.Context, shareID string, expiresAt *int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveShare{}).
+		Where("id = ?", shareID).
+		Updates(map[string]interface{}{
+			"expires_at":         expiresAt,
+			"expiry_notified_at": nil,
+			"modified_at":        time.Now().Unix(),
+		}).Error
+}
+
+// GetSharesDueForExpiryNotice retrieves shares expiring within the notice window whose members were not notified yet
+func (r *repo) GetSharesDueForExpiryNotice(ctx context.Context, now, noticeUntil int64, limit int) ([]*models.DriveShare, error) {
+	var shares []models.DriveShare
+	err := r.db.WithContext(ctx).
+		Where("expires_at > ? AND expires_at <= ? AND expiry_notified_at IS NULL", now, noticeUntil).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&shares).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*models.DriveShare
+	result := make([]*models.DriveShare, len(shares))
+	for i := range shares {
+		result[i] = &shares[i]
+	}
+
+	return result, nil
+}
+
+// MarkShareExpiryNotified records that a share's members were told about its expiry.
+// It reports false when the notice was already sent.
+func (r *repo) MarkShareExpiryNotified(ctx context.Context, shareID string, notifiedAt int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.DriveShare{}).
+		Where("id = ? AND expiry_notified_at IS NULL", shareID).
+		Update("expiry_notified_at", notifiedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetActiveShareMembers retrieves the users holding an active membership of a share
+func (r *repo) GetActiveShareMembers(ctx context.Context, shareID string) ([]*models.User, error) {
+	memberIDs := r.db.Model(&models.DriveShareMembership{}).
+		Select("user_id").
+		Where("share_id = ? AND state = ?", shareID, MEMBERSHIP_STATE_ACTIVE)
+
+	var members []*models.User
+	err := r.db.WithContext(ctx).
+		Where("active = ?", true).
+		Where("id IN (?)", memberIDs).
+		Find(&members).Error
+
+	return members, err
+}
+
+// GetExpiredShareIDs retrieves expired shares that still have active members or public links
+func (r *repo) GetExpiredShareIDs(ctx context.Context, now int64, limit int) ([]string, error) {
+	activeMembers := r.db.Model(&models.DriveShareMembership{}).
+		Select("1").
+		Where("drive_share_memberships.share_id = drive_shares.id AND drive_share_memberships.state = ?", MEMBERSHIP_STATE_ACTIVE)
+	activeURLs := r.db.Model(&models.DriveShareURL{}).
+		Select("1").
+		Where("drive_share_urls.share_id = drive_shares.id AND drive_share_urls.state = ?", SHARE_URL_STATE_ACTIVE)
+
+	var shareIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveShare{}).
+		Where("expires_at <= ?", now).
+		Where("EXISTS (?) OR EXISTS (?)", activeMembers, activeURLs).
+		Order("expires_at ASC").
+		Limit(limit).
+		Pluck("id", &shareIDs).Error
+
+	return shareIDs, err
+}
+
+// ExpireShareAccess moves a share's active memberships and public links to the expired state in one
+// transaction, so renewing the share can restore exactly those. It returns the members that lost access.
+func (r *repo) ExpireShareAccess(ctx context.Context, shareID string) ([]string, error) {
+	var userIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.DriveShareMembership{}).
+			Where("share_id = ? AND state = ?", shareID, MEMBERSHIP_STATE_ACTIVE).
+			Pluck("user_id", &userIDs).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		err = tx.Model(&models.DriveShareMembership{}).
+			Where("share_id = ? AND state = ?", shareID, MEMBERSHIP_STATE_ACTIVE).
+			Updates(map[string]interface{}{
+				"state":       MEMBERSHIP_STATE_EXPIRED,
+				"modified_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.DriveShareURL{}).
+			Where("share_id = ? AND state = ?", shareID, SHARE_URL_STATE_ACTIVE).
+			Updates(map[string]interface{}{
+				"state":       SHARE_URL_STATE_EXPIRED,
+				"modified_at": now,
+			}).Error
+	})
+
+	return userIDs, err
+}
+
+// RestoreShareAccess reactivates the memberships and public links a share lost when it expired.
+// Links whose own expiry has passed stay expired. It returns the members that got access back.
+func (r *repo) RestoreShareAccess(ctx context.Context, shareID string, now int64) ([]string, error) {
+	var userIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.DriveShareMembership{}).
+			Where("share_id = ? AND state = ?", shareID, MEMBERSHIP_STATE_EXPIRED).
+			Pluck("user_id", &userIDs).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.DriveShareMembership{}).
+			Where("share_id = ? AND state = ?", shareID, MEMBERSHIP_STATE_EXPIRED).
+			Updates(map[string]interface{}{
+				"state":       MEMBERSHIP_STATE_ACTIVE,
+				"modified_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.DriveShareURL{}).
+			Where("share_id = ? AND state = ?", shareID, SHARE_URL_STATE_EXPIRED).
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Updates(map[string]interface{}{
+				"state":       SHARE_URL_STATE_ACTIVE,
+				"modified_at": now,
+			}).Error
+	})
+
+	return userIDs, err
+}
+
+// ExpireMemberships moves memberships past their own permission expiry to the permission-expired
+// state and returns them. Invitations and memberships that lost access with their share are included,
+// so neither accepting nor renewing the share gives access back.
+func (r *repo) ExpireMemberships(ctx context.Context, now int64, limit int) ([]*models.DriveShareMembership, error) {
+	states := []int{MEMBERSHIP_STATE_ACTIVE, MEMBERSHIP_STATE_PENDING, MEMBERSHIP_STATE_AWAITING_APPROVAL, MEMBERSHIP_STATE_EXPIRED}
+
+	var memberships []models.DriveShareMembership
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("state IN ? AND permission_expires_at <= ?", states, now).
+			Order("permission_expires_at ASC").
+			Limit(limit).
+			Find(&memberships).Error
+		if err != nil || len(memberships) == 0 {
+			return err
+		}
+
+		membershipIDs := make([]string, len(memberships))
+		for i := range memberships {
+			membershipIDs[i] = memberships[i].ID
+			memberships[i].State = MEMBERSHIP_STATE_PERMISSION_EXPIRED
+		}
+
+		return tx.Model(&models.DriveShareMembership{}).
+			Where("id IN ? AND state IN ?", membershipIDs, states).
+			Updates(map[string]interface{}{
+				"state":       MEMBERSHIP_STATE_PERMISSION_EXPIRED,
+				"modified_at": now,
+			}).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.DriveShareMembership, len(memberships))
+	for i := range memberships {
+		result[i] = &memberships[i]
+	}
+
+	return result, nil
+}
+
+// ExpireShareURLs moves active public links past their own expiry to the expired state and returns them
+func (r *repo) ExpireShareURLs(ctx context.Context, now int64, limit int) ([]*models.DriveShareURL, error) {
+	var shareURLs []models.DriveShareURL
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("state = ? AND expires_at <= ?", SHARE_URL_STATE_ACTIVE, now).
+			Order("expires_at ASC").
+			Limit(limit).
+			Find(&shareURLs).Error
+		if err != nil || len(shareURLs) == 0 {
+			return err
+		}
+
+		urlIDs := make([]string, len(shareURLs))
+		for i := range shareURLs {
+			urlIDs[i] = shareURLs[i].ID
+			shareURLs[i].State = SHARE_URL_STATE_EXPIRED
+		}
+
+		return tx.Model(&models.DriveShareURL{}).
+			Where("id IN ? AND state = ?", urlIDs, SHARE_URL_STATE_ACTIVE).
+			Updates(map[string]interface{}{
+				"state":       SHARE_URL_STATE_EXPIRED,
+				"modified_at": now,
+			}).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*models.DriveShareURL
+	result := make([]*models.DriveShareURL, len(shareURLs))
+	for i := range shareURLs {
+		result[i] = &shareURLs[i]
+	}
+
+	return result, nil
+}
+
+// SampleActiveRevisions picks random active revisions with their uploaded blocks and their item
+func (r *repo) SampleActiveRevisions(ctx context.Context, limit int) ([]*models.FileRevision, error) {
+	var revisions []*models.FileRevision
+	err := r.db.WithContext(ctx).
+		Preload("Item").
+		Preload("Blocks", "upload_complete = ?", true).
+		Where("state = ?", REVISION_STATE_ACTIVE).
+		Order("random()").
+		Limit(limit).
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevisionsWithOpenIntegrityIssues loads revisions that have open issues so they are checked again
+func (r *repo) GetRevisionsWithOpenIntegrityIssues(ctx context.Context, limit int) ([]*models.FileRevision, error) {
+	var revisions []*models.FileRevision
+	err := r.db.WithContext(ctx).
+		Preload("Item").
+		Preload("Blocks", "upload_complete = ?", true).
+		Where("id IN (?)", r.db.Model(&models.StorageIntegrityIssue{}).
+			Select("revision_id").
+			Where("state = ?", INTEGRITY_ISSUE_STATE_OPEN).
+			Order("last_checked_at ASC").
+			Limit(limit)).
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// RecordIntegrityIssue stores a discrepancy, updating the block's existing issue if there is one.
+// An issue that comes back after being resolved is reopened and its owner notified again.
+func (r *repo) RecordIntegrityIssue(ctx context.Context, issue *models.StorageIntegrityIssue) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "block_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"kind":            issue.Kind,
+			"actual_size":     issue.ActualSize,
+			"actual_checksum": issue.ActualChecksum,
+			"etag":            issue.ETag,
+			"recoverable":     issue.Recoverable,
+			"state":           INTEGRITY_ISSUE_STATE_OPEN,
+			"last_checked_at": issue.LastCheckedAt,
+			"resolved_at":     nil,
+			"notified_at": gorm.Expr("CASE WHEN storage_integrity_issues.state = ? THEN NULL ELSE storage_integrity_issues.notified_at END",
+				INTEGRITY_ISSUE_STATE_RESOLVED),
+		}),
+	}).Create(issue).Error
+}
+
+// ResolveIntegrityIssues closes the open issues of blocks that were found intact
+func (r *repo) ResolveIntegrityIssues(ctx context.Context, blockIDs []string, now int64) (int64, error) {
+	if len(blockIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.StorageIntegrityIssue{}).
+		Where("block_id IN ? AND state = ?", blockIDs, INTEGRITY_ISSUE_STATE_OPEN).
+		Updates(map[string]interface{}{
+			"state":           INTEGRITY_ISSUE_STATE_RESOLVED,
+			"resolved_at":     now,
+			"last_checked_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// GetUnnotifiedIrrecoverableIssues retrieves open issues no replica can repair whose owner was not told yet
+func (r *repo) GetUnnotifiedIrrecoverableIssues(ctx context.Context, limit int) ([]*models.StorageIntegrityIssue, error) {
+	var issues []*models.StorageIntegrityIssue
+	err := r.db.WithContext(ctx).
+		Where("state = ? AND recoverable = ? AND notified_at IS NULL", INTEGRITY_ISSUE_STATE_OPEN, false).
+		Order("user_id ASC, detected_at ASC").
+		Limit(limit).
+		Find(&issues).Error
+	return issues, err
+}
+
+// MarkIntegrityIssuesNotified records that the owners of issues were notified
+func (r *repo) MarkIntegrityIssuesNotified(ctx context.Context, issueIDs []string, now int64) error {
+	if len(issueIDs) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Model(&models.StorageIntegrityIssue{}).
+		Where("id IN ?", issueIDs).
+		Update("notified_at", now).Error
+}
+
+// GetIntegrityReport counts issues and lists the most recently checked ones matching the filter
+func (r *repo) GetIntegrityReport(ctx context.Context, filter IntegrityFilter, since int64) (*IntegrityReport, error) {
+	db := r.db.WithContext(ctx)
+	report := &IntegrityReport{OpenByKind: map[string]int64{}}
+
+	var kinds []struct {
+		Kind  string
+		Count int64
+	}
+	err := db.Model(&models.StorageIntegrityIssue{}).
+		Select("kind, COUNT(*) AS count").
+		Where("state = ?", INTEGRITY_ISSUE_STATE_OPEN).
+		Group("kind").
+		Scan(&kinds).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range kinds {
+		report.OpenByKind[kind.Kind] = kind.Count
+	}
+
+	err = db.Model(&models.StorageIntegrityIssue{}).
+		Where("state = ? AND recoverable = ?", INTEGRITY_ISSUE_STATE_OPEN, false).
+		Count(&report.OpenIrrecoverable).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(&models.StorageIntegrityIssue{}).
+		Where("state = ?", INTEGRITY_ISSUE_STATE_OPEN).
+		Distinct("user_id").
+		Count(&report.AffectedUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(&models.StorageIntegrityIssue{}).
+		Where("state = ? AND resolved_at >= ?", INTEGRITY_ISSUE_STATE_RESOLVED, since).
+		Count(&report.ResolvedSince).Error
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Model(&models.StorageIntegrityIssue{})
+	if filter.State != "" {
+		query = query.Where("state = ?", filter.State)
+	}
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if err := query.Order("last_checked_at DESC").Limit(filter.Limit).Find(&report.Issues).Error; err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetUserDevice retrieves one of a user's active devices by its record ID
+func (r *repo) GetUserDevice(ctx context.Context, userID, deviceID string) (*models.UserDevice, error) {
+	var device models.UserDevice
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND active = ?", deviceID, userID, true).
+		First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetSessionDeviceUID retrieves the client-generated device identifier of a valid session
+func (r *repo) GetSessionDeviceUID(ctx context.Context, sessionID string) (string, error) {
+	var deviceUIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.UserSession{}).
+		Where("id = ? AND is_valid = ?", sessionID, true).
+		Limit(1).
+		Pluck("device_id", &deviceUIDs).Error
+	if err != nil {
+		return "", err
+	}
+	if len(deviceUIDs) == 0 {
+		return "", ErrDeviceNotFound
+	}
+	return deviceUIDs[0], nil
+}
+
+// CreateBackupSet creates a backup share, its owner's membership and the backup set in one transaction
+func (r *repo) CreateBackupSet(
+	ctx context.Context,
+	share *models.DriveShare,
+	membership *models.DriveShareMembership,
+	set *models.DriveBackupSet,
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(share).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(membership).Error; err != nil {
+			return err
+		}
+		return tx.Create(set).Error
+	})
+}
+
+// GetBackupSetByID retrieves a backup set
+func (r *repo) GetBackupSetByID(ctx context.Context, setID string) (*models.DriveBackupSet, error) {
+	var set models.DriveBackupSet
+	err := r.db.WithContext(ctx).Where("id = ?", setID).First(&set).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBackupSetNotFound
+		}
+		return nil, err
+	}
+	return &set, nil
+}
+
+// GetBackupSetByShareID retrieves the backup set stored in a share
+func (r *repo) GetBackupSetByShareID(ctx context.Context, shareID string) (*models.DriveBackupSet, error) {
+	var set models.DriveBackupSet
+	err := r.db.WithContext(ctx).Where("share_id = ?", shareID).First(&set).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBackupSetNotFound
+		}
+		return nil, err
+	}
+	return &set, nil
+}
+
+// GetBackupSetsByUserID retrieves a user's backup sets that are not being deleted, optionally
+// limited to one device
+func (r *repo) GetBackupSetsByUserID(ctx context.Context, userID, deviceID string) ([]*models.DriveBackupSet, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Share").
+		Where("user_id = ? AND state = ?", userID, BACKUP_SET_STATE_ACTIVE)
+	if deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+
+	var sets []*models.DriveBackupSet
+	if err := query.Order("created_at ASC").Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+// CountBackupSetsByDeviceID counts the backup sets of a device, including those being deleted
+func (r *repo) CountBackupSetsByDeviceID(ctx context.Context, deviceID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.DriveBackupSet{}).
+		Where("device_id = ?", deviceID).
+		Count(&count).Error
+	return count, err
+}
+
+// UpdateBackupSetRules replaces the versioning and retention rules of a backup set
+func (r *repo) UpdateBackupSetRules(ctx context.Context, setID string, maxVersions, retentionDays int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveBackupSet{}).
+		Where("id = ?", setID).
+		Updates(map[string]interface{}{
+			"max_versions":   maxVersions,
+			"retention_days": retentionDays,
+			"modified_at":    time.Now().Unix(),
+		}).Error
+}
+
+// MarkBackupSetDeleting hides a backup set while a background job deletes its contents
+func (r *repo) MarkBackupSetDeleting(ctx context.Context, setID string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveBackupSet{}).
+		Where("id = ?", setID).
+		Updates(map[string]interface{}{
+			"state":       BACKUP_SET_STATE_DELETING,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// TouchBackupSet records when a file was last backed up to the set stored in a share
+func (r *repo) TouchBackupSet(ctx context.Context, shareID string, backupAt int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DriveBackupSet{}).
+		Where("share_id = ?", shareID).
+		Update("last_backup_at", backupAt).Error
+}
+
+// GetActiveBackupSets retrieves active backup sets in ID order, starting after afterID
+func (r *repo) GetActiveBackupSets(ctx context.Context, afterID string, limit int) ([]*models.DriveBackupSet, error) {
+	var sets []*models.DriveBackupSet
+	err := r.db.WithContext(ctx).
+		Where("state = ? AND id > ?", BACKUP_SET_STATE_ACTIVE, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&sets).Error
+	return sets, err
+}
+
+// GetPrunableRevisionIDs retrieves previous revisions of a share's files that fall outside its
+// versioning rules: those past the newest maxVersions revisions of their file, counting the current
+// one, and those created before createdBefore
+func (r *repo) GetPrunableRevisionIDs(
+	ctx context.Context,
+	shareID string,
+	maxVersions int,
+	createdBefore int64,
+	limit int,
+) ([]string, error) {
+	var revisionIDs []string
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT id FROM (
+			SELECT r.id, r.created_at,
+				ROW_NUMBER() OVER (PARTITION BY r.item_id ORDER BY r.created_at DESC, r.id DESC) AS version
+			FROM file_revisions r
+			JOIN drive_items i ON i.id = r.item_id
+			WHERE i.share_id = ? AND r.state = ?
+		) previous
+		WHERE version >= ? OR created_at < ?
+		LIMIT ?`, shareID, REVISION_STATE_OBSOLETE, maxVersions, createdBefore, limit).
+		Scan(&revisionIDs).Error
+	return revisionIDs, err
+}
+
+// PurgeRevisions permanently deletes previous revisions and their blocks and thumbnails. Active and
+// draft revisions are never deleted.
+func (r *repo) PurgeRevisions(ctx context.Context, revisionIDs []string) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	if len(revisionIDs) == 0 {
+		return result, nil
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var obsoleteIDs []string
+		err := tx.Model(&models.FileRevision{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND state = ?", revisionIDs, REVISION_STATE_OBSOLETE).
+			Pluck("id", &obsoleteIDs).Error
+		if err != nil || len(obsoleteIDs) == 0 {
+			return err
+		}
+
+		err = tx.Model(&models.FileRevision{}).
+			Select("COALESCE(SUM(size), 0)").
+			Where("id IN ?", obsoleteIDs).
+			Scan(&result.FileBytes).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.FileBlock{}).
+			Where("revision_id IN ? AND storage_path <> ''", obsoleteIDs).
+			Pluck("storage_path", &result.StoragePaths).Error
+		if err != nil {
+			return err
+		}
+
+		var thumbnailPaths []string
+		err = tx.Model(&models.DriveThumbnail{}).
+			Where("revision_id IN ? AND storage_path <> ''", obsoleteIDs).
+			Pluck("storage_path", &thumbnailPaths).Error
+		if err != nil {
+			return err
+		}
+		result.StoragePaths = append(result.StoragePaths, thumbnailPaths...)
+
+		if err := tx.Where("revision_id IN ?", obsoleteIDs).Delete(&models.FileBlock{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("revision_id IN ?", obsoleteIDs).Delete(&models.DriveThumbnail{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", obsoleteIDs).Delete(&models.FileRevision{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteBackupShare deletes a backup set together with its share and memberships once the share's
+// items have been purged
+func (r *repo) DeleteBackupShare(ctx context.Context, setID, shareID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", setID).Delete(&models.DriveBackupSet{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("share_id = ?", shareID).Delete(&models.DriveShareMembership{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND type = ?", shareID, SHARE_TYPE_BACKUP).Delete(&models.DriveShare{}).Error
+	})
+}
+
+// UpsertSyncCursor stores where a device is in a volume's change log, replacing the position it
+// acknowledged before
+func (r *repo) UpsertSyncCursor(ctx context.Context, cursor *models.DriveSyncCursor) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "device_id"}, {Name: "volume_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_event_id":   cursor.LastEventID,
+			"acknowledged_at": cursor.AcknowledgedAt,
+			"modified_at":     cursor.AcknowledgedAt,
+		}),
+	}, clause.Returning{}).Create(cursor).Error
+}
+
+// GetSyncCursorsByUserID retrieves the sync cursors of a user's active devices
+func (r *repo) GetSyncCursorsByUserID(ctx context.Context, userID string) ([]*models.DriveSyncCursor, error) {
+	var cursors []*models.DriveSyncCursor
+	err := r.db.WithContext(ctx).
+		Joins("JOIN users_devices ON users_devices.id = drive_sync_cursors.device_id").
+		Where("drive_sync_cursors.user_id = ? AND users_devices.active = ?", userID, true).
+		Order("drive_sync_cursors.volume_id ASC, drive_sync_cursors.last_event_id DESC").
+		Find(&cursors).Error
+	return cursors, err
+}
+
+// CountEventsSince counts a volume's events after the given event ID, up to the limit
+func (r *repo) CountEventsSince(ctx context.Context, volumeID string, sinceID int64, limit int) (int64, error) {
+	pending := r.db.WithContext(ctx).
+		Model(&models.DriveEvent{}).
+		Select("1").
+		Where("volume_id = ? AND id > ?", volumeID, sinceID).
+		Limit(limit)
+
+	var count int64
+	err := r.db.WithContext(ctx).Table("(?) AS pending", pending).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,213 @@
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	utils "cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/config"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// JOB_TYPE_SANDBOX_RESET restores every sandbox drive to synthetic data
+const JOB_TYPE_SANDBOX_RESET = "drive.sandbox_reset"
+
+// SANDBOX_RESET_BATCH_SIZE is how many drives are loaded per page of the reset job
+const SANDBOX_RESET_BATCH_SIZE = 100
+
+// SANDBOX_SAMPLES_FOLDER is the folder synthetic files are created in
+const SANDBOX_SAMPLES_FOLDER = "Sandbox samples"
+
+// SANDBOX_PLACEHOLDER stands in for key material of synthetic items, which have no owner keys behind them
+const SANDBOX_PLACEHOLDER = "sandbox"
+
+// sandboxSampleKinds are the file names and types synthetic files cycle through
+var sandboxSampleKinds = []struct {
+	name     string
+	mimeType string
+}{
+	{"Meeting notes %02d.txt", "text/plain"},
+	{"Quarterly report %02d.pdf", "application/pdf"},
+	{"Team photo %02d.jpg", "image/jpeg"},
+	{"Budget %02d.csv", "text/csv"},
+}
+
+// sandboxSettings controls the nightly reset of a sandbox deployment
+type sandboxSettings struct {
+	enabled     bool
+	resetHour   int
+	sampleFiles int
+}
+
+// SetSandbox marks the deployment as the API sandbox, whose drives are reset to synthetic data every night
+func (s *Service) SetSandbox(cfg *config.SandboxConfig) {
+	s.sandbox = sandboxSettings{
+		enabled:     cfg.Enabled,
+		resetHour:   cfg.ResetHour % 24,
+		sampleFiles: max(cfg.SampleFiles, 0),
+	}
+}
+
+// StartSandboxResetScheduler queues a reset job at the reset hour every day until ctx is cancelled.
+// It does nothing outside the sandbox.
+func (s *Service) StartSandboxResetScheduler(ctx context.Context) {
+	if !s.sandbox.enabled || s.jobService == nil {
+		return
+	}
+
+	s.jobService.ScheduleDaily(ctx, "sandbox_reset_pass", s.sandbox.resetHour, JOB_TYPE_SANDBOX_RESET, nil)
+}
+
+// runSandboxResetJob deletes the contents of every sandbox drive and fills it with synthetic samples.
+// Accounts, organizations and access tokens are kept, so integrations keep working across resets.
+// Drives are reset in ID order, so an interrupted run picks up after the last drive it finished.
+func (s *Service) runSandboxResetJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if !s.sandbox.enabled {
+		return nil
+	}
+
+	result := map[string]int64{
+		"resetDrives":  job.Result["resetDrives"],
+		"deletedItems": job.Result["deletedItems"],
+	}
+	afterID := ""
+
+	for {
+		shares, err := s.repo.GetSharesByTypeAfter(ctx, SHARE_TYPE_ROOT, afterID, SANDBOX_RESET_BATCH_SIZE)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to load sandbox drives: %w", err)
+		}
+
+		for _, share := range shares {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			deleted, err := s.resetSandboxDrive(ctx, share)
+			if err != nil {
+				s.logger.Errorf("Failed to reset sandbox drive %s: %v", share.ID, err)
+				continue
+			}
+
+			result["resetDrives"]++
+			result["deletedItems"] += deleted
+			progress(result["resetDrives"], 0, result)
+		}
+
+		if len(shares) < SANDBOX_RESET_BATCH_SIZE {
+			break
+		}
+		afterID = shares[len(shares)-1].ID
+	}
+
+	s.logger.Infof("Reset %d sandbox drives to synthetic data", result["resetDrives"])
+
+	return nil
+}
+
+// resetSandboxDrive purges everything below the root folder of a drive and creates the synthetic samples.
+// It returns the number of items deleted.
+func (s *Service) resetSandboxDrive(ctx context.Context, share *models.DriveShare) (int64, error) {
+	root, err := s.repo.GetRootFolderByShareID(ctx, share.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	subtree, err := s.repo.GetSubtreeItems(ctx, root.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load drive contents: %w", err)
+	}
+
+	// The subtree comes deepest first and ends with the root, which stays
+	contents := make([]*models.DriveItem, 0, len(subtree))
+	for _, item := range subtree {
+		if item.ID != root.ID {
+			contents = append(contents, item)
+		}
+	}
+
+	purged, err := s.purgeTrashedTree(ctx, share, contents)
+	if err != nil {
+		return 0, err
+	}
+
+	samples := s.sandboxSamples(share, root)
+	if err := s.repo.CreateFileCopies(ctx, samples); err != nil {
+		return purged.DeletedItems, fmt.Errorf("failed to create synthetic samples: %w", err)
+	}
+
+	created := make([]*models.DriveItem, len(samples))
+	for i, sample := range samples {
+		created[i] = sample.Item
+	}
+	s.recordEvents(ctx, EVENT_TYPE_CREATE, created...)
+
+	// Synthetic files are empty, so only the samples folder counts against the quota
+	s.updateStorageUsed(context.Background(), share.UserID, FOLDER_METADATA_BYTES)
+
+	s.invalidateFolderCaches(ctx, root.ID)
+	s.invalidateUserCaches(ctx, share.UserID)
+
+	return purged.DeletedItems, nil
+}
+
+// sandboxSamples builds the samples folder and its synthetic files, parents first. Synthetic items carry
+// readable names and placeholder keys instead of encrypted ones: integrations can list, move, rename,
+// share and trash them, but not decrypt them.
+func (s *Service) sandboxSamples(share *models.DriveShare, root *models.DriveItem) []*FileCopy {
+	folder := sandboxSampleItem(share, root.ID, 1, SANDBOX_SAMPLES_FOLDER, nil)
+	samples := []*FileCopy{{Item: folder}}
+
+	for i := range s.sandbox.sampleFiles {
+		kind := sandboxSampleKinds[i%len(sandboxSampleKinds)]
+		mimeType := kind.mimeType
+		item := sandboxSampleItem(share, folder.ID, 2, fmt.Sprintf(kind.name, i+1), &mimeType)
+
+		samples = append(samples, &FileCopy{
+			Item: item,
+			Revision: &models.FileRevision{
+				ID:             utils.GenerateLinkID(),
+				ItemID:         item.ID,
+				State:          REVISION_STATE_ACTIVE,
+				SignatureEmail: share.Creator,
+			},
+		})
+	}
+
+	return samples
+}
+
+// sandboxSampleItem builds one synthetic item of a sandbox drive
+func sandboxSampleItem(share *models.DriveShare, parentID string, itemType int, name string, mimeType *string) *models.DriveItem {
+	hash := sha256.Sum256([]byte(parentID + "/" + name))
+
+	item := &models.DriveItem{
+		ID:                 utils.GenerateLinkID(),
+		ParentID:           &parentID,
+		ShareID:            share.ID,
+		VolumeID:           share.VolumeID,
+		Type:               itemType,
+		Name:               name,
+		Hash:               hex.EncodeToString(hash[:]),
+		NameSignatureEmail: share.Creator,
+		State:              ITEM_STATE_ACTIVE,
+		MimeType:           mimeType,
+		NodeKey:            SANDBOX_PLACEHOLDER,
+		NodePassphrase:     SANDBOX_PLACEHOLDER,
+		SignatureEmail:     share.Creator,
+		Permissions:        RW_PERMISSIONS,
+	}
+
+	if itemType == 1 {
+		item.FolderProperties = &models.FolderProperties{NodeHashKey: SANDBOX_PLACEHOLDER}
+	} else {
+		item.FileProperties = &models.FileProperties{ContentKeyPacket: SANDBOX_PLACEHOLDER}
+	}
+
+	return item
+}
@@ -0,0 +1,400 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"cirrussync-api/internal/models"
+)
+
+// Search token limits
+const (
+	MAX_SEARCH_TOKENS_PER_ITEM = 64 // Maximum tokens a client may store per item
+	MAX_SEARCH_QUERY_TOKENS    = 16 // Maximum tokens in a single search query
+	SEARCH_TOKEN_PURGE_BATCH   = 1000
+	REINDEX_STATUS_EXPIRATION  = 24 * time.Hour
+)
+
+// Reindex job states
+const (
+	REINDEX_STATE_PURGING   = "purging"
+	REINDEX_STATE_COMPLETED = "completed"
+	REINDEX_STATE_FAILED    = "failed"
+)
+
+// searchTokenRegex matches hex or base64url encoded tokens
+var searchTokenRegex = regexp.MustCompile(`^[A-Za-z0-9_\-]{16,128}$`)
+
+// ReindexStatus describes the progress of a search key rotation for a user
+type ReindexStatus struct {
+	KeyVersion        int    `json:"keyVersion"`
+	PendingKeyVersion *int   `json:"pendingKeyVersion,omitempty"`
+	PendingItems      int    `json:"pendingItems"`
+	PurgeState        string `json:"purgeState,omitempty"`
+	PurgedTokens      int64  `json:"purgedTokens"`
+	StartedAt         *int64 `json:"startedAt,omitempty"`
+	CompletedAt       *int64 `json:"completedAt,omitempty"`
+}
+
+// reindexJobStatus is the Redis-backed state of the background token purge
+type reindexJobStatus struct {
+	State        string `json:"state"`
+	FromVersion  int    `json:"fromVersion"`
+	PurgedTokens int64  `json:"purgedTokens"`
+	UpdatedAt    int64  `json:"updatedAt"`
+}
+
+// normalizeSearchTokens validates tokens and removes duplicates while preserving order
+func normalizeSearchTokens(tokens []string, maxTokens int) ([]string, error) {
+	if len(tokens) > maxTokens {
+		return nil, ErrTooManySearchTokens
+	}
+
+	seen := make(map[string]struct{}, len(tokens))
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !searchTokenRegex.MatchString(token) {
+			return nil, ErrInvalidSearchToken
+		}
+		if _, exists := seen[token]; exists {
+			continue
+		}
+		seen[token] = struct{}{}
+		result = append(result, token)
+	}
+
+	return result, nil
+}
+
+// GetSearchKeyState returns the user's search key state, creating it on first use
+func (s *Service) GetSearchKeyState(ctx context.Context, userID string) (*models.DriveSearchKeyState, error) {
+	// Check cache first
+	cacheKey := fmt.Sprintf("search_key:%s", userID)
+	var state models.DriveSearchKeyState
+	if err := s.getCached(ctx, cacheKey, &state); err == nil {
+		return &state, nil
+	}
+
+	existing, err := s.repo.GetSearchKeyState(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, ErrSearchKeyStateNotFound) {
+			return nil, err
+		}
+
+		// First use, start at key version 1
+		existing = &models.DriveSearchKeyState{
+			UserID:     userID,
+			KeyVersion: 1,
+		}
+		if err := s.repo.CreateSearchKeyState(ctx, existing); err != nil {
+			// Another request may have created it concurrently
+			existing, err = s.repo.GetSearchKeyState(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.setCached(ctx, cacheKey, existing)
+
+	return existing, nil
+}
+
+// SetItemSearchTokens replaces the caller's search tokens for an item
+func (s *Service) SetItemSearchTokens(ctx context.Context, userID, shareID, linkID string, keyVersion int, tokens []string) error {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	normalized, err := normalizeSearchTokens(tokens, MAX_SEARCH_TOKENS_PER_ITEM)
+	if err != nil {
+		return err
+	}
+
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
+	defer cancel()
+
+	// Tokens are only useful to users who can read the item
+	if err := s.CheckSharePermissions(opCtx, userID, shareID, READ_PERMISSION); err != nil {
+		return err
+	}
+
+	item, err := s.repo.GetLinkByID(opCtx, linkID)
+	if err != nil {
+		return ErrItemNotFound
+	}
+	if item.ShareID != shareID {
+		return ErrItemNotFound
+	}
+
+	// Accept tokens for the active key, or the pending key during a rotation
+	state, err := s.GetSearchKeyState(opCtx, userID)
+	if err != nil {
+		return err
+	}
+	if keyVersion != state.KeyVersion && (state.PendingKeyVersion == nil || keyVersion != *state.PendingKeyVersion) {
+		return ErrSearchKeyVersionMismatch
+	}
+
+	searchTokens := make([]*models.DriveSearchToken, len(normalized))
+	for i, token := range normalized {
+		searchTokens[i] = &models.DriveSearchToken{
+			UserID:     userID,
+			ShareID:    shareID,
+			ItemID:     linkID,
+			Token:      token,
+			KeyVersion: keyVersion,
+		}
+	}
+
+	return s.repo.ReplaceItemSearchTokens(opCtx, linkID, userID, keyVersion, searchTokens)
+}
+
+// SearchItems finds items in a share whose name tokens match all of the query tokens
+// and that carry all of the given tags
+func (s *Service) SearchItems(ctx context.Context, userID, shareID string, tokens, tagIDs []string, limit, offset int) ([]*models.DriveItem, int, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, 0, ctx.Err()
+	}
+
+	normalized, err := normalizeSearchTokens(tokens, MAX_SEARCH_QUERY_TOKENS)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(normalized) == 0 && len(tagIDs) == 0 {
+		return nil, 0, ErrNoSearchCriteria
+	}
+
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
+	defer cancel()
+
+	if err := s.CheckSharePermissions(opCtx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, 0, err
+	}
+
+	tagIDs, err = s.resolveTagFilter(opCtx, userID, tagIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	state, err := s.GetSearchKeyState(opCtx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items, total, err := s.repo.SearchItemsByTokens(opCtx, userID, shareID, state.KeyVersion, normalized, tagIDs, limit, offset)
+	if err != nil {
+		return nil, 0, ErrItemRetrieval
+	}
+
+	return items, total, nil
+}
+
+// SearchAllItems finds items across every share the user can read whose name tokens match all of the query tokens
+// and that carry all of the given tags
+func (s *Service) SearchAllItems(ctx context.Context, userID string, tokens, tagIDs []string, limit, offset int) ([]*models.DriveItem, int, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, 0, ctx.Err()
+	}
+
+	normalized, err := normalizeSearchTokens(tokens, MAX_SEARCH_QUERY_TOKENS)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(normalized) == 0 && len(tagIDs) == 0 {
+		return nil, 0, ErrNoSearchCriteria
+	}
+
+	opCtx, cancel := withBudget(ctx, s.extendedTimeout())
+	defer cancel()
+
+	tagIDs, err = s.resolveTagFilter(opCtx, userID, tagIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	state, err := s.GetSearchKeyState(opCtx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items, total, err := s.repo.SearchReadableItemsByTokens(opCtx, userID, state.KeyVersion, normalized, tagIDs, limit, offset)
+	if err != nil {
+		return nil, 0, ErrItemRetrieval
+	}
+
+	return items, total, nil
+}
+
+// StartSearchKeyRotation begins reindexing the user's search tokens under a new key version
+func (s *Service) StartSearchKeyRotation(ctx context.Context, userID string, newKeyVersion int) (*models.DriveSearchKeyState, error) {
+	state, err := s.GetSearchKeyState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.PendingKeyVersion != nil {
+		// Restarting the same rotation is a no-op
+		if *state.PendingKeyVersion == newKeyVersion {
+			return state, nil
+		}
+		return nil, ErrSearchReindexInProgress
+	}
+
+	if newKeyVersion <= state.KeyVersion {
+		return nil, ErrInvalidSearchKeyVersion
+	}
+
+	now := time.Now().Unix()
+	state.PendingKeyVersion = &newKeyVersion
+	state.ReindexStartedAt = &now
+	state.ReindexCompletedAt = nil
+
+	if err := s.repo.UpdateSearchKeyState(ctx, state); err != nil {
+		return nil, err
+	}
+
+	s.invalidateSearchCaches(ctx, userID)
+
+	return state, nil
+}
+
+// GetReindexBatch returns the next items the client must re-tokenize under the pending key
+func (s *Service) GetReindexBatch(ctx context.Context, userID string, limit int) ([]*models.DriveItem, *ReindexStatus, error) {
+	state, err := s.GetSearchKeyState(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status := s.buildReindexStatus(ctx, state)
+	if state.PendingKeyVersion == nil {
+		return []*models.DriveItem{}, status, nil
+	}
+
+	items, pending, err := s.repo.GetItemsPendingReindex(ctx, userID, state.KeyVersion, *state.PendingKeyVersion, limit)
+	if err != nil {
+		return nil, nil, ErrItemRetrieval
+	}
+	status.PendingItems = pending
+
+	return items, status, nil
+}
+
+// CompleteSearchKeyRotation activates the pending key and purges old tokens in the background
+func (s *Service) CompleteSearchKeyRotation(ctx context.Context, userID string) (*ReindexStatus, error) {
+	state, err := s.GetSearchKeyState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.PendingKeyVersion == nil {
+		return nil, ErrNoSearchReindex
+	}
+
+	// Every previously indexed item must be re-tokenized first
+	_, pending, err := s.repo.GetItemsPendingReindex(ctx, userID, state.KeyVersion, *state.PendingKeyVersion, 1)
+	if err != nil {
+		return nil, ErrItemRetrieval
+	}
+	if pending > 0 {
+		return nil, ErrSearchReindexIncomplete
+	}
+
+	oldVersion := state.KeyVersion
+	now := time.Now().Unix()
+	state.KeyVersion = *state.PendingKeyVersion
+	state.PendingKeyVersion = nil
+	state.ReindexCompletedAt = &now
+
+	if err := s.repo.UpdateSearchKeyState(ctx, state); err != nil {
+		return nil, err
+	}
+
+	s.invalidateSearchCaches(ctx, userID)
+
+	// Purge tokens for the retired key version
+	s.setReindexJobStatus(ctx, userID, reindexJobStatus{State: REINDEX_STATE_PURGING, FromVersion: oldVersion})
+	go s.purgeSearchTokens(context.Background(), userID, oldVersion)
+
+	return s.buildReindexStatus(ctx, state), nil
+}
+
+// purgeSearchTokens deletes tokens for a retired key version in batches
+func (s *Service) purgeSearchTokens(ctx context.Context, userID string, keyVersion int) {
+	var purged int64
+
+	for {
+		opCtx, cancel := withBudget(ctx, s.extendedTimeout())
+		deleted, err := s.repo.DeleteSearchTokensByVersion(opCtx, userID, keyVersion, SEARCH_TOKEN_PURGE_BATCH)
+		cancel()
+
+		if err != nil {
+			s.logger.Errorf("Failed to purge search tokens for user %s: %v", userID, err)
+			s.setReindexJobStatus(ctx, userID, reindexJobStatus{
+				State:        REINDEX_STATE_FAILED,
+				FromVersion:  keyVersion,
+				PurgedTokens: purged,
+			})
+			return
+		}
+
+		purged += deleted
+		if deleted == 0 {
+			break
+		}
+
+		s.setReindexJobStatus(ctx, userID, reindexJobStatus{
+			State:        REINDEX_STATE_PURGING,
+			FromVersion:  keyVersion,
+			PurgedTokens: purged,
+		})
+	}
+
+	s.setReindexJobStatus(ctx, userID, reindexJobStatus{
+		State:        REINDEX_STATE_COMPLETED,
+		FromVersion:  keyVersion,
+		PurgedTokens: purged,
+	})
+	s.logger.Debugf("Purged %d search tokens for user %s at key version %d", purged, userID, keyVersion)
+}
+
+// buildReindexStatus combines the key state with the background purge job status
+func (s *Service) buildReindexStatus(ctx context.Context, state *models.DriveSearchKeyState) *ReindexStatus {
+	status := &ReindexStatus{
+		KeyVersion:        state.KeyVersion,
+		PendingKeyVersion: state.PendingKeyVersion,
+		StartedAt:         state.ReindexStartedAt,
+		CompletedAt:       state.ReindexCompletedAt,
+	}
+
+	var job reindexJobStatus
+	if err := s.redisClient.GetJSON(ctx, fmt.Sprintf("search_reindex:%s", state.UserID), &job); err == nil {
+		status.PurgeState = job.State
+		status.PurgedTokens = job.PurgedTokens
+	}
+
+	return status
+}
+
+// setReindexJobStatus stores the background purge job status
+func (s *Service) setReindexJobStatus(ctx context.Context, userID string, job reindexJobStatus) {
+	job.UpdatedAt = time.Now().Unix()
+	cacheKey := fmt.Sprintf("search_reindex:%s", userID)
+	if err := s.redisClient.SetJSON(ctx, cacheKey, job, REINDEX_STATUS_EXPIRATION); err != nil {
+		s.logger.Errorf("Failed to store reindex status for user %s: %v", userID, err)
+	}
+}
+
+// invalidateSearchCaches invalidates caches related to a user's search index
+func (s *Service) invalidateSearchCaches(ctx context.Context, userID string) {
+	cacheKey := fmt.Sprintf("search_key:%s", userID)
+	if _, err := s.redisClient.Delete(ctx, cacheKey); err != nil {
+		s.logger.Errorf("Failed to delete search key cache for user %s: %v", userID, err)
+	}
+}
@@ -4,15 +4,22 @@ package drive
 import (
 	"cirrussync-api/internal/logger"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/quota"
 	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/metrics"
 	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/s3"
+	"cirrussync-api/pkg/tracing"
 	"context"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -36,139 +43,158 @@ const (
 	// Value 22 = Read(4) + Write(2) + Share(8) + Admin(8) = 22
 	MEMBERSHIP_DEFAULT = 22 // Default for share membership
 
-	// Default timeouts
-	DEFAULT_TIMEOUT  = 5 * time.Second
-	EXTENDED_TIMEOUT = 10 * time.Second
-	BATCH_SIZE       = 10 // Process in batches of 10 for parallel operations
-
 	// Cache expiration
 	CACHE_EXPIRATION = 1 * time.Hour
 )
 
+// Share types
+const (
+	SHARE_TYPE_ROOT   = 1 // The user's drive
+	SHARE_TYPE_BACKUP = 2 // The files of one backup set of a device
+)
+
 // NewService creates a new drive service
-func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger) *Service {
-	return &Service{
-		repo:        repo,
-		redisClient: redisClient,
-		logger:      logger,
+func NewService(
+	repo Repository,
+	redisClient *redis.Client,
+	logger *logger.Logger,
+	cfg *config.DriveConfig,
+	storage *s3.Client,
+	quotaService *quota.Service,
+) *Service {
+	urlBase := "https://cirrussync.me/urls"
+	if cfg != nil && cfg.PublicURLBase != "" {
+		urlBase = strings.TrimRight(cfg.PublicURLBase, "/")
 	}
-}
 
-// CreateDriveStructure sets up the initial drive structure for a new user
-func (s *Service) CreateDriveStructure(
-	ctx context.Context,
-	user *models.User,
-	driveVolume *models.DriveVolume,
-	driveShare *models.DriveShare,
-	driveShareMembership *models.DriveShareMembership,
-) error {
-	// Check context for cancellation
-	if ctx.Err() != nil {
-		return ctx.Err()
+	expiry := shareExpirySettings{scanInterval: time.Minute, notice: 24 * time.Hour}
+	if cfg != nil && cfg.ShareExpiryScanInterval > 0 {
+		expiry.scanInterval = cfg.ShareExpiryScanInterval
 	}
-
-	// Create a context with timeout for the operations
-	opCtx, cancel := context.WithTimeout(ctx, EXTENDED_TIMEOUT)
-	defer cancel()
-
-	// Create result channels for parallel existence checks
-	type checkResult struct {
-		exists bool
-		err    error
+	if cfg != nil && cfg.ShareExpiryNotice > 0 {
+		expiry.notice = cfg.ShareExpiryNotice
 	}
 
-	// Run all existence checks in parallel for efficiency
-	volumeCheckCh := make(chan checkResult, 1)
-	shareCheckCh := make(chan checkResult, 1)
-	allocCheckCh := make(chan checkResult, 1)
-	memberCheckCh := make(chan checkResult, 1)
+	integrity := integrityAuditSettings{interval: time.Hour}
+	if cfg != nil && cfg.IntegrityAuditInterval > 0 {
+		integrity.interval = cfg.IntegrityAuditInterval
+	}
+	if cfg != nil {
+		integrity.sampleSize = cfg.IntegrityAuditSampleSize
+	}
 
-	go func() {
-		count, err := s.repo.CountVolumesByUserID(opCtx, user.ID)
-		volumeCheckCh <- checkResult{exists: count > 0, err: err}
-	}()
+	uploads := uploadCleanupSettings{interval: time.Hour, ttl: 7 * 24 * time.Hour}
+	if cfg != nil && cfg.UploadCleanupInterval > 0 {
+		uploads.interval = cfg.UploadCleanupInterval
+	}
+	if cfg != nil && cfg.UploadDraftTTL > 0 {
+		uploads.ttl = cfg.UploadDraftTTL
+	}
 
-	go func() {
-		count, err := s.repo.CountSharesByUserIDAndType(opCtx, user.ID, 1)
-		shareCheckCh <- checkResult{exists: count > 0, err: err}
-	}()
+	backups := backupSettings{retentionInterval: 6 * time.Hour}
+	if cfg != nil && cfg.BackupRetentionInterval > 0 {
+		backups.retentionInterval = cfg.BackupRetentionInterval
+	}
 
-	go func() {
-		count, err := s.repo.CountAllocationsByUserID(opCtx, user.ID)
-		allocCheckCh <- checkResult{exists: count > 0, err: err}
-	}()
+	recent := recentSettings{retention: 30 * 24 * time.Hour}
+	if cfg != nil && cfg.RecentRetention > 0 {
+		recent.retention = cfg.RecentRetention
+	}
 
-	go func() {
-		count, err := s.repo.CountMembershipsByUserID(opCtx, user.ID)
-		memberCheckCh <- checkResult{exists: count > 0, err: err}
-	}()
+	folderSizes := folderSizeSettings{interval: 5 * time.Minute}
+	if cfg != nil && cfg.FolderSizeInterval > 0 {
+		folderSizes.interval = cfg.FolderSizeInterval
+	}
 
-	// Wait for results and check for errors
-	volumeCheck := <-volumeCheckCh
-	if volumeCheck.err != nil {
-		return volumeCheck.err
+	lifecycle := storageLifecycleSettings{
+		interval:        6 * time.Hour,
+		transitionAfter: 3 * 24 * time.Hour,
+		multipartAge:    24 * time.Hour,
 	}
-	if volumeCheck.exists {
-		return ErrVolumeAlreadyExists
+	if cfg != nil && cfg.StorageLifecycleInterval > 0 {
+		lifecycle.interval = cfg.StorageLifecycleInterval
 	}
-
-	shareCheck := <-shareCheckCh
-	if shareCheck.err != nil {
-		return shareCheck.err
+	if cfg != nil && cfg.TrashedBlockTransitionAfter > 0 {
+		lifecycle.transitionAfter = cfg.TrashedBlockTransitionAfter
 	}
-	if shareCheck.exists {
-		return ErrRootShareAlreadyExists
+	if cfg != nil && cfg.StaleMultipartUploadAge > 0 {
+		lifecycle.multipartAge = cfg.StaleMultipartUploadAge
 	}
 
-	allocCheck := <-allocCheckCh
-	if allocCheck.err != nil {
-		return allocCheck.err
+	gc := garbageCollectionSettings{interval: 24 * time.Hour, orphanGrace: 24 * time.Hour}
+	if cfg != nil && cfg.GarbageCollectionInterval > 0 {
+		gc.interval = cfg.GarbageCollectionInterval
 	}
-	if allocCheck.exists {
-		return ErrAllocationAlreadyExists
+	if cfg != nil && cfg.OrphanedBlockGracePeriod > 0 {
+		gc.orphanGrace = cfg.OrphanedBlockGracePeriod
 	}
 
-	memberCheck := <-memberCheckCh
-	if memberCheck.err != nil {
-		return memberCheck.err
-	}
-	if memberCheck.exists {
-		return ErrMembershipAlreadyExists
+	return &Service{
+		repo:        repo,
+		redisClient: redisClient,
+		logger:      logger,
+		settings:    newRuntimeSettings(cfg),
+		storage:     storage,
+		quota:       quotaService,
+		urlBase:     urlBase,
+		notifier:    newEventNotifier(),
+		expiry:      expiry,
+		integrity:   integrity,
+		uploads:     uploads,
+		backups:     backups,
+		trash:       newTrashSettings(cfg),
+		recent:      recent,
+		folderSizes: folderSizes,
+		lifecycle:   lifecycle,
+		gc:          gc,
+		cache:       newCacheSettings(cfg),
 	}
+}
 
-	// Pre-generate all IDs to ensure consistency
-	volumeID := utils.GenerateLinkID()
-	allocationID := utils.GenerateLinkID()
-	shareID := utils.GenerateLinkID()
-	shareLinkID := utils.GenerateLinkID()
-	membershipID := utils.GenerateLinkID()
+// CreateDriveStructure sets up the initial drive structure for a new user
+func (s *Service) CreateDriveStructure(
+	ctx context.Context,
+	user *models.User,
+	driveVolume *models.DriveVolume,
+	driveShare *models.DriveShare,
+	driveShareMembership *models.DriveShareMembership,
+) error {
+	ctx, span := tracing.Start(ctx, "drive.CreateDriveStructure")
+	defer span.End()
 
-	// Create volume
-	volume := &models.DriveVolume{
-		ID:       volumeID,
-		Name:     driveVolume.Name,
-		Hash:     driveVolume.Hash,
-		UserID:   user.ID,
-		Size:     3221225472, // 3GB default
-		PlanType: "free",
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
 
-	if err := s.repo.CreateVolume(ctx, volume); err != nil {
-		return ErrVolumeCreation
+	if driveShare.ExpiresAt != nil && *driveShare.ExpiresAt <= time.Now().Unix() {
+		return ErrInvalidExpiry
 	}
 
-	// Create allocation and share in parallel with proper error handling
-	type createResult struct {
-		err error
-	}
+	// Create a context with timeout for the operations
+	opCtx, cancel := withBudget(ctx, s.extendedTimeout())
+	defer cancel()
 
-	allocCreateCh := make(chan createResult, 1)
-	shareCreateCh := make(chan createResult, 1)
+	// Everything is created in one transaction, so a failure or crash part way leaves nothing behind
+	err := s.repo.WithTransaction(opCtx, func(tx Repository) error {
+		if err := checkNoDriveStructure(opCtx, tx, user.ID); err != nil {
+			return err
+		}
+
+		volume := &models.DriveVolume{
+			ID:       utils.GenerateLinkID(),
+			Name:     driveVolume.Name,
+			Hash:     driveVolume.Hash,
+			UserID:   user.ID,
+			Size:     quota.DEFAULT_STORAGE_QUOTA,
+			PlanType: "free",
+		}
+		if err := tx.CreateVolume(opCtx, volume); err != nil {
+			return ErrVolumeCreation
+		}
 
-	// Create allocation goroutine
-	go func() {
 		allocation := &models.VolumeAllocation{
-			ID:                   allocationID,
+			ID:                   utils.GenerateLinkID(),
 			VolumeID:             volume.ID,
 			UserID:               user.ID,
 			AllocatedSize:        volume.Size,
@@ -177,90 +203,112 @@ func (s *Service) CreateDriveStructure(
 			Active:               true,
 			IsOwner:              true,
 		}
+		if err := tx.CreateAllocation(opCtx, allocation); err != nil {
+			return ErrAllocationCreation
+		}
 
-		err := s.repo.CreateAllocation(opCtx, allocation)
-		allocCreateCh <- createResult{err: err}
-	}()
-
-	// Create share goroutine
-	go func() {
 		share := &models.DriveShare{
-			ID:                       shareID,
+			ID:                       utils.GenerateLinkID(),
 			VolumeID:                 volume.ID,
 			UserID:                   user.ID,
-			Type:                     1, // Root share
+			Type:                     SHARE_TYPE_ROOT,
 			State:                    1, // Active
 			Creator:                  user.Email,
-			LinkID:                   shareLinkID,
+			LinkID:                   utils.GenerateLinkID(),
 			ShareKey:                 driveShare.ShareKey,
 			SharePassphrase:          driveShare.SharePassphrase,
 			SharePassphraseSignature: driveShare.SharePassphraseSignature,
+			ExpiresAt:                driveShare.ExpiresAt,
+		}
+		if err := tx.CreateShare(opCtx, share); err != nil {
+			return ErrShareCreation
 		}
 
-		err := s.repo.CreateShare(opCtx, share)
-		shareCreateCh <- createResult{err: err}
-	}()
-
-	// Wait for creation results
-	allocResult := <-allocCreateCh
-	shareResult := <-shareCreateCh
+		shareMember := &models.DriveShareMembership{
+			ID:                  utils.GenerateLinkID(),
+			ShareID:             share.ID,
+			UserID:              user.ID,
+			MemberID:            user.ID,
+			Inviter:             user.Email,
+			State:               1,                  // Active
+			Permissions:         MEMBERSHIP_DEFAULT, // Full permissions (22)
+			KeyPacket:           driveShareMembership.KeyPacket,
+			KeyPacketSignature:  driveShareMembership.KeyPacketSignature,
+			SessionKeySignature: driveShareMembership.SessionKeySignature,
+		}
+		if err := tx.CreateMembership(opCtx, shareMember); err != nil {
+			return ErrMembershipCreation
+		}
 
-	// Handle allocation creation error
-	if allocResult.err != nil {
-		// Cleanup volume since allocation failed
-		_ = s.repo.DeleteVolume(ctx, volume.ID)
-		return ErrAllocationCreation
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Handle share creation error
-	if shareResult.err != nil {
-		// Cleanup volume (allocation will be cascaded)
-		_ = s.repo.DeleteVolume(ctx, volume.ID)
-		return ErrShareCreation
+	// Clear any related cache entries after creating structure
+	s.invalidateUserCaches(ctx, user.ID)
+
+	return nil
+}
+
+// checkNoDriveStructure returns an error when any part of a user's drive structure already exists
+func checkNoDriveStructure(ctx context.Context, repo Repository, userID string) error {
+	count, err := repo.CountVolumesByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVolumeAlreadyExists
 	}
 
-	// Create share membership
-	shareMember := &models.DriveShareMembership{
-		ID:                  membershipID,
-		ShareID:             shareID,
-		UserID:              user.ID,
-		MemberID:            user.ID,
-		Inviter:             user.Email,
-		State:               1,                  // Active
-		Permissions:         MEMBERSHIP_DEFAULT, // Full permissions (22)
-		KeyPacket:           driveShareMembership.KeyPacket,
-		KeyPacketSignature:  driveShareMembership.KeyPacketSignature,
-		SessionKeySignature: driveShareMembership.SessionKeySignature,
+	count, err = repo.CountSharesByUserIDAndType(ctx, userID, SHARE_TYPE_ROOT)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRootShareAlreadyExists
 	}
 
-	if err := s.repo.CreateMembership(ctx, shareMember); err != nil {
-		// Cleanup volume (will cascade delete shares and allocations)
-		_ = s.repo.DeleteVolume(ctx, volume.ID)
-		return ErrMembershipCreation
+	count, err = repo.CountAllocationsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrAllocationAlreadyExists
 	}
 
-	// Clear any related cache entries after creating structure
-	s.invalidateUserCaches(ctx, user.ID)
+	count, err = repo.CountMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrMembershipAlreadyExists
+	}
 
 	return nil
 }
 
 // CheckSharePermissions verifies if a user has specific permissions for a share
 func (s *Service) CheckSharePermissions(ctx context.Context, userID, shareID string, requiredPermission int) error {
+	ctx, span := tracing.Start(ctx, "drive.CheckSharePermissions")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
 
 	// Create cache key for share permissions check
-	cacheKey := fmt.Sprintf("perm:%s:%s:%d", userID, shareID, requiredPermission)
+	cacheKey := s.versionedKey(ctx, fmt.Sprintf("perm:%s:%s:%d", userID, shareID, requiredPermission),
+		cacheScope{cacheScopeUser, userID}, cacheScope{cacheScopeShare, shareID})
 
 	// Try to get from cache first
 	var permResult struct {
 		HasPermission bool
 	}
 
-	err := s.redisClient.GetJSON(ctx, cacheKey, &permResult)
+	err := s.getCached(ctx, cacheKey, &permResult)
 	if err == nil {
 		// Found in cache
 		if permResult.HasPermission {
@@ -310,11 +358,19 @@ func (s *Service) CheckSharePermissions(ctx context.Context, userID, shareID str
 
 		// Cache the positive result
 		permResult.HasPermission = true
-		_ = s.redisClient.SetJSON(ctx, cacheKey, permResult, CACHE_EXPIRATION)
+		s.setCached(ctx, cacheKey, permResult)
 
 		return nil
 	}
 
+	// Members lose access once the share expires, even before the scheduler removes their memberships
+	if shareExpired(share) {
+		// Consume membership result to prevent goroutine leak
+		<-membershipCh
+
+		return ErrShareExpired
+	}
+
 	// If share permissions mask is not 0, check if the required permission is allowed
 	if share.PermissionsMask != 0 && (share.PermissionsMask&requiredPermission) != requiredPermission {
 		// Consume membership result to prevent goroutine leak
@@ -322,7 +378,7 @@ func (s *Service) CheckSharePermissions(ctx context.Context, userID, shareID str
 
 		// Cache the negative result
 		permResult.HasPermission = false
-		_ = s.redisClient.SetJSON(ctx, cacheKey, permResult, CACHE_EXPIRATION)
+		s.setCached(ctx, cacheKey, permResult)
 
 		return ErrInsufficientPermissions
 	}
@@ -333,63 +389,44 @@ func (s *Service) CheckSharePermissions(ctx context.Context, userID, shareID str
 	if membershipRes.Err != nil || membershipRes.Membership == nil {
 		// Cache the negative result
 		permResult.HasPermission = false
-		_ = s.redisClient.SetJSON(ctx, cacheKey, permResult, CACHE_EXPIRATION)
+		s.setCached(ctx, cacheKey, permResult)
 
 		return ErrUnauthorized
 	}
 
 	membership := membershipRes.Membership
 
+	// Members lose access once their permissions expire, even before the scheduler deactivates them
+	if membershipExpired(membership) {
+		return ErrPermissionExpired
+	}
+
 	// Check if user has the required permission in their membership
 	if (membership.Permissions & requiredPermission) != requiredPermission {
 		// Cache the negative result
 		permResult.HasPermission = false
-		_ = s.redisClient.SetJSON(ctx, cacheKey, permResult, CACHE_EXPIRATION)
+		s.setCached(ctx, cacheKey, permResult)
 
 		return ErrInsufficientPermissions
 	}
 
-	// Cache the positive result
+	// Cache the positive result, no longer than the permissions last
 	permResult.HasPermission = true
-	_ = s.redisClient.SetJSON(ctx, cacheKey, permResult, CACHE_EXPIRATION)
+	s.setCachedUntil(ctx, cacheKey, permResult, membership.PermissionExpiresAt)
 
 	return nil
 }
 
 // CheckStorageQuota verifies if a user has enough storage space for an operation
 func (s *Service) CheckStorageQuota(ctx context.Context, userID string, requiredBytes int64) error {
-	// Get user's allocation with caching
-	cacheKey := fmt.Sprintf("allocation:%s", userID)
-
-	var allocation models.VolumeAllocation
-	err := s.redisClient.GetJSON(ctx, cacheKey, &allocation)
-
-	if err != nil {
-		// Cache miss, get from database
-		alloc, err := s.repo.GetAllocationByUserID(ctx, userID)
-		if err != nil {
-			return fmt.Errorf("failed to get storage allocation: %w", err)
-		}
-
-		allocation = *alloc
-
-		// Cache the allocation
-		_ = s.redisClient.SetJSON(ctx, cacheKey, allocation, CACHE_EXPIRATION)
-	}
-
-	// Calculate remaining space
-	remainingSpace := allocation.AllocatedSize - allocation.UsedSize
-
-	// Check if there's enough space
-	if requiredBytes > remainingSpace {
-		return ErrStorageQuotaExceeded
-	}
-
-	return nil
+	return s.quota.Check(ctx, userID, requiredBytes)
 }
 
 // CreateDriveFolder creates a new folder in the drive with improved parallel execution
 func (s *Service) CreateDriveFolder(ctx context.Context, userID string, shareID string, folderInput *models.DriveItem) (*models.DriveItem, error) {
+	ctx, span := tracing.Start(ctx, "drive.CreateDriveFolder")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -399,6 +436,9 @@ func (s *Service) CreateDriveFolder(ctx context.Context, userID string, shareID
 	if folderInput == nil {
 		return nil, errors.New("folder input cannot be nil")
 	}
+	if folderInput.FolderProperties == nil {
+		return nil, errors.New("folder properties are required")
+	}
 	if shareID == "" {
 		return nil, errors.New("shareID is required")
 	}
@@ -426,7 +466,7 @@ func (s *Service) CreateDriveFolder(ctx context.Context, userID string, shareID
 	nameCheckCh := make(chan nameCheckResult, 1)
 
 	// Create a context with timeout for the parallel operations
-	opCtx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT)
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
 	defer cancel()
 
 	// Check permissions and get share
@@ -456,9 +496,13 @@ func (s *Service) CreateDriveFolder(ctx context.Context, userID string, shareID
 			quotaCh <- quotaCheckResult{opCtx.Err()}
 			return
 		default:
-			// For folders, we use a minimal size (just metadata)
-			err := s.CheckStorageQuota(opCtx, userID, 1024) // 1KB for folder metadata
-			quotaCh <- quotaCheckResult{err}
+			// Folders count against the share owner with their metadata only
+			share, err := s.GetShareByID(opCtx, shareID)
+			if err != nil {
+				quotaCh <- quotaCheckResult{err}
+				return
+			}
+			quotaCh <- quotaCheckResult{s.CheckStorageQuota(opCtx, share.UserID, FOLDER_METADATA_BYTES)}
 		}
 	}()
 
@@ -541,8 +585,10 @@ func (s *Service) CreateDriveFolder(ctx context.Context, userID string, shareID
 		return nil, ErrFolderCreation
 	}
 
+	s.recordEvents(ctx, EVENT_TYPE_CREATE, folder)
+
 	// Update storage used (can be done asynchronously)
-	go s.updateStorageUsed(context.Background(), userID, 1024)
+	s.updateStorageUsed(context.WithoutCancel(ctx), share.UserID, FOLDER_METADATA_BYTES)
 
 	// Invalidate cached parent folder contents
 	if folder.ParentID != nil {
@@ -552,57 +598,12 @@ func (s *Service) CreateDriveFolder(ctx context.Context, userID string, shareID
 	return folder, nil
 }
 
-// updateStorageUsed updates the user's storage usage asynchronously
-func (s *Service) updateStorageUsed(ctx context.Context, userID string, bytes int64) {
-	// Create a new context with timeout to avoid hanging goroutines
-	opCtx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT)
-	defer cancel()
-
-	// Get from cache first
-	cacheKey := fmt.Sprintf("allocation:%s", userID)
-	var allocation models.VolumeAllocation
-	err := s.redisClient.GetJSON(opCtx, cacheKey, &allocation)
-
-	if err != nil {
-		// Cache miss, get from database
-		alloc, err := s.repo.GetAllocationByUserID(opCtx, userID)
-		if err != nil {
-			s.logger.Error("Failed to get allocation for updating storage used", err)
-			return
-		}
-		allocation = *alloc
-	}
-
-	allocation.UsedSize += bytes
-
-	// Ensure we don't update with zero AllocatedSize
-	if allocation.AllocatedSize <= 0 {
-		// Get correct allocation size from database
-		alloc, err := s.repo.GetAllocationByUserID(opCtx, userID)
-		if err != nil {
-			s.logger.Error("Failed to get allocation for updating storage used", err)
-			return
-		}
-		allocation.AllocatedSize = alloc.AllocatedSize
-	}
-
-	// Update in database
-	err = s.repo.UpdateAllocation(opCtx, &allocation)
-	if err != nil {
-		s.logger.Error("Failed to update allocation", err)
-		return
-	}
-
-	// Update in cache
-	_ = s.redisClient.SetJSON(opCtx, cacheKey, allocation, CACHE_EXPIRATION)
-}
-
 // Helper method to check if a folder with the same name exists
 func (s *Service) checkFolderNameExists(ctx context.Context, parentID, folderNameHash string) (bool, error) {
 	// Check cache first
-	cacheKey := fmt.Sprintf("folder_hash:%s:%s", parentID, folderNameHash)
+	cacheKey := s.versionedKey(ctx, fmt.Sprintf("folder_hash:%s:%s", parentID, folderNameHash), cacheScope{cacheScopeFolder, parentID})
 	var exists bool
-	err := s.redisClient.GetJSON(ctx, cacheKey, &exists)
+	err := s.getCached(ctx, cacheKey, &exists)
 	if err == nil {
 		return exists, nil
 	}
@@ -622,7 +623,7 @@ func (s *Service) checkFolderNameExists(ctx context.Context, parentID, folderNam
 	}
 
 	// Cache the result (short expiration as folder contents may change)
-	_ = s.redisClient.SetJSON(ctx, cacheKey, exists, 5*time.Minute)
+	s.setCached(ctx, cacheKey, exists)
 
 	return exists, nil
 }
@@ -642,7 +643,7 @@ func (s *Service) CountUserVolumes(ctx context.Context, userID string) (bool, er
 	// Check cache first
 	cacheKey := fmt.Sprintf("volumecount:%s", userID)
 	var count int
-	err := s.redisClient.GetJSON(ctx, cacheKey, &count)
+	err := s.getCached(ctx, cacheKey, &count)
 	if err == nil {
 		return count >= 2, nil
 	}
@@ -654,7 +655,7 @@ func (s *Service) CountUserVolumes(ctx context.Context, userID string) (bool, er
 	}
 
 	// Cache the result
-	_ = s.redisClient.SetJSON(ctx, cacheKey, dbCount, CACHE_EXPIRATION)
+	s.setCached(ctx, cacheKey, dbCount)
 
 	return dbCount >= 2, nil
 }
@@ -674,7 +675,7 @@ func (s *Service) CountDriveItems(ctx context.Context, userID string) (bool, err
 	// Check cache first
 	cacheKey := fmt.Sprintf("itemcount:%s", userID)
 	var count int
-	err := s.redisClient.GetJSON(ctx, cacheKey, &count)
+	err := s.getCached(ctx, cacheKey, &count)
 	if err == nil {
 		return count > 0, nil
 	}
@@ -686,13 +687,16 @@ func (s *Service) CountDriveItems(ctx context.Context, userID string) (bool, err
 	}
 
 	// Cache the result
-	_ = s.redisClient.SetJSON(ctx, cacheKey, dbCount, CACHE_EXPIRATION)
+	s.setCached(ctx, cacheKey, dbCount)
 
 	return dbCount > 0, nil
 }
 
 // GetSharesByUserID gets all shares for a user with pagination and caching
 func (s *Service) GetSharesByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.DriveShare, int, error) {
+	ctx, span := tracing.Start(ctx, "drive.GetSharesByUserID")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, 0, ctx.Err()
@@ -701,12 +705,12 @@ func (s *Service) GetSharesByUserID(ctx context.Context, userID string, limit, o
 	// Check cache first for total shares
 	cacheKey := fmt.Sprintf("shares:%s:all", userID)
 	var allShares []*models.DriveShare
-	err := s.redisClient.GetJSON(ctx, cacheKey, &allShares)
+	err := s.getCached(ctx, cacheKey, &allShares)
 
 	if err != nil {
 		// Cache miss, fetch from database
 		// Create a context with timeout
-		opCtx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT)
+		opCtx, cancel := withBudget(ctx, s.defaultTimeout())
 		defer cancel()
 
 		// Create channels for parallel operations
@@ -765,7 +769,7 @@ func (s *Service) GetSharesByUserID(ctx context.Context, userID string, limit, o
 		}
 
 		// Cache the results
-		_ = s.redisClient.SetJSON(ctx, cacheKey, allShares, CACHE_EXPIRATION)
+		s.setCached(ctx, cacheKey, allShares)
 	}
 
 	// Get total count
@@ -789,6 +793,9 @@ func (s *Service) GetSharesByUserID(ctx context.Context, userID string, limit, o
 
 // GetShareByID retrieves a share with caching
 func (s *Service) GetShareByID(ctx context.Context, shareID string) (*models.DriveShare, error) {
+	ctx, span := tracing.Start(ctx, "drive.GetShareByID")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -797,7 +804,7 @@ func (s *Service) GetShareByID(ctx context.Context, shareID string) (*models.Dri
 	// Check cache first
 	cacheKey := fmt.Sprintf("share:%s", shareID)
 	var share models.DriveShare
-	err := s.redisClient.GetJSON(ctx, cacheKey, &share)
+	err := s.getCached(ctx, cacheKey, &share)
 	if err == nil {
 		return &share, nil
 	}
@@ -809,13 +816,16 @@ func (s *Service) GetShareByID(ctx context.Context, shareID string) (*models.Dri
 	}
 
 	// Cache the result
-	_ = s.redisClient.SetJSON(ctx, cacheKey, dbShare, CACHE_EXPIRATION)
+	s.setCached(ctx, cacheKey, dbShare)
 
 	return dbShare, nil
 }
 
 // GetMembershipByShareAndUserID retrieves a membership with caching
 func (s *Service) GetMembershipByShareAndUserID(ctx context.Context, shareID, userID string) (*models.DriveShareMembership, error) {
+	ctx, span := tracing.Start(ctx, "drive.GetMembershipByShareAndUserID")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -824,7 +834,7 @@ func (s *Service) GetMembershipByShareAndUserID(ctx context.Context, shareID, us
 	// Check cache first
 	cacheKey := fmt.Sprintf("membership:%s:%s", shareID, userID)
 	var membership models.DriveShareMembership
-	err := s.redisClient.GetJSON(ctx, cacheKey, &membership)
+	err := s.getCached(ctx, cacheKey, &membership)
 	if err == nil {
 		return &membership, nil
 	}
@@ -836,33 +846,37 @@ func (s *Service) GetMembershipByShareAndUserID(ctx context.Context, shareID, us
 	}
 
 	// Cache the result
-	_ = s.redisClient.SetJSON(ctx, cacheKey, dbMembership, CACHE_EXPIRATION)
+	s.setCached(ctx, cacheKey, dbMembership)
 
 	return dbMembership, nil
 }
 
 // GetShareWithAllMemberships retrieves a share with all its memberships and caching
 func (s *Service) GetShareWithAllMemberships(ctx context.Context, shareID, userID string) (*models.DriveShare, []*models.DriveShareMembership, error) {
+	ctx, span := tracing.Start(ctx, "drive.GetShareWithAllMemberships")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, nil, ctx.Err()
 	}
 
 	// Check cache first
-	cacheKey := fmt.Sprintf("share_with_memberships:%s:%s", shareID, userID)
+	cacheKey := s.versionedKey(ctx, fmt.Sprintf("share_with_memberships:%s:%s", shareID, userID),
+		cacheScope{cacheScopeShare, shareID}, cacheScope{cacheScopeUser, userID})
 	var cachedResult struct {
 		Share       models.DriveShare
 		Memberships []*models.DriveShareMembership
 	}
 
-	err := s.redisClient.GetJSON(ctx, cacheKey, &cachedResult)
+	err := s.getCached(ctx, cacheKey, &cachedResult)
 	if err == nil {
 		return &cachedResult.Share, cachedResult.Memberships, nil
 	}
 
 	// Cache miss, proceed with parallel database operations
 	// Create a context with timeout
-	opCtx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT)
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
 	defer cancel()
 
 	// Use channels for parallel operations
@@ -956,7 +970,7 @@ func (s *Service) GetShareWithAllMemberships(ctx context.Context, shareID, userI
 			// Cache the result
 			cachedResult.Share = *share
 			cachedResult.Memberships = []*models.DriveShareMembership{userMembership}
-			_ = s.redisClient.SetJSON(ctx, cacheKey, cachedResult, CACHE_EXPIRATION)
+			s.setCached(ctx, cacheKey, cachedResult)
 
 			return share, []*models.DriveShareMembership{userMembership}, nil
 		}
@@ -975,20 +989,23 @@ func (s *Service) GetShareWithAllMemberships(ctx context.Context, shareID, userI
 	// Cache the result
 	cachedResult.Share = *share
 	cachedResult.Memberships = membershipRes.Memberships
-	_ = s.redisClient.SetJSON(ctx, cacheKey, cachedResult, CACHE_EXPIRATION)
+	s.setCached(ctx, cacheKey, cachedResult)
 
 	return share, membershipRes.Memberships, nil
 }
 
 // BatchGetSharesWithMemberships with optimized parallelism and caching
 func (s *Service) BatchGetSharesWithMemberships(ctx context.Context, shareIDs []string, userID string) (map[string]*ShareWithMemberships, error) {
+	ctx, span := tracing.Start(ctx, "drive.BatchGetSharesWithMemberships", attribute.Int("drive.share_count", len(shareIDs)))
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
 	// Create a context with timeout
-	opCtx, cancel := context.WithTimeout(ctx, EXTENDED_TIMEOUT)
+	opCtx, cancel := withBudget(ctx, s.extendedTimeout())
 	defer cancel()
 
 	// Create result map with proper capacity
@@ -999,7 +1016,7 @@ func (s *Service) BatchGetSharesWithMemberships(ctx context.Context, shareIDs []
 	var resultMutex sync.Mutex
 
 	// Process all share IDs in parallel with a limit on concurrency
-	semaphore := make(chan struct{}, 5) // Limit to 5 concurrent operations
+	semaphore := make(chan struct{}, s.maxConcurrency()) // Limit concurrent operations
 
 	for _, shareID := range shareIDs {
 		wg.Add(1)
@@ -1011,14 +1028,19 @@ func (s *Service) BatchGetSharesWithMemberships(ctx context.Context, shareIDs []
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
+			// One span per share, so the fan-out shows how the shares overlap
+			opCtx, span := tracing.Start(opCtx, "drive.BatchGetSharesWithMemberships.share", attribute.String("drive.share_id", id))
+			defer span.End()
+
 			// Check cache first
-			cacheKey := fmt.Sprintf("share_with_memberships:%s:%s", id, userID)
+			cacheKey := s.versionedKey(opCtx, fmt.Sprintf("share_with_memberships:%s:%s", id, userID),
+				cacheScope{cacheScopeShare, id}, cacheScope{cacheScopeUser, userID})
 			var cachedResult struct {
 				Share       models.DriveShare
 				Memberships []*models.DriveShareMembership
 			}
 
-			err := s.redisClient.GetJSON(opCtx, cacheKey, &cachedResult)
+			err := s.getCached(opCtx, cacheKey, &cachedResult)
 			if err == nil {
 				// Cache hit
 				resultMutex.Lock()
@@ -1066,6 +1088,9 @@ func hasAdminOrSharePermissions(memberships []*models.DriveShareMembership, user
 
 // GetLinkByID retrieves any drive item (file or folder) by ID with caching
 func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*models.DriveItem, error) {
+	ctx, span := tracing.Start(ctx, "drive.GetLinkByID")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -1074,7 +1099,7 @@ func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*mode
 	// Check cache first
 	cacheKey := fmt.Sprintf("link:%s", linkID)
 	var item models.DriveItem
-	err := s.redisClient.GetJSON(ctx, cacheKey, &item)
+	err := s.getCached(ctx, cacheKey, &item)
 	if err == nil {
 		// Check permissions separately
 		shareID := item.ShareID
@@ -1088,7 +1113,7 @@ func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*mode
 
 	// Cache miss, get with parallel operations
 	// Create a context with timeout for parallel operations
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT)
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
 	defer cancel()
 
 	// Use channels for parallel operations
@@ -1100,7 +1125,7 @@ func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*mode
 
 	// Get item in parallel
 	go func() {
-		item, err := s.repo.GetLinkByID(ctxWithTimeout, linkID)
+		item, err := s.repo.GetLinkByID(opCtx, linkID)
 		itemCh <- itemResult{Item: item, Err: err}
 	}()
 
@@ -1115,7 +1140,7 @@ func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*mode
 	item = *itemRes.Item
 
 	// Cache the item
-	_ = s.redisClient.SetJSON(ctx, cacheKey, item, CACHE_EXPIRATION)
+	s.setCached(ctx, cacheKey, item)
 
 	// Get the share to check permissions - start in parallel
 	type shareResult struct {
@@ -1124,7 +1149,7 @@ func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*mode
 	}
 	shareCh := make(chan shareResult, 1)
 	go func() {
-		share, err := s.GetShareByID(ctxWithTimeout, item.ShareID)
+		share, err := s.GetShareByID(opCtx, item.ShareID)
 		shareCh <- shareResult{Share: share, Err: err}
 	}()
 
@@ -1143,7 +1168,7 @@ func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*mode
 		// Check permissions through share membership
 		permissionCh := make(chan error, 1)
 		go func() {
-			err := s.CheckSharePermissions(ctxWithTimeout, userID, item.ShareID, READ_PERMISSION)
+			err := s.CheckSharePermissions(opCtx, userID, item.ShareID, READ_PERMISSION)
 			permissionCh <- err
 		}()
 		if err := <-permissionCh; err != nil {
@@ -1157,6 +1182,9 @@ func (s *Service) GetLinkByID(ctx context.Context, linkID, userID string) (*mode
 
 // GetFolderByID retrieves a folder by ID with caching
 func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*models.DriveItem, error) {
+	ctx, span := tracing.Start(ctx, "drive.GetFolderByID")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -1165,7 +1193,7 @@ func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*
 	// Check cache first
 	cacheKey := fmt.Sprintf("folder:%s", folderID)
 	var folder models.DriveItem
-	err := s.redisClient.GetJSON(ctx, cacheKey, &folder)
+	err := s.getCached(ctx, cacheKey, &folder)
 	if err == nil {
 		// Check permissions separately
 		shareID := folder.ShareID
@@ -1181,7 +1209,7 @@ func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*
 
 	// Cache miss, get with parallel operations
 	// Create a context with timeout for parallel operations
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT)
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
 	defer cancel()
 
 	// Use channels for parallel operations
@@ -1194,7 +1222,7 @@ func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*
 
 	// Get folder in parallel
 	go func() {
-		folder, err := s.repo.GetFolderByID(ctxWithTimeout, folderID)
+		folder, err := s.repo.GetFolderByID(opCtx, folderID)
 		folderCh <- folderResult{Folder: folder, Err: err}
 	}()
 
@@ -1214,7 +1242,7 @@ func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*
 	}
 
 	// Cache the folder
-	_ = s.redisClient.SetJSON(ctx, cacheKey, folder, CACHE_EXPIRATION)
+	s.setCached(ctx, cacheKey, folder)
 
 	// Get the share to check permissions - start in parallel
 	type shareResult struct {
@@ -1225,7 +1253,7 @@ func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*
 	shareCh := make(chan shareResult, 1)
 
 	go func() {
-		share, err := s.GetShareByID(ctxWithTimeout, folder.ShareID)
+		share, err := s.GetShareByID(opCtx, folder.ShareID)
 		shareCh <- shareResult{Share: share, Err: err}
 	}()
 
@@ -1247,7 +1275,7 @@ func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*
 		permissionCh := make(chan error, 1)
 
 		go func() {
-			err := s.CheckSharePermissions(ctxWithTimeout, userID, folder.ShareID, READ_PERMISSION)
+			err := s.CheckSharePermissions(opCtx, userID, folder.ShareID, READ_PERMISSION)
 			permissionCh <- err
 		}()
 
@@ -1260,34 +1288,45 @@ func (s *Service) GetFolderByID(ctx context.Context, folderID, userID string) (*
 	return &folder, nil
 }
 
-// GetFolderContents retrieves folder contents with caching for the first page only
+// GetFolderContents retrieves folder contents, optionally only items carrying all of the given tags,
+// with caching for the unfiltered first page only
 func (s *Service) GetFolderContents(
 	ctx context.Context,
 	shareID,
 	folderID,
 	userID string,
+	tagIDs []string,
 	limit,
 	offset int,
 	sortBy,
 	sortDir string,
 ) ([]*models.DriveItem, int, error) {
+	ctx, span := tracing.Start(ctx, "drive.GetFolderContents")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, 0, ctx.Err()
 	}
 
+	// Tag filters are per user, so filtered listings bypass the shared folder cache
+	tagIDs, err := s.resolveTagFilter(ctx, userID, tagIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	cacheable := offset == 0 && limit <= 100 && len(tagIDs) == 0
+
 	// Only cache the first page (offset 0)
 	// For other pages, go directly to the database
-	if offset == 0 && limit <= 100 {
+	var cacheKey string
+	if cacheable {
 		// We'll cache the first page only to optimize memory usage
-		cacheKey := fmt.Sprintf("folder_contents:%s:%s:%s:%d", folderID, sortBy, sortDir, limit)
-		var folderContents struct {
-			Items []*models.DriveItem
-			Total int
-		}
+		cacheKey = s.versionedKey(ctx, fmt.Sprintf("folder_contents:%s:%s:%s:%d", folderID, sortBy, sortDir, limit),
+			cacheScope{cacheScopeFolder, folderID})
+		var folderContents folderContentsCache
 
 		// Try to get from cache
-		err := s.redisClient.GetJSON(ctx, cacheKey, &folderContents)
+		err := s.getCached(ctx, cacheKey, &folderContents)
 		if err == nil {
 			// Check if user has permission to view this folder
 			permErr := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION)
@@ -1302,11 +1341,11 @@ func (s *Service) GetFolderContents(
 
 	// Cache miss or non-first page, proceed with database operations
 	// Create a context with timeout for parallel operations
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT)
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
 	defer cancel()
 
 	// Use errgroup for coordinated error handling in parallel operations
-	g, gCtx := errgroup.WithContext(ctxWithTimeout)
+	g, gCtx := errgroup.WithContext(opCtx)
 
 	var share *models.DriveShare
 	var folder *models.DriveItem
@@ -1377,7 +1416,11 @@ func (s *Service) GetFolderContents(
 	go func() {
 		// Get folder contents with pagination and sorting
 		var err error
-		items, total, err = s.repo.GetFolderContentsPaginated(ctx, folderID, limit, offset, sortBy, sortDir)
+		if cacheKey != "" {
+			items, total, err = s.loadFolderContents(ctx, cacheKey, folderID, limit, sortBy, sortDir)
+		} else {
+			items, total, err = s.repo.GetFolderContentsPaginated(ctx, folderID, tagIDs, limit, offset, sortBy, sortDir)
+		}
 		if err != nil {
 			contentErr = fmt.Errorf("failed to get folder contents: %w", err)
 		}
@@ -1398,32 +1441,64 @@ func (s *Service) GetFolderContents(
 		return nil, 0, contentErr
 	}
 
-	// Cache only the first page results
-	if offset == 0 && limit <= 100 {
-		cacheKey := fmt.Sprintf("folder_contents:%s:%s:%s:%d", folderID, sortBy, sortDir, limit)
-		folderContents := struct {
-			Items []*models.DriveItem
-			Total int
-		}{
-			Items: items,
-			Total: total,
+	// Return the results directly (no additional pagination needed)
+	return items, total, nil
+}
+
+// folderContentsCache is the cached first page of a folder listing
+type folderContentsCache struct {
+	Items []*models.DriveItem
+	Total int
+}
+
+// loadFolderContents reads the first page of a folder listing from the database and caches it. Callers
+// that miss the cache for the same key at once share one query, so an invalidated popular folder is not
+// read by every waiting request. The query is not cancelled when the caller that started it goes away,
+// since the others still wait on it.
+func (s *Service) loadFolderContents(ctx context.Context, cacheKey, folderID string, limit int, sortBy, sortDir string) ([]*models.DriveItem, int, error) {
+	value, err, _ := s.flights.Do(cacheKey, func() (any, error) {
+		loadCtx, cancel := withBudget(context.WithoutCancel(ctx), s.defaultTimeout())
+		defer cancel()
+
+		items, total, err := s.repo.GetFolderContentsPaginated(loadCtx, folderID, nil, limit, 0, sortBy, sortDir)
+		if err != nil {
+			return nil, err
 		}
 
-		// Store in cache with expiration
-		_ = s.redisClient.SetJSON(ctx, cacheKey, folderContents, CACHE_EXPIRATION)
+		contents := &folderContentsCache{Items: items, Total: total}
+		s.setCached(loadCtx, cacheKey, contents)
+		return contents, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	contents := value.(*folderContentsCache)
+	return contents.Items, contents.Total, nil
+}
+
+// getCached reads a cached value, recording a hit or miss for the cache named by the key's prefix
+func (s *Service) getCached(ctx context.Context, cacheKey string, dest interface{}) error {
+	if cacheKey == "" {
+		return errCacheBypassed
 	}
+	cache, _, _ := strings.Cut(cacheKey, ":")
 
-	// Return the results directly (no additional pagination needed)
-	return items, total, nil
+	err := s.redisClient.GetJSON(ctx, cacheKey, dest)
+	if err != nil {
+		metrics.CacheLookups.Inc(cache, metrics.CACHE_MISS)
+		return err
+	}
+	metrics.CacheLookups.Inc(cache, metrics.CACHE_HIT)
+	return nil
 }
 
 // Cache invalidation helpers
 
 // invalidateFolderCaches invalidates caches related to a folder
 func (s *Service) invalidateFolderCaches(ctx context.Context, folderID string) {
-	// Delete folder contents cache - using pattern to match all sort options
-	folderContentsPattern := fmt.Sprintf("folder_contents:%s:*", folderID)
-	s.deleteKeysWithPattern(ctx, folderContentsPattern)
+	// Folder contents for every sort option and the name checks of the folder
+	s.bumpCacheVersion(ctx, cacheScope{cacheScopeFolder, folderID})
 
 	// Delete folder cache
 	folderCacheKey := fmt.Sprintf("folder:%s", folderID)
@@ -1433,10 +1508,6 @@ func (s *Service) invalidateFolderCaches(ctx context.Context, folderID string) {
 	} else if deleted {
 		s.logger.Debugf("Deleted folder cache for folder %s", folderID)
 	}
-
-	// Delete all hash existence caches for this folder
-	hashExistencePattern := fmt.Sprintf("folder_hash:%s:*", folderID)
-	s.deleteKeysWithPattern(ctx, hashExistencePattern)
 }
 
 // invalidateShareCaches invalidates caches related to a share
@@ -1450,13 +1521,8 @@ func (s *Service) invalidateShareCaches(ctx context.Context, shareID string) {
 		s.logger.Debugf("Deleted share cache for share %s", shareID)
 	}
 
-	// Delete all share membership caches for this share
-	shareWithMembershipsPattern := fmt.Sprintf("share_with_memberships:%s:*", shareID)
-	s.deleteKeysWithPattern(ctx, shareWithMembershipsPattern)
-
-	// Delete all permission check caches related to this share
-	permissionPattern := fmt.Sprintf("perm:*:%s:*", shareID)
-	s.deleteKeysWithPattern(ctx, permissionPattern)
+	// Memberships and permission checks of every member of the share
+	s.bumpCacheVersion(ctx, cacheScope{cacheScopeShare, shareID})
 }
 
 // invalidateUserCaches invalidates caches related to a user
@@ -1497,41 +1563,6 @@ func (s *Service) invalidateUserCaches(ctx context.Context, userID string) {
 		s.logger.Debugf("Deleted item count cache for user %s", userID)
 	}
 
-	// Delete all permission caches related to this user
-	permissionPattern := fmt.Sprintf("perm:%s:*", userID)
-	s.deleteKeysWithPattern(ctx, permissionPattern)
-
-	// Delete all share membership caches related to this user
-	membershipPattern := fmt.Sprintf("share_with_memberships:*:%s", userID)
-	s.deleteKeysWithPattern(ctx, membershipPattern)
-}
-
-// deleteKeysWithPattern deletes all keys matching a pattern using SCAN
-func (s *Service) deleteKeysWithPattern(ctx context.Context, pattern string) {
-	// Execute Lua script to find and delete all keys matching the pattern
-	script := `
-		local cursor = "0"
-		local keyCount = 0
-		repeat
-			local result = redis.call("SCAN", cursor, "MATCH", ARGV[1], "COUNT", 100)
-			cursor = result[1]
-			local keys = result[2]
-			if #keys > 0 then
-				keyCount = keyCount + #keys
-				redis.call("DEL", unpack(keys))
-			end
-		until cursor == "0"
-		return keyCount
-	`
-
-	// Run the script
-	deletedCount, err := s.redisClient.Eval(ctx, script, []string{}, []string{pattern})
-	if err != nil {
-		s.logger.Errorf("Failed to delete keys with pattern %s: %v", pattern, err)
-	} else {
-		count, ok := deletedCount.(int64)
-		if ok && count > 0 {
-			s.logger.Debugf("Deleted %d keys matching pattern %s", count, pattern)
-		}
-	}
+	// Permission checks and share memberships of the user in every share
+	s.bumpCacheVersion(ctx, cacheScope{cacheScopeUser, userID})
 }
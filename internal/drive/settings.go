@@ -0,0 +1,136 @@
+package drive
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cirrussync-api/pkg/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Bounds for runtime-tunable settings
+const (
+	MIN_TIMEOUT         = 1 * time.Second
+	MAX_TIMEOUT         = 2 * time.Minute
+	MIN_MAX_CONCURRENCY = 1
+	MAX_MAX_CONCURRENCY = 100
+)
+
+// RuntimeSettings holds the concurrency and time budget knobs of the drive service
+type RuntimeSettings struct {
+	DefaultTimeout  time.Duration
+	ExtendedTimeout time.Duration
+	MaxConcurrency  int
+}
+
+// runtimeSettings guards the current settings so they can be tuned while serving traffic
+type runtimeSettings struct {
+	mu       sync.RWMutex
+	settings RuntimeSettings
+}
+
+// newRuntimeSettings builds runtime settings from config, falling back to defaults for invalid values
+func newRuntimeSettings(cfg *config.DriveConfig) *runtimeSettings {
+	settings := RuntimeSettings{
+		DefaultTimeout:  10 * time.Second,
+		ExtendedTimeout: 15 * time.Second,
+		MaxConcurrency:  5,
+	}
+
+	if cfg != nil {
+		candidate := RuntimeSettings{
+			DefaultTimeout:  cfg.DefaultTimeout,
+			ExtendedTimeout: cfg.ExtendedTimeout,
+			MaxConcurrency:  cfg.MaxConcurrency,
+		}
+		if validateRuntimeSettings(candidate) == nil {
+			settings = candidate
+		}
+	}
+
+	return &runtimeSettings{settings: settings}
+}
+
+// validateRuntimeSettings checks that all settings are within safe bounds
+func validateRuntimeSettings(settings RuntimeSettings) error {
+	if settings.DefaultTimeout < MIN_TIMEOUT || settings.DefaultTimeout > MAX_TIMEOUT {
+		return ErrInvalidRuntimeSettings
+	}
+	if settings.ExtendedTimeout < settings.DefaultTimeout || settings.ExtendedTimeout > MAX_TIMEOUT {
+		return ErrInvalidRuntimeSettings
+	}
+	if settings.MaxConcurrency < MIN_MAX_CONCURRENCY || settings.MaxConcurrency > MAX_MAX_CONCURRENCY {
+		return ErrInvalidRuntimeSettings
+	}
+	return nil
+}
+
+// GetRuntimeSettings returns a snapshot of the current runtime settings
+func (s *Service) GetRuntimeSettings() RuntimeSettings {
+	s.settings.mu.RLock()
+	defer s.settings.mu.RUnlock()
+	return s.settings.settings
+}
+
+// UpdateRuntimeSettings replaces the runtime settings after validating them.
+// Settings are held in memory and apply to this instance only.
+func (s *Service) UpdateRuntimeSettings(settings RuntimeSettings) (RuntimeSettings, error) {
+	if err := validateRuntimeSettings(settings); err != nil {
+		return s.GetRuntimeSettings(), err
+	}
+
+	s.settings.mu.Lock()
+	s.settings.settings = settings
+	s.settings.mu.Unlock()
+
+	s.logger.WithFields(logrus.Fields{
+		"defaultTimeout":  settings.DefaultTimeout.String(),
+		"extendedTimeout": settings.ExtendedTimeout.String(),
+		"maxConcurrency":  settings.MaxConcurrency,
+	}).Info("Drive runtime settings updated")
+
+	return settings, nil
+}
+
+// DefaultRequestBudget returns the deadline of a whole request to a single-entity route.
+// Budgets are read per request, so tuned settings apply without a restart.
+func (s *Service) DefaultRequestBudget() time.Duration {
+	return s.defaultTimeout()
+}
+
+// ExtendedRequestBudget returns the deadline of a whole request to a multi-step or batch route
+func (s *Service) ExtendedRequestBudget() time.Duration {
+	return s.extendedTimeout()
+}
+
+// EventWaitRequestBudget returns the deadline of a long-poll for volume events, which outlasts
+// the longest wait a client may ask for
+func (s *Service) EventWaitRequestBudget() time.Duration {
+	return MAX_EVENT_WAIT + s.defaultTimeout()
+}
+
+// withBudget derives an operation context from ctx. Requests already carry the deadline of their
+// route class, so only callers without one, such as background jobs, get the fallback budget.
+func withBudget(ctx context.Context, fallback time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, fallback)
+}
+
+// defaultTimeout returns the budget for single-entity operations
+func (s *Service) defaultTimeout() time.Duration {
+	return s.GetRuntimeSettings().DefaultTimeout
+}
+
+// extendedTimeout returns the budget for multi-step or batch operations
+func (s *Service) extendedTimeout() time.Duration {
+	return s.GetRuntimeSettings().ExtendedTimeout
+}
+
+// maxConcurrency returns the maximum number of concurrent workers for fan-out operations
+func (s *Service) maxConcurrency() int {
+	return s.GetRuntimeSettings().MaxConcurrency
+}
@@ -0,0 +1,211 @@
+// internal/drive/share_copy.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	utils "cirrussync-api/internal/utils"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// MAX_COPY_TREE_ITEMS bounds the number of items, the copied item included, a single copy may create
+const MAX_COPY_TREE_ITEMS = 1000
+
+// ItemShareCopy holds the data needed to copy an item into a folder of another share.
+// The node key is re-locked and its passphrase re-encrypted client-side for the destination folder.
+// Only the copied item itself is re-wrapped: its descendants stay encrypted with its node key,
+// which does not change.
+type ItemShareCopy struct {
+	TargetShareID           string
+	ParentID                string
+	Name                    string
+	Hash                    string
+	NameSignatureEmail      string
+	NodeKey                 string
+	NodePassphrase          string
+	NodePassphraseSignature string
+	SignatureEmail          string
+}
+
+// CopyItemToShare copies a file, or a folder with its live contents, into a folder of another share.
+// Stored blocks are copied server-side to the destination owner's storage, and the copy is charged to
+// the destination share's owner; the source share keeps its items and usage.
+func (s *Service) CopyItemToShare(ctx context.Context, userID, shareID, linkID string, target *ItemShareCopy) (*models.DriveItem, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+	if err := s.CheckSharePermissions(ctx, userID, target.TargetShareID, WRITE_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	if s.storage == nil || s.storageDegraded() {
+		return nil, ErrStorageUnavailable
+	}
+
+	source, err := s.repo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLiveItemOfShare(source, shareID) {
+		return nil, ErrItemNotFound
+	}
+
+	targetShare, err := s.GetShareByID(ctx, target.TargetShareID)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := s.getBatchDestination(ctx, target.TargetShareID, target.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A folder cannot be copied into itself or any of its descendants
+	if source.Type == 1 {
+		ancestors, err := s.repo.GetAncestorIDs(ctx, destination.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve destination path: %w", err)
+		}
+		if destination.ID == source.ID || slices.Contains(ancestors, source.ID) {
+			return nil, ErrInvalidMoveTarget
+		}
+	}
+
+	exists, err := s.checkNameExists(ctx, destination.ID, target.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrFileNameConflict
+	}
+
+	tree := []*models.DriveItem{source}
+	if source.Type == 1 {
+		// One more than allowed tells a tree at the limit from a larger one
+		tree, err = s.repo.GetLiveSubtree(ctx, source.ID, MAX_COPY_TREE_ITEMS+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load folder contents: %w", err)
+		}
+		if len(tree) > MAX_COPY_TREE_ITEMS {
+			return nil, ErrTooManyItems
+		}
+	}
+
+	copies, sourceBlocks, totalSize, err := s.prepareTreeCopy(ctx, targetShare, destination, tree, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.quota.Consume(ctx, targetShare.UserID, totalSize); err != nil {
+		return nil, err
+	}
+
+	copiedPaths := make([]string, 0)
+	for _, fileCopy := range copies {
+		for _, block := range fileCopy.Blocks {
+			copiedPaths = append(copiedPaths, block.StoragePath)
+		}
+	}
+
+	// A partial tree is no copy, so any block that cannot be copied fails the whole request
+	for _, copyErr := range s.copyStoredBlocks(ctx, copies, sourceBlocks) {
+		if copyErr != nil {
+			s.updateStorageUsed(context.Background(), targetShare.UserID, -totalSize)
+			go s.deleteStoredObjects(context.WithoutCancel(ctx), copiedPaths)
+			return nil, copyErr
+		}
+	}
+
+	if err := s.repo.CreateFileCopies(ctx, copies); err != nil {
+		// Give back the charge and drop the orphaned objects (can be done asynchronously)
+		s.updateStorageUsed(context.Background(), targetShare.UserID, -totalSize)
+		go s.deleteStoredObjects(context.WithoutCancel(ctx), copiedPaths)
+		return nil, fmt.Errorf("failed to copy item: %w", err)
+	}
+
+	created := make([]*models.DriveItem, len(copies))
+	for i, fileCopy := range copies {
+		created[i] = fileCopy.Item
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_CREATE, created...)
+	s.recordBackupActivity(ctx, targetShare)
+
+	s.invalidateFolderCaches(ctx, destination.ID)
+	s.invalidateUserCaches(ctx, targetShare.UserID)
+
+	return created[0], nil
+}
+
+// prepareTreeCopy builds the copies of a tree of items, parents first, into a destination folder. The root
+// takes the client's re-wrapped key and name; descendants keep theirs under their copied parents. It returns
+// the source blocks of each copy in the same order and the bytes the copies count against the quota.
+func (s *Service) prepareTreeCopy(ctx context.Context, share *models.DriveShare, destination *models.DriveItem, tree []*models.DriveItem, target *ItemShareCopy) ([]*FileCopy, [][]*models.FileBlock, int64, error) {
+	copies := make([]*FileCopy, len(tree))
+	sourceBlocks := make([][]*models.FileBlock, len(tree))
+	// Copied folder IDs by the ID of the folder they were copied from
+	copiedIDs := make(map[string]string, len(tree))
+	var totalSize int64
+
+	for i, source := range tree {
+		item := &models.DriveItem{
+			ID:                      utils.GenerateLinkID(),
+			ShareID:                 share.ID,
+			VolumeID:                share.VolumeID,
+			Type:                    source.Type,
+			Name:                    source.Name,
+			Hash:                    source.Hash,
+			NameSignatureEmail:      source.NameSignatureEmail,
+			State:                   ITEM_STATE_ACTIVE,
+			MimeType:                source.MimeType,
+			NodeKey:                 source.NodeKey,
+			NodePassphrase:          source.NodePassphrase,
+			NodePassphraseSignature: source.NodePassphraseSignature,
+			SignatureEmail:          source.SignatureEmail,
+			FileProperties:          source.FileProperties,
+			FolderProperties:        source.FolderProperties,
+			Xattrs:                  source.Xattrs,
+			Permissions:             RW_PERMISSIONS,
+		}
+
+		if i == 0 {
+			item.ParentID = &destination.ID
+			item.Name = target.Name
+			item.Hash = target.Hash
+			item.NameSignatureEmail = target.NameSignatureEmail
+			item.NodeKey = target.NodeKey
+			item.NodePassphrase = target.NodePassphrase
+			item.NodePassphraseSignature = target.NodePassphraseSignature
+			item.SignatureEmail = target.SignatureEmail
+		} else {
+			parentID, ok := copiedIDs[*source.ParentID]
+			if !ok {
+				return nil, nil, 0, fmt.Errorf("parent of %s was not copied before it", source.ID)
+			}
+			item.ParentID = &parentID
+		}
+
+		if source.Type == 1 {
+			copiedIDs[source.ID] = item.ID
+			copies[i] = &FileCopy{Item: item}
+			totalSize += FOLDER_METADATA_BYTES
+			continue
+		}
+
+		fileCopy, blocks, err := s.prepareRevisionCopy(ctx, share, source, item)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		copies[i] = fileCopy
+		sourceBlocks[i] = blocks
+		totalSize += fileCopy.Revision.Size
+	}
+
+	return copies, sourceBlocks, totalSize, nil
+}
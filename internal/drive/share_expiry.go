@@ -0,0 +1,213 @@
+// internal/drive/share_expiry.go
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// JOB_TYPE_SHARE_EXPIRY notifies members of shares about to expire and ends access to expired ones
+const JOB_TYPE_SHARE_EXPIRY = "drive.share_expiry"
+
+// SHARE_EXPIRY_BATCH_SIZE bounds how many shares or public links one scheduler pass handles of each kind
+const SHARE_EXPIRY_BATCH_SIZE = 100
+
+// SetShareExpiry sets, renews or clears when a share's members and public links lose access.
+// Only the share owner may change it. Renewing an expired share restores the access it removed.
+func (s *Service) SetShareExpiry(ctx context.Context, userID, shareID string, expiresAt *int64) (*models.DriveShare, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	now := time.Now().Unix()
+	if expiresAt != nil && *expiresAt <= now {
+		return nil, ErrInvalidExpiry
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if share.UserID != userID {
+		return nil, ErrInsufficientPermissions
+	}
+
+	if err := s.repo.SetShareExpiry(ctx, shareID, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to update share expiry: %w", err)
+	}
+
+	restoredIDs, err := s.repo.RestoreShareAccess(ctx, shareID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore share access: %w", err)
+	}
+
+	s.invalidateShareCaches(ctx, shareID)
+	for _, memberID := range restoredIDs {
+		s.invalidateMembershipCaches(ctx, shareID, memberID)
+	}
+
+	share.ExpiresAt = expiresAt
+	share.ExpiryNotifiedAt = nil
+	return share, nil
+}
+
+// SetShareURLExpiry sets, renews or clears the expiry of a public link. Only the link creator or
+// the share owner may change it. An expired link is reactivated by giving it a new expiry.
+func (s *Service) SetShareURLExpiry(ctx context.Context, userID, shareID, urlID string, expiresAt *int64) (*models.DriveShareURL, error) {
+	if expiresAt != nil && *expiresAt <= time.Now().Unix() {
+		return nil, ErrInvalidExpiry
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	shareURL, err := s.repo.GetShareURLByID(ctx, urlID)
+	if err != nil {
+		return nil, err
+	}
+	if shareURL.ShareID != shareID || shareURL.State == SHARE_URL_STATE_REVOKED {
+		return nil, ErrShareURLNotFound
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if shareURL.CreatorID != userID && share.UserID != userID {
+		return nil, ErrInsufficientPermissions
+	}
+	// Links of an expired share come back when the share is renewed
+	if shareExpired(share) {
+		return nil, ErrShareExpired
+	}
+
+	shareURL.ExpiresAt = expiresAt
+	shareURL.State = SHARE_URL_STATE_ACTIVE
+	if err := s.repo.UpdateShareURL(ctx, shareURL); err != nil {
+		return nil, fmt.Errorf("failed to update public link: %w", err)
+	}
+
+	s.invalidateLinkCache(ctx, shareURL.ItemID)
+
+	return shareURL, nil
+}
+
+// StartShareExpiryScheduler queues a share expiry job every scan interval until ctx is cancelled
+func (s *Service) StartShareExpiryScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.Schedule(ctx, "share_expiry_pass", s.expiry.scanInterval, JOB_TYPE_SHARE_EXPIRY, nil)
+}
+
+// runShareExpiryJob notifies members of shares about to expire and ends access to expired shares,
+// memberships and public links. Notices are marked before they are sent and expired access is not
+// found again, so running it again is safe.
+func (s *Service) runShareExpiryJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	now := time.Now().Unix()
+	s.notifyExpiringShares(ctx, now)
+	s.expireShares(ctx, now)
+	s.expireMemberships(ctx, now)
+	s.expireShareURLs(ctx, now)
+
+	return ctx.Err()
+}
+
+// notifyExpiringShares emails the members of shares that expire within the notice window, once per expiry date
+func (s *Service) notifyExpiringShares(ctx context.Context, now int64) {
+	noticeUntil := now + int64(s.expiry.notice.Seconds())
+	shares, err := s.repo.GetSharesDueForExpiryNotice(ctx, now, noticeUntil, SHARE_EXPIRY_BATCH_SIZE)
+	if err != nil {
+		s.logger.Errorf("Failed to load shares due for an expiry notice: %v", err)
+		return
+	}
+
+	for _, share := range shares {
+		// Mark first so a crash can at worst skip a notice, never send it twice
+		marked, err := s.repo.MarkShareExpiryNotified(ctx, share.ID, now)
+		if err != nil {
+			s.logger.Errorf("Failed to mark expiry notice of share %s: %v", share.ID, err)
+			continue
+		}
+		if !marked || s.mailer == nil {
+			continue
+		}
+
+		members, err := s.repo.GetActiveShareMembers(ctx, share.ID)
+		if err != nil {
+			s.logger.Errorf("Failed to load members of expiring share %s: %v", share.ID, err)
+			continue
+		}
+
+		for _, member := range members {
+			if err := s.mailer.SendShareExpiryEmail(member.Email, share.ID, *share.ExpiresAt); err != nil {
+				s.logger.Errorf("Failed to send expiry notice of share %s to %s: %v", share.ID, member.ID, err)
+			}
+		}
+	}
+}
+
+// expireShares removes the members and public links of shares past their expiry
+func (s *Service) expireShares(ctx context.Context, now int64) {
+	shareIDs, err := s.repo.GetExpiredShareIDs(ctx, now, SHARE_EXPIRY_BATCH_SIZE)
+	if err != nil {
+		s.logger.Errorf("Failed to load expired shares: %v", err)
+		return
+	}
+
+	for _, shareID := range shareIDs {
+		memberIDs, err := s.repo.ExpireShareAccess(ctx, shareID)
+		if err != nil {
+			s.logger.Errorf("Failed to expire access to share %s: %v", shareID, err)
+			continue
+		}
+
+		s.invalidateShareCaches(ctx, shareID)
+		for _, memberID := range memberIDs {
+			s.invalidateMembershipCaches(ctx, shareID, memberID)
+		}
+
+		s.logger.Infof("Share %s expired, removed access for %d members", shareID, len(memberIDs))
+	}
+}
+
+// expireMemberships deactivates memberships past their own permission expiry
+func (s *Service) expireMemberships(ctx context.Context, now int64) {
+	memberships, err := s.repo.ExpireMemberships(ctx, now, SHARE_EXPIRY_BATCH_SIZE)
+	if err != nil {
+		s.logger.Errorf("Failed to expire share memberships: %v", err)
+		return
+	}
+
+	for _, membership := range memberships {
+		s.invalidateMembershipCaches(ctx, membership.ShareID, membership.UserID)
+	}
+
+	if len(memberships) > 0 {
+		s.logger.Infof("Deactivated %d share memberships whose permissions expired", len(memberships))
+	}
+}
+
+// expireShareURLs deactivates public links past their own expiry
+func (s *Service) expireShareURLs(ctx context.Context, now int64) {
+	shareURLs, err := s.repo.ExpireShareURLs(ctx, now, SHARE_EXPIRY_BATCH_SIZE)
+	if err != nil {
+		s.logger.Errorf("Failed to expire public links: %v", err)
+		return
+	}
+
+	for _, shareURL := range shareURLs {
+		s.invalidateLinkCache(ctx, shareURL.ItemID)
+	}
+}
+
+// shareExpired reports whether a share is past its expiry
+func shareExpired(share *models.DriveShare) bool {
+	return share.ExpiresAt != nil && *share.ExpiresAt <= time.Now().Unix()
+}
@@ -0,0 +1,183 @@
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"context"
+	"time"
+)
+
+// ShareUnlockKeys are the share passphrase and the owner's key packet re-wrapped for the owner's
+// new key by a member who can still decrypt the share
+type ShareUnlockKeys struct {
+	SharePassphrase          string
+	SharePassphraseSignature string
+	OwnerKeyPacket           string
+	OwnerKeyPacketSignature  string
+}
+
+// LockOwnedShares locks the user's shares after their keys were replaced, since the share
+// passphrases are still wrapped for the old key. Active admin members are marked as able to unlock
+// each share. Returns how many shares were locked.
+func (s *Service) LockOwnedShares(ctx context.Context, userID string) (int, error) {
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	shareIDs, err := s.repo.LockSharesByUserID(ctx, userID, time.Now().Unix())
+	if err != nil {
+		s.logger.Errorf("Failed to lock shares of user %s: %v", userID, err)
+		return 0, err
+	}
+	if len(shareIDs) == 0 {
+		return 0, nil
+	}
+
+	for _, shareID := range shareIDs {
+		s.invalidateShareMembershipCaches(ctx, shareID)
+	}
+	s.invalidateUserCaches(ctx, userID)
+
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_SHARE_LOCKED,
+		Success:   true,
+		Metadata: map[string]any{
+			"reason":   "key_rotation",
+			"shareIds": shareIDs,
+		},
+	})
+
+	return len(shareIDs), nil
+}
+
+// GetLockedShares returns the locked shares the user owns or can unlock, each with the user's own
+// membership so the client can decrypt the share passphrase
+func (s *Service) GetLockedShares(ctx context.Context, userID string) ([]*ShareWithMemberships, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	memberships, err := s.repo.GetUnlockableMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memberships) == 0 {
+		return []*ShareWithMemberships{}, nil
+	}
+
+	shareIDs := make([]string, len(memberships))
+	for i, membership := range memberships {
+		shareIDs[i] = membership.ShareID
+	}
+
+	shares, err := s.repo.BatchGetSharesByIDs(ctx, shareIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ShareWithMemberships, 0, len(memberships))
+	for _, membership := range memberships {
+		share, ok := shares[membership.ShareID]
+		if !ok {
+			continue
+		}
+		result = append(result, &ShareWithMemberships{
+			Share:       share,
+			Memberships: []*models.DriveShareMembership{membership},
+		})
+	}
+
+	return result, nil
+}
+
+// UnlockShare stores a share passphrase and owner key packet re-wrapped for the owner's new key,
+// making the share usable by its owner again. Only the owner and members marked as able to unlock
+// when the share was locked can do this.
+func (s *Service) UnlockShare(ctx context.Context, userID, shareID string, keys ShareUnlockKeys) (*models.DriveShare, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if keys.SharePassphrase == "" || keys.SharePassphraseSignature == "" ||
+		keys.OwnerKeyPacket == "" || keys.OwnerKeyPacketSignature == "" {
+		return nil, ErrInvalidUnlockPacket
+	}
+
+	// Read past the cache, the lock state decides whether the keys are replaced
+	share, err := s.repo.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if !share.Locked {
+		return nil, ErrShareNotLocked
+	}
+
+	if share.UserID != userID {
+		membership, err := s.repo.GetMembershipByShareAndUserID(ctx, shareID, userID)
+		if err != nil {
+			if err == ErrMembershipNotFound {
+				return nil, ErrCannotUnlockShare
+			}
+			return nil, err
+		}
+		if membership.CanUnlock == nil || !*membership.CanUnlock {
+			return nil, ErrCannotUnlockShare
+		}
+	}
+
+	if err := s.repo.UnlockShare(ctx, share, keys); err != nil {
+		if err != ErrShareNotLocked {
+			s.logger.Errorf("Failed to unlock share %s: %v", shareID, err)
+		}
+		return nil, err
+	}
+	share.Locked = false
+	share.LockedAt = nil
+	share.SharePassphrase = keys.SharePassphrase
+	share.SharePassphraseSignature = keys.SharePassphraseSignature
+
+	s.invalidateShareMembershipCaches(ctx, shareID)
+	s.invalidateUserCaches(ctx, share.UserID)
+
+	// Clients of the owner pick the share up again from the change feed
+	if rootFolder, err := s.repo.GetRootFolderByShareID(ctx, shareID); err == nil {
+		s.recordEvents(ctx, EVENT_TYPE_UPDATE, rootFolder)
+	}
+
+	s.recordShareUnlocked(ctx, share.UserID, userID, shareID)
+	if userID != share.UserID {
+		s.recordShareUnlocked(ctx, userID, userID, shareID)
+	}
+
+	return share, nil
+}
+
+// recordShareUnlocked records an unlocked share as a security event of one of the users involved
+func (s *Service) recordShareUnlocked(ctx context.Context, userID, unlockedBy, shareID string) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_SHARE_UNLOCKED,
+		Success:   true,
+		Metadata: map[string]any{
+			"shareId":    shareID,
+			"unlockedBy": unlockedBy,
+		},
+	})
+}
+
+// invalidateShareMembershipCaches invalidates a share and the cached membership of every member,
+// whose unlock flags change with the share's lock state
+func (s *Service) invalidateShareMembershipCaches(ctx context.Context, shareID string) {
+	memberships, err := s.repo.GetMembershipsByShareID(ctx, shareID)
+	if err != nil {
+		s.logger.Errorf("Failed to load memberships of share %s: %v", shareID, err)
+		s.invalidateShareCaches(ctx, shareID)
+		return
+	}
+
+	for _, membership := range memberships {
+		s.invalidateMembershipCaches(ctx, shareID, membership.UserID)
+	}
+	s.invalidateShareCaches(ctx, shareID)
+}
@@ -0,0 +1,252 @@
+// internal/drive/share_url.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Public link states
+const (
+	SHARE_URL_STATE_ACTIVE  = 1
+	SHARE_URL_STATE_REVOKED = 2
+	SHARE_URL_STATE_EXPIRED = 3 // Past its own or its share's expiry, can be renewed
+)
+
+// Slugs are lowercase so lookups are case-insensitive, and must not collide with generated tokens
+var slugRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,46}[a-z0-9]$`)
+
+// reservedSlugs cannot be claimed because they clash with app routes or could be used to impersonate us
+var reservedSlugs = map[string]bool{
+	"admin":      true,
+	"api":        true,
+	"app":        true,
+	"auth":       true,
+	"billing":    true,
+	"cirrussync": true,
+	"download":   true,
+	"drive":      true,
+	"help":       true,
+	"login":      true,
+	"logout":     true,
+	"settings":   true,
+	"share":      true,
+	"signup":     true,
+	"support":    true,
+	"urls":       true,
+}
+
+// CreateShareURL creates a public link for an item, optionally with a vanity slug
+func (s *Service) CreateShareURL(ctx context.Context, userID, shareID, linkID string, input *models.DriveShareURL) (*models.DriveShareURL, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, SHARE_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	if input.ExpiresAt != nil && *input.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrInvalidExpiry
+	}
+
+	item, err := s.repo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if item.ShareID != shareID || item.IsTrashed {
+		return nil, ErrItemNotFound
+	}
+
+	shareURL := &models.DriveShareURL{
+		ShareID:                  shareID,
+		ItemID:                   item.ID,
+		CreatorID:                userID,
+		Permissions:              READ_PERMISSION,
+		State:                    SHARE_URL_STATE_ACTIVE,
+		SharePasswordSalt:        input.SharePasswordSalt,
+		SharePassphraseKeyPacket: input.SharePassphraseKeyPacket,
+		ExpiresAt:                input.ExpiresAt,
+	}
+
+	if input.Slug != nil {
+		slug, err := s.checkSlugAvailable(ctx, *input.Slug, "")
+		if err != nil {
+			return nil, err
+		}
+		shareURL.Slug = &slug
+	}
+
+	if err := s.checkShareURLRateLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateShareURL(ctx, shareURL); err != nil {
+		// A concurrent request may have claimed the slug between the check and the insert
+		if shareURL.Slug != nil {
+			if _, slugErr := s.checkSlugAvailable(ctx, *shareURL.Slug, ""); errors.Is(slugErr, ErrSlugTaken) {
+				return nil, ErrSlugTaken
+			}
+		}
+		s.logger.Errorf("Failed to create public link for link %s: %v", linkID, err)
+		return nil, ErrShareURLCreation
+	}
+
+	// The item's link counter changed
+	s.invalidateLinkCache(ctx, item.ID)
+
+	return shareURL, nil
+}
+
+// GetShareURL retrieves a public link that belongs to a share the user can read
+func (s *Service) GetShareURL(ctx context.Context, userID, shareID, urlID string) (*models.DriveShareURL, error) {
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	shareURL, err := s.repo.GetShareURLByID(ctx, urlID)
+	if err != nil {
+		return nil, err
+	}
+	if shareURL.ShareID != shareID || shareURL.State != SHARE_URL_STATE_ACTIVE {
+		return nil, ErrShareURLNotFound
+	}
+
+	return shareURL, nil
+}
+
+// SetShareURLSlug assigns or clears the vanity slug of a public link.
+// Only the link creator or the share owner may change it.
+func (s *Service) SetShareURLSlug(ctx context.Context, userID, shareID, urlID string, slug *string) (*models.DriveShareURL, error) {
+	shareURL, err := s.GetShareURL(ctx, userID, shareID, urlID)
+	if err != nil {
+		return nil, err
+	}
+
+	if shareURL.CreatorID != userID {
+		share, err := s.GetShareByID(ctx, shareID)
+		if err != nil {
+			return nil, err
+		}
+		if share.UserID != userID {
+			return nil, ErrInsufficientPermissions
+		}
+	}
+
+	if slug == nil {
+		shareURL.Slug = nil
+	} else {
+		normalized, err := s.checkSlugAvailable(ctx, *slug, shareURL.ID)
+		if err != nil {
+			return nil, err
+		}
+		shareURL.Slug = &normalized
+	}
+
+	if err := s.repo.UpdateShareURL(ctx, shareURL); err != nil {
+		if shareURL.Slug != nil {
+			if _, slugErr := s.checkSlugAvailable(ctx, *shareURL.Slug, shareURL.ID); errors.Is(slugErr, ErrSlugTaken) {
+				return nil, ErrSlugTaken
+			}
+		}
+		return nil, fmt.Errorf("failed to update public link: %w", err)
+	}
+
+	return shareURL, nil
+}
+
+// ResolveShareURL looks up an active public link by token or slug for anonymous visitors
+func (s *Service) ResolveShareURL(ctx context.Context, tokenOrSlug string) (*models.DriveShareURL, error) {
+	// Tokens are case-sensitive, slugs are stored lowercase
+	shareURL, err := s.repo.GetShareURLByTokenOrSlug(ctx, tokenOrSlug, strings.ToLower(tokenOrSlug))
+	if err != nil {
+		return nil, err
+	}
+
+	if shareURL.State == SHARE_URL_STATE_EXPIRED {
+		return nil, ErrShareURLExpired
+	}
+	if shareURL.State != SHARE_URL_STATE_ACTIVE {
+		return nil, ErrShareURLNotFound
+	}
+	if shareURL.ExpiresAt != nil && *shareURL.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrShareURLExpired
+	}
+
+	// Links end with their share, even before the scheduler deactivates them
+	share, err := s.GetShareByID(ctx, shareURL.ShareID)
+	if err != nil {
+		return nil, err
+	}
+	if shareExpired(share) {
+		return nil, ErrShareURLExpired
+	}
+
+	// Count the visit without delaying the response
+	go func(urlID string) {
+		opCtx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout())
+		defer cancel()
+
+		if err := s.repo.IncrementShareURLAccesses(opCtx, urlID); err != nil {
+			s.logger.Errorf("Failed to record access for public link %s: %v", urlID, err)
+		}
+	}(shareURL.ID)
+
+	return shareURL, nil
+}
+
+// GetShareURLQRCode renders a QR code for a public link in PNG or SVG
+func (s *Service) GetShareURLQRCode(ctx context.Context, userID, shareID, urlID, format string, size int) ([]byte, string, error) {
+	shareURL, err := s.GetShareURL(ctx, userID, shareID, urlID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return renderQRCode(s.ShareURLAddress(shareURL), format, size)
+}
+
+// ShareURLAddress returns the public address of a link, preferring its slug
+func (s *Service) ShareURLAddress(shareURL *models.DriveShareURL) string {
+	if shareURL.Slug != nil {
+		return s.urlBase + "/" + *shareURL.Slug
+	}
+	return s.urlBase + "/" + shareURL.Token
+}
+
+// checkSlugAvailable normalizes and validates a slug, ensuring no other link owns it
+func (s *Service) checkSlugAvailable(ctx context.Context, slug, currentURLID string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(slug))
+	if !slugRegex.MatchString(normalized) {
+		return "", ErrInvalidSlug
+	}
+	if reservedSlugs[normalized] {
+		return "", ErrSlugReserved
+	}
+
+	existing, err := s.repo.GetShareURLByTokenOrSlug(ctx, normalized, normalized)
+	if err != nil {
+		if errors.Is(err, ErrShareURLNotFound) {
+			return normalized, nil
+		}
+		return "", err
+	}
+	if existing.ID != currentURLID {
+		return "", ErrSlugTaken
+	}
+
+	return normalized, nil
+}
+
+// invalidateLinkCache removes a cached drive item
+func (s *Service) invalidateLinkCache(ctx context.Context, linkID string) {
+	linkCacheKey := fmt.Sprintf("link:%s", linkID)
+	if _, err := s.redisClient.Delete(ctx, linkCacheKey); err != nil {
+		s.logger.Errorf("Failed to delete link cache for link %s: %v", linkID, err)
+	}
+}
@@ -0,0 +1,121 @@
+// internal/drive/share_url_limits.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Daily public link creation limits by plan tier
+const (
+	SHARE_URL_DAILY_LIMIT_FREE    = 20
+	SHARE_URL_DAILY_LIMIT_PLUS    = 100
+	SHARE_URL_DAILY_LIMIT_PREMIUM = 500
+
+	// Young accounts are capped regardless of plan, since throwaway accounts are the main source of abuse
+	SHARE_URL_DAILY_LIMIT_FIRST_DAY = 3
+	SHARE_URL_DAILY_LIMIT_NEW       = 10
+	NEW_ACCOUNT_AGE                 = 7 * 24 * time.Hour
+
+	// Counters outlive the day they count so a late request near midnight still sees its own day
+	SHARE_URL_COUNTER_TTL = 48 * time.Hour
+
+	SECURITY_EVENT_SHARE_URL_RATE_LIMITED = "share_url_rate_limited"
+)
+
+// checkShareURLRateLimit counts a public link creation against the user's daily allowance.
+// Counting is best effort: if Redis is unavailable the creation is allowed.
+func (s *Service) checkShareURLRateLimit(ctx context.Context, userID string) error {
+	day := time.Now().UTC().Format("20060102")
+	counterKey := fmt.Sprintf("share_url_quota:%s:%s", userID, day)
+
+	count, err := s.redisClient.Incr(ctx, counterKey)
+	if err != nil {
+		s.logger.Errorf("Failed to count public link creation for user %s: %v", userID, err)
+		return nil
+	}
+	if count == 1 {
+		_, _ = s.redisClient.Expire(ctx, counterKey, SHARE_URL_COUNTER_TTL)
+	}
+
+	limit, tier, accountAge, err := s.shareURLDailyLimit(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to resolve public link limit for user %s: %v", userID, err)
+		return nil
+	}
+	if count <= int64(limit) {
+		return nil
+	}
+
+	// Record the first rejection of the day only, so a script hammering the endpoint does not flood the audit log
+	if count == int64(limit)+1 {
+		s.recordShareURLRateLimited(ctx, userID, limit, tier, accountAge)
+	}
+
+	return ErrShareURLRateLimited
+}
+
+// shareURLDailyLimit resolves the daily public link allowance from the user's plan and account age
+func (s *Service) shareURLDailyLimit(ctx context.Context, userID string) (int, int, time.Duration, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	tier, err := s.repo.GetActivePlanTier(ctx, userID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	limit := SHARE_URL_DAILY_LIMIT_FREE
+	switch {
+	case tier >= 2:
+		limit = SHARE_URL_DAILY_LIMIT_PREMIUM
+	case tier == 1:
+		limit = SHARE_URL_DAILY_LIMIT_PLUS
+	}
+
+	accountAge := time.Since(time.Unix(user.CreatedAt, 0))
+	switch {
+	case accountAge < 24*time.Hour:
+		limit = min(limit, SHARE_URL_DAILY_LIMIT_FIRST_DAY)
+	case accountAge < NEW_ACCOUNT_AGE:
+		limit = min(limit, SHARE_URL_DAILY_LIMIT_NEW)
+	}
+
+	return limit, tier, accountAge, nil
+}
+
+// recordShareURLRateLimited writes a security event for a user who hit the public link limit
+func (s *Service) recordShareURLRateLimited(ctx context.Context, userID string, limit, tier int, accountAge time.Duration) {
+	metadata, _ := json.Marshal(map[string]any{
+		"limit":          limit,
+		"planTier":       tier,
+		"accountAgeDays": int(accountAge.Hours() / 24),
+	})
+
+	event := &models.UserSecurityEvent{
+		ID:                 utils.GenerateID(),
+		UserID:             userID,
+		EventType:          SECURITY_EVENT_SHARE_URL_RATE_LIMITED,
+		Success:            false,
+		AdditionalMetadata: metadata,
+	}
+
+	if err := s.repo.CreateSecurityEvent(context.WithoutCancel(ctx), event); err != nil {
+		s.logger.Errorf("Failed to record public link rate limit event for user %s: %v", userID, err)
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId": userID,
+		"limit":  limit,
+		"tier":   tier,
+	}).Warn("Public link creation rate limited")
+}
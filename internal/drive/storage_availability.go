@@ -0,0 +1,40 @@
+// internal/drive/storage_availability.go
+package drive
+
+import (
+	"context"
+	"time"
+)
+
+// StorageStatus describes whether the blob store is reachable
+type StorageStatus struct {
+	Available     bool
+	DegradedSince time.Time     // Zero while storage is available
+	RetryAfter    time.Duration // How long clients should wait before retrying deferred uploads
+}
+
+// StorageStatus reports the availability of the blob store. Without a configured store nothing
+// can be uploaded either way, so storage counts as available and the usual errors apply.
+func (s *Service) StorageStatus() StorageStatus {
+	if s.storage == nil {
+		return StorageStatus{Available: true}
+	}
+
+	since := s.storage.DegradedSince()
+	return StorageStatus{
+		Available:     since.IsZero(),
+		DegradedSince: since,
+		RetryAfter:    s.storage.ProbeInterval(),
+	}
+}
+
+// storageDegraded reports whether a configured blob store is currently unreachable
+func (s *Service) storageDegraded() bool {
+	return s.storage != nil && !s.storage.Available()
+}
+
+// storageReachable reports whether a blob store is configured and reachable, for jobs that only
+// work on stored objects
+func (s *Service) storageReachable(ctx context.Context) bool {
+	return s.storage != nil && !s.storageDegraded()
+}
@@ -0,0 +1,114 @@
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// JOB_TYPE_STORAGE_LIFECYCLE aborts stale multipart uploads and moves blocks of files trashed for a
+// while to infrequent access, and back when they are restored
+const JOB_TYPE_STORAGE_LIFECYCLE = "drive.storage_lifecycle"
+
+// STORAGE_LIFECYCLE_BATCH_SIZE bounds how many blocks or uploads are handled per batch of a step
+const STORAGE_LIFECYCLE_BATCH_SIZE = 500
+
+// storagePrefix is where every user's objects are stored
+const storagePrefix = "users/"
+
+// StartStorageLifecycleScheduler queues a storage lifecycle job every interval until ctx is
+// cancelled. Intervals in which storage is unreachable are skipped.
+func (s *Service) StartStorageLifecycleScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.ScheduleWhen(ctx, "storage_lifecycle_pass", s.lifecycle.interval, JOB_TYPE_STORAGE_LIFECYCLE, nil, s.storageReachable)
+}
+
+// runStorageLifecycleJob runs every lifecycle step in turn. Each step only finds what is left to do,
+// so an interrupted run continues where it stopped.
+func (s *Service) runStorageLifecycleJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if s.storage == nil {
+		return ErrStorageUnavailable
+	}
+
+	result := map[string]int64{
+		"abortedUploads":     job.Result["abortedUploads"],
+		"transitionedBlocks": job.Result["transitionedBlocks"],
+		"restoredBlocks":     job.Result["restoredBlocks"],
+	}
+
+	aborted, err := s.storage.AbortStaleMultipartUploads(ctx, storagePrefix, time.Now().Add(-s.lifecycle.multipartAge), STORAGE_LIFECYCLE_BATCH_SIZE)
+	result["abortedUploads"] += int64(aborted)
+	if err != nil {
+		return fmt.Errorf("failed to abort stale multipart uploads: %w", err)
+	}
+	progress(1, 3, result)
+
+	// Blocks of files long in the trash are rarely read again before they are purged
+	trashedBefore := time.Now().Add(-s.lifecycle.transitionAfter).Unix()
+	transitioned, err := s.transitionBlocks(ctx, STORAGE_CLASS_STANDARD, STORAGE_CLASS_STANDARD_IA, func(ctx context.Context) ([]*models.FileBlock, error) {
+		return s.repo.GetTrashedBlocks(ctx, STORAGE_CLASS_STANDARD, trashedBefore, STORAGE_LIFECYCLE_BATCH_SIZE)
+	})
+	result["transitionedBlocks"] += transitioned
+	if err != nil {
+		return err
+	}
+	progress(2, 3, result)
+
+	// Restored files are read like any other, so they go back to standard
+	restored, err := s.transitionBlocks(ctx, STORAGE_CLASS_STANDARD_IA, STORAGE_CLASS_STANDARD, func(ctx context.Context) ([]*models.FileBlock, error) {
+		return s.repo.GetRestoredBlocks(ctx, STORAGE_CLASS_STANDARD_IA, STORAGE_LIFECYCLE_BATCH_SIZE)
+	})
+	result["restoredBlocks"] += restored
+	if err != nil {
+		return err
+	}
+	progress(3, 3, result)
+
+	return nil
+}
+
+// transitionBlocks moves the blocks a load returns from one storage class to another in batches until
+// none are left. Blocks that fail to move are left in their class and retried by the next run.
+func (s *Service) transitionBlocks(ctx context.Context, from, to string, load func(context.Context) ([]*models.FileBlock, error)) (int64, error) {
+	var moved int64
+	failed := make(map[string]bool)
+
+	for {
+		if ctx.Err() != nil {
+			return moved, ctx.Err()
+		}
+
+		blocks, err := load(ctx)
+		if err != nil {
+			return moved, fmt.Errorf("failed to load %s blocks to transition: %w", from, err)
+		}
+
+		blockIDs := make([]string, 0, len(blocks))
+		for _, block := range blocks {
+			if failed[block.ID] {
+				continue
+			}
+			if err := s.storage.TransitionObject(ctx, block.StoragePath, to, block.ReplicationRegion != ""); err != nil {
+				s.logger.Warnf("Failed to move block %s to %s: %v", block.ID, to, err)
+				failed[block.ID] = true
+				continue
+			}
+			blockIDs = append(blockIDs, block.ID)
+		}
+
+		// Nothing but blocks that failed earlier is left
+		if len(blockIDs) == 0 {
+			return moved, nil
+		}
+
+		if err := s.repo.SetBlocksStorageClass(ctx, blockIDs, to); err != nil {
+			return moved, fmt.Errorf("failed to record block storage class: %w", err)
+		}
+		moved += int64(len(blockIDs))
+	}
+}
@@ -0,0 +1,165 @@
+// internal/drive/storage_report.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Storage classes blocks can be held in. Blocks are written as standard, blocks of files long in the trash
+// move to infrequent access, and lifecycle rules archive them later.
+const (
+	STORAGE_CLASS_STANDARD     = "STANDARD"
+	STORAGE_CLASS_STANDARD_IA  = "STANDARD_IA"
+	STORAGE_CLASS_GLACIER_IR   = "GLACIER_IR"
+	STORAGE_CLASS_GLACIER      = "GLACIER"
+	STORAGE_CLASS_DEEP_ARCHIVE = "DEEP_ARCHIVE"
+)
+
+// replicationPlanTypes are the plans allowed to request cross-region replication
+var replicationPlanTypes = []string{"business", "enterprise"}
+
+// VolumeStorageReport describes how a volume's blocks are stored
+type VolumeStorageReport struct {
+	VolumeID               string
+	TotalBytes             int64
+	StandardBytes          int64
+	ArchivedBytes          int64
+	ReplicatedBytes        int64
+	CrossRegionReplication bool
+	Usage                  []*StorageUsage
+}
+
+// GetVolumeStorageReport computes the storage composition of a volume from its block metadata
+func (s *Service) GetVolumeStorageReport(ctx context.Context, userID, volumeID string) (*VolumeStorageReport, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	volume, err := s.getOwnedVolume(ctx, userID, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.repo.GetVolumeStorageUsage(ctx, volumeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute storage usage: %w", err)
+	}
+
+	report := &VolumeStorageReport{
+		VolumeID:               volume.ID,
+		CrossRegionReplication: volume.CrossRegionReplication,
+		Usage:                  usage,
+	}
+	for _, u := range usage {
+		report.TotalBytes += u.Bytes
+		if isArchivedStorageClass(u.StorageClass) {
+			report.ArchivedBytes += u.Bytes
+		} else {
+			report.StandardBytes += u.Bytes
+		}
+		if u.ReplicationRegion != "" {
+			report.ReplicatedBytes += u.Bytes
+		}
+	}
+
+	return report, nil
+}
+
+// SetVolumeReplication turns cross-region replication of new blocks on or off for a volume.
+// Existing blocks keep their placement; only blocks uploaded afterwards are affected.
+func (s *Service) SetVolumeReplication(ctx context.Context, userID, volumeID string, enabled bool) (*models.DriveVolume, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	volume, err := s.getOwnedVolume(ctx, userID, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		if s.storage == nil || s.storage.ReplicaRegion() == "" {
+			return nil, ErrReplicationUnavailable
+		}
+
+		planTypes, err := s.repo.GetActivePlanTypes(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plans: %w", err)
+		}
+		if !slices.ContainsFunc(planTypes, func(planType string) bool {
+			return slices.Contains(replicationPlanTypes, planType)
+		}) {
+			return nil, ErrReplicationNotAllowed
+		}
+	}
+
+	if volume.CrossRegionReplication == enabled {
+		return volume, nil
+	}
+
+	if err := s.repo.SetVolumeReplication(ctx, volumeID, enabled); err != nil {
+		return nil, err
+	}
+	volume.CrossRegionReplication = enabled
+
+	return volume, nil
+}
+
+// ReconcileStorageAllocation resizes the user's volume and owner allocation to a new storage limit.
+// Users who have not set up a drive yet have nothing to resize.
+func (s *Service) ReconcileStorageAllocation(ctx context.Context, userID string, limit int64) error {
+	volume, err := s.repo.GetVolumeByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrVolumeNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	allocation, err := s.repo.GetAllocationByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrAllocationNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if volume.Size == limit && allocation.AllocatedSize == limit {
+		return nil
+	}
+
+	if err := s.repo.ResizeOwnerStorage(ctx, volume.ID, allocation.ID, limit); err != nil {
+		return fmt.Errorf("failed to resize storage: %w", err)
+	}
+
+	s.invalidateUserCaches(ctx, userID)
+
+	return nil
+}
+
+// getOwnedVolume loads a volume, hiding volumes that belong to other users
+func (s *Service) getOwnedVolume(ctx context.Context, userID, volumeID string) (*models.DriveVolume, error) {
+	volume, err := s.repo.GetVolumeByID(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	if volume.UserID != userID {
+		return nil, ErrVolumeNotFound
+	}
+	return volume, nil
+}
+
+// isArchivedStorageClass reports whether blocks in a storage class need a restore before they can be read quickly
+func isArchivedStorageClass(storageClass string) bool {
+	switch storageClass {
+	case STORAGE_CLASS_GLACIER_IR, STORAGE_CLASS_GLACIER, STORAGE_CLASS_DEEP_ARCHIVE:
+		return true
+	}
+	return false
+}
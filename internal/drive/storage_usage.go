@@ -0,0 +1,57 @@
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// JOB_TYPE_STORAGE_ADJUST charges or releases storage of a user in the background
+const JOB_TYPE_STORAGE_ADJUST = "drive.storage_adjust"
+
+// updateStorageUsed changes the user's storage usage asynchronously. The change is queued as a job,
+// so it is not lost when the server restarts before it was applied.
+func (s *Service) updateStorageUsed(ctx context.Context, userID string, bytes int64) {
+	if s.jobService != nil {
+		_, err := s.jobService.Enqueue(ctx, userID, JOB_TYPE_STORAGE_ADJUST, map[string]string{
+			"bytes": strconv.FormatInt(bytes, 10),
+		})
+		if err == nil {
+			return
+		}
+		s.logger.Errorf("Failed to queue storage update of user %s, applying it now: %v", userID, err)
+	}
+
+	go s.adjustStorageUsed(context.WithoutCancel(ctx), userID, bytes)
+}
+
+// adjustStorageUsed applies a change of the user's storage usage
+func (s *Service) adjustStorageUsed(ctx context.Context, userID string, bytes int64) error {
+	// Create a new context with timeout to avoid hanging goroutines
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
+	defer cancel()
+
+	if err := s.quota.Adjust(opCtx, userID, bytes); err != nil {
+		s.logger.Error("Failed to update storage used", err)
+		return err
+	}
+	return nil
+}
+
+// runStorageAdjustJob applies a queued storage change. The change is a single update, so a failed
+// run changed nothing and is safe to retry.
+func (s *Service) runStorageAdjustJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	bytes, err := strconv.ParseInt(job.Payload["bytes"], 10, 64)
+	if err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid storage change: %w", err))
+	}
+
+	if err := s.adjustStorageUsed(ctx, job.UserID, bytes); err != nil {
+		return err
+	}
+
+	progress(1, 1, map[string]int64{"bytes": bytes})
+	return nil
+}
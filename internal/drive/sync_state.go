@@ -0,0 +1,141 @@
+// internal/drive/sync_state.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"time"
+)
+
+// MAX_SYNC_PENDING_EVENTS bounds how far behind a device is counted; devices further behind are
+// reported at this many pending events
+const MAX_SYNC_PENDING_EVENTS = 10000
+
+// SyncState is where each of the user's devices is in the change logs of the user's volumes
+type SyncState struct {
+	Volumes []*VolumeSyncState
+}
+
+// VolumeSyncState is the position of each device that syncs a volume, next to the volume's latest change
+type VolumeSyncState struct {
+	VolumeID string
+	Cursor   string // Cursor of the volume's latest event
+	Devices  []*DeviceSyncState
+}
+
+// DeviceSyncState is where one device is in a volume's change log
+type DeviceSyncState struct {
+	DeviceID       string
+	Cursor         string
+	PendingEvents  int64 // Events after the cursor, up to MAX_SYNC_PENDING_EVENTS
+	AcknowledgedAt int64
+}
+
+// AcknowledgeSyncCursor records that one of the user's devices has applied a volume's changes up
+// to the cursor. The cursor may move backwards, for instance when a device restores its replica
+// from a backup.
+func (s *Service) AcknowledgeSyncCursor(ctx context.Context, userID, volumeID, deviceID, cursor string) (*DeviceSyncState, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	volume, err := s.repo.GetVolumeByID(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not reveal other users' volumes
+	if volume.UserID != userID {
+		return nil, ErrVolumeNotFound
+	}
+
+	device, err := s.repo.GetUserDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID, err := decodeEventCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cursor past the latest event was not handed out for this volume
+	latestID, err := s.repo.GetLatestEventID(ctx, volume.ID)
+	if err != nil {
+		return nil, err
+	}
+	if eventID > latestID {
+		return nil, ErrInvalidEventCursor
+	}
+
+	syncCursor := &models.DriveSyncCursor{
+		UserID:         userID,
+		DeviceID:       device.ID,
+		VolumeID:       volume.ID,
+		LastEventID:    eventID,
+		AcknowledgedAt: time.Now().Unix(),
+	}
+	if err := s.repo.UpsertSyncCursor(ctx, syncCursor); err != nil {
+		s.logger.Errorf("Failed to store sync cursor of device %s on volume %s: %v", device.ID, volume.ID, err)
+		return nil, err
+	}
+
+	return s.deviceSyncState(ctx, syncCursor)
+}
+
+// GetSyncState returns where each of the user's active devices is in the change logs of the
+// volumes it syncs, so clients can show sync progress and notice replicas that fell behind
+func (s *Service) GetSyncState(ctx context.Context, userID string) (*SyncState, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	cursors, err := s.repo.GetSyncCursorsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &SyncState{Volumes: []*VolumeSyncState{}}
+	volumes := make(map[string]*VolumeSyncState)
+	for _, cursor := range cursors {
+		volume, ok := volumes[cursor.VolumeID]
+		if !ok {
+			latestID, err := s.repo.GetLatestEventID(ctx, cursor.VolumeID)
+			if err != nil {
+				return nil, err
+			}
+			volume = &VolumeSyncState{
+				VolumeID: cursor.VolumeID,
+				Cursor:   encodeEventCursor(latestID),
+				Devices:  []*DeviceSyncState{},
+			}
+			volumes[cursor.VolumeID] = volume
+			state.Volumes = append(state.Volumes, volume)
+		}
+
+		device, err := s.deviceSyncState(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		volume.Devices = append(volume.Devices, device)
+	}
+
+	return state, nil
+}
+
+// deviceSyncState describes a stored sync cursor with how far the device is behind
+func (s *Service) deviceSyncState(ctx context.Context, cursor *models.DriveSyncCursor) (*DeviceSyncState, error) {
+	pending, err := s.repo.CountEventsSince(ctx, cursor.VolumeID, cursor.LastEventID, MAX_SYNC_PENDING_EVENTS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceSyncState{
+		DeviceID:       cursor.DeviceID,
+		Cursor:         encodeEventCursor(cursor.LastEventID),
+		PendingEvents:  pending,
+		AcknowledgedAt: cursor.AcknowledgedAt,
+	}, nil
+}
@@ -0,0 +1,198 @@
+// internal/drive/tags.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"regexp"
+)
+
+// Tag limits
+const (
+	MAX_TAGS_PER_USER   = 500
+	MAX_TAGS_PER_ITEM   = 32
+	MAX_TAG_FILTERS     = 8    // Maximum tags a listing or search can be filtered by
+	MAX_TAG_NAME_LENGTH = 1024 // Encrypted label, armored by the client
+	MAX_TAG_ID_LENGTH   = 64
+)
+
+// tagColorRegex matches an optional #rrggbb display color
+var tagColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ListTags returns the user's tags
+func (s *Service) ListTags(ctx context.Context, userID string) ([]*models.DriveTag, error) {
+	return s.repo.GetTagsByUserID(ctx, userID)
+}
+
+// CreateTag creates a tag with a client-encrypted label
+func (s *Service) CreateTag(ctx context.Context, userID, encryptedName, color string) (*models.DriveTag, error) {
+	if err := validateTag(encryptedName, color); err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountTagsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MAX_TAGS_PER_USER {
+		return nil, ErrTooManyTags
+	}
+
+	tag := &models.DriveTag{
+		UserID:        userID,
+		EncryptedName: encryptedName,
+		Color:         color,
+	}
+	if err := s.repo.CreateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// UpdateTag replaces a tag's label and color
+func (s *Service) UpdateTag(ctx context.Context, userID, tagID, encryptedName, color string) (*models.DriveTag, error) {
+	if err := validateTag(encryptedName, color); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.getOwnedTag(ctx, userID, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.EncryptedName = encryptedName
+	tag.Color = color
+	if err := s.repo.UpdateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// DeleteTag deletes a tag and removes it from every item it was attached to
+func (s *Service) DeleteTag(ctx context.Context, userID, tagID string) error {
+	if _, err := s.getOwnedTag(ctx, userID, tagID); err != nil {
+		return err
+	}
+	return s.repo.DeleteTag(ctx, tagID)
+}
+
+// GetItemTags returns the IDs of the caller's tags on an item
+func (s *Service) GetItemTags(ctx context.Context, userID, shareID, linkID string) ([]string, error) {
+	if _, err := s.getReadableItem(ctx, userID, shareID, linkID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetItemTagIDs(ctx, linkID, userID)
+}
+
+// SetItemTags replaces the caller's tags on an item. Tagging only needs read access,
+// since tags are private to the user and do not change the item.
+func (s *Service) SetItemTags(ctx context.Context, userID, shareID, linkID string, tagIDs []string) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if len(tagIDs) > MAX_TAGS_PER_ITEM {
+		return nil, ErrTooManyItemTags
+	}
+	normalized, err := s.resolveTagIDs(ctx, userID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getReadableItem(ctx, userID, shareID, linkID); err != nil {
+		return nil, err
+	}
+
+	itemTags := make([]*models.DriveItemTag, len(normalized))
+	for i, tagID := range normalized {
+		itemTags[i] = &models.DriveItemTag{
+			TagID:   tagID,
+			ItemID:  linkID,
+			ShareID: shareID,
+			UserID:  userID,
+		}
+	}
+
+	if err := s.repo.ReplaceItemTags(ctx, linkID, userID, itemTags); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// resolveTagFilter validates the tags a listing or search is filtered by
+func (s *Service) resolveTagFilter(ctx context.Context, userID string, tagIDs []string) ([]string, error) {
+	if len(tagIDs) > MAX_TAG_FILTERS {
+		return nil, ErrTooManyTagFilters
+	}
+	return s.resolveTagIDs(ctx, userID, tagIDs)
+}
+
+// resolveTagIDs removes duplicate tag IDs and checks that all of them belong to the user.
+// Tags of other users are reported as not found so their IDs cannot be probed.
+func (s *Service) resolveTagIDs(ctx context.Context, userID string, tagIDs []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(tagIDs))
+	normalized := make([]string, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		if tagID == "" || len(tagID) > MAX_TAG_ID_LENGTH {
+			return nil, ErrTagNotFound
+		}
+		if _, exists := seen[tagID]; exists {
+			continue
+		}
+		seen[tagID] = struct{}{}
+		normalized = append(normalized, tagID)
+	}
+
+	if len(normalized) == 0 {
+		return normalized, nil
+	}
+
+	count, err := s.repo.CountUserTagsByIDs(ctx, userID, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if count != int64(len(normalized)) {
+		return nil, ErrTagNotFound
+	}
+
+	return normalized, nil
+}
+
+// getOwnedTag loads a tag, hiding tags that belong to other users
+func (s *Service) getOwnedTag(ctx context.Context, userID, tagID string) (*models.DriveTag, error) {
+	tag, err := s.repo.GetTagByID(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.UserID != userID {
+		return nil, ErrTagNotFound
+	}
+	return tag, nil
+}
+
+// getReadableItem loads an item of a share the user can read
+func (s *Service) getReadableItem(ctx context.Context, userID, shareID, linkID string) (*models.DriveItem, error) {
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	item, err := s.repo.GetLinkByID(ctx, linkID)
+	if err != nil || item.ShareID != shareID {
+		return nil, ErrItemNotFound
+	}
+	return item, nil
+}
+
+// validateTag checks a tag's encrypted label and optional color
+func validateTag(encryptedName, color string) error {
+	if encryptedName == "" || len(encryptedName) > MAX_TAG_NAME_LENGTH {
+		return ErrInvalidTagName
+	}
+	if color != "" && !tagColorRegex.MatchString(color) {
+		return ErrInvalidTagColor
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// internal/drive/thumbnail.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/s3"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Thumbnail types and the largest thumbnail accepted
+const (
+	THUMBNAIL_TYPE_SMALL  = 1
+	THUMBNAIL_TYPE_MEDIUM = 2
+	THUMBNAIL_TYPE_LARGE  = 3
+
+	MAX_THUMBNAIL_BYTES = 512 * 1024
+)
+
+// THUMBNAIL_CACHE_TTL is how long thumbnail contents stay cached after they were read from storage
+const THUMBNAIL_CACHE_TTL = 24 * time.Hour
+
+// thumbnailSizes names the stored object of each thumbnail type
+var thumbnailSizes = map[int]string{
+	THUMBNAIL_TYPE_SMALL:  "small",
+	THUMBNAIL_TYPE_MEDIUM: "medium",
+	THUMBNAIL_TYPE_LARGE:  "large",
+}
+
+// EncryptionKeyResolver finds the customer-managed KMS key objects of a storage owner must be encrypted with
+type EncryptionKeyResolver interface {
+	StorageEncryptionKey(ctx context.Context, userID string) (string, error)
+}
+
+// SetEncryptionKeyResolver configures where customer-managed encryption keys come from. Without a
+// resolver every object uses the bucket's default encryption.
+func (s *Service) SetEncryptionKeyResolver(keys EncryptionKeyResolver) {
+	s.keys = keys
+}
+
+// ThumbnailUploadURL is a presigned upload target for a revision thumbnail
+type ThumbnailUploadURL struct {
+	Type      int
+	UploadURL string
+	Headers   map[string]string // Headers the upload is signed with and must be sent unchanged
+}
+
+// RequestThumbnailUpload issues a presigned upload for a thumbnail of a draft revision and records it,
+// replacing a previous thumbnail of the same type. Thumbnails of owners with a customer-managed key
+// are encrypted with that key.
+func (s *Service) RequestThumbnailUpload(ctx context.Context, userID, shareID, linkID, revisionID string, thumbnail *models.DriveThumbnail) (*ThumbnailUploadURL, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	size, ok := thumbnailSizes[thumbnail.Type]
+	if !ok || thumbnail.Size <= 0 || thumbnail.Size > MAX_THUMBNAIL_BYTES {
+		return nil, ErrInvalidThumbnail
+	}
+
+	if s.storage == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	_, revision, share, err := s.getUploadingRevision(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.storageDegraded() {
+		return nil, ErrUploadDeferred
+	}
+
+	keyARN, err := s.storageEncryptionKey(ctx, share.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.storage.PrepareThumbnailUpload(ctx, share.UserID, share.VolumeID, linkID, revision.ID, size, keyARN)
+	if err != nil {
+		s.logger.Errorf("Failed to presign thumbnail upload for revision %s: %v", revision.ID, err)
+		return nil, ErrStorageUnavailable
+	}
+
+	thumbnail.ID = utils.GenerateLinkID()
+	thumbnail.RevisionID = revision.ID
+	thumbnail.StoragePath = s3.ThumbnailPath(share.UserID, share.VolumeID, linkID, revision.ID, size)
+	thumbnail.StorageBucket = s.storage.BucketName()
+	thumbnail.StorageRegion = s.storage.Region()
+	thumbnail.CreatedAt = time.Now().Unix()
+	if err := s.repo.ReplaceThumbnail(ctx, thumbnail); err != nil {
+		return nil, err
+	}
+
+	return &ThumbnailUploadURL{Type: thumbnail.Type, UploadURL: upload.URL, Headers: upload.Headers}, nil
+}
+
+// storageEncryptionKey returns the customer-managed key for objects of the storage owner, if any.
+// A lookup failure fails the upload rather than storing the object under the default encryption.
+func (s *Service) storageEncryptionKey(ctx context.Context, ownerID string) (string, error) {
+	if s.keys == nil {
+		return "", nil
+	}
+
+	keyARN, err := s.keys.StorageEncryptionKey(ctx, ownerID)
+	if err != nil {
+		s.logger.Errorf("Failed to look up encryption key of user %s: %v", ownerID, err)
+		return "", ErrStorageUnavailable
+	}
+
+	return keyARN, nil
+}
+
+// Thumbnail is the encrypted content of a file's thumbnail
+type Thumbnail struct {
+	Type      int
+	Data      []byte
+	Hash      string
+	Signature string
+}
+
+// GetThumbnail returns a thumbnail of a file's active revision. Contents are cached once read, so
+// thumbnails seen before can still be served while storage is unavailable.
+func (s *Service) GetThumbnail(ctx context.Context, userID, shareID, linkID string, thumbnailType int) (*Thumbnail, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if _, ok := thumbnailSizes[thumbnailType]; !ok {
+		return nil, ErrInvalidThumbnail
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	item, err := s.repo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLiveItemOfShare(item, shareID) {
+		return nil, ErrItemNotFound
+	}
+	if item.Type != 2 {
+		return nil, ErrNotAFile
+	}
+
+	revision, err := s.repo.GetActiveRevisionByItemID(ctx, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := s.repo.GetThumbnailByRevisionID(ctx, revision.ID, thumbnailType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Thumbnail{Type: thumbnail.Type, Hash: thumbnail.Hash, Signature: thumbnail.ThumbnailSignature}
+
+	// A replaced thumbnail gets a new ID, so cached contents never go stale
+	cacheKey := fmt.Sprintf("thumbnail:%s", thumbnail.ID)
+	if cached, err := s.redisClient.Get(ctx, cacheKey); err == nil && cached != "" {
+		result.Data = []byte(cached)
+		return result, nil
+	}
+
+	if s.storage == nil || s.storageDegraded() {
+		return nil, ErrStorageUnavailable
+	}
+
+	data, err := s.storage.GetObject(ctx, thumbnail.StoragePath, MAX_THUMBNAIL_BYTES)
+	if err != nil {
+		if errors.Is(err, s3.ErrObjectNotFound) {
+			return nil, ErrThumbnailNotFound
+		}
+		s.logger.Errorf("Failed to read thumbnail %s: %v", thumbnail.ID, err)
+		return nil, ErrStorageUnavailable
+	}
+
+	if err := s.redisClient.Set(ctx, cacheKey, data, THUMBNAIL_CACHE_TTL); err != nil {
+		s.logger.Warnf("Failed to cache thumbnail %s: %v", thumbnail.ID, err)
+	}
+
+	result.Data = data
+	return result, nil
+}
@@ -0,0 +1,278 @@
+// internal/drive/trash.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// MAX_BATCH_ITEMS bounds the number of links a single batch operation may touch
+const MAX_BATCH_ITEMS = 100
+
+// FOLDER_METADATA_BYTES is what a folder counts against the storage quota
+const FOLDER_METADATA_BYTES = 1024
+
+// ItemResult reports the outcome of a batch operation for a single link
+type ItemResult struct {
+	LinkID        string
+	CreatedLinkID string // Set by operations that create a new link, such as copies
+	Err           error
+}
+
+// EmptyTrashResult summarizes a trash purge
+type EmptyTrashResult struct {
+	DeletedItems  int64
+	ReleasedBytes int64
+}
+
+// TrashItems moves items of a share to the trash.
+// Trashing a folder hides its whole subtree without touching the descendants.
+func (s *Service) TrashItems(ctx context.Context, userID, shareID string, linkIDs []string) ([]*ItemResult, error) {
+	share, items, err := s.prepareBatch(ctx, userID, shareID, linkIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ItemResult, len(linkIDs))
+	toTrash := make([]string, 0, len(linkIDs))
+	trashed := make([]*models.DriveItem, 0, len(linkIDs))
+	parents := make(map[string]bool)
+
+	for i, linkID := range linkIDs {
+		item, ok := items[linkID]
+		switch {
+		case !ok || item.ShareID != shareID || item.State == ITEM_STATE_DELETING:
+			results[i] = &ItemResult{LinkID: linkID, Err: ErrItemNotFound}
+		case item.ID == share.LinkID:
+			results[i] = &ItemResult{LinkID: linkID, Err: ErrCannotTrashRoot}
+		case item.IsTrashed:
+			results[i] = &ItemResult{LinkID: linkID, Err: ErrItemAlreadyInTrash}
+		default:
+			results[i] = &ItemResult{LinkID: linkID}
+			toTrash = append(toTrash, linkID)
+			trashed = append(trashed, item)
+			if item.ParentID != nil {
+				parents[*item.ParentID] = true
+			}
+		}
+	}
+
+	// The share owner's plan decides how long the items stay in the trash
+	var purgeAt *int64
+	if len(toTrash) > 0 {
+		retention, err := s.TrashRetention(ctx, share.UserID)
+		if err != nil {
+			return nil, err
+		}
+		at := time.Now().Add(retention).Unix()
+		purgeAt = &at
+	}
+
+	if err := s.repo.SetItemsTrashed(ctx, toTrash, true, purgeAt); err != nil {
+		return nil, fmt.Errorf("failed to trash items: %w", err)
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_TRASH, trashed...)
+
+	s.invalidateBatchCaches(ctx, toTrash, parents)
+	s.invalidatePathCaches(ctx, shareID)
+
+	return results, nil
+}
+
+// RestoreItems moves items of a share out of the trash.
+// An item can only be restored into a parent that is not itself trashed and has no name clash.
+func (s *Service) RestoreItems(ctx context.Context, userID, shareID string, linkIDs []string) ([]*ItemResult, error) {
+	_, items, err := s.prepareBatch(ctx, userID, shareID, linkIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load parents to confirm they are live
+	parentIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.ParentID != nil {
+			parentIDs = append(parentIDs, *item.ParentID)
+		}
+	}
+	liveParents, err := s.repo.BatchGetFoldersByIDs(ctx, parentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load parent folders: %w", err)
+	}
+
+	results := make([]*ItemResult, len(linkIDs))
+	toRestore := make([]string, 0, len(linkIDs))
+	restored := make([]*models.DriveItem, 0, len(linkIDs))
+	parents := make(map[string]bool)
+	// Names claimed by earlier items in this batch
+	claimed := make(map[string]bool)
+
+	for i, linkID := range linkIDs {
+		item, ok := items[linkID]
+		if !ok || item.ShareID != shareID || item.State == ITEM_STATE_DELETING {
+			results[i] = &ItemResult{LinkID: linkID, Err: ErrItemNotFound}
+			continue
+		}
+		if !item.IsTrashed {
+			results[i] = &ItemResult{LinkID: linkID, Err: ErrItemNotInTrash}
+			continue
+		}
+
+		if item.ParentID != nil {
+			parentID := *item.ParentID
+			if _, live := liveParents[parentID]; !live {
+				results[i] = &ItemResult{LinkID: linkID, Err: ErrParentInTrash}
+				continue
+			}
+
+			exists, err := s.checkNameExists(ctx, parentID, item.Hash)
+			if err != nil {
+				results[i] = &ItemResult{LinkID: linkID, Err: err}
+				continue
+			}
+			nameKey := parentID + ":" + item.Hash
+			if exists || claimed[nameKey] {
+				results[i] = &ItemResult{LinkID: linkID, Err: ErrFileNameConflict}
+				continue
+			}
+			claimed[nameKey] = true
+			parents[parentID] = true
+		}
+
+		results[i] = &ItemResult{LinkID: linkID}
+		toRestore = append(toRestore, linkID)
+		restored = append(restored, item)
+	}
+
+	if err := s.repo.SetItemsTrashed(ctx, toRestore, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to restore items: %w", err)
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_RESTORE, restored...)
+
+	s.invalidateBatchCaches(ctx, toRestore, parents)
+	s.invalidatePathCaches(ctx, shareID)
+
+	return results, nil
+}
+
+// EmptyTrash permanently deletes every trashed item of a share, including the contents of trashed folders,
+// and releases the storage they used.
+func (s *Service) EmptyTrash(ctx context.Context, userID, shareID string) (*EmptyTrashResult, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, WRITE_PERMISSION); err != nil {
+		return nil, err
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.repo.GetTrashedTree(ctx, shareID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trash: %w", err)
+	}
+
+	return s.purgeTrashedTree(ctx, share, tree)
+}
+
+// purgeTrashedTree permanently deletes trashed items of a share and their descendants, and releases
+// the storage they used from the share owner
+func (s *Service) purgeTrashedTree(ctx context.Context, share *models.DriveShare, tree []*models.DriveItem) (*EmptyTrashResult, error) {
+	if len(tree) == 0 {
+		return &EmptyTrashResult{}, nil
+	}
+
+	itemIDs := make([]string, len(tree))
+	parents := make(map[string]bool)
+	for i, item := range tree {
+		itemIDs[i] = item.ID
+		if item.ParentID != nil {
+			parents[*item.ParentID] = true
+		}
+	}
+
+	purged, err := s.repo.PurgeItems(ctx, itemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge trash: %w", err)
+	}
+
+	releasedBytes := purged.FileBytes + purged.FolderCount*FOLDER_METADATA_BYTES
+
+	s.recordEvents(ctx, EVENT_TYPE_DELETE, tree...)
+
+	// Release storage and delete stored objects (can be done asynchronously)
+	s.updateStorageUsed(context.Background(), share.UserID, -releasedBytes)
+	go s.deleteStoredObjects(context.WithoutCancel(ctx), purged.StoragePaths)
+
+	s.invalidateBatchCaches(ctx, itemIDs, parents)
+
+	return &EmptyTrashResult{
+		DeletedItems:  purged.ItemCount,
+		ReleasedBytes: releasedBytes,
+	}, nil
+}
+
+// prepareBatch validates a batch request, checks write access and loads the referenced items
+func (s *Service) prepareBatch(ctx context.Context, userID, shareID string, linkIDs []string) (*models.DriveShare, map[string]*models.DriveItem, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	if len(linkIDs) > MAX_BATCH_ITEMS {
+		return nil, nil, ErrTooManyItems
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, WRITE_PERMISSION); err != nil {
+		return nil, nil, err
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items, err := s.repo.BatchGetItemsByIDs(ctx, linkIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load items: %w", err)
+	}
+
+	return share, items, nil
+}
+
+// invalidateBatchCaches drops cached items and the contents of the folders they live in
+func (s *Service) invalidateBatchCaches(ctx context.Context, linkIDs []string, parents map[string]bool) {
+	for _, linkID := range linkIDs {
+		s.invalidateLinkCache(ctx, linkID)
+	}
+	for parentID := range parents {
+		s.invalidateFolderCaches(ctx, parentID)
+	}
+}
+
+// deleteStoredObjects removes purged objects from storage. Failures are logged and left for garbage collection.
+// It runs after the request that purged them, so ctx should not be cancelled with it.
+func (s *Service) deleteStoredObjects(ctx context.Context, paths []string) {
+	if s.storage == nil || len(paths) == 0 {
+		return
+	}
+
+	failed := 0
+	for _, path := range paths {
+		if err := s.storage.DeleteObject(ctx, path); err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		s.logger.Errorf("Failed to delete %d of %d stored objects", failed, len(paths))
+	}
+}
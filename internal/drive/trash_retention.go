@@ -0,0 +1,168 @@
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/config"
+	"context"
+	"fmt"
+	"time"
+)
+
+// JOB_TYPE_TRASH_PURGE purges trashed items whose retention has ended
+const JOB_TYPE_TRASH_PURGE = "drive.trash_purge"
+
+// TRASH_PURGE_BATCH_SIZE is how many trashed items are purged per pass of the purge job
+const TRASH_PURGE_BATCH_SIZE = 100
+
+// Plan types that keep trashed items longer than free accounts
+var (
+	trashPlusPlanTypes     = []string{"plus", "pro", "max", "family"}
+	trashBusinessPlanTypes = []string{"business", "enterprise"}
+)
+
+// newTrashSettings builds the trash retention of every plan type from the drive configuration
+func newTrashSettings(cfg *config.DriveConfig) trashSettings {
+	settings := trashSettings{
+		purgeInterval: time.Hour,
+		freeRetention: 7 * 24 * time.Hour,
+		retention:     make(map[string]time.Duration),
+	}
+	plus, business := 30*24*time.Hour, 90*24*time.Hour
+
+	if cfg != nil {
+		if cfg.TrashPurgeInterval > 0 {
+			settings.purgeInterval = cfg.TrashPurgeInterval
+		}
+		if cfg.TrashRetentionFree > 0 {
+			settings.freeRetention = cfg.TrashRetentionFree
+		}
+		if cfg.TrashRetentionPlus > 0 {
+			plus = cfg.TrashRetentionPlus
+		}
+		if cfg.TrashRetentionBusiness > 0 {
+			business = cfg.TrashRetentionBusiness
+		}
+	}
+
+	for _, planType := range trashPlusPlanTypes {
+		settings.retention[planType] = plus
+	}
+	for _, planType := range trashBusinessPlanTypes {
+		settings.retention[planType] = business
+	}
+
+	return settings
+}
+
+// TrashRetention returns how long the user's trashed items are kept before they are purged: the
+// longest retention among the user's active plans, or the free retention without one
+func (s *Service) TrashRetention(ctx context.Context, userID string) (time.Duration, error) {
+	planTypes, err := s.repo.GetActivePlanTypes(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load plans: %w", err)
+	}
+
+	retention := s.trash.freeRetention
+	for _, planType := range planTypes {
+		retention = max(retention, s.trash.retention[planType])
+	}
+	return retention, nil
+}
+
+// ListTrash returns a page of the items trashed in a share, most recently trashed first. Each item
+// carries the time it is purged at, fixed by the owner's plan when it was trashed.
+func (s *Service) ListTrash(ctx context.Context, userID, shareID string, limit, offset int) ([]*models.DriveItem, int, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, 0, ctx.Err()
+	}
+
+	if err := s.CheckSharePermissions(ctx, userID, shareID, READ_PERMISSION); err != nil {
+		return nil, 0, err
+	}
+
+	return s.repo.GetTrashedItems(ctx, shareID, limit, offset)
+}
+
+// StartTrashPurgeScheduler queues a purge job every interval until ctx is cancelled. The job
+// permanently deletes trashed items whose retention has ended.
+func (s *Service) StartTrashPurgeScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.Schedule(ctx, "trash_purge_pass", s.trash.purgeInterval, JOB_TYPE_TRASH_PURGE, nil)
+}
+
+// runTrashPurgeJob purges trashed items past their retention in batches until none are left.
+// Purged items are not found again, so an interrupted run continues with whatever is left.
+func (s *Service) runTrashPurgeJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	result := map[string]int64{
+		"purgedItems":   job.Result["purgedItems"],
+		"releasedBytes": job.Result["releasedBytes"],
+	}
+	now := time.Now().Unix()
+
+	for {
+		due, err := s.repo.GetTrashDueForPurge(ctx, now, TRASH_PURGE_BATCH_SIZE)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to load trash due for purge: %w", err)
+		}
+		if len(due) == 0 {
+			break
+		}
+
+		byShare := make(map[string][]string)
+		for _, item := range due {
+			byShare[item.ShareID] = append(byShare[item.ShareID], item.ID)
+		}
+
+		purgedAny := false
+		for shareID, itemIDs := range byShare {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			purged, err := s.purgeExpiredTrash(ctx, shareID, itemIDs)
+			if err != nil {
+				s.logger.Errorf("Failed to purge trash of share %s: %v", shareID, err)
+				continue
+			}
+			purgedAny = true
+
+			result["purgedItems"] += purged.DeletedItems
+			result["releasedBytes"] += purged.ReleasedBytes
+			progress(result["purgedItems"], 0, result)
+		}
+
+		// Every share failed; leave the rest for the next run instead of retrying the same batch
+		if !purgedAny || len(due) < TRASH_PURGE_BATCH_SIZE {
+			break
+		}
+	}
+
+	if result["purgedItems"] > 0 {
+		s.logger.Infof("Purged %d items from the trash after their retention ended", result["purgedItems"])
+	}
+
+	return nil
+}
+
+// purgeExpiredTrash purges trashed items of one share and everything below them
+func (s *Service) purgeExpiredTrash(ctx context.Context, shareID string, itemIDs []string) (*EmptyTrashResult, error) {
+	share, err := s.repo.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.repo.GetTrashedSubtrees(ctx, itemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trash: %w", err)
+	}
+
+	return s.purgeTrashedTree(ctx, share, tree)
+}
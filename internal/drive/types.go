@@ -1,9 +1,17 @@
 package drive
 
 import (
+	"cirrussync-api/internal/jobs"
 	"cirrussync-api/internal/logger"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/pkg/db"
 	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/s3"
+	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // Service handles drive operations
@@ -11,6 +19,124 @@ type Service struct {
 	repo        Repository
 	redisClient *redis.Client
 	logger      *logger.Logger
+	settings    *runtimeSettings
+	storage     *s3.Client
+	quota       *quota.Service
+	urlBase     string
+	mailer      InvitationMailer
+	keys        EncryptionKeyResolver
+	jobService  *jobs.Service
+	notifier    *eventNotifier
+	expiry      shareExpirySettings
+	integrity   integrityAuditSettings
+	uploads     uploadCleanupSettings
+	backups     backupSettings
+	trash       trashSettings
+	regions     regionRouting
+	sandbox     sandboxSettings
+	recent      recentSettings
+	folderSizes folderSizeSettings
+	lifecycle   storageLifecycleSettings
+	gc          garbageCollectionSettings
+	cache       cacheSettings
+	flights     singleflight.Group // Shares cache misses of the same entry between concurrent requests
+
+	securityEvents *security.Service
+}
+
+// shareExpirySettings controls the scheduler that ends access to expired shares and public links
+type shareExpirySettings struct {
+	scanInterval time.Duration
+	notice       time.Duration
+}
+
+// uploadCleanupSettings controls the scheduler that cancels abandoned uploads
+type uploadCleanupSettings struct {
+	interval time.Duration
+	ttl      time.Duration
+}
+
+// backupSettings controls the scheduler that applies the retention rules of backup sets
+type backupSettings struct {
+	retentionInterval time.Duration
+}
+
+// recentSettings controls how long opened and modified files are listed as recent
+type recentSettings struct {
+	retention time.Duration
+}
+
+// folderSizeSettings controls the scheduler that recomputes cumulative folder sizes
+type folderSizeSettings struct {
+	interval time.Duration
+}
+
+// storageLifecycleSettings controls the scheduler that aborts stale multipart uploads and moves blocks of
+// trashed files to infrequent access
+type storageLifecycleSettings struct {
+	interval        time.Duration
+	transitionAfter time.Duration
+	multipartAge    time.Duration
+}
+
+// garbageCollectionSettings controls the scheduler that removes orphaned objects and dangling rows
+type garbageCollectionSettings struct {
+	interval    time.Duration
+	orphanGrace time.Duration
+}
+
+// trashSettings controls the scheduler that purges trashed items past their retention
+type trashSettings struct {
+	purgeInterval time.Duration
+	retention     map[string]time.Duration // Retention per plan type
+	freeRetention time.Duration            // Retention without an active plan
+}
+
+// integrityAuditSettings controls the scheduler that checks stored blocks against their records
+type integrityAuditSettings struct {
+	interval   time.Duration
+	sampleSize int
+}
+
+// IntegrityReport summarizes storage integrity issues for admins
+type IntegrityReport struct {
+	OpenByKind        map[string]int64 // Open issues per kind
+	OpenIrrecoverable int64            // Open issues no replica can repair
+	AffectedUsers     int64            // Users with at least one open issue
+	ResolvedSince     int64            // Issues resolved within the report window
+	Issues            []*models.StorageIntegrityIssue
+}
+
+// IntegrityFilter selects the issues listed in an integrity report
+type IntegrityFilter struct {
+	State  string
+	UserID string
+	Limit  int
+}
+
+// PurgeResult describes what was permanently deleted from the database
+type PurgeResult struct {
+	ItemCount    int64
+	FolderCount  int64
+	FileBytes    int64
+	StoragePaths []string
+}
+
+// StorageUsage sums the stored blocks of a volume that share a storage class and placement
+type StorageUsage struct {
+	StorageClass      string
+	StorageRegion     string
+	ReplicationRegion string
+	Bytes             int64
+	BlockCount        int64
+}
+
+// FileCopy is an item created as a copy of another. Files come with their copied active revision and
+// blocks; folders have neither.
+type FileCopy struct {
+	Item     *models.DriveItem
+	Revision *models.FileRevision
+	Blocks   []*models.FileBlock
 }
 
 // ShareWithMemberships represents a share with its memberships
@@ -42,3 +168,15 @@ type EncryptionKeys struct {
 	Drive            DriveItemKeys
 	DriveShareMember DriveShareMemberKeys
 }
+
+// ItemColumns is the allowlist of drive item columns that listings may sort and filter on, keyed by API field name
+var ItemColumns = db.Columns{
+	"id":         {Name: "id", Sortable: true, Filterable: true},
+	"createdAt":  {Name: "created_at", Sortable: true, Filterable: true},
+	"modifiedAt": {Name: "modified_at", Sortable: true, Filterable: true},
+	"size":       {Name: "size", Sortable: true, Filterable: true},
+	"type":       {Name: "type", Sortable: true, Filterable: true},
+	"parentId":   {Name: "parent_id", Filterable: true},
+	"isTrashed":  {Name: "is_trashed", Filterable: true},
+	"state":      {Name: "state", Filterable: true},
+}
@@ -0,0 +1,466 @@
+// internal/drive/upload.go
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/s3"
+	"cirrussync-api/pkg/tracing"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Item and revision lifecycle states
+const (
+	ITEM_STATE_ACTIVE   = 1
+	ITEM_STATE_DRAFT    = 2
+	ITEM_STATE_DELETING = 3
+
+	REVISION_STATE_ACTIVE   = 1
+	REVISION_STATE_DRAFT    = 2
+	REVISION_STATE_OBSOLETE = 3
+
+	// Maximum number of blocks that can be requested in a single call
+	MAX_BLOCKS_PER_REQUEST = 100
+)
+
+// BlockUploadURL is a presigned upload target for a single file block
+type BlockUploadURL struct {
+	BlockID   string
+	Index     int
+	UploadURL string
+	Headers   map[string]string   // Headers the upload is signed with and must be sent unchanged
+	Parts     []*s3.PresignedPart // Set instead of UploadURL for blocks larger than a single upload allows
+}
+
+// RevisionCommit holds the client-provided data needed to finalize a revision
+type RevisionCommit struct {
+	BlockCount        int
+	SignatureEmail    string
+	ManifestSignature string
+}
+
+// CreateFile registers a new draft file item and its first revision
+func (s *Service) CreateFile(ctx context.Context, userID, shareID string, fileInput *models.DriveItem) (*models.DriveItem, *models.FileRevision, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	if fileInput == nil || fileInput.ParentID == nil {
+		return nil, nil, ErrFolderNotFound
+	}
+
+	opCtx, cancel := withBudget(ctx, s.defaultTimeout())
+	defer cancel()
+
+	var share *models.DriveShare
+	var nameExists bool
+
+	g, gctx := errgroup.WithContext(opCtx)
+
+	// Check permissions and get share
+	g.Go(func() error {
+		if err := s.CheckSharePermissions(gctx, userID, shareID, WRITE_PERMISSION); err != nil {
+			return err
+		}
+
+		var err error
+		share, err = s.GetShareByID(gctx, shareID)
+		return err
+	})
+
+	// Verify the parent folder lives in this share
+	g.Go(func() error {
+		parent, err := s.repo.GetFolderByID(gctx, *fileInput.ParentID)
+		if err != nil {
+			return err
+		}
+		if parent.ShareID != shareID {
+			return ErrFolderNotFound
+		}
+		if parent.Type != 1 {
+			return ErrNotAFolder
+		}
+		return nil
+	})
+
+	// Check for name conflicts
+	g.Go(func() error {
+		var err error
+		nameExists, err = s.checkNameExists(gctx, *fileInput.ParentID, fileInput.Hash)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+	if nameExists {
+		return nil, nil, ErrFileNameConflict
+	}
+
+	file := &models.DriveItem{
+		ParentID:                fileInput.ParentID,
+		ShareID:                 shareID,
+		VolumeID:                share.VolumeID,
+		Type:                    2, // File
+		Name:                    fileInput.Name,
+		Hash:                    fileInput.Hash,
+		State:                   ITEM_STATE_DRAFT,
+		MimeType:                fileInput.MimeType,
+		SignatureEmail:          fileInput.SignatureEmail,
+		NameSignatureEmail:      fileInput.SignatureEmail,
+		NodeKey:                 fileInput.NodeKey,
+		NodePassphrase:          fileInput.NodePassphrase,
+		NodePassphraseSignature: fileInput.NodePassphraseSignature,
+		FileProperties:          fileInput.FileProperties,
+		Permissions:             RW_PERMISSIONS,
+	}
+
+	revision := &models.FileRevision{
+		State:          REVISION_STATE_DRAFT,
+		SignatureEmail: fileInput.SignatureEmail,
+	}
+
+	if err := s.repo.CreateFileWithRevision(ctx, file, revision); err != nil {
+		s.logger.Errorf("Failed to create file in share %s: %v", shareID, err)
+		return nil, nil, ErrFileCreation
+	}
+
+	// Draft files are hidden from listings, but the name is now taken
+	s.invalidateFolderCaches(ctx, *file.ParentID)
+
+	return file, revision, nil
+}
+
+// RequestBlockUploads registers blocks for a draft revision and returns presigned upload URLs
+func (s *Service) RequestBlockUploads(
+	ctx context.Context,
+	userID,
+	shareID,
+	linkID,
+	revisionID string,
+	blocks []*models.FileBlock,
+) ([]*BlockUploadURL, error) {
+	ctx, span := tracing.Start(ctx, "drive.RequestBlockUploads")
+	defer span.End()
+
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if len(blocks) == 0 || len(blocks) > MAX_BLOCKS_PER_REQUEST {
+		return nil, ErrInvalidBlockList
+	}
+
+	if s.storage == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	_, revision, share, err := s.getUploadingRevision(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The draft stays as it is, and the client keeps its blocks until storage is back
+	if s.storageDegraded() {
+		return nil, ErrUploadDeferred
+	}
+
+	// Validate block indexes and sizes
+	seen := make(map[int]bool, len(blocks))
+	var requestedBytes int64
+	for _, block := range blocks {
+		if block.Index < 0 || seen[block.Index] || block.Size <= 0 {
+			return nil, ErrInvalidBlockList
+		}
+		if block.Size > share.BlockSize {
+			return nil, ErrBlockTooLarge
+		}
+		if block.ChecksumSHA256 != "" && !isValidSHA256Checksum(block.ChecksumSHA256) {
+			return nil, ErrInvalidBlockChecksum
+		}
+		seen[block.Index] = true
+		requestedBytes += block.Size
+	}
+
+	// Storage is charged to the owner of the share
+	if err := s.CheckStorageQuota(ctx, share.UserID, requestedBytes); err != nil {
+		return nil, err
+	}
+
+	// Blocks of volumes that asked for higher redundancy are tagged for cross-region replication
+	volume, err := s.repo.GetVolumeByID(ctx, share.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	replicaRegion := ""
+	if volume.CrossRegionReplication {
+		replicaRegion = s.storage.ReplicaRegion()
+	}
+
+	// Presign block uploads with bounded concurrency
+	uploads := make([]*BlockUploadURL, len(blocks))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.maxConcurrency())
+
+	for i, block := range blocks {
+		i, block := i, block
+		g.Go(func() error {
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+
+			conditions := s3.BlockUploadConditions{
+				Size:           block.Size,
+				ChecksumSHA256: block.ChecksumSHA256,
+				Replicate:      replicaRegion != "",
+			}
+
+			// Blocks too large for a single upload are uploaded in parts and assembled on commit
+			upload := &BlockUploadURL{Index: block.Index}
+			if block.Size > s3.MaxSinglePutSize {
+				multipart, err := s.storage.PrepareMultipartFileBlockUpload(gctx, share.UserID, share.VolumeID, linkID, revision.ID, block.Index, conditions)
+				if err != nil {
+					return err
+				}
+				block.MultipartUploadID = multipart.UploadID
+				upload.Parts = multipart.Parts
+			} else {
+				presigned, err := s.storage.PrepareFileBlockUpload(gctx, share.UserID, share.VolumeID, linkID, revision.ID, block.Index, conditions)
+				if err != nil {
+					return err
+				}
+				upload.UploadURL = presigned.URL
+				upload.Headers = presigned.Headers
+			}
+
+			block.RevisionID = revision.ID
+			block.StoragePath = s3.FileBlockPath(share.UserID, share.VolumeID, linkID, revision.ID, block.Index)
+			block.StorageBucket = s.storage.BucketName()
+			block.StorageRegion = s.storage.Region()
+			block.StorageClass = STORAGE_CLASS_STANDARD
+			block.ReplicationRegion = replicaRegion
+			block.UploadComplete = false
+
+			uploads[i] = upload
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		s.logger.Errorf("Failed to presign block uploads for revision %s: %v", revisionID, err)
+		return nil, ErrStorageUnavailable
+	}
+
+	if err := s.repo.ReplaceRevisionBlocks(ctx, revision.ID, blocks); err != nil {
+		return nil, fmt.Errorf("failed to register blocks: %w", err)
+	}
+
+	// IDs are assigned on create
+	for i, block := range blocks {
+		uploads[i].BlockID = block.ID
+	}
+
+	return uploads, nil
+}
+
+// CommitRevision verifies every block of a draft revision was uploaded and makes it the active revision
+func (s *Service) CommitRevision(
+	ctx context.Context,
+	userID,
+	shareID,
+	linkID,
+	revisionID string,
+	commit *RevisionCommit,
+) (*models.DriveItem, error) {
+	ctx, span := tracing.Start(ctx, "drive.CommitRevision")
+	defer span.End()
+
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if s.storage == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	item, revision, share, err := s.getUploadingRevision(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks cannot be verified while storage is unreachable, which is not the client's fault
+	if s.storageDegraded() {
+		return nil, ErrUploadDeferred
+	}
+
+	blocks, err := s.repo.GetBlocksByRevisionID(ctx, revision.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision blocks: %w", err)
+	}
+
+	// Blocks must be contiguous from zero and match the client's manifest
+	if len(blocks) == 0 || len(blocks) != commit.BlockCount {
+		return nil, ErrBlocksIncomplete
+	}
+
+	var totalSize int64
+	for i, block := range blocks {
+		if block.Index != i {
+			return nil, ErrBlocksIncomplete
+		}
+		totalSize += block.Size
+	}
+
+	// Confirm every block not verified yet exists with the declared size and checksum
+	received := s.receivedBlockIndexes(ctx, revision.ID, blocks)
+	var pending []*models.FileBlock
+	for _, block := range blocks {
+		if !received[block.Index] {
+			pending = append(pending, block)
+		}
+	}
+
+	verifyCtx, cancel := withBudget(ctx, s.extendedTimeout())
+	defer cancel()
+
+	results, err := s.verifyUploadedBlocks(verifyCtx, revision.ID, pending)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range pending {
+		if errors.Is(results[block.ID], ErrBlockChecksumMismatch) {
+			return nil, ErrBlockChecksumMismatch
+		}
+	}
+	for _, block := range pending {
+		if results[block.ID] != nil {
+			return nil, ErrBlocksIncomplete
+		}
+	}
+
+	revision.Size = totalSize
+	revision.State = REVISION_STATE_ACTIVE
+	revision.ManifestSignature = commit.ManifestSignature
+	if commit.SignatureEmail != "" {
+		revision.SignatureEmail = commit.SignatureEmail
+	}
+
+	// The first committed revision is what makes a file visible to sync clients
+	eventType := EVENT_TYPE_UPDATE
+	if item.State == ITEM_STATE_DRAFT {
+		eventType = EVENT_TYPE_CREATE
+	}
+
+	item.Size = totalSize
+	item.State = ITEM_STATE_ACTIVE
+
+	// Storage is charged to the owner of the share. The charge is atomic, so concurrent
+	// commits cannot together exceed the plan limit.
+	if err := s.quota.Consume(ctx, share.UserID, totalSize); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CommitRevision(ctx, item, revision); err != nil {
+		// Only one commit claims the draft; the others give their charge back before returning,
+		// so the usage they added does not outlive the request
+		if err := s.quota.Adjust(context.WithoutCancel(ctx), share.UserID, -totalSize); err != nil {
+			s.logger.Errorf("Failed to give back the storage charge of revision %s: %v", revision.ID, err)
+		}
+		return nil, fmt.Errorf("failed to commit revision: %w", err)
+	}
+
+	s.recordEvents(ctx, eventType, item)
+	s.recordBackupActivity(ctx, share)
+	_, _ = s.redisClient.Delete(ctx, uploadBlocksKey(revision.ID))
+
+	// Invalidate cached item and parent folder contents
+	s.invalidateLinkCache(ctx, item.ID)
+	if item.ParentID != nil {
+		s.invalidateFolderCaches(ctx, *item.ParentID)
+	}
+
+	return item, nil
+}
+
+// getDraftRevision loads a draft revision after checking write access and that it belongs to the link and share
+func (s *Service) getDraftRevision(
+	ctx context.Context,
+	userID,
+	shareID,
+	linkID,
+	revisionID string,
+) (*models.DriveItem, *models.FileRevision, *models.DriveShare, error) {
+	if err := s.CheckSharePermissions(ctx, userID, shareID, WRITE_PERMISSION); err != nil {
+		return nil, nil, nil, err
+	}
+
+	item, err := s.repo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if item.ShareID != shareID || item.Type != 2 {
+		return nil, nil, nil, ErrItemNotFound
+	}
+
+	revision, err := s.repo.GetRevisionByID(ctx, revisionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if revision.ItemID != item.ID {
+		return nil, nil, nil, ErrRevisionNotFound
+	}
+	if revision.State != REVISION_STATE_DRAFT {
+		return nil, nil, nil, ErrRevisionNotDraft
+	}
+
+	share, err := s.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return item, revision, share, nil
+}
+
+// isValidSHA256Checksum checks that a checksum is a base64-encoded SHA-256 digest
+func isValidSHA256Checksum(checksum string) bool {
+	digest, err := base64.StdEncoding.DecodeString(checksum)
+	return err == nil && len(digest) == sha256.Size
+}
+
+// checkNameExists checks whether any item in a folder already uses the name hash
+func (s *Service) checkNameExists(ctx context.Context, parentID, nameHash string) (bool, error) {
+	// Check cache first
+	cacheKey := s.versionedKey(ctx, fmt.Sprintf("name_hash:%s:%s", parentID, nameHash), cacheScope{cacheScopeFolder, parentID})
+	var exists bool
+	err := s.getCached(ctx, cacheKey, &exists)
+	if err == nil {
+		return exists, nil
+	}
+
+	// Cache miss, check from database
+	items, err := s.repo.GetFolderContents(ctx, parentID)
+	if err != nil {
+		return false, err
+	}
+
+	exists = false
+	for _, item := range items {
+		if item.Hash == nameHash {
+			exists = true
+			break
+		}
+	}
+
+	// Cache the result (short expiration as folder contents may change)
+	s.setCached(ctx, cacheKey, exists)
+
+	return exists, nil
+}
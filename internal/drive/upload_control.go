@@ -0,0 +1,184 @@
+// internal/drive/upload_control.go
+package drive
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JOB_TYPE_UPLOAD_CLEANUP cancels uploads that were abandoned and deletes their blocks
+const JOB_TYPE_UPLOAD_CLEANUP = "drive.upload_cleanup"
+
+// UPLOAD_CLEANUP_BATCH_SIZE bounds how many abandoned uploads one cleanup job cancels
+const UPLOAD_CLEANUP_BATCH_SIZE = 100
+
+// PauseUpload pauses the upload of a draft revision. While paused no block or thumbnail uploads are
+// issued and the revision cannot be committed. Upload URLs handed out before the pause stay valid
+// until they expire, so a client should stop using them. Pausing a paused upload changes nothing.
+func (s *Service) PauseUpload(ctx context.Context, userID, shareID, linkID, revisionID string) (*models.FileRevision, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	_, revision, _, err := s.getDraftRevision(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.PausedAt != nil {
+		return revision, nil
+	}
+
+	now := time.Now().Unix()
+	if err := s.repo.SetRevisionPaused(ctx, revision.ID, &now); err != nil {
+		return nil, err
+	}
+	revision.PausedAt = &now
+	revision.ModifiedAt = now
+
+	return revision, nil
+}
+
+// ResumeUpload resumes a paused upload. Resuming an upload that is not paused changes nothing.
+func (s *Service) ResumeUpload(ctx context.Context, userID, shareID, linkID, revisionID string) (*models.FileRevision, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	_, revision, _, err := s.getDraftRevision(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.PausedAt == nil {
+		return revision, nil
+	}
+
+	if err := s.repo.SetRevisionPaused(ctx, revision.ID, nil); err != nil {
+		return nil, err
+	}
+	revision.PausedAt = nil
+	revision.ModifiedAt = time.Now().Unix()
+
+	return revision, nil
+}
+
+// CancelUpload discards a draft revision and deletes the blocks uploaded for it. Cancelling the
+// first upload of a file removes the file as well, freeing its name. Storage quota is only checked
+// while blocks are requested and charged when a revision is committed; nothing is reserved before
+// then, so a cancelled upload has no quota to give back.
+func (s *Service) CancelUpload(ctx context.Context, userID, shareID, linkID, revisionID string) error {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	item, revision, _, err := s.getDraftRevision(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		return err
+	}
+
+	return s.discardDraftRevision(ctx, item, revision)
+}
+
+// getUploadingRevision loads a draft revision whose upload is not paused
+func (s *Service) getUploadingRevision(
+	ctx context.Context,
+	userID,
+	shareID,
+	linkID,
+	revisionID string,
+) (*models.DriveItem, *models.FileRevision, *models.DriveShare, error) {
+	item, revision, share, err := s.getDraftRevision(ctx, userID, shareID, linkID, revisionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if revision.PausedAt != nil {
+		return nil, nil, nil, ErrUploadPaused
+	}
+
+	return item, revision, share, nil
+}
+
+// discardDraftRevision deletes a draft revision, and its item when the item was never committed,
+// then deletes their stored objects. A revision committed in the meantime is kept.
+func (s *Service) discardDraftRevision(ctx context.Context, item *models.DriveItem, revision *models.FileRevision) error {
+	purged, err := s.repo.DiscardDraftRevision(ctx, revision.ID)
+	if err != nil {
+		if errors.Is(err, ErrRevisionNotDraft) {
+			return err
+		}
+		return fmt.Errorf("failed to discard draft revision: %w", err)
+	}
+
+	_, _ = s.redisClient.Delete(ctx, uploadBlocksKey(revision.ID))
+
+	if purged.ItemCount > 0 {
+		s.invalidateLinkCache(ctx, item.ID)
+		if item.ParentID != nil {
+			s.invalidateFolderCaches(ctx, *item.ParentID)
+		}
+	}
+
+	// Delete stored objects (can be done asynchronously)
+	go s.deleteStoredObjects(context.WithoutCancel(ctx), purged.StoragePaths)
+
+	return nil
+}
+
+// StartUploadCleanupScheduler queues a cleanup job for uploads without activity for longer than the
+// draft TTL until ctx is cancelled, so abandoned blocks do not stay in storage
+func (s *Service) StartUploadCleanupScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	// Deferred uploads cannot make progress during a storage outage, so they are not abandoned
+	s.jobService.ScheduleWhen(ctx, "upload_cleanup_pass", s.uploads.interval, JOB_TYPE_UPLOAD_CLEANUP, nil, func(ctx context.Context) bool {
+		return !s.storageDegraded()
+	})
+}
+
+// runUploadCleanupJob cancels one batch of abandoned uploads. Uploads already cancelled by an
+// interrupted run are no longer found, so running it again continues with whatever is left.
+func (s *Service) runUploadCleanupJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	inactiveSince := time.Now().Add(-s.uploads.ttl).Unix()
+	revisions, err := s.repo.GetAbandonedDraftRevisions(ctx, inactiveSince, UPLOAD_CLEANUP_BATCH_SIZE)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to load abandoned uploads: %w", err)
+	}
+
+	result := map[string]int64{"cancelledUploads": job.Result["cancelledUploads"]}
+	total := result["cancelledUploads"] + int64(len(revisions))
+	progress(result["cancelledUploads"], total, result)
+
+	for _, revision := range revisions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := s.discardDraftRevision(ctx, &revision.Item, revision); err != nil {
+			// Committed since it was loaded, or failed; either way the next run sees it again if needed
+			if !errors.Is(err, ErrRevisionNotDraft) {
+				s.logger.Errorf("Failed to cancel abandoned upload %s: %v", revision.ID, err)
+			}
+			continue
+		}
+
+		result["cancelledUploads"]++
+		progress(result["cancelledUploads"], total, result)
+	}
+
+	if result["cancelledUploads"] > 0 {
+		s.logger.Infof("Cancelled %d abandoned uploads", result["cancelledUploads"])
+	}
+
+	return nil
+}
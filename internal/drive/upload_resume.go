@@ -0,0 +1,258 @@
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/s3"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+)
+
+// UploadStatus is what a client needs to resume an interrupted upload: which of the requested
+// blocks storage has received and verified, and which still have to be uploaded
+type UploadStatus struct {
+	Revision *models.FileRevision
+	Item     *models.DriveItem
+	Received []int // Verified against the size and checksum the block was requested with
+	Missing  []int // Not in storage yet, or stored with another size
+	Corrupt  []int // Stored but not matching the checksum the block was requested with
+	Verified bool  // False while storage is unreachable; only blocks verified earlier are listed as received
+}
+
+// uploadBlocksKey is the set of the blocks of a draft revision that were received and verified. It holds
+// block IDs rather than indexes, since requesting an index again replaces its block with a new one.
+func uploadBlocksKey(revisionID string) string {
+	return fmt.Sprintf("upload_blocks:%s", revisionID)
+}
+
+// GetUploadStatus reports which blocks of a draft revision storage has received, so a client can
+// resume an upload after a network failure by requesting only the missing and corrupt indexes.
+// Blocks not verified yet are checked against storage, and the ones found intact are recorded.
+func (s *Service) GetUploadStatus(ctx context.Context, userID, revisionID string) (*UploadStatus, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	revision, err := s.repo.GetRevisionByID(ctx, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.repo.GetLinkByID(ctx, revision.ItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checks write access, since only the uploader can continue the upload
+	item, revision, _, err = s.getDraftRevision(ctx, userID, item.ShareID, item.ID, revision.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := s.repo.GetBlocksByRevisionID(ctx, revision.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision blocks: %w", err)
+	}
+
+	received := s.receivedBlockIndexes(ctx, revision.ID, blocks)
+
+	status := &UploadStatus{
+		Revision: revision,
+		Item:     item,
+		Received: []int{},
+		Missing:  []int{},
+		Corrupt:  []int{},
+		Verified: s.storage != nil && !s.storageDegraded(),
+	}
+
+	var pending []*models.FileBlock
+	for _, block := range blocks {
+		if received[block.Index] {
+			status.Received = append(status.Received, block.Index)
+			continue
+		}
+		pending = append(pending, block)
+	}
+
+	if !status.Verified {
+		for _, block := range pending {
+			status.Missing = append(status.Missing, block.Index)
+		}
+		return status, nil
+	}
+
+	verifyCtx, cancel := withBudget(ctx, s.extendedTimeout())
+	defer cancel()
+
+	results, err := s.verifyUploadedBlocks(verifyCtx, revision.ID, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range pending {
+		switch {
+		case results[block.ID] == nil:
+			status.Received = append(status.Received, block.Index)
+		case errors.Is(results[block.ID], ErrBlockChecksumMismatch):
+			status.Corrupt = append(status.Corrupt, block.Index)
+		default:
+			status.Missing = append(status.Missing, block.Index)
+		}
+	}
+	slices.Sort(status.Received)
+
+	return status, nil
+}
+
+// receivedBlockIndexes returns the indexes of the blocks already verified. Redis answers without
+// touching the blocks again; when the set has expired or was lost, the blocks' own flags are used.
+func (s *Service) receivedBlockIndexes(ctx context.Context, revisionID string, blocks []*models.FileBlock) map[int]bool {
+	received := make(map[int]bool, len(blocks))
+
+	members, err := s.redisClient.SMembers(ctx, uploadBlocksKey(revisionID))
+	if err != nil {
+		s.logger.Warnf("Failed to load received blocks of revision %s: %v", revisionID, err)
+	}
+
+	for _, block := range blocks {
+		if block.UploadComplete || slices.Contains(members, block.ID) {
+			received[block.Index] = true
+		}
+	}
+
+	return received
+}
+
+// verifyUploadedBlocks checks blocks against their stored objects and records the ones that match.
+// It returns the result for each block ID: nil when the block is intact, ErrBlocksIncomplete when
+// the object is missing or has another size, and ErrBlockChecksumMismatch when its content differs.
+func (s *Service) verifyUploadedBlocks(ctx context.Context, revisionID string, blocks []*models.FileBlock) (map[string]error, error) {
+	results := make(map[string]error, len(blocks))
+	var mu sync.Mutex
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.maxConcurrency())
+
+	for _, block := range blocks {
+		block := block
+		g.Go(func() error {
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+
+			result := s.verifyUploadedBlock(gctx, block)
+			if result != nil && !errors.Is(result, ErrBlocksIncomplete) && !errors.Is(result, ErrBlockChecksumMismatch) {
+				return result
+			}
+
+			mu.Lock()
+			results[block.ID] = result
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		s.logger.Errorf("Failed to verify blocks of revision %s: %v", revisionID, err)
+		return nil, ErrStorageUnavailable
+	}
+
+	verified := make([]*models.FileBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if results[block.ID] == nil {
+			verified = append(verified, block)
+		}
+	}
+	s.markBlocksReceived(ctx, revisionID, verified)
+
+	return results, nil
+}
+
+// verifyUploadedBlock compares a block to its stored object, first assembling the parts of a block
+// uploaded in parts. A block requested with a checksum must match it: storage reports the checksum it
+// enforced on a single-part upload, and when it has none, the object is read back and hashed here.
+func (s *Service) verifyUploadedBlock(ctx context.Context, block *models.FileBlock) error {
+	info, err := s.storage.HeadObject(ctx, block.StoragePath)
+	if errors.Is(err, s3.ErrObjectNotFound) && block.MultipartUploadID != "" {
+		err = s.storage.CompleteMultipartUpload(ctx, block.StoragePath, block.MultipartUploadID, block.Size)
+		if errors.Is(err, s3.ErrMultipartIncomplete) {
+			return ErrBlocksIncomplete
+		}
+		if err != nil {
+			return err
+		}
+		info, err = s.storage.HeadObject(ctx, block.StoragePath)
+	}
+	if err != nil {
+		if errors.Is(err, s3.ErrObjectNotFound) {
+			return ErrBlocksIncomplete
+		}
+		return err
+	}
+	if info.Size != block.Size {
+		return ErrBlocksIncomplete
+	}
+
+	if block.ChecksumSHA256 == "" {
+		return nil
+	}
+	if info.ChecksumSHA256 != "" {
+		if info.ChecksumSHA256 != block.ChecksumSHA256 {
+			return ErrBlockChecksumMismatch
+		}
+		return nil
+	}
+
+	checksum, err := s.storage.ObjectSHA256(ctx, block.StoragePath)
+	if err != nil {
+		if errors.Is(err, s3.ErrObjectNotFound) {
+			return ErrBlocksIncomplete
+		}
+		return err
+	}
+	if checksum != block.ChecksumSHA256 {
+		return ErrBlockChecksumMismatch
+	}
+
+	return nil
+}
+
+// markBlocksReceived records verified blocks in Redis, which status requests read, and on the blocks
+// themselves, which outlive the Redis set
+func (s *Service) markBlocksReceived(ctx context.Context, revisionID string, blocks []*models.FileBlock) {
+	if len(blocks) == 0 {
+		return
+	}
+
+	blockIDs := make([]string, len(blocks))
+	members := make([]any, len(blocks))
+	for i, block := range blocks {
+		blockIDs[i] = block.ID
+		members[i] = block.ID
+	}
+
+	if err := s.repo.MarkBlocksReceived(ctx, revisionID, blockIDs, time.Now().Unix()); err != nil {
+		s.logger.Errorf("Failed to record received blocks of revision %s: %v", revisionID, err)
+		return
+	}
+
+	key := uploadBlocksKey(revisionID)
+	_, err := s.redisClient.Pipeline(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.SAdd(ctx, key, members...)
+		// Drafts without activity are cleaned up after the same time
+		pipe.Expire(ctx, key, s.uploads.ttl)
+		return nil
+	})
+	if err != nil {
+		s.logger.Warnf("Failed to cache received blocks of revision %s: %v", revisionID, err)
+	}
+}
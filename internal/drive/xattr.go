@@ -0,0 +1,40 @@
+package drive
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"fmt"
+)
+
+// MAX_XATTRS_SIZE bounds the encrypted extended attributes of an item, which hold small metadata such
+// as modification times and platform attributes, not content
+const MAX_XATTRS_SIZE = 64 * 1024
+
+// SetItemXattrs replaces the extended attributes of an item, a JSON document the client encrypts with
+// the item's node key. Sync clients keep modification times and platform metadata in it. A nil value
+// clears them.
+func (s *Service) SetItemXattrs(ctx context.Context, userID, shareID, linkID string, xattrs *string) (*models.DriveItem, error) {
+	if xattrs != nil && (*xattrs == "" || len(*xattrs) > MAX_XATTRS_SIZE) {
+		return nil, ErrInvalidXattrs
+	}
+
+	item, _, err := s.getMovableItem(ctx, userID, shareID, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Xattrs = xattrs
+	if err := s.repo.UpdateItemXattrs(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to update extended attributes: %w", err)
+	}
+
+	s.recordEvents(ctx, EVENT_TYPE_UPDATE, item)
+
+	// Folder listings carry the attributes of their children
+	s.invalidateLinkCache(ctx, item.ID)
+	if item.ParentID != nil {
+		s.invalidateFolderCaches(ctx, *item.ParentID)
+	}
+
+	return item, nil
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import "errors"
+
+// Common errors
+var (
+	ErrJobNotFound    = errors.New("Job not found")
+	ErrJobCreation    = errors.New("Failed to create job")
+	ErrUnknownJobType = errors.New("Unknown job type")
+	ErrAlreadyStarted = errors.New("Job workers are already running")
+	ErrJobNotFailed   = errors.New("Only failed jobs can be retried")
+)
+
+// permanentError marks a job failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+// Permanent marks an error returned by a handler as final, so the job fails without being retried
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Error implements the error interface
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the handler's error
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// isPermanent reports whether a handler failed for good
+func isPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
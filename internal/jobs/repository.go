@@ -0,0 +1,261 @@
+package jobs
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/db"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository interface for background job operations
+type Repository interface {
+	CreateJob(ctx context.Context, job *models.Job) error
+	GetJobByID(ctx context.Context, jobID string) (*models.Job, error)
+	ClaimJob(ctx context.Context, jobID string) (bool, error)
+	GetQueuedJobIDs(ctx context.Context, limit int) ([]string, error)
+	UpdateProgress(ctx context.Context, jobID string, processed, total int64, result map[string]int64) error
+	FinishJob(ctx context.Context, jobID, state string, jobErr *string) error
+	RequeueJob(ctx context.Context, jobID string) error
+	RequeueStaleJobs(ctx context.Context, staleBefore int64, maxAttempts int) (int64, error)
+	ScheduleRetry(ctx context.Context, jobID, jobErr string, runAfter int64) error
+	ClearPayload(ctx context.Context, jobID string) error
+	ResetFailedJob(ctx context.Context, jobID string) (bool, error)
+	ListJobs(ctx context.Context, filter Filter) ([]models.Job, error)
+	CountJobs(ctx context.Context) ([]Stats, error)
+	DeleteFinishedJobs(ctx context.Context, finishedBefore int64, limit int) (int64, error)
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db      *gorm.DB
+	jobRepo db.Repository[models.Job]
+}
+
+// NewRepository creates a new background job repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db:      database,
+		jobRepo: db.NewRepositoryWithDB[models.Job](database),
+	}
+}
+
+// CreateJob creates a new job
+func (r *repo) CreateJob(ctx context.Context, job *models.Job) error {
+	return r.jobRepo.Create(ctx, job)
+}
+
+// GetJobByID retrieves a job by its ID
+func (r *repo) GetJobByID(ctx context.Context, jobID string) (*models.Job, error) {
+	var job models.Job
+	err := r.db.WithContext(ctx).
+		Where("id = ?", jobID).
+		First(&job).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimJob atomically moves a queued job to running. It reports false when another worker got there
+// first or the job waits for a retry.
+func (r *repo) ClaimJob(ctx context.Context, jobID string) (bool, error) {
+	now := time.Now().Unix()
+	result := r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("id = ? AND state = ? AND run_after <= ?", jobID, STATE_QUEUED, now).
+		Updates(map[string]interface{}{
+			"state":       STATE_RUNNING,
+			"attempts":    gorm.Expr("attempts + 1"),
+			"started_at":  now,
+			"modified_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetQueuedJobIDs retrieves the oldest queued jobs that are due to run
+func (r *repo) GetQueuedJobIDs(ctx context.Context, limit int) ([]string, error) {
+	var jobIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("state = ? AND run_after <= ?", STATE_QUEUED, time.Now().Unix()).
+		Order("created_at ASC").
+		Limit(limit).
+		Pluck("id", &jobIDs).Error
+
+	return jobIDs, err
+}
+
+// UpdateProgress records a running job's progress, which also serves as its heartbeat
+func (r *repo) UpdateProgress(ctx context.Context, jobID string, processed, total int64, result map[string]int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("id = ?", jobID).
+		Select("processed", "total", "result", "modified_at").
+		Updates(&models.Job{
+			Processed:  processed,
+			Total:      total,
+			Result:     result,
+			ModifiedAt: time.Now().Unix(),
+		}).Error
+}
+
+// FinishJob moves a job to its final state
+func (r *repo) FinishJob(ctx context.Context, jobID, state string, jobErr *string) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("id = ?", jobID).
+		Updates(map[string]interface{}{
+			"state":        state,
+			"error":        jobErr,
+			"completed_at": now,
+			"modified_at":  now,
+		}).Error
+}
+
+// RequeueJob returns a running job to the queue, for example when the server shuts down mid-run
+func (r *repo) RequeueJob(ctx context.Context, jobID string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("id = ? AND state = ?", jobID, STATE_RUNNING).
+		Updates(map[string]interface{}{
+			"state":       STATE_QUEUED,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// RequeueStaleJobs requeues running jobs whose worker stopped reporting progress.
+// Jobs that have used up their attempts are failed instead.
+func (r *repo) RequeueStaleJobs(ctx context.Context, staleBefore int64, maxAttempts int) (int64, error) {
+	var requeued int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Unix()
+
+		err := tx.Model(&models.Job{}).
+			Where("state = ? AND modified_at < ? AND attempts >= ?", STATE_RUNNING, staleBefore, maxAttempts).
+			Updates(map[string]interface{}{
+				"state":        STATE_FAILED,
+				"error":        "Job was interrupted too many times",
+				"completed_at": now,
+				"modified_at":  now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Job{}).
+			Where("state = ? AND modified_at < ?", STATE_RUNNING, staleBefore).
+			Updates(map[string]interface{}{
+				"state":       STATE_QUEUED,
+				"modified_at": now,
+			})
+		requeued = result.RowsAffected
+		return result.Error
+	})
+
+	return requeued, err
+}
+
+// ScheduleRetry returns a failed running job to the queue, to run again no earlier than runAfter
+func (r *repo) ScheduleRetry(ctx context.Context, jobID, jobErr string, runAfter int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("id = ? AND state = ?", jobID, STATE_RUNNING).
+		Updates(map[string]interface{}{
+			"state":       STATE_QUEUED,
+			"error":       jobErr,
+			"run_after":   runAfter,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// ClearPayload removes a job's payload
+func (r *repo) ClearPayload(ctx context.Context, jobID string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("id = ?", jobID).
+		Updates(map[string]interface{}{
+			"payload":     nil,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// ResetFailedJob queues a failed job again with all its attempts. It reports false when the job
+// is not failed.
+func (r *repo) ResetFailedJob(ctx context.Context, jobID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Where("id = ? AND state = ?", jobID, STATE_FAILED).
+		Updates(map[string]interface{}{
+			"state":        STATE_QUEUED,
+			"attempts":     0,
+			"error":        nil,
+			"run_after":    0,
+			"started_at":   nil,
+			"completed_at": nil,
+			"modified_at":  time.Now().Unix(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListJobs retrieves the newest jobs matching a filter
+func (r *repo) ListJobs(ctx context.Context, filter Filter) ([]models.Job, error) {
+	query := r.db.WithContext(ctx).Model(&models.Job{})
+	if filter.State != "" {
+		query = query.Where("state = ?", filter.State)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	var jobs []models.Job
+	err := query.
+		Order("created_at DESC").
+		Limit(filter.Limit).
+		Find(&jobs).Error
+
+	return jobs, err
+}
+
+// CountJobs counts jobs by type and state
+func (r *repo) CountJobs(ctx context.Context) ([]Stats, error) {
+	var stats []Stats
+	err := r.db.WithContext(ctx).
+		Model(&models.Job{}).
+		Select("type, state, COUNT(*) AS count").
+		Group("type, state").
+		Order("type, state").
+		Scan(&stats).Error
+
+	return stats, err
+}
+
+// DeleteFinishedJobs deletes up to limit completed and failed jobs that finished before a time
+func (r *repo) DeleteFinishedJobs(ctx context.Context, finishedBefore int64, limit int) (int64, error) {
+	finished := r.db.Model(&models.Job{}).
+		Select("id").
+		Where("state IN ? AND modified_at < ?", []string{STATE_COMPLETED, STATE_FAILED}, finishedBefore).
+		Limit(limit)
+
+	result := r.db.WithContext(ctx).
+		Where("id IN (?)", finished).
+		Delete(&models.Job{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// ReadyFunc reports whether a scheduled job has work to do. A schedule skips the run while it returns false.
+type ReadyFunc func(ctx context.Context) bool
+
+// schedule queues one job type on a recurring timetable
+type schedule struct {
+	name    string // Lock shared by every instance running the schedule
+	jobType string
+	payload map[string]string
+	lockTTL time.Duration
+	ready   ReadyFunc
+	next    func(now time.Time) time.Time // When the job is due again after now
+}
+
+// Schedule queues a job every interval until ctx is cancelled, starting right away. Instances share
+// the schedule through a Redis lock named after it. The lock is left to expire, so only one job is
+// queued per interval however many instances run and however often they restart.
+func (s *Service) Schedule(ctx context.Context, name string, interval time.Duration, jobType string, payload map[string]string) {
+	s.ScheduleWhen(ctx, name, interval, jobType, payload, nil)
+}
+
+// ScheduleWhen is Schedule for jobs that only have work some of the time. A run skipped because ready
+// returned false does not take the lock, so the job is tried again at the next tick.
+func (s *Service) ScheduleWhen(ctx context.Context, name string, interval time.Duration, jobType string, payload map[string]string, ready ReadyFunc) {
+	s.start(ctx, time.Now(), &schedule{
+		name:    name,
+		jobType: jobType,
+		payload: payload,
+		lockTTL: interval,
+		ready:   ready,
+		next:    func(now time.Time) time.Time { return now.Add(interval) },
+	})
+}
+
+// ScheduleDaily queues a job once a day at hour, in UTC, until ctx is cancelled. The lock is held for
+// an hour, so instances whose clocks differ slightly still queue only one job.
+func (s *Service) ScheduleDaily(ctx context.Context, name string, hour int, jobType string, payload map[string]string) {
+	next := func(now time.Time) time.Time { return NextDailyRun(now, hour) }
+	s.start(ctx, next(time.Now()), &schedule{
+		name:    name,
+		jobType: jobType,
+		payload: payload,
+		lockTTL: time.Hour,
+		next:    next,
+	})
+}
+
+// NextDailyRun returns the next time after now at hour, in UTC
+func NextDailyRun(now time.Time, hour int) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// start runs a schedule from first until ctx is cancelled
+func (s *Service) start(ctx context.Context, first time.Time, sched *schedule) {
+	go func() {
+		due := first
+		for {
+			timer := time.NewTimer(time.Until(due))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			s.queueScheduled(ctx, sched)
+			due = sched.next(time.Now())
+		}
+	}()
+}
+
+// queueScheduled queues one job of a schedule unless another instance already did this period
+func (s *Service) queueScheduled(ctx context.Context, sched *schedule) {
+	if sched.ready != nil && !sched.ready(ctx) {
+		return
+	}
+
+	// The lock is left to expire so only one job is queued per period
+	acquired, err := s.redisClient.AcquireLock(ctx, sched.name, sched.lockTTL, 1, 0)
+	if err != nil {
+		s.logger.Errorf("Failed to acquire %s lock: %v", sched.name, err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	// Scheduled jobs belong to no user, so they cannot be looked up through the jobs API
+	if _, err := s.Enqueue(ctx, "", sched.jobType, sched.payload); err != nil {
+		s.logger.Errorf("Failed to queue scheduled %s job: %v", sched.jobType, err)
+	}
+}
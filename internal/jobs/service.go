@@ -0,0 +1,314 @@
+package jobs
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Job states
+const (
+	STATE_QUEUED    = "queued"
+	STATE_RUNNING   = "running"
+	STATE_COMPLETED = "completed"
+	STATE_FAILED    = "failed"
+)
+
+// MAX_ATTEMPTS bounds how often a job is started before a failing or interrupted job is given up on
+const MAX_ATTEMPTS = 3
+
+// MAX_LIST_LIMIT bounds how many jobs are listed for inspection at once
+const MAX_LIST_LIMIT = 500
+
+// PRUNE_BATCH_SIZE bounds how many finished jobs are deleted per poll
+const PRUNE_BATCH_SIZE = 1000
+
+// NewService creates a new background job service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger, cfg *config.JobsConfig) *Service {
+	return &Service{
+		repo:        repo,
+		redisClient: redisClient,
+		logger:      logger,
+		config:      cfg,
+		queue:       make(chan string, cfg.QueueSize),
+		handlers:    make(map[string]Handler),
+		transient:   make(map[string]bool),
+	}
+}
+
+// Register sets the handler for a job type. It must be called before Start.
+func (s *Service) Register(jobType string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = handler
+}
+
+// RegisterTransient sets the handler for a job type whose payload holds secrets, such as the links
+// in an email. The payload is cleared once a job completed; failed jobs keep it so they can be retried.
+func (s *Service) RegisterTransient(jobType string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = handler
+	s.transient[jobType] = true
+}
+
+// Enqueue stores a new job and hands it to a worker
+func (s *Service) Enqueue(ctx context.Context, userID, jobType string, payload map[string]string) (*models.Job, error) {
+	s.mu.RLock()
+	_, ok := s.handlers[jobType]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownJobType
+	}
+
+	job := &models.Job{
+		UserID:  userID,
+		Type:    jobType,
+		State:   STATE_QUEUED,
+		Payload: payload,
+	}
+	if err := s.repo.CreateJob(ctx, job); err != nil {
+		s.logger.Errorf("Failed to create %s job: %v", jobType, err)
+		return nil, ErrJobCreation
+	}
+
+	// If the queue is full the poller picks the job up later
+	select {
+	case s.queue <- job.ID:
+	default:
+	}
+
+	return job, nil
+}
+
+// GetJob retrieves a job started by the user
+func (s *Service) GetJob(ctx context.Context, userID, jobID string) (*models.Job, error) {
+	job, err := s.repo.GetJobByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not reveal other users' jobs
+	if job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+// InspectJob retrieves any job, for administrators
+func (s *Service) InspectJob(ctx context.Context, jobID string) (*models.Job, error) {
+	return s.repo.GetJobByID(ctx, jobID)
+}
+
+// ListJobs retrieves the newest jobs matching a filter, for administrators
+func (s *Service) ListJobs(ctx context.Context, filter Filter) ([]models.Job, error) {
+	if filter.Limit <= 0 || filter.Limit > MAX_LIST_LIMIT {
+		filter.Limit = MAX_LIST_LIMIT
+	}
+	return s.repo.ListJobs(ctx, filter)
+}
+
+// GetStats counts jobs by type and state, for administrators
+func (s *Service) GetStats(ctx context.Context) ([]Stats, error) {
+	return s.repo.CountJobs(ctx)
+}
+
+// RetryFailedJob queues a failed job again with all its attempts, for administrators
+func (s *Service) RetryFailedJob(ctx context.Context, jobID string) (*models.Job, error) {
+	reset, err := s.repo.ResetFailedJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !reset {
+		if _, err := s.repo.GetJobByID(ctx, jobID); err != nil {
+			return nil, err
+		}
+		return nil, ErrJobNotFailed
+	}
+
+	select {
+	case s.queue <- jobID:
+	default:
+	}
+
+	return s.repo.GetJobByID(ctx, jobID)
+}
+
+// Start runs the worker pool and the database poller until ctx is cancelled
+func (s *Service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return ErrAlreadyStarted
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	for i := 0; i < s.config.Workers; i++ {
+		go s.worker(ctx)
+	}
+	go s.poll(ctx)
+
+	return nil
+}
+
+// worker runs queued jobs one at a time
+func (s *Service) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case jobID := <-s.queue:
+			s.run(ctx, jobID)
+		}
+	}
+}
+
+// poll requeues jobs left behind by stopped workers and feeds queued jobs that were not picked up from memory,
+// including failed jobs whose retry is due
+func (s *Service) poll(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.recoverJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recoverJobs requeues stale jobs, deletes old finished ones and queues as many waiting jobs as there is room for
+func (s *Service) recoverJobs(ctx context.Context) {
+	staleBefore := time.Now().Add(-s.config.StaleAfter).Unix()
+	requeued, err := s.repo.RequeueStaleJobs(ctx, staleBefore, MAX_ATTEMPTS)
+	if err != nil {
+		s.logger.Errorf("Failed to requeue stale jobs: %v", err)
+	} else if requeued > 0 {
+		s.logger.Debugf("Requeued %d stale jobs", requeued)
+	}
+
+	// Finished jobs are kept for inspection until the retention period ended
+	retainedAfter := time.Now().Add(-s.config.Retention).Unix()
+	if pruned, err := s.repo.DeleteFinishedJobs(ctx, retainedAfter, PRUNE_BATCH_SIZE); err != nil {
+		s.logger.Errorf("Failed to delete finished jobs: %v", err)
+	} else if pruned > 0 {
+		s.logger.Debugf("Deleted %d finished jobs", pruned)
+	}
+
+	room := cap(s.queue) - len(s.queue)
+	if room <= 0 {
+		return
+	}
+
+	jobIDs, err := s.repo.GetQueuedJobIDs(ctx, room)
+	if err != nil {
+		s.logger.Errorf("Failed to load queued jobs: %v", err)
+		return
+	}
+
+	for _, jobID := range jobIDs {
+		select {
+		case s.queue <- jobID:
+		default:
+			return
+		}
+	}
+}
+
+// run claims a job and executes its handler
+func (s *Service) run(ctx context.Context, jobID string) {
+	// A job can be queued twice (by Enqueue and the poller); only one worker gets to claim it
+	claimed, err := s.repo.ClaimJob(ctx, jobID)
+	if err != nil {
+		s.logger.Errorf("Failed to claim job %s: %v", jobID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	job, err := s.repo.GetJobByID(ctx, jobID)
+	if err != nil {
+		s.logger.Errorf("Failed to load job %s: %v", jobID, err)
+		return
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[job.Type]
+	transient := s.transient[job.Type]
+	s.mu.RUnlock()
+	if !ok {
+		s.fail(job, ErrUnknownJobType)
+		return
+	}
+
+	progress := func(processed, total int64, result map[string]int64) {
+		if err := s.repo.UpdateProgress(ctx, job.ID, processed, total, result); err != nil {
+			s.logger.Errorf("Failed to record progress of job %s: %v", job.ID, err)
+		}
+	}
+
+	err = s.safeRun(ctx, handler, job, progress)
+	switch {
+	case err == nil:
+		if err := s.repo.FinishJob(context.Background(), job.ID, STATE_COMPLETED, nil); err != nil {
+			s.logger.Errorf("Failed to complete job %s: %v", job.ID, err)
+		}
+		if transient {
+			if err := s.repo.ClearPayload(context.Background(), job.ID); err != nil {
+				s.logger.Errorf("Failed to clear payload of job %s: %v", job.ID, err)
+			}
+		}
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		// Shutting down; another run picks the job up again
+		if err := s.repo.RequeueJob(context.Background(), job.ID); err != nil {
+			s.logger.Errorf("Failed to requeue job %s: %v", job.ID, err)
+		}
+	case isPermanent(err) || job.Attempts >= MAX_ATTEMPTS:
+		s.fail(job, err)
+	default:
+		s.retry(job, err)
+	}
+}
+
+// safeRun executes a handler, turning a panic into an error so one bad job cannot stop a worker
+func (s *Service) safeRun(ctx context.Context, handler Handler, job *models.Job, progress ProgressFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, job, progress)
+}
+
+// retry queues a failed job again after a backoff that doubles with every attempt
+func (s *Service) retry(job *models.Job, jobErr error) {
+	delay := s.config.RetryBackoff << (job.Attempts - 1)
+	s.logger.Warnf("Job %s (%s) failed on attempt %d, retrying in %s: %v", job.ID, job.Type, job.Attempts, delay, jobErr)
+
+	runAfter := time.Now().Add(delay).Unix()
+	if err := s.repo.ScheduleRetry(context.Background(), job.ID, jobErr.Error(), runAfter); err != nil {
+		s.logger.Errorf("Failed to schedule retry of job %s: %v", job.ID, err)
+	}
+}
+
+// fail marks a job as failed
+func (s *Service) fail(job *models.Job, jobErr error) {
+	s.logger.Errorf("Job %s (%s) failed: %v", job.ID, job.Type, jobErr)
+
+	message := jobErr.Error()
+	if err := s.repo.FinishJob(context.Background(), job.ID, STATE_FAILED, &message); err != nil {
+		s.logger.Errorf("Failed to mark job %s as failed: %v", job.ID, err)
+	}
+}
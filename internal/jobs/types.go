@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"sync"
+)
+
+// ProgressFunc records how far a job has got. Result holds job-specific counters.
+type ProgressFunc func(processed, total int64, result map[string]int64)
+
+// Handler runs a job. Handlers must be safe to run again from the start after an interruption,
+// and should return promptly once ctx is cancelled.
+type Handler func(ctx context.Context, job *models.Job, progress ProgressFunc) error
+
+// Service queues background jobs and runs them on a pool of workers
+type Service struct {
+	repo        Repository
+	redisClient *redis.Client // Shares schedules between instances
+	logger      *logger.Logger
+	config      *config.JobsConfig
+	queue       chan string
+	handlers    map[string]Handler
+	transient   map[string]bool // Job types whose payload is cleared once they completed
+	mu          sync.RWMutex
+	started     bool
+}
+
+// Filter selects the jobs listed for inspection. Empty fields match every job.
+type Filter struct {
+	State  string
+	Type   string
+	UserID string
+	Limit  int
+}
+
+// Stats counts jobs by type and state
+type Stats struct {
+	Type  string `json:"type"`
+	State string `json:"state"`
+	Count int64  `json:"count"`
+}
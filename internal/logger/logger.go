@@ -1,36 +1,75 @@
 package logger
 
 import (
-	"cirrussync-api/internal/utils"
+	"context"
 
 	"github.com/sirupsen/logrus"
 )
 
+// requestIDKey is the context key the ID of the request being served is stored under
+type requestIDKey struct{}
+
+// REQUEST_ID_FIELD is the log field entries logged for a request carry its ID in
+const REQUEST_ID_FIELD = "requestID"
+
+// WithRequestID returns a context carrying the ID of the request being served
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// requestIDHook adds the request ID to entries logged with a request's context
+type requestIDHook struct{}
+
+func (requestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (requestIDHook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[REQUEST_ID_FIELD]; ok {
+		return nil
+	}
+	if requestID := RequestIDFromContext(entry.Context); requestID != "" {
+		entry.Data[REQUEST_ID_FIELD] = requestID
+	}
+	return nil
+}
+
 // Logger wraps the logrus logger with additional functionality
 type Logger struct {
 	log *logrus.Logger
 }
 
-// New creates a new Logger instance
+// New creates a new Logger instance. Entries logged with a request's context include its ID.
 func New(log *logrus.Logger) *Logger {
+	log.AddHook(requestIDHook{})
 	return &Logger{
 		log: log,
 	}
 }
 
 // SecureLog logs errors without sensitive data that might expose code or credentials
-func (l *Logger) SecureLog(err error, message string, route string) {
-	// Generate request ID internally
-	requestID := utils.GenerateShortID()
-
+func (l *Logger) SecureLog(ctx context.Context, err error, message string, route string) {
 	// Log only necessary information, avoid including stack traces or request bodies
-	l.log.WithFields(logrus.Fields{
-		"request_id": requestID,
-		"route":      route,
-		"error_msg":  err.Error(),
+	l.log.WithContext(ctx).WithFields(logrus.Fields{
+		"route":    route,
+		"errorMsg": err.Error(),
 	}).Error(message)
 }
 
+// WithContext returns an entry that includes the ID of the request ctx belongs to
+func (l *Logger) WithContext(ctx context.Context) *logrus.Entry {
+	return l.log.WithContext(ctx)
+}
+
 // WithField adds a field to the logger
 func (l *Logger) WithField(key string, value interface{}) *logrus.Entry {
 	return l.log.WithField(key, value)
@@ -0,0 +1,197 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/metrics"
+	"cirrussync-api/pkg/redis"
+)
+
+const (
+	// DEAD_LETTER_KEY is the Redis list emails that could not be sent are kept in, newest first
+	DEAD_LETTER_KEY = "email:dead_letters"
+
+	// DEAD_LETTER_TTL is how long the dead-letter queue is kept after the last failed send
+	DEAD_LETTER_TTL = 7 * 24 * time.Hour
+)
+
+// DeadLetter is an email that failed every attempt to send it
+type DeadLetter struct {
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"`
+	HTMLBody string   `json:"htmlBody"`
+	TextBody string   `json:"textBody"`
+	Provider string   `json:"provider"`
+	Error    string   `json:"error"`
+	Attempts int      `json:"attempts"`
+	FailedAt int64    `json:"failedAt"`
+}
+
+// Dispatcher sends emails through a provider, retrying failed sends with exponential backoff and
+// keeping those that still fail in a dead-letter queue for inspection and resending
+type Dispatcher struct {
+	sender          Sender
+	redisClient     *redis.Client
+	logger          *logger.Logger
+	provider        string
+	maxAttempts     int
+	backoff         time.Duration
+	deadLetterLimit int64
+}
+
+// NewDispatcher creates a dispatcher for a sender with the retry settings of the configuration
+func NewDispatcher(sender Sender, cfg *config.MailConfig, redisClient *redis.Client, logger *logger.Logger) *Dispatcher {
+	maxAttempts := cfg.EmailMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Dispatcher{
+		sender:          sender,
+		redisClient:     redisClient,
+		logger:          logger,
+		provider:        cfg.EmailProvider,
+		maxAttempts:     maxAttempts,
+		backoff:         cfg.EmailRetryBackoff,
+		deadLetterLimit: cfg.EmailDeadLetterLimit,
+	}
+}
+
+// Send sends a message, retrying failures that may be temporary. The backoff doubles after every
+// attempt. A message that cannot be sent is added to the dead-letter queue and the last error returned.
+func (d *Dispatcher) Send(ctx context.Context, to []string, message *Message) error {
+	var err error
+	attempts := 0
+	for delay := d.backoff; attempts < d.maxAttempts; delay *= 2 {
+		attempts++
+		if err = d.SendOnce(ctx, to, message); err == nil {
+			return nil
+		}
+		if isPermanent(err) || attempts == d.maxAttempts {
+			break
+		}
+
+		d.logger.Warn("Email send failed, retrying", "provider", d.provider, "attempt", attempts, "retryIn", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			d.AddDeadLetter(to, message, err, attempts)
+			return err
+		case <-time.After(delay):
+		}
+	}
+
+	d.AddDeadLetter(to, message, err, attempts)
+	return err
+}
+
+// SendOnce makes a single attempt to send a message, without retries or dead-lettering
+func (d *Dispatcher) SendOnce(ctx context.Context, to []string, message *Message) error {
+	if d.sender == nil {
+		return ErrMissingCredentials
+	}
+	if len(to) == 0 {
+		return &PermanentError{Err: errors.New("email has no recipients")}
+	}
+
+	if err := d.sender.Send(ctx, to, message); err != nil {
+		metrics.SMTPSends.Inc("failure")
+		return err
+	}
+	metrics.SMTPSends.Inc("success")
+	return nil
+}
+
+// AddDeadLetter adds a message that could not be sent to the dead-letter queue. The queue keeps only
+// the newest messages, so a provider outage cannot fill Redis.
+func (d *Dispatcher) AddDeadLetter(to []string, message *Message, sendErr error, attempts int) {
+	entry := DeadLetter{
+		To:       to,
+		Subject:  message.Subject,
+		HTMLBody: message.HTMLBody,
+		TextBody: message.TextBody,
+		Provider: d.provider,
+		Error:    sendErr.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now().Unix(),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		d.logger.Error("Failed to encode dead letter", "error", err)
+		return
+	}
+
+	// The caller's context may be what ended the send
+	if err := d.redisClient.LPushCapped(context.Background(), DEAD_LETTER_KEY, data, d.deadLetterLimit, DEAD_LETTER_TTL); err != nil {
+		d.logger.Error("Failed to store dead letter", "subject", message.Subject, "error", err)
+		return
+	}
+	metrics.EmailDeadLetters.Inc()
+}
+
+// DeadLetters returns up to limit emails from the dead-letter queue, newest first
+func (d *Dispatcher) DeadLetters(ctx context.Context, limit int64) ([]DeadLetter, error) {
+	if limit <= 0 {
+		return []DeadLetter{}, nil
+	}
+
+	entries, err := d.redisClient.LRange(ctx, DEAD_LETTER_KEY, 0, limit-1)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(entries))
+	for _, entry := range entries {
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(entry), &letter); err != nil {
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// RetryDeadLetters resends the emails in the dead-letter queue, oldest first, and returns how many
+// were sent. Emails that fail again go back to the queue; entries that cannot be decoded are dropped.
+func (d *Dispatcher) RetryDeadLetters(ctx context.Context) (int, error) {
+	length, err := d.redisClient.LLen(ctx, DEAD_LETTER_KEY)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	// Only the entries queued before the retry started are taken, so failures are not retried again
+	for i := int64(0); i < length; i++ {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+
+		entry, err := d.redisClient.RPop(ctx, DEAD_LETTER_KEY)
+		if err != nil {
+			return sent, err
+		}
+		if entry == "" {
+			break
+		}
+
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(entry), &letter); err != nil {
+			d.logger.Warn("Dropping undecodable dead letter", "error", err)
+			continue
+		}
+
+		message := &Message{Subject: letter.Subject, HTMLBody: letter.HTMLBody, TextBody: letter.TextBody}
+		if err := d.SendOnce(ctx, letter.To, message); err != nil {
+			d.AddDeadLetter(letter.To, message, err, letter.Attempts+1)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
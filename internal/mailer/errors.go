@@ -0,0 +1,31 @@
+package mailer
+
+import "errors"
+
+// Common errors
+var (
+	ErrUnknownProvider    = errors.New("Unknown email provider")
+	ErrMissingCredentials = errors.New("Email provider credentials are incomplete")
+	ErrDeliveryFailed     = errors.New("Failed to send email")
+)
+
+// PermanentError marks a failed send that retrying cannot fix, like a rejected recipient
+type PermanentError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the provider's error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// isPermanent reports whether a send failed for good
+func isPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,267 @@
+// Package mailer renders the emails the API sends from embedded HTML templates. Texts come from a
+// message catalog per locale, and the plain text part of each email is generated from its HTML.
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"path"
+	"strings"
+	"time"
+)
+
+// Email templates, named after the intent they are sent for
+const (
+	TEMPLATE_SIGNUP         = "signup"
+	TEMPLATE_PASSWORD_RESET = "password-reset"
+	TEMPLATE_TWO_FACTOR     = "2fa"
+	TEMPLATE_SHARE_INVITE   = "share-invite"
+	TEMPLATE_QUOTA_WARNING  = "quota-warning"
+	TEMPLATE_ACCOUNT_LOCKED = "account-locked"
+	TEMPLATE_NEW_SIGN_IN    = "new-sign-in"
+	TEMPLATE_EMAIL_CHANGE   = "email-change"
+	TEMPLATE_EMAIL_CHANGED  = "email-changed"
+)
+
+// DEFAULT_LOCALE is used for recipients without a language and for texts missing from their catalog
+const DEFAULT_LOCALE = "en"
+
+//go:embed templates/*.html locales/*.json
+var files embed.FS
+
+// Message is a rendered email
+type Message struct {
+	Subject  string
+	HTMLBody string
+	TextBody string
+}
+
+// VerificationData fills the signup, password reset, two-factor setup and email change templates
+type VerificationData struct {
+	Username string
+	URL      string        // Link that verifies the email address or continues the flow
+	Expiry   time.Duration // How long the link stays valid
+}
+
+// ShareInviteData fills the share invitation template
+type ShareInviteData struct {
+	InviterName   string
+	InvitationURL string
+}
+
+// QuotaWarningData fills the quota warning template
+type QuotaWarningData struct {
+	UsedBytes  int64
+	LimitBytes int64
+	StorageURL string // Page where storage is freed up or the plan upgraded
+}
+
+// AccountLockedData fills the account lockout template
+type AccountLockedData struct {
+	FailedAttempts   int
+	LockDuration     time.Duration
+	UnlockURL        string // Link that unlocks the account right away
+	PasswordResetURL string
+}
+
+// NewSignInData fills the suspicious sign-in template
+type NewSignInData struct {
+	DeviceName       string
+	Location         string // City and country, e.g. "Berlin, DE"
+	IPAddress        string
+	SignedInAt       string // Formatted for display
+	SessionsURL      string // Security settings listing the signed in sessions
+	PasswordResetURL string
+}
+
+// EmailChangedData fills the template that tells the previous address of an account about an email change
+type EmailChangedData struct {
+	NewEmail         string // Masked, as the previous address may no longer be the user's
+	ChangedAt        string // Formatted for display
+	PasswordResetURL string
+}
+
+// Renderer renders the embedded templates. It is safe for concurrent use.
+type Renderer struct {
+	templates map[string]*template.Template
+	catalogs  map[string]map[string]string // Texts by locale, then key
+}
+
+// New parses the embedded templates and message catalogs
+func New() (*Renderer, error) {
+	r := &Renderer{
+		templates: make(map[string]*template.Template),
+		catalogs:  make(map[string]map[string]string),
+	}
+
+	catalogFiles, err := files.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range catalogFiles {
+		data, err := files.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("failed to parse catalog %s: %w", file.Name(), err)
+		}
+		r.catalogs[strings.TrimSuffix(file.Name(), ".json")] = catalog
+	}
+	if _, ok := r.catalogs[DEFAULT_LOCALE]; !ok {
+		return nil, fmt.Errorf("catalog of default locale %s is missing", DEFAULT_LOCALE)
+	}
+
+	// Functions are bound to the recipient's locale when a template is rendered
+	placeholders := template.FuncMap{
+		"t":        func(key string, args ...any) string { return "" },
+		"duration": func(d time.Duration) string { return "" },
+		"size":     func(bytes int64) string { return "" },
+		"percent":  func(part, whole int64) int64 { return 0 },
+		"year":     func() int { return 0 },
+	}
+	for _, name := range []string{TEMPLATE_SIGNUP, TEMPLATE_PASSWORD_RESET, TEMPLATE_TWO_FACTOR, TEMPLATE_SHARE_INVITE, TEMPLATE_QUOTA_WARNING, TEMPLATE_ACCOUNT_LOCKED, TEMPLATE_NEW_SIGN_IN, TEMPLATE_EMAIL_CHANGE, TEMPLATE_EMAIL_CHANGED} {
+		tmpl, err := template.New("layout.html").Funcs(placeholders).ParseFS(files, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		r.templates[name] = tmpl
+	}
+
+	return r, nil
+}
+
+// Locales lists the locales templates can be rendered in
+func (r *Renderer) Locales() []string {
+	locales := make([]string, 0, len(r.catalogs))
+	for locale := range r.catalogs {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// Render renders a template in the locale closest to the given one, such as a user's language
+// preference. Regional variants like "de-AT" fall back to their language, anything else to English.
+func (r *Renderer) Render(name, locale string, data any) (*Message, error) {
+	base, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %s", name)
+	}
+
+	// Templates are never executed themselves, so they can always be cloned
+	tmpl, err := base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Funcs(r.funcs(r.resolveLocale(locale)))
+
+	var subject bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&body, "layout.html", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	textBody, err := htmlToText(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate text of %s: %w", name, err)
+	}
+
+	return &Message{
+		// The subject is escaped as HTML by the template; line breaks would let it inject mail headers
+		Subject:  strings.Join(strings.Fields(html.UnescapeString(subject.String())), " "),
+		HTMLBody: body.String(),
+		TextBody: textBody,
+	}, nil
+}
+
+// resolveLocale returns the catalog locale for a requested one
+func (r *Renderer) resolveLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if _, ok := r.catalogs[locale]; ok {
+		return locale
+	}
+	if language, _, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); found {
+		if _, ok := r.catalogs[language]; ok {
+			return language
+		}
+	}
+	return DEFAULT_LOCALE
+}
+
+// translate returns the text of a key in a locale, formatted with args. Keys missing from the
+// locale's catalog use the default locale's text.
+func (r *Renderer) translate(locale, key string, args ...any) string {
+	text, ok := r.catalogs[locale][key]
+	if !ok {
+		if text, ok = r.catalogs[DEFAULT_LOCALE][key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// funcs returns the template functions for a locale
+func (r *Renderer) funcs(locale string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...any) string {
+			return r.translate(locale, key, args...)
+		},
+		"duration": func(d time.Duration) string {
+			return r.formatDuration(locale, d)
+		},
+		"size": formatSize,
+		"percent": func(part, whole int64) int64 {
+			if whole <= 0 {
+				return 0
+			}
+			return part * 100 / whole
+		},
+		"year": func() int {
+			return time.Now().Year()
+		},
+	}
+}
+
+// formatDuration spells out a link lifetime in whole days, hours or minutes
+func (r *Renderer) formatDuration(locale string, d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return r.translate(locale, "duration.days", int(d.Hours()/24))
+	case d >= 24*time.Hour:
+		return r.translate(locale, "duration.day")
+	case d >= 2*time.Hour:
+		return r.translate(locale, "duration.hours", int(d.Hours()))
+	case d >= time.Hour:
+		return r.translate(locale, "duration.hour")
+	case d >= 2*time.Minute:
+		return r.translate(locale, "duration.minutes", int(d.Minutes()))
+	default:
+		return r.translate(locale, "duration.minute")
+	}
+}
+
+// formatSize formats a byte count with binary units, the way storage limits are sold
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTP"[exp])
+}
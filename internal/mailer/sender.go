@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"strings"
+
+	"cirrussync-api/pkg/config"
+)
+
+// Supported providers
+const (
+	PROVIDER_SMTP     = "smtp"
+	PROVIDER_SES      = "ses"
+	PROVIDER_SENDGRID = "sendgrid"
+)
+
+// Sender delivers rendered emails. Implementations must be safe for concurrent use.
+type Sender interface {
+	// Send delivers a message to the recipients. Failures that retrying cannot fix are
+	// returned as a *PermanentError.
+	Send(ctx context.Context, to []string, message *Message) error
+}
+
+// NewSender creates the sender of the provider selected in the configuration
+func NewSender(cfg *config.MailConfig) (Sender, error) {
+	switch strings.ToLower(cfg.EmailProvider) {
+	case "", PROVIDER_SMTP:
+		return NewSMTPSender(cfg)
+	case PROVIDER_SES:
+		return NewSESSender(cfg)
+	case PROVIDER_SENDGRID:
+		return NewSendGridSender(cfg)
+	}
+
+	return nil, ErrUnknownProvider
+}
@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"cirrussync-api/pkg/config"
+)
+
+// sendGridEndpoint is the SendGrid v3 API emails are sent through
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridSender sends emails through the SendGrid v3 Mail Send API
+type SendGridSender struct {
+	apiKey     string
+	from       string
+	httpClient *http.Client
+}
+
+// sendGridAddress is an email address in SendGrid requests
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+// sendGridContent is one body of a SendGrid message
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// sendGridRequest is the body of a Mail Send request
+type sendGridRequest struct {
+	Personalizations []struct {
+		To []sendGridAddress `json:"to"`
+	} `json:"personalizations"`
+	From    sendGridAddress   `json:"from"`
+	Subject string            `json:"subject"`
+	Content []sendGridContent `json:"content"`
+}
+
+// NewSendGridSender creates a SendGrid sender, failing without an API key
+func NewSendGridSender(cfg *config.MailConfig) (*SendGridSender, error) {
+	if cfg.SendGridAPIKey == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	return &SendGridSender{
+		apiKey:     cfg.SendGridAPIKey,
+		from:       cfg.FromEmail,
+		httpClient: &http.Client{Timeout: cfg.EmailTimeout},
+	}, nil
+}
+
+// Send sends the message with one Mail Send request. Rate limiting and server errors can be
+// retried; any other rejection is permanent.
+func (s *SendGridSender) Send(ctx context.Context, to []string, message *Message) error {
+	request := sendGridRequest{
+		From:    sendGridAddress{Email: s.from},
+		Subject: message.Subject,
+		// SendGrid requires the plain text body before the HTML one
+		Content: []sendGridContent{
+			{Type: "text/plain", Value: message.TextBody},
+			{Type: "text/html", Value: message.HTMLBody},
+		},
+	}
+	request.Personalizations = make([]struct {
+		To []sendGridAddress `json:"to"`
+	}, 1)
+	for _, addr := range to {
+		request.Personalizations[0].To = append(request.Personalizations[0].To, sendGridAddress{Email: addr})
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDeliveryFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// SendGrid explains rejections in the body; keep it short for the logs
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("%w: sendgrid responded with status %d: %s", ErrDeliveryFailed, resp.StatusCode, strings.TrimSpace(string(detail)))
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return &PermanentError{Err: err}
+		}
+		return err
+	}
+
+	return nil
+}
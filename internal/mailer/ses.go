@@ -0,0 +1,87 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+
+	"cirrussync-api/pkg/config"
+)
+
+// permanentSESErrors are SES error codes that retrying the same message cannot fix
+var permanentSESErrors = map[string]bool{
+	ses.ErrCodeMessageRejected:                       true,
+	ses.ErrCodeMailFromDomainNotVerifiedException:    true,
+	ses.ErrCodeConfigurationSetDoesNotExistException: true,
+	ses.ErrCodeAccountSendingPausedException:         true,
+}
+
+// SESSender sends emails through the Amazon SES API
+type SESSender struct {
+	client           *ses.SES
+	from             string
+	configurationSet string
+}
+
+// NewSESSender creates an SES sender for the configured region. Without access keys the default
+// AWS credential chain is used, such as the role of the instance.
+func NewSESSender(cfg *config.MailConfig) (*SESSender, error) {
+	if cfg.SESRegion == "" || (cfg.SESAccessKeyID == "") != (cfg.SESSecretAccessKey == "") {
+		return nil, ErrMissingCredentials
+	}
+
+	awsConfig := &aws.Config{Region: aws.String(cfg.SESRegion)}
+	if cfg.SESAccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(cfg.SESAccessKeyID, cfg.SESSecretAccessKey, "")
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &SESSender{
+		client:           ses.New(sess),
+		from:             cfg.FromEmail,
+		configurationSet: cfg.SESConfigurationSet,
+	}, nil
+}
+
+// Send sends the message with the SES SendEmail API
+func (s *SESSender) Send(ctx context.Context, to []string, message *Message) error {
+	input := &ses.SendEmailInput{
+		Source:      aws.String(s.from),
+		Destination: &ses.Destination{ToAddresses: aws.StringSlice(to)},
+		Message: &ses.Message{
+			Subject: sesContent(message.Subject),
+			Body: &ses.Body{
+				Text: sesContent(message.TextBody),
+				Html: sesContent(message.HTMLBody),
+			},
+		},
+	}
+	if s.configurationSet != "" {
+		input.ConfigurationSetName = aws.String(s.configurationSet)
+	}
+
+	if _, err := s.client.SendEmailWithContext(ctx, input); err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && permanentSESErrors[awsErr.Code()] {
+			return &PermanentError{Err: fmt.Errorf("%w: %v", ErrDeliveryFailed, err)}
+		}
+		return fmt.Errorf("%w: %v", ErrDeliveryFailed, err)
+	}
+
+	return nil
+}
+
+// sesContent wraps UTF-8 text for the SES API
+func sesContent(data string) *ses.Content {
+	return &ses.Content{Charset: aws.String("UTF-8"), Data: aws.String(data)}
+}
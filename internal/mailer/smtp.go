@@ -0,0 +1,271 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/metrics"
+)
+
+const (
+	// SMTP_POOL_SIZE is how many idle connections the SMTP sender keeps open
+	SMTP_POOL_SIZE = 5
+
+	// smtpPoolRefreshInterval is how often the pool is topped up after connections were dropped
+	smtpPoolRefreshInterval = 5 * time.Minute
+)
+
+var (
+	// The pool gauges are registered once and report the newest pool
+	smtpPoolMetricsOnce sync.Once
+	currentSMTPPool     atomic.Pointer[smtpPool]
+)
+
+// SMTPSender sends emails through an SMTP server, reusing pooled connections
+type SMTPSender struct {
+	from string
+	pool *smtpPool
+}
+
+// smtpPool keeps authenticated connections to the SMTP server ready for use
+type smtpPool struct {
+	host      string
+	port      int
+	username  string
+	password  string
+	timeout   time.Duration
+	available chan *smtp.Client
+}
+
+// NewSMTPSender creates an SMTP sender and starts opening its pooled connections
+func NewSMTPSender(cfg *config.MailConfig) (*SMTPSender, error) {
+	if cfg.SMTPHost == "" || cfg.SMTPPort == 0 {
+		return nil, ErrMissingCredentials
+	}
+
+	pool := &smtpPool{
+		host:      cfg.SMTPHost,
+		port:      cfg.SMTPPort,
+		username:  cfg.SMTPUsername,
+		password:  cfg.SMTPPassword,
+		timeout:   cfg.EmailTimeout,
+		available: make(chan *smtp.Client, SMTP_POOL_SIZE),
+	}
+	go pool.maintain()
+
+	currentSMTPPool.Store(pool)
+	smtpPoolMetricsOnce.Do(registerSMTPPoolMetrics)
+
+	return &SMTPSender{from: cfg.FromEmail, pool: pool}, nil
+}
+
+// registerSMTPPoolMetrics exposes the pool's idle connections and size as gauges
+func registerSMTPPoolMetrics() {
+	metrics.Default.NewGaugeFunc("smtp_pool_idle_connections", "Open SMTP connections waiting in the pool", func() float64 {
+		return float64(len(currentSMTPPool.Load().available))
+	})
+	metrics.Default.NewGaugeFunc("smtp_pool_capacity", "Connections the SMTP pool keeps open", func() float64 {
+		return float64(cap(currentSMTPPool.Load().available))
+	})
+}
+
+// Send writes the message to a pooled connection. Connections that fail mid-message are closed
+// rather than returned to the pool, since their state is unknown.
+func (s *SMTPSender) Send(ctx context.Context, to []string, message *Message) error {
+	data, err := buildMIMEMessage(s.from, to, message)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	client, err := s.pool.get()
+	if err != nil {
+		return err
+	}
+
+	// Idle connections may have been closed by the server since they were pooled
+	if err := client.Reset(); err != nil {
+		client.Close()
+		if client, err = s.pool.dial(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.deliver(client, to, data); err != nil {
+		client.Close()
+		return classifySMTPError(err)
+	}
+
+	s.pool.put(client)
+	return nil
+}
+
+// deliver runs one mail transaction on a connection
+func (s *SMTPSender) deliver(client *smtp.Client, to []string, data []byte) error {
+	if err := client.Mail(s.from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("failed to set recipient %s: %w", addr, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to open data writer: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write email body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close data writer: %w", err)
+	}
+
+	return nil
+}
+
+// classifySMTPError marks permanent failures: replies in the 5xx range, like unknown mailboxes or
+// rejected content. Connection failures and 4xx replies may succeed when retried.
+func classifySMTPError(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// get takes an idle connection from the pool, or opens a new one when none is idle
+func (p *smtpPool) get() (*smtp.Client, error) {
+	select {
+	case client := <-p.available:
+		return client, nil
+	default:
+		return p.dial()
+	}
+}
+
+// put returns a working connection to the pool, closing it when the pool is full
+func (p *smtpPool) put(client *smtp.Client) {
+	select {
+	case p.available <- client:
+	default:
+		client.Close()
+	}
+}
+
+// dial connects and authenticates to the SMTP server, counting connections that fail
+func (p *smtpPool) dial() (*smtp.Client, error) {
+	client, err := p.connect()
+	if err != nil {
+		metrics.SMTPConnectionFailures.Inc()
+		return nil, fmt.Errorf("%w: %v", ErrDeliveryFailed, err)
+	}
+	return client, nil
+}
+
+// connect opens a connection, upgrades it to TLS when the server offers it and authenticates
+func (p *smtpPool) connect() (*smtp.Client, error) {
+	addr := net.JoinHostPort(p.host, strconv.Itoa(p.port))
+	conn, err := net.DialTimeout("tcp", addr, p.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, p.host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to greet SMTP server: %w", err)
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: p.host}); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	// Local relays like development mail catchers accept mail without credentials
+	if p.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", p.username, p.password, p.host)); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// maintain keeps the pool at least half full, so sends rarely wait for a connection to open
+func (p *smtpPool) maintain() {
+	ticker := time.NewTicker(smtpPoolRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if idle := len(p.available); idle < cap(p.available)/2+1 {
+			for i := idle; i < cap(p.available); i++ {
+				client, err := p.dial()
+				if err != nil {
+					break
+				}
+				p.put(client)
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+// buildMIMEMessage composes a multipart/alternative message with a plain text and an HTML part.
+// Both are quoted-printable encoded, so localized texts survive 7-bit relays.
+func buildMIMEMessage(from string, to []string, message *Message) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", message.TextBody},
+		{"text/html; charset=UTF-8", message.HTMLBody},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(w)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&data, "From: %s\r\n", from)
+	fmt.Fprintf(&data, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", message.Subject))
+	fmt.Fprintf(&data, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&data, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&data, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	data.Write(body.Bytes())
+
+	return data.Bytes(), nil
+}
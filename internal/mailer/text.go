@@ -0,0 +1,136 @@
+package mailer
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockElements start on a line of their own in the text version
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Table: true, atom.Tr: true,
+}
+
+// htmlToText generates the plain text version of an email from its HTML. Headings and paragraphs
+// become lines separated by a blank one, and links are followed by their target unless the link
+// text already is the target.
+func htmlToText(body string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	var w textWriter
+	w.walk(doc)
+	return w.String(), nil
+}
+
+// textWriter collects the text of a document, collapsing whitespace the way a browser does
+type textWriter struct {
+	lines   []string
+	current strings.Builder
+}
+
+// walk writes the text below a node
+func (w *textWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.write(n.Data)
+		return
+	case html.ElementNode:
+		switch n.DataAtom {
+		case atom.Head, atom.Style, atom.Script, atom.Img:
+			return
+		case atom.Br:
+			w.newline()
+			return
+		case atom.Li:
+			w.paragraph()
+			w.write("- ")
+		case atom.A:
+			w.link(n)
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+	if block {
+		w.paragraph()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		w.walk(child)
+	}
+	if block {
+		w.paragraph()
+	}
+}
+
+// link writes a link's text followed by its target
+func (w *textWriter) link(n *html.Node) {
+	var text textWriter
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		text.walk(child)
+	}
+	label := strings.Join(strings.Fields(text.String()), " ")
+
+	href := ""
+	for _, attr := range n.Attr {
+		if attr.Key == "href" {
+			href = attr.Val
+		}
+	}
+
+	switch {
+	case href == "" || href == label:
+		w.write(label)
+	case label == "":
+		w.write(href)
+	default:
+		w.write(label + ": " + href)
+	}
+}
+
+// write appends text to the current line, collapsing runs of whitespace into one space
+func (w *textWriter) write(text string) {
+	if text == "" {
+		return
+	}
+	leading := strings.TrimLeft(text, " \t\r\n") != text
+	trailing := strings.TrimRight(text, " \t\r\n") != text
+	words := strings.Fields(text)
+
+	if leading && w.current.Len() > 0 {
+		w.current.WriteByte(' ')
+	}
+	w.current.WriteString(strings.Join(words, " "))
+	if trailing && len(words) > 0 {
+		w.current.WriteByte(' ')
+	}
+}
+
+// newline ends the current line
+func (w *textWriter) newline() {
+	w.lines = append(w.lines, strings.TrimSpace(w.current.String()))
+	w.current.Reset()
+}
+
+// paragraph ends the current line and leaves one blank line before the next text
+func (w *textWriter) paragraph() {
+	if strings.TrimSpace(w.current.String()) != "" {
+		w.newline()
+	}
+	if len(w.lines) > 0 && w.lines[len(w.lines)-1] != "" {
+		w.lines = append(w.lines, "")
+	}
+}
+
+// String returns the collected text without surrounding blank lines
+func (w *textWriter) String() string {
+	lines := w.lines
+	if strings.TrimSpace(w.current.String()) != "" {
+		lines = append(lines, strings.TrimSpace(w.current.String()))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
@@ -0,0 +1,134 @@
+package mfa
+
+import (
+	"fmt"
+	"time"
+)
+
+// SendAccountDeletionEmail tells a user when their account is going to be deleted. It is sent when the
+// deletion is requested and again as it approaches, each time with a link that cancels it.
+func (s *Service) SendAccountDeletionEmail(email string, scheduledAt int64, cancelToken string) error {
+	email = NormalizeEmail(email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	cancelURL := fmt.Sprintf("%s/account/cancel-deletion?token=%s", s.config.BaseURL, cancelToken)
+	deletionDate := time.Unix(scheduledAt, 0).UTC().Format("January 2, 2006 at 15:04 UTC")
+	subject, htmlBody, textBody := s.getAccountDeletionEmailContent(deletionDate, cancelURL)
+
+	return s.sendEmailFast([]string{email}, subject, htmlBody, textBody)
+}
+
+// getAccountDeletionEmailContent returns the account deletion notice email content (subject, HTML and text)
+func (s *Service) getAccountDeletionEmailContent(deletionDate, cancelURL string) (string, string, string) {
+	subject := "Your account is scheduled for deletion - CirrusSync"
+
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Account Deletion Scheduled</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            margin: 0;
+            padding: 0;
+            background-color: #f9f9f9;
+        }
+        .container {
+            max-width: 600px;
+            margin: 20px auto;
+            background-color: #ffffff;
+            border-radius: 8px;
+            overflow: hidden;
+            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
+        }
+        .header {
+            background-color: #ef4444;
+            color: white;
+            padding: 20px;
+            text-align: center;
+        }
+        .content {
+            padding: 20px 30px;
+        }
+        .footer {
+            background-color: #f5f5f5;
+            padding: 15px;
+            text-align: center;
+            font-size: 12px;
+            color: #666;
+        }
+        .button {
+            display: inline-block;
+            background-color: #10b981;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            margin: 20px 0;
+            font-weight: 500;
+            text-align: center;
+        }
+        .link {
+            word-break: break-all;
+            color: #10b981;
+        }
+        .logo {
+            max-width: 150px;
+            margin-bottom: 10px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
+            <h1>Account Deletion Scheduled</h1>
+        </div>
+        <div class="content">
+            <p>Your CirrusSync account is scheduled for deletion. Until then you cannot sign in.</p>
+            <p>On <strong>%s</strong> your files, your subscription and your personal data will be deleted permanently. If you change your mind before then, cancel the deletion:</p>
+
+            <div style="text-align: center;">
+                <a href="%s" class="button">Keep My Account</a>
+            </div>
+
+            <p>Or copy and paste the following URL into your browser:</p>
+            <p class="link">%s</p>
+
+            <p>If you did not request this deletion, cancel it and change your password right away.</p>
+
+            <p>Thank you,<br>The CirrusSync Team</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
+            <p>This is an automated message, please do not reply to this email.</p>
+        </div>
+    </div>
+</body>
+</html>
+`, deletionDate, cancelURL, cancelURL)
+
+	textBody := fmt.Sprintf(`
+Hello,
+
+Your CirrusSync account is scheduled for deletion. Until then you cannot sign in.
+
+On %s your files, your subscription and your personal data will be deleted permanently. If you change your mind before then, cancel the deletion:
+
+%s
+
+If you did not request this deletion, cancel it and change your password right away.
+
+Thank you,
+The CirrusSync Team
+`, deletionDate, cancelURL)
+
+	return subject, htmlBody, textBody
+}
@@ -0,0 +1,33 @@
+package mfa
+
+import (
+	"cirrussync-api/internal/mailer"
+	"fmt"
+	"time"
+)
+
+// SendAccountLockedEmail tells a user that their account was locked after failed logins. The link
+// unlocks it right away, for when the failures were the user's own.
+func (s *Service) SendAccountLockedEmail(userID string, failedAttempts int, lockDuration time.Duration, unlockToken string) error {
+	user, err := s.repo.FindUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	email := NormalizeEmail(user.Email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	message, err := s.templates.Render(mailer.TEMPLATE_ACCOUNT_LOCKED, s.recipientLocale(email), mailer.AccountLockedData{
+		FailedAttempts:   failedAttempts,
+		LockDuration:     lockDuration,
+		UnlockURL:        fmt.Sprintf("%s/account/unlock?token=%s", s.config.BaseURL, unlockToken),
+		PasswordResetURL: fmt.Sprintf("%s/reset-password", s.config.BaseURL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmailFast([]string{email}, message.Subject, message.HTMLBody, message.TextBody)
+}
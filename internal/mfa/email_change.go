@@ -0,0 +1,48 @@
+package mfa
+
+import (
+	"cirrussync-api/internal/mailer"
+	"context"
+	"fmt"
+	"time"
+)
+
+// EMAIL_INTENT_EMAIL_CHANGE is the intent of the verification email sent to the new address of an email change
+const EMAIL_INTENT_EMAIL_CHANGE = "email-change"
+
+// SendEmailChangeVerification emails a link that confirms the change of an account's email address to
+// the new address, so only its owner can complete the change
+func (s *Service) SendEmailChangeVerification(ctx context.Context, newEmail, username string) (*EmailVerificationResult, error) {
+	return s.SendVerificationEmail(ctx, newEmail, username, EMAIL_INTENT_EMAIL_CHANGE)
+}
+
+// EmailChangeRequest returns the new address and the username of the account an email change token was
+// sent for. The token stays valid until ConsumeEmailChangeToken uses it up.
+func (s *Service) EmailChangeRequest(ctx context.Context, token string) (string, string, error) {
+	return s.intentToken(ctx, token, EMAIL_INTENT_EMAIL_CHANGE)
+}
+
+// ConsumeEmailChangeToken uses up an email change token so it cannot change the address again
+func (s *Service) ConsumeEmailChangeToken(ctx context.Context, token string) error {
+	return s.consumeEmailToken(ctx, token)
+}
+
+// SendEmailChangedEmail tells the previous address of an account that the account's email address was
+// changed, in case the change was not made by the owner of that address
+func (s *Service) SendEmailChangedEmail(previousEmail, newEmail string, changedAt int64) error {
+	previousEmail = NormalizeEmail(previousEmail)
+	if !ValidateEmail(previousEmail) {
+		return ErrInvalidEmail
+	}
+
+	message, err := s.templates.Render(mailer.TEMPLATE_EMAIL_CHANGED, s.recipientLocale(previousEmail), mailer.EmailChangedData{
+		NewEmail:         maskEmail(NormalizeEmail(newEmail)),
+		ChangedAt:        time.Unix(changedAt, 0).UTC().Format("January 2, 2006 at 15:04 UTC"),
+		PasswordResetURL: fmt.Sprintf("%s/reset-password", s.config.BaseURL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmailFast([]string{previousEmail}, message.Subject, message.HTMLBody, message.TextBody)
+}
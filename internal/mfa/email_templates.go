@@ -0,0 +1,201 @@
+package mfa
+
+import (
+	"cirrussync-api/internal/mailer"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Email templates that can be previewed and test-sent by admins
+const (
+	EMAIL_TEMPLATE_SIGNUP              = "signup"
+	EMAIL_TEMPLATE_PASSWORD_RESET      = "password-reset"
+	EMAIL_TEMPLATE_TWO_FACTOR          = "2fa"
+	EMAIL_TEMPLATE_LOGIN_CODE          = "login-code"
+	EMAIL_TEMPLATE_SHARE_INVITATION    = "share-invitation"
+	EMAIL_TEMPLATE_MEMBERSHIP_APPROVAL = "membership-approval"
+	EMAIL_TEMPLATE_SHARE_EXPIRY        = "share-expiry"
+	EMAIL_TEMPLATE_STORAGE_CORRUPTION  = "storage-corruption"
+	EMAIL_TEMPLATE_SECURITY_REPORT     = "security-report"
+	EMAIL_TEMPLATE_ACCOUNT_DELETION    = "account-deletion"
+	EMAIL_TEMPLATE_QUOTA_WARNING       = "quota-warning"
+	EMAIL_TEMPLATE_ACCOUNT_LOCKED      = "account-locked"
+	EMAIL_TEMPLATE_NEW_SIGN_IN         = "new-sign-in"
+	EMAIL_TEMPLATE_EMAIL_CHANGE        = "email-change"
+	EMAIL_TEMPLATE_EMAIL_CHANGED       = "email-changed"
+)
+
+// EmailTemplates lists every template in the order they are shown to admins
+var EmailTemplates = []string{
+	EMAIL_TEMPLATE_SIGNUP,
+	EMAIL_TEMPLATE_PASSWORD_RESET,
+	EMAIL_TEMPLATE_TWO_FACTOR,
+	EMAIL_TEMPLATE_LOGIN_CODE,
+	EMAIL_TEMPLATE_SHARE_INVITATION,
+	EMAIL_TEMPLATE_MEMBERSHIP_APPROVAL,
+	EMAIL_TEMPLATE_SHARE_EXPIRY,
+	EMAIL_TEMPLATE_STORAGE_CORRUPTION,
+	EMAIL_TEMPLATE_SECURITY_REPORT,
+	EMAIL_TEMPLATE_ACCOUNT_DELETION,
+	EMAIL_TEMPLATE_QUOTA_WARNING,
+	EMAIL_TEMPLATE_ACCOUNT_LOCKED,
+	EMAIL_TEMPLATE_NEW_SIGN_IN,
+	EMAIL_TEMPLATE_EMAIL_CHANGE,
+	EMAIL_TEMPLATE_EMAIL_CHANGED,
+}
+
+// testEmailSubjectPrefix marks test sends so they are not mistaken for real notices
+const testEmailSubjectPrefix = "[Test] "
+
+// RenderedEmail is an email template rendered with sample data
+type RenderedEmail struct {
+	Template string
+	Subject  string
+	HTMLBody string
+	TextBody string
+}
+
+// RenderEmailTemplate renders a template with sample data. Links point at the configured web app
+// but carry placeholder IDs and tokens, so nothing in a preview acts on a real account. Templates of
+// the mailer package are rendered in the locale, the others are English only.
+func (s *Service) RenderEmailTemplate(template, locale string) (*RenderedEmail, error) {
+	const (
+		sampleUsername = "Alex Example"
+		sampleToken    = "sample-token"
+		sampleID       = "sample-id"
+	)
+	expiry := s.formatDuration(s.config.TokenExpiry)
+	expiresAt := time.Now().Add(7 * 24 * time.Hour).UTC().Format("January 2, 2006 at 15:04 UTC")
+
+	var subject, htmlBody, textBody string
+	var message *mailer.Message
+	var err error
+	switch template {
+	case EMAIL_TEMPLATE_SIGNUP, EMAIL_TEMPLATE_TWO_FACTOR:
+		message, err = s.templates.Render(template, locale, mailer.VerificationData{
+			Username: sampleUsername,
+			URL:      fmt.Sprintf("%s/verify?token=%s", s.config.BaseURL, sampleToken),
+			Expiry:   s.config.TokenExpiry,
+		})
+	case EMAIL_TEMPLATE_PASSWORD_RESET:
+		message, err = s.templates.Render(template, locale, mailer.VerificationData{
+			Username: sampleUsername,
+			URL:      fmt.Sprintf("%s/reset-password?token=%s", s.config.BaseURL, sampleToken),
+			Expiry:   s.config.TokenExpiry,
+		})
+	case EMAIL_TEMPLATE_SHARE_INVITATION:
+		message, err = s.templates.Render(mailer.TEMPLATE_SHARE_INVITE, locale, mailer.ShareInviteData{
+			InviterName:   sampleUsername,
+			InvitationURL: fmt.Sprintf("%s/drive/invitations/%s", s.config.BaseURL, sampleID),
+		})
+	case EMAIL_TEMPLATE_QUOTA_WARNING:
+		message, err = s.templates.Render(mailer.TEMPLATE_QUOTA_WARNING, locale, mailer.QuotaWarningData{
+			UsedBytes:  2900 * 1024 * 1024,
+			LimitBytes: 3 * 1024 * 1024 * 1024,
+			StorageURL: fmt.Sprintf("%s/settings/storage", s.config.BaseURL),
+		})
+	case EMAIL_TEMPLATE_ACCOUNT_LOCKED:
+		message, err = s.templates.Render(mailer.TEMPLATE_ACCOUNT_LOCKED, locale, mailer.AccountLockedData{
+			FailedAttempts:   10,
+			LockDuration:     30 * time.Minute,
+			UnlockURL:        fmt.Sprintf("%s/account/unlock?token=%s", s.config.BaseURL, sampleToken),
+			PasswordResetURL: fmt.Sprintf("%s/reset-password", s.config.BaseURL),
+		})
+	case EMAIL_TEMPLATE_NEW_SIGN_IN:
+		message, err = s.templates.Render(mailer.TEMPLATE_NEW_SIGN_IN, locale, mailer.NewSignInData{
+			DeviceName:       "CirrusSync Desktop",
+			Location:         "Berlin, DE",
+			IPAddress:        "203.0.113.42",
+			SignedInAt:       time.Now().UTC().Format("January 2, 2006 at 15:04 UTC"),
+			SessionsURL:      fmt.Sprintf("%s/settings/security/sessions", s.config.BaseURL),
+			PasswordResetURL: fmt.Sprintf("%s/reset-password", s.config.BaseURL),
+		})
+	case EMAIL_TEMPLATE_EMAIL_CHANGE:
+		message, err = s.templates.Render(mailer.TEMPLATE_EMAIL_CHANGE, locale, mailer.VerificationData{
+			Username: sampleUsername,
+			URL:      fmt.Sprintf("%s/settings/account/email?token=%s", s.config.BaseURL, sampleToken),
+			Expiry:   s.config.TokenExpiry,
+		})
+	case EMAIL_TEMPLATE_EMAIL_CHANGED:
+		message, err = s.templates.Render(mailer.TEMPLATE_EMAIL_CHANGED, locale, mailer.EmailChangedData{
+			NewEmail:         maskEmail("alex@example.com"),
+			ChangedAt:        time.Now().UTC().Format("January 2, 2006 at 15:04 UTC"),
+			PasswordResetURL: fmt.Sprintf("%s/reset-password", s.config.BaseURL),
+		})
+	case EMAIL_TEMPLATE_LOGIN_CODE:
+		subject, htmlBody, textBody = s.getLoginCodeEmailContent(sampleUsername, "123456", expiry)
+	case EMAIL_TEMPLATE_MEMBERSHIP_APPROVAL:
+		approvalsURL := fmt.Sprintf("%s/drive/shares/%s/approvals", s.config.BaseURL, sampleID)
+		subject, htmlBody, textBody = s.getApprovalEmailContent(sampleUsername, approvalsURL)
+	case EMAIL_TEMPLATE_SHARE_EXPIRY:
+		shareURL := fmt.Sprintf("%s/drive/shares/%s", s.config.BaseURL, sampleID)
+		subject, htmlBody, textBody = s.getShareExpiryEmailContent(expiresAt, shareURL)
+	case EMAIL_TEMPLATE_STORAGE_CORRUPTION:
+		subject, htmlBody, textBody = s.getStorageCorruptionEmailContent(3, fmt.Sprintf("%s/drive", s.config.BaseURL))
+	case EMAIL_TEMPLATE_SECURITY_REPORT:
+		reportURL := fmt.Sprintf("%s/settings/security/exports/%s", s.config.BaseURL, sampleID)
+		subject, htmlBody, textBody = s.getSecurityReportEmailContent(expiresAt, reportURL)
+	case EMAIL_TEMPLATE_ACCOUNT_DELETION:
+		cancelURL := fmt.Sprintf("%s/account/cancel-deletion?token=%s", s.config.BaseURL, sampleToken)
+		subject, htmlBody, textBody = s.getAccountDeletionEmailContent(expiresAt, cancelURL)
+	default:
+		return nil, ErrUnknownEmailTemplate
+	}
+	if err != nil {
+		return nil, err
+	}
+	if message != nil {
+		subject, htmlBody, textBody = message.Subject, message.HTMLBody, message.TextBody
+	}
+
+	return &RenderedEmail{
+		Template: template,
+		Subject:  subject,
+		HTMLBody: htmlBody,
+		TextBody: textBody,
+	}, nil
+}
+
+// SendTestEmail renders a template with sample data in the locale and sends it to the address through
+// the configured provider. Provider errors are returned so deliverability problems show up right away.
+func (s *Service) SendTestEmail(template, locale, email string) (*RenderedEmail, error) {
+	email = NormalizeEmail(email)
+	if !ValidateEmail(email) {
+		return nil, ErrInvalidEmail
+	}
+
+	rendered, err := s.RenderEmailTemplate(template, locale)
+	if err != nil {
+		return nil, err
+	}
+	rendered.Subject = testEmailSubjectPrefix + rendered.Subject
+
+	// Test sends are made once and not dead-lettered, so the provider's error is reported as is
+	if s.dispatcher == nil {
+		return nil, &EmailSendError{Email: email, Err: ErrEmailNotConfigured}
+	}
+	message := &mailer.Message{Subject: rendered.Subject, HTMLBody: rendered.HTMLBody, TextBody: rendered.TextBody}
+	if err := s.dispatcher.SendOnce(context.Background(), []string{email}, message); err != nil {
+		s.logger.Error("Failed to send test email", "template", template, "error", err)
+		return nil, &EmailSendError{Email: email, Err: err}
+	}
+
+	return rendered, nil
+}
+
+// EmailDeadLetters returns up to limit emails that could not be sent, newest first
+func (s *Service) EmailDeadLetters(ctx context.Context, limit int64) ([]mailer.DeadLetter, error) {
+	if s.dispatcher == nil {
+		return nil, ErrEmailNotConfigured
+	}
+	return s.dispatcher.DeadLetters(ctx, limit)
+}
+
+// RetryEmailDeadLetters resends the emails that could not be sent and returns how many were sent
+func (s *Service) RetryEmailDeadLetters(ctx context.Context) (int, error) {
+	if s.dispatcher == nil {
+		return 0, ErrEmailNotConfigured
+	}
+	return s.dispatcher.RetryDeadLetters(ctx)
+}
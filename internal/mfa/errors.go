@@ -13,15 +13,16 @@ var (
 	ErrOperationFailed = errors.New("Operation failed")
 
 	// Email verification errors
-	ErrInvalidEmail      = errors.New("Invalid email address")
-	ErrInvalidToken      = errors.New("Invalid verification token")
-	ErrExpiredToken      = errors.New("Verification token has expired")
-	ErrEmailExists       = errors.New("A account with this email already exists")
-	ErrUsernameExists    = errors.New("Username is taken. Please try another one.")
-	ErrEmailAlreadySent  = errors.New("Verification email already sent to this address")
-	ErrFailedToSendEmail = errors.New("Failed to send verification email")
-	ErrEmailNotVerified  = errors.New("Email address is not verified")
-	ErrRateLimited       = errors.New("CirrusSync detected abuse, you are being rate limited. Please visit https://cirrussync.me/abuse for more information.")
+	ErrInvalidEmail       = errors.New("Invalid email address")
+	ErrInvalidToken       = errors.New("Invalid verification token")
+	ErrExpiredToken       = errors.New("Verification token has expired")
+	ErrEmailExists        = errors.New("A account with this email already exists")
+	ErrUsernameExists     = errors.New("Username is taken. Please try another one.")
+	ErrEmailAlreadySent   = errors.New("Verification email already sent to this address")
+	ErrFailedToSendEmail  = errors.New("Failed to send verification email")
+	ErrEmailNotVerified   = errors.New("Email address is not verified")
+	ErrRateLimited        = errors.New("CirrusSync detected abuse, you are being rate limited. Please visit https://cirrussync.me/abuse for more information.")
+	ErrEmailNotConfigured = errors.New("Email sending is not configured")
 
 	// TOTP errors
 	ErrTOTPAlreadyEnabled      = errors.New("TOTP is already enabled for this user")
@@ -31,6 +32,38 @@ var (
 	ErrTOTPSetupInProgress     = errors.New("TOTP setup is already in progress")
 	ErrTOTPOperationInProgress = errors.New("TOTP operation is already in progress")
 
+	// Passkey errors
+	ErrInvalidPasskeyResponse     = errors.New("Invalid passkey response")
+	ErrPasskeyChallengeExpired    = errors.New("Passkey challenge has expired, please try again")
+	ErrPasskeyChallengeMismatch   = errors.New("Passkey response does not match the issued challenge")
+	ErrPasskeyOriginMismatch      = errors.New("Passkey was created for a different site")
+	ErrUnsupportedPasskey         = errors.New("Passkey algorithm is not supported")
+	ErrInvalidPasskeySignature    = errors.New("Invalid passkey signature")
+	ErrPasskeyCloned              = errors.New("Passkey signature counter went backwards, the authenticator may have been cloned")
+	ErrPasskeyNotFound            = errors.New("Passkey not found")
+	ErrPasskeyAlreadyRegistered   = errors.New("Passkey is already registered")
+	ErrPasskeyLimitReached        = errors.New("Maximum number of passkeys reached")
+	ErrPasskeyOperationInProgress = errors.New("Passkey operation is already in progress")
+	ErrMFAMethodUnavailable       = errors.New("MFA method is not set up for this user")
+
+	// SMS errors
+	ErrSMSUnavailable         = errors.New("SMS verification is not available")
+	ErrInvalidPhoneNumber     = errors.New("Phone number must be in international format, e.g. +14155552671")
+	ErrPhoneNumberInUse       = errors.New("Phone number is already used by another account")
+	ErrPhoneMFAAlreadyEnabled = errors.New("SMS verification is already enabled for this user")
+	ErrPhoneMFANotEnabled     = errors.New("SMS verification is not enabled for this user")
+	ErrInvalidSMSCode         = errors.New("Invalid SMS verification code")
+	ErrSMSCodeExpired         = errors.New("SMS verification code has expired, please request a new one")
+	ErrTooManySMSAttempts     = errors.New("Too many invalid SMS codes, please request a new one")
+	ErrFailedToSendSMS        = errors.New("Failed to send verification text message")
+
+	// Login email code errors
+	ErrInvalidEmailCode = errors.New("Invalid email verification code")
+	ErrEmailCodeExpired = errors.New("Email verification code has expired, please request a new one")
+
+	// Email template errors
+	ErrUnknownEmailTemplate = errors.New("Unknown email template")
+
 	// Redis errors
 	ErrRateLimitExceeded = errors.New("CirrusSync detected abuse, you are being rate limited. Please visit https://cirrussync.me/abuse for more information.")
 )
@@ -0,0 +1,423 @@
+package mfa
+
+import (
+	"cirrussync-api/internal/mailer"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// SendShareInvitationEmail notifies a user that they have been invited to a share.
+// The link opens the invitation in the web app, where it can be accepted or declined.
+func (s *Service) SendShareInvitationEmail(email, inviterName, invitationID string) error {
+	email = NormalizeEmail(email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	message, err := s.templates.Render(mailer.TEMPLATE_SHARE_INVITE, s.recipientLocale(email), mailer.ShareInviteData{
+		InviterName:   inviterName,
+		InvitationURL: fmt.Sprintf("%s/drive/invitations/%s", s.config.BaseURL, invitationID),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmailFast([]string{email}, message.Subject, message.HTMLBody, message.TextBody)
+}
+
+// SendMembershipApprovalEmail asks a share admin to approve a member who accepted an invitation.
+// The link opens the share's pending approvals in the web app.
+func (s *Service) SendMembershipApprovalEmail(email, memberName, shareID string) error {
+	email = NormalizeEmail(email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	approvalsURL := fmt.Sprintf("%s/drive/shares/%s/approvals", s.config.BaseURL, shareID)
+	subject, htmlBody, textBody := s.getApprovalEmailContent(memberName, approvalsURL)
+
+	return s.sendEmailFast([]string{email}, subject, htmlBody, textBody)
+}
+
+// getApprovalEmailContent returns the membership approval request email content (subject, HTML and text)
+func (s *Service) getApprovalEmailContent(memberName, approvalsURL string) (string, string, string) {
+	// Line breaks in the name would otherwise let it inject mail headers through the subject
+	memberName = strings.Join(strings.Fields(memberName), " ")
+	if memberName == "" {
+		memberName = "A new member"
+	}
+	subject := fmt.Sprintf("%s is waiting to join your shared folder - CirrusSync", memberName)
+
+	// Member names are user-controlled, so escape them before embedding in HTML
+	safeMember := html.EscapeString(memberName)
+
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Approval Request</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            margin: 0;
+            padding: 0;
+            background-color: #f9f9f9;
+        }
+        .container {
+            max-width: 600px;
+            margin: 20px auto;
+            background-color: #ffffff;
+            border-radius: 8px;
+            overflow: hidden;
+            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
+        }
+        .header {
+            background-color: #10b981;
+            color: white;
+            padding: 20px;
+            text-align: center;
+        }
+        .content {
+            padding: 20px 30px;
+        }
+        .footer {
+            background-color: #f5f5f5;
+            padding: 15px;
+            text-align: center;
+            font-size: 12px;
+            color: #666;
+        }
+        .button {
+            display: inline-block;
+            background-color: #10b981;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            margin: 20px 0;
+            font-weight: 500;
+            text-align: center;
+        }
+        .link {
+            word-break: break-all;
+            color: #10b981;
+        }
+        .logo {
+            max-width: 150px;
+            margin-bottom: 10px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
+            <h1>Approval Request</h1>
+        </div>
+        <div class="content">
+            <p><strong>%s</strong> accepted an invitation to a shared folder you administer on CirrusSync.</p>
+            <p>This folder requires an admin to approve new members before they get access. Review the request:</p>
+
+            <div style="text-align: center;">
+                <a href="%s" class="button">Review Request</a>
+            </div>
+
+            <p>Or copy and paste the following URL into your browser:</p>
+            <p class="link">%s</p>
+
+            <p>Until it is approved, the member cannot see the folder's contents.</p>
+
+            <p>Thank you,<br>The CirrusSync Team</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
+            <p>This is an automated message, please do not reply to this email.</p>
+        </div>
+    </div>
+</body>
+</html>
+`, safeMember, approvalsURL, approvalsURL)
+
+	textBody := fmt.Sprintf(`
+Hello,
+
+%s accepted an invitation to a shared folder you administer on CirrusSync.
+
+This folder requires an admin to approve new members before they get access. Review the request:
+
+%s
+
+Until it is approved, the member cannot see the folder's contents.
+
+Thank you,
+The CirrusSync Team
+`, memberName, approvalsURL)
+
+	return subject, htmlBody, textBody
+}
+
+// SendShareExpiryEmail tells a member that their access to a shared folder is about to end.
+// The link opens the share in the web app so anything needed can be saved before it expires.
+func (s *Service) SendShareExpiryEmail(email, shareID string, expiresAt int64) error {
+	email = NormalizeEmail(email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	shareURL := fmt.Sprintf("%s/drive/shares/%s", s.config.BaseURL, shareID)
+	expiry := time.Unix(expiresAt, 0).UTC().Format("January 2, 2006 at 15:04 UTC")
+	subject, htmlBody, textBody := s.getShareExpiryEmailContent(expiry, shareURL)
+
+	return s.sendEmailFast([]string{email}, subject, htmlBody, textBody)
+}
+
+// getShareExpiryEmailContent returns the share expiry notice email content (subject, HTML and text)
+func (s *Service) getShareExpiryEmailContent(expiry, shareURL string) (string, string, string) {
+	subject := "Your access to a shared folder is ending soon - CirrusSync"
+
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Share Expiring</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            margin: 0;
+            padding: 0;
+            background-color: #f9f9f9;
+        }
+        .container {
+            max-width: 600px;
+            margin: 20px auto;
+            background-color: #ffffff;
+            border-radius: 8px;
+            overflow: hidden;
+            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
+        }
+        .header {
+            background-color: #10b981;
+            color: white;
+            padding: 20px;
+            text-align: center;
+        }
+        .content {
+            padding: 20px 30px;
+        }
+        .footer {
+            background-color: #f5f5f5;
+            padding: 15px;
+            text-align: center;
+            font-size: 12px;
+            color: #666;
+        }
+        .button {
+            display: inline-block;
+            background-color: #10b981;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            margin: 20px 0;
+            font-weight: 500;
+            text-align: center;
+        }
+        .link {
+            word-break: break-all;
+            color: #10b981;
+        }
+        .logo {
+            max-width: 150px;
+            margin-bottom: 10px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
+            <h1>Share Expiring</h1>
+        </div>
+        <div class="content">
+            <p>A folder shared with you on CirrusSync expires on <strong>%s</strong>.</p>
+            <p>After that you will no longer be able to open it, and its public links stop working. Open the folder to save anything you still need:</p>
+
+            <div style="text-align: center;">
+                <a href="%s" class="button">Open Folder</a>
+            </div>
+
+            <p>Or copy and paste the following URL into your browser:</p>
+            <p class="link">%s</p>
+
+            <p>If the owner renews the share, your access continues without any action from you.</p>
+
+            <p>Thank you,<br>The CirrusSync Team</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
+            <p>This is an automated message, please do not reply to this email.</p>
+        </div>
+    </div>
+</body>
+</html>
+`, expiry, shareURL, shareURL)
+
+	textBody := fmt.Sprintf(`
+Hello,
+
+A folder shared with you on CirrusSync expires on %s.
+
+After that you will no longer be able to open it, and its public links stop working. Open the folder to save anything you still need:
+
+%s
+
+If the owner renews the share, your access continues without any action from you.
+
+Thank you,
+The CirrusSync Team
+`, expiry, shareURL)
+
+	return subject, htmlBody, textBody
+}
+
+// SendStorageCorruptionEmail tells a user that stored files were found damaged and cannot be repaired.
+// The link opens the drive so the user can upload the files again from another copy.
+func (s *Service) SendStorageCorruptionEmail(email string, fileCount int) error {
+	email = NormalizeEmail(email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	driveURL := fmt.Sprintf("%s/drive", s.config.BaseURL)
+	subject, htmlBody, textBody := s.getStorageCorruptionEmailContent(fileCount, driveURL)
+
+	return s.sendEmailFast([]string{email}, subject, htmlBody, textBody)
+}
+
+// getStorageCorruptionEmailContent returns the storage corruption notice email content (subject, HTML and text)
+func (s *Service) getStorageCorruptionEmailContent(fileCount int, driveURL string) (string, string, string) {
+	subject := "Some of your files could not be verified - CirrusSync"
+
+	files := "1 file"
+	if fileCount != 1 {
+		files = fmt.Sprintf("%d files", fileCount)
+	}
+
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Files Damaged</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            margin: 0;
+            padding: 0;
+            background-color: #f9f9f9;
+        }
+        .container {
+            max-width: 600px;
+            margin: 20px auto;
+            background-color: #ffffff;
+            border-radius: 8px;
+            overflow: hidden;
+            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
+        }
+        .header {
+            background-color: #10b981;
+            color: white;
+            padding: 20px;
+            text-align: center;
+        }
+        .content {
+            padding: 20px 30px;
+        }
+        .footer {
+            background-color: #f5f5f5;
+            padding: 15px;
+            text-align: center;
+            font-size: 12px;
+            color: #666;
+        }
+        .button {
+            display: inline-block;
+            background-color: #10b981;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            margin: 20px 0;
+            font-weight: 500;
+            text-align: center;
+        }
+        .link {
+            word-break: break-all;
+            color: #10b981;
+        }
+        .logo {
+            max-width: 150px;
+            margin-bottom: 10px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
+            <h1>Files Damaged</h1>
+        </div>
+        <div class="content">
+            <p>During a routine storage check we found damaged data in <strong>%s</strong> in your CirrusSync drive, and no backup copy is available to repair them.</p>
+            <p>We are sorry about this. If you still have these files elsewhere, please upload them again:</p>
+
+            <div style="text-align: center;">
+                <a href="%s" class="button">Open Drive</a>
+            </div>
+
+            <p>Or copy and paste the following URL into your browser:</p>
+            <p class="link">%s</p>
+
+            <p>Your other files are not affected. Contact support if you need help identifying the damaged files.</p>
+
+            <p>Thank you,<br>The CirrusSync Team</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
+            <p>This is an automated message, please do not reply to this email.</p>
+        </div>
+    </div>
+</body>
+</html>
+`, files, driveURL, driveURL)
+
+	textBody := fmt.Sprintf(`
+Hello,
+
+During a routine storage check we found damaged data in %s in your CirrusSync drive, and no backup copy is available to repair them.
+
+We are sorry about this. If you still have these files elsewhere, please upload them again:
+
+%s
+
+Your other files are not affected. Contact support if you need help identifying the damaged files.
+
+Thank you,
+The CirrusSync Team
+`, files, driveURL)
+
+	return subject, htmlBody, textBody
+}
@@ -0,0 +1,154 @@
+package mfa
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MFA_METHOD_EMAIL is the email code second factor. It is only offered at login to accounts
+// that require a second factor but have no stronger method set up.
+const MFA_METHOD_EMAIL = "email"
+
+const (
+	// Redis key prefixes
+	loginEmailCodePrefix = "mfa:login_email:code:" // Pending login code per login ceremony
+
+	// emailIntentLogin is the rate limit intent of login codes
+	emailIntentLogin = "2fa"
+)
+
+// EmailCodeResult describes a login code that was just emailed
+type EmailCodeResult struct {
+	MaskedEmail       string // Address the code went to, mostly hidden
+	ExpiresAt         int64  // When the code stops being accepted
+	RemainingRequests int    // Emails that can still be sent to the address in the current window
+}
+
+// pendingEmailCode is a login code waiting to be entered. Only its hash is stored.
+type pendingEmailCode struct {
+	UserID   string `json:"userId"`
+	CodeHash string `json:"codeHash"`
+}
+
+// SendLoginEmailCode emails a one-time code to the user for the login ceremony, replacing any pending one.
+// It shares the cooldown and window limits of verification emails.
+func (s *Service) SendLoginEmailCode(ctx context.Context, userID, ceremonyID string) (*EmailCodeResult, error) {
+	if userID == "" || ceremonyID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	user, err := s.repo.FindUserByID(userID)
+	if err != nil || user.Email == "" {
+		return nil, ErrInvalidInput
+	}
+	email := NormalizeEmail(user.Email)
+
+	canSend, _, err := s.canSendEmail(ctx, email, emailIntentLogin)
+	if err != nil {
+		s.logger.Error("Failed to check rate limit", "userID", userID, "intent", emailIntentLogin, "error", err)
+		return nil, ErrOperationFailed
+	}
+	if !canSend {
+		return nil, ErrRateLimitExceeded
+	}
+
+	code, err := generateOneTimeCode()
+	if err != nil {
+		return nil, &TokenGenerationError{Err: err}
+	}
+
+	key := loginEmailCodePrefix + ceremonyID
+	pending := pendingEmailCode{UserID: userID, CodeHash: hashOneTimeCode(userID, code)}
+	if err := s.redisClient.SetJSON(ctx, key, pending, s.config.TokenExpiry); err != nil {
+		s.logger.Error("Failed to store login email code", "userID", userID, "error", err)
+		return nil, ErrOperationFailed
+	}
+
+	subject, htmlBody, textBody := s.getLoginCodeEmailContent(user.Username, code, s.formatDuration(s.config.TokenExpiry))
+
+	if err := s.sendEmailFast([]string{email}, subject, htmlBody, textBody); err != nil {
+		s.logger.Error("Failed to send login email code", "userID", userID, "error", err)
+		_, _ = s.redisClient.Delete(ctx, key)
+		return nil, ErrFailedToSendEmail
+	}
+
+	if err := s.trackEmailSent(ctx, email, emailIntentLogin); err != nil {
+		s.logger.Error("Failed to track email sending", "userID", userID, "error", err)
+	}
+
+	result := &EmailCodeResult{
+		MaskedEmail: maskEmail(email),
+		ExpiresAt:   time.Now().Add(s.config.TokenExpiry).Unix(),
+	}
+	if _, remaining, err := s.getRateLimitInfo(ctx, email); err == nil {
+		result.RemainingRequests = remaining
+	}
+
+	return result, nil
+}
+
+// getLoginCodeEmailContent returns the login code email content (subject, HTML and text)
+func (s *Service) getLoginCodeEmailContent(username, code, expiry string) (string, string, string) {
+	subject := "Your sign-in code - CirrusSync"
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Your sign-in code</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <h2>Hello %s,</h2>
+    <p>Use this code to finish signing in to CirrusSync:</p>
+    <p style="font-size: 28px; font-weight: bold; letter-spacing: 6px;">%s</p>
+    <p>The code expires in %s. If you did not try to sign in, change your password right away.</p>
+</body>
+</html>`, username, code, expiry)
+	textBody := fmt.Sprintf("Hello %s,\n\nUse this code to finish signing in to CirrusSync: %s\n\nThe code expires in %s. If you did not try to sign in, change your password right away.\n",
+		username, code, expiry)
+
+	return subject, htmlBody, textBody
+}
+
+// VerifyLoginEmailCode checks the code emailed for a login ceremony and consumes it.
+// Failed attempts are counted by the login ceremony itself.
+func (s *Service) VerifyLoginEmailCode(ctx context.Context, userID, ceremonyID, code string) error {
+	if userID == "" || ceremonyID == "" || code == "" {
+		return ErrInvalidInput
+	}
+
+	key := loginEmailCodePrefix + ceremonyID
+
+	var pending pendingEmailCode
+	if err := s.redisClient.GetJSON(ctx, key, &pending); err != nil || pending.CodeHash == "" || pending.UserID != userID {
+		return ErrEmailCodeExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashOneTimeCode(userID, NormalizeTOTPCode(code))), []byte(pending.CodeHash)) != 1 {
+		return ErrInvalidEmailCode
+	}
+
+	deleted, err := s.redisClient.Delete(ctx, key)
+	if err != nil || !deleted {
+		// Another request used the code first
+		return ErrEmailCodeExpired
+	}
+
+	return nil
+}
+
+// maskEmail hides most of the local part of an address
+func maskEmail(email string) string {
+	at := strings.IndexByte(email, '@')
+	if at < 0 {
+		return email
+	}
+	if at <= 1 {
+		return "*" + email[at:]
+	}
+	return email[:1] + "***" + email[at:]
+}
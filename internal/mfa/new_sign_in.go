@@ -0,0 +1,35 @@
+package mfa
+
+import (
+	"cirrussync-api/internal/mailer"
+	"fmt"
+	"time"
+)
+
+// SendNewSignInEmail tells a user about a sign-in from a new country or from further away than they
+// could have travelled, with a link to the sessions they can sign out
+func (s *Service) SendNewSignInEmail(userID, deviceName, location, ipAddress string, signedInAt int64) error {
+	user, err := s.repo.FindUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	email := NormalizeEmail(user.Email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	message, err := s.templates.Render(mailer.TEMPLATE_NEW_SIGN_IN, s.recipientLocale(email), mailer.NewSignInData{
+		DeviceName:       deviceName,
+		Location:         location,
+		IPAddress:        ipAddress,
+		SignedInAt:       time.Unix(signedInAt, 0).UTC().Format("January 2, 2006 at 15:04 UTC"),
+		SessionsURL:      fmt.Sprintf("%s/settings/security/sessions", s.config.BaseURL),
+		PasswordResetURL: fmt.Sprintf("%s/reset-password", s.config.BaseURL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmailFast([]string{email}, message.Subject, message.HTMLBody, message.TextBody)
+}
@@ -0,0 +1,50 @@
+package mfa
+
+import (
+	"context"
+	"strings"
+)
+
+// PasswordResetEmail returns the address a password reset token was sent to. The token stays valid
+// until ConsumePasswordResetToken uses it up.
+func (s *Service) PasswordResetEmail(ctx context.Context, token string) (string, error) {
+	email, _, err := s.intentToken(ctx, token, "password-reset")
+	return email, err
+}
+
+// ConsumePasswordResetToken uses up a password reset token so it cannot reset the password again
+func (s *Service) ConsumePasswordResetToken(ctx context.Context, token string) error {
+	return s.consumeEmailToken(ctx, token)
+}
+
+// intentToken returns the address and username an email token of the intent was sent for
+func (s *Service) intentToken(ctx context.Context, token, intent string) (string, string, error) {
+	if token == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	tokenData, err := s.redisClient.Get(ctx, emailTokenPrefix+token)
+	if err != nil || tokenData == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	// Token data is email:username:intent
+	parts := strings.Split(tokenData, ":")
+	if len(parts) < 3 || parts[len(parts)-1] != intent {
+		return "", "", ErrInvalidToken
+	}
+
+	return parts[0], strings.Join(parts[1:len(parts)-1], ":"), nil
+}
+
+// consumeEmailToken uses up an email token so it cannot be used again
+func (s *Service) consumeEmailToken(ctx context.Context, token string) error {
+	deleted, err := s.redisClient.Delete(ctx, emailTokenPrefix+token)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrInvalidToken
+	}
+	return nil
+}
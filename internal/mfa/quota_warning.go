@@ -0,0 +1,31 @@
+package mfa
+
+import (
+	"cirrussync-api/internal/mailer"
+	"fmt"
+)
+
+// SendQuotaWarningEmail warns a user that their storage is almost full. The link opens the storage
+// settings, where space can be freed up or the plan upgraded.
+func (s *Service) SendQuotaWarningEmail(userID string, usedBytes, limitBytes int64) error {
+	user, err := s.repo.FindUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	email := NormalizeEmail(user.Email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	message, err := s.templates.Render(mailer.TEMPLATE_QUOTA_WARNING, s.recipientLocale(email), mailer.QuotaWarningData{
+		UsedBytes:  usedBytes,
+		LimitBytes: limitBytes,
+		StorageURL: fmt.Sprintf("%s/settings/storage", s.config.BaseURL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmailFast([]string{email}, message.Subject, message.HTMLBody, message.TextBody)
+}
@@ -4,20 +4,53 @@ package mfa
 import (
 	"cirrussync-api/internal/models"
 	"cirrussync-api/pkg/db"
+	"context"
+	"encoding/json"
+	"time"
 
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository interface defines required methods
 type Repository interface {
 	// User
 	FindUserOneWhere(email *string, username *string) (*models.User, error)
+	FindUserByID(id string) (*models.User, error)
+	GetLanguageByEmail(email string) (string, error)
+
+	// MFA settings
+	GetMFASettings(userID string) (*models.UserMFASettings, error)
+	SetPreferredMethod(userID, method string) error
+
+	// Passkeys
+	GetWebAuthnCredentials(userID string) ([]models.WebAuthnCredential, error)
+	GetWebAuthnCredentialByCredentialID(credentialID string) (*models.WebAuthnCredential, error)
+	CreateWebAuthnCredential(credential *models.WebAuthnCredential) error
+	UpdateWebAuthnSignCount(id string, signCount int64) error
+	DeleteWebAuthnCredential(userID, id string) error
+
+	// Phone
+	FindUserByPhoneNumber(phoneNumber string) (*models.User, error)
+	GetPhoneMethod(userID string) (*models.PhoneMethods, error)
+	EnablePhoneMethod(userID, phoneNumber string) error
+	DisablePhoneMethod(userID string) error
+
+	// TOTP
+	GetTOTPMethod(userID string) (*models.TOTPMethods, error)
+	SaveTOTPSetup(userID, secret string, recoveryKeyHashes []string) error
+	ImportTOTP(userID, secret string, enabled bool, recoveryKeyHashes []string) error
+	EnableTOTPMethod(userID string) error
+	DisableTOTPMethod(userID string) error
+	UpdateTOTPLastUsed(userID string) error
+	ConsumeBackupCode(userID, hash string) (bool, error)
 }
 
 // It uses our base repository to inherit locking capabilities
 type repo struct {
+	db       *gorm.DB
 	userRepo *db.BaseRepository[models.User]
 }
 
@@ -27,6 +60,7 @@ func NewRepository(database *gorm.DB) Repository {
 	userRepo := db.NewRepositoryWithDB[models.User](database)
 	// Return our repository that wraps the base repositories
 	return &repo{
+		db:       database,
 		userRepo: userRepo,
 	}
 }
@@ -64,3 +98,409 @@ func (r *repo) FindUserOneWhere(email *string, username *string) (*models.User,
 	// If we get here, no user was found by either email or username
 	return nil, gorm.ErrRecordNotFound
 }
+
+// FindUserByID finds a user by ID
+func (r *repo) FindUserByID(id string) (*models.User, error) {
+	return r.userRepo.FindByID(context.Background(), id)
+}
+
+// GetMFASettings gets MFA settings for a user, nil when the user has none yet
+func (r *repo) GetMFASettings(userID string) (*models.UserMFASettings, error) {
+	var settings models.UserMFASettings
+	err := r.db.Where("user_id = ?", userID).First(&settings).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SetPreferredMethod stores the preferred MFA method, creating the settings row on first use
+func (r *repo) SetPreferredMethod(userID, method string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"preferred_method", "modified_at"}),
+	}).Create(&models.UserMFASettings{
+		UserID:          userID,
+		PreferredMethod: method,
+		BackupCodes:     []string{},
+	}).Error
+}
+
+// GetWebAuthnCredentials gets the passkeys of a user, oldest first
+func (r *repo) GetWebAuthnCredentials(userID string) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&credentials).Error
+	if err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// GetWebAuthnCredentialByCredentialID gets a passkey by its authenticator credential ID
+func (r *repo) GetWebAuthnCredentialByCredentialID(credentialID string) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// CreateWebAuthnCredential stores a new passkey.
+// The first passkey of a user becomes their preferred method unless they already picked one.
+func (r *repo) CreateWebAuthnCredential(credential *models.WebAuthnCredential) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(credential).Error; err != nil {
+			return err
+		}
+
+		settings := &models.UserMFASettings{
+			UserID:          credential.UserID,
+			PreferredMethod: MFA_METHOD_WEBAUTHN,
+			BackupCodes:     []string{},
+		}
+		err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).Create(settings).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.UserMFASettings{}).
+			Where("user_id = ? AND (preferred_method IS NULL OR preferred_method = '')", credential.UserID).
+			Updates(map[string]interface{}{
+				"preferred_method": MFA_METHOD_WEBAUTHN,
+				"modified_at":      time.Now().Unix(),
+			}).Error
+	})
+}
+
+// UpdateWebAuthnSignCount records a successful assertion.
+// The counter only moves forward so concurrent logins cannot roll it back.
+func (r *repo) UpdateWebAuthnSignCount(id string, signCount int64) error {
+	now := time.Now().Unix()
+	return r.db.Model(&models.WebAuthnCredential{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"sign_count":  gorm.Expr("GREATEST(sign_count, ?)", signCount),
+			"last_used":   now,
+			"modified_at": now,
+		}).Error
+}
+
+// DeleteWebAuthnCredential removes a passkey of a user.
+// Removing the last passkey clears a passkey preference so login falls back to the next method.
+func (r *repo) DeleteWebAuthnCredential(userID, id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var remaining int64
+		if err := tx.Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+
+		return tx.Model(&models.UserMFASettings{}).
+			Where("user_id = ? AND preferred_method = ?", userID, MFA_METHOD_WEBAUTHN).
+			Updates(map[string]interface{}{
+				"preferred_method": "",
+				"modified_at":      time.Now().Unix(),
+			}).Error
+	})
+}
+
+// GetLanguageByEmail returns the language preference of the user with the email, empty when there
+// is no such user or they never chose one
+func (r *repo) GetLanguageByEmail(email string) (string, error) {
+	var languages []string
+	err := r.db.Model(&models.UserPreferences{}).
+		Joins("JOIN users ON users.id = users_preferences.user_id").
+		Where("users.email = ?", email).
+		Limit(1).
+		Pluck("users_preferences.language", &languages).Error
+	if err != nil || len(languages) == 0 {
+		return "", err
+	}
+	return languages[0], nil
+}
+
+// FindUserByPhoneNumber finds the user a phone number belongs to
+func (r *repo) FindUserByPhoneNumber(phoneNumber string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("phone_number = ?", phoneNumber).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetPhoneMethod gets the phone method of a user, nil when the user never set one up
+func (r *repo) GetPhoneMethod(userID string) (*models.PhoneMethods, error) {
+	var method models.PhoneMethods
+	err := r.db.
+		Joins("JOIN users_mfa_settings ON users_mfa_settings.id = phone_methods.settings_id").
+		Where("users_mfa_settings.user_id = ?", userID).
+		First(&method).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &method, nil
+}
+
+// EnablePhoneMethod stores a verified phone number on the user and turns on their phone method
+func (r *repo) EnablePhoneMethod(userID, phoneNumber string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Unix()
+		err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"phone_number":   phoneNumber,
+				"phone_verified": true,
+				"modified_at":    now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		settings, err := ensureMFASettings(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var method models.PhoneMethods
+		err = tx.Where("settings_id = ?", settings.ID).First(&method).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Omit(clause.Associations).Create(&models.PhoneMethods{
+				SettingsID: settings.ID,
+				Enabled:    true,
+				Verified:   true,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&method).Updates(map[string]interface{}{
+			"enabled":     true,
+			"verified":    true,
+			"modified_at": now,
+		}).Error
+	})
+}
+
+// DisablePhoneMethod turns off the phone method of a user. The phone number stays on the account.
+// A phone preference is cleared so login falls back to the next method.
+func (r *repo) DisablePhoneMethod(userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var settings models.UserMFASettings
+		if err := tx.Where("user_id = ?", userID).First(&settings).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		err := tx.Model(&models.PhoneMethods{}).
+			Where("settings_id = ?", settings.ID).
+			Updates(map[string]interface{}{
+				"enabled":     false,
+				"modified_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.UserMFASettings{}).
+			Where("id = ? AND preferred_method = ?", settings.ID, MFA_METHOD_SMS).
+			Updates(map[string]interface{}{
+				"preferred_method": "",
+				"modified_at":      now,
+			}).Error
+	})
+}
+
+// GetTOTPMethod gets the TOTP method of a user, nil when the user never set one up
+func (r *repo) GetTOTPMethod(userID string) (*models.TOTPMethods, error) {
+	var method models.TOTPMethods
+	err := r.db.
+		Joins("JOIN users_mfa_settings ON users_mfa_settings.id = totp_methods.settings_id").
+		Where("users_mfa_settings.user_id = ?", userID).
+		First(&method).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &method, nil
+}
+
+// SaveTOTPSetup stores a new, not yet verified TOTP secret and its recovery key hashes,
+// replacing any earlier setup that was never verified
+func (r *repo) SaveTOTPSetup(userID, secret string, recoveryKeyHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return saveTOTPMethod(tx, userID, secret, false, recoveryKeyHashes)
+	})
+}
+
+// ImportTOTP stores TOTP state carried over from Redis. State already in the database wins,
+// so an import never overwrites a setup made after the legacy keys were written.
+func (r *repo) ImportTOTP(userID, secret string, enabled bool, recoveryKeyHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		err := tx.Model(&models.TOTPMethods{}).
+			Joins("JOIN users_mfa_settings ON users_mfa_settings.id = totp_methods.settings_id").
+			Where("users_mfa_settings.user_id = ? AND totp_methods.secret <> ''", userID).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+
+		return saveTOTPMethod(tx, userID, secret, enabled, recoveryKeyHashes)
+	})
+}
+
+// EnableTOTPMethod marks the pending TOTP secret of a user as verified and turns it on
+func (r *repo) EnableTOTPMethod(userID string) error {
+	return r.db.Model(&models.TOTPMethods{}).
+		Where("settings_id = (?) AND secret <> ''", r.db.Model(&models.UserMFASettings{}).Select("id").Where("user_id = ?", userID)).
+		Updates(map[string]interface{}{
+			"enabled":     true,
+			"verified":    true,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// DisableTOTPMethod turns off TOTP, dropping the secret and the recovery keys.
+// A TOTP preference is cleared so login falls back to the next method.
+func (r *repo) DisableTOTPMethod(userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var settings models.UserMFASettings
+		err := tx.Where("user_id = ?", userID).First(&settings).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		err = tx.Model(&models.TOTPMethods{}).
+			Where("settings_id = ?", settings.ID).
+			Updates(map[string]interface{}{
+				"secret":      "",
+				"enabled":     false,
+				"verified":    false,
+				"modified_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"backup_codes": gorm.Expr("'[]'::jsonb"),
+			"modified_at":  now,
+		}
+		if settings.PreferredMethod == MFA_METHOD_TOTP {
+			updates["preferred_method"] = ""
+		}
+		return tx.Model(&settings).Updates(updates).Error
+	})
+}
+
+// UpdateTOTPLastUsed records a successful TOTP login
+func (r *repo) UpdateTOTPLastUsed(userID string) error {
+	now := time.Now().Unix()
+	return r.db.Model(&models.TOTPMethods{}).
+		Where("settings_id = (?)", r.db.Model(&models.UserMFASettings{}).Select("id").Where("user_id = ?", userID)).
+		Updates(map[string]interface{}{
+			"last_used":   now,
+			"modified_at": now,
+		}).Error
+}
+
+// ConsumeBackupCode removes a recovery key hash and reports whether it was still there,
+// so concurrent requests cannot both use the same key
+func (r *repo) ConsumeBackupCode(userID, hash string) (bool, error) {
+	result := r.db.Model(&models.UserMFASettings{}).
+		Where("user_id = ? AND jsonb_exists(backup_codes, ?)", userID, hash).
+		Updates(map[string]interface{}{
+			"backup_codes": gorm.Expr("backup_codes - ?::text", hash),
+			"modified_at":  time.Now().Unix(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ensureMFASettings returns the MFA settings row of a user, creating it on first use
+func ensureMFASettings(tx *gorm.DB, userID string) (*models.UserMFASettings, error) {
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).Create(&models.UserMFASettings{
+		UserID:      userID,
+		BackupCodes: []string{},
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Read back the row, which may predate this call
+	var settings models.UserMFASettings
+	if err := tx.Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// saveTOTPMethod writes the TOTP method and recovery key hashes of a user inside a transaction
+func saveTOTPMethod(tx *gorm.DB, userID, secret string, enabled bool, recoveryKeyHashes []string) error {
+	settings, err := ensureMFASettings(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	codes, err := json.Marshal(recoveryKeyHashes)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	err = tx.Model(settings).Updates(map[string]interface{}{
+		"backup_codes": gorm.Expr("?::jsonb", string(codes)),
+		"modified_at":  now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var method models.TOTPMethods
+	err = tx.Where("settings_id = ?", settings.ID).First(&method).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Omit(clause.Associations).Create(&models.TOTPMethods{
+			SettingsID: settings.ID,
+			Secret:     secret,
+			Enabled:    enabled,
+			Verified:   enabled,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&method).Updates(map[string]interface{}{
+		"secret":      secret,
+		"enabled":     enabled,
+		"verified":    enabled,
+		"modified_at": now,
+	}).Error
+}
@@ -0,0 +1,134 @@
+package mfa
+
+import (
+	"fmt"
+	"time"
+)
+
+// SendSecurityReportEmail tells a user that the security event report they requested is ready.
+// The link opens the report in the account settings, so the download itself never travels by email.
+func (s *Service) SendSecurityReportEmail(email, downloadID string, expiresAt int64) error {
+	email = NormalizeEmail(email)
+	if !ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	reportURL := fmt.Sprintf("%s/settings/security/exports/%s", s.config.BaseURL, downloadID)
+	expiry := time.Unix(expiresAt, 0).UTC().Format("January 2, 2006 at 15:04 UTC")
+	subject, htmlBody, textBody := s.getSecurityReportEmailContent(expiry, reportURL)
+
+	return s.sendEmailFast([]string{email}, subject, htmlBody, textBody)
+}
+
+// getSecurityReportEmailContent returns the security report notice email content (subject, HTML and text)
+func (s *Service) getSecurityReportEmailContent(expiry, reportURL string) (string, string, string) {
+	subject := "Your security report is ready - CirrusSync"
+
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Security Report Ready</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            margin: 0;
+            padding: 0;
+            background-color: #f9f9f9;
+        }
+        .container {
+            max-width: 600px;
+            margin: 20px auto;
+            background-color: #ffffff;
+            border-radius: 8px;
+            overflow: hidden;
+            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
+        }
+        .header {
+            background-color: #10b981;
+            color: white;
+            padding: 20px;
+            text-align: center;
+        }
+        .content {
+            padding: 20px 30px;
+        }
+        .footer {
+            background-color: #f5f5f5;
+            padding: 15px;
+            text-align: center;
+            font-size: 12px;
+            color: #666;
+        }
+        .button {
+            display: inline-block;
+            background-color: #10b981;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            margin: 20px 0;
+            font-weight: 500;
+            text-align: center;
+        }
+        .link {
+            word-break: break-all;
+            color: #10b981;
+        }
+        .logo {
+            max-width: 150px;
+            margin-bottom: 10px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
+            <h1>Security Report Ready</h1>
+        </div>
+        <div class="content">
+            <p>The security event report you requested is ready to download.</p>
+            <p>For your protection the download is only available from your account settings, and only until <strong>%s</strong>:</p>
+
+            <div style="text-align: center;">
+                <a href="%s" class="button">Download Report</a>
+            </div>
+
+            <p>Or copy and paste the following URL into your browser:</p>
+            <p class="link">%s</p>
+
+            <p>If you did not request this report, change your password and review your active sessions.</p>
+
+            <p>Thank you,<br>The CirrusSync Team</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
+            <p>This is an automated message, please do not reply to this email.</p>
+        </div>
+    </div>
+</body>
+</html>
+`, expiry, reportURL, reportURL)
+
+	textBody := fmt.Sprintf(`
+Hello,
+
+The security event report you requested is ready to download.
+
+For your protection the download is only available from your account settings, and only until %s:
+
+%s
+
+If you did not request this report, change your password and review your active sessions.
+
+Thank you,
+The CirrusSync Team
+`, expiry, reportURL)
+
+	return subject, htmlBody, textBody
+}
@@ -4,16 +4,19 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/subtle"
-	"crypto/tls"
 	"encoding/base32"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"net/smtp"
 	"strings"
-	"sync"
 	"time"
 
+	"cirrussync-api/internal/jobs"
 	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/mailer"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/sms"
 	"cirrussync-api/pkg/config"
 	"cirrussync-api/pkg/redis"
 
@@ -26,9 +29,7 @@ const (
 	// Redis key prefixes
 	emailTokenPrefix         = "mfa:email:token:"        // Stores email verification tokens
 	emailVerifiedPrefix      = "mfa:email:verified:"     // Tracks verified emails
-	totpSecretPrefix         = "mfa:totp:secret:"        // Stores TOTP secrets
-	totpEnabledPrefix        = "mfa:totp:enabled:"       // Tracks enabled TOTP
-	totpRecoveryPrefix       = "mfa:totp:recovery:"      // Stores recovery keys
+	totpStatePrefix          = "mfa:totp:state:"         // Caches TOTP state held in the database
 	emailRateLimitPrefix     = "mfa:rate:email:"         // Email rate limiting by email
 	intentRateLimitPrefix    = "mfa:rate:intent:"        // Email rate limiting by intent
 	intentByEmailPrefix      = "mfa:intent:email:"       // Track intents by email
@@ -36,6 +37,11 @@ const (
 	emailCountPrefix         = "mfa:count:email:"        // Count of emails sent
 	emailCountByIntentPrefix = "mfa:count:intent:email:" // Count of emails sent by intent
 
+	// Legacy Redis key prefixes, from before TOTP state was kept in the database
+	legacyTOTPSecretPrefix   = "mfa:totp:secret:"
+	legacyTOTPEnabledPrefix  = "mfa:totp:enabled:"
+	legacyTOTPRecoveryPrefix = "mfa:totp:recovery:"
+
 	// Hash settings
 	argon2Memory      = 64 * 1024
 	argon2Iterations  = 3
@@ -44,16 +50,21 @@ const (
 	argon2SaltLength  = 16
 
 	// Default expiration times
-	defaultTokenExpiry    = 10 * time.Minute     // Email verification token expiration
-	emailVerifiedExpiry   = 30 * time.Hour       // How long to keep email verification status
-	totpSecretExpiry      = 365 * 24 * time.Hour // Permanent storage for TOTP secrets
-	singleEmailExpiry     = 3 * time.Minute      // Minimum time between emails to same address
-	windowRateLimitExpiry = 2 * time.Hour        // Window for rate limiting (5 emails per 2 hours)
+	defaultTokenExpiry    = 10 * time.Minute // Email verification token expiration
+	emailVerifiedExpiry   = 30 * time.Hour   // How long to keep email verification status
+	totpStateCacheExpiry  = 10 * time.Minute // How long TOTP state is cached in Redis
+	singleEmailExpiry     = 3 * time.Minute  // Minimum time between emails to same address
+	windowRateLimitExpiry = 2 * time.Hour    // Window for rate limiting (5 emails per 2 hours)
 
 	// Rate limits
 	maxEmailsPerWindow = 5 // Maximum emails per time window
+
+	recoveryKeyCount = 10 // Recovery keys issued with each TOTP setup
 )
 
+// JOB_TYPE_EMAIL_SEND sends a queued email, retrying with backoff until it is dead-lettered
+const JOB_TYPE_EMAIL_SEND = "email.send"
+
 // EmailVerificationResult contains the result of a verification email request
 type EmailVerificationResult struct {
 	RemainingRequests int    // Remaining requests in the current window
@@ -65,6 +76,8 @@ type EmailVerificationResult struct {
 type MFAConfig struct {
 	config.MailConfig
 	config.TOTPConfig
+	config.WebAuthnConfig
+	config.SMSConfig
 }
 
 // TOTPData contains TOTP setup information
@@ -74,34 +87,18 @@ type TOTPData struct {
 	RecoveryKeys []string `json:"recoveryKeys,omitempty"`
 }
 
-// SMTPClient represents a reusable SMTP client connection
-type SMTPClient struct {
-	client *smtp.Client
-	auth   smtp.Auth
-	from   string
-	mu     sync.Mutex
-}
-
-// SMTPClientPool manages a pool of SMTP client connections
-type SMTPClientPool struct {
-	pool      []*SMTPClient
-	config    *MFAConfig
-	available chan *SMTPClient
-	mu        sync.Mutex
-}
-
-var (
-	// Global pool instance
-	smtpPool     *SMTPClientPool
-	smtpPoolOnce sync.Once
-)
-
 // Service handles MFA operations
 type Service struct {
 	config      MFAConfig
 	repo        Repository
 	redisClient *redis.Client
 	logger      *logger.Logger
+	smsProvider sms.Provider
+	templates   *mailer.Renderer
+	dispatcher  *mailer.Dispatcher
+	jobService  *jobs.Service
+
+	securityEvents *security.Service
 }
 
 // NewService creates a new MFA service
@@ -129,143 +126,53 @@ func NewService(repo Repository,
 		config.TOTPAlgorithm = otp.AlgorithmSHA1
 	}
 
+	// Set SMS defaults
+	if config.SMSCodeExpiry == 0 {
+		config.SMSCodeExpiry = defaultTokenExpiry
+	}
+
+	// Templates are embedded, so they only fail to parse in a broken build
+	templates, err := mailer.New()
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse email templates: %v", err))
+	}
+
 	service := &Service{
 		config:      config,
 		repo:        repo,
 		redisClient: redisClient,
 		logger:      logger,
+		templates:   templates,
 	}
 
-	// Initialize SMTP pool
-	smtpPoolOnce.Do(func() {
-		smtpPool = initSMTPPool(&config, 5) // Pool size of 5
-	})
-
 	return service
 }
 
-// initSMTPPool initializes the SMTP client pool
-func initSMTPPool(config *MFAConfig, poolSize int) *SMTPClientPool {
-	pool := &SMTPClientPool{
-		pool:      make([]*SMTPClient, 0, poolSize),
-		config:    config,
-		available: make(chan *SMTPClient, poolSize),
-		mu:        sync.Mutex{},
-	}
-
-	// Pre-create connections
-	for range make([]struct{}, poolSize) {
-		client, err := pool.createClient()
-		if err != nil {
-			// Log error but continue - we'll create more connections as needed
-			continue
-		}
-		pool.available <- client
-	}
-
-	// Start a goroutine to periodically refresh connections
-	go pool.maintainConnections()
-
-	return pool
-}
-
-// createClient creates a new SMTP client connection
-func (p *SMTPClientPool) createClient() (*SMTPClient, error) {
-	// Connect to the server
-	smtpAddr := fmt.Sprintf("%s:%d", p.config.SMTPHost, p.config.SMTPPort)
-
-	// Create the SMTP client
-	client, err := smtp.Dial(smtpAddr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
-	}
-
-	// Create TLS config
-	tlsConfig := &tls.Config{
-		ServerName: p.config.SMTPHost,
-	}
-
-	// Start TLS
-	if err = client.StartTLS(tlsConfig); err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to start TLS: %w", err)
-	}
-
-	// Authenticate
-	auth := smtp.PlainAuth("", p.config.SMTPUsername, p.config.SMTPPassword, p.config.SMTPHost)
-	if err = client.Auth(auth); err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to authenticate: %w", err)
-	}
-
-	return &SMTPClient{
-		client: client,
-		auth:   auth,
-		from:   p.config.FromEmail,
-		mu:     sync.Mutex{},
-	}, nil
+// SetEmailDispatcher configures the provider emails are sent through. Without a dispatcher every
+// email fails with ErrEmailNotConfigured.
+func (s *Service) SetEmailDispatcher(dispatcher *mailer.Dispatcher) {
+	s.dispatcher = dispatcher
 }
 
-// getClient gets an available SMTP client from the pool or creates a new one
-func (p *SMTPClientPool) getClient() (*SMTPClient, error) {
-	// Try to get an existing client
-	select {
-	case client := <-p.available:
-		return client, nil
-	default:
-		// No available clients, create a new one
-		return p.createClient()
-	}
+// SetJobService queues emails as background jobs and registers the job that sends them
+func (s *Service) SetJobService(jobService *jobs.Service) {
+	s.jobService = jobService
+	jobService.RegisterTransient(JOB_TYPE_EMAIL_SEND, s.runEmailSendJob)
 }
 
-// releaseClient returns a client to the pool
-func (p *SMTPClientPool) releaseClient(client *SMTPClient) {
-	// Only return working clients to the pool
-	if client == nil || client.client == nil {
-		return
-	}
-
-	// Try to return to pool or close if pool is full
-	select {
-	case p.available <- client:
-		// Successfully returned to pool
-	default:
-		// Pool is full, close this connection
-		client.client.Close()
-	}
+// SetSecurityEvents records second factors being turned on or off as security events
+func (s *Service) SetSecurityEvents(events *security.Service) {
+	s.securityEvents = events
 }
 
-// maintainConnections periodically refreshes connections
-func (p *SMTPClientPool) maintainConnections() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		p.mu.Lock()
-		poolSize := cap(p.available)
-		p.mu.Unlock()
-
-		// Check current pool size
-		currentSize := len(p.available)
-		if currentSize < poolSize/2 {
-			// Replenish pool if it's less than half full
-			for i := 0; i < poolSize-currentSize; i++ {
-				client, err := p.createClient()
-				if err != nil {
-					// Log error but continue
-					continue
-				}
-				select {
-				case p.available <- client:
-					// Added to pool
-				default:
-					// Pool is somehow full now, close connection
-					client.client.Close()
-					break
-				}
-			}
-		}
-	}
+// recordMFAChange records a second factor method being turned on or off
+func (s *Service) recordMFAChange(ctx context.Context, userID, eventType, method string) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: eventType,
+		Success:   true,
+		Metadata:  map[string]any{"method": method},
+	})
 }
 
 // generateToken creates a secure random token for verification
@@ -410,12 +317,27 @@ func (s *Service) SendVerificationEmail(ctx context.Context, email string, usern
 
 		// Create verification URL
 		verificationURL := fmt.Sprintf("%s/verify?token=%s", s.config.BaseURL, token)
+		if intent == "password-reset" {
+			// The reset page posts the token back together with the new credentials
+			verificationURL = fmt.Sprintf("%s/reset-password?token=%s", s.config.BaseURL, token)
+		} else if intent == "email-change" {
+			// The signed in account posts the token back to confirm its new address
+			verificationURL = fmt.Sprintf("%s/settings/account/email?token=%s", s.config.BaseURL, token)
+		}
 
-		// Get email content
-		subject, htmlBody, textBody := s.getEmailContent(intent, verificationURL, username, s.formatDuration(s.config.TokenExpiry))
+		// Intents are validated above and each has a template of the same name
+		message, err := s.templates.Render(intent, s.recipientLocale(email), mailer.VerificationData{
+			Username: username,
+			URL:      verificationURL,
+			Expiry:   s.config.TokenExpiry,
+		})
+		if err != nil {
+			s.logger.Error("Failed to render verification email", "intent", intent, "error", err)
+			return
+		}
 
 		// Send email
-		err = s.sendEmailFast([]string{email}, subject, htmlBody, textBody)
+		err = s.sendEmailFast([]string{email}, message.Subject, message.HTMLBody, message.TextBody)
 		if err != nil {
 			s.logger.Error("Failed to send verification email", "email", email, "intent", intent, "error", err)
 		} else {
@@ -446,36 +368,9 @@ func (s *Service) GetInt(ctx context.Context, key string) (int, error) {
 	return result, nil
 }
 
-// Increment increments a counter in Redis
-func (s *Service) Increment(ctx context.Context, key string) (int64, error) {
-	// For each key, try to get current value
-	val, err := s.redisClient.Get(ctx, key)
-	if err != nil {
-		// If error, start from 1
-		err = s.redisClient.Set(ctx, key, "1", 0)
-		if err != nil {
-			return 0, err
-		}
-		return 1, nil
-	}
-
-	// Parse current value
-	var current int64
-	if val != "" {
-		_, err = fmt.Sscanf(val, "%d", &current)
-		if err != nil {
-			return 0, err
-		}
-	}
-
-	// Increment
-	current++
-	err = s.redisClient.Set(ctx, key, fmt.Sprintf("%d", current), 0)
-	if err != nil {
-		return 0, err
-	}
-
-	return current, nil
+// Increment increments a rate limit counter whose window starts with its first increment
+func (s *Service) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return s.redisClient.IncrWithExpiry(ctx, key, 1, window)
 }
 
 // canSendEmail checks if an email can be sent based on rate limits
@@ -568,21 +463,17 @@ func (s *Service) trackEmailSent(ctx context.Context, email, intent string) erro
 
 	// Increment per-intent counter
 	emailCountKey := emailCountByIntentPrefix + email + ":" + intent
-	_, err = s.Increment(ctx, emailCountKey)
+	_, err = s.Increment(ctx, emailCountKey, windowRateLimitExpiry)
 	if err != nil {
 		return err
 	}
-	// Set expiry if not already set
-	s.redisClient.Expire(ctx, emailCountKey, windowRateLimitExpiry)
 
 	// Increment total email counter
 	totalEmailCountKey := emailCountPrefix + email
-	_, err = s.Increment(ctx, totalEmailCountKey)
+	_, err = s.Increment(ctx, totalEmailCountKey, windowRateLimitExpiry)
 	if err != nil {
 		return err
 	}
-	// Set expiry if not already set
-	s.redisClient.Expire(ctx, totalEmailCountKey, windowRateLimitExpiry)
 
 	// Store the intent used
 	intentKey := intentByEmailPrefix + email
@@ -592,531 +483,15 @@ func (s *Service) trackEmailSent(ctx context.Context, email, intent string) erro
 	return nil
 }
 
-// getEmailContent returns email content (subject, HTML and text) based on intent
-func (s *Service) getEmailContent(intent, verificationURL, username, expiry string) (string, string, string) {
-	var subject string
-	var htmlTemplate string
-	var textTemplate string
-
-	switch intent {
-	case "signup":
-		subject = "Please verify your email address - CirrusSync"
-
-		// HTML version
-		htmlTemplate = fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Verify Your Email</title>
-    <style>
-        body {
-            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
-            line-height: 1.6;
-            color: #333;
-            margin: 0;
-            padding: 0;
-            background-color: #f9f9f9;
-        }
-        .container {
-            max-width: 600px;
-            margin: 20px auto;
-            background-color: #ffffff;
-            border-radius: 8px;
-            overflow: hidden;
-            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-        }
-        .header {
-            background-color: #10b981;
-            color: white;
-            padding: 20px;
-            text-align: center;
-        }
-        .content {
-            padding: 20px 30px;
-        }
-        .footer {
-            background-color: #f5f5f5;
-            padding: 15px;
-            text-align: center;
-            font-size: 12px;
-            color: #666;
-        }
-        .button {
-            display: inline-block;
-            background-color: #10b981;
-            color: white;
-            text-decoration: none;
-            padding: 12px 24px;
-            border-radius: 4px;
-            margin: 20px 0;
-            font-weight: 500;
-            text-align: center;
-        }
-        .button:hover {
-            background-color: #0d9668;
-        }
-        .link {
-            word-break: break-all;
-            color: #10b981;
-        }
-        .expiry {
-            background-color: #f0fdf4;
-            border-left: 4px solid #10b981;
-            padding: 10px 15px;
-            margin: 15px 0;
-            font-size: 14px;
-        }
-        .logo {
-            max-width: 150px;
-            margin-bottom: 10px;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
-            <h1>Email Verification</h1>
-        </div>
-        <div class="content">
-            <h2>Hello, %s!</h2>
-            <p>Thank you for signing up for CirrusSync. To complete your registration, please verify your email address by clicking the button below:</p>
-
-            <div style="text-align: center;">
-                <a href="%s" class="button">Verify Email Address</a>
-            </div>
-
-            <p>Or copy and paste the following URL into your browser:</p>
-            <p class="link">%s</p>
-
-            <div class="expiry">
-                <p><strong>Note:</strong> This verification link will expire in %s.</p>
-            </div>
-
-            <p>If you didn't sign up for CirrusSync, please ignore this email or contact our support team if you have any concerns.</p>
-
-            <p>Thank you,<br>The CirrusSync Team</p>
-        </div>
-        <div class="footer">
-            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
-            <p>This is an automated message, please do not reply to this email.</p>
-        </div>
-    </div>
-</body>
-</html>
-`, username, verificationURL, verificationURL, expiry)
-
-		// Plain text version
-		textTemplate = fmt.Sprintf(`
-Hello %s,
-
-Thank you for signing up for CirrusSync! Please verify your email address by clicking the link below:
-
-%s
-
-This link will expire in %s.
-
-If you did not sign up for CirrusSync, please ignore this email.
-
-Best regards,
-The CirrusSync Team
-`, username, verificationURL, expiry)
-
-	case "password-reset":
-		subject = "Password Reset Request - CirrusSync"
-
-		// HTML version
-		htmlTemplate = fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Password Reset</title>
-    <style>
-        body {
-            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
-            line-height: 1.6;
-            color: #333;
-            margin: 0;
-            padding: 0;
-            background-color: #f9f9f9;
-        }
-        .container {
-            max-width: 600px;
-            margin: 20px auto;
-            background-color: #ffffff;
-            border-radius: 8px;
-            overflow: hidden;
-            box-shadow: a 4px 6px rgba(0, 0, 0, 0.1);
-        }
-        .header {
-            background-color: #10b981;
-            color: white;
-            padding: 20px;
-            text-align: center;
-        }
-        .content {
-            padding: 20px 30px;
-        }
-        .footer {
-            background-color: #f5f5f5;
-            padding: 15px;
-            text-align: center;
-            font-size: 12px;
-            color: #666;
-        }
-        .button {
-            display: inline-block;
-            background-color: #10b981;
-            color: white;
-            text-decoration: none;
-            padding: 12px 24px;
-            border-radius: 4px;
-            margin: 20px 0;
-            font-weight: 500;
-            text-align: center;
-        }
-        .button:hover {
-            background-color: #0d9668;
-        }
-        .link {
-            word-break: break-all;
-            color: #10b981;
-        }
-        .expiry {
-            background-color: #f0fdf4;
-            border-left: 4px solid #10b981;
-            padding: 10px 15px;
-            margin: 15px 0;
-            font-size: 14px;
-        }
-        .security {
-            background-color: #fff8f1;
-            border-left: 4px solid #f59e0b;
-            padding: 10px 15px;
-            margin: 15px 0;
-            font-size: 14px;
-        }
-        .logo {
-            max-width: 150px;
-            margin-bottom: 10px;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
-            <h1>Password Reset</h1>
-        </div>
-        <div class="content">
-            <h2>Hello, %s!</h2>
-            <p>We received a request to reset your password for CirrusSync. To reset your password, please click the button below:</p>
-
-            <div style="text-align: center;">
-                <a href="%s" class="button">Reset Password</a>
-            </div>
-
-            <p>Or copy and paste the following URL into your browser:</p>
-            <p class="link">%s</p>
-
-            <div class="expiry">
-                <p><strong>Note:</strong> This password reset link will expire in %s.</p>
-            </div>
-
-            <div class="security">
-                <p><strong>Security Notice:</strong> If you did not request a password reset, please ignore this email or contact our support team immediately as your account security might be at risk.</p>
-            </div>
-
-            <p>Thank you,<br>The CirrusSync Team</p>
-        </div>
-        <div class="footer">
-            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
-            <p>This is an automated message, please do not reply to this email.</p>
-        </div>
-    </div>
-</body>
-</html>
-`, username, verificationURL, verificationURL, expiry)
-
-		// Plain text version
-		textTemplate = fmt.Sprintf(`
-Hello %s,
-
-We received a request to reset your password for CirrusSync. Please click the link below to reset your password:
-
-%s
-
-This link will expire in %s.
-
-If you did not request a password reset, please ignore this email or contact support if you have concerns.
-
-Best regards,
-The CirrusSync Team
-`, username, verificationURL, expiry)
-
-	case "2fa":
-		subject = "Two-Factor Authentication Setup - CirrusSync"
-
-		// HTML version
-		htmlTemplate = fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Two-Factor Authentication Setup</title>
-    <style>
-        body {
-            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
-            line-height: 1.6;
-            color: #333;
-            margin: 0;
-            padding: 0;
-            background-color: #f9f9f9;
-        }
-        .container {
-            max-width: 600px;
-            margin: 20px auto;
-            background-color: #ffffff;
-            border-radius: 8px;
-            overflow: hidden;
-            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-        }
-        .header {
-            background-color: #10b981;
-            color: white;
-            padding: 20px;
-            text-align: center;
-        }
-        .content {
-            padding: 20px 30px;
-        }
-        .footer {
-            background-color: #f5f5f5;
-            padding: 15px;
-            text-align: center;
-            font-size: 12px;
-            color: #666;
-        }
-        .button {
-            display: inline-block;
-            background-color: #10b981;
-            color: white;
-            text-decoration: none;
-            padding: 12px 24px;
-            border-radius: 4px;
-            margin: 20px 0;
-            font-weight: 500;
-            text-align: center;
-        }
-        .button:hover {
-            background-color: #0d9668;
-        }
-        .link {
-            word-break: break-all;
-            color: #10b981;
-        }
-        .expiry {
-            background-color: #f0fdf4;
-            border-left: 4px solid #10b981;
-            padding: 10px 15px;
-            margin: 15px 0;
-            font-size: 14px;
-        }
-        .security {
-            background-color: #fff8f1;
-            border-left: 4px solid #f59e0b;
-            padding: 10px 15px;
-            margin: 15px 0;
-            font-size: 14px;
-        }
-        .logo {
-            max-width: 150px;
-            margin-bottom: 10px;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
-            <h1>Two-Factor Authentication</h1>
-        </div>
-        <div class="content">
-            <h2>Hello, %s!</h2>
-            <p>We received a request to set up two-factor authentication (2FA) for your CirrusSync account. To continue with the setup process, please click the button below:</p>
-
-            <div style="text-align: center;">
-                <a href="%s" class="button">Set Up 2FA</a>
-            </div>
-
-            <p>Or copy and paste the following URL into your browser:</p>
-            <p class="link">%s</p>
-
-            <div class="expiry">
-                <p><strong>Note:</strong> This setup link will expire in %s.</p>
-            </div>
-
-            <div class="security">
-                <p><strong>Security Notice:</strong> Two-factor authentication adds an extra layer of security to your account. Once set up, you'll need both your password and a verification code to sign in.</p>
-                <p>If you did not request to set up 2FA, please ignore this email or contact our support team immediately as someone might be trying to access your account.</p>
-            </div>
-
-            <p>Thank you,<br>The CirrusSync Team</p>
-        </div>
-        <div class="footer">
-            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
-            <p>This is an automated message, please do not reply to this email.</p>
-        </div>
-    </div>
-</body>
-</html>
-`, username, verificationURL, verificationURL, expiry)
-
-		// Plain text version
-		textTemplate = fmt.Sprintf(`
-Hello %s,
-
-We received a request to set up two-factor authentication for your CirrusSync account. Please click the link below to continue:
-
-%s
-
-This link will expire in %s.
-
-If you did not request to set up 2FA, please ignore this email or contact support immediately as someone might be trying to access your account.
-
-Best regards,
-The CirrusSync Team
-`, username, verificationURL, expiry)
-
-	default:
-		// Generic verification email as fallback
-		subject = "Email Verification - CirrusSync"
-
-		// HTML version
-		htmlTemplate = fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Email Verification</title>
-    <style>
-        body {
-            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
-            line-height: 1.6;
-            color: #333;
-            margin: 0;
-            padding: 0;
-            background-color: #f9f9f9;
-        }
-        .container {
-            max-width: 600px;
-            margin: 20px auto;
-            background-color: #ffffff;
-            border-radius: 8px;
-            overflow: hidden;
-            box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-        }
-        .header {
-            background-color: #10b981;
-            color: white;
-            padding: 20px;
-            text-align: center;
-        }
-        .content {
-            padding: 20px 30px;
-        }
-        .footer {
-            background-color: #f5f5f5;
-            padding: 15px;
-            text-align: center;
-            font-size: 12px;
-            color: #666;
-        }
-        .button {
-            display: inline-block;
-            background-color: #10b981;
-            color: white;
-            text-decoration: none;
-            padding: 12px 24px;
-            border-radius: 4px;
-            margin: 20px 0;
-            font-weight: 500;
-            text-align: center;
-        }
-        .button:hover {
-            background-color: #0d9668;
-        }
-        .link {
-            word-break: break-all;
-            color: #10b981;
-        }
-        .expiry {
-            background-color: #f0fdf4;
-            border-left: 4px solid #10b981;
-            padding: 10px 15px;
-            margin: 15px 0;
-            font-size: 14px;
-        }
-        .logo {
-            max-width: 150px;
-            margin-bottom: 10px;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <img src="https://cirrussync.me/logo-white.png" alt="CirrusSync Logo" class="logo">
-            <h1>Email Verification</h1>
-        </div>
-        <div class="content">
-            <h2>Hello, %s!</h2>
-            <p>Please verify your email address by clicking the button below:</p>
-
-            <div style="text-align: center;">
-                <a href="%s" class="button">Verify Email Address</a>
-            </div>
-
-            <p>Or copy and paste the following URL into your browser:</p>
-            <p class="link">%s</p>
-
-            <div class="expiry">
-                <p><strong>Note:</strong> This verification link will expire in %s.</p>
-            </div>
-
-            <p>Thank you,<br>The CirrusSync Team</p>
-        </div>
-        <div class="footer">
-            <p>&copy; 2025 CirrusSync. All rights reserved.</p>
-            <p>This is an automated message, please do not reply to this email.</p>
-        </div>
-    </div>
-</body>
-</html>
-`, username, verificationURL, verificationURL, expiry)
-
-		// Plain text version
-		textTemplate = fmt.Sprintf(`
-Hello %s,
-
-Please verify your email address by clicking the link below:
-
-%s
-
-This link will expire in %s.
-
-Thank you,
-The CirrusSync Team
-`, username, verificationURL, expiry)
-	}
-
-	return subject, htmlTemplate, textTemplate
+// recipientLocale returns the language the owner of an email address chose for their account.
+// Addresses without an account, like those signing up, get the default language.
+func (s *Service) recipientLocale(email string) string {
+	language, err := s.repo.GetLanguageByEmail(email)
+	if err != nil {
+		s.logger.Warn("Failed to load language of email recipient", "error", err)
+		return mailer.DEFAULT_LOCALE
+	}
+	return language
 }
 
 // VerifyEmail verifies an email using a token
@@ -1149,6 +524,11 @@ func (s *Service) VerifyEmail(ctx context.Context, token string) (string, bool)
 
 	println(intent)
 
+	// Password reset and email change tokens are only used up by the reset or change itself
+	if intent == "password-reset" || intent == "email-change" {
+		return "", false
+	}
+
 	// Mark email as verified using a set
 	verifiedKey := emailVerifiedPrefix + email
 	_, err = s.redisClient.SAdd(ctx, verifiedKey, "true")
@@ -1185,6 +565,12 @@ func (s *Service) IsEmailVerified(ctx context.Context, email string) (bool, erro
 	return s.redisClient.SIsMember(ctx, verifiedKey, "true")
 }
 
+// totpState is the TOTP state of a user as cached in Redis
+type totpState struct {
+	Secret  string `json:"secret"`
+	Enabled bool   `json:"enabled"`
+}
+
 // EnableTOTP generates TOTP for a user
 func (s *Service) EnableTOTP(ctx context.Context, userID string) (*TOTPData, error) {
 	if userID == "" {
@@ -1233,51 +619,28 @@ func (s *Service) EnableTOTP(ctx context.Context, userID string) (*TOTPData, err
 	}
 
 	// Generate recovery keys
-	recoveryKeys, err := s.generateRecoveryKeys(10) // Generate 5 recovery keys
+	recoveryKeys, err := s.generateRecoveryKeys(recoveryKeyCount)
 	if err != nil {
 		s.logger.Error("Failed to generate recovery keys", "error", err)
 		return nil, err
 	}
 
-	// Store the TOTP secret in Redis (but don't enable it until verification)
-	secretKey := totpSecretPrefix + userID
-	err = s.redisClient.Set(ctx, secretKey, key.Secret(), totpSecretExpiry)
-	if err != nil {
-		s.logger.Error("Failed to store TOTP secret in Redis", "error", err)
-		return nil, err
-	}
-
-	// Store recovery keys (hashed with argon2)
+	// Hash recovery keys with argon2
+	recoveryKeyHashes := make([]string, len(recoveryKeys))
 	for i, recoveryKey := range recoveryKeys {
-		// Hash the recovery key
-		salt := make([]byte, argon2SaltLength)
-		_, err := rand.Read(salt)
+		recoveryKeyHashes[i], err = hashRecoveryKey(normalizeRecoveryKey(recoveryKey))
 		if err != nil {
-			s.logger.Error("Failed to generate salt for recovery key", "error", err)
-			continue
+			s.logger.Error("Failed to hash recovery key", "error", err)
+			return nil, err
 		}
+	}
 
-		hash := argon2.IDKey(
-			[]byte(recoveryKey),
-			salt,
-			argon2Iterations,
-			argon2Memory,
-			argon2Parallelism,
-			argon2KeyLength,
-		)
-
-		// Combine salt and hash for storage
-		hashData := append(salt, hash...)
-		encodedHash := base64.StdEncoding.EncodeToString(hashData)
-
-		// Store in Redis
-		recoveryKeyRedisKey := fmt.Sprintf("%s%s:%d", totpRecoveryPrefix, userID, i)
-		err = s.redisClient.Set(ctx, recoveryKeyRedisKey, encodedHash, totpSecretExpiry)
-		if err != nil {
-			s.logger.Error("Failed to store recovery key in Redis", "error", err)
-			// Continue anyway, non-critical
-		}
+	// Store the secret (but don't enable it until verification)
+	if err := s.repo.SaveTOTPSetup(userID, key.Secret(), recoveryKeyHashes); err != nil {
+		s.logger.Error("Failed to store TOTP secret", "userID", userID, "error", err)
+		return nil, err
 	}
+	s.invalidateTOTPState(ctx, userID)
 
 	// Create response
 	data := &TOTPData{
@@ -1303,26 +666,16 @@ func (s *Service) VerifyTOTP(ctx context.Context, userID string, code string) (b
 		return false, ErrInvalidTOTPCode
 	}
 
-	// Get TOTP secret from Redis
-	secretKey := totpSecretPrefix + userID
-	secret, err := s.redisClient.Get(ctx, secretKey)
-	if err != nil || secret == "" {
-		s.logger.Error("Failed to retrieve TOTP secret", "error", err)
+	state, err := s.getTOTPState(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if state.Secret == "" {
 		return false, ErrTOTPNotInitialized
 	}
 
 	// Verify the code
-	valid, err := totp.ValidateCustom(
-		code,
-		secret,
-		time.Now().UTC(),
-		totp.ValidateOpts{
-			Digits:    s.config.TOTPDigits,
-			Period:    s.config.TOTPPeriod,
-			Skew:      s.config.TOTPSkew,
-			Algorithm: s.config.TOTPAlgorithm,
-		},
-	)
+	valid, err := s.validateTOTP(code, state.Secret)
 	if err != nil {
 		s.logger.Error("Failed to validate TOTP code", "error", err)
 		return false, err
@@ -1350,19 +703,13 @@ func (s *Service) VerifyTOTP(ctx context.Context, userID string, code string) (b
 		}
 	}()
 
-	// If code is valid, enable TOTP for the user using a set
-	enabledKey := totpEnabledPrefix + userID
-	_, err = s.redisClient.SAdd(ctx, enabledKey, "true")
-	if err != nil {
-		s.logger.Error("Failed to enable TOTP in Redis", "error", err)
+	// If code is valid, enable TOTP for the user
+	if err := s.repo.EnableTOTPMethod(userID); err != nil {
+		s.logger.Error("Failed to enable TOTP", "userID", userID, "error", err)
 		return false, err
 	}
-
-	// Set expiry on the enabled flag (never expire)
-	_, err = s.redisClient.Expire(ctx, enabledKey, totpSecretExpiry)
-	if err != nil {
-		s.logger.Error("Failed to set expiry on TOTP enabled flag", "userID", userID, "error", err)
-	}
+	s.invalidateTOTPState(ctx, userID)
+	s.recordMFAChange(ctx, userID, security.EVENT_MFA_ENABLED, MFA_METHOD_TOTP)
 
 	s.logger.Info("TOTP enabled successfully", "userID", userID)
 	return true, nil
@@ -1377,54 +724,38 @@ func (s *Service) ValidateTOTPCode(ctx context.Context, userID string, code stri
 	// Normalize TOTP code
 	code = NormalizeTOTPCode(code)
 
-	// Check if TOTP is enabled for the user
-	enabled, err := s.IsTOTPEnabled(ctx, userID)
+	state, err := s.getTOTPState(ctx, userID)
 	if err != nil {
 		return false, err
 	}
-	if !enabled {
+	if !state.Enabled {
 		return false, ErrTOTPNotEnabled
 	}
 
 	// Check if it's a recovery key first (recovery keys take priority)
-	isRecoveryKey, err := s.checkRecoveryKey(ctx, userID, code)
-	if err != nil {
-		s.logger.Error("Failed to check recovery key", "error", err)
-		// Continue with normal validation if there's an error checking recovery keys
-	} else if isRecoveryKey {
-		// Remove the used recovery key
-		err = s.removeRecoveryKey(ctx, userID, code)
+	if strings.Contains(code, "-") {
+		used, err := s.useRecoveryKey(userID, code)
 		if err != nil {
-			s.logger.Error("Failed to remove recovery key", "error", err)
+			s.logger.Error("Failed to check recovery key", "error", err)
+			// Continue with normal validation if there's an error checking recovery keys
+		} else if used {
+			return true, nil
 		}
-		return true, nil
-	}
-
-	// Get TOTP secret from Redis
-	secretKey := totpSecretPrefix + userID
-	secret, err := s.redisClient.Get(ctx, secretKey)
-	if err != nil || secret == "" {
-		s.logger.Error("Failed to retrieve TOTP secret", "error", err)
-		return false, ErrTOTPNotInitialized
 	}
 
 	// Verify the code
-	valid, err := totp.ValidateCustom(
-		code,
-		secret,
-		time.Now().UTC(),
-		totp.ValidateOpts{
-			Digits:    s.config.TOTPDigits,
-			Period:    s.config.TOTPPeriod,
-			Skew:      s.config.TOTPSkew,
-			Algorithm: s.config.TOTPAlgorithm,
-		},
-	)
+	valid, err := s.validateTOTP(code, state.Secret)
 	if err != nil {
 		s.logger.Error("Failed to validate TOTP code", "error", err)
 		return false, err
 	}
 
+	if valid {
+		if err := s.repo.UpdateTOTPLastUsed(userID); err != nil {
+			s.logger.Error("Failed to record TOTP use", "userID", userID, "error", err)
+		}
+	}
+
 	return valid, nil
 }
 
@@ -1434,8 +765,11 @@ func (s *Service) IsTOTPEnabled(ctx context.Context, userID string) (bool, error
 		return false, ErrInvalidInput
 	}
 
-	enabledKey := totpEnabledPrefix + userID
-	return s.redisClient.SIsMember(ctx, enabledKey, "true")
+	state, err := s.getTOTPState(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return state.Enabled, nil
 }
 
 // DisableTOTP disables TOTP for a user
@@ -1483,126 +817,189 @@ func (s *Service) DisableTOTP(ctx context.Context, userID string, confirmationCo
 		return ErrTOTPNotEnabled
 	}
 
-	// Delete TOTP enablement and secrets
-	enabledKey := totpEnabledPrefix + userID
-	secretKey := totpSecretPrefix + userID
-
-	// Delete keys in Redis
-	keys := []string{enabledKey, secretKey}
-	_, err = s.redisClient.DeleteMany(ctx, keys...)
-	if err != nil {
-		s.logger.Error("Failed to delete TOTP keys", "error", err)
+	// Drop the secret and recovery keys
+	if err := s.repo.DisableTOTPMethod(userID); err != nil {
+		s.logger.Error("Failed to disable TOTP", "userID", userID, "error", err)
 		return err
 	}
-
-	// Delete recovery keys - find all recovery keys for this user
-	for i := 0; i < 10; i++ { // Assume max 10 recovery keys
-		recoveryKeyRedisKey := fmt.Sprintf("%s%s:%d", totpRecoveryPrefix, userID, i)
-		// Don't care about errors here - best effort cleanup
-		s.redisClient.Delete(ctx, recoveryKeyRedisKey)
-	}
+	s.invalidateTOTPState(ctx, userID)
+	s.recordMFAChange(ctx, userID, security.EVENT_MFA_DISABLED, MFA_METHOD_TOTP)
 
 	s.logger.Info("TOTP disabled successfully", "userID", userID)
 	return nil
 }
 
-// checkRecoveryKey checks if the provided code is a valid recovery key
-func (s *Service) checkRecoveryKey(ctx context.Context, userID, code string) (bool, error) {
-	if !strings.Contains(code, "-") {
-		return false, nil // Not a recovery key format
+// MigrateLegacyTOTP moves TOTP state still held only in Redis into the database.
+// Users are also migrated one at a time on first use, so this only speeds things up.
+func (s *Service) MigrateLegacyTOTP(ctx context.Context) {
+	lockName := "totp_legacy_migration"
+	acquired, err := s.redisClient.AcquireLock(ctx, lockName, 10*time.Minute, 1, 0)
+	if err != nil {
+		s.logger.Errorf("Failed to acquire TOTP migration lock: %v", err)
+		return
+	}
+	if !acquired {
+		return
 	}
 
-	// Strip hyphens for comparison
-	normalizedCode := strings.ReplaceAll(code, "-", "")
+	// Release lock when done
+	defer func() {
+		if _, err := s.redisClient.ReleaseLock(context.Background(), lockName); err != nil {
+			s.logger.Errorf("Failed to release lock %s: %v", lockName, err)
+		}
+	}()
 
-	// Check all possible recovery keys
-	for i := 0; i < 10; i++ { // Assume max 10 recovery keys
-		recoveryKeyRedisKey := fmt.Sprintf("%s%s:%d", totpRecoveryPrefix, userID, i)
-		storedHash, err := s.redisClient.Get(ctx, recoveryKeyRedisKey)
-		if err != nil || storedHash == "" {
-			continue // Key doesn't exist or error, try next one
+	keys, err := s.redisClient.ScanKeys(ctx, legacyTOTPSecretPrefix+"*")
+	if err != nil {
+		s.logger.Errorf("Failed to list legacy TOTP secrets: %v", err)
+		return
+	}
+
+	migrated := 0
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return
 		}
 
-		// Decode the stored hash to get salt and hash
-		hashData, err := base64.StdEncoding.DecodeString(storedHash)
-		if err != nil || len(hashData) < argon2SaltLength+argon2KeyLength {
-			s.logger.Error("Invalid hash data for recovery key", "key", recoveryKeyRedisKey)
+		userID := strings.TrimPrefix(key, legacyTOTPSecretPrefix)
+		if _, err := s.migrateLegacyTOTP(ctx, userID); err != nil {
+			s.logger.Errorf("Failed to migrate legacy TOTP state of %s: %v", userID, err)
 			continue
 		}
+		migrated++
+	}
 
-		// Extract salt and hash
-		salt := hashData[:argon2SaltLength]
-		storedHashPart := hashData[argon2SaltLength:]
+	if migrated > 0 {
+		s.logger.Infof("Migrated legacy TOTP state of %d users", migrated)
+	}
+}
 
-		// Hash the provided code
-		computedHash := argon2.IDKey(
-			[]byte(normalizedCode),
-			salt,
-			argon2Iterations,
-			argon2Memory,
-			argon2Parallelism,
-			argon2KeyLength,
-		)
+// getTOTPState returns the TOTP state of a user from the cache, falling back to the database
+func (s *Service) getTOTPState(ctx context.Context, userID string) (*totpState, error) {
+	var state totpState
+	if err := s.redisClient.GetJSON(ctx, totpStatePrefix+userID, &state); err == nil {
+		return &state, nil
+	}
 
-		// Compare the hashes (constant time comparison)
-		if subtle.ConstantTimeCompare(computedHash, storedHashPart) == 1 {
-			return true, nil
+	method, err := s.repo.GetTOTPMethod(userID)
+	if err != nil {
+		s.logger.Error("Failed to load TOTP state", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	if method == nil || method.Secret == "" {
+		// Users set up before TOTP moved to the database only have it in Redis
+		legacy, err := s.migrateLegacyTOTP(ctx, userID)
+		if err != nil {
+			s.logger.Error("Failed to migrate legacy TOTP state", "userID", userID, "error", err)
+			return nil, err
+		}
+		if legacy != nil {
+			return legacy, nil
 		}
+	} else {
+		state = totpState{Secret: method.Secret, Enabled: method.Enabled}
 	}
 
-	return false, nil
+	if err := s.redisClient.SetJSON(ctx, totpStatePrefix+userID, state, totpStateCacheExpiry); err != nil {
+		s.logger.Warn("Failed to cache TOTP state", "userID", userID, "error", err)
+	}
+	return &state, nil
 }
 
-// removeRecoveryKey removes a used recovery key
-func (s *Service) removeRecoveryKey(ctx context.Context, userID, code string) error {
-	if !strings.Contains(code, "-") {
-		return nil // Not a recovery key format
+// invalidateTOTPState drops the cached TOTP state of a user after a change
+func (s *Service) invalidateTOTPState(ctx context.Context, userID string) {
+	if _, err := s.redisClient.Delete(ctx, totpStatePrefix+userID); err != nil {
+		s.logger.Warn("Failed to invalidate TOTP state", "userID", userID, "error", err)
 	}
+}
 
-	// Strip hyphens for comparison
-	normalizedCode := strings.ReplaceAll(code, "-", "")
+// migrateLegacyTOTP copies the Redis-held TOTP state of a user into the database and
+// removes the Redis keys. It returns nil when the user has no legacy state.
+func (s *Service) migrateLegacyTOTP(ctx context.Context, userID string) (*totpState, error) {
+	secret, err := s.redisClient.Get(ctx, legacyTOTPSecretPrefix+userID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, nil
+	}
 
-	// Find and remove the used recovery key
-	for i := 0; i < 10; i++ { // Assume max 10 recovery keys
-		recoveryKeyRedisKey := fmt.Sprintf("%s%s:%d", totpRecoveryPrefix, userID, i)
-		storedHash, err := s.redisClient.Get(ctx, recoveryKeyRedisKey)
-		if err != nil || storedHash == "" {
-			continue // Key doesn't exist or error, try next one
-		}
+	enabled, err := s.redisClient.SIsMember(ctx, legacyTOTPEnabledPrefix+userID, "true")
+	if err != nil {
+		return nil, err
+	}
 
-		// Decode the stored hash to get salt and hash
-		hashData, err := base64.StdEncoding.DecodeString(storedHash)
-		if err != nil || len(hashData) < argon2SaltLength+argon2KeyLength {
-			continue
+	keys := []string{legacyTOTPSecretPrefix + userID, legacyTOTPEnabledPrefix + userID}
+	recoveryKeyHashes := []string{}
+	for i := 0; i < recoveryKeyCount; i++ {
+		key := fmt.Sprintf("%s%s:%d", legacyTOTPRecoveryPrefix, userID, i)
+		keys = append(keys, key)
+
+		hash, err := s.redisClient.Get(ctx, key)
+		if err != nil {
+			return nil, err
 		}
+		if hash != "" {
+			recoveryKeyHashes = append(recoveryKeyHashes, hash)
+		}
+	}
+
+	if err := s.repo.ImportTOTP(userID, secret, enabled, recoveryKeyHashes); err != nil {
+		return nil, err
+	}
 
-		// Extract salt and hash
-		salt := hashData[:argon2SaltLength]
-		storedHashPart := hashData[argon2SaltLength:]
+	// The database is the source of truth from here on
+	if _, err := s.redisClient.DeleteMany(ctx, keys...); err != nil {
+		s.logger.Warn("Failed to delete legacy TOTP keys", "userID", userID, "error", err)
+	}
+	s.invalidateTOTPState(ctx, userID)
+	s.logger.Info("Migrated legacy TOTP state", "userID", userID)
 
-		// Hash the provided code
-		computedHash := argon2.IDKey(
-			[]byte(normalizedCode),
-			salt,
-			argon2Iterations,
-			argon2Memory,
-			argon2Parallelism,
-			argon2KeyLength,
-		)
+	// Read back what was kept, which may be a newer setup than the one imported
+	method, err := s.repo.GetTOTPMethod(userID)
+	if err != nil {
+		return nil, err
+	}
+	if method == nil {
+		return &totpState{}, nil
+	}
+	return &totpState{Secret: method.Secret, Enabled: method.Enabled}, nil
+}
 
-		// Compare the hashes
-		if subtle.ConstantTimeCompare(computedHash, storedHashPart) == 1 {
-			// Delete the recovery key
-			_, err := s.redisClient.Delete(ctx, recoveryKeyRedisKey)
-			if err != nil {
-				s.logger.Error("Failed to delete recovery key", "key", recoveryKeyRedisKey, "error", err)
-				return err
-			}
-			return nil
+// validateTOTP checks a TOTP code against a secret
+func (s *Service) validateTOTP(code, secret string) (bool, error) {
+	return totp.ValidateCustom(
+		code,
+		secret,
+		time.Now().UTC(),
+		totp.ValidateOpts{
+			Digits:    s.config.TOTPDigits,
+			Period:    s.config.TOTPPeriod,
+			Skew:      s.config.TOTPSkew,
+			Algorithm: s.config.TOTPAlgorithm,
+		},
+	)
+}
+
+// useRecoveryKey checks the provided code against the stored recovery keys and consumes the one it matches
+func (s *Service) useRecoveryKey(userID, code string) (bool, error) {
+	settings, err := s.repo.GetMFASettings(userID)
+	if err != nil || settings == nil {
+		return false, err
+	}
+
+	normalizedCode := normalizeRecoveryKey(code)
+	for _, storedHash := range settings.BackupCodes {
+		if !recoveryKeyMatches(normalizedCode, storedHash) {
+			continue
 		}
+
+		// Only one request can remove the key, so a recovery key works exactly once
+		return s.repo.ConsumeBackupCode(userID, storedHash)
 	}
 
-	return nil
+	return false, nil
 }
 
 // generateRecoveryKeys generates a set of recovery keys
@@ -1621,215 +1018,137 @@ func (s *Service) generateRecoveryKeys(count int) ([]string, error) {
 		encoded := strings.ToUpper(base32.StdEncoding.EncodeToString(b))
 		encoded = encoded[:16] // Trim any padding
 
-		// Format as XXXX-XXXX-XXXX-XXXX
-		formatted := fmt.Sprintf("%s-%s-%s-%s",
-			encoded[0:4], encoded[4:8], encoded[8:12], encoded[12:16])
-
-		keys[i] = formatted
+		keys[i] = formatRecoveryKey(encoded)
 	}
 
 	return keys, nil
 }
 
-// sendEmailFast sends an email using the SMTP connection pool
-func (s *Service) sendEmailFast(to []string, subject, htmlBody, textBody string) error {
-	// Get a client from the pool
-	client, err := smtpPool.getClient()
-	if err != nil {
-		return &EmailSendError{
-			Email: to[0],
-			Err:   err,
-		}
-	}
-	defer smtpPool.releaseClient(client)
-
-	// Create a MIME message with multipart/alternative
-	boundary := "==CirrusSyncBoundary=="
-
-	// Compose email message with explicit From header and multipart content
-	message := fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
-		"Content-Type: multipart/alternative; boundary=\"%s\"\r\n"+
-		"\r\n"+
-		"--%s\r\n"+
-		"Content-Type: text/plain; charset=UTF-8\r\n"+
-		"Content-Transfer-Encoding: 7bit\r\n"+
-		"\r\n"+
-		"%s\r\n"+
-		"\r\n"+
-		"--%s\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"Content-Transfer-Encoding: 7bit\r\n"+
-		"\r\n"+
-		"%s\r\n"+
-		"\r\n"+
-		"--%s--\r\n",
-		to[0],
-		s.config.FromEmail,
-		subject,
-		boundary,
-		boundary,
-		textBody,
-		boundary,
-		htmlBody,
-		boundary)
-
-	client.mu.Lock()
-	defer client.mu.Unlock()
-
-	// Reset the connection for a new message
-	if err := client.client.Reset(); err != nil {
-		// If reset fails, create a new connection
-		newClient, createErr := smtpPool.createClient()
-		if createErr != nil {
-			return &EmailSendError{
-				Email: to[0],
-				Err:   createErr,
-			}
-		}
-		// Replace the old client
-		client = newClient
-	}
+// normalizeRecoveryKey strips hyphens and spaces from a recovery key and uppercases it
+func normalizeRecoveryKey(key string) string {
+	key = strings.ReplaceAll(key, "-", "")
+	key = strings.ReplaceAll(key, " ", "")
+	return strings.ToUpper(key)
+}
 
-	// Set the sender and recipients
-	if err := client.client.Mail(s.config.FromEmail); err != nil {
-		return &EmailSendError{
-			Email: to[0],
-			Err:   fmt.Errorf("failed to set sender: %w", err),
-		}
+// formatRecoveryKey formats a normalized recovery key as XXXX-XXXX-XXXX-XXXX
+func formatRecoveryKey(key string) string {
+	if len(key) != 16 {
+		return key
 	}
+	return fmt.Sprintf("%s-%s-%s-%s", key[0:4], key[4:8], key[8:12], key[12:16])
+}
 
-	for _, addr := range to {
-		if err := client.client.Rcpt(addr); err != nil {
-			return &EmailSendError{
-				Email: addr,
-				Err:   fmt.Errorf("failed to set recipient: %w", err),
-			}
-		}
+// hashRecoveryKey hashes a recovery key with argon2, storing the salt in front of the hash
+func hashRecoveryKey(key string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
 	}
 
-	// Send the email body
-	w, err := client.client.Data()
-	if err != nil {
-		return &EmailSendError{
-			Email: to[0],
-			Err:   fmt.Errorf("failed to open data writer: %w", err),
-		}
-	}
+	hash := argon2.IDKey(
+		[]byte(key),
+		salt,
+		argon2Iterations,
+		argon2Memory,
+		argon2Parallelism,
+		argon2KeyLength,
+	)
 
-	_, err = w.Write([]byte(message))
-	if err != nil {
-		return &EmailSendError{
-			Email: to[0],
-			Err:   fmt.Errorf("failed to write email body: %w", err),
-		}
+	// Combine salt and hash for storage
+	return base64.StdEncoding.EncodeToString(append(salt, hash...)), nil
+}
+
+// recoveryKeyMatches compares a normalized recovery key with a stored hash. Keys migrated
+// from Redis were hashed in their hyphenated form, so that form is tried as well.
+func recoveryKeyMatches(normalizedKey, storedHash string) bool {
+	// Decode the stored hash to get salt and hash
+	hashData, err := base64.StdEncoding.DecodeString(storedHash)
+	if err != nil || len(hashData) < argon2SaltLength+argon2KeyLength {
+		return false
 	}
 
-	err = w.Close()
-	if err != nil {
-		return &EmailSendError{
-			Email: to[0],
-			Err:   fmt.Errorf("failed to close data writer: %w", err),
+	// Extract salt and hash
+	salt := hashData[:argon2SaltLength]
+	storedHashPart := hashData[argon2SaltLength:]
+
+	for _, candidate := range []string{normalizedKey, formatRecoveryKey(normalizedKey)} {
+		computedHash := argon2.IDKey(
+			[]byte(candidate),
+			salt,
+			argon2Iterations,
+			argon2Memory,
+			argon2Parallelism,
+			argon2KeyLength,
+		)
+
+		// Compare the hashes (constant time comparison)
+		if subtle.ConstantTimeCompare(computedHash, storedHashPart) == 1 {
+			return true
 		}
 	}
 
-	return nil
+	return false
 }
 
-// sendEmail is a fallback for when we need to ensure delivery
-func (s *Service) sendEmail(to []string, subject, htmlBody, textBody string) error {
-	// Create a MIME message with multipart/alternative
-	boundary := "==CirrusSyncBoundary=="
-
-	// Compose email message with explicit From header and multipart content
-	message := fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
-		"Content-Type: multipart/alternative; boundary=\"%s\"\r\n"+
-		"\r\n"+
-		"--%s\r\n"+
-		"Content-Type: text/plain; charset=UTF-8\r\n"+
-		"Content-Transfer-Encoding: 7bit\r\n"+
-		"\r\n"+
-		"%s\r\n"+
-		"\r\n"+
-		"--%s\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"Content-Transfer-Encoding: 7bit\r\n"+
-		"\r\n"+
-		"%s\r\n"+
-		"\r\n"+
-		"--%s--\r\n",
-		to[0],
-		s.config.FromEmail,
-		subject,
-		boundary,
-		boundary,
-		textBody,
-		boundary,
-		htmlBody,
-		boundary)
-
-	// SMTP authentication
-	auth := smtp.PlainAuth("", s.config.SMTPUsername, s.config.SMTPPassword, s.config.SMTPHost)
-
-	// Create TLS config
-	tlsConfig := &tls.Config{
-		ServerName: s.config.SMTPHost,
-	}
-
-	// Connect to the server
-	smtpAddr := fmt.Sprintf("%s:%d", s.config.SMTPHost, s.config.SMTPPort)
-
-	// Create the SMTP client
-	client, err := smtp.Dial(smtpAddr)
-	if err != nil {
-		return fmt.Errorf("failed to dial SMTP server: %w", err)
+// sendEmailFast sends an email through the configured provider. With a job service the email is
+// queued, so it survives restarts and is retried with backoff; otherwise it is sent right away and
+// retried in process. Emails that fail every attempt end up in the dead-letter queue.
+func (s *Service) sendEmailFast(to []string, subject, htmlBody, textBody string) error {
+	if s.dispatcher == nil {
+		return &EmailSendError{Email: to[0], Err: ErrEmailNotConfigured}
 	}
-	defer client.Close()
 
-	// Start TLS
-	if err = client.StartTLS(tlsConfig); err != nil {
-		return fmt.Errorf("failed to start TLS: %w", err)
+	if s.jobService != nil {
+		_, err := s.jobService.Enqueue(context.Background(), "", JOB_TYPE_EMAIL_SEND, map[string]string{
+			"to":       strings.Join(to, ","),
+			"subject":  subject,
+			"htmlBody": htmlBody,
+			"textBody": textBody,
+		})
+		if err != nil {
+			return &EmailSendError{Email: to[0], Err: err}
+		}
+		return nil
 	}
 
-	// Authenticate
-	if err = client.Auth(auth); err != nil {
-		return fmt.Errorf("failed to authenticate: %w", err)
+	message := &mailer.Message{Subject: subject, HTMLBody: htmlBody, TextBody: textBody}
+	if err := s.dispatcher.Send(context.Background(), to, message); err != nil {
+		return &EmailSendError{Email: to[0], Err: err}
 	}
+	return nil
+}
 
-	// Set the sender and recipients
-	if err = client.Mail(s.config.FromEmail); err != nil {
-		return fmt.Errorf("failed to set sender: %w", err)
+// runEmailSendJob makes one attempt to send a queued email. The job service retries it with backoff;
+// when the last attempt failed or the provider rejected it, the email is dead-lettered.
+func (s *Service) runEmailSendJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if s.dispatcher == nil {
+		return jobs.Permanent(ErrEmailNotConfigured)
 	}
 
-	for _, addr := range to {
-		if err = client.Rcpt(addr); err != nil {
-			return fmt.Errorf("failed to set recipient: %w", err)
-		}
+	to := strings.Split(job.Payload["to"], ",")
+	message := &mailer.Message{
+		Subject:  job.Payload["subject"],
+		HTMLBody: job.Payload["htmlBody"],
+		TextBody: job.Payload["textBody"],
 	}
 
-	// Send the email body
-	w, err := client.Data()
-	if err != nil {
-		return fmt.Errorf("failed to open data writer: %w", err)
+	err := s.dispatcher.SendOnce(ctx, to, message)
+	if err == nil {
+		progress(1, 1, nil)
+		return nil
 	}
-
-	_, err = w.Write([]byte(message))
-	if err != nil {
-		return fmt.Errorf("failed to write email body: %w", err)
+	// Shutting down; the job runs again after the restart
+	if ctx.Err() != nil {
+		return err
 	}
 
-	err = w.Close()
-	if err != nil {
-		return fmt.Errorf("failed to close data writer: %w", err)
+	var permanent *mailer.PermanentError
+	if errors.As(err, &permanent) || job.Attempts >= jobs.MAX_ATTEMPTS {
+		s.dispatcher.AddDeadLetter(to, message, err, job.Attempts)
+		return jobs.Permanent(err)
 	}
-
-	// Close connection
-	return client.Quit()
+	return err
 }
 
 // formatTimeRemaining formats a duration in a user-friendly way
@@ -0,0 +1,373 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/sms"
+
+	"gorm.io/gorm"
+)
+
+// MFA_METHOD_SMS is the phone based second factor
+const MFA_METHOD_SMS = "sms"
+
+const (
+	// Redis key prefixes
+	smsCodePrefix      = "mfa:sms:code:"       // Pending code per user and intent
+	smsAttemptsPrefix  = "mfa:sms:attempts:"   // Failed attempts against a pending code
+	smsLastSentPrefix  = "mfa:last_sent:sms:"  // Last time a code was sent to a number for an intent
+	smsCountPrefix     = "mfa:count:sms:"      // Codes sent to a number in the rate limit window
+	smsUserCountPrefix = "mfa:count:sms_user:" // Codes requested by a user in the rate limit window
+
+	// Reasons an SMS code is sent
+	smsIntentEnroll  = "enroll"
+	smsIntentDisable = "disable"
+
+	oneTimeCodeDigits  = 6
+	maxSMSCodeAttempts = 5
+)
+
+// SMSCodeResult describes a code that was just sent
+type SMSCodeResult struct {
+	MaskedPhoneNumber string // Number the code went to, all but the last digits hidden
+	ExpiresAt         int64  // When the code stops being accepted
+	RemainingRequests int    // Codes that can still be sent to the number in the current window
+}
+
+// PhoneMFAStatus describes the phone second factor of a user
+type PhoneMFAStatus struct {
+	Available         bool   // Whether SMS can be sent at all
+	Enabled           bool   // Whether the user has turned on the phone method
+	MaskedPhoneNumber string // The verified number codes are sent to, if any
+}
+
+// pendingSMSCode is a code waiting to be entered. Only its hash is stored.
+type pendingSMSCode struct {
+	PhoneNumber string `json:"phoneNumber"`
+	CodeHash    string `json:"codeHash"`
+}
+
+// SetSMSProvider enables sending SMS codes. Without a provider every SMS operation fails with ErrSMSUnavailable.
+func (s *Service) SetSMSProvider(provider sms.Provider) {
+	s.smsProvider = provider
+}
+
+// GetPhoneMFAStatus reports whether the user has the phone second factor turned on
+func (s *Service) GetPhoneMFAStatus(ctx context.Context, userID string) (*PhoneMFAStatus, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	result := &PhoneMFAStatus{Available: s.smsProvider != nil}
+
+	method, err := s.repo.GetPhoneMethod(userID)
+	if err != nil {
+		s.logger.Error("Failed to load phone method", "userID", userID, "error", err)
+		return nil, ErrOperationFailed
+	}
+	if method == nil || !method.Enabled {
+		return result, nil
+	}
+
+	user, err := s.repo.FindUserByID(userID)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+	result.Enabled = true
+	if user.PhoneNumber != nil {
+		result.MaskedPhoneNumber = sms.MaskPhoneNumber(*user.PhoneNumber)
+	}
+
+	return result, nil
+}
+
+// IsPhoneMFAEnabled checks if the phone second factor is turned on for a user
+func (s *Service) IsPhoneMFAEnabled(ctx context.Context, userID string) (bool, error) {
+	method, err := s.repo.GetPhoneMethod(userID)
+	if err != nil {
+		s.logger.Error("Failed to load phone method", "userID", userID, "error", err)
+		return false, ErrOperationFailed
+	}
+	return method != nil && method.Enabled, nil
+}
+
+// StartPhoneEnrollment sends a code to the phone number the user wants to use as a second factor
+func (s *Service) StartPhoneEnrollment(ctx context.Context, userID, phoneNumber string) (*SMSCodeResult, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+	if s.smsProvider == nil {
+		return nil, ErrSMSUnavailable
+	}
+
+	phoneNumber, err := sms.NormalizePhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, ErrInvalidPhoneNumber
+	}
+
+	enabled, err := s.IsPhoneMFAEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrPhoneMFAAlreadyEnabled
+	}
+
+	if err := s.checkPhoneNumberAvailable(userID, phoneNumber); err != nil {
+		return nil, err
+	}
+
+	return s.sendSMSCode(ctx, userID, smsIntentEnroll, phoneNumber)
+}
+
+// VerifyPhoneEnrollment checks the enrollment code and turns on the phone second factor
+func (s *Service) VerifyPhoneEnrollment(ctx context.Context, userID, code string) error {
+	if userID == "" || code == "" {
+		return ErrInvalidInput
+	}
+
+	phoneNumber, err := s.checkSMSCode(ctx, userID, smsIntentEnroll, code)
+	if err != nil {
+		return err
+	}
+
+	// The number may have been claimed by another account while the code was pending
+	if err := s.checkPhoneNumberAvailable(userID, phoneNumber); err != nil {
+		return err
+	}
+
+	if err := s.repo.EnablePhoneMethod(userID, phoneNumber); err != nil {
+		s.logger.Error("Failed to enable phone method", "userID", userID, "error", err)
+		return ErrOperationFailed
+	}
+	s.recordMFAChange(ctx, userID, security.EVENT_MFA_ENABLED, MFA_METHOD_SMS)
+
+	return nil
+}
+
+// StartPhoneMFADisable sends a code to the enrolled phone number to confirm turning the method off
+func (s *Service) StartPhoneMFADisable(ctx context.Context, userID string) (*SMSCodeResult, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+	if s.smsProvider == nil {
+		return nil, ErrSMSUnavailable
+	}
+
+	phoneNumber, err := s.enrolledPhoneNumber(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sendSMSCode(ctx, userID, smsIntentDisable, phoneNumber)
+}
+
+// DisablePhoneMFA checks the confirmation code and turns off the phone second factor
+func (s *Service) DisablePhoneMFA(ctx context.Context, userID, code string) error {
+	if userID == "" || code == "" {
+		return ErrInvalidInput
+	}
+
+	if _, err := s.enrolledPhoneNumber(ctx, userID); err != nil {
+		return err
+	}
+
+	if _, err := s.checkSMSCode(ctx, userID, smsIntentDisable, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DisablePhoneMethod(userID); err != nil {
+		s.logger.Error("Failed to disable phone method", "userID", userID, "error", err)
+		return ErrOperationFailed
+	}
+	s.recordMFAChange(ctx, userID, security.EVENT_MFA_DISABLED, MFA_METHOD_SMS)
+
+	return nil
+}
+
+// enrolledPhoneNumber returns the number of a user whose phone method is turned on
+func (s *Service) enrolledPhoneNumber(ctx context.Context, userID string) (string, error) {
+	enabled, err := s.IsPhoneMFAEnabled(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return "", ErrPhoneMFANotEnabled
+	}
+
+	user, err := s.repo.FindUserByID(userID)
+	if err != nil {
+		return "", ErrInvalidInput
+	}
+	if user.PhoneNumber == nil || *user.PhoneNumber == "" {
+		return "", ErrPhoneMFANotEnabled
+	}
+
+	return *user.PhoneNumber, nil
+}
+
+// checkPhoneNumberAvailable makes sure no other account uses a phone number
+func (s *Service) checkPhoneNumberAvailable(userID, phoneNumber string) error {
+	owner, err := s.repo.FindUserByPhoneNumber(phoneNumber)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		s.logger.Error("Failed to check phone number", "userID", userID, "error", err)
+		return ErrOperationFailed
+	}
+	if owner.ID != userID {
+		return ErrPhoneNumberInUse
+	}
+	return nil
+}
+
+// sendSMSCode issues a new code for an intent, replacing any pending one, and texts it to the number.
+// It applies the same cooldown and window limits as verification emails.
+func (s *Service) sendSMSCode(ctx context.Context, userID, intent, phoneNumber string) (*SMSCodeResult, error) {
+	canSend, err := s.canSendSMS(ctx, userID, phoneNumber, intent)
+	if err != nil {
+		s.logger.Error("Failed to check SMS rate limit", "userID", userID, "intent", intent, "error", err)
+		return nil, ErrOperationFailed
+	}
+	if !canSend {
+		return nil, ErrRateLimitExceeded
+	}
+
+	code, err := generateOneTimeCode()
+	if err != nil {
+		return nil, &TokenGenerationError{Err: err}
+	}
+
+	key := smsCodePrefix + userID + ":" + intent
+	pending := pendingSMSCode{PhoneNumber: phoneNumber, CodeHash: hashOneTimeCode(userID, code)}
+	if err := s.redisClient.SetJSON(ctx, key, pending, s.config.SMSCodeExpiry); err != nil {
+		s.logger.Error("Failed to store SMS code", "userID", userID, "error", err)
+		return nil, ErrOperationFailed
+	}
+	_, _ = s.redisClient.Delete(ctx, smsAttemptsPrefix+userID+":"+intent)
+
+	body := fmt.Sprintf("Your CirrusSync verification code is %s. It expires in %s. Never share this code.", code, s.formatDuration(s.config.SMSCodeExpiry))
+	if err := s.smsProvider.Send(ctx, phoneNumber, body); err != nil {
+		s.logger.Error("Failed to send SMS code", "userID", userID, "intent", intent, "error", err)
+		_, _ = s.redisClient.Delete(ctx, key)
+		return nil, ErrFailedToSendSMS
+	}
+
+	sent, err := s.trackSMSSent(ctx, userID, phoneNumber, intent)
+	if err != nil {
+		s.logger.Error("Failed to track SMS sending", "userID", userID, "error", err)
+	}
+
+	remaining := maxEmailsPerWindow - int(sent)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &SMSCodeResult{
+		MaskedPhoneNumber: sms.MaskPhoneNumber(phoneNumber),
+		ExpiresAt:         time.Now().Add(s.config.SMSCodeExpiry).Unix(),
+		RemainingRequests: remaining,
+	}, nil
+}
+
+// checkSMSCode verifies a code for an intent and consumes it, returning the number it was sent to.
+// A code is dropped after too many wrong guesses.
+func (s *Service) checkSMSCode(ctx context.Context, userID, intent, code string) (string, error) {
+	key := smsCodePrefix + userID + ":" + intent
+	attemptsKey := smsAttemptsPrefix + userID + ":" + intent
+
+	var pending pendingSMSCode
+	if err := s.redisClient.GetJSON(ctx, key, &pending); err != nil || pending.CodeHash == "" {
+		return "", ErrSMSCodeExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashOneTimeCode(userID, code)), []byte(pending.CodeHash)) != 1 {
+		attempts, _ := s.Increment(ctx, attemptsKey, s.config.SMSCodeExpiry)
+		if attempts >= maxSMSCodeAttempts {
+			_, _ = s.redisClient.DeleteMany(ctx, key, attemptsKey)
+			return "", ErrTooManySMSAttempts
+		}
+		return "", ErrInvalidSMSCode
+	}
+
+	deleted, err := s.redisClient.Delete(ctx, key)
+	if err != nil || !deleted {
+		// Another request used the code first
+		return "", ErrSMSCodeExpired
+	}
+	_, _ = s.redisClient.Delete(ctx, attemptsKey)
+
+	return pending.PhoneNumber, nil
+}
+
+// canSendSMS checks the cooldown per number and intent and the window limits per number and per user
+func (s *Service) canSendSMS(ctx context.Context, userID, phoneNumber, intent string) (bool, error) {
+	lastSent, err := s.redisClient.Get(ctx, smsLastSentPrefix+phoneNumber+":"+intent)
+	if err != nil {
+		return false, err
+	}
+	if lastSent != "" {
+		return false, nil
+	}
+
+	count, err := s.GetInt(ctx, smsCountPrefix+phoneNumber)
+	if err != nil {
+		return false, err
+	}
+	if count >= maxEmailsPerWindow {
+		return false, nil
+	}
+
+	// A user cycling through numbers is limited as well
+	userCount, err := s.GetInt(ctx, smsUserCountPrefix+userID)
+	if err != nil {
+		return false, err
+	}
+	return userCount < maxEmailsPerWindow*2, nil
+}
+
+// trackSMSSent records a sent code against the rate limits and returns the number's count in the window
+func (s *Service) trackSMSSent(ctx context.Context, userID, phoneNumber, intent string) (int64, error) {
+	if err := s.redisClient.Set(ctx, smsLastSentPrefix+phoneNumber+":"+intent, time.Now().Unix(), singleEmailExpiry); err != nil {
+		return 0, err
+	}
+
+	countKey := smsCountPrefix + phoneNumber
+	count, err := s.Increment(ctx, countKey, windowRateLimitExpiry)
+	if err != nil {
+		return 0, err
+	}
+
+	userCountKey := smsUserCountPrefix + userID
+	if _, err := s.Increment(ctx, userCountKey, windowRateLimitExpiry); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// generateOneTimeCode returns a random numeric code for SMS and email verification
+func generateOneTimeCode() (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(oneTimeCodeDigits), nil)
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", oneTimeCodeDigits, n), nil
+}
+
+// hashOneTimeCode binds a code to its user so codes are never stored in the clear
+func hashOneTimeCode(userID, code string) string {
+	sum := sha256.Sum256([]byte(userID + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
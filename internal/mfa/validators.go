@@ -54,5 +54,5 @@ func NormalizeTOTPCode(code string) string {
 
 // isValidIntent checks if the intent is valid
 func isValidIntent(intent string) bool {
-	return intent == "signup" || intent == "password-reset" || intent == "2fa"
+	return intent == "signup" || intent == "password-reset" || intent == "2fa" || intent == "email-change"
 }
@@ -0,0 +1,501 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+
+	"gorm.io/gorm"
+)
+
+// MFA methods a user can prefer and complete login with
+const (
+	MFA_METHOD_WEBAUTHN = "webauthn"
+	MFA_METHOD_TOTP     = "totp"
+)
+
+// loginMethodOrder is the fallback order of second factors when the preferred one is unavailable
+var loginMethodOrder = []string{MFA_METHOD_WEBAUTHN, MFA_METHOD_TOTP}
+
+const (
+	// Redis key prefixes
+	webauthnRegisterPrefix = "mfa:webauthn:register:" // Pending registration challenge per user
+	webauthnLoginPrefix    = "mfa:webauthn:login:"    // Pending login challenge per login ceremony
+
+	// Ceremony types found in the collected client data
+	webauthnCreateType = "webauthn.create"
+	webauthnGetType    = "webauthn.get"
+
+	maxPasskeysPerUser      = 10
+	webauthnChallengeLength = 32
+	maxPasskeyNameLength    = 100
+)
+
+// webauthnChallenge is a challenge waiting for the authenticator response
+type webauthnChallenge struct {
+	UserID    string `json:"userId"`
+	Challenge string `json:"challenge"`
+}
+
+// PasskeyCredentialDescriptor identifies a registered passkey to the browser
+type PasskeyCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// PasskeyCreationOptions are the PublicKeyCredentialCreationOptions handed to navigator.credentials.create
+type PasskeyCreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int64                         `json:"timeout"`
+	Attestation            string                        `json:"attestation"`
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+}
+
+// PasskeyRequestOptions are the PublicKeyCredentialRequestOptions handed to navigator.credentials.get
+type PasskeyRequestOptions struct {
+	Challenge        string                        `json:"challenge"`
+	RPID             string                        `json:"rpId"`
+	Timeout          int64                         `json:"timeout"`
+	AllowCredentials []PasskeyCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                        `json:"userVerification"`
+}
+
+// PasskeyRegistration is the browser's response to a registration ceremony, binary fields base64url encoded
+type PasskeyRegistration struct {
+	ID                string
+	ClientDataJSON    string
+	AttestationObject string
+	Transports        []string
+	Name              string
+}
+
+// PasskeyAssertion is the browser's response to a login ceremony, binary fields base64url encoded
+type PasskeyAssertion struct {
+	ID                string
+	ClientDataJSON    string
+	AuthenticatorData string
+	Signature         string
+}
+
+// BeginPasskeyRegistration issues a challenge for registering a new passkey
+func (s *Service) BeginPasskeyRegistration(ctx context.Context, userID string) (*PasskeyCreationOptions, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	user, err := s.repo.FindUserByID(userID)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	credentials, err := s.repo.GetWebAuthnCredentials(userID)
+	if err != nil {
+		s.logger.Error("Failed to load passkeys", "userID", userID, "error", err)
+		return nil, ErrOperationFailed
+	}
+	if len(credentials) >= maxPasskeysPerUser {
+		return nil, ErrPasskeyLimitReached
+	}
+
+	challenge, err := s.storeWebAuthnChallenge(ctx, webauthnRegisterPrefix+userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &PasskeyCreationOptions{
+		Challenge:          challenge,
+		Timeout:            s.config.WebAuthnChallengeTimeout.Milliseconds(),
+		Attestation:        "none",
+		ExcludeCredentials: passkeyDescriptors(credentials),
+	}
+	options.RP.ID = s.config.WebAuthnRPID
+	options.RP.Name = s.config.WebAuthnRPName
+	options.User.ID = base64.RawURLEncoding.EncodeToString([]byte(user.ID))
+	options.User.Name = user.Email
+	options.User.DisplayName = user.Username
+	for _, alg := range supportedCOSEAlgorithms {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{Type: "public-key", Alg: alg})
+	}
+	options.AuthenticatorSelection.ResidentKey = "preferred"
+	options.AuthenticatorSelection.UserVerification = "preferred"
+
+	return options, nil
+}
+
+// FinishPasskeyRegistration verifies the authenticator response and stores the new passkey
+func (s *Service) FinishPasskeyRegistration(ctx context.Context, userID string, registration PasskeyRegistration) (*models.WebAuthnCredential, error) {
+	if userID == "" || registration.ClientDataJSON == "" || registration.AttestationObject == "" {
+		return nil, ErrInvalidInput
+	}
+
+	// Serialize registrations of the same user so the passkey limit holds
+	lockName := fmt.Sprintf("webauthn_register:%s", userID)
+	acquired, err := s.redisClient.AcquireLock(ctx, lockName, 10*time.Second, 3, 100*time.Millisecond)
+	if err != nil {
+		s.logger.Error("Error acquiring lock for passkey registration", "userID", userID, "error", err)
+		return nil, err
+	} else if !acquired {
+		return nil, ErrPasskeyOperationInProgress
+	}
+	defer func() {
+		if _, err := s.redisClient.ReleaseLock(ctx, lockName); err != nil {
+			s.logger.Error("Failed to release lock", "lock", lockName, "error", err)
+		}
+	}()
+
+	challenge, err := s.takeWebAuthnChallenge(ctx, webauthnRegisterPrefix+userID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataJSON, err := decodeBase64URL(registration.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidPasskeyResponse
+	}
+	if err := verifyClientData(clientDataJSON, webauthnCreateType, challenge.Challenge, s.config.WebAuthnOrigins); err != nil {
+		return nil, err
+	}
+
+	attestationObject, err := decodeBase64URL(registration.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidPasskeyResponse
+	}
+	rawAuthData, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+	authData, err := parseAuthenticatorData(rawAuthData, s.config.WebAuthnRPID)
+	if err != nil {
+		return nil, err
+	}
+	if authData.CredentialID == nil {
+		return nil, ErrInvalidPasskeyResponse
+	}
+
+	_, alg, err := parseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(authData.CredentialID)
+	if registration.ID != "" && trimBase64Padding(registration.ID) != credentialID {
+		return nil, ErrInvalidPasskeyResponse
+	}
+
+	credentials, err := s.repo.GetWebAuthnCredentials(userID)
+	if err != nil {
+		return nil, ErrOperationFailed
+	}
+	if len(credentials) >= maxPasskeysPerUser {
+		return nil, ErrPasskeyLimitReached
+	}
+
+	existing, err := s.repo.GetWebAuthnCredentialByCredentialID(credentialID)
+	if err == nil && existing != nil {
+		return nil, ErrPasskeyAlreadyRegistered
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOperationFailed
+	}
+
+	name := strings.TrimSpace(registration.Name)
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(credentials)+1)
+	}
+	if len(name) > maxPasskeyNameLength {
+		name = name[:maxPasskeyNameLength]
+	}
+
+	credential := &models.WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: credentialID,
+		PublicKey:    authData.PublicKey,
+		Algorithm:    alg,
+		SignCount:    int64(authData.SignCount),
+		AAGUID:       formatAAGUID(authData.AAGUID),
+		Transports:   registration.Transports,
+		Name:         name,
+	}
+	if credential.Transports == nil {
+		credential.Transports = []string{}
+	}
+
+	if err := s.repo.CreateWebAuthnCredential(credential); err != nil {
+		s.logger.Error("Failed to store passkey", "userID", userID, "error", err)
+		return nil, ErrOperationFailed
+	}
+
+	s.recordMFAChange(ctx, userID, security.EVENT_MFA_ENABLED, MFA_METHOD_WEBAUTHN)
+
+	s.logger.Info("Passkey registered", "userID", userID)
+	return credential, nil
+}
+
+// ListPasskeys returns the passkeys registered by a user
+func (s *Service) ListPasskeys(ctx context.Context, userID string) ([]models.WebAuthnCredential, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	credentials, err := s.repo.GetWebAuthnCredentials(userID)
+	if err != nil {
+		return nil, ErrOperationFailed
+	}
+	return credentials, nil
+}
+
+// DeletePasskey removes one of the user's passkeys
+func (s *Service) DeletePasskey(ctx context.Context, userID, id string) error {
+	if userID == "" || id == "" {
+		return ErrInvalidInput
+	}
+
+	if err := s.repo.DeleteWebAuthnCredential(userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPasskeyNotFound
+		}
+		return ErrOperationFailed
+	}
+
+	s.recordMFAChange(ctx, userID, security.EVENT_MFA_DISABLED, MFA_METHOD_WEBAUTHN)
+
+	s.logger.Info("Passkey removed", "userID", userID)
+	return nil
+}
+
+// HasPasskeys reports whether the user registered at least one passkey
+func (s *Service) HasPasskeys(ctx context.Context, userID string) (bool, error) {
+	credentials, err := s.repo.GetWebAuthnCredentials(userID)
+	if err != nil {
+		return false, ErrOperationFailed
+	}
+	return len(credentials) > 0, nil
+}
+
+// BeginPasskeyLogin issues a login challenge for the user's passkeys.
+// ceremonyID ties the challenge to a single login attempt so parallel logins do not clash.
+func (s *Service) BeginPasskeyLogin(ctx context.Context, userID, ceremonyID string) (*PasskeyRequestOptions, error) {
+	if userID == "" || ceremonyID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	credentials, err := s.repo.GetWebAuthnCredentials(userID)
+	if err != nil {
+		return nil, ErrOperationFailed
+	}
+	if len(credentials) == 0 {
+		return nil, ErrMFAMethodUnavailable
+	}
+
+	challenge, err := s.storeWebAuthnChallenge(ctx, webauthnLoginPrefix+ceremonyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyRequestOptions{
+		Challenge:        challenge,
+		RPID:             s.config.WebAuthnRPID,
+		Timeout:          s.config.WebAuthnChallengeTimeout.Milliseconds(),
+		AllowCredentials: passkeyDescriptors(credentials),
+		UserVerification: "preferred",
+	}, nil
+}
+
+// FinishPasskeyLogin verifies a passkey assertion for the login ceremony started by BeginPasskeyLogin
+func (s *Service) FinishPasskeyLogin(ctx context.Context, userID, ceremonyID string, assertion PasskeyAssertion) error {
+	if userID == "" || ceremonyID == "" || assertion.ID == "" {
+		return ErrInvalidInput
+	}
+
+	// The challenge is single use, a failed attempt needs a new one
+	challenge, err := s.takeWebAuthnChallenge(ctx, webauthnLoginPrefix+ceremonyID)
+	if err != nil {
+		return err
+	}
+	if challenge.UserID != userID {
+		return ErrPasskeyChallengeMismatch
+	}
+
+	credential, err := s.repo.GetWebAuthnCredentialByCredentialID(trimBase64Padding(assertion.ID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPasskeyNotFound
+		}
+		return ErrOperationFailed
+	}
+	if credential.UserID != userID {
+		return ErrPasskeyNotFound
+	}
+
+	clientDataJSON, err := decodeBase64URL(assertion.ClientDataJSON)
+	if err != nil {
+		return ErrInvalidPasskeyResponse
+	}
+	if err := verifyClientData(clientDataJSON, webauthnGetType, challenge.Challenge, s.config.WebAuthnOrigins); err != nil {
+		return err
+	}
+
+	rawAuthData, err := decodeBase64URL(assertion.AuthenticatorData)
+	if err != nil {
+		return ErrInvalidPasskeyResponse
+	}
+	authData, err := parseAuthenticatorData(rawAuthData, s.config.WebAuthnRPID)
+	if err != nil {
+		return err
+	}
+
+	signature, err := decodeBase64URL(assertion.Signature)
+	if err != nil {
+		return ErrInvalidPasskeyResponse
+	}
+	if err := verifyAssertionSignature(credential.PublicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		return err
+	}
+
+	// Authenticators without a counter always report zero, anyone else must move it forward
+	signCount := int64(authData.SignCount)
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		s.logger.Warn("Passkey signature counter did not increase", "userID", userID, "credentialID", credential.ID)
+		return ErrPasskeyCloned
+	}
+
+	if err := s.repo.UpdateWebAuthnSignCount(credential.ID, signCount); err != nil {
+		s.logger.Error("Failed to update passkey counter", "userID", userID, "error", err)
+	}
+
+	return nil
+}
+
+// GetLoginMethods returns the second factors the user can complete login with.
+// The preferred method comes first, the rest follow the default fallback order.
+func (s *Service) GetLoginMethods(ctx context.Context, userID string) ([]string, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	available := make(map[string]bool, len(loginMethodOrder))
+
+	hasPasskeys, err := s.HasPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	available[MFA_METHOD_WEBAUTHN] = hasPasskeys
+
+	totpEnabled, err := s.IsTOTPEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	available[MFA_METHOD_TOTP] = totpEnabled
+
+	settings, err := s.repo.GetMFASettings(userID)
+	if err != nil {
+		return nil, ErrOperationFailed
+	}
+
+	methods := make([]string, 0, len(loginMethodOrder))
+	if settings != nil && available[settings.PreferredMethod] {
+		methods = append(methods, settings.PreferredMethod)
+	}
+	for _, method := range loginMethodOrder {
+		if available[method] && !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods, nil
+}
+
+// SetPreferredMethod changes which second factor is offered first at login
+func (s *Service) SetPreferredMethod(ctx context.Context, userID, method string) error {
+	if userID == "" || !slices.Contains(loginMethodOrder, method) {
+		return ErrInvalidInput
+	}
+
+	methods, err := s.GetLoginMethods(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(methods, method) {
+		return ErrMFAMethodUnavailable
+	}
+
+	if err := s.repo.SetPreferredMethod(userID, method); err != nil {
+		s.logger.Error("Failed to set preferred MFA method", "userID", userID, "error", err)
+		return ErrOperationFailed
+	}
+	return nil
+}
+
+// storeWebAuthnChallenge creates a random challenge and keeps it until the ceremony finishes or times out
+func (s *Service) storeWebAuthnChallenge(ctx context.Context, key, userID string) (string, error) {
+	buf := make([]byte, webauthnChallengeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(buf)
+
+	pending := webauthnChallenge{UserID: userID, Challenge: challenge}
+	if err := s.redisClient.SetJSON(ctx, key, pending, s.config.WebAuthnChallengeTimeout); err != nil {
+		s.logger.Error("Failed to store passkey challenge", "error", err)
+		return "", ErrOperationFailed
+	}
+
+	return challenge, nil
+}
+
+// takeWebAuthnChallenge loads a pending challenge and deletes it so it cannot be replayed
+func (s *Service) takeWebAuthnChallenge(ctx context.Context, key string) (*webauthnChallenge, error) {
+	var pending webauthnChallenge
+	if err := s.redisClient.GetJSON(ctx, key, &pending); err != nil || pending.Challenge == "" {
+		return nil, ErrPasskeyChallengeExpired
+	}
+
+	deleted, err := s.redisClient.Delete(ctx, key)
+	if err != nil || !deleted {
+		// Another request consumed the challenge first
+		return nil, ErrPasskeyChallengeExpired
+	}
+
+	return &pending, nil
+}
+
+// passkeyDescriptors lists passkeys in the form browsers expect in allow and exclude lists
+func passkeyDescriptors(credentials []models.WebAuthnCredential) []PasskeyCredentialDescriptor {
+	descriptors := make([]PasskeyCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, PasskeyCredentialDescriptor{
+			Type:       "public-key",
+			ID:         credential.CredentialID,
+			Transports: credential.Transports,
+		})
+	}
+	return descriptors
+}
@@ -0,0 +1,360 @@
+package mfa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+)
+
+// COSE algorithm identifiers accepted for passkeys, in order of preference
+const (
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+// supportedCOSEAlgorithms is offered to authenticators in registration options
+var supportedCOSEAlgorithms = []int{coseAlgES256, coseAlgEdDSA, coseAlgRS256}
+
+// Authenticator data flags
+const (
+	authDataFlagUserPresent  = 0x01
+	authDataFlagAttestedData = 0x40
+)
+
+// Maximum nesting of CBOR containers accepted from a client
+const maxCBORDepth = 8
+
+var errMalformedCBOR = errors.New("malformed CBOR")
+
+// clientData is the subset of the collected client data checked by the relying party
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// authenticatorData is the parsed authenticator data of a registration or assertion
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // Raw COSE key bytes, only present on registration
+}
+
+// decodeBase64URL decodes the unpadded base64url encoding browsers use for binary WebAuthn fields
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(trimBase64Padding(value))
+}
+
+// trimBase64Padding drops trailing padding some clients still append
+func trimBase64Padding(value string) string {
+	for len(value) > 0 && value[len(value)-1] == '=' {
+		value = value[:len(value)-1]
+	}
+	return value
+}
+
+// verifyClientData checks the ceremony type, challenge and origin of the collected client data
+func verifyClientData(raw []byte, ceremonyType, challenge string, origins []string) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ErrInvalidPasskeyResponse
+	}
+
+	if data.Type != ceremonyType {
+		return ErrInvalidPasskeyResponse
+	}
+	if subtle.ConstantTimeCompare([]byte(trimBase64Padding(data.Challenge)), []byte(challenge)) != 1 {
+		return ErrPasskeyChallengeMismatch
+	}
+	if data.CrossOrigin || !slices.Contains(origins, data.Origin) {
+		return ErrPasskeyOriginMismatch
+	}
+
+	return nil
+}
+
+// parseAuthenticatorData parses authenticator data and checks it was produced for our relying party
+func parseAuthenticatorData(raw []byte, rpID string) (*authenticatorData, error) {
+	// rpIdHash (32) + flags (1) + signCount (4)
+	if len(raw) < 37 {
+		return nil, ErrInvalidPasskeyResponse
+	}
+
+	data := &authenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	expected := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(data.RPIDHash, expected[:]) {
+		return nil, ErrPasskeyOriginMismatch
+	}
+	if data.Flags&authDataFlagUserPresent == 0 {
+		return nil, ErrInvalidPasskeyResponse
+	}
+
+	if data.Flags&authDataFlagAttestedData == 0 {
+		return data, nil
+	}
+
+	// aaguid (16) + credentialIdLength (2) + credentialId + credentialPublicKey
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, ErrInvalidPasskeyResponse
+	}
+	data.AAGUID = rest[:16]
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLength == 0 || len(rest) < idLength {
+		return nil, ErrInvalidPasskeyResponse
+	}
+	data.CredentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// The public key is followed by optional extensions, keep only its own bytes
+	_, remaining, err := decodeCBOR(rest, 0)
+	if err != nil {
+		return nil, ErrInvalidPasskeyResponse
+	}
+	data.PublicKey = rest[:len(rest)-len(remaining)]
+
+	return data, nil
+}
+
+// parseAttestationObject extracts the authenticator data from an attestation object.
+// Registration options ask for no attestation, so the statement is never used for trust decisions.
+func parseAttestationObject(raw []byte) ([]byte, error) {
+	value, rest, err := decodeCBOR(raw, 0)
+	if err != nil || len(rest) != 0 {
+		return nil, ErrInvalidPasskeyResponse
+	}
+
+	object, ok := value.(map[any]any)
+	if !ok {
+		return nil, ErrInvalidPasskeyResponse
+	}
+	if _, ok := object["fmt"].(string); !ok {
+		return nil, ErrInvalidPasskeyResponse
+	}
+	authData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidPasskeyResponse
+	}
+
+	return authData, nil
+}
+
+// parseCOSEKey decodes a COSE public key and returns it with its algorithm
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int, error) {
+	value, _, err := decodeCBOR(raw, 0)
+	if err != nil {
+		return nil, 0, ErrUnsupportedPasskey
+	}
+	key, ok := value.(map[any]any)
+	if !ok {
+		return nil, 0, ErrUnsupportedPasskey
+	}
+
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+
+	switch {
+	case kty == 2 && alg == coseAlgES256:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if curve != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrUnsupportedPasskey
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, 0, ErrUnsupportedPasskey
+		}
+		return publicKey, coseAlgES256, nil
+
+	case kty == 1 && alg == coseAlgEdDSA:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if curve != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, ErrUnsupportedPasskey
+		}
+		return ed25519.PublicKey(x), coseAlgEdDSA, nil
+
+	case kty == 3 && alg == coseAlgRS256:
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, ErrUnsupportedPasskey
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, coseAlgRS256, nil
+	}
+
+	return nil, 0, ErrUnsupportedPasskey
+}
+
+// verifyAssertionSignature checks the authenticator signature over authData || SHA-256(clientDataJSON)
+func verifyAssertionSignature(coseKey []byte, authData, clientDataJSON, signature []byte) error {
+	publicKey, alg, err := parseCOSEKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(slices.Clone(authData), clientDataHash[:]...)
+	digest := sha256.Sum256(signed)
+
+	valid := false
+	switch alg {
+	case coseAlgES256:
+		valid = ecdsa.VerifyASN1(publicKey.(*ecdsa.PublicKey), digest[:], signature)
+	case coseAlgEdDSA:
+		valid = ed25519.Verify(publicKey.(ed25519.PublicKey), signed, signature)
+	case coseAlgRS256:
+		valid = rsa.VerifyPKCS1v15(publicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !valid {
+		return ErrInvalidPasskeySignature
+	}
+	return nil
+}
+
+// formatAAGUID renders an authenticator model identifier in its usual UUID form
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	encoded := hex.EncodeToString(aaguid)
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:32]
+}
+
+// decodeCBOR decodes a single definite-length CBOR item and returns it with the remaining bytes.
+// Only the subset WebAuthn uses is supported: integers, byte and text strings, arrays, maps and simple values.
+// Maps decode to map[any]any keyed by int64 or string.
+func decodeCBOR(data []byte, depth int) (any, []byte, error) {
+	if len(data) == 0 || depth > maxCBORDepth {
+		return nil, nil, errMalformedCBOR
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	// Simple values and floats carry their payload in the additional info
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		}
+		return nil, nil, errMalformedCBOR
+	}
+
+	var argument uint64
+	switch {
+	case info < 24:
+		argument = uint64(info)
+	case info == 24 && len(data) >= 1:
+		argument, data = uint64(data[0]), data[1:]
+	case info == 25 && len(data) >= 2:
+		argument, data = uint64(binary.BigEndian.Uint16(data)), data[2:]
+	case info == 26 && len(data) >= 4:
+		argument, data = uint64(binary.BigEndian.Uint32(data)), data[4:]
+	case info == 27 && len(data) >= 8:
+		argument, data = binary.BigEndian.Uint64(data), data[8:]
+	default:
+		// Indefinite lengths and reserved values are never produced by authenticators
+		return nil, nil, errMalformedCBOR
+	}
+
+	switch major {
+	case 0:
+		if argument > 1<<63-1 {
+			return nil, nil, errMalformedCBOR
+		}
+		return int64(argument), data, nil
+
+	case 1:
+		if argument > 1<<63-1 {
+			return nil, nil, errMalformedCBOR
+		}
+		return -1 - int64(argument), data, nil
+
+	case 2, 3:
+		if argument > uint64(len(data)) {
+			return nil, nil, errMalformedCBOR
+		}
+		value := data[:argument]
+		if major == 3 {
+			return string(value), data[argument:], nil
+		}
+		return slices.Clone(value), data[argument:], nil
+
+	case 4:
+		if argument > uint64(len(data)) {
+			return nil, nil, errMalformedCBOR
+		}
+		items := make([]any, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			item, rest, err := decodeCBOR(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+			data = rest
+		}
+		return items, data, nil
+
+	case 5:
+		if argument > uint64(len(data)) {
+			return nil, nil, errMalformedCBOR
+		}
+		entries := make(map[any]any, argument)
+		for i := uint64(0); i < argument; i++ {
+			key, rest, err := decodeCBOR(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errMalformedCBOR
+			}
+			value, rest, err := decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries[key] = value
+			data = rest
+		}
+		return entries, data, nil
+	}
+
+	// Tags (major type 6) are not used by WebAuthn
+	return nil, nil, errMalformedCBOR
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"cirrussync-api/internal/jwt"
+	"cirrussync-api/internal/oauth"
+	"cirrussync-api/internal/org"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/srp"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IsAccessTokenRequest reports whether a request authenticates with a service account or OAuth access token
+func IsAccessTokenRequest(r *http.Request) bool {
+	token := bearerToken(r)
+	return strings.HasPrefix(token, org.ACCESS_TOKEN_PREFIX) ||
+		strings.HasPrefix(token, org.SANDBOX_ACCESS_TOKEN_PREFIX) ||
+		strings.HasPrefix(token, oauth.ACCESS_TOKEN_PREFIX)
+}
+
+// AccessTokenOrJWTAuthMiddleware authenticates service accounts by access token, third-party apps by
+// OAuth access token and everyone else by JWT. Access tokens carry their own scopes and never create a session.
+// Only the access tokens of this deployment are accepted: live tokens in production, sandbox tokens in the sandbox.
+func AccessTokenOrJWTAuthMiddleware(orgService *org.Service, oauthService *oauth.Service, jwtService *jwt.JWTService, sessionService *session.Service) gin.HandlerFunc {
+	jwtAuth := JWTAuthMiddleware(jwtService, sessionService)
+	oauthAuth := OAuthTokenMiddleware(oauthService)
+	tokenPrefix := orgService.TokenPrefix()
+
+	return func(c *gin.Context) {
+		token := bearerToken(c.Request)
+		if strings.HasPrefix(token, oauth.ACCESS_TOKEN_PREFIX) {
+			oauthAuth(c)
+			return
+		}
+		if !strings.HasPrefix(token, tokenPrefix) {
+			jwtAuth(c)
+			return
+		}
+
+		identity, err := orgService.ValidateAccessToken(c.Request.Context(), token, srp.GetClientIPFromRequest(c.Request))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"detail": "Access token expired or invalid"})
+			c.Abort()
+			return
+		}
+
+		c.Set("userID", identity.UserID)
+		c.Set("roles", []string{org.ROLE_SERVICE_ACCOUNT})
+		c.Set("scopes", identity.Scopes)
+		c.Set("accessTokenID", identity.TokenID)
+		c.Set("isRefreshToken", false)
+		c.Next()
+	}
+}
+
+// OAuthTokenMiddleware authenticates third-party apps by the OAuth access token a user granted them.
+// The token's scopes are the ones the user consented to.
+func OAuthTokenMiddleware(oauthService *oauth.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, err := oauthService.ValidateAccessToken(c.Request.Context(), bearerToken(c.Request))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"detail": "Access token expired or invalid"})
+			c.Abort()
+			return
+		}
+
+		c.Set("userID", identity.UserID)
+		c.Set("roles", []string{})
+		c.Set("scopes", identity.Scopes)
+		c.Set("oauthClientID", identity.ClientID)
+		c.Set("oauthIdentity", identity)
+		c.Set("isRefreshToken", false)
+		c.Next()
+	}
+}
+
+// bearerToken extracts the token from an Authorization: Bearer header
+func bearerToken(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		return parts[1]
+	}
+	return ""
+}
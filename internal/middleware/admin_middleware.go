@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"cirrussync-api/internal/admin"
+	"cirrussync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminPermissionContextKey carries the permission a request was checked against to the audit entry
+const adminPermissionContextKey = "adminPermission"
+
+// Audit entries keep request details to the size of their columns
+const (
+	maxAuditPathLength      = 1024
+	maxAuditUserAgentLength = 255
+)
+
+// AdminAuditMiddleware records every request that reaches the admin API once it is answered,
+// including requests refused for missing roles or permissions. Register it before the
+// role and permission checks so refusals are recorded too.
+func AdminAuditMiddleware(adminService *admin.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		adminService.RecordRequest(context.WithoutCancel(c.Request.Context()), &models.AdminAuditLog{
+			ActorID:    c.GetString("userID"),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       truncate(c.Request.URL.Path, maxAuditPathLength),
+			Permission: c.GetString(adminPermissionContextKey),
+			StatusCode: c.Writer.Status(),
+			IPAddress:  c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), maxAuditUserAgentLength),
+		})
+	}
+}
+
+// AdminPermissionMiddleware lets a request through only when the admin holds a permission.
+// Permissions are looked up per request, so a revoked permission stops working without a new token.
+func AdminPermissionMiddleware(adminService *admin.Service, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(adminPermissionContextKey, permission)
+
+		allowed, err := adminService.HasPermission(c.Request.Context(), c.GetString("userID"), c.GetStringSlice("roles"), permission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": "Internal server error"})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"detail": "You need the " + permission + " permission to access this resource"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// truncate shortens a string to at most max bytes
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// surrogateKeysContextKey holds the surrogate keys a handler attached to its response
+const surrogateKeysContextKey = "surrogateKeys"
+
+// CachePolicy describes how a shared cache may store a response
+type CachePolicy struct {
+	MaxAge               time.Duration // Browser cache lifetime
+	SharedMaxAge         time.Duration // CDN cache lifetime, purgeable by surrogate key
+	StaleWhileRevalidate time.Duration // How long a stale copy may be served while refreshing
+	SurrogateKeys        []string      // Keys applied to every response of the route
+}
+
+// AddSurrogateKeys tags the current response so the CDN can purge it by key.
+// Call it before writing the response.
+func AddSurrogateKeys(c *gin.Context, keys ...string) {
+	existing := c.GetStringSlice(surrogateKeysContextKey)
+	c.Set(surrogateKeysContextKey, append(existing, keys...))
+}
+
+// SetValidators sets the ETag and Last-Modified headers of a private response and makes clients
+// revalidate it on every use. An empty etag or zero lastModified, a Unix time, is left out.
+func SetValidators(c *gin.Context, etag string, lastModified int64) {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if lastModified > 0 {
+		c.Header("Last-Modified", time.Unix(lastModified, 0).UTC().Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", "private, no-cache")
+}
+
+// ETagMatches reports whether the request's If-None-Match header lists the etag, comparing weakly
+// as RFC 9110 requires for If-None-Match
+func ETagMatches(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if etag == "" || header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheHeadersMiddleware sets Cache-Control, Expires and Surrogate-Key headers for cacheable routes.
+// Only successful responses are made public; everything else is marked no-store so errors are never cached.
+func CacheHeadersMiddleware(policy CachePolicy) gin.HandlerFunc {
+	cacheControl := fmt.Sprintf("public, max-age=%d, s-maxage=%d",
+		int(policy.MaxAge.Seconds()), int(policy.SharedMaxAge.Seconds()))
+	if policy.StaleWhileRevalidate > 0 {
+		cacheControl += fmt.Sprintf(", stale-while-revalidate=%d", int(policy.StaleWhileRevalidate.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		// Only safe methods are cacheable
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		c.Writer = &cacheHeaderWriter{
+			ResponseWriter: c.Writer,
+			context:        c,
+			policy:         policy,
+			cacheControl:   cacheControl,
+		}
+		c.Next()
+	}
+}
+
+// cacheHeaderWriter sets cache headers once the response status is known, before the headers are sent
+type cacheHeaderWriter struct {
+	gin.ResponseWriter
+	context      *gin.Context
+	policy       CachePolicy
+	cacheControl string
+	applied      bool
+}
+
+// WriteHeader applies the cache headers that match the status code
+func (w *cacheHeaderWriter) WriteHeader(code int) {
+	if !w.applied {
+		w.applied = true
+		w.applyHeaders(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// applyHeaders sets the headers for a response with the given status code
+func (w *cacheHeaderWriter) applyHeaders(code int) {
+	header := w.ResponseWriter.Header()
+
+	// Handlers that set their own policy keep it
+	if header.Get("Cache-Control") != "" {
+		return
+	}
+
+	if code != http.StatusOK && code != http.StatusNotModified {
+		header.Set("Cache-Control", "no-store")
+		return
+	}
+
+	header.Set("Cache-Control", w.cacheControl)
+	header.Set("Expires", time.Now().Add(w.policy.MaxAge).UTC().Format(http.TimeFormat))
+
+	keys := append(append([]string{}, w.policy.SurrogateKeys...), w.context.GetStringSlice(surrogateKeysContextKey)...)
+	if len(keys) > 0 {
+		header.Set("Surrogate-Key", strings.Join(keys, " "))
+	}
+}
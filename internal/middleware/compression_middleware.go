@@ -0,0 +1,339 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// skipCompressionContextKey marks a response that must be sent as is
+const skipCompressionContextKey = "skipCompression"
+
+// Supported content encodings, in order of preference
+const (
+	encodingZstd = "zstd"
+	encodingGzip = "gzip"
+)
+
+// compressibleContentTypes are the response types worth compressing. Encrypted block payloads
+// are served as application/octet-stream and would only grow.
+var compressibleContentTypes = []string{"application/json", "application/problem+json"}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		writer, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return writer
+	},
+}
+
+var zstdEncoderPool = sync.Pool{
+	New: func() interface{} {
+		encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return encoder
+	},
+}
+
+// compressionStats counts compressed responses and the bytes they saved, per encoding
+var compressionStats = map[string]*encodingStats{
+	encodingZstd: {},
+	encodingGzip: {},
+}
+
+type encodingStats struct {
+	responses       atomic.Int64
+	bytesIn         atomic.Int64
+	bytesOut        atomic.Int64
+	skippedTooSmall atomic.Int64
+}
+
+// CompressionStats summarizes the responses compressed with one encoding since startup
+type CompressionStats struct {
+	Encoding          string
+	Responses         int64
+	UncompressedBytes int64
+	CompressedBytes   int64
+	SavedBytes        int64
+	SkippedTooSmall   int64
+}
+
+// GetCompressionStats returns the compression counters for every supported encoding
+func GetCompressionStats() []CompressionStats {
+	result := make([]CompressionStats, 0, len(compressionStats))
+	for _, encoding := range []string{encodingZstd, encodingGzip} {
+		stats := compressionStats[encoding]
+		bytesIn := stats.bytesIn.Load()
+		bytesOut := stats.bytesOut.Load()
+		result = append(result, CompressionStats{
+			Encoding:          encoding,
+			Responses:         stats.responses.Load(),
+			UncompressedBytes: bytesIn,
+			CompressedBytes:   bytesOut,
+			SavedBytes:        bytesIn - bytesOut,
+			SkippedTooSmall:   stats.skippedTooSmall.Load(),
+		})
+	}
+	return result
+}
+
+// SkipCompression sends the current response uncompressed, for example when it carries ciphertext.
+// Call it before writing the response.
+func SkipCompression(c *gin.Context) {
+	c.Set(skipCompressionContextKey, true)
+}
+
+// CompressionMiddleware compresses JSON responses with zstd or gzip, as negotiated through Accept-Encoding.
+// Responses below minSize are sent as is, since compressing them costs more than it saves.
+func CompressionMiddleware(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.Request.Header.Get("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressionWriter{
+			ResponseWriter: c.Writer,
+			context:        c,
+			encoding:       encoding,
+			minSize:        minSize,
+		}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+	}
+}
+
+// negotiateEncoding picks the supported encoding with the highest quality value, preferring zstd on ties
+func negotiateEncoding(acceptEncoding string) string {
+	best := ""
+	bestQuality := 0.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encodingZstd && name != encodingGzip {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if quality > bestQuality || (quality == bestQuality && name == encodingZstd) {
+			best = name
+			bestQuality = quality
+		}
+	}
+
+	if bestQuality <= 0 {
+		return ""
+	}
+	return best
+}
+
+// compressionWriter buffers the start of a response until it knows whether compressing it is worthwhile
+type compressionWriter struct {
+	gin.ResponseWriter
+	context  *gin.Context
+	encoding string
+	minSize  int
+
+	status  int
+	buffer  bytes.Buffer
+	decided bool
+	encoder io.WriteCloser
+	counter *countingWriter
+	bytesIn int64
+}
+
+// WriteHeader holds the status back until the body shows whether the response will be compressed
+func (w *compressionWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+// WriteHeaderNow sends the headers, deciding on compression with what has been buffered so far
+func (w *compressionWriter) WriteHeaderNow() {
+	w.decide()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Status returns the status the handler set, even while it is still held back
+func (w *compressionWriter) Status() int {
+	if !w.decided && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+// Written reports whether the handler has started a response
+func (w *compressionWriter) Written() bool {
+	return w.status != 0 || w.buffer.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Write buffers the body until minSize bytes have been seen, then streams it through the encoder
+func (w *compressionWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buffer.Write(data)
+		if w.buffer.Len() >= w.minSize {
+			if err := w.decide(); err != nil {
+				return 0, err
+			}
+		}
+		return len(data), nil
+	}
+
+	if w.encoder != nil {
+		w.bytesIn += int64(len(data))
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes a string body
+func (w *compressionWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends everything written so far, for streaming responses
+func (w *compressionWriter) Flush() {
+	w.decide()
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Hijack hands the connection over; nothing is compressed afterwards
+func (w *compressionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide sends the headers and whatever has been buffered, compressed if the response qualifies
+func (w *compressionWriter) decide() error {
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if w.shouldCompress() {
+		header := w.ResponseWriter.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+
+		w.counter = &countingWriter{writer: w.ResponseWriter}
+		w.encoder = w.newEncoder(w.counter)
+	} else if w.buffer.Len() > 0 && w.buffer.Len() < w.minSize && w.isCompressibleType() {
+		compressionStats[w.encoding].skippedTooSmall.Add(1)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	_, err := w.Write(w.buffer.Bytes())
+	w.buffer.Reset()
+	return err
+}
+
+// shouldCompress reports whether the response is a large enough JSON body that nothing else has encoded
+func (w *compressionWriter) shouldCompress() bool {
+	if w.buffer.Len() < w.minSize {
+		return false
+	}
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	if w.context.GetBool(skipCompressionContextKey) {
+		return false
+	}
+	if w.ResponseWriter.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	return w.isCompressibleType()
+}
+
+// isCompressibleType reports whether the response content type is worth compressing
+func (w *compressionWriter) isCompressibleType() bool {
+	contentType := strings.ToLower(w.ResponseWriter.Header().Get("Content-Type"))
+	for _, compressible := range compressibleContentTypes {
+		if strings.HasPrefix(contentType, compressible) {
+			return true
+		}
+	}
+	return false
+}
+
+// newEncoder takes an encoder for the negotiated encoding from its pool
+func (w *compressionWriter) newEncoder(dst io.Writer) io.WriteCloser {
+	if w.encoding == encodingZstd {
+		encoder := zstdEncoderPool.Get().(*zstd.Encoder)
+		encoder.Reset(dst)
+		return encoder
+	}
+
+	writer := gzipWriterPool.Get().(*gzip.Writer)
+	writer.Reset(dst)
+	return writer
+}
+
+// finish sends a response that never reached minSize and closes the encoder, recording what was saved
+func (w *compressionWriter) finish() {
+	if err := w.decide(); err != nil {
+		return
+	}
+	if w.encoder == nil {
+		return
+	}
+
+	w.encoder.Close()
+	switch encoder := w.encoder.(type) {
+	case *zstd.Encoder:
+		zstdEncoderPool.Put(encoder)
+	case *gzip.Writer:
+		gzipWriterPool.Put(encoder)
+	}
+	w.encoder = nil
+
+	stats := compressionStats[w.encoding]
+	stats.responses.Add(1)
+	stats.bytesIn.Add(w.bytesIn)
+	stats.bytesOut.Add(w.counter.written)
+}
+
+// countingWriter counts the compressed bytes sent to the client
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (w *countingWriter) Write(data []byte) (int, error) {
+	n, err := w.writer.Write(data)
+	w.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+
+	"cirrussync-api/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CookieSpec names a cookie and the path it is scoped to. Clearing a cookie only works with the
+// path it was set with, so both always come from here.
+type CookieSpec struct {
+	Name string
+	Path string
+}
+
+// Cookies the API sets, each scoped to the routes that read it
+var (
+	SessionCookie      = CookieSpec{Name: "sessionID", Path: "/"}
+	AccessTokenCookie  = CookieSpec{Name: "accessToken", Path: "/api/v1"}
+	RefreshTokenCookie = CookieSpec{Name: "refreshToken", Path: "/api/v1/auth/refresh"}
+	DeviceTrustCookie  = CookieSpec{Name: "deviceTrust", Path: "/api/v1/auth"}
+)
+
+// Cookies sets HTTP-only cookies with the domain, Secure flag and SameSite mode of the deployment
+type Cookies struct {
+	domain   string
+	secure   bool
+	sameSite http.SameSite
+}
+
+// NewCookies creates a cookie helper from the server configuration
+func NewCookies(cfg *config.ServerConfig) *Cookies {
+	return &Cookies{
+		domain:   cfg.CookieDomain,
+		secure:   cfg.SecureCookies,
+		sameSite: cfg.SameSite,
+	}
+}
+
+// Set sets a cookie that expires after maxAge seconds
+func (ck *Cookies) Set(c *gin.Context, cookie CookieSpec, value string, maxAge int) {
+	c.SetSameSite(ck.sameSite)
+	c.SetCookie(cookie.Name, value, maxAge, cookie.Path, ck.domain, ck.secure, true)
+}
+
+// Clear expires a cookie immediately
+func (ck *Cookies) Clear(c *gin.Context, cookie CookieSpec) {
+	ck.Set(c, cookie, "", -1)
+}
+
+// SetSession sets the session, access token and refresh token cookies of a signed in client. The
+// access token lives for an hour and the refresh token for a day.
+func (ck *Cookies) SetSession(c *gin.Context, sessionID string, sessionMaxAge int, accessToken, refreshToken string) {
+	ck.Set(c, SessionCookie, sessionID, sessionMaxAge)
+	ck.Set(c, AccessTokenCookie, accessToken, 60*60)
+	ck.Set(c, RefreshTokenCookie, refreshToken, 24*60*60)
+}
+
+// ClearSession clears the session, access token and refresh token cookies
+func (ck *Cookies) ClearSession(c *gin.Context) {
+	ck.Clear(c, SessionCookie)
+	ck.Clear(c, AccessTokenCookie)
+	ck.Clear(c, RefreshTokenCookie)
+}
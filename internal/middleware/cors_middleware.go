@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"time"
+
+	"cirrussync-api/pkg/config"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSMiddleware lets the configured origins call the API with credentials and read the headers
+// clients rely on, like request IDs, retry hints, storage status and cache validators
+func CORSMiddleware(cfg *config.ServerConfig) gin.HandlerFunc {
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = cfg.AllowedOrigins
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-TOKEN", "X-App-Version", "X-Client-UID", "X-Client-Name", "If-None-Match"}
+	corsConfig.ExposeHeaders = []string{REQUEST_ID_HEADER, "Retry-After", STORAGE_STATUS_HEADER, STORAGE_DEGRADED_SINCE_HEADER, "ETag", "Last-Modified"}
+	corsConfig.AllowCredentials = true
+	corsConfig.MaxAge = 24 * time.Hour
+
+	return cors.New(corsConfig)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"cirrussync-api/pkg/status"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unbudgetedContextKey holds the request context from before the first budget was applied, which
+// is only cancelled when the client goes away
+const unbudgetedContextKey = "request_unbudgeted_context"
+
+// RequestBudgetMiddleware gives each request of a route class a single deadline. Handlers and
+// services derive their contexts from the request instead of starting timers of their own, so
+// an inner layer can never cancel work the route's budget still allows.
+// The budget is read per request so runtime-tuned values apply immediately. A route's own budget
+// replaces the default budget of the engine instead of nesting inside it, so it may be longer;
+// the request is still cancelled when the client goes away.
+func RequestBudgetMiddleware(budget func() time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parent := c.Request.Context()
+
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if unbudgeted, ok := c.Get(unbudgetedContextKey); ok {
+			// Keep the values added since, but not the enclosing deadline
+			ctx, cancel = context.WithTimeout(context.WithoutCancel(parent), budget())
+			stop := context.AfterFunc(unbudgeted.(context.Context), cancel)
+			defer stop()
+		} else {
+			ctx, cancel = context.WithTimeout(parent, budget())
+			c.Set(unbudgetedContextKey, parent)
+		}
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		// Handlers that gave up without responding still owe the client an answer
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{
+				"code":   status.StatusRequestTimeout,
+				"detail": "Error with requestId " + RequestID(c),
+				"error":  "Request timed out",
+			})
+		}
+	}
+}
+
+// FixedBudget returns a budget for RequestBudgetMiddleware that never changes
+func FixedBudget(budget time.Duration) func() time.Duration {
+	return func() time.Duration {
+		return budget
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorCodeContextKey is where the API error code of a rejected or failed request is kept in the gin context
+const errorCodeContextKey = "errorCode"
+
+// maxErrorBodyCapture bounds how much of an error body is kept to read its code. Error bodies are
+// small; anything longer is not one of ours and is counted without a code.
+const maxErrorBodyCapture = 4096
+
+// ErrorRecorder counts a rejected or failed request of a route towards the error metrics
+type ErrorRecorder interface {
+	RecordError(ctx context.Context, route, clientVersion string, httpStatus, errorCode int) error
+}
+
+// errorCodeWriter keeps the start of error bodies so their API code can be read once the handler is done
+type errorCodeWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *errorCodeWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorCodeWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *errorCodeWriter) capture(data []byte) {
+	if w.Status() < 400 || w.body.Len() >= maxErrorBodyCapture {
+		return
+	}
+	if room := maxErrorBodyCapture - w.body.Len(); len(data) > room {
+		data = data[:room]
+	}
+	w.body.Write(data)
+}
+
+// code returns the "code" field of a captured JSON error body, or 0 when there is none
+func (w *errorCodeWriter) code() int {
+	var body struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &body); err != nil {
+		return 0
+	}
+	return body.Code
+}
+
+// ErrorMetricsMiddleware reads the API error code of every rejected or failed request, adds it to the
+// request log line and counts it per route pattern, client version and HTTP status. It has to run
+// inside the compression middleware so it sees bodies before they are encoded. Counting happens after
+// the response, off the request path. Requests that matched no route are not counted.
+func ErrorMetricsMiddleware(recorder ErrorRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &errorCodeWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		status := writer.Status()
+		if status < 400 {
+			return
+		}
+		errorCode := writer.code()
+		c.Set(errorCodeContextKey, errorCode)
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		clientVersion := c.GetHeader("X-App-Version")
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), usageRecordTimeout)
+		go func() {
+			defer cancel()
+			_ = recorder.RecordError(ctx, route, clientVersion, status, errorCode)
+		}()
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"cirrussync-api/internal/admin"
 	"cirrussync-api/internal/jwt"
 	"cirrussync-api/internal/session"
 	"net/http"
@@ -202,9 +203,10 @@ func RoleRequiredMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	}
 }
 
-// AdminRequiredMiddleware creates a middleware that requires admin role
+// AdminRequiredMiddleware creates a middleware that requires the admin or superadmin role.
+// Individual admin routes additionally require a permission, see AdminPermissionMiddleware.
 func AdminRequiredMiddleware() gin.HandlerFunc {
-	return RoleRequiredMiddleware("admin")
+	return RoleRequiredMiddleware(admin.ROLE_ADMIN, admin.ROLE_SUPERADMIN)
 }
 
 // OptionalJWTAuthMiddleware attempts to validate JWT but continues if not present
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"cirrussync-api/pkg/metrics"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners cannot create a series per path
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records the latency and status code of every request under its route pattern
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+
+		metrics.HTTPRequestDuration.ObserveDuration(start, method, route)
+		metrics.HTTPRequests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// MetricsHandler serves the Prometheus metrics. With a token configured, scrapers must send it as
+// a bearer token.
+func MetricsHandler(token string) gin.HandlerFunc {
+	handler := metrics.Default.Handler()
+
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(bearerToken(c.Request)), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"detail": "Metrics token missing or invalid"})
+			c.Abort()
+			return
+		}
+
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
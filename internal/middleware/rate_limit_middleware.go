@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/status"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit classes, each with its own limits
+const (
+	rateLimitClassAuth    = "auth"
+	rateLimitClassRead    = "read"
+	rateLimitClassUpload  = "upload"
+	rateLimitClassDefault = "default"
+)
+
+// rateLimitAuthPrefixes are the route prefixes counted as authentication
+var rateLimitAuthPrefixes = []string{"/api/v1/auth/", "/api/v1/oauth/token"}
+
+// rateLimitUploadPrefixes cover file creation, block and thumbnail uploads, revision commits and
+// bulk folder creation
+var rateLimitUploadPrefixes = []string{"/api/v1/drive/shares/:shareID/files", "/api/v1/drive/shares/:shareID/folders/bulk"}
+
+// RateLimiter counts requests per client IP and per user in Redis sliding windows shared by every instance
+type RateLimiter struct {
+	redisClient *redis.Client
+	config      *config.RateLimitConfig
+	logger      *logger.Logger
+}
+
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter(redisClient *redis.Client, cfg *config.RateLimitConfig, log *logger.Logger) *RateLimiter {
+	return &RateLimiter{
+		redisClient: redisClient,
+		config:      cfg,
+		logger:      log,
+	}
+}
+
+// RateLimitMiddleware limits requests per client IP. Register it globally, before any route
+// group, so unauthenticated and unmatched requests are limited too.
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil || !limiter.config.Enabled {
+			c.Next()
+			return
+		}
+
+		class, rule := limiter.classify(c)
+		if !limiter.allow(c, "ratelimit:"+class+":ip:"+c.ClientIP(), rule.PerIP) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// UserRateLimitMiddleware limits requests per authenticated user. The user is only known once
+// the group's auth middleware has run, so register it right after that middleware.
+func UserRateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if limiter == nil || !limiter.config.Enabled || userID == "" {
+			c.Next()
+			return
+		}
+
+		class, rule := limiter.classify(c)
+		if !limiter.allow(c, "ratelimit:"+class+":user:"+userID, rule.PerUser) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// classify picks the rate limit class of a request from its route pattern and method
+func (l *RateLimiter) classify(c *gin.Context) (string, config.RateLimitRule) {
+	route := c.FullPath()
+	for _, prefix := range rateLimitAuthPrefixes {
+		if strings.HasPrefix(route, prefix) {
+			return rateLimitClassAuth, l.config.Auth
+		}
+	}
+
+	if c.Request.Method != http.MethodGet {
+		for _, prefix := range rateLimitUploadPrefixes {
+			if strings.HasPrefix(route, prefix) {
+				return rateLimitClassUpload, l.config.Upload
+			}
+		}
+	}
+
+	if strings.HasPrefix(route, "/api/v1/drive/") && (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) {
+		return rateLimitClassRead, l.config.Read
+	}
+	return rateLimitClassDefault, l.config.Default
+}
+
+// allow counts the request against a bucket and answers 429 with Retry-After once the bucket is full.
+// Requests are let through when Redis cannot be reached, so an outage there does not take the API down.
+func (l *RateLimiter) allow(c *gin.Context, key string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	allowed, _, retryAfter, err := l.redisClient.SlidingWindowAllow(c.Request.Context(), key, limit, l.config.Window)
+	if err != nil {
+		l.logger.WithContext(c.Request.Context()).WithError(err).Warn("Rate limiter unavailable, letting request through")
+		return true
+	}
+	if allowed {
+		return true
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"code":   status.StatusTooManyRequests,
+		"detail": "Error with requestId " + RequestID(c),
+		"error":  "Too many requests, please try again later",
+	})
+	return false
+}
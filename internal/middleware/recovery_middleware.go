@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/metrics"
+	"cirrussync-api/pkg/status"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RecoveryMiddleware turns a panic while serving a request into the standard error response,
+// logs it with its stack trace and the request ID, and counts it per route. Requests whose client
+// went away are only logged, as there is nobody left to respond to.
+func RecoveryMiddleware(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// The standard library uses this panic to abort a response on purpose
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			route := c.FullPath()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			metrics.HTTPPanics.Inc(c.Request.Method, route)
+
+			log.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"route":  route,
+				"panic":  fmt.Sprint(recovered),
+				"stack":  string(debug.Stack()),
+			}).Error("Recovered from panic while serving request")
+
+			if err, ok := recovered.(error); ok && isBrokenConnection(err) {
+				c.Abort()
+				return
+			}
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"code":   status.StatusInternalServerError,
+				"detail": "Error with requestId " + RequestID(c),
+				"error":  "Internal server error",
+			})
+		}()
+
+		c.Next()
+	}
+}
+
+// isBrokenConnection reports whether a write failed because the client closed the connection
+func isBrokenConnection(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if errors.As(opErr, &syscallErr) {
+		return errors.Is(syscallErr, syscall.EPIPE) || errors.Is(syscallErr, syscall.ECONNRESET)
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/utils"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// REQUEST_ID_HEADER carries the request ID in requests from trusted proxies and in every response
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// requestIDContextKey is where the request ID is kept in the gin context
+const requestIDContextKey = "requestID"
+
+// Request IDs from callers end up in logs and response bodies, so keep them to a safe character set
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{8,64}$`)
+
+// RequestLoggingMiddleware assigns every request an ID, keeps an ID sent by a proxy in front of the
+// API, returns it in the X-Request-ID header and logs one structured line per request. The ID is
+// stored in the request context, so errors logged while serving the request carry it too.
+func RequestLoggingMiddleware(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(REQUEST_ID_HEADER)
+		if !requestIDRegex.MatchString(requestID) {
+			requestID = utils.GenerateShortID()
+		}
+
+		c.Set(requestIDContextKey, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+		c.Header(REQUEST_ID_HEADER, requestID)
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := c.Writer.Status()
+
+		entry := log.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"method":    c.Request.Method,
+			"route":     route,
+			"path":      c.Request.URL.Path,
+			"status":    status,
+			"latencyMs": time.Since(start).Milliseconds(),
+			"bytes":     c.Writer.Size(),
+			"clientIP":  c.ClientIP(),
+		})
+		if errorCode, ok := c.Get(errorCodeContextKey); ok {
+			entry = entry.WithField("errorCode", errorCode)
+		}
+		switch {
+		case status >= 500:
+			entry.Error("Request failed")
+		case status >= 400:
+			entry.Warn("Request rejected")
+		default:
+			entry.Info("Request served")
+		}
+	}
+}
+
+// RequestID returns the ID of the request being served, which response bodies report in their
+// detail. Requests that did not pass through the logging middleware get a fresh ID.
+func RequestID(c *gin.Context) string {
+	if requestID := c.GetString(requestIDContextKey); requestID != "" {
+		return requestID
+	}
+	requestID := utils.GenerateShortID()
+	c.Set(requestIDContextKey, requestID)
+	return requestID
+}
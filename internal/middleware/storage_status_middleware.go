@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"cirrussync-api/pkg/status"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers set on every response while storage is degraded
+const (
+	STORAGE_STATUS_HEADER         = "X-Storage-Status"
+	STORAGE_DEGRADED_SINCE_HEADER = "X-Storage-Degraded-Since"
+)
+
+// StorageAvailability reports whether the blob store is reachable
+type StorageAvailability interface {
+	// DegradedSince returns when storage became unreachable, or the zero time while it is available
+	DegradedSince() time.Time
+}
+
+// StorageStatusMiddleware marks every response while storage is degraded, so clients can switch to
+// deferred uploads and cached content before a request fails
+func StorageStatusMiddleware(storage StorageAvailability) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if since := storage.DegradedSince(); !since.IsZero() {
+			c.Header(STORAGE_STATUS_HEADER, "degraded")
+			c.Header(STORAGE_DEGRADED_SINCE_HEADER, strconv.FormatInt(since.Unix(), 10))
+		}
+		c.Next()
+	}
+}
+
+// StatusHandler serves the public service status. Storage is reported as available when no blob store
+// is configured, since nothing is degraded then.
+func StatusHandler(storage StorageAvailability) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		overall := "ok"
+		storageStatus := gin.H{"status": "ok"}
+
+		if storage != nil {
+			if since := storage.DegradedSince(); !since.IsZero() {
+				overall = "degraded"
+				storageStatus = gin.H{
+					"status":        "degraded",
+					"degradedSince": since.Unix(),
+					"uploads":       "deferred",
+				}
+			}
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, gin.H{
+			"code":    status.StatusOK,
+			"detail":  "Success with requestId " + RequestID(c),
+			"status":  overall,
+			"storage": storageStatus,
+		})
+	}
+}
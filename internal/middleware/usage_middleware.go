@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usageRecordTimeout bounds how long counting a request may take once it has been answered
+const usageRecordTimeout = 2 * time.Second
+
+// usageIgnoredFamilies are route prefixes that say nothing about how the product is used
+var usageIgnoredFamilies = []string{"admin", "csrf"}
+
+// UsageRecorder counts a request of a user towards the usage of a route family from a client version
+type UsageRecorder interface {
+	Record(ctx context.Context, userID, routeFamily, clientVersion string) error
+}
+
+// UsageMetricsMiddleware counts authenticated requests per route family and client version.
+// Counting happens after the response, off the request path, so it never delays a client.
+// Whether a request is counted at all is up to the recorder, which checks the user's consent.
+func UsageMetricsMiddleware(recorder UsageRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		// The user is only known once the route's auth middleware has run
+		userID := c.GetString("userID")
+		routeFamily := usageRouteFamily(c.FullPath())
+		if userID == "" || routeFamily == "" {
+			return
+		}
+		clientVersion := c.GetHeader("X-App-Version")
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), usageRecordTimeout)
+		go func() {
+			defer cancel()
+			_ = recorder.Record(ctx, userID, routeFamily, clientVersion)
+		}()
+	}
+}
+
+// usageRouteFamily reduces a route pattern such as /api/v1/drive/shares/:shareID/urls to its
+// first two static segments (drive/shares). Unmatched and ignored routes have no family.
+func usageRouteFamily(fullPath string) string {
+	path, ok := strings.CutPrefix(fullPath, "/api/v1/")
+	if !ok {
+		return ""
+	}
+
+	segments := make([]string, 0, 2)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			break
+		}
+		segments = append(segments, segment)
+		if len(segments) == 2 {
+			break
+		}
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+
+	for _, ignored := range usageIgnoredFamilies {
+		if segments[0] == ignored {
+			return ""
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
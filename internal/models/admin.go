@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// AdminPermission is a capability granted to an admin user, such as billing.manage
+type AdminPermission struct {
+	ID         string `gorm:"primaryKey;column:id"`
+	UserID     string `gorm:"column:user_id;not null;uniqueIndex:idx_admin_permissions_user_permission,priority:1"`
+	Permission string `gorm:"column:permission;size:50;not null;uniqueIndex:idx_admin_permissions_user_permission,priority:2"`
+	GrantedBy  string `gorm:"column:granted_by;not null"`
+	CreatedAt  int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for AdminPermission
+func (AdminPermission) TableName() string {
+	return "admin_permissions"
+}
+
+// BeforeCreate hook for AdminPermission
+func (p *AdminPermission) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if p.ID == "" {
+		p.ID = utils.GenerateLinkID()
+	}
+	if p.CreatedAt == 0 {
+		p.CreatedAt = now
+	}
+	if p.ModifiedAt == 0 {
+		p.ModifiedAt = now
+	}
+	return nil
+}
+
+// AdminAuditLog is an append-only record of a request made against the admin API,
+// including requests that were refused
+type AdminAuditLog struct {
+	ID         string `gorm:"primaryKey;column:id"`
+	ActorID    string `gorm:"column:actor_id;index:idx_admin_audit_log_actor_created,priority:1"`
+	Method     string `gorm:"column:method;size:10;not null"`
+	Route      string `gorm:"column:route;size:255;not null"`
+	Path       string `gorm:"column:path;size:1024;not null"`
+	Permission string `gorm:"column:permission;size:50"`
+	StatusCode int    `gorm:"column:status_code;not null"`
+	IPAddress  string `gorm:"column:ip_address;size:45"`
+	UserAgent  string `gorm:"column:user_agent;size:255"`
+	CreatedAt  int64  `gorm:"column:created_at;autoCreateTime:false;not null;index:idx_admin_audit_log_actor_created,priority:2;index:idx_admin_audit_log_created"`
+}
+
+// TableName specifies the table name for AdminAuditLog
+func (AdminAuditLog) TableName() string {
+	return "admin_audit_log"
+}
+
+// BeforeCreate hook for AdminAuditLog
+func (l *AdminAuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = utils.GenerateLinkID()
+	}
+	if l.CreatedAt == 0 {
+		l.CreatedAt = time.Now().Unix()
+	}
+	return nil
+}
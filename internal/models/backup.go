@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// DriveBackupSet is a folder the desktop client backs up from one of the user's devices. Each set
+// is stored in a backup share of its own, so backed up files stay apart from the user's drive and
+// only the device that owns the set writes to it.
+type DriveBackupSet struct {
+	ID            string `gorm:"primaryKey;column:id"`
+	UserID        string `gorm:"column:user_id;not null;index:idx_drive_backup_sets_user_id"`
+	DeviceID      string `gorm:"column:device_id;not null;index:idx_drive_backup_sets_device_id"` // ID of the UserDevice
+	ShareID       string `gorm:"column:share_id;not null;uniqueIndex:idx_drive_backup_sets_share_id"`
+	EncryptedName string `gorm:"column:encrypted_name;type:text;not null"` // Local folder path, encrypted by the client
+	State         int    `gorm:"column:state;default:1"`                   // 1=active, 2=deleting
+	MaxVersions   int    `gorm:"column:max_versions;not null"`             // Revisions kept per file, including the current one
+	RetentionDays int    `gorm:"column:retention_days;not null"`           // Previous revisions older than this are removed, 0 keeps them
+	LastBackupAt  *int64 `gorm:"column:last_backup_at"`
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt    int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Share DriveShare `gorm:"foreignKey:ShareID"`
+}
+
+// TableName specifies the table name for DriveBackupSet
+func (DriveBackupSet) TableName() string {
+	return "drive_backup_sets"
+}
+
+// BeforeCreate hook for DriveBackupSet
+func (bs *DriveBackupSet) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if bs.ID == "" {
+		bs.ID = utils.GenerateLinkID()
+	}
+	if bs.CreatedAt == 0 {
+		bs.CreatedAt = now
+	}
+	if bs.ModifiedAt == 0 {
+		bs.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for DriveBackupSet
+func (bs *DriveBackupSet) BeforeUpdate(tx *gorm.DB) error {
+	bs.ModifiedAt = time.Now().Unix()
+	return nil
+}
@@ -79,6 +79,10 @@ type Plan struct {
 	CreatedAt    int64   `gorm:"column:created_at;autoCreateTime:false;not null"`
 	UpdatedAt    int64   `gorm:"column:updated_at;autoUpdateTime:false;not null"`
 
+	// Stripe prices a subscription to this plan is billed with, empty if the cycle cannot be purchased
+	StripeMonthlyPriceID string `gorm:"column:stripe_monthly_price_id;size:100"`
+	StripeYearlyPriceID  string `gorm:"column:stripe_yearly_price_id;size:100"`
+
 	// Relationships
 	UserPlans []UserPlan `gorm:"foreignKey:PlanID"`
 }
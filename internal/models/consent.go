@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// UserConsent holds the current state of a single consent flag for a user
+type UserConsent struct {
+	ID            string `gorm:"primaryKey;column:id"`
+	UserID        string `gorm:"column:user_id;not null;index:idx_user_consents_user_type,unique"`
+	ConsentType   string `gorm:"column:consent_type;size:32;not null;index:idx_user_consents_user_type,unique"`
+	Granted       bool   `gorm:"column:granted;default:false"`
+	PolicyVersion string `gorm:"column:policy_version;size:32;not null"`
+	GrantedAt     *int64 `gorm:"column:granted_at;default:null"`
+	RevokedAt     *int64 `gorm:"column:revoked_at;default:null"`
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt    int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for UserConsent
+func (UserConsent) TableName() string {
+	return "user_consents"
+}
+
+// BeforeCreate hook for UserConsent
+func (uc *UserConsent) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if uc.ID == "" {
+		uc.ID = utils.GenerateLinkID()
+	}
+	if uc.CreatedAt == 0 {
+		uc.CreatedAt = now
+	}
+	if uc.ModifiedAt == 0 {
+		uc.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for UserConsent
+func (uc *UserConsent) BeforeUpdate(tx *gorm.DB) error {
+	uc.ModifiedAt = time.Now().Unix()
+	return nil
+}
+
+// UserConsentRecord is an append-only audit entry written on every consent change.
+// Rows are never updated so the history can be produced for compliance requests.
+type UserConsentRecord struct {
+	ID            string `gorm:"primaryKey;column:id"`
+	UserID        string `gorm:"column:user_id;not null;index:idx_user_consent_records_user_id"`
+	ConsentType   string `gorm:"column:consent_type;size:32;not null"`
+	Granted       bool   `gorm:"column:granted;not null"`
+	PolicyVersion string `gorm:"column:policy_version;size:32;not null"`
+	IPAddress     string `gorm:"column:ip_address;size:45"`
+	UserAgent     string `gorm:"column:user_agent;type:text"`
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:false;not null;index:idx_user_consent_records_created_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for UserConsentRecord
+func (UserConsentRecord) TableName() string {
+	return "user_consent_records"
+}
+
+// BeforeCreate hook for UserConsentRecord
+func (ucr *UserConsentRecord) BeforeCreate(tx *gorm.DB) error {
+	if ucr.ID == "" {
+		ucr.ID = utils.GenerateLinkID()
+	}
+	if ucr.CreatedAt == 0 {
+		ucr.CreatedAt = time.Now().Unix()
+	}
+	return nil
+}
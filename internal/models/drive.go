@@ -20,7 +20,9 @@ type VolumeAllocation struct {
 	IsOwner              bool    `gorm:"column:is_owner;default:false"`  // Whether this user is the volume owner
 	CreatedAt            int64   `gorm:"column:created_at;autoCreateTime:false;not null"`
 	ModifiedAt           int64   `gorm:"column:modified_at;autoCreateTime:false;not null"`
-	Active               bool    `gorm:"column:active;default:true"` // Whether this allocation is active
+	Active               bool    `gorm:"column:active;default:true"`      // Whether this allocation is active
+	WarnedPercent        int     `gorm:"column:warned_percent;default:0"` // Highest usage threshold the user was warned about
+	WarnedAt             int64   `gorm:"column:warned_at;default:0"`      // When the user was last warned about their usage
 
 	// Relationships
 	Volume DriveVolume `gorm:"foreignKey:VolumeID"`
@@ -67,6 +69,9 @@ type DriveVolume struct {
 	IsShared  bool   `gorm:"column:is_shared;default:false"`
 	MaxUsers  int    `gorm:"column:max_users;default:5"` // Maximum number of users who can share this volume
 
+	// New blocks are written with a cross-region replication marker the bucket's replication rules act on
+	CrossRegionReplication bool `gorm:"column:cross_region_replication;default:false"`
+
 	// Relationships
 	User        User               `gorm:"foreignKey:UserID"`
 	Shares      []DriveShare       `gorm:"foreignKey:VolumeID"`
@@ -107,7 +112,8 @@ type DriveShare struct {
 	Type                     int    `gorm:"column:type;default:1"`
 	State                    int    `gorm:"column:state;default:1"`
 	Creator                  string `gorm:"column:creator;size:255;not null"`
-	Locked                   bool   `gorm:"column:locked;default:false"`
+	Locked                   bool   `gorm:"column:locked;default:false"` // The owner's keys were reset, an admin member has to unlock it
+	LockedAt                 *int64 `gorm:"column:locked_at;default:null"`
 	CreatedAt                int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
 	ModifiedAt               int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
 	LinkID                   string `gorm:"column:link_id;not null;index:idx_drive_shares_link_id"`
@@ -117,6 +123,10 @@ type DriveShare struct {
 	ShareKey                 string `gorm:"column:share_key;type:text;not null"`
 	SharePassphrase          string `gorm:"column:share_passphrase;type:text;not null"`
 	SharePassphraseSignature string `gorm:"column:share_passphrase_signature;type:text"`
+	RequiresApproval         bool   `gorm:"column:requires_approval;default:false"` // Accepted invitations wait for an admin
+	// Members and public links lose access once the share expires
+	ExpiresAt        *int64 `gorm:"column:expires_at;default:null;index:idx_drive_shares_expires_at"`
+	ExpiryNotifiedAt *int64 `gorm:"column:expiry_notified_at;default:null"`
 
 	// Relationships
 	User   User        `gorm:"foreignKey:UserID"`
@@ -164,6 +174,8 @@ type DriveShareMembership struct {
 	CreatedAt           int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
 	ModifiedAt          int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
 	CanUnlock           *bool  `gorm:"column:can_unlock;default:null"`
+	// The member loses access once their permissions expire, independently of the share's expiry
+	PermissionExpiresAt *int64 `gorm:"column:permission_expires_at;default:null;index:idx_share_members_permission_expires_at"`
 
 	// Relationships
 	Share DriveShare `gorm:"foreignKey:ShareID"`
@@ -211,7 +223,7 @@ type FileProperties struct {
 type DriveItem struct {
 	ID                      string            `gorm:"primaryKey;column:id"`
 	ParentID                *string           `gorm:"column:parent_id;index:idx_drive_items_parent_id"`
-	ShareID                 string            `gorm:"column:share_id;not null;index:idx_drive_items_share_id"`
+	ShareID                 string            `gorm:"column:share_id;not null;index:idx_drive_items_share_id;index:idx_drive_items_share_modified,priority:1"`
 	VolumeID                string            `gorm:"column:volume_id;not null"`
 	Type                    int               `gorm:"column:type;not null;index:idx_drive_items_type"` // 1=folder, 2=file
 	Name                    string            `gorm:"column:name;type:text;not null"`
@@ -219,15 +231,17 @@ type DriveItem struct {
 	NameSignatureEmail      string            `gorm:"column:name_signature_email;size:255"`
 	State                   int               `gorm:"column:state;default:1"`
 	Size                    int64             `gorm:"column:size;default:0"`
+	TotalSize               int64             `gorm:"column:total_size;default:0"` // Folders: live file bytes below, recomputed in the background
 	MimeType                *string           `gorm:"column:mime_type;size:100;default:null"`
 	NodeKey                 string            `gorm:"column:node_key;type:text;not null"`
 	NodePassphrase          string            `gorm:"column:node_passphrase;type:text;not null"`
 	NodePassphraseSignature string            `gorm:"column:node_passphrase_signature;type:text"`
 	SignatureEmail          string            `gorm:"column:signature_email;size:255"`
 	CreatedAt               int64             `gorm:"column:created_at;autoCreateTime:false;not null"`
-	ModifiedAt              int64             `gorm:"column:modified_at;autoCreateTime:false;not null"`
+	ModifiedAt              int64             `gorm:"column:modified_at;autoCreateTime:false;not null;index:idx_drive_items_share_modified,priority:2"`
 	IsTrashed               bool              `gorm:"column:is_trashed;default:false"`
 	TrashedAt               *int64            `gorm:"column:trashed_at;default:null"`
+	PurgeAt                 *int64            `gorm:"column:purge_at;default:null;index:idx_drive_items_purge_at"` // When a trashed item is purged
 	Permissions             int               `gorm:"column:permissions;default:7"`
 	PermissionExpiresAt     *int64            `gorm:"column:permission_expires_at;default:null"`
 	IsShared                bool              `gorm:"column:is_shared;default:false"`
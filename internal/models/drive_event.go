@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DriveEvent records a change to a drive item so sync clients can fetch what changed since they last looked.
+// Events are ordered by their sequential ID, which is what client cursors point at.
+type DriveEvent struct {
+	ID        int64   `gorm:"primaryKey;autoIncrement;column:id;index:idx_drive_events_volume_id_id,priority:2"`
+	VolumeID  string  `gorm:"column:volume_id;not null;index:idx_drive_events_volume_id_id,priority:1"`
+	ShareID   string  `gorm:"column:share_id;not null"`
+	LinkID    string  `gorm:"column:link_id;not null"`
+	ParentID  *string `gorm:"column:parent_id;default:null"`
+	Type      int     `gorm:"column:type;not null"`      // 1=create, 2=update, 3=move, 4=trash, 5=restore, 6=delete, 7=device settings
+	LinkType  int     `gorm:"column:link_type;not null"` // 0 for device settings events
+	CreatedAt int64   `gorm:"column:created_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for DriveEvent
+func (DriveEvent) TableName() string {
+	return "drive_events"
+}
+
+// BeforeCreate hook for DriveEvent
+func (de *DriveEvent) BeforeCreate(tx *gorm.DB) error {
+	if de.CreatedAt == 0 {
+		de.CreatedAt = time.Now().Unix()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// ErrorMetric is one hour of rejected or failed requests for a route, client version, HTTP status and API error code.
+// Like UsageMetric it holds counts only.
+type ErrorMetric struct {
+	ID            string `gorm:"primaryKey;column:id"`
+	Hour          string `gorm:"column:hour;size:13;not null;uniqueIndex:idx_error_metrics_hour_route_version_code,priority:1"`
+	Route         string `gorm:"column:route;size:200;not null;uniqueIndex:idx_error_metrics_hour_route_version_code,priority:2;index"`
+	ClientVersion string `gorm:"column:client_version;size:50;not null;uniqueIndex:idx_error_metrics_hour_route_version_code,priority:3"`
+	HTTPStatus    int    `gorm:"column:http_status;not null;uniqueIndex:idx_error_metrics_hour_route_version_code,priority:4"`
+	ErrorCode     int    `gorm:"column:error_code;not null;uniqueIndex:idx_error_metrics_hour_route_version_code,priority:5;index"`
+	Count         int64  `gorm:"column:count;default:0"`
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt    int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for ErrorMetric
+func (ErrorMetric) TableName() string {
+	return "error_metrics_hourly"
+}
+
+// BeforeCreate hook for ErrorMetric
+func (m *ErrorMetric) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if m.ID == "" {
+		m.ID = utils.GenerateLinkID()
+	}
+	if m.CreatedAt == 0 {
+		m.CreatedAt = now
+	}
+	if m.ModifiedAt == 0 {
+		m.ModifiedAt = now
+	}
+	return nil
+}
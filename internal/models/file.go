@@ -9,12 +9,15 @@ import (
 
 // FileRevision represents a revision of a file
 type FileRevision struct {
-	ID             string `gorm:"primaryKey;column:id"`
-	ItemID         string `gorm:"column:item_id;not null;index:idx_file_revisions_item_id"`
-	Size           int64  `gorm:"column:size"`
-	CreatedAt      int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
-	State          int    `gorm:"column:state;default:1"`
-	SignatureEmail string `gorm:"column:signature_email;size:255"`
+	ID                string `gorm:"primaryKey;column:id"`
+	ItemID            string `gorm:"column:item_id;not null;index:idx_file_revisions_item_id"`
+	Size              int64  `gorm:"column:size"`
+	CreatedAt         int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	State             int    `gorm:"column:state;default:1"` // 1=active, 2=draft, 3=obsolete
+	SignatureEmail    string `gorm:"column:signature_email;size:255"`
+	ManifestSignature string `gorm:"column:manifest_signature;type:text"`
+	ModifiedAt        int64  `gorm:"column:modified_at;autoCreateTime:false;not null;default:0"` // Last upload activity of a draft
+	PausedAt          *int64 `gorm:"column:paused_at"`                                           // Set while the upload of a draft is paused
 
 	// Relationships
 	Item       DriveItem        `gorm:"foreignKey:ItemID"`
@@ -35,6 +38,9 @@ func (fr *FileRevision) BeforeCreate(tx *gorm.DB) error {
 	if fr.CreatedAt == 0 {
 		fr.CreatedAt = time.Now().Unix()
 	}
+	if fr.ModifiedAt == 0 {
+		fr.ModifiedAt = fr.CreatedAt
+	}
 	return nil
 }
 
@@ -67,9 +73,13 @@ type FileBlock struct {
 	Index              int    `gorm:"column:index;default:0"`
 	Size               int64  `gorm:"column:size"`
 	Hash               string `gorm:"column:hash;size:128;index:idx_file_blocks_hash"`
-	StoragePath        string `gorm:"column:storage_path;size:1024"`
+	ChecksumSHA256     string `gorm:"column:checksum_sha256;size:44"` // Base64 SHA-256 of the stored block, enforced by storage on upload
+	StoragePath        string `gorm:"column:storage_path;size:1024;index:idx_file_blocks_storage_path"`
 	StorageBucket      string `gorm:"column:storage_bucket;size:255"`
 	StorageRegion      string `gorm:"column:storage_region;size:50"`
+	StorageClass       string `gorm:"column:storage_class;size:30;default:'STANDARD'"`
+	ReplicationRegion  string `gorm:"column:replication_region;size:50"`    // Empty unless the block is replicated cross-region
+	MultipartUploadID  string `gorm:"column:multipart_upload_id;size:1024"` // Set for blocks too large for a single upload, which are uploaded in parts
 	KeyPacket          string `gorm:"column:key_packet;type:text"`
 	KeyPacketSignature string `gorm:"column:key_packet_signature;type:text"`
 	UploadComplete     bool   `gorm:"column:upload_complete;default:false"`
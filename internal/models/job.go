@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// Job is a unit of background work and its progress, visible to the user who started it
+type Job struct {
+	ID          string            `gorm:"primaryKey;column:id"`
+	UserID      string            `gorm:"column:user_id;not null;index:idx_jobs_user_id"`
+	Type        string            `gorm:"column:type;size:50;not null"`
+	State       string            `gorm:"column:state;size:20;not null;index:idx_jobs_state_modified_at,priority:1"`
+	Payload     map[string]string `gorm:"column:payload;type:jsonb;serializer:json"`
+	Result      map[string]int64  `gorm:"column:result;type:jsonb;serializer:json"`
+	Total       int64             `gorm:"column:total;default:0"`
+	Processed   int64             `gorm:"column:processed;default:0"`
+	Attempts    int               `gorm:"column:attempts;default:0"`
+	RunAfter    int64             `gorm:"column:run_after;default:0"` // A failed job is retried from this time
+	Error       *string           `gorm:"column:error;type:text;default:null"`
+	StartedAt   *int64            `gorm:"column:started_at;default:null"`
+	CompletedAt *int64            `gorm:"column:completed_at;default:null"`
+	CreatedAt   int64             `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt  int64             `gorm:"column:modified_at;autoCreateTime:false;not null;index:idx_jobs_state_modified_at,priority:2"`
+}
+
+// TableName specifies the table name for Job
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// BeforeCreate hook for Job
+func (j *Job) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if j.ID == "" {
+		j.ID = utils.GenerateLinkID()
+	}
+	if j.CreatedAt == 0 {
+		j.CreatedAt = now
+	}
+	if j.ModifiedAt == 0 {
+		j.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for Job
+func (j *Job) BeforeUpdate(tx *gorm.DB) error {
+	j.ModifiedAt = time.Now().Unix()
+	return nil
+}
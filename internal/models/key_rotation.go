@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// DriveKeyRotation is a user's move of the passphrases of the shares they own to their new primary
+// key. Clients re-wrap the passphrases in batches and may resume an interrupted rotation at any time.
+type DriveKeyRotation struct {
+	ID          string `gorm:"primaryKey;column:id"`
+	UserID      string `gorm:"column:user_id;not null;index:idx_drive_key_rotations_user_id"`
+	KeyID       string `gorm:"column:key_id;not null"` // Primary UserKey the passphrases are re-wrapped for
+	State       int    `gorm:"column:state;default:1"` // 1=in progress, 2=completed, 3=superseded by a later rotation
+	CreatedAt   int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt  int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+	CompletedAt *int64 `gorm:"column:completed_at;default:null"`
+}
+
+// TableName specifies the table name for DriveKeyRotation
+func (DriveKeyRotation) TableName() string {
+	return "drive_key_rotations"
+}
+
+// BeforeCreate hook for DriveKeyRotation
+func (kr *DriveKeyRotation) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if kr.ID == "" {
+		kr.ID = utils.GenerateLinkID()
+	}
+	if kr.CreatedAt == 0 {
+		kr.CreatedAt = now
+	}
+	if kr.ModifiedAt == 0 {
+		kr.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for DriveKeyRotation
+func (kr *DriveKeyRotation) BeforeUpdate(tx *gorm.DB) error {
+	kr.ModifiedAt = time.Now().Unix()
+	return nil
+}
+
+// DriveKeyRotationShare is the progress of a key rotation in one share. Items are re-wrapped in the
+// order of their IDs, so the cursor is all a client needs to resume.
+type DriveKeyRotationShare struct {
+	ID                string `gorm:"primaryKey;column:id"`
+	RotationID        string `gorm:"column:rotation_id;not null;uniqueIndex:idx_drive_key_rotation_shares_share,priority:1"`
+	ShareID           string `gorm:"column:share_id;not null;uniqueIndex:idx_drive_key_rotation_shares_share,priority:2"`
+	PassphraseRotated bool   `gorm:"column:passphrase_rotated;default:false"`
+	ItemsRotated      int64  `gorm:"column:items_rotated;default:0"`
+	ItemCursor        string `gorm:"column:item_cursor;not null;default:''"` // ID of the last re-wrapped item
+	CompletedAt       *int64 `gorm:"column:completed_at;default:null"`
+	ModifiedAt        int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for DriveKeyRotationShare
+func (DriveKeyRotationShare) TableName() string {
+	return "drive_key_rotation_shares"
+}
+
+// BeforeCreate hook for DriveKeyRotationShare
+func (krs *DriveKeyRotationShare) BeforeCreate(tx *gorm.DB) error {
+	if krs.ID == "" {
+		krs.ID = utils.GenerateLinkID()
+	}
+	if krs.ModifiedAt == 0 {
+		krs.ModifiedAt = time.Now().Unix()
+	}
+	return nil
+}
+
+// BeforeUpdate hook for DriveKeyRotationShare
+func (krs *DriveKeyRotationShare) BeforeUpdate(tx *gorm.DB) error {
+	krs.ModifiedAt = time.Now().Unix()
+	return nil
+}
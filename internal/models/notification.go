@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// Notification is an in-app message shown to a user until they read it. Clients render the text
+// from the type and its data, so notifications follow the language of the app.
+type Notification struct {
+	ID        string            `gorm:"primaryKey;column:id"`
+	UserID    string            `gorm:"column:user_id;not null;index:idx_notifications_user_id_created_at,priority:1"`
+	Type      string            `gorm:"column:type;size:50;not null"`
+	Data      map[string]string `gorm:"column:data;type:jsonb;serializer:json"`
+	ReadAt    *int64            `gorm:"column:read_at;default:null"`
+	CreatedAt int64             `gorm:"column:created_at;autoCreateTime:false;not null;index:idx_notifications_user_id_created_at,priority:2"`
+}
+
+// TableName specifies the table name for Notification
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// BeforeCreate hook for Notification
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == "" {
+		n.ID = utils.GenerateLinkID()
+	}
+	if n.CreatedAt == 0 {
+		n.CreatedAt = time.Now().Unix()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// OAuthClient is a third-party application registered by an admin to act on behalf of users.
+// Confidential clients authenticate to the token endpoint with a secret; public clients, such as
+// mobile and single-page apps, rely on PKCE alone.
+type OAuthClient struct {
+	ID           string   `gorm:"primaryKey;column:id"`
+	ClientID     string   `gorm:"column:client_id;size:64;not null;uniqueIndex:idx_oauth_clients_client_id"`
+	Name         string   `gorm:"column:name;size:100;not null"`
+	Description  string   `gorm:"column:description;size:255"`
+	HomepageURL  string   `gorm:"column:homepage_url;size:2048"`
+	SecretHash   string   `gorm:"column:secret_hash;size:64" json:"-"` // Empty for public clients
+	Confidential bool     `gorm:"column:confidential;default:false"`
+	RedirectURIs []string `gorm:"column:redirect_uris;type:jsonb;serializer:json;default:'[]'"`
+	Scopes       []string `gorm:"column:scopes;type:jsonb;serializer:json;default:'[]'"` // Scopes the client may request
+	State        int      `gorm:"column:state;default:1"`                                // 1=active, 2=disabled
+	CreatedBy    string   `gorm:"column:created_by;not null"`
+	CreatedAt    int64    `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt   int64    `gorm:"column:modified_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for OAuthClient
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// BeforeCreate hook for OAuthClient
+func (c *OAuthClient) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if c.ID == "" {
+		c.ID = utils.GenerateLinkID()
+	}
+	if c.CreatedAt == 0 {
+		c.CreatedAt = now
+	}
+	if c.ModifiedAt == 0 {
+		c.ModifiedAt = now
+	}
+	return nil
+}
+
+// OAuthConsent records the scopes a user agreed to grant a client. Later authorizations for the
+// same scopes do not ask again.
+type OAuthConsent struct {
+	ID         string   `gorm:"primaryKey;column:id"`
+	UserID     string   `gorm:"column:user_id;not null;uniqueIndex:idx_oauth_consents_user_client"`
+	ClientID   string   `gorm:"column:client_id;size:64;not null;uniqueIndex:idx_oauth_consents_user_client"`
+	Scopes     []string `gorm:"column:scopes;type:jsonb;serializer:json;default:'[]'"`
+	CreatedAt  int64    `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt int64    `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName specifies the table name for OAuthConsent
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// BeforeCreate hook for OAuthConsent
+func (c *OAuthConsent) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if c.ID == "" {
+		c.ID = utils.GenerateLinkID()
+	}
+	if c.CreatedAt == 0 {
+		c.CreatedAt = now
+	}
+	if c.ModifiedAt == 0 {
+		c.ModifiedAt = now
+	}
+	return nil
+}
+
+// OAuthToken is a grant issued to a client: an access token and the refresh token that renews it.
+// Only hashes of the tokens are stored; refreshing replaces both.
+type OAuthToken struct {
+	ID               string   `gorm:"primaryKey;column:id"`
+	ClientID         string   `gorm:"column:client_id;size:64;not null;index:idx_oauth_tokens_client_user"`
+	UserID           string   `gorm:"column:user_id;not null;index:idx_oauth_tokens_client_user"`
+	Scopes           []string `gorm:"column:scopes;type:jsonb;serializer:json;default:'[]'"`
+	AccessTokenHash  string   `gorm:"column:access_token_hash;size:64;not null;uniqueIndex:idx_oauth_tokens_access_hash"`
+	RefreshTokenHash string   `gorm:"column:refresh_token_hash;size:64;not null;uniqueIndex:idx_oauth_tokens_refresh_hash"`
+	AccessExpiresAt  int64    `gorm:"column:access_expires_at;not null"`
+	RefreshExpiresAt int64    `gorm:"column:refresh_expires_at;not null"`
+	LastUsedAt       *int64   `gorm:"column:last_used_at;default:null"`
+	RevokedAt        *int64   `gorm:"column:revoked_at;default:null"`
+	CreatedAt        int64    `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt       int64    `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName specifies the table name for OAuthToken
+func (OAuthToken) TableName() string {
+	return "oauth_tokens"
+}
+
+// BeforeCreate hook for OAuthToken
+func (t *OAuthToken) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if t.ID == "" {
+		t.ID = utils.GenerateLinkID()
+	}
+	if t.CreatedAt == 0 {
+		t.CreatedAt = now
+	}
+	if t.ModifiedAt == 0 {
+		t.ModifiedAt = now
+	}
+	return nil
+}
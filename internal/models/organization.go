@@ -0,0 +1,213 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// Organization groups users and the service accounts they manage
+type Organization struct {
+	ID         string `gorm:"primaryKey;column:id"`
+	Name       string `gorm:"column:name;size:100;not null"`
+	OwnerID    string `gorm:"column:owner_id;not null;index:idx_organizations_owner_id"`
+	CreatedAt  int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Owner           User                 `gorm:"foreignKey:OwnerID"`
+	Members         []OrganizationMember `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE"`
+	ServiceAccounts []ServiceAccount     `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName specifies the table name for Organization
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// BeforeCreate hook for Organization
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if o.ID == "" {
+		o.ID = utils.GenerateLinkID()
+	}
+	if o.CreatedAt == 0 {
+		o.CreatedAt = now
+	}
+	if o.ModifiedAt == 0 {
+		o.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for Organization
+func (o *Organization) BeforeUpdate(tx *gorm.DB) error {
+	o.ModifiedAt = time.Now().Unix()
+	return nil
+}
+
+// OrganizationMember links a user to an organization with a role
+type OrganizationMember struct {
+	ID             string `gorm:"primaryKey;column:id"`
+	OrganizationID string `gorm:"column:organization_id;not null;index:idx_organization_members_org_user,unique"`
+	UserID         string `gorm:"column:user_id;not null;index:idx_organization_members_org_user,unique;index:idx_organization_members_user_id"`
+	Role           int    `gorm:"column:role;default:1"` // 1=member, 2=admin
+	CreatedAt      int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt     int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID"`
+	User         User         `gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for OrganizationMember
+func (OrganizationMember) TableName() string {
+	return "organization_members"
+}
+
+// BeforeCreate hook for OrganizationMember
+func (om *OrganizationMember) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if om.ID == "" {
+		om.ID = utils.GenerateLinkID()
+	}
+	if om.CreatedAt == 0 {
+		om.CreatedAt = now
+	}
+	if om.ModifiedAt == 0 {
+		om.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for OrganizationMember
+func (om *OrganizationMember) BeforeUpdate(tx *gorm.DB) error {
+	om.ModifiedAt = time.Now().Unix()
+	return nil
+}
+
+// ServiceAccount is an organization-owned, non-interactive identity.
+// It is backed by a User row so it can hold keys and share memberships, but it has no SRP
+// credentials and can only authenticate with access tokens.
+type ServiceAccount struct {
+	ID             string `gorm:"primaryKey;column:id"`
+	OrganizationID string `gorm:"column:organization_id;not null;index:idx_service_accounts_org_id"`
+	UserID         string `gorm:"column:user_id;not null;unique;index:idx_service_accounts_user_id"`
+	Name           string `gorm:"column:name;size:100;not null"`
+	Description    string `gorm:"column:description;size:255"`
+	CreatedBy      string `gorm:"column:created_by;not null"`
+	State          int    `gorm:"column:state;default:1"` // 1=active, 2=disabled
+	CreatedAt      int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt     int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID"`
+	User         User         `gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for ServiceAccount
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// BeforeCreate hook for ServiceAccount
+func (sa *ServiceAccount) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if sa.ID == "" {
+		sa.ID = utils.GenerateLinkID()
+	}
+	if sa.CreatedAt == 0 {
+		sa.CreatedAt = now
+	}
+	if sa.ModifiedAt == 0 {
+		sa.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for ServiceAccount
+func (sa *ServiceAccount) BeforeUpdate(tx *gorm.DB) error {
+	sa.ModifiedAt = time.Now().Unix()
+	return nil
+}
+
+// AccessToken is a personal access token. Only the SHA-256 hash of the secret is stored.
+type AccessToken struct {
+	ID         string   `gorm:"primaryKey;column:id"`
+	UserID     string   `gorm:"column:user_id;not null;index:idx_access_tokens_user_id"`
+	Name       string   `gorm:"column:name;size:100;not null"`
+	Prefix     string   `gorm:"column:prefix;size:16;not null"`
+	TokenHash  string   `gorm:"column:token_hash;size:64;not null;unique;index:idx_access_tokens_token_hash"`
+	Scopes     []string `gorm:"column:scopes;type:jsonb;serializer:json;default:'[]'"`
+	CreatedBy  string   `gorm:"column:created_by;not null"`
+	ExpiresAt  *int64   `gorm:"column:expires_at;default:null"`
+	LastUsedAt *int64   `gorm:"column:last_used_at;default:null"`
+	LastUsedIP *string  `gorm:"column:last_used_ip;size:45;default:null"`
+	RevokedAt  *int64   `gorm:"column:revoked_at;default:null"`
+	CreatedAt  int64    `gorm:"column:created_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for AccessToken
+func (AccessToken) TableName() string {
+	return "access_tokens"
+}
+
+// BeforeCreate hook for AccessToken
+func (at *AccessToken) BeforeCreate(tx *gorm.DB) error {
+	if at.ID == "" {
+		at.ID = utils.GenerateLinkID()
+	}
+	if at.CreatedAt == 0 {
+		at.CreatedAt = time.Now().Unix()
+	}
+	return nil
+}
+
+// OrganizationEncryptionKey is a customer-managed KMS key used to encrypt objects the organization
+// owner stores server-side. Only one key per organization is active; replaced keys are kept as
+// retired because objects written under them are still encrypted with them.
+type OrganizationEncryptionKey struct {
+	ID             string `gorm:"primaryKey;column:id"`
+	OrganizationID string `gorm:"column:organization_id;not null;index:idx_organization_encryption_keys_org_id"`
+	KeyARN         string `gorm:"column:key_arn;size:2048;not null"`
+	State          int    `gorm:"column:state;default:1"` // 1=active, 2=retired
+	CreatedBy      string `gorm:"column:created_by;not null"`
+	VerifiedAt     int64  `gorm:"column:verified_at;not null"` // Last time KMS issued a data key under the key
+	RetiredAt      *int64 `gorm:"column:retired_at;default:null"`
+	CreatedAt      int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt     int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID"`
+}
+
+// TableName specifies the table name for OrganizationEncryptionKey
+func (OrganizationEncryptionKey) TableName() string {
+	return "organization_encryption_keys"
+}
+
+// BeforeCreate hook for OrganizationEncryptionKey
+func (k *OrganizationEncryptionKey) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if k.ID == "" {
+		k.ID = utils.GenerateLinkID()
+	}
+	if k.CreatedAt == 0 {
+		k.CreatedAt = now
+	}
+	if k.ModifiedAt == 0 {
+		k.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for OrganizationEncryptionKey
+func (k *OrganizationEncryptionKey) BeforeUpdate(tx *gorm.DB) error {
+	k.ModifiedAt = time.Now().Unix()
+	return nil
+}
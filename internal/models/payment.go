@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// PaymentOutbox is a payment provider call recorded together with the change that needs it,
+// delivered by the payments worker with retries until the provider accepts it
+type PaymentOutbox struct {
+	ID            string  `gorm:"primaryKey;column:id"`
+	UserID        string  `gorm:"column:user_id;not null;uniqueIndex:idx_payment_outbox_user_type,priority:1"`
+	Type          string  `gorm:"column:type;size:50;not null;uniqueIndex:idx_payment_outbox_user_type,priority:2"`
+	State         string  `gorm:"column:state;size:20;not null;index:idx_payment_outbox_state_next_attempt,priority:1"`
+	Attempts      int     `gorm:"column:attempts;default:0"`
+	NextAttemptAt int64   `gorm:"column:next_attempt_at;not null;index:idx_payment_outbox_state_next_attempt,priority:2"`
+	LastError     *string `gorm:"column:last_error;type:text;default:null"`
+	CompletedAt   *int64  `gorm:"column:completed_at;default:null"`
+	CreatedAt     int64   `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt    int64   `gorm:"column:modified_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for PaymentOutbox
+func (PaymentOutbox) TableName() string {
+	return "payment_outbox"
+}
+
+// BeforeCreate hook for PaymentOutbox
+func (p *PaymentOutbox) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if p.ID == "" {
+		p.ID = utils.GenerateLinkID()
+	}
+	if p.CreatedAt == 0 {
+		p.CreatedAt = now
+	}
+	if p.ModifiedAt == 0 {
+		p.ModifiedAt = now
+	}
+	if p.NextAttemptAt == 0 {
+		p.NextAttemptAt = now
+	}
+	return nil
+}
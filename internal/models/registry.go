@@ -0,0 +1,85 @@
+package models
+
+// AutoMigrateModels returns every model managed by GORM auto-migration, in dependency order.
+// New models must be added here so development databases and the integration harness pick them up.
+func AutoMigrateModels() []interface{} {
+	return []interface{}{
+		&User{},
+		&UserSRP{},
+		&UserCredit{},
+		&UserSecurityEvent{},
+		&SecurityEventDownload{},
+		&UserSecuritySettings{},
+		&UserKey{},
+		&UserRecoveryKit{},
+		&UserSession{},
+		&VolumeAllocation{},
+		&UserStorage{},
+		&UserDevice{},
+		&EmailMethods{},
+		&PhoneMethods{},
+		&TOTPMethods{},
+		&UserMFASettings{},
+		&WebAuthnCredential{},
+		&UserNotifications{},
+		&UserPreferences{},
+		&UserConsent{},
+		&UserConsentRecord{},
+		&Notification{},
+
+		// Organization models
+		&Organization{},
+		&OrganizationMember{},
+		&ServiceAccount{},
+		&AccessToken{},
+		&OrganizationEncryptionKey{},
+
+		// OAuth provider models
+		&OAuthClient{},
+		&OAuthConsent{},
+		&OAuthToken{},
+
+		// Admin models
+		&AdminPermission{},
+		&AdminAuditLog{},
+
+		// Background jobs
+		&Job{},
+
+		// Billing models
+		&BillingInfo{},
+		&Plan{},
+		&UserPlan{},
+		&UserBilling{},
+		&UserPaymentMethod{},
+		&GiftCard{},
+		&PaymentOutbox{},
+
+		// Analytics models
+		&UsageMetric{},
+		&ErrorMetric{},
+
+		// Webhook models
+		&WebhookSecret{},
+
+		// Drive models
+		&DriveVolume{},
+		&DriveShare{},
+		&DriveShareMembership{},
+		&DriveItem{},
+		&FileRevision{},
+		&DriveThumbnail{},
+		&FileBlock{},
+		&DriveSearchToken{},
+		&DriveSearchKeyState{},
+		&DriveTag{},
+		&DriveItemTag{},
+		&DriveShareURL{},
+		&DriveEvent{},
+		&StorageIntegrityIssue{},
+		&DriveBackupSet{},
+		&DriveSyncCursor{},
+		&DriveKeyRotation{},
+		&DriveKeyRotationShare{},
+	}
+}
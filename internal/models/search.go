@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// DriveSearchToken stores a deterministic encrypted name token for a drive item.
+// Tokens are computed client-side so the server can match them without learning names.
+type DriveSearchToken struct {
+	ID         string `gorm:"primaryKey;column:id"`
+	UserID     string `gorm:"column:user_id;not null;index:idx_drive_search_tokens_user_id"`
+	ShareID    string `gorm:"column:share_id;not null;index:idx_drive_search_tokens_share_id"`
+	ItemID     string `gorm:"column:item_id;not null;index:idx_drive_search_tokens_item_id"`
+	Token      string `gorm:"column:token;size:128;not null;index:idx_drive_search_tokens_token"`
+	KeyVersion int    `gorm:"column:key_version;not null;default:1"`
+	CreatedAt  int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Item DriveItem `gorm:"foreignKey:ItemID"`
+}
+
+// TableName specifies the table name for DriveSearchToken
+func (DriveSearchToken) TableName() string {
+	return "drive_search_tokens"
+}
+
+// BeforeCreate hook for DriveSearchToken
+func (dst *DriveSearchToken) BeforeCreate(tx *gorm.DB) error {
+	if dst.ID == "" {
+		dst.ID = utils.GenerateLinkID()
+	}
+	if dst.CreatedAt == 0 {
+		dst.CreatedAt = time.Now().Unix()
+	}
+	return nil
+}
+
+// DriveSearchKeyState tracks a user's active search token key version and reindex progress
+type DriveSearchKeyState struct {
+	ID                 string `gorm:"primaryKey;column:id"`
+	UserID             string `gorm:"column:user_id;not null;unique;index:idx_drive_search_key_states_user_id"`
+	KeyVersion         int    `gorm:"column:key_version;not null;default:1"`
+	PendingKeyVersion  *int   `gorm:"column:pending_key_version;default:null"`
+	ReindexStartedAt   *int64 `gorm:"column:reindex_started_at;default:null"`
+	ReindexCompletedAt *int64 `gorm:"column:reindex_completed_at;default:null"`
+	CreatedAt          int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt         int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for DriveSearchKeyState
+func (DriveSearchKeyState) TableName() string {
+	return "drive_search_key_states"
+}
+
+// BeforeCreate hook for DriveSearchKeyState
+func (dsks *DriveSearchKeyState) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if dsks.ID == "" {
+		dsks.ID = utils.GenerateLinkID()
+	}
+	if dsks.CreatedAt == 0 {
+		dsks.CreatedAt = now
+	}
+	if dsks.ModifiedAt == 0 {
+		dsks.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for DriveSearchKeyState
+func (dsks *DriveSearchKeyState) BeforeUpdate(tx *gorm.DB) error {
+	dsks.ModifiedAt = time.Now().Unix()
+	return nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// DriveShareURL represents a public link to a drive item.
+// The share passphrase is wrapped client-side with the link password so the server never sees it.
+type DriveShareURL struct {
+	ID                       string  `gorm:"primaryKey;column:id"`
+	ShareID                  string  `gorm:"column:share_id;not null;index:idx_drive_share_urls_share_id"`
+	ItemID                   string  `gorm:"column:item_id;not null;index:idx_drive_share_urls_item_id"`
+	CreatorID                string  `gorm:"column:creator_id;not null;index:idx_drive_share_urls_creator_id"`
+	Token                    string  `gorm:"column:token;size:64;not null;unique;index:idx_drive_share_urls_token"`
+	Slug                     *string `gorm:"column:slug;size:64;unique;index:idx_drive_share_urls_slug;default:null"`
+	Permissions              int     `gorm:"column:permissions;default:4"`
+	State                    int     `gorm:"column:state;default:1"` // 1=active, 2=revoked
+	SharePasswordSalt        string  `gorm:"column:share_password_salt;type:text;not null"`
+	SharePassphraseKeyPacket string  `gorm:"column:share_passphrase_key_packet;type:text;not null"`
+	NumAccesses              int     `gorm:"column:num_accesses;default:0"`
+	ExpiresAt                *int64  `gorm:"column:expires_at;default:null"`
+	CreatedAt                int64   `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt               int64   `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Share DriveShare `gorm:"foreignKey:ShareID"`
+	Item  DriveItem  `gorm:"foreignKey:ItemID"`
+}
+
+// TableName specifies the table name for DriveShareURL
+func (DriveShareURL) TableName() string {
+	return "drive_share_urls"
+}
+
+// BeforeCreate hook for DriveShareURL
+func (dsu *DriveShareURL) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if dsu.ID == "" {
+		dsu.ID = utils.GenerateLinkID()
+	}
+	if dsu.Token == "" {
+		dsu.Token = utils.GenerateShortID()
+	}
+	if dsu.CreatedAt == 0 {
+		dsu.CreatedAt = now
+	}
+	if dsu.ModifiedAt == 0 {
+		dsu.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for DriveShareURL
+func (dsu *DriveShareURL) BeforeUpdate(tx *gorm.DB) error {
+	dsu.ModifiedAt = time.Now().Unix()
+	return nil
+}
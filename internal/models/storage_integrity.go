@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// StorageIntegrityIssue is a file block whose stored object does not match its database record.
+// One row is kept per block; it is updated while the problem persists and resolved once a later
+// audit finds the object intact again.
+type StorageIntegrityIssue struct {
+	ID               string `gorm:"primaryKey;column:id"`
+	BlockID          string `gorm:"column:block_id;not null;uniqueIndex:idx_storage_integrity_issues_block_id"`
+	RevisionID       string `gorm:"column:revision_id;not null;index:idx_storage_integrity_issues_revision_id"`
+	ItemID           string `gorm:"column:item_id;not null"`
+	VolumeID         string `gorm:"column:volume_id;not null"`
+	UserID           string `gorm:"column:user_id;not null;index:idx_storage_integrity_issues_user_id"` // Owner of the volume
+	StoragePath      string `gorm:"column:storage_path;size:1024"`
+	Kind             string `gorm:"column:kind;size:30;not null"` // missing, size_mismatch, checksum_mismatch
+	ExpectedSize     int64  `gorm:"column:expected_size"`
+	ActualSize       int64  `gorm:"column:actual_size"`
+	ExpectedChecksum string `gorm:"column:expected_checksum;size:44"`
+	ActualChecksum   string `gorm:"column:actual_checksum;size:44"`
+	ETag             string `gorm:"column:etag;size:100"`
+	Recoverable      bool   `gorm:"column:recoverable;default:false"` // Whether a replica can restore the block
+	State            string `gorm:"column:state;size:20;not null;default:'open';index:idx_storage_integrity_issues_state"`
+	NotifiedAt       *int64 `gorm:"column:notified_at;default:null"`
+	DetectedAt       int64  `gorm:"column:detected_at;not null"`
+	LastCheckedAt    int64  `gorm:"column:last_checked_at;not null"`
+	ResolvedAt       *int64 `gorm:"column:resolved_at;default:null"`
+}
+
+// TableName specifies the table name for StorageIntegrityIssue
+func (StorageIntegrityIssue) TableName() string {
+	return "storage_integrity_issues"
+}
+
+// BeforeCreate hook for StorageIntegrityIssue
+func (i *StorageIntegrityIssue) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if i.ID == "" {
+		i.ID = utils.GenerateLinkID()
+	}
+	if i.DetectedAt == 0 {
+		i.DetectedAt = now
+	}
+	if i.LastCheckedAt == 0 {
+		i.LastCheckedAt = now
+	}
+	return nil
+}
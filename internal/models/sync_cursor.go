@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// DriveSyncCursor is the point in a volume's change log up to which one of the user's devices has
+// applied the changes. Comparing the cursors of a user's devices shows which replicas lag behind
+// or have diverged.
+type DriveSyncCursor struct {
+	ID             string `gorm:"primaryKey;column:id"`
+	UserID         string `gorm:"column:user_id;not null;index:idx_drive_sync_cursors_user_id"`
+	DeviceID       string `gorm:"column:device_id;not null;uniqueIndex:idx_drive_sync_cursors_device_id_volume_id,priority:1"` // ID of the UserDevice
+	VolumeID       string `gorm:"column:volume_id;not null;uniqueIndex:idx_drive_sync_cursors_device_id_volume_id,priority:2"`
+	LastEventID    int64  `gorm:"column:last_event_id;not null;default:0"` // Last event the device acknowledged
+	AcknowledgedAt int64  `gorm:"column:acknowledged_at;not null"`
+	CreatedAt      int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt     int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for DriveSyncCursor
+func (DriveSyncCursor) TableName() string {
+	return "drive_sync_cursors"
+}
+
+// BeforeCreate hook for DriveSyncCursor
+func (sc *DriveSyncCursor) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if sc.ID == "" {
+		sc.ID = utils.GenerateLinkID()
+	}
+	if sc.CreatedAt == 0 {
+		sc.CreatedAt = now
+	}
+	if sc.ModifiedAt == 0 {
+		sc.ModifiedAt = now
+	}
+	return nil
+}
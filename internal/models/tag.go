@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// DriveTag is a user-defined label for organizing drive items. The label is encrypted
+// client-side, so the server only ever handles the tag's opaque ID.
+type DriveTag struct {
+	ID            string `gorm:"primaryKey;column:id"`
+	UserID        string `gorm:"column:user_id;not null;index:idx_drive_tags_user_id"`
+	EncryptedName string `gorm:"column:encrypted_name;type:text;not null"`
+	Color         string `gorm:"column:color;size:7"` // Optional #rrggbb display color
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt    int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Items []DriveItemTag `gorm:"foreignKey:TagID"`
+}
+
+// TableName specifies the table name for DriveTag
+func (DriveTag) TableName() string {
+	return "drive_tags"
+}
+
+// BeforeCreate hook for DriveTag
+func (dt *DriveTag) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if dt.ID == "" {
+		dt.ID = utils.GenerateLinkID()
+	}
+	if dt.CreatedAt == 0 {
+		dt.CreatedAt = now
+	}
+	if dt.ModifiedAt == 0 {
+		dt.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for DriveTag
+func (dt *DriveTag) BeforeUpdate(tx *gorm.DB) error {
+	dt.ModifiedAt = time.Now().Unix()
+	return nil
+}
+
+// DriveItemTag attaches a user's tag to a drive item. Tags are private, so other
+// members of the share never see them.
+type DriveItemTag struct {
+	ID        string `gorm:"primaryKey;column:id"`
+	TagID     string `gorm:"column:tag_id;not null;uniqueIndex:idx_drive_item_tags_tag_item,priority:1"`
+	ItemID    string `gorm:"column:item_id;not null;uniqueIndex:idx_drive_item_tags_tag_item,priority:2;index:idx_drive_item_tags_item_id"`
+	ShareID   string `gorm:"column:share_id;not null;index:idx_drive_item_tags_share_id"`
+	UserID    string `gorm:"column:user_id;not null;index:idx_drive_item_tags_user_id"`
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	Tag  DriveTag  `gorm:"foreignKey:TagID"`
+	Item DriveItem `gorm:"foreignKey:ItemID"`
+}
+
+// TableName specifies the table name for DriveItemTag
+func (DriveItemTag) TableName() string {
+	return "drive_item_tags"
+}
+
+// BeforeCreate hook for DriveItemTag
+func (dit *DriveItemTag) BeforeCreate(tx *gorm.DB) error {
+	if dit.ID == "" {
+		dit.ID = utils.GenerateLinkID()
+	}
+	if dit.CreatedAt == 0 {
+		dit.CreatedAt = time.Now().Unix()
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// UsageMetric is one day of anonymized feature usage for a route family and client version.
+// It holds counts only; no user identifier ever reaches this table.
+type UsageMetric struct {
+	ID            string `gorm:"primaryKey;column:id"`
+	Day           string `gorm:"column:day;size:10;not null;uniqueIndex:idx_usage_metrics_day_family_version,priority:1"`
+	RouteFamily   string `gorm:"column:route_family;size:100;not null;uniqueIndex:idx_usage_metrics_day_family_version,priority:2"`
+	ClientVersion string `gorm:"column:client_version;size:50;not null;uniqueIndex:idx_usage_metrics_day_family_version,priority:3"`
+	UniqueUsers   int64  `gorm:"column:unique_users;default:0"`
+	Requests      int64  `gorm:"column:requests;default:0"`
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt    int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+}
+
+// TableName specifies the table name for UsageMetric
+func (UsageMetric) TableName() string {
+	return "usage_metrics_daily"
+}
+
+// BeforeCreate hook for UsageMetric
+func (m *UsageMetric) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if m.ID == "" {
+		m.ID = utils.GenerateLinkID()
+	}
+	if m.CreatedAt == 0 {
+		m.CreatedAt = now
+	}
+	if m.ModifiedAt == 0 {
+		m.ModifiedAt = now
+	}
+	return nil
+}
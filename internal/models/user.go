@@ -30,6 +30,10 @@ type User struct {
 	StripeUserExists bool           `gorm:"column:stripe_user_exists;default:true"`
 	StripeCustomerID string         `gorm:"column:stripe_customer_id;size:100;unique;index:idx_users_stripe_customer_id"`
 
+	// A requested deletion purges the account at DeletionScheduledAt, 0 unless one is pending
+	DeletionScheduledAt int64 `gorm:"column:deletion_scheduled_at;default:0;index:idx_users_deletion_scheduled_at"`
+	DeletionWarnedAt    int64 `gorm:"column:deletion_warned_at;default:0"` // When the last warning of the pending deletion was sent
+
 	// Relationships
 	SRP                    []UserSRP               `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Credits                []UserCredit            `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
@@ -157,9 +161,12 @@ type SecurityEventDownload struct {
 	ID           string          `gorm:"primaryKey;column:id"`
 	UserID       string          `gorm:"column:user_id;not null;index:idx_downloads_user_id"`
 	Status       string          `gorm:"column:status;size:20;default:'processing';not null;index:idx_downloads_status"`
+	Format       string          `gorm:"column:format;size:10;default:'csv';not null"`
+	Progress     int             `gorm:"column:progress;default:0"` // Percentage of the report written
 	FileName     string          `gorm:"column:file_name;size:255;not null"`
 	FileSize     string          `gorm:"column:file_size;size:50"`
-	DownloadURL  string          `gorm:"column:download_url;size:512"`
+	StoragePath  string          `gorm:"column:storage_path;size:1024"`
+	DownloadURL  string          `gorm:"column:download_url;type:text"` // Presigned, valid until ExpiresAt
 	ErrorMessage string          `gorm:"column:error_message;type:text"`
 	CreatedAt    int64           `gorm:"column:created_at;autoCreateTime:false;not null;index:idx_downloads_created_at"`
 	CompletedAt  int64           `gorm:"column:completed_at"`
@@ -177,6 +184,9 @@ func (SecurityEventDownload) TableName() string {
 
 // BeforeCreate hook for SecurityEventDownload
 func (sed *SecurityEventDownload) BeforeCreate(tx *gorm.DB) error {
+	if sed.ID == "" {
+		sed.ID = utils.GenerateLinkID()
+	}
 	if sed.CreatedAt == 0 {
 		sed.CreatedAt = time.Now().Unix()
 	}
@@ -317,6 +327,14 @@ type UserSession struct {
 	IsValid    bool   `gorm:"column:is_valid;default:true;not null"`
 	LastActive int64  `gorm:"column:last_active;autoCreateTime:false;not null;index:idx_sessions_inactive,priority:1"`
 
+	// Where the session was signed in from, empty when the IP address could not be located
+	Country        string   `gorm:"column:country;size:2"`
+	City           string   `gorm:"column:city;size:100"`
+	Latitude       *float64 `gorm:"column:latitude"`
+	Longitude      *float64 `gorm:"column:longitude"`
+	ASN            uint     `gorm:"column:asn"`
+	ASOrganization string   `gorm:"column:as_organization;size:255"`
+
 	// Relationships
 	User User `gorm:"foreignKey:UserID"`
 }
@@ -403,6 +421,10 @@ type UserDevice struct {
 	RecoveryData json.RawMessage `gorm:"column:recovery_data;type:jsonb;serializer:json"`
 	Active       bool            `gorm:"column:active;default:true"`
 
+	// Sync pauses and bandwidth caps the desktop client applies on this device
+	SyncSchedule           json.RawMessage `gorm:"column:sync_schedule;type:jsonb;serializer:json"`
+	SyncScheduleModifiedAt int64           `gorm:"column:sync_schedule_modified_at;default:0"`
+
 	// Relationships
 	User User `gorm:"foreignKey:UserID"`
 }
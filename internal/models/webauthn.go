@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// WebAuthnCredential is a passkey registered by a user as a second factor.
+// Only the public key is stored, the private key never leaves the authenticator.
+type WebAuthnCredential struct {
+	ID           string   `gorm:"primaryKey;column:id"`
+	UserID       string   `gorm:"column:user_id;not null;index:idx_webauthn_credentials_user_id"`
+	CredentialID string   `gorm:"column:credential_id;size:1024;not null;uniqueIndex:idx_webauthn_credentials_credential_id"` // base64url encoded
+	PublicKey    []byte   `gorm:"column:public_key;not null"`                                                                 // COSE encoded
+	Algorithm    int      `gorm:"column:algorithm;not null"`
+	SignCount    int64    `gorm:"column:sign_count;default:0;not null"`
+	AAGUID       string   `gorm:"column:aaguid;size:36"`
+	Transports   []string `gorm:"column:transports;type:jsonb;serializer:json;default:'[]'"`
+	Name         string   `gorm:"column:name;size:100"`
+	LastUsed     *int64   `gorm:"column:last_used;default:null"`
+	CreatedAt    int64    `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt   int64    `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for WebAuthnCredential
+func (WebAuthnCredential) TableName() string {
+	return "users_webauthn_credentials"
+}
+
+// BeforeCreate hook for WebAuthnCredential
+func (wc *WebAuthnCredential) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if wc.ID == "" {
+		wc.ID = utils.GenerateLinkID()
+	}
+	if wc.CreatedAt == 0 {
+		wc.CreatedAt = now
+	}
+	if wc.ModifiedAt == 0 {
+		wc.ModifiedAt = now
+	}
+	return nil
+}
+
+// BeforeUpdate hook for WebAuthnCredential
+func (wc *WebAuthnCredential) BeforeUpdate(tx *gorm.DB) error {
+	wc.ModifiedAt = time.Now().Unix()
+	return nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"cirrussync-api/internal/utils"
+)
+
+// WebhookSecret is the key a user's webhook deliveries are signed with.
+// Receivers hold the same secret to verify the CirrusSync-Signature header.
+type WebhookSecret struct {
+	ID         string `gorm:"primaryKey;column:id"`
+	UserID     string `gorm:"column:user_id;not null;uniqueIndex:idx_webhook_secrets_user_id"`
+	Secret     string `gorm:"column:secret;size:100;not null" json:"-"`
+	CreatedAt  int64  `gorm:"column:created_at;autoCreateTime:false;not null"`
+	ModifiedAt int64  `gorm:"column:modified_at;autoCreateTime:false;not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName specifies the table name for WebhookSecret
+func (WebhookSecret) TableName() string {
+	return "webhook_secrets"
+}
+
+// BeforeCreate hook for WebhookSecret
+func (w *WebhookSecret) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().Unix()
+	if w.ID == "" {
+		w.ID = utils.GenerateLinkID()
+	}
+	if w.CreatedAt == 0 {
+		w.CreatedAt = now
+	}
+	if w.ModifiedAt == 0 {
+		w.ModifiedAt = now
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package notification
+
+import (
+	"errors"
+)
+
+// Notification errors
+var (
+	ErrInvalidInput         = errors.New("Invalid input")
+	ErrNotificationNotFound = errors.New("Notification not found")
+)
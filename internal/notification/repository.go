@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repository interface for notification operations
+type Repository interface {
+	CreateNotification(ctx context.Context, notification *models.Notification) error
+	GetNotifications(ctx context.Context, userID string, unreadOnly bool, limit int) ([]models.Notification, error)
+	CountUnread(ctx context.Context, userID string) (int64, error)
+	MarkRead(ctx context.Context, userID, notificationID string, readAt int64) (bool, error)
+	MarkAllRead(ctx context.Context, userID string, readAt int64) error
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new notification repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// CreateNotification stores a notification
+func (r *repo) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+// GetNotifications retrieves a user's newest notifications
+func (r *repo) GetNotifications(ctx context.Context, userID string, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&notifications).Error
+
+	return notifications, err
+}
+
+// CountUnread counts a user's unread notifications
+func (r *repo) CountUnread(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+
+	return count, err
+}
+
+// MarkRead marks one of a user's notifications as read. Reading a notification again keeps the time
+// it was first read. It reports false when the user has no such notification.
+func (r *repo) MarkRead(ctx context.Context, userID, notificationID string, readAt int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", notificationID, userID).
+		Update("read_at", readAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// MarkAllRead marks every unread notification of a user as read
+func (r *repo) MarkAllRead(ctx context.Context, userID string, readAt int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", readAt).Error
+}
@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"context"
+	"time"
+)
+
+// MAX_LIST_LIMIT bounds how many notifications are listed at once
+const MAX_LIST_LIMIT = 100
+
+// NewService creates a new notification service
+func NewService(repo Repository, logger *logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Notify adds a notification to a user's inbox
+func (s *Service) Notify(ctx context.Context, userID, notificationType string, data map[string]string) error {
+	if userID == "" || notificationType == "" {
+		return ErrInvalidInput
+	}
+
+	return s.repo.CreateNotification(ctx, &models.Notification{
+		UserID: userID,
+		Type:   notificationType,
+		Data:   data,
+	})
+}
+
+// GetInbox returns a user's newest notifications, optionally only the unread ones
+func (s *Service) GetInbox(ctx context.Context, userID string, unreadOnly bool, limit int) (*Inbox, error) {
+	if limit <= 0 || limit > MAX_LIST_LIMIT {
+		limit = MAX_LIST_LIMIT
+	}
+
+	notifications, err := s.repo.GetNotifications(ctx, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Inbox{Notifications: notifications, Unread: unread}, nil
+}
+
+// MarkRead marks one of a user's notifications as read
+func (s *Service) MarkRead(ctx context.Context, userID, notificationID string) error {
+	found, err := s.repo.MarkRead(ctx, userID, notificationID, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks every notification of a user as read
+func (s *Service) MarkAllRead(ctx context.Context, userID string) error {
+	return s.repo.MarkAllRead(ctx, userID, time.Now().Unix())
+}
@@ -0,0 +1,23 @@
+package notification
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+)
+
+// Notification types
+const (
+	TYPE_STORAGE_WARNING = "storage.warning" // Data: percent, usedBytes, limitBytes
+)
+
+// Service keeps the in-app notifications of users
+type Service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+// Inbox is the newest notifications of a user with how many of all their notifications are unread
+type Inbox struct {
+	Notifications []models.Notification
+	Unread        int64
+}
@@ -0,0 +1,169 @@
+package oauth
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// CODE_CHALLENGE_METHOD_S256 is the only PKCE method accepted; plain challenges would leak the verifier
+const CODE_CHALLENGE_METHOD_S256 = "S256"
+
+// GetAuthorizationPrompt validates an authorization request and describes it for the consent
+// screen, including whether the user already agreed to every requested scope
+func (s *Service) GetAuthorizationPrompt(ctx context.Context, userID string, request AuthorizationRequest) (*AuthorizationPrompt, error) {
+	client, scopes, err := s.validateAuthorizationRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	consent, err := s.repo.GetConsent(ctx, userID, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthorizationPrompt{
+		Client:         client,
+		Scopes:         scopes,
+		ConsentGranted: consent != nil && isSubset(scopes, consent.Scopes),
+	}, nil
+}
+
+// Authorize answers an authorization request on behalf of the user and returns the URL to send the
+// user back to. When approved, the consent is recorded and the URL carries a one-time code. Errors
+// that can be reported to the client, including a denial, are carried in the URL instead.
+// Requests whose client or redirect URI cannot be trusted return an error and must not redirect.
+func (s *Service) Authorize(ctx context.Context, userID string, request AuthorizationRequest, approved bool, ipAddress string) (string, error) {
+	client, scopes, err := s.validateAuthorizationRequest(ctx, request)
+	if err != nil {
+		var oauthErr *Error
+		if errors.As(err, &oauthErr) {
+			return errorRedirect(request.RedirectURI, request.State, s.config.Issuer, oauthErr), nil
+		}
+		return "", err
+	}
+
+	if !approved {
+		return errorRedirect(request.RedirectURI, request.State, s.config.Issuer, ErrAccessDenied), nil
+	}
+
+	// Keep earlier grants so apps that ask for fewer scopes later do not lose them
+	consent, err := s.repo.GetConsent(ctx, userID, client.ClientID)
+	if err != nil {
+		return "", err
+	}
+	if consent == nil || !isSubset(scopes, consent.Scopes) {
+		granted := scopes
+		if consent != nil {
+			granted = slices.Compact(slices.Sorted(slices.Values(append(slices.Clone(consent.Scopes), scopes...))))
+		}
+		err := s.repo.SaveConsent(ctx, &models.OAuthConsent{
+			UserID:     userID,
+			ClientID:   client.ClientID,
+			Scopes:     granted,
+			ModifiedAt: time.Now().Unix(),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to save OAuth consent: %w", err)
+		}
+
+		s.securityEvents.Record(ctx, security.Event{
+			UserID:    userID,
+			EventType: security.EVENT_OAUTH_AUTHORIZED,
+			Success:   true,
+			IPAddress: ipAddress,
+			Metadata: map[string]any{
+				"clientId": client.ClientID,
+				"scopes":   granted,
+			},
+		})
+	}
+
+	code := utils.GenerateID()
+	err = s.redisClient.SetJSON(ctx, codeCacheKey(code), authorizationCode{
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   request.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: request.CodeChallenge,
+		Nonce:         request.Nonce,
+		AuthTime:      time.Now().Unix(),
+	}, s.config.CodeExpiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to store authorization code: %w", err)
+	}
+
+	return redirectWithParams(request.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {request.State},
+		"iss":   {s.config.Issuer},
+	}), nil
+}
+
+// validateAuthorizationRequest checks the client and redirect URI first, since nothing can be
+// reported to the client without them, then the scopes and PKCE challenge
+func (s *Service) validateAuthorizationRequest(ctx context.Context, request AuthorizationRequest) (*models.OAuthClient, []string, error) {
+	client, err := s.getActiveClient(ctx, request.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(client.RedirectURIs, request.RedirectURI) {
+		return nil, nil, ErrRedirectURIMismatch
+	}
+
+	scopes := parseScopes(request.Scope)
+	if len(scopes) == 0 || !isSubset(scopes, client.Scopes) {
+		return nil, nil, ErrInvalidScope
+	}
+
+	if request.CodeChallengeMethod != CODE_CHALLENGE_METHOD_S256 || len(request.CodeChallenge) != 43 {
+		return nil, nil, ErrInvalidPKCE
+	}
+
+	return client, scopes, nil
+}
+
+// errorRedirect builds the redirect that reports an authorization error to the client
+func errorRedirect(redirectURI, state, issuer string, err *Error) string {
+	return redirectWithParams(redirectURI, url.Values{
+		"error":             {err.Code},
+		"error_description": {err.Description},
+		"state":             {state},
+		"iss":               {issuer},
+	})
+}
+
+// redirectWithParams adds query parameters to a registered redirect URI, dropping empty ones
+func redirectWithParams(redirectURI string, params url.Values) string {
+	// Registered URIs were parsed when the client was created
+	parsed, _ := url.Parse(redirectURI)
+	query := parsed.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// codeCacheKey is the cache key of an authorization code
+func codeCacheKey(code string) string {
+	return fmt.Sprintf("oauth_code:%s", hashSecret(code))
+}
+
+// isSubset reports whether every scope in requested is in allowed
+func isSubset(requested, allowed []string) bool {
+	for _, scope := range requested {
+		if !slices.Contains(allowed, scope) {
+			return false
+		}
+	}
+	return true
+}
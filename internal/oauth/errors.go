@@ -0,0 +1,41 @@
+package oauth
+
+import "errors"
+
+// Error is an error reported to OAuth clients with its RFC 6749 error code
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Description
+}
+
+// Protocol errors, returned to clients by the authorization and token endpoints
+var (
+	ErrInvalidRequest       = &Error{"invalid_request", "The request is missing a parameter or is otherwise malformed"}
+	ErrInvalidClient        = &Error{"invalid_client", "Client authentication failed"}
+	ErrInvalidGrant         = &Error{"invalid_grant", "Authorization code or refresh token is invalid, expired or revoked"}
+	ErrUnauthorizedClient   = &Error{"unauthorized_client", "Client is not allowed to use this grant"}
+	ErrUnsupportedGrantType = &Error{"unsupported_grant_type", "Grant type must be authorization_code or refresh_token"}
+	ErrInvalidScope         = &Error{"invalid_scope", "Requested scopes are unknown or not allowed for this client"}
+	ErrAccessDenied         = &Error{"access_denied", "The user denied the authorization request"}
+	ErrInvalidPKCE          = &Error{"invalid_request", "A code_challenge using the S256 method is required"}
+)
+
+// Client registration errors
+var (
+	ErrClientNotFound      = errors.New("OAuth client not found")
+	ErrClientDisabled      = errors.New("OAuth client is disabled")
+	ErrInvalidClientInput  = errors.New("OAuth clients need a name, at least one redirect URI and at least one allowed scope")
+	ErrInvalidRedirectURI  = errors.New("Redirect URIs must be absolute https URLs without a fragment, or http on localhost")
+	ErrRedirectURIMismatch = errors.New("Redirect URI is not registered for this client")
+)
+
+// Token and consent errors
+var (
+	ErrInvalidAccessToken = errors.New("OAuth access token is invalid, expired or revoked")
+	ErrConsentNotFound    = errors.New("No authorization found for this application")
+	ErrInvalidInput       = errors.New("Invalid input")
+)
@@ -0,0 +1,293 @@
+package oauth
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository interface for OAuth provider operations
+type Repository interface {
+	// Client methods
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	GetClients(ctx context.Context) ([]*models.OAuthClient, error)
+	GetClientsByClientIDs(ctx context.Context, clientIDs []string) (map[string]*models.OAuthClient, error)
+	UpdateClientSecret(ctx context.Context, clientID, secretHash string) error
+	DisableClient(ctx context.Context, clientID string) ([]string, error)
+
+	// Consent methods
+	GetConsent(ctx context.Context, userID, clientID string) (*models.OAuthConsent, error)
+	SaveConsent(ctx context.Context, consent *models.OAuthConsent) error
+	GetConsentsByUserID(ctx context.Context, userID string) ([]*models.OAuthConsent, error)
+	DeleteConsent(ctx context.Context, userID, clientID string) ([]string, error)
+
+	// Token methods
+	CreateToken(ctx context.Context, token *models.OAuthToken) error
+	GetTokenByAccessHash(ctx context.Context, accessHash string) (*models.OAuthToken, error)
+	GetTokenByRefreshHash(ctx context.Context, refreshHash string) (*models.OAuthToken, error)
+	RotateToken(ctx context.Context, token *models.OAuthToken, previousRefreshHash string) error
+	RevokeToken(ctx context.Context, tokenID string) error
+	TouchToken(ctx context.Context, tokenID string) error
+	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new OAuth repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// CreateClient registers a client
+func (r *repo) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+// GetClientByClientID retrieves a client by its public client ID
+func (r *repo) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClientNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// GetClients retrieves every registered client, oldest first
+func (r *repo) GetClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.WithContext(ctx).Order("created_at ASC").Find(&clients).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*OAuthClient
+	result := make([]*models.OAuthClient, len(clients))
+	for i := range clients {
+		result[i] = &clients[i]
+	}
+	return result, nil
+}
+
+// GetClientsByClientIDs retrieves clients by their public client IDs, keyed by client ID
+func (r *repo) GetClientsByClientIDs(ctx context.Context, clientIDs []string) (map[string]*models.OAuthClient, error) {
+	result := make(map[string]*models.OAuthClient, len(clientIDs))
+	if len(clientIDs) == 0 {
+		return result, nil
+	}
+
+	var clients []models.OAuthClient
+	if err := r.db.WithContext(ctx).Where("client_id IN ?", clientIDs).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range clients {
+		result[clients[i].ClientID] = &clients[i]
+	}
+	return result, nil
+}
+
+// UpdateClientSecret replaces the secret hash of a client
+func (r *repo) UpdateClientSecret(ctx context.Context, clientID, secretHash string) error {
+	return r.db.WithContext(ctx).Model(&models.OAuthClient{}).
+		Where("client_id = ?", clientID).
+		Updates(map[string]interface{}{
+			"secret_hash": secretHash,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// DisableClient disables a client and revokes every token issued to it, returning the access token
+// hashes that were revoked
+func (r *repo) DisableClient(ctx context.Context, clientID string) ([]string, error) {
+	now := time.Now().Unix()
+	var accessHashes []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OAuthClient{}).
+			Where("client_id = ?", clientID).
+			Updates(map[string]interface{}{"state": CLIENT_STATE_DISABLED, "modified_at": now}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		accessHashes, err = revokeTokens(tx, now, "client_id = ?", clientID)
+		return err
+	})
+	return accessHashes, err
+}
+
+// GetConsent retrieves the consent a user gave a client, nil when there is none
+func (r *repo) GetConsent(ctx context.Context, userID, clientID string) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ?", userID, clientID).
+		First(&consent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// SaveConsent stores a consent, replacing the scopes of an existing one for the same user and client
+func (r *repo) SaveConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"scopes", "modified_at"}),
+		}).
+		Create(consent).Error
+}
+
+// GetConsentsByUserID retrieves the consents a user gave, newest first
+func (r *repo) GetConsentsByUserID(ctx context.Context, userID string) ([]*models.OAuthConsent, error) {
+	var consents []models.OAuthConsent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("modified_at DESC").
+		Find(&consents).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*OAuthConsent
+	result := make([]*models.OAuthConsent, len(consents))
+	for i := range consents {
+		result[i] = &consents[i]
+	}
+	return result, nil
+}
+
+// DeleteConsent removes a user's consent for a client and revokes the client's tokens for the user,
+// returning the access token hashes that were revoked
+func (r *repo) DeleteConsent(ctx context.Context, userID, clientID string) ([]string, error) {
+	now := time.Now().Unix()
+	var accessHashes []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.OAuthConsent{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConsentNotFound
+		}
+
+		var err error
+		accessHashes, err = revokeTokens(tx, now, "user_id = ? AND client_id = ?", userID, clientID)
+		return err
+	})
+	return accessHashes, err
+}
+
+// CreateToken stores a newly issued grant
+func (r *repo) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetTokenByAccessHash retrieves a grant by the hash of its access token
+func (r *repo) GetTokenByAccessHash(ctx context.Context, accessHash string) (*models.OAuthToken, error) {
+	return r.getToken(ctx, "access_token_hash = ?", accessHash)
+}
+
+// GetTokenByRefreshHash retrieves a grant by the hash of its refresh token
+func (r *repo) GetTokenByRefreshHash(ctx context.Context, refreshHash string) (*models.OAuthToken, error) {
+	return r.getToken(ctx, "refresh_token_hash = ?", refreshHash)
+}
+
+// RotateToken stores a grant's new token pair, provided its refresh token is still the one that was
+// presented. Returns ErrInvalidGrant when a concurrent refresh got there first.
+func (r *repo) RotateToken(ctx context.Context, token *models.OAuthToken, previousRefreshHash string) error {
+	result := r.db.WithContext(ctx).Model(&models.OAuthToken{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", token.ID, previousRefreshHash).
+		Updates(map[string]interface{}{
+			"access_token_hash":  token.AccessTokenHash,
+			"refresh_token_hash": token.RefreshTokenHash,
+			"access_expires_at":  token.AccessExpiresAt,
+			"refresh_expires_at": token.RefreshExpiresAt,
+			"scopes":             token.Scopes,
+			"modified_at":        time.Now().Unix(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidGrant
+	}
+	return nil
+}
+
+// RevokeToken revokes a grant, invalidating both of its tokens
+func (r *repo) RevokeToken(ctx context.Context, tokenID string) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).Model(&models.OAuthToken{}).
+		Where("id = ? AND revoked_at IS NULL", tokenID).
+		Updates(map[string]interface{}{"revoked_at": now, "modified_at": now}).Error
+}
+
+// TouchToken records when a grant's access token was last used
+func (r *repo) TouchToken(ctx context.Context, tokenID string) error {
+	return r.db.WithContext(ctx).Model(&models.OAuthToken{}).
+		Where("id = ?", tokenID).
+		Update("last_used_at", time.Now().Unix()).Error
+}
+
+// GetUserByID retrieves an active user by ID
+func (r *repo) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("id = ? AND active = ?", userID, true).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// getToken retrieves a single grant matching a condition
+func (r *repo) getToken(ctx context.Context, query string, args ...interface{}) (*models.OAuthToken, error) {
+	var token models.OAuthToken
+	err := r.db.WithContext(ctx).Where(query, args...).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// revokeTokens revokes the live grants matching a condition inside a transaction, returning their
+// access token hashes so cached identities can be dropped
+func revokeTokens(tx *gorm.DB, now int64, query string, args ...interface{}) ([]string, error) {
+	var accessHashes []string
+	if err := tx.Model(&models.OAuthToken{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Pluck("access_token_hash", &accessHashes).Error; err != nil {
+		return nil, err
+	}
+	if len(accessHashes) == 0 {
+		return nil, nil
+	}
+
+	err := tx.Model(&models.OAuthToken{}).
+		Where("access_token_hash IN ?", accessHashes).
+		Updates(map[string]interface{}{"revoked_at": now, "modified_at": now}).Error
+	return accessHashes, err
+}
@@ -0,0 +1,323 @@
+package oauth
+
+import (
+	"cirrussync-api/internal/jwt"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Client states
+const (
+	CLIENT_STATE_ACTIVE   = 1
+	CLIENT_STATE_DISABLED = 2
+)
+
+// SCOPE_OPENID asks for an ID token and access to the userinfo endpoint
+const SCOPE_OPENID = "openid"
+
+const (
+	// Prefixes identify OAuth secrets so they can be told apart from JWTs and service account tokens
+	CLIENT_ID_PREFIX     = "csoc_"
+	CLIENT_SECRET_PREFIX = "csos_"
+	ACCESS_TOKEN_PREFIX  = "csoa_"
+	REFRESH_TOKEN_PREFIX = "csor_"
+
+	// Registration limits
+	MAX_REDIRECT_URIS = 10
+
+	// How long a validated access token is cached; also bounds how often last-used is written
+	ACCESS_TOKEN_CACHE_EXPIRATION = time.Minute
+)
+
+// SupportedScopes are the scopes clients can be allowed to request. API scopes are the ones
+// session tokens carry, so handlers check them the same way for both.
+var SupportedScopes = []string{SCOPE_OPENID, jwt.ScopeUserRead, jwt.ScopeUserWrite}
+
+// NewService creates a new OAuth provider service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger, cfg *config.OAuthConfig, jwtService *jwt.JWTService) *Service {
+	return &Service{
+		repo:        repo,
+		redisClient: redisClient,
+		logger:      logger,
+		config:      cfg,
+		jwtService:  jwtService,
+	}
+}
+
+// SetSecurityEvents records granted and revoked authorizations as security events of the user
+func (s *Service) SetSecurityEvents(events *security.Service) {
+	s.securityEvents = events
+}
+
+// CreateClient registers a client. For confidential clients the plaintext secret is returned once
+// and cannot be recovered afterwards.
+func (s *Service) CreateClient(ctx context.Context, adminID string, input ClientInput) (*models.OAuthClient, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(input.RedirectURIs) == 0 || len(input.RedirectURIs) > MAX_REDIRECT_URIS || len(input.Scopes) == 0 {
+		return nil, "", ErrInvalidClientInput
+	}
+	for _, redirectURI := range input.RedirectURIs {
+		if err := validateRedirectURI(redirectURI); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(SupportedScopes, scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+
+	client := &models.OAuthClient{
+		ClientID:     CLIENT_ID_PREFIX + utils.GenerateShortID(),
+		Name:         name,
+		Description:  strings.TrimSpace(input.Description),
+		HomepageURL:  strings.TrimSpace(input.HomepageURL),
+		Confidential: input.Confidential,
+		RedirectURIs: slices.Compact(slices.Sorted(slices.Values(input.RedirectURIs))),
+		Scopes:       slices.Compact(slices.Sorted(slices.Values(input.Scopes))),
+		State:        CLIENT_STATE_ACTIVE,
+		CreatedBy:    adminID,
+	}
+
+	var secret string
+	if input.Confidential {
+		secret = CLIENT_SECRET_PREFIX + utils.GenerateID()
+		client.SecretHash = hashSecret(secret)
+	}
+
+	if err := s.repo.CreateClient(ctx, client); err != nil {
+		return nil, "", fmt.Errorf("failed to create OAuth client: %w", err)
+	}
+
+	return client, secret, nil
+}
+
+// ListClients returns every registered client without its secret
+func (s *Service) ListClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	return s.repo.GetClients(ctx)
+}
+
+// RotateClientSecret replaces the secret of a confidential client. The old secret stops working
+// immediately; tokens already issued stay valid.
+func (s *Service) RotateClientSecret(ctx context.Context, clientID string) (*models.OAuthClient, string, error) {
+	client, err := s.repo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !client.Confidential {
+		return nil, "", ErrUnauthorizedClient
+	}
+	if client.State != CLIENT_STATE_ACTIVE {
+		return nil, "", ErrClientDisabled
+	}
+
+	secret := CLIENT_SECRET_PREFIX + utils.GenerateID()
+	client.SecretHash = hashSecret(secret)
+	if err := s.repo.UpdateClientSecret(ctx, clientID, client.SecretHash); err != nil {
+		return nil, "", fmt.Errorf("failed to rotate OAuth client secret: %w", err)
+	}
+
+	return client, secret, nil
+}
+
+// DisableClient disables a client and revokes every token issued to it
+func (s *Service) DisableClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	client, err := s.repo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessHashes, err := s.repo.DisableClient(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to disable OAuth client: %w", err)
+	}
+	s.invalidateTokenCaches(ctx, accessHashes...)
+
+	client.State = CLIENT_STATE_DISABLED
+	return client, nil
+}
+
+// ListAuthorizedApps returns the clients the user granted access to
+func (s *Service) ListAuthorizedApps(ctx context.Context, userID string) ([]*AuthorizedApp, error) {
+	consents, err := s.repo.GetConsentsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientIDs := make([]string, len(consents))
+	for i, consent := range consents {
+		clientIDs[i] = consent.ClientID
+	}
+	clients, err := s.repo.GetClientsByClientIDs(ctx, clientIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]*AuthorizedApp, 0, len(consents))
+	for _, consent := range consents {
+		client, ok := clients[consent.ClientID]
+		if !ok || client.State != CLIENT_STATE_ACTIVE {
+			continue
+		}
+		apps = append(apps, &AuthorizedApp{
+			Client:    client,
+			Scopes:    consent.Scopes,
+			GrantedAt: consent.ModifiedAt,
+		})
+	}
+
+	return apps, nil
+}
+
+// RevokeAuthorization withdraws the user's consent for a client and revokes the client's tokens for
+// the user. The client has to ask for consent again.
+func (s *Service) RevokeAuthorization(ctx context.Context, userID, clientID, ipAddress string) error {
+	accessHashes, err := s.repo.DeleteConsent(ctx, userID, clientID)
+	if err != nil {
+		if errors.Is(err, ErrConsentNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to revoke OAuth authorization: %w", err)
+	}
+	s.invalidateTokenCaches(ctx, accessHashes...)
+
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_OAUTH_REVOKED,
+		Success:   true,
+		IPAddress: ipAddress,
+		Metadata:  map[string]any{"clientId": clientID},
+	})
+
+	return nil
+}
+
+// GetProviderMetadata returns the OpenID Connect discovery document
+func (s *Service) GetProviderMetadata() ProviderMetadata {
+	issuer := strings.TrimSuffix(s.config.Issuer, "/")
+	return ProviderMetadata{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             s.config.AuthorizeURL,
+		TokenEndpoint:                     issuer + "/api/v1/oauth/token",
+		IntrospectionEndpoint:             issuer + "/api/v1/oauth/introspect",
+		RevocationEndpoint:                issuer + "/api/v1/oauth/revoke",
+		UserInfoEndpoint:                  issuer + "/api/v1/oauth/userinfo",
+		JWKSURI:                           issuer + "/api/v1/oauth/jwks",
+		ScopesSupported:                   SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GRANT_TYPE_AUTHORIZATION_CODE, GRANT_TYPE_REFRESH_TOKEN},
+		CodeChallengeMethodsSupported:     []string{CODE_CHALLENGE_METHOD_S256},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"EdDSA"},
+	}
+}
+
+// GetJWKS returns the keys ID tokens are signed with
+func (s *Service) GetJWKS() []jwt.JWK {
+	return []jwt.JWK{s.jwtService.PublicJWK()}
+}
+
+// getActiveClient loads a client that can take part in authorizations
+func (s *Service) getActiveClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, ErrClientNotFound
+	}
+
+	client, err := s.repo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.State != CLIENT_STATE_ACTIVE {
+		return nil, ErrClientDisabled
+	}
+	return client, nil
+}
+
+// authenticateClient checks the credentials a client presented to the token, introspection or
+// revocation endpoint. Public clients present their client ID only.
+func (s *Service) authenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+	client, err := s.getActiveClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, ErrClientNotFound) || errors.Is(err, ErrClientDisabled) {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if client.Confidential {
+		if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hashSecret(clientSecret)), []byte(client.SecretHash)) != 1 {
+			return nil, ErrInvalidClient
+		}
+	} else if clientSecret != "" {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// invalidateTokenCaches drops cached token identities so revocation takes effect immediately
+func (s *Service) invalidateTokenCaches(ctx context.Context, accessHashes ...string) {
+	if len(accessHashes) == 0 {
+		return
+	}
+
+	keys := make([]string, len(accessHashes))
+	for i, accessHash := range accessHashes {
+		keys[i] = tokenCacheKey(accessHash)
+	}
+	if _, err := s.redisClient.DeleteMany(ctx, keys...); err != nil {
+		s.logger.Errorf("Failed to delete OAuth token caches: %v", err)
+	}
+}
+
+// parseScopes splits a space-separated scope parameter, dropping duplicates
+func parseScopes(scope string) []string {
+	return slices.Compact(slices.Sorted(slices.Values(strings.Fields(scope))))
+}
+
+// validateRedirectURI checks a redirect URI can be registered. Plain http is only allowed for
+// loopback addresses, which native apps listen on.
+func validateRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || parsed.Host == "" || parsed.Fragment != "" || len(redirectURI) > 2048 {
+		return ErrInvalidRedirectURI
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := parsed.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return ErrInvalidRedirectURI
+}
+
+// tokenCacheKey is the cache key of a validated access token
+func tokenCacheKey(accessHash string) string {
+	return fmt.Sprintf("oauth_token:%s", accessHash)
+}
+
+// hashSecret returns the hex SHA-256 of a client secret, code or token
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
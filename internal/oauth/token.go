@@ -0,0 +1,370 @@
+package oauth
+
+import (
+	"cirrussync-api/internal/jwt"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Grant types accepted by the token endpoint
+const (
+	GRANT_TYPE_AUTHORIZATION_CODE = "authorization_code"
+	GRANT_TYPE_REFRESH_TOKEN      = "refresh_token"
+)
+
+// Token type hints and introspection token types
+const (
+	TOKEN_TYPE_ACCESS  = "access_token"
+	TOKEN_TYPE_REFRESH = "refresh_token"
+)
+
+// Exchange issues tokens for an authorization code or a refresh token. Refresh tokens are rotated:
+// each one can be used once, and the response carries its replacement.
+func (s *Service) Exchange(ctx context.Context, request TokenRequest) (*TokenSet, error) {
+	client, err := s.authenticateClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.GrantType {
+	case GRANT_TYPE_AUTHORIZATION_CODE:
+		return s.exchangeCode(ctx, client, request)
+	case GRANT_TYPE_REFRESH_TOKEN:
+		return s.refresh(ctx, client, request)
+	default:
+		return nil, ErrUnsupportedGrantType
+	}
+}
+
+// exchangeCode redeems a one-time authorization code after checking the PKCE verifier
+func (s *Service) exchangeCode(ctx context.Context, client *models.OAuthClient, request TokenRequest) (*TokenSet, error) {
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	cacheKey := codeCacheKey(request.Code)
+	var code authorizationCode
+	if err := s.redisClient.GetJSON(ctx, cacheKey, &code); err != nil {
+		return nil, ErrInvalidGrant
+	}
+
+	// Only the request that deletes the code may redeem it
+	deleted, err := s.redisClient.Delete(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrInvalidGrant
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != request.RedirectURI {
+		return nil, ErrInvalidGrant
+	}
+	if !verifyCodeChallenge(request.CodeVerifier, code.CodeChallenge) {
+		return nil, ErrInvalidGrant
+	}
+
+	accessToken, refreshToken := ACCESS_TOKEN_PREFIX+utils.GenerateID(), REFRESH_TOKEN_PREFIX+utils.GenerateID()
+	now := time.Now()
+	token := &models.OAuthToken{
+		ClientID:         client.ClientID,
+		UserID:           code.UserID,
+		Scopes:           code.Scopes,
+		AccessTokenHash:  hashSecret(accessToken),
+		RefreshTokenHash: hashSecret(refreshToken),
+		AccessExpiresAt:  now.Add(s.config.AccessTokenExpiry).Unix(),
+		RefreshExpiresAt: now.Add(s.config.RefreshTokenExpiry).Unix(),
+	}
+	if err := s.repo.CreateToken(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to store OAuth token: %w", err)
+	}
+
+	tokens := &TokenSet{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.AccessTokenExpiry.Seconds()),
+		Scopes:       token.Scopes,
+	}
+
+	if slices.Contains(code.Scopes, SCOPE_OPENID) {
+		idToken, err := s.generateIDToken(ctx, client.ClientID, code.UserID, code.Nonce, code.AuthTime)
+		if err != nil {
+			return nil, err
+		}
+		tokens.IDToken = idToken
+	}
+
+	return tokens, nil
+}
+
+// refresh replaces a grant's token pair. A narrower scope may be requested; it then applies to every
+// later refresh as well.
+func (s *Service) refresh(ctx context.Context, client *models.OAuthClient, request TokenRequest) (*TokenSet, error) {
+	if request.RefreshToken == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	previousRefreshHash := hashSecret(request.RefreshToken)
+	token, err := s.repo.GetTokenByRefreshHash(ctx, previousRefreshHash)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if token.ClientID != client.ClientID || token.RevokedAt != nil || token.RefreshExpiresAt <= now.Unix() {
+		return nil, ErrInvalidGrant
+	}
+
+	// The user may have withdrawn consent since the grant was issued
+	consent, err := s.repo.GetConsent(ctx, token.UserID, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if consent == nil {
+		return nil, ErrInvalidGrant
+	}
+
+	scopes := token.Scopes
+	if request.Scope != "" {
+		scopes = parseScopes(request.Scope)
+		if !isSubset(scopes, token.Scopes) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	previousAccessHash := token.AccessTokenHash
+	accessToken, refreshToken := ACCESS_TOKEN_PREFIX+utils.GenerateID(), REFRESH_TOKEN_PREFIX+utils.GenerateID()
+	token.Scopes = scopes
+	token.AccessTokenHash = hashSecret(accessToken)
+	token.RefreshTokenHash = hashSecret(refreshToken)
+	token.AccessExpiresAt = now.Add(s.config.AccessTokenExpiry).Unix()
+	token.RefreshExpiresAt = now.Add(s.config.RefreshTokenExpiry).Unix()
+
+	if err := s.repo.RotateToken(ctx, token, previousRefreshHash); err != nil {
+		return nil, err
+	}
+	s.invalidateTokenCaches(ctx, previousAccessHash)
+
+	return &TokenSet{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.AccessTokenExpiry.Seconds()),
+		Scopes:       scopes,
+	}, nil
+}
+
+// Introspect describes a token to the client it was issued to. Tokens that are invalid or belong to
+// another client are reported as inactive, as RFC 7662 requires.
+func (s *Service) Introspect(ctx context.Context, credentials ClientCredentials, tokenValue, tokenTypeHint string) (*Introspection, error) {
+	client, err := s.authenticateClient(ctx, credentials.ClientID, credentials.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenType, err := s.findToken(ctx, tokenValue, tokenTypeHint)
+	if err != nil {
+		if errors.Is(err, ErrInvalidGrant) {
+			return &Introspection{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	expiresAt := token.AccessExpiresAt
+	if tokenType == TOKEN_TYPE_REFRESH {
+		expiresAt = token.RefreshExpiresAt
+	}
+	if token.ClientID != client.ClientID || token.RevokedAt != nil || expiresAt <= time.Now().Unix() {
+		return &Introspection{Active: false}, nil
+	}
+
+	user, err := s.repo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAccessToken) {
+			return &Introspection{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	return &Introspection{
+		Active:    true,
+		Scopes:    token.Scopes,
+		ClientID:  token.ClientID,
+		UserID:    token.UserID,
+		Username:  user.Username,
+		TokenType: tokenType,
+		ExpiresAt: expiresAt,
+		IssuedAt:  token.ModifiedAt,
+	}, nil
+}
+
+// Revoke revokes the grant an access or refresh token belongs to. Unknown tokens and tokens of other
+// clients are ignored, as RFC 7009 requires.
+func (s *Service) Revoke(ctx context.Context, credentials ClientCredentials, tokenValue, tokenTypeHint string) error {
+	client, err := s.authenticateClient(ctx, credentials.ClientID, credentials.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := s.findToken(ctx, tokenValue, tokenTypeHint)
+	if err != nil {
+		if errors.Is(err, ErrInvalidGrant) {
+			return nil
+		}
+		return err
+	}
+	if token.ClientID != client.ClientID || token.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.repo.RevokeToken(ctx, token.ID); err != nil {
+		return fmt.Errorf("failed to revoke OAuth token: %w", err)
+	}
+	s.invalidateTokenCaches(ctx, token.AccessTokenHash)
+
+	return nil
+}
+
+// ValidateAccessToken resolves an OAuth access token to the user and scopes it was issued for.
+// Usage is recorded at most once per cache period.
+func (s *Service) ValidateAccessToken(ctx context.Context, accessToken string) (*TokenIdentity, error) {
+	if !strings.HasPrefix(accessToken, ACCESS_TOKEN_PREFIX) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	accessHash := hashSecret(accessToken)
+	cacheKey := tokenCacheKey(accessHash)
+
+	// Check cache first
+	var identity TokenIdentity
+	if err := s.redisClient.GetJSON(ctx, cacheKey, &identity); err == nil {
+		if identity.ExpiresAt <= time.Now().Unix() {
+			return nil, ErrInvalidAccessToken
+		}
+		return &identity, nil
+	}
+
+	token, err := s.repo.GetTokenByAccessHash(ctx, accessHash)
+	if err != nil {
+		if errors.Is(err, ErrInvalidGrant) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil || token.AccessExpiresAt <= time.Now().Unix() {
+		return nil, ErrInvalidAccessToken
+	}
+
+	if _, err := s.getActiveClient(ctx, token.ClientID); err != nil {
+		if errors.Is(err, ErrClientNotFound) || errors.Is(err, ErrClientDisabled) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+	if _, err := s.repo.GetUserByID(ctx, token.UserID); err != nil {
+		return nil, err
+	}
+
+	identity = TokenIdentity{
+		TokenID:   token.ID,
+		UserID:    token.UserID,
+		ClientID:  token.ClientID,
+		Scopes:    token.Scopes,
+		ExpiresAt: token.AccessExpiresAt,
+	}
+	_ = s.redisClient.SetJSON(ctx, cacheKey, identity, ACCESS_TOKEN_CACHE_EXPIRATION)
+
+	// Record usage without delaying the request
+	go func(tokenID string) {
+		opCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := s.repo.TouchToken(opCtx, tokenID); err != nil {
+			s.logger.Errorf("Failed to record use of OAuth token %s: %v", tokenID, err)
+		}
+	}(token.ID)
+
+	return &identity, nil
+}
+
+// GetUserInfo returns the OpenID Connect claims of the user an access token was issued for
+func (s *Service) GetUserInfo(ctx context.Context, identity *TokenIdentity) (*UserInfo, error) {
+	if !slices.Contains(identity.Scopes, SCOPE_OPENID) {
+		return nil, ErrInvalidScope
+	}
+
+	user, err := s.repo.GetUserByID(ctx, identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserInfo{
+		Subject:           user.ID,
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerified,
+		PreferredUsername: user.Username,
+	}, nil
+}
+
+// findToken looks a token up as the type hinted at first, then as the other type
+func (s *Service) findToken(ctx context.Context, tokenValue, tokenTypeHint string) (*models.OAuthToken, string, error) {
+	tokenHash := hashSecret(tokenValue)
+	lookups := []string{TOKEN_TYPE_ACCESS, TOKEN_TYPE_REFRESH}
+	if tokenTypeHint == TOKEN_TYPE_REFRESH || strings.HasPrefix(tokenValue, REFRESH_TOKEN_PREFIX) {
+		slices.Reverse(lookups)
+	}
+
+	for _, tokenType := range lookups {
+		var token *models.OAuthToken
+		var err error
+		if tokenType == TOKEN_TYPE_ACCESS {
+			token, err = s.repo.GetTokenByAccessHash(ctx, tokenHash)
+		} else {
+			token, err = s.repo.GetTokenByRefreshHash(ctx, tokenHash)
+		}
+		if err == nil {
+			return token, tokenType, nil
+		}
+		if !errors.Is(err, ErrInvalidGrant) {
+			return nil, "", err
+		}
+	}
+
+	return nil, "", ErrInvalidGrant
+}
+
+// generateIDToken signs an ID token for the user the code was issued to
+func (s *Service) generateIDToken(ctx context.Context, clientID, userID, nonce string, authTime int64) (string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAccessToken) {
+			return "", ErrInvalidGrant
+		}
+		return "", err
+	}
+
+	return s.jwtService.GenerateIDToken(s.config.Issuer, clientID, userID, jwt.IDTokenClaims{
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerified,
+		PreferredUsername: user.Username,
+		Nonce:             nonce,
+		AuthTime:          authTime,
+	}, s.config.IDTokenExpiry)
+}
+
+// verifyCodeChallenge checks a PKCE verifier against the S256 challenge sent with the authorization
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
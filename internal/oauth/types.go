@@ -0,0 +1,142 @@
+package oauth
+
+import (
+	"cirrussync-api/internal/jwt"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+)
+
+// Service is the OAuth 2.0 / OpenID Connect provider: it registers clients, records consent and
+// issues, validates and revokes client tokens
+type Service struct {
+	repo           Repository
+	redisClient    *redis.Client
+	logger         *logger.Logger
+	config         *config.OAuthConfig
+	jwtService     *jwt.JWTService
+	securityEvents *security.Service
+}
+
+// ClientInput describes a client registered by an admin
+type ClientInput struct {
+	Name         string
+	Description  string
+	HomepageURL  string
+	RedirectURIs []string
+	Scopes       []string
+	Confidential bool
+}
+
+// AuthorizationRequest is an authorization request forwarded by the consent screen
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scope               string // Space-separated, as sent by the client
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// AuthorizationPrompt is what the consent screen shows the user
+type AuthorizationPrompt struct {
+	Client         *models.OAuthClient
+	Scopes         []string
+	ConsentGranted bool // The user already agreed to every requested scope
+}
+
+// authorizationCode is what an authorization code stands for until it is exchanged
+type authorizationCode struct {
+	ClientID      string   `json:"clientId"`
+	UserID        string   `json:"userId"`
+	RedirectURI   string   `json:"redirectUri"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"codeChallenge"`
+	Nonce         string   `json:"nonce,omitempty"`
+	AuthTime      int64    `json:"authTime"`
+}
+
+// TokenRequest is a request to the token endpoint. The client secret comes from HTTP Basic
+// authentication or the form.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+}
+
+// TokenSet is what the token endpoint issues
+type TokenSet struct {
+	AccessToken  string
+	RefreshToken string
+	IDToken      string // Only when the openid scope was granted
+	ExpiresIn    int64
+	Scopes       []string
+}
+
+// ClientCredentials identify the client calling the introspection and revocation endpoints
+type ClientCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// Introspection describes a token to the client it was issued to, following RFC 7662
+type Introspection struct {
+	Active    bool
+	Scopes    []string
+	ClientID  string
+	UserID    string
+	Username  string
+	TokenType string
+	ExpiresAt int64
+	IssuedAt  int64
+}
+
+// TokenIdentity is what an OAuth access token authenticates as
+type TokenIdentity struct {
+	TokenID   string
+	UserID    string
+	ClientID  string
+	Scopes    []string
+	ExpiresAt int64
+}
+
+// UserInfo are the OpenID Connect claims about the user a token was issued for
+type UserInfo struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+// ProviderMetadata is the OpenID Connect discovery document
+type ProviderMetadata struct {
+	Issuer                            string
+	AuthorizationEndpoint             string
+	TokenEndpoint                     string
+	IntrospectionEndpoint             string
+	RevocationEndpoint                string
+	UserInfoEndpoint                  string
+	JWKSURI                           string
+	ScopesSupported                   []string
+	ResponseTypesSupported            []string
+	GrantTypesSupported               []string
+	CodeChallengeMethodsSupported     []string
+	TokenEndpointAuthMethodsSupported []string
+	SubjectTypesSupported             []string
+	IDTokenSigningAlgValuesSupported  []string
+}
+
+// AuthorizedApp is a client the user granted access to
+type AuthorizedApp struct {
+	Client    *models.OAuthClient
+	Scopes    []string
+	GrantedAt int64
+}
@@ -0,0 +1,185 @@
+package org
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/s3"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Encryption key states
+const (
+	ENCRYPTION_KEY_STATE_ACTIVE  = 1
+	ENCRYPTION_KEY_STATE_RETIRED = 2
+)
+
+const (
+	// encryptionKeyCachePrefix caches the key used for objects of an organization owner
+	encryptionKeyCachePrefix = "org:encryption_key:owner:"
+
+	// ENCRYPTION_KEY_CACHE_EXPIRATION bounds how long a replaced or disabled key keeps being used
+	// by other instances for new uploads
+	ENCRYPTION_KEY_CACHE_EXPIRATION = 5 * time.Minute
+
+	// noEncryptionKey is cached for owners without a customer-managed key
+	noEncryptionKey = "-"
+)
+
+// encryptionKeyPlanTypes are the plans allowed to bring their own encryption key
+var encryptionKeyPlanTypes = []string{"business", "enterprise"}
+
+// kmsKeyARNPattern matches KMS key and alias ARNs
+var kmsKeyARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:\d{12}:(key/[a-zA-Z0-9-]{1,128}|alias/[a-zA-Z0-9/_-]{1,250})$`)
+
+// SetEncryptionKey makes a KMS key the organization's customer-managed encryption key. New thumbnails
+// stored by the organization owner are encrypted with it server-side. Setting a different key rotates:
+// objects written before keep using the key they were written with, so a replaced key must stay
+// usable until those objects are gone. Setting the active key again re-verifies it.
+func (s *Service) SetEncryptionKey(ctx context.Context, adminID, orgID, keyARN string) (*models.OrganizationEncryptionKey, error) {
+	if !kmsKeyARNPattern.MatchString(keyARN) {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return nil, err
+	}
+
+	organization, err := s.repo.GetOrganizationByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects are stored under the owner's plan, so the owner's plan decides
+	planTypes, err := s.repo.GetActivePlanTypes(ctx, organization.OwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plans: %w", err)
+	}
+	if !slices.ContainsFunc(planTypes, func(planType string) bool {
+		return slices.Contains(encryptionKeyPlanTypes, planType)
+	}) {
+		return nil, ErrEncryptionKeyNotAllowed
+	}
+
+	if err := s.verifyEncryptionKey(ctx, keyARN); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+
+	current, err := s.repo.GetActiveEncryptionKey(ctx, orgID)
+	if err != nil && !errors.Is(err, ErrEncryptionKeyNotFound) {
+		return nil, err
+	}
+	if current != nil && current.KeyARN == keyARN {
+		if err := s.repo.TouchEncryptionKey(ctx, current.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to update encryption key: %w", err)
+		}
+		current.VerifiedAt = now
+		return current, nil
+	}
+
+	key := &models.OrganizationEncryptionKey{
+		OrganizationID: orgID,
+		KeyARN:         keyARN,
+		State:          ENCRYPTION_KEY_STATE_ACTIVE,
+		CreatedBy:      adminID,
+		VerifiedAt:     now,
+	}
+	if err := s.repo.ActivateEncryptionKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to store encryption key: %w", err)
+	}
+
+	s.invalidateEncryptionKeyCache(ctx, organization.OwnerID)
+	s.logger.Infof("Organization %s switched to encryption key %s", orgID, key.ID)
+
+	return key, nil
+}
+
+// ListEncryptionKeys returns the organization's active key and the keys it replaced
+func (s *Service) ListEncryptionKeys(ctx context.Context, adminID, orgID string) ([]*models.OrganizationEncryptionKey, error) {
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetEncryptionKeys(ctx, orgID)
+}
+
+// DisableEncryptionKey stops using the organization's key for new objects, which fall back to the
+// bucket's default encryption. Objects already written stay encrypted with the key.
+func (s *Service) DisableEncryptionKey(ctx context.Context, adminID, orgID string) error {
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return err
+	}
+
+	organization, err := s.repo.GetOrganizationByID(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	retired, err := s.repo.RetireEncryptionKey(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to retire encryption key: %w", err)
+	}
+	if !retired {
+		return ErrEncryptionKeyNotFound
+	}
+
+	s.invalidateEncryptionKeyCache(ctx, organization.OwnerID)
+
+	return nil
+}
+
+// StorageEncryptionKey returns the KMS key new objects of the user must be encrypted with, or an empty
+// string when the user owns no organization with a customer-managed key. It lets the drive service
+// encrypt uploads without depending on organizations.
+func (s *Service) StorageEncryptionKey(ctx context.Context, userID string) (string, error) {
+	cacheKey := encryptionKeyCachePrefix + userID
+	if cached, err := s.redisClient.Get(ctx, cacheKey); err == nil && cached != "" {
+		if cached == noEncryptionKey {
+			return "", nil
+		}
+		return cached, nil
+	}
+
+	keyARN, err := s.repo.GetOwnerEncryptionKeyARN(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	cached := keyARN
+	if cached == "" {
+		cached = noEncryptionKey
+	}
+	if err := s.redisClient.Set(ctx, cacheKey, cached, ENCRYPTION_KEY_CACHE_EXPIRATION); err != nil {
+		s.logger.Errorf("Failed to cache encryption key of user %s: %v", userID, err)
+	}
+
+	return keyARN, nil
+}
+
+// verifyEncryptionKey checks that storage can encrypt objects with a KMS key
+func (s *Service) verifyEncryptionKey(ctx context.Context, keyARN string) error {
+	if s.storage == nil {
+		return ErrEncryptionKeyUnavailable
+	}
+
+	if err := s.storage.VerifyEncryptionKey(ctx, keyARN); err != nil {
+		if errors.Is(err, s3.ErrEncryptionKeyUnusable) {
+			return ErrEncryptionKeyUnusable
+		}
+		s.logger.Errorf("Failed to verify encryption key: %v", err)
+		return ErrEncryptionKeyVerification
+	}
+
+	return nil
+}
+
+// invalidateEncryptionKeyCache drops the cached key of an organization owner so the change applies to the next upload
+func (s *Service) invalidateEncryptionKeyCache(ctx context.Context, ownerID string) {
+	if _, err := s.redisClient.Delete(ctx, encryptionKeyCachePrefix+ownerID); err != nil {
+		s.logger.Errorf("Failed to delete encryption key cache: %v", err)
+	}
+}
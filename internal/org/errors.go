@@ -0,0 +1,39 @@
+package org
+
+import "errors"
+
+// Organization errors
+var (
+	ErrOrganizationNotFound = errors.New("Organization not found")
+	ErrOrganizationCreation = errors.New("Failed to create organization")
+	ErrNotOrganizationAdmin = errors.New("Only organization admins can perform this action")
+	ErrMemberAlreadyExists  = errors.New("User is already a member of this organization")
+	ErrUserNotFound         = errors.New("User not found")
+	ErrInvalidRole          = errors.New("Invalid organization role")
+)
+
+// Service account errors
+var (
+	ErrServiceAccountNotFound = errors.New("Service account not found")
+	ErrServiceAccountCreation = errors.New("Failed to create service account")
+	ErrServiceAccountDisabled = errors.New("Service account is disabled")
+)
+
+// Access token errors
+var (
+	ErrAccessTokenNotFound = errors.New("Access token not found")
+	ErrInvalidAccessToken  = errors.New("Access token is invalid, expired or revoked")
+	ErrInvalidScopes       = errors.New("Access token scopes must be a non-empty subset of the allowed scopes")
+	ErrInvalidExpiration   = errors.New("Access token lifetime must be between 1 and 365 days")
+	ErrTooManyAccessTokens = errors.New("Service account has reached the maximum number of access tokens")
+)
+
+// Encryption key errors
+var (
+	ErrEncryptionKeyNotFound     = errors.New("Organization has no customer-managed encryption key")
+	ErrInvalidEncryptionKey      = errors.New("Encryption key must be a KMS key ARN")
+	ErrEncryptionKeyUnusable     = errors.New("KMS key is disabled, does not exist or has not granted CirrusSync access")
+	ErrEncryptionKeyNotAllowed   = errors.New("Customer-managed encryption keys require an active business plan")
+	ErrEncryptionKeyUnavailable  = errors.New("Customer-managed encryption keys are not available on this server")
+	ErrEncryptionKeyVerification = errors.New("Encryption key could not be verified, please try again")
+)
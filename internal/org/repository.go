@@ -0,0 +1,366 @@
+package org
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/db"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository interface for organization operations
+type Repository interface {
+	// Organization methods
+	CreateOrganization(ctx context.Context, organization *models.Organization, owner *models.OrganizationMember) error
+	GetOrganizationByID(ctx context.Context, orgID string) (*models.Organization, error)
+	GetMember(ctx context.Context, orgID, userID string) (*models.OrganizationMember, error)
+	CreateMember(ctx context.Context, member *models.OrganizationMember) error
+	UserExists(ctx context.Context, userID string) (bool, error)
+
+	// Service account methods
+	CreateServiceAccount(ctx context.Context, user *models.User, key *models.UserKey, account *models.ServiceAccount) error
+	GetServiceAccountByID(ctx context.Context, accountID string) (*models.ServiceAccount, error)
+	GetServiceAccountByUserID(ctx context.Context, userID string) (*models.ServiceAccount, error)
+	GetServiceAccountsByOrgID(ctx context.Context, orgID string) ([]*models.ServiceAccount, error)
+	DisableServiceAccount(ctx context.Context, account *models.ServiceAccount) error
+
+	// Access token methods
+	CreateAccessToken(ctx context.Context, token *models.AccessToken) error
+	GetAccessTokenByHash(ctx context.Context, tokenHash string) (*models.AccessToken, error)
+	GetAccessTokensByUserID(ctx context.Context, userID string) ([]*models.AccessToken, error)
+	CountActiveAccessTokens(ctx context.Context, userID string) (int64, error)
+	RevokeAccessToken(ctx context.Context, tokenID string) error
+	TouchAccessToken(ctx context.Context, tokenID, ipAddress string) error
+
+	// Encryption key methods
+	GetActiveEncryptionKey(ctx context.Context, orgID string) (*models.OrganizationEncryptionKey, error)
+	GetEncryptionKeys(ctx context.Context, orgID string) ([]*models.OrganizationEncryptionKey, error)
+	ActivateEncryptionKey(ctx context.Context, key *models.OrganizationEncryptionKey) error
+	TouchEncryptionKey(ctx context.Context, keyID string, verifiedAt int64) error
+	RetireEncryptionKey(ctx context.Context, orgID string) (bool, error)
+	GetOwnerEncryptionKeyARN(ctx context.Context, ownerID string) (string, error)
+	GetActivePlanTypes(ctx context.Context, userID string) ([]string, error)
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db              *gorm.DB
+	orgRepo         db.Repository[models.Organization]
+	memberRepo      db.Repository[models.OrganizationMember]
+	accountRepo     db.Repository[models.ServiceAccount]
+	accessTokenRepo db.Repository[models.AccessToken]
+}
+
+// NewRepository creates a new organization repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db:              database,
+		orgRepo:         db.NewRepositoryWithDB[models.Organization](database),
+		memberRepo:      db.NewRepositoryWithDB[models.OrganizationMember](database),
+		accountRepo:     db.NewRepositoryWithDB[models.ServiceAccount](database),
+		accessTokenRepo: db.NewRepositoryWithDB[models.AccessToken](database),
+	}
+}
+
+// CreateOrganization creates an organization together with its owner's admin membership
+func (r *repo) CreateOrganization(ctx context.Context, organization *models.Organization, owner *models.OrganizationMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+
+		owner.OrganizationID = organization.ID
+		return tx.Create(owner).Error
+	})
+}
+
+// GetOrganizationByID retrieves an organization by ID
+func (r *repo) GetOrganizationByID(ctx context.Context, orgID string) (*models.Organization, error) {
+	var organization models.Organization
+	err := r.db.WithContext(ctx).Where("id = ?", orgID).First(&organization).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// GetMember retrieves a user's membership in an organization
+func (r *repo) GetMember(ctx context.Context, orgID, userID string) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// CreateMember adds a user to an organization
+func (r *repo) CreateMember(ctx context.Context, member *models.OrganizationMember) error {
+	return r.memberRepo.Create(ctx, member)
+}
+
+// UserExists checks whether an active user exists
+func (r *repo) UserExists(ctx context.Context, userID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND active = ?", userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateServiceAccount creates the backing user, its primary key and the service account in one transaction
+func (r *repo) CreateServiceAccount(ctx context.Context, user *models.User, key *models.UserKey, account *models.ServiceAccount) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		key.UserID = user.ID
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+
+		account.UserID = user.ID
+		return tx.Create(account).Error
+	})
+}
+
+// GetServiceAccountByID retrieves a service account by ID
+func (r *repo) GetServiceAccountByID(ctx context.Context, accountID string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := r.db.WithContext(ctx).Where("id = ?", accountID).First(&account).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetServiceAccountByUserID retrieves the service account backed by a user
+func (r *repo) GetServiceAccountByUserID(ctx context.Context, userID string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&account).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetServiceAccountsByOrgID retrieves every service account of an organization
+func (r *repo) GetServiceAccountsByOrgID(ctx context.Context, orgID string) ([]*models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ?", orgID).
+		Order("created_at ASC").
+		Find(&accounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*ServiceAccount
+	result := make([]*models.ServiceAccount, len(accounts))
+	for i := range accounts {
+		result[i] = &accounts[i]
+	}
+	return result, nil
+}
+
+// DisableServiceAccount disables a service account, deactivates its user and revokes all of its tokens
+func (r *repo) DisableServiceAccount(ctx context.Context, account *models.ServiceAccount) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ServiceAccount{}).
+			Where("id = ?", account.ID).
+			Updates(map[string]interface{}{"state": account.State, "modified_at": now}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.User{}).
+			Where("id = ?", account.UserID).
+			Updates(map[string]interface{}{"active": false, "modified_at": now}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.AccessToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", account.UserID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// CreateAccessToken stores a new access token
+func (r *repo) CreateAccessToken(ctx context.Context, token *models.AccessToken) error {
+	return r.accessTokenRepo.Create(ctx, token)
+}
+
+// GetAccessTokenByHash retrieves an access token by the hash of its secret
+func (r *repo) GetAccessTokenByHash(ctx context.Context, tokenHash string) (*models.AccessToken, error) {
+	var token models.AccessToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccessTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetAccessTokensByUserID retrieves every access token of a user, newest first
+func (r *repo) GetAccessTokensByUserID(ctx context.Context, userID string) ([]*models.AccessToken, error) {
+	var tokens []models.AccessToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to []*AccessToken
+	result := make([]*models.AccessToken, len(tokens))
+	for i := range tokens {
+		result[i] = &tokens[i]
+	}
+	return result, nil
+}
+
+// CountActiveAccessTokens counts a user's tokens that are neither revoked nor expired
+func (r *repo) CountActiveAccessTokens(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.AccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now().Unix()).
+		Count(&count).Error
+	return count, err
+}
+
+// RevokeAccessToken marks an access token as revoked
+func (r *repo) RevokeAccessToken(ctx context.Context, tokenID string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.AccessToken{}).
+		Where("id = ? AND revoked_at IS NULL", tokenID).
+		Update("revoked_at", time.Now().Unix()).Error
+}
+
+// TouchAccessToken records when and from where an access token was last used
+func (r *repo) TouchAccessToken(ctx context.Context, tokenID, ipAddress string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.AccessToken{}).
+		Where("id = ?", tokenID).
+		Updates(map[string]interface{}{
+			"last_used_at": time.Now().Unix(),
+			"last_used_ip": ipAddress,
+		}).Error
+}
+
+// GetActiveEncryptionKey retrieves the encryption key an organization currently uses
+func (r *repo) GetActiveEncryptionKey(ctx context.Context, orgID string) (*models.OrganizationEncryptionKey, error) {
+	var key models.OrganizationEncryptionKey
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND state = ?", orgID, ENCRYPTION_KEY_STATE_ACTIVE).
+		First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEncryptionKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetEncryptionKeys retrieves every key an organization has used, newest first
+func (r *repo) GetEncryptionKeys(ctx context.Context, orgID string) ([]*models.OrganizationEncryptionKey, error) {
+	var keys []*models.OrganizationEncryptionKey
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ?", orgID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+// ActivateEncryptionKey stores a key as the organization's active key and retires the one it replaces
+func (r *repo) ActivateEncryptionKey(ctx context.Context, key *models.OrganizationEncryptionKey) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OrganizationEncryptionKey{}).
+			Where("organization_id = ? AND state = ?", key.OrganizationID, ENCRYPTION_KEY_STATE_ACTIVE).
+			Updates(map[string]interface{}{
+				"state":       ENCRYPTION_KEY_STATE_RETIRED,
+				"retired_at":  now,
+				"modified_at": now,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(key).Error
+	})
+}
+
+// TouchEncryptionKey records that a key was verified again
+func (r *repo) TouchEncryptionKey(ctx context.Context, keyID string, verifiedAt int64) error {
+	return r.db.WithContext(ctx).Model(&models.OrganizationEncryptionKey{}).
+		Where("id = ?", keyID).
+		Updates(map[string]interface{}{
+			"verified_at": verifiedAt,
+			"modified_at": verifiedAt,
+		}).Error
+}
+
+// RetireEncryptionKey retires an organization's active key, reporting whether there was one
+func (r *repo) RetireEncryptionKey(ctx context.Context, orgID string) (bool, error) {
+	now := time.Now().Unix()
+	result := r.db.WithContext(ctx).Model(&models.OrganizationEncryptionKey{}).
+		Where("organization_id = ? AND state = ?", orgID, ENCRYPTION_KEY_STATE_ACTIVE).
+		Updates(map[string]interface{}{
+			"state":       ENCRYPTION_KEY_STATE_RETIRED,
+			"retired_at":  now,
+			"modified_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetOwnerEncryptionKeyARN retrieves the active key of the newest organization the user owns that has one,
+// or an empty string when none does
+func (r *repo) GetOwnerEncryptionKeyARN(ctx context.Context, ownerID string) (string, error) {
+	var arns []string
+	err := r.db.WithContext(ctx).
+		Model(&models.OrganizationEncryptionKey{}).
+		Joins("JOIN organizations ON organizations.id = organization_encryption_keys.organization_id").
+		Where("organizations.owner_id = ? AND organization_encryption_keys.state = ?", ownerID, ENCRYPTION_KEY_STATE_ACTIVE).
+		Order("organizations.created_at DESC").
+		Limit(1).
+		Pluck("organization_encryption_keys.key_arn", &arns).Error
+	if err != nil || len(arns) == 0 {
+		return "", err
+	}
+	return arns[0], nil
+}
+
+// GetActivePlanTypes retrieves the plan types of a user's active plans
+func (r *repo) GetActivePlanTypes(ctx context.Context, userID string) ([]string, error) {
+	var planTypes []string
+	err := r.db.WithContext(ctx).
+		Model(&models.UserPlan{}).
+		Where("user_id = ? AND status = ?", userID, "active").
+		Pluck("plan_type", &planTypes).Error
+	return planTypes, err
+}
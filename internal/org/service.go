@@ -0,0 +1,490 @@
+package org
+
+import (
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/jwt"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/s3"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Organization roles
+const (
+	ROLE_MEMBER = 1
+	ROLE_ADMIN  = 2
+)
+
+// Service account states
+const (
+	SERVICE_ACCOUNT_STATE_ACTIVE   = 1
+	SERVICE_ACCOUNT_STATE_DISABLED = 2
+)
+
+const (
+	// ROLE_SERVICE_ACCOUNT marks users that back a service account and may not log in interactively
+	ROLE_SERVICE_ACCOUNT = "service_account"
+
+	// ACCESS_TOKEN_PREFIX identifies access tokens so they can be told apart from JWTs
+	ACCESS_TOKEN_PREFIX = "csat_"
+
+	// SANDBOX_ACCESS_TOKEN_PREFIX identifies access tokens of the API sandbox, so they are never
+	// mistaken for, or accepted as, tokens of real accounts
+	SANDBOX_ACCESS_TOKEN_PREFIX = "cssb_"
+
+	// Maximum number of live tokens per service account
+	MAX_ACCESS_TOKENS = 10
+
+	// Token lifetime bounds and default, in days
+	MAX_ACCESS_TOKEN_DAYS     = 365
+	DEFAULT_ACCESS_TOKEN_DAYS = 90
+
+	// How long a validated token is cached; also bounds how often last-used is written
+	ACCESS_TOKEN_CACHE_EXPIRATION = time.Minute
+)
+
+// allowedTokenScopes are the scopes an access token may carry. Sharing and account management stay interactive.
+var allowedTokenScopes = []string{jwt.ScopeUserRead, jwt.ScopeUserWrite, jwt.ScopeDriveRead, jwt.ScopeDriveWrite}
+
+// NewService creates a new organization service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger, driveService *drive.Service, storage *s3.Client) *Service {
+	return &Service{
+		repo:         repo,
+		redisClient:  redisClient,
+		logger:       logger,
+		driveService: driveService,
+		storage:      storage,
+		tokenPrefix:  ACCESS_TOKEN_PREFIX,
+	}
+}
+
+// SetSandbox makes the service issue and accept sandbox access tokens instead of live ones
+func (s *Service) SetSandbox() {
+	s.tokenPrefix = SANDBOX_ACCESS_TOKEN_PREFIX
+}
+
+// TokenPrefix returns the prefix of the access tokens this service issues and accepts
+func (s *Service) TokenPrefix() string {
+	return s.tokenPrefix
+}
+
+// CreateOrganization creates an organization owned and administered by the user
+func (s *Service) CreateOrganization(ctx context.Context, userID, name string) (*models.Organization, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	organization := &models.Organization{
+		Name:    strings.TrimSpace(name),
+		OwnerID: userID,
+	}
+	owner := &models.OrganizationMember{
+		UserID: userID,
+		Role:   ROLE_ADMIN,
+	}
+
+	if err := s.repo.CreateOrganization(ctx, organization, owner); err != nil {
+		s.logger.Errorf("Failed to create organization for user %s: %v", userID, err)
+		return nil, ErrOrganizationCreation
+	}
+
+	return organization, nil
+}
+
+// AddMember adds a user to an organization
+func (s *Service) AddMember(ctx context.Context, adminID, orgID, userID string, role int) (*models.OrganizationMember, error) {
+	if role != ROLE_MEMBER && role != ROLE_ADMIN {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	// Service accounts belong to exactly one organization through their account record
+	if _, err := s.repo.GetServiceAccountByUserID(ctx, userID); err == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if _, err := s.repo.GetMember(ctx, orgID, userID); err == nil {
+		return nil, ErrMemberAlreadyExists
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+	}
+	if err := s.repo.CreateMember(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to add organization member: %w", err)
+	}
+
+	return member, nil
+}
+
+// CreateServiceAccount creates a service account with its own user identity and primary key.
+// The key pair is generated client-side; the private key stays encrypted with a passphrase only the automation holds.
+func (s *Service) CreateServiceAccount(ctx context.Context, adminID, orgID, name, description string, key *models.UserKey) (*models.ServiceAccount, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return nil, err
+	}
+
+	userID := utils.GeneratePrefixedID("sa")
+	user := &models.User{
+		ID:               userID,
+		Username:         userID,
+		DisplayName:      strings.TrimSpace(name),
+		Email:            userID + "@service-accounts.invalid",
+		EmailVerified:    true,
+		Roles:            []string{ROLE_SERVICE_ACCOUNT},
+		Active:           true,
+		StripeUserExists: false,
+		StripeCustomerID: userID,
+	}
+
+	userKey := &models.UserKey{
+		PublicKey:           key.PublicKey,
+		PrivateKey:          key.PrivateKey,
+		Passphrase:          key.Passphrase,
+		PassphraseSignature: key.PassphraseSignature,
+		Fingerprint:         key.Fingerprint,
+		Version:             key.Version,
+		Primary:             true,
+		Active:              true,
+	}
+
+	account := &models.ServiceAccount{
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(name),
+		Description:    strings.TrimSpace(description),
+		CreatedBy:      adminID,
+		State:          SERVICE_ACCOUNT_STATE_ACTIVE,
+	}
+
+	if err := s.repo.CreateServiceAccount(ctx, user, userKey, account); err != nil {
+		s.logger.Errorf("Failed to create service account in organization %s: %v", orgID, err)
+		return nil, ErrServiceAccountCreation
+	}
+
+	return account, nil
+}
+
+// ListServiceAccounts returns the service accounts of an organization
+func (s *Service) ListServiceAccounts(ctx context.Context, adminID, orgID string) ([]*models.ServiceAccount, error) {
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetServiceAccountsByOrgID(ctx, orgID)
+}
+
+// DisableServiceAccount disables a service account and revokes every token it holds
+func (s *Service) DisableServiceAccount(ctx context.Context, adminID, orgID, accountID string) error {
+	account, err := s.getManagedAccount(ctx, adminID, orgID, accountID)
+	if err != nil {
+		return err
+	}
+	if account.State == SERVICE_ACCOUNT_STATE_DISABLED {
+		return nil
+	}
+
+	// Collect token hashes before revoking so their cached identities can be dropped
+	tokens, err := s.repo.GetAccessTokensByUserID(ctx, account.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load access tokens: %w", err)
+	}
+
+	account.State = SERVICE_ACCOUNT_STATE_DISABLED
+	if err := s.repo.DisableServiceAccount(ctx, account); err != nil {
+		return fmt.Errorf("failed to disable service account: %w", err)
+	}
+
+	for _, token := range tokens {
+		s.invalidateTokenCache(ctx, token.TokenHash)
+	}
+
+	return nil
+}
+
+// CreateAccessToken issues an access token for a service account.
+// The plaintext token is returned once and cannot be recovered afterwards.
+func (s *Service) CreateAccessToken(
+	ctx context.Context,
+	adminID,
+	orgID,
+	accountID,
+	name string,
+	scopes []string,
+	expiresInDays int,
+) (*models.AccessToken, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowedTokenScopes, scope) {
+			return nil, "", ErrInvalidScopes
+		}
+	}
+
+	if expiresInDays == 0 {
+		expiresInDays = DEFAULT_ACCESS_TOKEN_DAYS
+	}
+	if expiresInDays < 1 || expiresInDays > MAX_ACCESS_TOKEN_DAYS {
+		return nil, "", ErrInvalidExpiration
+	}
+
+	account, err := s.getManagedAccount(ctx, adminID, orgID, accountID)
+	if err != nil {
+		return nil, "", err
+	}
+	if account.State != SERVICE_ACCOUNT_STATE_ACTIVE {
+		return nil, "", ErrServiceAccountDisabled
+	}
+
+	count, err := s.repo.CountActiveAccessTokens(ctx, account.UserID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to count access tokens: %w", err)
+	}
+	if count >= MAX_ACCESS_TOKENS {
+		return nil, "", ErrTooManyAccessTokens
+	}
+
+	secret := s.tokenPrefix + utils.GenerateID()
+	expiresAt := time.Now().AddDate(0, 0, expiresInDays).Unix()
+
+	token := &models.AccessToken{
+		UserID:    account.UserID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    secret[:len(s.tokenPrefix)+6],
+		TokenHash: hashAccessToken(secret),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedBy: adminID,
+		ExpiresAt: &expiresAt,
+	}
+
+	if err := s.repo.CreateAccessToken(ctx, token); err != nil {
+		return nil, "", fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	return token, secret, nil
+}
+
+// ListAccessTokens returns the access tokens of a service account without their secrets
+func (s *Service) ListAccessTokens(ctx context.Context, adminID, orgID, accountID string) ([]*models.AccessToken, error) {
+	account, err := s.getManagedAccount(ctx, adminID, orgID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAccessTokensByUserID(ctx, account.UserID)
+}
+
+// RevokeAccessToken revokes a single access token of a service account
+func (s *Service) RevokeAccessToken(ctx context.Context, adminID, orgID, accountID, tokenID string) error {
+	account, err := s.getManagedAccount(ctx, adminID, orgID, accountID)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := s.repo.GetAccessTokensByUserID(ctx, account.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load access tokens: %w", err)
+	}
+
+	idx := slices.IndexFunc(tokens, func(token *models.AccessToken) bool { return token.ID == tokenID })
+	if idx < 0 {
+		return ErrAccessTokenNotFound
+	}
+
+	if err := s.repo.RevokeAccessToken(ctx, tokenID); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	s.invalidateTokenCache(ctx, tokens[idx].TokenHash)
+
+	return nil
+}
+
+// AddServiceAccountToShare gives a service account membership of a share the admin can share.
+// The key packet must be encrypted for the service account's public key by the admin's client.
+func (s *Service) AddServiceAccountToShare(
+	ctx context.Context,
+	adminID,
+	orgID,
+	accountID,
+	shareID string,
+	membership *models.DriveShareMembership,
+) (*models.DriveShareMembership, error) {
+	account, err := s.getManagedAccount(ctx, adminID, orgID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.State != SERVICE_ACCOUNT_STATE_ACTIVE {
+		return nil, ErrServiceAccountDisabled
+	}
+
+	membership.UserID = account.UserID
+	return s.driveService.AddShareMember(ctx, adminID, shareID, membership)
+}
+
+// ImportShareMembers invites a list of users to a share owned by a member of the organization, with
+// a result per row. The admin's client encrypts each invitee's key packet, and the admin needs share
+// permission on the share like any other inviter.
+func (s *Service) ImportShareMembers(ctx context.Context, adminID, orgID, shareID string, invitations []drive.BulkInvitation) ([]*drive.BulkInvitationResult, error) {
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return nil, err
+	}
+
+	share, err := s.driveService.GetShareByID(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shares outside the organization are reported as missing
+	if _, err := s.repo.GetMember(ctx, orgID, share.UserID); err != nil {
+		if errors.Is(err, ErrOrganizationNotFound) {
+			return nil, drive.ErrShareNotFound
+		}
+		return nil, err
+	}
+
+	return s.driveService.InviteShareMembers(ctx, adminID, shareID, invitations)
+}
+
+// ValidateAccessToken resolves an access token to the service account identity it authenticates.
+// Usage is recorded at most once per cache period so each automated identity leaves an audit trail.
+func (s *Service) ValidateAccessToken(ctx context.Context, secret, ipAddress string) (*TokenIdentity, error) {
+	if !strings.HasPrefix(secret, s.tokenPrefix) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	tokenHash := hashAccessToken(secret)
+	cacheKey := fmt.Sprintf("access_token:%s", tokenHash)
+
+	// Check cache first
+	var identity TokenIdentity
+	if err := s.redisClient.GetJSON(ctx, cacheKey, &identity); err == nil {
+		if identity.ExpiresAt != nil && *identity.ExpiresAt <= time.Now().Unix() {
+			return nil, ErrInvalidAccessToken
+		}
+		return &identity, nil
+	}
+
+	token, err := s.repo.GetAccessTokenByHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, ErrAccessTokenNotFound) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && *token.ExpiresAt <= time.Now().Unix()) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	account, err := s.repo.GetServiceAccountByUserID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, ErrServiceAccountNotFound) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+	if account.State != SERVICE_ACCOUNT_STATE_ACTIVE {
+		return nil, ErrInvalidAccessToken
+	}
+
+	identity = TokenIdentity{
+		TokenID:   token.ID,
+		UserID:    token.UserID,
+		Scopes:    token.Scopes,
+		ExpiresAt: token.ExpiresAt,
+	}
+	_ = s.redisClient.SetJSON(ctx, cacheKey, identity, ACCESS_TOKEN_CACHE_EXPIRATION)
+
+	// Record usage without delaying the request
+	go func(tokenID string) {
+		opCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := s.repo.TouchAccessToken(opCtx, tokenID, ipAddress); err != nil {
+			s.logger.Errorf("Failed to record use of access token %s: %v", tokenID, err)
+		}
+	}(token.ID)
+
+	return &identity, nil
+}
+
+// requireAdmin checks that the user administers the organization
+func (s *Service) requireAdmin(ctx context.Context, orgID, userID string) error {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if _, err := s.repo.GetOrganizationByID(ctx, orgID); err != nil {
+		return err
+	}
+
+	member, err := s.repo.GetMember(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role != ROLE_ADMIN {
+		return ErrNotOrganizationAdmin
+	}
+
+	return nil
+}
+
+// getManagedAccount loads a service account of the organization after checking the caller is an admin
+func (s *Service) getManagedAccount(ctx context.Context, adminID, orgID, accountID string) (*models.ServiceAccount, error) {
+	if err := s.requireAdmin(ctx, orgID, adminID); err != nil {
+		return nil, err
+	}
+
+	account, err := s.repo.GetServiceAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.OrganizationID != orgID {
+		return nil, ErrServiceAccountNotFound
+	}
+
+	return account, nil
+}
+
+// invalidateTokenCache drops a cached token identity so revocation takes effect immediately
+func (s *Service) invalidateTokenCache(ctx context.Context, tokenHash string) {
+	cacheKey := fmt.Sprintf("access_token:%s", tokenHash)
+	if _, err := s.redisClient.Delete(ctx, cacheKey); err != nil {
+		s.logger.Errorf("Failed to delete access token cache: %v", err)
+	}
+}
+
+// hashAccessToken returns the hex SHA-256 of a token secret
+func hashAccessToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
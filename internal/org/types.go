@@ -0,0 +1,26 @@
+package org
+
+import (
+	"cirrussync-api/internal/drive"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/s3"
+)
+
+// Service handles organizations, their service accounts and access tokens
+type Service struct {
+	repo         Repository
+	redisClient  *redis.Client
+	logger       *logger.Logger
+	driveService *drive.Service
+	storage      *s3.Client
+	tokenPrefix  string // Prefix of issued and accepted access tokens, which tells sandbox tokens apart
+}
+
+// TokenIdentity is what an access token authenticates as
+type TokenIdentity struct {
+	TokenID   string
+	UserID    string
+	Scopes    []string
+	ExpiresAt *int64
+}
@@ -0,0 +1,11 @@
+package payments
+
+import "errors"
+
+// Common errors
+var (
+	ErrEntryNotFound  = errors.New("Outbox entry not found")
+	ErrUserNotFound   = errors.New("User not found")
+	ErrAlreadyStarted = errors.New("Payments worker is already running")
+	ErrUnknownType    = errors.New("Unknown outbox entry type")
+)
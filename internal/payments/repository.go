@@ -0,0 +1,165 @@
+package payments
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository interface for payment outbox operations
+type Repository interface {
+	GetEntryByID(ctx context.Context, entryID string) (*models.PaymentOutbox, error)
+	GetEntryByUserID(ctx context.Context, userID, entryType string) (*models.PaymentOutbox, error)
+	GetDueEntryIDs(ctx context.Context, now int64, limit int) ([]string, error)
+	ClaimEntry(ctx context.Context, entryID string, now, leaseUntil int64) (bool, error)
+	RetryEntry(ctx context.Context, entryID string, nextAttemptAt int64, lastError string) error
+	FailEntry(ctx context.Context, entryID string, lastError string) error
+	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+	CompleteCustomerEntry(ctx context.Context, entryID, userID, customerID string) error
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new payment outbox repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// GetEntryByID retrieves an outbox entry by its ID
+func (r *repo) GetEntryByID(ctx context.Context, entryID string) (*models.PaymentOutbox, error) {
+	var entry models.PaymentOutbox
+	err := r.db.WithContext(ctx).
+		Where("id = ?", entryID).
+		First(&entry).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetEntryByUserID retrieves a user's outbox entry of a type
+func (r *repo) GetEntryByUserID(ctx context.Context, userID, entryType string) (*models.PaymentOutbox, error) {
+	var entry models.PaymentOutbox
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND type = ?", userID, entryType).
+		First(&entry).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetDueEntryIDs retrieves entries waiting for delivery, including claimed entries whose lease ran out
+func (r *repo) GetDueEntryIDs(ctx context.Context, now int64, limit int) ([]string, error) {
+	var entryIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.PaymentOutbox{}).
+		Where("state IN ? AND next_attempt_at <= ?", []string{STATE_PENDING, STATE_PROCESSING}, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Pluck("id", &entryIDs).Error
+
+	return entryIDs, err
+}
+
+// ClaimEntry atomically leases a due entry to this worker. It reports false when another worker got there first.
+func (r *repo) ClaimEntry(ctx context.Context, entryID string, now, leaseUntil int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.PaymentOutbox{}).
+		Where("id = ? AND state IN ? AND next_attempt_at <= ?", entryID, []string{STATE_PENDING, STATE_PROCESSING}, now).
+		Updates(map[string]interface{}{
+			"state":           STATE_PROCESSING,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+			"modified_at":     now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RetryEntry schedules another delivery attempt
+func (r *repo) RetryEntry(ctx context.Context, entryID string, nextAttemptAt int64, lastError string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.PaymentOutbox{}).
+		Where("id = ?", entryID).
+		Updates(map[string]interface{}{
+			"state":           STATE_PENDING,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+			"modified_at":     time.Now().Unix(),
+		}).Error
+}
+
+// FailEntry gives up on an entry
+func (r *repo) FailEntry(ctx context.Context, entryID string, lastError string) error {
+	now := time.Now().Unix()
+	return r.db.WithContext(ctx).
+		Model(&models.PaymentOutbox{}).
+		Where("id = ?", entryID).
+		Updates(map[string]interface{}{
+			"state":        STATE_FAILED,
+			"last_error":   lastError,
+			"completed_at": now,
+			"modified_at":  now,
+		}).Error
+}
+
+// GetUserByID retrieves the user fields needed to create a customer
+func (r *repo) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Select("id", "email", "stripe_customer_id").
+		Where("id = ?", userID).
+		First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CompleteCustomerEntry stores the created customer on the user and completes the entry in one transaction
+func (r *repo) CompleteCustomerEntry(ctx context.Context, entryID, userID, customerID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"stripe_customer_id": customerID,
+				"stripe_user_exists": true,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		return tx.Model(&models.PaymentOutbox{}).
+			Where("id = ?", entryID).
+			Updates(map[string]interface{}{
+				"state":        STATE_COMPLETED,
+				"last_error":   nil,
+				"completed_at": now,
+				"modified_at":  now,
+			}).Error
+	})
+}
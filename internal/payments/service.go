@@ -0,0 +1,225 @@
+package payments
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/config"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Outbox entry states
+const (
+	STATE_PENDING    = "pending"
+	STATE_PROCESSING = "processing"
+	STATE_COMPLETED  = "completed"
+	STATE_FAILED     = "failed"
+)
+
+// Outbox entry types
+const (
+	TYPE_CREATE_CUSTOMER = "create_customer"
+)
+
+// Customer setup statuses reported to clients
+const (
+	CUSTOMER_STATUS_READY   = "ready"   // The user has a payment provider customer
+	CUSTOMER_STATUS_PENDING = "pending" // Creation is queued or being retried
+	CUSTOMER_STATUS_FAILED  = "failed"  // Retries ran out; the customer is created on first purchase instead
+	CUSTOMER_STATUS_NONE    = "none"    // Accounts from before signup queued a customer; created on first purchase
+)
+
+// NewService creates a new payments service
+func NewService(repo Repository, logger *logger.Logger, cfg *config.PaymentsConfig) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+		config: cfg,
+	}
+}
+
+// NewCustomerEntry returns the outbox entry that creates a new user's customer.
+// Store it in the same transaction as the user.
+func NewCustomerEntry(userID string) *models.PaymentOutbox {
+	return &models.PaymentOutbox{
+		UserID: userID,
+		Type:   TYPE_CREATE_CUSTOMER,
+		State:  STATE_PENDING,
+	}
+}
+
+// HasCustomer reports whether a stored customer ID is a real payment provider customer
+// rather than the placeholder accounts are created with
+func HasCustomer(customerID string) bool {
+	return strings.HasPrefix(customerID, "cus_")
+}
+
+// SetCustomerProvider enables delivery of customer creation entries. It must be called before Start.
+func (s *Service) SetCustomerProvider(customers CustomerProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.customers = customers
+}
+
+// GetCustomerStatus reports how far the user's payment provider customer setup has got
+func (s *Service) GetCustomerStatus(ctx context.Context, userID string) (string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if HasCustomer(user.StripeCustomerID) {
+		return CUSTOMER_STATUS_READY, nil
+	}
+
+	entry, err := s.repo.GetEntryByUserID(ctx, userID, TYPE_CREATE_CUSTOMER)
+	if errors.Is(err, ErrEntryNotFound) {
+		return CUSTOMER_STATUS_NONE, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch entry.State {
+	case STATE_FAILED:
+		return CUSTOMER_STATUS_FAILED, nil
+	case STATE_COMPLETED:
+		// Completed entries store the customer on the user, so this only happens if it was cleared since
+		return CUSTOMER_STATUS_NONE, nil
+	default:
+		return CUSTOMER_STATUS_PENDING, nil
+	}
+}
+
+// Start runs the outbox poller until ctx is cancelled. Without a customer provider there is nothing to deliver.
+func (s *Service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return ErrAlreadyStarted
+	}
+	s.started = true
+	enabled := s.customers != nil
+	s.mu.Unlock()
+
+	if !enabled {
+		s.logger.Info("Payment provider is not configured, the payments outbox is not delivered")
+		return nil
+	}
+
+	go s.poll(ctx)
+	return nil
+}
+
+// poll delivers due entries on every tick
+func (s *Service) poll(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue claims and delivers a batch of due entries
+func (s *Service) deliverDue(ctx context.Context) {
+	now := time.Now().Unix()
+	entryIDs, err := s.repo.GetDueEntryIDs(ctx, now, s.config.BatchSize)
+	if err != nil {
+		s.logger.Errorf("Failed to load due payment outbox entries: %v", err)
+		return
+	}
+
+	leaseUntil := time.Now().Add(s.config.ProcessingLease).Unix()
+	for _, entryID := range entryIDs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Another instance may be delivering the same entry; only one gets to claim it
+		claimed, err := s.repo.ClaimEntry(ctx, entryID, now, leaseUntil)
+		if err != nil {
+			s.logger.Errorf("Failed to claim payment outbox entry %s: %v", entryID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		entry, err := s.repo.GetEntryByID(ctx, entryID)
+		if err != nil {
+			s.logger.Errorf("Failed to load payment outbox entry %s: %v", entryID, err)
+			continue
+		}
+
+		s.deliver(ctx, entry)
+	}
+}
+
+// deliver runs one attempt of an entry and records its outcome
+func (s *Service) deliver(ctx context.Context, entry *models.PaymentOutbox) {
+	var err error
+	switch entry.Type {
+	case TYPE_CREATE_CUSTOMER:
+		err = s.createCustomer(ctx, entry)
+	default:
+		err = ErrUnknownType
+	}
+
+	switch {
+	case err == nil:
+		return
+	case ctx.Err() != nil:
+		// Shutting down; the entry is retried once its lease runs out
+		return
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrUnknownType), entry.Attempts >= s.config.MaxAttempts:
+		s.logger.Errorf("Giving up on payment outbox entry %s (%s) after %d attempts: %v", entry.ID, entry.Type, entry.Attempts, err)
+		if err := s.repo.FailEntry(context.Background(), entry.ID, err.Error()); err != nil {
+			s.logger.Errorf("Failed to mark payment outbox entry %s as failed: %v", entry.ID, err)
+		}
+	default:
+		nextAttemptAt := time.Now().Add(s.retryDelay(entry.Attempts)).Unix()
+		s.logger.Warnf("Payment outbox entry %s (%s) failed attempt %d, retrying: %v", entry.ID, entry.Type, entry.Attempts, err)
+		if err := s.repo.RetryEntry(context.Background(), entry.ID, nextAttemptAt, err.Error()); err != nil {
+			s.logger.Errorf("Failed to schedule retry of payment outbox entry %s: %v", entry.ID, err)
+		}
+	}
+}
+
+// createCustomer creates the user's customer and back-fills its ID on the user
+func (s *Service) createCustomer(ctx context.Context, entry *models.PaymentOutbox) error {
+	user, err := s.repo.GetUserByID(ctx, entry.UserID)
+	if err != nil {
+		return err
+	}
+
+	// The customer may already have been created on a first purchase
+	customerID := user.StripeCustomerID
+	if !HasCustomer(customerID) {
+		customerID, err = s.customers.CreateCustomer(ctx, user.ID, user.Email)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.repo.CompleteCustomerEntry(ctx, entry.ID, user.ID, customerID)
+}
+
+// retryDelay doubles the base delay for every attempt made, up to the configured maximum
+func (s *Service) retryDelay(attempts int) time.Duration {
+	delay := s.config.RetryBaseDelay
+	for i := 1; i < attempts && delay < s.config.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.RetryMaxDelay {
+		delay = s.config.RetryMaxDelay
+	}
+	return delay
+}
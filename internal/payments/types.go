@@ -0,0 +1,24 @@
+package payments
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/config"
+	"context"
+	"sync"
+)
+
+// CustomerProvider creates customers at the payment provider. CreateCustomer is retried after
+// failures, so it must be idempotent per user.
+type CustomerProvider interface {
+	CreateCustomer(ctx context.Context, userID, email string) (string, error)
+}
+
+// Service delivers the payment provider outbox and reports how far a user's customer setup has got
+type Service struct {
+	repo      Repository
+	logger    *logger.Logger
+	config    *config.PaymentsConfig
+	customers CustomerProvider
+	mu        sync.Mutex
+	started   bool
+}
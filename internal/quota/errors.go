@@ -0,0 +1,9 @@
+package quota
+
+import "errors"
+
+// Common errors
+var (
+	ErrStorageQuotaExceeded = errors.New("Storage quota exceeded")
+	ErrAllocationNotFound   = errors.New("Storage allocation not found")
+)
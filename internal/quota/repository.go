@@ -0,0 +1,158 @@
+package quota
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository interface for storage quota operations
+type Repository interface {
+	GetPlanStorageQuota(ctx context.Context, userID string) (int64, bool, error)
+	GetAllocation(ctx context.Context, userID string) (*models.VolumeAllocation, error)
+	ConsumeAllocation(ctx context.Context, allocationID string, bytes, limit int64) (bool, error)
+	AdjustAllocation(ctx context.Context, allocationID string, bytes int64) error
+
+	// Usage warnings
+	GetAllocationsNearLimit(ctx context.Context, afterID string, minPercent, limit int) ([]models.VolumeAllocation, error)
+	MarkWarned(ctx context.Context, allocationID string, percent, previousPercent int) (bool, error)
+	ResetWarnings(ctx context.Context, belowPercent int) (int64, error)
+	GetEmailNotificationsEnabled(ctx context.Context, userID string) (bool, error)
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new storage quota repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// GetPlanStorageQuota retrieves the largest storage quota among a user's active plans,
+// including purchased add-on storage. The boolean is false when the user has no active plan.
+func (r *repo) GetPlanStorageQuota(ctx context.Context, userID string) (int64, bool, error) {
+	var quota *int64
+	err := r.db.WithContext(ctx).
+		Model(&models.UserPlan{}).
+		Select("MAX(storage_quota + additional_storage)").
+		Where("user_id = ? AND status = ?", userID, "active").
+		Scan(&quota).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if quota == nil {
+		return 0, false, nil
+	}
+	return *quota, true, nil
+}
+
+// GetAllocation retrieves a user's active storage allocation
+func (r *repo) GetAllocation(ctx context.Context, userID string) (*models.VolumeAllocation, error) {
+	var allocation models.VolumeAllocation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND active = ?", userID, true).
+		First(&allocation).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAllocationNotFound
+		}
+		return nil, err
+	}
+	return &allocation, nil
+}
+
+// ConsumeAllocation adds to the used size only if the result stays within the limit.
+// The check and the update are a single statement, so concurrent charges cannot overshoot.
+func (r *repo) ConsumeAllocation(ctx context.Context, allocationID string, bytes, limit int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.VolumeAllocation{}).
+		Where("id = ? AND used_size + ? <= ?", allocationID, bytes, limit).
+		Updates(map[string]any{
+			"used_size":      gorm.Expr("used_size + ?", bytes),
+			"allocated_size": limit,
+			"modified_at":    time.Now().Unix(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AdjustAllocation changes the used size without checking the limit, never going below zero
+func (r *repo) AdjustAllocation(ctx context.Context, allocationID string, bytes int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.VolumeAllocation{}).
+		Where("id = ?", allocationID).
+		Updates(map[string]any{
+			"used_size":   gorm.Expr("GREATEST(used_size + ?, 0)", bytes),
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
+
+// GetAllocationsNearLimit retrieves active allocations using at least minPercent of the limit they
+// were last charged against, ordered by ID and starting after afterID so all can be paged through
+func (r *repo) GetAllocationsNearLimit(ctx context.Context, afterID string, minPercent, limit int) ([]models.VolumeAllocation, error) {
+	var allocations []models.VolumeAllocation
+	err := r.db.WithContext(ctx).
+		Where("active = ? AND allocated_size > 0 AND used_size * 100 >= allocated_size * ?", true, minPercent).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&allocations).Error
+
+	return allocations, err
+}
+
+// MarkWarned records the threshold a user was warned about. It reports false when the recorded
+// threshold changed since it was read, so concurrent evaluations send one warning.
+func (r *repo) MarkWarned(ctx context.Context, allocationID string, percent, previousPercent int) (bool, error) {
+	now := time.Now().Unix()
+	result := r.db.WithContext(ctx).
+		Model(&models.VolumeAllocation{}).
+		Where("id = ? AND warned_percent = ?", allocationID, previousPercent).
+		Updates(map[string]any{
+			"warned_percent": percent,
+			"warned_at":      now,
+			"modified_at":    now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ResetWarnings forgets the warnings of users whose usage dropped below a threshold, so they are
+// warned again when it rises
+func (r *repo) ResetWarnings(ctx context.Context, belowPercent int) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.VolumeAllocation{}).
+		Where("warned_percent > 0 AND used_size * 100 < allocated_size * ?", belowPercent).
+		Update("warned_percent", 0)
+
+	return result.RowsAffected, result.Error
+}
+
+// GetEmailNotificationsEnabled reports whether a user wants to receive notifications by email.
+// Users who never changed their notification settings do.
+func (r *repo) GetEmailNotificationsEnabled(ctx context.Context, userID string) (bool, error) {
+	var enabled []bool
+	err := r.db.WithContext(ctx).
+		Model(&models.UserNotifications{}).
+		Joins("JOIN users_preferences ON users_preferences.id = user_notifications.preferences_id").
+		Where("users_preferences.user_id = ?", userID).
+		Limit(1).
+		Pluck("user_notifications.email", &enabled).Error
+	if err != nil {
+		return false, err
+	}
+	return len(enabled) == 0 || enabled[0], nil
+}
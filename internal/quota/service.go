@@ -0,0 +1,216 @@
+package quota
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DEFAULT_STORAGE_QUOTA is the storage limit of users without an active plan
+const DEFAULT_STORAGE_QUOTA int64 = 3 * 1024 * 1024 * 1024
+
+// LIMIT_CACHE_EXPIRATION bounds how long a plan change can take to apply
+const LIMIT_CACHE_EXPIRATION = 5 * time.Minute
+
+// USAGE_CACHE_EXPIRATION bounds how long the usage counter of pre-checks may drift from the database
+const USAGE_CACHE_EXPIRATION = 5 * time.Minute
+
+// NewService creates a new storage quota service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// SetWarningMailer configures how users are warned by email that their storage is almost full.
+// Without a mailer users are only warned in the app.
+func (s *Service) SetWarningMailer(mailer WarningMailer) {
+	s.warningMailer = mailer
+}
+
+// SetNotifier configures how users are warned in the app that their storage is almost full
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// SetJobService registers the job that evaluates the storage usage of every user
+func (s *Service) SetJobService(jobService *jobs.Service) {
+	s.jobService = jobService
+	jobService.Register(JOB_TYPE_QUOTA_WARNINGS, s.runWarningsJob)
+}
+
+// GetLimit returns the user's storage limit derived from their active plan
+func (s *Service) GetLimit(ctx context.Context, userID string) (int64, error) {
+	// Check cache first
+	cacheKey := fmt.Sprintf("quota_limit:%s", userID)
+	var limit int64
+	if err := s.redisClient.GetJSON(ctx, cacheKey, &limit); err == nil {
+		return limit, nil
+	}
+
+	planQuota, ok, err := s.repo.GetPlanStorageQuota(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get plan storage quota: %w", err)
+	}
+
+	limit = PlanLimit(planQuota, ok)
+
+	_ = s.redisClient.SetJSON(ctx, cacheKey, limit, LIMIT_CACHE_EXPIRATION)
+
+	return limit, nil
+}
+
+// PlanLimit returns the storage limit granted by the largest quota among a user's active plans.
+// Users without an active plan, or whose plans grant no storage, get the default.
+func PlanLimit(planQuota int64, hasPlan bool) int64 {
+	if hasPlan && planQuota > 0 {
+		return planQuota
+	}
+	return DEFAULT_STORAGE_QUOTA
+}
+
+// InvalidateLimit drops the cached limit, for use after a plan change
+func (s *Service) InvalidateLimit(ctx context.Context, userID string) {
+	if _, err := s.redisClient.Delete(ctx, fmt.Sprintf("quota_limit:%s", userID)); err != nil {
+		s.logger.Errorf("Failed to delete quota limit cache for user %s: %v", userID, err)
+	}
+}
+
+// GetUsage returns the user's current storage usage and limit
+func (s *Service) GetUsage(ctx context.Context, userID string) (*Usage, error) {
+	limit, err := s.GetLimit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocation, err := s.repo.GetAllocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{UsedBytes: allocation.UsedSize, LimitBytes: limit}, nil
+}
+
+// Check verifies the user has room for the given bytes without charging them. It reads usage
+// from a Redis counter, so it may be briefly behind; use Consume where the bytes are actually
+// being stored, which checks against the database.
+func (s *Service) Check(ctx context.Context, userID string, bytes int64) error {
+	limit, err := s.GetLimit(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	used, err := s.cachedUsed(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	usage := Usage{UsedBytes: used, LimitBytes: limit}
+	if bytes > usage.RemainingBytes() {
+		return &ExceededError{Usage: usage, RequestedBytes: bytes}
+	}
+	return nil
+}
+
+// Consume atomically charges bytes against the user's limit, failing with an ExceededError if they do not fit
+func (s *Service) Consume(ctx context.Context, userID string, bytes int64) error {
+	limit, err := s.GetLimit(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	allocation, err := s.repo.GetAllocation(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.repo.ConsumeAllocation(ctx, allocation.ID, bytes, limit)
+	if err != nil {
+		return fmt.Errorf("failed to charge storage: %w", err)
+	}
+	if !ok {
+		// Reload so the error reports usage as of the rejected charge
+		if current, err := s.repo.GetAllocation(ctx, userID); err == nil {
+			allocation = current
+		}
+		return &ExceededError{
+			Usage:          Usage{UsedBytes: allocation.UsedSize, LimitBytes: limit},
+			RequestedBytes: bytes,
+		}
+	}
+
+	s.recordUsage(ctx, userID, bytes)
+	s.warnIfNearlyFull(ctx, allocation, allocation.UsedSize+bytes, limit)
+
+	return nil
+}
+
+// Adjust changes the user's usage without enforcing the limit, for releases and bookkeeping charges
+func (s *Service) Adjust(ctx context.Context, userID string, bytes int64) error {
+	allocation, err := s.repo.GetAllocation(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.AdjustAllocation(ctx, allocation.ID, bytes); err != nil {
+		return fmt.Errorf("failed to adjust storage used: %w", err)
+	}
+
+	s.recordUsage(ctx, userID, bytes)
+
+	return nil
+}
+
+// usageKey is the Redis counter of a user's used bytes
+func usageKey(userID string) string {
+	return fmt.Sprintf("quota_used:%s", userID)
+}
+
+// cachedUsed returns the user's used bytes from the Redis counter, seeding it from the database
+// when it is missing. A charge committed while the counter is seeded can be missed until it expires.
+func (s *Service) cachedUsed(ctx context.Context, userID string) (int64, error) {
+	key := usageKey(userID)
+
+	if value, err := s.redisClient.Get(ctx, key); err == nil && value != "" {
+		if used, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return used, nil
+		}
+	}
+
+	allocation, err := s.repo.GetAllocation(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// A counter seeded concurrently already includes the charges since, so it is kept
+	if _, err := s.redisClient.SetNX(ctx, key, allocation.UsedSize, USAGE_CACHE_EXPIRATION); err != nil {
+		s.logger.Warnf("Failed to cache storage used for user %s: %v", userID, err)
+	}
+
+	return allocation.UsedSize, nil
+}
+
+// recordUsage applies a committed change to the Redis counter. A missing counter stays missing,
+// since it is seeded from the database on the next check.
+func (s *Service) recordUsage(ctx context.Context, userID string, bytes int64) {
+	key := usageKey(userID)
+
+	used, exists, err := s.redisClient.IncrByIfExists(ctx, key, bytes)
+	if err != nil {
+		s.logger.Warnf("Failed to update cached storage used for user %s: %v", userID, err)
+		return
+	}
+
+	// The database never goes below zero, so a counter that did has drifted and is reseeded
+	if exists && used < 0 {
+		if _, err := s.redisClient.Delete(ctx, key); err != nil {
+			s.logger.Warnf("Failed to reset cached storage used for user %s: %v", userID, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package quota
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"fmt"
+)
+
+// Service derives storage limits from a user's plan and charges usage against them
+type Service struct {
+	repo          Repository
+	redisClient   *redis.Client
+	logger        *logger.Logger
+	warningMailer WarningMailer
+	notifier      Notifier
+	jobService    *jobs.Service
+}
+
+// WarningMailer tells users that their storage is almost full
+type WarningMailer interface {
+	SendQuotaWarningEmail(userID string, usedBytes, limitBytes int64) error
+}
+
+// Notifier adds in-app notifications to a user's inbox
+type Notifier interface {
+	Notify(ctx context.Context, userID, notificationType string, data map[string]string) error
+}
+
+// Usage is a user's storage consumption against their plan limit
+type Usage struct {
+	UsedBytes  int64
+	LimitBytes int64
+}
+
+// RemainingBytes returns how much more the user may store
+func (u *Usage) RemainingBytes() int64 {
+	return max(u.LimitBytes-u.UsedBytes, 0)
+}
+
+// ExceededError reports a rejected charge along with the usage it was checked against
+type ExceededError struct {
+	Usage
+	RequestedBytes int64
+}
+
+// Error implements the error interface
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s: %d of %d bytes used, %d requested",
+		ErrStorageQuotaExceeded.Error(), e.UsedBytes, e.LimitBytes, e.RequestedBytes)
+}
+
+// Unwrap lets callers match the error with errors.Is(err, ErrStorageQuotaExceeded)
+func (e *ExceededError) Unwrap() error {
+	return ErrStorageQuotaExceeded
+}
@@ -0,0 +1,154 @@
+package quota
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/notification"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// JOB_TYPE_QUOTA_WARNINGS evaluates the storage usage of every user
+const JOB_TYPE_QUOTA_WARNINGS = "quota.warnings"
+
+// WARNING_THRESHOLDS are the usage percentages users are warned at, lowest first
+var WARNING_THRESHOLDS = []int{80, 95, 100}
+
+const (
+	// WARNING_MIN_INTERVAL rate limits warnings: a user is warned at most once per interval, except
+	// when their storage is full
+	WARNING_MIN_INTERVAL = 24 * time.Hour
+
+	// WARNING_CHECK_INTERVAL is how often the usage of every user is evaluated
+	WARNING_CHECK_INTERVAL = 24 * time.Hour
+
+	// WARNING_BATCH_SIZE is how many allocations are evaluated per query
+	WARNING_BATCH_SIZE = 500
+)
+
+// warningThreshold returns the highest threshold the usage reached, 0 below the lowest
+func warningThreshold(used, limit int64) int {
+	reached := 0
+	for _, threshold := range WARNING_THRESHOLDS {
+		if limit > 0 && used*100 >= limit*int64(threshold) {
+			reached = threshold
+		}
+	}
+	return reached
+}
+
+// warnIfNearlyFull warns a user whose charge took their usage past a threshold they were not warned
+// about yet. The warning is sent in the background so the charge is not held up by it.
+func (s *Service) warnIfNearlyFull(ctx context.Context, allocation *models.VolumeAllocation, used, limit int64) {
+	if warningThreshold(used, limit) <= allocation.WarnedPercent {
+		return
+	}
+
+	go s.warn(context.WithoutCancel(ctx), allocation, used, limit)
+}
+
+// warn warns a user about their usage when it passed a threshold they were not warned about yet and
+// the rate limit allows it. The user gets an in-app notification and, unless they turned email
+// notifications off, an email. It reports whether the user was warned.
+func (s *Service) warn(ctx context.Context, allocation *models.VolumeAllocation, used, limit int64) bool {
+	threshold := warningThreshold(used, limit)
+	if threshold <= allocation.WarnedPercent {
+		return false
+	}
+	full := threshold >= 100
+	if !full && time.Since(time.Unix(allocation.WarnedAt, 0)) < WARNING_MIN_INTERVAL {
+		return false
+	}
+
+	// Recording the warning first keeps concurrent charges and the scheduler from warning twice
+	marked, err := s.repo.MarkWarned(ctx, allocation.ID, threshold, allocation.WarnedPercent)
+	if err != nil {
+		s.logger.Errorf("Failed to record storage warning of user %s: %v", allocation.UserID, err)
+		return false
+	}
+	if !marked {
+		return false
+	}
+
+	if s.notifier != nil {
+		err := s.notifier.Notify(ctx, allocation.UserID, notification.TYPE_STORAGE_WARNING, map[string]string{
+			"percent":    strconv.Itoa(threshold),
+			"usedBytes":  strconv.FormatInt(used, 10),
+			"limitBytes": strconv.FormatInt(limit, 10),
+		})
+		if err != nil {
+			s.logger.Errorf("Failed to notify user %s of their storage usage: %v", allocation.UserID, err)
+		}
+	}
+
+	if s.warningMailer != nil {
+		enabled, err := s.repo.GetEmailNotificationsEnabled(ctx, allocation.UserID)
+		if err != nil {
+			s.logger.Errorf("Failed to load notification settings of user %s: %v", allocation.UserID, err)
+		} else if enabled {
+			if err := s.warningMailer.SendQuotaWarningEmail(allocation.UserID, used, limit); err != nil {
+				s.logger.Errorf("Failed to send storage warning to user %s: %v", allocation.UserID, err)
+			}
+		}
+	}
+
+	return true
+}
+
+// StartWarningScheduler queues a job every day that evaluates the storage usage of every user, warning
+// those who passed a threshold without a charge triggering the warning, such as after a plan
+// downgrade, until ctx is cancelled
+func (s *Service) StartWarningScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.Schedule(ctx, "quota_warnings", WARNING_CHECK_INTERVAL, JOB_TYPE_QUOTA_WARNINGS, nil)
+}
+
+// runWarningsJob resets the warnings of users whose usage dropped and warns users near their limit.
+// Users are only warned about thresholds they were not warned about yet, so running it again is safe.
+func (s *Service) runWarningsJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	if _, err := s.repo.ResetWarnings(ctx, WARNING_THRESHOLDS[0]); err != nil {
+		s.logger.Errorf("Failed to reset storage warnings: %v", err)
+	}
+
+	warned := 0
+	afterID := ""
+	for {
+		allocations, err := s.repo.GetAllocationsNearLimit(ctx, afterID, WARNING_THRESHOLDS[0], WARNING_BATCH_SIZE)
+		if err != nil {
+			return fmt.Errorf("failed to load storage allocations near their limit: %w", err)
+		}
+
+		for i := range allocations {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			allocation := &allocations[i]
+			// The allocated size is the limit of the last charge; the plan may have changed since
+			limit, err := s.GetLimit(ctx, allocation.UserID)
+			if err != nil {
+				s.logger.Errorf("Failed to get storage limit of user %s: %v", allocation.UserID, err)
+				continue
+			}
+			if s.warn(ctx, allocation, allocation.UsedSize, limit) {
+				warned++
+			}
+		}
+
+		if len(allocations) < WARNING_BATCH_SIZE {
+			break
+		}
+		afterID = allocations[len(allocations)-1].ID
+	}
+
+	if warned > 0 {
+		s.logger.Infof("Warned %d users about their storage usage", warned)
+	}
+
+	return nil
+}
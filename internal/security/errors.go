@@ -0,0 +1,16 @@
+package security
+
+import (
+	"errors"
+)
+
+// Security event errors
+var (
+	ErrInvalidInput     = errors.New("Invalid input")
+	ErrInvalidTimeRange = errors.New("Start of the time range must be before its end")
+
+	ErrInvalidExportFormat = errors.New("Report format must be csv or json")
+	ErrExportInProgress    = errors.New("A security report is already being generated")
+	ErrExportNotFound      = errors.New("Security report not found")
+	ErrExportsUnavailable  = errors.New("Security reports are not available")
+)
@@ -0,0 +1,308 @@
+package security
+
+import (
+	"bytes"
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// JOB_TYPE_EVENT_EXPORT generates a security event report in the background
+const JOB_TYPE_EVENT_EXPORT = "security.event_export"
+
+// Report formats
+const (
+	EXPORT_FORMAT_CSV  = "csv"
+	EXPORT_FORMAT_JSON = "json"
+)
+
+// Report states, stored in SecurityEventDownload.Status
+const (
+	EXPORT_STATUS_PROCESSING = "processing"
+	EXPORT_STATUS_COMPLETED  = "completed"
+	EXPORT_STATUS_FAILED     = "failed"
+)
+
+// Report limits
+const (
+	EXPORT_PAGE_SIZE  = 1000           // Events read per query
+	MAX_EXPORT_EVENTS = 100000         // Newest events included in one report
+	EXPORT_URL_TTL    = 24 * time.Hour // How long a finished report can be downloaded
+)
+
+// exportColumns are the columns of a CSV report
+var exportColumns = []string{"id", "eventType", "success", "createdAt", "metadata"}
+
+// exportedEvent is an event in a JSON report
+type exportedEvent struct {
+	ID        string          `json:"id"`
+	EventType string          `json:"eventType"`
+	Success   bool            `json:"success"`
+	CreatedAt int64           `json:"createdAt"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+}
+
+// SetJobService enables security event reports, which are generated by a background job
+func (s *Service) SetJobService(jobService *jobs.Service) {
+	s.jobService = jobService
+	jobService.Register(JOB_TYPE_EVENT_EXPORT, s.runEventExportJob)
+}
+
+// SetReportStorage configures where generated reports are stored
+func (s *Service) SetReportStorage(storage ReportStorage) {
+	s.storage = storage
+}
+
+// SetReportMailer configures how users are told that a report is ready. Without a mailer reports
+// are still generated and can be fetched once their status is completed.
+func (s *Service) SetReportMailer(mailer ReportMailer) {
+	s.mailer = mailer
+}
+
+// RequestExport queues a report of the user's security events matching the request. Only one
+// report per user is generated at a time.
+func (s *Service) RequestExport(ctx context.Context, userID string, request ExportRequest) (*models.SecurityEventDownload, error) {
+	if s.jobService == nil || s.storage == nil {
+		return nil, ErrExportsUnavailable
+	}
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+	if request.Format != EXPORT_FORMAT_CSV && request.Format != EXPORT_FORMAT_JSON {
+		return nil, ErrInvalidExportFormat
+	}
+
+	// Events recorded while the report is generated would shift the pages it is read in
+	now := time.Now()
+	if request.Until <= 0 || request.Until > now.Unix() {
+		request.Until = now.Unix()
+	}
+	if request.Since >= request.Until {
+		return nil, ErrInvalidTimeRange
+	}
+
+	processing, err := s.repo.CountDownloadsByStatus(ctx, userID, EXPORT_STATUS_PROCESSING)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending security reports: %w", err)
+	}
+	if processing > 0 {
+		return nil, ErrExportInProgress
+	}
+
+	filters, err := json.Marshal(map[string]any{
+		"eventTypes": request.EventTypes,
+		"since":      request.Since,
+		"until":      request.Until,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	download := &models.SecurityEventDownload{
+		UserID:   userID,
+		Status:   EXPORT_STATUS_PROCESSING,
+		Format:   request.Format,
+		FileName: fmt.Sprintf("security-events-%s.%s", now.UTC().Format("2006-01-02"), request.Format),
+		Filters:  filters,
+	}
+	if err := s.repo.CreateDownload(ctx, download); err != nil {
+		return nil, fmt.Errorf("failed to create security report: %w", err)
+	}
+
+	if _, err := s.jobService.Enqueue(ctx, userID, JOB_TYPE_EVENT_EXPORT, map[string]string{"downloadId": download.ID}); err != nil {
+		s.failExport(ctx, download.ID)
+		return nil, fmt.Errorf("failed to queue security report: %w", err)
+	}
+
+	return download, nil
+}
+
+// GetExport returns one of the user's security event reports. The download link is only
+// included while the report can still be downloaded.
+func (s *Service) GetExport(ctx context.Context, userID, downloadID string) (*models.SecurityEventDownload, error) {
+	download, err := s.repo.GetDownloadByID(ctx, downloadID)
+	if err != nil {
+		return nil, err
+	}
+	if download.UserID != userID {
+		return nil, ErrExportNotFound
+	}
+
+	if download.ExpiresAt > 0 && download.ExpiresAt <= time.Now().Unix() {
+		download.DownloadURL = ""
+	}
+
+	return download, nil
+}
+
+// runEventExportJob writes the report, stores it and notifies the user. An interrupted report is
+// generated again from the start.
+func (s *Service) runEventExportJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	download, err := s.repo.GetDownloadByID(ctx, job.Payload["downloadId"])
+	if err != nil {
+		return err
+	}
+	if download.Status != EXPORT_STATUS_PROCESSING {
+		return nil
+	}
+
+	var filters struct {
+		EventTypes []string `json:"eventTypes"`
+		Since      int64    `json:"since"`
+		Until      int64    `json:"until"`
+	}
+	if err := json.Unmarshal(download.Filters, &filters); err != nil {
+		s.failExport(ctx, download.ID)
+		return fmt.Errorf("invalid security report filters: %w", err)
+	}
+
+	filter := EventFilter{
+		EventTypes: filters.EventTypes,
+		Since:      filters.Since,
+		Until:      filters.Until,
+		Limit:      EXPORT_PAGE_SIZE,
+	}
+
+	var events []models.UserSecurityEvent
+	for filter.Page = 1; ; filter.Page++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		page, total, err := s.repo.GetEvents(ctx, download.UserID, filter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.failExport(ctx, download.ID)
+			return fmt.Errorf("failed to load security events: %w", err)
+		}
+		events = append(events, page...)
+
+		total = min(total, MAX_EXPORT_EVENTS)
+		progress(int64(len(events)), total, nil)
+		if total > 0 {
+			s.updateExport(ctx, download.ID, map[string]interface{}{"progress": int(int64(len(events)) * 100 / total)})
+		}
+
+		if len(page) < filter.Limit || int64(len(events)) >= total {
+			break
+		}
+	}
+	events = events[:min(len(events), MAX_EXPORT_EVENTS)]
+
+	body, contentType, err := encodeEvents(events, download.Format)
+	if err != nil {
+		s.failExport(ctx, download.ID)
+		return fmt.Errorf("failed to encode security report: %w", err)
+	}
+
+	key := fmt.Sprintf("users/%s/reports/security/%s.%s", download.UserID, download.ID, download.Format)
+	if err := s.storage.PutObject(ctx, key, contentType, body); err != nil {
+		s.failExport(ctx, download.ID)
+		return fmt.Errorf("failed to store security report: %w", err)
+	}
+
+	url, err := s.storage.GetDownloadPresignedURL(key, EXPORT_URL_TTL)
+	if err != nil {
+		s.failExport(ctx, download.ID)
+		return fmt.Errorf("failed to sign security report link: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(EXPORT_URL_TTL).Unix()
+	err = s.repo.UpdateDownload(ctx, download.ID, map[string]interface{}{
+		"status":       EXPORT_STATUS_COMPLETED,
+		"progress":     100,
+		"file_size":    strconv.Itoa(len(body)),
+		"storage_path": key,
+		"download_url": url,
+		"completed_at": now.Unix(),
+		"expires_at":   expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete security report: %w", err)
+	}
+
+	s.notifyExportReady(ctx, download.UserID, download.ID, expiresAt)
+
+	return nil
+}
+
+// encodeEvents writes events as a report in the given format, returning it with its content type
+func encodeEvents(events []models.UserSecurityEvent, format string) ([]byte, string, error) {
+	if format == EXPORT_FORMAT_JSON {
+		exported := make([]exportedEvent, len(events))
+		for i, event := range events {
+			exported[i] = exportedEvent{
+				ID:        event.ID,
+				EventType: event.EventType,
+				Success:   event.Success,
+				CreatedAt: event.CreatedAt,
+				Metadata:  event.AdditionalMetadata,
+			}
+		}
+
+		body, err := json.MarshalIndent(map[string]any{"events": exported}, "", "  ")
+		return body, "application/json", err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(exportColumns); err != nil {
+		return nil, "", err
+	}
+	for _, event := range events {
+		record := []string{
+			event.ID,
+			event.EventType,
+			strconv.FormatBool(event.Success),
+			time.Unix(event.CreatedAt, 0).UTC().Format(time.RFC3339),
+			string(event.AdditionalMetadata),
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, "", err
+		}
+	}
+	writer.Flush()
+
+	return buf.Bytes(), "text/csv", writer.Error()
+}
+
+// notifyExportReady emails the user that their report is ready. A failed email does not fail the
+// report, which can still be fetched from the account settings.
+func (s *Service) notifyExportReady(ctx context.Context, userID, downloadID string, expiresAt int64) {
+	if s.mailer == nil {
+		return
+	}
+
+	email, err := s.repo.GetUserEmail(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to load email of user %s for security report: %v", userID, err)
+		return
+	}
+
+	if err := s.mailer.SendSecurityReportEmail(email, downloadID, expiresAt); err != nil {
+		s.logger.Errorf("Failed to send security report email to user %s: %v", userID, err)
+	}
+}
+
+// failExport marks a report as failed. The reason is logged, users only see that it failed.
+func (s *Service) failExport(ctx context.Context, downloadID string) {
+	s.updateExport(context.WithoutCancel(ctx), downloadID, map[string]interface{}{
+		"status":        EXPORT_STATUS_FAILED,
+		"error_message": "The report could not be generated, please request it again",
+	})
+}
+
+// updateExport writes progress or status to a report, logging failures
+func (s *Service) updateExport(ctx context.Context, downloadID string, updates map[string]interface{}) {
+	if err := s.repo.UpdateDownload(ctx, downloadID, updates); err != nil {
+		s.logger.Errorf("Failed to update security report %s: %v", downloadID, err)
+	}
+}
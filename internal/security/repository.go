@@ -0,0 +1,126 @@
+package security
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Repository interface for security event operations
+type Repository interface {
+	CreateEvent(ctx context.Context, event *models.UserSecurityEvent) error
+	GetEvents(ctx context.Context, userID string, filter EventFilter) ([]models.UserSecurityEvent, int64, error)
+
+	// Report methods
+	CreateDownload(ctx context.Context, download *models.SecurityEventDownload) error
+	GetDownloadByID(ctx context.Context, downloadID string) (*models.SecurityEventDownload, error)
+	CountDownloadsByStatus(ctx context.Context, userID, status string) (int64, error)
+	UpdateDownload(ctx context.Context, downloadID string, updates map[string]interface{}) error
+	GetUserEmail(ctx context.Context, userID string) (string, error)
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new security event repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// CreateEvent records a security event
+func (r *repo) CreateEvent(ctx context.Context, event *models.UserSecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// GetEvents gets a page of a user's security events matching the filter, newest first, along with
+// the number of events matching the filter across all pages
+func (r *repo) GetEvents(ctx context.Context, userID string, filter EventFilter) ([]models.UserSecurityEvent, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.UserSecurityEvent{}).
+		Where("user_id = ?", userID)
+	if len(filter.EventTypes) > 0 {
+		query = query.Where("event_type IN ?", filter.EventTypes)
+	}
+	if filter.Since > 0 {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if filter.Until > 0 {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.UserSecurityEvent
+	err := query.
+		Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// CreateDownload records a requested security event report
+func (r *repo) CreateDownload(ctx context.Context, download *models.SecurityEventDownload) error {
+	return r.db.WithContext(ctx).Create(download).Error
+}
+
+// GetDownloadByID retrieves a security event report
+func (r *repo) GetDownloadByID(ctx context.Context, downloadID string) (*models.SecurityEventDownload, error) {
+	var download models.SecurityEventDownload
+	err := r.db.WithContext(ctx).Where("id = ?", downloadID).First(&download).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return &download, nil
+}
+
+// CountDownloadsByStatus counts a user's security event reports in a status
+func (r *repo) CountDownloadsByStatus(ctx context.Context, userID, status string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.SecurityEventDownload{}).
+		Where("user_id = ? AND status = ?", userID, status).
+		Count(&count).Error
+	return count, err
+}
+
+// UpdateDownload updates the status, progress or result of a security event report
+func (r *repo) UpdateDownload(ctx context.Context, downloadID string, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).
+		Model(&models.SecurityEventDownload{}).
+		Where("id = ?", downloadID).
+		Updates(updates).Error
+}
+
+// GetUserEmail retrieves the address a user is notified at
+func (r *repo) GetUserEmail(ctx context.Context, userID string) (string, error) {
+	var emails []string
+	err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Limit(1).
+		Pluck("email", &emails).Error
+	if err != nil {
+		return "", err
+	}
+	if len(emails) == 0 {
+		return "", ErrInvalidInput
+	}
+	return emails[0], nil
+}
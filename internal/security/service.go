@@ -0,0 +1,117 @@
+package security
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Security event types recorded by the services that perform the action
+const (
+	EVENT_LOGIN_SUCCEEDED            = "login_succeeded"
+	EVENT_LOGIN_FAILED               = "login_failed"
+	EVENT_PASSWORD_CHANGED           = "password_changed"
+	EVENT_PASSWORD_RESET             = "password_reset"
+	EVENT_EMAIL_CHANGED              = "email_changed"
+	EVENT_MFA_ENABLED                = "mfa_enabled"
+	EVENT_MFA_DISABLED               = "mfa_disabled"
+	EVENT_SESSION_REVOKED            = "session_revoked"
+	EVENT_SHARE_PERMISSION_CHANGED   = "share_permission_changed"
+	EVENT_SHARE_LOCKED               = "share_locked"
+	EVENT_SHARE_UNLOCKED             = "share_unlocked"
+	EVENT_OAUTH_AUTHORIZED           = "oauth_authorized"
+	EVENT_OAUTH_REVOKED              = "oauth_revoked"
+	EVENT_ACCOUNT_DELETION_SCHEDULED = "account_deletion_scheduled"
+	EVENT_ACCOUNT_DELETION_CANCELLED = "account_deletion_cancelled"
+	EVENT_ACCOUNT_LOCKED             = "account_locked"
+	EVENT_ACCOUNT_UNLOCKED           = "account_unlocked"
+	EVENT_SUSPICIOUS_LOGIN           = "suspicious_login"
+	EVENT_KEY_ROTATION_STARTED       = "key_rotation_started"
+	EVENT_KEY_ROTATION_COMPLETED     = "key_rotation_completed"
+)
+
+// Page sizes of event listings
+const (
+	DEFAULT_EVENTS_LIMIT = 50
+	MAX_EVENTS_LIMIT     = 200
+)
+
+// NewService creates a new security event service
+func NewService(repo Repository, logger *logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Record writes a security event. Recording never fails the action it describes: errors are logged,
+// and the write outlives a cancelled request so actions that completed are still recorded. A nil
+// service records nothing, so services that were not given one need no checks.
+func (s *Service) Record(ctx context.Context, event Event) {
+	if s == nil || event.UserID == "" || event.EventType == "" {
+		return
+	}
+
+	metadata := event.Metadata
+	if event.IPAddress != "" {
+		metadata = make(map[string]any, len(event.Metadata)+1)
+		for key, value := range event.Metadata {
+			metadata[key] = value
+		}
+		metadata["ipAddress"] = event.IPAddress
+	}
+
+	record := &models.UserSecurityEvent{
+		ID:        utils.GenerateID(),
+		UserID:    event.UserID,
+		EventType: event.EventType,
+		Success:   event.Success,
+	}
+	if len(metadata) > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			s.logger.Errorf("Failed to encode %s security event metadata: %v", event.EventType, err)
+		} else {
+			record.AdditionalMetadata = encoded
+		}
+	}
+
+	if err := s.repo.CreateEvent(context.WithoutCancel(ctx), record); err != nil {
+		s.logger.Errorf("Failed to record %s security event for user %s: %v", event.EventType, event.UserID, err)
+	}
+}
+
+// ListEvents returns a page of the user's security events matching the filter, newest first
+func (s *Service) ListEvents(ctx context.Context, userID string, filter EventFilter) (*EventPage, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+	if filter.Since > 0 && filter.Until > 0 && filter.Since >= filter.Until {
+		return nil, ErrInvalidTimeRange
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DEFAULT_EVENTS_LIMIT
+	}
+	if filter.Limit > MAX_EVENTS_LIMIT {
+		filter.Limit = MAX_EVENTS_LIMIT
+	}
+
+	events, total, err := s.repo.GetEvents(ctx, userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load security events: %w", err)
+	}
+
+	return &EventPage{Events: events, Page: filter.Page, Limit: filter.Limit, Total: total}, nil
+}
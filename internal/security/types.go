@@ -0,0 +1,63 @@
+package security
+
+import (
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"context"
+	"time"
+)
+
+// Service records security relevant account activity and lets users review it
+type Service struct {
+	repo       Repository
+	logger     *logger.Logger
+	jobService *jobs.Service
+	storage    ReportStorage
+	mailer     ReportMailer
+}
+
+// ReportStorage stores generated reports and hands out temporary download links to them
+type ReportStorage interface {
+	PutObject(ctx context.Context, key, contentType string, body []byte) error
+	GetDownloadPresignedURL(key string, expiresIn time.Duration) (string, error)
+}
+
+// ReportMailer tells users that a report they requested is ready
+type ReportMailer interface {
+	SendSecurityReportEmail(email, downloadID string, expiresAt int64) error
+}
+
+// ExportRequest describes a security event report to generate
+type ExportRequest struct {
+	Format     string
+	EventTypes []string
+	Since      int64
+	Until      int64 // Exclusive, defaults to the time of the request
+}
+
+// Event is a security relevant action on an account
+type Event struct {
+	UserID    string
+	EventType string
+	Success   bool
+	IPAddress string         // Address the action came from, when known
+	Metadata  map[string]any // Details of the action, never secrets
+}
+
+// EventFilter narrows a query of a user's security events. Times are Unix seconds.
+type EventFilter struct {
+	EventTypes []string
+	Since      int64
+	Until      int64 // Exclusive upper bound on CreatedAt
+	Page       int   // 1-based
+	Limit      int
+}
+
+// EventPage is one page of a user's security events
+type EventPage struct {
+	Events []models.UserSecurityEvent
+	Page   int
+	Limit  int
+	Total  int64 // Events matching the filter across all pages
+}
@@ -0,0 +1,41 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FREE_PLAN_ID groups users without an active paid plan in session analytics
+const FREE_PLAN_ID = "free"
+
+// Concurrent session analytics limits
+const (
+	MIN_CONCURRENCY_WINDOW = 5 * time.Minute
+	MAX_CONCURRENCY_WINDOW = 30 * 24 * time.Hour
+	maxHistogramSessions   = 10 // Users with more sessions share the last histogram bucket
+)
+
+// GetConcurrentSessionStats reports how many valid sessions users used within the window, per plan
+// and as a histogram, to inform per-plan session limits. A zero window uses the configured default.
+func (s *Service) GetConcurrentSessionStats(ctx context.Context, window time.Duration) (*ConcurrentSessionStats, error) {
+	if window == 0 {
+		window = s.config.ConcurrencyWindow
+	}
+	if window < MIN_CONCURRENCY_WINDOW || window > MAX_CONCURRENCY_WINDOW {
+		return nil, ErrInvalidWindow
+	}
+
+	now := time.Now()
+	plans, histogram, err := s.repo.GetConcurrentSessionStats(ctx, now.Add(-window).Unix(), now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute concurrent session stats: %w", err)
+	}
+
+	return &ConcurrentSessionStats{
+		Window:      int64(window.Seconds()),
+		GeneratedAt: now.Unix(),
+		Plans:       plans,
+		Histogram:   histogram,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cirrussync-api/internal/jobs"
+	"cirrussync-api/internal/models"
+)
+
+// JOB_TYPE_SESSION_CLEANUP deletes stale sessions
+const JOB_TYPE_SESSION_CLEANUP = "session.cleanup"
+
+// SetJobService registers the job that deletes stale sessions
+func (s *Service) SetJobService(jobService *jobs.Service) {
+	s.jobService = jobService
+	jobService.Register(JOB_TYPE_SESSION_CLEANUP, s.runCleanupJob)
+}
+
+// StartCleanupScheduler queues a cleanup job every cleanup interval until ctx is cancelled
+func (s *Service) StartCleanupScheduler(ctx context.Context) {
+	if s.jobService == nil {
+		return
+	}
+
+	s.jobService.Schedule(ctx, "session_cleanup_pass", s.config.CleanupInterval, JOB_TYPE_SESSION_CLEANUP, nil)
+}
+
+// runCleanupJob deletes stale sessions. Deleted sessions are not found again, so an interrupted run
+// continues with whatever is left.
+func (s *Service) runCleanupJob(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) error {
+	deleted, err := s.DeleteStaleSessions(ctx)
+	if deleted > 0 {
+		s.logger.Infof("Deleted %d stale sessions", deleted)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete stale sessions: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStaleSessions deletes sessions that expired or were revoked more than the retention period
+// ago, and sessions left unused for longer than the inactivity timeout. Deletes run in batches so
+// no statement holds locks on the sessions table for long. Returns how many sessions were deleted.
+func (s *Service) DeleteStaleSessions(ctx context.Context) (int, error) {
+	now := time.Now()
+	criteria := StaleSessionCriteria{
+		ExpiredBefore:  now.Add(-s.config.ExpiredRetention).Unix(),
+		InactiveBefore: now.Add(-s.config.InactiveTimeout).Unix(),
+	}
+	// Idle sessions are found through the same index range as expired ones
+	if criteria.InactiveBefore > criteria.ExpiredBefore {
+		criteria.InactiveBefore = criteria.ExpiredBefore
+	}
+
+	total := 0
+	for ctx.Err() == nil {
+		deleted, err := s.repo.DeleteStaleSessions(ctx, criteria, s.config.CleanupBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += len(deleted)
+
+		// Idle sessions may still be cached as valid
+		for _, session := range deleted {
+			_ = s.invalidateSessionCache(ctx, session.ID, session.UserID)
+		}
+
+		if len(deleted) < s.config.CleanupBatchSize {
+			break
+		}
+	}
+
+	return total, ctx.Err()
+}
@@ -25,4 +25,7 @@ var (
 
 	// ErrUnauthorized indicates the user is not authorized to access the session
 	ErrUnauthorized = errors.New("Unauthorized access to session")
+
+	// ErrInvalidWindow indicates a session analytics window outside the allowed range
+	ErrInvalidWindow = errors.New("Window must be between 5 minutes and 30 days")
 )
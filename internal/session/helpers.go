@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 )
 
 // redisKeyForSession generates a Redis key for a session
@@ -13,6 +12,11 @@ func redisKeyForSession(sessionID string) string {
 	return fmt.Sprintf("session:%s", sessionID)
 }
 
+// redisKeyForRevokedSession generates a Redis key marking a session as revoked
+func redisKeyForRevokedSession(sessionID string) string {
+	return fmt.Sprintf("session:revoked:%s", sessionID)
+}
+
 // redisKeyForUserSessions generates a Redis key for a user's sessions list
 func redisKeyForUserSessions(userID string) string {
 	return fmt.Sprintf("user:%s:sessions", userID)
@@ -27,9 +31,8 @@ func (s *Service) cacheSession(ctx context.Context, session *models.UserSession)
 		return ErrCacheError
 	}
 
-	// Cache with an hour expiration
 	sessionKey := redisKeyForSession(session.ID)
-	err = s.redisClient.Set(ctx, sessionKey, string(sessionJSON), time.Hour)
+	err = s.redisClient.Set(ctx, sessionKey, string(sessionJSON), sessionCacheExpiry)
 	if err != nil {
 		s.logger.Error("Failed to cache session", "error", err)
 		return ErrCacheError
@@ -44,7 +47,7 @@ func (s *Service) cacheSession(ctx context.Context, session *models.UserSession)
 	}
 
 	// Set expiration on the user's sessions set
-	_, err = s.redisClient.Expire(ctx, userSessionsKey, time.Hour)
+	_, err = s.redisClient.Expire(ctx, userSessionsKey, sessionCacheExpiry)
 	if err != nil {
 		s.logger.Warn("Failed to set expiration on user's sessions set", "error", err)
 		// Not returning error as this is not critical
@@ -73,6 +76,21 @@ func (s *Service) getSessionFromCache(ctx context.Context, sessionID string) (*m
 	return &session, nil
 }
 
+// markSessionRevoked records in Redis that a session was revoked. Lookups check the marker before
+// the cache, so a copy cached by a request racing the revocation is never trusted. The marker only
+// has to outlive such copies; after that the database is authoritative.
+func (s *Service) markSessionRevoked(ctx context.Context, sessionID string) {
+	if err := s.redisClient.Set(ctx, redisKeyForRevokedSession(sessionID), "1", sessionCacheExpiry); err != nil {
+		s.logger.Warn("Failed to mark session as revoked", "sessionID", sessionID, "error", err)
+	}
+}
+
+// isSessionRevoked reports whether a session was revoked within the cache lifetime
+func (s *Service) isSessionRevoked(ctx context.Context, sessionID string) bool {
+	revoked, err := s.redisClient.Get(ctx, redisKeyForRevokedSession(sessionID))
+	return err == nil && revoked != ""
+}
+
 // invalidateSessionCache removes a session from Redis cache
 func (s *Service) invalidateSessionCache(ctx context.Context, sessionID string, userID string) error {
 	// Delete session key
@@ -67,6 +67,19 @@ func (r *repo) FindUserOneWhere(email *string, username *string) (*models.User,
 	return nil, gorm.ErrRecordNotFound
 }
 
+// GetUserSecuritySettings returns a user's security settings, or nil when they were never saved
+func (r *repo) GetUserSecuritySettings(userID string) (*models.UserSecuritySettings, error) {
+	var settings models.UserSecuritySettings
+	err := r.sessionRepo.DB().Where("user_id = ?", userID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
 // SaveSession creates or updates a session
 func (r *repo) SaveSession(session *models.UserSession) error {
 	// Check if session exists
@@ -106,3 +119,72 @@ func (r *repo) UpdateSession(session *models.UserSession) error {
 func (r *repo) DeleteSession(sessionID string) error {
 	return r.sessionRepo.Delete(context.Background(), sessionID)
 }
+
+// DeleteStaleSessions deletes up to limit sessions matching the criteria and returns them
+func (r *repo) DeleteStaleSessions(ctx context.Context, criteria StaleSessionCriteria, limit int) ([]DeletedSession, error) {
+	var deleted []DeletedSession
+	err := r.sessionRepo.DB().WithContext(ctx).Raw(`
+		DELETE FROM users_sessions
+		WHERE id IN (
+			SELECT id FROM users_sessions
+			WHERE last_active < ?
+				AND (expires_at < ? OR is_valid = false OR last_active < ?)
+			LIMIT ?
+		)
+		RETURNING id, user_id`,
+		criteria.ExpiredBefore, criteria.ExpiredBefore, criteria.InactiveBefore, limit,
+	).Scan(&deleted).Error
+	return deleted, err
+}
+
+// GetConcurrentSessionStats counts the valid sessions each user used since activeSince and
+// summarizes the counts per plan and as a histogram capped at maxHistogramSessions
+func (r *repo) GetConcurrentSessionStats(ctx context.Context, activeSince, now int64) ([]PlanSessionStats, []SessionCountBucket, error) {
+	const activeSessions = `
+		WITH active AS (
+			SELECT user_id, COUNT(*) AS sessions
+			FROM users_sessions
+			WHERE is_valid = true AND expires_at > ? AND last_active >= ?
+			GROUP BY user_id
+		)`
+
+	var plans []PlanSessionStats
+	err := r.sessionRepo.DB().WithContext(ctx).Raw(activeSessions+`
+		SELECT
+			COALESCE(plan.plan_id, ?) AS plan_id,
+			COUNT(*) AS users,
+			SUM(active.sessions) AS sessions,
+			AVG(active.sessions) AS average,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY active.sessions) AS p50,
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY active.sessions) AS p90,
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY active.sessions) AS p99,
+			MAX(active.sessions) AS max
+		FROM active
+		LEFT JOIN LATERAL (
+			SELECT plan_id FROM users_plans
+			WHERE users_plans.user_id = active.user_id AND users_plans.status = 'active'
+			ORDER BY current_period_start DESC
+			LIMIT 1
+		) plan ON true
+		GROUP BY 1
+		ORDER BY users DESC`,
+		now, activeSince, FREE_PLAN_ID,
+	).Scan(&plans).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var histogram []SessionCountBucket
+	err = r.sessionRepo.DB().WithContext(ctx).Raw(activeSessions+`
+		SELECT LEAST(sessions, ?) AS sessions, COUNT(*) AS users
+		FROM active
+		GROUP BY 1
+		ORDER BY 1`,
+		now, activeSince, maxHistogramSessions,
+	).Scan(&histogram).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plans, histogram, nil
+}
@@ -6,25 +6,41 @@ import (
 
 	"cirrussync-api/internal/logger"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
 	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/config"
 	"cirrussync-api/pkg/redis"
 )
 
+// sessionCacheExpiry is how long sessions stay cached in Redis
+const sessionCacheExpiry = time.Hour
+
 // NewService creates a new session service
-func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger) *Service {
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger, cfg *config.SessionConfig) *Service {
 	return &Service{
 		repo:        repo,
 		redisClient: redisClient,
 		logger:      logger,
+		config:      cfg,
 	}
 }
 
+// SetSecurityEvents records session revocations as security events of the session owner
+func (s *Service) SetSecurityEvents(events *security.Service) {
+	s.securityEvents = events
+}
+
 // IsSessionValid checks if a session is valid
 func (s *Service) IsSessionValid(ctx context.Context, sessionID string) bool {
 	if sessionID == "" {
 		return false
 	}
 
+	// Revoked sessions are rejected before any cached copy is consulted
+	if s.isSessionRevoked(ctx, sessionID) {
+		return false
+	}
+
 	// Try to get from cache first
 	session, err := s.getSessionFromCache(ctx, sessionID)
 	if err == nil {
@@ -61,6 +77,7 @@ func (s *Service) InvalidateSession(ctx context.Context, sessionID string) error
 	}
 
 	// Always invalidate cache regardless of database result
+	s.markSessionRevoked(ctx, sessionID)
 	_ = s.invalidateSessionCache(ctx, sessionID, userID)
 
 	// Get from database
@@ -100,6 +117,7 @@ func (s *Service) CreateSession(ctx context.Context, user *models.User, deviceIn
 		ModifiedAt: time.Now().Unix(),
 		IsValid:    true,
 	}
+	s.locateSession(session)
 
 	// Save session to database
 	err := s.repo.SaveSession(session)
@@ -115,6 +133,9 @@ func (s *Service) CreateSession(ctx context.Context, user *models.User, deviceIn
 		// Not returning error as session is already saved to database
 	}
 
+	// Comparing the sign-in with earlier ones and emailing the user must not hold up the login
+	go s.detectSuspiciousLogin(context.WithoutCancel(ctx), session)
+
 	return session, nil
 }
 
@@ -124,6 +145,10 @@ func (s *Service) GetSessionByID(ctx context.Context, sessionID string) (*models
 		return nil, ErrInvalidInput
 	}
 
+	if s.isSessionRevoked(ctx, sessionID) {
+		return nil, ErrSessionInvalid
+	}
+
 	// Try to get from cache first
 	session, err := s.getSessionFromCache(ctx, sessionID)
 	if err == nil {
@@ -253,17 +278,26 @@ func (s *Service) InvalidateAllUserSessions(ctx context.Context, userID string)
 	_ = s.invalidateUserSessionsCache(ctx, userID)
 
 	// Update each session in the database
+	revoked := 0
 	now := time.Now().Unix()
 	for _, session := range sessions {
+		if !session.IsValid {
+			continue
+		}
+		s.markSessionRevoked(ctx, session.ID)
 		session.IsValid = false
 		session.ModifiedAt = now
 		err := s.repo.SaveSession(session)
 		if err != nil {
 			s.logger.Error("Failed to invalidate user session", "sessionID", session.ID, "error", err)
 			// Continue with other sessions
+			continue
 		}
+		revoked++
 	}
 
+	s.recordRevocation(ctx, userID, map[string]any{"scope": "all", "count": revoked})
+
 	return nil
 }
 
@@ -280,10 +314,12 @@ func (s *Service) InvalidateSessionsByDevice(ctx context.Context, userID string,
 	}
 
 	// Update each matching session
+	revoked := 0
 	now := time.Now().Unix()
 	for _, session := range sessions {
-		if session.DeviceID == deviceID {
+		if session.DeviceID == deviceID && session.IsValid {
 			// Invalidate in cache
+			s.markSessionRevoked(ctx, session.ID)
 			_ = s.invalidateSessionCache(ctx, session.ID, userID)
 
 			// Update in database
@@ -293,9 +329,80 @@ func (s *Service) InvalidateSessionsByDevice(ctx context.Context, userID string,
 			if err != nil {
 				s.logger.Error("Failed to invalidate device session", "sessionID", session.ID, "error", err)
 				// Continue with other sessions
+				continue
 			}
+			revoked++
 		}
 	}
 
+	s.recordRevocation(ctx, userID, map[string]any{"scope": "device", "deviceId": deviceID, "count": revoked})
+
 	return nil
 }
+
+// RevokeUserSession revokes one of the user's own sessions. Sessions of other users are
+// reported as not found so their IDs cannot be probed.
+func (s *Service) RevokeUserSession(ctx context.Context, userID, sessionID string) error {
+	if userID == "" || sessionID == "" {
+		return ErrInvalidInput
+	}
+
+	session, err := s.repo.GetSession(sessionID)
+	if err != nil || session.UserID != userID || !session.IsValid {
+		return ErrSessionNotFound
+	}
+
+	if err := s.InvalidateSession(ctx, sessionID); err != nil {
+		return err
+	}
+
+	s.recordRevocation(ctx, userID, map[string]any{"scope": "session", "sessionId": sessionID, "deviceName": session.DeviceName})
+
+	return nil
+}
+
+// RevokeOtherUserSessions revokes every active session of the user except the one in use,
+// returning how many were revoked
+func (s *Service) RevokeOtherUserSessions(ctx context.Context, userID, currentSessionID string) (int, error) {
+	if userID == "" || currentSessionID == "" {
+		return 0, ErrInvalidInput
+	}
+
+	sessions, err := s.repo.GetAllSessionsByUserID(userID)
+	if err != nil {
+		return 0, ErrDatabaseError
+	}
+
+	revoked := 0
+	now := time.Now().Unix()
+	for _, session := range sessions {
+		if session.ID == currentSessionID || !session.IsValid {
+			continue
+		}
+
+		s.markSessionRevoked(ctx, session.ID)
+		_ = s.invalidateSessionCache(ctx, session.ID, userID)
+
+		session.IsValid = false
+		session.ModifiedAt = now
+		if err := s.repo.UpdateSession(session); err != nil {
+			s.logger.Error("Failed to revoke user session", "sessionID", session.ID, "error", err)
+			continue
+		}
+		revoked++
+	}
+
+	s.recordRevocation(ctx, userID, map[string]any{"scope": "others", "count": revoked})
+
+	return revoked, nil
+}
+
+// recordRevocation records sessions of a user being revoked
+func (s *Service) recordRevocation(ctx context.Context, userID string, metadata map[string]any) {
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    userID,
+		EventType: security.EVENT_SESSION_REVOKED,
+		Success:   true,
+		Metadata:  metadata,
+	})
+}
@@ -0,0 +1,155 @@
+package session
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/pkg/geoip"
+	"context"
+	"math"
+	"strings"
+)
+
+const (
+	// Reasons a login is reported as suspicious
+	SUSPICIOUS_LOGIN_NEW_COUNTRY       = "new_country"
+	SUSPICIOUS_LOGIN_IMPOSSIBLE_TRAVEL = "impossible_travel"
+)
+
+// LoginAlertMailer sends the email that tells a user about a suspicious sign-in to their account
+type LoginAlertMailer interface {
+	SendNewSignInEmail(userID, deviceName, location, ipAddress string, signedInAt int64) error
+}
+
+// SetLocator configures the GeoIP database sessions are located with. Without it sessions have no
+// location and logins are never reported as suspicious.
+func (s *Service) SetLocator(locator *geoip.Reader) {
+	s.locator = locator
+}
+
+// SetLoginAlertMailer configures how users who enabled suspicious activity detection are told about
+// suspicious sign-ins
+func (s *Service) SetLoginAlertMailer(mailer LoginAlertMailer) {
+	s.alertMailer = mailer
+}
+
+// locateSession fills in where a new session signs in from
+func (s *Service) locateSession(session *models.UserSession) {
+	if s.locator == nil {
+		return
+	}
+
+	location := s.locator.Locate(session.IPAddress)
+	session.Country = location.Country
+	session.City = location.City
+	session.ASN = location.ASN
+	session.ASOrganization = location.ASOrganization
+	if location.HasCoordinates {
+		session.Latitude = &location.Latitude
+		session.Longitude = &location.Longitude
+	}
+}
+
+// detectSuspiciousLogin compares a new session with the earlier sessions of its user. A login from a
+// country none of them came from, or from too far away of the last used one to have travelled there
+// since, is recorded as a security event and emailed to users who enabled suspicious activity
+// detection. The first located session of a user only sets what later logins are compared with.
+func (s *Service) detectSuspiciousLogin(ctx context.Context, session *models.UserSession) {
+	if session.Country == "" {
+		return
+	}
+
+	sessions, err := s.repo.GetAllSessionsByUserID(session.UserID)
+	if err != nil {
+		s.logger.Errorf("Failed to load sessions of user %s to check a new login: %v", session.UserID, err)
+		return
+	}
+
+	var previous *models.UserSession // Most recently used earlier session with coordinates
+	located, knownCountry := false, false
+	for _, other := range sessions {
+		if other.ID == session.ID || other.Country == "" {
+			continue
+		}
+		located = true
+		if other.Country == session.Country {
+			knownCountry = true
+		}
+		if other.Latitude != nil && other.Longitude != nil && (previous == nil || lastUsed(other) > lastUsed(previous)) {
+			previous = other
+		}
+	}
+	if !located {
+		return
+	}
+
+	var reasons []string
+	metadata := map[string]any{
+		"sessionId": session.ID,
+		"country":   session.Country,
+		"city":      session.City,
+	}
+	if !knownCountry {
+		reasons = append(reasons, SUSPICIOUS_LOGIN_NEW_COUNTRY)
+	}
+	if previous != nil && session.Latitude != nil && session.Longitude != nil {
+		distance := geoip.DistanceKm(*previous.Latitude, *previous.Longitude, *session.Latitude, *session.Longitude)
+		if s.isImpossibleTravel(distance, session.CreatedAt-lastUsed(previous)) {
+			reasons = append(reasons, SUSPICIOUS_LOGIN_IMPOSSIBLE_TRAVEL)
+			metadata["distanceKm"] = math.Round(distance)
+			metadata["previousCountry"] = previous.Country
+		}
+	}
+	if len(reasons) == 0 {
+		return
+	}
+	metadata["reasons"] = reasons
+
+	s.securityEvents.Record(ctx, security.Event{
+		UserID:    session.UserID,
+		EventType: security.EVENT_SUSPICIOUS_LOGIN,
+		Success:   true,
+		IPAddress: session.IPAddress,
+		Metadata:  metadata,
+	})
+
+	if s.alertMailer == nil {
+		return
+	}
+	settings, err := s.repo.GetUserSecuritySettings(session.UserID)
+	if err != nil {
+		s.logger.Errorf("Failed to load security settings of user %s: %v", session.UserID, err)
+		return
+	}
+	if settings == nil || !settings.SuspiciousActivityDetection {
+		return
+	}
+
+	if err := s.alertMailer.SendNewSignInEmail(session.UserID, session.DeviceName, describeLocation(session), session.IPAddress, session.CreatedAt); err != nil {
+		s.logger.Errorf("Failed to send new sign-in email to user %s: %v", session.UserID, err)
+	}
+}
+
+// isImpossibleTravel reports whether covering the distance in the elapsed seconds takes a faster
+// speed than the configured limit. Nearby locations are never compared, as GeoIP is imprecise.
+func (s *Service) isImpossibleTravel(distanceKm float64, elapsedSeconds int64) bool {
+	if distanceKm < float64(s.config.ImpossibleTravelMinDistance) {
+		return false
+	}
+	hours := math.Max(float64(elapsedSeconds), 1) / 3600
+	return distanceKm/hours > float64(s.config.ImpossibleTravelSpeed)
+}
+
+// lastUsed returns when a session was last used, falling back to when it was created
+func lastUsed(session *models.UserSession) int64 {
+	return max(session.LastActive, session.CreatedAt)
+}
+
+// describeLocation names where a session signed in from, e.g. "Berlin, DE"
+func describeLocation(session *models.UserSession) string {
+	parts := make([]string, 0, 2)
+	if session.City != "" {
+		parts = append(parts, session.City)
+	}
+	parts = append(parts, session.Country)
+	return strings.Join(parts, ", ")
+}
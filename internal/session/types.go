@@ -1,9 +1,15 @@
 package session
 
 import (
+	"context"
+
+	"cirrussync-api/internal/jobs"
 	"cirrussync-api/internal/logger"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/security"
+	"cirrussync-api/pkg/config"
 	"cirrussync-api/pkg/db"
+	"cirrussync-api/pkg/geoip"
 	"cirrussync-api/pkg/redis"
 )
 
@@ -12,6 +18,12 @@ type Service struct {
 	repo        Repository
 	redisClient *redis.Client
 	logger      *logger.Logger
+	config      *config.SessionConfig
+
+	securityEvents *security.Service
+	locator        *geoip.Reader // nil when no GeoIP database is configured
+	alertMailer    LoginAlertMailer
+	jobService     *jobs.Service
 }
 
 // Repository defines the session repository interface
@@ -23,8 +35,13 @@ type Repository interface {
 	UpdateSession(session *models.UserSession) error
 	DeleteSession(sessionID string) error
 
+	// Cleanup and analytics
+	DeleteStaleSessions(ctx context.Context, criteria StaleSessionCriteria, limit int) ([]DeletedSession, error)
+	GetConcurrentSessionStats(ctx context.Context, activeSince, now int64) ([]PlanSessionStats, []SessionCountBucket, error)
+
 	// User operations
 	FindUserByID(id string) (*models.User, error)
+	GetUserSecuritySettings(userID string) (*models.UserSecuritySettings, error)
 	FindUserOneWhere(email *string, username *string) (*models.User, error)
 	UpdateUser(user *models.User) (*models.User, error)
 }
@@ -51,3 +68,42 @@ type SessionValidator interface {
 
 // sessionValidator is the concrete implementation of SessionValidator
 type sessionValidator struct{}
+
+// StaleSessionCriteria decides which sessions the cleanup deletes. Every stale session was last
+// used before ExpiredBefore, which keeps the lookup on idx_sessions_inactive.
+type StaleSessionCriteria struct {
+	ExpiredBefore  int64 // Expired or revoked sessions last used before this are deleted
+	InactiveBefore int64 // Sessions last used before this are deleted even if still valid
+}
+
+// DeletedSession identifies a session removed by the cleanup, so its cache entries can be dropped
+type DeletedSession struct {
+	ID     string
+	UserID string
+}
+
+// ConcurrentSessionStats describes how many sessions users keep in use at the same time
+type ConcurrentSessionStats struct {
+	Window      int64                // Seconds within which sessions counted as in use
+	GeneratedAt int64                // When the figures were computed
+	Plans       []PlanSessionStats   // Figures per plan, users without a paid plan as "free"
+	Histogram   []SessionCountBucket // Users by number of sessions in use, the last bucket open-ended
+}
+
+// PlanSessionStats summarizes the sessions in use by the users of one plan
+type PlanSessionStats struct {
+	PlanID   string
+	Users    int64
+	Sessions int64
+	Average  float64
+	P50      float64
+	P90      float64
+	P99      float64
+	Max      int64
+}
+
+// SessionCountBucket is the number of users with a given number of sessions in use
+type SessionCountBucket struct {
+	Sessions int64 // Sessions in use; the last bucket holds this many or more
+	Users    int64
+}
@@ -0,0 +1,12 @@
+package sms
+
+import "errors"
+
+// Common errors
+var (
+	ErrNotConfigured      = errors.New("SMS sending is not configured")
+	ErrUnknownProvider    = errors.New("Unknown SMS provider")
+	ErrMissingCredentials = errors.New("SMS provider credentials are incomplete")
+	ErrInvalidPhoneNumber = errors.New("Phone number must be in international format, e.g. +14155552671")
+	ErrDeliveryFailed     = errors.New("Failed to send text message")
+)
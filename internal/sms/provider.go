@@ -0,0 +1,57 @@
+package sms
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"cirrussync-api/pkg/config"
+)
+
+// Supported providers
+const (
+	PROVIDER_TWILIO = "twilio"
+)
+
+// e164Regex matches a phone number in E.164 format
+var e164Regex = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Provider sends text messages. Implementations must be safe for concurrent use.
+type Provider interface {
+	// Send delivers body to a phone number in E.164 format
+	Send(ctx context.Context, to, body string) error
+}
+
+// NewProvider creates the provider selected in the configuration.
+// It returns ErrNotConfigured when no provider is selected.
+func NewProvider(cfg *config.SMSConfig) (Provider, error) {
+	switch strings.ToLower(cfg.SMSProvider) {
+	case "":
+		return nil, ErrNotConfigured
+	case PROVIDER_TWILIO:
+		return NewTwilioProvider(cfg)
+	}
+
+	return nil, ErrUnknownProvider
+}
+
+// NormalizePhoneNumber strips common formatting from a phone number and checks it is in E.164 format
+func NormalizePhoneNumber(phoneNumber string) (string, error) {
+	normalized := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(phoneNumber))
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + normalized[2:]
+	}
+
+	if !e164Regex.MatchString(normalized) {
+		return "", ErrInvalidPhoneNumber
+	}
+	return normalized, nil
+}
+
+// MaskPhoneNumber hides all but the last two digits of a phone number for display
+func MaskPhoneNumber(phoneNumber string) string {
+	if len(phoneNumber) <= 4 {
+		return phoneNumber
+	}
+	return phoneNumber[:2] + strings.Repeat("*", len(phoneNumber)-4) + phoneNumber[len(phoneNumber)-2:]
+}
@@ -0,0 +1,72 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cirrussync-api/pkg/config"
+)
+
+// twilioAPIBase is the Twilio REST API messages are created through
+const twilioAPIBase = "https://api.twilio.com/2010-04-01"
+
+// TwilioProvider sends text messages through the Twilio Messages API
+type TwilioProvider struct {
+	accountSID string
+	authToken  string
+	from       string
+	httpClient *http.Client
+}
+
+// NewTwilioProvider creates a Twilio provider, failing when its credentials are incomplete
+func NewTwilioProvider(cfg *config.SMSConfig) (*TwilioProvider, error) {
+	if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" || cfg.TwilioFromNumber == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	return &TwilioProvider{
+		accountSID: cfg.TwilioAccountSID,
+		authToken:  cfg.TwilioAuthToken,
+		from:       cfg.TwilioFromNumber,
+		httpClient: &http.Client{Timeout: cfg.SMSTimeout},
+	}, nil
+}
+
+// Send creates a Twilio message to a phone number
+func (p *TwilioProvider) Send(ctx context.Context, to, body string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("Body", body)
+	// Messaging service SIDs and phone numbers are passed in different fields
+	if strings.HasPrefix(p.from, "MG") {
+		form.Set("MessagingServiceSid", p.from)
+	} else {
+		form.Set("From", p.from)
+	}
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioAPIBase, url.PathEscape(p.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.accountSID, p.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDeliveryFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Twilio explains rejections in the body; keep it short for the logs
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: twilio responded with status %d: %s", ErrDeliveryFailed, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	return nil
+}
@@ -19,4 +19,5 @@ var (
 	ErrInvalidClientPublic = errors.New("invalid client public key")
 	ErrServerError         = errors.New("Internal server error, please try again later.")
 	ErrAuthFailed          = errors.New("Authentication failed")
+	ErrKeysIncomplete      = errors.New("Every active key must be re-encrypted for the new password")
 )
@@ -9,8 +9,6 @@ import (
 	"fmt"
 	"math/big"
 	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
 // Cryptographic helper functions starts
@@ -171,82 +169,3 @@ func (s *Service) generateFakeResponse() (*InitResponse, error) {
 		ServerPublic: B.Text(16),
 	}, nil
 }
-
-// recordFailedAttempt records a failed authentication attempt
-func (s *Service) recordFailedAttempt(ctx context.Context, email, ipAddress string) {
-	// Increment and set expiry for email counter
-	emailKey := fmt.Sprintf("srp:failed:%s", email)
-	emailAttemptsStr, err := s.redisClient.Get(ctx, emailKey)
-
-	var attempts int64 = 0
-	if err == nil && emailAttemptsStr != "" {
-		fmt.Sscanf(emailAttemptsStr, "%d", &attempts)
-	}
-
-	attempts++
-
-	// Set TTL based on number of attempts
-	var ttl time.Duration = 10 * time.Minute
-	if attempts >= 10 {
-		ttl = 24 * time.Hour
-		// Log suspicious activity
-		s.logger.WithFields(logrus.Fields{
-			"email": email,
-			"ip":    ipAddress,
-		}).Warn("Possible brute force attack detected")
-	} else if attempts >= 5 {
-		ttl = 2 * time.Hour
-	} else if attempts >= 3 {
-		ttl = 30 * time.Minute
-	}
-
-	s.redisClient.Set(ctx, emailKey, fmt.Sprintf("%d", attempts), ttl)
-
-	// Increment and set expiry for IP counter
-	ipKey := fmt.Sprintf("srp:failed:ip:%s", ipAddress)
-	ipAttemptsStr, err := s.redisClient.Get(ctx, ipKey)
-
-	var ipAttempts int64 = 0
-	if err == nil && ipAttemptsStr != "" {
-		fmt.Sscanf(ipAttemptsStr, "%d", &ipAttempts)
-	}
-
-	ipAttempts++
-
-	// Set TTL based on number of attempts
-	var ipTTL time.Duration = 10 * time.Minute
-	if ipAttempts >= 10 {
-		ipTTL = 24 * time.Hour
-		// Log suspicious IP
-		s.logger.WithField("ip", ipAddress).Warn("Possible IP-based attack detected")
-	} else if ipAttempts >= 5 {
-		ipTTL = 1 * time.Hour
-	}
-
-	s.redisClient.Set(ctx, ipKey, fmt.Sprintf("%d", ipAttempts), ipTTL)
-}
-
-// resetFailedAttempts resets the failed attempt counters after successful authentication
-func (s *Service) resetFailedAttempts(ctx context.Context, email, ipAddress string) {
-	// Reset email counter
-	emailKey := fmt.Sprintf("srp:failed:%s", email)
-	s.redisClient.Delete(ctx, emailKey)
-
-	// Don't reset IP counter completely to prevent distributed attacks
-	// Instead, decrement it to allow legitimate future attempts
-	ipKey := fmt.Sprintf("srp:failed:ip:%s", ipAddress)
-	ipAttemptsStr, err := s.redisClient.Get(ctx, ipKey)
-
-	var ipAttempts int64 = 0
-	if err == nil && ipAttemptsStr != "" {
-		fmt.Sscanf(ipAttemptsStr, "%d", &ipAttempts)
-
-		// Decrement by 2 to reward successful login
-		if ipAttempts > 2 {
-			ipAttempts -= 2
-			s.redisClient.Set(ctx, ipKey, fmt.Sprintf("%d", ipAttempts), 10*time.Minute)
-		} else {
-			s.redisClient.Delete(ctx, ipKey)
-		}
-	}
-}
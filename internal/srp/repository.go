@@ -15,6 +15,8 @@ type Repository interface {
 	UpdateUserSRP(userSRP *models.UserSRP) error
 	GetUserSRP(userID string) (*models.UserSRP, error)
 	GetUserSRPByEmail(email string) (*models.UserSRP, error)
+	ReplaceCredentials(ctx context.Context, userSRP *models.UserSRP, keys []KeyPassphrase) error
+	ChangeEmail(ctx context.Context, userSRP *models.UserSRP) error
 }
 
 // repo implements the Repository interface
@@ -61,3 +63,77 @@ func (r *repo) GetUserSRPByEmail(email string) (*models.UserSRP, error) {
 	}
 	return &userSRP, nil
 }
+
+// ReplaceCredentials stores new SRP credentials together with the key passphrases re-encrypted for
+// them, so the password and the keys it unlocks never get out of step. Every active key of the user
+// has to be replaced.
+func (r *repo) ReplaceCredentials(ctx context.Context, userSRP *models.UserSRP, keys []KeyPassphrase) error {
+	return r.baseRepo.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var activeKeys int64
+		if err := tx.Model(&models.UserKey{}).
+			Where("user_id = ? AND active = ?", userSRP.UserID, true).
+			Count(&activeKeys).Error; err != nil {
+			return err
+		}
+		if activeKeys != int64(len(keys)) {
+			return ErrKeysIncomplete
+		}
+
+		for _, key := range keys {
+			result := tx.Model(&models.UserKey{}).
+				Where("id = ? AND user_id = ? AND active = ?", key.KeyID, userSRP.UserID, true).
+				Updates(map[string]interface{}{
+					"passphrase":           key.Passphrase,
+					"passphrase_signature": key.PassphraseSignature,
+					"modified_at":          userSRP.ModifiedAt,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrKeysIncomplete
+			}
+		}
+
+		return tx.Model(&models.UserSRP{}).
+			Where("id = ?", userSRP.ID).
+			Updates(map[string]interface{}{
+				"salt":        userSRP.Salt,
+				"verifier":    userSRP.Verifier,
+				"version":     userSRP.Version,
+				"modified_at": userSRP.ModifiedAt,
+				"active":      true,
+			}).Error
+	})
+}
+
+// ChangeEmail moves an account to the email address of its SRP credentials together with the
+// credentials themselves, so the address the account signs in with and the one it is known by never
+// get out of step
+func (r *repo) ChangeEmail(ctx context.Context, userSRP *models.UserSRP) error {
+	return r.baseRepo.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ?", userSRP.UserID).
+			Updates(map[string]interface{}{
+				"email":          userSRP.Email,
+				"email_verified": true,
+				"modified_at":    userSRP.ModifiedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		return tx.Model(&models.UserSRP{}).
+			Where("id = ?", userSRP.ID).
+			Updates(map[string]interface{}{
+				"email":       userSRP.Email,
+				"salt":        userSRP.Salt,
+				"verifier":    userSRP.Verifier,
+				"version":     userSRP.Version,
+				"modified_at": userSRP.ModifiedAt,
+			}).Error
+	})
+}
@@ -65,6 +65,13 @@ type VerifyResponse struct {
 	ServerProof string `json:"server_proof"`
 }
 
+// KeyPassphrase is a key passphrase the client re-encrypted for new credentials
+type KeyPassphrase struct {
+	KeyID               string
+	Passphrase          string
+	PassphraseSignature string
+}
+
 // Service handles SRP authentication business logic
 type Service struct {
 	repo        Repository
@@ -89,15 +96,14 @@ func (s *Service) InitAuthentication(ctx context.Context, email, clientPublic, i
 		return nil, err
 	}
 
-	// Check rate limiting
-	if err := checkRateLimiting(ctx, s.redisClient, normalizedEmail, ipAddress); err != nil {
+	// Check rate limiting; failed logins of accounts and IP addresses are throttled by the auth lockout
+	if err := checkRateLimiting(ctx, s.redisClient); err != nil {
 		return nil, err
 	}
 
 	// Validate client public key
 	A, err := validateClientPublic(clientPublic)
 	if err != nil {
-		s.recordFailedAttempt(ctx, normalizedEmail, ipAddress)
 		return nil, err
 	}
 
@@ -105,7 +111,6 @@ func (s *Service) InitAuthentication(ctx context.Context, email, clientPublic, i
 	userSRP, err := s.repo.GetUserSRPByEmail(normalizedEmail)
 	if err != nil {
 		// Don't reveal user existence, return a fake response
-		s.recordFailedAttempt(ctx, normalizedEmail, ipAddress)
 		return s.generateFakeResponse()
 	}
 
@@ -170,6 +175,20 @@ func (s *Service) InitAuthentication(ctx context.Context, email, clientPublic, i
 	}, nil
 }
 
+// SessionUserID returns the user an authentication session was started for, or an empty string when
+// the session is unknown or was started for an email without an account
+func (s *Service) SessionUserID(ctx context.Context, sessionID string) string {
+	if validateSessionID(sessionID) != nil {
+		return ""
+	}
+
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return ""
+	}
+	return session.UserID
+}
+
 // VerifyAuthentication verifies the client proof and generates a server proof
 func (s *Service) VerifyAuthentication(ctx context.Context, sessionID, clientProof, ipAddress string) (*VerifyResponse, *models.UserSRP, error) {
 	// Validate session ID
@@ -198,14 +217,12 @@ func (s *Service) VerifyAuthentication(ctx context.Context, sessionID, clientPro
 			"email":      session.Email,
 		}).Warn("IP address mismatch during SRP verification")
 
-		s.recordFailedAttempt(ctx, session.Email, ipAddress)
 		return nil, nil, ErrInvalidSession
 	}
 
 	// Parse stored values
 	A, success := new(big.Int).SetString(session.ClientPublic, 16)
 	if !success {
-		s.recordFailedAttempt(ctx, session.Email, ipAddress)
 		return nil, nil, ErrInvalidClientPublic
 	}
 
@@ -224,7 +241,6 @@ func (s *Service) VerifyAuthentication(ctx context.Context, sessionID, clientPro
 
 	// Constant-time comparison to prevent timing attacks
 	if subtle.ConstantTimeCompare(expectedM1, clientProofBytes) != 1 {
-		s.recordFailedAttempt(ctx, session.Email, ipAddress)
 		return nil, nil, ErrInvalidClientProof
 	}
 
@@ -238,9 +254,6 @@ func (s *Service) VerifyAuthentication(ctx context.Context, sessionID, clientPro
 	// Calculate server proof: M2 = H(A | M1 | K)
 	serverProof := calculateServerProof(A, clientProofBytes, K)
 
-	// Authentication successful - reset failed attempts
-	s.resetFailedAttempts(ctx, session.Email, ipAddress)
-
 	// Delete the session
 	s.deleteSession(ctx, sessionID)
 
@@ -324,3 +337,93 @@ func (s *Service) GetUserSRPByID(userID string) (*models.UserSRP, error) {
 
 	return userSRP, nil
 }
+
+// GetUserSRPByEmail fetches the SRP credentials of the account with the given email
+func (s *Service) GetUserSRPByEmail(email string) (*models.UserSRP, error) {
+	normalizedEmail, err := validateEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	userSRP, err := s.repo.GetUserSRPByEmail(normalizedEmail)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return userSRP, nil
+}
+
+// ResetCredentials replaces a user's SRP credentials and the key passphrases encrypted for the old
+// password in one step. Unlike RegisterSRPCredentials it does not need the old credentials.
+func (s *Service) ResetCredentials(ctx context.Context, userSRP *models.UserSRP, salt, verifier string, keys []KeyPassphrase) error {
+	if err := ValidateCredentials(salt, verifier, keys); err != nil {
+		return err
+	}
+
+	userSRP.Salt = salt
+	userSRP.Verifier = verifier
+	userSRP.Version++
+	userSRP.ModifiedAt = time.Now().Unix()
+
+	if err := s.repo.ReplaceCredentials(ctx, userSRP, keys); err != nil {
+		if err == ErrKeysIncomplete {
+			return err
+		}
+		s.logger.Error("Failed to reset SRP credentials", err)
+		return ErrServerError
+	}
+
+	return nil
+}
+
+// ChangeEmail moves a user's account and SRP credentials to a new, already verified email address.
+// Clients may derive the verifier from the address as well as the password, so the change carries
+// credentials for the new address.
+func (s *Service) ChangeEmail(ctx context.Context, userSRP *models.UserSRP, email, salt, verifier string) error {
+	normalizedEmail, err := validateEmail(email)
+	if err != nil {
+		return err
+	}
+	if salt == "" || verifier == "" {
+		return ErrInvalidInput
+	}
+	if _, err := hex.DecodeString(verifier); err != nil {
+		return ErrInvalidInput
+	}
+
+	userSRP.Email = normalizedEmail
+	userSRP.Salt = salt
+	userSRP.Verifier = verifier
+	userSRP.Version++
+	userSRP.ModifiedAt = time.Now().Unix()
+
+	if err := s.repo.ChangeEmail(ctx, userSRP); err != nil {
+		if err == ErrUserNotFound {
+			return err
+		}
+		s.logger.Error("Failed to change email of SRP credentials", err)
+		return ErrServerError
+	}
+
+	return nil
+}
+
+// ValidateCredentials checks new SRP credentials and re-encrypted key passphrases before they are stored
+func ValidateCredentials(salt, verifier string, keys []KeyPassphrase) error {
+	if salt == "" || verifier == "" || len(keys) == 0 {
+		return ErrInvalidInput
+	}
+	if _, err := hex.DecodeString(verifier); err != nil {
+		return ErrInvalidInput
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.KeyID == "" || key.Passphrase == "" || key.PassphraseSignature == "" || seen[key.KeyID] {
+			return ErrInvalidInput
+		}
+		seen[key.KeyID] = true
+	}
+
+	return nil
+}
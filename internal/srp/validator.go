@@ -95,32 +95,8 @@ func isHexString(s string) bool {
 	return true
 }
 
-// checkRateLimiting checks if the authentication request should be rate limited
-func checkRateLimiting(ctx context.Context, redisClient *redis.Client, email, ipAddress string) error {
-	// Check rate limiting for email
-	emailKey := fmt.Sprintf("srp:failed:%s", email)
-	emailAttemptsStr, err := redisClient.Get(ctx, emailKey)
-
-	var emailAttempts int
-	if err == nil && emailAttemptsStr != "" {
-		fmt.Sscanf(emailAttemptsStr, "%d", &emailAttempts)
-		if emailAttempts >= 3 {
-			return ErrRateLimited
-		}
-	}
-
-	// Check rate limiting for IP address
-	ipKey := fmt.Sprintf("srp:failed:ip:%s", ipAddress)
-	ipAttemptsStr, err := redisClient.Get(ctx, ipKey)
-
-	var ipAttempts int
-	if err == nil && ipAttemptsStr != "" {
-		fmt.Sscanf(ipAttemptsStr, "%d", &ipAttempts)
-		if ipAttempts >= 5 {
-			return ErrRateLimited
-		}
-	}
-
+// checkRateLimiting slows down authentication when requests spike across all clients
+func checkRateLimiting(ctx context.Context, redisClient *redis.Client) error {
 	// Check global rate limiting (prevent distributed attacks)
 	globalKey := "srp:global:request_count"
 	countStr, err := redisClient.Get(ctx, globalKey)
@@ -0,0 +1,115 @@
+//go:build integration
+
+package testharness
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+)
+
+// Client sends JSON requests to the harness as one authenticated user
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	accessToken string
+	csrfToken   string
+}
+
+// csrfTokenResponse mirrors the CSRF endpoint's response body
+type csrfTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// NewClient creates a client for the harness. An empty access token makes anonymous requests.
+func (h *Harness) NewClient(t testing.TB, accessToken string) *Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("testharness: failed to create cookie jar: %v", err)
+	}
+
+	client := &Client{
+		baseURL:     h.BaseURL,
+		httpClient:  &http.Client{Jar: jar},
+		accessToken: accessToken,
+	}
+
+	// State-changing requests need a CSRF token paired with the cookie set here
+	var csrf csrfTokenResponse
+	if code := client.Do(t, http.MethodGet, "/api/v1/csrf", nil, &csrf); code != http.StatusOK {
+		t.Fatalf("testharness: failed to fetch CSRF token, status %d", code)
+	}
+	client.csrfToken = csrf.Token
+
+	return client
+}
+
+// Do sends a request with an optional JSON body and decodes the JSON response into out.
+// It returns the status code so suites can assert on contract-level behavior.
+func (c *Client) Do(t testing.TB, method, path string, body, out any) int {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("testharness: failed to encode %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		t.Fatalf("testharness: failed to build %s %s: %v", method, path, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+	if c.csrfToken != "" {
+		req.Header.Set("X-CSRF-Token", c.csrfToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("testharness: %s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+			t.Fatalf("testharness: failed to decode %s %s response: %v", method, path, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+// PutBlock uploads raw block data to a presigned storage URL
+func (c *Client) PutBlock(t testing.TB, uploadURL string, data []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPut, uploadURL, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("testharness: failed to build block upload: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("testharness: block upload failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("testharness: block upload returned status %d", resp.StatusCode)
+	}
+}
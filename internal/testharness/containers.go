@@ -0,0 +1,114 @@
+//go:build integration
+
+package testharness
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/minio"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// Container images, pinned so runs are reproducible
+const (
+	postgresImage = "postgres:16-alpine"
+	redisImage    = "redis:7-alpine"
+	minioImage    = "minio/minio:RELEASE.2024-01-16T16-07-38Z"
+)
+
+// Credentials for the throwaway dependencies
+const (
+	postgresDatabase = "cirrussync"
+	postgresUser     = "cirrussync"
+	postgresPassword = "cirrussync"
+	minioUser        = "minioadmin"
+	minioPassword    = "minioadmin"
+	minioBucket      = "cirrussync"
+)
+
+// dependencies holds the addresses of the running containers
+type dependencies struct {
+	postgresHost string
+	postgresPort string
+	redisHost    string
+	redisPort    string
+	minioURL     string
+	containers   []testcontainers.Container
+}
+
+// startDependencies starts Postgres, Redis and MinIO. Containers that did start are
+// returned even on error so the caller can terminate them.
+func startDependencies(ctx context.Context) (*dependencies, error) {
+	deps := &dependencies{}
+
+	pg, err := postgres.Run(ctx, postgresImage,
+		postgres.WithDatabase(postgresDatabase),
+		postgres.WithUsername(postgresUser),
+		postgres.WithPassword(postgresPassword),
+		postgres.BasicWaitStrategies(),
+	)
+	if pg != nil {
+		deps.containers = append(deps.containers, pg)
+	}
+	if err != nil {
+		return deps, fmt.Errorf("failed to start postgres: %w", err)
+	}
+	if deps.postgresHost, deps.postgresPort, err = hostPort(ctx, pg, "5432/tcp"); err != nil {
+		return deps, err
+	}
+
+	rd, err := tcredis.Run(ctx, redisImage)
+	if rd != nil {
+		deps.containers = append(deps.containers, rd)
+	}
+	if err != nil {
+		return deps, fmt.Errorf("failed to start redis: %w", err)
+	}
+	if deps.redisHost, deps.redisPort, err = hostPort(ctx, rd, "6379/tcp"); err != nil {
+		return deps, err
+	}
+
+	mn, err := minio.Run(ctx, minioImage,
+		minio.WithUsername(minioUser),
+		minio.WithPassword(minioPassword),
+	)
+	if mn != nil {
+		deps.containers = append(deps.containers, mn)
+	}
+	if err != nil {
+		return deps, fmt.Errorf("failed to start minio: %w", err)
+	}
+	address, err := mn.ConnectionString(ctx)
+	if err != nil {
+		return deps, fmt.Errorf("failed to get minio address: %w", err)
+	}
+	deps.minioURL = "http://" + address
+
+	return deps, nil
+}
+
+// terminate stops every started container
+func (d *dependencies) terminate() {
+	for _, container := range d.containers {
+		_ = testcontainers.TerminateContainer(container)
+	}
+}
+
+// hostPort returns the host and mapped port a container exposes a port on
+func hostPort(ctx context.Context, container testcontainers.Container, port nat.Port) (string, string, error) {
+	host, err := container.Host(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get container host: %w", err)
+	}
+
+	mapped, err := container.MappedPort(ctx, port)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get mapped port %s: %w", port, err)
+	}
+
+	return host, mapped.Port(), nil
+}
@@ -0,0 +1,20 @@
+//go:build integration
+
+// Package testharness runs the API end to end against throwaway Postgres, Redis and MinIO
+// containers so regression suites can drive full HTTP flows.
+//
+// The package is only compiled with the integration build tag and needs Docker:
+//
+//	go test -tags integration ./...
+//
+// A suite starts one harness and drives it through tenant clients:
+//
+//	h := testharness.New(t)
+//	alice := h.SignUpTenant(t, "alice")
+//	alice.CreateDrive(t)
+//	file := alice.UploadFile(t, alice.RootShareID, alice.RootFolderID, "report.pdf", data)
+//
+// SignUpTenant registers through the signup endpoint and logs in with SRP like a client.
+// SeedTenant skips both and writes the user to the database, for suites that only need an
+// authenticated account.
+package testharness
@@ -0,0 +1,131 @@
+//go:build integration
+
+package testharness
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+
+	driveAPI "cirrussync-api/api/v1/drive"
+)
+
+// Placeholder armored values. The API stores key material opaquely, so contract tests
+// do not need real OpenPGP output.
+const (
+	fakeArmored   = "-----BEGIN PGP MESSAGE-----\ntestharness\n-----END PGP MESSAGE-----"
+	fakeSignature = "-----BEGIN PGP SIGNATURE-----\ntestharness\n-----END PGP SIGNATURE-----"
+)
+
+// CreateDrive provisions the tenant's volume, root share and root folder through the API
+// and records the root share and folder IDs on the tenant.
+func (tn *Tenant) CreateDrive(t testing.TB) {
+	t.Helper()
+
+	req := driveAPI.CreateDriveRequest{
+		DriveVolume: driveAPI.DriveVolumeWrapper{Name: fakeArmored, Hash: hashOf("volume:" + tn.User.ID)},
+		DriveShare: driveAPI.DriveShareWrapper{
+			ShareKey:                 fakeArmored,
+			SharePassphrase:          fakeArmored,
+			SharePassphraseSignature: fakeSignature,
+		},
+		DriveShareMembership: driveAPI.DriveShareMembershipWrapper{
+			KeyPacket:           fakeArmored,
+			KeyPacketSignature:  fakeSignature,
+			SessionKeySignature: fakeSignature,
+		},
+	}
+	if code := tn.Do(t, http.MethodPost, "/api/v1/drive/volumes/create", req, nil); code != http.StatusCreated {
+		t.Fatalf("testharness: create drive returned status %d", code)
+	}
+
+	var shares driveAPI.SharesListResponse
+	if code := tn.Do(t, http.MethodGet, "/api/v1/drive/shares", nil, &shares); code != http.StatusOK || len(shares.Shares) == 0 {
+		t.Fatalf("testharness: listing shares after create returned status %d", code)
+	}
+
+	tn.RootShareID = shares.Shares[0].ID
+	tn.RootFolderID = shares.Shares[0].LinkId
+}
+
+// UploadFile registers a file, uploads its content as a single block and commits the revision
+func (tn *Tenant) UploadFile(t testing.TB, shareID, folderID, name string, data []byte) *driveAPI.DriveItemResponseData {
+	t.Helper()
+
+	contentHash := hashOf(string(data))
+	mimeType := "application/octet-stream"
+
+	var created driveAPI.CreateFileResponse
+	code := tn.Do(t, http.MethodPost, "/api/v1/drive/shares/"+shareID+"/files", driveAPI.CreateFileRequest{
+		Name:                    name,
+		Hash:                    hashOf(folderID + "/" + name),
+		ParentId:                &folderID,
+		MimeType:                &mimeType,
+		SignatureEmail:          tn.User.Email,
+		NodeKey:                 fakeArmored,
+		NodePassphrase:          fakeArmored,
+		NodePassphraseSignature: fakeSignature,
+		FileProperties: driveAPI.FilePropertiesWrapper{
+			ContentHash:         contentHash,
+			ContentKeyPacket:    fakeArmored,
+			ContentKeySignature: fakeSignature,
+		},
+	}, &created)
+	if code != http.StatusCreated {
+		t.Fatalf("testharness: create file returned status %d", code)
+	}
+
+	revisionPath := fmt.Sprintf("/api/v1/drive/shares/%s/files/%s/revisions/%s", shareID, created.File.ID, created.Revision.ID)
+
+	var uploads driveAPI.BlockUploadsResponse
+	code = tn.Do(t, http.MethodPost, revisionPath+"/blocks", driveAPI.RequestBlockUploadsRequest{
+		Blocks: []driveAPI.BlockUploadRequestItem{{Index: 0, Size: int64(len(data)), Hash: contentHash}},
+	}, &uploads)
+	if code != http.StatusOK || len(uploads.Blocks) != 1 {
+		t.Fatalf("testharness: request block uploads returned status %d", code)
+	}
+
+	tn.PutBlock(t, uploads.Blocks[0].UploadURL, data)
+
+	var committed driveAPI.FileResponse
+	code = tn.Do(t, http.MethodPost, revisionPath+"/commit", driveAPI.CommitRevisionRequest{
+		BlockCount:        1,
+		SignatureEmail:    tn.User.Email,
+		ManifestSignature: fakeSignature,
+	}, &committed)
+	if code != http.StatusOK {
+		t.Fatalf("testharness: commit revision returned status %d", code)
+	}
+
+	return committed.File
+}
+
+// InviteToShare invites another tenant to a share and has them accept the invitation
+func (tn *Tenant) InviteToShare(t testing.TB, shareID string, invitee *Tenant, permissions int) {
+	t.Helper()
+
+	var invitation driveAPI.InvitationResponse
+	code := tn.Do(t, http.MethodPost, "/api/v1/drive/shares/"+shareID+"/invitations", driveAPI.InviteShareMemberRequest{
+		Email:               invitee.User.Email,
+		Permissions:         permissions,
+		KeyPacket:           fakeArmored,
+		KeyPacketSignature:  fakeSignature,
+		SessionKeySignature: fakeSignature,
+	}, &invitation)
+	if code != http.StatusCreated {
+		t.Fatalf("testharness: invite returned status %d", code)
+	}
+
+	code = invitee.Do(t, http.MethodPost, "/api/v1/drive/invitations/"+invitation.Invitation.ID+"/accept", nil, nil)
+	if code != http.StatusOK {
+		t.Fatalf("testharness: accepting invitation returned status %d", code)
+	}
+}
+
+// hashOf returns a hex SHA-256 digest, standing in for client-computed name and content hashes
+func hashOf(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
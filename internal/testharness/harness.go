@@ -0,0 +1,200 @@
+//go:build integration
+
+package testharness
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cirrussync-api/internal/jwt"
+	log "cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/db"
+	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/s3"
+	"cirrussync-api/router"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// startupTimeout bounds how long containers and migrations may take
+const startupTimeout = 3 * time.Minute
+
+// Harness is a running API server backed by throwaway dependencies
+type Harness struct {
+	Server  *httptest.Server
+	BaseURL string
+	DB      *gorm.DB
+	Redis   *redis.Client
+	Storage *s3.Client
+
+	jwtService     *jwt.JWTService
+	sessionService *session.Service
+}
+
+// New starts the dependencies, migrates the schema and serves the full router.
+// Everything is torn down when the test finishes. The router's services are package
+// globals, so tests that start a harness must not run in parallel.
+func New(t testing.TB) *Harness {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), startupTimeout)
+	defer cancel()
+
+	deps, err := startDependencies(ctx)
+	t.Cleanup(deps.terminate)
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+
+	// The router loads its signing keys from ./keys, so run from a directory that has them
+	workDir := t.TempDir()
+	if err := writeSigningKeys(filepath.Join(workDir, "keys")); err != nil {
+		t.Fatalf("testharness: failed to write signing keys: %v", err)
+	}
+	t.Chdir(workDir)
+
+	setEnvironment(t, deps)
+	appConfig := config.LoadConfig()
+
+	if err := db.Initialize(appConfig.Database); err != nil {
+		t.Fatalf("testharness: failed to connect to postgres: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	migrationCfg := db.NewMigrationConfig()
+	migrationCfg.AutoMigrateModels = true
+	if err := db.RunMigrations(migrationCfg, models.AutoMigrateModels()...); err != nil {
+		t.Fatalf("testharness: failed to migrate: %v", err)
+	}
+
+	redis.InitDefault(appConfig.Redis)
+	t.Cleanup(redis.CloseAll)
+	if err := redis.GetDefault().Ping(ctx); err != nil {
+		t.Fatalf("testharness: failed to connect to redis: %v", err)
+	}
+
+	if err := createBucket(appConfig.S3); err != nil {
+		t.Fatalf("testharness: failed to create bucket: %v", err)
+	}
+	if err := s3.InitS3(appConfig.S3); err != nil {
+		t.Fatalf("testharness: failed to initialize storage: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine, err := router.SetupRouter(db.GetDB())
+	if err != nil {
+		t.Fatalf("testharness: failed to set up router: %v", err)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	t.Cleanup(stopJobs)
+	if err := router.StartBackgroundJobs(jobsCtx); err != nil {
+		t.Fatalf("testharness: failed to start background jobs: %v", err)
+	}
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	// Mint tokens the router accepts, using the same keys and issuer
+	jwtService, err := jwt.NewJWTService("./keys/private.pem", "./keys/public.pem", "app.cirrussync.me", time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("testharness: failed to load signing keys: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+
+	return &Harness{
+		Server: server,
+		// The CSRF cookie is scoped to localhost, so address the server by name
+		BaseURL:        strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+		DB:             db.GetDB(),
+		Redis:          redis.GetDefault(),
+		Storage:        s3.GetS3Client(),
+		jwtService:     jwtService,
+		sessionService: session.NewService(session.NewRepository(db.GetDB()), redis.GetDefault(), log.New(logger), config.LoadSessionConfig()),
+	}
+}
+
+// setEnvironment points the application configuration at the containers
+func setEnvironment(t testing.TB, deps *dependencies) {
+	env := map[string]string{
+		"ENVIRONMENT":           "test",
+		"DB_HOST":               deps.postgresHost,
+		"DB_PORT":               deps.postgresPort,
+		"DB_NAME":               postgresDatabase,
+		"DB_USERNAME":           postgresUser,
+		"DB_PASSWORD":           postgresPassword,
+		"DB_SSLMODE":            "disable",
+		"REDIS_HOST":            deps.redisHost,
+		"REDIS_PORT":            deps.redisPort,
+		"AWS_REGION":            "us-east-1",
+		"AWS_ACCESS_KEY_ID":     minioUser,
+		"AWS_SECRET_ACCESS_KEY": minioPassword,
+		"S3_ENDPOINT":           deps.minioURL,
+		"S3_DISABLE_SSL":        "true",
+		"S3_FORCE_PATH_STYLE":   "true",
+		"S3_BUCKET_NAME":        minioBucket,
+		"CSRF_SECRET":           "testharness-csrf-secret-32-bytes",
+		"CSRF_SECURE":           "false",
+		"SMTP_HOST":             "localhost",
+		"SMTP_PORT":             "1",
+		"EMAIL_MAX_ATTEMPTS":    "1",
+	}
+
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+// writeSigningKeys generates an Ed25519 key pair in the layout the JWT service expects
+func writeSigningKeys(dir string) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "private.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "public.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o644)
+}
+
+// createBucket creates the storage bucket the application writes blocks to
+func createBucket(cfg *config.S3Config) error {
+	conn, err := s3.NewS3Connection(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.CreateBucket(&awss3.CreateBucketInput{Bucket: aws.String(cfg.BucketName)})
+	return err
+}
@@ -0,0 +1,125 @@
+//go:build integration
+
+package testharness
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	authAPI "cirrussync-api/api/v1/auth"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/user"
+	"cirrussync-api/internal/utils"
+)
+
+// Tenant is a seeded user with an authenticated client
+type Tenant struct {
+	*Client
+	User         *models.User
+	RootShareID  string
+	RootFolderID string
+}
+
+// SignUpTenant registers a user through the signup endpoint and signs them in with SRP, the
+// way a client does. The user has no drive yet; call CreateDrive to provision one through the API.
+func (h *Harness) SignUpTenant(t testing.TB, handle string) *Tenant {
+	t.Helper()
+
+	anonymous := h.NewClient(t, "")
+	email := handle + "@testharness.example.com"
+	credentials := newSRPCredentials(t)
+
+	var signup authAPI.SignupResponse
+	code := anonymous.Do(t, http.MethodPost, "/api/v1/auth/signup", authAPI.SignupRequest{
+		Email:       email,
+		Username:    handle,
+		SRPSalt:     credentials.salt,
+		SRPVerifier: credentials.verifier,
+		Keys: user.UserKey{
+			Version:             1,
+			PublicKey:           fakeArmored,
+			PrivateKey:          fakeArmored,
+			Passphrase:          fakeArmored,
+			PassphraseSignature: fakeSignature,
+			Fingerprint:         hashOf("fingerprint:" + handle),
+		},
+	}, &signup)
+	if code != http.StatusCreated {
+		t.Fatalf("testharness: signup of %s returned status %d", handle, code)
+	}
+
+	login := credentials.startLogin(t)
+
+	var challenge authAPI.LoginInitResponse
+	code = anonymous.Do(t, http.MethodPost, "/api/v1/auth/login/init", authAPI.LoginInitRequest{
+		Email:        email,
+		ClientPublic: login.clientPublic(),
+	}, &challenge)
+	if code != http.StatusOK {
+		t.Fatalf("testharness: login init of %s returned status %d", handle, code)
+	}
+
+	var verified authAPI.LoginVerifyResponse
+	code = anonymous.Do(t, http.MethodPost, "/api/v1/auth/login/verify", authAPI.LoginVerifyRequest{
+		SessionID:   challenge.SessionID,
+		ClientProof: login.clientProof(t, challenge.ServerPublic),
+	}, &verified)
+	if code != http.StatusOK || verified.AccessToken == "" {
+		t.Fatalf("testharness: login verify of %s returned status %d without tokens", handle, code)
+	}
+
+	signedUp := &models.User{}
+	if err := h.DB.Where("id = ?", signup.User.ID).First(signedUp).Error; err != nil {
+		t.Fatalf("testharness: failed to load signed up user %s: %v", handle, err)
+	}
+
+	return &Tenant{
+		Client: h.NewClient(t, verified.AccessToken),
+		User:   signedUp,
+	}
+}
+
+// SeedTenant creates a verified user named after the given handle and signs them in.
+// The user has no drive yet; call CreateDrive to provision one through the API.
+func (h *Harness) SeedTenant(t testing.TB, handle string) *Tenant {
+	t.Helper()
+
+	ctx := context.Background()
+
+	userID := utils.GenerateUserID()
+	user := &models.User{
+		ID:            userID,
+		Username:      handle,
+		DisplayName:   handle,
+		Email:         handle + "@testharness.invalid",
+		EmailVerified: true,
+		Roles:         []string{"user"},
+		// The column is unique, so seeded users cannot all share an empty customer ID
+		StripeCustomerID: "cus_testharness_" + userID,
+	}
+	if err := h.DB.WithContext(ctx).Create(user).Error; err != nil {
+		t.Fatalf("testharness: failed to seed user %s: %v", handle, err)
+	}
+
+	userSession, err := h.sessionService.CreateSession(ctx, user, session.DeviceInfo{
+		ClientName: "testharness",
+		ClientUID:  "testharness-" + handle,
+		AppVersion: "test",
+		UserAgent:  "cirrussync-testharness",
+	}, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("testharness: failed to create session for %s: %v", handle, err)
+	}
+
+	tokens, err := h.jwtService.GenerateAuthTokens(*user, userSession.ID)
+	if err != nil {
+		t.Fatalf("testharness: failed to issue tokens for %s: %v", handle, err)
+	}
+
+	return &Tenant{
+		Client: h.NewClient(t, tokens.AccessToken),
+		User:   user,
+	}
+}
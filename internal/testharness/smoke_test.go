@@ -0,0 +1,51 @@
+//go:build integration
+
+package testharness
+
+import (
+	"net/http"
+	"testing"
+
+	driveAPI "cirrussync-api/api/v1/drive"
+	"cirrussync-api/internal/drive"
+)
+
+// TestSignupDriveUploadShare drives the core contract end to end: two users sign up, one
+// provisions a drive, uploads a file and shares the drive, and the other reads the file.
+func TestSignupDriveUploadShare(t *testing.T) {
+	h := New(t)
+
+	alice := h.SignUpTenant(t, "alice")
+	bob := h.SignUpTenant(t, "bob")
+
+	alice.CreateDrive(t)
+	if alice.RootShareID == "" || alice.RootFolderID == "" {
+		t.Fatalf("drive was created without a root share and folder")
+	}
+
+	data := []byte("quarterly report")
+	file := alice.UploadFile(t, alice.RootShareID, alice.RootFolderID, "report.pdf", data)
+	if file == nil || file.ID == "" {
+		t.Fatalf("upload returned no file")
+	}
+	if file.Size != int64(len(data)) {
+		t.Errorf("uploaded file size = %d, want %d", file.Size, len(data))
+	}
+
+	linkPath := "/api/v1/drive/shares/" + alice.RootShareID + "/links/" + file.ID
+
+	// Before the share is granted the file is not visible to bob
+	if code := bob.Do(t, http.MethodGet, linkPath, nil, nil); code == http.StatusOK {
+		t.Fatalf("bob read the file before it was shared")
+	}
+
+	alice.InviteToShare(t, alice.RootShareID, bob, drive.READ_PERMISSION)
+
+	var shared driveAPI.FileResponse
+	if code := bob.Do(t, http.MethodGet, linkPath, nil, &shared); code != http.StatusOK {
+		t.Fatalf("reading the shared file returned status %d", code)
+	}
+	if shared.File == nil || shared.File.ID != file.ID {
+		t.Fatalf("shared link returned a different item")
+	}
+}
@@ -0,0 +1,117 @@
+//go:build integration
+
+package testharness
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// srpPrimeHex is the 2048-bit SRP-6a group the server uses, with generator 2
+const srpPrimeHex = "AC6BDB41324A9A9BF166DE5E1389582FAF72B6651987EE07FC3192943DB56050A37329CBB4A099ED8193E0757767A13DD52312AB4B03310DCD7F48A9DA04FD50E8083969EDB767B0CF6095179A163AB3661A05FBD5FAAAE82918A9962F0B93B855F97993EC975EEAA80D740ADBF4FF747359D041D5C33EA71D281E446B14773BCA97B43A23FB801676BD207A436C6481F1D2B9078717461A5B9D32E688F87748544523B524B0D57D5EA77A2775D2ECFA032CFBDBF52FB3786160279004E57AE6AF874E7303CE53299CCC041C7BC308D82A5698F3A8D0C38271AE35F8E9DBFBB694B5C803D89F7AE435DE236D525F54759B65E372FCD68EF20FA7111F9E4AFF73"
+
+var (
+	srpN, _ = new(big.Int).SetString(srpPrimeHex, 16)
+	srpG    = big.NewInt(2)
+)
+
+// srpCredentials is the client side of an SRP account. Real clients derive the private value
+// from the password; the server never sees it, so the harness picks a random one.
+type srpCredentials struct {
+	salt     string
+	private  *big.Int
+	verifier string
+}
+
+// newSRPCredentials generates a salt, private value and matching verifier for signup
+func newSRPCredentials(t testing.TB) *srpCredentials {
+	t.Helper()
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		t.Fatalf("testharness: failed to generate SRP salt: %v", err)
+	}
+
+	x := randomSRPExponent(t)
+	v := new(big.Int).Exp(srpG, x, srpN)
+
+	return &srpCredentials{
+		salt:     hex.EncodeToString(salt),
+		private:  x,
+		verifier: hex.EncodeToString(v.Bytes()),
+	}
+}
+
+// srpLogin holds the client's ephemeral values for one login
+type srpLogin struct {
+	credentials *srpCredentials
+	a           *big.Int
+	A           *big.Int
+}
+
+// startLogin picks the ephemeral key pair sent with the login init request
+func (c *srpCredentials) startLogin(t testing.TB) *srpLogin {
+	t.Helper()
+
+	a := randomSRPExponent(t)
+	return &srpLogin{
+		credentials: c,
+		a:           a,
+		A:           new(big.Int).Exp(srpG, a, srpN),
+	}
+}
+
+// clientPublic returns A as the server expects it
+func (l *srpLogin) clientPublic() string {
+	return l.A.Text(16)
+}
+
+// clientProof computes M1 = H(A | B | K) from the server's public value
+func (l *srpLogin) clientProof(t testing.TB, serverPublic string) string {
+	t.Helper()
+
+	B, ok := new(big.Int).SetString(serverPublic, 16)
+	if !ok || new(big.Int).Mod(B, srpN).Sign() == 0 {
+		t.Fatalf("testharness: server returned an invalid SRP public value")
+	}
+
+	// k = H(N | PAD(g)), u = H(A | B)
+	nBytes := srpN.Bytes()
+	gBytes := make([]byte, len(nBytes))
+	srpG.FillBytes(gBytes)
+	k := new(big.Int).SetBytes(sha256Of(nBytes, gBytes))
+	u := new(big.Int).SetBytes(sha256Of(l.A.Bytes(), B.Bytes()))
+
+	// S = (B - k * g^x) ^ (a + u * x) % N
+	x := l.credentials.private
+	base := new(big.Int).Sub(B, new(big.Int).Mul(k, new(big.Int).Exp(srpG, x, srpN)))
+	base.Mod(base, srpN)
+	exponent := new(big.Int).Add(l.a, new(big.Int).Mul(u, x))
+	S := new(big.Int).Exp(base, exponent, srpN)
+
+	K := sha256Of(S.Bytes())
+	return hex.EncodeToString(sha256Of(l.A.Bytes(), B.Bytes(), K))
+}
+
+// randomSRPExponent returns a random secret exponent in [1, N-1]
+func randomSRPExponent(t testing.TB) *big.Int {
+	t.Helper()
+
+	n, err := rand.Int(rand.Reader, new(big.Int).Sub(srpN, big.NewInt(1)))
+	if err != nil {
+		t.Fatalf("testharness: failed to generate SRP secret: %v", err)
+	}
+	return n.Add(n, big.NewInt(1))
+}
+
+// sha256Of hashes the concatenation of parts
+func sha256Of(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
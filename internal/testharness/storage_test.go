@@ -0,0 +1,159 @@
+//go:build integration
+
+package testharness
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	log "cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm/clause"
+)
+
+// TestConcurrentStorageCommits charges storage from many goroutines at once, the way concurrent
+// upload commits do, and checks that no charge is lost and that the usage counter pre-checks
+// read agrees with the database.
+func TestConcurrentStorageCommits(t *testing.T) {
+	h := New(t)
+	ctx := context.Background()
+
+	alice := h.SeedTenant(t, "alice")
+	seedAllocation(t, h, alice.User.ID, 0)
+
+	quotaService := quota.NewService(quota.NewRepository(h.DB), h.Redis, log.New(logrus.New()))
+
+	// Seeds the usage counter, so the charges below are applied to it as well
+	if err := quotaService.Check(ctx, alice.User.ID, 0); err != nil {
+		t.Fatalf("pre-check failed: %v", err)
+	}
+
+	const (
+		commits   = 50
+		blockSize = 4096
+	)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*commits)
+	for i := 0; i < commits; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := quotaService.Consume(ctx, alice.User.ID, 2*blockSize); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := quotaService.Adjust(ctx, alice.User.ID, blockSize); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("concurrent charge failed: %v", err)
+	}
+
+	usage, err := quotaService.GetUsage(ctx, alice.User.ID)
+	if err != nil {
+		t.Fatalf("failed to read usage: %v", err)
+	}
+	if want := int64(commits * 3 * blockSize); usage.UsedBytes != want {
+		t.Errorf("used bytes = %d, want %d", usage.UsedBytes, want)
+	}
+
+	cached, err := h.Redis.Get(ctx, "quota_used:"+alice.User.ID)
+	if err != nil {
+		t.Fatalf("failed to read usage counter: %v", err)
+	}
+	if cached != strconv.FormatInt(usage.UsedBytes, 10) {
+		t.Errorf("usage counter = %s, want %d", cached, usage.UsedBytes)
+	}
+
+	// A charge past the limit is rejected by the pre-check and by the charge itself
+	remaining := usage.RemainingBytes()
+	if err := quotaService.Check(ctx, alice.User.ID, remaining+1); err == nil {
+		t.Errorf("pre-check accepted %d bytes with %d remaining", remaining+1, remaining)
+	}
+	if err := quotaService.Consume(ctx, alice.User.ID, remaining+1); err == nil {
+		t.Errorf("charge of %d bytes succeeded with %d remaining", remaining+1, remaining)
+	}
+}
+
+// TestConcurrentChargesNearLimit races more charges than fit into the remaining storage and checks
+// that the conditional update lets exactly as many through as fit, so usage never passes the limit.
+func TestConcurrentChargesNearLimit(t *testing.T) {
+	h := New(t)
+	ctx := context.Background()
+
+	const (
+		charges = 40
+		fits    = 10
+		size    = 1 << 20
+	)
+
+	bob := h.SeedTenant(t, "bob")
+	seedAllocation(t, h, bob.User.ID, quota.DEFAULT_STORAGE_QUOTA-fits*size)
+
+	quotaService := quota.NewService(quota.NewRepository(h.DB), h.Redis, log.New(logrus.New()))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, charges)
+	for i := 0; i < charges; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- quotaService.Consume(ctx, bob.User.ID, size)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	charged := 0
+	for err := range errs {
+		var exceeded *quota.ExceededError
+		switch {
+		case err == nil:
+			charged++
+		case !errors.As(err, &exceeded):
+			t.Fatalf("charge failed: %v", err)
+		}
+	}
+	if charged != fits {
+		t.Errorf("%d charges succeeded, want %d", charged, fits)
+	}
+
+	usage, err := quotaService.GetUsage(ctx, bob.User.ID)
+	if err != nil {
+		t.Fatalf("failed to read usage: %v", err)
+	}
+	if usage.UsedBytes != quota.DEFAULT_STORAGE_QUOTA {
+		t.Errorf("used bytes = %d, want the limit of %d", usage.UsedBytes, quota.DEFAULT_STORAGE_QUOTA)
+	}
+}
+
+// seedAllocation gives a user a volume with used bytes already charged. Both are written directly,
+// so no background charge of the API races the test.
+func seedAllocation(t *testing.T, h *Harness, userID string, used int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	volume := &models.DriveVolume{ID: utils.GenerateLinkID(), Name: fakeArmored, UserID: userID, Size: quota.DEFAULT_STORAGE_QUOTA}
+	if err := h.DB.WithContext(ctx).Omit(clause.Associations).Create(volume).Error; err != nil {
+		t.Fatalf("failed to seed volume: %v", err)
+	}
+	allocation := &models.VolumeAllocation{VolumeID: volume.ID, UserID: userID, AllocatedSize: volume.Size, UsedSize: used, IsOwner: true, Active: true}
+	if err := h.DB.WithContext(ctx).Omit(clause.Associations).Create(allocation).Error; err != nil {
+		t.Fatalf("failed to seed allocation: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Reasons a username or email address is not available
+const (
+	AVAILABILITY_REASON_INVALID  = "invalid"
+	AVAILABILITY_REASON_RESERVED = "reserved"
+	AVAILABILITY_REASON_TAKEN    = "taken"
+)
+
+// Availability says whether a username or email address can be signed up with
+type Availability struct {
+	Available bool
+	Reason    string // Why it is not available, empty when it is
+}
+
+// AvailabilityResult holds the availability of what was asked about; nil parts were not asked about
+type AvailabilityResult struct {
+	Username *Availability
+	Email    *Availability
+}
+
+// CheckAvailability checks in parallel whether a username and an email address can be signed up with.
+// Usernames are compared ignoring case and email addresses after normalization, as signup does.
+// Either can be left empty to only check the other.
+func (s *Service) CheckAvailability(ctx context.Context, username, email string) (*AvailabilityResult, error) {
+	username = strings.TrimSpace(username)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if username == "" && email == "" {
+		return nil, ErrInvalidInput
+	}
+
+	result := &AvailabilityResult{}
+	var usernameErr, emailErr error
+	var wg sync.WaitGroup
+
+	if username != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.Username, usernameErr = s.usernameAvailability(ctx, username)
+		}()
+	}
+
+	if email != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.Email, emailErr = s.emailAvailability(ctx, email)
+		}()
+	}
+
+	wg.Wait()
+	if err := errors.Join(usernameErr, emailErr); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// IsUsernameReserved reports whether a username is one of the reserved names. Case, dashes and
+// underscores are ignored, so variants like "Sup-port" are reserved too.
+func (s *Service) IsUsernameReserved(username string) bool {
+	if s.security == nil {
+		return false
+	}
+	normalized := normalizeReservedUsername(username)
+	return slices.ContainsFunc(s.security.ReservedUsernames, func(reserved string) bool {
+		return normalizeReservedUsername(reserved) == normalized
+	})
+}
+
+// usernameAvailability checks a trimmed username against the username rules, the reserved names and
+// the existing accounts
+func (s *Service) usernameAvailability(ctx context.Context, username string) (*Availability, error) {
+	if !s.ValidateUsername(username) {
+		return &Availability{Reason: AVAILABILITY_REASON_INVALID}, nil
+	}
+	if s.IsUsernameReserved(username) {
+		return &Availability{Reason: AVAILABILITY_REASON_RESERVED}, nil
+	}
+
+	_, err := s.repo.FindUserOneWhere(ctx, nil, &username)
+	switch {
+	case err == nil:
+		return &Availability{Reason: AVAILABILITY_REASON_TAKEN}, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &Availability{Available: true}, nil
+	default:
+		s.logger.Errorf("Failed to check availability of username: %v", err)
+		return nil, ErrDatabaseError
+	}
+}
+
+// emailAvailability checks a normalized email address against the address rules and the existing accounts
+func (s *Service) emailAvailability(ctx context.Context, email string) (*Availability, error) {
+	if !s.ValidateEmail(email) {
+		return &Availability{Reason: AVAILABILITY_REASON_INVALID}, nil
+	}
+
+	_, err := s.repo.FindUserOneWhere(ctx, &email, nil)
+	switch {
+	case err == nil:
+		return &Availability{Reason: AVAILABILITY_REASON_TAKEN}, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &Availability{Available: true}, nil
+	default:
+		s.logger.Errorf("Failed to check availability of email: %v", err)
+		return nil, ErrDatabaseError
+	}
+}
+
+// normalizeReservedUsername reduces a username to what reserved names are compared by
+func normalizeReservedUsername(username string) string {
+	username = strings.ToLower(strings.TrimSpace(username))
+	return strings.NewReplacer("-", "", "_", "").Replace(username)
+}
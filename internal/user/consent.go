@@ -0,0 +1,158 @@
+package user
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"time"
+)
+
+// Consent types a user can grant or revoke
+const (
+	CONSENT_ANALYTICS         = "analytics"
+	CONSENT_PRODUCT_EMAILS    = "product_emails"
+	CONSENT_BREACH_MONITORING = "breach_monitoring"
+
+	// PRIVACY_POLICY_VERSION is the policy consent is currently collected against.
+	// Bump it whenever the privacy policy changes so new grants are tied to the right text.
+	PRIVACY_POLICY_VERSION = "2025-05-01"
+)
+
+// consentTypes lists every consent type in display order
+var consentTypes = []string{CONSENT_ANALYTICS, CONSENT_PRODUCT_EMAILS, CONSENT_BREACH_MONITORING}
+
+// ConsentUpdate is a requested change to a single consent flag
+type ConsentUpdate struct {
+	Type          string
+	Granted       bool
+	PolicyVersion string
+}
+
+// GetConsents returns every consent flag for a user.
+// Types the user never answered are reported as not granted.
+func (s *Service) GetConsents(ctx context.Context, userID string) ([]models.UserConsent, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	// Try to get from cache first
+	var consents []models.UserConsent
+	if err := s.redisClient.GetJSON(ctx, redisKeyForUserConsents(userID), &consents); err == nil {
+		return consents, nil
+	}
+
+	stored, err := s.repo.GetUserConsents(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+
+	byType := make(map[string]models.UserConsent, len(stored))
+	for _, consent := range stored {
+		byType[consent.ConsentType] = consent
+	}
+
+	consents = make([]models.UserConsent, 0, len(consentTypes))
+	for _, consentType := range consentTypes {
+		consent, ok := byType[consentType]
+		if !ok {
+			consent = models.UserConsent{UserID: userID, ConsentType: consentType}
+		}
+		consents = append(consents, consent)
+	}
+
+	_ = s.redisClient.SetJSON(ctx, redisKeyForUserConsents(userID), consents, time.Hour)
+
+	return consents, nil
+}
+
+// UpdateConsents applies consent changes and records who changed what, when and under which policy version.
+// Granting requires the current policy version; revoking is always accepted.
+func (s *Service) UpdateConsents(ctx context.Context, userID string, updates []ConsentUpdate, ipAddress, userAgent string) ([]models.UserConsent, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if userID == "" || len(updates) == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	current, err := s.GetConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[string]models.UserConsent, len(current))
+	for _, consent := range current {
+		byType[consent.ConsentType] = consent
+	}
+
+	now := time.Now().Unix()
+	consents := make([]*models.UserConsent, 0, len(updates))
+	records := make([]*models.UserConsentRecord, 0, len(updates))
+	seen := make(map[string]bool, len(updates))
+
+	for _, update := range updates {
+		consent, ok := byType[update.Type]
+		if !ok || seen[update.Type] {
+			return nil, ErrInvalidConsentType
+		}
+		seen[update.Type] = true
+
+		if update.Granted && update.PolicyVersion != PRIVACY_POLICY_VERSION {
+			return nil, ErrPolicyVersionMismatch
+		}
+
+		policyVersion := update.PolicyVersion
+		if policyVersion == "" {
+			policyVersion = PRIVACY_POLICY_VERSION
+		}
+
+		consent.Granted = update.Granted
+		consent.PolicyVersion = policyVersion
+		consent.ModifiedAt = now
+		if update.Granted {
+			consent.GrantedAt = &now
+			consent.RevokedAt = nil
+		} else {
+			consent.RevokedAt = &now
+		}
+		consents = append(consents, &consent)
+
+		records = append(records, &models.UserConsentRecord{
+			UserID:        userID,
+			ConsentType:   update.Type,
+			Granted:       update.Granted,
+			PolicyVersion: policyVersion,
+			IPAddress:     ipAddress,
+			UserAgent:     userAgent,
+			CreatedAt:     now,
+		})
+	}
+
+	if err := s.repo.SaveUserConsents(userID, consents, records); err != nil {
+		return nil, ErrDatabaseError
+	}
+
+	// Breach monitoring is mirrored onto the security settings
+	_, _ = s.redisClient.DeleteMany(ctx, redisKeyForUserConsents(userID), redisKeyForUserSecuritySettings(userID))
+
+	return s.GetConsents(ctx, userID)
+}
+
+// HasConsent reports whether the user currently grants a consent type.
+// Telemetry and marketing email paths must call this before processing anything for a user;
+// it fails closed, so any lookup error is treated as no consent.
+func (s *Service) HasConsent(ctx context.Context, userID, consentType string) bool {
+	consents, err := s.GetConsents(ctx, userID)
+	if err != nil {
+		return false
+	}
+
+	for _, consent := range consents {
+		if consent.ConsentType == consentType {
+			return consent.Granted
+		}
+	}
+
+	return false
+}
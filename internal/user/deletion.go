@@ -0,0 +1,99 @@
+package user
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"time"
+)
+
+// ACCOUNT_DELETION_GRACE_PERIOD is how long a requested account deletion can be cancelled before the
+// account is purged
+const ACCOUNT_DELETION_GRACE_PERIOD = 30 * 24 * time.Hour
+
+// ScheduleDeletion marks the account for deletion at the end of the grace period. The account cannot
+// sign in until the deletion is cancelled.
+func (s *Service) ScheduleDeletion(ctx context.Context, userID string) (*models.User, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	user, err := s.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledAt := time.Now().Add(ACCOUNT_DELETION_GRACE_PERIOD).Unix()
+	scheduled, err := s.repo.ScheduleDeletion(ctx, userID, scheduledAt)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	if !scheduled {
+		return nil, ErrDeletionPending
+	}
+
+	_ = s.invalidateUserCache(ctx, userID, user.Email, user.Username)
+
+	user.DeletionScheduledAt = scheduledAt
+	return user, nil
+}
+
+// CancelDeletion cancels the pending deletion of the account, which can sign in again
+func (s *Service) CancelDeletion(ctx context.Context, userID string) (*models.User, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	user, err := s.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.repo.ClearDeletion(ctx, userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	if !cancelled {
+		return nil, ErrNoDeletionPending
+	}
+
+	_ = s.invalidateUserCache(ctx, userID, user.Email, user.Username)
+
+	user.DeletionScheduledAt = 0
+	return user, nil
+}
+
+// GetDeletionsToWarn returns up to limit accounts whose deletion is at most lead away and that were
+// not warned since it was
+func (s *Service) GetDeletionsToWarn(ctx context.Context, lead time.Duration, limit int) ([]*models.User, error) {
+	return s.repo.GetDeletionsToWarn(ctx, time.Now().Add(lead).Unix(), int64(lead.Seconds()), limit)
+}
+
+// MarkDeletionWarned records that the account was warned of its deletion scheduled at scheduledAt
+func (s *Service) MarkDeletionWarned(ctx context.Context, userID string, scheduledAt int64) error {
+	return s.repo.MarkDeletionWarned(ctx, userID, scheduledAt, time.Now().Unix())
+}
+
+// GetDeletionsDue returns the IDs of up to limit accounts whose grace period has ended
+func (s *Service) GetDeletionsDue(ctx context.Context, limit int) ([]string, error) {
+	return s.repo.GetDeletionsDue(ctx, time.Now().Unix(), limit)
+}
+
+// PurgeUser removes the personal data of an account whose deletion is due. Anything else the account
+// owns must be removed before, since the account can no longer be looked up afterwards.
+func (s *Service) PurgeUser(ctx context.Context, userID string) error {
+	user, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.DeletionScheduledAt == 0 || user.DeletionScheduledAt > time.Now().Unix() {
+		return ErrNoDeletionPending
+	}
+
+	if err := s.repo.PurgeUser(ctx, userID); err != nil {
+		return err
+	}
+
+	_ = s.invalidateUserCache(ctx, userID, user.Email, user.Username)
+
+	return nil
+}
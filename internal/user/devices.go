@@ -0,0 +1,322 @@
+package user
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Security events recorded for changes to registered devices
+const (
+	SECURITY_EVENT_DEVICE_REGISTERED = "device_registered"
+	SECURITY_EVENT_DEVICE_TRUSTED    = "device_trusted"
+	SECURITY_EVENT_DEVICE_UNTRUSTED  = "device_untrusted"
+	SECURITY_EVENT_DEVICE_RENAMED    = "device_renamed"
+	SECURITY_EVENT_DEVICE_REMOVED    = "device_removed"
+
+	// maxDeviceNameLength matches the device_name column
+	maxDeviceNameLength = 100
+)
+
+// DeviceTrust is the signed token a trusted device presents at login to skip the second factor
+type DeviceTrust struct {
+	Token     string
+	ExpiresAt int64
+}
+
+// RegisterDevice records the device a user signed in from, or refreshes its last use when it is
+// already registered. Logins without a client UID cannot be told apart and are not registered.
+func (s *Service) RegisterDevice(ctx context.Context, userID, deviceUID, deviceName string) error {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if userID == "" || deviceUID == "" {
+		return ErrInvalidInput
+	}
+
+	now := time.Now().Unix()
+	device, err := s.findDeviceByUID(userID, deviceUID)
+	if err == nil {
+		device.LastUsed = now
+		if err := s.repo.UpdateUserDevice(device); err != nil {
+			return ErrDatabaseError
+		}
+		return nil
+	}
+	if err != ErrDeviceNotFound {
+		return err
+	}
+
+	device = &models.UserDevice{
+		UserID:     userID,
+		DeviceID:   deviceUID,
+		DeviceName: truncateDeviceName(deviceName),
+		LastUsed:   now,
+		Active:     true,
+	}
+	if err := s.repo.SaveUserDevice(device); err != nil {
+		return ErrDatabaseError
+	}
+
+	s.recordDeviceEvent(userID, SECURITY_EVENT_DEVICE_REGISTERED, device)
+
+	return nil
+}
+
+// ListDevices returns the active devices registered to the user
+func (s *Service) ListDevices(ctx context.Context, userID string) ([]models.UserDevice, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	devices, err := s.repo.GetUserDevices(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+
+	return devices, nil
+}
+
+// TrustDevice marks the device of the current session as trusted and signs the token it presents at
+// later logins to skip the second factor. Only the device itself can be trusted, so a stolen session
+// cannot vouch for another device.
+func (s *Service) TrustDevice(ctx context.Context, userID, deviceID, sessionID string) (*models.UserDevice, *DeviceTrust, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	if s.security == nil || s.security.DeviceTrustSigningKey == "" {
+		return nil, nil, ErrDeviceTrustUnavailable
+	}
+
+	device, err := s.findDevice(userID, deviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessions, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
+		return nil, nil, ErrDatabaseError
+	}
+	current := false
+	for _, session := range sessions {
+		if session.ID == sessionID && session.DeviceID == device.DeviceID {
+			current = true
+			break
+		}
+	}
+	if !current {
+		return nil, nil, ErrNotCurrentDevice
+	}
+
+	if !device.Trusted {
+		device.Trusted = true
+		if err := s.repo.UpdateUserDevice(device); err != nil {
+			return nil, nil, ErrDatabaseError
+		}
+	}
+
+	s.recordDeviceEvent(userID, SECURITY_EVENT_DEVICE_TRUSTED, device)
+
+	expiresAt := time.Now().Add(s.security.DeviceTrustDuration).Unix()
+	trust := &DeviceTrust{
+		Token:     strconv.FormatInt(expiresAt, 10) + "." + s.signDeviceTrust(userID, device.DeviceID, expiresAt),
+		ExpiresAt: expiresAt,
+	}
+
+	return device, trust, nil
+}
+
+// UntrustDevice withdraws trust from a device. Tokens already handed to it stop working at once
+// because every login checks the stored flag as well as the signature.
+func (s *Service) UntrustDevice(ctx context.Context, userID, deviceID string) (*models.UserDevice, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	device, err := s.findDevice(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Trusted {
+		device.Trusted = false
+		if err := s.repo.UpdateUserDevice(device); err != nil {
+			return nil, ErrDatabaseError
+		}
+		s.recordDeviceEvent(userID, SECURITY_EVENT_DEVICE_UNTRUSTED, device)
+	}
+
+	return device, nil
+}
+
+// RenameDevice changes the display name of a device
+func (s *Service) RenameDevice(ctx context.Context, userID, deviceID, name string) (*models.UserDevice, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxDeviceNameLength {
+		return nil, ErrInvalidDeviceName
+	}
+
+	device, err := s.findDevice(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := device.DeviceName
+	device.DeviceName = name
+	if err := s.repo.UpdateUserDevice(device); err != nil {
+		return nil, ErrDatabaseError
+	}
+
+	metadata, _ := json.Marshal(map[string]string{
+		"deviceId":     device.ID,
+		"previousName": previous,
+		"deviceName":   name,
+	})
+	_ = s.repo.CreateSecurityEvent(&models.UserSecurityEvent{
+		ID:                 utils.GenerateID(),
+		UserID:             userID,
+		EventType:          SECURITY_EVENT_DEVICE_RENAMED,
+		Success:            true,
+		AdditionalMetadata: metadata,
+	})
+
+	return device, nil
+}
+
+// RemoveDevice deletes a registered device together with its trust. Its sessions are left alone;
+// signing in from it again registers it anew as an untrusted device.
+func (s *Service) RemoveDevice(ctx context.Context, userID, deviceID string) error {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	device, err := s.findDevice(userID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteUserDevice(device.ID); err != nil {
+		return ErrDatabaseError
+	}
+
+	s.recordDeviceEvent(userID, SECURITY_EVENT_DEVICE_REMOVED, device)
+
+	return nil
+}
+
+// IsDeviceTrusted reports whether a login from the device may skip the second factor. The token must
+// carry a valid signature for this user and device, must not have expired, and the device must still
+// be registered as trusted.
+func (s *Service) IsDeviceTrusted(ctx context.Context, userID, deviceUID, token string) (bool, error) {
+	if s.security == nil || s.security.DeviceTrustSigningKey == "" || deviceUID == "" || token == "" {
+		return false, nil
+	}
+
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false, nil
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || expiresAt < time.Now().Unix() {
+		return false, nil
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signDeviceTrust(userID, deviceUID, expiresAt))) {
+		return false, nil
+	}
+
+	device, err := s.findDeviceByUID(userID, deviceUID)
+	if err == ErrDeviceNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return device.Trusted, nil
+}
+
+// findDevice returns an active device of the user by its ID
+func (s *Service) findDevice(userID, deviceID string) (*models.UserDevice, error) {
+	devices, err := s.repo.GetUserDevices(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+
+	for i := range devices {
+		if devices[i].ID == deviceID {
+			return &devices[i], nil
+		}
+	}
+
+	return nil, ErrDeviceNotFound
+}
+
+// findDeviceByUID returns an active device of the user by the UID its client reports
+func (s *Service) findDeviceByUID(userID, deviceUID string) (*models.UserDevice, error) {
+	devices, err := s.repo.GetUserDevices(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+
+	for i := range devices {
+		if devices[i].DeviceID == deviceUID {
+			return &devices[i], nil
+		}
+	}
+
+	return nil, ErrDeviceNotFound
+}
+
+// recordDeviceEvent records a security event about a device
+func (s *Service) recordDeviceEvent(userID, eventType string, device *models.UserDevice) {
+	metadata, _ := json.Marshal(map[string]string{
+		"deviceId":   device.ID,
+		"deviceName": device.DeviceName,
+		"deviceType": device.DeviceType,
+	})
+
+	_ = s.repo.CreateSecurityEvent(&models.UserSecurityEvent{
+		ID:                 utils.GenerateID(),
+		UserID:             userID,
+		EventType:          eventType,
+		Success:            true,
+		AdditionalMetadata: metadata,
+	})
+}
+
+// signDeviceTrust signs the user, device and expiry of a device trust token
+func (s *Service) signDeviceTrust(userID, deviceUID string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(s.security.DeviceTrustSigningKey))
+	mac.Write([]byte(fmt.Sprintf("%s|%s|%d", userID, deviceUID, expiresAt)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncateDeviceName cuts client supplied names down to what the column holds
+func truncateDeviceName(name string) string {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) <= maxDeviceNameLength {
+		return name
+	}
+	return string([]rune(name)[:maxDeviceNameLength])
+}
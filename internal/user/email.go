@@ -0,0 +1,32 @@
+package user
+
+import (
+	"context"
+	"strings"
+)
+
+// CheckNewEmail validates an address a user wants to move their account to and returns it normalized.
+// Addresses of any account, the user's own included, are not available.
+func (s *Service) CheckNewEmail(ctx context.Context, userID, email string) (string, error) {
+	if userID == "" {
+		return "", ErrInvalidInput
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !s.ValidateEmail(email) {
+		return "", ErrInvalidEmail
+	}
+
+	if existingUser, err := s.GetUserByEmail(ctx, email); err == nil && existingUser != nil {
+		return "", ErrEmailAlreadyExists
+	}
+
+	return email, nil
+}
+
+// EmailChanged drops everything cached under the previous and the new address of a user whose email
+// address was changed, so lookups by either address go to the database
+func (s *Service) EmailChanged(ctx context.Context, userID, previousEmail, newEmail string) {
+	_ = s.invalidateUserCache(ctx, userID, previousEmail, "")
+	_ = s.invalidateUserCache(ctx, userID, newEmail, "")
+}
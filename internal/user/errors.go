@@ -24,6 +24,9 @@ var (
 	// ErrUsernameAlreadyExists indicates the username is already in use
 	ErrUsernameAlreadyExists = errors.New("Username already exists")
 
+	// ErrUsernameReserved indicates the username could pass for the service or its staff
+	ErrUsernameReserved = errors.New("Username is reserved")
+
 	// ErrCacheError indicates an error occurred with the Redis cache
 	ErrCacheError = errors.New("Cache operation failed")
 
@@ -74,4 +77,37 @@ var (
 
 	// ErrAccountLocked indicates the user account is locked
 	ErrAccountLocked = errors.New("User account is locked")
+
+	// ErrInvalidConsentType indicates the consent type is not recognized
+	ErrInvalidConsentType = errors.New("Invalid consent type")
+
+	// ErrPolicyVersionMismatch indicates consent was given against an outdated privacy policy
+	ErrPolicyVersionMismatch = errors.New("Privacy policy version is not current")
+
+	// ErrSecurityExportUnavailable indicates security exports are disabled because no signing key is configured
+	ErrSecurityExportUnavailable = errors.New("Security export is not available")
+
+	// ErrDeviceNotFound indicates the device is not registered to the user
+	ErrDeviceNotFound = errors.New("Device not found")
+
+	// ErrNotCurrentDevice indicates a device can only be trusted from itself
+	ErrNotCurrentDevice = errors.New("A device can only be trusted while signed in on it")
+
+	// ErrDeviceTrustUnavailable indicates device trust is disabled because no signing key is configured
+	ErrDeviceTrustUnavailable = errors.New("Device trust is not available")
+
+	// ErrInvalidDeviceName indicates the device name is empty or too long
+	ErrInvalidDeviceName = errors.New("Device name must be 1-100 characters")
+
+	// ErrDeletionPending indicates the account is already scheduled for deletion
+	ErrDeletionPending = errors.New("Account is scheduled for deletion")
+
+	// ErrNoDeletionPending indicates the account is not scheduled for deletion
+	ErrNoDeletionPending = errors.New("Account is not scheduled for deletion")
+
+	// ErrInvalidSyncSchedule indicates a sync schedule has an unknown time zone, action, day or time, or a cap out of range
+	ErrInvalidSyncSchedule = errors.New("Invalid sync schedule")
+
+	// ErrInvalidPreferences indicates an unknown theme, language or time zone, or an empty update
+	ErrInvalidPreferences = errors.New("Invalid preferences")
 )
@@ -58,6 +58,10 @@ func redisKeyForUserBilling(userID string) string {
 	return fmt.Sprintf("user:%s:billing", userID)
 }
 
+func redisKeyForUserConsents(userID string) string {
+	return fmt.Sprintf("user:%s:consents", userID)
+}
+
 // Cache operations
 func (s *Service) cacheUser(ctx context.Context, user *models.User) error {
 	// Marshal user to JSON
@@ -0,0 +1,210 @@
+package user
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+const (
+	// Theme modes a user can pick
+	THEME_MODE_SYSTEM = "system"
+	THEME_MODE_LIGHT  = "light"
+	THEME_MODE_DARK   = "dark"
+
+	// USER_EVENTS_CHANNEL_PREFIX is the Redis channel, per user ID, that realtime connections of the
+	// user's clients listen on
+	USER_EVENTS_CHANNEL_PREFIX = "user:events:"
+
+	// USER_EVENT_PREFERENCES_CHANGED tells the user's other clients to reload their preferences
+	USER_EVENT_PREFERENCES_CHANGED = "preferences_changed"
+
+	// Parts of the preferences a change event is about
+	PREFERENCES_SECTION_GENERAL       = "general"
+	PREFERENCES_SECTION_NOTIFICATIONS = "notifications"
+
+	// maxTimezoneLength matches the timezone column
+	maxTimezoneLength = 50
+)
+
+var (
+	// themeModes lists every theme mode
+	themeModes = []string{THEME_MODE_SYSTEM, THEME_MODE_LIGHT, THEME_MODE_DARK}
+
+	// languages lists the languages the apps and emails are translated to
+	languages = []string{"en", "de", "es", "fr"}
+)
+
+// PreferencesUpdate is a requested change to a user's preferences. Nil fields are left unchanged.
+type PreferencesUpdate struct {
+	ThemeMode *string
+	Language  *string
+	Timezone  *string
+}
+
+// NotificationPreferencesUpdate is a requested change to how a user is notified. Nil fields are
+// left unchanged.
+type NotificationPreferencesUpdate struct {
+	Email    *bool
+	Push     *bool
+	Security *bool
+}
+
+// UserEvent is a change published to the realtime connections of a user's clients
+type UserEvent struct {
+	Type       string `json:"type"`
+	Section    string `json:"section"`
+	ModifiedAt int64  `json:"modifiedAt"`
+}
+
+// GetPreferences returns a user's theme, language and time zone
+func (s *Service) GetPreferences(ctx context.Context, userID string) (*models.UserPreferences, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	preferences, err := s.repo.GetUserPreferences(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences changes a user's theme, language or time zone and tells the user's other
+// clients about it
+func (s *Service) UpdatePreferences(ctx context.Context, userID string, update PreferencesUpdate) (*models.UserPreferences, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+	if err := validatePreferencesUpdate(update); err != nil {
+		return nil, err
+	}
+
+	preferences, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.ThemeMode != nil {
+		preferences.ThemeMode = *update.ThemeMode
+	}
+	if update.Language != nil {
+		preferences.Language = *update.Language
+	}
+	if update.Timezone != nil {
+		preferences.Timezone = *update.Timezone
+	}
+	preferences.ModifiedAt = time.Now().Unix()
+
+	if err := s.repo.UpdateUserPreferences(preferences); err != nil {
+		s.logger.Errorf("Failed to update preferences of user %s: %v", userID, err)
+		return nil, ErrDatabaseError
+	}
+
+	s.preferencesChanged(ctx, userID, PREFERENCES_SECTION_GENERAL, preferences.ModifiedAt)
+
+	return preferences, nil
+}
+
+// GetNotificationPreferences returns how a user wants to be notified
+func (s *Service) GetNotificationPreferences(ctx context.Context, userID string) (*models.UserNotifications, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	notifications, err := s.repo.GetUserNotificationsPreferences(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	return notifications, nil
+}
+
+// UpdateNotificationPreferences changes how a user wants to be notified and tells the user's other
+// clients about it
+func (s *Service) UpdateNotificationPreferences(ctx context.Context, userID string, update NotificationPreferencesUpdate) (*models.UserNotifications, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+	if update.Email == nil && update.Push == nil && update.Security == nil {
+		return nil, ErrInvalidPreferences
+	}
+
+	notifications, err := s.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Email != nil {
+		notifications.Email = *update.Email
+	}
+	if update.Push != nil {
+		notifications.Push = *update.Push
+	}
+	if update.Security != nil {
+		notifications.Security = *update.Security
+	}
+	notifications.ModifiedAt = time.Now().Unix()
+
+	if err := s.repo.UpdateUserNotificationsPreferences(notifications); err != nil {
+		s.logger.Errorf("Failed to update notification preferences of user %s: %v", userID, err)
+		return nil, ErrDatabaseError
+	}
+
+	s.preferencesChanged(ctx, userID, PREFERENCES_SECTION_NOTIFICATIONS, notifications.ModifiedAt)
+
+	return notifications, nil
+}
+
+// validatePreferencesUpdate checks that an update changes something and only to known values
+func validatePreferencesUpdate(update PreferencesUpdate) error {
+	if update.ThemeMode == nil && update.Language == nil && update.Timezone == nil {
+		return ErrInvalidPreferences
+	}
+	if update.ThemeMode != nil && !slices.Contains(themeModes, *update.ThemeMode) {
+		return ErrInvalidPreferences
+	}
+	if update.Language != nil && !slices.Contains(languages, *update.Language) {
+		return ErrInvalidPreferences
+	}
+	if update.Timezone != nil {
+		// LoadLocation also accepts an empty name and "Local", which are not zones clients can apply
+		if *update.Timezone == "" || *update.Timezone == "Local" || len(*update.Timezone) > maxTimezoneLength {
+			return ErrInvalidPreferences
+		}
+		if _, err := time.LoadLocation(*update.Timezone); err != nil {
+			return ErrInvalidPreferences
+		}
+	}
+	return nil
+}
+
+// preferencesChanged drops the cached profile, which embeds the preferences, and publishes the
+// change to the user's realtime connections. Clients that miss the event see the change the next
+// time they load the profile.
+func (s *Service) preferencesChanged(ctx context.Context, userID, section string, modifiedAt int64) {
+	_ = s.invalidateUserCache(ctx, userID, "", "")
+
+	event, err := json.Marshal(UserEvent{
+		Type:       USER_EVENT_PREFERENCES_CHANGED,
+		Section:    section,
+		ModifiedAt: modifiedAt,
+	})
+	if err != nil {
+		return
+	}
+	if _, err := s.redisClient.Publish(ctx, USER_EVENTS_CHANNEL_PREFIX+userID, string(event)); err != nil {
+		s.logger.Errorf("Failed to publish preferences change of user %s: %v", userID, err)
+	}
+}
@@ -2,12 +2,14 @@ package user
 
 import (
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/quota"
 	"cirrussync-api/pkg/db"
 	"context"
 	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // NewRepository creates a new user repository
@@ -26,6 +28,7 @@ func NewRepository(database *gorm.DB) Repository {
 	userBillingRepo := db.NewRepositoryWithDB[models.UserBilling](database)
 	userPaymentMethodRepo := db.NewRepositoryWithDB[models.UserPaymentMethod](database)
 	userDeviceRepo := db.NewRepositoryWithDB[models.UserDevice](database)
+	userConsentRepo := db.NewRepositoryWithDB[models.UserConsent](database)
 
 	// Return our repository that wraps the base repositories
 	return &repo{
@@ -43,6 +46,7 @@ func NewRepository(database *gorm.DB) Repository {
 		userBillingRepo:          userBillingRepo,
 		userPaymentMethodRepo:    userPaymentMethodRepo,
 		userDeviceRepo:           userDeviceRepo,
+		userConsentRepo:          userConsentRepo,
 	}
 }
 
@@ -62,6 +66,7 @@ type repo struct {
 	userBillingRepo          db.Repository[models.UserBilling]
 	userPaymentMethodRepo    db.Repository[models.UserPaymentMethod]
 	userDeviceRepo           db.Repository[models.UserDevice]
+	userConsentRepo          db.Repository[models.UserConsent]
 }
 
 // USER OPERATIONS
@@ -72,6 +77,30 @@ func (r *repo) SaveUser(user *models.User) (*models.User, error) {
 	return user, err
 }
 
+// WithTransaction runs fn in one transaction carried by the context it receives. Queries that take
+// that context run in it.
+func (r *repo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return db.RunInTx(ctx, r.db, fn)
+}
+
+// SaveUserWithOutbox creates a new user together with a payments outbox entry, so the
+// payment provider call is never lost if the process stops right after signup
+func (r *repo) SaveUserWithOutbox(ctx context.Context, user *models.User, outbox *models.PaymentOutbox) (*models.User, error) {
+	err := db.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		// The column defaults to true, which GORM applies for a false value on create
+		if err := tx.Model(user).Update("stripe_user_exists", false).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(outbox).Error
+	})
+	return user, err
+}
+
 // UpdateUserById updates a user with built-in locking
 func (r *repo) UpdateUserById(id string, user *models.User) (*models.User, error) {
 	err := r.userRepo.Update(context.Background(), user)
@@ -79,17 +108,17 @@ func (r *repo) UpdateUserById(id string, user *models.User) (*models.User, error
 }
 
 // FindUserByID finds a user by ID
-func (r *repo) FindUserByID(id string) (*models.User, error) {
-	return r.userRepo.FindByID(context.Background(), id)
+func (r *repo) FindUserByID(ctx context.Context, id string) (*models.User, error) {
+	return r.userRepo.FindByID(ctx, id)
 }
 
 // FindUserOneWhere finds a user by email or username
-func (r *repo) FindUserOneWhere(email *string, username *string) (*models.User, error) {
+func (r *repo) FindUserOneWhere(ctx context.Context, email *string, username *string) (*models.User, error) {
 	var user models.User
 
 	// First check by email if provided
 	if email != nil {
-		err := r.userRepo.DB().Where("email = ?", *email).First(&user).Error
+		err := r.userRepo.DB().WithContext(ctx).Where("email = ?", *email).First(&user).Error
 		if err == nil {
 			return &user, nil
 		}
@@ -99,9 +128,9 @@ func (r *repo) FindUserOneWhere(email *string, username *string) (*models.User,
 		}
 	}
 
-	// Then check by username if provided
+	// Then check by username if provided; usernames only differing in case are the same name
 	if username != nil {
-		err := r.userRepo.DB().Where("username = ?", *username).First(&user).Error
+		err := r.userRepo.DB().WithContext(ctx).Where("LOWER(username) = LOWER(?)", *username).First(&user).Error
 		if err == nil {
 			return &user, nil
 		}
@@ -120,11 +149,136 @@ func (r *repo) DeleteUser(id string) error {
 	return r.userRepo.Delete(context.Background(), id)
 }
 
+// ACCOUNT DELETION OPERATIONS
+
+// ScheduleDeletion marks an account for deletion at scheduledAt, counting the request as its first
+// warning. It returns false when a deletion is already pending.
+func (r *repo) ScheduleDeletion(ctx context.Context, userID string, scheduledAt int64) (bool, error) {
+	result := db.Conn(ctx, r.db).
+		Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at = 0", userID).
+		Updates(map[string]interface{}{
+			"deletion_scheduled_at": scheduledAt,
+			"deletion_warned_at":    time.Now().Unix(),
+			"modified_at":           time.Now().Unix(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ClearDeletion cancels the pending deletion of an account. It returns false when none is pending.
+func (r *repo) ClearDeletion(ctx context.Context, userID string) (bool, error) {
+	result := db.Conn(ctx, r.db).
+		Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at <> 0", userID).
+		Updates(map[string]interface{}{
+			"deletion_scheduled_at": 0,
+			"deletion_warned_at":    0,
+			"modified_at":           time.Now().Unix(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetDeletionsToWarn retrieves up to limit accounts whose deletion is scheduled by scheduledBefore
+// and that were not warned since lead seconds before their deletion
+func (r *repo) GetDeletionsToWarn(ctx context.Context, scheduledBefore, lead int64, limit int) ([]*models.User, error) {
+	var users []*models.User
+	err := db.Conn(ctx, r.db).
+		Select("id", "email", "username", "display_name", "deletion_scheduled_at").
+		Where("deletion_scheduled_at > 0 AND deletion_scheduled_at <= ? AND deletion_warned_at < deletion_scheduled_at - ?", scheduledBefore, lead).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// MarkDeletionWarned records that a warning was sent, unless the deletion was cancelled or
+// rescheduled meanwhile
+func (r *repo) MarkDeletionWarned(ctx context.Context, userID string, scheduledAt, warnedAt int64) error {
+	return db.Conn(ctx, r.db).
+		Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at = ?", userID, scheduledAt).
+		Update("deletion_warned_at", warnedAt).Error
+}
+
+// GetDeletionsDue retrieves the IDs of up to limit accounts whose grace period ended by now
+func (r *repo) GetDeletionsDue(ctx context.Context, now int64, limit int) ([]string, error) {
+	var userIDs []string
+	err := db.Conn(ctx, r.db).
+		Model(&models.User{}).
+		Where("deletion_scheduled_at > 0 AND deletion_scheduled_at <= ?", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Pluck("id", &userIDs).Error
+	return userIDs, err
+}
+
+// PurgeUser removes the personal data of an account: its credentials, keys, devices, sessions, second
+// factors, settings, security events and third-party grants are deleted. The user row is kept,
+// anonymized and soft deleted, so billing records still refer to an account.
+func (r *repo) PurgeUser(ctx context.Context, userID string) error {
+	return db.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		mfaSettingsIDs := tx.Model(&models.UserMFASettings{}).Select("id").Where("user_id = ?", userID)
+		for _, method := range []interface{}{&models.EmailMethods{}, &models.PhoneMethods{}, &models.TOTPMethods{}} {
+			if err := tx.Where("settings_id IN (?)", mfaSettingsIDs).Delete(method).Error; err != nil {
+				return err
+			}
+		}
+
+		preferencesIDs := tx.Model(&models.UserPreferences{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Where("preferences_id IN (?)", preferencesIDs).Delete(&models.UserNotifications{}).Error; err != nil {
+			return err
+		}
+
+		personalData := []interface{}{
+			&models.UserSRP{},
+			&models.UserKey{},
+			&models.UserRecoveryKit{},
+			&models.UserSession{},
+			&models.UserDevice{},
+			&models.UserMFASettings{},
+			&models.WebAuthnCredential{},
+			&models.UserSecuritySettings{},
+			&models.UserPreferences{},
+			&models.UserSecurityEvent{},
+			&models.SecurityEventDownload{},
+			&models.UserStorage{},
+			&models.OAuthToken{},
+			&models.OAuthConsent{},
+			&models.WebhookSecret{},
+		}
+		for _, model := range personalData {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// Email and username stay unique, so they are replaced rather than cleared
+		return tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"username":       "deleted-" + userID,
+				"display_name":   "",
+				"email":          userID + "@deleted.invalid",
+				"email_verified": false,
+				"phone_number":   nil,
+				"phone_verified": false,
+				"company_name":   nil,
+				"active":         false,
+				"deleted":        true,
+				"deleted_at":     time.Now(),
+				"modified_at":    time.Now().Unix(),
+				// Nothing is left to warn about or purge
+				"deletion_scheduled_at": 0,
+				"deletion_warned_at":    0,
+			}).Error
+	})
+}
+
 // KEY OPERATIONS
 
 // SaveUserKey saves a user key
-func (r *repo) SaveUserKey(userKey *models.UserKey) error {
-	return r.userKeyRepo.Create(context.Background(), userKey)
+func (r *repo) SaveUserKey(ctx context.Context, userKey *models.UserKey) error {
+	return r.userKeyRepo.Create(ctx, userKey)
 }
 
 // FindUserKeysByUserID finds all keys for a user
@@ -164,8 +318,8 @@ func (r *repo) GetUserStorage(userID string) (*models.UserStorage, error) {
 			storage = models.UserStorage{
 				UserID:        userID,
 				UsedSpace:     0,
-				MaxSpace:      3221225472, // 3GB default
-				BasePlanSpace: 3221225472,
+				MaxSpace:      quota.DEFAULT_STORAGE_QUOTA,
+				BasePlanSpace: quota.DEFAULT_STORAGE_QUOTA,
 				SharedSpace:   0,
 				CreatedAt:     time.Now().Unix(),
 				ModifiedAt:    time.Now().Unix(),
@@ -226,16 +380,39 @@ func (r *repo) UpdateUserPreferences(preferences *models.UserPreferences) error
 
 // Preferences Notifications
 
+// SaveUserNotificationsPreferences saves notification preferences for a user
 func (r *repo) SaveUserNotificationsPreferences(notifications *models.UserNotifications) error {
 	return r.userNotificationsRepo.Create(context.Background(), notifications)
 }
 
+// UpdateUserNotificationsPreferences updates notification preferences for a user
 func (r *repo) UpdateUserNotificationsPreferences(notifications *models.UserNotifications) error {
 	return r.userNotificationsRepo.Update(context.Background(), notifications)
 }
 
+// GetUserNotificationsPreferences gets the notification preferences of a user's preferences,
+// creating the defaults for users who have none yet
 func (r *repo) GetUserNotificationsPreferences(userID string) (*models.UserNotifications, error) {
-	return r.userNotificationsRepo.FindByID(context.Background(), userID)
+	preferences, err := r.GetUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications models.UserNotifications
+	err = r.userNotificationsRepo.DB().Where("preferences_id = ?", preferences.ID).First(&notifications).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		notifications = models.UserNotifications{
+			PreferencesID: preferences.ID,
+			Email:         true,
+			Push:          true,
+			Security:      true,
+		}
+		err = r.userNotificationsRepo.Create(context.Background(), &notifications)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &notifications, nil
 }
 
 // Security Settings Operations
@@ -247,7 +424,16 @@ func (r *repo) SaveUserSecuritySettings(settings *models.UserSecuritySettings) e
 
 // GetUserSecuritySettings gets security settings for a user
 func (r *repo) GetUserSecuritySettings(userID string) (*models.UserSecuritySettings, error) {
-	return r.userSecuritySettingsRepo.FindByID(context.Background(), userID)
+	var settings models.UserSecuritySettings
+	err := r.userSecuritySettingsRepo.DB().Where("user_id = ?", userID).First(&settings).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// User doesn't have security settings yet
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
 }
 
 // UpdateUserSecuritySettings updates security settings for a user
@@ -433,3 +619,104 @@ func (r *repo) UpdateUserPaymentMethod(method *models.UserPaymentMethod) error {
 func (r *repo) DeleteUserPaymentMethod(id string) error {
 	return r.userPaymentMethodRepo.Delete(context.Background(), id)
 }
+
+// CONSENT OPERATIONS
+
+// GetUserConsents gets the recorded consent flags for a user
+func (r *repo) GetUserConsents(userID string) ([]models.UserConsent, error) {
+	var consents []models.UserConsent
+	err := r.userConsentRepo.DB().Where("user_id = ?", userID).Find(&consents).Error
+	if err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+// SaveUserConsents upserts consent flags and appends their audit records in one transaction.
+// Breach monitoring enrollment is mirrored onto the security settings so both stay in step.
+func (r *repo) SaveUserConsents(userID string, consents []*models.UserConsent, records []*models.UserConsentRecord) error {
+	return r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		for _, consent := range consents {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "consent_type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"granted", "policy_version", "granted_at", "revoked_at", "modified_at"}),
+			}).Create(consent).Error
+			if err != nil {
+				return err
+			}
+
+			if consent.ConsentType == CONSENT_BREACH_MONITORING {
+				err = tx.Model(&models.UserSecuritySettings{}).
+					Where("user_id = ?", userID).
+					Updates(map[string]interface{}{
+						"dark_web_monitoring": consent.Granted,
+						"modified_at":         time.Now().Unix(),
+					}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(records) > 0 {
+			if err := tx.Create(&records).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SECURITY EXPORT OPERATIONS
+
+// GetUserMFAMethods gets MFA settings for a user together with their email, phone and TOTP methods
+func (r *repo) GetUserMFAMethods(userID string) (*models.UserMFASettings, error) {
+	var settings models.UserMFASettings
+	err := r.userMFASettingsRepo.DB().
+		Preload("Email").
+		Preload("Phone").
+		Preload("TOTP").
+		Where("user_id = ?", userID).
+		First(&settings).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// User doesn't have MFA settings yet
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// GetActiveSessions gets the valid, unexpired sessions of a user, most recently active first
+func (r *repo) GetActiveSessions(userID string) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := r.db.WithContext(context.Background()).
+		Where("user_id = ? AND is_valid = ? AND expires_at > ?", userID, true, time.Now().Unix()).
+		Order("last_active DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetSecurityEvents gets up to limit security events of a user created at or after since, newest first
+func (r *repo) GetSecurityEvents(userID string, since int64, limit int) ([]models.UserSecurityEvent, error) {
+	var events []models.UserSecurityEvent
+	err := r.db.WithContext(context.Background()).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// CreateSecurityEvent records a security event
+func (r *repo) CreateSecurityEvent(event *models.UserSecurityEvent) error {
+	return r.db.WithContext(context.Background()).Create(event).Error
+}
@@ -0,0 +1,270 @@
+package user
+
+import (
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	// SECURITY_EXPORT_VERSION is bumped whenever the shape of the exported snapshot changes
+	SECURITY_EXPORT_VERSION = 1
+
+	// SECURITY_EXPORT_ALGORITHM names the signature scheme over the exported snapshot bytes
+	SECURITY_EXPORT_ALGORITHM = "HMAC-SHA256"
+
+	// SECURITY_EVENT_SETTINGS_EXPORTED is recorded every time a user exports their security state
+	SECURITY_EVENT_SETTINGS_EXPORTED = "security_settings_exported"
+)
+
+// SecurityExport is a point-in-time snapshot of a user's security state.
+// It never carries secrets such as TOTP seeds, backup codes or device recovery data.
+type SecurityExport struct {
+	Version     int                       `json:"version"`
+	UserID      string                    `json:"userId"`
+	GeneratedAt int64                     `json:"generatedAt"`
+	EventsSince int64                     `json:"eventsSince"`
+	Settings    *ExportedSecuritySettings `json:"settings"`
+	MFA         ExportedMFA               `json:"mfa"`
+	Devices     []ExportedDevice          `json:"devices"`
+	Sessions    []ExportedSession         `json:"sessions"`
+	Events      []ExportedSecurityEvent   `json:"events"`
+}
+
+// ExportedSecuritySettings is the exported form of the user's security toggles
+type ExportedSecuritySettings struct {
+	DarkWebMonitoring           bool  `json:"darkWebMonitoring"`
+	DetailedEvents              bool  `json:"detailedEvents"`
+	SuspiciousActivityDetection bool  `json:"suspiciousActivityDetection"`
+	TwoFactorRequired           bool  `json:"twoFactorRequired"`
+	ModifiedAt                  int64 `json:"modifiedAt"`
+}
+
+// ExportedMFA describes which second factors are set up, without any of their secrets
+type ExportedMFA struct {
+	PreferredMethod  string              `json:"preferredMethod"`
+	BackupCodesCount int                 `json:"backupCodesCount"`
+	Methods          []ExportedMFAMethod `json:"methods"`
+}
+
+// ExportedMFAMethod is the state of a single second factor
+type ExportedMFAMethod struct {
+	Type       string `json:"type"`
+	Enabled    bool   `json:"enabled"`
+	Verified   bool   `json:"verified"`
+	LastUsed   *int64 `json:"lastUsed"`
+	ModifiedAt int64  `json:"modifiedAt"`
+}
+
+// ExportedDevice is an active device registered to the account
+type ExportedDevice struct {
+	DeviceID   string `json:"deviceId"`
+	DeviceName string `json:"deviceName"`
+	DeviceType string `json:"deviceType"`
+	Trusted    bool   `json:"trusted"`
+	LastUsed   int64  `json:"lastUsed"`
+	CreatedAt  int64  `json:"createdAt"`
+}
+
+// ExportedSession is a session that can still be used to access the account
+type ExportedSession struct {
+	ID         string `json:"id"`
+	DeviceID   string `json:"deviceId"`
+	DeviceName string `json:"deviceName"`
+	AppVersion string `json:"appVersion"`
+	IPAddress  string `json:"ipAddress"`
+	UserAgent  string `json:"userAgent"`
+	CreatedAt  int64  `json:"createdAt"`
+	LastActive int64  `json:"lastActive"`
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+// ExportedSecurityEvent is a recent security event on the account
+type ExportedSecurityEvent struct {
+	ID        string          `json:"id"`
+	EventType string          `json:"eventType"`
+	Success   bool            `json:"success"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt int64           `json:"createdAt"`
+}
+
+// SignedSecurityExport carries the exact snapshot bytes together with their signature,
+// so the snapshot can be archived as-is and checked later
+type SignedSecurityExport struct {
+	Export    json.RawMessage
+	Signature string
+	Algorithm string
+	SignedAt  int64
+}
+
+// ExportSecuritySettings builds and signs a snapshot of the user's devices, sessions,
+// MFA methods and recent security events
+func (s *Service) ExportSecuritySettings(ctx context.Context, userID string) (*SignedSecurityExport, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	// Refuse to hand out snapshots that cannot be verified later
+	if s.security == nil || s.security.ExportSigningKey == "" {
+		return nil, ErrSecurityExportUnavailable
+	}
+
+	now := time.Now().Unix()
+	since := now - int64(s.security.ExportEventWindow.Seconds())
+
+	export := SecurityExport{
+		Version:     SECURITY_EXPORT_VERSION,
+		UserID:      userID,
+		GeneratedAt: now,
+		EventsSince: since,
+		MFA:         ExportedMFA{Methods: []ExportedMFAMethod{}},
+		Devices:     []ExportedDevice{},
+		Sessions:    []ExportedSession{},
+		Events:      []ExportedSecurityEvent{},
+	}
+
+	settings, err := s.repo.GetUserSecuritySettings(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	if settings != nil {
+		export.Settings = &ExportedSecuritySettings{
+			DarkWebMonitoring:           settings.DarkWebMonitoring,
+			DetailedEvents:              settings.DetailedEvents,
+			SuspiciousActivityDetection: settings.SuspiciousActivityDetection,
+			TwoFactorRequired:           settings.TwoFactorRequired,
+			ModifiedAt:                  settings.ModifiedAt,
+		}
+	}
+
+	mfaSettings, err := s.repo.GetUserMFAMethods(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	if mfaSettings != nil {
+		export.MFA = exportMFA(mfaSettings)
+	}
+
+	devices, err := s.repo.GetUserDevices(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	for _, device := range devices {
+		export.Devices = append(export.Devices, ExportedDevice{
+			DeviceID:   device.DeviceID,
+			DeviceName: device.DeviceName,
+			DeviceType: device.DeviceType,
+			Trusted:    device.Trusted,
+			LastUsed:   device.LastUsed,
+			CreatedAt:  device.CreatedAt,
+		})
+	}
+
+	sessions, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, ExportedSession{
+			ID:         session.ID,
+			DeviceID:   session.DeviceID,
+			DeviceName: session.DeviceName,
+			AppVersion: session.AppVersion,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastActive: session.LastActive,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+
+	events, err := s.repo.GetSecurityEvents(userID, since, s.security.ExportEventLimit)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	for _, event := range events {
+		export.Events = append(export.Events, ExportedSecurityEvent{
+			ID:        event.ID,
+			EventType: event.EventType,
+			Success:   event.Success,
+			Metadata:  event.AdditionalMetadata,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	payload, err := json.Marshal(export)
+	if err != nil {
+		return nil, err
+	}
+
+	// The export itself is a security relevant action, record it after the snapshot is taken
+	_ = s.repo.CreateSecurityEvent(&models.UserSecurityEvent{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		EventType: SECURITY_EVENT_SETTINGS_EXPORTED,
+		Success:   true,
+	})
+
+	return &SignedSecurityExport{
+		Export:    payload,
+		Signature: signSecurityExport(payload, s.security.ExportSigningKey),
+		Algorithm: SECURITY_EXPORT_ALGORITHM,
+		SignedAt:  now,
+	}, nil
+}
+
+// exportMFA converts MFA settings into their exported form, dropping every secret
+func exportMFA(settings *models.UserMFASettings) ExportedMFA {
+	mfa := ExportedMFA{
+		PreferredMethod:  settings.PreferredMethod,
+		BackupCodesCount: len(settings.BackupCodes),
+		Methods:          []ExportedMFAMethod{},
+	}
+
+	if settings.Email != nil {
+		mfa.Methods = append(mfa.Methods, ExportedMFAMethod{
+			Type:       "email",
+			Enabled:    settings.Email.Enabled,
+			Verified:   settings.Email.Verified,
+			LastUsed:   settings.Email.LastUsed,
+			ModifiedAt: settings.Email.ModifiedAt,
+		})
+	}
+	if settings.Phone != nil {
+		mfa.Methods = append(mfa.Methods, ExportedMFAMethod{
+			Type:       "phone",
+			Enabled:    settings.Phone.Enabled,
+			Verified:   settings.Phone.Verified,
+			LastUsed:   settings.Phone.LastUsed,
+			ModifiedAt: settings.Phone.ModifiedAt,
+		})
+	}
+	if settings.TOTP != nil {
+		mfa.Methods = append(mfa.Methods, ExportedMFAMethod{
+			Type:       "totp",
+			Enabled:    settings.TOTP.Enabled,
+			Verified:   settings.TOTP.Verified,
+			LastUsed:   settings.TOTP.LastUsed,
+			ModifiedAt: settings.TOTP.ModifiedAt,
+		})
+	}
+
+	return mfa
+}
+
+// signSecurityExport returns the hex encoded HMAC-SHA256 of the exported snapshot bytes
+func signSecurityExport(payload []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
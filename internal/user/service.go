@@ -3,8 +3,12 @@ package user
 import (
 	"cirrussync-api/internal/drive"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/payments"
+	"cirrussync-api/internal/quota"
 	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/config"
 	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/tracing"
 	"context"
 	"slices"
 	"strings"
@@ -13,16 +17,20 @@ import (
 )
 
 // NewService creates a new user service
-func NewService(repo Repository, redisClient *redis.Client, driveService *drive.Service) *Service {
+func NewService(repo Repository, redisClient *redis.Client, driveService *drive.Service, securityConfig *config.SecurityConfig) *Service {
 	return &Service{
 		repo:         repo,
 		driveService: driveService,
 		redisClient:  redisClient,
+		security:     securityConfig,
 	}
 }
 
 // GetUserById retrieves a user by ID with cache lookup
 func (s *Service) GetUserById(ctx context.Context, userID string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "user.GetUserById")
+	defer span.End()
+
 	if userID == "" {
 		return nil, ErrInvalidInput
 	}
@@ -34,7 +42,7 @@ func (s *Service) GetUserById(ctx context.Context, userID string) (*models.User,
 	}
 
 	// Not in cache, get from database
-	user, err = s.repo.FindUserByID(userID)
+	user, err = s.repo.FindUserByID(ctx, userID)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -47,6 +55,9 @@ func (s *Service) GetUserById(ctx context.Context, userID string) (*models.User,
 
 // GetUserByEmail retrieves a user by email
 func (s *Service) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "user.GetUserByEmail")
+	defer span.End()
+
 	if email == "" {
 		return nil, ErrInvalidInput
 	}
@@ -62,7 +73,7 @@ func (s *Service) GetUserByEmail(ctx context.Context, email string) (*models.Use
 	}
 
 	// Not in cache, get from database
-	user, err := s.repo.FindUserOneWhere(&email, nil)
+	user, err := s.repo.FindUserOneWhere(ctx, &email, nil)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -75,6 +86,9 @@ func (s *Service) GetUserByEmail(ctx context.Context, email string) (*models.Use
 
 // GetUserByUsername retrieves a user by username
 func (s *Service) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "user.GetUserByUsername")
+	defer span.End()
+
 	if username == "" {
 		return nil, ErrInvalidInput
 	}
@@ -87,7 +101,7 @@ func (s *Service) GetUserByUsername(ctx context.Context, username string) (*mode
 	}
 
 	// Not in cache, get from database
-	user, err := s.repo.FindUserOneWhere(nil, &username)
+	user, err := s.repo.FindUserOneWhere(ctx, nil, &username)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -100,6 +114,9 @@ func (s *Service) GetUserByUsername(ctx context.Context, username string) (*mode
 
 // CreateUser creates a new user
 func (s *Service) CreateUser(ctx context.Context, email, username string, key UserKey) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "user.CreateUser")
+	defer span.End()
+
 	// Check context for cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -114,6 +131,9 @@ func (s *Service) CreateUser(ctx context.Context, email, username string, key Us
 	if err := validator.ValidateCreate(email, username, &key); err != nil {
 		return nil, err
 	}
+	if s.IsUsernameReserved(username) {
+		return nil, ErrUsernameReserved
+	}
 
 	// Check if email or username exists (perform both checks in parallel)
 	emailCh := make(chan error, 1)
@@ -145,27 +165,23 @@ func (s *Service) CreateUser(ctx context.Context, email, username string, key Us
 		return nil, err
 	}
 
-	// Create new user
+	// Create new user. The Stripe customer is created in the background, until then
+	// the user ID keeps the unique customer column filled.
+	userID := utils.GenerateUserID()
 	user := &models.User{
-		ID:               utils.GenerateUserID(),
+		ID:               userID,
 		Email:            email,
 		Username:         username,
 		DisplayName:      username,
 		EmailVerified:    true,
-		StripeCustomerID: "jj", // TODO: Generate proper Stripe customer ID
-	}
-
-	// Save user first
-	savedUser, err := s.repo.SaveUser(user)
-	if err != nil {
-		s.logger.Error("Failed to save user", "error", err)
-		return nil, ErrDatabaseError
+		StripeCustomerID: userID,
 	}
 
-	// Create user key - this is critical
+	// The user, the queued Stripe customer and the user key are saved in one transaction, so a
+	// concurrent signup never sees a user without a key
 	userKey := &models.UserKey{
 		ID:                  utils.GenerateLinkID(),
-		UserID:              savedUser.ID,
+		UserID:              user.ID,
 		PublicKey:           key.PublicKey,
 		PrivateKey:          key.PrivateKey,
 		Passphrase:          key.Passphrase,
@@ -174,18 +190,23 @@ func (s *Service) CreateUser(ctx context.Context, email, username string, key Us
 		Version:             key.Version,
 	}
 
-	// Save user key - critical operation
-	if err := s.repo.SaveUserKey(userKey); err != nil {
-		// If saving user key fails, delete the user and return the error
-		deleteErr := s.DeleteUser(ctx, savedUser.ID)
-		if deleteErr != nil {
-			// Log the delete error, but return the original error that caused the failure
-			s.logger.Error("Failed to delete user after key save error",
-				"userID", savedUser.ID,
-				"deleteError", deleteErr,
-				"originalError", err)
+	var savedUser *models.User
+	err := s.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		savedUser, err = s.repo.SaveUserWithOutbox(ctx, user, payments.NewCustomerEntry(userID))
+		if err != nil {
+			s.logger.Error("Failed to save user", "error", err)
+			return ErrDatabaseError
+		}
+
+		if err := s.repo.SaveUserKey(ctx, userKey); err != nil {
+			s.logger.Error("Failed to save user key", "userID", user.ID, "error", err)
+			return ErrKeyCreationFailed
 		}
-		return nil, ErrKeyCreationFailed
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Create non-critical resources in parallel
@@ -255,7 +276,8 @@ func (s *Service) CreateUser(ctx context.Context, email, username string, key Us
 	return savedUser, nil
 }
 
-// UpdateUser updates a user
+// UpdateUser updates a user's profile. The email address is not part of the profile, it is only
+// changed once the new address is verified.
 func (s *Service) UpdateUser(ctx context.Context, userID string, updates map[string]interface{}) (*models.User, error) {
 	if userID == "" {
 		return nil, ErrInvalidInput
@@ -272,22 +294,6 @@ func (s *Service) UpdateUser(ctx context.Context, userID string, updates map[str
 		user.DisplayName = displayName
 	}
 
-	if email, ok := updates["email"].(string); ok && email != "" {
-		// Validate email
-		validator := NewUserValidator()
-		if !validator.ValidateEmail(email) {
-			return nil, ErrInvalidEmail
-		}
-
-		// Check if email exists for another user
-		existingUser, err := s.GetUserByEmail(ctx, email)
-		if err == nil && existingUser != nil && existingUser.ID != userID {
-			return nil, ErrEmailAlreadyExists
-		}
-
-		user.Email = email
-	}
-
 	if phoneNumber, ok := updates["phone_number"].(string); ok {
 		user.PhoneNumber = &phoneNumber
 	}
@@ -365,7 +371,8 @@ func (s *Service) GetUserKeys(ctx context.Context, userID string) ([]*models.Use
 	return keys, nil
 }
 
-// AddUserKey adds a new key for a user and deactivates existing keys
+// AddUserKey adds a new key for a user and deactivates existing keys. Shares the user owns are
+// locked until they are unlocked or their key rotation re-wraps them for the new key.
 func (s *Service) AddUserKey(ctx context.Context, userID string, key UserKey) (*models.UserKey, error) {
 	if userID == "" {
 		return nil, ErrInvalidInput
@@ -406,7 +413,7 @@ func (s *Service) AddUserKey(ctx context.Context, userID string, key UserKey) (*
 	}
 
 	// Save user key
-	err = s.repo.SaveUserKey(userKey)
+	err = s.repo.SaveUserKey(ctx, userKey)
 	if err != nil {
 		s.logger.Error("Failed to save user key", "error", err)
 		return nil, ErrDatabaseError
@@ -416,6 +423,13 @@ func (s *Service) AddUserKey(ctx context.Context, userID string, key UserKey) (*
 	keysKey := redisKeyForUserKeys(userID)
 	_, _ = s.redisClient.Delete(ctx, keysKey)
 
+	// Share passphrases are still wrapped for the replaced key until a member re-wraps them
+	if len(existingKeys) > 0 && s.driveService != nil {
+		if _, err := s.driveService.LockOwnedShares(ctx, userID); err != nil {
+			s.logger.Error("Failed to lock shares after key rotation", "userID", userID, "error", err)
+		}
+	}
+
 	return userKey, nil
 }
 
@@ -535,7 +549,7 @@ func (s *Service) GetUser(ctx context.Context, userID string) (User, error) {
 		responseUser.MaxDriveSpace = int(enrichedModel.Storage.MaxSpace)
 		responseUser.UsedDriveSpace = int(enrichedModel.Storage.UsedSpace)
 	} else {
-		responseUser.MaxDriveSpace = 3221225472 // 3GB default
+		responseUser.MaxDriveSpace = int(quota.DEFAULT_STORAGE_QUOTA)
 		responseUser.UsedDriveSpace = 0
 	}
 
@@ -563,6 +577,38 @@ func (s *Service) GetUser(ctx context.Context, userID string) (User, error) {
 	return responseUser, nil
 }
 
+// GetPlanStatus summarizes the user's current plan. Users without an active plan are on the free tier.
+func (s *Service) GetPlanStatus(ctx context.Context, userID string) (*PlanStatus, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	userPlans, err := s.repo.GetUserPlans(userID)
+	if err != nil {
+		s.logger.Error("Failed to get user plans", "error", err, "userID", userID)
+		return nil, ErrDatabaseError
+	}
+
+	planStatus := &PlanStatus{
+		PlanType:   PLAN_TYPE_FREE,
+		Subscribed: isActiveSubscription(userPlans),
+		Delinquent: isDelinquentAccount(userPlans),
+	}
+	for _, plan := range userPlans {
+		if plan.Status == "active" {
+			planStatus.PlanID = plan.PlanID
+			planStatus.PlanType = plan.PlanType
+			planStatus.Status = plan.Status
+			planStatus.BillingCycle = plan.BillingCycle
+			planStatus.AutoRenew = plan.AutoRenew
+			planStatus.CurrentPeriodEnd = plan.CurrentPeriodEnd
+			break
+		}
+	}
+
+	return planStatus, nil
+}
+
 // enrichUserData loads all related data for a user
 func (s *Service) enrichUserData(ctx context.Context, user *models.User) (*models.User, error) {
 	// Load user's keys
@@ -622,6 +668,19 @@ func (s *Service) enrichUserData(ctx context.Context, user *models.User) (*model
 	return user, nil
 }
 
+// IsTwoFactorRequired reports whether the user's security settings require a second factor at login
+func (s *Service) IsTwoFactorRequired(ctx context.Context, userID string) (bool, error) {
+	if userID == "" {
+		return false, ErrInvalidInput
+	}
+
+	settings, err := s.repo.GetUserSecuritySettings(userID)
+	if err != nil {
+		return false, err
+	}
+	return settings != nil && settings.TwoFactorRequired, nil
+}
+
 // ValidateEmail is a helper function to validate email format
 func (s *Service) ValidateEmail(email string) bool {
 	validator := NewUserValidator()
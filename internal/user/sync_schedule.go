@@ -0,0 +1,177 @@
+package user
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Sync schedule actions
+const (
+	SYNC_ACTION_PAUSE = "pause" // Stop syncing during the window
+	SYNC_ACTION_LIMIT = "limit" // Sync at the window's bandwidth caps
+)
+
+// Sync schedule limits
+const (
+	MAX_SYNC_RULES     = 20
+	MAX_BANDWIDTH_KBPS = 10_000_000 // 10 Gbit/s, anything above is treated as a typo
+	syncTimeLayout     = "15:04"
+)
+
+// SyncSchedule holds the sync pauses and bandwidth caps a desktop client applies on one device.
+// The server only stores it; clients evaluate the rules against their own clock and connection.
+type SyncSchedule struct {
+	TimeZone          string     `json:"timeZone"`          // IANA zone the rule times are in
+	UploadLimitKbps   int        `json:"uploadLimitKbps"`   // Cap outside any rule, 0 is unlimited
+	DownloadLimitKbps int        `json:"downloadLimitKbps"` // Cap outside any rule, 0 is unlimited
+	Rules             []SyncRule `json:"rules"`
+}
+
+// SyncRule pauses or caps sync during a weekly time window. A window whose end is before its start
+// runs past midnight.
+type SyncRule struct {
+	Days              []int  `json:"days"`  // 0=Sunday … 6=Saturday
+	Start             string `json:"start"` // HH:MM
+	End               string `json:"end"`   // HH:MM
+	Action            string `json:"action"`
+	MeteredOnly       bool   `json:"meteredOnly"` // Only applies on metered connections
+	UploadLimitKbps   int    `json:"uploadLimitKbps,omitempty"`
+	DownloadLimitKbps int    `json:"downloadLimitKbps,omitempty"`
+}
+
+// UpdateSyncSchedule replaces the sync schedule of one of the user's devices, or clears it when
+// schedule is nil. The device's clients learn about the change from the drive change feed.
+func (s *Service) UpdateSyncSchedule(ctx context.Context, userID, deviceID string, schedule *SyncSchedule) (*models.UserDevice, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var encoded json.RawMessage
+	if schedule != nil {
+		if err := validateSyncSchedule(schedule); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(schedule)
+		if err != nil {
+			return nil, err
+		}
+		encoded = data
+	}
+
+	device, err := s.findDevice(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	device.SyncSchedule = encoded
+	device.SyncScheduleModifiedAt = time.Now().Unix()
+	if err := s.repo.UpdateUserDevice(device); err != nil {
+		return nil, ErrDatabaseError
+	}
+
+	if s.driveService != nil {
+		s.driveService.RecordDeviceSettingsChange(ctx, userID, device.ID)
+	}
+
+	return device, nil
+}
+
+// GetSyncSchedule returns the sync schedule of the device the client with the given UID runs on,
+// or nil when it has none or is not registered
+func (s *Service) GetSyncSchedule(ctx context.Context, userID, deviceUID string) (*SyncSchedule, error) {
+	// Check context for cancellation
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if deviceUID == "" {
+		return nil, nil
+	}
+
+	device, err := s.findDeviceByUID(userID, deviceUID)
+	if err == ErrDeviceNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeSyncSchedule(device), nil
+}
+
+// DecodeSyncSchedule returns the sync schedule stored on a device, or nil when it has none
+func DecodeSyncSchedule(device *models.UserDevice) *SyncSchedule {
+	if len(device.SyncSchedule) == 0 || string(device.SyncSchedule) == "null" {
+		return nil
+	}
+
+	var schedule SyncSchedule
+	if err := json.Unmarshal(device.SyncSchedule, &schedule); err != nil {
+		return nil
+	}
+	return &schedule
+}
+
+// validateSyncSchedule checks a schedule is one clients can apply
+func validateSyncSchedule(schedule *SyncSchedule) error {
+	if schedule.TimeZone == "" {
+		schedule.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		return ErrInvalidSyncSchedule
+	}
+	if !isValidBandwidth(schedule.UploadLimitKbps) || !isValidBandwidth(schedule.DownloadLimitKbps) {
+		return ErrInvalidSyncSchedule
+	}
+	if len(schedule.Rules) > MAX_SYNC_RULES {
+		return ErrInvalidSyncSchedule
+	}
+
+	for _, rule := range schedule.Rules {
+		if len(rule.Days) == 0 || len(rule.Days) > 7 {
+			return ErrInvalidSyncSchedule
+		}
+		seen := make(map[int]bool, len(rule.Days))
+		for _, day := range rule.Days {
+			if day < 0 || day > 6 || seen[day] {
+				return ErrInvalidSyncSchedule
+			}
+			seen[day] = true
+		}
+
+		start, err := time.Parse(syncTimeLayout, rule.Start)
+		if err != nil {
+			return ErrInvalidSyncSchedule
+		}
+		end, err := time.Parse(syncTimeLayout, rule.End)
+		if err != nil || start.Equal(end) {
+			return ErrInvalidSyncSchedule
+		}
+
+		switch rule.Action {
+		case SYNC_ACTION_PAUSE:
+			if rule.UploadLimitKbps != 0 || rule.DownloadLimitKbps != 0 {
+				return ErrInvalidSyncSchedule
+			}
+		case SYNC_ACTION_LIMIT:
+			if !isValidBandwidth(rule.UploadLimitKbps) || !isValidBandwidth(rule.DownloadLimitKbps) {
+				return ErrInvalidSyncSchedule
+			}
+			if rule.UploadLimitKbps == 0 && rule.DownloadLimitKbps == 0 {
+				return ErrInvalidSyncSchedule
+			}
+		default:
+			return ErrInvalidSyncSchedule
+		}
+	}
+
+	return nil
+}
+
+// isValidBandwidth reports whether a cap is unlimited or within range
+func isValidBandwidth(kbps int) bool {
+	return kbps >= 0 && kbps <= MAX_BANDWIDTH_KBPS
+}
@@ -4,7 +4,9 @@ import (
 	"cirrussync-api/internal/drive"
 	"cirrussync-api/internal/logger"
 	"cirrussync-api/internal/models"
+	"cirrussync-api/pkg/config"
 	"cirrussync-api/pkg/redis"
+	"context"
 )
 
 // Service defines the user service
@@ -12,20 +14,33 @@ type Service struct {
 	repo         Repository
 	redisClient  *redis.Client
 	driveService *drive.Service
+	security     *config.SecurityConfig
 	logger       *logger.Logger
 }
 
 // Repository defines the user repository interface
 type Repository interface {
+	// WithTransaction runs fn in one transaction carried by the context it receives
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+
 	// User operations
 	SaveUser(user *models.User) (*models.User, error)
+	SaveUserWithOutbox(ctx context.Context, user *models.User, outbox *models.PaymentOutbox) (*models.User, error)
 	UpdateUserById(id string, user *models.User) (*models.User, error)
-	FindUserByID(id string) (*models.User, error)
-	FindUserOneWhere(email *string, username *string) (*models.User, error)
+	FindUserByID(ctx context.Context, id string) (*models.User, error)
+	FindUserOneWhere(ctx context.Context, email *string, username *string) (*models.User, error)
 	DeleteUser(id string) error
 
+	// Account deletion operations
+	ScheduleDeletion(ctx context.Context, userID string, scheduledAt int64) (bool, error)
+	ClearDeletion(ctx context.Context, userID string) (bool, error)
+	GetDeletionsToWarn(ctx context.Context, scheduledBefore, lead int64, limit int) ([]*models.User, error)
+	MarkDeletionWarned(ctx context.Context, userID string, scheduledAt, warnedAt int64) error
+	GetDeletionsDue(ctx context.Context, now int64, limit int) ([]string, error)
+	PurgeUser(ctx context.Context, userID string) error
+
 	// Key operations
-	SaveUserKey(userKey *models.UserKey) error
+	SaveUserKey(ctx context.Context, userKey *models.UserKey) error
 	FindUserKeysByUserID(userID string) ([]*models.UserKey, error)
 	UpdateUserKey(userKey *models.UserKey) error
 	DeleteUserKey(id string) error
@@ -77,6 +92,16 @@ type Repository interface {
 	SaveUserDevice(device *models.UserDevice) error
 	UpdateUserDevice(device *models.UserDevice) error
 	DeleteUserDevice(id string) error
+
+	// Security export operations
+	GetUserMFAMethods(userID string) (*models.UserMFASettings, error)
+	GetActiveSessions(userID string) ([]models.UserSession, error)
+	GetSecurityEvents(userID string, since int64, limit int) ([]models.UserSecurityEvent, error)
+	CreateSecurityEvent(event *models.UserSecurityEvent) error
+
+	// Consent operations
+	GetUserConsents(userID string) ([]models.UserConsent, error)
+	SaveUserConsents(userID string, consents []*models.UserConsent, records []*models.UserConsentRecord) error
 }
 
 // UserKey represents a user's encryption key
@@ -129,6 +154,21 @@ type PlanSelection struct {
 	Completed bool `json:"completed"`
 }
 
+// PLAN_TYPE_FREE is reported for users without an active plan
+const PLAN_TYPE_FREE = "free"
+
+// PlanStatus summarizes a user's current plan
+type PlanStatus struct {
+	PlanID           string
+	PlanType         string
+	Status           string
+	BillingCycle     string
+	AutoRenew        bool
+	CurrentPeriodEnd int64
+	Subscribed       bool
+	Delinquent       bool
+}
+
 // OnboardingFlags represents all onboarding status flags
 type OnboardingFlags struct {
 	DriveSetup    DriveSetup    `json:"driveSetup"`
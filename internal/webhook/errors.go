@@ -0,0 +1,15 @@
+package webhook
+
+import (
+	"errors"
+)
+
+// Webhook errors
+var (
+	ErrInvalidInput     = errors.New("Invalid input")
+	ErrInvalidURL       = errors.New("Webhook URL must be an absolute https URL")
+	ErrForbiddenURL     = errors.New("Webhook URL must not point to a private or reserved network address")
+	ErrInvalidSignature = errors.New("Invalid webhook signature")
+	ErrDeliveryFailed   = errors.New("Webhook receiver could not be reached")
+	ErrTooManyTests     = errors.New("Too many test deliveries, please try again later")
+)
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"cirrussync-api/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository interface for webhook operations
+type Repository interface {
+	GetSecret(ctx context.Context, userID string) (*models.WebhookSecret, error)
+	CreateSecret(ctx context.Context, secret *models.WebhookSecret) (*models.WebhookSecret, error)
+	UpdateSecret(ctx context.Context, userID, secret string) error
+}
+
+// repo implements the Repository interface
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new webhook repository
+func NewRepository(database *gorm.DB) Repository {
+	return &repo{
+		db: database,
+	}
+}
+
+// GetSecret gets the signing secret of a user, nil when the user has none yet
+func (r *repo) GetSecret(ctx context.Context, userID string) (*models.WebhookSecret, error) {
+	var secret models.WebhookSecret
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&secret).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// CreateSecret stores a signing secret unless the user already has one, and returns the one that is kept
+func (r *repo) CreateSecret(ctx context.Context, secret *models.WebhookSecret) (*models.WebhookSecret, error) {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).
+		Create(secret).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// A concurrent request may have created it first
+	return r.GetSecret(ctx, secret.UserID)
+}
+
+// UpdateSecret replaces the signing secret of a user
+func (r *repo) UpdateSecret(ctx context.Context, userID, secret string) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookSecret{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"secret":      secret,
+			"modified_at": time.Now().Unix(),
+		}).Error
+}
@@ -0,0 +1,280 @@
+package webhook
+
+import (
+	"bytes"
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/internal/models"
+	"cirrussync-api/internal/utils"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	// EVENT_TYPE_TEST is sent by test deliveries only
+	EVENT_TYPE_TEST = "webhook.test"
+
+	// secretPrefix marks signing secrets so they are recognizable in configuration files
+	secretPrefix = "whsec_"
+
+	// testDeliveryWindow is the window MaxTestDeliveries applies to
+	testDeliveryWindow = time.Hour
+
+	// maxReceiverResponseBytes bounds how much of a receiver's response is read
+	maxReceiverResponseBytes = 4096
+
+	userAgent = "CirrusSync-Webhooks/1.0"
+)
+
+// NewService creates a new webhook service
+func NewService(repo Repository, redisClient *redis.Client, logger *logger.Logger, cfg *config.WebhookConfig) *Service {
+	return &Service{
+		repo:        repo,
+		redisClient: redisClient,
+		logger:      logger,
+		config:      cfg,
+		httpClient:  newDeliveryClient(cfg),
+	}
+}
+
+// GetSigningInfo describes the signing scheme of deliveries
+func (s *Service) GetSigningInfo() SigningInfo {
+	return SigningInfo{
+		Header:           SIGNATURE_HEADER,
+		Algorithm:        SIGNATURE_ALGORITHM,
+		Scheme:           SIGNATURE_SCHEME,
+		SignedPayload:    "<t>.<raw request body>",
+		ToleranceSeconds: int64(s.config.SignatureTolerance.Seconds()),
+		EventIDHeader:    EVENT_ID_HEADER,
+		EventTypeHeader:  EVENT_TYPE_HEADER,
+	}
+}
+
+// GetSecret returns the signing secret of a user, creating it on first use
+func (s *Service) GetSecret(ctx context.Context, userID string) (string, error) {
+	if userID == "" {
+		return "", ErrInvalidInput
+	}
+
+	existing, err := s.repo.GetSecret(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load webhook secret: %w", err)
+	}
+	if existing != nil {
+		return existing.Secret, nil
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	created, err := s.repo.CreateSecret(ctx, &models.WebhookSecret{UserID: userID, Secret: secret})
+	if err != nil || created == nil {
+		return "", fmt.Errorf("failed to create webhook secret: %w", err)
+	}
+	return created.Secret, nil
+}
+
+// RotateSecret replaces the signing secret of a user. Deliveries are signed with the new
+// secret right away, so receivers must be updated before the next event.
+func (s *Service) RotateSecret(ctx context.Context, userID string) (string, error) {
+	if _, err := s.GetSecret(ctx, userID); err != nil {
+		return "", err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.UpdateSecret(ctx, userID, secret); err != nil {
+		return "", fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+
+	s.logger.Infof("Rotated webhook secret of %s", userID)
+	return secret, nil
+}
+
+// SendTestEvent delivers a signed test event to a receiver so integrators can check their
+// signature verification. A receiver that answers with an error is reported, not returned as one.
+func (s *Service) SendTestEvent(ctx context.Context, userID, rawURL string) (*TestDelivery, error) {
+	if userID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	target, err := s.validateURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.countTestDelivery(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	secret, err := s.GetSecret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := Event{
+		ID:        "evt_" + utils.GenerateID(),
+		Type:      EVENT_TYPE_TEST,
+		CreatedAt: time.Now().Unix(),
+		Data: map[string]string{
+			"message": "This is a test delivery from CirrusSync. Verify its signature to check your receiver.",
+		},
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := Sign(payload, secret, time.Now())
+	delivery := &TestDelivery{
+		EventID:   event.ID,
+		URL:       target.String(),
+		Signature: signature,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(SIGNATURE_HEADER, signature)
+	req.Header.Set(EVENT_ID_HEADER, event.ID)
+	req.Header.Set(EVENT_TYPE_HEADER, event.Type)
+
+	started := time.Now()
+	resp, err := s.httpClient.Do(req)
+	delivery.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		delivery.Error = deliveryError(err)
+		return delivery, nil
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxReceiverResponseBytes))
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	return delivery, nil
+}
+
+// validateURL accepts absolute https URLs, or http ones when private URLs are allowed for development
+func (s *Service) validateURL(rawURL string) (*url.URL, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" || target.User != nil {
+		return nil, ErrInvalidURL
+	}
+
+	switch target.Scheme {
+	case "https":
+	case "http":
+		if !s.config.AllowPrivateURLs {
+			return nil, ErrInvalidURL
+		}
+	default:
+		return nil, ErrInvalidURL
+	}
+
+	// Literal addresses are refused up front; host names are checked again when dialing
+	if ip := net.ParseIP(target.Hostname()); ip != nil && !s.config.AllowPrivateURLs && !isPublicIP(ip) {
+		return nil, ErrForbiddenURL
+	}
+
+	return target, nil
+}
+
+// countTestDelivery applies the hourly test delivery limit of a user
+func (s *Service) countTestDelivery(ctx context.Context, userID string) error {
+	key := "webhook:test:" + userID
+	count, err := s.redisClient.Incr(ctx, key)
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		_, _ = s.redisClient.Expire(ctx, key, testDeliveryWindow)
+	}
+	if count > int64(s.config.MaxTestDeliveries) {
+		return ErrTooManyTests
+	}
+	return nil
+}
+
+// newDeliveryClient creates the HTTP client deliveries are made with. Redirects are not followed and,
+// unless private URLs are allowed, connections to non-public addresses are refused after DNS resolution
+// so a host name cannot be used to reach internal services.
+func newDeliveryClient(cfg *config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateURLs {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrForbiddenURL
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublicIP reports whether an address is routable on the public internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	// Carrier-grade NAT, commonly used for internal cloud networks
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return false
+	}
+	return true
+}
+
+// deliveryError describes why a receiver could not be reached without leaking internal details
+func deliveryError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrForbiddenURL):
+		return ErrForbiddenURL.Error()
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "Receiver did not respond in time"
+	default:
+		return ErrDeliveryFailed.Error()
+	}
+}
+
+// generateSecret returns a new random signing secret
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"cirrussync-api/internal/logger"
+	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/redis"
+	"net/http"
+)
+
+// Service signs and delivers webhooks to integrators
+type Service struct {
+	repo        Repository
+	redisClient *redis.Client
+	logger      *logger.Logger
+	config      *config.WebhookConfig
+	httpClient  *http.Client
+}
+
+// SigningInfo describes how deliveries are signed, so integrators can implement verification
+type SigningInfo struct {
+	Header           string
+	Algorithm        string
+	Scheme           string
+	SignedPayload    string // How the signed string is built from the timestamp and body
+	ToleranceSeconds int64  // Maximum timestamp age receivers should accept
+	EventIDHeader    string
+	EventTypeHeader  string
+}
+
+// Event is the envelope of every webhook delivery
+type Event struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	CreatedAt int64  `json:"createdAt"`
+	Data      any    `json:"data"`
+}
+
+// TestDelivery is the outcome of a test delivery
+type TestDelivery struct {
+	EventID    string
+	URL        string
+	Signature  string // Signature header that was sent
+	StatusCode int    // Status the receiver answered with, 0 if it could not be reached
+	Success    bool   // Whether the receiver answered with a 2xx status
+	DurationMs int64
+	Error      string // Why the receiver could not be reached
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"time"
+)
+
+// UsageMetricsConfig holds settings for the anonymized feature usage counters
+type UsageMetricsConfig struct {
+	Enabled       bool          // Whether requests from consenting users are counted at all
+	FlushInterval time.Duration // How often finished days are moved from Redis into the database
+	Retention     time.Duration // How long a day's Redis counters survive if they are never flushed
+}
+
+// LoadUsageMetricsConfig loads usage metrics configuration from environment variables
+func LoadUsageMetricsConfig() *UsageMetricsConfig {
+	config := &UsageMetricsConfig{
+		Enabled:       getEnvAsBool("USAGE_METRICS_ENABLED", true),
+		FlushInterval: getEnvAsDuration("USAGE_METRICS_FLUSH_INTERVAL", time.Hour),
+		Retention:     getEnvAsDuration("USAGE_METRICS_RETENTION", 7*24*time.Hour),
+	}
+
+	return config
+}
+
+// ErrorMetricsConfig holds settings for the per route API error code counters
+type ErrorMetricsConfig struct {
+	Enabled   bool          // Whether rejected and failed requests are counted at all
+	Retention time.Duration // How long an hour's Redis counters survive if they are never flushed
+}
+
+// LoadErrorMetricsConfig loads error metrics configuration from environment variables
+func LoadErrorMetricsConfig() *ErrorMetricsConfig {
+	config := &ErrorMetricsConfig{
+		Enabled:   getEnvAsBool("ERROR_METRICS_ENABLED", true),
+		Retention: getEnvAsDuration("ERROR_METRICS_RETENTION", 7*24*time.Hour),
+	}
+
+	return config
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"time"
+)
+
+// BillingConfig holds settings for the payment provider integration
+type BillingConfig struct {
+	StripeSecretKey        string        // API key for calls to Stripe, empty disables checkout and plan changes
+	StripeAPIBase          string        // Base URL of the Stripe API
+	StripeTimeout          time.Duration // Timeout of a single Stripe API call
+	StripeWebhookSecret    string        // Signing secret of the Stripe webhook endpoint, empty rejects all webhooks
+	StripeWebhookTolerance time.Duration // Maximum age of a webhook signature timestamp
+	CheckoutSuccessURL     string        // Where Stripe sends the user after a completed checkout
+	CheckoutCancelURL      string        // Where Stripe sends the user after an abandoned checkout
+}
+
+// LoadBillingConfig loads billing configuration from environment variables
+func LoadBillingConfig() *BillingConfig {
+	config := &BillingConfig{
+		StripeSecretKey:        getEnv("STRIPE_SECRET_KEY", ""),
+		StripeAPIBase:          getEnv("STRIPE_API_BASE", "https://api.stripe.com"),
+		StripeTimeout:          getEnvAsDuration("STRIPE_TIMEOUT", 10*time.Second),
+		StripeWebhookSecret:    getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		StripeWebhookTolerance: getEnvAsDuration("STRIPE_WEBHOOK_TOLERANCE", 5*time.Minute),
+		CheckoutSuccessURL:     getEnv("CHECKOUT_SUCCESS_URL", "https://cirrussync.me/billing?checkout=success"),
+		CheckoutCancelURL:      getEnv("CHECKOUT_CANCEL_URL", "https://cirrussync.me/billing?checkout=canceled"),
+	}
+
+	return config
+}
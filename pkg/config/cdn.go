@@ -0,0 +1,23 @@
+package config
+
+import (
+	"time"
+)
+
+// CDNConfig holds settings for purging cached responses from the CDN
+type CDNConfig struct {
+	PurgeURL string        // Endpoint a surrogate key is appended to for purging, empty disables purging
+	APIToken string        // Token sent with purge requests
+	Timeout  time.Duration // Timeout for a single purge request
+}
+
+// LoadCDNConfig loads CDN configuration from environment variables
+func LoadCDNConfig() *CDNConfig {
+	config := &CDNConfig{
+		PurgeURL: getEnv("CDN_PURGE_URL", ""),
+		APIToken: getEnv("CDN_API_TOKEN", ""),
+		Timeout:  getEnvAsDuration("CDN_PURGE_TIMEOUT", 5*time.Second),
+	}
+
+	return config
+}
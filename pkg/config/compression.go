@@ -0,0 +1,17 @@
+package config
+
+// CompressionConfig holds settings for compressing API responses
+type CompressionConfig struct {
+	Enabled bool // Whether responses are compressed at all
+	MinSize int  // Responses smaller than this many bytes are sent uncompressed
+}
+
+// LoadCompressionConfig loads response compression configuration from environment variables
+func LoadCompressionConfig() *CompressionConfig {
+	config := &CompressionConfig{
+		Enabled: getEnvAsBool("COMPRESSION_ENABLED", true),
+		MinSize: getEnvAsInt("COMPRESSION_MIN_SIZE", 1024),
+	}
+
+	return config
+}
@@ -12,11 +12,15 @@ import (
 // AppConfig holds all configuration settings for the application
 type AppConfig struct {
 	// Server settings
-	Port            string
-	Host            string
-	Environment     string
-	RequestTimeout  int
-	ShutdownTimeout int
+	Port              string
+	Host              string
+	Environment       string
+	RequestTimeout    int // Seconds a request may take on routes without a budget of their own
+	ReadHeaderTimeout int // Seconds a client may take to send request headers
+	ReadTimeout       int // Seconds a client may take to send a whole request
+	WriteTimeout      int // Seconds a response may take from the end of the request headers
+	IdleTimeout       int // Seconds a keep-alive connection may wait for the next request
+	ShutdownTimeout   int
 
 	// Mail settings (from mail.go)
 	Mail *MailConfig
@@ -32,6 +36,12 @@ type AppConfig struct {
 
 	// S3 settings (from s3.go)
 	S3 *S3Config
+
+	// Drive settings (from drive.go)
+	Drive *DriveConfig
+
+	// Sandbox settings (from sandbox.go)
+	Sandbox *SandboxConfig
 }
 
 var (
@@ -47,11 +57,15 @@ func LoadConfig() *AppConfig {
 
 		appConfig = &AppConfig{
 			// Server settings
-			Port:            getEnv("PORT", "8000"),
-			Host:            getEnv("HOST", "localhost"),
-			Environment:     getEnv("ENVIRONMENT", "development"),
-			RequestTimeout:  getEnvAsInt("REQUEST_TIMEOUT", 30),
-			ShutdownTimeout: getEnvAsInt("SHUTDOWN_TIMEOUT", 10),
+			Port:              getEnv("PORT", "8000"),
+			Host:              getEnv("HOST", "localhost"),
+			Environment:       getEnv("ENVIRONMENT", "development"),
+			RequestTimeout:    getEnvAsInt("REQUEST_TIMEOUT", 30),
+			ReadHeaderTimeout: getEnvAsInt("HTTP_READ_HEADER_TIMEOUT", 10),
+			ReadTimeout:       getEnvAsInt("HTTP_READ_TIMEOUT", 60),
+			WriteTimeout:      getEnvAsInt("HTTP_WRITE_TIMEOUT", 120),
+			IdleTimeout:       getEnvAsInt("HTTP_IDLE_TIMEOUT", 120),
+			ShutdownTimeout:   getEnvAsInt("SHUTDOWN_TIMEOUT", 10),
 
 			// Load database and redis configurations
 			Database: LoadDatabaseConfig(),
@@ -59,6 +73,14 @@ func LoadConfig() *AppConfig {
 			S3:       LoadS3Config(),
 			Mail:     LoadMailConfig(),
 			TOTP:     LoadTOTPConfig(),
+			Drive:    LoadDriveConfig(),
+			Sandbox:  LoadSandboxConfig(),
+		}
+
+		// The sandbox keeps its tables and keys apart from real accounts
+		if appConfig.Sandbox.Enabled {
+			appConfig.Database.Schema = appConfig.Sandbox.Schema
+			appConfig.Redis.DB = appConfig.Sandbox.RedisDB
 		}
 	})
 
@@ -84,6 +106,11 @@ func (c *AppConfig) IsProduction() bool {
 	return c.Environment == "production"
 }
 
+// IsSandbox returns true if the app serves the API sandbox
+func (c *AppConfig) IsSandbox() bool {
+	return c.Sandbox != nil && c.Sandbox.Enabled
+}
+
 // IsTest returns true if the app is in test mode
 func (c *AppConfig) IsTest() bool {
 	return c.Environment == "test"
@@ -16,6 +16,7 @@ type DatabaseConfig struct {
 	Port     string
 	Name     string
 	SSLMode  string
+	Schema   string // Schema on the search path; empty keeps the server default
 
 	// Connection pool settings
 	PoolMinSize     int
@@ -27,6 +28,10 @@ type DatabaseConfig struct {
 	PrepareCached   bool
 	MigrateOnBoot   bool
 	DefaultTimeZone string
+
+	// SQL migrations
+	MigrationsPath             string // Directory of versioned up and down migration files
+	AllowDestructiveMigrations bool   // Whether migrations that drop or rewrite data run on boot
 }
 
 // GetDatabaseURL returns a formatted connection string for PostgreSQL
@@ -38,6 +43,9 @@ func (c *DatabaseConfig) GetDatabaseURL() string {
 	// Add query parameters
 	params := fmt.Sprintf("?sslmode=%s&TimeZone=%s",
 		c.SSLMode, c.DefaultTimeZone)
+	if c.Schema != "" {
+		params += "&search_path=" + c.Schema
+	}
 
 	return baseURL + params
 }
@@ -72,6 +80,10 @@ func LoadDatabaseConfig() *DatabaseConfig {
 		// Features
 		PrepareCached: getEnvAsBool("DB_PREPARE_CACHED", true),
 		MigrateOnBoot: getEnvAsBool("DB_MIGRATE_ON_BOOT", true),
+
+		// SQL migrations
+		MigrationsPath:             getEnv("DB_MIGRATIONS_PATH", "migrations"),
+		AllowDestructiveMigrations: getEnvAsBool("DB_ALLOW_DESTRUCTIVE_MIGRATIONS", false),
 	}
 
 	return config
@@ -98,6 +110,17 @@ func getEnvAsDuration(key string, defaultVal time.Duration) time.Duration {
 	return defaultVal
 }
 
+// Helper to get environment variables as float
+func getEnvAsFloat(key string, defaultVal float64) float64 {
+	if val, exists := os.LookupEnv(key); exists {
+		floatVal, err := strconv.ParseFloat(val, 64)
+		if err == nil {
+			return floatVal
+		}
+	}
+	return defaultVal
+}
+
 // Helper to get environment variables as boolean
 func getEnvAsBool(key string, defaultVal bool) bool {
 	if val, exists := os.LookupEnv(key); exists {
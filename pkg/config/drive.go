@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DriveConfig holds concurrency, time budget and public link settings for the drive service
+type DriveConfig struct {
+	DefaultTimeout  time.Duration // Request budget for single-entity drive routes
+	ExtendedTimeout time.Duration // Request budget for multi-step or batch drive routes
+	MaxConcurrency  int           // Maximum concurrent workers for fan-out operations
+	PublicURLBase   string        // Base URL public links resolve under
+
+	ShareExpiryScanInterval time.Duration // How often expiring shares and public links are processed
+	ShareExpiryNotice       time.Duration // How long before a share expires its members are notified
+
+	IntegrityAuditInterval   time.Duration // How often a sample of file revisions is checked against storage
+	IntegrityAuditSampleSize int           // Revisions checked per audit pass, 0 disables the audit
+
+	UploadCleanupInterval time.Duration // How often abandoned uploads are looked for
+	UploadDraftTTL        time.Duration // How long an upload may go without activity before it is abandoned
+
+	BackupRetentionInterval time.Duration // How often backup sets are pruned to their versioning and retention rules
+
+	TrashPurgeInterval     time.Duration // How often trashed items past their retention are purged
+	TrashRetentionFree     time.Duration // How long trashed items are kept for users without a paid plan
+	TrashRetentionPlus     time.Duration // How long trashed items are kept on individual and family plans
+	TrashRetentionBusiness time.Duration // How long trashed items are kept on business and enterprise plans
+
+	RecentRetention time.Duration // How long opened and modified files are listed as recent
+
+	FolderSizeInterval time.Duration // How often folder sizes of changed shares are recomputed
+
+	StorageLifecycleInterval    time.Duration // How often stale multipart uploads and trashed blocks are processed
+	TrashedBlockTransitionAfter time.Duration // How long a file stays in the trash before its blocks move to infrequent access
+	StaleMultipartUploadAge     time.Duration // How long a block upload in parts may stay incomplete before it is aborted
+
+	GarbageCollectionInterval time.Duration // How often orphaned objects and dangling rows are looked for
+	OrphanedBlockGracePeriod  time.Duration // How old a stored block without a record must be before it is deleted
+
+	CacheTTL  time.Duration            // How long drive cache entries live unless their cache type sets otherwise
+	CacheTTLs map[string]time.Duration // Time to live per cache type, keyed by the prefix of its keys
+}
+
+// LoadDriveConfig loads drive configuration from environment variables
+func LoadDriveConfig() *DriveConfig {
+	config := &DriveConfig{
+		DefaultTimeout:  getEnvAsDuration("DRIVE_DEFAULT_TIMEOUT", 10*time.Second),
+		ExtendedTimeout: getEnvAsDuration("DRIVE_EXTENDED_TIMEOUT", 15*time.Second),
+		MaxConcurrency:  getEnvAsInt("DRIVE_MAX_CONCURRENCY", 5),
+		PublicURLBase:   getEnv("DRIVE_PUBLIC_URL_BASE", "https://cirrussync.me/urls"),
+
+		ShareExpiryScanInterval: getEnvAsDuration("DRIVE_SHARE_EXPIRY_SCAN_INTERVAL", time.Minute),
+		ShareExpiryNotice:       getEnvAsDuration("DRIVE_SHARE_EXPIRY_NOTICE", 24*time.Hour),
+
+		IntegrityAuditInterval:   getEnvAsDuration("DRIVE_INTEGRITY_AUDIT_INTERVAL", time.Hour),
+		IntegrityAuditSampleSize: getEnvAsInt("DRIVE_INTEGRITY_AUDIT_SAMPLE_SIZE", 100),
+
+		UploadCleanupInterval: getEnvAsDuration("DRIVE_UPLOAD_CLEANUP_INTERVAL", time.Hour),
+		UploadDraftTTL:        getEnvAsDuration("DRIVE_UPLOAD_DRAFT_TTL", 7*24*time.Hour),
+
+		BackupRetentionInterval: getEnvAsDuration("DRIVE_BACKUP_RETENTION_INTERVAL", 6*time.Hour),
+
+		TrashPurgeInterval:     getEnvAsDuration("DRIVE_TRASH_PURGE_INTERVAL", time.Hour),
+		TrashRetentionFree:     getEnvAsDuration("DRIVE_TRASH_RETENTION_FREE", 7*24*time.Hour),
+		TrashRetentionPlus:     getEnvAsDuration("DRIVE_TRASH_RETENTION_PLUS", 30*24*time.Hour),
+		TrashRetentionBusiness: getEnvAsDuration("DRIVE_TRASH_RETENTION_BUSINESS", 90*24*time.Hour),
+
+		RecentRetention: getEnvAsDuration("DRIVE_RECENT_RETENTION", 30*24*time.Hour),
+
+		FolderSizeInterval: getEnvAsDuration("DRIVE_FOLDER_SIZE_INTERVAL", 5*time.Minute),
+
+		StorageLifecycleInterval:    getEnvAsDuration("DRIVE_STORAGE_LIFECYCLE_INTERVAL", 6*time.Hour),
+		TrashedBlockTransitionAfter: getEnvAsDuration("DRIVE_TRASHED_BLOCK_TRANSITION_AFTER", 3*24*time.Hour),
+		StaleMultipartUploadAge:     getEnvAsDuration("DRIVE_STALE_MULTIPART_UPLOAD_AGE", 24*time.Hour),
+
+		GarbageCollectionInterval: getEnvAsDuration("DRIVE_GARBAGE_COLLECTION_INTERVAL", 24*time.Hour),
+		OrphanedBlockGracePeriod:  getEnvAsDuration("DRIVE_ORPHANED_BLOCK_GRACE_PERIOD", 24*time.Hour),
+
+		CacheTTL:  getEnvAsDuration("DRIVE_CACHE_TTL", time.Hour),
+		CacheTTLs: getEnvAsDurationMap("DRIVE_CACHE_TTLS"),
+	}
+
+	return config
+}
+
+// getEnvAsDurationMap reads comma-separated name=seconds pairs, skipping malformed ones
+func getEnvAsDurationMap(key string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		durations[strings.TrimSpace(name)] = time.Duration(seconds) * time.Second
+	}
+	return durations
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"time"
+)
+
+// JobsConfig holds settings for the background job worker pool
+type JobsConfig struct {
+	Workers      int           // Number of jobs processed concurrently
+	QueueSize    int           // Jobs buffered in memory before falling back to polling
+	PollInterval time.Duration // How often the database is checked for queued jobs
+	StaleAfter   time.Duration // Running jobs without progress for this long are requeued
+	RetryBackoff time.Duration // Wait before a failed job is retried, doubled before each further retry
+	Retention    time.Duration // How long completed and failed jobs are kept for inspection
+}
+
+// LoadJobsConfig loads background job configuration from environment variables
+func LoadJobsConfig() *JobsConfig {
+	config := &JobsConfig{
+		Workers:      getEnvAsInt("JOBS_WORKERS", 4),
+		QueueSize:    getEnvAsInt("JOBS_QUEUE_SIZE", 100),
+		PollInterval: getEnvAsDuration("JOBS_POLL_INTERVAL", 10*time.Second),
+		StaleAfter:   getEnvAsDuration("JOBS_STALE_AFTER", 5*time.Minute),
+		RetryBackoff: getEnvAsDuration("JOBS_RETRY_BACKOFF", 30*time.Second),
+		Retention:    getEnvAsDuration("JOBS_RETENTION", 7*24*time.Hour),
+	}
+
+	return config
+}
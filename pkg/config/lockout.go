@@ -0,0 +1,33 @@
+package config
+
+import (
+	"time"
+)
+
+// LoginLockoutConfig holds the thresholds that slow down and stop repeated failed logins
+type LoginLockoutConfig struct {
+	FailureWindow    time.Duration // How long failed logins are remembered after the last one
+	FreeAttempts     int           // Failed logins of an account before each further attempt is delayed
+	BaseDelay        time.Duration // Delay after the first delayed failure, doubled with every further one
+	MaxDelay         time.Duration // Longest delay between two attempts
+	AccountThreshold int           // Failed logins that lock an account
+	LockDuration     time.Duration // How long a locked account refuses logins unless it is unlocked
+	IPThreshold      int           // Failed logins from one IP address, across accounts, that block it
+	IPBlockDuration  time.Duration // How long a blocked IP address is refused
+}
+
+// LoadLoginLockoutConfig loads login lockout configuration from environment variables
+func LoadLoginLockoutConfig() *LoginLockoutConfig {
+	config := &LoginLockoutConfig{
+		FailureWindow:    getEnvAsDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		FreeAttempts:     getEnvAsInt("LOGIN_FREE_ATTEMPTS", 3),
+		BaseDelay:        getEnvAsDuration("LOGIN_BASE_DELAY", 2*time.Second),
+		MaxDelay:         getEnvAsDuration("LOGIN_MAX_DELAY", 5*time.Minute),
+		AccountThreshold: getEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LockDuration:     getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+		IPThreshold:      getEnvAsInt("LOGIN_IP_BLOCK_THRESHOLD", 50),
+		IPBlockDuration:  getEnvAsDuration("LOGIN_IP_BLOCK_DURATION", time.Hour),
+	}
+
+	return config
+}
@@ -14,6 +14,22 @@ type MailConfig struct {
 	FromEmail    string
 	BaseURL      string // Base URL for verification links
 	TokenExpiry  time.Duration
+
+	// Delivery
+	EmailProvider        string        // Provider emails are sent through: smtp, ses or sendgrid
+	EmailTimeout         time.Duration // Timeout of a single provider request
+	EmailMaxAttempts     int           // Attempts per email before it is dead-lettered
+	EmailRetryBackoff    time.Duration // Wait before the first retry, doubled before each further one
+	EmailDeadLetterLimit int64         // Failed emails kept in Redis for inspection and retry
+
+	// Amazon SES
+	SESRegion           string
+	SESAccessKeyID      string // Empty to use the default AWS credential chain
+	SESSecretAccessKey  string
+	SESConfigurationSet string // Optional configuration set for delivery and bounce tracking
+
+	// SendGrid
+	SendGridAPIKey string
 }
 
 // LoadS3Config loads S3 configuration from environment variables
@@ -26,6 +42,19 @@ func LoadMailConfig() *MailConfig {
 		FromEmail:    getEnv("SMTP_FROM_EMAIL", "no-reply@cirrussync.me"),
 		BaseURL:      getEnv("MAIL_BASE_URL", "https://cirrussync.me"),
 		TokenExpiry:  10 * time.Minute,
+
+		EmailProvider:        getEnv("EMAIL_PROVIDER", "smtp"),
+		EmailTimeout:         getEnvAsDuration("EMAIL_TIMEOUT", 10*time.Second),
+		EmailMaxAttempts:     getEnvAsInt("EMAIL_MAX_ATTEMPTS", 4),
+		EmailRetryBackoff:    getEnvAsDuration("EMAIL_RETRY_BACKOFF", time.Second),
+		EmailDeadLetterLimit: int64(getEnvAsInt("EMAIL_DEAD_LETTER_LIMIT", 1000)),
+
+		SESRegion:           getEnv("EMAIL_SES_REGION", getEnv("AWS_REGION", "us-east-1")),
+		SESAccessKeyID:      getEnv("EMAIL_SES_ACCESS_KEY_ID", ""),
+		SESSecretAccessKey:  getEnv("EMAIL_SES_SECRET_ACCESS_KEY", ""),
+		SESConfigurationSet: getEnv("EMAIL_SES_CONFIGURATION_SET", ""),
+
+		SendGridAPIKey: getEnv("SENDGRID_API_KEY", ""),
 	}
 
 	return config
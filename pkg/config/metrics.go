@@ -0,0 +1,17 @@
+package config
+
+// MetricsConfig holds settings for the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool   // Whether requests are instrumented and /metrics is served
+	Token   string // Bearer token scrapers must send, empty leaves the endpoint open
+}
+
+// LoadMetricsConfig loads metrics configuration from environment variables
+func LoadMetricsConfig() *MetricsConfig {
+	config := &MetricsConfig{
+		Enabled: getEnvAsBool("METRICS_ENABLED", true),
+		Token:   getEnv("METRICS_TOKEN", ""),
+	}
+
+	return config
+}
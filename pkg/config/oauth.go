@@ -0,0 +1,27 @@
+package config
+
+import "time"
+
+// OAuthConfig holds settings for the OAuth 2.0 / OpenID Connect provider used by third-party integrations
+type OAuthConfig struct {
+	Issuer             string        // Issuer identifier, the public base URL of this API
+	AuthorizeURL       string        // Web app page that shows the consent screen
+	CodeExpiry         time.Duration // How long an authorization code can be exchanged
+	AccessTokenExpiry  time.Duration
+	RefreshTokenExpiry time.Duration
+	IDTokenExpiry      time.Duration
+}
+
+// LoadOAuthConfig loads OAuth provider configuration from environment variables
+func LoadOAuthConfig() *OAuthConfig {
+	config := &OAuthConfig{
+		Issuer:             getEnv("OAUTH_ISSUER", "https://api.cirrussync.me"),
+		AuthorizeURL:       getEnv("OAUTH_AUTHORIZE_URL", "https://cirrussync.me/oauth/authorize"),
+		CodeExpiry:         getEnvAsDuration("OAUTH_CODE_EXPIRY", 10*time.Minute),
+		AccessTokenExpiry:  getEnvAsDuration("OAUTH_ACCESS_TOKEN_EXPIRY", time.Hour),
+		RefreshTokenExpiry: getEnvAsDuration("OAUTH_REFRESH_TOKEN_EXPIRY", 30*24*time.Hour),
+		IDTokenExpiry:      getEnvAsDuration("OAUTH_ID_TOKEN_EXPIRY", time.Hour),
+	}
+
+	return config
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"time"
+)
+
+// PaymentsConfig holds settings for the worker that delivers the payment provider outbox
+type PaymentsConfig struct {
+	PollInterval    time.Duration // How often the outbox is checked for entries that are due
+	BatchSize       int           // Entries delivered per poll
+	MaxAttempts     int           // Attempts before an entry is given up on
+	RetryBaseDelay  time.Duration // Delay before the first retry, doubled on every further attempt
+	RetryMaxDelay   time.Duration // Upper bound of the retry delay
+	ProcessingLease time.Duration // Entries claimed by a worker that stopped are retried after this long
+}
+
+// LoadPaymentsConfig loads payments outbox configuration from environment variables
+func LoadPaymentsConfig() *PaymentsConfig {
+	config := &PaymentsConfig{
+		PollInterval:    getEnvAsDuration("PAYMENTS_POLL_INTERVAL", 5*time.Second),
+		BatchSize:       getEnvAsInt("PAYMENTS_BATCH_SIZE", 20),
+		MaxAttempts:     getEnvAsInt("PAYMENTS_MAX_ATTEMPTS", 8),
+		RetryBaseDelay:  getEnvAsDuration("PAYMENTS_RETRY_BASE_DELAY", 30*time.Second),
+		RetryMaxDelay:   getEnvAsDuration("PAYMENTS_RETRY_MAX_DELAY", time.Hour),
+		ProcessingLease: getEnvAsDuration("PAYMENTS_PROCESSING_LEASE", 2*time.Minute),
+	}
+
+	return config
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"time"
+)
+
+// RateLimitRule caps the requests one client may make in a sliding window. A limit of 0 disables the rule.
+type RateLimitRule struct {
+	PerIP   int // Requests per window from one IP address
+	PerUser int // Requests per window from one authenticated user
+}
+
+// RateLimitConfig holds settings for the global request rate limiter
+type RateLimitConfig struct {
+	Enabled bool          // Whether requests are rate limited at all
+	Window  time.Duration // Length of the sliding window every rule counts over
+	Auth    RateLimitRule // Login, signup, token refresh and the OAuth token endpoint
+	Read    RateLimitRule // Drive reads
+	Upload  RateLimitRule // File creation, block and thumbnail uploads, revision commits and bulk folder creation
+	Default RateLimitRule // Everything else
+}
+
+// LoadRateLimitConfig loads rate limiter configuration from environment variables
+func LoadRateLimitConfig() *RateLimitConfig {
+	config := &RateLimitConfig{
+		Enabled: getEnvAsBool("RATE_LIMIT_ENABLED", true),
+		Window:  getEnvAsDuration("RATE_LIMIT_WINDOW", time.Minute),
+		Auth: RateLimitRule{
+			PerIP:   getEnvAsInt("RATE_LIMIT_AUTH_PER_IP", 30),
+			PerUser: getEnvAsInt("RATE_LIMIT_AUTH_PER_USER", 20),
+		},
+		Read: RateLimitRule{
+			PerIP:   getEnvAsInt("RATE_LIMIT_READ_PER_IP", 1200),
+			PerUser: getEnvAsInt("RATE_LIMIT_READ_PER_USER", 600),
+		},
+		Upload: RateLimitRule{
+			PerIP:   getEnvAsInt("RATE_LIMIT_UPLOAD_PER_IP", 600),
+			PerUser: getEnvAsInt("RATE_LIMIT_UPLOAD_PER_USER", 300),
+		},
+		Default: RateLimitRule{
+			PerIP:   getEnvAsInt("RATE_LIMIT_DEFAULT_PER_IP", 600),
+			PerUser: getEnvAsInt("RATE_LIMIT_DEFAULT_PER_USER", 300),
+		},
+	}
+
+	return config
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"strings"
+
+	"cirrussync-api/pkg/geoip"
+)
+
+// RegionEndpoint is the API and CDN hostnames of one deployment region, and the continents whose
+// clients it is closest to
+type RegionEndpoint struct {
+	Region     string // Storage region name, as used by S3
+	APIHost    string
+	CDNHost    string
+	Continents []string
+}
+
+// RegionsConfig holds settings for routing clients to regional endpoints
+type RegionsConfig struct {
+	Endpoints            []RegionEndpoint
+	GeoIPDatabasePath    string // MaxMind country or city database, empty to rely on CDN location headers
+	GeoIPASNDatabasePath string // MaxMind ASN database, empty to leave the networks of sign-ins unknown
+}
+
+// LoadRegionsConfig loads region routing configuration from environment variables.
+// REGION_ENDPOINTS lists regions separated by ';', each as region|apiHost|cdnHost|continents with
+// comma-separated continent codes. Malformed entries are skipped.
+func LoadRegionsConfig() *RegionsConfig {
+	config := &RegionsConfig{
+		GeoIPDatabasePath:    getEnv("GEOIP_DATABASE_PATH", ""),
+		GeoIPASNDatabasePath: getEnv("GEOIP_ASN_DATABASE_PATH", ""),
+	}
+
+	for _, entry := range strings.Split(getEnv("REGION_ENDPOINTS", ""), ";") {
+		fields := strings.Split(strings.TrimSpace(entry), "|")
+		if len(fields) != 4 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+
+		endpoint := RegionEndpoint{
+			Region:  strings.TrimSpace(fields[0]),
+			APIHost: strings.TrimSpace(fields[1]),
+			CDNHost: strings.TrimSpace(fields[2]),
+		}
+		for _, continent := range strings.Split(fields[3], ",") {
+			if code := geoip.NormalizeContinent(continent); code != "" {
+				endpoint.Continents = append(endpoint.Continents, code)
+			}
+		}
+		config.Endpoints = append(config.Endpoints, endpoint)
+	}
+
+	return config
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // S3Config holds configuration for the S3 client
 type S3Config struct {
 	Region          string
@@ -9,6 +11,12 @@ type S3Config struct {
 	DisableSSL      bool
 	ForcePathStyle  bool
 	BucketName      string
+	ReplicaRegion   string // Destination region of the bucket's cross-region replication rule, empty if none
+
+	// Storage is considered unavailable after this many consecutive failed calls, and is probed
+	// every ProbeInterval so outages and recoveries are noticed without user traffic
+	DegradedAfterFailures int
+	ProbeInterval         time.Duration
 }
 
 // LoadS3Config loads S3 configuration from environment variables
@@ -21,6 +29,10 @@ func LoadS3Config() *S3Config {
 		DisableSSL:      getEnvAsBool("S3_DISABLE_SSL", false),
 		ForcePathStyle:  getEnvAsBool("S3_FORCE_PATH_STYLE", false),
 		BucketName:      getEnv("S3_BUCKET_NAME", "cirrussync"),
+		ReplicaRegion:   getEnv("S3_REPLICA_REGION", ""),
+
+		DegradedAfterFailures: getEnvAsInt("S3_DEGRADED_AFTER_FAILURES", 5),
+		ProbeInterval:         getEnvAsDuration("S3_PROBE_INTERVAL", 15*time.Second),
 	}
 
 	if config.Region == "" {
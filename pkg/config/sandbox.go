@@ -0,0 +1,26 @@
+package config
+
+// SandboxConfig holds settings for running a deployment as the API sandbox: a separate tenant where
+// developers exercise the API with sandbox access tokens against synthetic data, apart from real accounts
+type SandboxConfig struct {
+	Enabled             bool   // Whether this deployment serves the sandbox instead of real accounts
+	Schema              string // Database schema holding the sandbox tables
+	RedisDB             int    // Redis database holding the sandbox keys
+	RateLimitMultiplier int    // Factor every rate limit is raised by
+	ResetHour           int    // Hour of the day, in UTC, sandbox drives are restored to synthetic data
+	SampleFiles         int    // Synthetic files created in every sandbox drive on reset
+}
+
+// LoadSandboxConfig loads sandbox configuration from environment variables
+func LoadSandboxConfig() *SandboxConfig {
+	config := &SandboxConfig{
+		Enabled:             getEnvAsBool("SANDBOX_ENABLED", false),
+		Schema:              getEnv("SANDBOX_SCHEMA", "sandbox"),
+		RedisDB:             getEnvAsInt("SANDBOX_REDIS_DB", 1),
+		RateLimitMultiplier: getEnvAsInt("SANDBOX_RATE_LIMIT_MULTIPLIER", 10),
+		ResetHour:           getEnvAsInt("SANDBOX_RESET_HOUR", 3),
+		SampleFiles:         getEnvAsInt("SANDBOX_SAMPLE_FILES", 20),
+	}
+
+	return config
+}
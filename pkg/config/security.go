@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// defaultReservedUsernames are names that could pass for the service or its staff
+const defaultReservedUsernames = "admin,administrator,root,support,help,security,system,staff,moderator,cirrussync,abuse,postmaster,hostmaster,webmaster,noreply,no-reply,api,www,mail"
+
+// SecurityConfig holds settings for account security tooling
+type SecurityConfig struct {
+	ExportSigningKey  string        // HMAC key for security exports, empty disables exports
+	ExportEventWindow time.Duration // How far back security events are included in an export
+	ExportEventLimit  int           // Maximum number of security events in an export
+
+	DeviceTrustSigningKey string        // HMAC key for trusted device cookies, empty disables device trust
+	DeviceTrustDuration   time.Duration // How long a device stays trusted before MFA is asked again
+
+	ReservedUsernames []string // Usernames nobody can sign up with, lower case
+}
+
+// LoadSecurityConfig loads security configuration from environment variables
+func LoadSecurityConfig() *SecurityConfig {
+	config := &SecurityConfig{
+		ExportSigningKey:  getEnv("SECURITY_EXPORT_SIGNING_KEY", ""),
+		ExportEventWindow: getEnvAsDuration("SECURITY_EXPORT_EVENT_WINDOW", 30*24*time.Hour),
+		ExportEventLimit:  getEnvAsInt("SECURITY_EXPORT_EVENT_LIMIT", 500),
+
+		DeviceTrustSigningKey: getEnv("SECURITY_DEVICE_TRUST_SIGNING_KEY", ""),
+		DeviceTrustDuration:   getEnvAsDuration("SECURITY_DEVICE_TRUST_DURATION", 30*24*time.Hour),
+	}
+
+	for _, username := range strings.Split(getEnv("RESERVED_USERNAMES", defaultReservedUsernames), ",") {
+		if username = strings.ToLower(strings.TrimSpace(username)); username != "" {
+			config.ReservedUsernames = append(config.ReservedUsernames, username)
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ServerConfig holds the cookie and cross-origin settings of the deployment the API is served from
+type ServerConfig struct {
+	CookieDomain   string        // Domain session, device trust and CSRF cookies are scoped to
+	SecureCookies  bool          // Whether cookies are only sent over HTTPS
+	SameSite       http.SameSite // SameSite mode of every cookie the API sets
+	AllowedOrigins []string      // Origins browsers may call the API from with credentials
+}
+
+// LoadServerConfig loads server configuration from environment variables
+func LoadServerConfig() *ServerConfig {
+	config := &ServerConfig{
+		CookieDomain: getEnv("COOKIE_DOMAIN", "localhost"),
+		// Deployments that only set CSRF_SECURE keep their CSRF and session cookies alike
+		SecureCookies: getEnvAsBool("COOKIE_SECURE", getEnvAsBool("CSRF_SECURE", false)),
+		SameSite:      parseSameSite(getEnv("COOKIE_SAME_SITE", "strict")),
+	}
+
+	for _, origin := range strings.Split(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:1420"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.AllowedOrigins = append(config.AllowedOrigins, origin)
+		}
+	}
+
+	// Browsers drop SameSite=None cookies that are not secure
+	if config.SameSite == http.SameSiteNoneMode {
+		config.SecureCookies = true
+	}
+
+	return config
+}
+
+// parseSameSite maps a SameSite setting to its mode, falling back to strict for unknown values
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
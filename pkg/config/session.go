@@ -0,0 +1,33 @@
+package config
+
+import (
+	"time"
+)
+
+// SessionConfig holds settings for the cleanup of stale sessions, session analytics and suspicious login detection
+type SessionConfig struct {
+	CleanupInterval   time.Duration // How often stale sessions are deleted
+	CleanupBatchSize  int           // Sessions deleted per statement, keeping each delete short
+	InactiveTimeout   time.Duration // Sessions unused for this long are deleted before they expire
+	ExpiredRetention  time.Duration // How long expired and revoked sessions stay listed before deletion
+	ConcurrencyWindow time.Duration // Default window in which sessions count as used at the same time
+
+	ImpossibleTravelSpeed       int // Km/h between two sign-in locations beyond which a login is suspicious
+	ImpossibleTravelMinDistance int // Km two sign-in locations must be apart to be compared, as GeoIP is imprecise
+}
+
+// LoadSessionConfig loads session configuration from environment variables
+func LoadSessionConfig() *SessionConfig {
+	config := &SessionConfig{
+		CleanupInterval:   getEnvAsDuration("SESSION_CLEANUP_INTERVAL", time.Hour),
+		CleanupBatchSize:  getEnvAsInt("SESSION_CLEANUP_BATCH_SIZE", 1000),
+		InactiveTimeout:   getEnvAsDuration("SESSION_INACTIVE_TIMEOUT", 30*24*time.Hour),
+		ExpiredRetention:  getEnvAsDuration("SESSION_EXPIRED_RETENTION", 7*24*time.Hour),
+		ConcurrencyWindow: getEnvAsDuration("SESSION_CONCURRENCY_WINDOW", time.Hour),
+
+		ImpossibleTravelSpeed:       getEnvAsInt("SESSION_IMPOSSIBLE_TRAVEL_SPEED", 1000),
+		ImpossibleTravelMinDistance: getEnvAsInt("SESSION_IMPOSSIBLE_TRAVEL_MIN_DISTANCE", 500),
+	}
+
+	return config
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"time"
+)
+
+// SMSConfig holds settings for sending text messages and the SMS second factor
+type SMSConfig struct {
+	SMSProvider      string        // SMS provider to send through; empty disables SMS
+	TwilioAccountSID string        // Twilio account the messages are billed to
+	TwilioAuthToken  string        // Twilio API credential
+	TwilioFromNumber string        // E.164 number or messaging service SID messages are sent from
+	SMSTimeout       time.Duration // Timeout of a single provider request
+	SMSCodeExpiry    time.Duration // How long an SMS verification code stays valid
+}
+
+// LoadSMSConfig loads SMS configuration from environment variables
+func LoadSMSConfig() *SMSConfig {
+	config := &SMSConfig{
+		SMSProvider:      getEnv("SMS_PROVIDER", ""),
+		TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+		TwilioAuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
+		TwilioFromNumber: getEnv("TWILIO_FROM_NUMBER", ""),
+		SMSTimeout:       getEnvAsDuration("SMS_TIMEOUT", 10*time.Second),
+		SMSCodeExpiry:    getEnvAsDuration("SMS_CODE_EXPIRY", 10*time.Minute),
+	}
+
+	return config
+}
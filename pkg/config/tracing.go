@@ -0,0 +1,16 @@
+package config
+
+import "cirrussync-api/pkg/tracing"
+
+// LoadTracingConfig loads tracing configuration from environment variables
+func LoadTracingConfig() *tracing.Config {
+	config := &tracing.Config{
+		Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "cirrussync-api"),
+		Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+		Insecure:    getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+		SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
+	}
+
+	return config
+}
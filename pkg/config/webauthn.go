@@ -0,0 +1,31 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// WebAuthnConfig holds the relying party settings for passkeys
+type WebAuthnConfig struct {
+	WebAuthnRPID             string        // Domain passkeys are scoped to, must be a suffix of every origin
+	WebAuthnRPName           string        // Name shown by the authenticator
+	WebAuthnOrigins          []string      // Origins allowed to perform ceremonies
+	WebAuthnChallengeTimeout time.Duration // How long a registration or login challenge stays valid
+}
+
+// LoadWebAuthnConfig loads WebAuthn configuration from environment variables
+func LoadWebAuthnConfig() *WebAuthnConfig {
+	config := &WebAuthnConfig{
+		WebAuthnRPID:             getEnv("WEBAUTHN_RP_ID", "cirrussync.me"),
+		WebAuthnRPName:           getEnv("WEBAUTHN_RP_NAME", "CirrusSync"),
+		WebAuthnChallengeTimeout: getEnvAsDuration("WEBAUTHN_CHALLENGE_TIMEOUT", 5*time.Minute),
+	}
+
+	for _, origin := range strings.Split(getEnv("WEBAUTHN_ORIGINS", "https://cirrussync.me"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.WebAuthnOrigins = append(config.WebAuthnOrigins, origin)
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"time"
+)
+
+// WebhookConfig holds settings for webhooks delivered to integrators
+type WebhookConfig struct {
+	Timeout            time.Duration // Timeout of a single delivery, including the receiver's response
+	SignatureTolerance time.Duration // Maximum age of a signature timestamp receivers should accept
+	AllowPrivateURLs   bool          // Whether deliveries may target loopback and private networks, for local development only
+	MaxTestDeliveries  int           // Test deliveries a user may trigger per hour
+}
+
+// LoadWebhookConfig loads webhook configuration from environment variables
+func LoadWebhookConfig() *WebhookConfig {
+	config := &WebhookConfig{
+		Timeout:            getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		SignatureTolerance: getEnvAsDuration("WEBHOOK_SIGNATURE_TOLERANCE", 5*time.Minute),
+		AllowPrivateURLs:   getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_URLS", false),
+		MaxTestDeliveries:  getEnvAsInt("WEBHOOK_MAX_TEST_DELIVERIES", 20),
+	}
+
+	return config
+}
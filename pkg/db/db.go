@@ -73,6 +73,16 @@ func connect(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Time queries for the metrics endpoint
+	if err := RegisterMetrics(db); err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+
+	// Trace queries under the request that made them
+	if err := RegisterTracing(db); err != nil {
+		return nil, fmt.Errorf("failed to register query tracing: %w", err)
+	}
+
 	// Configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -94,6 +104,13 @@ func connect(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// Tables are created in the first schema of the search path, so it has to exist before migrations
+	if cfg.Schema != "" {
+		if err := db.WithContext(ctx).Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %q", cfg.Schema)).Error; err != nil {
+			return nil, fmt.Errorf("failed to create schema %s: %w", cfg.Schema, err)
+		}
+	}
+
 	log.Printf("Connected to database %s on %s:%s (pool: %d-%d)",
 		cfg.Name, cfg.Host, cfg.Port, cfg.PoolMinSize, cfg.PoolMaxSize)
 
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"cirrussync-api/pkg/metrics"
+
+	"gorm.io/gorm"
+)
+
+// queryStartKey is where the before callbacks leave the time a statement started
+const queryStartKey = "metrics:query_start"
+
+// RegisterMetrics times every query GORM runs, recording it by operation and table
+func RegisterMetrics(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", startQueryTimer),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", observeQuery("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", startQueryTimer),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", observeQuery("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", startQueryTimer),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", observeQuery("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", startQueryTimer),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", observeQuery("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", startQueryTimer),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", observeQuery("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", startQueryTimer),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", observeQuery("raw")),
+	)
+}
+
+// startQueryTimer records when a statement started
+func startQueryTimer(tx *gorm.DB) {
+	tx.InstanceSet(queryStartKey, time.Now())
+}
+
+// observeQuery returns a callback recording the duration of a statement of the given operation
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := tx.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		metrics.DBQueryDuration.ObserveDuration(start, operation, table)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			metrics.DBQueryErrors.Inc(operation, table)
+		}
+	}
+}
@@ -5,14 +5,41 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"gorm.io/gorm"
 )
 
+// MIGRATION_HISTORY_TABLE records every migration applied or rolled back by this runner, next to the
+// current version golang-migrate keeps in schema_migrations
+const MIGRATION_HISTORY_TABLE = "schema_migration_history"
+
+var (
+	// ErrDestructiveMigrationsPending is returned when pending migrations drop or rewrite data and
+	// were not explicitly allowed
+	ErrDestructiveMigrationsPending = errors.New("pending migrations drop or rewrite data")
+
+	// ErrIrreversibleMigration is returned when rolling back a migration that has no down file
+	ErrIrreversibleMigration = errors.New("migration has no down file")
+
+	// ErrDirtyMigration is returned when a previous migration failed halfway
+	ErrDirtyMigration = errors.New("database is in a dirty migration state, fix it and force the version")
+)
+
+// destructiveStatement matches SQL that loses data when run: dropped tables, schemas and columns,
+// truncated or deleted rows and column type changes. The "-- migrate:destructive" marker flags
+// anything else a migration author knows to be destructive.
+var destructiveStatement = regexp.MustCompile(`(?i)\bDROP\s+(TABLE|SCHEMA|COLUMN)\b|\bTRUNCATE\b|\bDELETE\s+FROM\b|\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b|--\s*migrate:destructive`)
+
 // MigrationConfig holds configuration for database migrations
 type MigrationConfig struct {
 	// Path to migration files
@@ -26,6 +53,37 @@ type MigrationConfig struct {
 
 	// Target version (0 means latest)
 	TargetVersion uint
+
+	// Whether pending migrations that drop or rewrite data may run
+	AllowDestructive bool
+}
+
+// Migration is a versioned SQL migration and whether it was applied
+type Migration struct {
+	Version     uint
+	Name        string
+	Applied     bool
+	AppliedAt   *time.Time // Last time it was applied by this runner, nil for versions applied before the history existed
+	Destructive bool       // The up file drops or rewrites data
+	Reversible  bool       // A down file exists
+}
+
+// MigrationStatus is the migration state of the database
+type MigrationStatus struct {
+	Version    uint // Current version, 0 before the first migration
+	Dirty      bool
+	Migrations []*Migration
+}
+
+// Pending returns the migrations that were not applied yet
+func (s *MigrationStatus) Pending() []*Migration {
+	var pending []*Migration
+	for _, migration := range s.Migrations {
+		if !migration.Applied {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
 }
 
 // NewMigrationConfig creates a new migration configuration with default values
@@ -35,10 +93,12 @@ func NewMigrationConfig() *MigrationConfig {
 		ForceVersion:      false,
 		AutoMigrateModels: false,
 		TargetVersion:     0, // Latest
+		AllowDestructive:  false,
 	}
 }
 
-// RunMigrations runs database migrations
+// RunMigrations runs database migrations. SQL migrations refuse to start when a pending migration
+// is destructive, unless the config allows it.
 func RunMigrations(cfg *MigrationConfig, models ...interface{}) error {
 	if DB == nil {
 		return fmt.Errorf("database not initialized")
@@ -58,16 +118,140 @@ func RunMigrations(cfg *MigrationConfig, models ...interface{}) error {
 		return nil
 	}
 
-	// Use golang-migrate for production-ready migrations
+	m, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Handle dirty state if force version is enabled
+	if cfg.ForceVersion {
+		log.Println("Warning: Force version is enabled - resetting dirty state if needed")
+		if err := m.Force(int(cfg.TargetVersion)); err != nil {
+			return fmt.Errorf("failed to force version: %w", err)
+		}
+	}
+
+	if cfg.TargetVersion > 0 {
+		log.Printf("Migrating to version %d...", cfg.TargetVersion)
+	} else {
+		log.Println("Migrating to latest version...")
+	}
+	if err := migrateUp(m, cfg, 0); err != nil {
+		return err
+	}
+
+	// Get current version
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	log.Printf("Migration completed successfully. Current version: %d, Dirty: %v", version, dirty)
+	return nil
+}
+
+// MigrateUp applies pending migrations up to the config's target version, or only the given number
+// of steps when steps is positive
+func MigrateUp(cfg *MigrationConfig, steps int) error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	m, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+
+	return migrateUp(m, cfg, steps)
+}
+
+// MigrateDown rolls back the given number of applied migrations, newest first. Nothing is rolled
+// back when one of them has no down file.
+func MigrateDown(cfg *MigrationConfig, steps int) error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if steps <= 0 {
+		return fmt.Errorf("number of migrations to roll back must be positive")
+	}
+
+	m, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	status, err := migrationStatus(m, cfg)
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return ErrDirtyMigration
+	}
+
+	var rollback []*Migration
+	for i := len(status.Migrations) - 1; i >= 0 && len(rollback) < steps; i-- {
+		if status.Migrations[i].Applied {
+			rollback = append(rollback, status.Migrations[i])
+		}
+	}
+	for _, migration := range rollback {
+		if !migration.Reversible {
+			return fmt.Errorf("%w: %d_%s", ErrIrreversibleMigration, migration.Version, migration.Name)
+		}
+	}
+
+	for _, migration := range rollback {
+		log.Printf("Rolling back migration %d_%s...", migration.Version, migration.Name)
+		start := time.Now()
+		if err := m.Steps(-1); err != nil {
+			return fmt.Errorf("rollback of %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		recordMigration(migration, "down", time.Since(start))
+	}
+
+	return nil
+}
+
+// GetMigrationStatus lists the migration files with whether each was applied
+func GetMigrationStatus(cfg *MigrationConfig) (*MigrationStatus, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	m, err := newMigrator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrationStatus(m, cfg)
+}
+
+// CheckPendingMigrations refuses pending migrations that drop or rewrite data unless the config
+// allows them, so they are only run deliberately. A missing migrations directory has none pending.
+func CheckPendingMigrations(cfg *MigrationConfig) error {
+	if _, err := os.Stat(cfg.MigrationsPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	status, err := GetMigrationStatus(cfg)
+	if err != nil {
+		return err
+	}
+
+	return checkDestructive(status, cfg)
+}
+
+// newMigrator creates a golang-migrate instance on the shared connection. It is not closed after
+// use, since closing it would close the connection the application keeps using.
+func newMigrator(cfg *MigrationConfig) (*migrate.Migrate, error) {
 	sqlDB, err := DB.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get database connection: %w", err)
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
 	}
 
 	// Setup postgres driver
 	driver, err := postgres.WithInstance(sqlDB, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %w", err)
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
 	// Create migrate instance
@@ -75,38 +259,183 @@ func RunMigrations(cfg *MigrationConfig, models ...interface{}) error {
 		fmt.Sprintf("file://%s", cfg.MigrationsPath),
 		"postgres", driver)
 	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
 	}
 
-	// Handle dirty state if force version is enabled
-	if cfg.ForceVersion {
-		log.Println("Warning: Force version is enabled - resetting dirty state if needed")
-		if err := m.Force(int(cfg.TargetVersion)); err != nil {
-			return fmt.Errorf("failed to force version: %w", err)
+	return m, nil
+}
+
+// migrateUp applies pending migrations one at a time, so each is recorded in the history
+func migrateUp(m *migrate.Migrate, cfg *MigrationConfig, steps int) error {
+	status, err := migrationStatus(m, cfg)
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return ErrDirtyMigration
+	}
+	if cfg.TargetVersion > 0 && cfg.TargetVersion < status.Version {
+		return fmt.Errorf("target version %d is older than the current version %d, roll back with \"migrate down\"", cfg.TargetVersion, status.Version)
+	}
+
+	var pending []*Migration
+	for _, migration := range status.Pending() {
+		if cfg.TargetVersion > 0 && migration.Version > cfg.TargetVersion {
+			break
+		}
+		if steps > 0 && len(pending) == steps {
+			break
 		}
+		pending = append(pending, migration)
+	}
+	if err := checkDestructive(&MigrationStatus{Migrations: pending}, cfg); err != nil {
+		return err
 	}
 
-	// Run migrations
-	if cfg.TargetVersion > 0 {
-		log.Printf("Migrating to version %d...", cfg.TargetVersion)
-		if err := m.Migrate(cfg.TargetVersion); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-			return fmt.Errorf("migration failed: %w", err)
+	for _, migration := range pending {
+		log.Printf("Applying migration %d_%s...", migration.Version, migration.Name)
+		start := time.Now()
+		if err := m.Steps(1); err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
 		}
-	} else {
-		log.Println("Migrating to latest version...")
-		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-			return fmt.Errorf("migration failed: %w", err)
+		recordMigration(migration, "up", time.Since(start))
+	}
+
+	return nil
+}
+
+// checkDestructive returns ErrDestructiveMigrationsPending naming the pending destructive migrations
+func checkDestructive(status *MigrationStatus, cfg *MigrationConfig) error {
+	if cfg.AllowDestructive {
+		return nil
+	}
+
+	var destructive []string
+	for _, migration := range status.Pending() {
+		if migration.Destructive {
+			destructive = append(destructive, fmt.Sprintf("%d_%s", migration.Version, migration.Name))
 		}
 	}
+	if len(destructive) > 0 {
+		return fmt.Errorf("%w: %s; run them with \"migrate up -allow-destructive\"", ErrDestructiveMigrationsPending, strings.Join(destructive, ", "))
+	}
+
+	return nil
+}
+
+// migrationStatus reads the migration files and marks those up to the current version as applied
+func migrationStatus(m *migrate.Migrate, cfg *MigrationConfig) (*MigrationStatus, error) {
+	status := &MigrationStatus{}
 
-	// Get current version
 	version, dirty, err := m.Version()
 	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
-		return fmt.Errorf("failed to get migration version: %w", err)
+		return nil, fmt.Errorf("failed to get migration version: %w", err)
 	}
+	status.Version = version
+	status.Dirty = dirty
 
-	log.Printf("Migration completed successfully. Current version: %d, Dirty: %v", version, dirty)
-	return nil
+	status.Migrations, err = readMigrations(cfg.MigrationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := migrationHistory()
+	for _, migration := range status.Migrations {
+		// A dirty version failed halfway and does not count as applied
+		migration.Applied = migration.Version < version || (migration.Version == version && !dirty)
+		if migration.Applied {
+			if at, ok := appliedAt[migration.Version]; ok {
+				migration.AppliedAt = &at
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// readMigrations reads the up and down files of a migrations directory, ordered by version
+func readMigrations(path string) ([]*Migration, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		parsed, err := source.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		migration, ok := byVersion[parsed.Version]
+		if !ok {
+			migration = &Migration{Version: parsed.Version, Name: parsed.Identifier}
+			byVersion[parsed.Version] = migration
+		}
+
+		switch parsed.Direction {
+		case source.Up:
+			content, err := os.ReadFile(filepath.Join(path, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+			}
+			migration.Destructive = destructiveStatement.Match(content)
+		case source.Down:
+			migration.Reversible = true
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// migrationHistory returns when each version was last applied. The history is informational, so a
+// missing table yields an empty history.
+func migrationHistory() map[uint]time.Time {
+	var rows []struct {
+		Version   uint
+		AppliedAt time.Time
+	}
+	err := DB.Raw(fmt.Sprintf(
+		"SELECT version, MAX(applied_at) AS applied_at FROM %s WHERE direction = 'up' GROUP BY version",
+		MIGRATION_HISTORY_TABLE)).Scan(&rows).Error
+
+	history := make(map[uint]time.Time, len(rows))
+	if err != nil {
+		return history
+	}
+	for _, row := range rows {
+		history[row.Version] = row.AppliedAt
+	}
+	return history
+}
+
+// recordMigration adds an applied or rolled back migration to the history. Failures are logged,
+// since the migration itself already succeeded.
+func recordMigration(migration *Migration, direction string, duration time.Duration) {
+	err := DB.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id BIGSERIAL PRIMARY KEY,
+		version BIGINT NOT NULL,
+		name TEXT NOT NULL,
+		direction VARCHAR(4) NOT NULL,
+		duration_ms BIGINT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`, MIGRATION_HISTORY_TABLE)).Error
+	if err == nil {
+		err = DB.Exec(fmt.Sprintf("INSERT INTO %s (version, name, direction, duration_ms) VALUES (?, ?, ?, ?)", MIGRATION_HISTORY_TABLE),
+			migration.Version, migration.Name, direction, duration.Milliseconds()).Error
+	}
+	if err != nil {
+		log.Printf("Failed to record migration %d_%s in the history: %v", migration.Version, migration.Name, err)
+	}
 }
 
 // SeedDatabase seeds the database with initial data
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SortDirection is the direction of one ordering
+type SortDirection string
+
+const (
+	// SortAsc sorts from the smallest value up
+	SortAsc SortDirection = "asc"
+
+	// SortDesc sorts from the largest value down
+	SortDesc SortDirection = "desc"
+)
+
+// Operator compares a column with a value in a filter
+type Operator string
+
+const (
+	OpEq  Operator = "eq"
+	OpNe  Operator = "ne"
+	OpGt  Operator = "gt"
+	OpGte Operator = "gte"
+	OpLt  Operator = "lt"
+	OpLte Operator = "lte"
+	OpIn  Operator = "in"
+)
+
+var (
+	// ErrUnknownColumn indicates a field that is not in the model's column allowlist
+	ErrUnknownColumn = errors.New("unknown column")
+
+	// ErrColumnNotAllowed indicates a known field used for something its column does not allow
+	ErrColumnNotAllowed = errors.New("column not allowed")
+
+	// ErrUnknownOperator indicates a filter operator the query builder does not support
+	ErrUnknownOperator = errors.New("unknown filter operator")
+)
+
+// Column describes one database column a query may use, and how
+type Column struct {
+	Name       string // Database column name
+	Sortable   bool   // Whether results may be ordered by the column
+	Filterable bool   // Whether results may be filtered on the column
+}
+
+// Columns is the allowlist of one model, keyed by the field name callers use (usually the API name).
+// Queries built from it can only ever reference these columns, and gorm quotes every one of them,
+// so caller supplied field names never reach SQL.
+type Columns map[string]Column
+
+// Sortable reports whether a field exists and may be sorted on
+func (c Columns) Sortable(field string) bool {
+	column, ok := c[field]
+	return ok && column.Sortable
+}
+
+// Filterable reports whether a field exists and may be filtered on
+func (c Columns) Filterable(field string) bool {
+	column, ok := c[field]
+	return ok && column.Filterable
+}
+
+// ParseSortDirection reads a sort direction, defaulting to ascending for anything but "desc"
+func ParseSortDirection(direction string) SortDirection {
+	if direction == string(SortDesc) {
+		return SortDesc
+	}
+	return SortAsc
+}
+
+// QueryBuilder collects filters and orderings against a column allowlist and applies them to a gorm query.
+// The first invalid field or operator is remembered and returned by Apply, so calls can be chained.
+type QueryBuilder struct {
+	columns Columns
+	filters []clause.Expression
+	orders  []clause.OrderByColumn
+	err     error
+}
+
+// NewQueryBuilder creates a query builder for a model's column allowlist
+func NewQueryBuilder(columns Columns) *QueryBuilder {
+	return &QueryBuilder{
+		columns: columns,
+	}
+}
+
+// Where adds a filter comparing a field with a value. OpIn expects a slice value.
+func (b *QueryBuilder) Where(field string, op Operator, value interface{}) *QueryBuilder {
+	column, err := b.column(field, func(column Column) bool { return column.Filterable })
+	if err != nil {
+		return b.fail(err)
+	}
+
+	var expression clause.Expression
+	switch op {
+	case OpEq:
+		expression = clause.Eq{Column: column, Value: value}
+	case OpNe:
+		expression = clause.Neq{Column: column, Value: value}
+	case OpGt:
+		expression = clause.Gt{Column: column, Value: value}
+	case OpGte:
+		expression = clause.Gte{Column: column, Value: value}
+	case OpLt:
+		expression = clause.Lt{Column: column, Value: value}
+	case OpLte:
+		expression = clause.Lte{Column: column, Value: value}
+	case OpIn:
+		values, ok := toValues(value)
+		if !ok {
+			return b.fail(fmt.Errorf("%w: %s expects a slice", ErrUnknownOperator, op))
+		}
+		expression = clause.IN{Column: column, Values: values}
+	default:
+		return b.fail(fmt.Errorf("%w: %s", ErrUnknownOperator, op))
+	}
+
+	b.filters = append(b.filters, expression)
+	return b
+}
+
+// OrderBy adds an ordering by a field. Orderings apply in the order they were added.
+func (b *QueryBuilder) OrderBy(field string, direction SortDirection) *QueryBuilder {
+	column, err := b.column(field, func(column Column) bool { return column.Sortable })
+	if err != nil {
+		return b.fail(err)
+	}
+
+	b.orders = append(b.orders, clause.OrderByColumn{Column: column, Desc: direction == SortDesc})
+	return b
+}
+
+// ApplyFilters adds only the filters to a query, for example to count the rows of a paginated listing
+func (b *QueryBuilder) ApplyFilters(query *gorm.DB) (*gorm.DB, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	for _, filter := range b.filters {
+		query = query.Where(filter)
+	}
+	return query, nil
+}
+
+// Apply adds the filters and orderings to a query
+func (b *QueryBuilder) Apply(query *gorm.DB) (*gorm.DB, error) {
+	query, err := b.ApplyFilters(query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range b.orders {
+		query = query.Order(order)
+	}
+	return query, nil
+}
+
+// column resolves a field through the allowlist and checks what it is used for
+func (b *QueryBuilder) column(field string, allowed func(Column) bool) (clause.Column, error) {
+	column, ok := b.columns[field]
+	if !ok {
+		return clause.Column{}, fmt.Errorf("%w: %s", ErrUnknownColumn, field)
+	}
+	if !allowed(column) {
+		return clause.Column{}, fmt.Errorf("%w: %s", ErrColumnNotAllowed, field)
+	}
+	return clause.Column{Name: column.Name}, nil
+}
+
+// fail remembers the first error of a chain
+func (b *QueryBuilder) fail(err error) *QueryBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// toValues converts the common slice types used with OpIn into gorm values
+func toValues(value interface{}) ([]interface{}, bool) {
+	switch values := value.(type) {
+	case []interface{}:
+		return values, true
+	case []string:
+		result := make([]interface{}, len(values))
+		for i, v := range values {
+			result[i] = v
+		}
+		return result, true
+	case []int:
+		result := make([]interface{}, len(values))
+		for i, v := range values {
+			result[i] = v
+		}
+		return result, true
+	case []int64:
+		result := make([]interface{}, len(values))
+		for i, v := range values {
+			result[i] = v
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
@@ -58,7 +58,7 @@ func (r *BaseRepository[T]) Create(ctx context.Context, entity *T) error {
 // FindByID finds an entity by ID
 func (r *BaseRepository[T]) FindByID(ctx context.Context, id interface{}) (*T, error) {
 	var entity T
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&entity).Error
+	err := Conn(ctx, r.db).Where("id = ?", id).First(&entity).Error
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +92,7 @@ func (r *BaseRepository[T]) Delete(ctx context.Context, id interface{}) error {
 // FindWhere finds entities matching the given condition
 func (r *BaseRepository[T]) FindWhere(ctx context.Context, condition string, args ...interface{}) ([]T, error) {
 	var entities []T
-	err := r.db.WithContext(ctx).Where(condition, args...).Find(&entities).Error
+	err := Conn(ctx, r.db).Where(condition, args...).Find(&entities).Error
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +102,7 @@ func (r *BaseRepository[T]) FindWhere(ctx context.Context, condition string, arg
 // FindOneWhere finds a single entity matching the condition
 func (r *BaseRepository[T]) FindOneWhere(ctx context.Context, condition string, args ...interface{}) (*T, error) {
 	var entity T
-	err := r.db.WithContext(ctx).Where(condition, args...).First(&entity).Error
+	err := Conn(ctx, r.db).Where(condition, args...).First(&entity).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"errors"
+
+	"cirrussync-api/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// querySpanKey is where the before callbacks leave the span of a statement
+const querySpanKey = "tracing:query_span"
+
+// RegisterTracing records a span for every query GORM runs, as a child of the span in the
+// context the query was made with
+func RegisterTracing(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", startQuerySpan("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", endQuerySpan),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", startQuerySpan("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", endQuerySpan),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", startQuerySpan("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", endQuerySpan),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", startQuerySpan("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", endQuerySpan),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", startQuerySpan("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", endQuerySpan),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", startQuerySpan("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", endQuerySpan),
+	)
+}
+
+// startQuerySpan returns a callback starting the span of a statement of the given operation.
+// Statements run outside a trace are skipped.
+func startQuerySpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		if tx.Statement.Context == nil || !tracing.InTrace(tx.Statement.Context) {
+			return
+		}
+
+		table := tx.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		_, span := tracing.StartClient(tx.Statement.Context, "db."+operation+" "+table,
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", table),
+		)
+		tx.InstanceSet(querySpanKey, span)
+	}
+}
+
+// endQuerySpan ends the span of a statement with the SQL that ran and any error
+func endQuerySpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(querySpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.RowsAffected),
+	)
+
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -39,8 +39,16 @@ func WithTransaction(ctx context.Context, fn TxFn) error {
 	return withTransactionDB(DB, ctx, fn)
 }
 
-// withTransactionDB runs a transaction on the provided DB instance
+// withTransactionDB runs a transaction on the provided DB instance. When the instance is already in a
+// transaction, or ctx carries one from RunInTx, fn joins it, so the work commits or rolls back with it.
 func withTransactionDB(db *gorm.DB, ctx context.Context, fn TxFn) error {
+	if InTx(ctx) {
+		return fn(Conn(ctx, db))
+	}
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return fn(db.WithContext(ctx))
+	}
+
 	tx := db.WithContext(ctx).Begin()
 	if tx.Error != nil {
 		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
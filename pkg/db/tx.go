@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey is the context key of the transaction RunInTx carries
+type txKey struct{}
+
+// RunInTx runs fn in one transaction of database, carried by the context fn receives. Repositories of
+// any service that resolve their connection with Conn run their queries in it, so an operation spanning
+// several entities or services commits or rolls back as a whole. A call with a context that already
+// carries a transaction joins it.
+func RunInTx(ctx context.Context, database *gorm.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return withTransactionDB(database, ctx, func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Conn returns the transaction ctx carries, or database when it carries none, bound to ctx
+func Conn(ctx context.Context, database *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return database.WithContext(ctx)
+}
+
+// InTx reports whether ctx carries a transaction
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return ok
+}
@@ -0,0 +1,165 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ErrNotConfigured is returned by Open when no database path is set
+var ErrNotConfigured = errors.New("GeoIP database is not configured")
+
+// Continent codes used by MaxMind databases and CDN location headers
+var continentCodes = map[string]bool{"AF": true, "AN": true, "AS": true, "EU": true, "NA": true, "OC": true, "SA": true}
+
+// Reader looks up where IP addresses are located in a MaxMind country or city database, and
+// optionally which network they belong to in an ASN database
+type Reader struct {
+	db  *maxminddb.Reader
+	asn *maxminddb.Reader // nil unless an ASN database was opened
+}
+
+// Location is where an IP address is located and the network it belongs to. Fields a database does
+// not have are left empty: country databases have no city or coordinates.
+type Location struct {
+	Continent      string
+	Country        string // ISO 3166-1 alpha-2 code
+	City           string // English name
+	Latitude       float64
+	Longitude      float64
+	HasCoordinates bool
+	ASN            uint
+	ASOrganization string
+}
+
+// record holds the fields of a lookup this package uses
+type record struct {
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// asnRecord holds the fields of an ASN database lookup
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Open opens a GeoLite2 or GeoIP2 country or city database
+func Open(path string) (*Reader, error) {
+	if path == "" {
+		return nil, ErrNotConfigured
+	}
+
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+
+	return &Reader{db: db}, nil
+}
+
+// OpenASN opens a GeoLite2 or GeoIP2 ASN database, so lookups also report the network of an address
+func (r *Reader) OpenASN(path string) error {
+	if path == "" {
+		return ErrNotConfigured
+	}
+
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open GeoIP ASN database: %w", err)
+	}
+
+	r.asn = db
+	return nil
+}
+
+// Continent returns the two-letter continent code of an IP address, or an empty string when the
+// address is invalid, private or not in the database. A nil reader knows no addresses.
+func (r *Reader) Continent(ip string) string {
+	return r.Locate(ip).Continent
+}
+
+// Locate returns where an IP address is located. The location is empty when the address is invalid,
+// private or not in the database; a nil reader knows no addresses.
+func (r *Reader) Locate(ip string) Location {
+	var location Location
+	if r == nil {
+		return location
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.IsPrivate() || parsed.IsLoopback() {
+		return location
+	}
+
+	var result record
+	if err := r.db.Lookup(parsed, &result); err == nil {
+		location.Continent = result.Continent.Code
+		location.Country = result.Country.ISOCode
+		location.City = result.City.Names["en"]
+		if result.Location.Latitude != nil && result.Location.Longitude != nil {
+			location.Latitude = *result.Location.Latitude
+			location.Longitude = *result.Location.Longitude
+			location.HasCoordinates = true
+		}
+	}
+
+	if r.asn != nil {
+		var network asnRecord
+		if err := r.asn.Lookup(parsed, &network); err == nil {
+			location.ASN = network.Number
+			location.ASOrganization = network.Organization
+		}
+	}
+
+	return location
+}
+
+// Close releases the databases
+func (r *Reader) Close() error {
+	if r == nil {
+		return nil
+	}
+	if r.asn != nil {
+		r.asn.Close()
+	}
+	return r.db.Close()
+}
+
+// NormalizeContinent returns a continent code in upper case, or an empty string when it is not one
+func NormalizeContinent(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !continentCodes[code] {
+		return ""
+	}
+	return code
+}
+
+// earthRadiusKm is the mean radius of the earth
+const earthRadiusKm = 6371.0
+
+// DistanceKm returns the great-circle distance between two coordinates in kilometers
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,72 @@
+package metrics
+
+// Metrics recorded by the API. Route labels use the route pattern, not the request path, to keep
+// the number of series bounded.
+var (
+	HTTPRequestDuration = Default.NewHistogramVec(
+		"http_request_duration_seconds",
+		"Time spent serving HTTP requests, by route",
+		DefaultBuckets, "method", "route",
+	)
+	HTTPRequests = Default.NewCounterVec(
+		"http_requests_total",
+		"HTTP requests served, by route and status code",
+		"method", "route", "status",
+	)
+	HTTPPanics = Default.NewCounterVec(
+		"http_panics_total",
+		"Panics recovered while serving HTTP requests, by route",
+		"method", "route",
+	)
+	DBQueryDuration = Default.NewHistogramVec(
+		"db_query_duration_seconds",
+		"Time spent in database queries, by operation and table",
+		DefaultBuckets, "operation", "table",
+	)
+	DBQueryErrors = Default.NewCounterVec(
+		"db_query_errors_total",
+		"Database queries that failed, by operation and table",
+		"operation", "table",
+	)
+	CacheLookups = Default.NewCounterVec(
+		"cache_lookups_total",
+		"Redis cache lookups, by cache and result (hit or miss)",
+		"cache", "result",
+	)
+	SMTPSends = Default.NewCounterVec(
+		"smtp_sends_total",
+		"Email send attempts through the configured provider, by result (success or failure)",
+		"result",
+	)
+	SMTPConnectionFailures = Default.NewCounterVec(
+		"smtp_connection_failures_total",
+		"SMTP connections that could not be opened or authenticated",
+	)
+	EmailDeadLetters = Default.NewCounterVec(
+		"email_dead_letters_total",
+		"Emails added to the dead-letter queue after every attempt to send them failed",
+	)
+	StorageGCRemoved = Default.NewCounterVec(
+		"storage_gc_removed_total",
+		"Orphaned objects and dangling rows removed by storage garbage collection, by kind",
+		"kind",
+	)
+	StorageGCReclaimedBytes = Default.NewCounterVec(
+		"storage_gc_reclaimed_bytes_total",
+		"Bytes reclaimed by storage garbage collection, by kind",
+		"kind",
+	)
+)
+
+// Kinds of garbage removed by storage garbage collection
+const (
+	GC_ORPHANED_OBJECT = "orphaned_object"
+	GC_DANGLING_BLOCK  = "dangling_block"
+	GC_DANGLING_ITEM   = "dangling_item"
+)
+
+// Cache lookup results
+const (
+	CACHE_HIT  = "hit"
+	CACHE_MISS = "miss"
+)
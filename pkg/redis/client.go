@@ -85,6 +85,7 @@ func (c *Client) initClient() {
 		PoolTimeout:     4 * time.Second,
 		ConnMaxIdleTime: 5 * time.Minute,
 	})
+	c.client.AddHook(tracingHook{})
 }
 
 // registerScripts registers Lua scripts for atomic operations
@@ -107,6 +108,25 @@ func (c *Client) registerScripts() {
 		end
 	`)
 
+	// Script for incrementing a counter that starts its expiry on the first increment. A counter
+	// left without an expiry is given one too, so it cannot outlive its window forever.
+	c.scripts["incrWithExpiry"] = redis.NewScript(`
+		local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+		if value == tonumber(ARGV[1]) or redis.call("PTTL", KEYS[1]) == -1 then
+			redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return value
+	`)
+
+	// Script for incrementing a counter only while it exists
+	c.scripts["incrByIfExists"] = redis.NewScript(`
+		if redis.call("EXISTS", KEYS[1]) == 1 then
+			return {1, redis.call("INCRBY", KEYS[1], ARGV[1])}
+		else
+			return {0, 0}
+		end
+	`)
+
 	// Script for scanning and deleting keys by pattern
 	c.scripts["deleteByPattern"] = redis.NewScript(`
 		local cursor = "0"
@@ -152,6 +172,32 @@ func (c *Client) registerScripts() {
 		return result
 	`)
 
+	// Script for sliding window rate limiting. Timestamps come from the Redis server so instances
+	// with drifting clocks share one view of the window.
+	c.scripts["slidingWindow"] = redis.NewScript(`
+		local key = KEYS[1]
+		local window = tonumber(ARGV[1])
+		local limit = tonumber(ARGV[2])
+		local member = ARGV[3]
+		local time = redis.call("TIME")
+		local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+		redis.call("ZREMRANGEBYSCORE", key, "-inf", now - window)
+		local count = redis.call("ZCARD", key)
+		if count < limit then
+			redis.call("ZADD", key, now, member)
+			redis.call("PEXPIRE", key, window)
+			return {1, limit - count - 1, 0}
+		end
+
+		local retryAfter = window
+		local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+		if oldest[2] then
+			retryAfter = tonumber(oldest[2]) + window - now
+		end
+		return {0, 0, retryAfter}
+	`)
+
 	// Script for atomic cache refresh
 	c.scripts["refreshCache"] = redis.NewScript(`
 		local key = KEYS[1]
@@ -315,6 +361,74 @@ func (c *Client) TTL(ctx context.Context, key string) (time.Duration, error) {
 	return ttl, nil
 }
 
+// Incr atomically increments the integer value of a key by one
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.Incr(ctx, key).Result()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis incr error: %w", err)
+	}
+
+	return result, nil
+}
+
+// IncrBy increments a counter by delta and returns its new value
+func (c *Client) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.IncrBy(ctx, key, delta).Result()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis incrby error: %w", err)
+	}
+
+	return result, nil
+}
+
+// Decr decrements a counter and returns its new value
+func (c *Client) Decr(ctx context.Context, key string) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.Decr(ctx, key).Result()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis decr error: %w", err)
+	}
+
+	return result, nil
+}
+
+// IncrWithExpiry increments a counter by delta and returns its new value. The expiration is set
+// atomically with the first increment and left alone by later ones, so the counter covers a fixed
+// window from its first increment rather than sliding with every one.
+func (c *Client) IncrWithExpiry(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.scripts["incrWithExpiry"].Run(ctx, c.client, []string{key}, delta, expiration.Milliseconds()).Int64()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis incr error: %w", err)
+	}
+
+	return result, nil
+}
+
+// IncrByIfExists adds delta to a counter and returns its new value. A missing counter is left
+// missing, so one seeded from a source of truth is never recreated from zero; exists reports which.
+func (c *Client) IncrByIfExists(ctx context.Context, key string, delta int64) (value int64, exists bool, err error) {
+	c.checkAndResetClient()
+
+	result, err := c.scripts["incrByIfExists"].Run(ctx, c.client, []string{key}, delta).Int64Slice()
+	if err != nil {
+		c.recordError()
+		return 0, false, fmt.Errorf("redis incrby error: %w", err)
+	}
+
+	return result[1], result[0] == 1, nil
+}
+
 // Expire sets a key's time to live in seconds
 func (c *Client) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
 	c.checkAndResetClient()
@@ -742,6 +856,25 @@ func (c *Client) SetWithRefresh(ctx context.Context, key string, value any, expi
 	return nil
 }
 
+// SlidingWindowAllow counts a hit against a sliding window limit on key. It reports whether the hit
+// is allowed, how many hits the window has left and, once the limit is reached, how long until the
+// oldest hit leaves the window. Refused hits are not counted.
+func (c *Client) SlidingWindowAllow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	c.checkAndResetClient()
+
+	script := c.scripts["slidingWindow"]
+	result, err := script.Run(ctx, c.client, []string{key}, window.Milliseconds(), limit, uuid.New().String()).Int64Slice()
+	if err != nil {
+		c.recordError()
+		return false, 0, 0, fmt.Errorf("error checking rate limit: %w", err)
+	}
+	if len(result) != 3 {
+		return false, 0, 0, fmt.Errorf("unexpected rate limit result: %v", result)
+	}
+
+	return result[0] == 1, int(result[1]), time.Duration(result[2]) * time.Millisecond, nil
+}
+
 // SAdd adds members to a set
 func (c *Client) SAdd(ctx context.Context, key string, members ...any) (int64, error) {
 	c.checkAndResetClient()
@@ -781,6 +914,37 @@ func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
 	return result, nil
 }
 
+// HGetAll gets all fields and values of a hash
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("redis hgetall error: %w", err)
+	}
+
+	return result, nil
+}
+
+// ZRevRangeByScoreWithScores gets members of a sorted set with scores between min and max, highest first
+func (c *Client) ZRevRangeByScoreWithScores(ctx context.Context, key string, min, max string, offset, count int64) ([]redis.Z, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:    min,
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("redis zrevrangebyscore error: %w", err)
+	}
+
+	return result, nil
+}
+
 // SIsMember checks if a value is a member of a set
 func (c *Client) SIsMember(ctx context.Context, key string, member any) (bool, error) {
 	c.checkAndResetClient()
@@ -807,6 +971,134 @@ func (c *Client) SCard(ctx context.Context, key string) (int64, error) {
 	return result, nil
 }
 
+// SetNX sets a key only if it does not exist yet and reports whether it was set
+func (c *Client) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.SetNX(ctx, key, value, expiration).Result()
+	if err != nil {
+		c.recordError()
+		return false, fmt.Errorf("redis setnx error: %w", err)
+	}
+
+	return result, nil
+}
+
+// PFAdd adds elements to a HyperLogLog
+func (c *Client) PFAdd(ctx context.Context, key string, elements ...any) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.PFAdd(ctx, key, elements...).Result()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis pfadd error: %w", err)
+	}
+
+	return result, nil
+}
+
+// PFCount gets the approximate number of distinct elements in one or more HyperLogLogs
+func (c *Client) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.PFCount(ctx, keys...).Result()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis pfcount error: %w", err)
+	}
+
+	return result, nil
+}
+
+// LPushCapped prepends a value to a list, trims the list to its newest maxLen values and renews its
+// expiration, all in one transaction
+func (c *Client) LPushCapped(ctx context.Context, key string, value any, maxLen int64, expiration time.Duration) error {
+	c.checkAndResetClient()
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, value)
+		pipe.LTrim(ctx, key, 0, maxLen-1)
+		pipe.Expire(ctx, key, expiration)
+		return nil
+	})
+	if err != nil {
+		c.recordError()
+		return fmt.Errorf("redis lpush error: %w", err)
+	}
+
+	return nil
+}
+
+// LRange gets the values of a list between start and stop, inclusive
+func (c *Client) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.LRange(ctx, key, start, stop).Result()
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("redis lrange error: %w", err)
+	}
+
+	return result, nil
+}
+
+// LLen gets the length of a list, 0 when it does not exist
+func (c *Client) LLen(ctx context.Context, key string) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.LLen(ctx, key).Result()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis llen error: %w", err)
+	}
+
+	return result, nil
+}
+
+// RPop removes and returns the oldest value of a list, or "" when the list is empty
+func (c *Client) RPop(ctx context.Context, key string) (string, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.RPop(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil
+		}
+		c.recordError()
+		return "", fmt.Errorf("redis rpop error: %w", err)
+	}
+
+	return result, nil
+}
+
+// Publish sends a message to every subscriber of a channel and returns how many received it
+func (c *Client) Publish(ctx context.Context, channel string, message any) (int64, error) {
+	c.checkAndResetClient()
+
+	result, err := c.client.Publish(ctx, channel, message).Result()
+	if err != nil {
+		c.recordError()
+		return 0, fmt.Errorf("redis publish error: %w", err)
+	}
+
+	return result, nil
+}
+
+// PSubscribe subscribes to every channel matching the patterns.
+// The subscription is confirmed before returning; the caller must close it.
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) (*redis.PubSub, error) {
+	c.checkAndResetClient()
+
+	pubsub := c.client.PSubscribe(ctx, patterns...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		c.recordError()
+		pubsub.Close()
+		return nil, fmt.Errorf("redis psubscribe error: %w", err)
+	}
+
+	return pubsub, nil
+}
+
 // Eval executes a Lua script in Redis
 func (c *Client) Eval(ctx context.Context, script string, keys []string, args []string) (any, error) {
 	c.checkAndResetClient()
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"cirrussync-api/pkg/tracing"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracingHook records a span for every command and pipeline, as a child of the span in the
+// context the command was sent with. Commands sent outside a trace are skipped and cache misses
+// are not errors.
+type tracingHook struct{}
+
+func (tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if !tracing.InTrace(ctx) {
+			return next(ctx, network, addr)
+		}
+
+		ctx, span := tracing.StartClient(ctx, "redis.dial", attribute.String("db.system", "redis"))
+		conn, err := next(ctx, network, addr)
+		tracing.End(span, err)
+		return conn, err
+	}
+}
+
+func (tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !tracing.InTrace(ctx) {
+			return next(ctx, cmd)
+		}
+
+		ctx, span := tracing.StartClient(ctx, "redis."+cmd.Name(),
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", cmd.Name()),
+		)
+		err := next(ctx, cmd)
+		tracing.End(span, ignoreNil(err))
+		return err
+	}
+}
+
+func (tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !tracing.InTrace(ctx) {
+			return next(ctx, cmds)
+		}
+
+		names := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			names[i] = cmd.Name()
+		}
+
+		ctx, span := tracing.StartClient(ctx, "redis.pipeline",
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", strings.Join(names, " ")),
+			attribute.Int("db.redis.pipeline_length", len(cmds)),
+		)
+		err := next(ctx, cmds)
+		tracing.End(span, ignoreNil(err))
+		return err
+	}
+}
+
+// ignoreNil drops the error go-redis reports for missing keys
+func ignoreNil(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
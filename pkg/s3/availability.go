@@ -0,0 +1,125 @@
+// pkg/s3/availability.go
+package s3
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// availability tracks whether the bucket is reachable from the outcome of every S3 call.
+// Consecutive failures past the threshold mark storage degraded; any answer from S3,
+// even an error about a single object, marks it available again.
+type availability struct {
+	mu            sync.Mutex
+	threshold     int
+	failures      int
+	degradedSince time.Time
+}
+
+// record counts the outcome of one call
+func (a *availability) record(reachable bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if reachable {
+		if !a.degradedSince.IsZero() {
+			log.Printf("Storage is available again after an outage of %s", time.Since(a.degradedSince).Round(time.Second))
+		}
+		a.failures = 0
+		a.degradedSince = time.Time{}
+		return
+	}
+
+	a.failures++
+	if a.failures >= a.threshold && a.degradedSince.IsZero() {
+		a.degradedSince = time.Now()
+		log.Printf("Storage is degraded after %d consecutive failed calls", a.failures)
+	}
+}
+
+// since returns when storage became degraded, or the zero time while it is available
+func (a *availability) since() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.degradedSince
+}
+
+// observeRequest is a completion handler of the S3 client that feeds every call into the availability
+// tracker. Cancelled calls say nothing about storage and are ignored.
+func (c *Client) observeRequest(r *request.Request) {
+	if r.Error == nil {
+		c.availability.record(true)
+		return
+	}
+
+	var awsErr awserr.Error
+	if errors.As(r.Error, &awsErr) && awsErr.Code() == request.CanceledErrorCode {
+		return
+	}
+	if errors.Is(r.Error, context.Canceled) {
+		return
+	}
+
+	// Client errors such as a missing object still prove the bucket answers
+	reachable := r.HTTPResponse != nil &&
+		r.HTTPResponse.StatusCode < http.StatusInternalServerError &&
+		r.HTTPResponse.StatusCode != http.StatusTooManyRequests
+	c.availability.record(reachable)
+}
+
+// Available reports whether storage is currently considered reachable
+func (c *Client) Available() bool {
+	return c.availability.since().IsZero()
+}
+
+// DegradedSince returns when storage became unreachable, or the zero time while it is available
+func (c *Client) DegradedSince() time.Time {
+	return c.availability.since()
+}
+
+// ProbeInterval returns how often storage is probed, which is also a sensible client retry delay
+func (c *Client) ProbeInterval() time.Duration {
+	return c.probeInterval
+}
+
+// StartAvailabilityProbe checks the bucket every probe interval until ctx is cancelled. Most traffic
+// uses presigned URLs and never reaches the server, so outages and recoveries would otherwise only be
+// noticed by the few calls the server makes itself. A zero interval disables probing.
+func (c *Client) StartAvailabilityProbe(ctx context.Context) {
+	if c.probeInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.probeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			probeCtx, cancel := context.WithTimeout(ctx, c.probeInterval)
+			// Answers are recorded by the completion handler, which ignores cancelled calls,
+			// so a probe that hangs until its deadline is counted here
+			_, err := c.s3Client.HeadBucketWithContext(probeCtx, &s3.HeadBucketInput{
+				Bucket: aws.String(c.bucketName),
+			})
+			if err != nil && errors.Is(probeCtx.Err(), context.DeadlineExceeded) {
+				c.availability.record(false)
+			}
+			cancel()
+		}
+	}()
+}
@@ -2,18 +2,61 @@
 package s3
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/s3"
 
 	"cirrussync-api/pkg/config"
+	"cirrussync-api/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ReplicationTag marks objects for the bucket's cross-region replication rule
+const ReplicationTag = "replication=cross-region"
+
+// BlockUploadConditions are the properties a presigned block upload is bound to
+type BlockUploadConditions struct {
+	Size           int64
+	ChecksumSHA256 string // Base64 SHA-256 of the block, optional
+	Replicate      bool
+}
+
+// ErrObjectNotFound is returned when an object does not exist in the bucket
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrEncryptionKeyUnusable is returned when a KMS key cannot be used to encrypt objects,
+// because it does not exist, is disabled or has not granted access to the storage account
+var ErrEncryptionKeyUnusable = errors.New("encryption key is not usable")
+
+// ObjectInfo is the stored metadata of an object
+type ObjectInfo struct {
+	Size           int64
+	ETag           string
+	ChecksumSHA256 string // Base64 SHA-256, empty if the object was stored without one
+}
+
+// PresignedUpload is a presigned PUT request and the headers the client must send with it
+type PresignedUpload struct {
+	URL     string
+	Headers map[string]string
+}
+
 var (
 	// Global S3 client instance
 	client     *Client
@@ -22,8 +65,13 @@ var (
 
 // Client wraps S3 functionality
 type Client struct {
-	s3Client   *s3.S3
-	bucketName string
+	s3Client      *s3.S3
+	kmsClient     *kms.KMS
+	bucketName    string
+	region        string
+	replicaRegion string
+	availability  *availability
+	probeInterval time.Duration
 }
 
 // NewClient initializes a new S3 client
@@ -34,10 +82,25 @@ func NewClient(config *config.S3Config) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{
-		s3Client:   s3Client,
-		bucketName: config.BucketName,
-	}, nil
+	kmsClient, err := NewKMSConnection(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		s3Client:      s3Client,
+		kmsClient:     kmsClient,
+		bucketName:    config.BucketName,
+		region:        config.Region,
+		replicaRegion: config.ReplicaRegion,
+		availability:  &availability{threshold: max(config.DegradedAfterFailures, 1)},
+		probeInterval: config.ProbeInterval,
+	}
+
+	// Every call, whatever operation made it, tells whether storage answers
+	s3Client.Handlers.Complete.PushBack(c.observeRequest)
+
+	return c, nil
 }
 
 // InitS3 initializes the global S3 client instance
@@ -54,15 +117,34 @@ func GetS3Client() *Client {
 	return client
 }
 
+// BucketName returns the bucket objects are stored in
+func (c *Client) BucketName() string {
+	return c.bucketName
+}
+
+// Region returns the region of the bucket
+func (c *Client) Region() string {
+	return c.region
+}
+
+// ReplicaRegion returns the region objects tagged for replication are copied to, or an empty string
+// when the bucket has no cross-region replication rule
+func (c *Client) ReplicaRegion() string {
+	return c.replicaRegion
+}
+
 // CreateEmptyDirectory creates an empty directory marker in S3
-func (c *Client) CreateEmptyDirectory(path string) error {
+func (c *Client) CreateEmptyDirectory(ctx context.Context, path string) (err error) {
 	// Ensure path ends with a slash
 	if path[len(path)-1] != '/' {
 		path = path + "/"
 	}
 
+	ctx, span := c.startSpan(ctx, "PutObject", path)
+	defer func() { tracing.End(span, err) }()
+
 	// Put an empty object to create the "directory"
-	_, err := c.s3Client.PutObject(&s3.PutObjectInput{
+	_, err = c.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(c.bucketName),
 		Key:    aws.String(path),
 		Body:   strings.NewReader(""),
@@ -72,7 +154,7 @@ func (c *Client) CreateEmptyDirectory(path string) error {
 }
 
 // CreateUserBaseDirectories sets up the initial directory structure for a new user
-func (c *Client) CreateUserBaseDirectories(userID, volumeID string) error {
+func (c *Client) CreateUserBaseDirectories(ctx context.Context, userID, volumeID string) error {
 	directories := []string{
 		fmt.Sprintf("users/%s/", userID),
 		fmt.Sprintf("users/%s/volumes/", userID),
@@ -82,7 +164,7 @@ func (c *Client) CreateUserBaseDirectories(userID, volumeID string) error {
 	}
 
 	for _, dir := range directories {
-		if err := c.CreateEmptyDirectory(dir); err != nil {
+		if err := c.CreateEmptyDirectory(ctx, dir); err != nil {
 			return err
 		}
 	}
@@ -90,6 +172,92 @@ func (c *Client) CreateUserBaseDirectories(userID, volumeID string) error {
 	return nil
 }
 
+// PutObject stores a small object generated by the server, such as a report
+func (c *Client) PutObject(ctx context.Context, key, contentType string, body []byte) (err error) {
+	ctx, span := c.startSpan(ctx, "PutObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = c.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(c.bucketName),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        bytes.NewReader(body),
+	})
+	return err
+}
+
+// GetObject reads a small object, such as a thumbnail, into memory. Objects larger than maxBytes are
+// refused, and a missing object returns ErrObjectNotFound.
+func (c *Client) GetObject(ctx context.Context, key string, maxBytes int64) (body []byte, err error) {
+	ctx, span := c.startSpan(ctx, "GetObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := c.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var reqErr awserr.RequestFailure
+		if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer result.Body.Close()
+
+	body, err = io.ReadAll(io.LimitReader(result.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("object %s is larger than %d bytes", key, maxBytes)
+	}
+
+	return body, nil
+}
+
+// ObjectSHA256 streams an object and returns its base64 SHA-256, for objects stored without a
+// checksum or too large to read into memory. A missing object returns ErrObjectNotFound.
+func (c *Client) ObjectSHA256(ctx context.Context, key string) (checksum string, err error) {
+	ctx, span := c.startSpan(ctx, "GetObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := c.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var reqErr awserr.RequestFailure
+		if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+			return "", ErrObjectNotFound
+		}
+		return "", err
+	}
+	defer result.Body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, result.Body); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CopyObject copies an object to another key of the bucket without downloading it.
+// Tags are copied with the object, so replicated blocks stay replicated, and the copy is stored as standard.
+func (c *Client) CopyObject(ctx context.Context, sourceKey, destinationKey string) (err error) {
+	ctx, span := c.startSpan(ctx, "CopyObject", destinationKey)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = c.s3Client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		Bucket:       aws.String(c.bucketName),
+		Key:          aws.String(destinationKey),
+		CopySource:   aws.String(c.copySource(sourceKey)),
+		StorageClass: aws.String(s3.StorageClassStandard),
+	})
+	return err
+}
+
 // GetUploadPresignedURL generates a presigned URL for uploading a file
 func (c *Client) GetUploadPresignedURL(key string, contentType string, expiresIn time.Duration) (string, error) {
 	// Create a request for the specified object
@@ -125,31 +293,58 @@ func (c *Client) GetDownloadPresignedURL(key string, expiresIn time.Duration) (s
 	return url, nil
 }
 
-// PrepareFileBlockUpload creates the file directory if needed and returns a presigned URL for block upload
-func (c *Client) PrepareFileBlockUpload(userID, volumeID, fileID, revisionID string, blockIndex int) (string, error) {
+// FileBlockPath returns the object key for a block of a file revision
+func FileBlockPath(userID, volumeID, fileID, revisionID string, blockIndex int) string {
+	return fmt.Sprintf("users/%s/volumes/%s/files/%s/%s/block_%s",
+		userID, volumeID, fileID, revisionID, strconv.Itoa(blockIndex))
+}
+
+// PrepareFileBlockUpload creates the file directory if needed and returns a presigned request for block upload.
+// The request is signed for the exact block size, and for its checksum when one is given, so S3 rejects
+// uploads that do not match what the client declared. Replicated blocks are tagged so the bucket's
+// replication rule copies them to the replica region.
+func (c *Client) PrepareFileBlockUpload(ctx context.Context, userID, volumeID, fileID, revisionID string, blockIndex int, conditions BlockUploadConditions) (*PresignedUpload, error) {
 	// Define the path for the file blocks
-	fileDir := fmt.Sprintf("users/%s/volumes/%s/files/%s/", userID, volumeID, fileID)
-	blockPath := fmt.Sprintf("%sblock_%s", fileDir, strconv.Itoa(blockIndex))
+	fileDir := fmt.Sprintf("users/%s/volumes/%s/files/%s/%s/", userID, volumeID, fileID, revisionID)
+	blockPath := FileBlockPath(userID, volumeID, fileID, revisionID, blockIndex)
 
 	// Ensure the file directory exists
-	if err := c.CreateEmptyDirectory(fileDir); err != nil {
-		return "", err
+	if err := c.CreateEmptyDirectory(ctx, fileDir); err != nil {
+		return nil, err
 	}
 
-	// Generate a presigned URL for upload, valid for 15 minutes
-	uploadURL, err := c.GetUploadPresignedURL(blockPath, "application/octet-stream", 15*time.Minute)
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(c.bucketName),
+		Key:           aws.String(blockPath),
+		ContentType:   aws.String("application/octet-stream"),
+		ContentLength: aws.Int64(conditions.Size),
+	}
+	if conditions.ChecksumSHA256 != "" {
+		input.ChecksumSHA256 = aws.String(conditions.ChecksumSHA256)
+	}
+	if conditions.Replicate {
+		input.Tagging = aws.String(ReplicationTag)
+	}
+
+	// Generate a presigned request for upload, valid for 15 minutes
+	req, _ := c.s3Client.PutObjectRequest(input)
+	uploadURL, signedHeaders, err := req.PresignRequest(15 * time.Minute)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(signedHeaders))
+	for name := range signedHeaders {
+		headers[name] = signedHeaders.Get(name)
 	}
 
-	return uploadURL, nil
+	return &PresignedUpload{URL: uploadURL, Headers: headers}, nil
 }
 
 // GetFileBlockDownloadURL returns a presigned URL for downloading a file block
-func (c *Client) GetFileBlockDownloadURL(userID, volumeID, fileID string, blockIndex int) (string, error) {
+func (c *Client) GetFileBlockDownloadURL(userID, volumeID, fileID, revisionID string, blockIndex int) (string, error) {
 	// Build the path to the block
-	blockPath := fmt.Sprintf("users/%s/volumes/%s/files/%s/block_%s",
-		userID, volumeID, fileID, strconv.Itoa(blockIndex))
+	blockPath := FileBlockPath(userID, volumeID, fileID, revisionID, blockIndex)
 
 	// Generate a presigned URL for download, valid for 15 minutes
 	downloadURL, err := c.GetDownloadPresignedURL(blockPath, 15*time.Minute)
@@ -160,31 +355,126 @@ func (c *Client) GetFileBlockDownloadURL(userID, volumeID, fileID string, blockI
 	return downloadURL, nil
 }
 
-// PrepareThumbnailUpload creates thumbnail directory and returns a presigned URL for thumbnail upload
-func (c *Client) PrepareThumbnailUpload(userID, volumeID, fileID, size string) (string, error) {
+// GetObjectSize returns the size of an object, or an error if it does not exist
+func (c *Client) GetObjectSize(ctx context.Context, key string) (size int64, err error) {
+	ctx, span := c.startSpan(ctx, "HeadObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(c.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return aws.Int64Value(result.ContentLength), nil
+}
+
+// HeadObject returns the stored metadata of an object, or ErrObjectNotFound if it does not exist
+func (c *Client) HeadObject(ctx context.Context, key string) (info *ObjectInfo, err error) {
+	ctx, span := c.startSpan(ctx, "HeadObject", key)
+	defer func() {
+		// A missing object is an answer, not a failure
+		if errors.Is(err, ErrObjectNotFound) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	result, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(c.bucketName),
+		Key:          aws.String(key),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	})
+	if err != nil {
+		var reqErr awserr.RequestFailure
+		if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Size:           aws.Int64Value(result.ContentLength),
+		ETag:           strings.Trim(aws.StringValue(result.ETag), `"`),
+		ChecksumSHA256: aws.StringValue(result.ChecksumSHA256),
+	}, nil
+}
+
+// ThumbnailPath returns the object key for a thumbnail of a file revision
+func ThumbnailPath(userID, volumeID, fileID, revisionID, size string) string {
+	return fmt.Sprintf("users/%s/volumes/%s/thumbnails/%s/%s/%s", userID, volumeID, fileID, revisionID, size)
+}
+
+// PrepareThumbnailUpload creates the thumbnail directory and returns a presigned request for thumbnail upload.
+// When a KMS key is given the object is encrypted with it, and the encryption headers are part of the
+// signature so the upload cannot fall back to the bucket's default encryption.
+func (c *Client) PrepareThumbnailUpload(ctx context.Context, userID, volumeID, fileID, revisionID, size, kmsKeyARN string) (*PresignedUpload, error) {
 	// Define the path for the thumbnails
-	thumbnailDir := fmt.Sprintf("users/%s/volumes/%s/thumbnails/%s/", userID, volumeID, fileID)
-	thumbnailPath := fmt.Sprintf("%s%s", thumbnailDir, size) // size can be "small", "medium", "large"
+	thumbnailDir := fmt.Sprintf("users/%s/volumes/%s/thumbnails/%s/%s/", userID, volumeID, fileID, revisionID)
+	thumbnailPath := ThumbnailPath(userID, volumeID, fileID, revisionID, size) // size can be "small", "medium", "large"
 
 	// Ensure the thumbnail directory exists
-	if err := c.CreateEmptyDirectory(thumbnailDir); err != nil {
-		return "", err
+	if err := c.CreateEmptyDirectory(ctx, thumbnailDir); err != nil {
+		return nil, err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(c.bucketName),
+		Key:         aws.String(thumbnailPath),
+		ContentType: aws.String("image/jpeg"),
+	}
+	if kmsKeyARN != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(kmsKeyARN)
 	}
 
-	// Generate a presigned URL for upload, valid for 15 minutes
-	uploadURL, err := c.GetUploadPresignedURL(thumbnailPath, "image/jpeg", 15*time.Minute)
+	// Generate a presigned request for upload, valid for 15 minutes
+	req, _ := c.s3Client.PutObjectRequest(input)
+	uploadURL, signedHeaders, err := req.PresignRequest(15 * time.Minute)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(signedHeaders))
+	for name := range signedHeaders {
+		headers[name] = signedHeaders.Get(name)
+	}
+
+	return &PresignedUpload{URL: uploadURL, Headers: headers}, nil
+}
+
+// VerifyEncryptionKey checks that S3 will be able to encrypt objects with a KMS key by asking
+// KMS for a data key under it, which needs the same permission S3 uses on upload
+func (c *Client) VerifyEncryptionKey(ctx context.Context, kmsKeyARN string) (err error) {
+	ctx, span := tracing.StartClient(ctx, "kms.GenerateDataKey", attribute.String("rpc.system", "aws-api"))
+	defer func() { tracing.End(span, err) }()
+
+	_, err = c.kmsClient.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(kmsKeyARN),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) {
+			switch awsErr.Code() {
+			case kms.ErrCodeNotFoundException, kms.ErrCodeDisabledException, kms.ErrCodeInvalidStateException,
+				kms.ErrCodeKeyUnavailableException, kms.ErrCodeInvalidKeyUsageException, "AccessDeniedException":
+				return fmt.Errorf("%w: %s", ErrEncryptionKeyUnusable, awsErr.Code())
+			}
+		}
+		return err
 	}
 
-	return uploadURL, nil
+	return nil
 }
 
 // GetThumbnailDownloadURL returns a presigned URL for downloading a thumbnail
-func (c *Client) GetThumbnailDownloadURL(userID, volumeID, fileID, size string) (string, error) {
+func (c *Client) GetThumbnailDownloadURL(userID, volumeID, fileID, revisionID, size string) (string, error) {
 	// Build the path to the thumbnail
-	thumbnailPath := fmt.Sprintf("users/%s/volumes/%s/thumbnails/%s/%s",
-		userID, volumeID, fileID, size)
+	thumbnailPath := ThumbnailPath(userID, volumeID, fileID, revisionID, size)
 
 	// Generate a presigned URL for download, valid for 15 minutes
 	downloadURL, err := c.GetDownloadPresignedURL(thumbnailPath, 15*time.Minute)
@@ -196,18 +486,21 @@ func (c *Client) GetThumbnailDownloadURL(userID, volumeID, fileID, size string)
 }
 
 // ListObjects lists objects in a directory (prefix)
-func (c *Client) ListObjects(prefix string) ([]string, error) {
+func (c *Client) ListObjects(ctx context.Context, prefix string) (keys []string, err error) {
+	ctx, span := c.startSpan(ctx, "ListObjectsV2", prefix)
+	defer func() { tracing.End(span, err) }()
+
 	input := &s3.ListObjectsV2Input{
 		Bucket: aws.String(c.bucketName),
 		Prefix: aws.String(prefix),
 	}
 
-	result, err := c.s3Client.ListObjectsV2(input)
+	result, err := c.s3Client.ListObjectsV2WithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 
-	keys := make([]string, 0, len(result.Contents))
+	keys = make([]string, 0, len(result.Contents))
 	for _, obj := range result.Contents {
 		if obj.Key != nil {
 			keys = append(keys, *obj.Key)
@@ -217,26 +510,72 @@ func (c *Client) ListObjects(prefix string) ([]string, error) {
 	return keys, nil
 }
 
+// ObjectSummary is an object as listed, without its stored metadata
+type ObjectSummary struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ListObjectsPage lists one page of the objects under a prefix in key order, starting after a key.
+// It returns the key the next page starts after, empty after the last page. Unlike continuation
+// tokens, the key stays valid for as long as a caller wants to pause between pages.
+func (c *Client) ListObjectsPage(ctx context.Context, prefix, startAfter string, maxKeys int64) (objects []*ObjectSummary, next string, err error) {
+	ctx, span := c.startSpan(ctx, "ListObjectsV2", prefix)
+	defer func() { tracing.End(span, err) }()
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(c.bucketName),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(maxKeys),
+	}
+	if startAfter != "" {
+		input.StartAfter = aws.String(startAfter)
+	}
+
+	result, err := c.s3Client.ListObjectsV2WithContext(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	objects = make([]*ObjectSummary, 0, len(result.Contents))
+	for _, obj := range result.Contents {
+		objects = append(objects, &ObjectSummary{
+			Key:          aws.StringValue(obj.Key),
+			Size:         aws.Int64Value(obj.Size),
+			LastModified: aws.TimeValue(obj.LastModified),
+		})
+	}
+
+	if aws.BoolValue(result.IsTruncated) && len(objects) > 0 {
+		next = objects[len(objects)-1].Key
+	}
+	return objects, next, nil
+}
+
 // DeleteObject deletes an object from S3
-func (c *Client) DeleteObject(key string) error {
+func (c *Client) DeleteObject(ctx context.Context, key string) (err error) {
+	ctx, span := c.startSpan(ctx, "DeleteObject", key)
+	defer func() { tracing.End(span, err) }()
+
 	input := &s3.DeleteObjectInput{
 		Bucket: aws.String(c.bucketName),
 		Key:    aws.String(key),
 	}
 
-	_, err := c.s3Client.DeleteObject(input)
+	_, err = c.s3Client.DeleteObjectWithContext(ctx, input)
 	return err
 }
 
 // DeleteDirectory deletes all objects under a directory prefix
-func (c *Client) DeleteDirectory(prefix string) error {
+func (c *Client) DeleteDirectory(ctx context.Context, prefix string) error {
 	// Ensure path ends with a slash
 	if prefix[len(prefix)-1] != '/' {
 		prefix = prefix + "/"
 	}
 
 	// List all objects with the prefix
-	objects, err := c.ListObjects(prefix)
+	objects, err := c.ListObjects(ctx, prefix)
 	if err != nil {
 		return err
 	}
@@ -248,10 +587,21 @@ func (c *Client) DeleteDirectory(prefix string) error {
 
 	// Delete each object
 	for _, key := range objects {
-		if err := c.DeleteObject(key); err != nil {
+		if err := c.DeleteObject(ctx, key); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// startSpan starts the span of an S3 call on an object or prefix
+func (c *Client) startSpan(ctx context.Context, operation, key string) (context.Context, trace.Span) {
+	return tracing.StartClient(ctx, "s3."+operation,
+		attribute.String("rpc.system", "aws-api"),
+		attribute.String("rpc.service", "S3"),
+		attribute.String("rpc.method", operation),
+		attribute.String("aws.s3.bucket", c.bucketName),
+		attribute.String("aws.s3.key", key),
+	)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/s3"
 
 	"cirrussync-api/pkg/config"
@@ -12,6 +13,30 @@ import (
 
 // NewS3Connection creates a new S3 client connection
 func NewS3Connection(config *config.S3Config) (*s3.S3, error) {
+	sess, err := newAWSSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create S3 client
+	client := s3.New(sess)
+	return client, nil
+}
+
+// NewKMSConnection creates a KMS client with the storage credentials. Keys customers supply
+// for server-side encryption are checked with it before S3 is asked to use them.
+func NewKMSConnection(config *config.S3Config) (*kms.KMS, error) {
+	sess, err := newAWSSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// A custom storage endpoint is for S3 only; KMS always uses the AWS endpoint of the region
+	return kms.New(sess, &aws.Config{Endpoint: aws.String("")}), nil
+}
+
+// newAWSSession creates an AWS session for the configured region and credentials
+func newAWSSession(config *config.S3Config) (*session.Session, error) {
 	// Create custom AWS configuration
 	awsConfig := &aws.Config{
 		Region:      aws.String(config.Region),
@@ -26,12 +51,5 @@ func NewS3Connection(config *config.S3Config) (*s3.S3, error) {
 	}
 
 	// Initialize AWS session
-	sess, err := session.NewSession(awsConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create S3 client
-	client := s3.New(sess)
-	return client, nil
+	return session.NewSession(awsConfig)
 }
@@ -0,0 +1,307 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"cirrussync-api/pkg/tracing"
+)
+
+// MaxSinglePutSize is the largest object a single PUT or copy can write. Larger blocks are uploaded,
+// and larger objects copied, in parts.
+const MaxSinglePutSize int64 = 5 << 30
+
+const (
+	// minMultipartPartSize is where part sizes start; it is doubled until the parts fit the part limit
+	minMultipartPartSize int64 = 64 << 20
+	// maxMultipartParts is the most parts S3 accepts for one object
+	maxMultipartParts = 10000
+	// listPageSize bounds how many entries one list call of a cleanup returns
+	listPageSize int64 = 1000
+)
+
+// ErrMultipartIncomplete is returned when the parts uploaded for a multipart upload do not add up to
+// the declared object
+var ErrMultipartIncomplete = errors.New("multipart upload is incomplete")
+
+// PresignedPart is a presigned PUT request for one part of a multipart upload
+type PresignedPart struct {
+	Number  int
+	Size    int64
+	URL     string
+	Headers map[string]string
+}
+
+// MultipartUpload is a started multipart upload and the presigned requests for each of its parts
+type MultipartUpload struct {
+	UploadID string
+	Parts    []*PresignedPart
+}
+
+// MultipartPartSize returns the part size an object of the given size is split into
+func MultipartPartSize(size int64) int64 {
+	partSize := minMultipartPartSize
+	for (size+partSize-1)/partSize > maxMultipartParts {
+		partSize *= 2
+	}
+	return partSize
+}
+
+// PrepareMultipartFileBlockUpload starts a multipart upload for a block too large for a single PUT and
+// presigns every part for its exact size. The block only appears in the bucket once the upload is
+// completed with CompleteMultipartUpload. Replicated blocks are tagged like single-part blocks.
+func (c *Client) PrepareMultipartFileBlockUpload(ctx context.Context, userID, volumeID, fileID, revisionID string, blockIndex int, conditions BlockUploadConditions) (upload *MultipartUpload, err error) {
+	blockPath := FileBlockPath(userID, volumeID, fileID, revisionID, blockIndex)
+
+	ctx, span := c.startSpan(ctx, "CreateMultipartUpload", blockPath)
+	defer func() { tracing.End(span, err) }()
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(c.bucketName),
+		Key:         aws.String(blockPath),
+		ContentType: aws.String("application/octet-stream"),
+	}
+	if conditions.Replicate {
+		input.Tagging = aws.String(ReplicationTag)
+	}
+
+	result, err := c.s3Client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	uploadID := aws.StringValue(result.UploadId)
+
+	partSize := MultipartPartSize(conditions.Size)
+	parts := make([]*PresignedPart, 0, (conditions.Size+partSize-1)/partSize)
+	for offset, number := int64(0), 1; offset < conditions.Size; offset, number = offset+partSize, number+1 {
+		size := min(partSize, conditions.Size-offset)
+
+		// Parts are valid for as long as single-part block uploads
+		req, _ := c.s3Client.UploadPartRequest(&s3.UploadPartInput{
+			Bucket:        aws.String(c.bucketName),
+			Key:           aws.String(blockPath),
+			UploadId:      aws.String(uploadID),
+			PartNumber:    aws.Int64(int64(number)),
+			ContentLength: aws.Int64(size),
+		})
+		partURL, signedHeaders, err := req.PresignRequest(15 * time.Minute)
+		if err != nil {
+			// Do not leave the parts already stored behind
+			_ = c.AbortMultipartUpload(context.WithoutCancel(ctx), blockPath, uploadID)
+			return nil, err
+		}
+
+		headers := make(map[string]string, len(signedHeaders))
+		for name := range signedHeaders {
+			headers[name] = signedHeaders.Get(name)
+		}
+		parts = append(parts, &PresignedPart{Number: number, Size: size, URL: partURL, Headers: headers})
+	}
+
+	return &MultipartUpload{UploadID: uploadID, Parts: parts}, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts of a multipart upload into the object. The parts
+// are listed from storage rather than taken from the client, and must be numbered from 1 without gaps
+// and add up to the declared size; otherwise ErrMultipartIncomplete is returned and the upload is kept,
+// so the missing parts can still be uploaded.
+func (c *Client) CompleteMultipartUpload(ctx context.Context, key, uploadID string, size int64) (err error) {
+	ctx, span := c.startSpan(ctx, "CompleteMultipartUpload", key)
+	defer func() {
+		if errors.Is(err, ErrMultipartIncomplete) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	var completed []*s3.CompletedPart
+	var total int64
+	input := &s3.ListPartsInput{
+		Bucket:   aws.String(c.bucketName),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	}
+	for {
+		result, err := c.s3Client.ListPartsWithContext(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		for _, part := range result.Parts {
+			if aws.Int64Value(part.PartNumber) != int64(len(completed)+1) {
+				return ErrMultipartIncomplete
+			}
+			completed = append(completed, &s3.CompletedPart{ETag: part.ETag, PartNumber: part.PartNumber})
+			total += aws.Int64Value(part.Size)
+		}
+
+		if !aws.BoolValue(result.IsTruncated) {
+			break
+		}
+		input.PartNumberMarker = result.NextPartNumberMarker
+	}
+
+	if len(completed) == 0 || total != size {
+		return ErrMultipartIncomplete
+	}
+
+	_, err = c.s3Client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(c.bucketName),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	return err
+}
+
+// AbortMultipartUpload discards a multipart upload and the parts uploaded for it
+func (c *Client) AbortMultipartUpload(ctx context.Context, key, uploadID string) (err error) {
+	ctx, span := c.startSpan(ctx, "AbortMultipartUpload", key)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = c.s3Client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(c.bucketName),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	return err
+}
+
+// AbortStaleMultipartUploads aborts up to limit multipart uploads under a prefix that were started
+// before a time, so parts of uploads that were never completed stop taking up storage. It returns how
+// many uploads were aborted.
+func (c *Client) AbortStaleMultipartUploads(ctx context.Context, prefix string, initiatedBefore time.Time, limit int) (aborted int, err error) {
+	ctx, span := c.startSpan(ctx, "ListMultipartUploads", prefix)
+	defer func() { tracing.End(span, err) }()
+
+	input := &s3.ListMultipartUploadsInput{
+		Bucket:     aws.String(c.bucketName),
+		Prefix:     aws.String(prefix),
+		MaxUploads: aws.Int64(listPageSize),
+	}
+	for aborted < limit {
+		result, err := c.s3Client.ListMultipartUploadsWithContext(ctx, input)
+		if err != nil {
+			return aborted, err
+		}
+
+		for _, upload := range result.Uploads {
+			if aborted >= limit {
+				break
+			}
+			if upload.Initiated == nil || !upload.Initiated.Before(initiatedBefore) {
+				continue
+			}
+			if err := c.AbortMultipartUpload(ctx, aws.StringValue(upload.Key), aws.StringValue(upload.UploadId)); err != nil {
+				return aborted, err
+			}
+			aborted++
+		}
+
+		if !aws.BoolValue(result.IsTruncated) {
+			break
+		}
+		input.KeyMarker = result.NextKeyMarker
+		input.UploadIdMarker = result.NextUploadIdMarker
+	}
+
+	return aborted, nil
+}
+
+// TransitionObject moves an object to another storage class by copying it onto itself. Replicated
+// blocks are tagged again rather than having their tags copied, since a copy in parts cannot copy
+// them. Objects larger than a single copy allows are copied in parts.
+func (c *Client) TransitionObject(ctx context.Context, key, storageClass string, replicate bool) (err error) {
+	ctx, span := c.startSpan(ctx, "CopyObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	info, err := c.HeadObject(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	tagging := ""
+	if replicate {
+		tagging = ReplicationTag
+	}
+
+	if info.Size > MaxSinglePutSize {
+		return c.transitionObjectInParts(ctx, key, storageClass, tagging, info.Size)
+	}
+
+	_, err = c.s3Client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(c.bucketName),
+		Key:               aws.String(key),
+		CopySource:        aws.String(c.copySource(key)),
+		StorageClass:      aws.String(storageClass),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+		TaggingDirective:  aws.String(s3.TaggingDirectiveReplace),
+		Tagging:           aws.String(tagging),
+	})
+	return err
+}
+
+// transitionObjectInParts copies an object onto itself in another storage class through a multipart
+// upload whose parts are copied from the object
+func (c *Client) transitionObjectInParts(ctx context.Context, key, storageClass, tagging string, size int64) error {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:       aws.String(c.bucketName),
+		Key:          aws.String(key),
+		StorageClass: aws.String(storageClass),
+	}
+	if tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+
+	result, err := c.s3Client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	uploadID := aws.StringValue(result.UploadId)
+
+	partSize := MultipartPartSize(size)
+	var completed []*s3.CompletedPart
+	for offset, number := int64(0), int64(1); offset < size; offset, number = offset+partSize, number+1 {
+		end := min(offset+partSize, size) - 1
+
+		part, err := c.s3Client.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(c.bucketName),
+			Key:             aws.String(key),
+			UploadId:        aws.String(uploadID),
+			PartNumber:      aws.Int64(number),
+			CopySource:      aws.String(c.copySource(key)),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+		})
+		if err != nil {
+			_ = c.AbortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+			return err
+		}
+
+		completed = append(completed, &s3.CompletedPart{ETag: part.CopyPartResult.ETag, PartNumber: aws.Int64(number)})
+	}
+
+	_, err = c.s3Client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(c.bucketName),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		_ = c.AbortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return err
+	}
+
+	return nil
+}
+
+// copySource returns the escaped copy source of an object of the bucket
+func (c *Client) copySource(key string) string {
+	return (&url.URL{Path: c.bucketName + "/" + key}).EscapedPath()
+}
@@ -67,6 +67,7 @@ const (
 	StatusInternalServerError  int16 = 5000
 	StatusNotImplemented       int16 = 5001
 	StatusServiceUnavailable   int16 = 5002
+	StatusRequestTimeout       int16 = 5003
 	StatusDBError              int16 = 5010
 	StatusRedisError           int16 = 5011
 	StatusEncryptionError      int16 = 5020
@@ -132,6 +133,8 @@ func CodeToString(code int16) string {
 		return "Not implemented"
 	case StatusServiceUnavailable:
 		return "Service unavailable"
+	case StatusRequestTimeout:
+		return "Request timed out"
 	case StatusDBError:
 		return "Database error"
 	case StatusSRPError:
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer every span of the service is started from
+const instrumentationName = "cirrussync-api"
+
+// Config holds settings for OpenTelemetry tracing
+type Config struct {
+	Enabled     bool    // Whether spans are recorded and exported
+	ServiceName string  // service.name reported with every span
+	Endpoint    string  // OTLP/HTTP collector host:port
+	Insecure    bool    // Export over plain HTTP instead of HTTPS
+	SampleRatio float64 // Fraction of new traces sampled, traces from callers follow their decision
+}
+
+// Init installs an OTLP exporting tracer provider and the W3C trace context propagator. When
+// tracing is disabled the global no-op provider stays in place, so spans cost next to nothing.
+// The returned function flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the service's tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts an internal span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartClient starts a span for a call to a database, cache or storage backend
+func StartClient(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// InTrace reports whether ctx carries a span. Database and cache calls made outside any trace,
+// such as health checks, are not traced on their own so they do not flood the collector.
+func InTrace(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// End ends a span, marking it failed when err is set. Cancellations are recorded but not marked
+// as errors, since they are the caller going away rather than the operation failing.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, context.Canceled) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,186 @@
+// Package validation reports what is wrong with a request payload in one structured format, and
+// registers the validators request bindings share.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// Custom validation rules
+	RULE_LINK_ID     = "linkid"      // Link IDs as generated for drive items
+	RULE_PERMISSIONS = "permissions" // Drive permission masks
+	RULE_HASH        = "hash"        // Name and content hashes
+
+	// Range of drive permission masks, from read only to all permissions
+	MIN_PERMISSIONS = 1
+	MAX_PERMISSIONS = 31
+
+	// Length of name and content hashes, from a hex encoded 128-bit hash to a hex encoded 512-bit hash
+	MIN_HASH_LENGTH = 32
+	MAX_HASH_LENGTH = 128
+
+	// invalidFormatMessage describes payloads that could not be decoded at all
+	invalidFormatMessage = "Invalid request format"
+)
+
+// Link IDs are URL-safe base64
+var linkIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{16,64}$`)
+
+var registerOnce sync.Once
+
+// FieldError is one reason a request payload was rejected
+type FieldError struct {
+	Field   string `json:"field"`   // Path of the field as sent, e.g. "items[0].linkId"
+	Rule    string `json:"rule"`    // Rule the value broke, e.g. "required"
+	Message string `json:"message"` // Description of the problem for people
+}
+
+// Register adds the custom validators to the validator request bindings use and makes field errors
+// name fields as they are sent in JSON. Registering again has no effect.
+func Register() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		v.RegisterTagNameFunc(jsonFieldName)
+		_ = v.RegisterValidation(RULE_LINK_ID, validateLinkID)
+		_ = v.RegisterValidation(RULE_PERMISSIONS, validatePermissions)
+		_ = v.RegisterValidation(RULE_HASH, validateHash)
+	})
+}
+
+// Errors returns every field error of a failed request binding. Payloads that could not be decoded
+// have no field errors, unless a field had the wrong type.
+func Errors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrs := make([]FieldError, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			field := fieldPath(fieldErr)
+			fieldErrs[i] = FieldError{
+				Field:   field,
+				Rule:    fieldErr.Tag(),
+				Message: field + " " + describe(fieldErr),
+			}
+		}
+		return fieldErrs
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type),
+		}}
+	}
+
+	return nil
+}
+
+// Message summarizes a failed request binding in one sentence, naming the first field error
+func Message(err error) string {
+	if fieldErrs := Errors(err); len(fieldErrs) > 0 {
+		return fieldErrs[0].Message
+	}
+	return invalidFormatMessage
+}
+
+// fieldPath returns the path of a field without the name of the request type
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// describe says what a value must be to pass the rule it broke
+func describe(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + param + unit(fieldErr)
+	case "max", "lte":
+		return "must be at most " + param + unit(fieldErr)
+	case "len":
+		return "must be exactly " + param + unit(fieldErr)
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "base64":
+		return "must be base64 encoded"
+	case RULE_LINK_ID:
+		return "must be a valid link ID"
+	case RULE_PERMISSIONS:
+		return fmt.Sprintf("must be a permission mask from %d to %d", MIN_PERMISSIONS, MAX_PERMISSIONS)
+	case RULE_HASH:
+		return fmt.Sprintf("must be a hash of %d to %d characters", MIN_HASH_LENGTH, MAX_HASH_LENGTH)
+	default:
+		return "failed the " + fieldErr.Tag() + " rule"
+	}
+}
+
+// unit names what a size rule counts for the kind of the field
+func unit(fieldErr validator.FieldError) string {
+	switch fieldErr.Kind() {
+	case reflect.String:
+		return " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
+
+// jsonFieldName names a struct field as it appears in JSON
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// validateLinkID checks that a value is shaped like a link ID
+func validateLinkID(fl validator.FieldLevel) bool {
+	return linkIDRegex.MatchString(fl.Field().String())
+}
+
+// validatePermissions checks that a value is a drive permission mask
+func validatePermissions(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int() >= MIN_PERMISSIONS && field.Int() <= MAX_PERMISSIONS
+	default:
+		return false
+	}
+}
+
+// validateHash checks that a value has the length of a name or content hash
+func validateHash(fl validator.FieldLevel) bool {
+	length := len(fl.Field().String())
+	return length >= MIN_HASH_LENGTH && length <= MAX_HASH_LENGTH
+}
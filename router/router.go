@@ -1,35 +1,60 @@
 package router
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"os"
-	"strconv"
+	"strings"
 	"time"
 
+	accountAPI "cirrussync-api/api/v1/account"
+	adminAPI "cirrussync-api/api/v1/admin"
 	authAPI "cirrussync-api/api/v1/auth"
+	billingAPI "cirrussync-api/api/v1/billing"
 	csrfAPI "cirrussync-api/api/v1/csrf"
 	driveAPI "cirrussync-api/api/v1/drive"
 	mfaAPI "cirrussync-api/api/v1/mfa"
+	notificationsAPI "cirrussync-api/api/v1/notifications"
+	oauthAPI "cirrussync-api/api/v1/oauth"
+	orgAPI "cirrussync-api/api/v1/orgs"
+	securityAPI "cirrussync-api/api/v1/security"
 	sessionAPI "cirrussync-api/api/v1/sessions"
 	userAPI "cirrussync-api/api/v1/users"
+	webhookAPI "cirrussync-api/api/v1/webhooks"
+	"cirrussync-api/internal/account"
+	internalAdmin "cirrussync-api/internal/admin"
+	"cirrussync-api/internal/analytics"
 	internalAuth "cirrussync-api/internal/auth"
+	"cirrussync-api/internal/billing"
+	"cirrussync-api/internal/cdn"
 	internalDrive "cirrussync-api/internal/drive"
+	"cirrussync-api/internal/jobs"
 	jwt "cirrussync-api/internal/jwt"
 	log "cirrussync-api/internal/logger"
-	"cirrussync-api/internal/mfa"
+	"cirrussync-api/internal/mailer"
 	internalMfa "cirrussync-api/internal/mfa"
 	"cirrussync-api/internal/middleware"
+	"cirrussync-api/internal/notification"
+	internalOAuth "cirrussync-api/internal/oauth"
+	internalOrg "cirrussync-api/internal/org"
+	"cirrussync-api/internal/payments"
+	"cirrussync-api/internal/quota"
+	"cirrussync-api/internal/security"
 	"cirrussync-api/internal/session"
+	"cirrussync-api/internal/sms"
 	srp "cirrussync-api/internal/srp"
 	internalUser "cirrussync-api/internal/user"
+	"cirrussync-api/internal/webhook"
 	"cirrussync-api/pkg/config"
 	"cirrussync-api/pkg/db"
+	"cirrussync-api/pkg/geoip"
 	"cirrussync-api/pkg/redis"
+	"cirrussync-api/pkg/s3"
+	"cirrussync-api/pkg/validation"
 
 	"github.com/getsentry/sentry-go"
 	sentrylogrus "github.com/getsentry/sentry-go/logrus"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/csrf"
 	"github.com/sirupsen/logrus"
@@ -38,13 +63,30 @@ import (
 
 // Package-level services to avoid recreation
 var (
-	jwtService     *jwt.JWTService
-	sessionService *session.Service
-	userService    *internalUser.Service
-	authService    *internalAuth.Service
-	driveService   *internalDrive.Service
-	logger         *logrus.Logger
-	customLogger   *log.Logger
+	jwtService          *jwt.JWTService
+	sessionService      *session.Service
+	userService         *internalUser.Service
+	authService         *internalAuth.Service
+	driveService        *internalDrive.Service
+	orgService          *internalOrg.Service
+	cdnService          *cdn.Service
+	mfaService          *internalMfa.Service
+	jobService          *jobs.Service
+	quotaService        *quota.Service
+	billingService      *billing.Service
+	paymentService      *payments.Service
+	usageService        *analytics.Service
+	adminService        *internalAdmin.Service
+	webhookService      *webhook.Service
+	securityService     *security.Service
+	oauthService        *internalOAuth.Service
+	accountService      *account.Service
+	notificationService *notification.Service
+	rateLimiter         *middleware.RateLimiter
+	serverConfig        *config.ServerConfig
+	cookies             *middleware.Cookies
+	logger              *logrus.Logger
+	customLogger        *log.Logger
 )
 
 // InitServices initializes all required services
@@ -81,6 +123,10 @@ func InitServices(database *gorm.DB, redisClient *redis.Client) error {
 	// Initialize custom logger wrapper
 	customLogger = log.New(logger)
 
+	// Cookies, CSRF protection and CORS share the deployment's domain and origin settings
+	serverConfig = config.LoadServerConfig()
+	cookies = middleware.NewCookies(serverConfig)
+
 	// Initialize JWT service
 	var err error
 	jwtService, err = jwt.NewJWTService(
@@ -95,39 +141,157 @@ func InitServices(database *gorm.DB, redisClient *redis.Client) error {
 		return err
 	}
 
+	// Initialize storage quota service
+	quotaService = quota.NewService(quota.NewRepository(database), redisClient, customLogger)
+
 	// Initialize Drive service
 	driveRepo := internalDrive.NewRepository(database)
-	driveService = internalDrive.NewService(driveRepo, redisClient, customLogger)
+	driveService = internalDrive.NewService(driveRepo, redisClient, customLogger, config.LoadDriveConfig(), s3.GetS3Client(), quotaService)
+
+	// Initialize MFA service, which sends the verification, invitation and notification emails
+	mfaConfig := internalMfa.MFAConfig{
+		MailConfig:     *config.LoadMailConfig(),
+		TOTPConfig:     *config.LoadTOTPConfig(),
+		WebAuthnConfig: *config.LoadWebAuthnConfig(),
+		SMSConfig:      *config.LoadSMSConfig(),
+	}
+	mfaService = internalMfa.NewService(internalMfa.NewRepository(database), mfaConfig, redisClient, customLogger)
+
+	// SMS codes are only offered when a provider is configured
+	smsProvider, err := sms.NewProvider(&mfaConfig.SMSConfig)
+	switch {
+	case err == nil:
+		mfaService.SetSMSProvider(smsProvider)
+	case !errors.Is(err, sms.ErrNotConfigured):
+		logger.WithError(err).Warn("SMS provider could not be initialized, SMS verification is disabled")
+	}
+
+	// Emails go through the configured provider; without one every send fails and is logged
+	emailSender, err := mailer.NewSender(&mfaConfig.MailConfig)
+	if err != nil {
+		logger.WithError(err).Warn("Email provider could not be initialized, emails cannot be sent")
+	} else {
+		mfaService.SetEmailDispatcher(mailer.NewDispatcher(emailSender, &mfaConfig.MailConfig, redisClient, customLogger))
+	}
+	driveService.SetInvitationMailer(mfaService)
+	quotaService.SetWarningMailer(mfaService)
+
+	// In-app notifications, such as storage warnings, are listed in the user's inbox
+	notificationService = notification.NewService(notification.NewRepository(database), customLogger)
+	quotaService.SetNotifier(notificationService)
+
+	// Clients are routed to regional endpoints; without a GeoIP database only CDN location headers locate them
+	regionsConfig := config.LoadRegionsConfig()
+	geoipReader, err := geoip.Open(regionsConfig.GeoIPDatabasePath)
+	if err != nil && !errors.Is(err, geoip.ErrNotConfigured) {
+		logger.WithError(err).Warn("GeoIP database could not be opened, clients are located from CDN headers only")
+	}
+	if geoipReader != nil {
+		if err := geoipReader.OpenASN(regionsConfig.GeoIPASNDatabasePath); err != nil && !errors.Is(err, geoip.ErrNotConfigured) {
+			logger.WithError(err).Warn("GeoIP ASN database could not be opened, sessions are located without their network")
+		}
+	}
+	driveService.SetRegionRouting(regionsConfig.Endpoints, geoipReader)
+
+	// Initialize background jobs; handlers register themselves before workers start
+	jobService = jobs.NewService(jobs.NewRepository(database), redisClient, customLogger, config.LoadJobsConfig())
+	driveService.SetJobService(jobService)
+	mfaService.SetJobService(jobService)
+	quotaService.SetJobService(jobService)
+	sessionService.SetJobService(jobService)
+
+	// Initialize billing service
+	billingService = billing.NewService(billing.NewRepository(database), redisClient, customLogger, config.LoadBillingConfig(), quotaService, driveService)
+
+	// Initialize the payments outbox; billing creates the customers queued at signup
+	paymentService = payments.NewService(payments.NewRepository(database), customLogger, config.LoadPaymentsConfig())
+	billingService.SetPaymentsService(paymentService)
+
+	// Initialize CDN purge service
+	cdnService = cdn.NewService(config.LoadCDNConfig(), customLogger)
+
+	// Initialize organization service
+	orgRepo := internalOrg.NewRepository(database)
+	orgService = internalOrg.NewService(orgRepo, redisClient, customLogger, driveService, s3.GetS3Client())
+	driveService.SetEncryptionKeyResolver(orgService)
 
 	// Initialize user repository and service
 	userRepo := internalUser.NewRepository(database)
-	userService = internalUser.NewService(userRepo, redisClient, driveService)
+	userService = internalUser.NewService(userRepo, redisClient, driveService, config.LoadSecurityConfig())
 
 	// Initialize session repository and service
 	sessionRepo := session.NewRepository(database)
-	sessionService = session.NewService(sessionRepo, redisClient, customLogger)
+	sessionService = session.NewService(sessionRepo, redisClient, customLogger, config.LoadSessionConfig())
+
+	// Sessions are located by IP address; logins from new countries or impossible travel are reported
+	sessionService.SetLocator(geoipReader)
+	sessionService.SetLoginAlertMailer(mfaService)
+
+	// Initialize anonymized usage metrics, counted only for users who consented to analytics, and API error metrics
+	usageService = analytics.NewService(analytics.NewRepository(database), redisClient, customLogger, config.LoadUsageMetricsConfig(), config.LoadErrorMetricsConfig(), userService)
+
+	// Initialize admin permissions and audit log
+	adminService = internalAdmin.NewService(internalAdmin.NewRepository(database), redisClient, customLogger)
+
+	// Initialize webhook signing and test deliveries
+	webhookService = webhook.NewService(webhook.NewRepository(database), redisClient, customLogger, config.LoadWebhookConfig())
+
+	// Initialize the security event log, written by the services that perform the recorded actions
+	securityService = security.NewService(security.NewRepository(database), customLogger)
+	driveService.SetSecurityEvents(securityService)
+	mfaService.SetSecurityEvents(securityService)
+	sessionService.SetSecurityEvents(securityService)
+
+	// Security event reports are generated by a job, stored in S3 and announced by email
+	securityService.SetJobService(jobService)
+	if storage := s3.GetS3Client(); storage != nil {
+		securityService.SetReportStorage(storage)
+	}
+	securityService.SetReportMailer(mfaService)
 
 	// Initialize SRP repository
 	srpRepo := srp.NewRepository(database)
 
 	// Initialize Auth service with all dependencies
-	authService = internalAuth.NewService(redisClient, customLogger, srpRepo, userService)
+	authService = internalAuth.NewService(redisClient, customLogger, srpRepo, userService, mfaService, config.LoadLoginLockoutConfig())
+	authService.SetSecurityEvents(securityService)
+	authService.SetSessionService(sessionService)
+	authService.SetLockoutMailer(mfaService)
+
+	// Initialize the OAuth provider for third-party apps; its ID tokens are signed with the JWT keys
+	oauthService = internalOAuth.NewService(internalOAuth.NewRepository(database), redisClient, customLogger, config.LoadOAuthConfig(), jwtService)
+	oauthService.SetSecurityEvents(securityService)
+
+	// Initialize the dashboard account overview, assembled from the services above
+	accountService = account.NewService(quotaService, securityService, sessionService, userService, driveService, redisClient, customLogger)
+
+	// Deleted accounts are purged by a job after their grace period, warned and cancelled by email
+	accountService.SetBillingService(billingService)
+	accountService.SetJobService(jobService)
+	accountService.SetDeletionMailer(mfaService)
+
+	// The sandbox only accepts sandbox access tokens and resets its drives to synthetic data every night
+	if sandboxConfig := config.LoadSandboxConfig(); sandboxConfig.Enabled {
+		orgService.SetSandbox()
+		driveService.SetSandbox(sandboxConfig)
+		logger.Info("Serving the API sandbox")
+	}
 
 	logger.Info("All services initialized successfully")
 	return nil
 }
 
-// CSRFMiddleware creates a middleware for CSRF protection
-func CSRFMiddleware(secret string, secure bool) gin.HandlerFunc {
+// CSRFMiddleware creates a middleware for CSRF protection whose cookie follows the server's cookie settings
+func CSRFMiddleware(secret string, serverConfig *config.ServerConfig) gin.HandlerFunc {
 	csrfMiddleware := csrf.Protect(
 		[]byte(secret),
-		csrf.Secure(secure),
+		csrf.Secure(serverConfig.SecureCookies),
 		csrf.HttpOnly(true),
 		csrf.Path("/"),
 		csrf.CookieName("csrfToken"),
 		csrf.MaxAge(3600), // 1 hour
-		csrf.SameSite(csrf.SameSiteStrictMode),
-		csrf.Domain("localhost"),
+		csrf.SameSite(csrfSameSite(serverConfig.SameSite)),
+		csrf.Domain(serverConfig.CookieDomain),
 		csrf.ErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Ensure CORS headers are set even for CSRF errors
 			c, _ := gin.CreateTestContext(w)
@@ -147,6 +311,21 @@ func CSRFMiddleware(secret string, secure bool) gin.HandlerFunc {
 	)
 
 	return func(c *gin.Context) {
+		// Access tokens are sent explicitly in a header rather than by the browser, so CSRF does not apply
+		if middleware.IsAccessTokenRequest(c.Request) {
+			c.Request = csrf.UnsafeSkipCheck(c.Request)
+		}
+
+		// Payment provider webhooks are verified by their signature
+		if strings.HasPrefix(c.Request.URL.Path, billingAPI.WebhookPathPrefix) {
+			c.Request = csrf.UnsafeSkipCheck(c.Request)
+		}
+
+		// OAuth clients authenticate with their own credentials or tokens
+		if oauthAPI.IsClientPath(c.Request.URL.Path) {
+			c.Request = csrf.UnsafeSkipCheck(c.Request)
+		}
+
 		csrfMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			c.Request = r
 			c.Next()
@@ -155,9 +334,27 @@ func CSRFMiddleware(secret string, secure bool) gin.HandlerFunc {
 	}
 }
 
-// SetupEngine creates a new Gin engine with default middleware
+// csrfSameSite maps a SameSite mode to the CSRF package's equivalent
+func csrfSameSite(mode http.SameSite) csrf.SameSiteMode {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return csrf.SameSiteLaxMode
+	case http.SameSiteNoneMode:
+		return csrf.SameSiteNoneMode
+	default:
+		return csrf.SameSiteStrictMode
+	}
+}
+
+// SetupEngine creates a new Gin engine whose first middleware assigns request IDs and logs requests,
+// followed by panic recovery so recovered requests are logged with their ID and status
 func SetupEngine() *gin.Engine {
-	return gin.Default()
+	// Request bindings share custom validators and report fields by their JSON names
+	validation.Register()
+
+	r := gin.New()
+	r.Use(middleware.RequestLoggingMiddleware(customLogger), middleware.RecoveryMiddleware(customLogger))
+	return r
 }
 
 // SetupCsrfRoutes configures CSRF-related routes
@@ -172,32 +369,20 @@ func SetupCsrfRoutes(r *gin.Engine) {
 }
 
 // SetupMFARoutes configures MFA-related routes
-func SetupMFARoutes(r *gin.Engine, database *gorm.DB) {
+func SetupMFARoutes(r *gin.Engine) {
 	// Create API v1 group
 	v1 := r.Group("/api/v1")
 
-	// Configure MFA service
-	mailConfig := config.LoadMailConfig()
-	totpConfig := config.LoadTOTPConfig()
-
-	MFAConfig := internalMfa.MFAConfig{
-		MailConfig: *mailConfig,
-		TOTPConfig: *totpConfig,
-	}
-
-	// Get Redis client
-	redisClient := redis.GetDefault()
-
-	mfaRepo := internalMfa.NewRepository(database)
-
-	// Create MFA service
-	mfaService := mfa.NewService(mfaRepo, MFAConfig, redisClient, customLogger)
-
-	// Create MFA handler
+	// Create MFA handler using the global service
 	mfaHandler := mfaAPI.NewHandler(mfaService, customLogger)
 
 	// Register routes
 	mfaAPI.RegisterProtectedRoutes(v1, mfaHandler)
+
+	// Passkey management acts on the signed in user
+	authenticated := v1.Group("")
+	authenticated.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	mfaAPI.RegisterAuthenticatedRoutes(authenticated, mfaHandler)
 }
 
 // SetupAuthRoutes configures auth-related routes
@@ -206,14 +391,14 @@ func SetupAuthRoutes(r *gin.Engine) {
 	v1 := r.Group("/api/v1")
 
 	// Create auth handler using the global services
-	authHandler := authAPI.NewHandler(authService, userService, jwtService, sessionService, customLogger)
+	authHandler := authAPI.NewHandler(authService, userService, jwtService, sessionService, cookies, customLogger)
 
 	// Register public auth routes
 	authAPI.RegisterPublicRoutes(v1, authHandler)
 
 	// Create authenticated route group
 	authGroup := v1.Group("/auth")
-	authGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService))
+	authGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
 	authAPI.RegisterProtectedRoutes(authGroup, authHandler)
 }
 
@@ -223,12 +408,35 @@ func SetupSessionsRoutes(r *gin.Engine) {
 	v1 := r.Group("/api/v1")
 
 	// Create user handler using the global service
-	userHandler := userAPI.NewHandler(userService, customLogger)
+	userHandler := userAPI.NewHandler(userService, cookies, customLogger)
 
 	// Create user route group with auth middleware
 	userGroup := v1.Group("/users")
-	userGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService))
+	userGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
 	userAPI.RegisterProtectedRoutes(userGroup, userHandler)
+
+	// The current user's sessions are managed by the session handler
+	sessionAPI.RegisterUserRoutes(userGroup, sessionAPI.NewHandler(sessionService, cookies, customLogger))
+
+	// The current user's security events are served by the security handler
+	securityAPI.RegisterUserRoutes(userGroup, securityAPI.NewHandler(securityService, customLogger))
+
+	// Changing the current user's email address is handled by the auth handler
+	authAPI.RegisterUserRoutes(userGroup, authAPI.NewHandler(authService, userService, jwtService, sessionService, cookies, customLogger))
+
+	// Deleting the current user's account is handled by the account handler
+	accountAPI.RegisterUserRoutes(userGroup, accountAPI.NewHandler(accountService, customLogger))
+
+	// The current user's in-app notifications are served by the notifications handler
+	notificationsAPI.RegisterUserRoutes(userGroup, notificationsAPI.NewHandler(notificationService, customLogger))
+
+	// Account settings routes share the user handler
+	settingsGroup := v1.Group("/settings")
+	settingsGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	userAPI.RegisterSettingsRoutes(settingsGroup, userHandler)
+
+	// Login lockout settings are served by the auth handler
+	authAPI.RegisterSettingsRoutes(settingsGroup, authAPI.NewHandler(authService, userService, jwtService, sessionService, cookies, customLogger))
 }
 
 // SetupUserRoutes configures user-related routes
@@ -237,11 +445,11 @@ func SetupUsersRoutes(r *gin.Engine) {
 	v1 := r.Group("/api/v1")
 
 	// Create user handler using the global service
-	sessionHandler := sessionAPI.NewHandler(sessionService, customLogger)
+	sessionHandler := sessionAPI.NewHandler(sessionService, cookies, customLogger)
 
 	// Create session route group with auth middleware
 	sessionGroup := v1.Group("/sessions")
-	sessionGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService))
+	sessionGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
 	sessionAPI.RegisterProtectedRoutes(sessionGroup, sessionHandler)
 }
 
@@ -253,12 +461,109 @@ func SetupDriveRoutes(r *gin.Engine, database *gorm.DB) {
 	// Create drive handler using the global service
 	driveHandler := driveAPI.NewHandler(driveService, userService, customLogger)
 
+	// Register public drive routes
+	driveAPI.RegisterPublicRoutes(v1, driveHandler)
+
 	// Create drive route group with auth middleware
 	driveGroup := v1.Group("/drive")
-	driveGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService))
+	driveGroup.Use(middleware.AccessTokenOrJWTAuthMiddleware(orgService, oauthService, jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
 	driveAPI.RegisterProtectedRoutes(driveGroup, driveHandler)
 }
 
+// SetupOrgRoutes configures organization and service account routes
+func SetupOrgRoutes(r *gin.Engine) {
+	// Create API v1 group
+	v1 := r.Group("/api/v1")
+
+	// Create organization handler using the global service
+	orgHandler := orgAPI.NewHandler(orgService, customLogger)
+
+	// Organizations are managed interactively, so access tokens are not accepted here
+	orgGroup := v1.Group("/orgs")
+	orgGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	orgAPI.RegisterProtectedRoutes(orgGroup, orgHandler)
+}
+
+// SetupBillingRoutes configures billing routes
+func SetupBillingRoutes(r *gin.Engine) {
+	// Create API v1 group
+	v1 := r.Group("/api/v1")
+
+	// Create billing handler using the global service
+	billingHandler := billingAPI.NewHandler(billingService, customLogger)
+	billingAPI.RegisterPublicRoutes(v1, billingHandler)
+
+	// Purchases are made interactively, so access tokens are not accepted here
+	billingGroup := v1.Group("/billing")
+	billingGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	billingAPI.RegisterProtectedRoutes(billingGroup, billingHandler)
+}
+
+// SetupWebhookRoutes configures webhook signing and test delivery routes
+func SetupWebhookRoutes(r *gin.Engine) {
+	// Create API v1 group
+	v1 := r.Group("/api/v1")
+
+	// Create webhook handler using the global service
+	webhookHandler := webhookAPI.NewHandler(webhookService, customLogger)
+	webhookAPI.RegisterPublicRoutes(v1, webhookHandler)
+
+	// Signing secrets are managed interactively, so access tokens are not accepted here
+	webhookGroup := v1.Group("/webhooks")
+	webhookGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	webhookAPI.RegisterProtectedRoutes(webhookGroup, webhookHandler)
+}
+
+// SetupOAuthRoutes configures the OAuth provider: client endpoints, discovery and the consent API
+func SetupOAuthRoutes(r *gin.Engine) {
+	// Create API v1 group
+	v1 := r.Group("/api/v1")
+
+	// Create OAuth handler using the global service
+	oauthHandler := oauthAPI.NewHandler(oauthService, customLogger)
+	oauthAPI.RegisterDiscoveryRoutes(r, oauthHandler)
+	oauthAPI.RegisterClientRoutes(v1, oauthHandler, middleware.OAuthTokenMiddleware(oauthService))
+
+	// Consent is given interactively, so neither access tokens nor OAuth tokens are accepted here
+	consentGroup := v1.Group("/oauth")
+	consentGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	oauthAPI.RegisterConsentRoutes(consentGroup, oauthHandler)
+}
+
+// SetupAdminRoutes configures admin-related routes
+func SetupAdminRoutes(r *gin.Engine) {
+	// Create API v1 group
+	v1 := r.Group("/api/v1")
+
+	// Create admin handler using the global services
+	adminHandler := adminAPI.NewHandler(driveService, cdnService, billingService, usageService, adminService, mfaService, oauthService, sessionService, jobService, customLogger)
+
+	// Create admin route group with auth and admin role middleware; every request that
+	// authenticates is audited, including ones refused for missing roles or permissions
+	adminGroup := v1.Group("/admin")
+	adminGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	adminGroup.Use(middleware.AdminAuditMiddleware(adminService))
+	adminGroup.Use(middleware.AdminRequiredMiddleware())
+	adminAPI.RegisterProtectedRoutes(adminGroup, adminHandler)
+}
+
+// SetupAccountRoutes configures account overview routes
+func SetupAccountRoutes(r *gin.Engine) {
+	// Create API v1 group
+	v1 := r.Group("/api/v1")
+
+	// Create account handler using the global service
+	accountHandler := accountAPI.NewHandler(accountService, customLogger)
+
+	// Register public account routes
+	accountAPI.RegisterPublicRoutes(v1, accountHandler)
+
+	// The dashboard is interactive, so access tokens are not accepted here
+	accountGroup := v1.Group("/account")
+	accountGroup.Use(middleware.JWTAuthMiddleware(jwtService, sessionService), middleware.UserRateLimitMiddleware(rateLimiter))
+	accountAPI.RegisterProtectedRoutes(accountGroup, accountHandler)
+}
+
 // SetupCSRFProtection configures CSRF protection
 func SetupCSRFProtection(r *gin.Engine) error {
 	csrfSecret := os.Getenv("CSRF_SECRET")
@@ -267,10 +572,7 @@ func SetupCSRFProtection(r *gin.Engine) error {
 		return errors.New("CSRF_SECRET environment variable is required")
 	}
 
-	csrfSecureStr := os.Getenv("CSRF_SECURE")
-	csrfSecure, _ := strconv.ParseBool(csrfSecureStr)
-
-	r.Use(CSRFMiddleware(csrfSecret, csrfSecure))
+	r.Use(CSRFMiddleware(csrfSecret, serverConfig))
 
 	return nil
 }
@@ -280,14 +582,122 @@ func SetupCORS(r *gin.Engine) {
 	// Trusted Proxies
 	r.SetTrustedProxies([]string{"http://localhost:1420"})
 
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{"http://localhost:1420"}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-TOKEN", "X-App-Version", "X-Client-UID", "X-Client-Name"}
-	corsConfig.AllowCredentials = true
-	corsConfig.MaxAge = 24 * time.Hour
+	r.Use(middleware.CORSMiddleware(serverConfig))
+}
+
+// SetupRateLimiting limits requests per client IP; route groups add per-user limits after authenticating
+func SetupRateLimiting(r *gin.Engine, redisClient *redis.Client) {
+	rateLimitConfig := config.LoadRateLimitConfig()
+	if !rateLimitConfig.Enabled {
+		return
+	}
+
+	// Integrations under development hit the sandbox harder than real clients hit production
+	if sandboxConfig := config.LoadSandboxConfig(); sandboxConfig.Enabled && sandboxConfig.RateLimitMultiplier > 1 {
+		for _, rule := range []*config.RateLimitRule{&rateLimitConfig.Auth, &rateLimitConfig.Read, &rateLimitConfig.Upload, &rateLimitConfig.Default} {
+			rule.PerIP *= sandboxConfig.RateLimitMultiplier
+			rule.PerUser *= sandboxConfig.RateLimitMultiplier
+		}
+	}
+
+	rateLimiter = middleware.NewRateLimiter(redisClient, rateLimitConfig, customLogger)
+	r.Use(middleware.RateLimitMiddleware(rateLimiter))
+}
+
+// SetupCompression configures response compression
+func SetupCompression(r *gin.Engine) {
+	compressionConfig := config.LoadCompressionConfig()
+	if !compressionConfig.Enabled {
+		return
+	}
+
+	r.Use(middleware.CompressionMiddleware(compressionConfig.MinSize))
+}
+
+// SetupMetrics records request metrics and serves them to Prometheus at /metrics
+func SetupMetrics(r *gin.Engine) {
+	metricsConfig := config.LoadMetricsConfig()
+	if !metricsConfig.Enabled {
+		return
+	}
 
-	r.Use(cors.New(corsConfig))
+	r.Use(middleware.MetricsMiddleware())
+	r.GET("/metrics", middleware.MetricsHandler(metricsConfig.Token))
+}
+
+// SetupStorageStatus serves the service status at /status and marks responses while storage is degraded
+func SetupStorageStatus(r *gin.Engine) {
+	storage := s3.GetS3Client()
+	if storage == nil {
+		r.GET("/status", middleware.StatusHandler(nil))
+		return
+	}
+
+	r.Use(middleware.StorageStatusMiddleware(storage))
+	r.GET("/status", middleware.StatusHandler(storage))
+}
+
+// SetupTracing starts a trace span for every request when tracing is enabled
+func SetupTracing(r *gin.Engine) {
+	if !config.LoadTracingConfig().Enabled {
+		return
+	}
+
+	r.Use(middleware.TracingMiddleware())
+}
+
+// SetupUsageMetrics counts requests towards anonymized feature usage once they are handled
+func SetupUsageMetrics(r *gin.Engine) {
+	if !config.LoadUsageMetricsConfig().Enabled {
+		return
+	}
+
+	r.Use(middleware.UsageMetricsMiddleware(usageService))
+}
+
+// SetupErrorMetrics reads the API error code of rejected and failed requests for the request log and
+// counts it per route and client version when error metrics are enabled
+func SetupErrorMetrics(r *gin.Engine) {
+	if !config.LoadErrorMetricsConfig().Enabled {
+		return
+	}
+
+	r.Use(middleware.ErrorMetricsMiddleware(usageService))
+}
+
+// SetupRequestDeadlines gives every request the default deadline. Route groups with a budget of
+// their own, such as drive and billing, replace it.
+func SetupRequestDeadlines(r *gin.Engine) {
+	timeout := time.Duration(config.LoadConfig().RequestTimeout) * time.Second
+	r.Use(middleware.RequestBudgetMiddleware(middleware.FixedBudget(timeout)))
+}
+
+// StartBackgroundJobs starts the job workers, the share expiry, storage integrity, abandoned upload, backup retention, trash purge, folder size, storage lifecycle, garbage collection, sandbox reset, session cleanup, account deletion and storage warning schedulers, the storage availability probe, the usage and error metrics flush, the legacy TOTP migration and the payments outbox worker. They stop picking up work when ctx is cancelled.
+func StartBackgroundJobs(ctx context.Context) error {
+	if jobService == nil || paymentService == nil || usageService == nil || mfaService == nil || accountService == nil {
+		return errors.New("services have not been initialized")
+	}
+	if err := jobService.Start(ctx); err != nil {
+		return err
+	}
+	driveService.StartShareExpiryScheduler(ctx)
+	driveService.StartIntegrityAuditScheduler(ctx)
+	driveService.StartUploadCleanupScheduler(ctx)
+	driveService.StartBackupRetentionScheduler(ctx)
+	driveService.StartTrashPurgeScheduler(ctx)
+	driveService.StartSandboxResetScheduler(ctx)
+	driveService.StartFolderSizeScheduler(ctx)
+	driveService.StartStorageLifecycleScheduler(ctx)
+	driveService.StartGarbageCollectionScheduler(ctx)
+	sessionService.StartCleanupScheduler(ctx)
+	accountService.StartDeletionScheduler(ctx)
+	quotaService.StartWarningScheduler(ctx)
+	usageService.StartFlushScheduler(ctx)
+	if storage := s3.GetS3Client(); storage != nil {
+		storage.StartAvailabilityProbe(ctx)
+	}
+	go mfaService.MigrateLegacyTOTP(ctx)
+	return paymentService.Start(ctx)
 }
 
 // SetupRouter creates and configures the main router with all routes
@@ -307,22 +717,50 @@ func SetupRouter(database *gorm.DB) (*gin.Engine, error) {
 	// Create and configure Gin router
 	r := SetupEngine()
 
+	// Setup tracing and Prometheus metrics first so every request is traced and timed
+	SetupTracing(r)
+	SetupMetrics(r)
+
 	// Setup CORS
 	SetupCORS(r)
 
+	// Setup storage status headers after CORS, so clients can read them on every response
+	SetupStorageStatus(r)
+
+	// Setup rate limiting after CORS, so preflights are not counted and refusals carry CORS headers
+	SetupRateLimiting(r, redisClient)
+
+	// Setup response compression
+	SetupCompression(r)
+
+	// Setup anonymized feature usage counting
+	SetupUsageMetrics(r)
+
+	// Setup API error code counting, inside compression so error bodies can be read
+	SetupErrorMetrics(r)
+
 	// Setup CSRF protection
 	if err := SetupCSRFProtection(r); err != nil {
 		logger.WithError(err).Error("Failed to setup CSRF protection")
 		return nil, err
 	}
 
+	// Setup the default request deadline, which routes with a budget of their own replace
+	SetupRequestDeadlines(r)
+
 	// Configure routes
 	SetupCsrfRoutes(r)
 	SetupAuthRoutes(r)
 	SetupUsersRoutes(r)
+	SetupAccountRoutes(r)
 	SetupSessionsRoutes(r)
-	SetupMFARoutes(r, database)
+	SetupMFARoutes(r)
 	SetupDriveRoutes(r, database)
+	SetupOrgRoutes(r)
+	SetupBillingRoutes(r)
+	SetupWebhookRoutes(r)
+	SetupOAuthRoutes(r)
+	SetupAdminRoutes(r)
 
 	logger.Info("Router setup completed successfully")
 	return r, nil